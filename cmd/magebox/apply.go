@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply config changes to a running project",
+	Long: `Compares the generated nginx vhosts, PHP-FPM pool and Docker services of the
+running project with .magebox.yaml and .magebox.local.yaml, and applies only
+what changed: nginx and PHP-FPM are reloaded, and only the Docker services whose
+definition changed are recreated. There's no need to stop and start the project
+after editing the config.
+
+With --watch the config files are watched and changes are applied as they are
+saved.
+
+Examples:
+  magebox apply --dry-run   # Show what would change
+  magebox apply             # Apply the changes
+  magebox apply --watch     # Apply changes on every save`,
+	Args: cobra.NoArgs,
+	RunE: runApply,
+}
+
+var (
+	applyDryRun bool
+	applyWatch  bool
+)
+
+// applyWatchInterval is how often --watch checks the config files
+const applyWatchInterval = time.Second
+
+func init() {
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show what would change without changing anything")
+	applyCmd.Flags().BoolVarP(&applyWatch, "watch", "w", false, "Watch the config files and apply changes as they are saved")
+	rootCmd.AddCommand(applyCmd)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if !projectRunning(p, cwd) {
+		cli.PrintError("Project %s is not running", cfg.Name)
+		cli.PrintInfo("Run %s to start it", cli.Command("magebox start"))
+		return nil
+	}
+
+	mgr := project.NewManager(p)
+	mgr.SetLowMemory(lowMemoryDefault(p))
+
+	if !applyWatch {
+		mgr.SetContext(cmd.Context())
+		applyProjectChanges(mgr, cwd)
+		return nil
+	}
+
+	// Stop watching on Ctrl+C, after the changes being applied
+	commandHandlesInterrupt.Store(true)
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	applyProjectChanges(mgr, cwd)
+	fmt.Println()
+	cli.PrintInfo("Watching the config of %s, press Ctrl+C to stop", cfg.Name)
+
+	last := configModTimes(cwd)
+	ticker := time.NewTicker(applyWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current := configModTimes(cwd)
+			if current == last {
+				continue
+			}
+			last = current
+			fmt.Println()
+			cli.PrintInfo("Config changed at %s", time.Now().Format("15:04:05"))
+			applyProjectChanges(mgr, cwd)
+		}
+	}
+}
+
+// applyProjectChanges plans and applies the config changes of a project and
+// prints a summary
+func applyProjectChanges(mgr *project.Manager, cwd string) {
+	plan, err := mgr.PlanApply(cwd)
+	if err != nil {
+		if structuredOutput() {
+			_ = printStructured(map[string]string{"error": err.Error()})
+			return
+		}
+		cli.PrintError("%v", err)
+		return
+	}
+
+	if applyDryRun || plan.Empty() {
+		if structuredOutput() {
+			_ = printStructured(plan)
+			return
+		}
+		if plan.Empty() {
+			cli.PrintSuccess("%s is up to date", plan.Project)
+			return
+		}
+		cli.PrintTitle("Dry Run: Would apply to %s", plan.Project)
+		fmt.Println()
+		printApplyChanges(plan.Changes)
+		return
+	}
+
+	warnings := mgr.Apply(plan)
+	if structuredOutput() {
+		_ = printStructured(struct {
+			*project.ApplyPlan
+			Warnings []string `json:"warnings"`
+		}{plan, warnings})
+		return
+	}
+
+	printApplyChanges(plan.Changes)
+	fmt.Println()
+	for _, w := range warnings {
+		cli.PrintWarning("%s", w)
+	}
+	if len(warnings) > 0 {
+		cli.PrintWarning("Applied %d change(s) to %s with %d warning(s)", len(plan.Changes), plan.Project, len(warnings))
+		return
+	}
+	cli.PrintSuccess("Applied %d change(s) to %s", len(plan.Changes), plan.Project)
+}
+
+// printApplyChanges lists the changes of an apply plan
+func printApplyChanges(changes []project.ApplyChange) {
+	for _, c := range changes {
+		fmt.Printf("  %-7s %-15s %s\n", c.Action, c.Kind, c.Target)
+	}
+}
+
+// projectRunning reports whether the project in dir has nginx vhosts
+func projectRunning(p *platform.Platform, dir string) bool {
+	projects, err := project.NewProjectDiscovery(p).DiscoverProjects()
+	if err != nil {
+		return false
+	}
+	for _, proj := range projects {
+		if proj.Path == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// configModTimes returns the modification times of the project config files,
+// which change when any of them is saved, created or removed
+func configModTimes(dir string) [4]time.Time {
+	var times [4]time.Time
+	names := []string{config.ConfigFileName, config.ConfigFileNameLegacy, config.LocalConfigFileName, config.LocalConfigFileNameLegacy}
+	for i, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/composer"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage Composer authentication",
+	Long: `Manages the credentials in your global Composer auth.json.
+
+Entries are merged into the file: other hosts and sections, like a GitLab
+token or a private Packagist login, are kept as they are.
+
+Examples:
+  magebox auth set repo.magento.com <public-key> <private-key>
+  magebox auth set github <token>
+  magebox auth set hyva-themes.repo.packagist.com token <token>
+  magebox auth show`,
+}
+
+var authSetCmd = &cobra.Command{
+	Use:   "set <host> <username> <password> | github <token>",
+	Short: "Set the credentials of a Composer repository",
+	Long: `Sets the http-basic credentials of a repository host, e.g. the public and
+private key of repo.magento.com, or with 'github' the GitHub token Composer
+uses against API rate limits.`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: runAuthSet,
+}
+
+var authShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the Composer credentials with masked secrets",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthShow,
+}
+
+func init() {
+	authCmd.AddCommand(authSetCmd)
+	authCmd.AddCommand(authShowCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+// loadComposerAuth loads the auth.json MageBox manages
+func loadComposerAuth() (*composer.Auth, error) {
+	p, err := getPlatform()
+	if err != nil {
+		return nil, err
+	}
+	return composer.LoadAuth(composer.AuthPath(p.HomeDir))
+}
+
+func runAuthSet(cmd *cobra.Command, args []string) error {
+	auth, err := loadComposerAuth()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	host := args[0]
+	switch {
+	case host == "github" || host == composer.GitHubHost:
+		if len(args) != 2 {
+			cli.PrintError("Usage: magebox auth set github <token>")
+			return nil
+		}
+		if err := auth.SetGitHubToken(args[1]); err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		host = composer.GitHubHost
+	case len(args) != 3:
+		cli.PrintError("Usage: magebox auth set %s <username> <password>", host)
+		return nil
+	default:
+		if err := auth.SetHTTPBasic(host, args[1], args[2]); err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+	}
+
+	if err := auth.Save(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", auth.Path, err)
+	}
+	cli.PrintSuccess("Saved the credentials of %s in %s", host, cli.Path(auth.Path))
+	return nil
+}
+
+func runAuthShow(cmd *cobra.Command, args []string) error {
+	auth, err := loadComposerAuth()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	entries := auth.Entries()
+	if structuredOutput() {
+		if entries == nil {
+			entries = []composer.Entry{}
+		}
+		return printStructured(entries)
+	}
+
+	cli.PrintTitle("Composer Authentication")
+	fmt.Printf("File: %s\n", cli.Path(auth.Path))
+	fmt.Println()
+
+	if len(entries) == 0 {
+		cli.PrintInfo("No credentials configured")
+		fmt.Println(cli.Bullet("Add the Magento keys with " + cli.Command("magebox auth set repo.magento.com <public> <private>")))
+		return nil
+	}
+
+	for _, e := range entries {
+		if e.Username != "" {
+			fmt.Printf("  %-14s %-40s %s / %s\n", e.Section, cli.Highlight(e.Host), e.Username, e.Secret)
+		} else {
+			fmt.Printf("  %-14s %-40s %s\n", e.Section, cli.Highlight(e.Host), e.Secret)
+		}
+	}
+	return nil
+}
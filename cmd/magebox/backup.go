@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/backup"
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/ssl"
+)
+
+var (
+	backupMedia bool
+	backupNoDB  bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [file]",
+	Short: "Back up the project state to a single archive",
+	Long: `Bundles everything a project needs besides its git checkout into one
+archive, to move a project to another machine or hand it to a new team member:
+
+  - a dump of every project database
+  - .magebox.yaml, .magebox.local.yaml and app/etc/env.php
+  - the SSL certificates of the project domains
+  - pub/media with --media (without the image cache)
+
+The archive is compressed with zstd (.tar.zst, needs the zstd binary). Give a
+file name ending in .tar.gz to use gzip instead.
+
+Restore it with 'magebox restore'.
+
+Examples:
+  magebox backup                       # mystore-backup-2024-05-01_12-00-00.tar.zst
+  magebox backup --media
+  magebox backup ~/mystore.tar.gz --no-db`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runBackup,
+}
+
+// backupProjectFiles are the project files a backup includes, when present
+var backupProjectFiles = []string{
+	config.ConfigFileName,
+	config.ConfigFileNameLegacy,
+	config.LocalConfigFileName,
+	config.LocalConfigFileNameLegacy,
+	"app/etc/env.php",
+}
+
+// backupMediaSkip are the pub/media directories left out of a backup,
+// Magento regenerates them
+var backupMediaSkip = map[string]bool{
+	"catalog/product/cache": true,
+	"tmp":                   true,
+}
+
+func init() {
+	backupCmd.Flags().BoolVar(&backupMedia, "media", false, "Include pub/media")
+	backupCmd.Flags().BoolVar(&backupNoDB, "no-db", false, "Skip the database dump")
+	rootCmd.AddCommand(backupCmd)
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	archivePath := fmt.Sprintf("%s-backup-%s.tar.zst", cfg.Name, time.Now().Format("2006-01-02_15-04-05"))
+	if len(args) > 0 {
+		archivePath = args[0]
+	}
+
+	if backup.UsesZstd(archivePath) && !platform.CommandExists("zstd") {
+		cli.PrintError("zstd is not installed")
+		cli.PrintInfo("Install zstd, or write a gzip archive: magebox backup %s.tar.gz", cfg.Name)
+		return nil
+	}
+	if _, err := os.Stat(archivePath); err == nil {
+		cli.PrintError("%s already exists", archivePath)
+		return nil
+	}
+
+	manifest := &backup.Manifest{
+		Version:   backup.FormatVersion,
+		Project:   cfg.Name,
+		CreatedAt: time.Now().UTC(),
+		MageBox:   version,
+	}
+
+	var db *dbInfo
+	if !backupNoDB {
+		if db, err = getDbInfo(cfg); err != nil {
+			cli.PrintWarning("%v, skipping the database", err)
+		} else {
+			manifest.Databases = cfg.DatabaseNames()
+		}
+	}
+
+	sslMgr := ssl.NewManager(p)
+	for _, domain := range cfg.Domains {
+		base := ssl.ExtractBaseDomainForTLD(domain.Host, cfg.TLD)
+		if domain.IsSSLEnabled() && sslMgr.CertExists(base) && !slices.Contains(manifest.Certs, base) {
+			manifest.Certs = append(manifest.Certs, base)
+		}
+	}
+
+	mediaDir := filepath.Join(cwd, "pub", "media")
+	if backupMedia {
+		if info, err := os.Stat(mediaDir); err == nil && info.IsDir() {
+			manifest.Media = true
+		} else {
+			cli.PrintWarning("pub/media not found, skipping media")
+		}
+	}
+
+	cli.PrintTitle("Project Backup")
+	fmt.Printf("Project: %s\n", cli.Highlight(cfg.Name))
+	fmt.Printf("Archive: %s\n", cli.Path(archivePath))
+	fmt.Println()
+
+	w, err := backup.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	if err := writeBackup(w, manifest, cwd, db, sslMgr); err != nil {
+		_ = w.Close()
+		os.Remove(archivePath)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		os.Remove(archivePath)
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	info, _ := os.Stat(archivePath)
+	fmt.Println()
+	cli.PrintSuccess("Backup written: %s (%s)", archivePath, formatFileSize(info.Size()))
+	cli.PrintInfo("Restore with: magebox restore %s", archivePath)
+	return nil
+}
+
+// writeBackup writes the manifest and everything it lists to w
+func writeBackup(w *backup.Writer, manifest *backup.Manifest, cwd string, db *dbInfo, sslMgr *ssl.Manager) error {
+	if err := w.WriteManifest(manifest); err != nil {
+		return err
+	}
+
+	for _, dbName := range manifest.Databases {
+		fmt.Printf("Dumping database '%s'... ", dbName)
+		if err := addDatabaseDump(w, db, dbName); err != nil {
+			fmt.Println(cli.Error("failed"))
+			return err
+		}
+		fmt.Println(cli.Success("done"))
+	}
+
+	fmt.Print("Adding project files... ")
+	for _, name := range backupProjectFiles {
+		src := filepath.Join(cwd, filepath.FromSlash(name))
+		if info, err := os.Stat(src); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if err := w.AddFile(backup.ProjectPrefix+name, src); err != nil {
+			fmt.Println(cli.Error("failed"))
+			return err
+		}
+	}
+	fmt.Println(cli.Success("done"))
+
+	if len(manifest.Certs) > 0 {
+		fmt.Print("Adding SSL certificates... ")
+		for _, base := range manifest.Certs {
+			paths := sslMgr.GetCertPaths(base)
+			for _, file := range []string{paths.CertFile, paths.KeyFile} {
+				if err := w.AddFile(backup.CertsPrefix+base+"/"+filepath.Base(file), file); err != nil {
+					fmt.Println(cli.Error("failed"))
+					return err
+				}
+			}
+		}
+		fmt.Println(cli.Success("done"))
+	}
+
+	if manifest.Media {
+		fmt.Print("Adding pub/media... ")
+		before := w.Count()
+		skip := func(rel string) bool { return backupMediaSkip[rel] }
+		if err := w.AddDir(backup.ProjectPrefix+"pub/media", filepath.Join(cwd, "pub", "media"), skip); err != nil {
+			fmt.Println(cli.Error("failed"))
+			return err
+		}
+		fmt.Println(cli.Success(fmt.Sprintf("done (%d files)", w.Count()-before)))
+	}
+
+	return nil
+}
+
+// addDatabaseDump dumps a database to a temporary file, tar needs the size
+// up front, and adds it to the archive
+func addDatabaseDump(w *backup.Writer, db *dbInfo, dbName string) error {
+	tmp, err := os.CreateTemp("", "magebox-backup-*.sql")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	dumpCmd := exec.Command("docker", "exec", db.ContainerName,
+		"mysqldump", "-u"+db.User, "-p"+db.Password, "--no-tablespaces", "--single-transaction", dbName)
+	dumpCmd.Stdout = tmp
+	dumpCmd.Stderr = os.Stderr
+	if err := dumpCmd.Run(); err != nil {
+		return fmt.Errorf("dump of '%s' failed: %w", dbName, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return w.AddFile(backup.DatabaseEntry(dbName), tmp.Name())
+}
@@ -605,7 +605,7 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 		Name:       "mailpit",
 		Domain:     mailpitDomain,
 		ProxyHost:  "127.0.0.1",
-		ProxyPort:  8025,
+		ProxyPort:  docker.LoadPorts(p).MailpitWebPort(),
 		SSLEnabled: true,
 	}
 	if err := vhostGen.GenerateProxyVhost(mailpitCfg); err != nil {
@@ -973,7 +973,7 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("Services available:")
 	fmt.Printf("  MySQL 8.0:    %s (root password: magebox)\n", cli.URL("localhost:33080"))
-	fmt.Printf("  Redis:        %s\n", cli.URL("localhost:6379"))
+	fmt.Printf("  Redis:        %s\n", cli.URL(fmt.Sprintf("localhost:%d", docker.LoadPorts(p).CachePort())))
 	fmt.Printf("  Mailpit:      %s\n", cli.URL(fmt.Sprintf("https://mailpit.magebox.%s", tld)))
 	if globalCfg.Portainer {
 		fmt.Printf("  Portainer:    %s\n", cli.URL("http://localhost:9000"))
@@ -15,7 +15,6 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -50,7 +49,7 @@ var certRenewCmd = &cobra.Command{
 	Short: "Renew SSH certificate",
 	Long: `Renew your SSH certificate from the team server.
 
-The certificate is automatically saved to ~/.magebox/keys/<server>-cert.pub
+The certificate is saved next to your SSH key in ~/.magebox/team/keys/
 
 Examples:
   magebox cert renew
@@ -83,35 +82,12 @@ func init() {
 
 func runCertRenew(cmd *cobra.Command, args []string) error {
 	// Load team server config
-	homeDir, err := os.UserHomeDir()
+	config, err := loadClientSession()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
 
-	configPath := filepath.Join(homeDir, ".magebox", "teamserver", "client.json")
-	configData, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("not connected to a team server. Run 'magebox server join' first")
-		}
-		return fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var config struct {
-		ServerURL    string `json:"server_url"`
-		SessionToken string `json:"session_token"`
-		KeyFile      string `json:"key_file"`
-		KeyPath      string `json:"key_path"` // Fallback for older configs
-	}
-	if err := json.Unmarshal(configData, &config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
-	}
-
-	// Use KeyPath as fallback for KeyFile
-	keyFile := config.KeyFile
-	if keyFile == "" {
-		keyFile = config.KeyPath
-	}
+	keyFile := config.privateKeyPath()
 	if keyFile == "" {
 		return fmt.Errorf("no key file found in config. Re-join the team server")
 	}
@@ -164,32 +140,12 @@ func runCertRenew(cmd *cobra.Command, args []string) error {
 
 func runCertShow(cmd *cobra.Command, args []string) error {
 	// Load team server config
-	homeDir, err := os.UserHomeDir()
+	config, err := loadClientConfig()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	configPath := filepath.Join(homeDir, ".magebox", "teamserver", "client.json")
-	configData, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("not connected to a team server. Run 'magebox server join' first")
-		}
-		return fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var config struct {
-		KeyFile string `json:"key_file"`
-		KeyPath string `json:"key_path"`
-	}
-	if err := json.Unmarshal(configData, &config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return err
 	}
 
-	keyFile := config.KeyFile
-	if keyFile == "" {
-		keyFile = config.KeyPath
-	}
+	keyFile := config.privateKeyPath()
 	if keyFile == "" {
 		return fmt.Errorf("no key file found in config")
 	}
@@ -238,26 +194,9 @@ func runCertShow(cmd *cobra.Command, args []string) error {
 
 func runCertExpiry(cmd *cobra.Command, args []string) error {
 	// Load team server config
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	configPath := filepath.Join(homeDir, ".magebox", "teamserver", "client.json")
-	configData, err := os.ReadFile(configPath)
+	config, err := loadClientSession()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("not connected to a team server. Run 'magebox server join' first")
-		}
-		return fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var config struct {
-		ServerURL    string `json:"server_url"`
-		SessionToken string `json:"session_token"`
-	}
-	if err := json.Unmarshal(configData, &config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return err
 	}
 
 	// Request certificate info
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -23,6 +24,7 @@ import (
 	"qoliber/magebox/internal/php"
 	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/portforward"
+	"qoliber/magebox/internal/ssl"
 )
 
 var checkCmd = &cobra.Command{
@@ -150,6 +152,7 @@ func runCheck(cmd *cobra.Command, args []string) error {
 
 		// Check required extensions
 		results = append(results, checkPHPExtensions(p, cfg.PHP)...)
+		results = append(results, checkProjectExtensions(p, cfg)...)
 	} else {
 		// Check installed versions
 		installed := detector.DetectInstalled()
@@ -226,6 +229,14 @@ func runCheck(cmd *cobra.Command, args []string) error {
 			})
 		}
 		printCheckResult(results[len(results)-1])
+
+		// Ask each vhost whether PHP-FPM answers behind it
+		if nginxCtrl.IsRunning() {
+			for _, d := range cfg.Domains {
+				results = append(results, checkVhostHealth(d))
+				printCheckResult(results[len(results)-1])
+			}
+		}
 	}
 	fmt.Println()
 
@@ -252,16 +263,17 @@ func runCheck(cmd *cobra.Command, args []string) error {
 		// Check individual services
 		composeGen := docker.NewComposeGenerator(p)
 		dockerCtrl := docker.NewDockerController(composeGen.ComposeFilePath())
+		ports := docker.LoadPorts(p)
 
 		services := []struct {
 			name        string
 			serviceName string
 			port        int
 		}{
-			{"MySQL 8.0", "mysql80", 33080},
-			{"Redis", "redis", 6379},
-			{"Valkey", "valkey", 6379},
-			{"Mailpit", "mailpit", 8025},
+			{"MySQL 8.0", "mysql80", getDbPort("mysql", "8.0")},
+			{"Redis", "redis", ports.CachePort()},
+			{"Valkey", "valkey", ports.CachePort()},
+			{"Mailpit", "mailpit", ports.MailpitWebPort()},
 		}
 
 		for _, svc := range services {
@@ -284,7 +296,7 @@ func runCheck(cmd *cobra.Command, args []string) error {
 		// Check project-specific services
 		if cfg != nil {
 			if cfg.Services.HasOpenSearch() {
-				osPort := docker.GetOpenSearchPort(cfg.Services.OpenSearch.Version)
+				osPort := docker.LoadPorts(p).OpenSearchPort(cfg.Services.OpenSearch.Version)
 				if dockerCtrl.IsServiceRunning("opensearch") {
 					results = append(results, checkResult{
 						name:    "OpenSearch",
@@ -301,12 +313,39 @@ func runCheck(cmd *cobra.Command, args []string) error {
 				printCheckResult(results[len(results)-1])
 			}
 
+			for _, engine := range []struct {
+				enabled       bool
+				service, name string
+				port          int
+			}{
+				{cfg.Services.HasMeilisearch(), "meilisearch", "Meilisearch", config.MeilisearchPort},
+				{cfg.Services.HasTypesense(), "typesense", "Typesense", config.TypesensePort},
+			} {
+				if !engine.enabled {
+					continue
+				}
+				if dockerCtrl.IsServiceRunning(engine.service) {
+					results = append(results, checkResult{
+						name:    engine.name,
+						status:  "ok",
+						message: fmt.Sprintf("Running (port %d)", engine.port),
+					})
+				} else {
+					results = append(results, checkResult{
+						name:    engine.name,
+						status:  "warning",
+						message: "Not running",
+					})
+				}
+				printCheckResult(results[len(results)-1])
+			}
+
 			if cfg.Services.HasRabbitMQ() {
 				if dockerCtrl.IsServiceRunning("rabbitmq") {
 					results = append(results, checkResult{
 						name:    "RabbitMQ",
 						status:  "ok",
-						message: "Running (port 5672)",
+						message: fmt.Sprintf("Running (port %d)", ports.RabbitMQPort()),
 					})
 				} else {
 					results = append(results, checkResult{
@@ -374,21 +413,36 @@ func runCheck(cmd *cobra.Command, args []string) error {
 
 	// Check domain certificates
 	if cfg != nil && len(cfg.Domains) > 0 {
+		sslMgr := ssl.NewManager(p)
+		now := time.Now()
 		for _, domain := range cfg.Domains {
 			if domain.IsSSLEnabled() {
-				// Certificates are stored in ~/.magebox/certs/{domain}/cert.pem
-				certPath := filepath.Join(p.MageBoxDir(), "certs", domain.Host, "cert.pem")
-				if _, err := os.Stat(certPath); err == nil {
+				// Certificates are stored in ~/.magebox/certs/{base domain}/cert.pem
+				info, err := sslMgr.CertInfo(ssl.ExtractBaseDomainForTLD(domain.Host, cfg.TLD))
+				switch {
+				case err != nil:
 					results = append(results, checkResult{
 						name:    domain.Host,
-						status:  "ok",
-						message: "Certificate exists",
+						status:  "warning",
+						message: "Certificate missing - will be created on 'magebox start'",
 					})
-				} else {
+				case info.Expired(now):
+					results = append(results, checkResult{
+						name:    domain.Host,
+						status:  "error",
+						message: "Certificate expired - run 'magebox ssl renew'",
+					})
+				case info.Expiring(now):
 					results = append(results, checkResult{
 						name:    domain.Host,
 						status:  "warning",
-						message: "Certificate missing - will be created on 'magebox start'",
+						message: fmt.Sprintf("Certificate expires in %d days - run 'magebox ssl renew'", info.DaysLeft(now)),
+					})
+				default:
+					results = append(results, checkResult{
+						name:    domain.Host,
+						status:  "ok",
+						message: "Certificate valid until " + info.NotAfter.Format("2006-01-02"),
 					})
 				}
 				printCheckResult(results[len(results)-1])
@@ -599,6 +653,55 @@ func checkPHPExtensions(p *platform.Platform, version string) []checkResult {
 	return results
 }
 
+// checkProjectExtensions checks the php_extensions of the project. Installed
+// extensions PHP doesn't enable are fine, the project's pool loads them.
+func checkProjectExtensions(p *platform.Platform, cfg *config.Config) []checkResult {
+	if len(cfg.PHPExtensions) == 0 || !platform.BinaryExists(p.PHPBinary(cfg.PHP)) {
+		return nil
+	}
+
+	extMgr := php.NewExtensionManager(p)
+	check, err := extMgr.CheckExtensions(cfg.PHPExtensions, cfg.PHP)
+
+	var result checkResult
+	switch {
+	case err != nil:
+		result = checkResult{name: "Project Extensions", status: "warning", message: err.Error()}
+	case len(check.Missing) > 0:
+		result = checkResult{name: "Project Extensions", status: "error", message: fmt.Sprintf("Missing: %s", strings.Join(check.Missing, ", "))}
+	case len(check.Pool) > 0:
+		result = checkResult{name: "Project Extensions", status: "ok", message: fmt.Sprintf("All loaded (%s by the project's pool)", strings.Join(check.Pool, ", "))}
+	default:
+		result = checkResult{name: "Project Extensions", status: "ok", message: "All loaded"}
+	}
+	printCheckResult(result)
+
+	if err == nil {
+		for _, ext := range check.Missing {
+			fmt.Printf("      %s\n", cli.Command(extMgr.InstallCommand(ext, cfg.PHP)))
+		}
+	}
+	return []checkResult{result}
+}
+
+// checkVhostHealth requests the /magebox-health endpoint of a domain
+func checkVhostHealth(d config.Domain) checkResult {
+	name := "Health " + d.Host
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	health, err := nginx.CheckHealth(ctx, nginx.HealthURL(d.Host, d.IsSSLEnabled()))
+	switch {
+	case err != nil:
+		return checkResult{name: name, status: "warning", message: err.Error()}
+	case !health.FPM:
+		return checkResult{name: name, status: "error", message: fmt.Sprintf("PHP-FPM %s not reachable - run 'magebox restart'", health.PHP)}
+	case !health.OK():
+		return checkResult{name: name, status: "error", message: fmt.Sprintf("Served by PHP %s, project needs %s - run 'magebox restart'", health.PHPVersion, health.PHP)}
+	}
+	return checkResult{name: name, status: "ok", message: "PHP-FPM " + health.PHPVersion}
+}
+
 // getMkcertCARoot returns the mkcert CA root directory
 func getMkcertCARoot() string {
 	cmd := exec.Command("mkcert", "-CAROOT")
@@ -618,15 +721,13 @@ func checkDatabaseConnection(p *platform.Platform, cfg *config.Config) checkResu
 
 	if cfg.Services.HasMySQL() {
 		serviceName = fmt.Sprintf("mysql%s", strings.ReplaceAll(cfg.Services.MySQL.Version, ".", ""))
-		switch cfg.Services.MySQL.Version {
-		case "8.4":
-			port = "33084"
-		default:
-			port = "33080"
-		}
+		port = fmt.Sprint(getDbPort("mysql", cfg.Services.MySQL.Version))
 	} else if cfg.Services.HasMariaDB() {
 		serviceName = fmt.Sprintf("mariadb%s", strings.ReplaceAll(cfg.Services.MariaDB.Version, ".", ""))
-		port = "33106"
+		port = fmt.Sprint(getDbPort("mariadb", cfg.Services.MariaDB.Version))
+	} else if cfg.Services.HasPercona() {
+		serviceName = fmt.Sprintf("percona%s", strings.ReplaceAll(cfg.Services.Percona.Version, ".", ""))
+		port = fmt.Sprint(getDbPort("percona", cfg.Services.Percona.Version))
 	}
 
 	// Try to connect via TCP
@@ -3,19 +3,25 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/dns"
+	"qoliber/magebox/internal/nginx"
 	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/ssl"
 )
 
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "MageBox configuration",
-	Long:  "View and modify MageBox global configuration",
+	Long:  "View and modify MageBox global configuration, and show the effective project configuration",
 }
 
 var configShowCmd = &cobra.Command{
@@ -34,13 +40,26 @@ Available keys:
   dns_mode     - DNS resolution mode: "hosts" or "dnsmasq"
   default_php  - Default PHP version for new projects (e.g., "8.2")
   tld          - Top-level domain for local dev (default: "test")
+  tlds         - Additional TLDs routed to this machine, comma-separated
+                 (e.g. "localhost,acme"), "" to clear
   portainer    - Enable Portainer Docker UI: "true" or "false"
   elasticvue   - Enable Elasticvue search UI: "true" or "false"
-  phpmyadmin   - Enable phpMyAdmin database UI: "true" or "false"`,
+  phpmyadmin   - Enable phpMyAdmin database UI: "true" or "false"
+
+Changing the tld offers to migrate every registered project using the old
+TLD: domains in .magebox.yaml, SSL certificates, nginx vhosts, /etc/hosts
+entries and the Magento base URLs in core_config_data. Projects that set
+their own tld in .magebox.yaml keep it, and the old TLD stays routed for
+projects that aren't migrated.`,
 	Args: cobra.ExactArgs(2),
 	RunE: runConfigSet,
 }
 
+var (
+	configSetYes       bool
+	configSetNoMigrate bool
+)
+
 var configInitCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize global configuration",
@@ -50,11 +69,30 @@ var configInitCmd = &cobra.Command{
 
 func init() {
 	configCmd.AddCommand(configShowCmd)
+	configSetCmd.Flags().BoolVarP(&configSetYes, "yes", "y", false, "Migrate projects to a new tld without asking")
+	configSetCmd.Flags().BoolVar(&configSetNoMigrate, "no-migrate", false, "Change the tld without migrating projects")
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configInitCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
+// configShowOutput is the global config printed by config show --output json|yaml.
+// Credentials such as profiling keys are left out.
+type configShowOutput struct {
+	ConfigFile      string                 `json:"config_file"`
+	Exists          bool                   `json:"exists"`
+	DNSMode         string                 `json:"dns_mode"`
+	DefaultPHP      string                 `json:"default_php"`
+	TLD             string                 `json:"tld"`
+	TLDs            []string               `json:"tlds,omitempty"`
+	Portainer       bool                   `json:"portainer"`
+	Elasticvue      bool                   `json:"elasticvue"`
+	PhpMyAdmin      bool                   `json:"phpmyadmin"`
+	AutoStart       bool                   `json:"auto_start"`
+	LowMemory       bool                   `json:"low_memory"`
+	DefaultServices config.DefaultServices `json:"default_services"`
+}
+
 func runConfigShow(cmd *cobra.Command, args []string) error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -63,14 +101,34 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 
 	cfg, err := config.LoadGlobalConfig(homeDir)
 	if err != nil {
+		if structuredOutput() {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
 		cli.PrintError("Failed to load config: %v", err)
 		return nil
 	}
 
+	configPath := config.GlobalConfigPath(homeDir)
+	if structuredOutput() {
+		return printStructured(configShowOutput{
+			ConfigFile:      configPath,
+			Exists:          config.GlobalConfigExists(homeDir),
+			DNSMode:         cfg.DNSMode,
+			DefaultPHP:      cfg.DefaultPHP,
+			TLD:             cfg.TLD,
+			TLDs:            cfg.TLDs,
+			Portainer:       cfg.Portainer,
+			Elasticvue:      cfg.Elasticvue,
+			PhpMyAdmin:      cfg.PhpMyAdmin,
+			AutoStart:       cfg.AutoStart,
+			LowMemory:       cfg.LowMemory,
+			DefaultServices: cfg.DefaultServices,
+		})
+	}
+
 	cli.PrintTitle("MageBox Global Configuration")
 	fmt.Println()
 
-	configPath := config.GlobalConfigPath(homeDir)
 	if config.GlobalConfigExists(homeDir) {
 		fmt.Printf("Config file: %s\n", cli.Path(configPath))
 	} else {
@@ -81,10 +139,14 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  %-14s %s\n", "dns_mode:", cli.Highlight(cfg.DNSMode))
 	fmt.Printf("  %-14s %s\n", "default_php:", cli.Highlight(cfg.DefaultPHP))
 	fmt.Printf("  %-14s %s\n", "tld:", cli.Highlight(cfg.TLD))
+	if len(cfg.TLDs) > 0 {
+		fmt.Printf("  %-14s %s\n", "tlds:", cli.Highlight(strings.Join(cfg.TLDs, ", ")))
+	}
 	fmt.Printf("  %-14s %s\n", "portainer:", cli.Highlight(fmt.Sprintf("%v", cfg.Portainer)))
 	fmt.Printf("  %-14s %s\n", "elasticvue:", cli.Highlight(fmt.Sprintf("%v", cfg.Elasticvue)))
 	fmt.Printf("  %-14s %s\n", "phpmyadmin:", cli.Highlight(fmt.Sprintf("%v", cfg.PhpMyAdmin)))
 	fmt.Printf("  %-14s %s\n", "auto_start:", cli.Highlight(fmt.Sprintf("%v", cfg.AutoStart)))
+	fmt.Printf("  %-14s %s\n", "low_memory:", cli.Highlight(fmt.Sprintf("%v", cfg.LowMemory)))
 
 	fmt.Println(cli.Header("Default Services"))
 	if cfg.DefaultServices.MySQL != "" {
@@ -130,11 +192,32 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 	case "default_php":
 		cfg.DefaultPHP = value
 	case "tld":
+		value = strings.TrimPrefix(value, ".")
+		if value == "" || strings.ContainsAny(value, " /:") {
+			cli.PrintError("Invalid value for tld: %q", args[1])
+			return nil
+		}
 		cfg.TLD = value
+	case "tlds":
+		var tlds []string
+		for _, tld := range strings.Split(value, ",") {
+			if tld = config.NormalizeTLD(tld); tld == "" {
+				continue
+			}
+			if !config.ValidTLD(tld) {
+				cli.PrintError("Invalid TLD in tlds: %q", tld)
+				return nil
+			}
+			tlds = append(tlds, tld)
+		}
+		cfg.TLDs = tlds
+		value = strings.Join(tlds, ",")
 	case "portainer":
 		cfg.Portainer = (value == "true" || value == "1" || value == "yes")
 	case "auto_start":
 		cfg.AutoStart = (value == "true" || value == "1" || value == "yes")
+	case "low_memory":
+		cfg.LowMemory = (value == "true" || value == "1" || value == "yes")
 	case "elasticvue":
 		cfg.Elasticvue = (value == "true" || value == "1" || value == "yes")
 	case "phpmyadmin":
@@ -142,10 +225,51 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 	default:
 		cli.PrintError("Unknown configuration key: %s", key)
 		fmt.Println()
-		cli.PrintInfo("Available keys: dns_mode, default_php, tld, portainer, elasticvue, phpmyadmin, auto_start")
+		cli.PrintInfo("Available keys: dns_mode, default_php, tld, tlds, portainer, elasticvue, phpmyadmin, auto_start, low_memory")
 		return nil
 	}
 
+	tldChanged := key == "tld" && value != oldTLD
+	var p *platform.Platform
+	var plan []project.TLDMigration
+	if tldChanged {
+		if p, err = platform.Detect(); err != nil {
+			cli.PrintWarning("Could not detect platform, projects and DNS are not updated: %v", err)
+			tldChanged = false
+		} else {
+			projects, _ := project.NewProjectDiscovery(p).DiscoverProjects()
+			// Projects on their own TLD keep resolving
+			for _, tld := range project.ProjectTLDs(projects) {
+				cfg.AddTLD(tld)
+			}
+			if !configSetNoMigrate {
+				plan = project.PlanTLDMigration(projects, oldTLD, value)
+			}
+		}
+	}
+
+	migrate := len(plan) > 0
+	if migrate {
+		cli.PrintWarning("%d project(s) use the current tld .%s:", len(plan), oldTLD)
+		for _, m := range plan {
+			fmt.Printf("  %s\n", cli.Highlight(m.Config.Name))
+			for _, c := range m.Changes {
+				fmt.Printf("    %s -> %s\n", c.Old, c.New)
+			}
+		}
+		fmt.Println()
+		if !configSetYes {
+			fmt.Printf("Migrate them to .%s (domains, certificates, vhosts, hosts and base URLs)? [Y/n]: ", value)
+			var confirm string
+			_, _ = fmt.Scanln(&confirm)
+			migrate = confirm == "" || confirm == "y" || confirm == "Y"
+		}
+	}
+	if len(plan) > 0 && !migrate {
+		cfg.AddTLD(oldTLD)
+	}
+
+	recorder.Track(config.GlobalConfigPath(homeDir))
 	if err := config.SaveGlobalConfig(homeDir, cfg); err != nil {
 		cli.PrintError("Failed to save config: %v", err)
 		return nil
@@ -153,41 +277,171 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 
 	cli.PrintSuccess("Configuration updated: %s = %s", key, value)
 
-	// If TLD changed and dnsmasq is configured, reconfigure DNS
-	if key == "tld" && value != oldTLD {
-		p, err := platform.Detect()
-		if err != nil {
-			cli.PrintWarning("Could not detect platform for DNS reconfiguration: %v", err)
+	if key == "tlds" {
+		if p, err = platform.Detect(); err != nil {
+			cli.PrintWarning("Could not detect platform, DNS is not updated: %v", err)
 			return nil
 		}
+		reconfigureDNS(p, cfg.GetTLDs())
+		return nil
+	}
+	if !tldChanged {
+		return nil
+	}
+	reconfigureDNS(p, cfg.GetTLDs())
 
-		dnsMgr := dns.NewDnsmasqManager(p)
-		if dnsMgr.IsConfigured() {
-			cli.PrintInfo("Reconfiguring DNS for new TLD: %s", value)
+	if migrate {
+		migrateProjectsTLD(p, cfg, plan)
+	} else if len(plan) > 0 {
+		cli.PrintInfo("Projects still using .%s keep resolving, .%s stays routed next to .%s", oldTLD, oldTLD, value)
+		cli.PrintInfo("Move their domains with %s and %s", cli.Command("magebox domain add"), cli.Command("magebox domain remove"))
+	}
 
-			// Remove old macOS resolver if exists
-			if p.Type == platform.Darwin {
-				_ = dnsMgr.Remove() // This removes the old resolver file
-			}
+	return nil
+}
 
-			// Reconfigure dnsmasq
-			if err := dnsMgr.Configure(); err != nil {
-				cli.PrintWarning("Failed to reconfigure DNS: %v", err)
-				cli.PrintInfo("Run %s to reconfigure manually", cli.Command("magebox dns setup"))
-				return nil
-			}
+// reconfigureDNS points dnsmasq at the TLDs if it is configured
+func reconfigureDNS(p *platform.Platform, tlds []string) {
+	dnsMgr := dns.NewDnsmasqManager(p)
+	if !dnsMgr.IsConfigured() {
+		return
+	}
 
-			// Restart dnsmasq
-			if err := dnsMgr.Restart(); err != nil {
-				cli.PrintWarning("Failed to restart dnsmasq: %v", err)
-				return nil
+	cli.PrintInfo("Reconfiguring DNS for TLDs: %s", strings.Join(tlds, ", "))
+
+	// Remove old macOS resolver if exists
+	if p.Type == platform.Darwin {
+		_ = dnsMgr.Remove() // This removes the old resolver file
+	}
+
+	// Reconfigure dnsmasq
+	if err := dnsMgr.Configure(); err != nil {
+		cli.PrintWarning("Failed to reconfigure DNS: %v", err)
+		cli.PrintInfo("Run %s to reconfigure manually", cli.Command("magebox dns setup"))
+		return
+	}
+
+	// Restart dnsmasq
+	if err := dnsMgr.Restart(); err != nil {
+		cli.PrintWarning("Failed to restart dnsmasq: %v", err)
+		return
+	}
+
+	cli.PrintSuccess("DNS reconfigured for *.%s domains", strings.Join(tlds, ", *."))
+}
+
+// migrateProjectsTLD moves projects to their new domains and reloads nginx
+// once at the end
+func migrateProjectsTLD(p *platform.Platform, globalCfg *config.GlobalConfig, plan []project.TLDMigration) {
+	sslManager := ssl.NewManager(p)
+	vhostGen := nginx.NewVhostGenerator(p, sslManager)
+	hostsManager := dns.NewHostsManager(p)
+
+	for i := range plan {
+		m := &plan[i]
+		fmt.Println()
+		cli.PrintInfo("Migrating %s", cli.Highlight(m.Config.Name))
+		migrateProjectTLD(m, globalCfg, sslManager, vhostGen, hostsManager)
+	}
+
+	fmt.Println()
+	fmt.Println("Reloading nginx...")
+	ngxController := nginx.NewController(p)
+	if err := ngxController.Test(); err != nil {
+		cli.PrintError("Nginx config test failed: %v", err)
+		return
+	}
+	if err := ngxController.Reload(); err != nil {
+		cli.PrintWarning("Failed to reload nginx: %v", err)
+		return
+	}
+	cli.PrintSuccess("Migrated %d project(s)", len(plan))
+}
+
+// migrateProjectTLD renames the domains of one project and regenerates
+// everything derived from them. Failures are reported and the remaining
+// steps still run.
+func migrateProjectTLD(m *project.TLDMigration, globalCfg *config.GlobalConfig, sslManager *ssl.Manager, vhostGen *nginx.VhostGenerator, hostsManager *dns.HostsManager) {
+	cfg, path := m.Config, m.Project.Path
+
+	recorder.Track(filepath.Join(path, config.ConfigFileName))
+	for _, c := range m.Changes {
+		recorder.Track(filepath.Join(vhostGen.VhostsDir(), fmt.Sprintf("%s-%s.conf", cfg.Name, c.Old)))
+		recorder.Track(filepath.Join(vhostGen.VhostsDir(), fmt.Sprintf("%s-%s.conf", cfg.Name, c.New)))
+	}
+
+	m.Apply()
+	if err := config.SaveToPath(cfg, path); err != nil {
+		cli.PrintError("  Failed to save config: %v", err)
+		return
+	}
+	fmt.Printf("  %-14s %s\n", "Config:", cli.Success("updated"))
+
+	for _, d := range cfg.Domains {
+		if !d.IsSSLEnabled() {
+			continue
+		}
+		for _, c := range m.Changes {
+			if c.New != d.Host {
+				continue
 			}
+			if _, err := sslManager.GenerateCert(d.Host); err != nil {
+				cli.PrintWarning("  SSL certificate for %s failed: %v", d.Host, err)
+			}
+		}
+	}
 
-			cli.PrintSuccess("DNS reconfigured for *.%s domains", value)
+	if err := vhostGen.Remove(cfg.Name); err != nil {
+		cli.PrintWarning("  Failed to remove old vhosts: %v", err)
+	}
+	if err := vhostGen.Generate(cfg, path); err != nil {
+		cli.PrintWarning("  Failed to regenerate vhosts: %v", err)
+	} else {
+		fmt.Printf("  %-14s %s\n", "Vhosts:", cli.Success("regenerated"))
+	}
+
+	if globalCfg.UseHosts() {
+		if err := hostsManager.RemoveDomains(m.OldHosts()); err != nil {
+			cli.PrintWarning("  Failed to remove old hosts entries: %v", err)
+		}
+		domains := make([]string, len(cfg.Domains))
+		for i, d := range cfg.Domains {
+			domains[i] = d.Host
+		}
+		if err := hostsManager.AddDomains(domains); err != nil {
+			cli.PrintWarning("  Failed to update hosts: %v", err)
 		}
 	}
 
-	return nil
+	if !cfg.IsMagento() {
+		return
+	}
+	db, err := getDbInfo(cfg)
+	if err != nil {
+		return
+	}
+	updateCmd := exec.Command("docker", "exec", db.ContainerName,
+		"mysql", "-u"+db.User, "-p"+db.Password, cfg.DatabaseName(), "-e", m.BaseURLUpdateSQL())
+	if err := updateCmd.Run(); err != nil {
+		cli.PrintWarning("  Base URLs not updated (is the database running?), update core_config_data manually")
+	} else {
+		fmt.Printf("  %-14s %s\n", "Base URLs:", cli.Success("updated"))
+	}
+
+	// URLs locked in env.php/config.php override the database
+	for _, file := range []string{"env.php", "config.php"} {
+		data, err := os.ReadFile(filepath.Join(path, "app", "etc", file))
+		if err != nil {
+			continue
+		}
+		for _, c := range m.Changes {
+			if strings.Contains(string(data), "://"+c.Old) {
+				cli.PrintWarning("  app/etc/%s still contains %s, update it by hand", file, c.Old)
+				break
+			}
+		}
+	}
+	cli.PrintInfo("  Run %s to apply the new base URLs", cli.Command("bin/magento cache:flush"))
 }
 
 func runConfigInit(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+)
+
+var configEffectiveCmd = &cobra.Command{
+	Use:   "effective",
+	Short: "Show the merged project configuration",
+	Long: `Prints the project configuration MageBox uses: ~/.magebox/defaults.yaml,
+then .magebox.yaml and its include_config files, then .magebox.local.yaml,
+each merged over the ones before.
+
+The files are listed in the order they were merged, later ones taking
+precedence.
+
+Examples:
+  magebox config effective
+  magebox config effective > /tmp/effective.yaml`,
+	RunE: runConfigEffective,
+}
+
+func init() {
+	configCmd.AddCommand(configEffectiveCmd)
+}
+
+func runConfigEffective(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	loader := config.NewLoader(cwd)
+	cfg, err := loader.Load()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	data, err := config.Marshal(cfg)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	fmt.Println("# Effective configuration, merged from:")
+	for _, path := range loader.LoadedFiles() {
+		fmt.Printf("#   %s\n", configFileDisplayPath(cwd, path))
+	}
+	if cfg.Profile != "" {
+		fmt.Printf("# with profile %s applied before the local overrides\n", cfg.Profile)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// configFileDisplayPath returns the path of a loaded config file relative to
+// the project, or as is when it's outside, like ~/.magebox/defaults.yaml
+func configFileDisplayPath(projectPath, path string) string {
+	rel, err := filepath.Rel(projectPath, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,170 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the project configuration against the schema",
+	Long: `Checks ~/.magebox/defaults.yaml, .magebox.yaml, the files it includes and
+.magebox.local.yaml against the configuration schema, then the merged
+configuration:
+
+  - unknown keys, e.g. a misspelled service
+  - PHP versions MageBox doesn't support
+  - database versions without an image, search versions that aren't numbers
+  - domain host names and memory sizes such as 2g
+  - unquoted service versions, which are not read as versions
+
+Exits with status 1 when a problem is found. 'magebox start --strict' runs the
+same checks before starting.
+
+Examples:
+  magebox config validate
+  magebox config validate --output json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the project configuration",
+	Long: `Prints the JSON Schema of .magebox.yaml, for editors that validate and
+complete YAML files against a schema.
+
+Example:
+  magebox config schema > ~/.magebox/schema.json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchema,
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+// configFileIssues are the schema issues of one configuration file
+type configFileIssues struct {
+	Path   string         `json:"path"`
+	Issues []config.Issue `json:"issues"`
+}
+
+// configValidateOutput is the result printed by config validate --output json|yaml
+type configValidateOutput struct {
+	Valid bool               `json:"valid"`
+	Files []configFileIssues `json:"files"`
+	Error string             `json:"error,omitempty"`
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	files, loadErr := validateProjectConfig(cwd)
+	if _, ok := loadErr.(*config.ConfigNotFoundError); ok {
+		cli.PrintError("Configuration file not found: %s/.magebox.yaml", cwd)
+		return nil
+	}
+	problems := countConfigIssues(files, loadErr)
+
+	if structuredOutput() {
+		out := configValidateOutput{Valid: problems == 0, Files: files}
+		if loadErr != nil {
+			out.Error = loadErr.Error()
+		}
+		if err := printStructured(out); err != nil {
+			return err
+		}
+	} else {
+		printConfigIssues(files, loadErr)
+		if problems == 0 {
+			cli.PrintSuccess("Configuration is valid")
+		} else {
+			fmt.Printf("%d problem(s) found\n", problems)
+		}
+	}
+
+	if problems > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(config.ConfigSchema())
+}
+
+// validateProjectConfig checks every configuration file of a project against
+// the schema. The error is the one loading the merged configuration, which
+// covers what the schema can't, such as a missing name or an include that
+// doesn't exist.
+func validateProjectConfig(projectPath string) ([]configFileIssues, error) {
+	loader := config.NewLoader(projectPath)
+	_, loadErr := loader.Load()
+
+	var files []configFileIssues
+	for _, path := range loader.LoadedFiles() {
+		issues, err := config.ValidateFile(path)
+		if err != nil {
+			// Unreadable or not YAML, which the load error reports
+			continue
+		}
+		files = append(files, configFileIssues{Path: configFileDisplayPath(projectPath, path), Issues: issues})
+	}
+	return files, loadErr
+}
+
+// countConfigIssues returns the number of problems validateProjectConfig found
+func countConfigIssues(files []configFileIssues, loadErr error) int {
+	problems := 0
+	for _, f := range files {
+		problems += len(f.Issues)
+	}
+	if loadErr != nil {
+		problems++
+	}
+	return problems
+}
+
+// printConfigIssues prints the issues of every file, then the load error
+func printConfigIssues(files []configFileIssues, loadErr error) {
+	for _, f := range files {
+		fmt.Println(cli.Header(f.Path))
+		if len(f.Issues) == 0 {
+			fmt.Printf("  %s no problems\n", cli.Success(""))
+		}
+		for _, issue := range f.Issues {
+			fmt.Printf("  %s %s\n", cli.Error(""), issue)
+		}
+		fmt.Println()
+	}
+	if loadErr != nil {
+		cli.PrintError("%v", loadErr)
+	}
+}
+
+// checkConfigStrict runs the config validate checks for start --strict,
+// printing the problems it finds
+func checkConfigStrict(projectPath string) error {
+	files, loadErr := validateProjectConfig(projectPath)
+	if problems := countConfigIssues(files, loadErr); problems > 0 {
+		printConfigIssues(files, loadErr)
+		return fmt.Errorf("%d configuration problem(s) found, not starting (run without --strict to start anyway)", problems)
+	}
+	return nil
+}
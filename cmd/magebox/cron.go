@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/cron"
+	"qoliber/magebox/internal/platform"
+)
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Manage Magento cron for the project",
+	Long: `Installs and removes the crontab entry that runs bin/magento cron:run every
+minute with the project's PHP version.
+
+The entry lives in your user crontab between MageBox markers, one block per
+project, and logs to var/log/magento.cron.log. 'magebox php <version>'
+updates the entry; 'magebox cron status' warns when it uses another PHP
+binary than the project, e.g. after editing .magebox.yaml by hand.`,
+}
+
+var cronEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Run Magento cron every minute",
+	Long:  "Adds (or updates) the project's entry in your crontab",
+	RunE:  runCronEnable,
+}
+
+var cronDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop running Magento cron",
+	Long:  "Removes the project's entry from your crontab",
+	RunE:  runCronDisable,
+}
+
+var cronStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the cron status of the project",
+	Long:  "Shows whether cron is enabled, which PHP binary it uses and when jobs last ran",
+	RunE:  runCronStatus,
+}
+
+var cronRunCmd = &cobra.Command{
+	Use:   "run [group]",
+	Short: "Run Magento cron now",
+	Long: `Runs bin/magento cron:run once in the foreground, for all groups or one.
+
+Examples:
+  magebox cron run
+  magebox cron run index`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCronRun,
+}
+
+func init() {
+	cronCmd.AddCommand(cronEnableCmd)
+	cronCmd.AddCommand(cronDisableCmd)
+	cronCmd.AddCommand(cronStatusCmd)
+	cronCmd.AddCommand(cronRunCmd)
+	rootCmd.AddCommand(cronCmd)
+}
+
+// cronEntry returns the crontab entry of the project
+func cronEntry(p *platform.Platform, cfg *config.Config, cwd string) cron.Entry {
+	return cron.Entry{Project: cfg.Name, Path: cwd, PHPBinary: p.PHPBinary(cfg.PHP)}
+}
+
+// refreshCron points an enabled cron entry at the project's current PHP
+// version and path
+func refreshCron(p *platform.Platform, cfg *config.Config, cwd string) {
+	crontab, err := cron.Read()
+	if err != nil {
+		return
+	}
+	entry := cronEntry(p, cfg, cwd)
+	if line, enabled := cron.Find(crontab, cfg.Name); !enabled || line == entry.Line() {
+		return
+	}
+	updated, err := cron.Install(crontab, entry)
+	if err == nil {
+		err = cron.Write(updated)
+	}
+	if err != nil {
+		cli.PrintWarning("Failed to update the cron entry: %v", err)
+		return
+	}
+	cli.PrintInfo("Cron now runs with PHP %s", cfg.PHP)
+}
+
+func runCronEnable(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+		cli.PrintError("bin/magento not found in %s", cwd)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(cwd, cron.LogFile)), 0755); err != nil {
+		return err
+	}
+
+	crontab, err := cron.Read()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	entry := cronEntry(p, cfg, cwd)
+	updated, err := cron.Install(crontab, entry)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	if err := cron.Write(updated); err != nil {
+		cli.PrintError("Failed to update crontab: %v", err)
+		return nil
+	}
+
+	cli.PrintSuccess("Cron enabled for %s (PHP %s)", cfg.Name, cfg.PHP)
+	fmt.Printf("  %s\n", cli.Dim+entry.Line()+cli.Reset)
+	for _, line := range cron.Unmanaged(crontab, cwd) {
+		cli.PrintWarning("Your crontab also runs cron for this project outside MageBox, remove it with 'crontab -e': %s", line)
+	}
+	return nil
+}
+
+func runCronDisable(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	crontab, err := cron.Read()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	updated, found, err := cron.Remove(crontab, cfg.Name)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	if !found {
+		cli.PrintInfo("Cron is not enabled for %s", cfg.Name)
+		return nil
+	}
+	if err := cron.Write(updated); err != nil {
+		cli.PrintError("Failed to update crontab: %v", err)
+		return nil
+	}
+
+	cli.PrintSuccess("Cron disabled for %s", cfg.Name)
+	return nil
+}
+
+func runCronStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	crontab, err := cron.Read()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	cli.PrintTitle("Cron: %s", cfg.Name)
+	fmt.Println()
+
+	line, enabled := cron.Find(crontab, cfg.Name)
+	fmt.Printf("Enabled:  %s\n", cli.Status(enabled))
+	if enabled {
+		fmt.Printf("Entry:    %s\n", cli.Dim+line+cli.Reset)
+		if want := cronEntry(p, cfg, cwd).Line(); line != want {
+			cli.PrintWarning("The entry doesn't match the project (PHP %s or path changed); run 'magebox cron enable' to update it", cfg.PHP)
+		}
+	}
+	for _, l := range cron.Unmanaged(crontab, cwd) {
+		cli.PrintWarning("Unmanaged crontab entry for this project: %s", l)
+	}
+	fmt.Printf("Log:      %s\n", cli.Path(filepath.Join(cwd, cron.LogFile)))
+
+	// Job history is best-effort, the database may not run or have no cron tables yet
+	if db, err := getDbInfo(cfg); err == nil {
+		table := fmt.Sprintf("`%s`.`cron_schedule`", cfg.DatabaseName())
+		if rows, err := rootQueryLines(db, "SELECT IFNULL(MAX(executed_at), 'never') FROM "+table); err == nil && len(rows) == 1 {
+			fmt.Printf("Last run: %s\n", rows[0])
+		}
+		if rows, err := rootQueryLines(db, "SELECT status, COUNT(*) FROM "+table+
+			" WHERE scheduled_at > NOW() - INTERVAL 1 HOUR GROUP BY status ORDER BY status"); err == nil && len(rows) > 0 {
+			fmt.Println(cli.Header("Jobs in the last hour"))
+			for _, row := range rows {
+				status, count, _ := strings.Cut(row, "\t")
+				fmt.Printf("  %-10s %s\n", status, count)
+			}
+		}
+	}
+	return nil
+}
+
+func runCronRun(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	cronArgs := []string{"cron:run"}
+	if len(args) == 1 {
+		cronArgs = append(cronArgs, "--group="+args[0])
+	}
+	runCmd := magentoCommand(context.Background(), p, cfg, cwd, cronArgs...)
+	runCmd.Stdout = os.Stdout
+	runCmd.Stderr = os.Stderr
+	if err := runCmd.Run(); err != nil {
+		cli.PrintError("bin/magento cron:run failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/dashboard"
+	"qoliber/magebox/internal/dns"
+	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/ssl"
+)
+
+var (
+	dashboardPort     int
+	dashboardNoDomain bool
+	dashboardOpen     bool
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Serve a web dashboard of all projects",
+	Long: `Serves a local web dashboard listing every project MageBox started, running
+or not, with its domains, PHP version and services, links to Mailpit, RabbitMQ
+and OpenSearch Dashboards, and buttons to start and stop it.
+
+The dashboard listens on 127.0.0.1 and is served through nginx on
+https://magebox.<tld> (e.g. https://magebox.test). It runs until Ctrl+C.
+
+Examples:
+  magebox dashboard
+  magebox dashboard --open
+  magebox dashboard --port 7171 --no-domain`,
+	Args: cobra.NoArgs,
+	RunE: runDashboard,
+}
+
+func init() {
+	dashboardCmd.Flags().IntVar(&dashboardPort, "port", 7070, "Port to listen on (127.0.0.1)")
+	dashboardCmd.Flags().BoolVar(&dashboardNoDomain, "no-domain", false, "Don't serve the dashboard through nginx on magebox.<tld>")
+	dashboardCmd.Flags().BoolVar(&dashboardOpen, "open", false, "Open the dashboard in the browser")
+	rootCmd.AddCommand(dashboardCmd)
+}
+
+func runDashboard(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	globalCfg, err := config.LoadGlobalConfig(p.HomeDir)
+	if err != nil {
+		return err
+	}
+	tld := globalCfg.GetTLD()
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(dashboardPort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		cli.PrintError("Cannot listen on %s: %v", addr, err)
+		cli.PrintInfo("Pick another port with %s", cli.Command("--port"))
+		return nil
+	}
+
+	url := "http://" + addr
+	hosts := []string{addr, net.JoinHostPort("localhost", strconv.Itoa(dashboardPort))}
+	if !dashboardNoDomain {
+		domain := "magebox." + tld
+		if err := writeDashboardVhost(p, globalCfg, domain, dashboardPort); err != nil {
+			cli.PrintWarning("Serving on %s failed: %v", domain, err)
+		} else {
+			url = "https://" + domain
+			hosts = append(hosts, domain)
+		}
+	}
+
+	source := &dashboardSource{platform: p, tld: tld}
+	server := &http.Server{
+		Handler:           dashboard.NewServer(source, hosts...).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-cmd.Context().Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	fmt.Println()
+	cli.PrintSuccess("Dashboard on %s", cli.URL(url))
+	cli.PrintInfo("Press Ctrl+C to stop")
+	fmt.Println()
+
+	if dashboardOpen {
+		openDashboard(url)
+	}
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// writeDashboardVhost proxies the dashboard domain to the dashboard through
+// nginx, like the Mailpit vhost
+func writeDashboardVhost(p *platform.Platform, globalCfg *config.GlobalConfig, domain string, port int) error {
+	vhostGen := nginx.NewVhostGenerator(p, ssl.NewManager(p))
+	if err := vhostGen.GenerateProxyVhost(nginx.ProxyConfig{
+		Name:       "dashboard",
+		Domain:     domain,
+		ProxyHost:  "127.0.0.1",
+		ProxyPort:  port,
+		SSLEnabled: true,
+	}); err != nil {
+		return err
+	}
+
+	if globalCfg.UseHosts() {
+		if err := dns.NewHostsManager(p).AddDomains([]string{domain}); err != nil {
+			return err
+		}
+	}
+
+	ngxController := nginx.NewController(p)
+	if !ngxController.IsRunning() {
+		return fmt.Errorf("nginx is not running")
+	}
+	if err := ngxController.Test(); err != nil {
+		return err
+	}
+	return ngxController.Reload()
+}
+
+// openDashboard opens the dashboard in the default browser
+func openDashboard(url string) {
+	var openCmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		openCmd = exec.Command("open", url)
+	default:
+		openCmd = exec.Command("xdg-open", url)
+	}
+	if err := openCmd.Start(); err != nil {
+		cli.PrintWarning("Failed to open the browser: %v", err)
+	}
+}
+
+// dashboardSource feeds the dashboard from project discovery and project
+// status, and starts and stops projects with the project manager
+type dashboardSource struct {
+	platform *platform.Platform
+	tld      string
+}
+
+func (s *dashboardSource) Projects() ([]dashboard.Project, error) {
+	infos, err := project.NewProjectDiscovery(s.platform).AllProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	mgr := project.NewManager(s.platform)
+	projects := make([]dashboard.Project, 0, len(infos))
+	for _, info := range infos {
+		proj := dashboard.Project{
+			Name:     info.Name,
+			Path:     info.Path,
+			PHP:      info.PHPVersion,
+			Domains:  info.Domains,
+			Running:  info.Running,
+			Services: []dashboard.Service{},
+			Links:    []dashboard.Link{},
+		}
+		if info.HasConfig {
+			if cfg, err := config.LoadFromPath(info.Path); err == nil {
+				proj.Links = s.links(cfg)
+			}
+			if status, err := mgr.Status(info.Path); err == nil {
+				proj.Services, proj.Links = dashboardServices(status, proj.Links)
+			}
+		}
+		projects = append(projects, proj)
+	}
+	return projects, nil
+}
+
+// links returns the web UIs of the services a project uses
+func (s *dashboardSource) links(cfg *config.Config) []dashboard.Link {
+	links := []dashboard.Link{}
+	if !cfg.Services.MailpitDisabled() {
+		links = append(links, dashboard.Link{Name: "Mailpit", URL: "https://mailpit.magebox." + s.tld})
+	}
+	if cfg.Services.HasRabbitMQ() {
+		links = append(links, dashboard.Link{Name: "RabbitMQ", URL: fmt.Sprintf("http://localhost:%d", servicePorts().RabbitMQManagementPort())})
+	}
+	return links
+}
+
+// dashboardServices returns the services of a project status sorted by name,
+// and adds the ones with a web UI to links. Xdebug and Blackfire are PHP
+// extensions, not services, and are left out.
+func dashboardServices(status *project.ProjectStatus, links []dashboard.Link) ([]dashboard.Service, []dashboard.Link) {
+	services := []dashboard.Service{}
+	for key, svc := range status.Services {
+		if key == "xdebug" || key == "blackfire" {
+			continue
+		}
+		services = append(services, dashboard.Service{Name: svc.Name, Running: svc.IsRunning})
+		if svc.URL != "" {
+			links = append(links, dashboard.Link{Name: svc.Name, URL: svc.URL})
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, links
+}
+
+func (s *dashboardSource) Start(projectPath string) error {
+	mgr := project.NewManager(s.platform)
+	mgr.SetLowMemory(lowMemoryDefault(s.platform))
+
+	cli.PrintInfo("Starting %s", projectPath)
+	result, err := mgr.Start(projectPath)
+	if err != nil {
+		return err
+	}
+	for _, w := range result.Warnings {
+		cli.PrintWarning("%s", w)
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors[0]
+	}
+	cli.PrintSuccess("Started %s", projectPath)
+	return nil
+}
+
+func (s *dashboardSource) Stop(projectPath string) error {
+	cli.PrintInfo("Stopping %s", projectPath)
+	if err := project.NewManager(s.platform).Stop(projectPath); err != nil {
+		return err
+	}
+	cli.PrintSuccess("Stopped %s", projectPath)
+	return nil
+}
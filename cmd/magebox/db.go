@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -15,6 +16,8 @@ import (
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/dbimport"
+	"qoliber/magebox/internal/dbverify"
 	"qoliber/magebox/internal/docker"
 	"qoliber/magebox/internal/progress"
 )
@@ -25,20 +28,47 @@ var dbCmd = &cobra.Command{
 	Long:  "Database management commands",
 }
 
+var (
+	dbImportVerify   bool
+	dbImportManifest string
+	dbExportManifest bool
+	dbTarget         string
+)
+
 var dbImportCmd = &cobra.Command{
 	Use:   "import [file]",
 	Short: "Import database",
-	Long:  "Imports a SQL file into the project database",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runDbImport,
+	Long: `Imports a SQL dump into the project database. Plain SQL, gzip (.sql.gz)
+and zstd (.sql.zst, needs the zstd binary) dumps are streamed into the
+server, the progress bar counts the bytes read from the file.
+
+With --sanitize the sanitize ruleset of .magebox.yaml is applied afterwards:
+customer data replaced, admin passwords reset, base URLs pointed at the
+project's domains. sanitize.always applies it to every import, --sanitize=false
+skips it once.
+
+With --verify the imported tables are checked against the manifest written
+by 'magebox db export --manifest' (<file>.manifest.json), see 'magebox db verify'.
+
+Afterwards the post_db_import hook of .magebox.yaml runs, e.g. to run
+setup:upgrade and flush the cache; --no-hooks skips it.
+
+Examples:
+  magebox db import dump.sql
+  magebox db import production.sql.zst --sanitize`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDbImport,
 }
 
 var dbExportCmd = &cobra.Command{
 	Use:   "export [file]",
 	Short: "Export database",
-	Long:  "Exports the project database to a SQL file",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  runDbExport,
+	Long: `Exports the project database to a SQL file.
+
+With --manifest the row count and checksum of every table are written to
+<file>.manifest.json, so an import can be verified with 'magebox db import --verify'.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDbExport,
 }
 
 var dbShellCmd = &cobra.Command{
@@ -72,7 +102,7 @@ var dbResetCmd = &cobra.Command{
 var dbTopCmd = &cobra.Command{
 	Use:   "top",
 	Short: "Monitor database processes",
-	Long:  "Shows real-time MySQL/MariaDB process list (like mytop/mysqladmin processlist)",
+	Long:  "Shows real-time MySQL/MariaDB/Percona process list (like mytop/mysqladmin processlist)",
 	RunE:  runDbTop,
 }
 
@@ -90,6 +120,8 @@ var dbSnapshotCreateCmd = &cobra.Command{
 	RunE:  runDbSnapshotCreate,
 }
 
+var dbSnapshotRestoreYes bool
+
 var dbSnapshotRestoreCmd = &cobra.Command{
 	Use:   "restore [name]",
 	Short: "Restore a snapshot",
@@ -114,6 +146,16 @@ var dbSnapshotDeleteCmd = &cobra.Command{
 }
 
 func init() {
+	dbImportCmd.Flags().BoolVar(&dbImportVerify, "verify", false, "Verify the import against the export manifest")
+	dbImportCmd.Flags().StringVar(&dbImportManifest, "manifest", "", "Manifest to verify against (default: <file>.manifest.json)")
+	dbImportCmd.Flags().BoolVar(&dbImportSanitize, "sanitize", false, "Apply the sanitize ruleset of .magebox.yaml (default: sanitize.always)")
+	dbImportCmd.Flags().BoolVar(&skipHooks, "no-hooks", false, "Don't run the post_db_import hook")
+	dbSnapshotRestoreCmd.Flags().BoolVarP(&dbSnapshotRestoreYes, "yes", "y", false, "Skip confirmation")
+	dbExportCmd.Flags().BoolVar(&dbExportManifest, "manifest", false, "Write row counts and checksums to <file>.manifest.json")
+	for _, c := range []*cobra.Command{dbImportCmd, dbExportCmd, dbShellCmd} {
+		c.Flags().StringVar(&dbTarget, "db", "", "Database to use, one of the project's databases (default: main database)")
+	}
+
 	dbCmd.AddCommand(dbImportCmd)
 	dbCmd.AddCommand(dbExportCmd)
 	dbCmd.AddCommand(dbShellCmd)
@@ -136,8 +178,10 @@ func init() {
 type dbInfo struct {
 	ContainerName string // e.g., "magebox-mysql-8.0"
 	Version       string // e.g., "8.0"
-	Type          string // "mysql" or "mariadb"
+	Type          string // "mysql", "mariadb" or "percona"
 	Port          int    // e.g., 33080
+	User          string // project user, or root
+	Password      string
 }
 
 // getDbInfo extracts database connection info from project config
@@ -150,6 +194,8 @@ func getDbInfo(cfg *config.Config) (*dbInfo, error) {
 			Version:       version,
 			Type:          "mysql",
 			Port:          port,
+			User:          cfg.DatabaseUser(),
+			Password:      cfg.DatabasePassword(),
 		}, nil
 	}
 	if cfg.Services.MariaDB != nil && cfg.Services.MariaDB.Enabled {
@@ -160,37 +206,51 @@ func getDbInfo(cfg *config.Config) (*dbInfo, error) {
 			Version:       version,
 			Type:          "mariadb",
 			Port:          port,
+			User:          cfg.DatabaseUser(),
+			Password:      cfg.DatabasePassword(),
+		}, nil
+	}
+	if cfg.Services.Percona != nil && cfg.Services.Percona.Enabled {
+		version := cfg.Services.Percona.Version
+		port := getDbPort("percona", version)
+		return &dbInfo{
+			ContainerName: fmt.Sprintf("magebox-percona-%s", version),
+			Version:       version,
+			Type:          "percona",
+			Port:          port,
+			User:          cfg.DatabaseUser(),
+			Password:      cfg.DatabasePassword(),
 		}, nil
 	}
 	return nil, fmt.Errorf("no database service configured in %s", config.ConfigFileName)
 }
 
-// getDbPort returns the host port for a database version
-func getDbPort(dbType, version string) int {
-	if dbType == "mysql" {
-		ports := map[string]int{
-			"5.7": 33057,
-			"8.0": 33080,
-			"8.4": 33084,
-		}
-		if port, ok := ports[version]; ok {
-			return port
-		}
-		return 33080
+// targetDatabase returns the database selected with --db, the main database
+// by default. It prints an error for a database the project doesn't declare.
+func targetDatabase(cfg *config.Config) (string, bool) {
+	if dbTarget == "" {
+		return cfg.DatabaseName(), true
 	}
-	// MariaDB
-	ports := map[string]int{
-		"10.4":  33104,
-		"10.5":  33105,
-		"10.6":  33106,
-		"10.11": 33111,
-		"11.0":  33110,
-		"11.4":  33114,
+	if !cfg.HasDatabase(dbTarget) {
+		cli.PrintError("Unknown database '%s' (available: %s)", dbTarget, strings.Join(cfg.DatabaseNames(), ", "))
+		return "", false
 	}
-	if port, ok := ports[version]; ok {
-		return port
+	return dbTarget, true
+}
+
+// servicePorts returns the host ports reserved for the shared services in
+// ~/.magebox/ports.json, nil for the conventional ports
+func servicePorts() *docker.PortRegistry {
+	p, err := getPlatform()
+	if err != nil {
+		return nil
 	}
-	return 33106
+	return docker.LoadPorts(p)
+}
+
+// getDbPort returns the host port for a database version
+func getDbPort(dbType, version string) int {
+	return servicePorts().DBPort(dbType, version)
 }
 
 func runDbImport(cmd *cobra.Command, args []string) error {
@@ -211,72 +271,89 @@ func runDbImport(cmd *cobra.Command, args []string) error {
 	}
 
 	sqlFile := args[0]
-	dbName := cfg.DatabaseName()
+	dbName, ok := targetDatabase(cfg)
+	if !ok {
+		return nil
+	}
+	sanitize, ok := shouldSanitize(cmd, cfg)
+	if !ok {
+		return nil
+	}
+
+	// Open the dump before creating the database, a missing file or zstd
+	// binary should not leave an empty database behind
+	bar := progress.NewBar("Importing:")
+	dump, err := dbimport.Open(sqlFile, func(p progress.Progress) {
+		bar.Update(p)
+		events.Bytes("import", p)
+	})
+	if err == dbimport.ErrZstdNotInstalled {
+		cli.PrintError("%s is compressed with zstd, which is not installed", filepath.Base(sqlFile))
+		cli.PrintInfo("Install zstd, or decompress it first: zstd -d %s", sqlFile)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open SQL file: %w", err)
+	}
+	defer dump.Close()
+
 	fmt.Printf("Importing %s into database '%s' (%s)\n", filepath.Base(sqlFile), dbName, db.ContainerName)
 
 	// Create database if it doesn't exist
+	events.Phase("create", 0, "Creating database "+dbName)
 	createCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", dbName))
 	createCmd.Stderr = os.Stderr
 	if err := createCmd.Run(); err != nil {
 		return fmt.Errorf("failed to create database: %w", err)
 	}
 
-	// Get file info for progress tracking
-	fileInfo, err := os.Stat(sqlFile)
-	if err != nil {
-		return fmt.Errorf("failed to stat SQL file: %w", err)
-	}
-	fileSize := fileInfo.Size()
+	events.Phase("import", 5, "Importing "+filepath.Base(sqlFile))
 
-	// Open file
-	file, err := os.Open(sqlFile)
-	if err != nil {
-		return fmt.Errorf("failed to open SQL file: %w", err)
-	}
-	defer file.Close()
-
-	// Create progress bar
-	bar := progress.NewBar("Importing:")
-
-	// Use docker exec directly with container name
+	// Use docker exec directly with container name. The dump is decompressed
+	// while mysql reads it, compressed bytes are tracked for the progress.
 	importCmd := exec.Command("docker", "exec", "-i", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, dbName)
+		"mysql", "-u"+db.User, "-p"+db.Password, dbName)
+	importCmd.Stdin = dump
+	importCmd.Stderr = io.Discard // Suppress mysql warnings
 
-	// Handle gzip compressed files
-	if strings.HasSuffix(sqlFile, ".gz") {
-		// For gzip, track compressed bytes read
-		progressReader := progress.NewReader(file, fileSize, bar.Update)
+	if err := importCmd.Run(); err != nil {
+		bar.Finish()
+		return fmt.Errorf("import failed: %w", err)
+	}
+	bar.Finish()
+	// A corrupt zstd dump ends the stream early, which mysql can't tell
+	if err := dump.Close(); err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+	cli.PrintSuccess("Import completed successfully!")
 
-		gzReader, err := gzip.NewReader(progressReader)
-		if err != nil {
-			return fmt.Errorf("failed to create gzip reader: %w", err)
+	if dbImportVerify || dbImportManifest != "" {
+		manifestPath := dbImportManifest
+		if manifestPath == "" {
+			manifestPath = dbverify.ManifestPath(sqlFile)
 		}
-		defer gzReader.Close()
-
-		importCmd.Stdin = gzReader
-		importCmd.Stderr = io.Discard // Suppress mysql warnings
-
-		if err := importCmd.Run(); err != nil {
-			bar.Finish()
-			return fmt.Errorf("import failed: %w", err)
+		events.Phase("verify", 95, "Verifying import")
+		if err := verifyDatabase(db, dbName, manifestPath); err != nil {
+			return err
 		}
-	} else {
-		// For plain SQL, track bytes directly
-		progressReader := progress.NewReader(file, fileSize, bar.Update)
-
-		importCmd.Stdin = progressReader
-		importCmd.Stderr = io.Discard // Suppress mysql warnings
+	}
 
-		if err := importCmd.Run(); err != nil {
-			bar.Finish()
-			return fmt.Errorf("import failed: %w", err)
+	// Sanitize after verifying, the manifest has the checksums of the dump
+	if sanitize {
+		events.Phase("sanitize", 97, "Sanitizing database")
+		if err := sanitizeDatabase(cfg, cwd, db, dbName); err != nil {
+			return err
 		}
 	}
 
-	bar.Finish()
-	cli.PrintSuccess("Import completed successfully!")
+	if err := runHook(cfg, cwd, config.HookPostDBImport); err != nil {
+		cli.PrintError("%v", err)
+		return err
+	}
+
+	events.Done("Import completed")
 	return nil
 }
 
@@ -297,11 +374,17 @@ func runDbExport(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	dbName, ok := targetDatabase(cfg)
+	if !ok {
+		return nil
+	}
+
 	// Determine output file
-	dbName := cfg.DatabaseName()
 	var outputFile string
 	if len(args) > 0 {
 		outputFile = args[0]
+	} else if dbName != cfg.DatabaseName() {
+		outputFile = fmt.Sprintf("%s.sql", dbName)
 	} else {
 		outputFile = fmt.Sprintf("%s.sql", cfg.Name)
 	}
@@ -311,7 +394,7 @@ func runDbExport(cmd *cobra.Command, args []string) error {
 	// Use docker exec directly with container name
 	// --no-tablespaces: Skip TABLESPACE statements (avoids permission issues on import)
 	exportCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysqldump", "-uroot", "-p"+docker.DefaultDBRootPassword, "--no-tablespaces", dbName)
+		"mysqldump", "-u"+db.User, "-p"+db.Password, "--no-tablespaces", dbName)
 
 	file, err := os.Create(outputFile)
 	if err != nil {
@@ -327,6 +410,18 @@ func runDbExport(cmd *cobra.Command, args []string) error {
 	}
 
 	cli.PrintSuccess("Export completed: %s", outputFile)
+
+	if dbExportManifest {
+		manifest, err := databaseManifest(db, dbName)
+		if err != nil {
+			return fmt.Errorf("failed to build manifest: %w", err)
+		}
+		manifestPath := dbverify.ManifestPath(outputFile)
+		if err := manifest.Save(manifestPath); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		cli.PrintSuccess("Manifest written: %s (%d tables)", manifestPath, len(manifest.Tables))
+	}
 	return nil
 }
 
@@ -347,12 +442,15 @@ func runDbShell(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	dbName := cfg.DatabaseName()
+	dbName, ok := targetDatabase(cfg)
+	if !ok {
+		return nil
+	}
 	fmt.Printf("Connecting to database '%s' (%s)...\n", dbName, db.ContainerName)
 
 	// Use docker exec directly with container name
 	shellCmd := exec.Command("docker", "exec", "-it", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, dbName)
+		"mysql", "-u"+db.User, "-p"+db.Password, dbName)
 	shellCmd.Stdin = os.Stdin
 	shellCmd.Stdout = os.Stdout
 	shellCmd.Stderr = os.Stderr
@@ -385,7 +483,7 @@ func runDbCreate(cmd *cobra.Command, args []string) error {
 
 	// Check if database already exists
 	checkCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("SELECT SCHEMA_NAME FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = '%s'", dbName))
 	output, err := checkCmd.Output()
 	if err == nil && strings.Contains(string(output), dbName) {
@@ -396,7 +494,7 @@ func runDbCreate(cmd *cobra.Command, args []string) error {
 	// Create database
 	fmt.Print("Creating database... ")
 	createCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", dbName))
 	createCmd.Stderr = os.Stderr
 
@@ -447,7 +545,7 @@ func runDbDrop(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Print("Dropping database... ")
 	dropCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbName))
 	dropCmd.Stderr = os.Stderr
 
@@ -500,7 +598,7 @@ func runDbReset(cmd *cobra.Command, args []string) error {
 	// Drop database
 	fmt.Print("Dropping database... ")
 	dropCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbName))
 	dropCmd.Stderr = os.Stderr
 
@@ -513,7 +611,7 @@ func runDbReset(cmd *cobra.Command, args []string) error {
 	// Create database
 	fmt.Print("Creating database... ")
 	createCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("CREATE DATABASE `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", dbName))
 	createCmd.Stderr = os.Stderr
 
@@ -551,8 +649,8 @@ func runDbTop(cmd *cobra.Command, args []string) error {
 		innotopCmd := exec.Command(innotopPath,
 			"--host", "127.0.0.1",
 			"--port", fmt.Sprintf("%d", db.Port),
-			"--user", "root",
-			"--password", docker.DefaultDBRootPassword,
+			"--user", db.User,
+			"--password", db.Password,
 		)
 		innotopCmd.Stdin = os.Stdin
 		innotopCmd.Stdout = os.Stdout
@@ -568,7 +666,7 @@ func runDbTop(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Monitoring %s (Ctrl+C to stop)\n\n", cli.Highlight(db.ContainerName))
 
 	topCmd := exec.Command("docker", "exec", "-it", db.ContainerName,
-		"mysqladmin", "-uroot", "-p"+docker.DefaultDBRootPassword,
+		"mysqladmin", "-u"+db.User, "-p"+db.Password,
 		"processlist", "--sleep=2", "--verbose")
 	topCmd.Stdin = os.Stdin
 	topCmd.Stdout = os.Stdout
@@ -583,9 +681,25 @@ func getSnapshotDir(projectName string) string {
 	return filepath.Join(home, ".magebox", "snapshots", projectName)
 }
 
-// getSnapshotPath returns the full path for a snapshot file
-func getSnapshotPath(projectName, snapshotName string) string {
-	return filepath.Join(getSnapshotDir(projectName), snapshotName+".sql.gz")
+// snapshotNameRegex allows letters, digits, '-', '_' and '.', without a leading dot
+var snapshotNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// validateSnapshotName rejects names that would leave the snapshot directory
+// or can't be told apart from the files kept next to snapshots
+func validateSnapshotName(name string) error {
+	if !snapshotNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name '%s': use letters, digits, '-', '_' and '.' without a leading dot", name)
+	}
+	return nil
+}
+
+// getSnapshotPath returns the full path for a snapshot file, rejecting names
+// that fail validateSnapshotName
+func getSnapshotPath(projectName, snapshotName string) (string, error) {
+	if err := validateSnapshotName(snapshotName); err != nil {
+		return "", err
+	}
+	return filepath.Join(getSnapshotDir(projectName), snapshotName+".sql.gz"), nil
 }
 
 func runDbSnapshotCreate(cmd *cobra.Command, args []string) error {
@@ -609,6 +723,9 @@ func runDbSnapshotCreate(cmd *cobra.Command, args []string) error {
 	var snapshotName string
 	if len(args) > 0 {
 		snapshotName = args[0]
+		if err := validateSnapshotName(snapshotName); err != nil {
+			return err
+		}
 	} else {
 		// Generate name with timestamp
 		snapshotName = time.Now().Format("2006-01-02_15-04-05")
@@ -620,7 +737,10 @@ func runDbSnapshotCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create snapshot directory: %w", err)
 	}
 
-	snapshotPath := getSnapshotPath(cfg.Name, snapshotName)
+	snapshotPath, err := getSnapshotPath(cfg.Name, snapshotName)
+	if err != nil {
+		return err
+	}
 
 	// Check if snapshot already exists
 	if _, err := os.Stat(snapshotPath); err == nil {
@@ -638,10 +758,17 @@ func runDbSnapshotCreate(cmd *cobra.Command, args []string) error {
 
 	fmt.Print("Dumping database... ")
 
-	// Create gzipped dump
-	dumpCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysqldump", "-uroot", "-p"+docker.DefaultDBRootPassword,
-		"--no-tablespaces", "--single-transaction", dbName)
+	// Create gzipped dump. With binary logging on, record the binlog position
+	// so the snapshot can be rolled forward by 'magebox db restore --to'.
+	// Reading the position needs RELOAD, so the dump runs as root.
+	dumpArgs := []string{"exec", db.ContainerName, "mysqldump", "-u" + db.User, "-p" + db.Password}
+	withPosition := binlogEnabled(db)
+	if withPosition {
+		dumpArgs = []string{"exec", db.ContainerName, "mysqldump", "-uroot", "-p" + docker.DefaultDBRootPassword,
+			docker.DumpPositionFlag(db.Type, db.Version)}
+	}
+	dumpArgs = append(dumpArgs, "--no-tablespaces", "--single-transaction", dbName)
+	dumpCmd := exec.Command("docker", dumpArgs...)
 
 	// Create output file with gzip compression
 	outFile, err := os.Create(snapshotPath)
@@ -657,6 +784,7 @@ func runDbSnapshotCreate(cmd *cobra.Command, args []string) error {
 	dumpCmd.Stdout = gzWriter
 	dumpCmd.Stderr = os.Stderr
 
+	startedAt := time.Now()
 	if err := dumpCmd.Run(); err != nil {
 		fmt.Println(cli.Error("failed"))
 		os.Remove(snapshotPath)
@@ -671,6 +799,12 @@ func runDbSnapshotCreate(cmd *cobra.Command, args []string) error {
 	info, _ := os.Stat(snapshotPath)
 	fmt.Println(cli.Success("done"))
 
+	if withPosition {
+		if err := writeSnapshotPosition(snapshotPath, startedAt); err != nil {
+			cli.PrintWarning("Snapshot cannot be used for point-in-time restore: %v", err)
+		}
+	}
+
 	fmt.Println()
 	cli.PrintSuccess("Snapshot '%s' created (%s)", snapshotName, formatFileSize(info.Size()))
 	cli.PrintInfo("Restore with: magebox db snapshot restore %s", snapshotName)
@@ -695,7 +829,10 @@ func runDbSnapshotRestore(cmd *cobra.Command, args []string) error {
 	}
 
 	snapshotName := args[0]
-	snapshotPath := getSnapshotPath(cfg.Name, snapshotName)
+	snapshotPath, err := getSnapshotPath(cfg.Name, snapshotName)
+	if err != nil {
+		return err
+	}
 
 	// Check if snapshot exists
 	info, err := os.Stat(snapshotPath)
@@ -713,22 +850,34 @@ func runDbSnapshotRestore(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Container: %s\n", cli.Highlight(db.ContainerName))
 	fmt.Println()
 
-	cli.PrintWarning("This will replace ALL data in database '%s'!", dbName)
-	fmt.Print("Are you sure? [y/N]: ")
+	if !dbSnapshotRestoreYes {
+		cli.PrintWarning("This will replace ALL data in database '%s'!", dbName)
+		fmt.Print("Are you sure? [y/N]: ")
 
-	var confirm string
-	_, _ = fmt.Scanln(&confirm)
-	if confirm != "y" && confirm != "Y" {
-		cli.PrintInfo("Aborted")
-		return nil
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			cli.PrintInfo("Aborted")
+			return nil
+		}
+
+		fmt.Println()
+	}
+
+	if err := loadSnapshot(db, dbName, snapshotPath); err != nil {
+		return err
 	}
 
 	fmt.Println()
+	cli.PrintSuccess("Snapshot '%s' restored!", snapshotName)
+	return nil
+}
 
-	// Drop and recreate database
+// resetDatabase drops and recreates an empty database
+func resetDatabase(db *dbInfo, dbName string) error {
 	fmt.Print("Resetting database... ")
 	resetCmd := exec.Command("docker", "exec", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		"mysql", "-u"+db.User, "-p"+db.Password, "-e",
 		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`; CREATE DATABASE `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", dbName, dbName))
 	resetCmd.Stderr = os.Stderr
 	if err := resetCmd.Run(); err != nil {
@@ -736,6 +885,14 @@ func runDbSnapshotRestore(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to reset database: %w", err)
 	}
 	fmt.Println(cli.Success("done"))
+	return nil
+}
+
+// loadSnapshot drops and recreates the database and imports a snapshot into it
+func loadSnapshot(db *dbInfo, dbName, snapshotPath string) error {
+	if err := resetDatabase(db, dbName); err != nil {
+		return err
+	}
 
 	// Restore from snapshot
 	fmt.Print("Restoring snapshot... ")
@@ -757,7 +914,7 @@ func runDbSnapshotRestore(cmd *cobra.Command, args []string) error {
 
 	// Import into database
 	importCmd := exec.Command("docker", "exec", "-i", db.ContainerName,
-		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, dbName)
+		"mysql", "-u"+db.User, "-p"+db.Password, dbName)
 	importCmd.Stdin = gzReader
 	importCmd.Stderr = os.Stderr
 
@@ -766,9 +923,6 @@ func runDbSnapshotRestore(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("restore failed: %w", err)
 	}
 	fmt.Println(cli.Success("done"))
-
-	fmt.Println()
-	cli.PrintSuccess("Snapshot '%s' restored!", snapshotName)
 	return nil
 }
 
@@ -864,7 +1018,10 @@ func runDbSnapshotDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	snapshotName := args[0]
-	snapshotPath := getSnapshotPath(cfg.Name, snapshotName)
+	snapshotPath, err := getSnapshotPath(cfg.Name, snapshotName)
+	if err != nil {
+		return err
+	}
 
 	// Check if snapshot exists
 	info, err := os.Stat(snapshotPath)
@@ -890,6 +1047,7 @@ func runDbSnapshotDelete(cmd *cobra.Command, args []string) error {
 	if err := os.Remove(snapshotPath); err != nil {
 		return fmt.Errorf("failed to delete snapshot: %w", err)
 	}
+	_ = os.Remove(snapshotPositionPath(snapshotPath))
 
 	fmt.Println()
 	cli.PrintSuccess("Snapshot '%s' deleted", snapshotName)
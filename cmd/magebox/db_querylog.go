@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/querylog"
+)
+
+var (
+	dbQuerylogFor      time.Duration
+	dbQuerylogSlow     time.Duration
+	dbQuerylogBackend  string
+	dbQuerylogExpireAt string
+)
+
+var dbQuerylogCmd = &cobra.Command{
+	Use:   "querylog",
+	Short: "Show, enable or disable database query logging",
+	Long: `Logs every database query of the project. Without a subcommand, shows
+whether query logging is on and when it turns off.
+
+Magento projects use Magento's DB logger, which only logs the project's own
+queries, with their duration, to var/debug/db.log. Other projects (or
+--backend mysql) use the general log of the database container, filtered to
+the connections using the project database.
+
+Query logs grow fast, so logging turns itself off after --for (30 minutes
+by default). Stream the log with 'magebox logs queries'.
+
+Examples:
+  magebox db querylog on                # Log queries for 30 minutes
+  magebox db querylog on --for 2h       # Log queries for 2 hours
+  magebox db querylog on --slow 500ms   # Highlight queries over 500ms
+  magebox db querylog off`,
+	RunE: runDbQuerylogStatus,
+}
+
+var dbQuerylogOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Enable database query logging",
+	RunE:  runDbQuerylogOn,
+}
+
+var dbQuerylogOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Disable database query logging",
+	RunE:  runDbQuerylogOff,
+}
+
+func init() {
+	dbQuerylogOnCmd.Flags().DurationVar(&dbQuerylogFor, "for", querylog.DefaultDuration, "Turn logging off after this long (0 keeps it on)")
+	dbQuerylogOnCmd.Flags().DurationVar(&dbQuerylogSlow, "slow", querylog.DefaultSlow, "Highlight queries taking at least this long")
+	dbQuerylogOnCmd.Flags().StringVar(&dbQuerylogBackend, "backend", "", "Query logger: magento or mysql (default: magento for Magento projects)")
+	dbQuerylogOffCmd.Flags().StringVar(&dbQuerylogExpireAt, "expire-at", "", "")
+	_ = dbQuerylogOffCmd.Flags().MarkHidden("expire-at")
+
+	dbQuerylogCmd.AddCommand(dbQuerylogOnCmd)
+	dbQuerylogCmd.AddCommand(dbQuerylogOffCmd)
+	dbCmd.AddCommand(dbQuerylogCmd)
+}
+
+// getQuerylogStatePath returns the query log state file of a project
+func getQuerylogStatePath(p *platform.Platform, projectName string) string {
+	return querylog.StatePath(filepath.Join(p.MageBoxDir(), "run"), projectName)
+}
+
+func runDbQuerylogOn(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	db, err := getDbInfo(cfg)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	backend := dbQuerylogBackend
+	if backend == "" {
+		backend = querylog.BackendMySQL
+		if cfg.IsMagento() {
+			backend = querylog.BackendMagento
+		}
+	}
+	if backend != querylog.BackendMagento && backend != querylog.BackendMySQL {
+		cli.PrintError("Unknown backend %q, use magento or mysql", backend)
+		return nil
+	}
+	if dbQuerylogFor < 0 || dbQuerylogSlow < 0 {
+		cli.PrintError("--for and --slow can't be negative")
+		return nil
+	}
+
+	statePath := getQuerylogStatePath(p, cfg.Name)
+	if current, err := querylog.LoadState(statePath); err == nil && current != nil {
+		// Turning it on again restarts the timer
+		stopQuerylogTimer(current)
+	}
+
+	s := &querylog.State{
+		Project:  cfg.Name,
+		Backend:  backend,
+		Database: cfg.DatabaseName(),
+		Slow:     dbQuerylogSlow,
+		Since:    time.Now(),
+	}
+	if dbQuerylogFor > 0 {
+		s.Until = s.Since.Add(dbQuerylogFor).Truncate(time.Second)
+	}
+
+	switch backend {
+	case querylog.BackendMagento:
+		if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+			cli.PrintError("bin/magento not found, use %s for non-Magento projects", cli.Command("--backend mysql"))
+			return nil
+		}
+		if out, err := runMagentoQuerylog(p, cfg, cwd, querylog.MagentoEnableArgs()); err != nil {
+			cli.PrintError("Failed to enable the Magento DB logger: %v", err)
+			fmt.Print(out)
+			return nil
+		}
+		s.LogFile = filepath.Join(cwd, querylog.MagentoLogFile)
+	case querylog.BackendMySQL:
+		if _, err := rootQueryLines(db, querylog.MySQLEnableStatements()); err != nil {
+			cli.PrintError("Failed to enable the general log on %s: %v", db.ContainerName, err)
+			return nil
+		}
+		s.Container = db.ContainerName
+		s.LogFile = querylog.MySQLLogFile
+	}
+
+	if !s.Until.IsZero() {
+		pid, err := startQuerylogTimer(cwd, s.Until)
+		if err != nil {
+			cli.PrintWarning("Could not schedule turning the query log off: %v", err)
+		}
+		s.TimerPID = pid
+	}
+	if err := s.Save(statePath); err != nil {
+		return err
+	}
+
+	cli.PrintSuccess("Query logging enabled for %s (%s)", cli.Highlight(cfg.Name), s.Backend)
+	if s.Until.IsZero() {
+		cli.PrintWarning("Logging stays on until %s", cli.Command("magebox db querylog off"))
+	} else {
+		cli.PrintInfo("Turns off at %s", s.Until.Format("15:04:05"))
+	}
+	if s.Backend == querylog.BackendMySQL {
+		cli.PrintInfo("The general log of %s is shared by all projects using it; 'magebox logs queries' filters it to %s",
+			s.Container, s.Database)
+	}
+	cli.PrintInfo("Stream queries with %s", cli.Command("magebox logs queries"))
+	return nil
+}
+
+func runDbQuerylogOff(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	statePath := getQuerylogStatePath(p, cfg.Name)
+	s, err := querylog.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	if dbQuerylogExpireAt != "" {
+		// Scheduled by 'db querylog on': wait, then turn off the log unless it
+		// was turned on again with another expiry in the meantime
+		expireAt, err := time.Parse(time.RFC3339, dbQuerylogExpireAt)
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Until(expireAt))
+		if s, err = querylog.LoadState(statePath); err != nil || s == nil || !s.Until.Equal(expireAt) {
+			return err
+		}
+	}
+
+	if s == nil {
+		cli.PrintInfo("Query logging is not enabled for this project")
+		return nil
+	}
+
+	if err := disableQuerylog(p, cfg, cwd, s); err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	cli.PrintSuccess("Query logging disabled for %s", cli.Highlight(cfg.Name))
+	if s.Backend == querylog.BackendMagento {
+		if info, err := os.Stat(s.LogFile); err == nil {
+			cli.PrintInfo("The log is kept at %s (%s)", cli.Path(s.LogFile), formatFileSize(info.Size()))
+		}
+	}
+	return nil
+}
+
+func runDbQuerylogStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	s, err := activeQuerylog(p, cfg, cwd)
+	if err != nil {
+		return err
+	}
+
+	cli.PrintTitle("Query Log")
+	fmt.Printf("Project:  %s\n", cli.Highlight(cfg.Name))
+	if s == nil {
+		fmt.Printf("Status:   %s\n", cli.Warning("off"))
+		fmt.Printf("\nEnable with %s\n", cli.Command("magebox db querylog on"))
+		return nil
+	}
+
+	fmt.Printf("Status:   %s (%s)\n", cli.Success("on"), s.Backend)
+	fmt.Printf("Since:    %s\n", s.Since.Format("2006-01-02 15:04:05"))
+	if s.Until.IsZero() {
+		fmt.Printf("Until:    %s\n", cli.Warning("turned off manually"))
+	} else {
+		fmt.Printf("Until:    %s (%s left)\n", s.Until.Format("2006-01-02 15:04:05"), s.Remaining(time.Now()))
+	}
+	fmt.Printf("Slow:     %s\n", s.Slow)
+	if s.Backend == querylog.BackendMySQL {
+		fmt.Printf("Log:      %s:%s\n", s.Container, s.LogFile)
+	} else {
+		fmt.Printf("Log:      %s\n", cli.Path(s.LogFile))
+	}
+	return nil
+}
+
+// activeQuerylog returns the query log state of a project, turning the log
+// off first when it expired without the timer doing so (e.g. after a reboot)
+func activeQuerylog(p *platform.Platform, cfg *config.Config, cwd string) (*querylog.State, error) {
+	s, err := querylog.LoadState(getQuerylogStatePath(p, cfg.Name))
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if !s.Expired(time.Now()) {
+		return s, nil
+	}
+	if err := disableQuerylog(p, cfg, cwd, s); err != nil {
+		cli.PrintWarning("Query logging expired but could not be turned off: %v", err)
+		return nil, nil
+	}
+	cli.PrintInfo("Query logging expired at %s and was turned off", s.Until.Format("15:04:05"))
+	return nil, nil
+}
+
+// disableQuerylog turns a project query log off and removes its state
+func disableQuerylog(p *platform.Platform, cfg *config.Config, cwd string, s *querylog.State) error {
+	stopQuerylogTimer(s)
+
+	switch s.Backend {
+	case querylog.BackendMagento:
+		if out, err := runMagentoQuerylog(p, cfg, cwd, querylog.MagentoDisableArgs()); err != nil {
+			return fmt.Errorf("failed to disable the Magento DB logger: %v\n%s", err, out)
+		}
+	case querylog.BackendMySQL:
+		// The general log is server-wide, keep it on while another project uses it
+		if !querylog.ContainerInUse(filepath.Dir(getQuerylogStatePath(p, cfg.Name)), s.Container, s.Project, time.Now()) {
+			if db, err := getDbInfo(cfg); err == nil && db.ContainerName == s.Container {
+				if _, err := rootQueryLines(db, querylog.MySQLDisableStatements()); err != nil {
+					return fmt.Errorf("failed to disable the general log on %s: %w", s.Container, err)
+				}
+				_ = exec.Command("docker", "exec", s.Container, "rm", "-f", s.LogFile).Run()
+			}
+		}
+	}
+
+	return os.Remove(getQuerylogStatePath(p, cfg.Name))
+}
+
+// runMagentoQuerylog runs a bin/magento dev:query-log command
+func runMagentoQuerylog(p *platform.Platform, cfg *config.Config, cwd string, args []string) (string, error) {
+	magentoCmd := exec.Command(p.PHPBinary(cfg.PHP), append([]string{"bin/magento"}, args...)...)
+	magentoCmd.Dir = cwd
+	out, err := magentoCmd.CombinedOutput()
+	return string(out), err
+}
+
+// startQuerylogTimer starts a detached 'db querylog off' that waits until the
+// log expires
+func startQuerylogTimer(cwd string, until time.Time) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	timer := exec.Command(exe, "db", "querylog", "off", "--expire-at", until.Format(time.RFC3339))
+	timer.Dir = cwd
+	timer.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := timer.Start(); err != nil {
+		return 0, err
+	}
+	pid := timer.Process.Pid
+	_ = timer.Process.Release()
+	return pid, nil
+}
+
+// stopQuerylogTimer stops the scheduled 'db querylog off' of a state
+func stopQuerylogTimer(s *querylog.State) {
+	if s.TimerPID == 0 || s.TimerPID == os.Getpid() || !processRunning(s.TimerPID) {
+		return
+	}
+	if process, err := os.FindProcess(s.TimerPID); err == nil {
+		_ = process.Signal(syscall.SIGTERM)
+	}
+}
+
+// streamQuerylog follows a query log and writes it through the formatter
+func streamQuerylog(cmd *cobra.Command, s *querylog.State) error {
+	lines := strconv.Itoa(logsLinesFlag)
+	var tailCmd *exec.Cmd
+	if s.Backend == querylog.BackendMySQL {
+		tailCmd = exec.CommandContext(cmd.Context(), "docker", "exec", s.Container, "tail", "-n", lines, "-F", s.LogFile)
+	} else {
+		tailCmd = exec.CommandContext(cmd.Context(), "tail", "-n", lines, "-F", s.LogFile)
+	}
+	tailCmd.Stderr = io.Discard
+
+	out, err := tailCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := tailCmd.Start(); err != nil {
+		return fmt.Errorf("failed to follow %s: %w", s.LogFile, err)
+	}
+
+	cli.PrintInfo("Streaming queries of %s, slow queries (>= %s) highlighted (Ctrl+C to stop)...", s.Database, s.Slow)
+	fmt.Println()
+
+	formatter := querylog.NewFormatter(os.Stdout, s, cli.Error)
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		formatter.Line(scanner.Text())
+	}
+	formatter.Flush()
+
+	if err := tailCmd.Wait(); err != nil && cmd.Context().Err() == nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/docker"
+)
+
+var (
+	dbRestoreTo       string
+	dbRestoreSnapshot string
+	dbRestoreYes      bool
+)
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the database to a point in time",
+	Long: `Restores the database to a point in time by loading the most recent snapshot
+taken before that time and replaying the binary log up to it.
+
+Binary logging is enabled on the MageBox database containers and binlogs are
+kept for 7 days. Only snapshots created while binary logging was on can be
+rolled forward.
+
+The time can be relative ("10 minutes ago", "2 hours ago"), a time today
+("14:30") or a full timestamp ("2024-05-01 14:30:00"), in local time.
+
+Examples:
+  magebox db restore --to "10 minutes ago"
+  magebox db restore --to "14:30"
+  magebox db restore --to "2024-05-01 14:30:00" --snapshot before-upgrade`,
+	RunE: runDbRestore,
+}
+
+func init() {
+	dbRestoreCmd.Flags().StringVar(&dbRestoreTo, "to", "", "Point in time to restore to (required)")
+	dbRestoreCmd.Flags().StringVar(&dbRestoreSnapshot, "snapshot", "", "Snapshot to start from (default: latest before --to)")
+	dbRestoreCmd.Flags().BoolVarP(&dbRestoreYes, "yes", "y", false, "Skip confirmation")
+	_ = dbRestoreCmd.MarkFlagRequired("to")
+	dbCmd.AddCommand(dbRestoreCmd)
+}
+
+func runDbRestore(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	db, err := getDbInfo(cfg)
+	if err != nil {
+		return err
+	}
+
+	target, err := parseRestoreTarget(dbRestoreTo, time.Now())
+	if err != nil {
+		return err
+	}
+	if target.After(time.Now()) {
+		return fmt.Errorf("cannot restore to %s, it is in the future", target.Format("2006-01-02 15:04:05"))
+	}
+
+	if !binlogEnabled(db) {
+		cli.PrintInfo("Recreate the container with %s, then take a snapshot with %s",
+			cli.Command("magebox restart"), cli.Command("magebox db snapshot create"))
+		return fmt.Errorf("binary logging is not enabled on %s", db.ContainerName)
+	}
+
+	snapshotName, pos, err := findRestoreSnapshot(cfg.Name, dbRestoreSnapshot, target)
+	if err != nil {
+		cli.PrintInfo("Create one with: magebox db snapshot create [name]")
+		return err
+	}
+
+	allLogs, err := rootQueryLines(db, "SHOW BINARY LOGS")
+	if err != nil {
+		return fmt.Errorf("failed to list binary logs: %w", err)
+	}
+	logNames := make([]string, 0, len(allLogs))
+	for _, line := range allLogs {
+		logNames = append(logNames, strings.Fields(line)[0])
+	}
+	files, err := docker.BinlogsFrom(logNames, pos.File)
+	if err != nil {
+		return err
+	}
+	basename, err := rootQueryLines(db, "SELECT @@log_bin_basename")
+	if err != nil || len(basename) == 0 {
+		return fmt.Errorf("failed to locate binary logs: %w", err)
+	}
+	binlogDir := filepath.Dir(basename[0])
+
+	dbName := cfg.DatabaseName()
+	cli.PrintTitle("Point-in-Time Restore")
+	fmt.Printf("Database:  %s\n", cli.Highlight(dbName))
+	fmt.Printf("Restore to: %s\n", cli.Highlight(target.Format("2006-01-02 15:04:05")))
+	fmt.Printf("Snapshot:  %s (%s)\n", cli.Highlight(snapshotName), pos.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Binlogs:   %s\n", strings.Join(files, ", "))
+	fmt.Printf("Container: %s\n", cli.Highlight(db.ContainerName))
+	fmt.Println()
+
+	if !dbRestoreYes {
+		cli.PrintWarning("This will replace ALL data in database '%s'!", dbName)
+		fmt.Print("Are you sure? [y/N]: ")
+
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			cli.PrintInfo("Aborted")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	snapshotPath, err := getSnapshotPath(cfg.Name, snapshotName)
+	if err != nil {
+		return err
+	}
+	if err := loadSnapshot(db, dbName, snapshotPath); err != nil {
+		return err
+	}
+
+	// Replaying row events needs privileges the project user doesn't have
+	fmt.Print("Replaying binary log... ")
+	script := docker.BinlogReplayScript(db.Type, binlogDir, files, pos.Position, target,
+		dbName, "root", docker.DefaultDBRootPassword)
+	replayCmd := exec.Command("docker", "exec", db.ContainerName, "bash", "-c", script)
+	replayCmd.Stderr = os.Stderr
+	if err := replayCmd.Run(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		return fmt.Errorf("binlog replay failed: %w", err)
+	}
+	fmt.Println(cli.Success("done"))
+
+	fmt.Println()
+	cli.PrintSuccess("Database '%s' restored to %s", dbName, target.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// binlogEnabled reports whether the database server writes a binary log
+func binlogEnabled(db *dbInfo) bool {
+	lines, err := rootQueryLines(db, "SELECT @@log_bin")
+	return err == nil && len(lines) == 1 && lines[0] == "1"
+}
+
+// rootQueryLines runs a query as root and returns the output rows
+func rootQueryLines(db *dbInfo, query string) ([]string, error) {
+	out, err := exec.Command("docker", "exec", db.ContainerName,
+		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-N", "-B", "-e", query).Output()
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// snapshotPositionPath returns the path of the binlog position file kept next to a snapshot
+func snapshotPositionPath(snapshotPath string) string {
+	return strings.TrimSuffix(snapshotPath, ".sql.gz") + ".binlog.json"
+}
+
+// writeSnapshotPosition reads the binlog position from the header of a
+// snapshot and stores it next to the snapshot. createdAt is when the dump
+// started: the position is taken then, not when the dump finished.
+func writeSnapshotPosition(snapshotPath string, createdAt time.Time) error {
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	pos, err := docker.ParseBinlogPosition(gz)
+	if err != nil {
+		return err
+	}
+	pos.CreatedAt = createdAt.UTC()
+
+	data, err := json.MarshalIndent(pos, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotPositionPath(snapshotPath), data, 0644)
+}
+
+// readSnapshotPosition loads the binlog position of a snapshot
+func readSnapshotPosition(snapshotPath string) (*docker.BinlogPosition, error) {
+	data, err := os.ReadFile(snapshotPositionPath(snapshotPath))
+	if err != nil {
+		return nil, err
+	}
+	var pos docker.BinlogPosition
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return nil, err
+	}
+	return &pos, nil
+}
+
+// findRestoreSnapshot returns the snapshot to roll forward: the named one, or
+// the most recent snapshot with a binlog position taken before target
+func findRestoreSnapshot(projectName, name string, target time.Time) (string, *docker.BinlogPosition, error) {
+	if name != "" {
+		snapshotPath, err := getSnapshotPath(projectName, name)
+		if err != nil {
+			return "", nil, err
+		}
+		pos, err := readSnapshotPosition(snapshotPath)
+		if err != nil {
+			return "", nil, fmt.Errorf("snapshot '%s' has no binlog position and cannot be rolled forward", name)
+		}
+		if pos.CreatedAt.After(target) {
+			return "", nil, fmt.Errorf("snapshot '%s' was taken after %s", name, target.Format("2006-01-02 15:04:05"))
+		}
+		return name, pos, nil
+	}
+
+	entries, err := os.ReadDir(getSnapshotDir(projectName))
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, err
+	}
+
+	var bestName string
+	var best *docker.BinlogPosition
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql.gz") {
+			continue
+		}
+		snapshotName := strings.TrimSuffix(entry.Name(), ".sql.gz")
+		snapshotPath, err := getSnapshotPath(projectName, snapshotName)
+		if err != nil {
+			continue
+		}
+		pos, err := readSnapshotPosition(snapshotPath)
+		if err != nil || pos.CreatedAt.After(target) {
+			continue
+		}
+		if best == nil || pos.CreatedAt.After(best.CreatedAt) {
+			bestName, best = snapshotName, pos
+		}
+	}
+	if best == nil {
+		return "", nil, fmt.Errorf("no snapshot with a binlog position was taken before %s", target.Format("2006-01-02 15:04:05"))
+	}
+	return bestName, best, nil
+}
+
+var relativeTimeRe = regexp.MustCompile(`^(\d+)\s*(second|sec|s|minute|min|m|hour|h|day|d)s?\s+ago$`)
+
+// parseRestoreTarget parses the --to expression relative to now
+func parseRestoreTarget(expr string, now time.Time) (time.Time, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+
+	if m := relativeTimeRe.FindStringSubmatch(expr); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		var unit time.Duration
+		switch m[2] {
+		case "second", "sec", "s":
+			unit = time.Second
+		case "minute", "min", "m":
+			unit = time.Minute
+		case "hour", "h":
+			unit = time.Hour
+		case "day", "d":
+			unit = 24 * time.Hour
+		}
+		return now.Add(-time.Duration(n) * unit), nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, expr, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, expr, now.Location()); err == nil {
+			return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. \"10 minutes ago\", \"14:30\" or \"2024-05-01 14:30:00\")", expr)
+}
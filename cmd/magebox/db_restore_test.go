@@ -0,0 +1,109 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRestoreTarget(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"10 minutes ago", now.Add(-10 * time.Minute), false},
+		{"1 minute ago", now.Add(-time.Minute), false},
+		{"2 hours ago", now.Add(-2 * time.Hour), false},
+		{"30s ago", now.Add(-30 * time.Second), false},
+		{"1 day ago", now.Add(-24 * time.Hour), false},
+		{"14:30", time.Date(2024, 5, 1, 14, 30, 0, 0, time.Local), false},
+		{"14:30:15", time.Date(2024, 5, 1, 14, 30, 15, 0, time.Local), false},
+		{"2024-04-30 09:15", time.Date(2024, 4, 30, 9, 15, 0, 0, time.Local), false},
+		{"2024-04-30 09:15:42", time.Date(2024, 4, 30, 9, 15, 42, 0, time.Local), false},
+		{"yesterday", time.Time{}, true},
+		{"10 fortnights ago", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseRestoreTarget(tt.expr, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseRestoreTarget(%q) = %v, want error", tt.expr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRestoreTarget(%q) error: %v", tt.expr, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseRestoreTarget(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSnapshotName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"before-upgrade", false},
+		{"2024-05-01_14-30-00", false},
+		{"feature_checkout.v2", false},
+		{"", true},
+		{"..", true},
+		{"../other-project/dump", true},
+		{"nested/name", true},
+		{`windows\name`, true},
+		{".hidden", true},
+		{"with space", true},
+		{"name;rm", true},
+		{"dump\x00", true},
+		{"café", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSnapshotName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSnapshotName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteSnapshotPositionUsesDumpStart(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "before-upgrade.sql.gz")
+	f, err := os.Create(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte("-- MySQL dump 10.13\n-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='mysql-bin.000003', SOURCE_LOG_POS=157;\n")); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	f.Close()
+
+	startedAt := time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC)
+	if err := writeSnapshotPosition(snapshotPath, startedAt); err != nil {
+		t.Fatalf("writeSnapshotPosition failed: %v", err)
+	}
+
+	pos, err := readSnapshotPosition(snapshotPath)
+	if err != nil {
+		t.Fatalf("readSnapshotPosition failed: %v", err)
+	}
+	if !pos.CreatedAt.Equal(startedAt) {
+		t.Errorf("CreatedAt = %v, want the dump start %v", pos.CreatedAt, startedAt)
+	}
+	if pos.File != "mysql-bin.000003" || pos.Position != 157 {
+		t.Errorf("position = %s:%d, want mysql-bin.000003:157", pos.File, pos.Position)
+	}
+}
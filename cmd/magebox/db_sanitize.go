@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/dbimport"
+)
+
+// dbImportSanitize is --sanitize of 'magebox db import'
+var dbImportSanitize bool
+
+// shouldSanitize reports whether an import applies the sanitize ruleset:
+// --sanitize when given, sanitize.always otherwise. It prints an error when
+// sanitizing is asked for without rules.
+func shouldSanitize(cmd *cobra.Command, cfg *config.Config) (bool, bool) {
+	sanitize := cfg.Sanitize != nil && cfg.Sanitize.Always
+	if cmd.Flags().Changed("sanitize") {
+		sanitize = dbImportSanitize
+	}
+	if sanitize && cfg.Sanitize.IsEmpty() {
+		cli.PrintError("No sanitize rules in %s", config.ConfigFileName)
+		cli.PrintInfo("Add a sanitize section, e.g. customers: true and admin_password: admin123")
+		return false, false
+	}
+	return sanitize, true
+}
+
+// sanitizeDatabase applies the sanitize ruleset of the project to dbName
+func sanitizeDatabase(cfg *config.Config, cwd string, db *dbInfo, dbName string) error {
+	lines, err := rootQueryLines(db, fmt.Sprintf(
+		"SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = '%s'", dbName))
+	if err != nil {
+		return fmt.Errorf("failed to read the tables of %s: %w", dbName, err)
+	}
+	columns := make(map[string][]string)
+	for _, line := range lines {
+		if table, column, ok := strings.Cut(line, "\t"); ok {
+			columns[table] = append(columns[table], column)
+		}
+	}
+
+	sanitizer := &dbimport.Sanitizer{
+		Rules:   cfg.Sanitize,
+		Domains: cfg.Domains,
+		Prefix:  tablePrefix(cwd),
+		Columns: columns,
+	}
+
+	stmts, missing := sanitizer.Statements()
+	for _, table := range missing {
+		cli.PrintWarning("sanitize.truncate: table %s does not exist", table)
+	}
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	if err := execSQL(db, dbName, append([]string{"SET FOREIGN_KEY_CHECKS = 0"}, stmts...)); err != nil {
+		return fmt.Errorf("failed to sanitize database: %w", err)
+	}
+
+	var applied []string
+	rules := cfg.Sanitize
+	if rules.Customers {
+		applied = append(applied, "customer data replaced")
+	}
+	if rules.AdminPassword != "" {
+		applied = append(applied, "admin passwords reset")
+	}
+	if rules.BaseURLs {
+		applied = append(applied, "base URLs pointed at the project's domains")
+	}
+	if n := len(rules.Truncate) - len(missing); n > 0 {
+		applied = append(applied, fmt.Sprintf("%d table(s) emptied", n))
+	}
+	if len(rules.SQL) > 0 {
+		applied = append(applied, fmt.Sprintf("%d custom statement(s) run", len(rules.SQL)))
+	}
+	cli.PrintSuccess("Database sanitized: %s", strings.Join(applied, ", "))
+	if rules.Customers {
+		cli.PrintInfo("Rebuild the customer grid with: %s", cli.Command("bin/magento indexer:reindex customer_grid"))
+	}
+	return nil
+}
+
+// execSQL runs statements in one session of the mysql client in the database
+// container, stopping at the first failing one
+func execSQL(db *dbInfo, dbName string, stmts []string) error {
+	sqlCmd := exec.Command("docker", "exec", "-i", db.ContainerName,
+		"mysql", "-u"+db.User, "-p"+db.Password, dbName)
+	sqlCmd.Stdin = strings.NewReader(strings.Join(stmts, ";\n") + ";\n")
+	if out, err := sqlCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s", lastLine(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/dbverify"
+)
+
+var dbVerifyCmd = &cobra.Command{
+	Use:   "verify <manifest>",
+	Short: "Verify the database against an export manifest",
+	Long: `Compares the row count and checksum of every table in the project database
+with a manifest written by 'magebox db export --manifest', and reports tables
+that are missing or differ.
+
+Row count differences point at a truncated dump, checksum differences with
+the same row count at data changed on the way, e.g. by a charset conversion.
+Checksums are only compared when both databases run the same server version.
+
+Examples:
+  magebox db export --manifest dump.sql      # writes dump.sql.manifest.json
+  magebox db import dump.sql --verify        # import and verify in one go
+  magebox db verify dump.sql.manifest.json   # verify an earlier import`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDbVerify,
+}
+
+func init() {
+	dbCmd.AddCommand(dbVerifyCmd)
+}
+
+func runDbVerify(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	db, err := getDbInfo(cfg)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	return verifyDatabase(db, cfg.DatabaseName(), args[0])
+}
+
+// databaseManifest takes the row counts and checksums of every table in dbName
+func databaseManifest(db *dbInfo, dbName string) (*dbverify.Manifest, error) {
+	version, err := rootQueryLines(db, "SELECT VERSION()")
+	if err != nil || len(version) != 1 {
+		return nil, fmt.Errorf("failed to read the server version of %s", db.ContainerName)
+	}
+
+	tables, err := rootQueryLines(db, dbverify.TablesQuery(dbName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	if len(tables) == 0 {
+		return dbverify.Build(dbName, version[0], nil, nil)
+	}
+
+	counts, err := rootQueryLines(db, dbverify.CountQuery(dbName, tables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	checksums, err := rootQueryLines(db, dbverify.ChecksumQuery(dbName, tables))
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum tables: %w", err)
+	}
+	return dbverify.Build(dbName, version[0], counts, checksums)
+}
+
+// verifyDatabase compares dbName with the manifest at manifestPath, failing
+// when a table is missing or differs
+func verifyDatabase(db *dbInfo, dbName, manifestPath string) error {
+	expected, err := dbverify.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	fmt.Printf("Verifying database '%s' against %s... ", dbName, manifestPath)
+	actual, err := databaseManifest(db, dbName)
+	if err != nil {
+		fmt.Println(cli.Error("failed"))
+		return err
+	}
+
+	problems := dbverify.Compare(expected, actual)
+	if len(problems) == 0 {
+		fmt.Println(cli.Success("done"))
+		cli.PrintSuccess("All %d tables match", len(expected.Tables))
+	} else {
+		fmt.Println(cli.Error("failed"))
+		for _, p := range problems {
+			fmt.Printf("  %s %s: %s\n", cli.Error("✗"), cli.Highlight(p.Table), p.Message)
+		}
+	}
+	if !dbverify.ChecksumsComparable(expected, actual) {
+		cli.PrintWarning("Checksums not compared: exported from server %s, imported into %s", expected.Server, actual.Server)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d of %d tables differ from the export", len(problems), len(expected.Tables))
+	}
+	return nil
+}
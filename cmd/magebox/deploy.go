@@ -0,0 +1,240 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/deploy"
+	"qoliber/magebox/internal/remote"
+	"qoliber/magebox/internal/teamserver"
+)
+
+var deployDryRun bool
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy <environment>",
+	Short: "Deploy the project to a remote environment",
+	Long: `Runs the deployment pipeline of the project over SSH on a remote environment,
+streaming the output of every step. The pipeline stops at the first step
+that fails.
+
+The environment comes from the environments: block of .magebox.yaml, from
+'magebox env', or from the team server you joined (myproject/staging, or just
+staging). Deployments to team server environments are recorded in the
+server's audit log.
+
+Without a deploy: block, Magento projects run composer install,
+setup:upgrade, setup:di:compile, setup:static-content:deploy and cache:flush;
+Laravel projects run composer install, migrate and optimize.
+
+  deploy:
+    path: /var/www/shop              # for environments without a path
+    steps:
+      - composer install --no-dev --optimize-autoloader
+      - name: Upgrade
+        run: php bin/magento setup:upgrade --keep-generated
+      - name: Reindex
+        run: php bin/magento indexer:reindex
+        environments: [staging]      # only run on staging
+
+Examples:
+  magebox deploy staging
+  magebox deploy production --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDeploy,
+}
+
+func init() {
+	deployCmd.Flags().BoolVar(&deployDryRun, "dry-run", false, "Show the steps without running them")
+	rootCmd.AddCommand(deployCmd)
+}
+
+// deployTarget is the environment a deployment runs on
+type deployTarget struct {
+	env  *remote.Environment
+	name string        // Name the deploy steps' environments: refer to
+	team *clientConfig // Session of the team server the environment comes from
+}
+
+func runDeploy(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	target, err := findDeployTarget(cfg, args[0])
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	env := target.env
+	if env.Path == "" && cfg.Deploy != nil {
+		env.Path = cfg.Deploy.Path
+	}
+	if env.Path == "" {
+		cli.PrintError("Environment '%s' has no path, set it on the environment or as deploy.path", env.Name)
+		return nil
+	}
+
+	steps := cfg.DeploySteps(target.name)
+	if len(steps) == 0 {
+		cli.PrintWarning("No deploy steps run on %s", env.Name)
+		return nil
+	}
+
+	cli.PrintTitle("Deploying %s to %s", cfg.Name, env.Name)
+	fmt.Println()
+	fmt.Printf("  Host: %s\n", cli.Highlight(env.GetConnectionString()))
+	fmt.Printf("  Path: %s\n", cli.Highlight(env.Path))
+	fmt.Println()
+
+	if deployDryRun {
+		fmt.Println(cli.Warning("DRY RUN - No changes will be made"))
+		fmt.Println()
+		for i, step := range steps {
+			fmt.Printf("  %d. %s\n", i+1, step.Name)
+			if step.Run != step.Name {
+				fmt.Printf("     %s\n", step.Run)
+			}
+		}
+		return nil
+	}
+
+	stepNames := make([]string, len(steps))
+	for i, step := range steps {
+		stepNames[i] = step.Name
+	}
+	target.report(teamserver.DeploymentReport{Environment: env.Name, Status: teamserver.DeployStarted, Steps: stepNames})
+
+	runner := deploy.NewRunner(env)
+	runner.OnStep = func(i, total int, step config.DeployStep) {
+		if i > 1 {
+			fmt.Println()
+		}
+		fmt.Printf("%s %s\n", cli.Highlight(fmt.Sprintf("[%d/%d]", i, total)), step.Name)
+		events.Step("deploy", int64(i), int64(total), step.Name)
+	}
+
+	var result *deploy.Result
+	err = target.withKey(func() error {
+		var runErr error
+		result, runErr = runner.Run(steps)
+		return runErr
+	})
+	if result == nil {
+		// The SSH key couldn't be written, no step ran
+		target.report(teamserver.DeploymentReport{Environment: env.Name, Status: teamserver.DeployFailed})
+		return err
+	}
+	duration := result.Duration.Round(time.Second).String()
+	fmt.Println()
+	if err != nil {
+		failed := result.Failed()
+		target.report(teamserver.DeploymentReport{Environment: env.Name, Status: teamserver.DeployFailed, FailedStep: failed.Name, Duration: duration})
+		err = fmt.Errorf("deployment to %s failed after %s: %w", env.Name, duration, err)
+		events.Fail(err)
+		return err
+	}
+
+	target.report(teamserver.DeploymentReport{Environment: env.Name, Status: teamserver.DeploySucceeded, Duration: duration})
+	cli.PrintSuccess("Deployed to %s in %s", env.Name, duration)
+	events.Done("Deployed to " + env.Name)
+	return nil
+}
+
+// findDeployTarget looks an environment up in the project and global
+// environments, then in the team server session
+func findDeployTarget(cfg *config.Config, name string) (*deployTarget, error) {
+	if env, err := findEnvironment(name); err == nil {
+		return &deployTarget{env: env, name: env.Name}, nil
+	}
+
+	session, err := loadClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("environment '%s' not found", name)
+	}
+	teamEnv := findClientEnvironment(session, name, cfg.Name)
+	if teamEnv == nil {
+		return nil, fmt.Errorf("environment '%s' not found (refresh team server environments with: magebox team env list)", name)
+	}
+
+	_, shortName, _ := strings.Cut(teamEnv.Name, "/")
+	return &deployTarget{
+		env: &remote.Environment{
+			Name: teamEnv.Name,
+			User: teamEnv.DeployUser,
+			Host: teamEnv.Host,
+			Port: teamEnv.Port,
+		},
+		name: shortName,
+		team: session,
+	}, nil
+}
+
+// withKey runs fn with the SSH key of a team server environment written to
+// a temporary file, other environments bring their own key
+func (t *deployTarget) withKey(fn func() error) error {
+	if t.team == nil {
+		return fn()
+	}
+	return withTeamKeyFile(t.team, func(keyFile string) error {
+		t.env.SSHKeyPath = keyFile
+		defer func() { t.env.SSHKeyPath = "" }()
+		return fn()
+	})
+}
+
+// report sends a deployment report to the team server of the environment.
+// A failed report doesn't stop the deployment.
+func (t *deployTarget) report(report teamserver.DeploymentReport) {
+	if t.team == nil {
+		return
+	}
+	if err := sendDeploymentReport(t.team, report); err != nil {
+		cli.PrintWarning("Failed to record the deployment on %s: %v", t.team.ServerURL, err)
+	}
+}
+
+// sendDeploymentReport posts a deployment report to the team server
+func sendDeploymentReport(session *clientConfig, report teamserver.DeploymentReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", session.ServerURL+"/api/deployments", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+session.SessionToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("%s", errResp.Error)
+	}
+	return nil
+}
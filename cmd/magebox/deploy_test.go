@@ -0,0 +1,41 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"qoliber/magebox/internal/teamserver"
+)
+
+func TestSendDeploymentReport(t *testing.T) {
+	var got teamserver.DeploymentReport
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/deployments" || r.Header.Get("Authorization") != "Bearer session" {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(teamserver.ErrorResponse{Error: "No access to this project"})
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_ = json.NewEncoder(w).Encode(teamserver.SuccessResponse{Success: true})
+	}))
+	defer srv.Close()
+
+	session := &clientConfig{ServerURL: srv.URL, SessionToken: "session"}
+	report := teamserver.DeploymentReport{Environment: "shop/staging", Status: teamserver.DeployFailed, FailedStep: "DI compile"}
+	if err := sendDeploymentReport(session, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Environment != "shop/staging" || got.Status != teamserver.DeployFailed || got.FailedStep != "DI compile" {
+		t.Errorf("server got %+v", got)
+	}
+
+	session.SessionToken = "expired"
+	if err := sendDeploymentReport(session, report); err == nil || err.Error() != "No access to this project" {
+		t.Errorf("err = %v, want the server's error", err)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+)
+
+var destroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "Remove everything MageBox created for the project",
+	Long: `Removes what MageBox created for the project: nginx vhosts, the PHP-FPM pool,
+SSL certificates, hosts entries, databases and Docker volumes. The project files
+and .magebox.yaml are left alone.
+
+Without flags everything but the Docker volumes is removed and the project is
+forgotten by MageBox. Select parts with the flags to tear down only those.
+
+Certificates other projects still use are kept. --volumes removes the volume
+of the database service, which is shared, only when no other known project
+uses the service and it holds no databases but the project's.
+
+Examples:
+  magebox destroy --dry-run        # Show what would be removed
+  magebox destroy                  # Remove everything but volumes, after confirmation
+  magebox destroy --vhosts --dns   # Only the vhosts, pool and hosts entries
+  magebox destroy --volumes -y     # Only the database volume, without asking`,
+	Args: cobra.NoArgs,
+	RunE: runDestroy,
+}
+
+var (
+	destroyVhosts    bool
+	destroyCerts     bool
+	destroyDNS       bool
+	destroyDatabases bool
+	destroyVolumes   bool
+	destroyDryRun    bool
+	destroyYes       bool
+)
+
+func init() {
+	destroyCmd.Flags().BoolVar(&destroyVhosts, "vhosts", false, "Remove the nginx vhosts and PHP-FPM pool")
+	destroyCmd.Flags().BoolVar(&destroyCerts, "certs", false, "Remove the SSL certificates")
+	destroyCmd.Flags().BoolVar(&destroyDNS, "dns", false, "Remove the hosts entries")
+	destroyCmd.Flags().BoolVar(&destroyDatabases, "databases", false, "Drop the project databases")
+	destroyCmd.Flags().BoolVar(&destroyVolumes, "volumes", false, "Remove the volume of the database service if it holds only the project's databases")
+	destroyCmd.Flags().BoolVar(&destroyDryRun, "dry-run", false, "Show what would be removed without changing anything")
+	destroyCmd.Flags().BoolVarP(&destroyYes, "yes", "y", false, "Skip confirmation")
+	rootCmd.AddCommand(destroyCmd)
+}
+
+func runDestroy(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	others, err := otherProjectConfigs(p, cwd)
+	if err != nil {
+		cli.PrintError("Failed to discover projects: %v", err)
+		return nil
+	}
+
+	opts := project.DestroyOptions{
+		Vhosts:    destroyVhosts,
+		Certs:     destroyCerts,
+		DNS:       destroyDNS,
+		Databases: destroyDatabases,
+		Volumes:   destroyVolumes,
+		Others:    others,
+	}
+	if !destroyVhosts && !destroyCerts && !destroyDNS && !destroyDatabases && !destroyVolumes {
+		opts = project.DestroyAll(others)
+	}
+
+	mgr := project.NewManager(p)
+	plan := mgr.DestroyPlan(cfg, opts)
+
+	if destroyDryRun {
+		if structuredOutput() {
+			return printStructured(plan)
+		}
+		cli.PrintTitle("Dry Run: Would destroy %s", cfg.Name)
+		fmt.Println()
+		printDestroyActions(plan)
+		return nil
+	}
+
+	if len(plan) == 0 {
+		if structuredOutput() {
+			return printStructured(plan)
+		}
+		cli.PrintInfo("Nothing to remove for %s", cfg.Name)
+		return nil
+	}
+
+	if !destroyYes {
+		cli.PrintTitle("Destroy %s", cfg.Name)
+		fmt.Println()
+		printDestroyActions(plan)
+		fmt.Println()
+		cli.PrintWarning("This permanently removes the items above!")
+		fmt.Print("Are you sure? [y/N]: ")
+
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			cli.PrintInfo("Aborted")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	// Consumers would keep using the pool and databases
+	if opts.Vhosts {
+		stopQueueConsumers(p, cfg.Name)
+	}
+
+	actions := mgr.Destroy(cfg, opts)
+	if structuredOutput() {
+		return printStructured(actions)
+	}
+
+	failed := 0
+	for _, a := range actions {
+		if a.Error != "" {
+			failed++
+			fmt.Printf("  %s\n", cli.Error(fmt.Sprintf("%-12s %s: %s", a.Kind, a.Target, a.Error)))
+		} else {
+			fmt.Printf("  %s\n", cli.Success(fmt.Sprintf("%-12s %s", a.Kind, a.Target)))
+		}
+	}
+	fmt.Println()
+	if failed > 0 {
+		cli.PrintWarning("Destroyed %s with %d error(s)", cfg.Name, failed)
+		return nil
+	}
+	cli.PrintSuccess("Destroyed %s", cfg.Name)
+	return nil
+}
+
+// printDestroyActions lists what destroy removes
+func printDestroyActions(actions []project.DestroyAction) {
+	if len(actions) == 0 {
+		cli.PrintInfo("Nothing to remove")
+		return
+	}
+	for _, a := range actions {
+		target := a.Target
+		if a.Service != "" {
+			target += " (" + a.Service + ")"
+		}
+		fmt.Printf("  %-14s %s\n", a.Kind, target)
+	}
+}
+
+// otherProjectConfigs loads the configs of the running and known projects
+// other than the one in dir
+func otherProjectConfigs(p *platform.Platform, dir string) ([]*config.Config, error) {
+	projects, err := project.NewProjectDiscovery(p).AllProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var others []*config.Config
+	for _, proj := range projects {
+		if !proj.HasConfig || proj.Path == dir {
+			continue
+		}
+		cfg, err := config.LoadFromPath(proj.Path)
+		if err != nil {
+			continue
+		}
+		others = append(others, cfg)
+	}
+	return others, nil
+}
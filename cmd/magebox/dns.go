@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -10,6 +11,7 @@ import (
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/dns"
 	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
 )
 
 var dnsCmd = &cobra.Command{
@@ -25,7 +27,10 @@ var dnsSetupCmd = &cobra.Command{
 
 This eliminates the need to add each domain to /etc/hosts manually.
 Requires dnsmasq to be installed first.
-The TLD used is configured via 'magebox config set tld <value>' (default: test).`,
+The TLD used is configured via 'magebox config set tld <value>' (default: test).
+Additional TLDs from 'magebox config set tlds <a,b>' and the tld of every
+registered project that sets its own are routed as well, so e.g. .test and
+.localhost coexist.`,
 	RunE: runDnsSetup,
 }
 
@@ -36,9 +41,33 @@ var dnsStatusCmd = &cobra.Command{
 	RunE:  runDnsStatus,
 }
 
+var dnsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconcile the DNS entries of project domains",
+	Long: `Makes sure the domains of the project resolve to this machine.
+
+In hosts mode the domains missing from /etc/hosts are added; the file is left
+alone when nothing is missing. In dnsmasq mode the wildcard resolution of every
+domain is verified, and domains that don't resolve (e.g. outside the TLD) are
+reported.
+
+'magebox start' does the same for the project it starts, and stopping a
+project or removing a domain cleans its hosts entries up again.
+
+Examples:
+  magebox dns sync           # Domains of the current project
+  magebox dns sync --all     # Domains of all running projects`,
+	Args: cobra.NoArgs,
+	RunE: runDnsSync,
+}
+
+var dnsSyncAll bool
+
 func init() {
+	dnsSyncCmd.Flags().BoolVar(&dnsSyncAll, "all", false, "Sync the domains of all running projects")
 	dnsCmd.AddCommand(dnsSetupCmd)
 	dnsCmd.AddCommand(dnsStatusCmd)
+	dnsCmd.AddCommand(dnsSyncCmd)
 	rootCmd.AddCommand(dnsCmd)
 }
 
@@ -48,10 +77,16 @@ func runDnsSetup(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Load global config for TLD
+	// Load global config for TLD, routing the TLDs projects set as well
 	homeDir, _ := os.UserHomeDir()
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
 	tld := globalCfg.GetTLD()
+	if projects, err := project.NewProjectDiscovery(p).DiscoverProjects(); err == nil {
+		for _, projectTLD := range project.ProjectTLDs(projects) {
+			globalCfg.AddTLD(projectTLD)
+		}
+	}
+	zones := "*." + strings.Join(globalCfg.GetTLDs(), ", *.")
 
 	dnsMgr := dns.NewDnsmasqManager(p)
 
@@ -63,7 +98,13 @@ func runDnsSetup(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	cli.PrintInfo("Setting up dnsmasq for *.%s domain resolution...", tld)
+	cli.PrintInfo("Setting up dnsmasq for %s domain resolution...", zones)
+
+	// Save the TLDs dnsmasq is configured from
+	if err := config.SaveGlobalConfig(homeDir, globalCfg); err != nil {
+		cli.PrintError("Failed to save config: %v", err)
+		return nil
+	}
 
 	// Configure dnsmasq
 	if err := dnsMgr.Configure(); err != nil {
@@ -90,7 +131,7 @@ func runDnsSetup(cmd *cobra.Command, args []string) error {
 
 	cli.PrintSuccess("dnsmasq configured successfully!")
 	fmt.Println()
-	cli.PrintInfo("All *.%s domains now resolve to 127.0.0.1", tld)
+	cli.PrintInfo("All %s domains now resolve to 127.0.0.1", zones)
 	fmt.Println(cli.Bullet("No need to edit /etc/hosts for new projects"))
 
 	// Show test command with correct DNS server address
@@ -103,26 +144,46 @@ func runDnsSetup(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// dnsStatusOutput is the DNS status printed by dns status --output json|yaml
+type dnsStatusOutput struct {
+	Mode    string            `json:"mode"`
+	TLD     string            `json:"tld"`
+	TLDs    []string          `json:"tlds"`
+	Dnsmasq dns.DnsmasqStatus `json:"dnsmasq"`
+}
+
 func runDnsStatus(cmd *cobra.Command, args []string) error {
 	p, err := getPlatform()
 	if err != nil {
 		return err
 	}
 
-	cli.PrintTitle("DNS Configuration Status")
-	fmt.Println()
-
 	// Check global config
 	homeDir, _ := os.UserHomeDir()
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
 
-	fmt.Printf("DNS Mode:      %s\n", cli.Highlight(globalCfg.DNSMode))
-	fmt.Printf("TLD:           %s\n", cli.Highlight(globalCfg.GetTLD()))
-
 	// Check dnsmasq status
 	dnsMgr := dns.NewDnsmasqManager(p)
 	status := dnsMgr.GetStatus()
 
+	if structuredOutput() {
+		return printStructured(dnsStatusOutput{
+			Mode:    globalCfg.DNSMode,
+			TLD:     globalCfg.GetTLD(),
+			TLDs:    globalCfg.GetTLDs(),
+			Dnsmasq: status,
+		})
+	}
+
+	cli.PrintTitle("DNS Configuration Status")
+	fmt.Println()
+
+	fmt.Printf("DNS Mode:      %s\n", cli.Highlight(globalCfg.DNSMode))
+	fmt.Printf("TLD:           %s\n", cli.Highlight(globalCfg.GetTLD()))
+	if tlds := globalCfg.GetTLDs(); len(tlds) > 1 {
+		fmt.Printf("Also routed:   %s\n", cli.Highlight(strings.Join(tlds[1:], ", ")))
+	}
+
 	fmt.Println(cli.Header("dnsmasq"))
 	fmt.Printf("  %-14s %s\n", "Installed:", cli.StatusInstalled(status.Installed))
 	fmt.Printf("  %-14s %s\n", "Configured:", cli.StatusInstalled(status.Configured))
@@ -144,3 +205,99 @@ func runDnsStatus(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runDnsSync(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	var domains []string
+	if dnsSyncAll {
+		projects, err := project.NewProjectDiscovery(p).DiscoverProjects()
+		if err != nil {
+			return fmt.Errorf("failed to discover projects: %w", err)
+		}
+		for _, proj := range projects {
+			domains = append(domains, proj.Domains...)
+		}
+	} else {
+		cwd, err := getCwd()
+		if err != nil {
+			return err
+		}
+		cfg, ok := loadProjectConfig(cwd)
+		if !ok {
+			return nil
+		}
+		domains = cfg.Hosts()
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	globalCfg, _ := config.LoadGlobalConfig(homeDir)
+
+	result, err := dns.NewSyncer(p).Sync(globalCfg, domains)
+	if err != nil {
+		cli.PrintError("Failed to sync DNS: %v", err)
+		return nil
+	}
+
+	if structuredOutput() {
+		return printStructured(result)
+	}
+
+	cli.PrintTitle("DNS Sync")
+	fmt.Printf("DNS Mode:      %s\n", cli.Highlight(result.Mode))
+	fmt.Println()
+
+	if len(domains) == 0 {
+		cli.PrintInfo("No domains to sync")
+		return nil
+	}
+
+	added := make(map[string]bool)
+	for _, d := range result.Added {
+		added[d] = true
+	}
+	unresolved := make(map[string]bool)
+	for _, d := range result.Unresolved {
+		unresolved[d] = true
+	}
+	for _, d := range domains {
+		switch {
+		case added[d]:
+			fmt.Println("  " + cli.Success(d+" (added to "+p.HostsFilePath()+")"))
+		case unresolved[d]:
+			fmt.Println("  " + cli.Error(d+" (not resolving)"))
+		default:
+			fmt.Println("  " + cli.Success(d))
+		}
+	}
+
+	if len(result.Unresolved) > 0 {
+		fmt.Println()
+		cli.PrintWarning("%d domain(s) don't resolve to this machine", len(result.Unresolved))
+		fmt.Println(cli.Bullet("Check dnsmasq with " + cli.Command("magebox dns status")))
+		fmt.Println(cli.Bullet("Domains outside ." + strings.Join(globalCfg.GetTLDs(), ", .") + " need an entry in " + p.HostsFilePath()))
+	}
+	return nil
+}
+
+// syncDomainsDNS adds the domains missing from the hosts file in hosts mode
+// and warns about domains that don't resolve in dnsmasq mode
+func syncDomainsDNS(p *platform.Platform, domains []string) {
+	homeDir, _ := os.UserHomeDir()
+	globalCfg, _ := config.LoadGlobalConfig(homeDir)
+
+	result, err := dns.NewSyncer(p).Sync(globalCfg, domains)
+	if err != nil {
+		cli.PrintWarning("Failed to update hosts: %v", err)
+		return
+	}
+	if len(result.Added) > 0 {
+		fmt.Printf("Added %s to %s\n", strings.Join(result.Added, ", "), p.HostsFilePath())
+	}
+	if len(result.Unresolved) > 0 {
+		cli.PrintWarning("Not resolving to this machine: %s", strings.Join(result.Unresolved, ", "))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/dns"
 	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/project"
 	"qoliber/magebox/internal/ssl"
 )
 
@@ -44,6 +45,31 @@ Example:
 	RunE: runDomainRemove,
 }
 
+var domainImportCmd = &cobra.Command{
+	Use:   "import <file.csv>",
+	Short: "Add domains from a CSV file",
+	Long: `Adds the domains listed in a CSV file to the current project's .magebox
+configuration, then generates SSL certificates, regenerates the nginx vhosts
+and reloads nginx once for all of them.
+
+The CSV has host, store_code, run_type and ssl columns. The header row is
+optional, and store exports with code and base_url columns work too; the
+scheme of a base URL decides SSL unless the ssl column is set. Domains that
+are already configured are skipped.
+
+Example CSV:
+  host,store_code,run_type,ssl
+  shop.de.test,german,store,true
+  shop.fr.test,french,store,true
+  b2b.shop.test,b2b,website,false
+
+Example:
+  magebox domain import stores.csv
+  magebox domain import stores.csv --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDomainImport,
+}
+
 var domainListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List project domains",
@@ -55,6 +81,7 @@ var (
 	domainStoreCode string
 	domainRoot      string
 	domainSSL       bool
+	domainDryRun    bool
 )
 
 func init() {
@@ -62,7 +89,10 @@ func init() {
 	domainAddCmd.Flags().StringVar(&domainRoot, "root", "", "Document root relative to project (default: \"pub\" for Magento, \"public\" for Laravel)")
 	domainAddCmd.Flags().BoolVar(&domainSSL, "ssl", true, "Enable SSL for the domain")
 
+	domainImportCmd.Flags().BoolVar(&domainDryRun, "dry-run", false, "Show the domains that would be added without changing anything")
+
 	domainCmd.AddCommand(domainAddCmd)
+	domainCmd.AddCommand(domainImportCmd)
 	domainCmd.AddCommand(domainRemoveCmd)
 	domainCmd.AddCommand(domainListCmd)
 	rootCmd.AddCommand(domainCmd)
@@ -105,6 +135,15 @@ func runDomainAdd(cmd *cobra.Command, args []string) error {
 
 	cfg.Domains = append(cfg.Domains, newDomain)
 
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+	sslManager := ssl.NewManager(p)
+	vhostGen := nginx.NewVhostGenerator(p, sslManager)
+	recorder.Track(filepath.Join(cwd, config.ConfigFileName))
+	recorder.Track(filepath.Join(vhostGen.VhostsDir(), fmt.Sprintf("%s-%s.conf", cfg.Name, host)))
+
 	// Save config
 	if err := config.SaveToPath(cfg, cwd); err != nil {
 		cli.PrintError("Failed to save config: %v", err)
@@ -113,14 +152,6 @@ func runDomainAdd(cmd *cobra.Command, args []string) error {
 
 	cli.PrintSuccess("Added domain: %s", host)
 
-	// Generate SSL certificate and nginx vhost
-	p, err := getPlatform()
-	if err != nil {
-		return err
-	}
-
-	sslManager := ssl.NewManager(p)
-
 	// Generate SSL certificate for the new domain
 	if newDomain.IsSSLEnabled() {
 		fmt.Println("Generating SSL certificate...")
@@ -131,25 +162,12 @@ func runDomainAdd(cmd *cobra.Command, args []string) error {
 
 	// Regenerate vhosts
 	fmt.Println("Regenerating nginx vhosts...")
-	vhostGen := nginx.NewVhostGenerator(p, sslManager)
 	if err := vhostGen.Generate(cfg, cwd); err != nil {
 		cli.PrintWarning("Failed to regenerate vhosts: %v", err)
 	}
 
 	// Update DNS (hosts file)
-	homeDir, _ := os.UserHomeDir()
-	globalCfg, _ := config.LoadGlobalConfig(homeDir)
-	if globalCfg.UseHosts() {
-		fmt.Println("Updating /etc/hosts...")
-		hostsManager := dns.NewHostsManager(p)
-		domains := make([]string, len(cfg.Domains))
-		for i, d := range cfg.Domains {
-			domains[i] = d.Host
-		}
-		if err := hostsManager.AddDomains(domains); err != nil {
-			cli.PrintWarning("Failed to update hosts: %v", err)
-		}
-	}
+	syncDomainsDNS(p, cfg.Hosts())
 
 	// Reload nginx
 	fmt.Println("Reloading nginx...")
@@ -168,6 +186,104 @@ func runDomainAdd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runDomainImport(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		cli.PrintError("Failed to load config: %v", err)
+		return nil
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		cli.PrintError("Failed to open %s: %v", args[0], err)
+		return nil
+	}
+	imported, err := config.ParseDomainsCSV(file)
+	file.Close()
+	if err != nil {
+		cli.PrintError("Failed to read %s: %v", args[0], err)
+		return nil
+	}
+
+	existing := make(map[string]bool, len(cfg.Domains))
+	for _, d := range cfg.Domains {
+		existing[d.Host] = true
+	}
+
+	var added []config.Domain
+	for _, d := range imported {
+		if existing[d.Host] {
+			fmt.Printf("  %s %s %s\n", cli.Warning("-"), d.Host, cli.Dim+"(already configured)"+cli.Reset)
+			continue
+		}
+		sslStatus := "SSL"
+		if !d.IsSSLEnabled() {
+			sslStatus = "HTTP"
+		}
+		fmt.Printf("  %s %-40s %s %s %s\n", cli.Success("+"), d.Host, d.GetStoreCode(), d.GetMageRunType(), sslStatus)
+		added = append(added, d)
+	}
+
+	if len(added) == 0 {
+		cli.PrintInfo("All %d domains are already configured", len(imported))
+		return nil
+	}
+	if domainDryRun {
+		fmt.Println()
+		cli.PrintInfo("Dry run: %d domains would be added", len(added))
+		return nil
+	}
+
+	cfg.Domains = append(cfg.Domains, added...)
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+	vhostsDir := nginx.NewVhostGenerator(p, ssl.NewManager(p)).VhostsDir()
+	recorder.Track(filepath.Join(cwd, config.ConfigFileName))
+	for _, d := range added {
+		recorder.Track(filepath.Join(vhostsDir, fmt.Sprintf("%s-%s.conf", cfg.Name, d.Host)))
+	}
+
+	if err := config.SaveToPath(cfg, cwd); err != nil {
+		cli.PrintError("Failed to save config: %v", err)
+		return nil
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("Added %d domains", len(added))
+
+	// Certificates and vhosts for all domains at once
+	fmt.Println("Generating SSL certificates and nginx vhosts...")
+	if err := project.NewManager(p).RegenerateDomains(cwd); err != nil {
+		cli.PrintWarning("%v", err)
+	}
+
+	hosts := make([]string, len(added))
+	for i, d := range added {
+		hosts[i] = d.Host
+	}
+	syncDomainsDNS(p, hosts)
+
+	fmt.Println("Reloading nginx...")
+	ngxController := nginx.NewController(p)
+	if err := ngxController.Test(); err != nil {
+		cli.PrintError("Nginx config test failed: %v", err)
+		return nil
+	}
+	if err := ngxController.Reload(); err != nil {
+		cli.PrintWarning("Failed to reload nginx: %v", err)
+	}
+
+	return nil
+}
+
 func runDomainRemove(cmd *cobra.Command, args []string) error {
 	cwd, err := getCwd()
 	if err != nil {
@@ -205,6 +321,16 @@ func runDomainRemove(cmd *cobra.Command, args []string) error {
 
 	cfg.Domains = newDomains
 
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+	sslManager := ssl.NewManager(p)
+	vhostGen := nginx.NewVhostGenerator(p, sslManager)
+	vhostFile := filepath.Join(vhostGen.VhostsDir(), fmt.Sprintf("%s-%s.conf", cfg.Name, host))
+	recorder.Track(filepath.Join(cwd, config.ConfigFileName))
+	recorder.Track(vhostFile)
+
 	// Save config
 	if err := config.SaveToPath(cfg, cwd); err != nil {
 		cli.PrintError("Failed to save config: %v", err)
@@ -213,19 +339,8 @@ func runDomainRemove(cmd *cobra.Command, args []string) error {
 
 	cli.PrintSuccess("Removed domain: %s", host)
 
-	// Regenerate vhosts (will remove the old vhost file)
-	p, err := getPlatform()
-	if err != nil {
-		return err
-	}
-
-	sslManager := ssl.NewManager(p)
-	vhostGen := nginx.NewVhostGenerator(p, sslManager)
-
 	// Remove the specific vhost file
 	fmt.Println("Removing nginx vhost...")
-	vhostsDir := vhostGen.VhostsDir()
-	vhostFile := filepath.Join(vhostsDir, fmt.Sprintf("%s-%s.conf", cfg.Name, host))
 	if err := os.Remove(vhostFile); err != nil && !os.IsNotExist(err) {
 		cli.PrintWarning("Failed to remove vhost file: %v", err)
 	}
@@ -235,8 +350,7 @@ func runDomainRemove(cmd *cobra.Command, args []string) error {
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
 	if globalCfg.UseHosts() {
 		fmt.Println("Removing from /etc/hosts...")
-		hostsManager := dns.NewHostsManager(p)
-		if err := hostsManager.RemoveDomains([]string{host}); err != nil {
+		if err := dns.NewSyncer(p).Remove(globalCfg, []string{host}); err != nil {
 			cli.PrintWarning("Failed to remove %s from hosts: %v", host, err)
 		}
 	}
@@ -255,6 +369,15 @@ func runDomainRemove(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// domainOutput is a domain as printed by domain list --output json|yaml
+type domainOutput struct {
+	Host      string `json:"host"`
+	URL       string `json:"url"`
+	Root      string `json:"root"`
+	StoreCode string `json:"store_code"`
+	SSL       bool   `json:"ssl"`
+}
+
 func runDomainList(cmd *cobra.Command, args []string) error {
 	cwd, err := getCwd()
 	if err != nil {
@@ -263,10 +386,31 @@ func runDomainList(cmd *cobra.Command, args []string) error {
 
 	cfg, err := config.LoadFromPath(cwd)
 	if err != nil {
+		if structuredOutput() {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
 		cli.PrintError("Failed to load config: %v", err)
 		return nil
 	}
 
+	if structuredOutput() {
+		domains := make([]domainOutput, 0, len(cfg.Domains))
+		for _, d := range cfg.Domains {
+			protocol := "https"
+			if !d.IsSSLEnabled() {
+				protocol = "http"
+			}
+			domains = append(domains, domainOutput{
+				Host:      d.Host,
+				URL:       protocol + "://" + d.Host,
+				Root:      d.GetRoot(),
+				StoreCode: d.GetStoreCode(),
+				SSL:       d.IsSSLEnabled(),
+			})
+		}
+		return printStructured(domains)
+	}
+
 	cli.PrintTitle("Project Domains: %s", cfg.Name)
 	fmt.Println()
 
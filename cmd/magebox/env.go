@@ -4,18 +4,13 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/remote"
-	"qoliber/magebox/internal/teamserver"
 
 	"github.com/spf13/cobra"
 )
@@ -26,7 +21,11 @@ var envCmd = &cobra.Command{
 	Long: `Manage remote server environments for SSH access.
 
 Environments are stored globally and can be used to quickly SSH into remote servers.
-Supports custom SSH keys and SSH tunnel configurations.`,
+Supports custom SSH keys and SSH tunnel configurations.
+
+Projects can also define environments in the environments: block of .magebox.yaml
+(with personal overrides in .magebox.local.yaml). Inside a project directory these
+take precedence over global environments with the same name.`,
 	RunE: runEnvList,
 }
 
@@ -64,6 +63,19 @@ Example:
 	RunE: runEnvSSH,
 }
 
+var envTunnelCmd = &cobra.Command{
+	Use:   "tunnel <name>",
+	Short: "Forward a local port to the database of a remote environment",
+	Long: `Opens an SSH port forward to the database of a remote environment, using the
+db settings of the environment (host and port as seen from the remote server).
+
+Example:
+  magebox env tunnel staging
+  magebox env tunnel production --port 33307`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEnvTunnel,
+}
+
 var envShowCmd = &cobra.Command{
 	Use:   "show <name>",
 	Short: "Show details of a remote environment",
@@ -91,6 +103,8 @@ var (
 	envPort       int
 	envSSHKey     string
 	envSSHCommand string
+
+	envTunnelPort int
 )
 
 func init() {
@@ -99,6 +113,7 @@ func init() {
 	envCmd.AddCommand(envRemoveCmd)
 	envCmd.AddCommand(envSSHCmd)
 	envCmd.AddCommand(envShowCmd)
+	envCmd.AddCommand(envTunnelCmd)
 	envCmd.AddCommand(envSyncCmd)
 
 	// Add flags to env add
@@ -108,6 +123,8 @@ func init() {
 	envAddCmd.Flags().StringVarP(&envSSHKey, "key", "k", "", "Path to SSH private key")
 	envAddCmd.Flags().StringVar(&envSSHCommand, "ssh-command", "", "Custom SSH command (for tunnels/jump hosts)")
 
+	envTunnelCmd.Flags().IntVar(&envTunnelPort, "port", 33306, "Local port to listen on")
+
 	// Add to root command
 	rootCmd.AddCommand(envCmd)
 }
@@ -123,7 +140,19 @@ func runEnvList(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	projectEnvs := projectEnvironments()
+	if len(projectEnvs) > 0 {
+		fmt.Println("Project Environments:")
+		fmt.Println()
+		printEnvironmentTable(projectEnvs)
+		fmt.Println()
+	}
+
 	envs := globalCfg.Environments
+	if len(envs) == 0 && len(projectEnvs) > 0 {
+		fmt.Println("SSH into an environment with: magebox env ssh <name>")
+		return nil
+	}
 	if len(envs) == 0 {
 		fmt.Println("No remote environments configured.")
 		fmt.Println()
@@ -134,7 +163,16 @@ func runEnvList(_ *cobra.Command, _ []string) error {
 
 	fmt.Println("Remote Environments:")
 	fmt.Println()
+	printEnvironmentTable(envs)
 
+	fmt.Println()
+	fmt.Println("SSH into an environment with: magebox env ssh <name>")
+
+	return nil
+}
+
+// printEnvironmentTable prints environments as a NAME/CONNECTION table
+func printEnvironmentTable(envs []remote.Environment) {
 	// Calculate column widths
 	maxName := 4  // "NAME"
 	maxConn := 10 // "CONNECTION"
@@ -156,11 +194,42 @@ func runEnvList(_ *cobra.Command, _ []string) error {
 	for _, env := range envs {
 		fmt.Printf("  %-*s  %s\n", maxName, env.Name, env.GetConnectionString())
 	}
+}
 
-	fmt.Println()
-	fmt.Println("SSH into an environment with: magebox env ssh <name>")
+// projectEnvironments returns the environments defined by the project in the
+// current directory, if any
+func projectEnvironments() []remote.Environment {
+	cwd, err := getCwd()
+	if err != nil {
+		return nil
+	}
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		return nil
+	}
+	return cfg.Environments
+}
 
-	return nil
+// findEnvironment looks an environment up in the current project first and
+// in the global configuration second
+func findEnvironment(name string) (*remote.Environment, error) {
+	for _, env := range projectEnvironments() {
+		if env.Name == name {
+			return &env, nil
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	globalCfg, err := config.LoadGlobalConfig(homeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return globalCfg.GetEnvironment(name)
 }
 
 func runEnvAdd(_ *cobra.Command, args []string) error {
@@ -270,17 +339,7 @@ func runEnvRemove(_ *cobra.Command, args []string) error {
 func runEnvSSH(_ *cobra.Command, args []string) error {
 	name := args[0]
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	globalCfg, err := config.LoadGlobalConfig(homeDir)
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	env, err := globalCfg.GetEnvironment(name)
+	env, err := findEnvironment(name)
 	if err != nil {
 		return err
 	}
@@ -288,6 +347,10 @@ func runEnvSSH(_ *cobra.Command, args []string) error {
 	fmt.Printf("Connecting to %s...\n", name)
 
 	cmd := env.BuildSSHCommand()
+	if env.Path != "" {
+		// Open a login shell in the application directory
+		cmd = env.BuildRemoteCommand("exec $SHELL -l", "-t")
+	}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -298,17 +361,7 @@ func runEnvSSH(_ *cobra.Command, args []string) error {
 func runEnvShow(_ *cobra.Command, args []string) error {
 	name := args[0]
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	globalCfg, err := config.LoadGlobalConfig(homeDir)
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	env, err := globalCfg.GetEnvironment(name)
+	env, err := findEnvironment(name)
 	if err != nil {
 		return err
 	}
@@ -326,6 +379,16 @@ func runEnvShow(_ *cobra.Command, args []string) error {
 			fmt.Printf("  SSH Key: %s\n", env.SSHKeyPath)
 		}
 	}
+	if env.Path != "" {
+		fmt.Printf("  Path: %s\n", env.Path)
+	}
+	if env.DB != nil {
+		fmt.Printf("  Database: %s", env.GetDBMethod())
+		if env.DB.Name != "" {
+			fmt.Printf(" (%s)", env.DB.Name)
+		}
+		fmt.Println()
+	}
 
 	fmt.Println()
 	fmt.Println("SSH Command:")
@@ -335,87 +398,52 @@ func runEnvShow(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runEnvSync(_ *cobra.Command, _ []string) error {
-	// Load client config
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
-	configPath := filepath.Join(homeDir, ".magebox", "teamserver", "client.json")
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("not connected to a team server. Use 'magebox server join' first")
-		}
-		return err
-	}
-
-	var clientCfg clientConfig
-	if err := json.Unmarshal(data, &clientCfg); err != nil {
-		return err
-	}
-
-	cli.PrintInfo("Syncing environments from %s...", clientCfg.ServerURL)
+func runEnvTunnel(_ *cobra.Command, args []string) error {
+	name := args[0]
 
-	// Fetch environments from server
-	req, err := http.NewRequest("GET", clientCfg.ServerURL+"/api/environments", nil)
+	env, err := findEnvironment(name)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+clientCfg.SessionToken)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to connect to server: %w", err)
+	if env.SSHCommand != "" {
+		return fmt.Errorf("environment '%s' uses a custom ssh_command; add the port forward to that command instead", name)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("session expired. Rejoin with: magebox server join")
-	}
+	cmd := env.BuildSSHCommand(env.TunnelArgs(envTunnelPort)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 
-	if resp.StatusCode != http.StatusOK {
-		var errResp teamserver.ErrorResponse
-		_ = json.NewDecoder(resp.Body).Decode(&errResp)
-		return fmt.Errorf("failed to fetch environments: %s", errResp.Error)
+	cli.PrintInfo("Forwarding 127.0.0.1:%d to the %s database (Ctrl+C to stop)", envTunnelPort, name)
+	if env.DB != nil && env.DB.Name != "" {
+		fmt.Printf("  mysql -h 127.0.0.1 -P %d %s\n", envTunnelPort, env.DB.Name)
 	}
 
-	var envs []struct {
-		Name       string `json:"name"`
-		Project    string `json:"project"`
-		Host       string `json:"host"`
-		Port       int    `json:"port"`
-		DeployUser string `json:"deploy_user"`
-	}
+	return cmd.Run()
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&envs); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
+func runEnvSync(_ *cobra.Command, _ []string) error {
+	// Load client config
+	clientCfg, err := loadClientSession()
+	if err != nil {
+		return err
 	}
 
-	// Update client config with new environments
-	clientCfg.Environments = make([]clientEnvironmentConfig, len(envs))
-	for i, env := range envs {
-		clientCfg.Environments[i] = clientEnvironmentConfig{
-			Name:       env.Name,
-			Project:    env.Project,
-			Host:       env.Host,
-			Port:       env.Port,
-			DeployUser: env.DeployUser,
-		}
-	}
+	cli.PrintInfo("Syncing environments from %s...", clientCfg.ServerURL)
 
-	// Save updated config
-	updatedData, err := json.MarshalIndent(clientCfg, "", "  ")
-	if err != nil {
+	if err := fetchClientEnvironments(clientCfg); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(configPath, updatedData, 0600); err != nil {
+	// Save updated config
+	if err := saveClientConfig(clientCfg); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
+	if err := writeTeamSSHConfig(clientCfg); err != nil {
+		cli.PrintWarning("Failed to configure SSH: %v", err)
+	}
 
+	envs := clientCfg.Environments
 	cli.PrintSuccess("Synced %d environment(s)", len(envs))
 
 	if len(envs) > 0 {
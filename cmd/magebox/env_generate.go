@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/project"
+)
+
+var envGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate app/etc/env.php from the project config",
+	Long: `Renders app/etc/env.php from the services in .magebox.yaml: the database
+host and port, Redis/Valkey cache and session databases, RabbitMQ,
+OpenSearch/Elasticsearch and Mailpit.
+
+The crypt key and cache prefix of an existing env.php are kept, and the file
+is backed up to app/etc/env.php.<timestamp>.bak first. Other changes made to
+env.php by hand are not carried over, compare the backup after generating.
+
+'magebox start --env-php' does the same on start.
+
+Examples:
+  magebox env generate
+  magebox env generate --dry-run   # Print the env.php instead of writing it`,
+	RunE: runEnvGenerate,
+}
+
+var envGenerateDryRun bool
+
+func init() {
+	envGenerateCmd.Flags().BoolVar(&envGenerateDryRun, "dry-run", false, "Print the generated env.php instead of writing it")
+	envCmd.AddCommand(envGenerateCmd)
+}
+
+func runEnvGenerate(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := loadProjectConfig(cwd); !ok {
+		return nil
+	}
+
+	mgr := project.NewManager(p)
+
+	if envGenerateDryRun {
+		content, err := mgr.RenderEnvPHP(cwd)
+		if err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		fmt.Print(content)
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(cwd, "app", "etc")); os.IsNotExist(err) {
+		cli.PrintError("app/etc not found, run this in the Magento root")
+		return nil
+	}
+
+	backup, err := mgr.GenerateEnvPHP(cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	if backup != "" {
+		cli.PrintInfo("Previous env.php saved to %s", cli.Path(backup))
+	}
+	cli.PrintSuccess("Generated app/etc/env.php")
+	return nil
+}
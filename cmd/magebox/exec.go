@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/project"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <service> [command...]",
+	Short: "Run a command in a service container",
+	Long: `Runs a command in one of the project's service containers, or opens a shell
+when no command is given.
+
+The service is named as in 'magebox start': mysql, mariadb, percona, redis,
+valkey, opensearch, elasticsearch, rabbitmq, mailpit, ... or db, cache and
+search. MageBox finds the compose service (e.g. mysql80) and the compose file
+itself. Database containers get MYSQL_PWD set to the password of the
+project's database user, so the mysql client doesn't prompt for it.
+
+Examples:
+  magebox exec db                          # Shell in the database container
+  magebox exec mysql mysql -uroot          # MySQL client as the default user
+  magebox exec redis redis-cli info memory
+  magebox exec opensearch curl -s localhost:9200/_cat/indices`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	// Flags after the service belong to the command run in the container
+	execCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	service, err := project.ResolveService(cfg, args[0])
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	command := args[1:]
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+
+	composeFile := docker.NewComposeGenerator(p).ComposeFilePath()
+	execArgs := append([]string{"exec"}, execFlags(cfg, service, stdinIsTerminal())...)
+	execArgs = append(append(execArgs, service), command...)
+
+	containerCmd := docker.BuildComposeCmd(composeFile, execArgs...)
+	containerCmd.Stdin = os.Stdin
+	containerCmd.Stdout = os.Stdout
+	containerCmd.Stderr = os.Stderr
+
+	if err := containerCmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to exec in %s: %w", service, err)
+	}
+	return nil
+}
+
+// execFlags returns the docker compose exec flags for a service: -T without
+// a terminal, and the database password for database services
+func execFlags(cfg *config.Config, service string, tty bool) []string {
+	var flags []string
+	if !tty {
+		flags = append(flags, "-T")
+	}
+	for _, prefix := range []string{"mysql", "mariadb", "percona"} {
+		if strings.HasPrefix(service, prefix) {
+			flags = append(flags, "-e", "MYSQL_PWD="+cfg.DatabasePassword())
+			break
+		}
+	}
+	return flags
+}
+
+// stdinIsTerminal reports whether stdin is a terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
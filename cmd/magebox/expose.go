@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
@@ -18,25 +19,34 @@ import (
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
-	"qoliber/magebox/internal/docker"
 	"qoliber/magebox/internal/nginx"
 	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/ssl"
 )
 
+var exposeProvider string
+
 var exposeCmd = &cobra.Command{
-	Use:   "expose [domain]",
-	Short: "Expose project via Cloudflare Tunnel",
-	Long: `Creates a public Cloudflare Tunnel URL pointing to your local project.
+	Use:     "expose [domain]",
+	Aliases: []string{"tunnel"},
+	Short:   "Expose project via Cloudflare Tunnel or ngrok",
+	Long: `Creates a public tunnel URL pointing to your local project.
 
-Uses cloudflared quick tunnels (no account required) to generate a
-temporary *.trycloudflare.com URL. The tunnel domain is added to
-.magebox.yaml so nginx serves it alongside your local domains.
+By default uses cloudflared quick tunnels (no account required) to generate
+a temporary *.trycloudflare.com URL. With --provider ngrok an ngrok tunnel
+is used instead, which needs an ngrok account ('ngrok config add-authtoken').
+The tunnel domain is added to .magebox.yaml so nginx serves it alongside
+your local domains.
 
 Automatically updates Magento base URLs (across all scopes and config
 files) to the tunnel URL and reverts them when the tunnel is stopped.
 
-Requires cloudflared to be installed (brew install cloudflared).`,
+Also available as 'magebox tunnel'.
+
+Examples:
+  magebox expose
+  magebox tunnel store.mystore.test
+  magebox tunnel --provider ngrok`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runExpose,
 }
@@ -44,7 +54,7 @@ Requires cloudflared to be installed (brew install cloudflared).`,
 var exposeStopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the tunnel",
-	Long:  "Stops the running Cloudflare Tunnel and reverts Magento base URLs",
+	Long:  "Stops the running tunnel and reverts Magento base URLs",
 	RunE:  runExposeStop,
 }
 
@@ -56,11 +66,83 @@ var exposeStatusCmd = &cobra.Command{
 }
 
 func init() {
+	exposeCmd.Flags().StringVar(&exposeProvider, "provider", "cloudflared", "Tunnel provider: cloudflared or ngrok")
 	exposeCmd.AddCommand(exposeStopCmd)
 	exposeCmd.AddCommand(exposeStatusCmd)
 	rootCmd.AddCommand(exposeCmd)
 }
 
+// tunnelProvider is a program that opens a public tunnel to a local URL
+type tunnelProvider struct {
+	binary  string
+	install string
+	// args returns the arguments that tunnel to localURL, logging JSON
+	args func(localURL string) []string
+	// stdout is set when the provider logs to stdout instead of stderr
+	stdout bool
+	// urlPattern matches the public URL in the log output
+	urlPattern *regexp.Regexp
+	// hostSuffixes are the domains of the public host names
+	hostSuffixes []string
+}
+
+// tunnelProviders are the providers 'magebox expose --provider' supports
+var tunnelProviders = map[string]*tunnelProvider{
+	"cloudflared": {
+		binary:  "cloudflared",
+		install: "brew install cloudflared",
+		args: func(localURL string) []string {
+			return []string{"tunnel", "--url", localURL, "--output", "json", "--loglevel", "debug"}
+		},
+		urlPattern:   regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`),
+		hostSuffixes: []string{".trycloudflare.com"},
+	},
+	"ngrok": {
+		binary:  "ngrok",
+		install: "brew install ngrok, then ngrok config add-authtoken <token>",
+		args: func(localURL string) []string {
+			return []string{"http", localURL, "--log", "stdout", "--log-format", "json"}
+		},
+		stdout:       true,
+		urlPattern:   regexp.MustCompile(`https://[a-zA-Z0-9.-]+\.ngrok(-free)?\.(app|dev|io)`),
+		hostSuffixes: []string{".ngrok-free.app", ".ngrok-free.dev", ".ngrok.app", ".ngrok.dev", ".ngrok.io"},
+	},
+}
+
+// isTunnelHost reports whether a host name is a public tunnel host of any
+// provider, e.g. left behind by a tunnel that didn't shut down cleanly
+func isTunnelHost(host string) bool {
+	for _, provider := range tunnelProviders {
+		for _, suffix := range provider.hostSuffixes {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ngrokLogEntry represents a JSON log line from ngrok (--log-format json)
+type ngrokLogEntry struct {
+	Level   string `json:"lvl"`
+	Message string `json:"msg"`
+	Err     string `json:"err"`
+}
+
+// tunnelLogError returns the error a provider logged, for when it exits
+// without a tunnel URL
+func tunnelLogError(line string) string {
+	var ngrok ngrokLogEntry
+	if err := json.Unmarshal([]byte(line), &ngrok); err == nil && ngrok.Err != "" && ngrok.Err != "<nil>" {
+		return ngrok.Err
+	}
+	var cloudflared cloudflaredLogEntry
+	if err := json.Unmarshal([]byte(line), &cloudflared); err == nil && (cloudflared.Level == "error" || cloudflared.Level == "fatal") {
+		return cloudflared.Message
+	}
+	return ""
+}
+
 // baseURLConfigPaths lists the Magento config paths for base URLs
 var baseURLConfigPaths = []string{
 	"web/unsecure/base_url",
@@ -102,11 +184,17 @@ func runExpose(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Check cloudflared is installed
-	cloudflaredPath, err := exec.LookPath("cloudflared")
+	provider, ok := tunnelProviders[exposeProvider]
+	if !ok {
+		cli.PrintError("Unknown tunnel provider %q, use cloudflared or ngrok", exposeProvider)
+		return nil
+	}
+
+	// Check the provider is installed
+	providerPath, err := exec.LookPath(provider.binary)
 	if err != nil {
-		cli.PrintError("cloudflared is not installed")
-		cli.PrintInfo("Install it with: brew install cloudflared")
+		cli.PrintError("%s is not installed", provider.binary)
+		cli.PrintInfo("Install it with: %s", provider.install)
 		return nil
 	}
 
@@ -156,7 +244,7 @@ func runExpose(cmd *cobra.Command, args []string) error {
 
 	phpBin := p.PHPBinary(cfg.PHP)
 
-	// Determine local URL — cloudflared connects to nginx HTTP
+	// Determine local URL — the tunnel connects to nginx HTTP
 	httpPort := 80
 	if p.Type == platform.Darwin {
 		httpPort = 8080
@@ -167,6 +255,7 @@ func runExpose(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Project: %s\n", cli.Highlight(cfg.Name))
 	fmt.Printf("Domain:  %s\n", cli.Highlight(domain))
 	fmt.Printf("Backend: %s\n", cli.Highlight(localURL))
+	fmt.Printf("Tunnel:  %s\n", cli.Highlight(provider.binary))
 	fmt.Println()
 
 	// Save current state (Magento only: DB rows + env.php backup)
@@ -176,17 +265,22 @@ func runExpose(cmd *cobra.Command, args []string) error {
 		saveExposeState(db, cfg.DatabaseName(), phpBin, cwd, stateFile, domain)
 	}
 
-	// Start cloudflared tunnel
-	tunnelCmd := exec.Command(cloudflaredPath, "tunnel", "--url", localURL, "--output", "json", "--loglevel", "debug")
+	// Start the tunnel
+	tunnelCmd := exec.Command(providerPath, provider.args(localURL)...)
 	tunnelCmd.Env = os.Environ()
 
-	stderr, err := tunnelCmd.StderrPipe()
+	var logOutput io.ReadCloser
+	if provider.stdout {
+		logOutput, err = tunnelCmd.StdoutPipe()
+	} else {
+		logOutput, err = tunnelCmd.StderrPipe()
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return fmt.Errorf("failed to create log pipe: %w", err)
 	}
 
 	if err := tunnelCmd.Start(); err != nil {
-		return fmt.Errorf("failed to start cloudflared: %w", err)
+		return fmt.Errorf("failed to start %s: %w", provider.binary, err)
 	}
 
 	if err := writePidFile(pidFile, tunnelCmd.Process.Pid); err != nil {
@@ -195,17 +289,24 @@ func runExpose(cmd *cobra.Command, args []string) error {
 
 	urlFile := getTunnelURLFile(p, cfg.Name)
 
-	urlPattern := regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`)
-	scanner := bufio.NewScanner(stderr)
+	scanner := bufio.NewScanner(logOutput)
 	tunnelURL := ""
+	tunnelErr := ""
+	logDone := make(chan struct{})
 
 	fmt.Print("Starting tunnel... ")
 
 	lastReq := &tunnelRequest{}
 	go func() {
+		defer close(logDone)
 		for scanner.Scan() {
 			line := scanner.Text()
-			if match := urlPattern.FindString(line); match != "" && tunnelURL == "" {
+			if tunnelURL == "" {
+				if msg := tunnelLogError(line); msg != "" {
+					tunnelErr = msg
+				}
+			}
+			if match := provider.urlPattern.FindString(line); match != "" && tunnelURL == "" {
 				tunnelURL = match
 				fmt.Println(cli.Success("done"))
 				fmt.Println()
@@ -231,14 +332,15 @@ func runExpose(cmd *cobra.Command, args []string) error {
 			}
 
 			// After tunnel is established, show request logs from cloudflared
-			if tunnelURL != "" {
+			if tunnelURL != "" && !provider.stdout {
 				printTunnelRequestLog(line, lastReq)
 			}
 		}
 	}()
 
-	// Handle Ctrl+C — cloudflared also receives the signal and exits,
+	// Handle Ctrl+C — the tunnel also receives the signal and exits,
 	// which makes Wait() return. We do the revert after Wait() completes.
+	commandHandlesInterrupt.Store(true)
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
@@ -246,11 +348,13 @@ func runExpose(cmd *cobra.Command, args []string) error {
 		<-sigChan
 		fmt.Println()
 		fmt.Println("Shutting down...")
-		// Cloudflared receives the same signal and will exit on its own.
+		// The tunnel receives the same signal and will exit on its own.
 		// If it doesn't, send SIGTERM explicitly.
 		_ = tunnelCmd.Process.Signal(syscall.SIGTERM)
 	}()
 
+	// Read the log to the end before Wait closes the pipe
+	<-logDone
 	_ = tunnelCmd.Wait()
 
 	// Revert everything after cloudflared has stopped
@@ -268,7 +372,10 @@ func runExpose(cmd *cobra.Command, args []string) error {
 
 	if tunnelURL == "" {
 		fmt.Println(cli.Error("failed"))
-		return fmt.Errorf("cloudflared exited without providing a tunnel URL")
+		if tunnelErr != "" {
+			return fmt.Errorf("%s exited without providing a tunnel URL: %s", provider.binary, tunnelErr)
+		}
+		return fmt.Errorf("%s exited without providing a tunnel URL", provider.binary)
 	}
 
 	cli.PrintSuccess("Tunnel stopped and all changes reverted")
@@ -393,7 +500,7 @@ func saveExposeState(db *dbInfo, dbName, phpBin, cwd, stateFile, localDomain str
 		)
 
 		cmd := exec.Command("docker", "exec", db.ContainerName,
-			"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword,
+			"mysql", "-u"+db.User, "-p"+db.Password,
 			"-N", "-B", dbName, "-e", query)
 		if out, err := cmd.Output(); err == nil {
 			localBaseURL := fmt.Sprintf("https://%s/", localDomain)
@@ -406,7 +513,7 @@ func saveExposeState(db *dbInfo, dbName, phpBin, cwd, stateFile, localDomain str
 					value := fields[3]
 					isNull := value == "NULL"
 					// Fix stale tunnel URLs
-					if !isNull && strings.Contains(value, ".trycloudflare.com") {
+					if !isNull && isTunnelHost(extractHostname(value)) {
 						switch {
 						case strings.Contains(fields[2], "media"):
 							value = localBaseURL + "media/"
@@ -478,7 +585,7 @@ func setAllBaseURLs(db *dbInfo, dbName, phpBin, cwd, tunnelBaseURL string) {
 		}
 		query := strings.Join(statements, "; ")
 		cmd := exec.Command("docker", "exec", db.ContainerName,
-			"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword,
+			"mysql", "-u"+db.User, "-p"+db.Password,
 			dbName, "-e", query)
 		if err := cmd.Run(); err != nil {
 			fmt.Println(cli.Error("failed"))
@@ -565,7 +672,7 @@ func revertExposeState(db *dbInfo, dbName, phpBin, cwd, stateFile string) {
 		}
 		query := strings.Join(statements, "; ")
 		cmd := exec.Command("docker", "exec", db.ContainerName,
-			"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword,
+			"mysql", "-u"+db.User, "-p"+db.Password,
 			dbName, "-e", query)
 		if err := cmd.Run(); err != nil {
 			fmt.Println(cli.Error("failed"))
@@ -605,7 +712,7 @@ func addTunnelDomain(p *platform.Platform, cwd string, cfg *config.Config, tunne
 	var root string
 	cleanDomains := make([]config.Domain, 0, len(cfg.Domains))
 	for _, d := range cfg.Domains {
-		if strings.HasSuffix(d.Host, ".trycloudflare.com") {
+		if isTunnelHost(d.Host) {
 			continue // skip stale tunnel domain
 		}
 		if d.Host == sourceDomain {
@@ -632,7 +739,7 @@ func addTunnelDomain(p *platform.Platform, cwd string, cfg *config.Config, tunne
 	regenNginxVhosts(p, cfg, cwd)
 }
 
-// removeTunnelDomain removes any *.trycloudflare.com domain from .magebox.yaml
+// removeTunnelDomain removes any tunnel domain from .magebox.yaml
 func removeTunnelDomain(p *platform.Platform, cwd string, cfg *config.Config) {
 	freshCfg, err := config.LoadFromPath(cwd)
 	if err != nil {
@@ -642,7 +749,7 @@ func removeTunnelDomain(p *platform.Platform, cwd string, cfg *config.Config) {
 	var removedHosts []string
 	newDomains := make([]config.Domain, 0, len(freshCfg.Domains))
 	for _, d := range freshCfg.Domains {
-		if strings.HasSuffix(d.Host, ".trycloudflare.com") {
+		if isTunnelHost(d.Host) {
 			removedHosts = append(removedHosts, d.Host)
 		} else {
 			newDomains = append(newDomains, d)
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestTunnelProviderURLs(t *testing.T) {
+	tests := []struct {
+		provider string
+		line     string
+		want     string
+	}{
+		{"cloudflared", `{"level":"info","message":"|  https://quiet-river-1234.trycloudflare.com  |"}`, "https://quiet-river-1234.trycloudflare.com"},
+		{"ngrok", `{"lvl":"info","msg":"started tunnel","obj":"tunnels","url":"https://1a2b-3c4d.ngrok-free.app"}`, "https://1a2b-3c4d.ngrok-free.app"},
+		{"ngrok", `{"lvl":"info","msg":"open config file","path":"/home/dev/.config/ngrok/ngrok.yml"}`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tunnelProviders[tt.provider].urlPattern.FindString(tt.line); got != tt.want {
+			t.Errorf("%s URL in %s = %q, want %q", tt.provider, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestIsTunnelHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"quiet-river-1234.trycloudflare.com": true,
+		"1a2b-3c4d.ngrok-free.app":           true,
+		"demo.ngrok.io":                      true,
+		"mystore.test":                       false,
+		"ngrok.app.mystore.test":             false,
+	} {
+		if got := isTunnelHost(host); got != want {
+			t.Errorf("isTunnelHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestTunnelLogError(t *testing.T) {
+	line := `{"lvl":"eror","msg":"failed to reconnect session","err":"authentication failed: Usage of ngrok requires a verified account and authtoken."}`
+	if got := tunnelLogError(line); got == "" {
+		t.Error("tunnelLogError() found no ngrok error")
+	}
+	if got := tunnelLogError(`{"lvl":"info","msg":"client session established","err":"<nil>"}`); got != "" {
+		t.Errorf("tunnelLogError() = %q for an info line", got)
+	}
+	if got := tunnelLogError(`{"level":"error","message":"Failed to create new quick Tunnel"}`); got != "Failed to create new quick Tunnel" {
+		t.Errorf("tunnelLogError() = %q for a cloudflared error", got)
+	}
+}
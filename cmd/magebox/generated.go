@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/fileutil"
+)
+
+// generatedManifestPath returns the checksum manifest of generated files
+func generatedManifestPath(homeDir string) string {
+	return filepath.Join(homeDir, ".magebox", "generated.json")
+}
+
+// resolveGeneratedEdit asks whether a generated file that was edited by hand
+// is kept or overwritten. Without a terminal to ask on, the edit is kept.
+func resolveGeneratedEdit(path string, current, generated []byte) fileutil.EditAction {
+	cli.PrintWarning("%s was edited since MageBox generated it", cli.Path(path))
+
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		cli.PrintInfo("Keeping your edits; delete the file to let MageBox regenerate it")
+		return fileutil.KeepEdits
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Keep your edits, overwrite them, or show the diff? [K/o/d] ")
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return fileutil.KeepEdits
+		}
+
+		switch strings.TrimSpace(strings.ToLower(answer)) {
+		case "", "k", "keep":
+			return fileutil.KeepEdits
+		case "o", "overwrite":
+			return fileutil.Overwrite
+		case "d", "diff":
+			printGeneratedDiff(path, generated)
+		}
+	}
+}
+
+// printGeneratedDiff shows how the generated content differs from the file
+// on disk
+func printGeneratedDiff(path string, generated []byte) {
+	tmp, err := os.CreateTemp("", "magebox-generated-*")
+	if err != nil {
+		cli.PrintError("Failed to create temp file: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(generated)
+	tmp.Close()
+	if err != nil {
+		cli.PrintError("Failed to write temp file: %v", err)
+		return
+	}
+
+	diffCmd := exec.Command("diff", "-u", "-L", path+" (yours)", "-L", path+" (generated)", path, tmp.Name())
+	diffCmd.Stdout = os.Stdout
+	diffCmd.Stderr = os.Stderr
+	// diff exits 1 when the files differ
+	if err := diffCmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			cli.PrintError("Failed to run diff: %v", err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -12,6 +13,7 @@ import (
 	"qoliber/magebox/internal/nginx"
 	"qoliber/magebox/internal/php"
 	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
 )
 
 var globalCmd = &cobra.Command{
@@ -88,6 +90,14 @@ func runGlobalStart(cmd *cobra.Command, args []string) error {
 		if err := composeGen.GenerateGlobalServices(configs); err != nil {
 			cli.PrintWarning("Could not regenerate docker-compose: %v", err)
 		}
+		for _, move := range composeGen.PortMoves() {
+			cli.PrintWarning("Port %d of %s is used by another program, moved it to %d", move.From, move.Service, move.To)
+		}
+		if updated, err := project.UpdateEnvPHPPorts(p, composeGen.PortMoves()); err != nil {
+			cli.PrintWarning("%v, run 'magebox env generate' there", err)
+		} else if len(updated) > 0 {
+			cli.PrintInfo("Updated the service ports in env.php of %s", strings.Join(updated, ", "))
+		}
 
 		fmt.Print("  Docker services... ")
 		dockerCtrl := docker.NewDockerController(composeFile)
@@ -147,49 +157,88 @@ func runGlobalStop(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// globalStatusOutput is the global status printed by global status --output json|yaml
+type globalStatusOutput struct {
+	Nginx struct {
+		Running bool `json:"running"`
+	} `json:"nginx"`
+	Docker struct {
+		Installed bool `json:"installed"`
+		Running   bool `json:"running"`
+	} `json:"docker"`
+	Mkcert struct {
+		Installed bool `json:"installed"`
+	} `json:"mkcert"`
+	PHP []phpVersionStatus `json:"php"`
+}
+
+// phpVersionStatus is the state of one supported PHP version
+type phpVersionStatus struct {
+	Version    string `json:"version"`
+	Installed  bool   `json:"installed"`
+	FPMRunning bool   `json:"fpm_running"`
+}
+
 func runGlobalStatus(cmd *cobra.Command, args []string) error {
 	p, err := getPlatform()
 	if err != nil {
 		return err
 	}
 
-	cli.PrintTitle("Global Services Status")
-	fmt.Println()
+	var status globalStatusOutput
 
 	// Check Nginx
 	nginxCtrl := nginx.NewController(p)
-	fmt.Printf("  %-20s %s\n", "Nginx", cli.Status(nginxCtrl.IsRunning()))
+	status.Nginx.Running = nginxCtrl.IsRunning()
 
 	// Check Docker
-	dockerInstalled := platform.CommandExists("docker")
-	dockerRunning := false
-	if dockerInstalled {
+	status.Docker.Installed = platform.CommandExists("docker")
+	if status.Docker.Installed {
 		dockerCmd := exec.Command("docker", "info")
-		dockerRunning = dockerCmd.Run() == nil
-	}
-	if !dockerInstalled {
-		fmt.Printf("  %-20s %s\n", "Docker", cli.StatusInstalled(false))
-	} else {
-		fmt.Printf("  %-20s %s\n", "Docker", cli.Status(dockerRunning))
+		status.Docker.Running = dockerCmd.Run() == nil
 	}
 
 	// Check mkcert
-	fmt.Printf("  %-20s %s\n", "mkcert", cli.StatusInstalled(platform.CommandExists("mkcert")))
+	status.Mkcert.Installed = platform.CommandExists("mkcert")
 
-	// List PHP versions
-	fmt.Println(cli.Header("PHP Versions"))
+	// Check PHP versions
 	detector := php.NewDetector(p)
 	for _, v := range php.SupportedVersions {
 		version := detector.Detect(v)
-		var status string
-		if !version.Installed {
-			status = cli.StatusInstalled(false)
-		} else if version.FPMRunning {
-			status = cli.Status(true)
+		status.PHP = append(status.PHP, phpVersionStatus{
+			Version:    v,
+			Installed:  version.Installed,
+			FPMRunning: version.FPMRunning,
+		})
+	}
+
+	if structuredOutput() {
+		return printStructured(status)
+	}
+
+	cli.PrintTitle("Global Services Status")
+	fmt.Println()
+
+	fmt.Printf("  %-20s %s\n", "Nginx", cli.Status(status.Nginx.Running))
+	if !status.Docker.Installed {
+		fmt.Printf("  %-20s %s\n", "Docker", cli.StatusInstalled(false))
+	} else {
+		fmt.Printf("  %-20s %s\n", "Docker", cli.Status(status.Docker.Running))
+	}
+	fmt.Printf("  %-20s %s\n", "mkcert", cli.StatusInstalled(status.Mkcert.Installed))
+
+	// List PHP versions
+	fmt.Println(cli.Header("PHP Versions"))
+	for _, v := range status.PHP {
+		var state string
+		if !v.Installed {
+			state = cli.StatusInstalled(false)
+		} else if v.FPMRunning {
+			state = cli.Status(true)
 		} else {
-			status = cli.StatusInstalled(true)
+			state = cli.StatusInstalled(true)
 		}
-		fmt.Printf("  %-20s %s\n", "PHP "+v, status)
+		fmt.Printf("  %-20s %s\n", "PHP "+v.Version, state)
 	}
 
 	return nil
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/project"
+)
+
+var (
+	graphFormat   string
+	graphNoHealth bool
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render the project's service topology",
+	Long: `Renders the service topology of the project: the request flow from Nginx
+(and Varnish) to PHP-FPM, and the backends used by PHP-FPM, cron and queue
+consumers. Nodes are colored by their current health (green running, red
+stopped, grey unknown).
+
+The output is Graphviz DOT or a Mermaid flowchart, ready to embed in project
+documentation.
+
+Examples:
+  magebox graph | dot -Tsvg > services.svg
+  magebox graph --format mermaid >> README.md
+  magebox graph --no-health         # Static graph without health checks`,
+	RunE: runGraph,
+}
+
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format: dot or mermaid")
+	graphCmd.Flags().BoolVar(&graphNoHealth, "no-health", false, "Skip health checks (all nodes grey)")
+	rootCmd.AddCommand(graphCmd)
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	if graphFormat != "dot" && graphFormat != "mermaid" {
+		cli.PrintError("Unknown format %q (use dot or mermaid)", graphFormat)
+		return nil
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	var g *project.ServiceGraph
+	if graphNoHealth {
+		g = project.BuildServiceGraph(cfg)
+	} else {
+		p, err := getPlatform()
+		if err != nil {
+			return err
+		}
+		g = project.NewManager(p).GraphHealth(cwd, cfg)
+	}
+
+	if graphFormat == "mermaid" {
+		fmt.Print(g.Mermaid())
+	} else {
+		fmt.Print(g.DOT())
+	}
+	return nil
+}
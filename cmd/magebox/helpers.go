@@ -10,6 +10,7 @@ import (
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
 )
 
 // getPlatform returns the current platform
@@ -40,3 +41,29 @@ func loadProjectConfig(cwd string) (*config.Config, bool) {
 	}
 	return cfg, true
 }
+
+// resolveProjectDir returns the directory of the named project, or the
+// current directory when name is empty. Unknown names are printed as an
+// error and reported as not ok.
+func resolveProjectDir(p *platform.Platform, name string) (string, bool) {
+	if name == "" {
+		cwd, err := getCwd()
+		if err != nil {
+			cli.PrintError("%v", err)
+			return "", false
+		}
+		return cwd, true
+	}
+
+	info, err := project.NewProjectDiscovery(p).FindProjectByName(name)
+	if err != nil {
+		cli.PrintError("Failed to discover projects: %v", err)
+		return "", false
+	}
+	if info == nil {
+		cli.PrintError("Unknown project '%s'", name)
+		cli.PrintInfo("List the projects with %s", cli.Command("magebox status --all"))
+		return "", false
+	}
+	return info.Path, true
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/history"
+	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/ssl"
+)
+
+var (
+	historyLimit     int
+	historyUndoForce bool
+)
+
+// recorder tracks the files changed by the running command for the history log
+var recorder = history.NewRecorder()
+
+// historyUndoOf is set by 'history undo' to the ID of the entry it reverted
+var historyUndoOf int
+
+// stateChangingCommands are the commands recorded in the history log, by path
+// without the leading "magebox". Commands that track file changes are
+// recorded as well.
+var stateChangingCommands = map[string]bool{
+	"start": true, "stop": true, "restart": true, "init": true, "new": true, "clone": true, "restore": true, "destroy": true, "apply": true,
+	"purge": true, "php": true, "install": true, "uninstall": true, "bootstrap": true,
+	"domain add": true, "domain remove": true, "domain import": true, "registry import": true,
+	"config set": true, "config init": true, "auth set": true,
+	"db create": true, "db drop": true, "db import": true, "db reset": true, "db restore": true,
+	"db snapshot create": true, "db snapshot delete": true, "db snapshot restore": true,
+	"db querylog on": true, "db querylog off": true,
+	"dns setup": true, "ssl generate": true, "ssl renew": true, "ssl trust": true, "mode": true,
+	"remote provision": true, "remote attach": true, "remote sync": true, "remote detach": true,
+	"cron enable": true, "cron disable": true, "profile use": true, "profile clear": true, "queue start": true, "queue stop": true,
+	"xdebug on": true, "xdebug off": true, "xdebug mode": true, "xdebug trigger": true, "xdebug listen": true,
+	"blackfire on": true, "blackfire off": true, "tideways on": true, "tideways off": true,
+	"profiler install": true, "profiler on": true, "profiler off": true, "node install": true,
+	"varnish enable": true, "varnish disable": true, "varnish vcl-import": true, "varnish vcl-reset": true,
+	"global start": true, "global stop": true,
+	"ext install": true, "ext remove": true, "team pull-config": true, "team join": true,
+	"env add": true, "env remove": true, "env sync": true, "env generate": true,
+	"lib set": true, "lib unset": true, "lib reset": true, "lib update": true,
+	"services update": true, "service install": true, "service uninstall": true,
+	"stop-protocol enable": true, "stop-protocol disable": true,
+	"redis flush": true, "redis release": true, "mail clear": true,
+	"docker use": true, "sync": true, "sync db": true, "sync media": true, "sync all": true, "fetch": true, "media optimize": true,
+	"deploy": true,
+}
+
+// stateChangingFlags are the commands recorded in the history log only when
+// run with the flag that makes them change state, e.g. 'urls --set'
+var stateChangingFlags = map[string]string{
+	"urls": "set",
+}
+
+// reversibleCommands are the commands 'history undo' can revert
+var reversibleCommands = map[string]bool{
+	"domain add":    true,
+	"domain remove": true,
+	"config set":    true,
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the history of state-changing commands",
+	Long: `Shows the state-changing MageBox commands run on this machine: who ran them,
+when, in which directory and which files they changed. The history is kept in
+~/.magebox/history.log.
+
+Domain and configuration changes can be reverted with 'magebox history undo'.
+
+Examples:
+  magebox history               # Last 20 commands
+  magebox history --limit 100   # Last 100 commands
+  magebox history show 42       # Files changed by entry 42
+  magebox history undo 42       # Revert entry 42`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of a history entry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+var historyUndoCmd = &cobra.Command{
+	Use:   "undo <id>",
+	Short: "Revert a domain or configuration change",
+	Long: `Restores the files changed by a history entry to their previous content and
+applies the result. Only domain add/remove and config set can be undone.
+
+Undo refuses to overwrite files that were changed again after the entry;
+use --force to revert anyway.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistoryUndo,
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of entries to show (0 for all)")
+	historyUndoCmd.Flags().BoolVarP(&historyUndoForce, "force", "f", false, "Revert even if the files changed since")
+
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyUndoCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func getHistoryLog() (*history.Log, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return history.NewLog(history.LogPath(homeDir)), nil
+}
+
+// recordHistory appends the executed command to the history log if it is
+// state-changing or changed tracked files. Failures to record are ignored so
+// they never mask the command's own result.
+func recordHistory(cmd *cobra.Command, args []string, cmdErr error) {
+	if cmd == nil {
+		return
+	}
+	path := strings.TrimPrefix(cmd.CommandPath(), "magebox ")
+	changes := recorder.Changes()
+	if !stateChangingCommands[path] && !changesStateWithFlag(cmd, path) && len(changes) == 0 {
+		return
+	}
+
+	log, err := getHistoryLog()
+	if err != nil {
+		return
+	}
+	cwd, _ := os.Getwd()
+	entry := &history.Entry{
+		Time:        time.Now().UTC(),
+		User:        historyUser(),
+		Command:     path,
+		CommandLine: formatHistoryCommand(args),
+		Dir:         cwd,
+		Files:       changes,
+		UndoOf:      historyUndoOf,
+	}
+	if cmdErr != nil {
+		entry.Error = cmdErr.Error()
+	}
+	_ = log.Append(entry)
+}
+
+// changesStateWithFlag reports whether cmd was run with the flag that makes it
+// change state
+func changesStateWithFlag(cmd *cobra.Command, path string) bool {
+	flag, ok := stateChangingFlags[path]
+	return ok && cmd.Flags().Changed(flag)
+}
+
+// historyUser returns the user running MageBox, including the invoking user
+// when run through sudo
+func historyUser() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		name = fmt.Sprintf("%s (sudo from %s)", name, sudoUser)
+	}
+	return name
+}
+
+// formatHistoryCommand joins the command-line arguments, quoting those with spaces
+func formatHistoryCommand(args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, "magebox")
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	log, err := getHistoryLog()
+	if err != nil {
+		return err
+	}
+	entries, err := log.Entries()
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	if len(entries) == 0 {
+		cli.PrintInfo("No commands recorded yet")
+		return nil
+	}
+
+	undone := make(map[int]int)
+	for _, e := range entries {
+		if e.UndoOf != 0 {
+			undone[e.UndoOf] = e.ID
+		}
+	}
+
+	if historyLimit > 0 && len(entries) > historyLimit {
+		entries = entries[len(entries)-historyLimit:]
+	}
+
+	cli.PrintTitle("Command History")
+	fmt.Println()
+	for _, e := range entries {
+		status := ""
+		switch {
+		case e.Error != "":
+			status = "  " + cli.Error("failed")
+		case undone[e.ID] != 0:
+			status = "  " + cli.Warning(fmt.Sprintf("undone by #%d", undone[e.ID]))
+		case e.Undoable() && reversibleCommands[e.Command]:
+			status = "  " + cli.Subtitle("undoable")
+		}
+		fmt.Printf("  %s  %s  %-12s %s%s\n", cli.Highlight(fmt.Sprintf("#%-4d", e.ID)),
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.User, e.CommandLine, status)
+	}
+	fmt.Println()
+	cli.PrintInfo("Details: %s", cli.Command("magebox history show <id>"))
+	return nil
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	entry, ok := loadHistoryEntry(args[0])
+	if !ok {
+		return nil
+	}
+
+	cli.PrintTitle("History Entry #%d", entry.ID)
+	fmt.Printf("Command:   %s\n", cli.Highlight(entry.CommandLine))
+	fmt.Printf("Time:      %s\n", entry.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("User:      %s\n", entry.User)
+	fmt.Printf("Directory: %s\n", cli.Path(entry.Dir))
+	if entry.UndoOf != 0 {
+		fmt.Printf("Reverted:  #%d\n", entry.UndoOf)
+	}
+	if entry.Error != "" {
+		fmt.Printf("Error:     %s\n", cli.Error(entry.Error))
+	}
+
+	if len(entry.Files) > 0 {
+		fmt.Println()
+		fmt.Println("Files changed:")
+		for _, f := range entry.Files {
+			action := "modified"
+			switch {
+			case !f.Existed:
+				action = "created"
+			case f.After == "":
+				action = "removed"
+			}
+			fmt.Printf("  %-9s %s\n", action, cli.Path(f.Path))
+		}
+	}
+	return nil
+}
+
+func runHistoryUndo(cmd *cobra.Command, args []string) error {
+	entry, ok := loadHistoryEntry(args[0])
+	if !ok {
+		return nil
+	}
+
+	if !reversibleCommands[entry.Command] || !entry.Undoable() {
+		cli.PrintError("Entry #%d (%s) cannot be undone", entry.ID, entry.CommandLine)
+		cli.PrintInfo("Only domain add, domain remove and config set can be undone")
+		return nil
+	}
+
+	log, err := getHistoryLog()
+	if err != nil {
+		return err
+	}
+	if by, err := log.UndoneBy(entry.ID); err == nil && by != 0 {
+		cli.PrintError("Entry #%d was already undone by #%d", entry.ID, by)
+		return nil
+	}
+
+	if modified := entry.Modified(); len(modified) > 0 && !historyUndoForce {
+		cli.PrintError("These files changed after entry #%d:", entry.ID)
+		for _, f := range modified {
+			fmt.Printf("  %s\n", cli.Path(f))
+		}
+		cli.PrintInfo("Use %s to revert anyway", cli.Command(fmt.Sprintf("magebox history undo %d --force", entry.ID)))
+		return nil
+	}
+
+	if err := entry.Undo(recorder); err != nil {
+		return err
+	}
+	historyUndoOf = entry.ID
+	cli.PrintSuccess("Reverted #%d: %s", entry.ID, entry.CommandLine)
+
+	switch entry.Command {
+	case "domain add", "domain remove":
+		reloadProjectVhosts(entry.Dir)
+	case "config set":
+		cli.PrintInfo("Run %s to apply the restored configuration", cli.Command("magebox restart"))
+	}
+	return nil
+}
+
+// loadHistoryEntry parses an entry ID and loads the entry, printing an error
+// when it does not exist
+func loadHistoryEntry(arg string) (*history.Entry, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		cli.PrintError("Invalid history entry ID: %s", arg)
+		return nil, false
+	}
+	log, err := getHistoryLog()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil, false
+	}
+	entry, err := log.Get(id)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil, false
+	}
+	return entry, true
+}
+
+// reloadProjectVhosts regenerates the vhosts of the project in dir and
+// reloads nginx
+func reloadProjectVhosts(dir string) {
+	p, err := getPlatform()
+	if err != nil {
+		cli.PrintWarning("%v", err)
+		return
+	}
+	cfg, err := config.LoadFromPath(dir)
+	if err != nil {
+		cli.PrintWarning("Failed to load config: %v", err)
+		return
+	}
+
+	fmt.Println("Regenerating nginx vhosts...")
+	vhostGen := nginx.NewVhostGenerator(p, ssl.NewManager(p))
+	if err := vhostGen.Generate(cfg, dir); err != nil {
+		cli.PrintWarning("Failed to regenerate vhosts: %v", err)
+		return
+	}
+
+	fmt.Println("Reloading nginx...")
+	ngxController := nginx.NewController(p)
+	if err := ngxController.Test(); err != nil {
+		cli.PrintError("Nginx config test failed: %v", err)
+		return
+	}
+	if err := ngxController.Reload(); err != nil {
+		cli.PrintWarning("Failed to reload nginx: %v", err)
+	}
+}
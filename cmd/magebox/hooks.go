@@ -0,0 +1,38 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"fmt"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+)
+
+// skipHooks is set by --no-hooks of the commands that run hooks
+var skipHooks bool
+
+// runHook runs the commands of a project hook in order, like 'magebox run'
+// does, and stops at the first one that fails. MAGEBOX_HOOK tells the
+// commands which hook runs them.
+func runHook(cfg *config.Config, projectPath, event string) error {
+	commands := cfg.Hooks.Commands(event)
+	if skipHooks || len(commands) == 0 {
+		return nil
+	}
+
+	cli.PrintInfo("Running %s hook", cli.Highlight(event))
+	for _, command := range commands {
+		fmt.Printf("  %s\n", cli.Command(command))
+		shellCmd, err := projectShellCommand(cfg, projectPath, command)
+		if err != nil {
+			return err
+		}
+		shellCmd.Env = append(shellCmd.Env, "MAGEBOX_HOOK="+event)
+		if err := shellCmd.Run(); err != nil {
+			return fmt.Errorf("%s hook failed: %s: %w", event, command, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestRunHook(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+
+	cfg := &config.Config{
+		Name: "mystore",
+		Env:  map[string]string{"APP_STAGE": "local"},
+		Hooks: &config.Hooks{
+			PostDBImport: config.HookCommands{
+				`echo "$MAGEBOX_HOOK $APP_STAGE" > hook.out`,
+				`echo "$PATH" | cut -d: -f1 >> hook.out`,
+			},
+			PreStart: config.HookCommands{"false", "touch never"},
+		},
+	}
+
+	if err := runHook(cfg, dir, config.HookPostDBImport); err != nil {
+		t.Fatalf("runHook() = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "hook.out"))
+	want := "post_db_import local\n" + filepath.Join(home, ".magebox", "bin") + "\n"
+	if string(data) != want {
+		t.Errorf("hook output = %q, want %q", data, want)
+	}
+
+	err := runHook(cfg, dir, config.HookPreStart)
+	if err == nil || !strings.Contains(err.Error(), "pre_start") {
+		t.Errorf("runHook() with a failing command = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "never")); err == nil {
+		t.Error("commands after the failing one ran")
+	}
+
+	// Events without commands and --no-hooks do nothing
+	if err := runHook(cfg, dir, config.HookPostSync); err != nil {
+		t.Errorf("runHook() without commands = %v", err)
+	}
+	skipHooks = true
+	defer func() { skipHooks = false }()
+	if err := runHook(cfg, dir, config.HookPreStart); err != nil {
+		t.Errorf("runHook() with --no-hooks = %v", err)
+	}
+}
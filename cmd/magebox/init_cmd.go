@@ -13,22 +13,44 @@ import (
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/php"
 	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/templates"
 )
 
 var (
 	initProjectType string
+	initTemplate    string
+	initTLD         string
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init [name]",
 	Short: "Initialize a new MageBox project",
-	Long:  "Creates a .magebox configuration file in the current directory",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  runInit,
+	Long: `Creates a .magebox configuration file in the current directory.
+
+--template seeds the project from a template: its commands, env vars, PHP
+settings and services are merged into .magebox.yaml, nginx snippets go to
+.magebox/nginx and bootstrap scripts to .magebox/bootstrap, run by
+'magebox run bootstrap'. A template is one of the built-in templates
+(hyva, b2b, headless), a directory in ~/.magebox/templates, a path or a git
+URL with an optional #branch.
+
+--tld puts the project on its own TLD instead of the global one, e.g. a
+client-specific TLD next to .test. It is written to .magebox.yaml and
+dnsmasq routes it once the project starts.
+
+Examples:
+  magebox init mystore
+  magebox init mystore --tld localhost
+  magebox init mystore --template hyva
+  magebox init mystore --template git@github.com:acme/magebox-template.git#v2`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
 }
 
 func init() {
 	initCmd.Flags().StringVar(&initProjectType, "type", config.ProjectTypeMagento, "Project type: \"magento\" or \"laravel\"")
+	initCmd.Flags().StringVar(&initTLD, "tld", "", "TLD of the project's domains when it differs from the global tld")
+	initCmd.Flags().StringVar(&initTemplate, "template", "", "Seed the project from a template: "+strings.Join(templates.BuiltinProjectTemplateNames(), ", ")+", a directory or a git URL")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -42,6 +64,17 @@ func runInit(cmd *cobra.Command, args []string) error {
 	homeDir, _ := os.UserHomeDir()
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
 	tld := globalCfg.GetTLD()
+	if initTLD != "" {
+		tld = config.NormalizeTLD(initTLD)
+		if !config.ValidTLD(tld) {
+			cli.PrintError("Invalid value for --tld: %q", initTLD)
+			return nil
+		}
+		if !config.LocalTLD(tld) {
+			cli.PrintError("--tld %q is not a local TLD (use test, localhost, example, invalid, internal or a name under them)", initTLD)
+			return nil
+		}
+	}
 
 	reader := bufio.NewReader(os.Stdin)
 
@@ -90,18 +123,71 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var tpl *templates.ProjectTemplate
+	if initTemplate != "" {
+		if tpl, err = loadProjectTemplate(initTemplate); err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		defer func() { _ = tpl.Close() }()
+	}
+
 	mgr := project.NewManager(p)
-	if err := mgr.Init(cwd, projectName, initProjectType, phpVersion); err != nil {
+	projectTLD := ""
+	if tld != globalCfg.GetTLD() {
+		projectTLD = tld
+	}
+	added, err := mgr.Init(cwd, projectName, initProjectType, phpVersion, projectTLD)
+	if err != nil {
 		return err
 	}
 
 	cli.PrintSuccess("Created %s for project '%s'", config.ConfigFileName, projectName)
+	for _, module := range added {
+		cli.PrintInfo("Enabled %s for %s (%s)", module.ServiceSuggestion(), module.Name, module.Source)
+	}
+	var bootstrap []string
+	if tpl != nil {
+		if bootstrap, err = applyProjectTemplate(tpl, cwd, projectName, projectName+"."+tld); err != nil {
+			return err
+		}
+	}
 	fmt.Println()
 	fmt.Printf("Domain: %s\n", cli.URL(projectName+"."+tld))
 	fmt.Println()
 	cli.PrintInfo("Next steps:")
 	fmt.Println(cli.Bullet("Edit " + config.ConfigFileName + " to customize your configuration"))
 	fmt.Println(cli.Bullet("Run " + cli.Command("magebox start") + " to start your project"))
+	if len(bootstrap) > 0 {
+		fmt.Println(cli.Bullet("Run " + cli.Command("magebox run "+templates.BootstrapCommand) + " to run the template's bootstrap scripts"))
+	}
 
 	return nil
 }
+
+// loadProjectTemplate loads the template of --template, cloning git templates
+func loadProjectTemplate(ref string) (*templates.ProjectTemplate, error) {
+	homeDir, _ := os.UserHomeDir()
+	if _, ok := templates.BuiltinProjectTemplates[ref]; !ok {
+		cli.PrintInfo("Loading template %s...", cli.Highlight(ref))
+	}
+	return templates.LoadProjectTemplate(ref, homeDir)
+}
+
+// applyProjectTemplate applies a template to the project, reports the files it
+// created and returns its bootstrap scripts
+func applyProjectTemplate(tpl *templates.ProjectTemplate, projectDir, name, domain string) ([]string, error) {
+	result, err := tpl.Apply(projectDir, templates.TemplateVars{Name: name, Domain: domain})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template %s: %w", tpl.Name, err)
+	}
+
+	cli.PrintSuccess("Applied template %s to %s", cli.Highlight(tpl.Name), config.ConfigFileName)
+	for _, file := range result.Files {
+		fmt.Printf("  Created %s\n", cli.Highlight(file))
+	}
+	for _, file := range result.Skipped {
+		cli.PrintWarning("%s exists, kept the project's version", file)
+	}
+	return result.Bootstrap, nil
+}
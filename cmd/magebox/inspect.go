@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/project"
+)
+
+var (
+	inspectJSON          bool
+	inspectNoSideEffects bool
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show the fully resolved project model",
+	Long: `Shows the resolved project model: the configuration after include_config
+and .magebox.local.yaml are merged, the effective services, and previews of
+the PHP-FPM pool and Nginx vhosts MageBox would generate.
+
+Inspect never writes files, starts services or checks for updates, which makes
+it safe to run from CI validators and code review bots. Pass --no-side-effects
+to state that requirement explicitly in scripts.
+
+Examples:
+  magebox inspect
+  magebox inspect --json --no-side-effects`,
+	RunE: runInspect,
+}
+
+func init() {
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Output the project model as JSON")
+	inspectCmd.Flags().BoolVar(&inspectNoSideEffects, "no-side-effects", false, "Guarantee no files are written and no services are started (always true for inspect)")
+	rootCmd.AddCommand(inspectCmd)
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	mgr := project.NewManager(p)
+	inspection, err := mgr.Inspect(cwd)
+	if err != nil {
+		if inspectJSON {
+			return err
+		}
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	if inspectJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(inspection)
+	}
+
+	cli.PrintTitle("Project Inspection")
+	fmt.Println()
+	fmt.Printf("Project: %s\n", cli.Highlight(inspection.Name))
+	fmt.Printf("Path:    %s\n", cli.Path(inspection.Path))
+	fmt.Printf("Type:    %s\n", inspection.Type)
+	fmt.Printf("PHP:     %s\n", cli.Highlight(inspection.PHPVersion))
+
+	fmt.Println(cli.Header("Config Files"))
+	for _, f := range inspection.ConfigFiles {
+		fmt.Printf("  %s\n", cli.Path(f))
+	}
+
+	fmt.Println(cli.Header("Services"))
+	for _, svc := range inspection.Services {
+		if svc.Version != "" {
+			fmt.Printf("  %-15s %-10s %s\n", svc.Name, svc.Version, cli.Subtitle(svc.ComposeService))
+		} else {
+			fmt.Printf("  %-15s %-10s %s\n", svc.Name, "-", cli.Subtitle(svc.ComposeService))
+		}
+	}
+
+	fmt.Println(cli.Header("Generated Files"))
+	for _, f := range inspection.GeneratedFiles {
+		fmt.Printf("  %s\n", cli.Path(f.Path))
+	}
+	fmt.Println()
+	cli.PrintInfo("Use %s to include file contents", cli.Command("magebox inspect --json"))
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/lint"
+)
+
+var (
+	lintJSON   bool
+	lintStrict bool
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the Magento installation for local misconfigurations",
+	Long: `Runs read-only sanity checks against app/etc/env.php and the project database,
+typically after importing a production dump:
+
+  production-mode   MAGE_MODE is production
+  full-page-cache   The full_page cache type is disabled
+  asset-merging     JS/CSS merging, bundling or minification on in development
+  base-urls         Base URLs that do not point at the project domains
+  cron              Cron never ran or stopped running
+  indexers          Invalid indexers waiting for a reindex
+
+Exits with status 1 when an error is found (or any finding with --strict).
+
+The subcommands run code quality tools instead:
+
+  code          PHPCS, PHPStan and PHP-CS-Fixer together
+  phpcs         PHP_CodeSniffer with the Magento2 coding standard
+  phpstan       PHPStan with the Magento extension
+  cs-fixer      PHP-CS-Fixer
+  init          Create their config files (--install adds the tools)
+
+Examples:
+  magebox lint
+  magebox lint --json
+  magebox lint --strict      # Fail on warnings too
+  magebox lint code          # Run the code quality tools`,
+	RunE: runLint,
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintJSON, "json", false, "Output findings as JSON")
+	lintCmd.Flags().BoolVar(&lintStrict, "strict", false, "Exit with status 1 on warnings too")
+	rootCmd.AddCommand(lintCmd)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	ctx := &lint.Context{Now: time.Now().UTC()}
+	for _, d := range cfg.Domains {
+		ctx.Domains = append(ctx.Domains, d.Host)
+	}
+
+	var skipped []string
+	envPHP, err := readEnvPHP(filepath.Join(p.MageBoxDir(), "bin", "php"), cwd)
+	if err != nil {
+		skipped = append(skipped, fmt.Sprintf("env.php checks skipped: %v", err))
+	} else {
+		ctx.EnvPHP = envPHP
+	}
+	if db, err := getDbInfo(cfg); err != nil {
+		skipped = append(skipped, fmt.Sprintf("database checks skipped: %v", err))
+	} else {
+		querier := &mysqlQuerier{db: db, dbName: cfg.DatabaseName()}
+		if _, err := querier.Query("SELECT 1"); err != nil {
+			skipped = append(skipped, fmt.Sprintf("database checks skipped: %v (is the project running?)", err))
+		} else {
+			ctx.DB = querier
+		}
+	}
+
+	findings := lint.Run(ctx)
+
+	if lintJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]interface{}{"findings": findings, "skipped": skipped}); err != nil {
+			return err
+		}
+	} else {
+		printLintFindings(cfg, findings, skipped)
+	}
+
+	for _, f := range findings {
+		if f.Severity == lint.SeverityError || lintStrict {
+			os.Exit(1)
+		}
+	}
+	return nil
+}
+
+// printLintFindings prints findings grouped by check
+func printLintFindings(cfg *config.Config, findings []lint.Finding, skipped []string) {
+	cli.PrintTitle("Magento Lint: %s", cfg.Name)
+	fmt.Println()
+
+	for _, s := range skipped {
+		cli.PrintWarning("%s", s)
+	}
+	if len(skipped) > 0 {
+		fmt.Println()
+	}
+
+	errors, warnings := 0, 0
+	for _, check := range lint.Checks {
+		var checkFindings []lint.Finding
+		for _, f := range findings {
+			if f.Check == check.Name {
+				checkFindings = append(checkFindings, f)
+			}
+		}
+		if len(checkFindings) == 0 {
+			continue
+		}
+
+		fmt.Println(cli.Header(check.Description))
+		for _, f := range checkFindings {
+			if f.Severity == lint.SeverityError {
+				errors++
+				fmt.Printf("  %s %s\n", cli.Error(""), f.Message)
+			} else {
+				warnings++
+				fmt.Printf("  %s %s\n", cli.Warning(""), f.Message)
+			}
+			if f.Fix != "" {
+				fmt.Printf("    Fix: %s\n", cli.Command(f.Fix))
+			}
+		}
+		fmt.Println()
+	}
+
+	if errors == 0 && warnings == 0 {
+		cli.PrintSuccess("No problems found")
+		return
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n", errors, warnings)
+}
+
+// readEnvPHP decodes app/etc/env.php by including it with the project's PHP
+func readEnvPHP(phpBinary, projectPath string) (map[string]interface{}, error) {
+	envPath := filepath.Join(projectPath, "app", "etc", "env.php")
+	if _, err := os.Stat(envPath); err != nil {
+		return nil, fmt.Errorf("app/etc/env.php not found")
+	}
+	if _, err := os.Stat(phpBinary); err != nil {
+		phpBinary = "php"
+	}
+
+	cmd := exec.Command(phpBinary, "-r", `echo json_encode(include $argv[1]);`, envPath)
+	cmd.Dir = projectPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env.php: %w", err)
+	}
+
+	var env map[string]interface{}
+	if err := json.Unmarshal(out, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode env.php: %w", err)
+	}
+	return env, nil
+}
+
+// tablePrefix returns the table prefix of the project's app/etc/env.php,
+// empty when it has none or can't be read
+func tablePrefix(cwd string) string {
+	p, err := getPlatform()
+	if err != nil {
+		return ""
+	}
+	envPHP, err := readEnvPHP(filepath.Join(p.MageBoxDir(), "bin", "php"), cwd)
+	if err != nil {
+		return ""
+	}
+	db, ok := envPHP["db"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	prefix, _ := db["table_prefix"].(string)
+	return prefix
+}
+
+// mysqlQuerier runs lint queries through the mysql client in the database container
+type mysqlQuerier struct {
+	db     *dbInfo
+	dbName string
+}
+
+func (q *mysqlQuerier) Query(sql string) ([][]string, error) {
+	cmd := exec.Command("docker", "exec", q.db.ContainerName,
+		"mysql", "-u"+q.db.User, "-p"+q.db.Password,
+		"-N", "-B", q.dbName, "-e", sql)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("%s", strings.TrimSpace(lastLine(string(exitErr.Stderr))))
+		}
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows, nil
+}
+
+// lastLine returns the last non-empty line, skipping the mysql password warning
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
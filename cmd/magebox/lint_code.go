@@ -0,0 +1,275 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/testing"
+)
+
+var (
+	lintFix          bool
+	lintInitInstall  bool
+	lintInitLevel    int
+	lintCodeStandard string
+)
+
+var lintCodeCmd = &cobra.Command{
+	Use:   "code [path...]",
+	Short: "Run PHPCS, PHPStan and PHP-CS-Fixer",
+	Long: `Runs the code quality tools installed in the project with its PHP version:
+
+  phpcs         PHP_CodeSniffer with the Magento2 coding standard
+  phpstan       PHPStan with the bitexpert/phpstan-magento extension
+  cs-fixer      PHP-CS-Fixer in dry-run mode
+
+Tools that aren't installed are skipped; 'magebox lint init --install' installs
+them and drops their config files. Paths default to the testing section of
+.magebox.yaml, then app/code.
+
+Exits with status 1 when a tool fails, so it can gate CI.
+
+Examples:
+  magebox lint code
+  magebox lint code app/code/Vendor/Module`,
+	RunE: runLintCode,
+}
+
+var lintPHPCSCmd = &cobra.Command{
+	Use:   "phpcs [path...]",
+	Short: "Run PHP_CodeSniffer",
+	Long: `Runs PHP_CodeSniffer with phpcs.xml, or the Magento2 coding standard.
+
+Examples:
+  magebox lint phpcs
+  magebox lint phpcs --standard PSR12 app/code/Vendor`,
+	Run: runTestPHPCS,
+}
+
+var lintPHPStanCmd = &cobra.Command{
+	Use:   "phpstan [path...]",
+	Short: "Run PHPStan",
+	Long: `Runs PHPStan with phpstan.neon, which 'magebox lint init' sets up with the
+Magento extension.
+
+Examples:
+  magebox lint phpstan
+  magebox lint phpstan --level 5`,
+	Run: runTestPHPStan,
+}
+
+var lintCSFixerCmd = &cobra.Command{
+	Use:   "cs-fixer [path...]",
+	Short: "Run PHP-CS-Fixer",
+	Long: `Runs PHP-CS-Fixer with .php-cs-fixer.dist.php, or the PSR-12 rules. Without
+--fix it shows what it would change and exits with status 1 if anything.
+
+Examples:
+  magebox lint cs-fixer
+  magebox lint cs-fixer --fix`,
+	RunE: runLintCSFixer,
+}
+
+var lintInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create PHPCS, PHPStan and PHP-CS-Fixer config files",
+	Long: `Creates phpcs.xml, phpstan.neon and .php-cs-fixer.dist.php in the project,
+leaving the ones that exist alone. Magento projects get the Magento2 coding
+standard, Laravel projects PSR-12.
+
+--install first adds the tools to require-dev with composer.
+
+Examples:
+  magebox lint init
+  magebox lint init --install
+  magebox lint init --level 5`,
+	Args: cobra.NoArgs,
+	RunE: runLintInit,
+}
+
+func init() {
+	lintCodeCmd.Flags().StringVarP(&lintCodeStandard, "standard", "s", "", "Coding standard for PHPCS (Magento2, PSR12)")
+	lintPHPCSCmd.Flags().StringVarP(&phpcsStandard, "standard", "s", "", "Coding standard (Magento2, PSR12)")
+	lintPHPStanCmd.Flags().IntVarP(&phpstanLevel, "level", "l", -1, "PHPStan analysis level (0-9)")
+	lintCSFixerCmd.Flags().BoolVar(&lintFix, "fix", false, "Fix the files instead of showing the changes")
+	lintInitCmd.Flags().BoolVar(&lintInitInstall, "install", false, "Install the tools with composer first")
+	lintInitCmd.Flags().IntVarP(&lintInitLevel, "level", "l", -1, "PHPStan level for phpstan.neon (default 1)")
+
+	lintCmd.AddCommand(lintCodeCmd)
+	lintCmd.AddCommand(lintPHPCSCmd)
+	lintCmd.AddCommand(lintPHPStanCmd)
+	lintCmd.AddCommand(lintCSFixerCmd)
+	lintCmd.AddCommand(lintInitCmd)
+}
+
+// projectTestingConfig returns the testing section of a project config
+func projectTestingConfig(cfg *config.Config) config.TestingConfig {
+	if cfg == nil || cfg.Testing == nil {
+		return config.TestingConfig{}
+	}
+	return *cfg.Testing
+}
+
+func runLintCode(cmd *cobra.Command, args []string) error {
+	mgr, err := getTestManager()
+	if err != nil {
+		cli.PrintError("%v", err)
+		os.Exit(1)
+	}
+
+	cwd, _ := getCwd()
+	cfg, _ := loadProjectConfig(cwd)
+	testingCfg := projectTestingConfig(cfg)
+	status := mgr.GetStatus()
+
+	tools := []struct {
+		status *testing.ToolStatus
+		run    func() error
+	}{
+		{&status.PHPCS, func() error {
+			return testing.NewPHPCSRunner(mgr, testingCfg.PHPCS).Run(args, lintCodeStandard)
+		}},
+		{&status.PHPStan, func() error {
+			return testing.NewPHPStanRunner(mgr, testingCfg.PHPStan).Run(args, -1)
+		}},
+		{&status.PHPCSFixer, func() error {
+			return testing.NewPHPCSFixerRunner(mgr, testingCfg.PHPCSFixer).Run(args, false)
+		}},
+	}
+
+	var ran, failed []string
+	for _, tool := range tools {
+		if !tool.status.Installed {
+			cli.PrintWarning("%s is not installed, skipping", tool.status.Name)
+			continue
+		}
+
+		cli.PrintTitle("Running %s", tool.status.Name)
+		fmt.Println()
+		ran = append(ran, tool.status.Name)
+		if err := tool.run(); err != nil {
+			cli.PrintError("%s found problems", tool.status.Name)
+			failed = append(failed, tool.status.Name)
+		} else {
+			cli.PrintSuccess("%s passed", tool.status.Name)
+		}
+		fmt.Println()
+	}
+
+	if len(ran) == 0 {
+		cli.PrintError("No code quality tools are installed")
+		cli.PrintInfo("Run %s to install them", cli.Command("magebox lint init --install"))
+		os.Exit(1)
+	}
+
+	cli.PrintTitle("Summary")
+	if len(failed) > 0 {
+		cli.PrintError("%d of %d tools failed", len(failed), len(ran))
+		os.Exit(1)
+	}
+	cli.PrintSuccess("All checks passed!")
+	return nil
+}
+
+func runLintCSFixer(cmd *cobra.Command, args []string) error {
+	mgr, err := getTestManager()
+	if err != nil {
+		cli.PrintError("%v", err)
+		os.Exit(1)
+	}
+
+	cwd, _ := getCwd()
+	cfg, _ := loadProjectConfig(cwd)
+	runner := testing.NewPHPCSFixerRunner(mgr, projectTestingConfig(cfg).PHPCSFixer)
+
+	cli.PrintTitle("Running PHP-CS-Fixer")
+	fmt.Println()
+
+	if err := runner.Run(args, lintFix); err != nil {
+		// The fixer prints its own findings, only report why it didn't run
+		if _, ok := err.(*exec.ExitError); !ok {
+			cli.PrintError("%v", err)
+		}
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runLintInit(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+	mgr := testing.NewManager(p, cfg.PHP, cwd)
+
+	if lintInitInstall {
+		cli.PrintTitle("Installing Code Quality Tools")
+		installer := testing.NewInstaller(mgr)
+		if err := installer.InstallSelected(testing.SetupOptions{InstallPHPStan: true, InstallPHPCS: true, InstallPHPCSFixer: true}); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	testingCfg := projectTestingConfig(cfg)
+	standard := testing.DefaultPHPCSStandard()
+	if cfg.IsLaravel() {
+		standard = "PSR12"
+	}
+
+	files := []struct {
+		name     string
+		generate func() error
+	}{
+		{"phpcs.xml", func() error {
+			return testing.NewPHPCSRunner(mgr, testingCfg.PHPCS).GenerateConfig(standard, nil)
+		}},
+		{"phpstan.neon", func() error {
+			return testing.NewPHPStanRunner(mgr, testingCfg.PHPStan).GenerateConfig(lintInitLevel, nil)
+		}},
+		{".php-cs-fixer.dist.php", func() error {
+			return testing.NewPHPCSFixerRunner(mgr, testingCfg.PHPCSFixer).GenerateConfig(nil)
+		}},
+	}
+
+	status := mgr.GetStatus()
+	existing := map[string]bool{
+		"phpcs.xml":              status.PHPCS.Configured,
+		"phpstan.neon":           status.PHPStan.Configured,
+		".php-cs-fixer.dist.php": status.PHPCSFixer.Configured,
+	}
+
+	for _, f := range files {
+		if existing[f.name] {
+			fmt.Printf("  %-24s %s\n", f.name, cli.Warning("config exists, skipped"))
+			continue
+		}
+		if err := f.generate(); err != nil {
+			fmt.Printf("  %-24s %s\n", f.name, cli.Error(err.Error()))
+			continue
+		}
+		fmt.Printf("  %-24s %s\n", f.name, cli.Success("created"))
+	}
+
+	fmt.Println()
+	fmt.Printf("Run %s to check the code\n", cli.Command("magebox lint code"))
+	return nil
+}
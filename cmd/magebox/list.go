@@ -26,16 +26,26 @@ func runList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	cli.PrintTitle("MageBox Projects")
-	fmt.Println()
-
 	discovery := project.NewProjectDiscovery(p)
 	projects, err := discovery.DiscoverProjects()
 	if err != nil {
+		if structuredOutput() {
+			return fmt.Errorf("failed to discover projects: %w", err)
+		}
 		cli.PrintError("Failed to discover projects: %v", err)
 		return nil
 	}
 
+	if structuredOutput() {
+		if projects == nil {
+			projects = []project.ProjectInfo{}
+		}
+		return printStructured(projects)
+	}
+
+	cli.PrintTitle("MageBox Projects")
+	fmt.Println()
+
 	if len(projects) == 0 {
 		cli.PrintInfo("No projects found")
 		fmt.Println()
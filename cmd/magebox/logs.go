@@ -13,10 +13,16 @@ import (
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/docker"
 	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/varnish"
 )
 
 var logsFollowFlag bool
 var logsLinesFlag int
+var logsSourceFlag string
+var logsServiceFlag []string
+
+// logServices are the sources 'magebox logs --service' multiplexes
+var logServices = []string{"magento", "php", "nginx", "mysql", "redis", "varnish"}
 
 var logsCmd = &cobra.Command{
 	Use:   "logs",
@@ -28,9 +34,18 @@ Without a subcommand, opens Magento system.log and exception.log in multitail.
 Service-specific logs:
   magebox logs php      # PHP-FPM error logs
   magebox logs nginx    # Nginx access/error logs
-  magebox logs mysql    # MySQL/MariaDB container logs
+  magebox logs mysql    # MySQL/MariaDB/Percona container logs
   magebox logs redis    # Redis container logs
   magebox logs varnish  # Varnish logs
+  magebox logs queries  # Database queries (see 'magebox db querylog')
+
+--source <name> is the same as the subcommand, e.g. --source queries.
+
+--service streams several sources at once, each line prefixed with its
+source in its own color: magento (var/log), php, nginx, mysql, redis and
+varnish. Add -f to keep following them:
+  magebox logs --service nginx,php -f
+  magebox logs --service magento --service mysql -n 20
 
 Press 'q' to quit, 'b' to scroll back in history (multitail views).
 Use -f to follow (tail) file-based logs, or Ctrl+C to stop container log streams.`,
@@ -60,8 +75,8 @@ With -f, tails all matching log files.`,
 
 var logsMysqlCmd = &cobra.Command{
 	Use:   "mysql",
-	Short: "View MySQL/MariaDB logs",
-	Long: `Streams logs from the MySQL or MariaDB Docker container.
+	Short: "View MySQL/MariaDB/Percona logs",
+	Long: `Streams logs from the MySQL, MariaDB or Percona Docker container.
 
 Uses docker compose logs to stream the container output.
 Press Ctrl+C to stop.`,
@@ -87,19 +102,48 @@ Press Ctrl+C to stop.`,
 	RunE: runLogsVarnish,
 }
 
+var logsQueriesCmd = &cobra.Command{
+	Use:   "queries",
+	Short: "Stream database queries",
+	Long: `Streams the query log enabled with 'magebox db querylog on'.
+
+Magento DB logger entries that took at least the --slow threshold of the
+query log are highlighted. General log lines are filtered to the
+connections using the project database.
+Press Ctrl+C to stop.`,
+	RunE: runLogsQueries,
+}
+
 func init() {
 	logsCmd.PersistentFlags().BoolVarP(&logsFollowFlag, "follow", "f", false, "Follow log output (tail -f)")
 	logsCmd.PersistentFlags().IntVarP(&logsLinesFlag, "lines", "n", 100, "Number of lines to show")
+	logsCmd.Flags().StringVar(&logsSourceFlag, "source", "", "Log source: php, nginx, mysql, redis, varnish or queries")
+	logsCmd.Flags().StringSliceVar(&logsServiceFlag, "service", nil, "Sources to stream together: magento, php, nginx, mysql, redis, varnish")
 
 	logsCmd.AddCommand(logsPhpCmd)
 	logsCmd.AddCommand(logsNginxCmd)
 	logsCmd.AddCommand(logsMysqlCmd)
 	logsCmd.AddCommand(logsRedisCmd)
 	logsCmd.AddCommand(logsVarnishCmd)
+	logsCmd.AddCommand(logsQueriesCmd)
 	rootCmd.AddCommand(logsCmd)
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
+	if logsSourceFlag != "" {
+		for _, sub := range cmd.Commands() {
+			if sub.Name() == logsSourceFlag {
+				sub.SetContext(cmd.Context())
+				return sub.RunE(sub, args)
+			}
+		}
+		cli.PrintError("Unknown log source %q, use php, nginx, mysql, redis, varnish or queries", logsSourceFlag)
+		return nil
+	}
+	if len(logsServiceFlag) > 0 {
+		return runLogsServices(cmd, logsServiceFlag)
+	}
+
 	cwd, err := getCwd()
 	if err != nil {
 		return err
@@ -218,15 +262,9 @@ func runLogsNginx(cmd *cobra.Command, args []string) error {
 
 	// Find log files matching the project domains
 	var logFiles []string
-	for _, domain := range cfg.Domains {
-		accessLog := filepath.Join(logsDir, fmt.Sprintf("%s-access.log", domain.Host))
-		errorLog := filepath.Join(logsDir, fmt.Sprintf("%s-error.log", domain.Host))
-
-		if _, err := os.Stat(accessLog); err == nil {
-			logFiles = append(logFiles, accessLog)
-		}
-		if _, err := os.Stat(errorLog); err == nil {
-			logFiles = append(logFiles, errorLog)
+	for _, logFile := range nginxLogPaths(logsDir, cfg) {
+		if _, err := os.Stat(logFile); err == nil {
+			logFiles = append(logFiles, logFile)
 		}
 	}
 
@@ -273,10 +311,12 @@ func runLogsMysql(cmd *cobra.Command, args []string) error {
 	composeGen := docker.NewComposeGenerator(p)
 	composeFile := composeGen.ComposeFilePath()
 
-	// Determine compose service name
-	serviceName := fmt.Sprintf("%s%s", db.Type, strings.ReplaceAll(db.Version, ".", ""))
+	return streamDockerLogs(composeFile, dbComposeService(db), db.Type)
+}
 
-	return streamDockerLogs(composeFile, serviceName, db.Type)
+// dbComposeService returns the compose service name of a database
+func dbComposeService(db *dbInfo) string {
+	return fmt.Sprintf("%s%s", db.Type, strings.ReplaceAll(db.Version, ".", ""))
 }
 
 func runLogsRedis(cmd *cobra.Command, args []string) error {
@@ -312,6 +352,136 @@ func runLogsVarnish(cmd *cobra.Command, args []string) error {
 	return runVarnishLogs(cmd, args)
 }
 
+func runLogsQueries(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	s, err := activeQuerylog(p, cfg, cwd)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		cli.PrintError("Query logging is not enabled for %s", cli.Highlight(cfg.Name))
+		cli.PrintInfo("Enable it with %s", cli.Command("magebox db querylog on"))
+		return nil
+	}
+
+	return streamQuerylog(cmd, s)
+}
+
+// runLogsServices streams the logs of several sources at once, prefixing each
+// line with its source
+func runLogsServices(cmd *cobra.Command, services []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	composeFile := docker.NewComposeGenerator(p).ComposeFilePath()
+	composeLogs := func(service string) *exec.Cmd {
+		args := []string{"logs", "--no-log-prefix", "--tail", fmt.Sprintf("%d", logsLinesFlag)}
+		if logsFollowFlag {
+			args = append(args, "-f")
+		}
+		return docker.BuildComposeCmd(composeFile, append(args, service)...)
+	}
+
+	tailer := cli.NewLogTailer("", "", logsFollowFlag, logsLinesFlag)
+	for _, service := range services {
+		switch service {
+		case "magento":
+			for _, name := range []string{"system.log", "exception.log", "debug.log"} {
+				tailer.AddFile(service, filepath.Join(cwd, "var", "log", name))
+			}
+		case "php":
+			logsDir := filepath.Join(p.MageBoxDir(), "logs", "php-fpm")
+			logFiles := findProjectLogFiles(logsDir, cfg.Name)
+			if len(logFiles) == 0 {
+				cli.PrintWarning("No PHP-FPM log files found for project %s, skipping", cfg.Name)
+			}
+			for _, logFile := range logFiles {
+				tailer.AddFile(service, logFile)
+			}
+		case "nginx":
+			for _, logFile := range nginxLogPaths(filepath.Join(p.MageBoxDir(), "logs", "nginx"), cfg) {
+				tailer.AddFile(service, logFile)
+			}
+		case "mysql":
+			db, err := getDbInfo(cfg)
+			if err != nil {
+				cli.PrintWarning("%v, skipping mysql", err)
+				continue
+			}
+			tailer.AddCommand(service, composeLogs(dbComposeService(db)))
+		case "redis":
+			if !cfg.Services.HasCacheService() {
+				cli.PrintWarning("Neither Redis nor Valkey is configured, skipping redis")
+				continue
+			}
+			tailer.AddCommand(service, composeLogs(cfg.Services.GetCacheServiceName()))
+		case "varnish":
+			ctrl := varnish.NewController(p, varnish.NewVCLGenerator(p).VCLFilePath())
+			if !ctrl.IsRunning() {
+				cli.PrintWarning("Varnish is not running, skipping varnish")
+				continue
+			}
+			// Without -d varnishlog only shows new requests
+			args := []string{"exec", "magebox-varnish", "varnishlog"}
+			if !logsFollowFlag {
+				args = append(args, "-d")
+			}
+			tailer.AddCommand(service, exec.Command("docker", args...))
+		default:
+			cli.PrintError("Unknown service %q, use %s", service, strings.Join(logServices, ", "))
+			return nil
+		}
+	}
+
+	go func() {
+		<-cmd.Context().Done()
+		tailer.Stop()
+	}()
+
+	if err := tailer.Start(); err != nil {
+		cli.PrintError("%v", err)
+	}
+	return nil
+}
+
+// nginxLogPaths returns the access and error log paths of the project's domains
+func nginxLogPaths(logsDir string, cfg *config.Config) []string {
+	var paths []string
+	for _, domain := range cfg.Domains {
+		paths = append(paths,
+			filepath.Join(logsDir, fmt.Sprintf("%s-access.log", domain.Host)),
+			filepath.Join(logsDir, fmt.Sprintf("%s-error.log", domain.Host)),
+		)
+	}
+	return paths
+}
+
 // streamDockerLogs streams logs from a Docker Compose service
 func streamDockerLogs(composeFile, serviceName, displayName string) error {
 	logsArgs := []string{"logs"}
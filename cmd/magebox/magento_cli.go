@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/magentocli"
+	"qoliber/magebox/internal/platform"
+)
+
+// magentoListTimeout bounds how long completion waits for bin/magento list
+const magentoListTimeout = 30 * time.Second
+
+var magentoCliCmd = &cobra.Command{
+	Use:   "cli [command] [args...]",
+	Short: "Run bin/magento with the project PHP",
+	Long: `Runs bin/magento with the project's PHP version and env vars.
+
+Shell completion lists the Magento commands of the project, with their
+descriptions. The list is cached per project and rebuilt when bin/magento,
+app/etc/config.php or composer.lock change, e.g. after enabling a module or
+running composer.
+
+Examples:
+  magebox cli cache:flush
+  magebox cli indexer:reindex catalog_product_price
+  magebox cli setup:upgrade --keep-generated`,
+	RunE:               runMagentoCli,
+	DisableFlagParsing: true,
+	ValidArgsFunction:  completeMagentoCommands,
+}
+
+func init() {
+	rootCmd.AddCommand(magentoCliCmd)
+}
+
+func runMagentoCli(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+		cli.PrintError("bin/magento not found in %s", cwd)
+		return nil
+	}
+
+	magentoCmd := magentoCommand(context.Background(), p, cfg, cwd, args...)
+	magentoCmd.Stdin = os.Stdin
+	magentoCmd.Stdout = os.Stdout
+	magentoCmd.Stderr = os.Stderr
+	return magentoCmd.Run()
+}
+
+// magentoCommand builds a bin/magento command using the project PHP and env
+func magentoCommand(ctx context.Context, p *platform.Platform, cfg *config.Config, cwd string, args ...string) *exec.Cmd {
+	magentoCmd := exec.CommandContext(ctx, p.PHPBinary(cfg.PHP), append([]string{"bin/magento"}, args...)...)
+	magentoCmd.Dir = cwd
+	magentoCmd.Env = os.Environ()
+	for key, value := range cfg.PHPEnv() {
+		magentoCmd.Env = append(magentoCmd.Env, key+"="+value)
+	}
+	return magentoCmd
+}
+
+// completeMagentoCommands completes the first argument of 'magebox cli' with
+// the cached Magento command list, rebuilding the cache when it is stale
+func completeMagentoCommands(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	p, err := getPlatform()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	commands, err := magentoCommands(p, cfg, cwd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return magentocli.Complete(commands, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// magentoCommands returns the Magento commands of the project, from the cache
+// when it is still fresh
+func magentoCommands(p *platform.Platform, cfg *config.Config, cwd string) ([]magentocli.Command, error) {
+	cachePath := magentocli.CachePath(p.MageBoxDir(), cfg.Name)
+	fingerprint := magentocli.Fingerprint(cwd)
+
+	cache, err := magentocli.LoadCache(cachePath)
+	if err == nil && cache.Fresh(fingerprint) {
+		return cache.Commands, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), magentoListTimeout)
+	defer cancel()
+
+	listCmd := magentoCommand(ctx, p, cfg, cwd, "list", "--format", "json", "--no-ansi")
+	var out bytes.Buffer
+	listCmd.Stdout = &out
+	if err := listCmd.Run(); err != nil {
+		return nil, err
+	}
+
+	commands, err := magentocli.ParseList(out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache that can't be written only costs speed on the next completion
+	_ = (&magentocli.Cache{Fingerprint: fingerprint, Commands: commands}).Save(cachePath)
+	return commands, nil
+}
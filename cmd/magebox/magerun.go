@@ -51,7 +51,7 @@ func magerunEnv(cwd string) []string {
 			env = append(env, "PATH="+phpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
 		}
 	}
-	for key, value := range cfg.Env {
+	for key, value := range cfg.PHPEnv() {
 		env = append(env, key+"="+value)
 	}
 	return env
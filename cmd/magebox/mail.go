@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/mailpit"
+	"qoliber/magebox/internal/php"
+)
+
+var (
+	mailListJSON   bool
+	mailListLimit  int
+	mailListSearch string
+	mailLatestHTML bool
+	mailLatestText bool
+	mailClearYes   bool
+)
+
+var mailCmd = &cobra.Command{
+	Use:   "mail",
+	Short: "Inspect emails sent by the project",
+	Long: `Inspect emails sent by the project.
+
+By default mail goes to the shared Mailpit container and these commands use
+its HTTP API, so there is no port to look up or browser tab to find.
+
+When Mailpit is disabled (services.mailpit: false), PHP mail() is routed to a
+sendmail wrapper that writes every message to var/mail/*.eml instead of
+delivering it, so no test email can reach a real customer. list, clear and
+latest then work on those files.
+
+Examples:
+  magebox mail list                          # Newest emails
+  magebox mail list --search "subject:order" # Mailpit search syntax
+  magebox mail latest                        # Text body of the newest email
+  magebox mail latest --html > order.html
+  magebox mail open                          # Mailpit inbox
+  magebox mail clear`,
+}
+
+var mailListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List emails",
+	Long:  "Lists the emails in Mailpit, or captured in var/mail when Mailpit is disabled, newest first",
+	RunE:  runMailList,
+}
+
+var mailOpenCmd = &cobra.Command{
+	Use:   "open [id]",
+	Short: "Open Mailpit in the browser",
+	Long:  "Opens the Mailpit inbox, or a single email by the ID shown by 'magebox mail list --json' (latest for the newest)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runMailOpen,
+}
+
+var mailClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete all emails",
+	Long: `Deletes every email in Mailpit, or the captured emails in var/mail when
+Mailpit is disabled. Mailpit is shared, so this empties it for every project.
+Asks for confirmation unless --yes is given.`,
+	RunE: runMailClear,
+}
+
+var mailLatestCmd = &cobra.Command{
+	Use:   "latest",
+	Short: "Print the newest email",
+	Long: `Prints the headers and text body of the newest email. With --html or --text
+only that body is printed, ready to redirect to a file.
+
+When Mailpit is disabled the raw message of the newest captured email is printed.`,
+	RunE: runMailLatest,
+}
+
+func init() {
+	mailListCmd.Flags().BoolVar(&mailListJSON, "json", false, "Output emails as JSON")
+	mailListCmd.Flags().IntVarP(&mailListLimit, "limit", "n", 20, "Number of emails to list (Mailpit)")
+	mailListCmd.Flags().StringVar(&mailListSearch, "search", "", "Mailpit search query, e.g. \"to:jane@example.com subject:order\"")
+	mailLatestCmd.Flags().BoolVar(&mailLatestHTML, "html", false, "Print only the HTML body")
+	mailLatestCmd.Flags().BoolVar(&mailLatestText, "text", false, "Print only the text body")
+	mailLatestCmd.MarkFlagsMutuallyExclusive("html", "text")
+	mailClearCmd.Flags().BoolVarP(&mailClearYes, "yes", "y", false, "Skip confirmation")
+
+	mailCmd.AddCommand(mailListCmd)
+	mailCmd.AddCommand(mailOpenCmd)
+	mailCmd.AddCommand(mailClearCmd)
+	mailCmd.AddCommand(mailLatestCmd)
+	rootCmd.AddCommand(mailCmd)
+}
+
+// mailCaptured reports whether the project in cwd captures mail to var/mail.
+// Outside a project mail goes to Mailpit.
+func mailCaptured(cwd string) bool {
+	cfg, err := config.LoadFromPath(cwd)
+	return err == nil && cfg.Services.MailpitDisabled()
+}
+
+// mailpitUnreachable prints the error of a failed Mailpit API call
+func mailpitUnreachable(err error) error {
+	cli.PrintError("%v", err)
+	fmt.Println()
+	cli.PrintInfo("Start global services with: magebox global start")
+	return nil
+}
+
+func runMailList(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	if mailCaptured(cwd) {
+		return listCapturedMail(cwd)
+	}
+
+	messages, total, err := mailpit.NewClient(getMailpitURL()).List(mailListLimit, mailListSearch)
+	if err != nil {
+		return mailpitUnreachable(err)
+	}
+
+	if mailListJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(messages)
+	}
+
+	cli.PrintTitle("Mailpit")
+	fmt.Println()
+
+	if len(messages) == 0 {
+		cli.PrintInfo("No emails")
+		return nil
+	}
+
+	for _, msg := range messages {
+		subject := msg.Subject
+		if !msg.Read {
+			subject = cli.Highlight(subject)
+		}
+		fmt.Printf("  %s  %-30s %s\n",
+			cli.Subtitle(msg.Created.Local().Format("2006-01-02 15:04:05")),
+			mailpit.Recipients(msg.To),
+			subject)
+	}
+
+	fmt.Println()
+	cli.PrintInfo("%d of %d email(s), newest first", len(messages), total)
+	return nil
+}
+
+// listCapturedMail lists the emails the sendmail wrapper wrote to var/mail
+func listCapturedMail(cwd string) error {
+	messages, err := php.ListCapturedMail(cwd)
+	if err != nil {
+		cli.PrintError("Failed to read %s: %v", php.MailDir(cwd), err)
+		return nil
+	}
+
+	if mailListJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(messages)
+	}
+
+	cli.PrintTitle("Captured Emails")
+	fmt.Println()
+
+	if len(messages) == 0 {
+		cli.PrintInfo("No emails captured in %s", cli.Path(php.MailDir(cwd)))
+		return nil
+	}
+
+	for _, msg := range messages {
+		fmt.Printf("  %s  %-30s %s\n",
+			cli.Subtitle(msg.Date.Local().Format("2006-01-02 15:04:05")),
+			msg.To,
+			cli.Highlight(msg.Subject))
+		fmt.Printf("    %s\n", cli.Path(msg.Path))
+	}
+
+	fmt.Println()
+	cli.PrintInfo("%d email(s) in %s", len(messages), cli.Path(php.MailDir(cwd)))
+	return nil
+}
+
+func runMailOpen(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	if mailCaptured(cwd) {
+		cli.PrintError("Mailpit is disabled for this project, emails are captured in %s", php.MailDir(cwd))
+		return nil
+	}
+
+	url := getMailpitURL()
+	client := mailpit.NewClient(url)
+	if len(args) > 0 {
+		_, err := client.Message(args[0])
+		if errors.Is(err, mailpit.ErrNotFound) {
+			cli.PrintError("No email with ID %s", args[0])
+			return nil
+		}
+		if err != nil {
+			return mailpitUnreachable(err)
+		}
+		url = client.ViewURL(args[0])
+	} else if _, _, err := client.List(1, ""); err != nil {
+		return mailpitUnreachable(err)
+	}
+
+	cli.PrintInfo("Opening %s", cli.URL(url))
+
+	var browser *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		browser = exec.Command("open", url)
+	default:
+		browser = exec.Command("xdg-open", url)
+	}
+
+	return browser.Start()
+}
+
+func runMailClear(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	captured := mailCaptured(cwd)
+	if !mailClearYes {
+		if captured {
+			cli.PrintWarning("This will delete every email captured in %s", php.MailDir(cwd))
+		} else {
+			cli.PrintWarning("This will delete every email in Mailpit, for all projects")
+		}
+		fmt.Print("Are you sure? [y/N]: ")
+
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			cli.PrintInfo("Aborted")
+			return nil
+		}
+	}
+
+	if captured {
+		n, err := php.ClearCapturedMail(cwd)
+		if err != nil {
+			cli.PrintError("Failed to clear %s: %v", php.MailDir(cwd), err)
+			return nil
+		}
+		cli.PrintSuccess("Removed %d captured email(s)", n)
+		return nil
+	}
+
+	if err := mailpit.NewClient(getMailpitURL()).DeleteAll(); err != nil {
+		return mailpitUnreachable(err)
+	}
+	cli.PrintSuccess("Mailpit emptied")
+	return nil
+}
+
+func runMailLatest(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	if mailCaptured(cwd) {
+		if mailLatestHTML {
+			cli.PrintError("--html needs Mailpit, captured emails are printed as raw messages")
+			return nil
+		}
+		messages, err := php.ListCapturedMail(cwd)
+		if err != nil || len(messages) == 0 {
+			cli.PrintInfo("No emails captured in %s", cli.Path(php.MailDir(cwd)))
+			return nil
+		}
+		data, err := os.ReadFile(messages[0].Path)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	msg, err := mailpit.NewClient(getMailpitURL()).Message(mailpit.Latest)
+	if errors.Is(err, mailpit.ErrNotFound) {
+		cli.PrintInfo("No emails")
+		return nil
+	}
+	if err != nil {
+		return mailpitUnreachable(err)
+	}
+
+	switch {
+	case mailLatestHTML:
+		fmt.Println(msg.HTML)
+	case mailLatestText:
+		fmt.Println(msg.Text)
+	default:
+		fmt.Printf("From:    %s\n", msg.From)
+		fmt.Printf("To:      %s\n", mailpit.Recipients(msg.To))
+		fmt.Printf("Subject: %s\n", cli.Highlight(msg.Subject))
+		fmt.Printf("Date:    %s\n", msg.Date.Local().Format("2006-01-02 15:04:05"))
+		fmt.Println()
+		fmt.Println(msg.Text)
+	}
+	return nil
+}
@@ -11,10 +11,8 @@ import (
 	"qoliber/magebox/internal/cli"
 )
 
-const mailpitDefaultPort = "8025"
-
 // getMailpitURL returns the Mailpit URL, reading the actual port from the running container.
-// Falls back to the default port if the container is not running.
+// Falls back to the reserved port if the container is not running.
 func getMailpitURL() string {
 	portCmd := exec.Command("docker", "port", "magebox-mailpit", "8025")
 	output, err := portCmd.Output()
@@ -27,7 +25,7 @@ func getMailpitURL() string {
 			}
 		}
 	}
-	return fmt.Sprintf("http://localhost:%s", mailpitDefaultPort)
+	return fmt.Sprintf("http://localhost:%d", servicePorts().MailpitWebPort())
 }
 
 var mailpitCmd = &cobra.Command{
@@ -94,7 +92,7 @@ func runMailpitStatus(cmd *cobra.Command, args []string) error {
 	if err == nil && len(strings.TrimSpace(string(output))) > 0 {
 		fmt.Println("Status:  " + cli.Success("running"))
 		fmt.Printf("Web UI:  %s\n", cli.Highlight(getMailpitURL()))
-		fmt.Printf("SMTP:    %s\n", cli.Highlight("localhost:1025"))
+		fmt.Printf("SMTP:    %s\n", cli.Highlight(fmt.Sprintf("localhost:%d", servicePorts().MailpitSMTPPort())))
 	} else {
 		fmt.Println("Status:  " + cli.Warning("stopped"))
 		fmt.Println()
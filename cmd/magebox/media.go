@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/media"
+	"qoliber/magebox/internal/platform"
+)
+
+var (
+	mediaOptimizeForeground bool
+	mediaOptimizeWebP       bool
+	mediaOptimizeQuality    int
+	mediaOptimizePause      time.Duration
+	mediaOptimizeDryRun     bool
+)
+
+var mediaCmd = &cobra.Command{
+	Use:   "media",
+	Short: "Media file operations",
+	Long:  "Commands for working with the project's pub/media directory",
+}
+
+var mediaOptimizeCmd = &cobra.Command{
+	Use:   "optimize",
+	Short: "Optimize pub/media images in the background",
+	Long: `Compresses the JPEG and PNG images in pub/media in place with jpegoptim and
+pngquant, optionally writing a WebP copy next to each image with cwebp.
+
+The job runs in the background at the lowest CPU and I/O priority and keeps a
+manifest of processed files, so later runs only touch new or replaced images
+and a stopped job resumes where it left off. Generated image caches
+(catalog/product/cache) and tmp/import directories are skipped.
+
+Examples:
+  magebox media optimize              # Start the background job
+  magebox media optimize --webp       # Also write .webp copies
+  magebox media optimize --dry-run    # Show what would be processed
+  magebox media optimize status       # Show progress
+  magebox media optimize stop         # Stop the background job`,
+	RunE: runMediaOptimize,
+}
+
+var mediaOptimizeStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show image optimization progress",
+	RunE:  runMediaOptimizeStatus,
+}
+
+var mediaOptimizeStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the background image optimization",
+	RunE:  runMediaOptimizeStop,
+}
+
+func init() {
+	mediaOptimizeCmd.Flags().BoolVar(&mediaOptimizeForeground, "foreground", false, "Run in the foreground instead of as a background job")
+	mediaOptimizeCmd.Flags().BoolVar(&mediaOptimizeWebP, "webp", false, "Write a .webp copy next to each image")
+	mediaOptimizeCmd.Flags().IntVar(&mediaOptimizeQuality, "quality", media.DefaultQuality, "Maximum JPEG quality and WebP quality")
+	mediaOptimizeCmd.Flags().DurationVar(&mediaOptimizePause, "pause", 50*time.Millisecond, "Pause between files")
+	mediaOptimizeCmd.Flags().BoolVar(&mediaOptimizeDryRun, "dry-run", false, "List the images that would be optimized")
+
+	mediaOptimizeCmd.AddCommand(mediaOptimizeStatusCmd)
+	mediaOptimizeCmd.AddCommand(mediaOptimizeStopCmd)
+	mediaCmd.AddCommand(mediaOptimizeCmd)
+	rootCmd.AddCommand(mediaCmd)
+}
+
+func runMediaOptimize(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	mediaDir := filepath.Join(cwd, "pub", "media")
+	if _, err := os.Stat(mediaDir); os.IsNotExist(err) {
+		cli.PrintError("No pub/media directory in %s", cwd)
+		return nil
+	}
+
+	opts := media.Options{Quality: mediaOptimizeQuality, WebP: mediaOptimizeWebP, Pause: mediaOptimizePause}
+	tools := media.DetectTools()
+	if missing := tools.Missing(opts); len(missing) > 0 {
+		cli.PrintError("Missing image optimizers: %v", missing)
+		cli.PrintInfo("Install them with your package manager, e.g. %s", cli.Command("brew install jpegoptim pngquant webp"))
+		return nil
+	}
+
+	pidFile := getMediaOptimizePidFile(p, cfg.Name)
+	if pid, err := readPidFile(pidFile); err == nil && processRunning(pid) && pid != os.Getpid() {
+		cli.PrintWarning("Image optimization is already running for this project (PID %d)", pid)
+		cli.PrintInfo("Check progress with %s", cli.Command("magebox media optimize status"))
+		return nil
+	}
+
+	manifestPath := getMediaManifestPath(p, cfg.Name)
+	manifest, err := media.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := media.Scan(mediaDir, manifest, opts)
+	if err != nil {
+		return fmt.Errorf("failed to scan pub/media: %w", err)
+	}
+
+	if mediaOptimizeDryRun {
+		for _, f := range files {
+			fmt.Println(f)
+		}
+		cli.PrintInfo("%d image(s) would be optimized, %d already done", len(files), len(manifest.Files))
+		return nil
+	}
+
+	if len(files) == 0 {
+		cli.PrintSuccess("All images in pub/media are already optimized")
+		return nil
+	}
+
+	if !mediaOptimizeForeground {
+		return startMediaOptimizeJob(p, cfg.Name, cwd, len(files))
+	}
+
+	// Stop cleanly on Ctrl+C or 'magebox media optimize stop', keeping the manifest
+	commandHandlesInterrupt.Store(true)
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := writePidFile(pidFile, os.Getpid()); err != nil {
+		return err
+	}
+	defer os.Remove(pidFile)
+
+	optimizer := &media.Optimizer{
+		Dir:          mediaDir,
+		ManifestPath: manifestPath,
+		Manifest:     manifest,
+		Tools:        tools,
+		Options:      opts,
+	}
+
+	fmt.Printf("Optimizing %d image(s) in %s\n", len(files), mediaDir)
+	var done, failed int
+	var before, after int64
+	err = optimizer.Run(ctx, files, func(r media.Result) {
+		done++
+		if r.Err != nil {
+			failed++
+			fmt.Printf("[%d/%d] %s: %v\n", done, len(files), r.Path, r.Err)
+			return
+		}
+		before += r.Before
+		after += r.After
+		fmt.Printf("[%d/%d] %s %s -> %s\n", done, len(files), r.Path, formatFileSize(r.Before), formatFileSize(r.After))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		fmt.Printf("Stopped after %d of %d image(s)\n", done, len(files))
+	}
+	fmt.Printf("Done: %d image(s), %d failed, saved %s\n", done, failed, formatFileSize(before-after))
+	return nil
+}
+
+// startMediaOptimizeJob re-runs the command with --foreground as a detached
+// process writing to the media optimize log
+func startMediaOptimizeJob(p *platform.Platform, projectName, cwd string, count int) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	logPath := getMediaOptimizeLogPath(p, projectName)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	jobArgs := []string{"media", "optimize", "--foreground",
+		"--quality", strconv.Itoa(mediaOptimizeQuality), "--pause", mediaOptimizePause.String()}
+	if mediaOptimizeWebP {
+		jobArgs = append(jobArgs, "--webp")
+	}
+
+	job := exec.Command(exe, jobArgs...)
+	job.Dir = cwd
+	job.Stdout = logFile
+	job.Stderr = logFile
+	job.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := job.Start(); err != nil {
+		return fmt.Errorf("failed to start background job: %w", err)
+	}
+	if err := writePidFile(getMediaOptimizePidFile(p, projectName), job.Process.Pid); err != nil {
+		return err
+	}
+	_ = job.Process.Release()
+
+	cli.PrintSuccess("Optimizing %d image(s) in the background (PID %d)", count, job.Process.Pid)
+	cli.PrintInfo("Progress: %s", cli.Command("magebox media optimize status"))
+	cli.PrintInfo("Log:      %s", cli.Path(logPath))
+	return nil
+}
+
+func runMediaOptimizeStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	manifest, err := media.LoadManifest(getMediaManifestPath(p, cfg.Name))
+	if err != nil {
+		return err
+	}
+
+	cli.PrintTitle("Media Optimization")
+	fmt.Printf("Project:   %s\n", cli.Highlight(cfg.Name))
+
+	pid, err := readPidFile(getMediaOptimizePidFile(p, cfg.Name))
+	if err == nil && processRunning(pid) {
+		fmt.Printf("Status:    %s (PID %d)\n", cli.Success("running"), pid)
+		if last := lastLogLine(getMediaOptimizeLogPath(p, cfg.Name)); last != "" {
+			fmt.Printf("Progress:  %s\n", last)
+		}
+	} else {
+		fmt.Printf("Status:    %s\n", cli.Warning("not running"))
+	}
+
+	original, current := manifest.Savings()
+	fmt.Printf("Optimized: %d image(s)\n", len(manifest.Files))
+	if original > 0 {
+		fmt.Printf("Saved:     %s (%s -> %s, %.0f%%)\n", formatFileSize(original-current),
+			formatFileSize(original), formatFileSize(current), float64(original-current)*100/float64(original))
+	}
+	return nil
+}
+
+func runMediaOptimizeStop(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	pidFile := getMediaOptimizePidFile(p, cfg.Name)
+	pid, err := readPidFile(pidFile)
+	if err != nil || !processRunning(pid) {
+		cli.PrintInfo("No image optimization is running for this project")
+		os.Remove(pidFile)
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process: %w", err)
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to stop image optimization: %w", err)
+	}
+
+	cli.PrintSuccess("Image optimization stopped; run %s to resume", cli.Command("magebox media optimize"))
+	return nil
+}
+
+// lastLogLine returns the last line of a log file
+func lastLogLine(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// The log grows by one line per image, only its end is needed
+	const tailSize = 4096
+	if info, err := f.Stat(); err == nil && info.Size() > tailSize {
+		_, _ = f.Seek(-tailSize, io.SeekEnd)
+	}
+	data, _ := io.ReadAll(f)
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func getMediaOptimizePidFile(p *platform.Platform, projectName string) string {
+	return filepath.Join(p.MageBoxDir(), "run", fmt.Sprintf("media-optimize-%s.pid", projectName))
+}
+
+func getMediaOptimizeLogPath(p *platform.Platform, projectName string) string {
+	return filepath.Join(p.MageBoxDir(), "logs", fmt.Sprintf("media-optimize-%s.log", projectName))
+}
+
+func getMediaManifestPath(p *platform.Platform, projectName string) string {
+	return filepath.Join(p.MageBoxDir(), "media", projectName+".json")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+)
+
+var modeSkipCompilation bool
+
+var modeCmd = &cobra.Command{
+	Use:   "mode [developer|production|default]",
+	Short: "Show or switch the Magento deploy mode",
+	Long: `Shows or switches the Magento deploy mode of the current project.
+
+Switching runs bin/magento deploy:mode:set and adjusts the environment to
+match, so Magento and the web server agree on the mode:
+
+  Mode         opcache.validate_timestamps   /static/ caching
+  developer    1                             no-cache
+  production   0                             expires max
+  default      1                             no-cache
+
+The mode is stored as MAGE_MODE in .magebox.local.yaml and shown by
+'magebox status'. php_ini settings in the project config still win over the
+mode's defaults.
+
+Examples:
+  magebox mode                                  # show the current mode
+  magebox mode production                       # compile, deploy static content and switch
+  magebox mode production --skip-compilation    # switch without compiling
+  magebox mode developer`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: config.MageModes,
+	RunE:      runMode,
+}
+
+func init() {
+	modeCmd.Flags().BoolVarP(&modeSkipCompilation, "skip-compilation", "s", false, "Skip clearing and regenerating static content and compiled code")
+	rootCmd.AddCommand(modeCmd)
+}
+
+func runMode(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if len(args) == 0 {
+		showMode(p, cfg, cwd)
+		return nil
+	}
+
+	mode := args[0]
+	if !config.IsMageMode(mode) {
+		cli.PrintError("Unknown mode '%s' (expected developer, production or default)", mode)
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+		cli.PrintError("bin/magento not found in %s", cwd)
+		return nil
+	}
+
+	// bin/magento must not see the old mode in its environment
+	env := make(map[string]string, len(cfg.Env)+1)
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	env["MAGE_MODE"] = mode
+	cfg.Env = env
+
+	cli.PrintTitle("Switching to %s mode", mode)
+	fmt.Println()
+
+	setArgs := []string{"deploy:mode:set", mode}
+	if modeSkipCompilation {
+		setArgs = append(setArgs, "--skip-compilation")
+	}
+	setCmd := magentoCommand(context.Background(), p, cfg, cwd, setArgs...)
+	setCmd.Stdin = os.Stdin
+	setCmd.Stdout = os.Stdout
+	setCmd.Stderr = os.Stderr
+	if err := setCmd.Run(); err != nil {
+		cli.PrintError("bin/magento deploy:mode:set failed: %v", err)
+		return nil
+	}
+
+	localCfg, err := config.LoadLocalConfig(cwd)
+	if err != nil {
+		localCfg = &config.LocalConfig{}
+	}
+	if localCfg.Env == nil {
+		localCfg.Env = make(map[string]string)
+	}
+	localCfg.Env["MAGE_MODE"] = mode
+	if err := config.SaveLocalConfig(cwd, localCfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Print("Regenerating PHP-FPM pool and Nginx vhost... ")
+	if err := project.NewManager(p).RegenerateConfigs(cwd); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to regenerate configs: %v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	fmt.Print("Reloading PHP-FPM... ")
+	if err := php.NewFPMController(p, cfg.PHP).Reload(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to reload PHP-FPM: %v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	fmt.Print("Reloading Nginx... ")
+	ngxController := nginx.NewController(p)
+	if err := ngxController.Test(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Nginx config test failed: %v", err)
+	} else if err := ngxController.Reload(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to reload Nginx: %v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("Magento runs in %s mode", mode)
+	printModeSettings(cfg)
+	return nil
+}
+
+// showMode prints the configured deploy mode and warns when env.php disagrees
+func showMode(p *platform.Platform, cfg *config.Config, cwd string) {
+	mode := cfg.MageMode()
+	fmt.Printf("Mode: %s\n", cli.Highlight(mode))
+	printModeSettings(cfg)
+
+	if envMode := envPHPMode(p, cwd); envMode != "" && envMode != mode {
+		fmt.Println()
+		cli.PrintWarning("app/etc/env.php is set to %s mode; run 'magebox mode %s' to switch properly", envMode, envMode)
+	}
+}
+
+// printModeSettings prints the environment settings that follow the deploy mode
+func printModeSettings(cfg *config.Config) {
+	static := "no-cache"
+	if cfg.MageMode() == config.ModeProduction {
+		static = "expires max"
+	}
+	fmt.Printf("  opcache.validate_timestamps = %s\n", cli.Highlight(cfg.PoolPHPINI()["opcache.validate_timestamps"]))
+	fmt.Printf("  /static/ caching            = %s\n", cli.Highlight(static))
+}
+
+// envPHPMode returns MAGE_MODE from app/etc/env.php, empty when it can't be read
+func envPHPMode(p *platform.Platform, cwd string) string {
+	env, err := readEnvPHP(filepath.Join(p.MageBoxDir(), "bin", "php"), cwd)
+	if err != nil {
+		return ""
+	}
+	mode, _ := env["MAGE_MODE"].(string)
+	return mode
+}
@@ -2,39 +2,40 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/composer"
 	"qoliber/magebox/internal/config"
-	"qoliber/magebox/internal/docker"
 	libconfig "qoliber/magebox/internal/lib/config"
-	"qoliber/magebox/internal/nginx"
 	"qoliber/magebox/internal/php"
 	"qoliber/magebox/internal/platform"
-	"qoliber/magebox/internal/project"
 	"qoliber/magebox/internal/templates"
 )
 
 var newCmd = &cobra.Command{
 	Use:   "new [directory]",
 	Short: "Create a new Magento/MageOS project",
-	Long: `Creates a new Magento or MageOS project with interactive setup wizard.
+	Long: `Creates a new Magento, Adobe Commerce or MageOS project with interactive setup wizard.
 
 This command will guide you through:
-  1. Selecting Magento or MageOS distribution
+  1. Selecting Magento, Adobe Commerce (with optional B2B) or MageOS
   2. Choosing the version to install
   3. Configuring Composer authentication
   4. Selecting PHP version
   5. Choosing services (MySQL, Redis, OpenSearch, etc.)
   6. Optional sample data installation
-  7. Database setup
+  7. Project name and domain
+  8. Installing Magento: starting the services and running setup:install,
+     sample data deploy and reindex, or printing the command to run
 
 Quick Mode (--quick):
   Skip all questions and install MageOS with sensible defaults:
@@ -43,15 +44,32 @@ Quick Mode (--quick):
   - Sample data included
   - Domain: {directory}.test
 
+Versions:
+  The Magento and MageOS releases are fetched from Packagist and the Mage-OS
+  repository and cached for a day, see 'magebox versions'. Without a
+  connection the cached or bundled releases are offered; --offline skips
+  fetching.
+
+Adobe Commerce:
+  Requires access keys licensed for Adobe Commerce (and B2B, if selected),
+  which are checked against repo.magento.com before installing. RabbitMQ is
+  always enabled, and the checkout and sales databases can be split off.
+
 Hyvä Theme (--hyva):
   Install the Hyvä theme alongside Magento/MageOS.
   Requires your Hyvä Private Packagist repository URL (prompted if not configured).
 
+Templates (--template):
+  Seed .magebox.yaml, nginx snippets and bootstrap scripts from a built-in
+  template (hyva, b2b, headless), a directory in ~/.magebox/templates, a path
+  or a git URL. See 'magebox init --help'.
+
 Example:
   magebox new mystore              # Interactive wizard
   magebox new mystore --quick      # Quick install with defaults + sample data
   magebox new mystore --quick --hyva  # Quick install with Hyvä theme
-  magebox new . --quick            # Quick install in current directory`,
+  magebox new . --quick            # Quick install in current directory
+  magebox new mystore --template b2b  # Wizard, seeded from the B2B template`,
 	Args: cobra.ExactArgs(1),
 	RunE: runNew,
 }
@@ -61,12 +79,16 @@ var (
 	newQuick      bool
 	newWithSample bool
 	newHyva       bool
+	newTemplate   string
+	newOffline    bool
 )
 
 func init() {
 	newCmd.Flags().BoolVarP(&newQuick, "quick", "q", false, "Quick install with defaults (MageOS + sample data)")
 	newCmd.Flags().BoolVar(&newWithSample, "with-sample", false, "Include sample data (used with --quick)")
 	newCmd.Flags().BoolVar(&newHyva, "hyva", false, "Install Hyvä theme")
+	newCmd.Flags().BoolVar(&newOffline, "offline", false, "Offer the bundled releases without fetching the current ones")
+	newCmd.Flags().StringVar(&newTemplate, "template", "", "Seed the project from a template: "+strings.Join(templates.BuiltinProjectTemplateNames(), ", ")+", a directory or a git URL")
 	rootCmd.AddCommand(newCmd)
 }
 
@@ -81,16 +103,9 @@ type MagentoVersion struct {
 
 // Distribution types
 const (
-	DistMagento = "magento"
-	DistMageOS  = "mageos"
-)
-
-// Service readiness configuration
-const (
-	// OpenSearchReadinessMaxRetries is the number of retries when waiting for OpenSearch
-	OpenSearchReadinessMaxRetries = 30
-	// OpenSearchReadinessRetryInterval is the time between readiness checks
-	OpenSearchReadinessRetryInterval = 2 * time.Second
+	DistMagento  = "magento"
+	DistMageOS   = "mageos"
+	DistCommerce = "commerce"
 )
 
 // Default service versions for quick install
@@ -113,9 +128,8 @@ const (
 	DefaultAdminEmail    = "admin@example.com"
 )
 
-// Redis default ports and database numbers
+// Redis database numbers
 const (
-	RedisDefaultPort     = 6379
 	RedisSessionDB       = 2
 	RedisCacheDB         = 0
 	RedisFullPageCacheDB = 1
@@ -123,14 +137,19 @@ const (
 
 // RabbitMQ defaults
 const (
-	RabbitMQDefaultPort = 5672
 	RabbitMQDefaultUser = "guest"
 	RabbitMQDefaultPass = "guest"
 )
 
-// loadVersions loads and converts versions from the config
+// loadVersions loads the releases from the version catalog, warning when
+// the current ones can't be fetched
 func loadVersions(p *platform.Platform) (*libconfig.VersionsConfig, error) {
-	return libconfig.LoadVersions(p.MageBoxDir())
+	result, err := versionCatalog(p, newOffline, false).Load()
+	if err != nil {
+		return nil, err
+	}
+	warnCatalogFallback(result)
+	return result.Versions, nil
 }
 
 // getMagentoVersions returns Magento versions from config
@@ -163,6 +182,60 @@ func getMageOSVersions(cfg *libconfig.VersionsConfig) []MagentoVersion {
 	return versions
 }
 
+// getCommerceVersions returns Adobe Commerce versions, which follow the
+// Magento Open Source releases
+func getCommerceVersions(cfg *libconfig.VersionsConfig) []MagentoVersion {
+	versions := getMagentoVersions(cfg)
+	for i := range versions {
+		versions[i].Name = strings.Replace(versions[i].Name, "Magento", "Adobe Commerce", 1)
+		versions[i].Package = composer.CommerceProjectPackage
+	}
+	return versions
+}
+
+// checkCommerceLicense checks the access keys against repo.magento.com.
+// Only a rejected license stops the wizard; network errors are a warning.
+func checkCommerceLicense(creds composer.Credentials, b2b bool) bool {
+	packages := []string{composer.CommerceProductPackage}
+	if b2b {
+		packages = append(packages, composer.B2BPackage)
+	}
+
+	fmt.Print("  Checking license... ")
+	err := composer.CheckPackageAccess(creds, packages...)
+	var accessErr *composer.PackageAccessError
+	switch {
+	case err == nil:
+		fmt.Println(cli.Success("✓"))
+		return true
+	case errors.Is(err, composer.ErrInvalidKeys), errors.As(err, &accessErr):
+		fmt.Println(cli.Error("✗"))
+		cli.PrintError("%v", err)
+		fmt.Println("  Check the keys of the account that holds the license at " + cli.URL("https://commercemarketplace.adobe.com/customer/accessKeys/"))
+		return false
+	default:
+		fmt.Println(cli.Warning("?"))
+		cli.PrintWarning("Could not verify the license: %v", err)
+		return true
+	}
+}
+
+// splitDatabaseCommands returns the commands that move the checkout and sales
+// tables of Adobe Commerce into databases of their own
+func splitDatabaseCommands(dbName, dbPort string) []string {
+	var commands []string
+	for _, split := range []struct{ command, suffix string }{
+		{"setup:db-schema:split-quote", "quote"},
+		{"setup:db-schema:split-sales", "sales"},
+	} {
+		splitDB := dbName + "_" + split.suffix
+		commands = append(commands,
+			fmt.Sprintf("mysql -h127.0.0.1 -P%s -u%s -p%s -e 'CREATE DATABASE IF NOT EXISTS %s'", dbPort, DefaultDBUser, DefaultDBPassword, splitDB),
+			fmt.Sprintf("php bin/magento %s --host=127.0.0.1:%s --dbname=%s --username=%s --password=%s", split.command, dbPort, splitDB, DefaultDBUser, DefaultDBPassword))
+	}
+	return commands
+}
+
 // findRealComposer finds the real composer binary, skipping our wrapper
 func findRealComposer(p *platform.Platform) (string, error) {
 	// Our wrapper is in ~/.magebox/bin/composer - we need to skip it
@@ -246,9 +319,19 @@ func runNew(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Load the template up front, so a typo doesn't fail after composer install
+	var tpl *templates.ProjectTemplate
+	if newTemplate != "" {
+		if tpl, err = loadProjectTemplate(newTemplate); err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		defer func() { _ = tpl.Close() }()
+	}
+
 	// Quick mode - skip all questions, use sensible defaults
 	if newQuick {
-		return runNewQuick(targetDir, p)
+		return runNewQuick(targetDir, p, tpl)
 	}
 
 	// Load versions from config
@@ -265,6 +348,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("  [1] Magento Open Source (Adobe)")
 	fmt.Println("  [2] MageOS (Community Fork)")
+	fmt.Println("  [3] Adobe Commerce (license required)")
 	fmt.Println()
 	fmt.Print("Select distribution [1]: ")
 
@@ -276,11 +360,16 @@ func runNew(cmd *cobra.Command, args []string) error {
 
 	var distribution string
 	var versions []MagentoVersion
-	if distChoice == "2" {
+	switch distChoice {
+	case "2":
 		distribution = DistMageOS
 		versions = getMageOSVersions(versionsCfg)
 		fmt.Println("  → MageOS selected")
-	} else {
+	case "3":
+		distribution = DistCommerce
+		versions = getCommerceVersions(versionsCfg)
+		fmt.Println("  → Adobe Commerce selected")
+	default:
 		distribution = DistMagento
 		versions = getMagentoVersions(versionsCfg)
 		fmt.Println("  → Magento Open Source selected")
@@ -317,6 +406,22 @@ func runNew(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  → %s selected\n", selectedVersion.Name)
 	fmt.Println()
 
+	// B2B is an extension of Adobe Commerce
+	var installB2B bool
+	if distribution == DistCommerce {
+		if _, err := templates.B2BConstraint(selectedVersion.Version); err != nil {
+			cli.PrintWarning("B2B is not available for Adobe Commerce %s", selectedVersion.Version)
+		} else {
+			fmt.Print("Install the B2B extension? [y/N]: ")
+			b2bChoice, _ := reader.ReadString('\n')
+			installB2B = strings.ToLower(strings.TrimSpace(b2bChoice)) == "y"
+			if installB2B {
+				fmt.Println("  → B2B selected")
+			}
+		}
+		fmt.Println()
+	}
+
 	// Step 3: PHP Version
 	fmt.Println(cli.Header("Step 3: PHP Version"))
 	fmt.Println()
@@ -361,26 +466,20 @@ func runNew(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Step 4: Composer Authentication (for Magento only)
+	// Step 4: Composer Authentication (for Magento and Adobe Commerce)
 	var composerUser, composerPass string
-	if distribution == DistMagento {
+	if distribution != DistMageOS {
 		fmt.Println(cli.Header("Step 4: Composer Authentication"))
 		fmt.Println()
 		fmt.Println("  Magento requires authentication keys from marketplace.magento.com")
 		fmt.Println("  Get your keys at: " + cli.URL("https://marketplace.magento.com/customer/accessKeys/"))
 		fmt.Println()
 
-		// Check for existing auth.json
+		// Reuse the keys of auth.json, see 'magebox auth'
 		homeDir, _ := os.UserHomeDir()
-		authFile := filepath.Join(homeDir, ".composer", "auth.json")
-		hasAuth := false
-		if _, err := os.Stat(authFile); err == nil {
-			// Check if repo.magento.com exists in auth.json
-			authContent, _ := os.ReadFile(authFile)
-			if strings.Contains(string(authContent), "repo.magento.com") {
-				hasAuth = true
-				fmt.Println("  " + cli.Success("✓") + " Found existing Composer authentication")
-			}
+		existingCreds, hasAuth := composer.FindHTTPBasic(homeDir, composer.MagentoRepoHost)
+		if hasAuth {
+			fmt.Println("  " + cli.Success("✓") + " Found existing Composer authentication")
 		}
 
 		if !hasAuth {
@@ -397,6 +496,18 @@ func runNew(cmd *cobra.Command, args []string) error {
 				return nil
 			}
 		}
+
+		// The Commerce packages are only served to licensed keys, so catch
+		// a missing license before Composer fails halfway
+		if distribution == DistCommerce {
+			creds := existingCreds
+			if !hasAuth {
+				creds = composer.Credentials{Username: composerUser, Password: composerPass}
+			}
+			if !checkCommerceLicense(creds, installB2B) {
+				return nil
+			}
+		}
 		fmt.Println()
 	} else {
 		fmt.Println(cli.Header("Step 4: Composer Authentication"))
@@ -415,6 +526,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 	fmt.Println("  [2]   MySQL 8.4")
 	fmt.Println("  [3]   MariaDB 10.6")
 	fmt.Println("  [4]   MariaDB 11.4")
+	fmt.Println("  [5]   Percona Server 8.0")
 	fmt.Println()
 	fmt.Print("Select database [1]: ")
 
@@ -432,10 +544,14 @@ func runNew(cmd *cobra.Command, args []string) error {
 		dbService, dbVersion = "mariadb", "10.6"
 	case "4":
 		dbService, dbVersion = "mariadb", "11.4"
+	case "5":
+		dbService, dbVersion = "percona", "8.0"
 	default:
 		dbService, dbVersion = "mysql", "8.0"
 	}
 	fmt.Printf("  → %s %s selected\n", titleCase(dbService), dbVersion)
+	compat := compatibilityFor(versionsCfg, distribution, selectedVersion.Version)
+	warnIncompatible(compat, selectedVersion.Name, dbService, dbVersion)
 	fmt.Println()
 
 	// Search engine
@@ -463,6 +579,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 	if searchEngine != "" {
 		fmt.Printf("  → %s %s selected\n", titleCase(searchEngine), searchVersion)
+		warnIncompatible(compat, selectedVersion.Name, searchEngine, searchVersion)
 	} else {
 		fmt.Println("  → No search engine (MySQL search)")
 	}
@@ -490,9 +607,20 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
-	fmt.Print("  Enable RabbitMQ? [y/N]: ")
-	rabbitChoice, _ := reader.ReadString('\n')
-	enableRabbitMQ := strings.ToLower(strings.TrimSpace(rabbitChoice)) == "y"
+	// Adobe Commerce uses the message queue for its own features
+	var enableRabbitMQ, splitDB bool
+	if distribution == DistCommerce {
+		enableRabbitMQ = true
+		fmt.Println("  RabbitMQ: required by Adobe Commerce")
+
+		fmt.Print("  Split the checkout and sales databases? [y/N]: ")
+		splitChoice, _ := reader.ReadString('\n')
+		splitDB = strings.ToLower(strings.TrimSpace(splitChoice)) == "y"
+	} else {
+		fmt.Print("  Enable RabbitMQ? [y/N]: ")
+		rabbitChoice, _ := reader.ReadString('\n')
+		enableRabbitMQ = strings.ToLower(strings.TrimSpace(rabbitChoice)) == "y"
+	}
 
 	// Mailpit is always enabled by default for email testing
 	enableMailpit := true
@@ -544,11 +672,23 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
+	// Step 8: Installation
+	fmt.Println(cli.Header("Step 8: Magento Installation"))
+	fmt.Println()
+	fmt.Println("  MageBox can start the services and run setup:install once Composer is done.")
+	fmt.Print("  Install Magento? [Y/n]: ")
+	installChoice, _ := reader.ReadString('\n')
+	runInstall := strings.ToLower(strings.TrimSpace(installChoice)) != "n"
+	fmt.Println()
+
 	// Summary
 	fmt.Println(cli.Header("Summary"))
 	fmt.Println()
 	fmt.Printf("  Distribution:    %s\n", cli.Highlight(distribution))
 	fmt.Printf("  Version:         %s\n", cli.Highlight(selectedVersion.Version))
+	if distribution == DistCommerce {
+		fmt.Printf("  B2B:             %s\n", cli.Status(installB2B))
+	}
 	fmt.Printf("  PHP:             %s\n", cli.Highlight(selectedPHP))
 	fmt.Printf("  Database:        %s %s\n", cli.Highlight(dbService), cli.Highlight(dbVersion))
 	if searchEngine != "" {
@@ -560,6 +700,9 @@ func runNew(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Cache:           %s\n", cli.Status(enableRedis))
 	}
 	fmt.Printf("  RabbitMQ:        %s\n", cli.Status(enableRabbitMQ))
+	if distribution == DistCommerce {
+		fmt.Printf("  Split Database:  %s\n", cli.Status(splitDB))
+	}
 	fmt.Printf("  Mailpit:         %s\n", cli.Status(enableMailpit))
 	fmt.Printf("  Sample Data:     %s\n", cli.Status(installSampleData))
 	if newHyva {
@@ -567,6 +710,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("  Project:         %s\n", cli.Highlight(projectName))
 	fmt.Printf("  Domain:          %s\n", cli.URL("https://"+domainInput))
+	fmt.Printf("  Install Magento: %s\n", cli.Status(runInstall))
 	fmt.Println()
 
 	fmt.Print("Proceed with installation? [Y/n]: ")
@@ -577,19 +721,17 @@ func runNew(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
-	// Get the correct PHP binary and composer path for this version
+	// Get the correct PHP binary for this version and make sure Composer is installed
 	phpBin := p.PHPBinary(selectedPHP)
-	composerPath, err := findRealComposer(p)
-	if err != nil {
+	if _, err := findRealComposer(p); err != nil {
 		return fmt.Errorf("composer not found: %w", err)
 	}
 
 	// Check Hyvä Composer repository URL if --hyva is set
-	composerWrapperPath := filepath.Join(p.MageBoxDir(), "bin", "composer")
 	var hyvaRepoURL string
 	if newHyva {
 		var err error
-		hyvaRepoURL, err = ensureHyvaRepoURL(composerWrapperPath)
+		hyvaRepoURL, err = ensureHyvaRepoURL(p.HomeDir)
 		if err != nil {
 			return err
 		}
@@ -598,11 +740,10 @@ func runNew(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Set up Composer auth if needed (use explicit PHP to avoid shebang issues)
+	// Save the Composer keys, merged into the user's auth.json
 	if composerUser != "" && composerPass != "" {
 		cli.PrintInfo("Configuring Composer authentication...")
-		authCmd := exec.Command(phpBin, composerPath, "config", "--global", "http-basic.repo.magento.com", composerUser, composerPass)
-		if err := authCmd.Run(); err != nil {
+		if err := saveComposerHTTPBasic(p.HomeDir, composer.MagentoRepoHost, composerUser, composerPass); err != nil {
 			cli.PrintWarning("Failed to configure Composer auth: %v", err)
 		}
 	}
@@ -618,6 +759,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 
 	// Create .magebox.yaml with PHP version so our wrapper uses it
 	cli.PrintInfo("Creating MageBox configuration...")
+	events.Phase("config", 0, "Creating MageBox configuration")
 
 	mageboxConfig := fmt.Sprintf(`name: %s
 domains:
@@ -629,11 +771,7 @@ services:
 `, projectName, domainInput, selectedPHP)
 
 	// Add database
-	if dbService == "mysql" {
-		mageboxConfig += fmt.Sprintf("  mysql: \"%s\"\n", dbVersion)
-	} else {
-		mageboxConfig += fmt.Sprintf("  mariadb: \"%s\"\n", dbVersion)
-	}
+	mageboxConfig += fmt.Sprintf("  %s: \"%s\"\n", dbService, dbVersion)
 
 	// Add search
 	if searchEngine == "opensearch" {
@@ -679,16 +817,27 @@ commands:
 		fmt.Printf("  Created %s\n", cli.Highlight(config.ConfigFileName))
 	}
 
+	var bootstrap []string
+	if tpl != nil {
+		if bootstrap, err = applyProjectTemplate(tpl, projectDir, projectName, domainInput); err != nil {
+			return err
+		}
+	}
+
 	// Step 2: Initialize composer.json and install Magento
 	fmt.Println()
 	cli.PrintInfo("Installing Magento via Composer...")
+	events.Phase("composer", 10, "Installing Magento via Composer")
 	fmt.Printf("  Using PHP %s: %s\n", selectedPHP, phpBin)
 
 	// Create composer.json from proper template
 	var composerJSON []byte
-	if distribution == DistMageOS {
+	switch distribution {
+	case DistMageOS:
 		composerJSON, err = templates.GenerateMageOSComposerJSON(projectName, selectedVersion.Version)
-	} else {
+	case DistCommerce:
+		composerJSON, err = templates.GenerateCommerceComposerJSON(projectName, selectedVersion.Version, installB2B)
+	default:
 		composerJSON, err = templates.GenerateMagentoComposerJSON(projectName, selectedVersion.Version)
 	}
 	if err != nil {
@@ -718,6 +867,7 @@ commands:
 	if installSampleData {
 		fmt.Println()
 		cli.PrintInfo("Installing sample data...")
+		events.Phase("sample-data", 70, "Installing sample data")
 
 		// Use our wrapper which reads .magebox.yaml for PHP version
 		sampleCmd := exec.Command(wrapperPath, "require",
@@ -750,10 +900,49 @@ commands:
 
 	// Install Hyvä theme if requested
 	if newHyva {
+		events.Phase("hyva", 85, "Installing Hyvä theme")
 		if err := installHyvaComposer(wrapperPath, projectDir, hyvaRepoURL); err != nil {
 			cli.PrintWarning("Hyvä installation failed: %v", err)
 		}
 	}
+	install := &magentoInstall{
+		projectDir:    projectDir,
+		projectName:   projectName,
+		domain:        domainInput,
+		dbService:     dbService,
+		dbVersion:     dbVersion,
+		searchEngine:  searchEngine,
+		searchVersion: searchVersion,
+		cache:         enableRedis || enableValkey,
+		rabbitMQ:      enableRabbitMQ,
+		sampleData:    installSampleData,
+		splitDB:       splitDB,
+		hyva:          newHyva,
+	}
+	if runInstall {
+		if err := install.run(p); err != nil {
+			return err
+		}
+
+		fmt.Println()
+		cli.PrintTitle("Installation Complete!")
+		fmt.Println()
+		cli.PrintSuccess("%s installed successfully!", selectedVersion.Name)
+		fmt.Println()
+		fmt.Println("Your store is ready at: " + cli.URL("https://"+domainInput))
+		fmt.Println("Admin panel: " + cli.URL("https://"+domainInput+"/admin"))
+		fmt.Println()
+		fmt.Println("Admin credentials:")
+		fmt.Println("  Username: " + cli.Highlight(DefaultAdminUser))
+		fmt.Println("  Password: " + cli.Highlight(DefaultAdminPassword))
+		fmt.Println()
+		if len(bootstrap) > 0 {
+			fmt.Println("Run the " + tpl.Name + " template's bootstrap scripts with: " + cli.Command("magebox run "+templates.BootstrapCommand))
+			fmt.Println()
+		}
+		return nil
+	}
+	events.Done("Project created")
 
 	// Success!
 	fmt.Println()
@@ -762,30 +951,6 @@ commands:
 	cli.PrintSuccess("Project created successfully!")
 	fmt.Println()
 
-	// Determine database port based on service and version
-	var dbPort string
-	if dbService == "mysql" {
-		switch dbVersion {
-		case "8.4":
-			dbPort = "33084"
-		default:
-			dbPort = "33080"
-		}
-	} else {
-		switch dbVersion {
-		case "10.4":
-			dbPort = "33104"
-		case "10.5":
-			dbPort = "33105"
-		case "11.0":
-			dbPort = "33110"
-		case "11.4":
-			dbPort = "33114"
-		default:
-			dbPort = "33106"
-		}
-	}
-
 	fmt.Println("Next steps:")
 	fmt.Println()
 	fmt.Println(cli.Bullet("1. Start services:"))
@@ -793,84 +958,41 @@ commands:
 	fmt.Println("      " + cli.Command("magebox start"))
 	fmt.Println()
 	fmt.Println(cli.Bullet("2. Install Magento:"))
+	fmt.Println("      " + cli.Command(install.setupCommand(servicePorts())))
+	fmt.Println()
 
-	// Build setup:install command based on selected services
-	// Sanitize database name (hyphens → underscores, matching ensureDatabase)
-	dbName := strings.ReplaceAll(projectName, "-", "_")
-	installCmd := fmt.Sprintf(`php bin/magento setup:install \
-    --base-url=https://%s \
-    --backend-frontname=admin \
-    --db-host=127.0.0.1:%s \
-    --db-name=%s \
-    --db-user=root \
-    --db-password=magebox`, domainInput, dbPort, dbName)
-
-	// Add search engine config
-	if searchEngine == "opensearch" {
-		searchPort := docker.GetOpenSearchPort(searchVersion)
-		installCmd += fmt.Sprintf(` \
-    --search-engine=opensearch \
-    --opensearch-host=127.0.0.1 \
-    --opensearch-port=%d \
-    --opensearch-index-prefix=%s \
-    --opensearch-timeout=15`, searchPort, projectName)
-	} else if searchEngine == "elasticsearch" {
-		searchPort := docker.GetElasticsearchPort(searchVersion)
-		installCmd += fmt.Sprintf(` \
-    --search-engine=elasticsearch7 \
-    --elasticsearch-host=127.0.0.1 \
-    --elasticsearch-port=%d \
-    --elasticsearch-index-prefix=%s \
-    --elasticsearch-timeout=15`, searchPort, projectName)
-	}
-
-	// Add cache config (Valkey is Redis-compatible, same Magento flags)
-	if enableRedis || enableValkey {
-		installCmd += ` \
-    --session-save=redis \
-    --session-save-redis-host=127.0.0.1 \
-    --session-save-redis-port=6379 \
-    --session-save-redis-db=2 \
-    --cache-backend=redis \
-    --cache-backend-redis-server=127.0.0.1 \
-    --cache-backend-redis-port=6379 \
-    --cache-backend-redis-db=0 \
-    --page-cache=redis \
-    --page-cache-redis-server=127.0.0.1 \
-    --page-cache-redis-port=6379 \
-    --page-cache-redis-db=1`
-	}
-
-	// Add RabbitMQ config
-	if enableRabbitMQ {
-		installCmd += ` \
-    --amqp-host=127.0.0.1 \
-    --amqp-port=5672 \
-    --amqp-user=guest \
-    --amqp-password=guest`
+	stepNum := 3
+	if splitDB {
+		fmt.Println(cli.Bullet(fmt.Sprintf("%d. Split the checkout and sales databases:", stepNum)))
+		for _, c := range splitDatabaseCommands(install.dbName(), install.dbPort(servicePorts())) {
+			fmt.Println("      " + cli.Command(c))
+		}
+		fmt.Println()
+		stepNum++
 	}
 
-	fmt.Println("      " + cli.Command(installCmd))
-	fmt.Println()
-
 	if installSampleData {
-		fmt.Println(cli.Bullet("3. Deploy sample data:"))
+		fmt.Println(cli.Bullet(fmt.Sprintf("%d. Deploy sample data:", stepNum)))
 		fmt.Println("      " + cli.Command("php bin/magento sampledata:deploy"))
 		fmt.Println("      " + cli.Command("php bin/magento setup:upgrade"))
 		fmt.Println("      " + cli.Command("php bin/magento indexer:reindex"))
 		fmt.Println("      " + cli.Command("php bin/magento cache:flush"))
 		fmt.Println()
+		stepNum++
 	}
 
 	if newHyva {
-		stepNum := "3"
-		if installSampleData {
-			stepNum = "4"
-		}
-		fmt.Println(cli.Bullet(stepNum + ". Activate Hyvä theme:"))
+		fmt.Println(cli.Bullet(fmt.Sprintf("%d. Activate Hyvä theme:", stepNum)))
 		fmt.Println("      Go to Admin > Content > Design > Configuration")
 		fmt.Println("      Set the theme to " + cli.Highlight("Hyva/default"))
 		fmt.Println()
+		stepNum++
+	}
+
+	if len(bootstrap) > 0 {
+		fmt.Println(cli.Bullet(fmt.Sprintf("%d. Run the %s template's bootstrap scripts:", stepNum, tpl.Name)))
+		fmt.Println("      " + cli.Command("magebox run "+templates.BootstrapCommand))
+		fmt.Println()
 	}
 
 	fmt.Println("After setup, access your store at: " + cli.URL("https://"+domainInput))
@@ -881,7 +1003,7 @@ commands:
 }
 
 // runNewQuick creates a new MageOS project with sensible defaults (no questions)
-func runNewQuick(targetDir string, p *platform.Platform) error {
+func runNewQuick(targetDir string, p *platform.Platform, tpl *templates.ProjectTemplate) error {
 	if newHyva {
 		cli.PrintTitle("Quick Install - MageOS with Sample Data + Hyvä Theme")
 	} else {
@@ -894,14 +1016,14 @@ func runNewQuick(targetDir string, p *platform.Platform) error {
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
 	tld := globalCfg.GetTLD()
 
-	// Composer wrapper path (needed early for Hyvä auth check)
+	// Composer wrapper path
 	wrapperPath := filepath.Join(p.MageBoxDir(), "bin", "composer")
 
 	// Check Hyvä Composer repository URL if --hyva is set
 	var hyvaRepoURL string
 	if newHyva {
 		var err error
-		hyvaRepoURL, err = ensureHyvaRepoURL(wrapperPath)
+		hyvaRepoURL, err = ensureHyvaRepoURL(p.HomeDir)
 		if err != nil {
 			return err
 		}
@@ -931,6 +1053,10 @@ func runNewQuick(targetDir string, p *platform.Platform) error {
 		}
 	}
 	selectedPHP := versionsCfg.Defaults.PHP
+	if len(selectedVersion.PHPVersions) > 0 && !slices.Contains(selectedVersion.PHPVersions, selectedPHP) {
+		selectedPHP = selectedVersion.PHPVersions[0]
+	}
+	defaultPHP := selectedPHP
 	dbVersion := versionsCfg.Defaults.MySQL
 	searchVersion := versionsCfg.Defaults.OpenSearch
 
@@ -974,7 +1100,7 @@ func runNewQuick(targetDir string, p *platform.Platform) error {
 				if v.Version == compatiblePHP {
 					selectedPHP = compatiblePHP
 					phpFound = true
-					cli.PrintWarning("PHP 8.3 not found, using PHP %s instead", selectedPHP)
+					cli.PrintWarning("PHP %s not found, using PHP %s instead", defaultPHP, selectedPHP)
 					break
 				}
 			}
@@ -987,9 +1113,8 @@ func runNewQuick(targetDir string, p *platform.Platform) error {
 	if !phpFound {
 		cli.PrintError("No compatible PHP version found!")
 		fmt.Println()
-		cli.PrintInfo("Install PHP 8.3 first:")
-		fmt.Println("  macOS:  brew install php@8.3")
-		fmt.Println("  Ubuntu: sudo apt install php8.3-fpm php8.3-cli ...")
+		cli.PrintInfo("Install PHP %s first:", defaultPHP)
+		fmt.Println("  " + cli.Command(p.PHPInstallCommand(defaultPHP)))
 		return nil
 	}
 
@@ -1023,6 +1148,7 @@ func runNewQuick(targetDir string, p *platform.Platform) error {
 
 	// Step 2: Create .magebox.yaml first so our wrapper uses correct PHP
 	cli.PrintInfo("Creating MageBox configuration...")
+	events.Phase("config", 0, "Creating MageBox configuration")
 
 	mageboxConfig := fmt.Sprintf(`name: %s
 domains:
@@ -1059,9 +1185,17 @@ commands:
 		fmt.Printf("  Created %s\n", cli.Highlight(config.ConfigFileName))
 	}
 
+	var bootstrap []string
+	if tpl != nil {
+		if bootstrap, err = applyProjectTemplate(tpl, projectDir, projectName, domainInput); err != nil {
+			return err
+		}
+	}
+
 	// Step 3: Create composer.json and install MageOS
 	fmt.Println()
 	cli.PrintInfo("Installing MageOS via Composer...")
+	events.Phase("composer", 5, "Installing MageOS via Composer")
 	fmt.Printf("  Using PHP %s: %s\n", selectedPHP, phpBin)
 
 	// Create composer.json from proper template
@@ -1089,174 +1223,29 @@ commands:
 
 	// Install Hyvä theme if requested
 	if newHyva {
+		events.Phase("hyva", 30, "Installing Hyvä theme")
 		if err := installHyvaComposer(wrapperPath, projectDir, hyvaRepoURL); err != nil {
 			cli.PrintError("Hyvä installation failed: %v", err)
 			return err
 		}
 	}
 
-	// Step 4: Start MageBox services
-	fmt.Println()
-	cli.PrintInfo("Starting MageBox services...")
-
-	// Use project manager to start all services (nginx, php-fpm, docker)
-	projectMgr := project.NewManager(p)
-	startResult, err := projectMgr.Start(projectDir)
-	if err != nil {
-		cli.PrintWarning("Failed to start services: %v", err)
-	} else {
-		for _, svc := range startResult.Services {
-			fmt.Printf("  %s %s\n", cli.Success("✓"), svc)
-		}
-		for _, warn := range startResult.Warnings {
-			cli.PrintWarning("%s", warn)
-		}
-	}
-
-	// Reload nginx to pick up new vhost
-	nginxCtrl := nginx.NewController(p)
-	if err := nginxCtrl.Reload(); err != nil {
-		cli.PrintWarning("Failed to reload nginx: %v", err)
+	// Step 4: Start MageBox services and install Magento
+	install := &magentoInstall{
+		projectDir:    projectDir,
+		projectName:   projectName,
+		domain:        domainInput,
+		dbService:     "mysql",
+		dbVersion:     dbVersion,
+		searchEngine:  "opensearch",
+		searchVersion: searchVersion,
+		sampleData:    true,
+		hyva:          newHyva,
 	}
-
-	fmt.Println("  Services started " + cli.Success("✓"))
-
-	// Wait for OpenSearch to be ready
-	fmt.Println()
-	cli.PrintInfo("Waiting for OpenSearch to be ready...")
-	dbPort := "33080" // MySQL 8.0 default port
-	opensearchPort := docker.GetOpenSearchPort(searchVersion)
-	opensearchURL := fmt.Sprintf("http://127.0.0.1:%d", opensearchPort)
-	for i := 0; i < OpenSearchReadinessMaxRetries; i++ {
-		checkCmd := exec.Command("curl", "-s", "-o", "/dev/null", "-w", "%{http_code}", opensearchURL)
-		output, err := checkCmd.Output()
-		if err == nil && string(output) == "200" {
-			fmt.Println("  OpenSearch ready " + cli.Success("✓"))
-			break
-		}
-		if i == OpenSearchReadinessMaxRetries-1 {
-			cli.PrintWarning("OpenSearch may not be ready, continuing anyway...")
-		}
-		time.Sleep(OpenSearchReadinessRetryInterval)
-	}
-
-	// Step 6: Run Magento setup:install
-	fmt.Println()
-	cli.PrintInfo("Running Magento setup:install (this may take several minutes)...")
-
-	// Remove env.php before setup:install so Magento generates a clean one.
-	// The Start() step may have created env.php (via ensureEnvPHP) with developer
-	// mode and cache types enabled, which can interfere with DI preference resolution
-	// during schema installation.
-	envPHPPath := filepath.Join(projectDir, "app", "etc", "env.php")
-	os.Remove(envPHPPath)
-
-	// Sanitize database name (hyphens → underscores, matching ensureDatabase)
-	dbName := strings.ReplaceAll(projectName, "-", "_")
-
-	setupArgs := []string{
-		"bin/magento", "setup:install",
-		"--base-url=https://" + domainInput,
-		"--backend-frontname=admin",
-		"--db-host=127.0.0.1:" + dbPort,
-		"--db-name=" + dbName,
-		fmt.Sprintf("--db-user=%s", DefaultDBUser),
-		fmt.Sprintf("--db-password=%s", DefaultDBPassword),
-		"--admin-firstname=Admin",
-		"--admin-lastname=User",
-		fmt.Sprintf("--admin-email=%s", DefaultAdminEmail),
-		fmt.Sprintf("--admin-user=%s", DefaultAdminUser),
-		fmt.Sprintf("--admin-password=%s", DefaultAdminPassword),
-		"--language=en_US",
-		"--currency=USD",
-		"--timezone=America/New_York",
-		"--use-rewrites=1",
-		"--search-engine=opensearch",
-		"--opensearch-host=127.0.0.1",
-		fmt.Sprintf("--opensearch-port=%d", opensearchPort),
-		"--opensearch-index-prefix=" + projectName,
-		"--opensearch-timeout=15",
-	}
-
-	phpWrapperPath := filepath.Join(p.MageBoxDir(), "bin", "php")
-	setupCmd := exec.Command(phpWrapperPath, setupArgs...)
-	setupCmd.Dir = projectDir
-	setupCmd.Stdout = os.Stdout
-	setupCmd.Stderr = os.Stderr
-	setupCmd.Stdin = os.Stdin
-
-	if err := setupCmd.Run(); err != nil {
-		cli.PrintError("Magento setup:install failed: %v", err)
-		fmt.Println()
-		fmt.Println("You can retry manually with:")
-		fmt.Println("  cd " + cli.Highlight(projectDir))
-		fmt.Println("  " + cli.Command("php "+strings.Join(setupArgs, " ")))
+	if err := install.run(p); err != nil {
 		return err
 	}
 
-	fmt.Println("  Magento installed " + cli.Success("✓"))
-
-	// Regenerate env.php with MageBox-specific settings (developer mode, Mailpit, etc.)
-	// setup:install creates a basic env.php; we overwrite it with the full template.
-	if err := projectMgr.RegenerateEnvPHP(projectDir); err != nil {
-		cli.PrintWarning("Failed to regenerate env.php: %v", err)
-	}
-
-	// Step 7: Deploy sample data
-	fmt.Println()
-	cli.PrintInfo("Deploying sample data...")
-
-	sampleDataCmd := exec.Command(phpWrapperPath, "bin/magento", "sampledata:deploy")
-	sampleDataCmd.Dir = projectDir
-	sampleDataCmd.Stdout = os.Stdout
-	sampleDataCmd.Stderr = os.Stderr
-	sampleDataCmd.Stdin = os.Stdin
-	if err := sampleDataCmd.Run(); err != nil {
-		cli.PrintWarning("sampledata:deploy failed: %v", err)
-	}
-
-	// Step 8: Run setup:upgrade
-	fmt.Println()
-	cli.PrintInfo("Running setup:upgrade...")
-
-	upgradeCmd := exec.Command(phpWrapperPath, "bin/magento", "setup:upgrade")
-	upgradeCmd.Dir = projectDir
-	upgradeCmd.Stdout = os.Stdout
-	upgradeCmd.Stderr = os.Stderr
-	if err := upgradeCmd.Run(); err != nil {
-		cli.PrintWarning("setup:upgrade failed: %v", err)
-	}
-
-	// Activate Hyvä theme after setup:upgrade has registered it in the database
-	if newHyva {
-		fmt.Println()
-		activateHyvaTheme(phpWrapperPath, projectDir)
-	}
-
-	// Step 9: Reindex
-	fmt.Println()
-	cli.PrintInfo("Running indexer:reindex...")
-
-	reindexCmd := exec.Command(phpWrapperPath, "bin/magento", "indexer:reindex")
-	reindexCmd.Dir = projectDir
-	reindexCmd.Stdout = os.Stdout
-	reindexCmd.Stderr = os.Stderr
-	if err := reindexCmd.Run(); err != nil {
-		cli.PrintWarning("indexer:reindex failed: %v", err)
-	}
-
-	// Step 10: Flush cache
-	fmt.Println()
-	cli.PrintInfo("Flushing cache...")
-
-	cacheCmd := exec.Command(phpWrapperPath, "bin/magento", "cache:flush")
-	cacheCmd.Dir = projectDir
-	cacheCmd.Stdout = os.Stdout
-	cacheCmd.Stderr = os.Stderr
-	if err := cacheCmd.Run(); err != nil {
-		cli.PrintWarning("cache:flush failed: %v", err)
-	}
-
 	// Success!
 	fmt.Println()
 	cli.PrintTitle("Installation Complete!")
@@ -1274,6 +1263,10 @@ commands:
 	fmt.Println("  Username: " + cli.Highlight(DefaultAdminUser))
 	fmt.Println("  Password: " + cli.Highlight(DefaultAdminPassword))
 	fmt.Println()
+	if len(bootstrap) > 0 {
+		fmt.Println("Run the " + tpl.Name + " template's bootstrap scripts with: " + cli.Command("magebox run "+templates.BootstrapCommand))
+		fmt.Println()
+	}
 
 	return nil
 }
@@ -1282,18 +1275,22 @@ commands:
 const HyvaComposerRepoHost = "hyva-themes.repo.packagist.com"
 
 // hasHyvaComposerAuth checks if Hyvä Composer authentication is already configured
-func hasHyvaComposerAuth() bool {
-	homeDir, _ := os.UserHomeDir()
-	for _, authFile := range []string{
-		filepath.Join(homeDir, ".config", "composer", "auth.json"),
-		filepath.Join(homeDir, ".composer", "auth.json"),
-	} {
-		content, err := os.ReadFile(authFile)
-		if err == nil && strings.Contains(string(content), HyvaComposerRepoHost) {
-			return true
-		}
+func hasHyvaComposerAuth(homeDir string) bool {
+	_, ok := composer.FindHTTPBasic(homeDir, HyvaComposerRepoHost)
+	return ok
+}
+
+// saveComposerHTTPBasic merges http-basic credentials into the auth.json
+// managed by 'magebox auth'
+func saveComposerHTTPBasic(homeDir, host, username, password string) error {
+	auth, err := composer.LoadAuth(composer.AuthPath(homeDir))
+	if err != nil {
+		return err
+	}
+	if err := auth.SetHTTPBasic(host, username, password); err != nil {
+		return err
 	}
-	return false
+	return auth.Save()
 }
 
 // getHyvaRepoURL checks the global Composer config for an existing Hyvä repository URL.
@@ -1326,9 +1323,9 @@ func getHyvaRepoURL() string {
 
 // ensureHyvaRepoURL checks for an existing Hyvä repo URL and auth, or prompts the user.
 // Returns the repo URL or empty string if the user declined.
-func ensureHyvaRepoURL(composerWrapper string) (string, error) {
+func ensureHyvaRepoURL(homeDir string) (string, error) {
 	repoURL := getHyvaRepoURL()
-	hasAuth := hasHyvaComposerAuth()
+	hasAuth := hasHyvaComposerAuth(homeDir)
 
 	if repoURL != "" && hasAuth {
 		fmt.Println("  " + cli.Success("✓") + " Found existing Hyvä Composer repository and authentication")
@@ -1376,9 +1373,7 @@ func ensureHyvaRepoURL(composerWrapper string) (string, error) {
 		}
 
 		// Configure http-basic auth: username is "token", password is the actual token
-		authCmd := exec.Command(composerWrapper, "config", "--global",
-			fmt.Sprintf("http-basic.%s", HyvaComposerRepoHost), "token", token)
-		if err := authCmd.Run(); err != nil {
+		if err := saveComposerHTTPBasic(homeDir, HyvaComposerRepoHost, "token", token); err != nil {
 			return "", fmt.Errorf("failed to configure Hyvä Composer auth: %w", err)
 		}
 		fmt.Println("  " + cli.Success("✓") + " Hyvä Composer authentication configured")
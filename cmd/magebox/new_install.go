@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+)
+
+// magentoInstall is the Magento installation `magebox new` runs after
+// Composer: the project and the services the setup:install parameters are
+// computed from
+type magentoInstall struct {
+	projectDir    string
+	projectName   string
+	domain        string
+	dbService     string // mysql, mariadb or percona
+	dbVersion     string
+	searchEngine  string // opensearch, elasticsearch or empty for MySQL search
+	searchVersion string
+	cache         bool // Redis or Valkey
+	rabbitMQ      bool
+	sampleData    bool
+	splitDB       bool
+	hyva          bool
+}
+
+// dbName returns the database name, sanitized like ensureDatabase does
+func (i *magentoInstall) dbName() string {
+	return strings.ReplaceAll(i.projectName, "-", "_")
+}
+
+// dbPort returns the host port of the database service
+func (i *magentoInstall) dbPort(ports *docker.PortRegistry) string {
+	return fmt.Sprint(ports.DBPort(i.dbService, i.dbVersion))
+}
+
+// setupArgs returns the bin/magento setup:install command line for the
+// project's services and host ports
+func (i *magentoInstall) setupArgs(ports *docker.PortRegistry) []string {
+	args := []string{
+		"bin/magento", "setup:install",
+		"--base-url=https://" + i.domain,
+		"--backend-frontname=admin",
+		"--db-host=127.0.0.1:" + i.dbPort(ports),
+		"--db-name=" + i.dbName(),
+		"--db-user=" + DefaultDBUser,
+		"--db-password=" + DefaultDBPassword,
+		"--admin-firstname=Admin",
+		"--admin-lastname=User",
+		"--admin-email=" + DefaultAdminEmail,
+		"--admin-user=" + DefaultAdminUser,
+		"--admin-password=" + DefaultAdminPassword,
+		"--language=en_US",
+		"--currency=USD",
+		"--timezone=America/New_York",
+		"--use-rewrites=1",
+	}
+
+	switch i.searchEngine {
+	case "opensearch":
+		args = append(args,
+			"--search-engine=opensearch",
+			"--opensearch-host=127.0.0.1",
+			fmt.Sprintf("--opensearch-port=%d", ports.OpenSearchPort(i.searchVersion)),
+			"--opensearch-index-prefix="+i.projectName,
+			"--opensearch-timeout=15")
+	case "elasticsearch":
+		args = append(args,
+			"--search-engine=elasticsearch7",
+			"--elasticsearch-host=127.0.0.1",
+			fmt.Sprintf("--elasticsearch-port=%d", ports.ElasticsearchPort(i.searchVersion)),
+			"--elasticsearch-index-prefix="+i.projectName,
+			"--elasticsearch-timeout=15")
+	}
+
+	// Valkey is Redis-compatible, same Magento flags
+	if i.cache {
+		args = append(args,
+			"--session-save=redis",
+			"--session-save-redis-host=127.0.0.1",
+			fmt.Sprintf("--session-save-redis-port=%d", ports.CachePort()),
+			fmt.Sprintf("--session-save-redis-db=%d", RedisSessionDB),
+			"--cache-backend=redis",
+			"--cache-backend-redis-server=127.0.0.1",
+			fmt.Sprintf("--cache-backend-redis-port=%d", ports.CachePort()),
+			fmt.Sprintf("--cache-backend-redis-db=%d", RedisCacheDB),
+			"--page-cache=redis",
+			"--page-cache-redis-server=127.0.0.1",
+			fmt.Sprintf("--page-cache-redis-port=%d", ports.CachePort()),
+			fmt.Sprintf("--page-cache-redis-db=%d", RedisFullPageCacheDB))
+	}
+
+	if i.rabbitMQ {
+		args = append(args,
+			"--amqp-host=127.0.0.1",
+			fmt.Sprintf("--amqp-port=%d", ports.RabbitMQPort()),
+			"--amqp-user="+RabbitMQDefaultUser,
+			"--amqp-password="+RabbitMQDefaultPass)
+	}
+
+	return args
+}
+
+// setupCommand returns setup:install as a command to copy, one option per line
+func (i *magentoInstall) setupCommand(ports *docker.PortRegistry) string {
+	args := i.setupArgs(ports)
+	return "php " + strings.Join(args[:2], " ") + " \\\n    " + strings.Join(args[2:], " \\\n    ")
+}
+
+// run starts the project and installs Magento: setup:install, the split
+// databases, sample data, setup:upgrade, reindex and a cache flush. It fails
+// when a service isn't ready or setup:install fails; the later steps only
+// warn.
+func (i *magentoInstall) run(p *platform.Platform) error {
+	fmt.Println()
+	cli.PrintInfo("Starting MageBox services...")
+	events.Phase("start", 40, "Starting MageBox services")
+
+	// Start waits until the database, cache, search and queue accept
+	// connections and creates the database
+	projectMgr := project.NewManager(p)
+	startResult, err := projectMgr.Start(i.projectDir)
+	if err != nil {
+		cli.PrintError("Failed to start services: %v", err)
+		return err
+	}
+	for _, svc := range startResult.Services {
+		fmt.Printf("  %s %s\n", cli.Success("✓"), svc)
+	}
+	for _, err := range startResult.Errors {
+		cli.PrintError("%v", err)
+	}
+	for _, warn := range startResult.Warnings {
+		cli.PrintWarning("%s", warn)
+	}
+	for _, r := range startResult.Readiness {
+		if !r.Ready {
+			cli.PrintError("%s is not ready, Magento can't be installed", r.Service)
+			cli.PrintInfo("Check it with %s, then run setup:install:", cli.Command("magebox logs"))
+			i.printSetupCommand()
+			return fmt.Errorf("%s is not ready: %v", r.Service, r.Err)
+		}
+	}
+	fmt.Println("  Services started " + cli.Success("✓"))
+
+	fmt.Println()
+	cli.PrintInfo("Running Magento setup:install (this may take several minutes)...")
+	events.Phase("setup-install", 55, "Running setup:install")
+
+	// Remove env.php before setup:install so Magento generates a clean one.
+	// The Start() step may have created env.php (via ensureEnvPHP) with developer
+	// mode and cache types enabled, which can interfere with DI preference resolution
+	// during schema installation.
+	envPHPPath := filepath.Join(i.projectDir, "app", "etc", "env.php")
+	os.Remove(envPHPPath)
+
+	phpWrapperPath := filepath.Join(p.MageBoxDir(), "bin", "php")
+	setupCmd := exec.Command(phpWrapperPath, i.setupArgs(servicePorts())...)
+	setupCmd.Dir = i.projectDir
+	setupCmd.Stdout = os.Stdout
+	setupCmd.Stderr = os.Stderr
+	setupCmd.Stdin = os.Stdin
+
+	if err := setupCmd.Run(); err != nil {
+		cli.PrintError("Magento setup:install failed: %v", err)
+		fmt.Println()
+		fmt.Println("You can retry manually with:")
+		i.printSetupCommand()
+		return err
+	}
+
+	fmt.Println("  Magento installed " + cli.Success("✓"))
+
+	// Regenerate env.php with MageBox-specific settings (developer mode, Mailpit, etc.)
+	// setup:install creates a basic env.php; we overwrite it with the full template.
+	if err := projectMgr.RegenerateEnvPHP(i.projectDir); err != nil {
+		cli.PrintWarning("Failed to regenerate env.php: %v", err)
+	}
+
+	// The split commands add their connections to env.php, so they run after
+	// it is regenerated
+	if i.splitDB {
+		fmt.Println()
+		cli.PrintInfo("Splitting the checkout and sales databases...")
+		events.Phase("split-db", 65, "Splitting databases")
+		if err := i.splitDatabases(phpWrapperPath); err != nil {
+			cli.PrintWarning("Splitting the databases failed: %v", err)
+		}
+	}
+
+	if i.sampleData {
+		fmt.Println()
+		cli.PrintInfo("Deploying sample data...")
+		events.Phase("sample-data", 75, "Deploying sample data")
+		if err := runBinMagento(phpWrapperPath, i.projectDir, "sampledata:deploy"); err != nil {
+			cli.PrintWarning("sampledata:deploy failed: %v", err)
+		}
+	}
+
+	if i.sampleData || i.hyva {
+		fmt.Println()
+		cli.PrintInfo("Running setup:upgrade...")
+		events.Phase("setup-upgrade", 80, "Running setup:upgrade")
+		if err := runBinMagento(phpWrapperPath, i.projectDir, "setup:upgrade"); err != nil {
+			cli.PrintWarning("setup:upgrade failed: %v", err)
+		}
+	}
+
+	// Activate Hyvä theme after setup:upgrade has registered it in the database
+	if i.hyva {
+		fmt.Println()
+		activateHyvaTheme(phpWrapperPath, i.projectDir)
+	}
+
+	fmt.Println()
+	cli.PrintInfo("Running indexer:reindex...")
+	events.Phase("reindex", 90, "Running indexer:reindex")
+	if err := runBinMagento(phpWrapperPath, i.projectDir, "indexer:reindex"); err != nil {
+		cli.PrintWarning("indexer:reindex failed: %v", err)
+	}
+
+	fmt.Println()
+	cli.PrintInfo("Flushing cache...")
+	events.Phase("cache", 97, "Flushing cache")
+	if err := runBinMagento(phpWrapperPath, i.projectDir, "cache:flush"); err != nil {
+		cli.PrintWarning("cache:flush failed: %v", err)
+	}
+	events.Done("Project installed")
+
+	return nil
+}
+
+// splitDatabases creates the checkout and sales databases of Adobe Commerce
+// and moves their tables there
+func (i *magentoInstall) splitDatabases(phpWrapperPath string) error {
+	cfg, err := config.LoadFromPath(i.projectDir)
+	if err != nil {
+		return err
+	}
+	db, err := getDbInfo(cfg)
+	if err != nil {
+		return err
+	}
+
+	dbPort := i.dbPort(servicePorts())
+	for _, split := range []struct{ command, suffix string }{
+		{"setup:db-schema:split-quote", "quote"},
+		{"setup:db-schema:split-sales", "sales"},
+	} {
+		splitDB := i.dbName() + "_" + split.suffix
+		createCmd := exec.Command("docker", "exec", db.ContainerName,
+			"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+			fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", splitDB))
+		if output, err := createCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to create database %s: %s", splitDB, strings.TrimSpace(string(output)))
+		}
+		if err := runBinMagento(phpWrapperPath, i.projectDir, split.command,
+			"--host=127.0.0.1:"+dbPort, "--dbname="+splitDB, "--username="+DefaultDBUser, "--password="+DefaultDBPassword); err != nil {
+			return fmt.Errorf("%s failed: %w", split.command, err)
+		}
+	}
+	return nil
+}
+
+// printSetupCommand prints setup:install to run by hand in the project
+func (i *magentoInstall) printSetupCommand() {
+	fmt.Println("  cd " + cli.Highlight(i.projectDir))
+	fmt.Println("  " + cli.Command(i.setupCommand(servicePorts())))
+}
+
+// runBinMagento runs a bin/magento command in the project with the MageBox
+// PHP wrapper
+func runBinMagento(phpWrapperPath, projectDir string, args ...string) error {
+	cmd := exec.Command(phpWrapperPath, append([]string{"bin/magento"}, args...)...)
+	cmd.Dir = projectDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/docker"
+)
+
+func TestMagentoInstall_SetupArgs(t *testing.T) {
+	install := &magentoInstall{
+		projectName:   "my-store",
+		domain:        "my-store.test",
+		dbService:     "mariadb",
+		dbVersion:     "10.6",
+		searchEngine:  "elasticsearch",
+		searchVersion: "8.11",
+		cache:         true,
+		rabbitMQ:      true,
+	}
+
+	args := install.setupArgs(nil)
+	if args[0] != "bin/magento" || args[1] != "setup:install" {
+		t.Fatalf("args start with %v, want bin/magento setup:install", args[:2])
+	}
+	for _, want := range []string{
+		"--base-url=https://my-store.test",
+		fmt.Sprintf("--db-host=127.0.0.1:%d", docker.DBPort("mariadb", "10.6")),
+		"--db-name=my_store",
+		"--admin-user=" + DefaultAdminUser,
+		"--search-engine=elasticsearch7",
+		"--elasticsearch-index-prefix=my-store",
+		"--session-save=redis",
+		"--page-cache-redis-db=1",
+		"--amqp-host=127.0.0.1",
+	} {
+		if !slices.Contains(args, want) {
+			t.Errorf("setupArgs() has no %s:\n%s", want, strings.Join(args, "\n"))
+		}
+	}
+
+	// Without search, cache and queue only the database is configured
+	install = &magentoInstall{projectName: "shop", domain: "shop.test", dbService: "mysql", dbVersion: "8.0"}
+	for _, arg := range install.setupArgs(nil) {
+		for _, prefix := range []string{"--search-engine", "--opensearch", "--session-save", "--amqp"} {
+			if strings.HasPrefix(arg, prefix) {
+				t.Errorf("setupArgs() without services has %s", arg)
+			}
+		}
+	}
+
+	command := install.setupCommand(nil)
+	if !strings.HasPrefix(command, "php bin/magento setup:install \\\n    --base-url=https://shop.test \\\n") {
+		t.Errorf("setupCommand() = %q", command)
+	}
+}
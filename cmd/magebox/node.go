@@ -0,0 +1,208 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/frontend"
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Show the Node.js version of the project",
+	Long: `Shows the Node.js version the project builds its themes with, where the
+version comes from and which installation MageBox uses for it.
+
+The version is frontend.node of .magebox.yaml, else the one of .nvmrc,
+.node-version or engines.node of package.json. MageBox picks the newest
+matching installation of nvm, fnm or Volta, then the node on PATH.
+'magebox theme watch', 'magebox run' and hooks run with it first on PATH.`,
+	RunE: runNode,
+}
+
+var nodeInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the project's Node.js version",
+	Long:  "Installs a Node.js matching the project's version with fnm, or nvm when fnm is not installed",
+	RunE:  runNodeInstall,
+}
+
+var nodeExecCmd = &cobra.Command{
+	Use:   "exec <command> [args...]",
+	Short: "Run a command with the project's Node.js",
+	Long: `Runs a command in the project directory with the project's Node.js first
+on PATH.
+
+Examples:
+  magebox node exec npm ci
+  magebox node exec npx grunt clean`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runNodeExec,
+}
+
+func init() {
+	// Flags after the command belong to it
+	nodeExecCmd.Flags().SetInterspersed(false)
+	nodeCmd.AddCommand(nodeInstallCmd)
+	nodeCmd.AddCommand(nodeExecCmd)
+	rootCmd.AddCommand(nodeCmd)
+}
+
+// projectNode returns the Node.js installation of a project. It is the node
+// on PATH for projects pinning no version, nil when there is none.
+func projectNode(cfg *config.Config, projectPath string) (*frontend.Node, error) {
+	req, pinned := frontend.DetectRequirement(projectPath, cfg.NodeVersion())
+	if !pinned {
+		return frontend.SystemNode(), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return frontend.Resolve(req, homeDir)
+}
+
+func runNode(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	cli.PrintTitle("Node.js")
+	fmt.Println()
+
+	req, pinned := frontend.DetectRequirement(cwd, cfg.NodeVersion())
+	if pinned {
+		fmt.Printf("  %-10s %s (%s)\n", "Required:", cli.Highlight(req.Version), req.Source)
+	} else {
+		fmt.Printf("  %-10s %s\n", "Required:", "any (no version pinned)")
+	}
+
+	node, err := projectNode(cfg, cwd)
+	if err != nil {
+		fmt.Println()
+		cli.PrintWarning("%v", err)
+		cli.PrintInfo("Install it with: %s", cli.Command("magebox node install"))
+		return nil
+	}
+	if node == nil {
+		fmt.Println()
+		cli.PrintWarning("Node.js is not installed")
+		return nil
+	}
+	fmt.Printf("  %-10s %s (%s)\n", "Using:", cli.Highlight(node.Version), node.Manager)
+	fmt.Printf("  %-10s %s\n", "Binaries:", cli.Path(node.BinDir))
+
+	if !pinned {
+		fmt.Println()
+		cli.PrintInfo("Pin a version with frontend.node in .magebox.yaml or an .nvmrc file")
+	}
+	return nil
+}
+
+func runNodeInstall(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	req, pinned := frontend.DetectRequirement(cwd, cfg.NodeVersion())
+	if !pinned {
+		cli.PrintError("The project pins no Node.js version")
+		cli.PrintInfo("Set frontend.node in .magebox.yaml or add an .nvmrc file")
+		return nil
+	}
+	if node, err := projectNode(cfg, cwd); err == nil {
+		cli.PrintSuccess("Node.js %s (%s) is already installed", node.Version, node.Manager)
+		return nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	install, err := frontend.InstallCommand(req, homeDir)
+	if err != nil {
+		cli.PrintError("%v", err)
+		cli.PrintInfo("Install fnm (https://github.com/Schniz/fnm) or nvm (https://github.com/nvm-sh/nvm) first")
+		return nil
+	}
+
+	cli.PrintInfo("Installing Node.js %s (from %s)...", req.Version, req.Source)
+	install.Stdin = os.Stdin
+	install.Stdout = os.Stdout
+	install.Stderr = os.Stderr
+	if err := install.Run(); err != nil {
+		return fmt.Errorf("failed to install Node.js %s: %w", req.Version, err)
+	}
+
+	node, err := projectNode(cfg, cwd)
+	if err != nil {
+		cli.PrintWarning("Installed, but %v", err)
+		return nil
+	}
+	cli.PrintSuccess("Node.js %s installed", node.Version)
+	return nil
+}
+
+func runNodeExec(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	node, err := projectNode(cfg, cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		cli.PrintInfo("Install it with: %s", cli.Command("magebox node install"))
+		return nil
+	}
+	if node == nil {
+		cli.PrintError("Node.js is not installed")
+		return nil
+	}
+
+	// npm, npx and node itself come from the project's installation
+	bin := args[0]
+	if path, err := exec.LookPath(filepath.Join(node.BinDir, args[0])); err == nil {
+		bin = path
+	}
+
+	run := exec.Command(bin, args[1:]...)
+	run.Dir = cwd
+	run.Env = node.Env(os.Environ())
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+	if err := run.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+	return nil
+}
@@ -75,7 +75,7 @@ func openProject(projectPath string, forceStart bool) error {
 
 	mgr := project.NewManager(p)
 	if forceStart || !projectFullyRunning(mgr, projectPath) {
-		if err := startProject(mgr, projectPath, true); err != nil {
+		if err := startProject(mgr, projectPath, nil, true); err != nil {
 			return err
 		}
 		fmt.Println()
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat is the --output format of status and list commands: text, json or yaml
+var outputFormat string
+
+// structuredOutput reports whether --output asks for machine-readable output
+func structuredOutput() bool {
+	return outputFormat == "json" || outputFormat == "yaml"
+}
+
+// printStructured writes v to stdout in the --output format
+func printStructured(v interface{}) error {
+	return writeStructured(os.Stdout, outputFormat, v)
+}
+
+// writeStructured writes v as JSON or YAML. Both use the json tags of v, so
+// the two formats always carry the same keys in the same order.
+func writeStructured(w io.Writer, format string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "yaml":
+		// JSON is valid YAML, so decoding it keeps the key order; clearing
+		// the styles turns its flow mappings and quoted strings into block YAML
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+		resetYAMLStyle(&node)
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		_, err = w.Write(buf.Bytes())
+		return err
+	default:
+		return fmt.Errorf("invalid output format %q (use text, json or yaml)", format)
+	}
+}
+
+// resetYAMLStyle clears the style of a node and its children
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
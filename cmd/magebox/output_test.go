@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteStructured(t *testing.T) {
+	value := struct {
+		Name    string            `json:"name"`
+		PHP     string            `json:"php"`
+		Running bool              `json:"running"`
+		Domains []string          `json:"domains"`
+		Ports   map[string]int    `json:"ports"`
+		Extra   map[string]string `json:"extra,omitempty"`
+	}{
+		Name:    "mystore",
+		PHP:     "8.3",
+		Running: true,
+		Domains: []string{"mystore.test"},
+		Ports:   map[string]int{"mysql": 33080},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `{
+  "name": "mystore",
+  "php": "8.3",
+  "running": true,
+  "domains": [
+    "mystore.test"
+  ],
+  "ports": {
+    "mysql": 33080
+  }
+}
+`},
+		{"yaml", `name: mystore
+php: "8.3"
+running: true
+domains:
+  - mystore.test
+ports:
+  mysql: 33080
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeStructured(&buf, tt.format, value); err != nil {
+				t.Fatalf("writeStructured failed: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeStructured(%s) =\n%s\nwant\n%s", tt.format, got, tt.want)
+			}
+		})
+	}
+
+	if err := writeStructured(&bytes.Buffer{}, "xml", value); err == nil {
+		t.Error("writeStructured(xml) should fail")
+	}
+}
+
+func TestParseVarnishStats(t *testing.T) {
+	output := `MAIN.uptime            3600         1.00 Child process uptime
+MAIN.client_req           42         0.01 Good client requests received
+MAIN.cache_hit            30         0.01 Cache hits
+MAIN.cache_hit_grace       2         0.00 Cache grace hits
+MAIN.cache_miss           12         0.00 Cache misses
+`
+	got := parseVarnishStats(output, varnishStatsCounters)
+	want := map[string]int64{"MAIN.client_req": 42, "MAIN.cache_hit": 30, "MAIN.cache_miss": 12}
+	if len(got) != len(want) {
+		t.Fatalf("parseVarnishStats() = %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %d, want %d", name, got[name], value)
+		}
+	}
+}
@@ -87,7 +87,7 @@ func runPhp(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Reload config with new PHP version
-	_, ok = loadProjectConfig(cwd)
+	cfg, ok = loadProjectConfig(cwd)
 	if !ok {
 		return nil
 	}
@@ -121,6 +121,7 @@ func runPhp(cmd *cobra.Command, args []string) error {
 	if len(result.Domains) > 0 {
 		fmt.Printf("  Domain: %s\n", cli.URL("https://"+result.Domains[0]))
 	}
+	refreshCron(p, cfg, cwd)
 
 	return nil
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/php"
+)
+
+var phpCacheStatsJSON bool
+
+var phpCacheStatsCmd = &cobra.Command{
+	Use:   "cache-stats",
+	Short: "Show OPcache and realpath cache utilization",
+	Long: `Shows OPcache and realpath cache utilization of the project's PHP-FPM pool.
+
+The numbers are read from inside the pool (the CLI has its own OPcache) by
+running a temporary script over the pool's FastCGI socket. Caches that are
+more than 90% full are flagged with the setting to raise.
+
+Examples:
+  magebox php cache-stats
+  magebox php cache-stats --json`,
+	RunE: runPHPCacheStats,
+}
+
+func init() {
+	phpCacheStatsCmd.Flags().BoolVar(&phpCacheStatsJSON, "json", false, "Output statistics as JSON")
+	phpCmd.AddCommand(phpCacheStatsCmd)
+}
+
+func runPHPCacheStats(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	socketPath := php.NewPoolGenerator(p).GetSocketPath(cfg.Name, cfg.PHP)
+	stats, err := php.QueryCacheStats(socketPath, cwd)
+	if err != nil {
+		cli.PrintError("Failed to query PHP-FPM pool: %v", err)
+		cli.PrintInfo("Is the project running? Start it with %s", cli.Command("magebox start"))
+		return nil
+	}
+
+	if phpCacheStatsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	cli.PrintTitle("PHP Cache Statistics")
+	fmt.Println()
+	fmt.Printf("Pool:     %s (PHP %s)\n", cli.Highlight(cfg.Name), cfg.PHP)
+
+	fmt.Println(cli.Header("OPcache"))
+	if !stats.OPcacheEnabled {
+		fmt.Println("  Status:    " + cli.Warning("disabled"))
+		fmt.Println(cli.Bullet("Enable it with " + cli.Command("magebox php opcache enable")))
+	} else {
+		fmt.Println("  Status:    " + cli.Success("enabled"))
+		fmt.Printf("  Memory:    %s / %s (%s wasted)\n",
+			formatFileSize(stats.MemoryUsed), formatFileSize(stats.MemoryTotal()), formatFileSize(stats.MemoryWasted))
+		fmt.Printf("  Hit rate:  %.1f%% (%d hits, %d misses)\n", stats.HitRate, stats.Hits, stats.Misses)
+		fmt.Printf("  Scripts:   %d / %d\n", stats.CachedScripts, stats.MaxAcceleratedFiles)
+		fmt.Printf("  Interned:  %s / %s\n", formatFileSize(stats.InternedUsed), formatFileSize(stats.InternedSize))
+	}
+
+	fmt.Println(cli.Header("Realpath Cache"))
+	fmt.Printf("  Used:      %s / %s (%d entries)\n",
+		formatFileSize(stats.RealpathUsed), formatFileSize(stats.RealpathLimit), stats.RealpathEntries)
+
+	warnings := stats.Warnings()
+	fmt.Println()
+	if len(warnings) == 0 {
+		cli.PrintSuccess("All caches have headroom")
+		return nil
+	}
+	for _, w := range warnings {
+		cli.PrintWarning("%s", w)
+	}
+	cli.PrintInfo("Set values with %s, e.g. %s", cli.Command("magebox php ini set"), cli.Command("magebox php ini set opcache.memory_consumption 512"))
+	return nil
+}
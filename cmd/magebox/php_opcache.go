@@ -156,7 +156,7 @@ func runOpcacheStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get merged settings (defaults + custom)
-	merged := php.GetMergedPHPINI(cfg.PHPINI)
+	merged := php.GetMergedPHPINI(cfg.PoolPHPINI())
 
 	cli.PrintTitle("OPcache Settings")
 	fmt.Println()
@@ -11,6 +11,8 @@ var portforwardDaemonCmd = &cobra.Command{
 	Short:  "Run port forwarding daemon (internal — called by LaunchDaemon)",
 	Hidden: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// The proxy shuts down its listeners itself on SIGTERM
+		commandHandlesInterrupt.Store(true)
 		return portforward.RunProxy(portforward.DefaultPairs())
 	},
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/project"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage project profiles",
+	Long: `Profiles are named sets of services, PHP settings and env vars defined under
+'profiles:' in .magebox.yaml, e.g. a 'performance' profile with Varnish on
+and Xdebug off.
+
+The active profile is stored in .magebox.local.yaml and merged between
+.magebox.yaml and the rest of .magebox.local.yaml, so local overrides still
+win over the profile.
+
+Examples:
+  magebox profile                    # list profiles
+  magebox profile use performance    # switch to the performance profile
+  magebox profile clear              # back to the plain project config`,
+	RunE: runProfileList,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles of the project",
+	RunE:  runProfileList,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch to a profile",
+	Long:  "Activates a profile in .magebox.local.yaml and restarts the project with it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileUse,
+}
+
+var profileClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Deactivate the current profile",
+	Long:  "Removes the active profile from .magebox.local.yaml and restarts the project without it",
+	RunE:  runProfileClear,
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileClearCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	names := cfg.ProfileNames()
+	if len(names) == 0 {
+		cli.PrintInfo("No profiles defined, add them under 'profiles:' in %s", config.ConfigFileName)
+		return nil
+	}
+
+	cli.PrintTitle("Profiles: %s", cfg.Name)
+	fmt.Println()
+	for _, name := range names {
+		if name == cfg.Profile {
+			fmt.Printf("  %s %s %s\n", cli.Success("*"), cli.Highlight(name), cli.Dim+"(active)"+cli.Reset)
+		} else {
+			fmt.Printf("    %s\n", name)
+		}
+		if summary := profileSummary(cfg.Profiles[name]); summary != "" {
+			fmt.Printf("      %s\n", cli.Dim+summary+cli.Reset)
+		}
+	}
+	return nil
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	name := args[0]
+	if _, exists := cfg.Profiles[name]; !exists {
+		if len(cfg.Profiles) == 0 {
+			cli.PrintError("No profiles defined in %s", config.ConfigFileName)
+		} else {
+			cli.PrintError("Unknown profile '%s' (available: %s)", name, strings.Join(cfg.ProfileNames(), ", "))
+		}
+		return nil
+	}
+	if name == cfg.Profile {
+		cli.PrintInfo("Profile %s is already active", name)
+		return nil
+	}
+
+	return switchProfile(cwd, name)
+}
+
+func runProfileClear(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+	if cfg.Profile == "" {
+		cli.PrintInfo("No profile is active")
+		return nil
+	}
+
+	return switchProfile(cwd, "")
+}
+
+// switchProfile restarts the project with another profile, none when name
+// is empty. The project is stopped first so services the old profile
+// enabled are stopped along with it.
+func switchProfile(cwd, name string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	mgr := project.NewManager(p)
+
+	fmt.Print("Stopping services... ")
+	if err := mgr.Stop(cwd); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to stop: %v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	localCfg, err := config.LoadLocalConfig(cwd)
+	if err != nil {
+		cli.PrintError("Failed to read %s: %v", config.LocalConfigFileName, err)
+		return nil
+	}
+	if name == "" {
+		delete(localCfg.Other, "profile")
+	} else {
+		if localCfg.Other == nil {
+			localCfg.Other = make(map[string]interface{})
+		}
+		localCfg.Other["profile"] = name
+	}
+	if err := config.SaveLocalConfig(cwd, localCfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	fmt.Print("Starting services... ")
+	result, err := mgr.Start(cwd)
+	if err != nil {
+		fmt.Println(cli.Error("failed"))
+		return fmt.Errorf("failed to start: %w", err)
+	}
+	fmt.Println(cli.Success("done"))
+	fmt.Println()
+
+	if name == "" {
+		cli.PrintSuccess("Profile cleared, %s runs with its project config", cfg.Name)
+	} else {
+		cli.PrintSuccess("%s now runs with profile %s", cfg.Name, name)
+	}
+	if len(result.Domains) > 0 {
+		fmt.Printf("  Domain: %s\n", cli.URL("https://"+result.Domains[0]))
+	}
+	refreshCron(p, cfg, cwd)
+	return nil
+}
+
+// profileSummary describes what a profile changes in one line
+func profileSummary(profile *config.Config) string {
+	if profile == nil {
+		return ""
+	}
+	var parts []string
+	if profile.PHP != "" {
+		parts = append(parts, "PHP "+profile.PHP)
+	}
+	if n := len(profile.PHPINI); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d php_ini", n))
+	}
+	if n := len(profile.Env); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d env", n))
+	}
+	services := profile.Services
+	for _, svc := range []struct {
+		name string
+		cfg  *config.ServiceConfig
+	}{
+		{"mysql", services.MySQL}, {"mariadb", services.MariaDB}, {"percona", services.Percona},
+		{"redis", services.Redis}, {"valkey", services.Valkey},
+		{"opensearch", services.OpenSearch}, {"elasticsearch", services.Elasticsearch},
+		{"meilisearch", services.Meilisearch}, {"typesense", services.Typesense},
+		{"rabbitmq", services.RabbitMQ}, {"mailpit", services.Mailpit},
+		{"varnish", services.Varnish}, {"phpmyadmin", services.PhpMyAdmin},
+	} {
+		if svc.cfg == nil {
+			continue
+		}
+		state := "off"
+		if svc.cfg.Enabled {
+			state = "on"
+		}
+		parts = append(parts, svc.name+" "+state)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/blackfire"
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/tideways"
+	"qoliber/magebox/internal/xdebug"
+)
+
+var profilerCmd = &cobra.Command{
+	Use:   "profiler",
+	Short: "Manage the PHP profiler of the project",
+	Long: `Turns Blackfire or Tideways on and off for the current project only.
+
+The profiler extension is loaded by the project's PHP-FPM pool, so other
+projects on the same PHP version run without it, and its agent runs as a
+container next to the other MageBox services. Set the profiler the project
+uses in .magebox.yaml:
+
+  profiler: blackfire
+
+'magebox profiler on' and 'off' store the choice in .magebox.local.yaml.
+Credentials are configured globally with 'magebox blackfire config' or
+'magebox tideways config'.
+
+Examples:
+  magebox profiler install             # install the extension for the project's PHP
+  magebox profiler on                  # load the profiler set in .magebox.yaml
+  magebox profiler on tideways         # use Tideways for this project
+  magebox profiler off`,
+	RunE: runProfilerStatus,
+}
+
+var profilerStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the profiler status of the project",
+	RunE:  runProfilerStatus,
+}
+
+var profilerInstallCmd = &cobra.Command{
+	Use:       "install [blackfire|tideways]",
+	Short:     "Install the profiler extension for the project's PHP version",
+	Long:      "Installs the profiler's PHP extension without enabling it for every project; 'magebox profiler on' loads it for this one",
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: config.Profilers,
+	RunE:      runProfilerInstall,
+}
+
+var profilerOnCmd = &cobra.Command{
+	Use:       "on [blackfire|tideways]",
+	Short:     "Load the profiler for the project",
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: config.Profilers,
+	RunE:      runProfilerOn,
+}
+
+var profilerOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Stop loading the profiler for the project",
+	RunE:  runProfilerOff,
+}
+
+func init() {
+	profilerCmd.AddCommand(profilerStatusCmd)
+	profilerCmd.AddCommand(profilerInstallCmd)
+	profilerCmd.AddCommand(profilerOnCmd)
+	profilerCmd.AddCommand(profilerOffCmd)
+	rootCmd.AddCommand(profilerCmd)
+}
+
+// profilerExtension is the part of the Blackfire and Tideways managers the
+// profiler commands use
+type profilerExtension interface {
+	IsExtensionInstalled(phpVersion string) bool
+	IsExtensionEnabled(phpVersion string) bool
+	Disable(phpVersion string) error
+}
+
+func profilerManager(p *platform.Platform, profiler string) profilerExtension {
+	if profiler == config.ProfilerTideways {
+		return tideways.NewManager(p, nil)
+	}
+	return blackfire.NewManager(p, nil)
+}
+
+// resolveProfiler returns the profiler named in args, or the one configured
+// for the project
+func resolveProfiler(cfg *config.Config, args []string) (string, bool) {
+	name := cfg.Profiler
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if !config.IsProfiler(name) {
+		if len(args) == 0 {
+			cli.PrintError("No profiler set for the project, name one: blackfire or tideways")
+		} else {
+			cli.PrintError("Unknown profiler '%s' (expected blackfire or tideways)", name)
+		}
+		return "", false
+	}
+	return name, true
+}
+
+func runProfilerStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	cli.PrintTitle("Profiler: %s", cfg.Name)
+	fmt.Println()
+
+	active := cfg.ActiveProfiler()
+	if active == "" {
+		fmt.Printf("Profiler:  %s\n", cli.Warning("off"))
+		fmt.Println()
+		cli.PrintInfo("Enable with: magebox profiler on blackfire|tideways")
+		return nil
+	}
+
+	mgr := profilerManager(p, active)
+	composeGen := docker.NewComposeGenerator(p)
+	running := docker.NewDockerController(composeGen.ComposeFilePath()).IsServiceRunning(docker.ProfilerService(active))
+
+	fmt.Printf("Profiler:  %s\n", cli.Highlight(active))
+	fmt.Printf("PHP:       %s\n", cli.Highlight(cfg.PHP))
+	fmt.Printf("Extension: %s\n", formatBool(mgr.IsExtensionInstalled(cfg.PHP)))
+	fmt.Printf("Agent:     %s\n", formatBool(running))
+	fmt.Println()
+
+	if !mgr.IsExtensionInstalled(cfg.PHP) {
+		cli.PrintWarning("The %s extension is not installed for PHP %s, run 'magebox profiler install'", active, cfg.PHP)
+	}
+	if !running {
+		cli.PrintWarning("The %s agent is not running, run 'magebox start'", active)
+	}
+	warnProfilerConflicts(p, cfg.PHP, active, mgr)
+	return nil
+}
+
+func runProfilerInstall(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	profiler, ok := resolveProfiler(cfg, args)
+	if !ok {
+		return nil
+	}
+
+	mgr := profilerManager(p, profiler)
+	if mgr.IsExtensionInstalled(cfg.PHP) {
+		cli.PrintInfo("The %s extension is already installed for PHP %s", profiler, cfg.PHP)
+		return nil
+	}
+
+	fmt.Printf("Installing the %s extension for PHP %s...\n", profiler, cfg.PHP)
+	if profiler == config.ProfilerTideways {
+		err = tideways.NewInstaller(p).InstallExtension(cfg.PHP)
+	} else {
+		err = blackfire.NewInstaller(p).InstallExtension(cfg.PHP)
+	}
+	if err != nil {
+		cli.PrintError("Failed to install the %s extension: %v", profiler, err)
+		return nil
+	}
+
+	// Packages enable the extension for every pool; projects load it themselves
+	if mgr.IsExtensionEnabled(cfg.PHP) {
+		if err := mgr.Disable(cfg.PHP); err != nil {
+			cli.PrintWarning("Failed to disable the extension for all projects: %v", err)
+		}
+	}
+
+	cli.PrintSuccess("The %s extension is installed for PHP %s", profiler, cfg.PHP)
+	cli.PrintInfo("Load it for this project with: magebox profiler on %s", profiler)
+	return nil
+}
+
+func runProfilerOn(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	profiler, ok := resolveProfiler(cfg, args)
+	if !ok {
+		return nil
+	}
+
+	mgr := profilerManager(p, profiler)
+	if !mgr.IsExtensionInstalled(cfg.PHP) {
+		cli.PrintError("The %s extension is not installed for PHP %s", profiler, cfg.PHP)
+		cli.PrintInfo("Install with: magebox profiler install %s", profiler)
+		return nil
+	}
+
+	cli.PrintTitle("Enabling %s for %s", profiler, cfg.Name)
+	fmt.Println()
+
+	if err := saveProfiler(cwd, profiler); err != nil {
+		return err
+	}
+	if cfg, ok = loadProjectConfig(cwd); !ok {
+		return nil
+	}
+
+	fmt.Printf("Starting the %s agent... ", profiler)
+	if err := startProfilerAgent(p, profiler); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("%v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	applyPoolSettings(p, cfg, cwd)
+
+	fmt.Println()
+	cli.PrintSuccess("%s is loaded for %s", profiler, cfg.Name)
+	warnProfilerConflicts(p, cfg.PHP, profiler, mgr)
+	if profiler == config.ProfilerBlackfire {
+		if globalCfg, err := config.LoadGlobalConfig(p.HomeDir); err == nil && !globalCfg.HasBlackfireCredentials() {
+			cli.PrintWarning("Configure the agent credentials with: magebox blackfire config")
+		}
+		if len(cfg.Domains) > 0 {
+			scheme := "http"
+			if cfg.Domains[0].IsSSLEnabled() {
+				scheme = "https"
+			}
+			cli.PrintInfo("Profile a request with: blackfire curl %s://%s/", scheme, cfg.Domains[0].Host)
+		}
+	}
+	return nil
+}
+
+func runProfilerOff(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	profiler := cfg.ActiveProfiler()
+	if profiler == "" {
+		cli.PrintInfo("No profiler is loaded for %s", cfg.Name)
+		return nil
+	}
+
+	cli.PrintTitle("Disabling %s for %s", profiler, cfg.Name)
+	fmt.Println()
+
+	if err := saveProfiler(cwd, config.ProfilerOff); err != nil {
+		return err
+	}
+	if cfg, ok = loadProjectConfig(cwd); !ok {
+		return nil
+	}
+
+	applyPoolSettings(p, cfg, cwd)
+	stopUnusedProfilerAgent(p, profiler)
+
+	fmt.Println()
+	cli.PrintSuccess("%s is no longer loaded for %s", profiler, cfg.Name)
+	return nil
+}
+
+// saveProfiler stores the project's profiler in .magebox.local.yaml
+func saveProfiler(cwd, profiler string) error {
+	localCfg, err := config.LoadLocalConfig(cwd)
+	if err != nil {
+		localCfg = &config.LocalConfig{}
+	}
+	if localCfg.Other == nil {
+		localCfg.Other = make(map[string]interface{})
+	}
+	localCfg.Other["profiler"] = profiler
+	if err := config.SaveLocalConfig(cwd, localCfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+// startProfilerAgent adds the profiler's agent to the shared compose file
+// and starts it
+func startProfilerAgent(p *platform.Platform, profiler string) error {
+	composeGen := docker.NewComposeGenerator(p)
+	if err := composeGen.GenerateGlobalServices(discoverAllConfigs(p)); err != nil {
+		return fmt.Errorf("failed to generate docker-compose: %w", err)
+	}
+	dockerCtrl := docker.NewDockerController(composeGen.ComposeFilePath())
+	if err := dockerCtrl.StartService(docker.ProfilerService(profiler)); err != nil {
+		return fmt.Errorf("failed to start the %s agent: %w", profiler, err)
+	}
+	return nil
+}
+
+// stopUnusedProfilerAgent stops the profiler's agent when no project loads
+// the profiler anymore
+func stopUnusedProfilerAgent(p *platform.Platform, profiler string) {
+	configs := discoverAllConfigs(p)
+	for _, c := range configs {
+		if c.ActiveProfiler() == profiler {
+			return
+		}
+	}
+
+	composeGen := docker.NewComposeGenerator(p)
+	dockerCtrl := docker.NewDockerController(composeGen.ComposeFilePath())
+	fmt.Printf("Stopping the %s agent... ", profiler)
+	if err := dockerCtrl.StopService(docker.ProfilerService(profiler)); err != nil {
+		fmt.Println(cli.Warning("not running"))
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+	if err := composeGen.GenerateGlobalServices(configs); err != nil {
+		cli.PrintWarning("Failed to update docker-compose: %v", err)
+	}
+}
+
+// applyPoolSettings regenerates the project's PHP-FPM pool and reloads PHP-FPM,
+// so pool settings changed by the profiler and xdebug commands take effect
+func applyPoolSettings(p *platform.Platform, cfg *config.Config, cwd string) {
+	fmt.Print("Regenerating PHP-FPM pool... ")
+	if err := project.NewManager(p).RegenerateConfigs(cwd); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to regenerate configs: %v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	fmt.Print("Reloading PHP-FPM... ")
+	if err := php.NewFPMController(p, cfg.PHP).Reload(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to reload PHP-FPM: %v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+}
+
+// warnProfilerConflicts warns about extensions loaded for every project that
+// get in the way of the project's profiler
+func warnProfilerConflicts(p *platform.Platform, phpVersion, profiler string, mgr profilerExtension) {
+	if mgr.IsExtensionEnabled(phpVersion) {
+		cli.PrintWarning("%s is also enabled for all PHP %s projects, disable it with 'magebox %s off' so it isn't loaded twice",
+			titleCase(profiler), phpVersion, profiler)
+	}
+	if xdebug.NewManager(p).IsEnabled(phpVersion) {
+		cli.PrintWarning("Xdebug is enabled for PHP %s and skews profiles, disable it with 'magebox xdebug off'", phpVersion)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"sync"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/project"
 )
 
 var purgeCmd = &cobra.Command{
@@ -100,21 +102,33 @@ func purgeGlob(cwd, pattern string, mu *sync.Mutex) {
 	mu.Unlock()
 }
 
-// flushCache flushes Redis or Valkey if configured
+// flushCache flushes the project's Redis or Valkey databases if configured
 func flushCache(cfg *config.Config) {
-	if cfg.Services.HasRedis() {
-		flushRedis("magebox-redis")
-	} else if cfg.Services.HasValkey() {
-		flushRedis("magebox-valkey")
+	if !cfg.Services.HasCacheService() {
+		return
+	}
+
+	// Projects that weren't started since databases are allocated still use the defaults
+	dbs := project.DefaultRedisDBs
+	if p, err := getPlatform(); err == nil {
+		if allocated, ok, _ := project.NewRedisDBRegistry(p).Get(cfg.Name); ok {
+			dbs = allocated
+		}
 	}
+
+	service := cfg.Services.GetCacheServiceName()
+	flushRedis("magebox-"+service, service+"-cli", dbs)
 }
 
-// flushRedis sends FLUSHALL to a Redis-compatible container
-func flushRedis(containerName string) {
-	cmd := exec.Command("docker", "exec", containerName, "redis-cli", "flushall")
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("  %s Redis flush (not running)\n", cli.Warning(cli.SymbolWarning))
-		return
+// flushRedis sends FLUSHDB for each of the project's databases to a
+// Redis-compatible container
+func flushRedis(containerName, cliBinary string, dbs project.RedisDBs) {
+	for _, db := range dbs.Numbers() {
+		cmd := exec.Command("docker", "exec", containerName, cliBinary, "-n", strconv.Itoa(db), "flushdb")
+		if err := cmd.Run(); err != nil {
+			fmt.Printf("  %s Redis flush (not running)\n", cli.Warning(cli.SymbolWarning))
+			return
+		}
 	}
 	fmt.Printf("  %s Redis caches flushed\n", cli.Success(cli.SymbolCheck))
 }
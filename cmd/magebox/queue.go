@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/queue"
+)
+
+var queueForeground bool
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Run Magento message queue consumers",
+	Long: `Runs Magento's message queue consumers (RabbitMQ or MySQL queues) as
+supervised background processes with the project's PHP version.
+
+A consumer that exits is restarted, and 'magebox queue status' shows how often
+each one crashed and why, so asynchronous operations like mass actions, export
+and bulk API calls don't silently stop being processed.
+
+Magento also starts consumers from cron; disable that in app/etc/env.php
+('cron_consumers_runner' => ['cron_run' => false]) to not run them twice.
+
+Examples:
+  magebox queue list                        # Consumers of the project
+  magebox queue start                       # Run all consumers
+  magebox queue start async.operations.all  # Run selected consumers
+  magebox queue status
+  magebox queue stop`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the queue consumers of the project",
+	RunE:  runQueueList,
+}
+
+var queueStartCmd = &cobra.Command{
+	Use:   "start [consumer...]",
+	Short: "Run queue consumers in the background",
+	Long: `Starts a supervisor in the background that runs the given consumers, or all
+consumers of the project, and restarts them when they exit.`,
+	RunE: runQueueStart,
+}
+
+var queueStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the queue consumers",
+	RunE:  runQueueStop,
+}
+
+var queueStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the queue consumers and their restarts",
+	RunE:  runQueueStatus,
+}
+
+func init() {
+	queueStartCmd.Flags().BoolVar(&queueForeground, "foreground", false, "Supervise the consumers in the foreground")
+
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueStartCmd)
+	queueCmd.AddCommand(queueStopCmd)
+	queueCmd.AddCommand(queueStatusCmd)
+	rootCmd.AddCommand(queueCmd)
+}
+
+// queueConsumers returns the consumers bin/magento queue:consumers:list reports
+func queueConsumers(p *platform.Platform, cfg *config.Config, cwd string) ([]string, error) {
+	listCmd := magentoCommand(context.Background(), p, cfg, cwd, "queue:consumers:list")
+	output, err := listCmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("bin/magento queue:consumers:list failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("bin/magento queue:consumers:list failed: %w", err)
+	}
+	return queue.ParseConsumers(output), nil
+}
+
+// runningQueueSupervisor returns the PID of the project's queue supervisor,
+// or 0 when it is not running
+func runningQueueSupervisor(p *platform.Platform, projectName string) int {
+	pid, err := readPidFile(getQueuePidFile(p, projectName))
+	if err != nil || !processRunning(pid) || pid == os.Getpid() {
+		return 0
+	}
+	return pid
+}
+
+func runQueueList(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+		cli.PrintError("bin/magento not found in %s", cwd)
+		return nil
+	}
+
+	consumers, err := queueConsumers(p, cfg, cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	running := map[string]bool{}
+	if runningQueueSupervisor(p, cfg.Name) != 0 {
+		if state, err := queue.LoadState(getQueueStatePath(p, cfg.Name)); err == nil {
+			for name := range state.Consumers {
+				running[name] = true
+			}
+		}
+	}
+
+	cli.PrintTitle("Queue Consumers")
+	for _, name := range consumers {
+		if running[name] {
+			fmt.Printf("  %s %s\n", name, cli.Success("(supervised)"))
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	fmt.Println()
+	cli.PrintInfo("%d consumer(s), %d supervised", len(consumers), len(running))
+	return nil
+}
+
+func runQueueStart(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+		cli.PrintError("bin/magento not found in %s", cwd)
+		return nil
+	}
+
+	if pid := runningQueueSupervisor(p, cfg.Name); pid != 0 {
+		cli.PrintWarning("Queue consumers are already running for this project (PID %d)", pid)
+		cli.PrintInfo("Restart them with %s", cli.Command("magebox queue stop && magebox queue start"))
+		return nil
+	}
+
+	available, err := queueConsumers(p, cfg, cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	consumers := available
+	if len(args) > 0 {
+		for _, name := range args {
+			if !slices.Contains(available, name) {
+				cli.PrintError("Unknown consumer '%s'", name)
+				cli.PrintInfo("List the consumers with %s", cli.Command("magebox queue list"))
+				return nil
+			}
+		}
+		consumers = args
+	}
+	if len(consumers) == 0 {
+		cli.PrintInfo("The project has no queue consumers")
+		return nil
+	}
+
+	if !queueForeground {
+		return startQueueSupervisor(p, cfg.Name, cwd, consumers)
+	}
+
+	// Stop the consumers on Ctrl+C or 'magebox queue stop'
+	commandHandlesInterrupt.Store(true)
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pidFile := getQueuePidFile(p, cfg.Name)
+	if err := writePidFile(pidFile, os.Getpid()); err != nil {
+		return err
+	}
+	defer os.Remove(pidFile)
+
+	supervisor := &queue.Supervisor{
+		Consumers: consumers,
+		Command: func(ctx context.Context, consumer string) *exec.Cmd {
+			return magentoCommand(ctx, p, cfg, cwd, "queue:consumers:start", consumer)
+		},
+		StatePath: getQueueStatePath(p, cfg.Name),
+		Log:       os.Stdout,
+	}
+	return supervisor.Run(ctx)
+}
+
+// startQueueSupervisor re-runs the command with --foreground as a detached
+// process writing to the queue log
+func startQueueSupervisor(p *platform.Platform, projectName, cwd string, consumers []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	logPath := getQueueLogPath(p, projectName)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	job := exec.Command(exe, append([]string{"queue", "start", "--foreground"}, consumers...)...)
+	job.Dir = cwd
+	job.Stdout = logFile
+	job.Stderr = logFile
+	job.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := job.Start(); err != nil {
+		return fmt.Errorf("failed to start queue supervisor: %w", err)
+	}
+	pid := job.Process.Pid
+	if err := writePidFile(getQueuePidFile(p, projectName), pid); err != nil {
+		return err
+	}
+	_ = job.Process.Release()
+
+	cli.PrintSuccess("Running %d queue consumer(s) in the background (PID %d)", len(consumers), pid)
+	cli.PrintInfo("Status: %s", cli.Command("magebox queue status"))
+	cli.PrintInfo("Log:    %s", cli.Path(logPath))
+	return nil
+}
+
+func runQueueStop(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	if !stopQueueConsumers(p, cfg.Name) {
+		cli.PrintInfo("No queue consumers are running for this project")
+		return nil
+	}
+	cli.PrintSuccess("Queue consumers stopped")
+	return nil
+}
+
+// stopQueueConsumers stops the project's queue supervisor, which stops its
+// consumers, and waits for it to exit. It reports whether one was running.
+func stopQueueConsumers(p *platform.Platform, projectName string) bool {
+	pidFile := getQueuePidFile(p, projectName)
+	pid := runningQueueSupervisor(p, projectName)
+	if pid == 0 {
+		os.Remove(pidFile)
+		return false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil || process.Signal(syscall.SIGTERM) != nil {
+		return false
+	}
+
+	// Consumers get queue.DefaultStopTimeout to finish their message
+	deadline := time.Now().Add(queue.DefaultStopTimeout + 5*time.Second)
+	for processRunning(pid) && time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+	}
+	return true
+}
+
+func runQueueStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	state, err := queue.LoadState(getQueueStatePath(p, cfg.Name))
+	if err != nil {
+		return err
+	}
+
+	cli.PrintTitle("Queue Consumers")
+	fmt.Printf("Project:    %s\n", cli.Highlight(cfg.Name))
+
+	pid := runningQueueSupervisor(p, cfg.Name)
+	if pid == 0 {
+		fmt.Printf("Supervisor: %s\n", cli.Warning("not running"))
+		fmt.Println()
+		cli.PrintInfo("Start the consumers with %s", cli.Command("magebox queue start"))
+		return nil
+	}
+	fmt.Printf("Supervisor: %s (PID %d, since %s)\n", cli.Success("running"), pid, state.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println()
+
+	crashed := 0
+	for _, name := range state.Names() {
+		c := state.Consumers[name]
+		status := cli.Success(fmt.Sprintf("running (PID %d)", c.PID))
+		if c.PID == 0 {
+			status = cli.Warning("restarting")
+		}
+		fmt.Printf("  %-45s %s\n", name, status)
+		if c.Restarts == 0 {
+			continue
+		}
+		restarts := fmt.Sprintf("%d restart(s), %d crash(es), last: %s at %s",
+			c.Restarts, c.Crashes, c.LastExit, c.LastExitAt.Local().Format("15:04:05"))
+		if c.Crashes > 0 {
+			crashed++
+			fmt.Printf("  %-45s %s\n", "", cli.Error(restarts))
+		} else {
+			fmt.Printf("  %-45s %s\n", "", cli.Dim+restarts+cli.Reset)
+		}
+	}
+
+	if crashed > 0 {
+		fmt.Println()
+		cli.PrintWarning("%d consumer(s) crashed since the start, see %s", crashed, cli.Path(getQueueLogPath(p, cfg.Name)))
+	}
+	return nil
+}
+
+func getQueuePidFile(p *platform.Platform, projectName string) string {
+	return filepath.Join(p.MageBoxDir(), "run", fmt.Sprintf("queue-%s.pid", projectName))
+}
+
+func getQueueStatePath(p *platform.Platform, projectName string) string {
+	return filepath.Join(p.MageBoxDir(), "run", fmt.Sprintf("queue-%s.json", projectName))
+}
+
+func getQueueLogPath(p *platform.Platform, projectName string) string {
+	return filepath.Join(p.MageBoxDir(), "logs", fmt.Sprintf("queue-%s.log", projectName))
+}
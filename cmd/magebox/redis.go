@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -10,6 +11,7 @@ import (
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/project"
 )
 
 var redisCmd = &cobra.Command{
@@ -21,10 +23,23 @@ var redisCmd = &cobra.Command{
 var redisFlushCmd = &cobra.Command{
 	Use:   "flush",
 	Short: "Flush cache",
-	Long:  "Flushes all data from the Redis/Valkey cache",
-	RunE:  runRedisFlush,
+	Long: `Flushes the project's Redis/Valkey databases (cache, page cache and
+sessions). Other projects sharing the container keep their data; --all
+flushes every database.`,
+	RunE: runRedisFlush,
 }
 
+var redisReleaseCmd = &cobra.Command{
+	Use:   "release <project>",
+	Short: "Free the cache databases of a project",
+	Long: `Frees the Redis/Valkey databases allocated to a project, e.g. one that was
+deleted. The project gets a new block on its next start.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRedisRelease,
+}
+
+var redisFlushAll bool
+
 var redisShellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Open cache shell",
@@ -40,7 +55,9 @@ var redisInfoCmd = &cobra.Command{
 }
 
 func init() {
+	redisFlushCmd.Flags().BoolVar(&redisFlushAll, "all", false, "Flush the databases of all projects")
 	redisCmd.AddCommand(redisFlushCmd)
+	redisCmd.AddCommand(redisReleaseCmd)
 	redisCmd.AddCommand(redisShellCmd)
 	redisCmd.AddCommand(redisInfoCmd)
 	rootCmd.AddCommand(redisCmd)
@@ -58,6 +75,16 @@ func getCacheServiceInfo(cfg *config.Config) (serviceName, cliBinary, displayNam
 	return "", "", "", false
 }
 
+// cacheCLIArgs builds a redis-cli/valkey-cli invocation, authenticating as the
+// project's cache user when a password is configured
+func cacheCLIArgs(cfg *config.Config, cliBinary string, args ...string) []string {
+	cmdArgs := []string{cliBinary}
+	if user, password := cfg.RedisCredentials(); password != "" {
+		cmdArgs = append(cmdArgs, "--user", user, "--pass", password, "--no-auth-warning")
+	}
+	return append(cmdArgs, args...)
+}
+
 func runRedisFlush(cmd *cobra.Command, args []string) error {
 	cwd, err := getCwd()
 	if err != nil {
@@ -83,21 +110,67 @@ func runRedisFlush(cmd *cobra.Command, args []string) error {
 	composeGen := docker.NewComposeGenerator(p)
 	composeFile := composeGen.ComposeFilePath()
 
-	cli.PrintInfo("Flushing %s cache...", displayName)
+	if redisFlushAll {
+		cli.PrintInfo("Flushing all %s databases...", displayName)
+		if err := flushCacheDatabases(composeFile, serviceName, cacheCLIArgs(cfg, cliBinary, "FLUSHALL")); err != nil {
+			cli.PrintError("Failed to flush %s: %v", displayName, err)
+			return nil
+		}
+		cli.PrintSuccess("%s cache flushed successfully", displayName)
+		return nil
+	}
 
-	flushCmd := docker.BuildComposeCmd(composeFile, "exec", "-T", serviceName, cliBinary, "FLUSHALL")
-	output, err := flushCmd.CombinedOutput()
+	dbs, err := project.NewRedisDBRegistry(p).Allocate(cfg.Name, cwd)
 	if err != nil {
-		cli.PrintError("Failed to flush %s: %v", displayName, err)
+		cli.PrintError("%v", err)
 		return nil
 	}
 
-	if strings.TrimSpace(string(output)) == "OK" {
-		cli.PrintSuccess("%s cache flushed successfully", displayName)
-	} else {
-		fmt.Println(string(output))
+	cli.PrintInfo("Flushing %s databases %d, %d and %d...", displayName, dbs.Cache, dbs.PageCache, dbs.Session)
+	for _, db := range dbs.Numbers() {
+		if err := flushCacheDatabases(composeFile, serviceName, cacheCLIArgs(cfg, cliBinary, "-n", strconv.Itoa(db), "FLUSHDB")); err != nil {
+			cli.PrintError("Failed to flush %s database %d: %v", displayName, db, err)
+			return nil
+		}
+	}
+	cli.PrintSuccess("%s cache flushed successfully", displayName)
+
+	return nil
+}
+
+// flushCacheDatabases runs a flush command in the cache container
+func flushCacheDatabases(composeFile, serviceName string, cliArgs []string) error {
+	flushCmd := docker.BuildComposeCmd(composeFile, append([]string{"exec", "-T", serviceName}, cliArgs...)...)
+	output, err := flushCmd.CombinedOutput()
+	if err != nil {
+		return err
+	}
+	if result := strings.TrimSpace(string(output)); result != "OK" {
+		return fmt.Errorf("%s", result)
+	}
+	return nil
+}
+
+func runRedisRelease(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
 	}
 
+	registry := project.NewRedisDBRegistry(p)
+	dbs, ok, err := registry.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		cli.PrintWarning("Project '%s' has no cache databases", args[0])
+		return nil
+	}
+
+	if err := registry.Release(args[0]); err != nil {
+		return err
+	}
+	cli.PrintSuccess("Released databases %d, %d and %d of '%s'", dbs.Cache, dbs.PageCache, dbs.Session, args[0])
 	return nil
 }
 
@@ -129,7 +202,7 @@ func runRedisShell(cmd *cobra.Command, args []string) error {
 	cli.PrintInfo("Connecting to %s...", displayName)
 	fmt.Println()
 
-	shellCmd := docker.BuildComposeCmd(composeFile, "exec", serviceName, cliBinary)
+	shellCmd := docker.BuildComposeCmd(composeFile, append([]string{"exec", serviceName}, cacheCLIArgs(cfg, cliBinary)...)...)
 	shellCmd.Stdin = os.Stdin
 	shellCmd.Stdout = os.Stdout
 	shellCmd.Stderr = os.Stderr
@@ -165,7 +238,7 @@ func runRedisInfo(cmd *cobra.Command, args []string) error {
 	cli.PrintTitle("%s Information", displayName)
 	fmt.Println()
 
-	infoCmd := docker.BuildComposeCmd(composeFile, "exec", "-T", serviceName, cliBinary, "INFO")
+	infoCmd := docker.BuildComposeCmd(composeFile, append([]string{"exec", "-T", serviceName}, cacheCLIArgs(cfg, cliBinary, "INFO")...)...)
 	output, err := infoCmd.Output()
 	if err != nil {
 		cli.PrintError("Failed to get %s info: %v", displayName, err)
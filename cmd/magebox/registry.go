@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/fileutil"
+	"qoliber/magebox/internal/project"
+)
+
+var (
+	registryRelocate []string
+	registryClone    bool
+	registryDryRun   bool
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Export and import the project registry",
+	Long: `Exports all registered projects with their paths, domains, PHP versions,
+services, allocated ports and config into one portable file, and imports such
+a file on another machine.
+
+Team leads can audit the environments of the team with it, and new machines
+can be pre-seeded with the projects of an existing one. Everything stays on
+your machine: MageBox sends no usage data anywhere.
+
+Examples:
+  magebox registry export projects.json
+  magebox registry export projects.yaml
+  magebox registry stats
+  magebox registry import projects.json --relocate /Users/jane=/home/jane --clone`,
+}
+
+var registryExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the registered projects",
+	Long: `Writes the registered projects to a file, or to stdout without one. The
+format follows the file extension (.yaml or .yml for YAML, JSON otherwise) or
+--output on stdout.
+
+Only the shared project config is exported, never .magebox.local.yaml.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRegistryExport,
+}
+
+var registryImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Pre-seed projects from an export",
+	Long: `Recreates the projects of an export on this machine. Projects whose
+directory exists get their .magebox.yaml back when it is missing; with --clone,
+missing projects are cloned from their git remote first. Existing configs are
+never overwritten.
+
+Run 'magebox start' in a project to register its domains with nginx.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRegistryImport,
+}
+
+var registryStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Count the projects per PHP version and service",
+	RunE:  runRegistryStats,
+}
+
+func init() {
+	registryImportCmd.Flags().StringArrayVar(&registryRelocate, "relocate", nil, "Rewrite a path prefix, as FROM=TO (repeatable)")
+	registryImportCmd.Flags().BoolVar(&registryClone, "clone", false, "Clone missing projects from their git remote")
+	registryImportCmd.Flags().BoolVar(&registryDryRun, "dry-run", false, "Show what would be done without changing anything")
+
+	registryCmd.AddCommand(registryExportCmd)
+	registryCmd.AddCommand(registryImportCmd)
+	registryCmd.AddCommand(registryStatsCmd)
+	rootCmd.AddCommand(registryCmd)
+}
+
+// exportRegistry collects the registry of this machine
+func exportRegistry() (*project.Registry, error) {
+	p, err := getPlatform()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := project.NewProjectDiscovery(p).ExportRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover projects: %w", err)
+	}
+	registry.MageBox = version
+	return registry, nil
+}
+
+func runRegistryExport(cmd *cobra.Command, args []string) error {
+	registry, err := exportRegistry()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		format := outputFormat
+		if !structuredOutput() {
+			format = "json"
+		}
+		return writeStructured(os.Stdout, format, registry)
+	}
+
+	path := args[0]
+	format := "json"
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+
+	var buf strings.Builder
+	if err := writeStructured(&buf, format, registry); err != nil {
+		return err
+	}
+	if err := fileutil.WriteFileAtomic(path, []byte(buf.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	cli.PrintSuccess("Exported %d project(s) to %s", len(registry.Projects), cli.Path(path))
+	return nil
+}
+
+func runRegistryImport(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		cli.PrintError("Failed to read %s: %v", args[0], err)
+		return nil
+	}
+
+	// JSON is valid YAML, so one decoder reads both formats
+	var registry project.Registry
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		cli.PrintError("Invalid registry export %s: %v", args[0], err)
+		return nil
+	}
+	if registry.Version > project.RegistryVersion {
+		cli.PrintError("%s was exported by a newer MageBox (format %d), run 'magebox self-update'", args[0], registry.Version)
+		return nil
+	}
+
+	relocate := make(map[string]string)
+	for _, r := range registryRelocate {
+		from, to, ok := strings.Cut(r, "=")
+		if !ok || from == "" || to == "" {
+			cli.PrintError("Invalid --relocate %q, use FROM=TO", r)
+			return nil
+		}
+		relocate[from] = to
+	}
+
+	if registryDryRun {
+		cli.PrintTitle("Registry Import (dry run)")
+	} else {
+		cli.PrintTitle("Registry Import")
+	}
+	if registry.Host != "" {
+		fmt.Printf("Exported from %s on %s\n", cli.Highlight(registry.Host), registry.ExportedAt.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	results := project.ImportRegistry(&registry, project.ImportOptions{
+		Relocate: relocate,
+		Clone:    registryClone,
+		DryRun:   registryDryRun,
+	})
+
+	var seeded, missing, failed int
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("  %s %-25s %s\n", cli.Error(""), r.Name, cli.Error(r.Err.Error()))
+			continue
+		case r.Action == project.ImportWroteConfig || r.Action == project.ImportCloned:
+			seeded++
+			fmt.Printf("  %s %-25s %s\n", cli.Success(""), r.Name, string(r.Action))
+		case r.Action == project.ImportMissing:
+			missing++
+			fmt.Printf("  %s %-25s %s\n", cli.Warning(""), r.Name, cli.Warning(string(r.Action)))
+		case r.Action == project.ImportConfigChanged:
+			fmt.Printf("  %s %-25s %s\n", cli.Warning(""), r.Name, cli.Warning(string(r.Action)+", kept the local one"))
+		default:
+			fmt.Printf("  %s %-25s %s\n", cli.Info(""), r.Name, string(r.Action))
+		}
+		fmt.Printf("    %s\n", cli.Path(r.Path))
+	}
+
+	fmt.Println()
+	cli.PrintInfo("%d seeded, %d missing, %d failed of %d project(s)", seeded, missing, failed, len(results))
+	if missing > 0 && !registryClone {
+		cli.PrintInfo("Clone missing projects with %s, or move paths with %s", cli.Command("--clone"), cli.Command("--relocate FROM=TO"))
+	}
+	if seeded > 0 && !registryDryRun {
+		cli.PrintInfo("Run %s in each project to register its domains", cli.Command("magebox start"))
+	}
+	return nil
+}
+
+func runRegistryStats(cmd *cobra.Command, args []string) error {
+	registry, err := exportRegistry()
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printStructured(registry.Stats)
+	}
+
+	cli.PrintTitle("Project Registry")
+	fmt.Printf("Projects: %d\n", registry.Stats.Projects)
+	printRegistryCounts("PHP", registry.Stats.PHP)
+	printRegistryCounts("Services", registry.Stats.Services)
+	return nil
+}
+
+// printRegistryCounts prints counts by name, most used first
+func printRegistryCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Println()
+	fmt.Println(cli.Header(title))
+	for _, name := range names {
+		fmt.Printf("  %-25s %d\n", name, counts[name])
+	}
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/dns"
+	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/remote"
+	"qoliber/magebox/internal/remotevm"
+	"qoliber/magebox/internal/ssl"
+)
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Run the project stack on a remote Linux VM",
+	Long: `Runs the project's MageBox stack on a remote Linux VM while its domains keep
+working on this machine. PHP, Nginx and the Docker services live on the VM;
+locally MageBox only forwards ports over SSH and proxies the project domains
+to them.
+
+  magebox remote provision dev@build-box     # install MageBox on the VM
+  magebox remote attach dev@build-box        # sync config, start, forward
+  magebox remote exec bin/magento cache:flush
+  magebox remote detach
+
+The project code lives on the VM in ~/magebox/<project> (see --dir); clone
+it there and edit it with your IDE's remote development support. Only the
+MageBox config (.magebox.yaml, .magebox.local.yaml, .magebox/) is synced.`,
+}
+
+var remoteProvisionCmd = &cobra.Command{
+	Use:   "provision <[user@]host[:port]>",
+	Short: "Install MageBox on a remote VM",
+	Long: `Installs MageBox on a remote Linux VM over SSH, unless it is already
+installed, then runs 'magebox bootstrap' there to set up PHP, Nginx, Docker
+and DNS. Bootstrap runs in a terminal, so sudo can ask for a password.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRemoteProvision,
+}
+
+var remoteAttachCmd = &cobra.Command{
+	Use:   "attach <[user@]host[:port]>",
+	Short: "Run the project on a remote VM",
+	Long: `Attaches the current project to a remote VM:
+
+  1. Syncs the project config to the VM
+  2. Runs 'magebox start' on the VM
+  3. Forwards the VM's Nginx (and any --forward ports) over an SSH tunnel
+  4. Replaces the local vhosts with proxies to the tunnel, so the project
+     domains open the stack on the VM
+
+Examples:
+  magebox remote attach dev@build-box
+  magebox remote attach build-box --forward 33080 --forward 8025
+  magebox remote attach dev@10.0.0.5:2222 --dir /srv/mystore --no-start`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRemoteAttach,
+}
+
+var remoteExecCmd = &cobra.Command{
+	Use:   "exec <command> [args...]",
+	Short: "Run a command in the project directory on the VM",
+	Long: `Runs a command in the project directory on the attached VM.
+Put -- before commands that take flags.
+
+Examples:
+  magebox remote exec magebox status
+  magebox remote exec -- bin/magento setup:upgrade --keep-generated`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRemoteExec,
+}
+
+var remoteSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the project config to the VM",
+	Long:  "Copies the project config to the attached VM and optionally restarts the project there",
+	RunE:  runRemoteSync,
+}
+
+var remoteStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the remote VM of the project",
+	Long:  "Shows the VM the project is attached to, the SSH tunnel and the health of each domain",
+	RunE:  runRemoteStatus,
+}
+
+var remoteDetachCmd = &cobra.Command{
+	Use:   "detach",
+	Short: "Stop forwarding the project to its remote VM",
+	Long:  "Closes the SSH tunnel and removes the local proxy vhosts; run 'magebox start' to serve the project locally again",
+	RunE:  runRemoteDetach,
+}
+
+var (
+	remoteSSHKey        string
+	remoteDir           string
+	remoteForwards      []string
+	remoteHTTPPort      int
+	remoteHTTPSPort     int
+	remoteNoStart       bool
+	remoteSkipBootstrap bool
+	remoteRestart       bool
+	remoteStop          bool
+)
+
+func init() {
+	remoteProvisionCmd.Flags().StringVar(&remoteSSHKey, "ssh-key", "", "SSH private key (default: SSH agent or ~/.ssh/config)")
+	remoteProvisionCmd.Flags().BoolVar(&remoteSkipBootstrap, "skip-bootstrap", false, "Only install MageBox, don't run 'magebox bootstrap'")
+
+	remoteAttachCmd.Flags().StringVar(&remoteSSHKey, "ssh-key", "", "SSH private key (default: SSH agent or ~/.ssh/config)")
+	remoteAttachCmd.Flags().StringVar(&remoteDir, "dir", "", "Project directory on the VM (default: ~/magebox/<project>)")
+	remoteAttachCmd.Flags().StringArrayVar(&remoteForwards, "forward", nil, "Extra port to forward from the VM, as port or local:remote (repeatable)")
+	remoteAttachCmd.Flags().IntVar(&remoteHTTPPort, "http-port", remotevm.DefaultHTTPPort, "Local port the VM's HTTP port is forwarded to")
+	remoteAttachCmd.Flags().IntVar(&remoteHTTPSPort, "https-port", remotevm.DefaultHTTPSPort, "Local port the VM's HTTPS port is forwarded to")
+	remoteAttachCmd.Flags().BoolVar(&remoteNoStart, "no-start", false, "Don't run 'magebox start' on the VM")
+
+	remoteSyncCmd.Flags().BoolVar(&remoteRestart, "restart", false, "Run 'magebox restart' on the VM after syncing")
+	remoteDetachCmd.Flags().BoolVar(&remoteStop, "stop", false, "Run 'magebox stop' on the VM before detaching")
+
+	remoteCmd.AddCommand(remoteProvisionCmd)
+	remoteCmd.AddCommand(remoteAttachCmd)
+	remoteCmd.AddCommand(remoteExecCmd)
+	remoteCmd.AddCommand(remoteSyncCmd)
+	remoteCmd.AddCommand(remoteStatusCmd)
+	remoteCmd.AddCommand(remoteDetachCmd)
+	rootCmd.AddCommand(remoteCmd)
+}
+
+func runRemoteProvision(cmd *cobra.Command, args []string) error {
+	target, err := remotevm.ParseTarget(args[0])
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	target.SSHKey = remoteSSHKey
+	provider, err := remotevm.NewProvider(target)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	cli.PrintTitle("Provisioning %s", target)
+	fmt.Println()
+
+	if err := runInteractive(provider.Command("", remotevm.ProvisionScript, false)); err != nil {
+		cli.PrintError("Failed to install MageBox on %s: %v", target, err)
+		return nil
+	}
+
+	if !remoteSkipBootstrap {
+		fmt.Println()
+		cli.PrintInfo("Running 'magebox bootstrap' on %s...", target)
+		if err := runInteractive(provider.Command("", remotevm.RemoteCommand("magebox bootstrap"), true)); err != nil {
+			cli.PrintError("Bootstrap failed on %s: %v", target, err)
+			return nil
+		}
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("%s is ready", target)
+	cli.PrintInfo("Attach a project with: magebox remote attach %s", args[0])
+	return nil
+}
+
+func runRemoteAttach(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	statePath := remotevm.StatePath(p.MageBoxDir(), cfg.Name)
+	if state, err := remotevm.LoadState(statePath); err == nil {
+		cli.PrintError("%s is already attached to %s; run 'magebox remote detach' first", cfg.Name, state.Target)
+		return nil
+	}
+
+	target, err := remotevm.ParseTarget(args[0])
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	target.SSHKey = remoteSSHKey
+	provider, err := remotevm.NewProvider(target)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	state := &remotevm.State{
+		Project:    cfg.Name,
+		Target:     target,
+		Dir:        remoteDir,
+		HTTPPort:   remoteHTTPPort,
+		HTTPSPort:  remoteHTTPSPort,
+		AttachedAt: time.Now().UTC(),
+	}
+	if state.Dir == "" {
+		state.Dir = remotevm.DefaultDir(cfg.Name)
+	}
+	for _, spec := range remoteForwards {
+		f, err := remotevm.ParseForward(spec)
+		if err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		state.Forwards = append(state.Forwards, f)
+	}
+	for _, d := range cfg.Domains {
+		state.Domains = append(state.Domains, d.Host)
+	}
+
+	cli.PrintTitle("Attaching %s to %s", cfg.Name, target)
+	fmt.Println()
+
+	fmt.Print("Syncing project config... ")
+	if err := syncRemoteConfig(provider, cwd, state.Dir); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintError("%v", err)
+		return nil
+	}
+	fmt.Println(cli.Success("done"))
+
+	if !remoteNoStart {
+		cli.PrintInfo("Starting %s on %s...", cfg.Name, target)
+		if err := runInteractive(provider.Command(state.Dir, remotevm.RemoteCommand("magebox start"), true)); err != nil {
+			cli.PrintError("'magebox start' failed on %s: %v", target, err)
+			return nil
+		}
+		fmt.Println()
+	}
+
+	fmt.Print("Opening SSH tunnel... ")
+	pid, err := startRemoteTunnel(p, provider, state)
+	if err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintError("%v", err)
+		return nil
+	}
+	state.TunnelPID = pid
+	fmt.Println(cli.Success("done"))
+
+	if err := state.Save(statePath); err != nil {
+		stopRemoteTunnel(pid)
+		return fmt.Errorf("failed to save remote state: %w", err)
+	}
+
+	fmt.Print("Proxying domains to the VM... ")
+	if err := writeRemoteProxies(p, cfg, state); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("%v", err)
+	} else {
+		fmt.Println(cli.Success("done"))
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	globalCfg, _ := config.LoadGlobalConfig(homeDir)
+	if globalCfg.UseHosts() {
+		if err := dns.NewHostsManager(p).AddDomains(state.Domains); err != nil {
+			cli.PrintWarning("Failed to update hosts: %v", err)
+		}
+	}
+
+	reloadNginx(p)
+
+	fmt.Println()
+	cli.PrintSuccess("%s runs on %s", cfg.Name, target)
+	for _, d := range cfg.Domains {
+		scheme := "https"
+		if !d.IsSSLEnabled() {
+			scheme = "http"
+		}
+		fmt.Printf("  %s\n", cli.URL(scheme+"://"+d.Host+"/"))
+	}
+	for _, f := range state.Forwards {
+		fmt.Printf("  localhost:%d → VM port %d\n", f.Local, f.Remote)
+	}
+	return nil
+}
+
+func runRemoteExec(cmd *cobra.Command, args []string) error {
+	_, _, state, provider, ok := loadRemoteState()
+	if !ok {
+		return nil
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = remote.ShellQuote(arg)
+	}
+	info, err := os.Stdin.Stat()
+	tty := err == nil && info.Mode()&os.ModeCharDevice != 0
+
+	if err := runInteractive(provider.Command(state.Dir, remotevm.RemoteCommand(strings.Join(quoted, " ")), tty)); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
+
+func runRemoteSync(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+	_, _, state, provider, ok := loadRemoteState()
+	if !ok {
+		return nil
+	}
+
+	fmt.Printf("Syncing project config to %s... ", state.Target)
+	if err := syncRemoteConfig(provider, cwd, state.Dir); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintError("%v", err)
+		return nil
+	}
+	fmt.Println(cli.Success("done"))
+
+	if remoteRestart {
+		if err := runInteractive(provider.Command(state.Dir, remotevm.RemoteCommand("magebox restart"), true)); err != nil {
+			cli.PrintError("'magebox restart' failed on %s: %v", state.Target, err)
+		}
+	}
+	return nil
+}
+
+func runRemoteStatus(cmd *cobra.Command, args []string) error {
+	_, cfg, state, _, ok := loadRemoteState()
+	if !ok {
+		return nil
+	}
+
+	cli.PrintTitle("Remote VM")
+	fmt.Println()
+	fmt.Printf("Project:  %s\n", cli.Highlight(state.Project))
+	fmt.Printf("VM:       %s (%s)\n", cli.Highlight(state.Target.String()), state.Target.Provider)
+	fmt.Printf("Dir:      %s\n", state.Dir)
+	fmt.Printf("Attached: %s\n", state.AttachedAt.Local().Format("2006-01-02 15:04"))
+	fmt.Printf("Tunnel:   %s\n", cli.Status(processRunning(state.TunnelPID)))
+
+	fmt.Println(cli.Header("Forwards"))
+	for _, f := range state.AllForwards() {
+		fmt.Printf("  localhost:%-6d → VM port %d\n", f.Local, f.Remote)
+	}
+
+	fmt.Println(cli.Header("Domains"))
+	for _, d := range cfg.Domains {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		health, err := nginx.CheckHealth(ctx, nginx.HealthURL(d.Host, d.IsSSLEnabled()))
+		cancel()
+		switch {
+		case err != nil:
+			fmt.Printf("  %-40s %s\n", d.Host, cli.Error("unreachable"))
+		case !health.OK():
+			fmt.Printf("  %-40s %s\n", d.Host, cli.Warning(health.Status))
+		default:
+			fmt.Printf("  %-40s %s\n", d.Host, cli.Success("PHP "+health.PHPVersion))
+		}
+	}
+	return nil
+}
+
+func runRemoteDetach(cmd *cobra.Command, args []string) error {
+	p, cfg, state, provider, ok := loadRemoteState()
+	if !ok {
+		return nil
+	}
+
+	if remoteStop {
+		if err := runInteractive(provider.Command(state.Dir, remotevm.RemoteCommand("magebox stop"), false)); err != nil {
+			cli.PrintWarning("'magebox stop' failed on %s: %v", state.Target, err)
+		}
+	}
+
+	fmt.Print("Closing SSH tunnel... ")
+	stopRemoteTunnel(state.TunnelPID)
+	fmt.Println(cli.Success("done"))
+
+	vhostGen := nginx.NewVhostGenerator(p, ssl.NewManager(p))
+	for _, host := range state.Domains {
+		if err := vhostGen.RemoveProxyVhost(remoteProxyName(cfg.Name, host)); err != nil {
+			cli.PrintWarning("Failed to remove proxy vhost for %s: %v", host, err)
+		}
+	}
+	reloadNginx(p)
+
+	if err := os.Remove(remotevm.StatePath(p.MageBoxDir(), cfg.Name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	cli.PrintSuccess("%s detached from %s", cfg.Name, state.Target)
+	cli.PrintInfo("Run 'magebox start' to serve the project locally again")
+	return nil
+}
+
+// loadRemoteState loads the project config and its attach state
+func loadRemoteState() (*platform.Platform, *config.Config, *remotevm.State, remotevm.Provider, bool) {
+	cwd, err := getCwd()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil, nil, nil, nil, false
+	}
+	p, err := getPlatform()
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil, nil, nil, nil, false
+	}
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil, nil, nil, nil, false
+	}
+
+	state, err := remotevm.LoadState(remotevm.StatePath(p.MageBoxDir(), cfg.Name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			cli.PrintError("%s isn't attached to a remote VM; run 'magebox remote attach <host>'", cfg.Name)
+		} else {
+			cli.PrintError("Failed to read remote state: %v", err)
+		}
+		return nil, nil, nil, nil, false
+	}
+	provider, err := remotevm.NewProvider(state.Target)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil, nil, nil, nil, false
+	}
+	return p, cfg, state, provider, true
+}
+
+// syncRemoteConfig copies the project config to dir on the VM
+func syncRemoteConfig(provider remotevm.Provider, cwd, dir string) error {
+	if out, err := provider.Command("", "mkdir -p "+remote.ShellQuote(dir), false).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create %s: %s", dir, strings.TrimSpace(string(out)))
+	}
+	if out, err := provider.Sync(cwd, remotevm.ProjectFiles(cwd), dir).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to sync config: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// startRemoteTunnel starts the SSH tunnel in the background and returns its
+// pid once the forwards are up
+func startRemoteTunnel(p *platform.Platform, provider remotevm.Provider, state *remotevm.State) (int, error) {
+	logPath := filepath.Join(p.MageBoxDir(), "logs", "remote-"+state.Project+".log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return 0, err
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	tunnel := provider.Tunnel(state.AllForwards())
+	tunnel.Stdout = logFile
+	tunnel.Stderr = logFile
+	tunnel.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := tunnel.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start ssh: %w", err)
+	}
+
+	// ssh exits right away when a forward can't be set up
+	exited := make(chan error, 1)
+	go func() { exited <- tunnel.Wait() }()
+	select {
+	case <-exited:
+		out, _ := os.ReadFile(logPath)
+		return 0, fmt.Errorf("SSH tunnel closed: %s", strings.TrimSpace(string(out)))
+	case <-time.After(3 * time.Second):
+	}
+	return tunnel.Process.Pid, nil
+}
+
+// stopRemoteTunnel stops the SSH tunnel if it still runs
+func stopRemoteTunnel(pid int) {
+	if pid == 0 || !processRunning(pid) {
+		return
+	}
+	if process, err := os.FindProcess(pid); err == nil {
+		_ = process.Signal(syscall.SIGTERM)
+	}
+}
+
+// remoteProxyName returns the proxy vhost name of a domain served from the VM
+func remoteProxyName(project, host string) string {
+	return "remote-" + project + "-" + host
+}
+
+// writeRemoteProxies replaces the project vhosts with proxies to the tunnel
+func writeRemoteProxies(p *platform.Platform, cfg *config.Config, state *remotevm.State) error {
+	vhostGen := nginx.NewVhostGenerator(p, ssl.NewManager(p))
+	if err := vhostGen.Remove(cfg.Name); err != nil {
+		return err
+	}
+	for _, d := range cfg.Domains {
+		port := state.HTTPPort
+		if d.IsSSLEnabled() {
+			port = state.HTTPSPort
+		}
+		if err := vhostGen.GenerateProxyVhost(nginx.ProxyConfig{
+			Name:        remoteProxyName(cfg.Name, d.Host),
+			Domain:      d.Host,
+			ProxyHost:   "127.0.0.1",
+			ProxyPort:   port,
+			UpstreamSSL: d.IsSSLEnabled(),
+			SSLEnabled:  d.IsSSLEnabled(),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reloadNginx tests and reloads the local Nginx
+func reloadNginx(p *platform.Platform) {
+	fmt.Print("Reloading nginx... ")
+	ngxController := nginx.NewController(p)
+	if err := ngxController.Test(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Nginx config test failed: %v", err)
+		return
+	}
+	if err := ngxController.Reload(); err != nil {
+		fmt.Println(cli.Error("failed"))
+		cli.PrintWarning("Failed to reload nginx: %v", err)
+		return
+	}
+	fmt.Println(cli.Success("done"))
+}
+
+// runInteractive runs a command attached to the terminal
+func runInteractive(c *exec.Cmd) error {
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/project"
 )
 
@@ -14,12 +15,16 @@ var restartAllProjects bool
 var restartCmd = &cobra.Command{
 	Use:   "restart",
 	Short: "Restart project services",
-	Long:  "Stops and starts all services for the current project, or all projects with --all",
-	RunE:  runRestart,
+	Long: `Stops and starts all services for the current project, or all projects with --all.
+
+Restarting the current project runs its pre_stop, pre_start and post_start
+hooks; --no-hooks skips them.`,
+	RunE: runRestart,
 }
 
 func init() {
 	restartCmd.Flags().BoolVarP(&restartAllProjects, "all", "a", false, "Restart all MageBox projects")
+	restartCmd.Flags().BoolVar(&skipHooks, "no-hooks", false, "Don't run the project's hooks")
 	rootCmd.AddCommand(restartCmd)
 }
 
@@ -30,6 +35,7 @@ func runRestart(cmd *cobra.Command, args []string) error {
 	}
 
 	mgr := project.NewManager(p)
+	mgr.SetLowMemory(lowMemoryDefault(p))
 
 	if restartAllProjects {
 		return restartAll(p, mgr)
@@ -47,6 +53,16 @@ func runRestart(cmd *cobra.Command, args []string) error {
 	// On macOS, verify pf port forwarding is active (survives reboot/sleep)
 	ensurePortForwarding()
 
+	cfg, _ := config.LoadFromPath(cwd)
+	if cfg != nil {
+		for _, event := range []string{config.HookPreStop, config.HookPreStart} {
+			if err := runHook(cfg, cwd, event); err != nil {
+				cli.PrintError("%v", err)
+				return err
+			}
+		}
+	}
+
 	// Stop
 	fmt.Print("Stopping services... ")
 	if err := mgr.Stop(cwd); err != nil {
@@ -91,6 +107,13 @@ func runRestart(cmd *cobra.Command, args []string) error {
 		fmt.Println(result.SystemINIInfo)
 	}
 
+	if cfg != nil && len(result.Errors) == 0 {
+		if err := runHook(cfg, cwd, config.HookPostStart); err != nil {
+			cli.PrintError("%v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
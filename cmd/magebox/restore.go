@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/backup"
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/ssl"
+)
+
+var (
+	restoreYes    bool
+	restoreNoDB   bool
+	restoreDBOnly bool
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore a project backup",
+	Long: `Restores an archive written by 'magebox backup' into the current
+directory, usually a fresh checkout of the project:
+
+  - .magebox.yaml, .magebox.local.yaml, app/etc/env.php and pub/media are written
+  - SSL certificates are installed when they were issued by this machine's
+    mkcert CA; otherwise 'magebox start' generates new ones
+  - the databases are replaced with the dumps
+
+The databases are imported into the running database service, so on a new
+machine restore the files, start the project and then import the databases:
+
+  magebox restore mystore.tar.zst
+  magebox start
+  magebox restore mystore.tar.zst --db-only`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Skip the confirmation prompt")
+	restoreCmd.Flags().BoolVar(&restoreNoDB, "no-db", false, "Restore only the files")
+	restoreCmd.Flags().BoolVar(&restoreDBOnly, "db-only", false, "Restore only the databases")
+	restoreCmd.MarkFlagsMutuallyExclusive("no-db", "db-only")
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	archivePath := args[0]
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	manifest, err := backup.ReadManifest(archivePath)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	restoreFiles := !restoreDBOnly
+	restoreDB := !restoreNoDB && len(manifest.Databases) > 0
+
+	cli.PrintTitle("Restore Backup")
+	fmt.Printf("Project:   %s\n", cli.Highlight(manifest.Project))
+	fmt.Printf("Created:   %s (MageBox %s)\n", manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"), manifest.MageBox)
+	if len(manifest.Databases) > 0 {
+		fmt.Printf("Databases: %s\n", strings.Join(manifest.Databases, ", "))
+	}
+	if len(manifest.Certs) > 0 {
+		fmt.Printf("SSL:       %s\n", strings.Join(manifest.Certs, ", "))
+	}
+	fmt.Printf("Media:     %v\n", manifest.Media)
+	fmt.Printf("Target:    %s\n", cli.Path(cwd))
+	fmt.Println()
+
+	if !restoreYes {
+		if existing, err := config.LoadFromPath(cwd); err == nil && existing.Name != manifest.Project {
+			cli.PrintWarning("This directory holds project '%s', the backup is of '%s'", existing.Name, manifest.Project)
+		}
+		if restoreFiles {
+			cli.PrintWarning("Existing MageBox config, app/etc/env.php and media files will be overwritten!")
+		}
+		if restoreDB {
+			cli.PrintWarning("This will replace ALL data in database(s) %s!", strings.Join(manifest.Databases, ", "))
+		}
+		fmt.Print("Are you sure? [y/N]: ")
+
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			cli.PrintInfo("Aborted")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	if restoreFiles {
+		if err := restoreBackupFiles(archivePath, manifest, cwd); err != nil {
+			return err
+		}
+	}
+
+	if restoreDB {
+		if err := restoreBackupDatabases(archivePath, cwd); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("Backup of '%s' restored", manifest.Project)
+	if restoreFiles && !restoreDB {
+		cli.PrintInfo("Start the project with: magebox start")
+	}
+	return nil
+}
+
+// restoreBackupFiles writes the project files and certificates of a backup
+func restoreBackupFiles(archivePath string, manifest *backup.Manifest, cwd string) error {
+	fmt.Print("Restoring files... ")
+	files := 0
+	certs := make(map[string][]byte)
+	err := backup.Walk(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+		switch {
+		case strings.HasPrefix(hdr.Name, backup.ProjectPrefix):
+			target, err := backup.SafePath(cwd, strings.TrimPrefix(hdr.Name, backup.ProjectPrefix))
+			if err != nil {
+				return err
+			}
+			files++
+			return backup.ExtractTo(target, hdr, r)
+		case strings.HasPrefix(hdr.Name, backup.CertsPrefix):
+			// Certificates are small, keep them until they are checked
+			// against the local CA
+			data, err := io.ReadAll(r)
+			certs[strings.TrimPrefix(hdr.Name, backup.CertsPrefix)] = data
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println(cli.Error("failed"))
+		return err
+	}
+	fmt.Println(cli.Success(fmt.Sprintf("done (%d files)", files)))
+
+	if len(manifest.Certs) == 0 {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+	sslMgr := ssl.NewManager(p)
+
+	var foreign []string
+	for _, base := range manifest.Certs {
+		certPEM, keyPEM := certs[path.Join(base, "cert.pem")], certs[path.Join(base, "key.pem")]
+		if certPEM == nil || keyPEM == nil {
+			continue
+		}
+		if !sslMgr.IssuedByLocalCA(certPEM) {
+			foreign = append(foreign, base)
+			continue
+		}
+		if _, err := backup.SafePath(sslMgr.CertsDir(), base); err != nil {
+			return err
+		}
+		paths := sslMgr.GetCertPaths(base)
+		if err := os.MkdirAll(filepath.Dir(paths.CertFile), 0755); err != nil {
+			return fmt.Errorf("failed to create certs directory: %w", err)
+		}
+		if err := os.WriteFile(paths.CertFile, certPEM, 0644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(paths.KeyFile, keyPEM, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Restored SSL certificate for %s\n", base)
+	}
+	if len(foreign) > 0 {
+		cli.PrintInfo("SSL certificates for %s were issued by another machine's CA and are skipped; 'magebox start' generates new ones", strings.Join(foreign, ", "))
+	}
+	return nil
+}
+
+// restoreBackupDatabases replaces the project databases with the dumps of a
+// backup
+func restoreBackupDatabases(archivePath, cwd string) error {
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		return fmt.Errorf("failed to load the project config: %w", err)
+	}
+
+	db, err := getDbInfo(cfg)
+	if err != nil {
+		return err
+	}
+
+	checkCmd := exec.Command("docker", "ps", "--filter", "name=^"+db.ContainerName+"$", "--filter", "status=running", "-q")
+	if output, err := checkCmd.Output(); err != nil || len(strings.TrimSpace(string(output))) == 0 {
+		cli.PrintInfo("Start the project with 'magebox start', then run: magebox restore %s --db-only", archivePath)
+		return fmt.Errorf("%s is not running, the databases were not restored", db.ContainerName)
+	}
+
+	err = backup.Walk(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		if !strings.HasPrefix(hdr.Name, backup.DatabasePrefix) {
+			return nil
+		}
+		dbName := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, backup.DatabasePrefix), ".sql")
+		if !cfg.HasDatabase(dbName) {
+			cli.PrintWarning("Skipping database '%s', the project does not declare it", dbName)
+			return nil
+		}
+
+		fmt.Printf("Database '%s':\n", dbName)
+		if err := resetDatabase(db, dbName); err != nil {
+			return err
+		}
+
+		fmt.Print("Importing dump... ")
+		importCmd := exec.Command("docker", "exec", "-i", db.ContainerName,
+			"mysql", "-u"+db.User, "-p"+db.Password, dbName)
+		importCmd.Stdin = r
+		importCmd.Stderr = os.Stderr
+		if err := importCmd.Run(); err != nil {
+			fmt.Println(cli.Error("failed"))
+			return fmt.Errorf("import of '%s' failed: %w", dbName, err)
+		}
+		fmt.Println(cli.Success("done"))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("the databases were not restored: %w", err)
+	}
+	return nil
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/execctx"
+	"qoliber/magebox/internal/fileutil"
+	"qoliber/magebox/internal/progress"
 	"qoliber/magebox/internal/updater"
 	"qoliber/magebox/internal/verbose"
 )
@@ -20,6 +29,38 @@ var verbosity int
 // versionChecker runs an async update check in the background
 var versionChecker *updater.VersionChecker
 
+// progressFormat is the --progress output format: text or json
+var progressFormat string
+
+// events receives structured progress events; nil unless --progress json
+var events *progress.Emitter
+
+// interruptGrace is how long a command gets to stop its child processes after
+// Ctrl+C before MageBox exits anyway
+const interruptGrace = 5 * time.Second
+
+// commandHandlesInterrupt is set by long-running commands that install their
+// own Ctrl+C handling and shut down in their own time
+var commandHandlesInterrupt atomic.Bool
+
+// handleInterrupts cancels the command context on Ctrl+C or SIGTERM, which
+// stops the docker and nginx commands started with it. A second Ctrl+C, or the
+// command not returning within interruptGrace, exits immediately.
+func handleInterrupts(cancel context.CancelFunc) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+		if commandHandlesInterrupt.Load() {
+			return
+		}
+		signal.Stop(sigChan)
+		time.Sleep(interruptGrace)
+		os.Exit(130)
+	}()
+}
+
 func main() {
 	// If the first non-flag argument is not a known command,
 	// check if it's a custom command from .magebox and delegate to "run".
@@ -58,8 +99,18 @@ func main() {
 		}
 	}
 
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handleInterrupts(cancel)
+
+	executed, err := rootCmd.ExecuteContextC(ctx)
+	recordHistory(executed, os.Args[1:], err)
+	if err != nil {
+		events.Fail(err)
 		fmt.Fprintln(os.Stderr, err)
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
@@ -72,23 +123,53 @@ var rootCmd = &cobra.Command{
 It uses native PHP-FPM and Nginx for maximum performance,
 with Docker for services like MySQL, Redis, OpenSearch, and Varnish.`,
 	Version: version,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Set verbosity level based on -v count
 		verbose.SetLevel(verbose.Level(verbosity))
 
+		switch progressFormat {
+		case "text":
+		case "json":
+			// Events go to stderr so stdout output (e.g. db export -) stays usable
+			events = progress.NewEmitter(os.Stderr, strings.TrimPrefix(cmd.CommandPath(), "magebox "))
+		default:
+			return fmt.Errorf("invalid --progress format %q (use text or json)", progressFormat)
+		}
+
+		switch outputFormat {
+		case "text", "json", "yaml":
+		default:
+			return fmt.Errorf("invalid --output format %q (use text, json or yaml)", outputFormat)
+		}
+
+		// Bind docker and nginx commands to Ctrl+C and the configured timeouts
+		timeouts := execctx.DefaultTimeouts()
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			if globalCfg, err := config.LoadGlobalConfig(homeDir); err == nil {
+				timeouts = globalCfg.GetTimeouts()
+			}
+		}
+		execctx.SetDefaults(cmd.Context(), timeouts)
+
+		// Detect hand edits to generated vhosts, pools, VCL and compose files
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			fileutil.SetManifest(generatedManifestPath(homeDir), resolveGeneratedEdit)
+		}
+
 		if verbose.IsEnabled(verbose.LevelDebug) {
 			verbose.Debug("MageBox version: %s", version)
 			verbose.Debug("Verbosity level: %d", verbosity)
 			verbose.Env()
 		}
 
-		// Start async version check (skip for self-update and dev builds)
-		if cmd.Name() != "self-update" && version != "dev" {
+		// Start async version check (skip for self-update, read-only inspect and dev builds)
+		if cmd.Name() != "self-update" && cmd.Name() != "inspect" && version != "dev" {
 			if homeDir, err := os.UserHomeDir(); err == nil {
 				versionChecker = updater.NewVersionChecker(version, homeDir)
 				versionChecker.Start()
 			}
 		}
+		return nil
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		if versionChecker != nil {
@@ -109,4 +190,8 @@ with Docker for services like MySQL, Redis, OpenSearch, and Varnish.`,
 func init() {
 	// Global verbose flag - can be repeated: -v, -vv, -vvv
 	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Increase verbosity (-v, -vv, -vvv)")
+	// Machine-readable progress for GUI wrappers and IDE plugins
+	rootCmd.PersistentFlags().StringVar(&progressFormat, "progress", "text", "Progress output format: text or json (NDJSON events on stderr)")
+	// Machine-readable results of status and list commands for scripts
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format of status and list commands: text, json or yaml")
 }
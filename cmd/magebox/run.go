@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/frontend"
 	"qoliber/magebox/internal/php"
 )
 
@@ -169,35 +170,48 @@ func runCustomCommand(cmd *cobra.Command, args []string) error {
 		cmdToRun = cmdToRun + " " + strings.Join(args[1:], " ")
 	}
 
-	// Set up environment with correct PHP in PATH.
+	shellCmd, err := projectShellCommand(cfg, cwd, cmdToRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Running: %s\n\n", cmdToRun)
+	return shellCmd.Run()
+}
+
+// projectShellCommand returns a bash command running script in the project
+// directory, with the project's PHP first in PATH and its env vars set
+func projectShellCommand(cfg *config.Config, projectPath, script string) (*exec.Cmd, error) {
 	// Prepend ~/.magebox/bin (where the php/composer/blackfire wrappers live)
 	// so `php` resolves to the project-aware wrapper instead of a system PHP.
 	// On Linux, filepath.Dir(PHPBinary) is /usr/bin, which would shadow the
 	// wrapper and silently run the wrong PHP version.
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to determine home directory: %w", err)
+		return nil, fmt.Errorf("failed to determine home directory: %w", err)
 	}
 	wrapperDir := filepath.Join(homeDir, ".magebox", "bin")
-	currentPath := os.Getenv("PATH")
-	newPath := wrapperDir + string(os.PathListSeparator) + currentPath
+	newPath := wrapperDir + string(os.PathListSeparator) + os.Getenv("PATH")
 
-	fmt.Printf("Running: %s\n\n", cmdToRun)
+	// Projects pinning a Node.js version get it ahead of the system node
+	if req, pinned := frontend.DetectRequirement(projectPath, cfg.NodeVersion()); pinned {
+		if node, err := frontend.Resolve(req, homeDir); err == nil {
+			newPath = wrapperDir + string(os.PathListSeparator) + node.BinDir + string(os.PathListSeparator) + os.Getenv("PATH")
+		}
+	}
 
-	// Execute command via shell
-	shellCmd := exec.Command("bash", "-c", cmdToRun)
-	shellCmd.Dir = cwd
+	shellCmd := exec.Command("bash", "-c", script)
+	shellCmd.Dir = projectPath
 	shellCmd.Stdin = os.Stdin
 	shellCmd.Stdout = os.Stdout
 	shellCmd.Stderr = os.Stderr
 	shellCmd.Env = append(os.Environ(), "PATH="+newPath)
 
 	// Add project env vars
-	for key, value := range cfg.Env {
+	for key, value := range cfg.PHPEnv() {
 		shellCmd.Env = append(shellCmd.Env, key+"="+value)
 	}
-
-	return shellCmd.Run()
+	return shellCmd, nil
 }
 
 func completeCustomCommands(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/teamserver"
@@ -35,6 +36,18 @@ var (
 	serverMasterKey  string
 	serverBackground bool
 	serverRateLimit  int
+	serverNoUI       bool
+	serverSyncEvery  string
+	serverSyncDays   int
+	serverHealthTick string
+	serverSessionTTL string
+	serverRefreshTTL string
+	serverOIDCConfig string
+	serverCAConfig   string
+	serverDBDriver   string
+	serverDBDSNFile  string
+	serverWebhooks   []string
+	serverWebhookCfg string
 
 	// SMTP configuration
 	serverSMTPHost     string
@@ -114,6 +127,19 @@ func init() {
 	serverStartCmd.Flags().StringVar(&serverMasterKey, "master-key", "", "Master encryption key (hex)")
 	serverStartCmd.Flags().BoolVar(&serverBackground, "background", false, "Run in background")
 	serverStartCmd.Flags().IntVar(&serverRateLimit, "rate-limit", -1, "Rate limit per minute (0 to disable, -1 for default)")
+	serverStartCmd.Flags().BoolVar(&serverNoUI, "no-ui", false, "Don't serve the web admin UI under /ui")
+	serverStartCmd.Flags().StringVar(&serverSyncEvery, "sync-interval", "", "How often keys are reconciled with the environments, e.g. 30m (default 1h, 0 to disable)")
+	serverStartCmd.Flags().IntVar(&serverSyncDays, "sync-history-days", 90, "Days the sync history is kept (0 keeps it forever)")
+	serverStartCmd.Flags().StringVar(&serverHealthTick, "health-interval", "", "How often the environments are health checked, e.g. 5m (default 15m, 0 to disable)")
+	serverStartCmd.Flags().StringVar(&serverSessionTTL, "session-expiry", "", "How long session tokens are valid, e.g. 24h (default 720h, 0 for no expiry)")
+	serverStartCmd.Flags().StringVar(&serverRefreshTTL, "refresh-expiry", "", "How long refresh tokens rotate sessions, e.g. 720h (default 2160h, 0 for no expiry)")
+	serverStartCmd.Flags().StringVar(&serverOIDCConfig, "oidc-config", "", "SSO settings file (default: <data-dir>/oidc.yaml if it exists)")
+	serverStartCmd.Flags().StringVar(&serverCAConfig, "ca-config", "", "SSH CA settings file (default: <data-dir>/ca.yaml if it exists)")
+	addServerDatabaseFlags(serverStartCmd)
+
+	// Webhook flags
+	serverStartCmd.Flags().StringArrayVar(&serverWebhooks, "webhook", nil, "Webhook URL notified of events, Slack, Teams or JSON (repeatable)")
+	serverStartCmd.Flags().StringVar(&serverWebhookCfg, "webhooks-config", "", "Webhooks file (default: <data-dir>/webhooks.yaml if it exists)")
 
 	// SMTP configuration flags
 	serverStartCmd.Flags().StringVar(&serverSMTPHost, "smtp-host", "", "SMTP server host for email notifications")
@@ -126,6 +152,7 @@ func init() {
 	serverInitCmd.Flags().StringVar(&serverDataDir, "data-dir", "", "Data directory (default: ~/.magebox/teamserver)")
 	serverInitCmd.Flags().StringVar(&serverAdminToken, "admin-token", "", "Admin token (optional, for testing)")
 	serverInitCmd.Flags().StringVar(&serverMasterKey, "master-key", "", "Master key in hex (optional, for testing)")
+	addServerDatabaseFlags(serverInitCmd)
 
 	serverCmd.AddCommand(serverStartCmd)
 	serverCmd.AddCommand(serverStopCmd)
@@ -147,6 +174,62 @@ func getServerDataDir() (string, error) {
 	return filepath.Join(homeDir, ".magebox", "teamserver"), nil
 }
 
+// addServerDatabaseFlags adds the flags selecting the database of the server
+func addServerDatabaseFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&serverDBDriver, "db-driver", "", "Database driver: sqlite, postgres or mysql (default: sqlite)")
+	cmd.Flags().StringVar(&serverDBDSNFile, "db-dsn-file", "", "File holding the database DSN, readable by its owner only (default for sqlite: <data-dir>/teamserver.db)")
+}
+
+// getServerDatabase returns the database of the server: the flags, then
+// MAGEBOX_SERVER_DB_DRIVER and MAGEBOX_SERVER_DB_DSN, then server.json. The
+// DSN, which holds the database password, is read from a file and only its
+// path is saved.
+func getServerDatabase(dataDir string, savedConfig map[string]interface{}) (teamserver.DatabaseConfig, error) {
+	db := teamserver.DatabaseConfig{Driver: serverDBDriver}
+	if db.Driver == "" {
+		db.Driver = os.Getenv("MAGEBOX_SERVER_DB_DRIVER")
+	}
+	if driver, ok := savedConfig["db_driver"].(string); ok && db.Driver == "" {
+		db.Driver = driver
+	}
+
+	dsnFile := serverDBDSNFile
+	if dsnFile == "" {
+		db.DSN = os.Getenv("MAGEBOX_SERVER_DB_DSN")
+	}
+	if file, ok := savedConfig["db_dsn_file"].(string); ok && dsnFile == "" && db.DSN == "" {
+		dsnFile = file
+	}
+	if dsnFile != "" {
+		dsn, err := readDSNFile(dsnFile)
+		if err != nil {
+			return db, err
+		}
+		db.DSN = dsn
+	}
+	return db.Resolve(dataDir), nil
+}
+
+// readDSNFile reads a database DSN from a file other users can't read
+func readDSNFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the DSN file: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("the DSN file %s is readable by other users, restrict it with: chmod 600 %s", path, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the DSN file: %w", err)
+	}
+	dsn := strings.TrimSpace(string(data))
+	if dsn == "" {
+		return "", fmt.Errorf("the DSN file %s is empty", path)
+	}
+	return dsn, nil
+}
+
 func runServerInit(cmd *cobra.Command, args []string) error {
 	dataDir, err := getServerDataDir()
 	if err != nil {
@@ -224,13 +307,21 @@ func runServerInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create crypto: %w", err)
 	}
 
-	dbPath := filepath.Join(dataDir, "teamserver.db")
-	storage, err := teamserver.NewStorage(dbPath, crypto)
+	dbConfig, err := getServerDatabase(dataDir, nil)
+	if err != nil {
+		return err
+	}
+	storage, err := teamserver.OpenStorage(dbConfig, crypto)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer storage.Close()
 
+	if _, err := storage.Migrate(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	cli.PrintSuccess("Database initialized (%s)", dbConfig.Driver)
+
 	// Store CA keys (encrypted)
 	if err := storage.SaveCAKeys(caKeyPair.PrivateKeyPEM, caKeyPair.PublicKeySSH); err != nil {
 		return fmt.Errorf("failed to store CA keys: %w", err)
@@ -246,6 +337,15 @@ func runServerInit(cmd *cobra.Command, args []string) error {
 		"tls_enabled":      false,
 		"ca_enabled":       true,
 		"initialized_at":   time.Now().UTC().Format(time.RFC3339),
+		"db_driver":        dbConfig.Driver,
+	}
+	if serverDBDSNFile != "" {
+		// Only the path: the DSN holds the database password
+		dsnFile, err := filepath.Abs(serverDBDSNFile)
+		if err != nil {
+			return err
+		}
+		config["db_dsn_file"] = dsnFile
 	}
 
 	configData, err := json.MarshalIndent(config, "", "  ")
@@ -337,6 +437,9 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	config.Port = serverPort
 	config.Host = serverHost
 	config.AdminTokenHash = adminTokenHash
+	if config.Database, err = getServerDatabase(dataDir, savedConfig); err != nil {
+		return err
+	}
 
 	if port, ok := savedConfig["port"].(float64); ok && serverPort == 7443 {
 		config.Port = int(port)
@@ -371,6 +474,46 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 		config.Security.RateLimitPerMinute = serverRateLimit
 	}
 
+	config.UI.Disabled = serverNoUI
+	if serverSyncEvery != "" {
+		if _, err := time.ParseDuration(serverSyncEvery); err != nil && serverSyncEvery != "0" {
+			return fmt.Errorf("invalid --sync-interval %q: %w", serverSyncEvery, err)
+		}
+		config.Sync.Interval = serverSyncEvery
+	}
+	if serverSyncDays < 0 {
+		return fmt.Errorf("invalid --sync-history-days %d", serverSyncDays)
+	}
+	config.Sync.HistoryDays = serverSyncDays
+	if serverHealthTick != "" {
+		if _, err := time.ParseDuration(serverHealthTick); err != nil && serverHealthTick != "0" {
+			return fmt.Errorf("invalid --health-interval %q: %w", serverHealthTick, err)
+		}
+		config.Health.Interval = serverHealthTick
+	}
+	if serverSessionTTL != "" {
+		if _, err := time.ParseDuration(serverSessionTTL); err != nil && serverSessionTTL != "0" {
+			return fmt.Errorf("invalid --session-expiry %q: %w", serverSessionTTL, err)
+		}
+		config.Security.SessionExpiry = serverSessionTTL
+	}
+	if serverRefreshTTL != "" {
+		if _, err := time.ParseDuration(serverRefreshTTL); err != nil && serverRefreshTTL != "0" {
+			return fmt.Errorf("invalid --refresh-expiry %q: %w", serverRefreshTTL, err)
+		}
+		config.Security.RefreshExpiry = serverRefreshTTL
+	}
+
+	if err := loadOIDCConfig(config, dataDir); err != nil {
+		return err
+	}
+	if err := loadWebhooksConfig(config, dataDir); err != nil {
+		return err
+	}
+	if err := loadCAConfig(config, dataDir); err != nil {
+		return err
+	}
+
 	// SMTP configuration (flags take precedence over env vars)
 	smtpHost := serverSMTPHost
 	if smtpHost == "" {
@@ -415,6 +558,7 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	}
 
 	// Handle shutdown signals
+	commandHandlesInterrupt.Store(true)
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -438,6 +582,16 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	cli.PrintSuccess("Server starting on %s://%s:%d", protocol, config.Host, config.Port)
 	cli.PrintInfo("Data directory: %s", dataDir)
+	cli.PrintInfo("Database: %s", config.Database.Driver)
+	if !config.UI.Disabled {
+		cli.PrintInfo("Admin UI: %s://%s:%d/ui/", protocol, config.Host, config.Port)
+	}
+	if config.OIDC.Enabled {
+		cli.PrintInfo("SSO: %s", config.OIDC.Issuer)
+	}
+	if n := len(config.Notifications.Webhooks); n > 0 {
+		cli.PrintInfo("Webhooks: %d", n)
+	}
 	cli.PrintInfo("Press Ctrl+C to stop")
 	fmt.Println()
 
@@ -449,6 +603,113 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// loadOIDCConfig reads the SSO settings from --oidc-config or the data
+// directory. The client secret can be passed in MAGEBOX_OIDC_CLIENT_SECRET
+// instead of the file.
+func loadOIDCConfig(config *teamserver.ServerConfig, dataDir string) error {
+	path := serverOIDCConfig
+	if path == "" {
+		path = filepath.Join(dataDir, "oidc.yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read SSO config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &config.OIDC); err != nil {
+		return fmt.Errorf("failed to parse SSO config %s: %w", path, err)
+	}
+	if secret := os.Getenv("MAGEBOX_OIDC_CLIENT_SECRET"); secret != "" {
+		config.OIDC.ClientSecret = secret
+	}
+
+	if !config.OIDC.Enabled {
+		return nil
+	}
+	if config.OIDC.Issuer == "" || config.OIDC.ClientID == "" {
+		return fmt.Errorf("SSO config %s needs issuer and client_id", path)
+	}
+	if config.OIDC.DefaultRole != "" && !config.OIDC.DefaultRole.IsValid() {
+		return fmt.Errorf("SSO config %s has invalid default_role %q", path, config.OIDC.DefaultRole)
+	}
+	for group, mapping := range config.OIDC.Groups {
+		if !mapping.Role.IsValid() {
+			return fmt.Errorf("SSO config %s has invalid role %q for group %s", path, mapping.Role, group)
+		}
+	}
+	if config.OIDC.DefaultRole == "" && len(config.OIDC.Groups) == 0 {
+		return fmt.Errorf("SSO config %s needs default_role or groups, or no one can sign in", path)
+	}
+	return nil
+}
+
+// loadWebhooksConfig reads the webhooks from --webhooks-config or the data
+// directory, and adds those of --webhook and MAGEBOX_WEBHOOK_URLS, which
+// get all events
+func loadWebhooksConfig(config *teamserver.ServerConfig, dataDir string) error {
+	path := serverWebhookCfg
+	if path == "" {
+		path = filepath.Join(dataDir, "webhooks.yaml")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && (serverWebhookCfg != "" || !os.IsNotExist(err)) {
+		return fmt.Errorf("failed to read webhooks config: %w", err)
+	}
+	if err == nil {
+		var file struct {
+			Webhooks []teamserver.WebhookConfig `yaml:"webhooks"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse webhooks config %s: %w", path, err)
+		}
+		config.Notifications.Webhooks = file.Webhooks
+	}
+
+	urls := serverWebhooks
+	if env := os.Getenv("MAGEBOX_WEBHOOK_URLS"); env != "" {
+		urls = append(urls, strings.Split(env, ",")...)
+	}
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			config.Notifications.Webhooks = append(config.Notifications.Webhooks, teamserver.WebhookConfig{URL: u})
+		}
+	}
+
+	for _, w := range config.Notifications.Webhooks {
+		if err := w.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCAConfig reads the SSH CA settings, e.g. the principals of roles, from
+// --ca-config or the data directory
+func loadCAConfig(config *teamserver.ServerConfig, dataDir string) error {
+	path := serverCAConfig
+	if path == "" {
+		path = filepath.Join(dataDir, "ca.yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &config.CA); err != nil {
+		return fmt.Errorf("failed to parse CA config %s: %w", path, err)
+	}
+	if err := config.CA.Validate(); err != nil {
+		return fmt.Errorf("CA config %s: %w", path, err)
+	}
+	return nil
+}
+
 func runServerStop(cmd *cobra.Command, args []string) error {
 	dataDir, err := getServerDataDir()
 	if err != nil {
@@ -580,8 +841,15 @@ func runServerStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check database
-	dbPath := filepath.Join(dataDir, "teamserver.db")
-	if info, err := os.Stat(dbPath); err == nil {
+	db, err := getServerDatabase(dataDir, config)
+	if err != nil {
+		return err
+	}
+	cli.PrintInfo("Database: %s", db.Driver)
+	if db.Driver != teamserver.DriverSQLite {
+		return nil
+	}
+	if info, err := os.Stat(db.DSN); err == nil {
 		cli.PrintInfo("Database size: %.2f KB", float64(info.Size())/1024)
 	}
 
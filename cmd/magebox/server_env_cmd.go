@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -28,6 +29,10 @@ var (
 	serverEnvDeployUser string
 	serverEnvDeployKey  string
 	serverEnvProject    string
+	serverEnvPrincipals []string
+	serverEnvDefaultPr  bool
+	serverEnvDriftOnly  bool
+	serverEnvLimit      int
 )
 
 var serverEnvCmd = &cobra.Command{
@@ -42,7 +47,8 @@ can access all environments within that project.
 Examples:
   magebox server env list
   magebox server env add production --project myproject --host prod.example.com --deploy-user deploy --deploy-key ~/.ssh/deploy_prod
-  magebox server env remove myproject/staging`,
+  magebox server env remove myproject/staging
+  magebox server env check myproject/production`,
 	Run: func(cmd *cobra.Command, args []string) {
 		_ = cmd.Help()
 	},
@@ -56,9 +62,13 @@ var serverEnvAddCmd = &cobra.Command{
 The deploy key is the SSH private key used to connect to the server.
 The environment must belong to an existing project.
 
+With the SSH CA enabled, --principal sets the principals certificates get for
+the environment instead of the default principals of the server.
+
 Examples:
   magebox server env add production --project myproject --host prod.example.com --deploy-user deploy --deploy-key ~/.ssh/deploy_key
-  magebox server env add staging --project myproject --host staging.example.com --deploy-user deploy --deploy-key ~/.ssh/deploy_key`,
+  magebox server env add staging --project myproject --host staging.example.com --deploy-user deploy --deploy-key ~/.ssh/deploy_key
+  magebox server env add production --project myproject --host prod.example.com --deploy-key ~/.ssh/deploy_key --principal deploy-prod`,
 	Args: cobra.ExactArgs(1),
 	RunE: runServerEnvAdd,
 }
@@ -109,6 +119,60 @@ Examples:
 	RunE: runServerEnvSync,
 }
 
+var serverEnvCheckCmd = &cobra.Command{
+	Use:   "check <project/name>",
+	Short: "Check the health of an environment",
+	Long: `Connect to an environment with its deploy key and check its health.
+
+The check reports whether the server is reachable and how long connecting
+took, whether ~/.ssh/authorized_keys of the deploy user exists and is
+writable, and, with the SSH CA enabled, whether the TrustedUserCAKeys of sshd
+hold the CA of the team server.
+
+The team server checks all environments in the background as well, every
+15 minutes by default; 'magebox server env list' shows the latest results.
+
+Examples:
+  magebox server env check myproject/production`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServerEnvCheck,
+}
+
+var serverEnvPrincipalsCmd = &cobra.Command{
+	Use:   "principals <project/name> [principal...]",
+	Short: "Show or set the certificate principals of an environment",
+	Long: `Show or set the principals SSH certificates get for an environment.
+
+A certificate holds the principals of all environments of the user's projects,
+limited to the principals the user's role may get (role_principals in the CA
+settings). Environments without principals use the default principals of the
+server. With --default the environment goes back to them.
+
+Examples:
+  magebox server env principals myproject/production                  # Show
+  magebox server env principals myproject/production deploy-prod      # Set
+  magebox server env principals myproject/production --default        # Reset`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runServerEnvPrincipals,
+}
+
+var serverEnvHistoryCmd = &cobra.Command{
+	Use:   "history [project/name]",
+	Short: "Show the key sync history",
+	Long: `Show the results of past key syncs, manual and scheduled.
+
+The team server reconciles the keys of every environment in the background.
+A sync that added or removed keys found drift: keys that were missing, or
+keys that shouldn't have been on the server.
+
+Examples:
+  magebox server env history                       # All environments
+  magebox server env history myproject/production  # One environment
+  magebox server env history --drift               # Only drift and failures`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runServerEnvHistory,
+}
+
 func init() {
 	// Environment add flags
 	serverEnvAddCmd.Flags().StringVar(&serverEnvProject, "project", "", "Project this environment belongs to (required)")
@@ -116,6 +180,7 @@ func init() {
 	serverEnvAddCmd.Flags().IntVar(&serverEnvPort, "port", 22, "SSH port")
 	serverEnvAddCmd.Flags().StringVar(&serverEnvDeployUser, "deploy-user", "deploy", "Deploy username")
 	serverEnvAddCmd.Flags().StringVar(&serverEnvDeployKey, "deploy-key", "", "Path to deploy SSH private key (required)")
+	serverEnvAddCmd.Flags().StringArrayVar(&serverEnvPrincipals, "principal", nil, "Principal of certificates for this environment (repeatable, default: the server's default principals)")
 	_ = serverEnvAddCmd.MarkFlagRequired("project")
 	_ = serverEnvAddCmd.MarkFlagRequired("host")
 	_ = serverEnvAddCmd.MarkFlagRequired("deploy-key")
@@ -125,6 +190,14 @@ func init() {
 	serverEnvCmd.AddCommand(serverEnvListCmd)
 	serverEnvCmd.AddCommand(serverEnvShowCmd)
 	serverEnvCmd.AddCommand(serverEnvSyncCmd)
+	serverEnvCmd.AddCommand(serverEnvCheckCmd)
+
+	serverEnvPrincipalsCmd.Flags().BoolVar(&serverEnvDefaultPr, "default", false, "Use the default principals of the server")
+	serverEnvCmd.AddCommand(serverEnvPrincipalsCmd)
+
+	serverEnvHistoryCmd.Flags().BoolVar(&serverEnvDriftOnly, "drift", false, "Only show syncs that found drift or failed")
+	serverEnvHistoryCmd.Flags().IntVar(&serverEnvLimit, "limit", 20, "Number of entries to show")
+	serverEnvCmd.AddCommand(serverEnvHistoryCmd)
 
 	serverCmd.AddCommand(serverEnvCmd)
 }
@@ -156,6 +229,7 @@ func runServerEnvAdd(cmd *cobra.Command, args []string) error {
 		"port":        serverEnvPort,
 		"deploy_user": serverEnvDeployUser,
 		"deploy_key":  keyContent,
+		"principals":  serverEnvPrincipals,
 	}
 
 	resp, err := apiRequest("POST", "/api/admin/environments", reqBody, adminToken)
@@ -187,6 +261,9 @@ func runServerEnvAdd(cmd *cobra.Command, args []string) error {
 	cli.PrintInfo("Project:     %s", result.Project)
 	cli.PrintInfo("Host:        %s:%d", result.Host, result.Port)
 	cli.PrintInfo("Deploy User: %s", result.DeployUser)
+	if len(serverEnvPrincipals) > 0 {
+		cli.PrintInfo("Principals:  %s", strings.Join(serverEnvPrincipals, ", "))
+	}
 	fmt.Println()
 	cli.PrintInfo("Run 'magebox server env sync %s/%s' to deploy SSH keys", result.Project, envName)
 
@@ -236,7 +313,7 @@ func runServerEnvList(cmd *cobra.Command, args []string) error {
 		endpoint = "/api/admin/environments"
 	} else {
 		// Try as regular user
-		config, err := loadClientConfig()
+		config, err := loadClientSession()
 		if err != nil {
 			return fmt.Errorf("not authenticated. Use 'magebox server join' or set MAGEBOX_ADMIN_TOKEN")
 		}
@@ -263,6 +340,8 @@ func runServerEnvList(cmd *cobra.Command, args []string) error {
 		Port       int       `json:"port"`
 		DeployUser string    `json:"deploy_user"`
 		CreatedAt  time.Time `json:"created_at"`
+
+		Health *teamserver.EnvironmentHealth `json:"health"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&envs); err != nil {
@@ -286,6 +365,8 @@ func runServerEnvList(cmd *cobra.Command, args []string) error {
 		Port       int       `json:"port"`
 		DeployUser string    `json:"deploy_user"`
 		CreatedAt  time.Time `json:"created_at"`
+
+		Health *teamserver.EnvironmentHealth `json:"health"`
 	})
 
 	for _, env := range envs {
@@ -297,6 +378,9 @@ func runServerEnvList(cmd *cobra.Command, args []string) error {
 		for _, env := range envList {
 			fmt.Printf("    └─ %s\n", env.Name)
 			fmt.Printf("         Host: %s:%d  User: %s\n", env.Host, env.Port, env.DeployUser)
+			if adminToken != "" {
+				fmt.Printf("         Health: %s\n", formatEnvHealth(env.Health))
+			}
 		}
 		fmt.Println()
 	}
@@ -341,6 +425,9 @@ func runServerEnvShow(cmd *cobra.Command, args []string) error {
 		Port       int       `json:"port"`
 		DeployUser string    `json:"deploy_user"`
 		CreatedAt  time.Time `json:"created_at"`
+
+		Principals []string                      `json:"principals"`
+		Health     *teamserver.EnvironmentHealth `json:"health"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
@@ -353,7 +440,9 @@ func runServerEnvShow(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Host:        %s\n", env.Host)
 	fmt.Printf("  Port:        %d\n", env.Port)
 	fmt.Printf("  Deploy User: %s\n", env.DeployUser)
+	fmt.Printf("  Principals:  %s\n", formatEnvPrincipals(env.Principals))
 	fmt.Printf("  Created:     %s\n", env.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Printf("  Health:      %s\n", formatEnvHealth(env.Health))
 
 	// Show which users have access to this project
 	fmt.Println()
@@ -424,3 +513,195 @@ func runServerEnvSync(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runServerEnvHistory(cmd *cobra.Command, args []string) error {
+	adminToken, err := getAdminToken()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if len(args) > 0 {
+		query.Set("environment", args[0])
+	}
+	if serverEnvDriftOnly {
+		query.Set("drift", "true")
+	}
+	query.Set("limit", fmt.Sprintf("%d", serverEnvLimit))
+
+	resp, err := apiRequest("GET", "/api/admin/sync/history?"+query.Encode(), nil, adminToken)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to get sync history: %s", errResp.Error)
+	}
+
+	var entries []teamserver.SyncHistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(entries) == 0 {
+		cli.PrintInfo("No syncs recorded")
+		return nil
+	}
+
+	cli.PrintTitle("Key Sync History")
+	fmt.Println()
+
+	for _, e := range entries {
+		var status string
+		switch {
+		case !e.Success:
+			status = cli.Error("failed: " + e.Error)
+		case e.Drift():
+			status = cli.Warning(fmt.Sprintf("drift corrected (+%d, -%d)", e.KeysAdded, e.KeysRemoved))
+		default:
+			status = cli.Success("in sync")
+		}
+		fmt.Printf("  %s  %-9s  %-30s  %s\n", e.Timestamp.Local().Format("2006-01-02 15:04"), e.Trigger, e.Environment, status)
+	}
+
+	return nil
+}
+
+func runServerEnvCheck(cmd *cobra.Command, args []string) error {
+	envPath := args[0]
+
+	// Parse project/name format
+	if parts := strings.SplitN(envPath, "/", 2); len(parts) != 2 {
+		return fmt.Errorf("environment must be specified as project/name (e.g., myproject/staging)")
+	}
+
+	adminToken, err := getAdminToken()
+	if err != nil {
+		return err
+	}
+
+	cli.PrintInfo("Checking %s...", envPath)
+	fmt.Println()
+
+	resp, err := apiRequest("POST", "/api/admin/environments/"+envPath+"/check", nil, adminToken)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to check environment: %s", errResp.Error)
+	}
+
+	var health teamserver.EnvironmentHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	cli.PrintTitle("Environment Health: %s", envPath)
+	fmt.Println()
+	if health.Reachable {
+		fmt.Printf("  Reachable:       %s (%d ms)\n", cli.Success("yes"), health.LatencyMs)
+		fmt.Printf("  authorized_keys: %s, %d managed keys\n", health.AuthorizedKeys, health.ManagedKeys)
+		if health.TrustedCA != "" {
+			fmt.Printf("  Trusted CA:      %s\n", health.TrustedCA)
+		}
+	} else {
+		fmt.Printf("  Reachable:       %s\n", cli.Error("no"))
+	}
+	fmt.Println()
+
+	if health.Healthy {
+		cli.PrintSuccess("%s is healthy", envPath)
+		return nil
+	}
+	for _, problem := range health.Problems {
+		cli.PrintWarning("%s", problem)
+	}
+	fmt.Println()
+	cli.PrintError("%s is unhealthy", envPath)
+	return nil
+}
+
+// formatEnvHealth returns the latest health check of an environment for
+// listings
+func formatEnvHealth(health *teamserver.EnvironmentHealth) string {
+	switch {
+	case health == nil:
+		return "not checked yet"
+	case health.Healthy:
+		return cli.Success(fmt.Sprintf("healthy (%d ms)", health.LatencyMs))
+	case !health.Reachable:
+		return cli.Error("unreachable: " + strings.Join(health.Problems, "; "))
+	default:
+		return cli.Warning("unhealthy: " + strings.Join(health.Problems, "; "))
+	}
+}
+
+func runServerEnvPrincipals(cmd *cobra.Command, args []string) error {
+	envPath, principals := args[0], args[1:]
+
+	// Parse project/name format
+	if parts := strings.SplitN(envPath, "/", 2); len(parts) != 2 {
+		return fmt.Errorf("environment must be specified as project/name (e.g., myproject/staging)")
+	}
+	if serverEnvDefaultPr && len(principals) > 0 {
+		cli.PrintError("Give principals or --default, not both")
+		return nil
+	}
+	if err := teamserver.ValidatePrincipals(principals); err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	adminToken, err := getAdminToken()
+	if err != nil {
+		return err
+	}
+
+	method, body := "GET", interface{}(nil)
+	if serverEnvDefaultPr || len(principals) > 0 {
+		if principals == nil {
+			principals = []string{}
+		}
+		method, body = "PUT", teamserver.UpdateEnvironmentRequest{Principals: &principals}
+	}
+
+	resp, err := apiRequest(method, "/api/admin/environments/"+envPath, body, adminToken)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to update environment: %s", errResp.Error)
+	}
+
+	var env teamserver.Environment
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if method == "PUT" {
+		cli.PrintSuccess("Principals of %s set to %s", envPath, formatEnvPrincipals(env.Principals))
+		cli.PrintInfo("Certificates get them when they are issued or renewed")
+		return nil
+	}
+	cli.PrintInfo("Principals of %s: %s", envPath, formatEnvPrincipals(env.Principals))
+	return nil
+}
+
+// formatEnvPrincipals returns the principals of an environment for output
+func formatEnvPrincipals(principals []string) string {
+	if len(principals) == 0 {
+		return "the default principals"
+	}
+	return strings.Join(principals, ", ")
+}
@@ -0,0 +1,162 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/teamserver"
+)
+
+var (
+	serverMigrateStatus     bool
+	serverMigrateFromSQLite string
+)
+
+var serverMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply database migrations of the team server",
+	Long: `Apply the pending schema migrations to the team server database.
+
+The server applies them on start as well; run this before starting a new
+version against a shared PostgreSQL or MySQL database, or to check which
+migrations a database has with --status.
+
+With --from-sqlite the data of a SQLite database, e.g. the teamserver.db of a
+server so far, is copied into the configured database. The target must be
+empty. The master key stays the same, the copied secrets are still encrypted
+with it.
+
+Examples:
+  magebox server migrate
+  magebox server migrate --status
+  magebox server migrate --db-driver postgres --db-dsn-file /etc/magebox/db.dsn
+  magebox server migrate --db-driver mysql --from-sqlite ~/.magebox/teamserver/teamserver.db`,
+	Args: cobra.NoArgs,
+	RunE: runServerMigrate,
+}
+
+func init() {
+	serverMigrateCmd.Flags().StringVar(&serverDataDir, "data-dir", "", "Data directory (default: ~/.magebox/teamserver)")
+	serverMigrateCmd.Flags().BoolVar(&serverMigrateStatus, "status", false, "Only list the applied and pending migrations")
+	serverMigrateCmd.Flags().StringVar(&serverMigrateFromSQLite, "from-sqlite", "", "Copy the data of this SQLite database into the configured one")
+	addServerDatabaseFlags(serverMigrateCmd)
+
+	serverCmd.AddCommand(serverMigrateCmd)
+}
+
+func runServerMigrate(cmd *cobra.Command, args []string) error {
+	dataDir, err := getServerDataDir()
+	if err != nil {
+		return err
+	}
+
+	var savedConfig map[string]interface{}
+	if data, err := os.ReadFile(filepath.Join(dataDir, "server.json")); err == nil {
+		if err := json.Unmarshal(data, &savedConfig); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	dbConfig, err := getServerDatabase(dataDir, savedConfig)
+	if err != nil {
+		return err
+	}
+	if serverMigrateFromSQLite != "" {
+		if dbConfig.Driver == teamserver.DriverSQLite {
+			cli.PrintError("--from-sqlite copies into a PostgreSQL or MySQL database, select it with --db-driver and --db-dsn-file")
+			return nil
+		}
+		if _, err := os.Stat(serverMigrateFromSQLite); err != nil {
+			cli.PrintError("SQLite database not found: %s", serverMigrateFromSQLite)
+			return nil
+		}
+	}
+
+	// Migrations and copies don't decrypt anything, no master key needed
+	storage, err := teamserver.OpenStorage(dbConfig, nil)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	cli.PrintTitle("Team Server Migrations")
+	fmt.Println()
+	cli.PrintInfo("Database: %s", dbConfig.Driver)
+	fmt.Println()
+
+	applied, err := storage.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+	pending, err := storage.PendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range applied {
+		fmt.Printf("  %s %3d  applied %s\n", cli.Success("✓"), m.Version, m.AppliedAt.Local().Format("2006-01-02 15:04"))
+	}
+	for _, m := range pending {
+		fmt.Printf("  %s %3d  %s (pending)\n", cli.Warning("•"), m.Version, m.Description)
+	}
+	fmt.Println()
+
+	if serverMigrateStatus {
+		if len(pending) > 0 {
+			cli.PrintInfo("Apply them with: %s", cli.Command("magebox server migrate"))
+		}
+		return nil
+	}
+
+	if len(pending) == 0 {
+		cli.PrintSuccess("Database is up to date")
+	} else {
+		done, err := storage.Migrate()
+		for _, m := range done {
+			cli.PrintSuccess("Applied migration %d: %s", m.Version, m.Description)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if serverMigrateFromSQLite == "" {
+		return nil
+	}
+
+	fmt.Println()
+	source, err := teamserver.NewStorage(serverMigrateFromSQLite, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", serverMigrateFromSQLite, err)
+	}
+	defer source.Close()
+
+	counts, err := storage.CopyFrom(source)
+	if err != nil {
+		return fmt.Errorf("failed to copy the data: %w", err)
+	}
+	for _, c := range counts {
+		if c.Rows > 0 {
+			cli.PrintSuccess("%s: %d rows", c.Table, c.Rows)
+		}
+	}
+	fmt.Println()
+	cli.PrintSuccess("Copied %s into the %s database", serverMigrateFromSQLite, dbConfig.Driver)
+	if savedConfig != nil && savedConfig["db_driver"] != dbConfig.Driver {
+		cli.PrintInfo("Start the server with --db-driver %s and the DSN in --db-dsn-file or MAGEBOX_SERVER_DB_DSN", dbConfig.Driver)
+	}
+	return nil
+}
@@ -12,11 +12,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/teamserver"
 )
 
@@ -35,7 +37,8 @@ to projects, which gives them access to all environments within that project.
 Examples:
   magebox server project list
   magebox server project add myproject --description "My Project"
-  magebox server project remove myproject`,
+  magebox server project remove myproject
+  magebox server project push-config myproject .magebox.yaml`,
 	Run: func(cmd *cobra.Command, args []string) {
 		_ = cmd.Help()
 	},
@@ -85,6 +88,22 @@ var serverProjectShowCmd = &cobra.Command{
 	RunE:  runServerProjectShow,
 }
 
+var serverProjectPushConfigCmd = &cobra.Command{
+	Use:   "push-config <name> [file]",
+	Short: "Store the project's shared .magebox.yaml",
+	Long: `Upload the canonical .magebox.yaml of a project to the team server.
+
+Team members with access to the project fetch it with
+'magebox team pull-config <name>'. The file defaults to .magebox.yaml
+in the current directory. Pushing again replaces the stored config.
+
+Examples:
+  magebox server project push-config myproject
+  magebox server project push-config myproject ~/templates/shop.yaml`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runServerProjectPushConfig,
+}
+
 func init() {
 	// Project add flags
 	serverProjectAddCmd.Flags().StringVar(&serverProjectDescription, "description", "", "Project description")
@@ -93,6 +112,7 @@ func init() {
 	serverProjectCmd.AddCommand(serverProjectRemoveCmd)
 	serverProjectCmd.AddCommand(serverProjectListCmd)
 	serverProjectCmd.AddCommand(serverProjectShowCmd)
+	serverProjectCmd.AddCommand(serverProjectPushConfigCmd)
 
 	serverCmd.AddCommand(serverProjectCmd)
 }
@@ -339,3 +359,39 @@ func runServerProjectShow(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runServerProjectPushConfig(cmd *cobra.Command, args []string) error {
+	projectName := args[0]
+	file := config.ConfigFileName
+	if len(args) > 1 {
+		file = args[1]
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	adminToken, err := getAdminToken()
+	if err != nil {
+		return err
+	}
+
+	req := teamserver.SetProjectConfigRequest{Content: string(content)}
+	resp, err := apiRequest("PUT", "/api/admin/projects/"+projectName+"/config", req, adminToken)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to store project config: %s", errResp.Error)
+	}
+
+	cli.PrintSuccess("Config for project '%s' stored (%s)", projectName, file)
+	fmt.Printf("Team members can fetch it with: %s\n", cli.Command("magebox team pull-config "+projectName))
+
+	return nil
+}
@@ -0,0 +1,139 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/teamserver"
+)
+
+// sessionRotateWindow is how long before it expires a session is rotated
+const sessionRotateWindow = 10 * time.Minute
+
+var serverTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage your team server session",
+	Long: `Manage the session token of your team server connection.
+
+Session tokens expire (30 days by default). MageBox rotates them with the
+refresh token it got on joining before they do; once the refresh token
+expires as well, join the team server again. Sessions joined before tokens
+expired get a refresh token on their next request to the team server.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var serverTokenRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotate your session token",
+	Long: `Replace your session and refresh tokens with new ones. The old tokens
+stop working, rotate when you suspect they leaked.
+
+Examples:
+  magebox server token rotate`,
+	Args: cobra.NoArgs,
+	RunE: runServerTokenRotate,
+}
+
+func init() {
+	serverTokenCmd.AddCommand(serverTokenRotateCmd)
+	serverCmd.AddCommand(serverTokenCmd)
+}
+
+func runServerTokenRotate(cmd *cobra.Command, args []string) error {
+	config, err := loadClientConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := rotateClientSession(config); err != nil {
+		return err
+	}
+
+	cli.PrintSuccess("Session token rotated")
+	if config.SessionExpiresAt != nil {
+		cli.PrintInfo("Valid until %s", config.SessionExpiresAt.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// loadClientSession loads the client config for a request to the team
+// server, rotating the session token first when it is about to expire
+func loadClientSession() (*clientConfig, error) {
+	config, err := loadClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions joined before session expiry have no refresh token and would
+	// be locked out once the server stamps an expiry on them, so trade the
+	// session token for a session with a refresh token
+	if config.RefreshToken == "" && config.SessionToken != "" {
+		if err := rotateClientSession(config); err != nil {
+			cli.PrintWarning("Your team server session has no refresh token: %v", err)
+			cli.PrintInfo("Run 'magebox server token rotate' before the session expires")
+		}
+		return config, nil
+	}
+
+	if config.RefreshToken != "" && config.SessionExpiresAt != nil &&
+		time.Until(*config.SessionExpiresAt) < sessionRotateWindow {
+		if err := rotateClientSession(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// rotateClientSession gets new session tokens, with the refresh token when
+// there is one, and saves them
+func rotateClientSession(config *clientConfig) error {
+	var body interface{}
+	token := config.SessionToken
+	if config.RefreshToken != "" {
+		body = teamserver.TokenRotateRequest{RefreshToken: config.RefreshToken}
+		token = ""
+	}
+
+	resp, err := apiRequest("POST", "/api/token/rotate", body, token)
+	if err != nil {
+		return fmt.Errorf("failed to rotate session token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("session expired or revoked. Use 'magebox server join' to reconnect")
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to rotate session token: %s", errResp.Error)
+	}
+
+	var tokens teamserver.TokenRotateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	config.SessionToken = tokens.SessionToken
+	config.SessionExpiresAt = tokens.SessionExpiresAt
+	config.RefreshToken = tokens.RefreshToken
+	if err := saveClientConfig(config); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
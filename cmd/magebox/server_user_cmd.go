@@ -11,19 +11,23 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/credentials"
 	"qoliber/magebox/internal/teamserver"
 )
 
@@ -111,6 +115,7 @@ var (
 	userRole       string
 	userExpiryDays int
 	inviteToken    string
+	joinSSO        bool
 	userProject    string
 )
 
@@ -211,17 +216,53 @@ Examples:
 	RunE: runServerUserRevoke,
 }
 
+var serverUserRevokeSessionsCmd = &cobra.Command{
+	Use:   "revoke-sessions <name>",
+	Short: "Sign a user out everywhere",
+	Long: `Revoke a user's session and refresh tokens, e.g. after a token leaked.
+
+The user, their project access and SSH keys stay, their session token stops
+working at once. SSO users sign in again with 'magebox server join --sso';
+invited users are removed and invited again.
+
+Examples:
+  magebox server user revoke-sessions alice`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServerUserRevokeSessions,
+}
+
+var serverUserApproveSSOCmd = &cobra.Command{
+	Use:   "approve-sso <name>",
+	Short: "Link an SSO sign-in to an existing user",
+	Long: `Approve the SSO identity waiting to be linked to an existing user.
+
+When someone signs in with SSO as a user that already exists, e.g. one who
+joined with an invite before SSO was set up, the identity provider's account
+isn't linked to the user until an admin approves it. The user then signs in
+again with 'magebox server join --sso'.
+
+Examples:
+  magebox server user approve-sso alice`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServerUserApproveSSO,
+}
+
 var serverJoinCmd = &cobra.Command{
 	Use:   "join <server-url>",
 	Short: "Join a team server",
-	Long: `Join a MageBox team server using an invite token.
+	Long: `Join a MageBox team server using an invite token, or by signing in
+through your company's identity provider if the server has SSO enabled.
 
 The server generates a unique SSH key pair for you. The private key is
 downloaded and saved locally. You can then use 'magebox ssh <env>' to
 connect to environments you have access to.
 
+With --sso, a browser opens to sign in. Run it again to sign in on
+another machine or when your session expires.
+
 Examples:
-  magebox server join https://teamserver.example.com --token <invite-token>`,
+  magebox server join https://teamserver.example.com --token <invite-token>
+  magebox server join https://teamserver.example.com --sso`,
 	Args: cobra.ExactArgs(1),
 	RunE: runServerJoin,
 }
@@ -250,8 +291,10 @@ func init() {
 	_ = serverUserRevokeCmd.MarkFlagRequired("project")
 
 	// Join flags
-	serverJoinCmd.Flags().StringVar(&inviteToken, "token", "", "Invite token (required)")
-	_ = serverJoinCmd.MarkFlagRequired("token")
+	serverJoinCmd.Flags().StringVar(&inviteToken, "token", "", "Invite token")
+	serverJoinCmd.Flags().BoolVar(&joinSSO, "sso", false, "Sign in through the server's identity provider instead of an invite token")
+	serverJoinCmd.MarkFlagsMutuallyExclusive("token", "sso")
+	serverJoinCmd.MarkFlagsOneRequired("token", "sso")
 
 	serverUserCmd.AddCommand(serverUserAddCmd)
 	serverUserCmd.AddCommand(serverUserRemoveCmd)
@@ -260,23 +303,29 @@ func init() {
 	serverUserCmd.AddCommand(serverUserRenewCmd)
 	serverUserCmd.AddCommand(serverUserGrantCmd)
 	serverUserCmd.AddCommand(serverUserRevokeCmd)
+	serverUserCmd.AddCommand(serverUserRevokeSessionsCmd)
+	serverUserCmd.AddCommand(serverUserApproveSSOCmd)
 
 	serverCmd.AddCommand(serverUserCmd)
 	serverCmd.AddCommand(serverJoinCmd)
 	serverCmd.AddCommand(serverWhoamiCmd)
 }
 
-// Client configuration for connecting to team server
+// Client configuration for connecting to team server. The session and
+// refresh tokens are kept in the credential store, not in client.json.
 type clientConfig struct {
 	ServerURL    string                    `json:"server_url"`
-	SessionToken string                    `json:"session_token"`
+	SessionToken string                    `json:"session_token,omitempty"`
 	UserName     string                    `json:"user_name"`
 	Role         string                    `json:"role"`
 	JoinedAt     string                    `json:"joined_at"`
-	KeyPath      string                    `json:"key_path"`     // Path to private SSH key (used by older config)
-	KeyFile      string                    `json:"key_file"`     // Path to private SSH key (for cert command)
+	KeyPath      string                    `json:"key_path"`     // Path of the SSH key files (used by older config)
+	KeyFile      string                    `json:"key_file"`     // Path of the SSH key files (for cert command)
 	CAEnabled    bool                      `json:"ca_enabled"`   // Whether SSH CA is enabled
 	Environments []clientEnvironmentConfig `json:"environments"` // Accessible environments
+
+	SessionExpiresAt *time.Time `json:"session_expires_at,omitempty"`
+	RefreshToken     string     `json:"refresh_token,omitempty"` // Rotates the session token before it expires
 }
 
 // clientEnvironmentConfig stores environment info for SSH connections
@@ -288,12 +337,32 @@ type clientEnvironmentConfig struct {
 	DeployUser string `json:"deploy_user"`
 }
 
-func getClientConfigPath() (string, error) {
+// privateKeyPath returns the path the user's SSH key files are named after:
+// the public key is at <path>.pub and the certificate at <path>-cert.pub.
+// The private key is kept in the credential store.
+func (c *clientConfig) privateKeyPath() string {
+	if c.KeyFile != "" {
+		return c.KeyFile
+	}
+	return c.KeyPath
+}
+
+// getClientDir returns the directory the team server session, SSH keys
+// and SSH config are kept in
+func getClientDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(homeDir, ".magebox", "teamserver", "client.json"), nil
+	return filepath.Join(homeDir, ".magebox", "team"), nil
+}
+
+func getClientConfigPath() (string, error) {
+	dir, err := getClientDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "client.json"), nil
 }
 
 func loadClientConfig() (*clientConfig, error) {
@@ -303,6 +372,11 @@ func loadClientConfig() (*clientConfig, error) {
 	}
 
 	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Sessions used to be kept next to the server's data
+		homeDir, _ := os.UserHomeDir()
+		data, err = os.ReadFile(filepath.Join(homeDir, ".magebox", "teamserver", "client.json"))
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("not connected to a team server. Use 'magebox server join' first")
@@ -315,6 +389,26 @@ func loadClientConfig() (*clientConfig, error) {
 		return nil, err
 	}
 
+	if config.SessionToken != "" {
+		// Sessions used to be saved in plain text, move the tokens into
+		// the credential store
+		if err := saveClientConfig(&config); err != nil {
+			cli.PrintWarning("Failed to move the session tokens into the credential store: %v", err)
+		}
+		return &config, nil
+	}
+
+	store, err := clientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if config.SessionToken, err = getCredential(store, credentialSessionToken); err != nil {
+		return nil, err
+	}
+	if config.RefreshToken, err = getCredential(store, credentialRefreshToken); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
@@ -328,7 +422,21 @@ func saveClientConfig(config *clientConfig) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	store, err := clientCredentials()
+	if err != nil {
+		return err
+	}
+	if err := setCredential(store, credentialSessionToken, config.SessionToken); err != nil {
+		return err
+	}
+	if err := setCredential(store, credentialRefreshToken, config.RefreshToken); err != nil {
+		return err
+	}
+
+	withoutTokens := *config
+	withoutTokens.SessionToken = ""
+	withoutTokens.RefreshToken = ""
+	data, err := json.MarshalIndent(&withoutTokens, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -336,6 +444,48 @@ func saveClientConfig(config *clientConfig) error {
 	return os.WriteFile(path, data, 0600)
 }
 
+// Keys of the session tokens in the credential store
+const (
+	credentialSessionToken = "team/session_token"
+	credentialRefreshToken = "team/refresh_token"
+)
+
+// clientCredentials returns the credential store of the session tokens: the
+// OS keychain, or an encrypted file in the client directory
+func clientCredentials() (credentials.Store, error) {
+	dir, err := getClientDir()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.Open(dir), nil
+}
+
+// getCredential reads a credential, empty when it isn't stored
+func getCredential(store credentials.Store, key string) (string, error) {
+	value, err := store.Get(key)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the %s: %w", key, store.Name(), err)
+	}
+	return value, nil
+}
+
+// setCredential saves a credential, or removes it when value is empty
+func setCredential(store credentials.Store, key, value string) error {
+	var err error
+	if value == "" {
+		err = store.Delete(key)
+	} else {
+		err = store.Set(key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save %s in the %s: %w", key, store.Name(), err)
+	}
+	return nil
+}
+
 func getAdminToken() (string, error) {
 	// Check environment variable first
 	if token := os.Getenv("MAGEBOX_ADMIN_TOKEN"); token != "" {
@@ -495,6 +645,58 @@ func runServerUserRemove(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runServerUserRevokeSessions(cmd *cobra.Command, args []string) error {
+	userName := args[0]
+
+	adminToken, err := getAdminToken()
+	if err != nil {
+		return err
+	}
+
+	resp, err := apiRequest("DELETE", "/api/admin/users/"+userName+"/sessions", nil, adminToken)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to revoke sessions: %s", errResp.Error)
+	}
+
+	cli.PrintSuccess("Sessions of '%s' revoked", userName)
+	cli.PrintInfo("SSO users sign in again with 'magebox server join --sso', invited users need a new invite")
+
+	return nil
+}
+
+func runServerUserApproveSSO(cmd *cobra.Command, args []string) error {
+	userName := args[0]
+
+	adminToken, err := getAdminToken()
+	if err != nil {
+		return err
+	}
+
+	resp, err := apiRequest("POST", "/api/admin/users/"+userName+"/sso", nil, adminToken)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to approve SSO sign-in: %s", errResp.Error)
+	}
+
+	cli.PrintSuccess("SSO sign-in of '%s' approved", userName)
+	cli.PrintInfo("The user signs in again with 'magebox server join --sso'")
+
+	return nil
+}
+
 func runServerUserList(cmd *cobra.Command, args []string) error {
 	adminToken, err := getAdminToken()
 	if err != nil {
@@ -676,6 +878,32 @@ func runServerUserRenew(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// joinResult is what the server returns on joining
+type joinResult struct {
+	SessionToken string     `json:"session_token"`
+	PrivateKey   string     `json:"private_key"`
+	Certificate  string     `json:"certificate,omitempty"`
+	ValidUntil   *time.Time `json:"valid_until,omitempty"`
+	Principals   []string   `json:"principals,omitempty"`
+	CAEnabled    bool       `json:"ca_enabled"`
+	CAPublicKey  string     `json:"ca_public_key,omitempty"`
+	ServerHost   string     `json:"server_host"`
+	User         struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	} `json:"user"`
+	Environments []struct {
+		Name       string `json:"name"`
+		Project    string `json:"project"`
+		Host       string `json:"host"`
+		Port       int    `json:"port"`
+		DeployUser string `json:"deploy_user"`
+	} `json:"environments"`
+
+	SessionExpiresAt *time.Time `json:"session_expires_at,omitempty"`
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+}
+
 func runServerJoin(cmd *cobra.Command, args []string) error {
 	// Validate and normalize the server URL
 	serverURLArg, err := validateServerURL(args[0])
@@ -686,60 +914,22 @@ func runServerJoin(cmd *cobra.Command, args []string) error {
 	cli.PrintInfo("Joining team server: %s", serverURLArg)
 	fmt.Println()
 
-	// Make join request (server will generate SSH key pair)
-	reqBody := map[string]string{
-		"invite_token": inviteToken,
+	var result *joinResult
+	if joinSSO {
+		result, err = joinWithSSO(serverURLArg)
+	} else {
+		result, err = joinWithInvite(serverURLArg)
 	}
-
-	jsonData, _ := json.Marshal(reqBody)
-	req, err := http.NewRequest("POST", serverURLArg+"/api/join", bytes.NewReader(jsonData))
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	// Keep the SSH private key in the credential store
+	clientDir, err := getClientDir()
 	if err != nil {
-		return fmt.Errorf("failed to connect to server: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var errResp teamserver.ErrorResponse
-		_ = json.NewDecoder(resp.Body).Decode(&errResp)
-		return fmt.Errorf("join failed: %s", errResp.Error)
-	}
-
-	var result struct {
-		SessionToken string     `json:"session_token"`
-		PrivateKey   string     `json:"private_key"`
-		Certificate  string     `json:"certificate,omitempty"`
-		ValidUntil   *time.Time `json:"valid_until,omitempty"`
-		Principals   []string   `json:"principals,omitempty"`
-		CAEnabled    bool       `json:"ca_enabled"`
-		CAPublicKey  string     `json:"ca_public_key,omitempty"`
-		ServerHost   string     `json:"server_host"`
-		User         struct {
-			Name string `json:"name"`
-			Role string `json:"role"`
-		} `json:"user"`
-		Environments []struct {
-			Name       string `json:"name"`
-			Project    string `json:"project"`
-			Host       string `json:"host"`
-			Port       int    `json:"port"`
-			DeployUser string `json:"deploy_user"`
-		} `json:"environments"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
+		return err
 	}
-
-	// Save SSH private key
-	homeDir, _ := os.UserHomeDir()
-	keysDir := filepath.Join(homeDir, ".magebox", "keys")
+	keysDir := filepath.Join(clientDir, "keys")
 	if err := os.MkdirAll(keysDir, 0700); err != nil {
 		return fmt.Errorf("failed to create keys directory: %w", err)
 	}
@@ -750,7 +940,7 @@ func runServerJoin(cmd *cobra.Command, args []string) error {
 	keyFileName := fmt.Sprintf("%s_%s.key", safeHost, result.User.Name)
 	keyPath := filepath.Join(keysDir, keyFileName)
 
-	if err := os.WriteFile(keyPath, []byte(result.PrivateKey), 0600); err != nil {
+	if err := saveTeamKey(keyPath, result.PrivateKey); err != nil {
 		return fmt.Errorf("failed to save SSH private key: %w", err)
 	}
 
@@ -786,17 +976,31 @@ func runServerJoin(cmd *cobra.Command, args []string) error {
 		KeyFile:      keyPath,
 		CAEnabled:    result.CAEnabled,
 		Environments: envConfigs,
+
+		SessionExpiresAt: result.SessionExpiresAt,
+		RefreshToken:     result.RefreshToken,
 	}
 
 	if err := saveClientConfig(config); err != nil {
 		cli.PrintWarning("Failed to save session: %v", err)
 	}
+	if err := writeTeamSSHConfig(config); err != nil {
+		cli.PrintWarning("Failed to configure SSH: %v", err)
+	}
+	agentErr := addTeamKeyToAgent(config)
 
 	cli.PrintSuccess("Successfully joined team server!")
 	fmt.Println()
 	cli.PrintInfo("User: %s", result.User.Name)
 	cli.PrintInfo("Role: %s", result.User.Role)
-	cli.PrintInfo("SSH Key: %s", keyPath)
+	cli.PrintInfo("SSH Key: %s.pub", keyPath)
+	if store, err := clientCredentials(); err == nil {
+		cli.PrintInfo("Session and SSH private key: %s", store.Name())
+	}
+	if agentErr != nil {
+		cli.PrintWarning("SSH key not added to ssh-agent: %v", agentErr)
+		cli.PrintInfo("Connect with 'magebox ssh <environment>', which loads the key itself")
+	}
 
 	// Show certificate info if CA is enabled
 	if result.CAEnabled && certPath != "" {
@@ -815,10 +1019,10 @@ func runServerJoin(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 		cli.PrintInfo("Accessible environments:")
 		for _, env := range result.Environments {
-			fmt.Printf("  - %s (%s@%s)\n", env.Name, env.DeployUser, env.Host)
+			fmt.Printf("  - %s (ssh %s)\n", env.Name, teamSSHAlias(env.Name))
 		}
 		fmt.Println()
-		cli.PrintInfo("Connect with: magebox ssh <environment-name>")
+		cli.PrintInfo("Connect with: magebox team ssh <project>/<env>")
 	}
 
 	fmt.Println()
@@ -827,8 +1031,144 @@ func runServerJoin(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// joinWithInvite joins with an invite token
+func joinWithInvite(serverURL string) (*joinResult, error) {
+	// Make join request (server will generate SSH key pair)
+	reqBody := map[string]string{
+		"invite_token": inviteToken,
+	}
+
+	jsonData, _ := json.Marshal(reqBody)
+	req, err := http.NewRequest("POST", serverURL+"/api/join", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return nil, fmt.Errorf("join failed: %s", errResp.Error)
+	}
+
+	var result joinResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &result, nil
+}
+
+// joinWithSSO signs in through the server's identity provider: it opens the
+// sign-in page in a browser, which the server redirects to a loopback
+// listener of the CLI with the completion code, and polls the server until
+// the sign-in completes
+func joinWithSSO(serverURL string) (*joinResult, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the sign-in callback: %w", err)
+	}
+	defer listener.Close()
+
+	codes := make(chan string, 1)
+	go func() {
+		_ = http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			code := r.URL.Query().Get("code")
+			if r.URL.Path != "/callback" || code == "" {
+				http.NotFound(w, r)
+				return
+			}
+			select {
+			case codes <- code:
+			default:
+			}
+			teamserver.WriteOIDCPage(w, http.StatusOK, "Signed in", "You can close this window and return to your terminal.")
+		}))
+	}()
+
+	loginBody, _ := json.Marshal(teamserver.OIDCLoginRequest{
+		ClientCallback: fmt.Sprintf("http://%s/callback", listener.Addr()),
+	})
+	resp, err := client.Post(serverURL+"/api/oidc/login", "application/json", bytes.NewReader(loginBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("this team server doesn't have SSO enabled, join with an invite token instead")
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return nil, fmt.Errorf("SSO login failed: %s", errResp.Error)
+	}
+
+	var login teamserver.OIDCLoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	cli.PrintInfo("Sign in with your browser on this machine. If it doesn't open, visit:")
+	fmt.Printf("  %s\n", login.AuthURL)
+	fmt.Println()
+
+	var browser *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		browser = exec.Command("open", login.AuthURL)
+	default:
+		browser = exec.Command("xdg-open", login.AuthURL)
+	}
+	_ = browser.Start()
+
+	var completionCode string
+	deadline := time.Now().Add(time.Duration(login.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case completionCode = <-codes:
+		case <-time.After(2 * time.Second):
+		}
+
+		pollBody, _ := json.Marshal(teamserver.OIDCPollRequest{LoginID: login.LoginID, PollToken: login.PollToken, CompletionCode: completionCode})
+		resp, err := client.Post(serverURL+"/api/oidc/poll", "application/json", bytes.NewReader(pollBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to server: %w", err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusAccepted:
+			resp.Body.Close()
+			continue
+		case http.StatusOK:
+			var result joinResult
+			err := json.NewDecoder(resp.Body).Decode(&result)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse response: %w", err)
+			}
+			return &result, nil
+		default:
+			var errResp teamserver.ErrorResponse
+			_ = json.NewDecoder(resp.Body).Decode(&errResp)
+			resp.Body.Close()
+			return nil, fmt.Errorf("SSO login failed: %s", errResp.Error)
+		}
+	}
+
+	return nil, fmt.Errorf("SSO login timed out, run the command again")
+}
+
 func runServerWhoami(cmd *cobra.Command, args []string) error {
-	config, err := loadClientConfig()
+	config, err := loadClientSession()
 	if err != nil {
 		return err
 	}
@@ -881,6 +1221,9 @@ func runServerWhoami(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Printf("  Status:  %s\n", cli.Success("Active (no expiry)"))
 	}
+	if config.SessionExpiresAt != nil {
+		fmt.Printf("  Session: valid until %s\n", config.SessionExpiresAt.Local().Format("2006-01-02 15:04"))
+	}
 
 	// Get accessible environments
 	envResp, err := apiRequest("GET", "/api/environments", nil, config.SessionToken)
@@ -17,8 +17,10 @@ import (
 
 var serviceCmd = &cobra.Command{
 	Use:   "service",
-	Short: "Manage MageBox autostart service",
-	Long:  "Install or remove a system service that automatically starts MageBox on login",
+	Short: "Manage project services and the MageBox autostart service",
+	Long: `Add, remove and list the services of the current project (add, remove,
+list), or install or remove a system service that automatically starts
+MageBox on login (install, uninstall, status)`,
 }
 
 var serviceInstallCmd = &cobra.Command{
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/project"
+)
+
+var serviceAddCmd = &cobra.Command{
+	Use:   "add <service> [version]",
+	Short: "Add a service to the project",
+	Long: `Adds a service to the current project's .magebox configuration, or changes
+the version and memory of one it already has.
+
+The version is checked against the versions MageBox supports; without one the
+default version of the service is added. See 'magebox service list --available'
+for the services and their versions.
+
+Example:
+  magebox service add opensearch 2.19 --memory 2g
+  magebox service add rabbitmq
+  magebox service add mariadb 10.11 --apply`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runServiceAdd,
+}
+
+var serviceRemoveCmd = &cobra.Command{
+	Use:   "remove <service>",
+	Short: "Remove a service from the project",
+	Long: `Removes a service from the current project's .magebox configuration.
+Removing mailpit turns it off, as it runs unless disabled. The data of removed
+databases and search engines stays in their Docker volumes.
+
+Example:
+  magebox service remove rabbitmq
+  magebox service remove varnish --apply`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServiceRemove,
+}
+
+var serviceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List project services",
+	Long: `Lists the services configured for the current project, or with --available
+the services that can be added and their supported versions.`,
+	Args: cobra.NoArgs,
+	RunE: runServiceList,
+}
+
+var (
+	serviceMemory    string
+	serviceApply     bool
+	serviceAvailable bool
+)
+
+func init() {
+	serviceAddCmd.Flags().StringVar(&serviceMemory, "memory", "", "Memory of the service, e.g. 512m or 2g")
+	serviceAddCmd.Flags().BoolVar(&serviceApply, "apply", false, "Apply the change to the running project")
+	serviceRemoveCmd.Flags().BoolVar(&serviceApply, "apply", false, "Apply the change to the running project")
+	serviceListCmd.Flags().BoolVar(&serviceAvailable, "available", false, "List the services that can be added and their versions")
+
+	serviceCmd.AddCommand(serviceAddCmd)
+	serviceCmd.AddCommand(serviceRemoveCmd)
+	serviceCmd.AddCommand(serviceListCmd)
+}
+
+func runServiceAdd(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		cli.PrintError("Failed to load config: %v", err)
+		return nil
+	}
+
+	name := args[0]
+	info, ok := config.LookupService(name)
+	if !ok {
+		cli.PrintError("Unknown service %s", name)
+		cli.PrintInfo("Run %s for the services that can be added", cli.Command("magebox service list --available"))
+		return nil
+	}
+
+	var requested string
+	if len(args) > 1 {
+		requested = args[1]
+	}
+	version, err := info.ResolveVersion(requested)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	if serviceMemory != "" {
+		if err := config.ValidateMemory(serviceMemory); err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+	}
+
+	// Changing a service keeps its other settings, e.g. credentials
+	svc := &config.ServiceConfig{}
+	existing := cfg.Services.Get(name)
+	if existing != nil && existing.Enabled {
+		copied := *existing
+		svc = &copied
+		if requested == "" && existing.Version != "" {
+			version = existing.Version
+		}
+	}
+	svc.Version = version
+	if serviceMemory != "" {
+		svc.Memory = serviceMemory
+	}
+
+	if err := cfg.Services.Add(name, svc); err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	recorder.Track(filepath.Join(cwd, config.ConfigFileName))
+	if err := config.SaveToPath(cfg, cwd); err != nil {
+		cli.PrintError("Failed to save config: %v", err)
+		return nil
+	}
+
+	label := name
+	if version != "" {
+		label += " " + version
+	}
+	switch {
+	case existing == nil || !existing.Enabled:
+		cli.PrintSuccess("Added service: %s", label)
+	case existing.Version != version && isDatabaseService(name):
+		cli.PrintSuccess("Changed service: %s (was %s)", label, existing.Version)
+		cli.PrintWarning("%s %s keeps its data in a volume of its own, import the database again", name, version)
+	case existing.Version != version:
+		cli.PrintSuccess("Changed service: %s (was %s)", label, existing.Version)
+	default:
+		cli.PrintSuccess("Updated service: %s", label)
+	}
+
+	return applyServiceChange(cwd)
+}
+
+func runServiceRemove(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		cli.PrintError("Failed to load config: %v", err)
+		return nil
+	}
+
+	name := args[0]
+	if _, ok := config.LookupService(name); !ok {
+		cli.PrintError("Unknown service %s", name)
+		return nil
+	}
+	if !cfg.Services.Remove(name) {
+		cli.PrintError("Service %s is not configured", name)
+		return nil
+	}
+
+	recorder.Track(filepath.Join(cwd, config.ConfigFileName))
+	if err := config.SaveToPath(cfg, cwd); err != nil {
+		cli.PrintError("Failed to save config: %v", err)
+		return nil
+	}
+
+	cli.PrintSuccess("Removed service: %s", name)
+
+	return applyServiceChange(cwd)
+}
+
+// isDatabaseService reports whether a service is one of the databases
+func isDatabaseService(name string) bool {
+	_, ok := config.DatabaseVersions[name]
+	return ok
+}
+
+// applyServiceChange applies a saved service change with --apply, or tells
+// how to
+func applyServiceChange(cwd string) error {
+	if !serviceApply {
+		cli.PrintInfo("Run %s to apply the change", cli.Command("magebox apply"))
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+	if !projectRunning(p, cwd) {
+		cli.PrintInfo("The project isn't running, the change applies on %s", cli.Command("magebox start"))
+		return nil
+	}
+
+	fmt.Println()
+	mgr := project.NewManager(p)
+	mgr.SetLowMemory(lowMemoryDefault(p))
+	applyProjectChanges(mgr, cwd)
+	return nil
+}
+
+// serviceOutput is a service of `magebox service list` in JSON and YAML
+type serviceOutput struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Memory  string `json:"memory,omitempty"`
+	Port    int    `json:"port,omitempty"`
+}
+
+func runServiceList(cmd *cobra.Command, args []string) error {
+	if serviceAvailable {
+		return listAvailableServices()
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadFromPath(cwd)
+	if err != nil {
+		if structuredOutput() {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		cli.PrintError("Failed to load config: %v", err)
+		return nil
+	}
+
+	names := cfg.Services.Enabled()
+	services := make([]serviceOutput, 0, len(names))
+	for _, name := range names {
+		svc := cfg.Services.Get(name)
+		services = append(services, serviceOutput{Name: name, Version: svc.Version, Memory: svc.Memory, Port: svc.Port})
+	}
+	if structuredOutput() {
+		return printStructured(services)
+	}
+
+	cli.PrintTitle("Project Services: %s", cfg.Name)
+	fmt.Println()
+
+	if len(services) == 0 {
+		cli.PrintInfo("No services configured")
+		return nil
+	}
+	for _, svc := range services {
+		details := svc.Version
+		if svc.Memory != "" {
+			details = strings.TrimSpace(details + " (memory " + svc.Memory + ")")
+		}
+		fmt.Printf("  %-24s %s\n", cli.Highlight(svc.Name), details)
+	}
+	if cfg.Services.Mailpit == nil {
+		fmt.Printf("  %-24s %s\n", cli.Highlight("mailpit"), cli.Dim+"(default)"+cli.Reset)
+	}
+
+	return nil
+}
+
+// listAvailableServices prints the service catalog
+func listAvailableServices() error {
+	if structuredOutput() {
+		return printStructured(config.ServiceCatalog)
+	}
+
+	cli.PrintTitle("Available Services")
+	fmt.Println()
+
+	for _, info := range config.ServiceCatalog {
+		fmt.Printf("  %-24s %s\n", cli.Highlight(info.Name), info.Description)
+		switch {
+		case len(info.Versions) > 0:
+			fmt.Printf("  %-24s versions: %s (default %s)\n", "", strings.Join(info.Versions, ", "), info.DefaultVersion)
+		case info.Versioned():
+			fmt.Printf("  %-24s any image tag (default %s)\n", "", info.DefaultVersion)
+		}
+	}
+
+	return nil
+}
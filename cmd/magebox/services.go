@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/docker"
+)
+
+var servicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "Manage pinned service images",
+	Long: `Manage the image digests recorded in .magebox.lock.
+
+MageBox pins every Docker service image of the project to the digest stored in
+.magebox.lock, so mutable tags like opensearch:2 cannot silently change between
+teammates. Digests only change when you run 'magebox services update'.`,
+}
+
+var servicesUpdateCmd = &cobra.Command{
+	Use:   "update [service...]",
+	Short: "Pull service images and record their digests",
+	Long: `Pulls the images used by the project's services and records their digests in
+.magebox.lock. Pass compose service names (e.g. mysql80, redis) to update only
+those services.
+
+Commit .magebox.lock so the whole team runs the same images.`,
+	RunE: runServicesUpdate,
+}
+
+var servicesStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Compare locked digests with upstream tags",
+	Long:  "Shows the locked digest of each service image and warns when the upstream tag has moved",
+	RunE:  runServicesStatus,
+}
+
+func init() {
+	servicesCmd.AddCommand(servicesUpdateCmd)
+	servicesCmd.AddCommand(servicesStatusCmd)
+	rootCmd.AddCommand(servicesCmd)
+}
+
+func runServicesUpdate(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	lock, err := docker.LoadImageLock(cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	images := docker.NewComposeGenerator(p).ProjectImages(cfg)
+	selected := make(map[string]bool, len(args))
+	for _, name := range args {
+		if _, ok := images[name]; !ok {
+			cli.PrintError("Unknown service %q for this project", name)
+			return nil
+		}
+		selected[name] = true
+	}
+
+	cli.PrintTitle("Updating Service Images")
+	fmt.Println()
+
+	failed := 0
+	for _, name := range sortedKeys(images) {
+		if len(selected) > 0 && !selected[name] {
+			continue
+		}
+		image := images[name]
+		fmt.Printf("  %-20s %s... ", name, image)
+		digest, err := docker.PullImageDigest(image)
+		if err != nil {
+			fmt.Println(cli.Error("failed"))
+			cli.PrintWarning("    %v", err)
+			failed++
+			continue
+		}
+		previous := lock.Images[image].Digest
+		lock.Set(image, digest)
+		if previous != "" && previous != digest {
+			fmt.Printf("%s %s → %s\n", cli.Success("updated"), docker.ShortDigest(previous), docker.ShortDigest(digest))
+		} else {
+			fmt.Printf("%s %s\n", cli.Success("locked"), docker.ShortDigest(digest))
+		}
+	}
+
+	if err := lock.Save(cwd); err != nil {
+		cli.PrintError("Failed to write %s: %v", docker.LockFileName, err)
+		return nil
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		cli.PrintWarning("%d image(s) could not be updated", failed)
+	}
+	cli.PrintSuccess("Wrote %s", docker.LockFileName)
+	cli.PrintInfo("Run %s to apply the pinned images", cli.Command("magebox restart"))
+	return nil
+}
+
+func runServicesStatus(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	lock, err := docker.LoadImageLock(cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	cli.PrintTitle("Service Image Lock")
+	fmt.Println()
+
+	if len(lock.Images) == 0 {
+		cli.PrintInfo("No %s found", docker.LockFileName)
+		fmt.Println(cli.Bullet("Run " + cli.Command("magebox services update") + " to pin the current images"))
+		return nil
+	}
+
+	drifted := 0
+	for _, image := range lock.SortedImages() {
+		locked := lock.Images[image].Digest
+		upstream, err := docker.RemoteImageDigest(image)
+		switch {
+		case err != nil:
+			fmt.Printf("  %-45s %s %s\n", image, docker.ShortDigest(locked), cli.Subtitle("(upstream unknown)"))
+		case upstream != locked:
+			fmt.Printf("  %-45s %s %s\n", image, docker.ShortDigest(locked), cli.Warning("upstream moved to "+docker.ShortDigest(upstream)))
+			drifted++
+		default:
+			fmt.Printf("  %-45s %s %s\n", image, docker.ShortDigest(locked), cli.Success("up to date"))
+		}
+	}
+
+	if drifted > 0 {
+		fmt.Println()
+		cli.PrintWarning("%d upstream tag(s) moved since they were locked", drifted)
+		cli.PrintInfo("Run %s to adopt the new images", cli.Command("magebox services update"))
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a string map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
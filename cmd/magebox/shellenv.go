@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/config"
+)
+
+var shellenvShell string
+
+var shellenvCmd = &cobra.Command{
+	Use:   "shellenv",
+	Short: "Print project environment exports for direnv",
+	Long: `Prints shell exports that make the current shell project-aware: the
+php/composer wrappers first in PATH, database, Redis, search engine and
+RabbitMQ endpoints, and the env vars from .magebox.yaml.
+
+Add it to the project's .envrc so direnv loads it whenever you enter the
+directory, without running 'magebox shell':
+
+  echo 'eval "$(magebox shellenv)"' > .envrc
+  direnv allow
+
+Examples:
+  eval "$(magebox shellenv)"
+  magebox shellenv --shell fish | source`,
+	RunE: runShellenv,
+}
+
+func init() {
+	shellenvCmd.Flags().StringVar(&shellenvShell, "shell", "bash", "Output syntax: bash (also zsh/sh) or fish")
+	rootCmd.AddCommand(shellenvCmd)
+}
+
+// shellVar is a single exported environment variable
+type shellVar struct {
+	Name  string
+	Value string
+}
+
+func runShellenv(cmd *cobra.Command, args []string) error {
+	// The output is eval'd, so report a bad shell on stderr and exit non-zero
+	switch shellenvShell {
+	case "bash", "zsh", "sh", "fish":
+	default:
+		return fmt.Errorf("unsupported shell %q (use bash, zsh, sh or fish)", shellenvShell)
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	binDir := filepath.Join(p.MageBoxDir(), "bin")
+	vars := shellEnvVars(cfg, cwd)
+
+	if shellenvShell == "fish" {
+		fmt.Print(formatFishShellEnv(binDir, vars))
+	} else {
+		fmt.Print(formatPosixShellEnv(binDir, vars))
+	}
+	return nil
+}
+
+// shellEnvVars returns the project variables exported by shellenv.
+// Service endpoints come first, project env vars from .magebox.yaml last so
+// they can override them.
+func shellEnvVars(cfg *config.Config, projectPath string) []shellVar {
+	vars := []shellVar{
+		{"MAGEBOX_PROJECT", cfg.Name},
+		{"MAGEBOX_PROJECT_DIR", projectPath},
+		{"MAGEBOX_PHP_VERSION", cfg.PHP},
+	}
+
+	if db, err := getDbInfo(cfg); err == nil {
+		vars = append(vars,
+			shellVar{"DB_HOST", "127.0.0.1"},
+			shellVar{"DB_PORT", strconv.Itoa(db.Port)},
+			shellVar{"DB_NAME", cfg.DatabaseName()},
+			shellVar{"DB_USER", cfg.DatabaseUser()},
+			shellVar{"DB_PASSWORD", cfg.DatabasePassword()},
+		)
+	}
+	if cfg.Services.HasCacheService() {
+		vars = append(vars,
+			shellVar{"REDIS_HOST", "127.0.0.1"},
+			shellVar{"REDIS_PORT", strconv.Itoa(servicePorts().CachePort())},
+		)
+		if user, password := cfg.RedisCredentials(); password != "" {
+			vars = append(vars,
+				shellVar{"REDIS_USER", user},
+				shellVar{"REDIS_PASSWORD", password},
+			)
+		}
+	}
+	if cfg.Services.HasOpenSearch() {
+		vars = append(vars,
+			shellVar{"OPENSEARCH_HOST", "127.0.0.1"},
+			shellVar{"OPENSEARCH_PORT", strconv.Itoa(servicePorts().OpenSearchPort(cfg.Services.OpenSearch.Version))},
+		)
+	}
+	if cfg.Services.HasElasticsearch() {
+		vars = append(vars,
+			shellVar{"ELASTICSEARCH_HOST", "127.0.0.1"},
+			shellVar{"ELASTICSEARCH_PORT", strconv.Itoa(servicePorts().ElasticsearchPort(cfg.Services.Elasticsearch.Version))},
+		)
+	}
+	searchEnv := cfg.SearchEngineEnv()
+	for _, k := range sortedKeys(searchEnv) {
+		vars = append(vars, shellVar{k, searchEnv[k]})
+	}
+	if cfg.Services.HasRabbitMQ() {
+		vars = append(vars,
+			shellVar{"RABBITMQ_HOST", "127.0.0.1"},
+			shellVar{"RABBITMQ_PORT", strconv.Itoa(servicePorts().RabbitMQPort())},
+		)
+		user, password := cfg.RabbitMQCredentials()
+		vars = append(vars,
+			shellVar{"RABBITMQ_USER", user},
+			shellVar{"RABBITMQ_PASSWORD", password},
+		)
+	}
+
+	for _, k := range sortedKeys(cfg.Env) {
+		vars = append(vars, shellVar{k, cfg.Env[k]})
+	}
+
+	return vars
+}
+
+// formatPosixShellEnv renders exports for bash, zsh and sh
+func formatPosixShellEnv(binDir string, vars []shellVar) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "export PATH=%s:\"$PATH\"\n", posixQuote(binDir))
+	for _, v := range vars {
+		fmt.Fprintf(&b, "export %s=%s\n", v.Name, posixQuote(v.Value))
+	}
+	return b.String()
+}
+
+// formatFishShellEnv renders exports for fish
+func formatFishShellEnv(binDir string, vars []shellVar) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "set -gx PATH %s $PATH\n", posixQuote(binDir))
+	for _, v := range vars {
+		fmt.Fprintf(&b, "set -gx %s %s\n", v.Name, posixQuote(v.Value))
+	}
+	return b.String()
+}
+
+// posixQuote single-quotes a value so the shell takes it literally
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestShellEnvVars(t *testing.T) {
+	cfg := &config.Config{
+		Name: "mystore",
+		PHP:  "8.3",
+		Services: config.Services{
+			MySQL:      &config.ServiceConfig{Enabled: true, Version: "8.0"},
+			Redis:      &config.ServiceConfig{Enabled: true},
+			OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+			Typesense:  &config.ServiceConfig{Enabled: true},
+		},
+		Env: map[string]string{"MAGE_MODE": "developer"},
+	}
+
+	vars := shellEnvVars(cfg, "/work/mystore")
+	got := make(map[string]string, len(vars))
+	for _, v := range vars {
+		got[v.Name] = v.Value
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"MAGEBOX_PROJECT", "mystore"},
+		{"MAGEBOX_PROJECT_DIR", "/work/mystore"},
+		{"MAGEBOX_PHP_VERSION", "8.3"},
+		{"DB_PORT", "33080"},
+		{"DB_NAME", "mystore"},
+		{"REDIS_PORT", "6379"},
+		{"OPENSEARCH_PORT", "9259"},
+		{"TYPESENSE_PORT", "8108"},
+		{"TYPESENSE_API_KEY", "magebox"},
+		{"MAGE_MODE", "developer"},
+	}
+	for _, tt := range tests {
+		if got[tt.name] != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got[tt.name], tt.want)
+		}
+	}
+
+	if _, ok := got["MEILISEARCH_HOST"]; ok {
+		t.Error("MEILISEARCH_HOST should not be exported when Meilisearch is disabled")
+	}
+	if _, ok := got["RABBITMQ_HOST"]; ok {
+		t.Error("RABBITMQ_HOST should not be exported when RabbitMQ is disabled")
+	}
+	if last := vars[len(vars)-1]; last.Name != "MAGE_MODE" {
+		t.Errorf("project env vars should come last so they can override endpoints, got %s", last.Name)
+	}
+}
+
+func TestFormatPosixShellEnv(t *testing.T) {
+	out := formatPosixShellEnv("/home/me/.magebox/bin", []shellVar{{"GREETING", "it's fine"}})
+
+	if !strings.HasPrefix(out, "export PATH='/home/me/.magebox/bin':\"$PATH\"\n") {
+		t.Errorf("PATH export missing or wrong:\n%s", out)
+	}
+	if !strings.Contains(out, `export GREETING='it'\''s fine'`) {
+		t.Errorf("value not quoted safely:\n%s", out)
+	}
+}
+
+func TestRunShellenv_UnsupportedShell(t *testing.T) {
+	orig := shellenvShell
+	shellenvShell = "powershell"
+	defer func() { shellenvShell = orig }()
+
+	err := runShellenv(shellenvCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported shell") {
+		t.Errorf("runShellenv() error = %v, want unsupported shell error", err)
+	}
+}
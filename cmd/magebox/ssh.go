@@ -9,13 +9,10 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"qoliber/magebox/internal/cli"
@@ -46,26 +43,13 @@ func runSSH(cmd *cobra.Command, args []string) error {
 	envName := args[0]
 
 	// Load client config
-	config, err := loadTeamServerConfig()
+	config, err := loadClientConfig()
 	if err != nil {
 		return err
 	}
 
 	// Find the environment
-	var targetEnv *clientEnvironmentConfig
-	for i, env := range config.Environments {
-		if env.Name == envName {
-			targetEnv = &config.Environments[i]
-			break
-		}
-		// Also try matching just the environment name without project prefix
-		parts := strings.Split(env.Name, "/")
-		if len(parts) == 2 && parts[1] == envName {
-			targetEnv = &config.Environments[i]
-			break
-		}
-	}
-
+	targetEnv := findClientEnvironment(config, envName, "")
 	if targetEnv == nil {
 		// List available environments
 		cli.PrintError("Environment '%s' not found", envName)
@@ -81,17 +65,10 @@ func runSSH(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("environment not found")
 	}
 
-	// Check SSH key exists
-	keyPath := config.KeyPath
-	if keyPath == "" {
-		keyPath = config.KeyFile
-	}
+	keyPath := config.privateKeyPath()
 	if keyPath == "" {
 		return fmt.Errorf("no SSH key configured. Rejoin the team server with: magebox server join")
 	}
-	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-		return fmt.Errorf("SSH key not found at %s. Rejoin the team server with: magebox server join", keyPath)
-	}
 
 	// Check for certificate if CA is enabled
 	certFile := keyPath + "-cert.pub"
@@ -128,13 +105,6 @@ func runSSH(cmd *cobra.Command, args []string) error {
 		port = 22
 	}
 
-	sshArgs := []string{
-		"-i", keyPath,
-		"-p", strconv.Itoa(port),
-		"-o", "StrictHostKeyChecking=accept-new",
-		fmt.Sprintf("%s@%s", targetEnv.DeployUser, targetEnv.Host),
-	}
-
 	// Show connection info
 	if config.CAEnabled && hasCert && !certExpired {
 		cli.PrintInfo("Connecting to %s using certificate (%s@%s:%d)...", targetEnv.Name, targetEnv.DeployUser, targetEnv.Host, port)
@@ -147,34 +117,17 @@ func runSSH(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("ssh not found in PATH: %w", err)
 	}
 
-	sshExec := exec.Command(sshPath, sshArgs...)
-	sshExec.Stdin = os.Stdin
-	sshExec.Stdout = os.Stdout
-	sshExec.Stderr = os.Stderr
-
-	return sshExec.Run()
-}
-
-// loadTeamServerConfig loads the team server client config
-func loadTeamServerConfig() (*clientConfig, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
-
-	path := filepath.Join(homeDir, ".magebox", "teamserver", "client.json")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("not connected to a team server. Use 'magebox server join' first")
-		}
-		return nil, err
-	}
-
-	var config clientConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, err
-	}
-
-	return &config, nil
+	// The private key is written only for the length of the session
+	return withTeamKeyFile(config, func(keyFile string) error {
+		sshExec := exec.Command(sshPath,
+			"-i", keyFile,
+			"-p", strconv.Itoa(port),
+			"-o", "StrictHostKeyChecking=accept-new",
+			fmt.Sprintf("%s@%s", targetEnv.DeployUser, targetEnv.Host),
+		)
+		sshExec.Stdin = os.Stdin
+		sshExec.Stdout = os.Stdout
+		sshExec.Stderr = os.Stderr
+		return sshExec.Run()
+	})
 }
@@ -2,12 +2,23 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/nginx"
 	"qoliber/magebox/internal/ssl"
 )
 
+var (
+	sslRenewAll   bool
+	sslRenewForce bool
+)
+
 var sslCmd = &cobra.Command{
 	Use:   "ssl",
 	Short: "SSL certificate management",
@@ -28,9 +39,40 @@ var sslGenerateCmd = &cobra.Command{
 	RunE:  runSslGenerate,
 }
 
+var sslStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show certificate expiry dates",
+	Long: `Lists the certificates in ~/.magebox/certs with the hosts they cover and
+when they expire. mkcert certificates are valid for about two years; the ones
+expiring within 30 days are flagged and 'magebox ssl renew' replaces them.`,
+	Args: cobra.NoArgs,
+	RunE: runSslStatus,
+}
+
+var sslRenewCmd = &cobra.Command{
+	Use:   "renew [domain...]",
+	Short: "Renew expiring certificates",
+	Long: `Regenerates the certificates of the project that expire within 30 days, for
+the same hosts, and reloads nginx. 'magebox start' renews them as well.
+
+Named domains are renewed whether they expire or not.
+
+Examples:
+  magebox ssl renew                  # Expiring certificates of the project
+  magebox ssl renew --all            # Expiring certificates of all projects
+  magebox ssl renew --force          # All certificates of the project
+  magebox ssl renew mystore.test`,
+	RunE: runSslRenew,
+}
+
 func init() {
+	sslRenewCmd.Flags().BoolVar(&sslRenewAll, "all", false, "Renew the certificates of all projects")
+	sslRenewCmd.Flags().BoolVar(&sslRenewForce, "force", false, "Renew certificates that don't expire yet")
+
 	sslCmd.AddCommand(sslTrustCmd)
 	sslCmd.AddCommand(sslGenerateCmd)
+	sslCmd.AddCommand(sslStatusCmd)
+	sslCmd.AddCommand(sslRenewCmd)
 	rootCmd.AddCommand(sslCmd)
 }
 
@@ -94,7 +136,7 @@ func runSslGenerate(cmd *cobra.Command, args []string) error {
 
 	for _, domain := range cfg.Domains {
 		if domain.IsSSLEnabled() {
-			baseDomain := ssl.ExtractBaseDomain(domain.Host)
+			baseDomain := ssl.ExtractBaseDomainForTLD(domain.Host, cfg.TLD)
 			fmt.Printf("  %s... ", baseDomain)
 			cert, err := sslMgr.GenerateCert(baseDomain)
 			if err != nil {
@@ -110,3 +152,210 @@ func runSslGenerate(cmd *cobra.Command, args []string) error {
 	fmt.Println("\nSSL certificates generated!")
 	return nil
 }
+
+// sslCertStatus is a certificate as shown by ssl status
+type sslCertStatus struct {
+	Domain   string   `json:"domain" yaml:"domain"`
+	Hosts    []string `json:"hosts" yaml:"hosts"`
+	Expires  string   `json:"expires,omitempty" yaml:"expires,omitempty"`
+	DaysLeft int      `json:"days_left" yaml:"days_left"`
+	Status   string   `json:"status" yaml:"status"` // valid, expiring, expired, untrusted or invalid
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// sslCertStatuses reads the certificates in the certs directory
+func sslCertStatuses(sslMgr *ssl.Manager) ([]sslCertStatus, error) {
+	domains, err := sslMgr.ListCerts()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	checkCA := sslMgr.IsMkcertInstalled()
+	statuses := make([]sslCertStatus, 0, len(domains))
+	for _, domain := range domains {
+		info, err := sslMgr.CertInfo(domain)
+		if err != nil {
+			statuses = append(statuses, sslCertStatus{Domain: domain, Hosts: []string{}, Status: "invalid", Error: err.Error()})
+			continue
+		}
+
+		status := sslCertStatus{
+			Domain:   domain,
+			Hosts:    info.Hosts,
+			Expires:  info.NotAfter.Format("2006-01-02"),
+			DaysLeft: info.DaysLeft(now),
+			Status:   "valid",
+		}
+		switch {
+		case info.Expired(now):
+			status.Status = "expired"
+		case info.Expiring(now):
+			status.Status = "expiring"
+		case checkCA:
+			if certPEM, err := os.ReadFile(info.CertFile); err == nil && !sslMgr.IssuedByLocalCA(certPEM) {
+				status.Status = "untrusted"
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func runSslStatus(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	sslMgr := ssl.NewManager(p)
+	statuses, err := sslCertStatuses(sslMgr)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printStructured(statuses)
+	}
+
+	cli.PrintTitle("SSL Certificates")
+	fmt.Println()
+
+	if len(statuses) == 0 {
+		cli.PrintInfo("No certificates in %s", sslMgr.CertsDir())
+		return nil
+	}
+
+	renew := false
+	for _, s := range statuses {
+		var state string
+		switch s.Status {
+		case "invalid":
+			state = cli.Error(s.Error)
+		case "expired":
+			state = cli.Error(fmt.Sprintf("expired %d days ago", -s.DaysLeft))
+			renew = true
+		case "expiring":
+			state = cli.Warning(fmt.Sprintf("expires in %d days", s.DaysLeft))
+			renew = true
+		case "untrusted":
+			state = cli.Warning("not issued by the local CA")
+		default:
+			state = cli.Success(fmt.Sprintf("%d days left", s.DaysLeft))
+		}
+
+		fmt.Printf("  %-30s %-12s %s\n", s.Domain, s.Expires, state)
+		if len(s.Hosts) > 0 {
+			fmt.Printf("    %s\n", cli.Subtitle(strings.Join(s.Hosts, ", ")))
+		}
+	}
+
+	if renew {
+		fmt.Println()
+		cli.PrintInfo("Run %s to renew expiring certificates", cli.Command("magebox ssl renew --all"))
+	}
+	return nil
+}
+
+func runSslRenew(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	sslMgr := ssl.NewManager(p)
+	if !sslMgr.IsMkcertInstalled() {
+		cli.PrintError("mkcert is not installed, install it with: %s", p.MkcertInstallCommand())
+		return nil
+	}
+
+	// Named domains are renewed regardless of their expiry
+	force := sslRenewForce || len(args) > 0
+	var domains []string
+	switch {
+	case len(args) > 0:
+		globalCfg, err := config.LoadGlobalConfig(p.HomeDir)
+		if err != nil {
+			return err
+		}
+		for _, arg := range args {
+			domains = append(domains, ssl.ExtractBaseDomainForTLDs(arg, globalCfg.GetTLDs()))
+		}
+	case sslRenewAll:
+		if domains, err = sslMgr.ListCerts(); err != nil {
+			return err
+		}
+	default:
+		cwd, err := getCwd()
+		if err != nil {
+			return err
+		}
+		cfg, ok := loadProjectConfig(cwd)
+		if !ok {
+			return nil
+		}
+		seen := make(map[string]bool)
+		for _, d := range cfg.Domains {
+			if base := ssl.ExtractBaseDomainForTLD(d.Host, cfg.TLD); d.IsSSLEnabled() && !seen[base] {
+				seen[base] = true
+				domains = append(domains, base)
+			}
+		}
+	}
+
+	if len(domains) == 0 {
+		cli.PrintInfo("No certificates to renew")
+		return nil
+	}
+
+	cli.PrintTitle("Renewing SSL Certificates")
+	now := time.Now()
+	renewed := 0
+	for _, domain := range domains {
+		info, err := sslMgr.CertInfo(domain)
+		switch {
+		case os.IsNotExist(err):
+			fmt.Printf("  %-30s %s\n", domain, cli.Warning("no certificate, 'magebox start' generates it"))
+			continue
+		case err != nil:
+			fmt.Printf("  %-30s %s\n", domain, cli.Error(err.Error()))
+			continue
+		case !force && !info.Expiring(now):
+			fmt.Printf("  %-30s %s\n", domain, cli.Subtitle(fmt.Sprintf("%d days left, skipped", info.DaysLeft(now))))
+			continue
+		}
+
+		if _, err := sslMgr.RenewCert(domain); err != nil {
+			fmt.Printf("  %-30s %s\n", domain, cli.Error(err.Error()))
+			continue
+		}
+		renewed++
+		message := "renewed"
+		if info, err := sslMgr.CertInfo(domain); err == nil {
+			message += ", valid until " + info.NotAfter.Format("2006-01-02")
+		}
+		fmt.Printf("  %-30s %s\n", domain, cli.Success(message))
+	}
+	fmt.Println()
+
+	if renewed == 0 {
+		return nil
+	}
+
+	ngxController := nginx.NewController(p)
+	if !ngxController.IsRunning() {
+		cli.PrintSuccess("Renewed %d certificates", renewed)
+		return nil
+	}
+	fmt.Println("Reloading nginx...")
+	if err := ngxController.Test(); err != nil {
+		cli.PrintError("Nginx config test failed: %v", err)
+		return nil
+	}
+	if err := ngxController.Reload(); err != nil {
+		cli.PrintWarning("Failed to reload nginx: %v", err)
+		return nil
+	}
+	cli.PrintSuccess("Renewed %d certificates and reloaded nginx", renewed)
+	return nil
+}
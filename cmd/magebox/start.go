@@ -7,26 +7,78 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/portforward"
 	"qoliber/magebox/internal/project"
 )
 
-var startAllProjects bool
+var (
+	startAllProjects bool
+	startProjectName string
+	startOnly        []string
+	startLowMemory   bool
+	startNoWait      bool
+	startEnvPHP      bool
+	startStrict      bool
+)
 
 var startCmd = &cobra.Command{
-	Use:   "start",
+	Use:   "start [service|component...]",
 	Short: "Start project services",
-	Long:  "Starts all services defined in .magebox for the current project, or all projects with --all",
-	RunE:  runStart,
+	Long: `Starts all services defined in .magebox for the current project, the project
+named with --project from any directory, or all projects with --all.
+
+Pass service or component names to start only part of the project, e.g. to
+save memory. Components are "web" (PHP-FPM, Nginx, SSL, DNS) and "services"
+(all Docker services); services are mysql, mariadb, percona, redis, valkey,
+opensearch, elasticsearch, meilisearch, typesense, rabbitmq, varnish, mailpit
+and composer-mirror, or db, cache and search.
+
+--low-memory trims the stack for 8GB machines: PHP-FPM pools start a single
+worker, OpenSearch/Elasticsearch get a 512m heap, the database buffer pool
+shrinks to 128M, and RabbitMQ and Mailpit are skipped unless a module needs
+RabbitMQ or the project enables Mailpit. Make it the default with
+'magebox config set low_memory true'.
+
+Start waits until the database, cache, search engine and RabbitMQ accept
+connections (up to timeouts.ready in ~/.magebox/config.yaml, 2m by default).
+--no-wait returns as soon as the containers are up.
+
+--strict checks the configuration like 'magebox config validate' first and
+doesn't start when it finds a problem, such as a misspelled key.
+
+A full start runs the pre_start and post_start hooks of .magebox.yaml;
+--no-hooks skips them.
+
+Examples:
+  magebox start                      # Start everything
+  magebox start --project mystore    # Start another project from anywhere
+  magebox start mysql opensearch     # Start only MySQL and OpenSearch
+  magebox start --only web           # Start PHP-FPM and Nginx only
+  magebox start --only web,db        # Web plus the database
+  magebox start --low-memory         # Everything, with less memory
+  magebox start --no-wait            # Don't wait for services to be ready
+  magebox start --env-php            # Regenerate app/etc/env.php
+  magebox start --strict             # Refuse to start on config problems`,
+	RunE: runStart,
 }
 
 func init() {
 	startCmd.Flags().BoolVarP(&startAllProjects, "all", "a", false, "Start all MageBox projects")
+	startCmd.Flags().StringVar(&startProjectName, "project", "", "Start the named project instead of the current one")
+	startCmd.Flags().StringSliceVar(&startOnly, "only", nil, "Start only these services or components (comma-separated)")
+	startCmd.Flags().BoolVar(&startLowMemory, "low-memory", false, "Reduce memory use for machines with 8GB of RAM")
+	startCmd.Flags().BoolVar(&startNoWait, "no-wait", false, "Don't wait for services to accept connections")
+	startCmd.Flags().BoolVar(&startEnvPHP, "env-php", false, "Regenerate app/etc/env.php from the project config (backs up the existing one)")
+	startCmd.Flags().BoolVar(&startStrict, "strict", false, "Validate the configuration against the schema and don't start on problems")
+	startCmd.Flags().BoolVar(&skipHooks, "no-hooks", false, "Don't run the pre_start and post_start hooks")
 	rootCmd.AddCommand(startCmd)
 }
 
@@ -37,18 +89,35 @@ func runStart(cmd *cobra.Command, args []string) error {
 	}
 
 	mgr := project.NewManager(p)
+	mgr.SetLowMemory(startLowMemory || lowMemoryDefault(p))
+	if startNoWait {
+		mgr.SetReadyTimeout(0)
+	}
+	mgr.SetRegenerateEnvPHP(startEnvPHP)
 
+	targetNames := append(append([]string{}, args...), startOnly...)
 	if startAllProjects {
+		if len(targetNames) > 0 || startProjectName != "" {
+			cli.PrintError("Services, components and --project cannot be combined with --all")
+			return nil
+		}
 		return startAll(p, mgr)
 	}
+	mgr.SetProgress(events)
 
-	// Start current project
-	cwd, err := getCwd()
-	if err != nil {
-		return err
+	// Start the current or named project
+	projectDir, ok := resolveProjectDir(p, startProjectName)
+	if !ok {
+		return nil
 	}
 
-	return startProject(mgr, cwd, true)
+	return startProject(mgr, projectDir, targetNames, true)
+}
+
+// lowMemoryDefault reports whether the global config turns on low-memory mode
+func lowMemoryDefault(p *platform.Platform) bool {
+	globalCfg, err := config.LoadGlobalConfig(p.HomeDir)
+	return err == nil && globalCfg.LowMemory
 }
 
 func ensurePortForwarding() {
@@ -69,33 +138,65 @@ func ensurePortForwarding() {
 	}
 }
 
-func startProject(mgr *project.Manager, projectPath string, verbose bool) error {
-	if verbose {
-		cli.PrintTitle("Starting MageBox Services")
-		fmt.Println()
-
-		// On macOS, verify pf port forwarding is active (survives reboot/sleep)
-		ensurePortForwarding()
-	}
-
+// startProject starts the project at projectPath; targetNames limits the start
+// to the named services and components
+func startProject(mgr *project.Manager, projectPath string, targetNames []string, verbose bool) error {
 	// Validate first
+	if startStrict {
+		if err := checkConfigStrict(projectPath); err != nil {
+			return err
+		}
+	}
 	cfg, warnings, err := mgr.ValidateConfig(projectPath)
 	if err != nil {
 		cli.PrintError("%v", err)
 		return err
 	}
 
+	targets, err := project.ParseTargets(cfg, targetNames)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	if verbose {
+		if targets != nil {
+			cli.PrintTitle("Starting MageBox Services (%s)", targets)
+		} else {
+			cli.PrintTitle("Starting MageBox Services")
+		}
+		fmt.Println()
+
+		// On macOS, verify pf port forwarding is active (survives reboot/sleep)
+		if targets.Web() {
+			ensurePortForwarding()
+		}
+	}
+
 	// Show warnings
 	for _, w := range warnings {
 		cli.PrintWarning("%s", w)
 	}
 
+	// Hooks only run around a full start
+	if targets == nil {
+		if err := runHook(cfg, projectPath, config.HookPreStart); err != nil {
+			cli.PrintError("%v", err)
+			return err
+		}
+	}
+
 	// Start services
-	result, err := mgr.Start(projectPath)
+	result, err := mgr.StartTargets(projectPath, targets)
 	if err != nil {
 		cli.PrintError("%v", err)
 		return err
 	}
+	if len(result.Errors) > 0 {
+		events.Fail(result.Errors[0])
+	} else {
+		events.Done("Project started")
+	}
 
 	if verbose {
 		// Show results
@@ -103,11 +204,13 @@ func startProject(mgr *project.Manager, projectPath string, verbose bool) error
 		fmt.Printf("PHP:     %s\n", cli.Highlight(result.PHPVersion))
 		fmt.Println()
 
-		fmt.Println(cli.Header("Domains"))
-		for _, d := range result.Domains {
-			fmt.Printf("  %s\n", cli.URL("https://"+d))
+		if targets.Web() {
+			fmt.Println(cli.Header("Domains"))
+			for _, d := range result.Domains {
+				fmt.Printf("  %s\n", cli.URL("https://"+d))
+			}
+			fmt.Println()
 		}
-		fmt.Println()
 
 		fmt.Println(cli.Header("Services"))
 		for _, s := range result.Services {
@@ -115,6 +218,22 @@ func startProject(mgr *project.Manager, projectPath string, verbose bool) error
 		}
 		fmt.Println()
 
+		if len(result.Readiness) > 0 {
+			fmt.Println(cli.Header("Readiness"))
+			for _, r := range result.Readiness {
+				if r.Ready {
+					fmt.Printf("  %s %s ready in %s\n", cli.Success(""), r.Service, r.Elapsed.Round(100*time.Millisecond))
+				} else {
+					fmt.Printf("  %s %s not ready\n", cli.Error(""), r.Service)
+				}
+			}
+			fmt.Println()
+		}
+
+		if result.EnvPHPBackup != "" {
+			cli.PrintInfo("env.php regenerated, previous file saved to %s", cli.Path(result.EnvPHPBackup))
+		}
+
 		// Show warnings from start
 		for _, w := range result.Warnings {
 			cli.PrintWarning("%s", w)
@@ -136,7 +255,7 @@ func startProject(mgr *project.Manager, projectPath string, verbose bool) error
 	}
 
 	// Handle project-specific compose file
-	if cfg.ComposeFile != "" {
+	if cfg.ComposeFile != "" && targets == nil {
 		composeFile := cfg.ComposeFile
 		if !filepath.IsAbs(composeFile) {
 			composeFile = filepath.Join(projectPath, composeFile)
@@ -150,6 +269,13 @@ func startProject(mgr *project.Manager, projectPath string, verbose bool) error
 		}
 	}
 
+	if targets == nil && len(result.Errors) == 0 {
+		if err := runHook(cfg, projectPath, config.HookPostStart); err != nil {
+			cli.PrintError("%v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -187,18 +313,13 @@ func promptComposeUp(composeFile string) error {
 	return nil
 }
 
-func startAll(p interface{ MageBoxDir() string }, mgr *project.Manager) error {
+func startAll(p *platform.Platform, mgr *project.Manager) error {
 	cli.PrintTitle("Starting All MageBox Projects")
 	fmt.Println()
 
-	// Need platform type for discovery
-	plat, err := getPlatform()
-	if err != nil {
-		return err
-	}
-
-	discovery := project.NewProjectDiscovery(plat)
-	projects, err := discovery.DiscoverProjects()
+	// Stopped projects have no vhosts, so include the ones started before
+	discovery := project.NewProjectDiscovery(p)
+	projects, err := discovery.AllProjects()
 	if err != nil {
 		cli.PrintError("Failed to discover projects: %v", err)
 		return nil
@@ -212,15 +333,21 @@ func startAll(p interface{ MageBoxDir() string }, mgr *project.Manager) error {
 	started := 0
 	failed := 0
 
-	for _, proj := range projects {
+	for i, proj := range projects {
 		if !proj.HasConfig {
 			continue // Skip projects without .magebox.yaml
 		}
 
 		fmt.Printf("Starting %s... ", cli.Highlight(proj.Name))
+		events.Step("projects", int64(i), int64(len(projects)), "Starting "+proj.Name)
 
 		// Validate and start
 		_, _, err := mgr.ValidateConfig(proj.Path)
+		if err == nil && startStrict {
+			if files, loadErr := validateProjectConfig(proj.Path); countConfigIssues(files, loadErr) > 0 {
+				err = fmt.Errorf("configuration problems found, see 'magebox config validate'")
+			}
+		}
 		if err != nil {
 			fmt.Println(cli.Error("failed"))
 			cli.PrintError("  %v", err)
@@ -248,8 +375,10 @@ func startAll(p interface{ MageBoxDir() string }, mgr *project.Manager) error {
 	fmt.Println()
 	if failed > 0 {
 		cli.PrintWarning("Started %d project(s), %d failed", started, failed)
+		events.Fail(fmt.Errorf("%d project(s) failed to start", failed))
 	} else {
 		cli.PrintSuccess("Started %d project(s)", started)
+		events.Done(fmt.Sprintf("Started %d project(s)", started))
 	}
 
 	return nil
@@ -3,48 +3,134 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/project"
 )
 
+var (
+	statusAllProjects   bool
+	statusProjectName   string
+	statusWatch         bool
+	statusWatchInterval time.Duration
+)
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show project status",
-	Long:  "Shows the status of all services for the current project",
-	RunE:  runStatus,
+	Long: `Shows the status of all services for the current project, or the project
+named with --project. --all lists every project, running or stopped.
+
+--watch opens a live view that updates in place: services, PHP-FPM pool
+utilization, container health and restarts, and the latest lines of the
+project's nginx error logs. It refreshes every --interval and right away
+when a container starts, stops or changes health. Press r to refresh, q to quit.
+
+Examples:
+  magebox status
+  magebox status --watch
+  magebox status -w --interval 5s`,
+	RunE: runStatus,
 }
 
 func init() {
+	statusCmd.Flags().BoolVarP(&statusAllProjects, "all", "a", false, "List all MageBox projects and whether they run")
+	statusCmd.Flags().StringVar(&statusProjectName, "project", "", "Show the named project instead of the current one")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Show a live view that updates in place")
+	statusCmd.Flags().DurationVar(&statusWatchInterval, "interval", 2*time.Second, "Refresh interval of --watch")
 	rootCmd.AddCommand(statusCmd)
 }
 
+// statusOutput is the project status printed by --output json|yaml
+type statusOutput struct {
+	*project.ProjectStatus
+	PHPSystemINI *phpSystemINIStatus `json:"php_system_ini,omitempty"`
+}
+
+// phpSystemINIStatus describes the project owning the PHP system INI settings
+type phpSystemINIStatus struct {
+	Owner  string `json:"owner"`
+	Active bool   `json:"active"`
+	Path   string `json:"path"`
+}
+
 func runStatus(cmd *cobra.Command, args []string) error {
-	cwd, err := getCwd()
+	p, err := getPlatform()
 	if err != nil {
 		return err
 	}
 
-	p, err := getPlatform()
-	if err != nil {
-		return err
+	if statusWatch {
+		if statusAllProjects {
+			cli.PrintError("--watch cannot be combined with --all")
+			return nil
+		}
+		if structuredOutput() {
+			cli.PrintError("--watch cannot be combined with --output")
+			return nil
+		}
+		if statusWatchInterval < time.Second {
+			cli.PrintError("--interval must be at least 1s")
+			return nil
+		}
+	}
+
+	if statusAllProjects {
+		if statusProjectName != "" {
+			cli.PrintError("--project cannot be combined with --all")
+			return nil
+		}
+		return statusAll(p)
+	}
+
+	cwd, ok := resolveProjectDir(p, statusProjectName)
+	if !ok {
+		return nil
+	}
+
+	if statusWatch {
+		return watchStatus(cmd.Context(), p, cwd)
 	}
 
 	mgr := project.NewManager(p)
 	status, err := mgr.Status(cwd)
 	if err != nil {
+		if structuredOutput() {
+			return err
+		}
 		cli.PrintError("%v", err)
 		return nil
 	}
 
+	sysMgr := php.NewSystemINIManager(p)
+	owner, _ := sysMgr.GetCurrentOwner(status.PHPVersion)
+
+	if structuredOutput() {
+		out := statusOutput{ProjectStatus: status}
+		if owner != nil {
+			out.PHPSystemINI = &phpSystemINIStatus{
+				Owner:  owner.ProjectName,
+				Active: sysMgr.IsSymlinkActive(status.PHPVersion),
+				Path:   sysMgr.GetSystemINIPath(status.PHPVersion),
+			}
+		}
+		return printStructured(out)
+	}
+
 	cli.PrintTitle("Project Status")
 	fmt.Println()
 	fmt.Printf("Project: %s\n", cli.Highlight(status.Name))
 	fmt.Printf("Path:    %s\n", cli.Path(status.Path))
 	fmt.Printf("PHP:     %s\n", cli.Highlight(status.PHPVersion))
+	fmt.Printf("Mode:    %s\n", cli.Highlight(status.MageMode))
+	if mode := envPHPMode(p, cwd); mode != "" && mode != status.MageMode {
+		cli.PrintWarning("Magento runs in %s mode, MageBox serves it as %s; run 'magebox mode %s'", mode, status.MageMode, mode)
+	}
 
 	fmt.Println(cli.Header("Domains"))
 	for _, d := range status.Domains {
@@ -53,7 +139,19 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	fmt.Println(cli.Header("Services"))
 	for _, svc := range status.Services {
-		fmt.Printf("  %-20s %s\n", svc.Name, cli.Status(svc.IsRunning))
+		line := fmt.Sprintf("  %-20s %s", svc.Name, cli.Status(svc.IsRunning))
+		if svc.URL != "" {
+			line += "  " + cli.URL(svc.URL)
+		} else if svc.Port != 0 {
+			line += fmt.Sprintf("  127.0.0.1:%d", svc.Port)
+		}
+		if svc.PortConflict {
+			line += "  " + cli.Warning(fmt.Sprintf("port %d is used by another program", svc.Port))
+		}
+		fmt.Println(line)
+	}
+	if dbs := status.RedisDBs; dbs != nil {
+		fmt.Printf("  %-20s cache %d, page cache %d, session %d\n", "Redis databases", dbs.Cache, dbs.PageCache, dbs.Session)
 	}
 
 	fmt.Println(cli.Header("Config Files"))
@@ -66,8 +164,6 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show PHP system INI settings info
-	sysMgr := php.NewSystemINIManager(p)
-	owner, _ := sysMgr.GetCurrentOwner(status.PHPVersion)
 	if owner != nil {
 		fmt.Println(cli.Header("PHP System Settings"))
 		if owner.ProjectName == status.Name {
@@ -85,3 +181,44 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// statusAll lists the running and stopped projects
+func statusAll(p *platform.Platform) error {
+	projects, err := project.NewProjectDiscovery(p).AllProjects()
+	if err != nil {
+		if structuredOutput() {
+			return fmt.Errorf("failed to discover projects: %w", err)
+		}
+		cli.PrintError("Failed to discover projects: %v", err)
+		return nil
+	}
+
+	if structuredOutput() {
+		if projects == nil {
+			projects = []project.ProjectInfo{}
+		}
+		return printStructured(projects)
+	}
+
+	cli.PrintTitle("MageBox Projects")
+	fmt.Println()
+
+	if len(projects) == 0 {
+		cli.PrintInfo("No projects found")
+		return nil
+	}
+
+	running := 0
+	for _, proj := range projects {
+		state := cli.Warning("stopped")
+		if proj.Running {
+			state = cli.Success("running")
+			running++
+		}
+		fmt.Printf("  %-25s %-18s PHP %-5s %s\n", cli.Highlight(proj.Name), state, proj.PHPVersion, cli.Path(proj.Path))
+	}
+
+	fmt.Println()
+	fmt.Printf("Total: %d project(s), %d running\n", len(projects), running)
+	return nil
+}
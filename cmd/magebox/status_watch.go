@@ -0,0 +1,139 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/testmode"
+	"qoliber/magebox/internal/tui"
+)
+
+// watchStatus shows the live status view of the project until it is closed.
+// It refreshes every --interval and whenever a container changes state.
+func watchStatus(ctx context.Context, p *platform.Platform, projectPath string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	composeFile := docker.NewComposeGenerator(p).ComposeFilePath()
+	var events <-chan struct{}
+	if !testmode.SkipDocker() {
+		events = containerEvents(docker.NewDockerController(composeFile).WithContext(ctx))
+	}
+
+	source := &statusWatchSource{platform: p, projectPath: projectPath, composeFile: composeFile}
+	return tui.RunStatus(source, statusWatchInterval, events)
+}
+
+// containerEvents streams docker compose events of the global services. The
+// channel is closed when the stream ends, e.g. because Docker is not running,
+// which leaves the view on timed refreshes.
+func containerEvents(ctrl *docker.DockerController) <-chan struct{} {
+	events := make(chan struct{}, 1)
+	cmd := ctrl.EventsCommand()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		close(events)
+		return events
+	}
+	if err := cmd.Start(); err != nil {
+		close(events)
+		return events
+	}
+
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			// Coalesce bursts, a pending refresh covers them all
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+		_ = cmd.Wait()
+	}()
+	return events
+}
+
+// statusWatchSource takes the snapshots of the live status view
+type statusWatchSource struct {
+	platform    *platform.Platform
+	projectPath string
+	composeFile string
+}
+
+func (s *statusWatchSource) Snapshot() (tui.StatusSnapshot, error) {
+	// Reloaded on every refresh, so edits to the config show up
+	cfg, err := config.LoadFromPath(s.projectPath)
+	if err != nil {
+		return tui.StatusSnapshot{}, err
+	}
+	status, err := project.NewManager(s.platform).Status(s.projectPath)
+	if err != nil {
+		return tui.StatusSnapshot{}, err
+	}
+
+	snapshot := tui.StatusSnapshot{Project: status.Name, Services: []tui.Service{}}
+	for _, svc := range status.Services {
+		snapshot.Services = append(snapshot.Services, tui.Service{Name: svc.Name, Running: svc.IsRunning})
+	}
+	sort.Slice(snapshot.Services, func(i, j int) bool { return snapshot.Services[i].Name < snapshot.Services[j].Name })
+
+	socketPath := php.NewPoolGenerator(s.platform).GetSocketPath(cfg.Name, cfg.PHP)
+	if _, err := os.Stat(socketPath); err != nil {
+		snapshot.PoolErr = fmt.Errorf("the pool is not running")
+	} else if pool, err := php.QueryPoolStatus(socketPath); err == nil {
+		snapshot.Pool = &tui.PoolUsage{
+			Active:             pool.ActiveProcesses,
+			Idle:               pool.IdleProcesses,
+			Total:              pool.TotalProcesses,
+			MaxActive:          pool.MaxActiveProcesses,
+			MaxChildrenReached: pool.MaxChildrenReached,
+			ListenQueue:        pool.ListenQueue,
+			SlowRequests:       pool.SlowRequests,
+		}
+	} else {
+		snapshot.PoolErr = err
+	}
+
+	if !testmode.SkipDocker() {
+		services := project.ComposeServiceNames(cfg)
+		states, _ := docker.NewDockerController(s.composeFile).ContainerStates()
+		for _, c := range states {
+			if !slices.Contains(services, c.Service) {
+				continue
+			}
+			snapshot.Containers = append(snapshot.Containers, tui.Container{
+				Name:      c.Name,
+				State:     c.State,
+				Health:    c.Health,
+				Restarts:  c.RestartCount,
+				StartedAt: c.StartedAt,
+			})
+		}
+		sort.Slice(snapshot.Containers, func(i, j int) bool { return snapshot.Containers[i].Name < snapshot.Containers[j].Name })
+	}
+
+	logsDir := filepath.Join(s.platform.MageBoxDir(), "logs", "nginx")
+	for _, path := range nginxLogPaths(logsDir, cfg) {
+		if strings.HasSuffix(path, "-error.log") {
+			snapshot.ErrorLogs = append(snapshot.ErrorLogs, path)
+		}
+	}
+
+	return snapshot, nil
+}
@@ -12,24 +12,55 @@ import (
 	"qoliber/magebox/internal/cli"
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/project"
 )
 
 var (
 	stopAllProjects bool
+	stopOthers      bool
+	stopProjectName string
 	stopDryRun      bool
+	stopOnly        []string
+	stopNoCascade   bool
 )
 
 var stopCmd = &cobra.Command{
-	Use:   "stop",
+	Use:   "stop [service|component...]",
 	Short: "Stop project services",
-	Long:  "Stops all services for the current project, or all projects with --all",
-	RunE:  runStop,
+	Long: `Stops all services for the current project, the project named with
+--project from any directory, or all projects with --all.
+
+Without arguments the project's vhosts, PHP-FPM pool and DNS entries are
+removed while the shared Docker services keep running. Pass service or
+component names (see 'magebox start --help') to stop only those, including
+Docker services, e.g. to free memory.
+
+--others switches focus to the current (or --project) project: every other
+project is stopped, and so are the Docker services only those projects use.
+Services the kept project needs keep running.
+
+Stopping the whole project runs the pre_stop hook of .magebox.yaml first;
+--no-hooks skips it. The running worktree projects of the project (see
+'magebox worktree') are stopped with it unless --no-cascade is given.
+
+Examples:
+  magebox stop                     # Stop the project
+  magebox stop --project mystore   # Stop another project from anywhere
+  magebox stop --others            # Stop everything this project doesn't need
+  magebox stop opensearch          # Stop the OpenSearch container
+  magebox stop --only services     # Stop all Docker services of the project`,
+	RunE: runStop,
 }
 
 func init() {
 	stopCmd.Flags().BoolVarP(&stopAllProjects, "all", "a", false, "Stop all MageBox projects")
+	stopCmd.Flags().BoolVar(&stopOthers, "others", false, "Stop all other projects and the services only they use")
+	stopCmd.Flags().StringVar(&stopProjectName, "project", "", "Stop the named project instead of the current one")
 	stopCmd.Flags().BoolVar(&stopDryRun, "dry-run", false, "Show what would be stopped without stopping")
+	stopCmd.Flags().StringSliceVar(&stopOnly, "only", nil, "Stop only these services or components (comma-separated)")
+	stopCmd.Flags().BoolVar(&skipHooks, "no-hooks", false, "Don't run the pre_stop hook")
+	stopCmd.Flags().BoolVar(&stopNoCascade, "no-cascade", false, "Keep the worktree projects of the project running")
 	rootCmd.AddCommand(stopCmd)
 }
 
@@ -41,14 +72,31 @@ func runStop(cmd *cobra.Command, args []string) error {
 
 	mgr := project.NewManager(p)
 
+	targetNames := append(append([]string{}, args...), stopOnly...)
 	if stopAllProjects {
+		if len(targetNames) > 0 || stopProjectName != "" || stopOthers {
+			cli.PrintError("Services, components, --project and --others cannot be combined with --all")
+			return nil
+		}
 		return stopAll(p, mgr)
 	}
 
-	// Stop current project
-	cwd, err := getCwd()
-	if err != nil {
-		return err
+	// Stop the current or named project
+	cwd, ok := resolveProjectDir(p, stopProjectName)
+	if !ok {
+		return nil
+	}
+
+	if stopOthers {
+		if len(targetNames) > 0 {
+			cli.PrintError("Services and components cannot be combined with --others")
+			return nil
+		}
+		return stopOtherProjects(p, mgr, cwd)
+	}
+
+	if len(targetNames) > 0 {
+		return stopTargets(mgr, cwd, targetNames)
 	}
 
 	if stopDryRun {
@@ -57,6 +105,12 @@ func runStop(cmd *cobra.Command, args []string) error {
 
 	// Handle project-specific compose file before stopping
 	cfg, _ := config.LoadFromPath(cwd)
+	if cfg != nil {
+		if err := runHook(cfg, cwd, config.HookPreStop); err != nil {
+			cli.PrintError("%v", err)
+			return err
+		}
+	}
 	if cfg != nil && cfg.ComposeFile != "" {
 		composeFile := cfg.ComposeFile
 		if !filepath.IsAbs(composeFile) {
@@ -69,6 +123,11 @@ func runStop(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Consumers would crash-loop without the database and broker
+	if cfg != nil && stopQueueConsumers(p, cfg.Name) {
+		cli.PrintInfo("Queue consumers stopped")
+	}
+
 	cli.PrintInfo("Stopping MageBox services...")
 
 	if err := mgr.Stop(cwd); err != nil {
@@ -76,10 +135,46 @@ func runStop(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if cfg != nil && !stopNoCascade {
+		stopWorktrees(mgr, cfg.Name)
+	}
+
 	cli.PrintSuccess("Project stopped successfully!")
 	return nil
 }
 
+// stopTargets stops the named services and components of the project
+func stopTargets(mgr *project.Manager, cwd string, names []string) error {
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	targets, err := project.ParseTargets(cfg, names)
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+
+	for service, projects := range mgr.SharedServices(cfg, targets.Services()) {
+		cli.PrintWarning("%s is shared with: %s", service, strings.Join(projects, ", "))
+	}
+
+	if stopDryRun {
+		cli.PrintInfo("Would stop: %s", targets)
+		return nil
+	}
+
+	cli.PrintInfo("Stopping %s...", targets)
+	if err := mgr.StopTargets(cwd, targets); err != nil {
+		cli.PrintError("%v", err)
+		return err
+	}
+
+	cli.PrintSuccess("Stopped %s", targets)
+	return nil
+}
+
 // promptComposeDown asks the user whether to stop project-specific Docker containers
 func promptComposeDown(composeFile string) error {
 	services, err := docker.ProjectComposeServices(composeFile)
@@ -133,6 +228,14 @@ func stopDryRunSingle(cwd string) error {
 		fmt.Printf("  %s PHP-FPM %s pool configuration\n", cli.Bullet(""), cfg.PHP)
 	}
 	fmt.Printf("  %s DNS entries (if using hosts mode)\n", cli.Bullet(""))
+	if p, err := getPlatform(); err == nil && !stopNoCascade {
+		children, _ := project.NewWorktrees(p).Children(cfg.Name)
+		for _, wt := range children {
+			if projectRunning(p, wt.Path) {
+				fmt.Printf("  %s Worktree project %s\n", cli.Bullet(""), wt.Name)
+			}
+		}
+	}
 	fmt.Println()
 	fmt.Println("Note: Docker services (MySQL, Redis, etc.) remain running")
 	fmt.Println("      as they are shared across all MageBox projects.")
@@ -144,17 +247,11 @@ func stopDryRunSingle(cwd string) error {
 	return nil
 }
 
-func stopAll(p interface{ MageBoxDir() string }, mgr *project.Manager) error {
+func stopAll(p *platform.Platform, mgr *project.Manager) error {
 	cli.PrintTitle("Stopping All MageBox Projects")
 	fmt.Println()
 
-	// Need platform type for discovery
-	plat, err := getPlatform()
-	if err != nil {
-		return err
-	}
-
-	discovery := project.NewProjectDiscovery(plat)
+	discovery := project.NewProjectDiscovery(p)
 	projects, err := discovery.DiscoverProjects()
 	if err != nil {
 		cli.PrintError("Failed to discover projects: %v", err)
@@ -176,6 +273,7 @@ func stopAll(p interface{ MageBoxDir() string }, mgr *project.Manager) error {
 
 		fmt.Printf("Stopping %s... ", cli.Highlight(proj.Name))
 
+		stopQueueConsumers(p, proj.Name)
 		if err := mgr.Stop(proj.Path); err != nil {
 			fmt.Println(cli.Error("failed"))
 			cli.PrintError("  %v", err)
@@ -195,3 +293,92 @@ func stopAll(p interface{ MageBoxDir() string }, mgr *project.Manager) error {
 
 	return nil
 }
+
+// stopOtherProjects stops every running project except the one in keepDir,
+// then the Docker services that only the stopped projects used
+func stopOtherProjects(p *platform.Platform, mgr *project.Manager, keepDir string) error {
+	keep, ok := loadProjectConfig(keepDir)
+	if !ok {
+		return nil
+	}
+
+	projects, err := project.NewProjectDiscovery(p).DiscoverProjects()
+	if err != nil {
+		cli.PrintError("Failed to discover projects: %v", err)
+		return nil
+	}
+
+	if stopDryRun {
+		cli.PrintTitle("Dry Run: Would stop all projects but %s", keep.Name)
+	} else {
+		cli.PrintTitle("Stopping All Projects but %s", keep.Name)
+	}
+	fmt.Println()
+
+	var stopped []*config.Config
+	kept := []*config.Config{keep}
+	failed := 0
+	for _, proj := range projects {
+		if !proj.HasConfig || proj.Name == keep.Name {
+			continue // Projects without .magebox.yaml can't be stopped cleanly
+		}
+		cfg, err := config.LoadFromPath(proj.Path)
+		if err != nil {
+			cli.PrintWarning("Skipping %s: %v", proj.Name, err)
+			continue
+		}
+
+		if stopDryRun {
+			fmt.Printf("  %s %s\n", cli.Bullet(""), cfg.Name)
+			stopped = append(stopped, cfg)
+			continue
+		}
+
+		fmt.Printf("Stopping %s... ", cli.Highlight(cfg.Name))
+		stopQueueConsumers(p, cfg.Name)
+		if err := mgr.Stop(proj.Path); err != nil {
+			fmt.Println(cli.Error("failed"))
+			cli.PrintError("  %v", err)
+			kept = append(kept, cfg)
+			failed++
+			continue
+		}
+		fmt.Println(cli.Success("done"))
+		stopped = append(stopped, cfg)
+	}
+
+	if len(stopped) == 0 && failed == 0 {
+		cli.PrintInfo("No other projects are running")
+		return nil
+	}
+
+	// Shared services stay up for the kept project and projects that failed to stop
+	services := project.ExclusiveServices(stopped, kept)
+	if stopDryRun {
+		if len(services) > 0 {
+			fmt.Println()
+			fmt.Printf("Docker services: %s\n", strings.Join(services, ", "))
+		}
+		fmt.Println()
+		cli.PrintInfo("Run without --dry-run to actually stop")
+		return nil
+	}
+
+	if len(services) > 0 {
+		fmt.Printf("Stopping %s... ", strings.Join(services, ", "))
+		if err := mgr.StopServices(services); err != nil {
+			fmt.Println(cli.Error("failed"))
+			cli.PrintError("  %v", err)
+		} else {
+			fmt.Println(cli.Success("done"))
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		cli.PrintWarning("Stopped %d project(s), %d failed", len(stopped), failed)
+	} else {
+		cli.PrintSuccess("Stopped %d project(s), %s keeps running", len(stopped), keep.Name)
+	}
+	return nil
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/dbverify"
 	"qoliber/magebox/internal/progress"
 	"qoliber/magebox/internal/team"
 )
@@ -32,7 +34,11 @@ Examples:
   magebox sync --db         # Only sync database
   magebox sync --media      # Only sync media
   magebox sync --backup     # Backup current DB before syncing
-  magebox sync --dry-run    # Show what would happen`,
+  magebox sync --verify     # Verify the import against the dump's manifest
+  magebox sync --dry-run    # Show what would happen
+
+The imported database runs the post_db_import hook of .magebox.yaml, and a
+completed sync the post_sync hook; --no-hooks skips both.`,
 	RunE: runSync,
 }
 
@@ -41,6 +47,7 @@ var (
 	syncMediaOnly bool
 	syncBackup    bool
 	syncDryRun    bool
+	syncVerify    bool
 )
 
 func init() {
@@ -48,6 +55,8 @@ func init() {
 	syncCmd.Flags().BoolVar(&syncMediaOnly, "media", false, "Only sync media")
 	syncCmd.Flags().BoolVar(&syncBackup, "backup", false, "Backup current database before syncing")
 	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would happen without making changes")
+	syncCmd.Flags().BoolVar(&syncVerify, "verify", false, "Verify the imported database against <dump>.manifest.json")
+	syncCmd.Flags().BoolVar(&skipHooks, "no-hooks", false, "Don't run the post_db_import and post_sync hooks")
 
 	rootCmd.AddCommand(syncCmd)
 }
@@ -87,12 +96,14 @@ func runSync(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Connect to asset storage
+	// Connect to asset storage; downloads report progress for the current phase
+	phase := ""
 	assetClient := team.NewAssetClient(t, func(prog team.DownloadProgress) {
 		fmt.Printf("\r  %s: %.1f%% (%s/%s) %s ETA: %s",
 			prog.Filename, prog.Percentage,
 			team.FormatBytes(prog.Downloaded), team.FormatBytes(prog.TotalBytes),
 			team.FormatSpeed(prog.Speed), prog.ETA)
+		events.Step(phase, prog.Downloaded, prog.TotalBytes, prog.Filename)
 	})
 
 	// Connect to verify files exist (even in dry-run mode)
@@ -115,6 +126,8 @@ func runSync(cmd *cobra.Command, args []string) error {
 			fmt.Println("Would import to MySQL")
 		} else {
 			fmt.Println("Downloading database...")
+			phase = "download-db"
+			events.Phase(phase, 0, "Downloading "+project.DB)
 
 			tmpDir := os.TempDir()
 			localPath := filepath.Join(tmpDir, "magebox-sync-db-"+filepath.Base(project.DB))
@@ -124,10 +137,27 @@ func runSync(cmd *cobra.Command, args []string) error {
 			}
 			defer os.Remove(localPath)
 
+			importArgs := dbImportArgs(localPath)
+			if syncVerify {
+				remoteManifest := project.DB + dbverify.ManifestSuffix
+				if assetClient.FileExists(remoteManifest) {
+					manifestPath := dbverify.ManifestPath(localPath)
+					if err := assetClient.Download(remoteManifest, manifestPath); err != nil {
+						return fmt.Errorf("failed to download manifest: %w", err)
+					}
+					defer os.Remove(manifestPath)
+					importArgs = append(importArgs, "--verify")
+				} else {
+					fmt.Println()
+					cli.PrintWarning("No manifest found at %s, the import is not verified", remoteManifest)
+				}
+			}
+
 			fmt.Println()
 			fmt.Println("Importing database...")
+			events.Phase("import-db", 30, "Importing database")
 
-			importCmd := exec.Command("magebox", "db", "import", localPath)
+			importCmd := exec.Command("magebox", importArgs...)
 			importCmd.Dir = cwd
 			importCmd.Stdout = os.Stdout
 			importCmd.Stderr = os.Stderr
@@ -155,6 +185,8 @@ func runSync(cmd *cobra.Command, args []string) error {
 		} else {
 			fmt.Println()
 			fmt.Println("Downloading media...")
+			phase = "download-media"
+			events.Phase(phase, 50, "Downloading "+project.Media)
 
 			tmpDir := os.TempDir()
 			localPath := filepath.Join(tmpDir, "magebox-sync-media-"+filepath.Base(project.Media))
@@ -186,8 +218,12 @@ func runSync(cmd *cobra.Command, args []string) error {
 			defer file.Close()
 
 			// Create progress bar
+			events.Phase("extract-media", 80, "Extracting media")
 			bar := progress.NewBar("Extracting:")
-			progressReader := progress.NewReader(file, fileInfo.Size(), bar.Update)
+			progressReader := progress.NewReader(file, fileInfo.Size(), func(p progress.Progress) {
+				bar.Update(p)
+				events.Bytes("extract-media", p)
+			})
 
 			// Use tar command reading from stdin (faster than Go implementation for large files)
 			tarCmd := exec.Command("tar", "-xz", "-C", mediaDir)
@@ -207,6 +243,36 @@ func runSync(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	cli.PrintSuccess("Sync completed!")
 
+	if err := runSyncHook(cwd); err != nil {
+		return err
+	}
+	events.Done("Sync completed")
+
+	return nil
+}
+
+// dbImportArgs returns the arguments of 'magebox db import' for a dump
+func dbImportArgs(dumpPath string) []string {
+	args := []string{"db", "import", dumpPath}
+	if skipHooks {
+		args = append(args, "--no-hooks")
+	}
+	return args
+}
+
+// runSyncHook runs the post_sync hook of the project, unless it's a dry run
+func runSyncHook(projectPath string) error {
+	if syncDryRun {
+		return nil
+	}
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		return nil // Synced into a project without a MageBox config
+	}
+	if err := runHook(cfg, projectPath, config.HookPostSync); err != nil {
+		cli.PrintError("%v", err)
+		return err
+	}
 	return nil
 }
 
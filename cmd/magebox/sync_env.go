@@ -0,0 +1,353 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/progress"
+	"qoliber/magebox/internal/remote"
+)
+
+var syncEnvDBCmd = &cobra.Command{
+	Use:   "db <environment>",
+	Short: "Pull the database from a remote environment",
+	Long: `Dumps the database of a remote environment over SSH and imports it into the
+project database.
+
+The environment comes from the environments: block of .magebox.yaml or from
+'magebox env' (synced from the team server). Its db.method decides how the
+dump is made: n98-magerun2 db:dump in the remote path (magerun, the default),
+mysqldump on the remote host (mysqldump), or a local mysqldump through an SSH
+port forward (tunnel).
+
+Examples:
+  magebox sync db staging
+  magebox sync db production --backup`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSyncEnvDB,
+}
+
+var syncEnvMediaCmd = &cobra.Command{
+	Use:   "media <environment>",
+	Short: "Pull pub/media from a remote environment",
+	Long: `Copies pub/media of a remote environment into the project with rsync.
+Image caches are skipped, Magento regenerates them. Local files are not removed.
+
+Example:
+  magebox sync media staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSyncEnvMedia,
+}
+
+var syncEnvAllCmd = &cobra.Command{
+	Use:   "all <environment>",
+	Short: "Pull the database and pub/media from a remote environment",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSyncEnvAll,
+}
+
+// mediaSyncExcludes are the pub/media directories Magento regenerates
+var mediaSyncExcludes = []string{"/cache/", "/catalog/product/cache/", "/tmp/"}
+
+func init() {
+	for _, c := range []*cobra.Command{syncEnvDBCmd, syncEnvAllCmd} {
+		c.Flags().BoolVar(&syncBackup, "backup", false, "Backup current database before syncing")
+	}
+	for _, c := range []*cobra.Command{syncEnvDBCmd, syncEnvMediaCmd, syncEnvAllCmd} {
+		c.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would happen without making changes")
+		c.Flags().BoolVar(&skipHooks, "no-hooks", false, "Don't run the post_db_import and post_sync hooks")
+		syncCmd.AddCommand(c)
+	}
+}
+
+func runSyncEnvDB(cmd *cobra.Command, args []string) error {
+	return syncFromEnvironment(args[0], true, false)
+}
+
+func runSyncEnvMedia(cmd *cobra.Command, args []string) error {
+	return syncFromEnvironment(args[0], false, true)
+}
+
+func runSyncEnvAll(cmd *cobra.Command, args []string) error {
+	return syncFromEnvironment(args[0], true, true)
+}
+
+// syncFromEnvironment pulls the database and/or media of a remote environment
+func syncFromEnvironment(name string, syncDB, syncMedia bool) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := loadProjectConfig(cwd); !ok {
+		return nil
+	}
+
+	env, err := findEnvironment(name)
+	if err != nil {
+		return err
+	}
+
+	cli.PrintTitle("Syncing from %s", env.Name)
+	fmt.Println()
+	fmt.Printf("  Host: %s\n", cli.Highlight(env.GetConnectionString()))
+	if env.Path != "" {
+		fmt.Printf("  Path: %s\n", cli.Highlight(env.Path))
+	}
+	fmt.Println()
+
+	if syncDryRun {
+		fmt.Println(cli.Warning("DRY RUN - No changes will be made"))
+		fmt.Println()
+	}
+
+	if syncDB {
+		if err := syncEnvironmentDB(cwd, env); err != nil {
+			return err
+		}
+	}
+	if syncMedia {
+		if err := syncEnvironmentMedia(cwd, env); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("Sync completed!")
+
+	if err := runSyncHook(cwd); err != nil {
+		return err
+	}
+	events.Done("Sync completed")
+	return nil
+}
+
+// syncEnvironmentDB dumps the environment's database into a temporary
+// gzipped file and imports it with 'magebox db import'
+func syncEnvironmentDB(cwd string, env *remote.Environment) error {
+	if err := env.ValidateDB(); err != nil {
+		return fmt.Errorf("environment '%s': %w", env.Name, err)
+	}
+	method := env.GetDBMethod()
+	if method == remote.DBAccessMagerun && env.Path == "" {
+		return fmt.Errorf("environment '%s' has no path, set it or use db.method: mysqldump", env.Name)
+	}
+
+	if syncDryRun {
+		fmt.Printf("Would dump the database with %s\n", method)
+		fmt.Println("Would import to MySQL")
+		return nil
+	}
+
+	if syncBackup {
+		if err := backupDatabase(cwd); err != nil {
+			cli.PrintWarning("Failed to backup database: %v", err)
+		}
+	}
+
+	// The dump holds production data: keep it in a directory only this user
+	// can read
+	dumpDir, err := os.MkdirTemp("", "magebox-sync-")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for the dump: %w", err)
+	}
+	defer os.RemoveAll(dumpDir)
+	dumpPath := filepath.Join(dumpDir, "dump.sql.gz")
+
+	fmt.Printf("Dumping database (%s)... ", method)
+	events.Phase("dump-db", 0, "Dumping database from "+env.Name)
+	var size int64
+	if method == remote.DBAccessTunnel {
+		size, err = dumpThroughTunnel(env, dumpPath)
+	} else {
+		size, err = dumpOverSSH(env, dumpPath)
+	}
+	if err != nil {
+		fmt.Println(cli.Error("failed"))
+		return err
+	}
+	fmt.Println(cli.Success(progress.FormatBytes(size)))
+
+	fmt.Println("Importing database...")
+	events.Phase("import-db", 30, "Importing database")
+	importCmd := exec.Command("magebox", dbImportArgs(dumpPath)...)
+	importCmd.Dir = cwd
+	importCmd.Stdout = os.Stdout
+	importCmd.Stderr = os.Stderr
+	if err := importCmd.Run(); err != nil {
+		return fmt.Errorf("failed to import database: %w", err)
+	}
+
+	fmt.Println(cli.Success("Database synced!"))
+	return nil
+}
+
+// dumpOverSSH runs the dump on the remote host. ssh compresses the stream,
+// the dump is gzipped locally so a failing dump still fails the command.
+func dumpOverSSH(env *remote.Environment, dumpPath string) (int64, error) {
+	dump, err := env.DBDumpCommand()
+	if err != nil {
+		return 0, err
+	}
+	return writeDump(env.BuildRemoteCommand(dump, "-C"), dumpPath)
+}
+
+// dumpThroughTunnel forwards a local port to the environment's database and
+// dumps it with the local mysqldump
+func dumpThroughTunnel(env *remote.Environment, dumpPath string) (int64, error) {
+	if env.SSHCommand != "" {
+		return 0, fmt.Errorf("environment '%s' uses a custom ssh_command, which can't open the port forward", env.Name)
+	}
+	if _, err := exec.LookPath("mysqldump"); err != nil {
+		return 0, fmt.Errorf("db method %q needs mysqldump installed locally", remote.DBAccessTunnel)
+	}
+
+	port, err := freeLocalPort()
+	if err != nil {
+		return 0, err
+	}
+	tunnel := env.BuildSSHCommand(env.TunnelArgs(port)...)
+	tunnel.Stderr = os.Stderr
+	if err := tunnel.Start(); err != nil {
+		return 0, fmt.Errorf("failed to open tunnel: %w", err)
+	}
+	defer func() {
+		_ = tunnel.Process.Kill()
+		_ = tunnel.Wait()
+	}()
+	if err := waitForPort(port, 15*time.Second); err != nil {
+		return 0, fmt.Errorf("tunnel to %s did not come up: %w", env.Name, err)
+	}
+
+	dump, err := env.TunnelDumpCommand(port)
+	if err != nil {
+		return 0, err
+	}
+	return writeDump(dump, dumpPath)
+}
+
+// writeDump runs a dump command and gzips its output into dumpPath, a new
+// file only the user can read, returning the size of the uncompressed dump
+func writeDump(dump *exec.Cmd, dumpPath string) (int64, error) {
+	file, err := os.OpenFile(dumpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create dump file: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	counter := &countingWriter{w: gz}
+	dump.Stdout = counter
+	dump.Stderr = os.Stderr
+	if err := dump.Run(); err != nil {
+		return 0, fmt.Errorf("dump failed: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write dump file: %w", err)
+	}
+	if counter.n == 0 {
+		return 0, fmt.Errorf("dump is empty")
+	}
+	return counter.n, nil
+}
+
+// syncEnvironmentMedia copies the environment's pub/media with rsync
+func syncEnvironmentMedia(cwd string, env *remote.Environment) error {
+	if env.Path == "" {
+		return fmt.Errorf("environment '%s' has no path, set the Magento root on the remote host", env.Name)
+	}
+	if env.SSHCommand != "" {
+		return fmt.Errorf("environment '%s' uses a custom ssh_command, which rsync can't use", env.Name)
+	}
+	if _, err := exec.LookPath("rsync"); err != nil {
+		return fmt.Errorf("rsync is not installed")
+	}
+
+	mediaDir := filepath.Join(cwd, "pub", "media")
+	args := mediaSyncArgs(env, mediaDir)
+
+	if syncDryRun {
+		fmt.Printf("Would sync %s to pub/media\n", env.RsyncPath("pub/media/"))
+		args = append([]string{"--dry-run", "--stats"}, args...)
+	} else {
+		fmt.Println()
+		fmt.Println("Syncing media...")
+		events.Phase("sync-media", 60, "Syncing pub/media from "+env.Name)
+		if err := os.MkdirAll(mediaDir, 0755); err != nil {
+			return fmt.Errorf("failed to create media directory: %w", err)
+		}
+	}
+
+	rsync := exec.Command("rsync", args...)
+	rsync.Stdout = os.Stdout
+	rsync.Stderr = os.Stderr
+	if err := rsync.Run(); err != nil {
+		return fmt.Errorf("failed to sync media: %w", err)
+	}
+
+	if !syncDryRun {
+		fmt.Println(cli.Success("Media synced!"))
+	}
+	return nil
+}
+
+// mediaSyncArgs returns the rsync arguments that copy the environment's
+// pub/media into mediaDir
+func mediaSyncArgs(env *remote.Environment, mediaDir string) []string {
+	args := []string{"-az", "--info=progress2", "-e", env.RsyncShell()}
+	for _, exclude := range mediaSyncExcludes {
+		args = append(args, "--exclude="+exclude)
+	}
+	return append(args, env.RsyncPath("pub/media/"), mediaDir+string(filepath.Separator))
+}
+
+// freeLocalPort returns a TCP port on 127.0.0.1 nothing listens on
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitForPort waits until something accepts connections on the local port
+func waitForPort(port int, timeout time.Duration) error {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/remote"
+)
+
+func TestMediaSyncArgs(t *testing.T) {
+	env := &remote.Environment{User: "deploy", Host: "staging.example.com", Port: 2222, Path: "/var/www/shop"}
+
+	got := strings.Join(mediaSyncArgs(env, "/home/me/shop/pub/media"), " ")
+	want := "-az --info=progress2 -e ssh -p 2222 --exclude=/cache/ --exclude=/catalog/product/cache/ --exclude=/tmp/ " +
+		"deploy@staging.example.com:/var/www/shop/pub/media/ /home/me/shop/pub/media/"
+	if got != want {
+		t.Errorf("mediaSyncArgs() = %s\nwant %s", got, want)
+	}
+}
+
+func TestWriteDumpIsPrivate(t *testing.T) {
+	dumpPath := filepath.Join(t.TempDir(), "dump.sql.gz")
+	size, err := writeDump(exec.Command("echo", "CREATE TABLE t (id INT);"), dumpPath)
+	if err != nil || size == 0 {
+		t.Fatalf("writeDump() = %d, %v", size, err)
+	}
+	info, err := os.Stat(dumpPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("dump mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	// A file planted at the path is never written to
+	if _, err := writeDump(exec.Command("echo", "x"), dumpPath); err == nil {
+		t.Error("expected an error for an existing dump file")
+	}
+}
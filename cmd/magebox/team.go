@@ -29,7 +29,14 @@ Examples:
   magebox team list                 # List all configured teams
   magebox team myteam show          # Show team configuration
   magebox team myteam repos         # List repositories in namespace
-  magebox team remove myteam        # Remove a team`,
+  magebox team remove myteam        # Remove a team
+  magebox team pull-config myproject  # Fetch .magebox.yaml from the team server
+
+Team server:
+  magebox team join <url> <invite-token>  # Join a team server
+  magebox team env list                   # List environments you can access
+  magebox team ssh myproject/staging      # SSH into an environment
+  magebox team cert renew                 # Renew your SSH certificate`,
 	DisableFlagParsing: true,
 	RunE:               runTeamCmd,
 }
@@ -111,10 +118,23 @@ func runTeamCmd(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("team remove requires a team name")
 		}
 		return runTeamRemove(teamRemoveCmd, args[1:])
+	case "pull-config":
+		if err := teamPullConfigCmd.ParseFlags(args[1:]); err != nil {
+			return err
+		}
+		remainingArgs := teamPullConfigCmd.Flags().Args()
+		if len(remainingArgs) != 1 {
+			return fmt.Errorf("team pull-config requires exactly one argument: project name")
+		}
+		return runTeamPullConfig(teamPullConfigCmd, remainingArgs)
 	case "-h", "--help", "help":
 		return cmd.Help()
 	}
 
+	if handled, err := runTeamClientCmd(args); handled {
+		return err
+	}
+
 	// If not a known subcommand, treat first arg as team name
 	return runTeamDynamic(cmd, args)
 }
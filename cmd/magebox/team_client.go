@@ -0,0 +1,358 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/teamserver"
+)
+
+var teamJoinCmd = &cobra.Command{
+	Use:   "join <server-url> [invite-token]",
+	Short: "Join a team server",
+	Long: `Joins a MageBox team server with an invite token, or through the server's
+identity provider with --sso.
+
+The session, your SSH key and certificate are stored in ~/.magebox/team/.
+An SSH host is configured for every environment you can access, so plain
+ssh, scp and rsync work too: ~/.ssh/config includes ~/.magebox/team/ssh_config.
+
+Examples:
+  magebox team join https://teamserver.example.com <invite-token>
+  magebox team join https://teamserver.example.com --sso`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runTeamJoin,
+}
+
+var teamEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Team server environments",
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var teamEnvListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the environments you can access",
+	Long: `Fetches the environments you can access from the team server and refreshes
+their SSH hosts.
+
+Example:
+  magebox team env list`,
+	Args: cobra.NoArgs,
+	RunE: runTeamEnvList,
+}
+
+var teamSSHCmd = &cobra.Command{
+	Use:   "ssh <project>/<env>",
+	Short: "SSH into a team server environment",
+	Long: `Opens an SSH session on a team server environment with your team key.
+
+Example:
+  magebox team ssh myproject/staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSSH,
+}
+
+var teamCertCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Team server SSH certificates",
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var teamCertRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew your SSH certificate",
+	Long: `Renews your SSH certificate, for team servers with SSH CA enabled.
+
+Examples:
+  magebox team cert renew
+  magebox team cert renew --quiet    # For scripts/cron`,
+	Args: cobra.NoArgs,
+	RunE: runCertRenew,
+}
+
+func init() {
+	teamJoinCmd.Flags().BoolVar(&joinSSO, "sso", false, "Sign in through the server's identity provider instead of an invite token")
+	teamCertRenewCmd.Flags().BoolVar(&certQuiet, "quiet", false, "Quiet mode (suppress output)")
+
+	teamEnvCmd.AddCommand(teamEnvListCmd)
+	teamCertCmd.AddCommand(teamCertRenewCmd)
+
+	teamCmd.AddCommand(teamJoinCmd)
+	teamCmd.AddCommand(teamEnvCmd)
+	teamCmd.AddCommand(teamSSHCmd)
+	teamCmd.AddCommand(teamCertCmd)
+}
+
+// runTeamClientCmd routes the team server subcommands of 'magebox team',
+// which parses no flags itself. It reports whether args named one.
+func runTeamClientCmd(args []string) (bool, error) {
+	switch args[0] {
+	case "join":
+		if err := teamJoinCmd.ParseFlags(args[1:]); err != nil {
+			return true, err
+		}
+		remainingArgs := teamJoinCmd.Flags().Args()
+		if len(remainingArgs) < 1 || len(remainingArgs) > 2 {
+			return true, fmt.Errorf("team join requires a server URL and an invite token or --sso")
+		}
+		return true, runTeamJoin(teamJoinCmd, remainingArgs)
+	case "env":
+		if len(args) < 2 || args[1] != "list" {
+			return true, teamEnvCmd.Help()
+		}
+		return true, runTeamEnvList(teamEnvListCmd, nil)
+	case "ssh":
+		if len(args) != 2 {
+			return true, fmt.Errorf("team ssh requires exactly one argument: <project>/<env>")
+		}
+		return true, runSSH(teamSSHCmd, args[1:])
+	case "cert":
+		if len(args) < 2 || args[1] != "renew" {
+			return true, teamCertCmd.Help()
+		}
+		if err := teamCertRenewCmd.ParseFlags(args[2:]); err != nil {
+			return true, err
+		}
+		return true, runCertRenew(teamCertRenewCmd, nil)
+	}
+	return false, nil
+}
+
+func runTeamJoin(cmd *cobra.Command, args []string) error {
+	if len(args) == 2 {
+		inviteToken = args[1]
+	}
+	if (inviteToken == "") == !joinSSO {
+		cli.PrintError("Pass either an invite token or --sso")
+		return nil
+	}
+	return runServerJoin(cmd, args[:1])
+}
+
+func runTeamEnvList(cmd *cobra.Command, args []string) error {
+	config, err := loadClientSession()
+	if err != nil {
+		return err
+	}
+
+	if err := fetchClientEnvironments(config); err != nil {
+		return err
+	}
+	if err := saveClientConfig(config); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := writeTeamSSHConfig(config); err != nil {
+		cli.PrintWarning("Failed to configure SSH: %v", err)
+	}
+
+	if len(config.Environments) == 0 {
+		cli.PrintInfo("You don't have access to any environments on %s", config.ServerURL)
+		return nil
+	}
+
+	maxName, maxConn := len("ENVIRONMENT"), len("CONNECTION")
+	for _, env := range config.Environments {
+		maxName = max(maxName, len(env.Name))
+		maxConn = max(maxConn, len(clientEnvConnection(env)))
+	}
+
+	fmt.Printf("  %-*s  %-*s  %s\n", maxName, "ENVIRONMENT", maxConn, "CONNECTION", "SSH HOST")
+	fmt.Printf("  %s  %s  %s\n", strings.Repeat("-", maxName), strings.Repeat("-", maxConn), strings.Repeat("-", 8))
+	for _, env := range config.Environments {
+		fmt.Printf("  %-*s  %-*s  %s\n", maxName, env.Name, maxConn, clientEnvConnection(env), teamSSHAlias(env.Name))
+	}
+
+	return nil
+}
+
+// findClientEnvironment returns the environment of a session named
+// project/env, or just env. Without the project, an environment of the given
+// project wins over same-named ones of other projects.
+func findClientEnvironment(config *clientConfig, name, project string) *clientEnvironmentConfig {
+	var match *clientEnvironmentConfig
+	for i, env := range config.Environments {
+		if env.Name == name {
+			return &config.Environments[i]
+		}
+		if _, envName, ok := strings.Cut(env.Name, "/"); ok && envName == name {
+			if env.Project == project {
+				return &config.Environments[i]
+			}
+			if match == nil {
+				match = &config.Environments[i]
+			}
+		}
+	}
+	return match
+}
+
+// clientEnvConnection returns user@host[:port] of an environment
+func clientEnvConnection(env clientEnvironmentConfig) string {
+	conn := env.DeployUser + "@" + env.Host
+	if env.Port != 0 && env.Port != 22 {
+		conn += ":" + strconv.Itoa(env.Port)
+	}
+	return conn
+}
+
+// fetchClientEnvironments replaces the cached environments of a session with
+// the ones the server says the user can access
+func fetchClientEnvironments(config *clientConfig) error {
+	req, err := http.NewRequest("GET", config.ServerURL+"/api/environments", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.SessionToken)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("session expired. Rejoin with: magebox team join")
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("failed to fetch environments: %s", errResp.Error)
+	}
+
+	var envs []teamserver.EnvironmentForUser
+	if err := json.NewDecoder(resp.Body).Decode(&envs); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	config.Environments = make([]clientEnvironmentConfig, 0, len(envs))
+	for _, env := range envs {
+		if err := teamserver.ValidateSSHTarget(env.Host, env.DeployUser); err != nil {
+			cli.PrintWarning("Skipping environment %s/%s: %v", env.Project, env.Name, err)
+			continue
+		}
+		config.Environments = append(config.Environments, clientEnvironmentConfig{
+			Name:       env.Name,
+			Project:    env.Project,
+			Host:       env.Host,
+			Port:       env.Port,
+			DeployUser: env.DeployUser,
+		})
+	}
+	return nil
+}
+
+// teamSSHAlias returns the SSH host of an environment, e.g.
+// magebox-myproject-staging for myproject/staging
+func teamSSHAlias(envName string) string {
+	var b strings.Builder
+	b.WriteString("magebox-")
+	for _, r := range strings.ToLower(envName) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// renderTeamSSHConfig returns an ssh_config with a host per environment
+func renderTeamSSHConfig(config *clientConfig) string {
+	var b strings.Builder
+	b.WriteString("# Generated by MageBox from your team server session, don't edit.\n")
+	b.WriteString("# Refresh with: magebox team env list\n")
+
+	keyPath := config.privateKeyPath()
+	for _, env := range config.Environments {
+		// A host or user that could inject ssh_config options is left out,
+		// the error quotes them
+		if err := teamserver.ValidateSSHTarget(env.Host, env.DeployUser); err != nil {
+			fmt.Fprintf(&b, "\n# Skipped %s: %s\n", teamSSHAlias(env.Name), err)
+			continue
+		}
+		port := env.Port
+		if port == 0 {
+			port = 22
+		}
+		fmt.Fprintf(&b, "\nHost %s\n", teamSSHAlias(env.Name))
+		fmt.Fprintf(&b, "    HostName %s\n", env.Host)
+		fmt.Fprintf(&b, "    Port %d\n", port)
+		fmt.Fprintf(&b, "    User %s\n", env.DeployUser)
+		if keyPath != "" {
+			// The private key is in ssh-agent, the public key selects it
+			fmt.Fprintf(&b, "    IdentityFile \"%s.pub\"\n", keyPath)
+			if config.CAEnabled {
+				fmt.Fprintf(&b, "    CertificateFile \"%s-cert.pub\"\n", keyPath)
+			}
+			b.WriteString("    IdentitiesOnly yes\n")
+		}
+	}
+	return b.String()
+}
+
+// writeTeamSSHConfig writes ~/.magebox/team/ssh_config for a session and
+// makes ~/.ssh/config include it
+func writeTeamSSHConfig(config *clientConfig) error {
+	clientDir, err := getClientDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(clientDir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(clientDir, "ssh_config")
+	if err := os.WriteFile(path, []byte(renderTeamSSHConfig(config)), 0600); err != nil {
+		return err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	return ensureSSHInclude(filepath.Join(homeDir, ".ssh", "config"), path)
+}
+
+// ensureSSHInclude adds an Include of path to the top of an SSH config. It
+// must come before the first Host block, or it only applies to that host.
+func ensureSSHInclude(sshConfigPath, path string) error {
+	content, err := os.ReadFile(sshConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	include := fmt.Sprintf("Include \"%s\"", path)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == include || line == "Include "+path {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sshConfigPath), 0700); err != nil {
+		return err
+	}
+	updated := "# Added by MageBox for team server environments\n" + include + "\n"
+	if len(content) > 0 {
+		updated += "\n" + string(content)
+	}
+	return os.WriteFile(sshConfigPath, []byte(updated), 0600)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTeamSSHAlias(t *testing.T) {
+	tests := map[string]string{
+		"shop/staging":     "magebox-shop-staging",
+		"My Shop/Prod":     "magebox-my-shop-prod",
+		"shop.eu/stage_01": "magebox-shop.eu-stage_01",
+	}
+	for name, want := range tests {
+		if got := teamSSHAlias(name); got != want {
+			t.Errorf("teamSSHAlias(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRenderTeamSSHConfig(t *testing.T) {
+	config := &clientConfig{
+		KeyFile:   "/home/alice/.magebox/team/keys/alice.key",
+		CAEnabled: true,
+		Environments: []clientEnvironmentConfig{
+			{Name: "shop/staging", Host: "staging.example.com", DeployUser: "deploy"},
+			{Name: "shop/production", Host: "10.0.0.5", Port: 2222, DeployUser: "magento"},
+		},
+	}
+
+	content := renderTeamSSHConfig(config)
+	for _, want := range []string{
+		"Host magebox-shop-staging\n    HostName staging.example.com\n    Port 22\n    User deploy\n",
+		"Host magebox-shop-production\n    HostName 10.0.0.5\n    Port 2222\n    User magento\n",
+		`IdentityFile "/home/alice/.magebox/team/keys/alice.key.pub"`,
+		`CertificateFile "/home/alice/.magebox/team/keys/alice.key-cert.pub"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("ssh_config is missing %q:\n%s", want, content)
+		}
+	}
+
+	config.CAEnabled = false
+	if strings.Contains(renderTeamSSHConfig(config), "CertificateFile") {
+		t.Error("CertificateFile set without SSH CA")
+	}
+}
+
+func TestRenderTeamSSHConfigSkipsInvalidTargets(t *testing.T) {
+	config := &clientConfig{
+		Environments: []clientEnvironmentConfig{
+			{Name: "shop/evil", Host: "example.com\n    ProxyCommand sh -c id", DeployUser: "deploy"},
+			{Name: "shop/user", Host: "example.com", DeployUser: "deploy ProxyCommand=id"},
+			{Name: "shop/staging", Host: "staging.example.com", DeployUser: "deploy"},
+		},
+	}
+
+	content := renderTeamSSHConfig(config)
+	if strings.Contains(content, "\n    ProxyCommand") || strings.Contains(content, "User deploy ProxyCommand") {
+		t.Errorf("ssh_config contains injected options:\n%s", content)
+	}
+	if strings.Contains(content, "Host magebox-shop-evil") || strings.Contains(content, "Host magebox-shop-user") {
+		t.Errorf("ssh_config should skip invalid environments:\n%s", content)
+	}
+	if !strings.Contains(content, "Host magebox-shop-staging\n") {
+		t.Errorf("ssh_config should keep valid environments:\n%s", content)
+	}
+}
+
+func TestEnsureSSHInclude(t *testing.T) {
+	dir := t.TempDir()
+	sshConfig := filepath.Join(dir, ".ssh", "config")
+	include := filepath.Join(dir, ".magebox", "team", "ssh_config")
+
+	// Creates the config when there's none
+	if err := ensureSSHInclude(sshConfig, include); err != nil {
+		t.Fatalf("ensureSSHInclude() error = %v", err)
+	}
+	content, _ := os.ReadFile(sshConfig)
+	if !strings.Contains(string(content), `Include "`+include+`"`) {
+		t.Fatalf("config = %q", content)
+	}
+
+	// Goes before existing hosts, and only once
+	existing := "Host example\n    User me\n"
+	if err := os.WriteFile(sshConfig, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := ensureSSHInclude(sshConfig, include); err != nil {
+			t.Fatalf("ensureSSHInclude() error = %v", err)
+		}
+	}
+	content, _ = os.ReadFile(sshConfig)
+	if strings.Count(string(content), "\nInclude ") != 1 {
+		t.Errorf("Include added more than once:\n%s", content)
+	}
+	if !strings.HasSuffix(string(content), existing) || strings.Index(string(content), "\nInclude ") > strings.Index(string(content), "Host example") {
+		t.Errorf("Include isn't above the existing hosts:\n%s", content)
+	}
+}
+
+func TestLoadClientConfigLegacyPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	legacy := filepath.Join(home, ".magebox", "teamserver", "client.json")
+	if err := os.MkdirAll(filepath.Dir(legacy), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte(`{"server_url":"https://team.example.com","key_path":"/old.key"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadClientConfig()
+	if err != nil {
+		t.Fatalf("loadClientConfig() error = %v", err)
+	}
+	if config.ServerURL != "https://team.example.com" || config.privateKeyPath() != "/old.key" {
+		t.Errorf("config = %+v", config)
+	}
+
+	// Saving moves the session to ~/.magebox/team
+	if err := saveClientConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".magebox", "team", "client.json")); err != nil {
+		t.Errorf("session not saved under ~/.magebox/team: %v", err)
+	}
+}
+
+func TestFindClientEnvironment(t *testing.T) {
+	config := &clientConfig{
+		Environments: []clientEnvironmentConfig{
+			{Name: "blog/staging", Project: "blog"},
+			{Name: "shop/staging", Project: "shop"},
+			{Name: "shop/production", Project: "shop"},
+		},
+	}
+
+	tests := []struct {
+		name, project, want string
+	}{
+		{"shop/production", "", "shop/production"},
+		{"staging", "", "blog/staging"},
+		{"staging", "shop", "shop/staging"},
+		{"production", "blog", "shop/production"},
+	}
+	for _, tt := range tests {
+		env := findClientEnvironment(config, tt.name, tt.project)
+		if env == nil || env.Name != tt.want {
+			t.Errorf("findClientEnvironment(%q, %q) = %v, want %s", tt.name, tt.project, env, tt.want)
+		}
+	}
+	if env := findClientEnvironment(config, "dev", ""); env != nil {
+		t.Errorf("findClientEnvironment(dev) = %v, want nil", env)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// credentialSSHKey is the key of the team SSH private key in the credential store
+const credentialSSHKey = "team/ssh_key"
+
+// saveTeamKey keeps the SSH private key of a session in the credential store
+// and writes only its public key, to keyPath.pub. A private key file left at
+// keyPath by older versions is removed.
+func saveTeamKey(keyPath, privateKey string) error {
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return fmt.Errorf("invalid SSH private key: %w", err)
+	}
+
+	store, err := clientCredentials()
+	if err != nil {
+		return err
+	}
+	if err := setCredential(store, credentialSSHKey, privateKey); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath+".pub", ssh.MarshalAuthorizedKey(signer.PublicKey()), 0644); err != nil {
+		return fmt.Errorf("failed to save SSH public key: %w", err)
+	}
+	if err := os.Remove(keyPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the old SSH key file: %w", err)
+	}
+	return nil
+}
+
+// teamPrivateKey returns the SSH private key of a session, moving a key file
+// written by older versions into the credential store
+func teamPrivateKey(config *clientConfig) (string, error) {
+	store, err := clientCredentials()
+	if err != nil {
+		return "", err
+	}
+	key, err := getCredential(store, credentialSSHKey)
+	if err != nil || key != "" {
+		return key, err
+	}
+
+	keyPath := config.privateKeyPath()
+	if keyPath == "" {
+		return "", fmt.Errorf("no SSH key configured. Rejoin the team server with: magebox server join")
+	}
+	data, err := os.ReadFile(keyPath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no SSH key found. Rejoin the team server with: magebox server join")
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := saveTeamKey(keyPath, string(data)); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// withTeamKeyFile writes the SSH private key, and the certificate when there
+// is one, to a private temporary directory for as long as fn runs
+func withTeamKeyFile(config *clientConfig, fn func(keyFile string) error) error {
+	key, err := teamPrivateKey(config)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "magebox-ssh-")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for the SSH key: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "id")
+	if err := os.WriteFile(keyFile, []byte(key), 0600); err != nil {
+		return fmt.Errorf("failed to write the SSH key: %w", err)
+	}
+	if cert, err := os.ReadFile(config.privateKeyPath() + "-cert.pub"); err == nil {
+		if err := os.WriteFile(keyFile+"-cert.pub", cert, 0644); err != nil {
+			return fmt.Errorf("failed to write the SSH certificate: %w", err)
+		}
+	}
+
+	return fn(keyFile)
+}
+
+// addTeamKeyToAgent loads the SSH private key of a session into the running
+// ssh-agent, which plain ssh uses for the hosts of the team ssh_config
+func addTeamKeyToAgent(config *clientConfig) error {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return fmt.Errorf("no ssh-agent is running")
+	}
+
+	key, err := teamPrivateKey(config)
+	if err != nil {
+		return err
+	}
+	rawKey, err := ssh.ParseRawPrivateKey([]byte(key))
+	if err != nil {
+		return fmt.Errorf("invalid SSH private key: %w", err)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to reach ssh-agent: %w", err)
+	}
+	defer conn.Close()
+
+	return agent.NewClient(conn).Add(agent.AddedKey{
+		PrivateKey: rawKey,
+		Comment:    "magebox team " + config.UserName,
+	})
+}
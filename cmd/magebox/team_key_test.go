@@ -0,0 +1,136 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh/agent"
+
+	"qoliber/magebox/internal/teamserver"
+)
+
+func TestTeamPrivateKeyMovesKeyFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	keyPair, err := teamserver.GenerateSSHKeyPair("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(home, ".magebox", "team", "keys", "alice.key")
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, []byte(keyPair.PrivateKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &clientConfig{KeyFile: keyPath}
+	key, err := teamPrivateKey(config)
+	if err != nil || key != keyPair.PrivateKey {
+		t.Fatalf("teamPrivateKey() = %q, %v", key, err)
+	}
+	if _, err := os.Stat(keyPath); !os.IsNotExist(err) {
+		t.Error("the private key file should be removed once the key is in the credential store")
+	}
+	if _, err := os.Stat(keyPath + ".pub"); err != nil {
+		t.Errorf("public key not written: %v", err)
+	}
+
+	// Later reads come from the credential store
+	if key, err := teamPrivateKey(config); err != nil || key != keyPair.PrivateKey {
+		t.Errorf("teamPrivateKey() = %q, %v after the move", key, err)
+	}
+}
+
+func TestWithTeamKeyFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	keyPair, err := teamserver.GenerateSSHKeyPair("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(home, ".magebox", "team", "keys", "alice.key")
+	if err := saveTeamKey(keyPath, keyPair.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath+"-cert.pub", []byte("cert\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var written string
+	err = withTeamKeyFile(&clientConfig{KeyFile: keyPath}, func(keyFile string) error {
+		written = keyFile
+		data, err := os.ReadFile(keyFile)
+		if err != nil || string(data) != keyPair.PrivateKey {
+			t.Errorf("key file = %q, %v", data, err)
+		}
+		if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("key file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+		}
+		if _, err := os.Stat(keyFile + "-cert.pub"); err != nil {
+			t.Errorf("certificate not next to the key file: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withTeamKeyFile() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(written)); !os.IsNotExist(err) {
+		t.Error("the temporary key directory should be removed")
+	}
+}
+
+func TestAddTeamKeyToAgent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	keyPair, err := teamserver.GenerateSSHKeyPair("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(home, ".magebox", "team", "keys", "alice.key")
+	if err := saveTeamKey(keyPath, keyPair.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(home, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if err := addTeamKeyToAgent(&clientConfig{KeyFile: keyPath}); err == nil {
+		t.Error("expected an error without ssh-agent")
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	if err := addTeamKeyToAgent(&clientConfig{KeyFile: keyPath, UserName: "alice"}); err != nil {
+		t.Fatalf("addTeamKeyToAgent() error = %v", err)
+	}
+	keys, err := keyring.List()
+	if err != nil || len(keys) != 1 || keys[0].Comment != "magebox team alice" {
+		t.Errorf("agent keys = %v, %v", keys, err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/teamserver"
+)
+
+var teamPullConfigCmd = &cobra.Command{
+	Use:   "pull-config <project>",
+	Short: "Fetch the project's shared .magebox.yaml from the team server",
+	Long: `Fetches the canonical .magebox.yaml of a project from the team server and
+writes it to the current directory.
+
+An existing .magebox.yaml that differs is kept as .magebox.yaml.bak.
+.magebox.local.yaml is never touched, so keep personal overrides (PHP
+version, php_ini, env, ...) there and they survive every pull.
+
+Admins store the config with 'magebox server project push-config'.
+
+Examples:
+  magebox team pull-config myproject
+  magebox team pull-config myproject --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTeamPullConfig,
+}
+
+var teamPullConfigDryRun bool
+
+func init() {
+	teamPullConfigCmd.Flags().BoolVar(&teamPullConfigDryRun, "dry-run", false, "Print the config instead of writing it")
+	teamCmd.AddCommand(teamPullConfigCmd)
+}
+
+func runTeamPullConfig(cmd *cobra.Command, args []string) error {
+	projectName := args[0]
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	clientCfg, err := loadClientSession()
+	if err != nil {
+		return err
+	}
+
+	projectConfig, err := fetchProjectConfig(clientCfg, projectName)
+	if err != nil {
+		return err
+	}
+
+	if teamPullConfigDryRun {
+		fmt.Print(projectConfig.Content)
+		return nil
+	}
+
+	configPath := filepath.Join(cwd, config.ConfigFileName)
+	current, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", config.ConfigFileName, err)
+	}
+	if err == nil && string(current) == projectConfig.Content {
+		cli.PrintSuccess("%s is up to date with project '%s'", config.ConfigFileName, projectName)
+		return nil
+	}
+
+	if err == nil {
+		backupPath := configPath + ".bak"
+		if err := os.WriteFile(backupPath, current, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", config.ConfigFileName, err)
+		}
+		cli.PrintInfo("Previous config saved to %s", filepath.Base(backupPath))
+	}
+
+	if err := os.WriteFile(configPath, []byte(projectConfig.Content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", config.ConfigFileName, err)
+	}
+
+	updated := projectConfig.UpdatedAt.Format("2006-01-02 15:04")
+	if projectConfig.UpdatedBy != "" {
+		updated += " by " + projectConfig.UpdatedBy
+	}
+	cli.PrintSuccess("Wrote %s for project '%s' (updated %s)", config.ConfigFileName, projectName, updated)
+
+	if _, err := os.Stat(filepath.Join(cwd, config.LocalConfigFileName)); err == nil {
+		fmt.Printf("Local overrides in %s are kept\n", config.LocalConfigFileName)
+	}
+
+	// The loader merges the local overrides, so this also catches overrides
+	// that no longer fit the shared config
+	if _, err := config.LoadFromPath(cwd); err != nil {
+		cli.PrintWarning("The project config does not load: %v", err)
+	}
+
+	return nil
+}
+
+// fetchProjectConfig fetches the canonical .magebox.yaml of a project
+func fetchProjectConfig(clientCfg *clientConfig, projectName string) (*teamserver.ProjectConfig, error) {
+	req, err := http.NewRequest("GET", clientCfg.ServerURL+"/api/projects/"+url.PathEscape(projectName)+"/config", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+clientCfg.SessionToken)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("session expired. Rejoin with: magebox server join")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp teamserver.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return nil, fmt.Errorf("failed to fetch config of project '%s': %s", projectName, errResp.Error)
+	}
+
+	var projectConfig teamserver.ProjectConfig
+	if err := json.NewDecoder(resp.Body).Decode(&projectConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &projectConfig, nil
+}
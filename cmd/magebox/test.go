@@ -58,7 +58,7 @@ var testSetupCmd = &cobra.Command{
 
 Available options:
   unit    - Install PHPUnit for unit testing
-  static  - Install static analysis tools (PHPStan, PHPCS, PHPMD)
+  static  - Install static analysis tools (PHPStan, PHPCS, PHPMD, PHP-CS-Fixer)
   all     - Install all testing tools (default)
 
 Examples:
@@ -248,6 +248,10 @@ func runTestSetup(cmd *cobra.Command, args []string) {
 				cli.PrintError("%v", err)
 				return
 			}
+			if err := installer.InstallPHPCSFixer(); err != nil {
+				cli.PrintError("%v", err)
+				return
+			}
 			cli.PrintSuccess("Static analysis tools installed successfully")
 			return
 
@@ -286,9 +290,13 @@ func runTestSetup(cmd *cobra.Command, args []string) {
 	fmt.Print("  [4] PHP Mess Detector [Y/n]: ")
 	opts.InstallPHPMD = askYesNo(true)
 
+	// PHP-CS-Fixer
+	fmt.Print("  [5] PHP-CS-Fixer (code style fixer) [y/N]: ")
+	opts.InstallPHPCSFixer = askYesNo(false)
+
 	fmt.Println()
 
-	if !opts.InstallPHPUnit && !opts.InstallPHPStan && !opts.InstallPHPCS && !opts.InstallPHPMD {
+	if !opts.InstallPHPUnit && !opts.InstallPHPStan && !opts.InstallPHPCS && !opts.InstallPHPMD && !opts.InstallPHPCSFixer {
 		cli.PrintWarning("No tools selected for installation")
 		return
 	}
@@ -315,6 +323,9 @@ func runTestSetup(cmd *cobra.Command, args []string) {
 	if opts.InstallPHPMD {
 		fmt.Printf("  %s - Run mess detection\n", cli.Command("magebox test phpmd"))
 	}
+	if opts.InstallPHPCSFixer {
+		fmt.Printf("  %s - Check code style with PHP-CS-Fixer\n", cli.Command("magebox lint cs-fixer"))
+	}
 	fmt.Printf("  %s - Run all (except integration)\n", cli.Command("magebox test all"))
 }
 
@@ -578,6 +589,7 @@ func runTestStatus(cmd *cobra.Command, args []string) {
 	printToolStatus("PHPStan", &status.PHPStan)
 	printToolStatus("PHP_CodeSniffer", &status.PHPCS)
 	printToolStatus("PHP Mess Detector", &status.PHPMD)
+	printToolStatus("PHP-CS-Fixer", &status.PHPCSFixer)
 
 	fmt.Println()
 	fmt.Println("Run", cli.Command("magebox test setup"), "to install missing tools")
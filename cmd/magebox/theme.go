@@ -0,0 +1,278 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/frontend"
+)
+
+var themeCmd = &cobra.Command{
+	Use:   "theme",
+	Short: "Build and watch frontend themes",
+	Long: `Lists the themes under app/design/frontend and runs their watchers with the
+project's Node.js.
+
+The watcher follows the build setup of the theme:
+  hyva   npm run watch in web/tailwind (Hyvä's Tailwind build)
+  vite   npm run dev, a Vite dev server (headless and Vite based themes)
+  npm    npm run watch of a package.json anywhere in the theme
+  grunt  grunt exec, less and watch of Magento's Gruntfile (Luma based themes)`,
+}
+
+var themeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the themes and their watchers",
+	RunE:  runThemeList,
+}
+
+var themeWatchCmd = &cobra.Command{
+	Use:   "watch [Vendor/theme]",
+	Short: "Run the watcher of a theme",
+	Long: `Runs the watcher of a theme in the foreground with the project's Node.js
+first on PATH. Without a theme, frontend.theme of .magebox.yaml is watched,
+or the only theme with a watcher; you are asked when there are several.
+
+The watcher gets NODE_ENV=development, PROXY_URL (the URL of the project's
+first domain, used by Hyvä's browser-sync config) and the project's env: vars.
+A Vite dev server is started on frontend.dev_server.port, which nginx proxies
+on every domain of the project. Missing npm dependencies are installed first.
+
+Examples:
+  magebox theme watch
+  magebox theme watch Acme/hyva`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runThemeWatch,
+}
+
+func init() {
+	themeCmd.AddCommand(themeListCmd)
+	themeCmd.AddCommand(themeWatchCmd)
+	rootCmd.AddCommand(themeCmd)
+}
+
+func runThemeList(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	themes := frontend.FindThemes(cwd)
+	if len(themes) == 0 {
+		cli.PrintInfo("No themes found in app/design/frontend")
+		return nil
+	}
+
+	cli.PrintTitle("Themes: %s", cfg.Name)
+	fmt.Println()
+	for _, t := range themes {
+		name := t.Name
+		if cfg.Frontend != nil && strings.EqualFold(cfg.Frontend.Theme, t.Name) {
+			name += " " + cli.Dim + "(default)" + cli.Reset
+		}
+		if t.Watcher == "" {
+			fmt.Printf("  %-30s %s\n", name, cli.Dim+"no build setup"+cli.Reset)
+			continue
+		}
+		relDir, err := filepath.Rel(cwd, t.WorkDir)
+		if err != nil {
+			relDir = t.WorkDir
+		}
+		fmt.Printf("  %-30s %-6s %s\n", name, t.Watcher, cli.Path(relDir))
+	}
+	return nil
+}
+
+func runThemeWatch(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	theme, err := watchTheme(cfg, cwd, args)
+	if err != nil {
+		return err
+	}
+	if theme == nil {
+		return nil
+	}
+	if theme.Watcher == "" {
+		cli.PrintError("%s has no build setup to watch", theme.Name)
+		cli.PrintInfo("Expected web/tailwind/package.json (Hyvä), a vite.config.js, a package.json with a watch script or Magento's Gruntfile.js")
+		return nil
+	}
+
+	node, err := projectNode(cfg, cwd)
+	if err != nil {
+		cli.PrintError("%v", err)
+		cli.PrintInfo("Install it with: %s", cli.Command("magebox node install"))
+		return nil
+	}
+	if node == nil {
+		cli.PrintError("Node.js is not installed")
+		return nil
+	}
+
+	devServerPort := 0
+	if ds := cfg.DevServer(); ds != nil {
+		devServerPort = ds.Port
+	}
+	env := themeWatchEnv(cfg, node)
+	watchArgs := theme.WatchArgs(devServerPort)
+
+	relDir, err := filepath.Rel(cwd, theme.WorkDir)
+	if err != nil {
+		relDir = theme.WorkDir
+	}
+	cli.PrintInfo("Theme: %s (%s)", cli.Highlight(theme.Name), theme.Watcher)
+	cli.PrintInfo("Node.js: %s (%s)", node.Version, node.Manager)
+	if theme.Watcher == frontend.WatcherVite && devServerPort > 0 {
+		cli.PrintInfo("Dev server: %s", cli.URL(cfg.DevServer().URL()))
+	}
+	if theme.Watcher == frontend.WatcherGrunt {
+		checkGruntTheme(cwd, theme)
+	}
+
+	if theme.NeedsInstall() {
+		install := []string{"npm", "install"}
+		if _, err := os.Stat(filepath.Join(theme.WorkDir, "package-lock.json")); err == nil {
+			install = []string{"npm", "ci"}
+		}
+		cli.PrintInfo("Installing npm dependencies in %s...", cli.Path(relDir))
+		if err := runThemeCommand(theme.WorkDir, env, install); err != nil {
+			return fmt.Errorf("failed to install npm dependencies: %w", err)
+		}
+	}
+
+	cli.PrintInfo("Running %s in %s", cli.Command(strings.Join(watchArgs, " ")), cli.Path(relDir))
+	fmt.Println()
+
+	if err := runThemeCommand(theme.WorkDir, env, watchArgs); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// watchTheme returns the theme to watch: the one given, frontend.theme, the
+// only theme with a watcher, or the one picked from several. It returns nil
+// when there is none, after telling the user.
+func watchTheme(cfg *config.Config, cwd string, args []string) (*frontend.Theme, error) {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else if cfg.Frontend != nil {
+		name = cfg.Frontend.Theme
+	}
+	if name != "" {
+		theme, err := frontend.FindTheme(cwd, name)
+		if err != nil {
+			cli.PrintError("%v", err)
+			return nil, nil
+		}
+		return theme, nil
+	}
+
+	themes := frontend.WatchableThemes(cwd)
+	switch len(themes) {
+	case 0:
+		cli.PrintError("No theme with a build setup found in app/design/frontend")
+		return nil, nil
+	case 1:
+		return &themes[0], nil
+	}
+
+	options := make([]huh.Option[int], 0, len(themes))
+	for i, t := range themes {
+		options = append(options, huh.NewOption(fmt.Sprintf("%s (%s)", t.Name, t.Watcher), i))
+	}
+	var selected int
+	err := huh.NewSelect[int]().
+		Title("Which theme should be watched?").
+		Options(options...).
+		Value(&selected).
+		Run()
+	if err != nil {
+		return nil, err
+	}
+	return &themes[selected], nil
+}
+
+// themeWatchEnv returns the environment of a theme watcher: the project's
+// Node.js first on PATH, development mode, the project URL and its env vars
+func themeWatchEnv(cfg *config.Config, node *frontend.Node) []string {
+	env := append(node.Env(os.Environ()), "NODE_ENV=development")
+	if len(cfg.Domains) > 0 {
+		domain := cfg.Domains[0]
+		scheme := "https"
+		if !domain.IsSSLEnabled() {
+			scheme = "http"
+		}
+		env = append(env, fmt.Sprintf("PROXY_URL=%s://%s", scheme, domain.Host))
+	}
+	for key, value := range cfg.Env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// runThemeCommand runs a watcher or npm command in the foreground. npm and
+// npx come from the bin directory first on the PATH of env.
+func runThemeCommand(dir string, env, args []string) error {
+	bin := args[0]
+	for _, kv := range env {
+		if path, ok := strings.CutPrefix(kv, "PATH="); ok {
+			candidate := filepath.Join(strings.Split(path, string(os.PathListSeparator))[0], bin)
+			if _, err := os.Stat(candidate); err == nil {
+				bin = candidate
+			}
+		}
+	}
+
+	run := exec.Command(bin, args[1:]...)
+	run.Dir = dir
+	run.Env = env
+	run.Stdin = os.Stdin
+	run.Stdout = os.Stdout
+	run.Stderr = os.Stderr
+	return run.Run()
+}
+
+// checkGruntTheme warns when a Luma based theme is not registered in
+// Magento's Grunt theme list, which its grunt tasks need
+func checkGruntTheme(cwd string, theme *frontend.Theme) {
+	for _, name := range []string{"local-themes.js", "themes.js"} {
+		data, err := os.ReadFile(filepath.Join(cwd, "dev", "tools", "grunt", "configs", name))
+		if err != nil {
+			continue
+		}
+		if strings.Contains(string(data), theme.GruntKey()+":") || strings.Contains(string(data), "'"+theme.Name+"'") {
+			return
+		}
+	}
+	cli.PrintWarning("%s may not be registered in dev/tools/grunt/configs/local-themes.js", theme.Name)
+	cli.PrintInfo("Add it under the key %s, see https://developer.adobe.com/commerce/frontend-core/guide/css/preprocess/css-preprocess/", cli.Highlight(theme.GruntKey()))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/frontend"
+)
+
+func TestThemeWatchEnv(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	ssl := false
+	cfg := &config.Config{
+		Domains: []config.Domain{{Host: "mystore.test", SSL: &ssl}, {Host: "b2b.mystore.test"}},
+		Env:     map[string]string{"HYVA_THEME": "Acme/hyva"},
+	}
+	node := &frontend.Node{BinDir: "/home/me/.nvm/versions/node/v20.11.1/bin"}
+
+	env := themeWatchEnv(cfg, node)
+	for _, want := range []string{
+		"PATH=/home/me/.nvm/versions/node/v20.11.1/bin:/usr/bin",
+		"NODE_ENV=development",
+		"PROXY_URL=http://mystore.test",
+		"HYVA_THEME=Acme/hyva",
+	} {
+		if !slices.Contains(env, want) {
+			t.Errorf("env misses %s", want)
+		}
+	}
+}
+
+func TestWatchTheme_Configured(t *testing.T) {
+	dir := t.TempDir()
+	for _, theme := range []string{"Acme/hyva", "Acme/other"} {
+		pkg := filepath.Join(dir, "app", "design", "frontend", theme, "web", "tailwind", "package.json")
+		if err := os.MkdirAll(filepath.Dir(pkg), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(pkg, []byte(`{"scripts": {"watch": "tailwindcss --watch"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// frontend.theme picks a theme without asking
+	cfg := &config.Config{Frontend: &config.FrontendConfig{Theme: "Acme/other"}}
+	theme, err := watchTheme(cfg, dir, nil)
+	if err != nil || theme == nil || theme.Name != "Acme/other" {
+		t.Fatalf("watchTheme() = %+v, %v", theme, err)
+	}
+
+	// and an argument wins over it
+	theme, err = watchTheme(cfg, dir, []string{"Acme/hyva"})
+	if err != nil || theme == nil || theme.Name != "Acme/hyva" {
+		t.Errorf("watchTheme(Acme/hyva) = %+v, %v", theme, err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/tui"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Open the interactive terminal dashboard",
+	Long: `Opens a full-screen dashboard with all MageBox projects, the services of the
+selected project and the tail of its Magento logs.
+
+Keys:
+  ↑/↓ (or k/j)  Select project
+  s             Start project
+  x             Stop project
+  r             Restart project
+  f             Flush Magento cache (bin/magento cache:flush)
+  i             Reindex (bin/magento indexer:reindex)
+  l             Switch between system.log, exception.log and debug.log
+  R             Refresh project list
+  q             Quit
+
+Actions take over the terminal while they run, so their output and any
+password prompts are shown as usual.`,
+	RunE: runUI,
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
+
+func runUI(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	return tui.Run(&uiSource{platform: p}, uiActions(self, filepath.Join(p.MageBoxDir(), "bin", "php")))
+}
+
+// uiActions returns the dashboard shortcuts. MageBox commands run through the
+// current binary, Magento commands through the PHP wrapper so the project's
+// PHP version is used.
+func uiActions(magebox, phpWrapper string) []tui.Action {
+	mageboxCmd := func(args ...string) func(tui.Project) *exec.Cmd {
+		return func(tui.Project) *exec.Cmd { return exec.Command(magebox, args...) }
+	}
+	magentoCmd := func(args ...string) func(tui.Project) *exec.Cmd {
+		return func(tui.Project) *exec.Cmd {
+			return exec.Command(phpWrapper, append([]string{"bin/magento"}, args...)...)
+		}
+	}
+
+	return []tui.Action{
+		{Key: "s", Label: "Start", Command: mageboxCmd("start")},
+		{Key: "x", Label: "Stop", Command: mageboxCmd("stop")},
+		{Key: "r", Label: "Restart", Command: mageboxCmd("restart")},
+		{Key: "f", Label: "Flush", Command: magentoCmd("cache:flush")},
+		{Key: "i", Label: "Reindex", Command: magentoCmd("indexer:reindex")},
+	}
+}
+
+// uiSource feeds the dashboard from project discovery and project status
+type uiSource struct {
+	platform *platform.Platform
+}
+
+func (s *uiSource) Projects() ([]tui.Project, error) {
+	infos, err := project.NewProjectDiscovery(s.platform).DiscoverProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]tui.Project, 0, len(infos))
+	for _, info := range infos {
+		projects = append(projects, tui.Project{
+			Name:       info.Name,
+			Path:       info.Path,
+			PHPVersion: info.PHPVersion,
+			Domains:    info.Domains,
+			HasConfig:  info.HasConfig,
+		})
+	}
+	return projects, nil
+}
+
+func (s *uiSource) Services(projectPath string) ([]tui.Service, error) {
+	status, err := project.NewManager(s.platform).Status(projectPath)
+	if err != nil {
+		return []tui.Service{}, err
+	}
+
+	services := make([]tui.Service, 0, len(status.Services))
+	for _, svc := range status.Services {
+		services = append(services, tui.Service{Name: svc.Name, Running: svc.IsRunning})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
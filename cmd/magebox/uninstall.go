@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -17,6 +18,7 @@ import (
 	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/portforward"
 	"qoliber/magebox/internal/project"
+	"qoliber/magebox/internal/ssl"
 )
 
 var (
@@ -24,6 +26,8 @@ var (
 	uninstallKeepVhosts    bool
 	uninstallDryRun        bool
 	uninstallPurgePackages bool
+	uninstallPurge         bool
+	uninstallRemoveCA      bool
 )
 
 var uninstallCmd = &cobra.Command{
@@ -35,10 +39,17 @@ var uninstallCmd = &cobra.Command{
   - Removes CLI wrappers (php, composer, blackfire)
   - Removes nginx vhosts (unless --keep-vhosts)
   - Removes port forwarding rules (macOS)
-  - Stops and disables dnsmasq
+  - Stops and disables dnsmasq and removes resolver configuration
+
+Use --purge for a full clean removal, which additionally removes Docker
+volumes, PHP-FPM pools, Varnish VCL, /etc/hosts entries, the sudoers entry
+and the ~/.magebox state directory. Use --remove-ca to also remove the
+local certificate authority from the system trust stores.
 
 Note: This does not uninstall system packages (PHP, nginx, etc.)
-Use --purge-packages to also remove system packages.`,
+Use --purge-packages to also remove system packages.
+
+Anything MageBox could not remove is listed at the end for manual review.`,
 	RunE: runUninstall,
 }
 
@@ -47,6 +58,8 @@ func init() {
 	uninstallCmd.Flags().BoolVar(&uninstallKeepVhosts, "keep-vhosts", false, "Keep nginx vhost configurations")
 	uninstallCmd.Flags().BoolVar(&uninstallDryRun, "dry-run", false, "Show what would be removed without removing")
 	uninstallCmd.Flags().BoolVar(&uninstallPurgePackages, "purge-packages", false, "Also remove system packages (PHP, nginx, dnsmasq)")
+	uninstallCmd.Flags().BoolVar(&uninstallPurge, "purge", false, "Remove all MageBox state: volumes, pools, VCL, hosts entries, sudoers and ~/.magebox")
+	uninstallCmd.Flags().BoolVar(&uninstallRemoveCA, "remove-ca", false, "Also remove the local certificate authority (mkcert -uninstall)")
 	rootCmd.AddCommand(uninstallCmd)
 }
 
@@ -56,8 +69,9 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	plan := newUninstallPlan(p, uninstallPurge, uninstallRemoveCA)
 	if uninstallDryRun {
-		return uninstallDryRunFunc(p)
+		return uninstallDryRunFunc(p, plan)
 	}
 
 	cli.PrintTitle("MageBox Uninstall")
@@ -75,6 +89,21 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		fmt.Println(cli.Bullet("Remove port forwarding rules (pf)"))
 	}
 	fmt.Println(cli.Bullet("Stop and disable dnsmasq"))
+	if plan.RemoveVolumes {
+		fmt.Println(cli.Bullet("Remove Docker volumes (all service data, including databases)"))
+	}
+	if plan.RemoveHosts {
+		fmt.Println(cli.Bullet("Remove MageBox entries from " + p.HostsFilePath()))
+	}
+	for _, path := range plan.SudoRemove {
+		fmt.Println(cli.Bullet("Remove " + path))
+	}
+	for _, path := range plan.RemovePaths {
+		fmt.Println(cli.Bullet("Remove " + path + " (pools, VCL, compose files, certificates, logs)"))
+	}
+	if plan.RemoveCA {
+		fmt.Println(cli.Bullet("Remove the local certificate authority from system trust stores"))
+	}
 	fmt.Println()
 
 	if uninstallPurgePackages {
@@ -88,7 +117,9 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println(cli.Bullet("Docker images"))
 	fmt.Println(cli.Bullet("Project files or .magebox.yaml configs"))
-	fmt.Println(cli.Bullet("SSL certificates"))
+	if !uninstallPurge {
+		fmt.Println(cli.Bullet("SSL certificates, Docker volumes and ~/.magebox — use --purge"))
+	}
 	fmt.Println()
 
 	// Confirm unless --force
@@ -139,7 +170,12 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 	if _, err := os.Stat(composeFile); err == nil {
 		dockerCtrl := docker.NewDockerController(composeFile)
 		fmt.Print("  Stopping MageBox containers... ")
-		if err := dockerCtrl.Down(); err != nil {
+		down := dockerCtrl.Down
+		if plan.RemoveVolumes {
+			fmt.Print("and removing volumes... ")
+			down = dockerCtrl.DownWithVolumes
+		}
+		if err := down(); err != nil {
 			fmt.Println(cli.Warning("failed"))
 			cli.PrintWarning("    %v", err)
 		} else {
@@ -316,24 +352,173 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	// Step 8: Purge MageBox state (if requested)
+	if uninstallPurge {
+		fmt.Println(cli.Header("Step 8: Purging MageBox state"))
+
+		if plan.RemoveHosts {
+			hostsMgr := dns.NewHostsManager(p)
+			if domains, err := hostsMgr.ListDomains(); err == nil && len(domains) > 0 {
+				fmt.Printf("  Removing %d hosts entries... ", len(domains))
+				if err := hostsMgr.RemoveAllDomains(); err != nil {
+					fmt.Println(cli.Warning("failed"))
+					cli.PrintWarning("    %v", err)
+				} else {
+					fmt.Println(cli.Success("done"))
+				}
+			} else {
+				fmt.Println("  No hosts entries to remove")
+			}
+		}
+
+		for _, path := range plan.SudoRemove {
+			if _, err := os.Stat(path); err == nil {
+				fmt.Printf("  Removing %s... ", path)
+				if err := exec.Command("sudo", "rm", "-f", path).Run(); err != nil {
+					fmt.Println(cli.Warning("failed"))
+					cli.PrintWarning("    %v", err)
+				} else {
+					fmt.Println(cli.Success("done"))
+				}
+			}
+		}
+
+		for _, path := range plan.RemovePaths {
+			fmt.Printf("  Removing %s... ", path)
+			if err := os.RemoveAll(path); err != nil {
+				fmt.Println(cli.Warning("failed"))
+				cli.PrintWarning("    %v", err)
+			} else {
+				fmt.Println(cli.Success("done"))
+			}
+		}
+		fmt.Println()
+	}
+
+	// Step 9: Remove the local CA (if requested)
+	if plan.RemoveCA {
+		fmt.Println(cli.Header("Step 9: Removing local certificate authority"))
+
+		sslMgr := ssl.NewManager(p)
+		fmt.Print("  Running mkcert -uninstall... ")
+		if err := sslMgr.UninstallCA(); err != nil {
+			fmt.Println(cli.Warning("failed"))
+			cli.PrintWarning("    %v", err)
+		} else {
+			fmt.Println(cli.Success("done"))
+		}
+		fmt.Println()
+	}
+
 	cli.PrintSuccess("MageBox components removed")
-	fmt.Println()
-	fmt.Println("To completely remove MageBox:")
-	fmt.Println(cli.Bullet("Remove the magebox binary from your PATH"))
-	fmt.Println(cli.Bullet("Remove ~/.magebox directory: " + cli.Command("rm -rf ~/.magebox")))
+
+	leftovers := uninstallLeftovers(p, readUninstallState(p), plan.RemoveCA)
+	if len(leftovers) > 0 {
+		fmt.Println()
+		fmt.Println("Left for manual review:")
+		for _, item := range leftovers {
+			fmt.Println(cli.Bullet(item))
+		}
+	}
 	fmt.Println(cli.Bullet("Remove PATH entry from ~/.zshrc or ~/.bashrc"))
 
 	return nil
 }
 
-func uninstallDryRunFunc(p interface {
-	MageBoxDir() string
-}) error {
+// sudoersFilePath is the sudoers drop-in written by bootstrap on Linux
+const sudoersFilePath = "/etc/sudoers.d/magebox"
+
+// uninstallPlan is the state --purge and --remove-ca remove on top of the
+// default uninstall steps
+type uninstallPlan struct {
+	RemoveVolumes bool     // Docker volumes of the MageBox services
+	RemoveHosts   bool     // MageBox entries in the hosts file
+	SudoRemove    []string // Files removed with sudo
+	RemovePaths   []string // Directories removed with everything in them
+	RemoveCA      bool     // mkcert local CA in the system trust stores
+}
+
+// newUninstallPlan returns what an uninstall with the flags removes, only
+// MageBox's own files are planned for removal
+func newUninstallPlan(p *platform.Platform, purge, removeCA bool) uninstallPlan {
+	plan := uninstallPlan{RemoveCA: removeCA}
+	if !purge {
+		return plan
+	}
+	plan.RemoveVolumes = true
+	plan.RemoveHosts = true
+	if p.Type == platform.Linux {
+		plan.SudoRemove = append(plan.SudoRemove, sudoersFilePath)
+	}
+	plan.RemovePaths = append(plan.RemovePaths, p.MageBoxDir())
+	return plan
+}
+
+// uninstallState is what uninstallLeftovers checks for after uninstall
+type uninstallState struct {
+	Binary       string            // Path of the running magebox binary
+	HostsEntries int               // MageBox entries left in the hosts file
+	DNSPaths     []string          // dnsmasq and resolver configuration files
+	CARoot       string            // mkcert CA directory, empty without a CA
+	Exists       func(string) bool // Reports whether a path exists
+}
+
+// readUninstallState reads the state of the machine uninstallLeftovers checks
+func readUninstallState(p *platform.Platform) uninstallState {
+	state := uninstallState{
+		Exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+	}
+	if binary, err := os.Executable(); err == nil {
+		state.Binary = binary
+	}
+	if domains, err := dns.NewHostsManager(p).ListDomains(); err == nil {
+		state.HostsEntries = len(domains)
+	}
+	dnsManager := dns.NewDnsmasqManager(p)
+	state.DNSPaths = append([]string{dnsManager.ConfigPath()}, dnsManager.ResolverConfigPaths()...)
+	if caRoot, err := ssl.NewManager(p).CARoot(); err == nil {
+		state.CARoot = caRoot
+	}
+	return state
+}
+
+// uninstallLeftovers lists MageBox artifacts that still exist after uninstall
+func uninstallLeftovers(p *platform.Platform, state uninstallState, removedCA bool) []string {
+	var leftovers []string
+
+	if state.Binary != "" {
+		leftovers = append(leftovers, "magebox binary: "+state.Binary)
+	}
+	if state.Exists(p.MageBoxDir()) {
+		leftovers = append(leftovers, "State directory: "+p.MageBoxDir()+" (use --purge)")
+	}
+	if state.HostsEntries > 0 {
+		leftovers = append(leftovers, fmt.Sprintf("%d MageBox entries in %s", state.HostsEntries, p.HostsFilePath()))
+	}
+	if p.Type == platform.Linux && state.Exists(sudoersFilePath) {
+		leftovers = append(leftovers, "Sudoers entry: "+sudoersFilePath)
+	}
+	for _, path := range state.DNSPaths {
+		if state.Exists(path) {
+			leftovers = append(leftovers, "DNS configuration: "+path)
+		}
+	}
+	if !removedCA && state.CARoot != "" {
+		leftovers = append(leftovers, "Local CA: "+state.CARoot+" (use --remove-ca)")
+	}
+	leftovers = append(leftovers, "Docker images: "+cli.Command("docker images | grep -E 'mysql|mariadb|opensearch|redis|valkey|varnish|mailpit'"))
+
+	return leftovers
+}
+
+func uninstallDryRunFunc(plat *platform.Platform, plan uninstallPlan) error {
 	cli.PrintTitle("Dry Run: Would uninstall")
 	fmt.Println()
 
 	// Check projects
-	plat, _ := getPlatform()
 	discovery := project.NewProjectDiscovery(plat)
 	projects, _ := discovery.DiscoverProjects()
 
@@ -370,7 +555,7 @@ func uninstallDryRunFunc(p interface {
 	// Check vhosts
 	if !uninstallKeepVhosts {
 		fmt.Println(cli.Header("Vhost files that would be removed"))
-		vhostsDir := p.MageBoxDir() + "/nginx/vhosts"
+		vhostsDir := plat.MageBoxDir() + "/nginx/vhosts"
 		if entries, err := os.ReadDir(vhostsDir); err == nil {
 			count := 0
 			for _, entry := range entries {
@@ -388,6 +573,25 @@ func uninstallDryRunFunc(p interface {
 		fmt.Println()
 	}
 
+	if uninstallPurge {
+		fmt.Println(cli.Header("State that would be purged"))
+		if plan.RemoveVolumes {
+			fmt.Printf("  %s Docker volumes of MageBox services\n", cli.Bullet(""))
+		}
+		if plan.RemoveHosts {
+			fmt.Printf("  %s MageBox entries in %s\n", cli.Bullet(""), plat.HostsFilePath())
+		}
+		for _, path := range append(plan.SudoRemove, plan.RemovePaths...) {
+			fmt.Printf("  %s %s\n", cli.Bullet(""), path)
+		}
+		fmt.Println()
+	}
+	if plan.RemoveCA {
+		fmt.Println(cli.Header("Certificate authority that would be removed"))
+		fmt.Printf("  %s mkcert local CA\n", cli.Bullet(""))
+		fmt.Println()
+	}
+
 	cli.PrintInfo("Run without --dry-run to actually uninstall")
 	return nil
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/platform"
+)
+
+func TestNewUninstallPlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		osType      platform.Type
+		purge       bool
+		removeCA    bool
+		wantVolumes bool
+		wantHosts   bool
+		wantRemoved []string
+		wantCA      bool
+	}{
+		{name: "default", osType: platform.Linux},
+		{name: "purge on linux", osType: platform.Linux, purge: true, wantVolumes: true, wantHosts: true,
+			wantRemoved: []string{sudoersFilePath, "/home/dev/.magebox"}},
+		{name: "purge on macos", osType: platform.Darwin, purge: true, wantVolumes: true, wantHosts: true,
+			wantRemoved: []string{"/home/dev/.magebox"}},
+		{name: "remove ca", osType: platform.Linux, removeCA: true, wantCA: true},
+		{name: "purge and remove ca", osType: platform.Linux, purge: true, removeCA: true, wantVolumes: true, wantHosts: true,
+			wantRemoved: []string{sudoersFilePath, "/home/dev/.magebox"}, wantCA: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &platform.Platform{Type: tt.osType, HomeDir: "/home/dev"}
+			plan := newUninstallPlan(p, tt.purge, tt.removeCA)
+
+			if plan.RemoveVolumes != tt.wantVolumes || plan.RemoveHosts != tt.wantHosts || plan.RemoveCA != tt.wantCA {
+				t.Errorf("plan = volumes %v, hosts %v, ca %v", plan.RemoveVolumes, plan.RemoveHosts, plan.RemoveCA)
+			}
+			removed := append(append([]string{}, plan.SudoRemove...), plan.RemovePaths...)
+			if strings.Join(removed, ",") != strings.Join(tt.wantRemoved, ",") {
+				t.Errorf("removed = %v, want %v", removed, tt.wantRemoved)
+			}
+
+			// Nothing outside MageBox's own files is removed
+			for _, path := range removed {
+				if path != sudoersFilePath && path != p.MageBoxDir() && !strings.HasPrefix(path, p.MageBoxDir()+string(filepath.Separator)) {
+					t.Errorf("plan removes %s, outside the MageBox paths", path)
+				}
+			}
+		})
+	}
+}
+
+func TestUninstallLeftovers(t *testing.T) {
+	p := &platform.Platform{Type: platform.Linux, HomeDir: "/home/dev"}
+	dnsPath := "/etc/systemd/resolved.conf.d/magebox.conf"
+
+	tests := []struct {
+		name      string
+		existing  []string
+		hosts     int
+		removedCA bool
+		want      []string
+		wantNot   []string
+	}{
+		{
+			name:     "default",
+			existing: []string{"/home/dev/.magebox", sudoersFilePath, dnsPath},
+			hosts:    3,
+			want: []string{
+				"magebox binary: /usr/local/bin/magebox",
+				"State directory: /home/dev/.magebox (use --purge)",
+				"3 MageBox entries in /etc/hosts",
+				"Sudoers entry: " + sudoersFilePath,
+				"DNS configuration: " + dnsPath,
+				"Local CA: /home/dev/.local/share/mkcert (use --remove-ca)",
+			},
+		},
+		{
+			name:     "purge",
+			existing: []string{dnsPath},
+			want:     []string{"magebox binary: /usr/local/bin/magebox", "DNS configuration: " + dnsPath},
+			wantNot:  []string{"State directory", "entries in /etc/hosts", "Sudoers entry"},
+		},
+		{
+			name:      "remove ca",
+			existing:  []string{"/home/dev/.magebox"},
+			removedCA: true,
+			want:      []string{"State directory: /home/dev/.magebox (use --purge)"},
+			wantNot:   []string{"Local CA"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := uninstallState{
+				Binary:       "/usr/local/bin/magebox",
+				HostsEntries: tt.hosts,
+				DNSPaths:     []string{"/etc/dnsmasq.d/magebox.conf", dnsPath},
+				CARoot:       "/home/dev/.local/share/mkcert",
+				Exists: func(path string) bool {
+					for _, existing := range tt.existing {
+						if path == existing {
+							return true
+						}
+					}
+					return false
+				},
+			}
+			got := strings.Join(uninstallLeftovers(p, state, tt.removedCA), "\n")
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("leftovers missing %q:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("leftovers contain %q:\n%s", unwanted, got)
+				}
+			}
+			if !strings.Contains(got, "Docker images") {
+				t.Errorf("leftovers missing the Docker images:\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/versioncatalog"
+)
+
+var upgradeAdvisorCmd = &cobra.Command{
+	Use:   "upgrade-advisor [version]",
+	Short: "Show the Magento upgrades of the project and the stack they need",
+	Long: `Reads the installed Magento, MageOS or Adobe Commerce version from
+composer.lock and lists the releases the project can upgrade to: the newest
+release of the current release line and of each newer one. For each, it shows
+the PHP, database and search engine versions the project has to move to,
+checked against the compatibility matrix of 'magebox versions'.
+
+With a version, only the upgrade to that release is shown, with the steps to
+run. --prepare writes the PHP and service versions of the upgrade to
+.magebox.local.yaml, to try the new stack before committing to it. Run it in
+a worktree to keep the current stack running next to it.
+
+Example:
+  magebox upgrade-advisor                      # List the upgrade targets
+  magebox upgrade-advisor 2.4.8-p4             # Show one upgrade and its steps
+  magebox upgrade-advisor 2.4.8-p4 --prepare   # Write its stack to .magebox.local.yaml
+  magebox upgrade-advisor --prepare            # Prepare the latest release`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUpgradeAdvisor,
+}
+
+var (
+	upgradePrepare bool
+	upgradeOffline bool
+)
+
+func init() {
+	upgradeAdvisorCmd.Flags().BoolVar(&upgradePrepare, "prepare", false, "Write the PHP and service versions of the upgrade to .magebox.local.yaml")
+	upgradeAdvisorCmd.Flags().BoolVar(&upgradeOffline, "offline", false, "Use the bundled releases without fetching")
+	rootCmd.AddCommand(upgradeAdvisorCmd)
+}
+
+// upgradeAdvisorOutput is `magebox upgrade-advisor` in JSON and YAML
+type upgradeAdvisorOutput struct {
+	Installed *versioncatalog.Installed `json:"installed"`
+	Stack     versioncatalog.Stack      `json:"stack"`
+	Targets   []versioncatalog.Target   `json:"targets"`
+	Prepared  []string                  `json:"prepared,omitempty"`
+}
+
+func runUpgradeAdvisor(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+	installed, err := versioncatalog.DetectInstalled(cwd)
+	if err != nil {
+		cli.PrintError("Can't tell the installed version: %v", err)
+		return nil
+	}
+
+	result, err := versionCatalog(p, upgradeOffline, false).Load()
+	if err != nil {
+		return fmt.Errorf("failed to load versions config: %w", err)
+	}
+	if !structuredOutput() {
+		warnCatalogFallback(result)
+	}
+
+	stack := projectStack(cfg)
+	var targets []versioncatalog.Target
+	if len(args) > 0 {
+		target, err := versioncatalog.FindTarget(result.Versions, installed, stack, args[0])
+		if err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		targets = []versioncatalog.Target{*target}
+	} else {
+		targets = versioncatalog.Advise(result.Versions, installed, stack)
+	}
+
+	var prepared []string
+	if upgradePrepare && len(targets) > 0 {
+		if prepared, err = prepareUpgradeStack(cwd, targets[len(targets)-1]); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	if structuredOutput() {
+		if targets == nil {
+			targets = []versioncatalog.Target{}
+		}
+		return printStructured(upgradeAdvisorOutput{Installed: installed, Stack: stack, Targets: targets, Prepared: prepared})
+	}
+
+	cli.PrintTitle("Upgrade Advisor: %s", cfg.Name)
+	fmt.Println()
+	fmt.Printf("  %-12s %s %s (%s)\n", "Installed:", distributionName(installed.Distribution), cli.Highlight(installed.Version), installed.Package)
+	fmt.Printf("  %-12s %s\n", "PHP:", stack.PHP)
+	if stack.Database != "" {
+		fmt.Printf("  %-12s %s %s\n", "Database:", titleCase(stack.Database), stack.DatabaseVersion)
+	}
+	if stack.Search != "" {
+		fmt.Printf("  %-12s %s %s\n", "Search:", titleCase(stack.Search), stack.SearchVersion)
+	}
+
+	if len(targets) == 0 {
+		fmt.Println()
+		cli.PrintSuccess("%s %s is the latest release", distributionName(installed.Distribution), installed.Version)
+		return nil
+	}
+
+	fmt.Println(cli.Header("Upgrade Targets"))
+	for _, target := range targets {
+		printUpgradeTarget(target)
+	}
+
+	target := targets[len(targets)-1]
+	if len(args) > 0 || upgradePrepare {
+		fmt.Println(cli.Header("Upgrade Steps"))
+		printUpgradeSteps(installed, target, prepared != nil)
+	} else {
+		fmt.Println()
+		cli.PrintInfo("Show the steps of an upgrade and prepare its stack with:")
+		fmt.Println("  " + cli.Command("magebox upgrade-advisor "+target.Version+" --prepare"))
+	}
+
+	if upgradePrepare {
+		fmt.Println()
+		if len(prepared) == 0 {
+			cli.PrintSuccess("The stack already supports %s, %s is unchanged", target.Version, config.LocalConfigFileName)
+		} else {
+			cli.PrintSuccess("Wrote the stack of %s to %s:", target.Version, config.LocalConfigFileName)
+			for _, change := range prepared {
+				fmt.Println("  " + change)
+			}
+		}
+	}
+
+	return nil
+}
+
+// projectStack returns the PHP, database and search engine of a project,
+// with the default versions of services configured without one
+func projectStack(cfg *config.Config) versioncatalog.Stack {
+	stack := versioncatalog.Stack{PHP: cfg.PHP}
+	if db, err := getDbInfo(cfg); err == nil {
+		stack.Database, stack.DatabaseVersion = db.Type, serviceVersion(db.Type, db.Version)
+	}
+	switch {
+	case cfg.Services.HasOpenSearch():
+		stack.Search, stack.SearchVersion = "opensearch", serviceVersion("opensearch", cfg.Services.OpenSearch.Version)
+	case cfg.Services.HasElasticsearch():
+		stack.Search, stack.SearchVersion = "elasticsearch", serviceVersion("elasticsearch", cfg.Services.Elasticsearch.Version)
+	}
+	return stack
+}
+
+// serviceVersion returns the version of a service, its default if empty
+func serviceVersion(name, version string) string {
+	if version != "" {
+		return version
+	}
+	if info, ok := config.LookupService(name); ok {
+		return info.DefaultVersion
+	}
+	return ""
+}
+
+// distributionName returns the display name of a distribution
+func distributionName(distribution string) string {
+	switch distribution {
+	case DistMageOS:
+		return "MageOS"
+	case DistCommerce:
+		return "Adobe Commerce"
+	}
+	return "Magento"
+}
+
+// printUpgradeTarget prints a target and the stack changes it needs
+func printUpgradeTarget(target versioncatalog.Target) {
+	name := target.Version
+	if target.Latest {
+		name += " (latest)"
+	}
+	fmt.Printf("  %s\n", cli.Highlight(name))
+
+	for _, bump := range target.Bumps {
+		fmt.Printf("    %s %s\n", cli.Warning("↑"), describeBump(bump))
+	}
+	if len(target.Bumps) == 0 {
+		fmt.Printf("    %s no stack changes\n", cli.Success("✓"))
+	}
+
+	if compat := target.Compatibility; compat != nil {
+		var others []string
+		for _, service := range []string{"redis", "valkey", "rabbitmq"} {
+			if v := compat.ServiceVersions(service); len(v) > 0 {
+				others = append(others, titleCase(service)+" "+strings.Join(v, "/"))
+			}
+		}
+		if compat.Composer != "" {
+			others = append(others, "Composer "+compat.Composer)
+		}
+		if len(others) > 0 {
+			fmt.Printf("    %s\n", cli.Subtitle("Supported: "+strings.Join(others, ", ")))
+		}
+	} else {
+		fmt.Printf("    %s\n", cli.Subtitle("Services unchecked: the release line isn't in the compatibility matrix"))
+	}
+}
+
+// describeBump describes a stack change, e.g. "PHP 8.1 → 8.4 (supports 8.4, 8.3, 8.2)"
+func describeBump(bump versioncatalog.Bump) string {
+	supported := "(supports " + strings.Join(bump.Supported, ", ") + ")"
+	if bump.Service == "php" {
+		return fmt.Sprintf("PHP %s → %s %s", bump.From, bump.To, supported)
+	}
+	if bump.NewService != "" {
+		return fmt.Sprintf("%s %s → %s %s %s", titleCase(bump.Service), bump.From, titleCase(bump.NewService), bump.To, supported)
+	}
+	return fmt.Sprintf("%s %s → %s %s", titleCase(bump.Service), bump.From, bump.To, supported)
+}
+
+// printUpgradeSteps prints the steps of an upgrade: the stack, then Composer
+// and setup:upgrade
+func printUpgradeSteps(installed *versioncatalog.Installed, target versioncatalog.Target, prepared bool) {
+	step := 0
+	next := func(format string, args ...interface{}) {
+		step++
+		fmt.Printf("  %d. %s\n", step, fmt.Sprintf(format, args...))
+	}
+
+	// A database or search engine of another version is another container,
+	// which starts empty
+	var dbBump, searchBump *versioncatalog.Bump
+	for i, bump := range target.Bumps {
+		switch bump.Service {
+		case "mysql", "mariadb", "percona":
+			dbBump = &target.Bumps[i]
+		case "opensearch", "elasticsearch":
+			searchBump = &target.Bumps[i]
+		}
+	}
+
+	next("Back up the project: %s", cli.Command("magebox backup"))
+	if dbBump != nil {
+		next("Export the database, the %s %s container starts empty: %s", titleCase(dbBump.Service), dbBump.To, cli.Command("magebox db export"))
+	}
+	if len(target.Bumps) > 0 {
+		if prepared {
+			next("Apply the new stack: %s", cli.Command("magebox apply"))
+		} else {
+			next("Switch the stack: %s", cli.Command("magebox upgrade-advisor "+target.Version+" --prepare && magebox apply"))
+		}
+	}
+	if dbBump != nil {
+		next("Import the database: %s", cli.Command("magebox db import"))
+	}
+
+	if installed.Distribution == DistMageOS {
+		next("Update the packages: %s", cli.Command("composer require "+installed.Package+":"+target.Version+" --no-update && composer update"))
+	} else {
+		next("Update the packages: %s", cli.Command("composer require-commerce "+installed.Package+" "+target.Version+" --no-update && composer update"))
+	}
+	next("Upgrade the database: %s", cli.Command("php bin/magento setup:upgrade"))
+	if searchBump != nil {
+		next("Fill the new search index: %s", cli.Command("php bin/magento indexer:reindex"))
+	}
+	if target.Compatibility != nil && target.Compatibility.Composer != "" {
+		fmt.Printf("  %s\n", cli.Subtitle("The release line needs Composer "+target.Compatibility.Composer))
+	}
+}
+
+// prepareUpgradeStack writes the PHP and service versions of an upgrade to
+// .magebox.local.yaml and returns the changed settings. A changed search
+// engine replaces the project's one, as services in the local file do.
+func prepareUpgradeStack(cwd string, target versioncatalog.Target) ([]string, error) {
+	if len(target.Bumps) == 0 {
+		return []string{}, nil
+	}
+
+	localCfg, err := config.LoadLocalConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
+	if localCfg.Other == nil {
+		localCfg.Other = make(map[string]interface{})
+	}
+	services, _ := localCfg.Other["services"].(map[string]interface{})
+	if services == nil {
+		services = make(map[string]interface{})
+	}
+
+	var changes []string
+	for _, bump := range target.Bumps {
+		if bump.Service == "php" {
+			localCfg.PHP = bump.To
+			changes = append(changes, "php: "+bump.To)
+			continue
+		}
+		service := bump.Service
+		if bump.NewService != "" {
+			delete(services, bump.Service)
+			service = bump.NewService
+		}
+		// Keep the other settings of a service configured as a mapping
+		if settings, ok := services[service].(map[string]interface{}); ok {
+			settings["version"] = bump.To
+		} else {
+			services[service] = bump.To
+		}
+		changes = append(changes, "services."+service+": "+bump.To)
+	}
+	if len(services) > 0 {
+		localCfg.Other["services"] = services
+	}
+
+	if err := config.SaveLocalConfig(cwd, localCfg); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,225 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/baseurl"
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+)
+
+var (
+	urlsSet     bool
+	urlsMagento bool
+)
+
+var urlsCmd = &cobra.Command{
+	Use:   "urls",
+	Short: "List the project URLs and set the Magento base URLs",
+	Long: `Lists the URL of every domain in .magebox.yaml with the store view or
+website it runs, next to the base URL the database has for that scope.
+
+With --set the base URLs are written to core_config_data: the default scope
+gets the first domain without a store code, the store view or website of every
+store code the first domain with that code. Absolute link, media and static
+URLs and the cookie domain are removed, then the config cache is cleaned.
+--magento writes them with bin/magento config:set instead of SQL.
+
+Examples:
+  magebox urls                   # List URLs and base URLs
+  magebox urls --set             # Point the base URLs at the domains
+  magebox urls --set --magento   # The same with bin/magento config:set`,
+	Args: cobra.NoArgs,
+	RunE: runUrls,
+}
+
+func init() {
+	urlsCmd.Flags().BoolVar(&urlsSet, "set", false, "Write the base URLs of the domains to the database")
+	urlsCmd.Flags().BoolVar(&urlsMagento, "magento", false, "Write them with bin/magento config:set instead of SQL")
+	rootCmd.AddCommand(urlsCmd)
+}
+
+// urlOutput is a project URL in --output json/yaml
+type urlOutput struct {
+	Host      string `json:"host"`
+	URL       string `json:"url"`
+	Scope     string `json:"scope"`
+	Code      string `json:"code,omitempty"`
+	Alias     bool   `json:"alias,omitempty"`
+	BaseURL   string `json:"base_url,omitempty"`
+	SecureURL string `json:"secure_base_url,omitempty"`
+}
+
+func runUrls(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+	if len(cfg.Domains) == 0 {
+		cli.PrintError("No domains configured in %s", config.ConfigFileName)
+		return nil
+	}
+	if urlsMagento && !urlsSet {
+		cli.PrintError("--magento only applies to --set")
+		return nil
+	}
+
+	targets := baseurl.Targets(cfg.Domains)
+	prefix := tablePrefix(cwd)
+
+	if urlsSet {
+		if err := setBaseURLs(cfg, cwd, targets, prefix); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	// The base URLs in the database, when it can be reached
+	var settings []baseurl.Setting
+	dbReachable := false
+	if db, err := getDbInfo(cfg); err == nil {
+		querier := &mysqlQuerier{db: db, dbName: cfg.DatabaseName()}
+		if rows, err := querier.Query(baseurl.Query(prefix)); err == nil {
+			settings = baseurl.ParseSettings(rows)
+			dbReachable = true
+		}
+	}
+
+	var urls []urlOutput
+	for _, d := range cfg.Domains {
+		target, alias := domainTarget(d, targets)
+		u := urlOutput{Host: d.Host, URL: baseurl.URL(d), Scope: target.Scope, Code: target.Code, Alias: alias}
+		if !alias {
+			u.BaseURL = target.Current(settings, "web/unsecure/base_url")
+			u.SecureURL = target.Current(settings, "web/secure/base_url")
+		}
+		urls = append(urls, u)
+	}
+
+	if structuredOutput() {
+		return printStructured(urls)
+	}
+
+	cli.PrintTitle("URLs: %s", cfg.Name)
+	fmt.Println()
+	mismatches := 0
+	for i, u := range urls {
+		label := baseurl.Target{Scope: u.Scope, Code: u.Code}.Label()
+		fmt.Printf("  %-40s %-18s ", u.URL, label)
+		switch {
+		case u.Alias:
+			fmt.Print(cli.Dim + "alias of " + targetHost(urls[:i], u) + cli.Reset)
+		case !dbReachable:
+		case u.BaseURL == u.URL && u.SecureURL == u.URL:
+			fmt.Print(cli.Success("✓ base URL"))
+		default:
+			mismatches++
+			current := u.SecureURL
+			if current == "" || current == u.URL {
+				current = u.BaseURL
+			}
+			if current == "" {
+				current = "not set"
+				if u.Scope != baseurl.ScopeDefault {
+					current = "inherited"
+				}
+			}
+			fmt.Print(cli.Warning("✗ base URL is " + current))
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+
+	switch {
+	case !dbReachable:
+		cli.PrintInfo("Base URLs not checked, the database is not reachable (magebox start)")
+	case mismatches > 0:
+		cli.PrintInfo("Point the base URLs at the domains with: %s", cli.Command("magebox urls --set"))
+	}
+	return nil
+}
+
+// domainTarget returns the base URL target a domain runs and whether the
+// domain is an alias, a further domain of the same scope
+func domainTarget(d config.Domain, targets []baseurl.Target) (baseurl.Target, bool) {
+	for _, t := range targets[1:] {
+		if t.Code == d.MageRunCode && (t.Scope == baseurl.ScopeWebsites) == (d.GetMageRunType() == "website") {
+			return t, t.Host != d.Host
+		}
+	}
+	return targets[0], targets[0].Host != d.Host
+}
+
+// targetHost returns the host of the listed URL u is an alias of
+func targetHost(listed []urlOutput, u urlOutput) string {
+	for _, l := range listed {
+		if !l.Alias && l.Scope == u.Scope && l.Code == u.Code {
+			return l.Host
+		}
+	}
+	return ""
+}
+
+// setBaseURLs writes the base URLs of the targets with SQL, or with
+// bin/magento config:set for --magento, and cleans the config cache
+func setBaseURLs(cfg *config.Config, cwd string, targets []baseurl.Target, prefix string) error {
+	hasMagento := true
+	if _, err := os.Stat(filepath.Join(cwd, "bin", "magento")); err != nil {
+		hasMagento = false
+	}
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	if urlsMagento {
+		if !hasMagento {
+			cli.PrintError("bin/magento not found in %s", cwd)
+			return nil
+		}
+		for _, t := range targets {
+			for _, args := range baseurl.MagentoArgs(t) {
+				out, err := magentoCommand(context.Background(), p, cfg, cwd, args...).CombinedOutput()
+				if err != nil {
+					cli.PrintError("%s (%s): %s", args[len(args)-2], t.Label(), lastLine(string(out)))
+					return fmt.Errorf("bin/magento config:set failed: %w", err)
+				}
+			}
+			cli.PrintSuccess("%s: %s", t.Label(), t.URL)
+		}
+	} else {
+		db, err := getDbInfo(cfg)
+		if err != nil {
+			cli.PrintError("%v", err)
+			return nil
+		}
+		if err := execSQL(db, cfg.DatabaseName(), baseurl.Statements(targets, prefix)); err != nil {
+			return fmt.Errorf("failed to set base URLs: %w", err)
+		}
+		for _, t := range targets {
+			cli.PrintSuccess("%s: %s", t.Label(), t.URL)
+		}
+	}
+
+	if !hasMagento {
+		return nil
+	}
+	if out, err := magentoCommand(context.Background(), p, cfg, cwd, "cache:clean", "config").CombinedOutput(); err != nil {
+		cli.PrintWarning("Failed to clean the config cache: %s", lastLine(string(out)))
+		cli.PrintInfo("Clean it with: %s", cli.Command("bin/magento cache:clean config"))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"qoliber/magebox/internal/baseurl"
+	"qoliber/magebox/internal/config"
+)
+
+func TestDomainTarget(t *testing.T) {
+	domains := []config.Domain{
+		{Host: "mystore.test"},
+		{Host: "de.mystore.test", MageRunCode: "de"},
+		{Host: "www.de.mystore.test", MageRunCode: "de"},
+		{Host: "www.mystore.test"},
+		{Host: "de.b2b.test", MageRunCode: "de", MageRunType: "website"},
+	}
+	targets := baseurl.Targets(domains)
+
+	tests := []struct {
+		domain int
+		label  string
+		alias  bool
+	}{
+		{0, "default", false},
+		{1, "store de", false},
+		{2, "store de", true},
+		{3, "default", true},
+		{4, "website de", false},
+	}
+	for _, tt := range tests {
+		target, alias := domainTarget(domains[tt.domain], targets)
+		if target.Label() != tt.label || alias != tt.alias {
+			t.Errorf("domainTarget(%s) = %s, alias %v, want %s, alias %v", domains[tt.domain].Host, target.Label(), alias, tt.label, tt.alias)
+		}
+	}
+}
+
+func TestUrlsRecordedOnlyWithSet(t *testing.T) {
+	if stateChangingCommands["urls"] || changesStateWithFlag(urlsCmd, "urls") {
+		t.Error("listing the URLs should not be recorded in the history log")
+	}
+
+	if err := urlsCmd.Flags().Set("set", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		urlsSet = false
+		urlsCmd.Flags().Lookup("set").Changed = false
+	}()
+	if !changesStateWithFlag(urlsCmd, "urls") {
+		t.Error("urls --set should be recorded in the history log")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/docker"
 	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/ssl"
 	"qoliber/magebox/internal/varnish"
 )
@@ -27,12 +30,23 @@ var varnishCmd = &cobra.Command{
 	Long:  "Manage Varnish full-page cache",
 }
 
+var varnishPurgeTags []string
+
 var varnishPurgeCmd = &cobra.Command{
 	Use:   "purge [url]",
-	Short: "Purge a URL from cache",
-	Long:  "Purges a specific URL from Varnish cache",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  runVarnishPurge,
+	Short: "Purge a URL or cache tags from cache",
+	Long: `Purges a specific URL from Varnish cache, or every page carrying one of the
+given Magento cache tags.
+
+Tag purges send the same PURGE request with X-Magento-Tags-Pattern that
+Magento sends when an entity is saved, so they behave exactly like production.
+
+Examples:
+  magebox varnish purge /women.html
+  magebox varnish purge --tag cat_p_123
+  magebox varnish purge --tag cat_c_5 --tag cms_b_footer`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVarnishPurge,
 }
 
 var varnishFlushCmd = &cobra.Command{
@@ -42,11 +56,27 @@ var varnishFlushCmd = &cobra.Command{
 	RunE:  runVarnishFlush,
 }
 
+var (
+	varnishStatusWindow time.Duration
+	varnishStatusTop    int
+	varnishStatusAll    bool
+)
+
 var varnishStatusCmd = &cobra.Command{
 	Use:   "status",
-	Short: "Show Varnish status",
-	Long:  "Shows Varnish cache statistics and status",
-	RunE:  runVarnishStatus,
+	Short: "Show Varnish status and cache hit rates",
+	Long: `Shows whether Varnish runs, its backends, and the hit, miss and pass rates,
+grace hits and hit-for-miss/hit-for-pass counts since it started.
+
+--window samples the traffic for a while: the rates are then those of the
+window, broken down by Host header with the most requested URLs that missed
+or passed the cache. In a project only its domains are shown, unless --all.
+
+Examples:
+  magebox varnish status
+  magebox varnish status --window 30s
+  magebox varnish status --window 1m --all --top 20`,
+	RunE: runVarnishStatus,
 }
 
 var varnishEnableCmd = &cobra.Command{
@@ -113,6 +143,10 @@ Examples:
 }
 
 func init() {
+	varnishPurgeCmd.Flags().StringSliceVar(&varnishPurgeTags, "tag", nil, "Purge pages tagged with this Magento cache tag (repeatable)")
+	varnishStatusCmd.Flags().DurationVarP(&varnishStatusWindow, "window", "w", 0, "Sample the traffic for this long, e.g. 30s")
+	varnishStatusCmd.Flags().IntVar(&varnishStatusTop, "top", 10, "Uncached URLs to show per host")
+	varnishStatusCmd.Flags().BoolVar(&varnishStatusAll, "all", false, "Show all hosts, not only the project's")
 	varnishCmd.AddCommand(varnishPurgeCmd)
 	varnishCmd.AddCommand(varnishFlushCmd)
 	varnishCmd.AddCommand(varnishStatusCmd)
@@ -150,6 +184,25 @@ func runVarnishPurge(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if len(varnishPurgeTags) > 0 {
+		if len(args) > 0 {
+			cli.PrintError("Use either a URL or --tag, not both")
+			return nil
+		}
+		// The Host header limits the purge to this project's pages
+		host := ""
+		if len(cfg.Domains) > 0 {
+			host = cfg.Domains[0].Host
+		}
+		fmt.Printf("Purging tags %s... ", strings.Join(varnishPurgeTags, ", "))
+		if err := ctrl.PurgeTags(host, varnishPurgeTags); err != nil {
+			fmt.Printf("failed: %v\n", err)
+		} else {
+			fmt.Println("done")
+		}
+		return nil
+	}
+
 	// Determine URL to purge
 	url := "/"
 	if len(args) > 0 {
@@ -192,6 +245,20 @@ func runVarnishFlush(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// varnishStatsCounters are the raw varnishstat counters in the stats field of
+// varnish status --output json|yaml
+var varnishStatsCounters = []string{"MAIN.cache_hit", "MAIN.cache_miss", "MAIN.client_req"}
+
+// varnishStatusOutput is the Varnish status printed by varnish status --output json|yaml
+type varnishStatusOutput struct {
+	Running  bool                 `json:"running"`
+	Backends []string             `json:"backends,omitempty"`
+	Stats    map[string]int64     `json:"stats,omitempty"`
+	Cache    *varnish.CacheStats  `json:"cache,omitempty"`  // Since Varnish started, or over the window
+	Window   string               `json:"window,omitempty"` // Sampling window, empty for totals
+	Hosts    []varnish.HostReport `json:"hosts,omitempty"`
+}
+
 func runVarnishStatus(cmd *cobra.Command, args []string) error {
 	p, err := getPlatform()
 	if err != nil {
@@ -201,48 +268,157 @@ func runVarnishStatus(cmd *cobra.Command, args []string) error {
 	vclGen := varnish.NewVCLGenerator(p)
 	ctrl := varnish.NewController(p, vclGen.VCLFilePath())
 
-	fmt.Println("Varnish Status")
-	fmt.Println("==============")
-
-	if ctrl.IsRunning() {
-		fmt.Println("Status: " + cli.Success("running"))
-
+	var status varnishStatusOutput
+	var backendErr, statsErr error
+	status.Running = ctrl.IsRunning()
+	if status.Running {
 		// Get backend health
-		backendCmd := exec.Command("docker", "exec", "magebox-varnish", "varnishadm", "backend.list")
-		backendOutput, err := backendCmd.Output()
-		if err == nil {
-			lines := strings.Split(string(backendOutput), "\n")
-			fmt.Println()
-			fmt.Println("Backends:")
-			for _, line := range lines {
+		var backendOutput []byte
+		backendOutput, backendErr = exec.Command("docker", "exec", "magebox-varnish", "varnishadm", "backend.list").Output()
+		if backendErr == nil {
+			for _, line := range strings.Split(string(backendOutput), "\n") {
 				if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "Backend name") {
-					fmt.Printf("  %s\n", strings.TrimSpace(line))
+					status.Backends = append(status.Backends, strings.TrimSpace(line))
 				}
 			}
 		}
 
 		// Get cache stats
-		statsCmd := exec.Command("docker", "exec", "magebox-varnish", "varnishstat", "-1")
-		output, err := statsCmd.Output()
-		if err == nil {
-			lines := strings.Split(string(output), "\n")
-			fmt.Println()
-			fmt.Println("Cache Statistics:")
-			for _, line := range lines {
-				if strings.Contains(line, "MAIN.cache_hit ") ||
-					strings.Contains(line, "MAIN.cache_miss ") ||
-					strings.Contains(line, "MAIN.client_req ") {
-					fmt.Printf("  %s\n", strings.TrimSpace(line))
-				}
+		var statsOutput []byte
+		var counters varnish.Counters
+		statsOutput, statsErr = exec.Command("docker", "exec", "magebox-varnish", "varnishstat", "-1").Output()
+		if statsErr == nil {
+			status.Stats = parseVarnishStats(string(statsOutput), varnishStatsCounters)
+			counters = varnish.ParseCounters(string(statsOutput))
+			cache := varnish.StatsFromCounters(counters)
+			status.Cache = &cache
+		}
+
+		if statsErr == nil && varnishStatusWindow > 0 {
+			if err := sampleVarnishTraffic(cmd, ctrl, counters, &status); err != nil {
+				return err
 			}
 		}
-	} else {
+	}
+
+	if structuredOutput() {
+		return printStructured(status)
+	}
+
+	fmt.Println("Varnish Status")
+	fmt.Println("==============")
+
+	if !status.Running {
 		fmt.Println("Status: " + cli.Warning("stopped"))
+		return nil
+	}
+
+	fmt.Println("Status: " + cli.Success("running"))
+
+	if backendErr == nil {
+		fmt.Println()
+		fmt.Println("Backends:")
+		for _, line := range status.Backends {
+			fmt.Printf("  %s\n", line)
+		}
 	}
 
+	if statsErr != nil {
+		return nil
+	}
+
+	fmt.Println()
+	if status.Window != "" {
+		fmt.Printf("Cache Statistics (last %s):\n", status.Window)
+	} else {
+		fmt.Println("Cache Statistics (since start):")
+	}
+	printVarnishCacheStats(*status.Cache, "  ")
+
+	if status.Window == "" {
+		fmt.Println()
+		cli.PrintInfo("Run %s to break the traffic down by host", cli.Command("magebox varnish status --window 30s"))
+		return nil
+	}
+
+	if len(status.Hosts) == 0 {
+		fmt.Println()
+		cli.PrintInfo("No requests for the hosts in the window")
+		return nil
+	}
+	for _, host := range status.Hosts {
+		fmt.Println()
+		fmt.Printf("%s\n", cli.Highlight(host.Host))
+		printVarnishCacheStats(host.Stats, "  ")
+		if len(host.Uncached) > 0 {
+			fmt.Println("  Top uncached URLs:")
+			for _, u := range host.Uncached {
+				fmt.Printf("    %6d  %s\n", u.Count, u.URL)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sampleVarnishTraffic logs the requests Varnish handles during the window
+// and replaces the totals of status with the numbers of the window
+func sampleVarnishTraffic(cmd *cobra.Command, ctrl *varnish.Controller, before varnish.Counters, status *varnishStatusOutput) error {
+	var hosts []string
+	if !varnishStatusAll {
+		if cwd, err := getCwd(); err == nil {
+			if cfg, err := config.LoadFromPath(cwd); err == nil {
+				hosts = cfg.Hosts()
+			}
+		}
+	}
+
+	if !structuredOutput() {
+		cli.PrintInfo("Sampling traffic for %s (Ctrl+C to stop early)...", varnishStatusWindow)
+	}
+	start := time.Now()
+	requests, err := ctrl.SampleRequests(cmd.Context(), varnishStatusWindow)
+	if err != nil && cmd.Context().Err() == nil {
+		return err
+	}
+
+	// Stopped early with Ctrl+C, the window ends there
+	after, err := ctrl.Counters()
+	if err != nil {
+		return err
+	}
+	cache := varnish.StatsFromCounters(after.Sub(before))
+	status.Cache = &cache
+	status.Window = time.Since(start).Round(time.Second).String()
+	status.Hosts = varnish.HostReports(requests, hosts, varnishStatusTop)
 	return nil
 }
 
+// printVarnishCacheStats prints hit, miss and pass rates and the grace and
+// hit-for-miss/pass counts
+func printVarnishCacheStats(s varnish.CacheStats, indent string) {
+	fmt.Printf("%sRequests:     %d\n", indent, s.Requests)
+	fmt.Printf("%sHit rate:     %s (%d hits, %d in grace)\n", indent, cli.Highlight(fmt.Sprintf("%.1f%%", s.HitRate()*100)), s.Hits, s.Grace)
+	fmt.Printf("%sMiss rate:    %.1f%% (%d misses, %d hit-for-miss)\n", indent, s.MissRate()*100, s.Misses, s.HitForMiss)
+	fmt.Printf("%sPass rate:    %.1f%% (%d passes, %d hit-for-pass)\n", indent, s.PassRate()*100, s.Passes, s.HitForPass)
+}
+
+// parseVarnishStats reads the given counters from `varnishstat -1` output,
+// whose lines are "NAME VALUE RATE DESCRIPTION"
+func parseVarnishStats(output string, counters []string) map[string]int64 {
+	stats := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !slices.Contains(counters, fields[0]) {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			stats[fields[0]] = value
+		}
+	}
+	return stats
+}
+
 func runVarnishEnable(cmd *cobra.Command, args []string) error {
 	cwd, err := getCwd()
 	if err != nil {
@@ -279,21 +455,27 @@ func runVarnishEnable(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	// Update config to enable Varnish
-	cfg.Services.Varnish = &config.ServiceConfig{
-		Enabled: true,
-		Version: "7.5",
+	// Update config to enable Varnish, keeping the settings of a disabled entry
+	varnishCfg := &config.ServiceConfig{Version: "7.5"}
+	if cfg.Services.Varnish != nil {
+		varnishCfg.VCLExtra = cfg.Services.Varnish.VCLExtra
+		varnishCfg.VCLExtraPath = cfg.Services.Varnish.VCLExtraPath
+		varnishCfg.Mode = cfg.Services.Varnish.Mode
+		varnishCfg.Platform = cfg.Services.Varnish.Platform
 	}
+	varnishCfg.Enabled = true
+	cfg.Services.Varnish = varnishCfg
 
 	// Save config
 	if err := config.SaveToPath(cfg, cwd); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	// Generate VCL
+	// Generate VCL, with the backends of all projects sharing Varnish
+	configs := varnishProjectConfigs(p, cfg)
 	fmt.Print("Generating VCL configuration... ")
 	vclGen := varnish.NewVCLGenerator(p)
-	if err := vclGen.Generate([]*config.Config{cfg}); err != nil {
+	if err := vclGen.Generate(configs); err != nil {
 		fmt.Println(cli.Error("failed"))
 		return fmt.Errorf("failed to generate VCL: %w", err)
 	}
@@ -302,7 +484,7 @@ func runVarnishEnable(cmd *cobra.Command, args []string) error {
 	// Regenerate docker-compose and start Varnish
 	fmt.Print("Starting Varnish container... ")
 	composeGen := docker.NewComposeGenerator(p)
-	if err := composeGen.GenerateGlobalServices([]*config.Config{cfg}); err != nil {
+	if err := composeGen.GenerateGlobalServices(configs); err != nil {
 		fmt.Println(cli.Error("failed"))
 		return fmt.Errorf("failed to generate docker-compose: %w", err)
 	}
@@ -410,20 +592,38 @@ func runVarnishDisable(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	// Stop Varnish container
-	fmt.Print("Stopping Varnish container... ")
+	configs := varnishProjectConfigs(p, cfg)
 	composeGen := docker.NewComposeGenerator(p)
 	dockerCtrl := docker.NewDockerController(composeGen.ComposeFilePath())
-	if err := dockerCtrl.StopService("varnish"); err != nil {
-		fmt.Println(cli.Warning("not running"))
+
+	// Other projects may still use the shared Varnish container
+	othersUseVarnish := false
+	for _, c := range configs {
+		if c.Name != cfg.Name && c.Services.HasVarnish() {
+			othersUseVarnish = true
+		}
+	}
+
+	if othersUseVarnish {
+		cli.PrintInfo("Varnish keeps running for other projects")
 	} else {
-		fmt.Println(cli.Success("done"))
+		fmt.Print("Stopping Varnish container... ")
+		if err := dockerCtrl.StopService("varnish"); err != nil {
+			fmt.Println(cli.Warning("not running"))
+		} else {
+			fmt.Println(cli.Success("done"))
+		}
 	}
 
-	// Regenerate docker-compose without Varnish
-	if err := composeGen.GenerateGlobalServices([]*config.Config{cfg}); err != nil {
+	// Regenerate docker-compose (and the VCL of the remaining projects)
+	if err := composeGen.GenerateGlobalServices(configs); err != nil {
 		return fmt.Errorf("failed to update docker-compose: %w", err)
 	}
+	if othersUseVarnish {
+		if err := varnish.NewController(p, varnish.NewVCLGenerator(p).VCLFilePath()).Reload(); err != nil {
+			cli.PrintWarning("Failed to reload Varnish: %v", err)
+		}
+	}
 
 	// Regenerate vhost configuration to remove Varnish proxy
 	fmt.Print("Regenerating Nginx vhosts... ")
@@ -553,11 +753,12 @@ func runVarnishVCLReset(cmd *cobra.Command, args []string) error {
 	cli.PrintTitle("Resetting VCL to Default")
 	fmt.Println()
 
-	// Load project config(s) to regenerate VCL
+	// Load project configs to regenerate VCL
 	var configs []*config.Config
-	cfg, ok := loadProjectConfig(cwd)
-	if ok {
-		configs = append(configs, cfg)
+	if cfg, ok := loadProjectConfig(cwd); ok {
+		configs = varnishProjectConfigs(p, cfg)
+	} else {
+		configs = discoverAllConfigs(p)
 	}
 
 	// Regenerate VCL
@@ -750,3 +951,16 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+// varnishProjectConfigs returns the configs of all projects, with cfg in place
+// of the saved config of its project. Varnish is shared, so the VCL always
+// needs the backends of every project.
+func varnishProjectConfigs(p *platform.Platform, cfg *config.Config) []*config.Config {
+	configs := []*config.Config{cfg}
+	for _, c := range discoverAllConfigs(p) {
+		if c.Name != cfg.Name {
+			configs = append(configs, c)
+		}
+	}
+	return configs
+}
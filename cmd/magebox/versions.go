@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	libconfig "qoliber/magebox/internal/lib/config"
+	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/versioncatalog"
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List the Magento and MageOS releases",
+	Long: `Lists the Magento and MageOS releases 'magebox new' offers, with the PHP
+versions they support and the services each Magento release line supports.
+
+The releases are fetched from Packagist and the Mage-OS repository, or from
+the manifest set as version_catalog.url in ~/.magebox/config.yaml, and cached
+for a day. When they can't be fetched, the cached or bundled releases are
+used.
+
+Example:
+  magebox versions              # List the releases
+  magebox versions --refresh    # Fetch the releases now
+  magebox versions --offline    # List the bundled releases`,
+	Args: cobra.NoArgs,
+	RunE: runVersions,
+}
+
+var (
+	versionsRefresh bool
+	versionsOffline bool
+)
+
+func init() {
+	versionsCmd.Flags().BoolVar(&versionsRefresh, "refresh", false, "Fetch the releases even if the cached ones are recent")
+	versionsCmd.Flags().BoolVar(&versionsOffline, "offline", false, "Use the bundled releases without fetching")
+	rootCmd.AddCommand(versionsCmd)
+}
+
+// releaseOutput is a release of `magebox versions` in JSON and YAML
+type releaseOutput struct {
+	Version string   `json:"version"`
+	PHP     []string `json:"php"`
+	Base    string   `json:"base,omitempty"`
+	Default bool     `json:"default,omitempty"`
+}
+
+// versionsOutput is `magebox versions` in JSON and YAML
+type versionsOutput struct {
+	Source        string                         `json:"source"`
+	FetchedAt     *time.Time                     `json:"fetched_at,omitempty"`
+	Error         string                         `json:"error,omitempty"`
+	Magento       []releaseOutput                `json:"magento"`
+	MageOS        []releaseOutput                `json:"mageos"`
+	Compatibility []libconfig.CompatibilityEntry `json:"compatibility"`
+}
+
+func runVersions(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	result, err := versionCatalog(p, versionsOffline, versionsRefresh).Load()
+	if err != nil {
+		return fmt.Errorf("failed to load versions config: %w", err)
+	}
+	versions := result.Versions
+
+	if structuredOutput() {
+		out := versionsOutput{
+			Source:        result.Source,
+			Magento:       releaseOutputs(versions.GetMagentoVersions()),
+			MageOS:        releaseOutputs(versions.GetMageOSVersions()),
+			Compatibility: versions.Compatibility,
+		}
+		if !result.FetchedAt.IsZero() {
+			out.FetchedAt = &result.FetchedAt
+		}
+		if result.Err != nil {
+			out.Error = result.Err.Error()
+		}
+		return printStructured(out)
+	}
+
+	cli.PrintTitle("Magento and MageOS Releases")
+	fmt.Println()
+	printCatalogSource(result)
+
+	fmt.Println(cli.Header("Magento Open Source / Adobe Commerce"))
+	for _, v := range versions.GetMagentoVersions() {
+		printRelease(v)
+	}
+
+	fmt.Println(cli.Header("MageOS"))
+	for _, v := range versions.GetMageOSVersions() {
+		printRelease(v)
+	}
+
+	if len(versions.Compatibility) > 0 {
+		fmt.Println(cli.Header("Compatibility"))
+		for _, c := range versions.Compatibility {
+			fmt.Printf("  %s\n", cli.Highlight(c.Magento))
+			for _, service := range []string{"php", "mysql", "mariadb", "opensearch", "elasticsearch", "redis", "valkey", "rabbitmq"} {
+				if v := c.ServiceVersions(service); len(v) > 0 {
+					fmt.Printf("    %-14s %s\n", service+":", strings.Join(v, ", "))
+				}
+			}
+			if c.Composer != "" {
+				fmt.Printf("    %-14s %s\n", "composer:", c.Composer)
+			}
+		}
+	}
+
+	return nil
+}
+
+// releaseOutputs converts releases for structured output
+func releaseOutputs(entries []libconfig.VersionEntry) []releaseOutput {
+	out := make([]releaseOutput, 0, len(entries))
+	for _, v := range entries {
+		out = append(out, releaseOutput{Version: v.Version, PHP: v.PHP, Base: v.Base, Default: v.Default})
+	}
+	return out
+}
+
+// printRelease prints a release and its PHP versions
+func printRelease(v libconfig.VersionEntry) {
+	marker := "  "
+	if v.Default {
+		marker = "→ "
+	}
+	details := "PHP " + strings.Join(v.PHP, ", ")
+	if v.Base != "" {
+		details += ", based on Magento " + v.Base
+	}
+	fmt.Printf("  %s%-12s %s\n", marker, v.Version, details)
+}
+
+// printCatalogSource says where the releases come from, and why they
+// couldn't be fetched
+func printCatalogSource(result *versioncatalog.Result) {
+	switch result.Source {
+	case versioncatalog.SourceRemote:
+		fmt.Printf("Fetched %s\n", result.FetchedAt.Format("2006-01-02 15:04"))
+	case versioncatalog.SourceCache:
+		fmt.Printf("Cached, fetched %s\n", result.FetchedAt.Format("2006-01-02 15:04"))
+	default:
+		fmt.Println("Bundled with MageBox")
+	}
+	if result.Err != nil {
+		cli.PrintWarning("Couldn't fetch the current releases: %v", result.Err)
+	}
+}
+
+// warnCatalogFallback warns when the current releases couldn't be fetched
+// and cached or bundled ones are used
+func warnCatalogFallback(result *versioncatalog.Result) {
+	if result.Err == nil {
+		return
+	}
+	list := "bundled"
+	if result.Source == versioncatalog.SourceCache {
+		list = "cached"
+	}
+	cli.PrintWarning("Couldn't fetch the current releases, using the %s list: %v", list, result.Err)
+}
+
+// versionCatalog creates the version catalog configured in the global config
+func versionCatalog(p *platform.Platform, offline, refresh bool) *versioncatalog.Catalog {
+	opts := versioncatalog.Options{TTL: config.DefaultVersionCatalogTTL, Offline: offline, Refresh: refresh}
+	if globalCfg, err := config.LoadGlobalConfig(p.HomeDir); err == nil {
+		opts.TTL = globalCfg.GetVersionCatalogTTL()
+		if globalCfg.VersionCatalog != nil {
+			opts.ManifestURL = globalCfg.VersionCatalog.URL
+			opts.Offline = opts.Offline || globalCfg.VersionCatalog.Offline
+		}
+	}
+	return versioncatalog.New(p.MageBoxDir(), opts)
+}
+
+// compatibilityFor returns the compatibility of a release of a distribution,
+// or nil if its release line is unknown
+func compatibilityFor(versions *libconfig.VersionsConfig, distribution, version string) *libconfig.CompatibilityEntry {
+	if distribution == DistMageOS {
+		return versions.GetMageOSCompatibility(version)
+	}
+	return versions.GetCompatibility(version)
+}
+
+// warnIncompatible warns when a release doesn't support a service version
+func warnIncompatible(compat *libconfig.CompatibilityEntry, release, service, version string) {
+	if compat == nil || version == "" || compat.Supports(service, version) {
+		return
+	}
+	cli.PrintWarning("%s supports %s %s, not %s", release, titleCase(service), strings.Join(compat.ServiceVersions(service), ", "), version)
+}
@@ -88,8 +88,14 @@ func runWatch(cmd *cobra.Command, args []string) error {
 	// Detect theme directories with an npm watch script
 	themeDirs := findThemeWatchDirs(cwd)
 
+	// The theme watcher runs with the project's Node.js
+	nodeBinDir := ""
+	if node, err := projectNode(cfg, cwd); err == nil && node != nil {
+		nodeBinDir = node.BinDir
+	}
+
 	if len(themeDirs) == 1 {
-		return runWatchWithTheme(bin, cwd, themeDirs[0])
+		return runWatchWithTheme(bin, cwd, themeDirs[0], nodeBinDir)
 	}
 	if len(themeDirs) > 1 {
 		selected, err := selectThemeWatchDir(cwd, themeDirs)
@@ -97,7 +103,7 @@ func runWatch(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		if selected != "" {
-			return runWatchWithTheme(bin, cwd, selected)
+			return runWatchWithTheme(bin, cwd, selected, nodeBinDir)
 		}
 	}
 
@@ -125,7 +131,8 @@ func runWatchCacheCleanOnly(bin, cwd string) error {
 }
 
 // runWatchWithTheme runs cache-clean.js and npm run watch side by side in a tmux session.
-func runWatchWithTheme(bin, cwd, themeDir string) error {
+// nodeBinDir, when set, is put first on the PATH of npm.
+func runWatchWithTheme(bin, cwd, themeDir, nodeBinDir string) error {
 	if _, err := exec.LookPath("tmux"); err != nil {
 		cli.PrintError("tmux is not installed")
 		cli.PrintInfo("tmux is required to run cache-clean and the theme watcher side by side")
@@ -153,6 +160,9 @@ func runWatchWithTheme(bin, cwd, themeDir string) error {
 	cacheCleanCmd := fmt.Sprintf("%s --watch --directory %s", bin, cwd)
 	// Wrap npm watch so errors pause with a visible message instead of closing the pane.
 	npmWatchCmd := fmt.Sprintf("npm --prefix %s run watch || { echo ''; echo 'npm run watch failed — press Enter to close'; read; }", themeDir)
+	if nodeBinDir != "" {
+		npmWatchCmd = fmt.Sprintf("PATH='%s':\"$PATH\" %s", nodeBinDir, npmWatchCmd)
+	}
 
 	// Create a new detached tmux session with npm watch in the left pane
 	if err := exec.Command("tmux", "new-session", "-d", "-s", sessionName, "sh", "-c", npmWatchCmd).Run(); err != nil {
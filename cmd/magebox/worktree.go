@@ -0,0 +1,731 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/baseurl"
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/project"
+)
+
+var worktreeCmd = &cobra.Command{
+	Use:   "worktree",
+	Short: "Projects for git worktrees",
+	Long: `Creates linked projects for git worktree checkouts of the current project,
+e.g. to review a branch next to the main checkout.
+
+A worktree project is named <project>-<branch>, serves every domain of the
+project under <branch>.<host>, shares the project's media and uses the
+project's database or a copy of it. Stopping the project stops its worktree
+projects too.`,
+}
+
+var worktreeAddCmd = &cobra.Command{
+	Use:   "add <branch>",
+	Short: "Check out a branch in a worktree and create its project",
+	Long: `Checks out a branch in a new git worktree next to the current project and
+creates a linked project for it. A branch that doesn't exist yet is created
+from the current HEAD.
+
+The worktree gets a .magebox.local.yaml with the project name
+<project>-<branch> and the project's domains prefixed with <branch>., e.g.
+feature-x.mystore.test. pub/media links to the project's media, and
+app/etc/env.php is copied from the project with the worktree's database and
+services, keeping the crypt key.
+
+--db shared (the default) points the worktree at the project's database;
+--db clone copies it to <database>_<branch> first, so the branch can run its
+own setup:upgrade.
+
+Example:
+  magebox worktree add feature/checkout
+  magebox worktree add hotfix --db clone --start
+  magebox worktree add review --path ~/review/mystore`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeAdd,
+}
+
+var worktreeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List worktree projects",
+	Long: `Lists the worktree projects of the current project, or of every project
+outside of one.`,
+	Args: cobra.NoArgs,
+	RunE: runWorktreeList,
+}
+
+var worktreeRemoveCmd = &cobra.Command{
+	Use:   "remove <branch>",
+	Short: "Remove a worktree and its project",
+	Long: `Removes the project of a worktree of the current project: its vhosts,
+PHP-FPM pool, certificates and hosts entries, the cloned database if it has
+one, and the git worktree itself. The branch is kept. The project's own
+database and media are never touched.
+
+Example:
+  magebox worktree remove feature/checkout
+  magebox worktree remove hotfix --force -y`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeRemove,
+}
+
+var (
+	worktreePath  string
+	worktreeDB    string
+	worktreeStart bool
+	worktreeForce bool
+	worktreeYes   bool
+)
+
+// worktreeSlugInvalid matches the runs of characters a branch slug can't have
+var worktreeSlugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+func init() {
+	worktreeAddCmd.Flags().StringVar(&worktreePath, "path", "", "Directory of the worktree (default: <project dir>-<branch> next to the project)")
+	worktreeAddCmd.Flags().StringVar(&worktreeDB, "db", "shared", "Database of the worktree: shared or clone")
+	worktreeAddCmd.Flags().BoolVar(&worktreeStart, "start", false, "Start the worktree project")
+	worktreeRemoveCmd.Flags().BoolVar(&worktreeForce, "force", false, "Remove the worktree even with uncommitted changes")
+	worktreeRemoveCmd.Flags().BoolVarP(&worktreeYes, "yes", "y", false, "Skip confirmation")
+
+	worktreeCmd.AddCommand(worktreeAddCmd)
+	worktreeCmd.AddCommand(worktreeListCmd)
+	worktreeCmd.AddCommand(worktreeRemoveCmd)
+	rootCmd.AddCommand(worktreeCmd)
+}
+
+func runWorktreeAdd(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	parent, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	branch := args[0]
+	slug := worktreeSlug(branch)
+	if slug == "" {
+		cli.PrintError("Branch %s has no letters or digits to name the worktree after", branch)
+		return nil
+	}
+	if worktreeDB != "shared" && worktreeDB != "clone" {
+		cli.PrintError("Invalid --db %q (use shared or clone)", worktreeDB)
+		return nil
+	}
+
+	registry := project.NewWorktrees(p)
+	if wt, err := registry.Get(parent.Name); err != nil {
+		return err
+	} else if wt != nil {
+		cli.PrintError("%s is a worktree of %s, add worktrees from %s", parent.Name, wt.Parent, wt.Parent)
+		return nil
+	}
+
+	name := parent.Name + "-" + slug
+	if wt, err := registry.Get(name); err != nil {
+		return err
+	} else if wt != nil {
+		cli.PrintError("Worktree project %s already exists at %s", name, cli.Path(wt.Path))
+		return nil
+	}
+
+	path := worktreePath
+	if path == "" {
+		path = filepath.Join(filepath.Dir(cwd), filepath.Base(cwd)+"-"+slug)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		cli.PrintError("%s already exists", cli.Path(path))
+		return nil
+	}
+
+	if err := exec.Command("git", "-C", cwd, "rev-parse", "--git-dir").Run(); err != nil {
+		cli.PrintError("%s is not a git repository", cli.Path(cwd))
+		return nil
+	}
+
+	db, err := getDbInfo(parent)
+	if err != nil && worktreeDB == "clone" {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	wt := project.Worktree{Name: name, Parent: parent.Name, Branch: branch, Path: path}
+	if db != nil {
+		wt.Database = parent.DatabaseName()
+		if worktreeDB == "clone" {
+			wt.Database += "_" + strings.ReplaceAll(slug, "-", "_")
+			wt.Cloned = true
+		}
+	}
+
+	cli.PrintTitle("Adding Worktree %s", name)
+	fmt.Println()
+
+	fmt.Print("Checking out branch... ")
+	if output, err := gitWorktreeAdd(cwd, path, branch); err != nil {
+		fmt.Println(cli.Error("failed"))
+		return fmt.Errorf("git worktree add failed: %s", strings.TrimSpace(output))
+	}
+	fmt.Println(cli.Success("done"))
+
+	if err := ensureWorktreeConfig(cwd, path); err != nil {
+		return err
+	}
+	overlay, err := worktreeOverlay(parent, branch, slug, db, wt.Database)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(path, config.LocalConfigFileName), overlay, 0644); err != nil {
+		return fmt.Errorf("cannot write %s: %w", config.LocalConfigFileName, err)
+	}
+	if err := registry.Add(wt); err != nil {
+		return err
+	}
+
+	if wt.Cloned {
+		fmt.Printf("Cloning database %s to %s... ", parent.DatabaseName(), wt.Database)
+		if err := cloneDatabase(db, parent.DatabaseName(), wt.Database); err != nil {
+			fmt.Println(cli.Error("failed"))
+			cli.PrintError("%v", err)
+			cli.PrintInfo("Remove the worktree with %s and try again", cli.Command("magebox worktree remove "+branch))
+			return nil
+		}
+		fmt.Println(cli.Success("done"))
+	}
+
+	if err := linkWorktreeMedia(cwd, path); err != nil {
+		cli.PrintWarning("Media not shared: %v", err)
+	}
+
+	mgr := project.NewManager(p)
+	mgr.SetLowMemory(lowMemoryDefault(p))
+	if err := copyWorktreeEnvPHP(mgr, cwd, path); err != nil {
+		cli.PrintWarning("env.php not copied: %v", err)
+	}
+
+	cfg, ok := loadProjectConfig(path)
+	if !ok {
+		return nil
+	}
+
+	// The clone holds the parent's base URLs, a shared database gets the
+	// worktree's in env.php instead
+	if wt.Cloned {
+		fmt.Print("Setting base URLs... ")
+		if err := execSQL(db, wt.Database, baseurl.Statements(baseurl.Targets(cfg.Domains), tablePrefix(path))); err != nil {
+			fmt.Println(cli.Error("failed"))
+			cli.PrintWarning("%v", err)
+			cli.PrintInfo("Set them with %s in the worktree", cli.Command("magebox urls --set"))
+		} else {
+			fmt.Println(cli.Success("done"))
+		}
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("Worktree project %s created", cfg.Name)
+	fmt.Printf("  Path:     %s\n", cli.Path(path))
+	fmt.Printf("  Domains:  %s\n", strings.Join(cfg.Hosts(), ", "))
+	if wt.Database != "" {
+		kind := "shared with " + parent.Name
+		if wt.Cloned {
+			kind = "cloned"
+		}
+		fmt.Printf("  Database: %s (%s)\n", wt.Database, kind)
+	}
+	fmt.Println()
+
+	if !worktreeStart {
+		cli.PrintInfo("Run %s in the worktree, then %s", cli.Command("composer install"), cli.Command("magebox start"))
+		return nil
+	}
+	return startProject(mgr, path, nil, true)
+}
+
+// worktreeOutput is a worktree of `magebox worktree list` in JSON and YAML
+type worktreeOutput struct {
+	project.Worktree
+	Running bool `json:"running"`
+}
+
+func runWorktreeList(cmd *cobra.Command, args []string) error {
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	registry := project.NewWorktrees(p)
+	var worktrees []project.Worktree
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+	if cfg, err := config.LoadFromPath(cwd); err == nil {
+		if worktrees, err = registry.Children(cfg.Name); err != nil {
+			return err
+		}
+	} else {
+		all, err := registry.Load()
+		if err != nil {
+			return err
+		}
+		for _, wt := range all {
+			worktrees = append(worktrees, wt)
+		}
+		sort.Slice(worktrees, func(i, j int) bool {
+			if worktrees[i].Parent != worktrees[j].Parent {
+				return worktrees[i].Parent < worktrees[j].Parent
+			}
+			return worktrees[i].Name < worktrees[j].Name
+		})
+	}
+
+	list := make([]worktreeOutput, 0, len(worktrees))
+	for _, wt := range worktrees {
+		list = append(list, worktreeOutput{Worktree: wt, Running: projectRunning(p, wt.Path)})
+	}
+	if structuredOutput() {
+		return printStructured(list)
+	}
+
+	cli.PrintTitle("Worktree Projects")
+	fmt.Println()
+
+	if len(list) == 0 {
+		cli.PrintInfo("No worktree projects, add one with %s", cli.Command("magebox worktree add <branch>"))
+		return nil
+	}
+	for _, wt := range list {
+		status := cli.Dim + "stopped" + cli.Reset
+		if wt.Running {
+			status = cli.Success("running")
+		}
+		fmt.Printf("  %-32s %s\n", cli.Highlight(wt.Name), status)
+		fmt.Printf("  %-32s branch %s of %s, %s\n", "", wt.Branch, wt.Parent, cli.Path(wt.Path))
+		if wt.Cloned {
+			fmt.Printf("  %-32s database %s (cloned)\n", "", wt.Database)
+		}
+	}
+
+	return nil
+}
+
+func runWorktreeRemove(cmd *cobra.Command, args []string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	parent, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	registry := project.NewWorktrees(p)
+	children, err := registry.Children(parent.Name)
+	if err != nil {
+		return err
+	}
+	var wt *project.Worktree
+	for i := range children {
+		if children[i].Branch == args[0] || children[i].Name == args[0] {
+			wt = &children[i]
+			break
+		}
+	}
+	if wt == nil {
+		cli.PrintError("%s has no worktree for %s", parent.Name, args[0])
+		cli.PrintInfo("List the worktrees with %s", cli.Command("magebox worktree list"))
+		return nil
+	}
+
+	// Check before anything is removed, git worktree remove would refuse
+	// only after the project is gone
+	if _, err := os.Stat(wt.Path); err == nil && !worktreeForce {
+		changes, err := worktreeChanges(wt.Path)
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			cli.PrintError("%s has changes git would lose:", cli.Path(wt.Path))
+			for _, change := range changes {
+				fmt.Printf("  %s\n", change)
+			}
+			cli.PrintInfo("Commit them, or remove the worktree anyway with %s", cli.Command("--force"))
+			return nil
+		}
+	}
+
+	if !worktreeYes {
+		cli.PrintTitle("Remove Worktree %s", wt.Name)
+		fmt.Println()
+		fmt.Printf("  Path:     %s\n", cli.Path(wt.Path))
+		if wt.Cloned {
+			fmt.Printf("  Database: %s\n", wt.Database)
+		}
+		fmt.Println()
+		fmt.Print("Are you sure? [y/N]: ")
+
+		var confirm string
+		_, _ = fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			cli.PrintInfo("Aborted")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	mgr := project.NewManager(p)
+	if cfg, err := config.LoadFromPath(wt.Path); err == nil {
+		others, err := otherProjectConfigs(p, wt.Path)
+		if err != nil {
+			cli.PrintError("Failed to discover projects: %v", err)
+			return nil
+		}
+
+		fmt.Print("Removing project... ")
+		stopQueueConsumers(p, cfg.Name)
+		opts := project.DestroyOptions{Vhosts: true, Certs: true, DNS: true, State: true, Others: others}
+		failed := false
+		for _, a := range mgr.Destroy(cfg, opts) {
+			if a.Error != "" {
+				if !failed {
+					fmt.Println(cli.Error("failed"))
+				}
+				failed = true
+				cli.PrintError("  %s %s: %s", a.Kind, a.Target, a.Error)
+			}
+		}
+		if !failed {
+			fmt.Println(cli.Success("done"))
+		}
+
+		if wt.Cloned {
+			fmt.Printf("Dropping database %s... ", wt.Database)
+			if db, err := getDbInfo(cfg); err != nil {
+				fmt.Println(cli.Error("failed"))
+				cli.PrintError("  %v", err)
+			} else if err := dropDatabase(db, wt.Database); err != nil {
+				fmt.Println(cli.Error("failed"))
+				cli.PrintError("  %v", err)
+			} else {
+				fmt.Println(cli.Success("done"))
+			}
+		}
+	}
+
+	if _, err := os.Stat(wt.Path); err == nil {
+		fmt.Print("Removing git worktree... ")
+		if err := unlinkWorktreeMedia(wt.Path); err != nil {
+			fmt.Println(cli.Error("failed"))
+			return fmt.Errorf("failed to unlink media: %w", err)
+		}
+		// The changes were checked above, what is left are the files
+		// worktree add wrote
+		output, err := exec.Command("git", "-C", cwd, "worktree", "remove", "--force", wt.Path).CombinedOutput()
+		if err != nil {
+			fmt.Println(cli.Error("failed"))
+			return fmt.Errorf("git worktree remove failed: %s", strings.TrimSpace(string(output)))
+		}
+		fmt.Println(cli.Success("done"))
+	}
+
+	if err := registry.Remove(wt.Name); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("Removed worktree %s, branch %s is kept", wt.Name, wt.Branch)
+	return nil
+}
+
+// stopWorktrees stops the running worktree projects of a parent project
+func stopWorktrees(mgr *project.Manager, parent string) {
+	p, err := getPlatform()
+	if err != nil {
+		return
+	}
+	children, err := project.NewWorktrees(p).Children(parent)
+	if err != nil {
+		cli.PrintWarning("Worktree projects: %v", err)
+		return
+	}
+
+	for _, wt := range children {
+		if !projectRunning(p, wt.Path) {
+			continue
+		}
+		fmt.Printf("Stopping worktree %s... ", cli.Highlight(wt.Name))
+		stopQueueConsumers(p, wt.Name)
+		if err := mgr.Stop(wt.Path); err != nil {
+			fmt.Println(cli.Error("failed"))
+			cli.PrintError("  %v", err)
+			continue
+		}
+		fmt.Println(cli.Success("done"))
+	}
+}
+
+// worktreeSlug turns a branch name into the label of the worktree project
+// name and domains: feature/Checkout_v2 -> feature-checkout-v2
+func worktreeSlug(branch string) string {
+	return strings.Trim(worktreeSlugInvalid.ReplaceAllString(strings.ToLower(branch), "-"), "-")
+}
+
+// worktreeChanges returns the git status lines of a worktree's changes,
+// leaving out the untracked files worktree add writes: the MageBox config,
+// env.php and the media links
+func worktreeChanges(worktreePath string) ([]string, error) {
+	output, err := exec.Command("git", "-C", worktreePath, "status", "--porcelain", "--untracked-files=all").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	written := []string{config.ConfigFileName, config.ConfigFileNameLegacy, config.LocalConfigFileName,
+		config.LocalConfigFileNameLegacy, "app/etc/env.php", "pub/media"}
+	var changes []string
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if file, ok := strings.CutPrefix(line, "?? "); ok {
+			if slices.Contains(written, file) || strings.HasPrefix(file, "pub/media/") {
+				continue
+			}
+		}
+		changes = append(changes, line)
+	}
+	return changes, nil
+}
+
+// gitWorktreeAdd checks out branch in a new worktree at path, creating the
+// branch from HEAD when neither it nor origin/<branch> exists. A branch name
+// git wouldn't accept, like one starting with a dash, is refused before it
+// reaches git worktree.
+func gitWorktreeAdd(repo, path, branch string) (string, error) {
+	if output, err := exec.Command("git", "-C", repo, "check-ref-format", "--branch", branch).CombinedOutput(); err != nil {
+		return fmt.Sprintf("invalid branch name %q: %s", branch, strings.TrimSpace(string(output))), err
+	}
+	args := []string{"-C", repo, "worktree", "add", path, branch}
+	local := exec.Command("git", "-C", repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Run()
+	remote := exec.Command("git", "-C", repo, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch).Run()
+	if local != nil && remote != nil {
+		args = []string{"-C", repo, "worktree", "add", "-b", branch, path}
+	}
+	output, err := exec.Command("git", args...).CombinedOutput()
+	return string(output), err
+}
+
+// ensureWorktreeConfig copies the parent's .magebox.yaml into a worktree of a
+// branch that doesn't have one
+func ensureWorktreeConfig(parentPath, worktreePath string) error {
+	for _, name := range []string{config.ConfigFileName, config.ConfigFileNameLegacy} {
+		if _, err := os.Stat(filepath.Join(worktreePath, name)); err == nil {
+			return nil
+		}
+	}
+	for _, name := range []string{config.ConfigFileName, config.ConfigFileNameLegacy} {
+		data, err := os.ReadFile(filepath.Join(parentPath, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(worktreePath, name), data, 0644)
+	}
+	return nil
+}
+
+// worktreeOverlay returns the .magebox.local.yaml of a worktree project: the
+// parent's name, PHP version and domains made its own, and the database it
+// uses. The domains come from the parent's effective config, so overrides of
+// the parent's own .magebox.local.yaml carry over.
+func worktreeOverlay(parent *config.Config, branch, slug string, db *dbInfo, database string) ([]byte, error) {
+	overlay := struct {
+		Name     string                       `yaml:"name"`
+		PHP      string                       `yaml:"php,omitempty"`
+		Domains  []config.Domain              `yaml:"domains"`
+		Services map[string]map[string]string `yaml:"services,omitempty"`
+	}{
+		Name: parent.Name + "-" + slug,
+		PHP:  parent.PHP,
+	}
+	for _, d := range parent.Domains {
+		d.Host = slug + "." + d.Host
+		overlay.Domains = append(overlay.Domains, d)
+	}
+	if db != nil {
+		// The version pins the parent's database service, which the
+		// branch's .magebox.yaml may not match
+		overlay.Services = map[string]map[string]string{
+			db.Type: {"version": db.Version, "database": database},
+		}
+	}
+
+	data, err := yaml.Marshal(overlay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode local config: %w", err)
+	}
+	header := fmt.Sprintf("# Worktree project of %s for branch %s, written by magebox worktree add\n", parent.Name, branch)
+	return append([]byte(header), data...), nil
+}
+
+// cloneDatabase copies a database to a new one in the same container
+func cloneDatabase(db *dbInfo, source, target string) error {
+	createCmd := exec.Command("docker", "exec", db.ContainerName,
+		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		fmt.Sprintf("CREATE DATABASE `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", target))
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create database %s: %s", target, strings.TrimSpace(string(output)))
+	}
+
+	dumpCmd := exec.Command("docker", "exec", db.ContainerName,
+		"mysqldump", "-uroot", "-p"+docker.DefaultDBRootPassword, "--no-tablespaces", "--single-transaction", source)
+	importCmd := exec.Command("docker", "exec", "-i", db.ContainerName,
+		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, target)
+	dumpCmd.Stderr = os.Stderr
+	importCmd.Stderr = os.Stderr
+
+	dump, err := dumpCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	importCmd.Stdin = dump
+	if err := dumpCmd.Start(); err != nil {
+		return fmt.Errorf("dump failed: %w", err)
+	}
+	importErr := importCmd.Run()
+	if err := dumpCmd.Wait(); err != nil {
+		return fmt.Errorf("dump failed: %w", err)
+	}
+	if importErr != nil {
+		return fmt.Errorf("import failed: %w", importErr)
+	}
+	return nil
+}
+
+// dropDatabase drops a database as root
+func dropDatabase(db *dbInfo, name string) error {
+	dropCmd := exec.Command("docker", "exec", db.ContainerName,
+		"mysql", "-uroot", "-p"+docker.DefaultDBRootPassword, "-e",
+		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name))
+	if output, err := dropCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to drop database %s: %s", name, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// linkWorktreeMedia shares the parent's pub/media with a worktree: the whole
+// directory when the worktree has none, otherwise every entry the worktree
+// lacks, so the files git tracks there stay in place
+func linkWorktreeMedia(parentPath, worktreePath string) error {
+	src := filepath.Join(parentPath, "pub", "media")
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	dst := filepath.Join(worktreePath, "pub", "media")
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(src, dst)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		target := filepath.Join(dst, entry.Name())
+		if _, err := os.Lstat(target); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(src, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlinkWorktreeMedia removes the links of linkWorktreeMedia, so removing the
+// worktree can't reach into the parent's media
+func unlinkWorktreeMedia(worktreePath string) error {
+	dst := filepath.Join(worktreePath, "pub", "media")
+	info, err := os.Lstat(dst)
+	if err != nil {
+		return nil
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return os.Remove(dst)
+	}
+
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink != 0 {
+			if err := os.Remove(filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyWorktreeEnvPHP gives a worktree the parent's app/etc/env.php rewritten
+// for the worktree's database and services. The crypt key is kept, so the
+// encrypted settings of a shared or cloned database still decrypt.
+func copyWorktreeEnvPHP(mgr *project.Manager, parentPath, worktreePath string) error {
+	data, err := os.ReadFile(filepath.Join(parentPath, "app", "etc", "env.php"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	envPath := filepath.Join(worktreePath, "app", "etc", "env.php")
+	if _, err := os.Stat(envPath); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Dir(envPath)); err != nil {
+		return nil // Not a Magento checkout
+	}
+	if err := os.WriteFile(envPath, data, 0644); err != nil {
+		return err
+	}
+
+	backup, err := mgr.GenerateEnvPHP(worktreePath)
+	if backup != "" {
+		_ = os.Remove(backup)
+	}
+	return err
+}
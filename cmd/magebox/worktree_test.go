@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestWorktreeSlug(t *testing.T) {
+	tests := map[string]string{
+		"feature/checkout":    "feature-checkout",
+		"Feature/Checkout_v2": "feature-checkout-v2",
+		"hotfix":              "hotfix",
+		"--fix--":             "fix",
+		"///":                 "",
+	}
+	for branch, want := range tests {
+		if got := worktreeSlug(branch); got != want {
+			t.Errorf("worktreeSlug(%q) = %q, want %q", branch, got, want)
+		}
+	}
+}
+
+func TestWorktreeOverlay(t *testing.T) {
+	parent := &config.Config{
+		Name: "mystore",
+		PHP:  "8.3",
+		Domains: []config.Domain{
+			{Host: "mystore.test"},
+			{Host: "de.mystore.test", MageRunCode: "de"},
+		},
+	}
+	db := &dbInfo{Type: "mariadb", Version: "10.6"}
+
+	data, err := worktreeOverlay(parent, "feature/x", "feature-x", db, "mystore_feature_x")
+	if err != nil {
+		t.Fatalf("worktreeOverlay failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Worktree project of mystore for branch feature/x") {
+		t.Errorf("overlay has no header:\n%s", data)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("overlay is not a config: %v\n%s", err, data)
+	}
+	if cfg.Name != "mystore-feature-x" || cfg.PHP != "8.3" {
+		t.Errorf("name, php = %q, %q", cfg.Name, cfg.PHP)
+	}
+	if hosts := cfg.Hosts(); len(hosts) != 2 || hosts[0] != "feature-x.mystore.test" || hosts[1] != "feature-x.de.mystore.test" {
+		t.Errorf("hosts = %v", hosts)
+	}
+	if cfg.Domains[1].MageRunCode != "de" {
+		t.Errorf("domain settings were dropped: %+v", cfg.Domains[1])
+	}
+	if cfg.Services.MariaDB == nil || cfg.Services.MariaDB.Version != "10.6" || cfg.DatabaseName() != "mystore_feature_x" {
+		t.Errorf("database = %+v", cfg.Services.MariaDB)
+	}
+	if parent.Domains[0].Host != "mystore.test" {
+		t.Errorf("parent domains were changed: %v", parent.Hosts())
+	}
+
+	data, err = worktreeOverlay(parent, "x", "x", nil, "")
+	if err != nil {
+		t.Fatalf("worktreeOverlay without database failed: %v", err)
+	}
+	if strings.Contains(string(data), "services") {
+		t.Errorf("overlay of a project without database has services:\n%s", data)
+	}
+}
+
+func TestLinkWorktreeMedia(t *testing.T) {
+	tmpDir := t.TempDir()
+	parent := filepath.Join(tmpDir, "shop")
+	for _, dir := range []string{"catalog", "wysiwyg"} {
+		if err := os.MkdirAll(filepath.Join(parent, "pub", "media", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(parent, "pub", "media", ".htaccess"), []byte("parent"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without pub/media the worktree links the whole directory
+	bare := filepath.Join(tmpDir, "bare")
+	if err := linkWorktreeMedia(parent, bare); err != nil {
+		t.Fatalf("linkWorktreeMedia failed: %v", err)
+	}
+	if info, err := os.Lstat(filepath.Join(bare, "pub", "media")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("pub/media is not a link: %v", err)
+	}
+	if err := unlinkWorktreeMedia(bare); err != nil {
+		t.Fatalf("unlinkWorktreeMedia failed: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(bare, "pub", "media")); !os.IsNotExist(err) {
+		t.Errorf("pub/media link was kept: %v", err)
+	}
+
+	// Files the worktree checks out stay, the rest is linked
+	tracked := filepath.Join(tmpDir, "tracked")
+	if err := os.MkdirAll(filepath.Join(tracked, "pub", "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tracked, "pub", "media", ".htaccess"), []byte("branch"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkWorktreeMedia(parent, tracked); err != nil {
+		t.Fatalf("linkWorktreeMedia failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tracked, "pub", "media", ".htaccess")); string(data) != "branch" {
+		t.Errorf(".htaccess = %q, want the worktree's", data)
+	}
+	if _, err := os.Stat(filepath.Join(tracked, "pub", "media", "catalog")); err != nil {
+		t.Errorf("catalog is not linked: %v", err)
+	}
+
+	if err := unlinkWorktreeMedia(tracked); err != nil {
+		t.Fatalf("unlinkWorktreeMedia failed: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(tracked, "pub", "media"))
+	if len(entries) != 1 || entries[0].Name() != ".htaccess" {
+		t.Errorf("pub/media after unlink = %v, want only .htaccess", entries)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "pub", "media", "catalog")); err != nil {
+		t.Errorf("parent media was removed: %v", err)
+	}
+}
+
+func TestGitWorktreeAddRejectsInvalidBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+
+	for _, branch := range []string{"--detach", "-b", "feature..x", "feature x"} {
+		path := filepath.Join(t.TempDir(), "wt")
+		if output, err := gitWorktreeAdd(repo, path, branch); err == nil || !strings.Contains(output, "invalid branch name") {
+			t.Errorf("gitWorktreeAdd(%q) = %q, %v, want it refused", branch, output, err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("gitWorktreeAdd(%q) created %s", branch, path)
+		}
+	}
+}
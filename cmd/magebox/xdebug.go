@@ -5,6 +5,9 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -15,13 +18,19 @@ import (
 )
 
 var xdebugCmd = &cobra.Command{
-	Use:   "xdebug",
-	Short: "Manage Xdebug for PHP",
+	Use:     "xdebug",
+	Aliases: []string{"debug"},
+	Short:   "Manage Xdebug for PHP",
 	Long: `Enable, disable, or check status of Xdebug for PHP debugging.
 
 Use 'magebox xdebug on' to enable Xdebug for the current project's PHP version.
 Use 'magebox xdebug off' to disable Xdebug.
-Use 'magebox xdebug status' to check current Xdebug status.`,
+Use 'magebox xdebug status' to check current Xdebug status.
+
+Xdebug and its mode are set per PHP version, the trigger per project:
+Use 'magebox xdebug mode profile' to profile instead of debug.
+Use 'magebox xdebug trigger off' to start Xdebug on every request.
+Use 'magebox xdebug listen' to set up the IDE and check it gets connections.`,
 	RunE: runXdebugStatus,
 }
 
@@ -46,10 +55,52 @@ var xdebugStatusCmd = &cobra.Command{
 	RunE:  runXdebugStatus,
 }
 
+var xdebugOutputDir string
+
+var xdebugModeCmd = &cobra.Command{
+	Use:   "mode <debug|profile|trace|coverage>",
+	Short: "Set the Xdebug mode of the project",
+	Long: `Sets xdebug.mode for the project's PHP version and restarts PHP-FPM.
+Xdebug reads the mode only at startup, so it applies to every project on
+that PHP version. The mode is kept in .magebox.local.yaml and set again by
+'magebox xdebug on'.
+
+Several modes are separated by commas. Profiles and traces are written to
+--output-dir, /tmp by default.
+
+Examples:
+  magebox xdebug mode profile --output-dir var/xdebug
+  magebox xdebug mode debug,trace
+  magebox xdebug mode debug`,
+	Args: cobra.ExactArgs(1),
+	RunE: runXdebugMode,
+}
+
+var xdebugTriggerCmd = &cobra.Command{
+	Use:   "trigger [on|off]",
+	Short: "Start Xdebug per request or on every request",
+	Long: `With the trigger on (the default) Xdebug only starts for requests that carry
+XDEBUG_TRIGGER (cookie, GET/POST parameter or environment variable), e.g. set by
+a browser extension. With the trigger off it starts on every request.
+
+Examples:
+  magebox xdebug trigger                          # only triggered requests
+  magebox xdebug trigger off                      # every request
+  magebox xdebug trigger --output-dir var/xdebug`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	RunE:      runXdebugTrigger,
+}
+
 func init() {
+	xdebugModeCmd.Flags().StringVar(&xdebugOutputDir, "output-dir", "", "Directory for profiles and traces (relative to the project)")
+	xdebugTriggerCmd.Flags().StringVar(&xdebugOutputDir, "output-dir", "", "Directory for profiles and traces (relative to the project)")
+
 	xdebugCmd.AddCommand(xdebugOnCmd)
 	xdebugCmd.AddCommand(xdebugOffCmd)
 	xdebugCmd.AddCommand(xdebugStatusCmd)
+	xdebugCmd.AddCommand(xdebugModeCmd)
+	xdebugCmd.AddCommand(xdebugTriggerCmd)
 	rootCmd.AddCommand(xdebugCmd)
 }
 
@@ -91,6 +142,13 @@ func runXdebugOn(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println(cli.Success("done"))
 
+	// The project's mode goes into the version's ini, Xdebug reads it at startup
+	if cfg, err := config.LoadFromPath(cwd); err == nil && cfg.Xdebug != nil && cfg.Xdebug.Mode != "" {
+		if _, err := mgr.SetMode(phpVersion, cfg.Xdebug.Mode); err != nil {
+			cli.PrintWarning("Failed to set the Xdebug mode: %v", err)
+		}
+	}
+
 	// Restart PHP-FPM
 	fmt.Print("Restarting PHP-FPM... ")
 	fpmCtrl := php.NewFPMController(p, phpVersion)
@@ -104,7 +162,7 @@ func runXdebugOn(cmd *cobra.Command, args []string) error {
 	cli.PrintSuccess("Xdebug enabled!")
 	fmt.Println()
 	fmt.Println("Configuration:")
-	fmt.Printf("  Mode:        %s\n", cli.Highlight("debug"))
+	fmt.Printf("  Mode:        %s\n", cli.Highlight(mgr.GetStatus(phpVersion).Mode))
 	fmt.Printf("  Client Host: %s\n", cli.Highlight("127.0.0.1"))
 	fmt.Printf("  Client Port: %s\n", cli.Highlight("9003"))
 	fmt.Printf("  IDE Key:     %s\n", cli.Highlight("PHPSTORM"))
@@ -202,6 +260,20 @@ func runXdebugStatus(cmd *cobra.Command, args []string) error {
 		fmt.Printf("INI Path:  %s\n", cli.Highlight(status.IniPath))
 	}
 
+	if cfg, err := config.LoadFromPath(cwd); err == nil && cfg.Xdebug != nil {
+		fmt.Println()
+		fmt.Println("Project settings:")
+		if cfg.Xdebug.Mode != "" {
+			fmt.Printf("  Mode:       %s\n", cli.Highlight(cfg.Xdebug.Mode))
+		}
+		if cfg.Xdebug.StartWithRequest != "" {
+			fmt.Printf("  Start:      %s\n", cli.Highlight(cfg.Xdebug.StartWithRequest))
+		}
+		if cfg.Xdebug.OutputDir != "" {
+			fmt.Printf("  Output Dir: %s\n", cli.Highlight(cfg.Xdebug.OutputDir))
+		}
+	}
+
 	fmt.Println()
 
 	if !status.Installed {
@@ -215,6 +287,128 @@ func runXdebugStatus(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runXdebugMode(cmd *cobra.Command, args []string) error {
+	modes, err := config.ParseXdebugMode(args[0])
+	if err != nil {
+		cli.PrintError("%v", err)
+		return nil
+	}
+	mode := strings.Join(modes, ",")
+
+	return updateXdebugSettings(func(settings map[string]interface{}) {
+		settings["mode"] = mode
+	}, fmt.Sprintf("Xdebug mode set to %s", mode))
+}
+
+func runXdebugTrigger(cmd *cobra.Command, args []string) error {
+	state := "on"
+	if len(args) > 0 {
+		state = args[0]
+	}
+
+	var start, message string
+	switch state {
+	case "on":
+		start, message = config.XdebugStartTrigger, "Xdebug starts for requests with XDEBUG_TRIGGER"
+	case "off":
+		start, message = config.XdebugStartYes, "Xdebug starts on every request"
+	default:
+		cli.PrintError("Unknown trigger state '%s' (expected on or off)", state)
+		return nil
+	}
+
+	return updateXdebugSettings(func(settings map[string]interface{}) {
+		settings["start_with_request"] = start
+	}, message)
+}
+
+// updateXdebugSettings changes the xdebug section of .magebox.local.yaml,
+// along with the output dir when --output-dir is given, and applies it to
+// the project's PHP-FPM pool and the mode to the PHP version's xdebug ini
+func updateXdebugSettings(update func(settings map[string]interface{}), message string) error {
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := loadProjectConfig(cwd); !ok {
+		return nil
+	}
+
+	localCfg, err := config.LoadLocalConfig(cwd)
+	if err != nil {
+		localCfg = &config.LocalConfig{}
+	}
+	if localCfg.Other == nil {
+		localCfg.Other = make(map[string]interface{})
+	}
+	settings, _ := localCfg.Other["xdebug"].(map[string]interface{})
+	if settings == nil {
+		settings = make(map[string]interface{})
+	}
+	update(settings)
+
+	if xdebugOutputDir != "" {
+		outputDir := xdebugOutputDir
+		if !filepath.IsAbs(outputDir) {
+			outputDir = filepath.Join(cwd, outputDir)
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			cli.PrintError("Failed to create %s: %v", outputDir, err)
+			return nil
+		}
+		settings["output_dir"] = outputDir
+	}
+
+	localCfg.Other["xdebug"] = settings
+	if err := config.SaveLocalConfig(cwd, localCfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	applyPoolSettings(p, cfg, cwd)
+
+	// Xdebug reads the mode only at startup, so it is set for the PHP
+	// version and PHP-FPM restarts to pick it up
+	mgr := xdebug.NewManager(p)
+	modeChanged := false
+	if cfg.Xdebug.Mode != "" && mgr.IsInstalled(cfg.PHP) {
+		changed, err := mgr.SetMode(cfg.PHP, cfg.Xdebug.Mode)
+		if err != nil {
+			cli.PrintError("Failed to set the Xdebug mode for PHP %s: %v", cfg.PHP, err)
+			return nil
+		}
+		if changed {
+			modeChanged = true
+			if err := php.NewFPMController(p, cfg.PHP).Reload(); err != nil {
+				cli.PrintWarning("Failed to restart PHP-FPM %s (may need manual restart): %v", cfg.PHP, err)
+			}
+		}
+	}
+
+	fmt.Println()
+	cli.PrintSuccess("%s", message)
+	if cfg.Xdebug.OutputDir != "" {
+		fmt.Printf("  Output Dir: %s\n", cli.Highlight(cfg.Xdebug.OutputDir))
+	}
+	if modeChanged {
+		cli.PrintInfo("The mode applies to every project on PHP %s", cfg.PHP)
+	}
+	if !mgr.IsEnabled(cfg.PHP) {
+		cli.PrintInfo("Xdebug is not loaded for PHP %s, enable it with: magebox xdebug on", cfg.PHP)
+	}
+	return nil
+}
+
 // getProjectPHPVersion gets the PHP version from the project config
 func getProjectPHPVersion(cwd string) (string, error) {
 	cfg, err := config.LoadFromPath(cwd)
@@ -0,0 +1,172 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"qoliber/magebox/internal/cli"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/xdebug"
+)
+
+var xdebugListenCmd = &cobra.Command{
+	Use:   "listen [phpstorm|vscode]",
+	Short: "Set up the IDE for step debugging",
+	Long: `Prints the settings the IDE needs to accept Xdebug connections of the
+project: client port, IDE key, server name and path mapping. Then checks that
+Xdebug is loaded in debug mode and opens a test connection to the IDE, which
+succeeds when it listens for debug connections.
+
+Naming an IDE writes its project settings first:
+
+  phpstorm   adds the project's first domain as a PHP server with a path
+             mapping to .idea/workspace.xml (close the project first)
+  vscode     adds a "Listen for Xdebug" configuration to .vscode/launch.json
+
+Also available as 'magebox debug listen'.
+
+Examples:
+  magebox xdebug listen
+  magebox debug listen vscode
+  magebox debug listen phpstorm`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"phpstorm", "vscode"},
+	RunE:      runXdebugListen,
+}
+
+func init() {
+	xdebugCmd.AddCommand(xdebugListenCmd)
+}
+
+func runXdebugListen(cmd *cobra.Command, args []string) error {
+	ide := ""
+	if len(args) > 0 {
+		ide = strings.ToLower(args[0])
+		if ide != "phpstorm" && ide != "vscode" {
+			cli.PrintError("Unknown IDE %q, use phpstorm or vscode", args[0])
+			return nil
+		}
+	}
+
+	cwd, err := getCwd()
+	if err != nil {
+		return err
+	}
+
+	cfg, ok := loadProjectConfig(cwd)
+	if !ok {
+		return nil
+	}
+
+	p, err := getPlatform()
+	if err != nil {
+		return err
+	}
+
+	client := xdebug.DefaultXdebugConfig()
+	serverName, serverPort := "", 0
+	if len(cfg.Domains) > 0 {
+		serverName, serverPort = cfg.Domains[0].Host, 80
+		if cfg.Domains[0].IsSSLEnabled() {
+			serverPort = 443
+		}
+	}
+
+	cli.PrintTitle("Xdebug Client Settings")
+	fmt.Printf("  Client host:  %s\n", cli.Highlight(client.ClientHost))
+	fmt.Printf("  Client port:  %s\n", cli.Highlight(client.ClientPort))
+	fmt.Printf("  IDE key:      %s\n", cli.Highlight(client.IdeKey))
+	if serverName != "" {
+		fmt.Printf("  Server name:  %s\n", cli.Highlight(serverName))
+	}
+	fmt.Printf("  Path mapping: %s → %s\n", cli.Highlight(cwd), cli.Highlight(cwd))
+	fmt.Println()
+
+	switch ide {
+	case "phpstorm":
+		if serverName == "" {
+			cli.PrintError("The project has no domain to name the PHP server after")
+			return nil
+		}
+		written, err := xdebug.WritePhpStormServer(cwd, xdebug.PhpStormServer{Name: serverName, Port: serverPort, ProjectPath: cwd})
+		switch {
+		case err != nil:
+			cli.PrintError("%v", err)
+			return nil
+		case written:
+			cli.PrintSuccess("Added PHP server %s to .idea/workspace.xml", serverName)
+		default:
+			cli.PrintInfo("PHP server %s already in .idea/workspace.xml", serverName)
+		}
+		fmt.Println()
+	case "vscode":
+		written, err := xdebug.WriteVSCodeLaunch(cwd, client.ClientPort)
+		switch {
+		case err != nil:
+			cli.PrintError("%v", err)
+			return nil
+		case written:
+			cli.PrintSuccess("Added %q to .vscode/launch.json", xdebug.VSCodeLaunchName)
+		default:
+			cli.PrintInfo("%q already in .vscode/launch.json", xdebug.VSCodeLaunchName)
+		}
+		fmt.Println()
+	}
+
+	cli.PrintTitle("Checks")
+	status := xdebug.NewManager(p).GetStatus(cfg.PHP)
+	mode := status.Mode
+	if cfg.Xdebug != nil && cfg.Xdebug.Mode != "" {
+		mode = cfg.Xdebug.Mode
+	}
+	if mode == "" {
+		mode = "develop" // Xdebug's default
+	}
+
+	var hints []string
+	switch {
+	case !status.Installed:
+		fmt.Printf("  %s Xdebug is not installed for PHP %s\n", cli.Error(""), cfg.PHP)
+		hints = append(hints, "magebox ext install xdebug")
+	case !status.Enabled:
+		fmt.Printf("  %s Xdebug is not enabled for PHP %s\n", cli.Error(""), cfg.PHP)
+		hints = append(hints, "magebox xdebug on")
+	case !slices.Contains(strings.Split(strings.ReplaceAll(mode, " ", ""), ","), "debug"):
+		fmt.Printf("  %s Xdebug mode is %s, step debugging needs debug\n", cli.Error(""), mode)
+		hints = append(hints, "magebox xdebug mode debug")
+	default:
+		fmt.Printf("  %s Xdebug is enabled for PHP %s in %s mode\n", cli.Success(""), cfg.PHP, mode)
+	}
+
+	if err := xdebug.CheckClient(client.ClientHost, client.ClientPort, 2*time.Second); err != nil {
+		fmt.Printf("  %s No IDE listens on port %s\n", cli.Error(""), client.ClientPort)
+	} else {
+		fmt.Printf("  %s The IDE accepts connections on port %s\n", cli.Success(""), client.ClientPort)
+	}
+	fmt.Println()
+
+	for _, hint := range hints {
+		cli.PrintInfo("Run %s", cli.Command(hint))
+	}
+	switch ide {
+	case "vscode":
+		cli.PrintInfo("Start %q in the Run and Debug view (needs the PHP Debug extension)", xdebug.VSCodeLaunchName)
+	default:
+		cli.PrintInfo("In PhpStorm: Run → Start Listening for PHP Debug Connections")
+	}
+	if cfg.Xdebug == nil || cfg.Xdebug.StartWithRequest != config.XdebugStartYes {
+		cli.PrintInfo("Requests need XDEBUG_TRIGGER, e.g. from a browser extension (%s debugs every request)", cli.Command("magebox xdebug trigger off"))
+	}
+	if serverName != "" {
+		cli.PrintInfo("For CLI scripts in PhpStorm: export PHP_IDE_CONFIG=\"serverName=%s\"", serverName)
+	}
+
+	return nil
+}
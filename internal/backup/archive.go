@@ -0,0 +1,328 @@
+// Package backup reads and writes project backup archives.
+//
+// A backup is a tar archive, compressed with zstd (.tar.zst) or gzip
+// (.tar.gz), holding everything needed to bring a project up on another
+// machine next to its git checkout:
+//
+//	manifest.json           what the archive contains, always the first entry
+//	database/<name>.sql     one dump per project database
+//	certs/<domain>/*.pem    the mkcert certificates of the project domains
+//	project/...             files relative to the project root: the MageBox
+//	                        config, app/etc/env.php and optionally pub/media
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archive entry names and prefixes
+const (
+	ManifestName   = "manifest.json"
+	DatabasePrefix = "database/"
+	CertsPrefix    = "certs/"
+	ProjectPrefix  = "project/"
+)
+
+// FormatVersion is the archive layout version written to the manifest
+const FormatVersion = 1
+
+// zstdMagic starts every zstd frame
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Manifest describes the contents of a backup
+type Manifest struct {
+	Version   int       `json:"version"`
+	Project   string    `json:"project"`
+	CreatedAt time.Time `json:"created_at"`
+	MageBox   string    `json:"magebox"`
+	Databases []string  `json:"databases,omitempty"`
+	Certs     []string  `json:"certs,omitempty"` // base domains
+	Media     bool      `json:"media"`
+}
+
+// DatabaseEntry returns the archive entry name of a database dump
+func DatabaseEntry(name string) string {
+	return DatabasePrefix + name + ".sql"
+}
+
+// UsesZstd reports whether an archive path asks for zstd compression.
+// Everything but .tar.gz and .tgz does.
+func UsesZstd(archivePath string) bool {
+	return !strings.HasSuffix(archivePath, ".tar.gz") && !strings.HasSuffix(archivePath, ".tgz")
+}
+
+// Writer writes a backup archive
+type Writer struct {
+	file  *os.File
+	gzip  *gzip.Writer
+	zstd  *exec.Cmd
+	pipe  io.WriteCloser
+	tar   *tar.Writer
+	count int
+}
+
+// Create starts a backup archive at archivePath. zstd compression runs the
+// zstd binary, see UsesZstd.
+func Create(archivePath string) (*Writer, error) {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{file: file}
+	if UsesZstd(archivePath) {
+		w.zstd = exec.Command("zstd", "-q", "-T0")
+		w.zstd.Stdout = file
+		w.zstd.Stderr = os.Stderr
+		w.pipe, err = w.zstd.StdinPipe()
+		if err == nil {
+			err = w.zstd.Start()
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(archivePath)
+			return nil, fmt.Errorf("failed to start zstd: %w", err)
+		}
+		w.tar = tar.NewWriter(w.pipe)
+	} else {
+		w.gzip = gzip.NewWriter(file)
+		w.tar = tar.NewWriter(w.gzip)
+	}
+	return w, nil
+}
+
+// Count returns the number of files written so far
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// WriteManifest writes the manifest. It must be the first entry.
+func (w *Writer) WriteManifest(m *Manifest) error {
+	if w.count != 0 {
+		return errors.New("manifest must be the first archive entry")
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.AddReader(ManifestName, int64(len(data)), 0644, bytes.NewReader(data))
+}
+
+// AddReader writes an entry of size bytes read from r
+func (w *Writer) AddReader(name string, size int64, mode fs.FileMode, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode.Perm()),
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := w.tar.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(w.tar, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	w.count++
+	return nil
+}
+
+// AddFile writes the file at src as entry name
+func (w *Writer) AddFile(name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return w.AddReader(name, info.Size(), info.Mode(), f)
+}
+
+// AddDir writes the regular files below dir under prefix. skip is called
+// with the slash-separated path relative to dir; returning true leaves out
+// the file, or the whole directory.
+func (w *Writer) AddDir(prefix, dir string, skip func(rel string) bool) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip != nil && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return w.AddFile(path.Join(prefix, rel), p)
+	})
+}
+
+// Close finishes the archive
+func (w *Writer) Close() error {
+	err := w.tar.Close()
+	if w.gzip != nil {
+		if cerr := w.gzip.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if w.zstd != nil {
+		if cerr := w.pipe.Close(); err == nil {
+			err = cerr
+		}
+		if cerr := w.zstd.Wait(); err == nil && cerr != nil {
+			err = fmt.Errorf("zstd failed: %w", cerr)
+		}
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Walk calls fn for every entry of the archive at archivePath, in archive
+// order. The compression is detected from the content, not the file name.
+// Returning an error from fn stops the walk and returns that error.
+func Walk(archivePath string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(zstdMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return fmt.Errorf("%s is not a backup archive", archivePath)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var stream io.Reader
+	var zstd *exec.Cmd
+	done := false
+	if bytes.Equal(magic, zstdMagic) {
+		zstd = exec.Command("zstd", "-q", "-d", "-c")
+		zstd.Stdin = file
+		zstd.Stderr = os.Stderr
+		out, err := zstd.StdoutPipe()
+		if err == nil {
+			err = zstd.Start()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to start zstd: %w", err)
+		}
+		defer func() {
+			// Stop zstd when the walk ends early
+			if !done {
+				_ = zstd.Process.Kill()
+				_ = zstd.Wait()
+			}
+		}()
+		stream = out
+	} else {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s is not a backup archive", archivePath)
+		}
+		defer gz.Close()
+		stream = gz
+	}
+
+	tr := tar.NewReader(stream)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			if zstd != nil {
+				done = true
+				_, _ = io.Copy(io.Discard, stream)
+				if err := zstd.Wait(); err != nil {
+					return fmt.Errorf("zstd failed: %w", err)
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// errManifestRead stops the walk of ReadManifest after the first entry
+var errManifestRead = errors.New("manifest read")
+
+// ReadManifest reads the manifest of the archive at archivePath
+func ReadManifest(archivePath string) (*Manifest, error) {
+	var m *Manifest
+	err := Walk(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name != ManifestName {
+			return fmt.Errorf("%s has no manifest, it is not a MageBox backup", archivePath)
+		}
+		m = &Manifest{}
+		if err := json.NewDecoder(r).Decode(m); err != nil {
+			return fmt.Errorf("invalid manifest: %w", err)
+		}
+		return errManifestRead
+	})
+	if err != nil && !errors.Is(err, errManifestRead) {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("%s is empty", archivePath)
+	}
+	if m.Version > FormatVersion {
+		return nil, fmt.Errorf("backup format %d is newer than this MageBox supports (%d), update MageBox", m.Version, FormatVersion)
+	}
+	return m, nil
+}
+
+// SafePath returns the path an archive entry name, stripped of its prefix,
+// extracts to below dir. Names that would escape dir are rejected.
+func SafePath(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid file path in archive: %s", name)
+	}
+	return target, nil
+}
+
+// ExtractTo writes an entry read from r to target, creating its directory
+func ExtractTo(target string, hdr *tar.Header, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, name := range []string{"backup.tar.gz", "backup.tar.zst"} {
+		t.Run(name, func(t *testing.T) {
+			if UsesZstd(name) {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd not installed")
+				}
+			}
+
+			src := t.TempDir()
+			mediaDir := filepath.Join(src, "pub", "media")
+			writeFile(t, filepath.Join(src, "app", "etc", "env.php"), "<?php return [];")
+			writeFile(t, filepath.Join(mediaDir, "catalog", "product", "a.jpg"), "jpg")
+			writeFile(t, filepath.Join(mediaDir, "catalog", "product", "cache", "b.jpg"), "cached")
+
+			archivePath := filepath.Join(t.TempDir(), name)
+			w, err := Create(archivePath)
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			manifest := &Manifest{
+				Version:   FormatVersion,
+				Project:   "mystore",
+				CreatedAt: time.Now().UTC().Truncate(time.Second),
+				Databases: []string{"mystore"},
+				Media:     true,
+			}
+			if err := w.WriteManifest(manifest); err != nil {
+				t.Fatalf("WriteManifest failed: %v", err)
+			}
+			if err := w.AddReader(DatabaseEntry("mystore"), 6, 0644, strings.NewReader("SELECT")); err != nil {
+				t.Fatalf("AddReader failed: %v", err)
+			}
+			if err := w.AddFile(ProjectPrefix+"app/etc/env.php", filepath.Join(src, "app", "etc", "env.php")); err != nil {
+				t.Fatalf("AddFile failed: %v", err)
+			}
+			skipCache := func(rel string) bool { return rel == "catalog/product/cache" }
+			if err := w.AddDir(ProjectPrefix+"pub/media", mediaDir, skipCache); err != nil {
+				t.Fatalf("AddDir failed: %v", err)
+			}
+			if err := w.WriteManifest(manifest); err == nil {
+				t.Error("WriteManifest after other entries should fail")
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			got, err := ReadManifest(archivePath)
+			if err != nil {
+				t.Fatalf("ReadManifest failed: %v", err)
+			}
+			if got.Project != "mystore" || !got.Media || !got.CreatedAt.Equal(manifest.CreatedAt) {
+				t.Errorf("ReadManifest() = %+v", got)
+			}
+
+			entries := map[string]string{}
+			err = Walk(archivePath, func(hdr *tar.Header, r io.Reader) error {
+				data, err := io.ReadAll(r)
+				entries[hdr.Name] = string(data)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("Walk failed: %v", err)
+			}
+			want := map[string]string{
+				ManifestName:                              "",
+				"database/mystore.sql":                    "SELECT",
+				"project/app/etc/env.php":                 "<?php return [];",
+				"project/pub/media/catalog/product/a.jpg": "jpg",
+			}
+			if len(entries) != len(want) {
+				t.Errorf("archive entries = %v", entries)
+			}
+			for name, content := range want {
+				got, ok := entries[name]
+				if !ok {
+					t.Errorf("missing entry %s", name)
+				} else if name != ManifestName && got != content {
+					t.Errorf("%s = %q, want %q", name, got, content)
+				}
+			}
+		})
+	}
+}
+
+func TestReadManifest_NotABackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "random.tar.gz")
+	writeFile(t, path, "definitely not an archive")
+	if _, err := ReadManifest(path); err == nil {
+		t.Error("ReadManifest() of a non-archive should fail")
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "nomanifest.tar.gz")
+	w, err := Create(archivePath)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := w.AddReader("other.txt", 2, 0644, strings.NewReader("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(archivePath); err == nil || !strings.Contains(err.Error(), "no manifest") {
+		t.Errorf("ReadManifest() without a manifest = %v", err)
+	}
+}
+
+func TestSafePath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"app/etc/env.php", false},
+		{".magebox.yaml", false},
+		{"../outside", true},
+		{"app/../../outside", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SafePath(dir, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SafePath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != filepath.Join(dir, tt.name) {
+				t.Errorf("SafePath(%q) = %q", tt.name, got)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
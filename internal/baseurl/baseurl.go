@@ -0,0 +1,196 @@
+// Package baseurl maps the domains of a project to the Magento base URL
+// settings in core_config_data.
+//
+// The default scope gets the first domain without a mage_run_code; every
+// domain with one sets the base URLs of its store view or website. Absolute
+// link, media and static URLs are removed, so Magento derives them from the
+// base URL, and so is the cookie domain of the production site.
+package baseurl
+
+import (
+	"fmt"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+)
+
+// Config scopes of core_config_data
+const (
+	ScopeDefault  = "default"
+	ScopeStores   = "stores"
+	ScopeWebsites = "websites"
+)
+
+// Paths are the base URL settings written for every target
+var Paths = []string{"web/unsecure/base_url", "web/secure/base_url"}
+
+// DerivedPaths are removed when they hold an absolute URL, Magento derives
+// them from the base URL again
+var DerivedPaths = []string{
+	"web/unsecure/base_link_url",
+	"web/secure/base_link_url",
+	"web/unsecure/base_media_url",
+	"web/secure/base_media_url",
+	"web/unsecure/base_static_url",
+	"web/secure/base_static_url",
+}
+
+// Target is the base URL of one config scope
+type Target struct {
+	Scope string // default, stores or websites
+	Code  string // store or website code, empty for the default scope
+	Host  string
+	URL   string // e.g. https://mystore.test/
+}
+
+// Label returns the scope of a target as shown to the user, e.g. "store de"
+func (t Target) Label() string {
+	switch t.Scope {
+	case ScopeStores:
+		return "store " + t.Code
+	case ScopeWebsites:
+		return "website " + t.Code
+	default:
+		return ScopeDefault
+	}
+}
+
+// URL returns the base URL of a domain
+func URL(d config.Domain) string {
+	scheme := "https"
+	if !d.IsSSLEnabled() {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/", scheme, d.Host)
+}
+
+// Targets returns the base URL targets of the domains: the default scope
+// first, then a store or website per run code. The first domain of a run
+// code wins, further domains of it are aliases.
+func Targets(domains []config.Domain) []Target {
+	if len(domains) == 0 {
+		return nil
+	}
+
+	def := domains[0]
+	for _, d := range domains {
+		if d.MageRunCode == "" {
+			def = d
+			break
+		}
+	}
+	targets := []Target{{Scope: ScopeDefault, Host: def.Host, URL: URL(def)}}
+
+	seen := make(map[string]bool)
+	for _, d := range domains {
+		if d.MageRunCode == "" {
+			continue
+		}
+		scope := ScopeStores
+		if d.GetMageRunType() == "website" {
+			scope = ScopeWebsites
+		}
+		if seen[scope+"/"+d.MageRunCode] {
+			continue
+		}
+		seen[scope+"/"+d.MageRunCode] = true
+		targets = append(targets, Target{Scope: scope, Code: d.MageRunCode, Host: d.Host, URL: URL(d)})
+	}
+	return targets
+}
+
+// Statements returns the SQL writing the base URLs of the targets. prefix is
+// the table prefix of app/etc/env.php. A store or website code the database
+// doesn't have writes nothing.
+func Statements(targets []Target, prefix string) []string {
+	ccd := "`" + prefix + "core_config_data`"
+
+	var stmts []string
+	for _, t := range targets {
+		for _, path := range Paths {
+			switch t.Scope {
+			case ScopeDefault:
+				stmts = append(stmts, fmt.Sprintf(
+					"INSERT INTO %s (scope, scope_id, path, value) VALUES ('default', 0, %s, %s) ON DUPLICATE KEY UPDATE value = VALUES(value)",
+					ccd, quote(path), quote(t.URL)))
+			default:
+				table, id := "store", "store_id"
+				if t.Scope == ScopeWebsites {
+					table, id = "store_website", "website_id"
+				}
+				stmts = append(stmts, fmt.Sprintf(
+					"INSERT INTO %s (scope, scope_id, path, value) SELECT '%s', %s, %s, %s FROM `%s%s` WHERE code = %s ON DUPLICATE KEY UPDATE value = VALUES(value)",
+					ccd, t.Scope, id, quote(path), quote(t.URL), prefix, table, quote(t.Code)))
+			}
+		}
+	}
+
+	derived := make([]string, len(DerivedPaths))
+	for i, path := range DerivedPaths {
+		derived[i] = quote(path)
+	}
+	return append(stmts,
+		fmt.Sprintf("DELETE FROM %s WHERE path IN (%s) AND value LIKE 'http%%'", ccd, strings.Join(derived, ", ")),
+		fmt.Sprintf("DELETE FROM %s WHERE path = 'web/cookie/cookie_domain'", ccd))
+}
+
+// MagentoArgs returns the bin/magento config:set commands writing the base
+// URLs of a target
+func MagentoArgs(t Target) [][]string {
+	var commands [][]string
+	for _, path := range Paths {
+		args := []string{"config:set"}
+		if t.Scope != ScopeDefault {
+			args = append(args, "--scope="+t.Scope, "--scope-code="+t.Code)
+		}
+		commands = append(commands, append(args, path, t.URL))
+	}
+	return commands
+}
+
+// Query returns the SQL selecting the base URL settings: scope, store or
+// website code, path and value
+func Query(prefix string) string {
+	return fmt.Sprintf(
+		"SELECT c.scope, COALESCE(s.code, w.code, ''), c.path, c.value FROM `%[1]score_config_data` c "+
+			"LEFT JOIN `%[1]sstore` s ON c.scope = 'stores' AND s.store_id = c.scope_id "+
+			"LEFT JOIN `%[1]sstore_website` w ON c.scope = 'websites' AND w.website_id = c.scope_id "+
+			"WHERE c.path IN ('web/unsecure/base_url', 'web/secure/base_url') ORDER BY c.scope, c.scope_id, c.path",
+		prefix)
+}
+
+// Setting is a base URL setting in the database
+type Setting struct {
+	Scope string
+	Code  string
+	Path  string
+	Value string
+}
+
+// ParseSettings parses the rows of Query
+func ParseSettings(rows [][]string) []Setting {
+	var settings []Setting
+	for _, row := range rows {
+		if len(row) != 4 {
+			continue
+		}
+		settings = append(settings, Setting{Scope: row[0], Code: row[1], Path: row[2], Value: row[3]})
+	}
+	return settings
+}
+
+// Current returns the value of a base URL path of a target in settings,
+// empty when it isn't set in that scope
+func (t Target) Current(settings []Setting, path string) string {
+	for _, s := range settings {
+		if s.Scope == t.Scope && s.Path == path && (t.Scope == ScopeDefault || s.Code == t.Code) {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// quote returns a SQL string literal
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
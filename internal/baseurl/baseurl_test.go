@@ -0,0 +1,118 @@
+package baseurl
+
+import (
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func testDomains() []config.Domain {
+	noSSL := false
+	return []config.Domain{
+		{Host: "de.mystore.test", MageRunCode: "de"},
+		{Host: "mystore.test"},
+		{Host: "www.de.mystore.test", MageRunCode: "de"},
+		{Host: "b2b.mystore.test", MageRunCode: "b2b", MageRunType: "website", SSL: &noSSL},
+	}
+}
+
+func TestTargets(t *testing.T) {
+	got := Targets(testDomains())
+	want := []Target{
+		{Scope: ScopeDefault, Host: "mystore.test", URL: "https://mystore.test/"},
+		{Scope: ScopeStores, Code: "de", Host: "de.mystore.test", URL: "https://de.mystore.test/"},
+		{Scope: ScopeWebsites, Code: "b2b", Host: "b2b.mystore.test", URL: "http://b2b.mystore.test/"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Targets() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Targets()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Without a domain lacking a run code, the first domain is the default
+	only := Targets([]config.Domain{{Host: "de.mystore.test", MageRunCode: "de"}})
+	if only[0].Scope != ScopeDefault || only[0].Host != "de.mystore.test" {
+		t.Errorf("Targets()[0] = %+v, want the first domain as default", only[0])
+	}
+	if Targets(nil) != nil {
+		t.Error("no domains have no targets")
+	}
+}
+
+func TestStatements(t *testing.T) {
+	stmts := Statements(Targets(testDomains()), "m2_")
+
+	want := []string{
+		"INSERT INTO `m2_core_config_data` (scope, scope_id, path, value) VALUES ('default', 0, 'web/unsecure/base_url', 'https://mystore.test/') ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		"INSERT INTO `m2_core_config_data` (scope, scope_id, path, value) VALUES ('default', 0, 'web/secure/base_url', 'https://mystore.test/') ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		"INSERT INTO `m2_core_config_data` (scope, scope_id, path, value) SELECT 'stores', store_id, 'web/unsecure/base_url', 'https://de.mystore.test/' FROM `m2_store` WHERE code = 'de' ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		"INSERT INTO `m2_core_config_data` (scope, scope_id, path, value) SELECT 'stores', store_id, 'web/secure/base_url', 'https://de.mystore.test/' FROM `m2_store` WHERE code = 'de' ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		"INSERT INTO `m2_core_config_data` (scope, scope_id, path, value) SELECT 'websites', website_id, 'web/unsecure/base_url', 'http://b2b.mystore.test/' FROM `m2_store_website` WHERE code = 'b2b' ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		"INSERT INTO `m2_core_config_data` (scope, scope_id, path, value) SELECT 'websites', website_id, 'web/secure/base_url', 'http://b2b.mystore.test/' FROM `m2_store_website` WHERE code = 'b2b' ON DUPLICATE KEY UPDATE value = VALUES(value)",
+	}
+	if len(stmts) != len(want)+2 {
+		t.Fatalf("Statements() =\n%s", strings.Join(stmts, "\n"))
+	}
+	for i := range want {
+		if stmts[i] != want[i] {
+			t.Errorf("statement %d =\n%s\nwant\n%s", i, stmts[i], want[i])
+		}
+	}
+	if !strings.Contains(stmts[6], "base_static_url") || !strings.Contains(stmts[6], "LIKE 'http%'") {
+		t.Errorf("absolute derived URLs should be removed: %s", stmts[6])
+	}
+	if !strings.Contains(stmts[7], "web/cookie/cookie_domain") {
+		t.Errorf("the cookie domain should be removed: %s", stmts[7])
+	}
+}
+
+func TestMagentoArgs(t *testing.T) {
+	targets := Targets(testDomains())
+
+	got := MagentoArgs(targets[1])
+	want := "config:set --scope=stores --scope-code=de web/secure/base_url https://de.mystore.test/"
+	if len(got) != 2 || strings.Join(got[1], " ") != want {
+		t.Errorf("MagentoArgs() = %v, want ... %s", got, want)
+	}
+	if def := MagentoArgs(targets[0]); strings.Join(def[0], " ") != "config:set web/unsecure/base_url https://mystore.test/" {
+		t.Errorf("MagentoArgs(default) = %v", def)
+	}
+}
+
+func TestTarget_Current(t *testing.T) {
+	settings := ParseSettings([][]string{
+		{"default", "", "web/secure/base_url", "https://www.shop.com/"},
+		{"stores", "de", "web/secure/base_url", "https://www.shop.de/"},
+		{"stores", "fr"},
+	})
+	targets := Targets(testDomains())
+
+	if got := targets[0].Current(settings, "web/secure/base_url"); got != "https://www.shop.com/" {
+		t.Errorf("Current(default) = %q", got)
+	}
+	if got := targets[1].Current(settings, "web/secure/base_url"); got != "https://www.shop.de/" {
+		t.Errorf("Current(store de) = %q", got)
+	}
+	if got := targets[2].Current(settings, "web/secure/base_url"); got != "" {
+		t.Errorf("Current(website b2b) = %q, want empty for an inherited URL", got)
+	}
+}
+
+func TestTarget_Label(t *testing.T) {
+	for _, tt := range []struct {
+		target Target
+		want   string
+	}{
+		{Target{Scope: ScopeDefault}, "default"},
+		{Target{Scope: ScopeStores, Code: "de"}, "store de"},
+		{Target{Scope: ScopeWebsites, Code: "b2b"}, "website b2b"},
+	} {
+		if got := tt.target.Label(); got != tt.want {
+			t.Errorf("Label() = %q, want %q", got, tt.want)
+		}
+	}
+}
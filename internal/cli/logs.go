@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,17 +16,25 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
-// LogTailer watches and tails multiple log files
+// LogTailer watches and tails multiple log files. Besides the files in its
+// log directory it can tail files and command output of named sources, e.g.
+// nginx or docker compose logs, printing each line with the source as prefix.
 type LogTailer struct {
-	logDir     string
-	pattern    string
-	follow     bool
-	lines      int
-	watcher    *fsnotify.Watcher
-	files      map[string]*tailedFile
-	mutex      sync.Mutex
-	stopChan   chan struct{}
-	levelRegex *regexp.Regexp
+	logDir      string
+	pattern     string
+	follow      bool
+	lines       int
+	watcher     *fsnotify.Watcher
+	files       map[string]*tailedFile
+	sourceFiles map[string]string // path → source
+	commands    []*sourceCommand
+	sources     []string // in the order they were added, for their colors
+	mutex       sync.Mutex
+	outMutex    sync.Mutex
+	out         io.Writer
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	levelRegex  *regexp.Regexp
 }
 
 // tailedFile represents a file being tailed
@@ -33,8 +43,18 @@ type tailedFile struct {
 	file   *os.File
 	offset int64
 	name   string
+	source string
 }
 
+// sourceCommand is a command whose output is tailed, e.g. docker compose logs
+type sourceCommand struct {
+	source string
+	cmd    *exec.Cmd
+}
+
+// sourceColors are the colors of the source prefixes
+var sourceColors = []string{Cyan, Green, Yellow, Blue, Magenta, BrightCyan, BrightGreen, BrightYellow, BrightBlue, BrightMagenta}
+
 // NewLogTailer creates a new log tailer
 func NewLogTailer(logDir string, pattern string, follow bool, lines int) *LogTailer {
 	// Regex to extract log level from Magento log format
@@ -42,25 +62,55 @@ func NewLogTailer(logDir string, pattern string, follow bool, lines int) *LogTai
 	levelRegex := regexp.MustCompile(`\]\s+\w+\.(\w+):`)
 
 	return &LogTailer{
-		logDir:     logDir,
-		pattern:    pattern,
-		follow:     follow,
-		lines:      lines,
-		files:      make(map[string]*tailedFile),
-		stopChan:   make(chan struct{}),
-		levelRegex: levelRegex,
+		logDir:      logDir,
+		pattern:     pattern,
+		follow:      follow,
+		lines:       lines,
+		files:       make(map[string]*tailedFile),
+		sourceFiles: make(map[string]string),
+		out:         os.Stdout,
+		stopChan:    make(chan struct{}),
+		levelRegex:  levelRegex,
 	}
 }
 
+// AddFile tails a file as part of a source. Files of a source that don't
+// exist yet are tailed once they are created.
+func (t *LogTailer) AddFile(source, path string) {
+	t.addSource(source)
+	t.sourceFiles[path] = source
+}
+
+// AddCommand tails the output of a command as a source. Without follow the
+// command should exit by itself, e.g. docker compose logs without -f.
+func (t *LogTailer) AddCommand(source string, cmd *exec.Cmd) {
+	t.addSource(source)
+	t.commands = append(t.commands, &sourceCommand{source: source, cmd: cmd})
+}
+
+// addSource registers a source name, which picks its color
+func (t *LogTailer) addSource(source string) {
+	for _, s := range t.sources {
+		if s == source {
+			return
+		}
+	}
+	t.sources = append(t.sources, source)
+}
+
 // Start begins tailing log files
 func (t *LogTailer) Start() error {
 	// Find matching log files
-	files, err := t.findLogFiles()
-	if err != nil {
-		return err
+	var files []string
+	if t.logDir != "" {
+		var err error
+		files, err = t.findLogFiles()
+		if err != nil {
+			return err
+		}
 	}
 
-	if len(files) == 0 {
+	if len(files) == 0 && len(t.sourceFiles) == 0 && len(t.commands) == 0 {
 		return fmt.Errorf("no log files found matching pattern '%s' in %s", t.pattern, t.logDir)
 	}
 
@@ -71,9 +121,34 @@ func (t *LogTailer) Start() error {
 			continue
 		}
 	}
+	var missing []string
+	for _, path := range t.sortedSourceFiles() {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			missing = append(missing, path)
+			continue
+		}
+		if err := t.initFile(path); err != nil {
+			PrintWarning("Could not read %s: %v", filepath.Base(path), err)
+		}
+	}
+
+	// Commands print their initial lines by themselves
+	var commands sync.WaitGroup
+	for _, c := range t.commands {
+		if err := t.startCommand(c, &commands); err != nil {
+			PrintWarning("Could not run %s logs: %v", c.source, err)
+		}
+	}
 
 	// If not following, we're done
 	if !t.follow {
+		commands.Wait()
+		return nil
+	}
+
+	// Only commands to follow: done when they are
+	if len(files) == 0 && len(t.sourceFiles) == 0 {
+		commands.Wait()
 		return nil
 	}
 
@@ -85,12 +160,25 @@ func (t *LogTailer) Start() error {
 	t.watcher = watcher
 
 	// Watch the log directory for new files
-	if err := watcher.Add(t.logDir); err != nil {
-		return fmt.Errorf("failed to watch directory: %w", err)
+	if t.logDir != "" {
+		if err := watcher.Add(t.logDir); err != nil {
+			return fmt.Errorf("failed to watch directory: %w", err)
+		}
+	}
+
+	// Watch the directories of source files that don't exist yet
+	for _, path := range missing {
+		_ = watcher.Add(filepath.Dir(path))
 	}
 
 	// Watch each log file
-	for _, path := range files {
+	t.mutex.Lock()
+	watched := make([]string, 0, len(t.files))
+	for path := range t.files {
+		watched = append(watched, path)
+	}
+	t.mutex.Unlock()
+	for _, path := range watched {
 		if err := watcher.Add(path); err != nil {
 			PrintWarning("Could not watch %s: %v", filepath.Base(path), err)
 		}
@@ -110,11 +198,17 @@ func (t *LogTailer) Start() error {
 
 // Stop stops the log tailer
 func (t *LogTailer) Stop() {
-	close(t.stopChan)
+	t.stopOnce.Do(func() { close(t.stopChan) })
 	if t.watcher != nil {
 		t.watcher.Close()
 	}
 
+	for _, c := range t.commands {
+		if c.cmd.Process != nil {
+			_ = c.cmd.Process.Kill()
+		}
+	}
+
 	// Close all open files
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
@@ -125,6 +219,54 @@ func (t *LogTailer) Stop() {
 	}
 }
 
+// startCommand starts a source command, printing its output lines until it
+// exits
+func (t *LogTailer) startCommand(c *sourceCommand, wg *sync.WaitGroup) error {
+	pr, pw := io.Pipe()
+	c.cmd.Stdout = pw
+	c.cmd.Stderr = pw
+	if err := c.cmd.Start(); err != nil {
+		return err
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_ = c.cmd.Wait()
+		pw.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+				t.printLogLine(c.source, line)
+			}
+		}
+		// Keep draining so the command never blocks on a full pipe
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return nil
+}
+
+// sortedSourceFiles returns the source files by source, in the order the
+// sources were added
+func (t *LogTailer) sortedSourceFiles() []string {
+	var paths []string
+	for _, source := range t.sources {
+		var sourcePaths []string
+		for path, s := range t.sourceFiles {
+			if s == source {
+				sourcePaths = append(sourcePaths, path)
+			}
+		}
+		sort.Strings(sourcePaths)
+		paths = append(paths, sourcePaths...)
+	}
+	return paths
+}
+
 // findLogFiles finds all log files matching the pattern
 func (t *LogTailer) findLogFiles() ([]string, error) {
 	var files []string
@@ -168,6 +310,7 @@ func (t *LogTailer) initFile(path string) error {
 	}
 
 	name := filepath.Base(path)
+	source := t.sourceFiles[path]
 
 	// Get file info for size
 	info, err := file.Stat()
@@ -180,6 +323,7 @@ func (t *LogTailer) initFile(path string) error {
 		path:   path,
 		file:   file,
 		name:   name,
+		source: source,
 		offset: 0,
 	}
 
@@ -187,9 +331,12 @@ func (t *LogTailer) initFile(path string) error {
 	if t.lines > 0 && info.Size() > 0 {
 		lines := t.readLastLines(file, t.lines)
 		if len(lines) > 0 {
-			fmt.Println(Header(name))
+			// Source lines carry the source as prefix instead
+			if source == "" {
+				fmt.Fprintln(t.out, Header(name))
+			}
 			for _, line := range lines {
-				t.printLogLine(name, line)
+				t.printLogLine(tf.prefix(), line)
 			}
 		}
 		// Set offset to end of file for follow mode
@@ -280,8 +427,17 @@ func (t *LogTailer) watchLoop() {
 			if event.Has(fsnotify.Write) {
 				t.handleFileChange(event.Name)
 			} else if event.Has(fsnotify.Create) {
-				// New file created - check if it matches our pattern
-				if matched, _ := filepath.Match(t.pattern, filepath.Base(event.Name)); matched {
+				// New file created - check if it matches our pattern or is
+				// a source file that didn't exist yet
+				t.mutex.Lock()
+				_, tailed := t.files[event.Name]
+				t.mutex.Unlock()
+				_, isSource := t.sourceFiles[event.Name]
+				matched := false
+				if t.logDir != "" && filepath.Dir(event.Name) == filepath.Clean(t.logDir) {
+					matched, _ = filepath.Match(t.pattern, filepath.Base(event.Name))
+				}
+				if !tailed && (matched || isSource) {
 					_ = t.initFile(event.Name)
 					_ = t.watcher.Add(event.Name)
 				}
@@ -369,7 +525,7 @@ func (t *LogTailer) readNewContent(tf *tailedFile) {
 
 		line = strings.TrimRight(line, "\n\r")
 		if line != "" {
-			t.printLogLine(tf.name, line)
+			t.printLogLine(tf.prefix(), line)
 		}
 	}
 
@@ -377,8 +533,16 @@ func (t *LogTailer) readNewContent(tf *tailedFile) {
 	tf.offset, _ = tf.file.Seek(0, io.SeekCurrent)
 }
 
+// prefix returns the prefix of the file's lines: its source, or its name
+func (tf *tailedFile) prefix() string {
+	if tf.source != "" {
+		return tf.source
+	}
+	return tf.name
+}
+
 // printLogLine prints a formatted log line
-func (t *LogTailer) printLogLine(filename, line string) {
+func (t *LogTailer) printLogLine(prefix, line string) {
 	// Extract and colorize log level
 	coloredLine := line
 
@@ -389,8 +553,25 @@ func (t *LogTailer) printLogLine(filename, line string) {
 		coloredLine = strings.Replace(line, "."+level+":", "."+coloredLevel+":", 1)
 	}
 
-	// Print with filename prefix
-	fmt.Printf("%s %s\n", LogFile(fmt.Sprintf("[%s]", filename)), coloredLine)
+	t.outMutex.Lock()
+	defer t.outMutex.Unlock()
+	fmt.Fprintf(t.out, "%s %s\n", t.formatPrefix(prefix), coloredLine)
+}
+
+// formatPrefix returns the line prefix: a source padded to the longest source
+// in its color, or a file name
+func (t *LogTailer) formatPrefix(prefix string) string {
+	width := 0
+	for _, s := range t.sources {
+		width = max(width, len(s))
+	}
+	for i, s := range t.sources {
+		if s == prefix {
+			label := fmt.Sprintf("%-*s", width+2, "["+s+"]")
+			return colorize(sourceColors[i%len(sourceColors)], label)
+		}
+	}
+	return LogFile(fmt.Sprintf("[%s]", prefix))
 }
 
 // TailLogs is a convenience function to tail logs
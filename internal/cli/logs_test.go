@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"bytes"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -227,3 +229,49 @@ func TestLogLevelRegex(t *testing.T) {
 		})
 	}
 }
+
+func TestLogTailer_Sources(t *testing.T) {
+	DisableColors()
+	defer EnableColors()
+
+	tmpDir := t.TempDir()
+	nginxLog := filepath.Join(tmpDir, "shop.test-error.log")
+	os.WriteFile(nginxLog, []byte("first\nsecond\n"), 0644)
+
+	tailer := NewLogTailer("", "", false, 1)
+	var out bytes.Buffer
+	tailer.out = &out
+	tailer.AddFile("nginx", nginxLog)
+	tailer.AddFile("nginx", filepath.Join(tmpDir, "missing.log"))
+	tailer.AddCommand("mysql", exec.Command("sh", "-c", "echo ready; echo >&2 warning"))
+
+	if err := tailer.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"[nginx] second\n", "[mysql] ready\n", "[mysql] warning\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output misses %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "first") || strings.Contains(got, "shop.test-error.log") {
+		t.Errorf("output has more than the last line, or a file header:\n%s", got)
+	}
+}
+
+func TestLogTailer_FormatPrefix(t *testing.T) {
+	DisableColors()
+	defer EnableColors()
+
+	tailer := NewLogTailer("", "", false, 10)
+	tailer.AddFile("php", "/tmp/php.log")
+	tailer.AddCommand("varnish", exec.Command("true"))
+
+	if got := tailer.formatPrefix("php"); got != "[php]    " {
+		t.Errorf("formatPrefix(php) = %q, want it padded to [varnish]", got)
+	}
+	if got := tailer.formatPrefix("system.log"); got != "[system.log]" {
+		t.Errorf("formatPrefix(system.log) = %q", got)
+	}
+}
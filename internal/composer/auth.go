@@ -0,0 +1,220 @@
+// Package composer manages the user's global Composer credentials in
+// auth.json.
+//
+// Entries are changed in place: sections and hosts MageBox doesn't touch,
+// like gitlab-token or bearer, are written back as they were read.
+package composer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// MagentoRepoHost is the host of the Magento Marketplace Composer repository
+const MagentoRepoHost = "repo.magento.com"
+
+// GitHubHost is the github-oauth host of github.com
+const GitHubHost = "github.com"
+
+// Auth sections of auth.json
+const (
+	SectionHTTPBasic   = "http-basic"
+	SectionGitHubOAuth = "github-oauth"
+)
+
+// AuthPaths returns the auth.json files Composer reads for the user, in
+// order of precedence
+func AuthPaths(homeDir string) []string {
+	var paths []string
+	if composerHome := os.Getenv("COMPOSER_HOME"); composerHome != "" {
+		paths = append(paths, filepath.Join(composerHome, "auth.json"))
+	}
+	return append(paths,
+		filepath.Join(homeDir, ".config", "composer", "auth.json"),
+		filepath.Join(homeDir, ".composer", "auth.json"))
+}
+
+// AuthPath returns the auth.json MageBox manages: the first one that
+// exists, else the one in Composer's home directory
+func AuthPath(homeDir string) string {
+	paths := AuthPaths(homeDir)
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if os.Getenv("COMPOSER_HOME") != "" {
+		return paths[0]
+	}
+	// Composer uses the XDG directory when it exists
+	if info, err := os.Stat(filepath.Join(homeDir, ".config", "composer")); err == nil && info.IsDir() {
+		return filepath.Join(homeDir, ".config", "composer", "auth.json")
+	}
+	return filepath.Join(homeDir, ".composer", "auth.json")
+}
+
+// Auth is the content of an auth.json file
+type Auth struct {
+	Path     string
+	sections map[string]json.RawMessage
+}
+
+// Credentials are the http-basic credentials of a host
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoadAuth reads an auth.json file. A missing file is empty.
+func LoadAuth(path string) (*Auth, error) {
+	a := &Auth{Path: path, sections: make(map[string]json.RawMessage)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return a, nil
+	}
+	if err := json.Unmarshal(data, &a.sections); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// HTTPBasic returns the http-basic credentials of a host
+func (a *Auth) HTTPBasic(host string) (Credentials, bool) {
+	var hosts map[string]Credentials
+	if json.Unmarshal(a.sections[SectionHTTPBasic], &hosts) != nil {
+		return Credentials{}, false
+	}
+	creds, ok := hosts[host]
+	return creds, ok && creds.Username != ""
+}
+
+// GitHubToken returns the github-oauth token of github.com
+func (a *Auth) GitHubToken() string {
+	var hosts map[string]string
+	if json.Unmarshal(a.sections[SectionGitHubOAuth], &hosts) != nil {
+		return ""
+	}
+	return hosts[GitHubHost]
+}
+
+// SetHTTPBasic sets the http-basic credentials of a host
+func (a *Auth) SetHTTPBasic(host, username, password string) error {
+	return a.setEntry(SectionHTTPBasic, host, Credentials{Username: username, Password: password})
+}
+
+// SetGitHubToken sets the github-oauth token of github.com
+func (a *Auth) SetGitHubToken(token string) error {
+	return a.setEntry(SectionGitHubOAuth, GitHubHost, token)
+}
+
+// setEntry sets one host of a section, keeping the other hosts as they are
+func (a *Auth) setEntry(section, host string, value any) error {
+	hosts := make(map[string]json.RawMessage)
+	if raw, ok := a.sections[section]; ok {
+		if err := json.Unmarshal(raw, &hosts); err != nil {
+			return fmt.Errorf("invalid %s section in %s: %w", section, a.Path, err)
+		}
+	}
+	entry, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	hosts[host] = entry
+	raw, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+	a.sections[section] = raw
+	return nil
+}
+
+// Entry is one credential of auth.json, as shown by 'magebox auth show'
+type Entry struct {
+	Section  string `json:"section"`
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+	Secret   string `json:"secret"` // Masked
+}
+
+// Entries returns every credential of the file with masked secrets, sorted by
+// section and host
+func (a *Auth) Entries() []Entry {
+	var entries []Entry
+	for section, raw := range a.sections {
+		var hosts map[string]json.RawMessage
+		if json.Unmarshal(raw, &hosts) != nil {
+			continue
+		}
+		for host, value := range hosts {
+			entry := Entry{Section: section, Host: host}
+			var creds Credentials
+			var token string
+			switch {
+			case json.Unmarshal(value, &token) == nil:
+				entry.Secret = Mask(token)
+			case json.Unmarshal(value, &creds) == nil && creds.Username != "":
+				entry.Username = creds.Username
+				entry.Secret = Mask(creds.Password)
+			default:
+				entry.Secret = "****"
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Section != entries[j].Section {
+			return entries[i].Section < entries[j].Section
+		}
+		return entries[i].Host < entries[j].Host
+	})
+	return entries
+}
+
+// Save writes the file atomically, readable only by the user
+func (a *Auth) Save() error {
+	if err := os.MkdirAll(filepath.Dir(a.Path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(a.sections, "", "    ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(a.Path, append(data, '\n'), 0600)
+}
+
+// Mask hides all but the last four characters of a secret
+func Mask(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", 8) + secret[len(secret)-4:]
+}
+
+// FindHTTPBasic returns the http-basic credentials of a host from the first
+// auth.json of the user that has them
+func FindHTTPBasic(homeDir, host string) (Credentials, bool) {
+	for _, path := range AuthPaths(homeDir) {
+		auth, err := LoadAuth(path)
+		if err != nil {
+			continue
+		}
+		if creds, ok := auth.HTTPBasic(host); ok {
+			return creds, true
+		}
+	}
+	return Credentials{}, false
+}
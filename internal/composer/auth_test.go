@@ -0,0 +1,114 @@
+package composer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuth_SetKeepsOtherEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	existing := `{
+    "http-basic": {
+        "repo.example.com": {"username": "jane", "password": "secret"}
+    },
+    "gitlab-token": {"gitlab.com": "glpat-abc"},
+    "bearer": {"repo.packagist.com": "xyz"}
+}`
+	if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := LoadAuth(path)
+	if err != nil {
+		t.Fatalf("LoadAuth failed: %v", err)
+	}
+	if err := auth.SetHTTPBasic(MagentoRepoHost, "public", "private"); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.SetGitHubToken("ghp_1234567890"); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved auth.json is invalid: %v\n%s", err, data)
+	}
+	if saved["gitlab-token"]["gitlab.com"] != "glpat-abc" || saved["bearer"]["repo.packagist.com"] != "xyz" {
+		t.Errorf("other sections were not kept:\n%s", data)
+	}
+	if _, ok := saved["http-basic"]["repo.example.com"]; !ok {
+		t.Errorf("other http-basic hosts were not kept:\n%s", data)
+	}
+	if saved["github-oauth"]["github.com"] != "ghp_1234567890" {
+		t.Errorf("github token not saved:\n%s", data)
+	}
+
+	reloaded, err := LoadAuth(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds, ok := reloaded.HTTPBasic(MagentoRepoHost); !ok || creds.Username != "public" || creds.Password != "private" {
+		t.Errorf("HTTPBasic() = %+v, %v", creds, ok)
+	}
+	if token := reloaded.GitHubToken(); token != "ghp_1234567890" {
+		t.Errorf("GitHubToken() = %q", token)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("auth.json mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestAuth_Entries(t *testing.T) {
+	auth, err := LoadAuth(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadAuth of a missing file failed: %v", err)
+	}
+	if entries := auth.Entries(); len(entries) != 0 {
+		t.Errorf("Entries() of an empty file = %v", entries)
+	}
+
+	_ = auth.SetHTTPBasic(MagentoRepoHost, "public", "0123456789abcdef")
+	_ = auth.SetGitHubToken("short")
+	entries := auth.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries() = %v, want 2", entries)
+	}
+	if e := entries[0]; e.Section != SectionGitHubOAuth || e.Secret != "*****" {
+		t.Errorf("github entry = %+v", e)
+	}
+	if e := entries[1]; e.Host != MagentoRepoHost || e.Username != "public" || e.Secret != "********cdef" {
+		t.Errorf("magento entry = %+v", e)
+	}
+}
+
+func TestAuthPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("COMPOSER_HOME", "")
+
+	if got, want := AuthPath(home), filepath.Join(home, ".composer", "auth.json"); got != want {
+		t.Errorf("AuthPath() = %q, want %q", got, want)
+	}
+
+	if err := os.MkdirAll(filepath.Join(home, ".config", "composer"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := AuthPath(home), filepath.Join(home, ".config", "composer", "auth.json"); got != want {
+		t.Errorf("AuthPath() with an XDG directory = %q, want %q", got, want)
+	}
+
+	composerHome := filepath.Join(home, "custom")
+	t.Setenv("COMPOSER_HOME", composerHome)
+	if got, want := AuthPath(home), filepath.Join(composerHome, "auth.json"); got != want {
+		t.Errorf("AuthPath() with COMPOSER_HOME = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package composer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Adobe Commerce packages on repo.magento.com
+const (
+	CommerceProjectPackage = "magento/project-enterprise-edition"
+	CommerceProductPackage = "magento/product-enterprise-edition"
+	B2BPackage             = "magento/extension-b2b"
+)
+
+// magentoRepoURL is the base URL of repo.magento.com.
+// It can be overridden in tests to point at a local httptest server.
+var magentoRepoURL = "https://" + MagentoRepoHost
+
+// repoHTTPClient is the HTTP client used for repository checks
+var repoHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// ErrInvalidKeys is returned when repo.magento.com rejects the access keys
+var ErrInvalidKeys = errors.New("the access keys were rejected by " + MagentoRepoHost)
+
+// PackageAccessError is returned when the access keys are valid but the
+// license doesn't cover a package
+type PackageAccessError struct {
+	Package string
+}
+
+func (e *PackageAccessError) Error() string {
+	return fmt.Sprintf("the access keys have no license for %s", e.Package)
+}
+
+// CheckPackageAccess checks that the access keys can download the packages
+// from repo.magento.com, using the Composer v2 metadata of each package. The
+// repository answers 401 for unknown keys and 404 for packages the license
+// doesn't include.
+func CheckPackageAccess(creds Credentials, packages ...string) error {
+	for _, pkg := range packages {
+		req, err := http.NewRequest(http.MethodGet, magentoRepoURL+"/p2/"+pkg+".json", nil)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(creds.Username, creds.Password)
+
+		resp, err := repoHTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to reach %s: %w", MagentoRepoHost, err)
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusUnauthorized:
+			return ErrInvalidKeys
+		case http.StatusForbidden, http.StatusNotFound:
+			return &PackageAccessError{Package: pkg}
+		default:
+			return fmt.Errorf("%s returned %s for %s", MagentoRepoHost, resp.Status, pkg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package composer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckPackageAccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "public" || pass != "private" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/p2/" + CommerceProductPackage + ".json":
+			_, _ = w.Write([]byte(`{"packages":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	orig := magentoRepoURL
+	magentoRepoURL = server.URL
+	defer func() { magentoRepoURL = orig }()
+
+	valid := Credentials{Username: "public", Password: "private"}
+
+	if err := CheckPackageAccess(valid, CommerceProductPackage); err != nil {
+		t.Errorf("licensed package: unexpected error %v", err)
+	}
+
+	err := CheckPackageAccess(Credentials{Username: "public", Password: "wrong"}, CommerceProductPackage)
+	if !errors.Is(err, ErrInvalidKeys) {
+		t.Errorf("invalid keys: got %v, want ErrInvalidKeys", err)
+	}
+
+	err = CheckPackageAccess(valid, CommerceProductPackage, B2BPackage)
+	var accessErr *PackageAccessError
+	if !errors.As(err, &accessErr) || accessErr.Package != B2BPackage {
+		t.Errorf("unlicensed package: got %v, want PackageAccessError for %s", err, B2BPackage)
+	}
+}
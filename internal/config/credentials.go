@@ -0,0 +1,89 @@
+package config
+
+import "strings"
+
+// Default service credentials, used when a project does not configure its own
+const (
+	DefaultDBUser           = "root"
+	DefaultDBPassword       = "magebox"
+	DefaultRabbitMQUser     = "guest"
+	DefaultRabbitMQPassword = "guest"
+)
+
+// HasCredentials returns true if the service sets its own user or password
+func (s *ServiceConfig) HasCredentials() bool {
+	return s.User != "" || s.Password != ""
+}
+
+// HasCustomDatabaseUser returns true if the project connects to its database
+// with its own user instead of the shared root account
+func (c *Config) HasCustomDatabaseUser() bool {
+	db := c.Services.GetDatabaseService()
+	return db != nil && db.HasCredentials() && db.User != DefaultDBUser
+}
+
+// DatabaseUser returns the user the project connects to its database with.
+// Setting only a password creates a user named after the database.
+func (c *Config) DatabaseUser() string {
+	if !c.HasCustomDatabaseUser() {
+		return DefaultDBUser
+	}
+	if user := c.Services.GetDatabaseService().User; user != "" {
+		return user
+	}
+	return c.DatabaseName()
+}
+
+// DatabasePassword returns the password for DatabaseUser
+func (c *Config) DatabasePassword() string {
+	if !c.HasCustomDatabaseUser() {
+		return DefaultDBPassword
+	}
+	if password := c.Services.GetDatabaseService().Password; password != "" {
+		return password
+	}
+	return DefaultDBPassword
+}
+
+// GetCacheService returns the configured cache service (Redis or Valkey)
+func (s *Services) GetCacheService() *ServiceConfig {
+	if s.HasValkey() {
+		return s.Valkey
+	}
+	if s.HasRedis() {
+		return s.Redis
+	}
+	return nil
+}
+
+// RedisCredentials returns the ACL user and password the project uses for
+// Redis/Valkey. Both are empty when the project uses the open default user.
+// Setting only a password creates an ACL user named after the project.
+func (c *Config) RedisCredentials() (user, password string) {
+	cache := c.Services.GetCacheService()
+	if cache == nil || cache.Password == "" {
+		return "", ""
+	}
+	user = cache.User
+	if user == "" {
+		user = strings.ReplaceAll(c.Name, "-", "_")
+	}
+	return user, cache.Password
+}
+
+// RabbitMQCredentials returns the RabbitMQ user and password of the project
+func (c *Config) RabbitMQCredentials() (user, password string) {
+	rmq := c.Services.RabbitMQ
+	if rmq == nil || !rmq.HasCredentials() {
+		return DefaultRabbitMQUser, DefaultRabbitMQPassword
+	}
+	user = rmq.User
+	if user == "" {
+		user = strings.ReplaceAll(c.Name, "-", "_")
+	}
+	password = rmq.Password
+	if password == "" {
+		password = DefaultRabbitMQPassword
+	}
+	return user, password
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestServiceConfig_UnmarshalYAML_Credentials(t *testing.T) {
+	var sc ServiceConfig
+	err := yaml.Unmarshal([]byte(`
+version: "8.0"
+user: shop
+password: 1234
+database: shop_db`), &sc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sc.User != "shop" || sc.Password != "1234" || sc.Database != "shop_db" {
+		t.Errorf("credentials = %q/%q/%q, want shop/1234/shop_db", sc.User, sc.Password, sc.Database)
+	}
+	if !sc.Enabled {
+		t.Error("Enabled should be true for an object form")
+	}
+}
+
+func TestConfig_DatabaseNames(t *testing.T) {
+	var sc ServiceConfig
+	if err := yaml.Unmarshal([]byte(`
+version: "8.0"
+databases: [shop_eu, shop_us, my_shop]`), &sc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &Config{Name: "my-shop", Services: Services{MySQL: &sc}}
+	got := cfg.DatabaseNames()
+	want := []string{"my_shop", "shop_eu", "shop_us"}
+	if len(got) != len(want) {
+		t.Fatalf("DatabaseNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("DatabaseNames()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if !cfg.HasDatabase("shop_us") || cfg.HasDatabase("shop_uk") {
+		t.Error("HasDatabase() should only match declared databases")
+	}
+
+	out, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var back ServiceConfig
+	if err := yaml.Unmarshal(out, &back); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(back.Databases) != 3 {
+		t.Errorf("round trip lost databases: %s", out)
+	}
+}
+
+func TestConfig_ValidateDatabases(t *testing.T) {
+	tests := []struct {
+		name      string
+		databases []string
+		wantErr   bool
+	}{
+		{name: "none", databases: nil},
+		{name: "valid", databases: []string{"shop_eu", "Shop2"}},
+		{name: "hyphen", databases: []string{"shop-eu"}, wantErr: true},
+		{name: "backtick", databases: []string{"shop`; DROP"}, wantErr: true},
+		{name: "empty", databases: []string{""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Name:     "my-shop",
+				Domains:  []Domain{{Host: "shop.test"}},
+				PHP:      "8.3",
+				Services: Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0", Databases: tt.databases}},
+			}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_DatabaseCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		db           *ServiceConfig
+		wantUser     string
+		wantPassword string
+		wantDBName   string
+	}{
+		{
+			name:         "defaults",
+			db:           &ServiceConfig{Enabled: true, Version: "8.0"},
+			wantUser:     DefaultDBUser,
+			wantPassword: DefaultDBPassword,
+			wantDBName:   "my_shop",
+		},
+		{
+			name:         "user and password",
+			db:           &ServiceConfig{Enabled: true, Version: "8.0", User: "app", Password: "secret"},
+			wantUser:     "app",
+			wantPassword: "secret",
+			wantDBName:   "my_shop",
+		},
+		{
+			name:         "password only uses database name as user",
+			db:           &ServiceConfig{Enabled: true, Version: "8.0", Password: "secret", Database: "shop_db"},
+			wantUser:     "shop_db",
+			wantPassword: "secret",
+			wantDBName:   "shop_db",
+		},
+		{
+			name:         "explicit root keeps defaults",
+			db:           &ServiceConfig{Enabled: true, Version: "8.0", User: "root"},
+			wantUser:     DefaultDBUser,
+			wantPassword: DefaultDBPassword,
+			wantDBName:   "my_shop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Name: "my-shop", Services: Services{MySQL: tt.db}}
+
+			if got := cfg.DatabaseUser(); got != tt.wantUser {
+				t.Errorf("DatabaseUser() = %q, want %q", got, tt.wantUser)
+			}
+			if got := cfg.DatabasePassword(); got != tt.wantPassword {
+				t.Errorf("DatabasePassword() = %q, want %q", got, tt.wantPassword)
+			}
+			if got := cfg.DatabaseName(); got != tt.wantDBName {
+				t.Errorf("DatabaseName() = %q, want %q", got, tt.wantDBName)
+			}
+		})
+	}
+}
+
+func TestConfig_RedisCredentials(t *testing.T) {
+	cfg := &Config{Name: "my-shop", Services: Services{Redis: &ServiceConfig{Enabled: true}}}
+	if user, password := cfg.RedisCredentials(); user != "" || password != "" {
+		t.Errorf("RedisCredentials() = %q/%q, want empty without a password", user, password)
+	}
+
+	cfg.Services.Redis.Password = "secret"
+	if user, password := cfg.RedisCredentials(); user != "my_shop" || password != "secret" {
+		t.Errorf("RedisCredentials() = %q/%q, want my_shop/secret", user, password)
+	}
+}
+
+func TestConfig_RabbitMQCredentials(t *testing.T) {
+	cfg := &Config{Name: "my-shop", Services: Services{RabbitMQ: &ServiceConfig{Enabled: true}}}
+	if user, password := cfg.RabbitMQCredentials(); user != DefaultRabbitMQUser || password != DefaultRabbitMQPassword {
+		t.Errorf("RabbitMQCredentials() = %q/%q, want guest/guest", user, password)
+	}
+
+	cfg.Services.RabbitMQ.User = "shop"
+	cfg.Services.RabbitMQ.Password = "secret"
+	if user, password := cfg.RabbitMQCredentials(); user != "shop" || password != "secret" {
+		t.Errorf("RabbitMQCredentials() = %q/%q, want shop/secret", user, password)
+	}
+}
+
+func TestConfig_Validate_RootPassword(t *testing.T) {
+	cfg := &Config{
+		Name:     "shop",
+		Domains:  []Domain{{Host: "shop.test"}},
+		PHP:      "8.3",
+		Services: Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0", User: "root", Password: "other"}},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an error when overriding the shared root password")
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dbServerOptionPattern matches a MySQL server option name
+var dbServerOptionPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// DBServerSettings are the server settings of services.<database>.config the
+// schema describes. Any other [mysqld] option is accepted and written as is.
+var DBServerSettings = map[string]*Schema{
+	"innodb_buffer_pool_size":        {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"innodb_log_file_size":           {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"innodb_redo_log_capacity":       {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"innodb_flush_log_at_trx_commit": {Type: "string", Enum: []string{"0", "1", "2"}, Description: "When the redo log is flushed, 2 speeds up imports"},
+	"sort_buffer_size":               {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"join_buffer_size":               {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"tmp_table_size":                 {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"max_heap_table_size":            {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"max_allowed_packet":             {Type: "string", Pattern: memoryPattern, Description: "Memory size with a unit, e.g. 512m or 2g"},
+	"max_connections":                {Type: "string", Pattern: `^[0-9]+$`, Description: "Number of connections, e.g. 500"},
+	"sql_mode":                       {Type: "string", Description: "Comma separated SQL modes, empty for none"},
+}
+
+// DBServerConfig returns the server settings of a database service with the
+// option names normalized to underscores, as MySQL treats - and _ alike
+func (s *ServiceConfig) DBServerConfig() map[string]string {
+	if s == nil || len(s.Config) == 0 {
+		return nil
+	}
+	settings := make(map[string]string, len(s.Config))
+	for key, value := range s.Config {
+		settings[strings.ReplaceAll(strings.ToLower(key), "-", "_")] = value
+	}
+	return settings
+}
+
+// validateDBServerConfig checks the config settings of the services: only
+// database services take them, as my.cnf options
+func (c *Config) validateDBServerConfig() error {
+	for name, svc := range c.Services.byName() {
+		if svc == nil || len(svc.Config) == 0 {
+			continue
+		}
+		if name != "mysql" && name != "mariadb" && name != "percona" {
+			return &ValidationError{Field: "services", Message: fmt.Sprintf("%s has no server config, only mysql, mariadb and percona do", name)}
+		}
+		for key, value := range svc.Config {
+			if !dbServerOptionPattern.MatchString(strings.ToLower(key)) {
+				return &ValidationError{Field: "services", Message: fmt.Sprintf("invalid %s config option %q", name, key)}
+			}
+			if strings.ContainsAny(value, "\n\r") {
+				return &ValidationError{Field: "services", Message: fmt.Sprintf("%s config option %s must be a single line", name, key)}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestServiceConfig_UnmarshalYAML_Config(t *testing.T) {
+	var sc ServiceConfig
+	err := yaml.Unmarshal([]byte(`
+version: "8.0"
+config:
+  innodb_buffer_pool_size: 2G
+  max_connections: 500
+  sql_mode: ""`), &sc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{"innodb_buffer_pool_size": "2G", "max_connections": "500", "sql_mode": ""}
+	if len(sc.Config) != len(want) {
+		t.Fatalf("Config = %v, want %v", sc.Config, want)
+	}
+	for key, value := range want {
+		if got, ok := sc.Config[key]; !ok || got != value {
+			t.Errorf("Config[%s] = %q, want %q", key, got, value)
+		}
+	}
+
+	out, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(out), "innodb_buffer_pool_size: 2G") {
+		t.Errorf("round trip lost config: %s", out)
+	}
+}
+
+func TestServiceConfig_DBServerConfig(t *testing.T) {
+	sc := &ServiceConfig{Config: map[string]string{"Innodb-Buffer-Pool-Size": "1G", "sql_mode": ""}}
+	got := sc.DBServerConfig()
+	if got["innodb_buffer_pool_size"] != "1G" {
+		t.Errorf("DBServerConfig() = %v, want option names with underscores", got)
+	}
+	if _, ok := got["sql_mode"]; !ok {
+		t.Error("an empty value should be kept, it clears the option")
+	}
+
+	var none *ServiceConfig
+	if none.DBServerConfig() != nil {
+		t.Error("a missing service has no server config")
+	}
+}
+
+func TestConfig_ValidateDBServerConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		services Services
+		wantErr  string
+	}{
+		{"mysql", Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0", Config: map[string]string{"max_connections": "500"}}}, ""},
+		{"percona", Services{Percona: &ServiceConfig{Enabled: true, Version: "8.0", Config: map[string]string{"innodb-log-file-size": "512M"}}}, ""},
+		{"redis", Services{Redis: &ServiceConfig{Enabled: true, Config: map[string]string{"maxmemory": "1G"}}}, "only mysql, mariadb and percona"},
+		{"option name", Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0", Config: map[string]string{"max connections": "500"}}}, "invalid mysql config option"},
+		{"multi-line", Services{MariaDB: &ServiceConfig{Enabled: true, Version: "10.6", Config: map[string]string{"sql_mode": "a\n[client]"}}}, "single line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Services: tt.services}
+			err := cfg.validateDBServerConfig()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoader_MergeServiceConfig(t *testing.T) {
+	main := Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0", Config: map[string]string{"innodb_buffer_pool_size": "1G", "max_connections": "500"}}}
+	local := Services{MySQL: &ServiceConfig{Enabled: true, Config: map[string]string{"innodb_buffer_pool_size": "4G"}}}
+
+	got := NewLoader(t.TempDir()).mergeServices(main, local)
+	if got.MySQL.Config["innodb_buffer_pool_size"] != "4G" || got.MySQL.Config["max_connections"] != "500" {
+		t.Errorf("Config = %v, want the local buffer pool on top of the main settings", got.MySQL.Config)
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeployConfig is the deployment pipeline of a project, run on a remote
+// environment by 'magebox deploy'
+type DeployConfig struct {
+	Path  string       `yaml:"path,omitempty"`  // Remote project path, for environments that don't set one
+	Steps []DeployStep `yaml:"steps,omitempty"` // Replaces the default steps of the project type
+}
+
+// DeployStep is a shell command of a deployment pipeline
+type DeployStep struct {
+	Name         string   `yaml:"name,omitempty"`
+	Run          string   `yaml:"run"`
+	Environments []string `yaml:"environments,omitempty"` // Only run on these environments
+}
+
+// UnmarshalYAML allows steps to be defined as string or object
+func (s *DeployStep) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var run string
+	if err := unmarshal(&run); err == nil {
+		// steps:
+		//   - "php bin/magento cache:flush"
+		s.Name = run
+		s.Run = run
+		return nil
+	}
+
+	// steps:
+	//   - name: Reindex
+	//     run: "php bin/magento indexer:reindex"
+	//     environments: [staging]
+	type plain DeployStep
+	var p plain
+	if err := unmarshal(&p); err != nil {
+		return err
+	}
+	*s = DeployStep(p)
+	if s.Name == "" {
+		s.Name = s.Run
+	}
+	return nil
+}
+
+// RunsOn reports whether the step runs on the named environment
+func (s DeployStep) RunsOn(envName string) bool {
+	if len(s.Environments) == 0 {
+		return true
+	}
+	for _, e := range s.Environments {
+		if e == envName {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultDeploySteps returns the deployment pipeline of a project type
+func DefaultDeploySteps(projectType string) []DeployStep {
+	composer := DeployStep{Name: "Composer install", Run: "composer install --no-dev --optimize-autoloader --no-interaction"}
+	if projectType == ProjectTypeLaravel {
+		return []DeployStep{
+			composer,
+			{Name: "Migrate", Run: "php artisan migrate --force"},
+			{Name: "Optimize", Run: "php artisan optimize"},
+		}
+	}
+	return []DeployStep{
+		composer,
+		{Name: "Setup upgrade", Run: "php bin/magento setup:upgrade --keep-generated"},
+		{Name: "DI compile", Run: "php bin/magento setup:di:compile"},
+		{Name: "Static content deploy", Run: "php bin/magento setup:static-content:deploy -f"},
+		{Name: "Cache flush", Run: "php bin/magento cache:flush"},
+	}
+}
+
+// DeploySteps returns the steps to run on the named environment: the
+// project's deploy steps, or the defaults of its type
+func (c *Config) DeploySteps(envName string) []DeployStep {
+	steps := DefaultDeploySteps(c.GetType())
+	if c.Deploy != nil && len(c.Deploy.Steps) > 0 {
+		steps = c.Deploy.Steps
+	}
+
+	var result []DeployStep
+	for _, s := range steps {
+		if s.RunsOn(envName) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// validateDeploy checks the deploy section
+func (c *Config) validateDeploy() error {
+	if c.Deploy == nil {
+		return nil
+	}
+	for i, s := range c.Deploy.Steps {
+		if strings.TrimSpace(s.Run) == "" {
+			return &ValidationError{Field: "deploy.steps", Message: fmt.Sprintf("step '%s' has no run command", s.Name), Index: i}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDeployStep_UnmarshalYAML(t *testing.T) {
+	var d DeployConfig
+	err := yaml.Unmarshal([]byte(`
+path: /var/www/shop
+steps:
+  - "composer install --no-dev"
+  - name: Reindex
+    run: "php bin/magento indexer:reindex"
+    environments: [staging]`), &d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(d.Steps) != 2 {
+		t.Fatalf("got %d steps, want 2", len(d.Steps))
+	}
+	if d.Steps[0].Name != "composer install --no-dev" || d.Steps[0].Run != "composer install --no-dev" {
+		t.Errorf("string step = %+v, want name and run set to the command", d.Steps[0])
+	}
+	if d.Steps[1].Name != "Reindex" || d.Steps[1].Run != "php bin/magento indexer:reindex" {
+		t.Errorf("object step = %+v", d.Steps[1])
+	}
+	if !d.Steps[1].RunsOn("staging") || d.Steps[1].RunsOn("production") {
+		t.Error("object step should only run on staging")
+	}
+}
+
+func TestConfig_DeploySteps(t *testing.T) {
+	cfg := &Config{}
+	if steps := cfg.DeploySteps("production"); len(steps) != 5 || steps[4].Run != "php bin/magento cache:flush" {
+		t.Errorf("magento defaults = %+v", steps)
+	}
+
+	cfg.Type = ProjectTypeLaravel
+	if steps := cfg.DeploySteps("production"); len(steps) != 3 || steps[1].Run != "php artisan migrate --force" {
+		t.Errorf("laravel defaults = %+v", steps)
+	}
+
+	cfg.Deploy = &DeployConfig{Steps: []DeployStep{
+		{Name: "build", Run: "make build"},
+		{Name: "seed", Run: "make seed", Environments: []string{"staging"}},
+	}}
+	if steps := cfg.DeploySteps("production"); len(steps) != 1 || steps[0].Name != "build" {
+		t.Errorf("production steps = %+v, want only build", steps)
+	}
+	if steps := cfg.DeploySteps("staging"); len(steps) != 2 {
+		t.Errorf("staging steps = %+v, want build and seed", steps)
+	}
+}
+
+func TestConfig_ValidateDeploy(t *testing.T) {
+	cfg := &Config{Deploy: &DeployConfig{Steps: []DeployStep{{Name: "empty"}}}}
+	if err := cfg.validateDeploy(); err == nil {
+		t.Error("expected an error for a step without run")
+	}
+
+	cfg.Deploy.Steps[0].Run = "make"
+	if err := cfg.validateDeploy(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
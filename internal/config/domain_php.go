@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"qoliber/magebox/internal/php"
+)
+
+// GetPHP returns the PHP version of the domain: its own, or the project's
+func (d *Domain) GetPHP(projectPHP string) string {
+	if d.PHP == "" {
+		return projectPHP
+	}
+	return d.PHP
+}
+
+// PHPVersions returns the PHP versions the project runs: the project's
+// followed by the other versions of its domains. Each gets a PHP-FPM pool.
+func (c *Config) PHPVersions() []string {
+	versions := []string{c.PHP}
+	for _, d := range c.Domains {
+		if v := d.GetPHP(c.PHP); !slices.Contains(versions, v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// validateDomainPHP checks the PHP versions of the domains
+func (c *Config) validateDomainPHP() error {
+	for i, d := range c.Domains {
+		if d.PHP == "" || d.PHP == c.PHP {
+			continue
+		}
+		if !slices.Contains(php.SupportedVersions, d.PHP) {
+			return &ValidationError{Field: "domains", Message: fmt.Sprintf("%s: unsupported php version %q (use %s)", d.Host, d.PHP, strings.Join(php.SupportedVersions, ", ")), Index: i}
+		}
+		if c.Isolated {
+			return &ValidationError{Field: "domains", Message: fmt.Sprintf("%s: isolated projects run a single PHP version, remove php from the domain", d.Host), Index: i}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_PHPVersions(t *testing.T) {
+	cfg := &Config{
+		PHP: "8.2",
+		Domains: []Domain{
+			{Host: "mystore.test"},
+			{Host: "next.mystore.test", PHP: "8.3"},
+			{Host: "de.next.mystore.test", PHP: "8.3"},
+			{Host: "old.mystore.test", PHP: "8.2"},
+		},
+	}
+	if got, want := cfg.PHPVersions(), []string{"8.2", "8.3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PHPVersions() = %v, want %v", got, want)
+	}
+	if got := cfg.Domains[0].GetPHP(cfg.PHP); got != "8.2" {
+		t.Errorf("GetPHP() = %q, want the project's 8.2", got)
+	}
+	if got := cfg.Domains[1].GetPHP(cfg.PHP); got != "8.3" {
+		t.Errorf("GetPHP() = %q, want 8.3", got)
+	}
+}
+
+func TestConfig_ValidateDomainPHP(t *testing.T) {
+	tests := []struct {
+		name     string
+		php      string
+		isolated bool
+		wantErr  string
+	}{
+		{"project version", "8.2", false, ""},
+		{"other version", "8.3", false, ""},
+		{"unsupported", "7.4", false, "unsupported php version"},
+		{"isolated", "8.3", true, "isolated projects"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Name:     "mystore",
+				PHP:      "8.2",
+				Isolated: tt.isolated,
+				Domains:  []Domain{{Host: "mystore.test"}, {Host: "next.mystore.test", PHP: tt.php}},
+			}
+			err := cfg.validateDomainPHP()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDomainPHP() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDomainPHP() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// domainCSVColumns maps the accepted CSV headers to the domain column they
+// fill, so store exports with their own column names import unchanged
+var domainCSVColumns = map[string]string{
+	"host":          "host",
+	"domain":        "host",
+	"base_url":      "host",
+	"url":           "host",
+	"store_code":    "store_code",
+	"code":          "store_code",
+	"mage_run_code": "store_code",
+	"run_type":      "run_type",
+	"mage_run_type": "run_type",
+	"ssl":           "ssl",
+}
+
+// domainCSVOrder is the column order of a CSV without a header row
+var domainCSVOrder = []string{"host", "store_code", "run_type", "ssl"}
+
+// ParseDomainsCSV reads domains from CSV with host, store_code, run_type and
+// ssl columns. The header row is optional; without one the columns are taken
+// in that order. Hosts may be given as base URLs, in which case the scheme
+// decides SSL unless the ssl column says otherwise.
+func ParseDomainsCSV(r io.Reader) ([]Domain, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no domains found")
+	}
+
+	columns := domainCSVOrder
+	first := 0
+	if header, ok := domainCSVHeader(records[0]); ok {
+		columns = header
+		first = 1
+	}
+
+	var domains []Domain
+	seen := make(map[string]int)
+	for i, record := range records[first:] {
+		line := first + i + 1
+		fields := make(map[string]string)
+		for j, value := range record {
+			if j < len(columns) && columns[j] != "" {
+				fields[columns[j]] = strings.TrimSpace(value)
+			}
+		}
+		if fields["host"] == "" {
+			continue
+		}
+
+		domain, err := domainFromCSV(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if prev, dup := seen[domain.Host]; dup {
+			return nil, fmt.Errorf("line %d: %s is already listed on line %d", line, domain.Host, prev)
+		}
+		seen[domain.Host] = line
+		domains = append(domains, domain)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains found")
+	}
+	return domains, nil
+}
+
+// domainCSVHeader returns the columns of a header row, false when the row
+// holds no known column name and is data
+func domainCSVHeader(record []string) ([]string, bool) {
+	columns := make([]string, len(record))
+	known := false
+	for i, name := range record {
+		if column, ok := domainCSVColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[i] = column
+			known = true
+		}
+	}
+	return columns, known
+}
+
+// domainFromCSV builds a domain from the fields of one CSV row
+func domainFromCSV(fields map[string]string) (Domain, error) {
+	host := fields["host"]
+	var schemeSSL *bool
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil || u.Hostname() == "" {
+			return Domain{}, fmt.Errorf("invalid base URL %q", host)
+		}
+		host = u.Hostname()
+		if u.Scheme == "http" {
+			off := false
+			schemeSSL = &off
+		}
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "/"))
+	if strings.ContainsAny(host, " /:") {
+		return Domain{}, fmt.Errorf("invalid host %q", host)
+	}
+
+	domain := Domain{
+		Host:        host,
+		MageRunCode: fields["store_code"],
+		SSL:         schemeSSL,
+	}
+
+	switch runType := strings.ToLower(fields["run_type"]); runType {
+	case "", "store":
+	case "website":
+		domain.MageRunType = runType
+	default:
+		return Domain{}, fmt.Errorf("run_type must be store or website, got %q", fields["run_type"])
+	}
+
+	if value := fields["ssl"]; value != "" {
+		enabled, err := parseCSVBool(value)
+		if err != nil {
+			return Domain{}, fmt.Errorf("ssl must be true or false, got %q", value)
+		}
+		if enabled {
+			domain.SSL = nil
+		} else {
+			domain.SSL = &enabled
+		}
+	}
+
+	return domain, nil
+}
+
+// parseCSVBool parses the yes/no spellings spreadsheets produce
+func parseCSVBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDomainsCSV(t *testing.T) {
+	input := `host,store_code,run_type,ssl
+shop.de.test,german,store,
+shop.fr.test,french,website,true
+# staging stores come later
+b2b.test,b2b,,no
+`
+	domains, err := ParseDomainsCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDomainsCSV() error = %v", err)
+	}
+	if len(domains) != 3 {
+		t.Fatalf("ParseDomainsCSV() returned %d domains, want 3", len(domains))
+	}
+
+	if d := domains[0]; d.Host != "shop.de.test" || d.GetStoreCode() != "german" || d.GetMageRunType() != "store" || !d.IsSSLEnabled() {
+		t.Errorf("domains[0] = %+v", d)
+	}
+	if d := domains[1]; d.MageRunType != "website" || d.SSL != nil {
+		t.Errorf("domains[1] = %+v, want website with default SSL", d)
+	}
+	if d := domains[2]; d.IsSSLEnabled() {
+		t.Errorf("domains[2] should have SSL disabled")
+	}
+}
+
+func TestParseDomainsCSV_StoreExport(t *testing.T) {
+	input := `code,base_url
+default,https://shop.test/
+outlet,http://outlet.shop.test/
+`
+	domains, err := ParseDomainsCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDomainsCSV() error = %v", err)
+	}
+	if domains[0].Host != "shop.test" || !domains[0].IsSSLEnabled() {
+		t.Errorf("domains[0] = %+v, want shop.test with SSL", domains[0])
+	}
+	if domains[1].Host != "outlet.shop.test" || domains[1].IsSSLEnabled() || domains[1].MageRunCode != "outlet" {
+		t.Errorf("domains[1] = %+v, want outlet.shop.test without SSL", domains[1])
+	}
+}
+
+func TestParseDomainsCSV_NoHeader(t *testing.T) {
+	domains, err := ParseDomainsCSV(strings.NewReader("shop.nl.test,dutch\n"))
+	if err != nil {
+		t.Fatalf("ParseDomainsCSV() error = %v", err)
+	}
+	if domains[0].Host != "shop.nl.test" || domains[0].MageRunCode != "dutch" {
+		t.Errorf("domains[0] = %+v", domains[0])
+	}
+}
+
+func TestParseDomainsCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "host,store_code\n", "no domains found"},
+		{"bad run type", "shop.test,default,group\n", "line 1: run_type"},
+		{"bad ssl", "shop.test,default,store,maybe\n", "line 1: ssl"},
+		{"duplicate", "host\nshop.test\nSHOP.test\n", "line 3: shop.test is already listed on line 2"},
+		{"invalid host", "shop test\n", "invalid host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDomainsCSV(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseDomainsCSV() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+
+	"qoliber/magebox/internal/remote"
+)
+
+// GetEnvironment returns a remote environment of the project by name
+func (c *Config) GetEnvironment(name string) (*remote.Environment, error) {
+	for i := range c.Environments {
+		if c.Environments[i].Name == name {
+			return &c.Environments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("environment '%s' not found in %s", name, ConfigFileName)
+}
+
+// validateEnvironments checks the environments: block. SSH key files are not
+// checked here; key paths are personal and usually set in .magebox.local.yaml.
+func (c *Config) validateEnvironments() error {
+	seen := make(map[string]bool, len(c.Environments))
+	for i, env := range c.Environments {
+		if env.Name == "" {
+			return &ValidationError{Field: "environments", Message: "environment name is required", Index: i}
+		}
+		if seen[env.Name] {
+			return &ValidationError{Field: "environments", Message: fmt.Sprintf("duplicate environment '%s'", env.Name), Index: i}
+		}
+		seen[env.Name] = true
+
+		if env.SSHCommand == "" && env.Host == "" {
+			return &ValidationError{Field: "environments", Message: fmt.Sprintf("environment '%s' needs a host (or ssh_command)", env.Name), Index: i}
+		}
+		if err := env.ValidateDB(); err != nil {
+			return &ValidationError{Field: "environments", Message: fmt.Sprintf("environment '%s': %v", env.Name, err), Index: i}
+		}
+	}
+	return nil
+}
+
+// mergeEnvironments applies local environments on top of the project ones.
+// Environments are matched by name and only fields set locally are replaced,
+// so a developer can swap in a personal SSH alias, user or key without
+// repeating the shared host, path and database settings.
+func mergeEnvironments(main, local []remote.Environment) []remote.Environment {
+	if len(local) == 0 {
+		return main
+	}
+
+	result := make([]remote.Environment, len(main))
+	copy(result, main)
+
+	for _, l := range local {
+		merged := false
+		for i := range result {
+			if result[i].Name == l.Name {
+				result[i] = mergeEnvironment(result[i], l)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// mergeEnvironment overrides the fields of base that are set in local
+func mergeEnvironment(base, local remote.Environment) remote.Environment {
+	if local.User != "" {
+		base.User = local.User
+	}
+	if local.Host != "" {
+		base.Host = local.Host
+	}
+	if local.Port != 0 {
+		base.Port = local.Port
+	}
+	if local.SSHKeyPath != "" {
+		base.SSHKeyPath = local.SSHKeyPath
+	}
+	if local.SSHCommand != "" {
+		base.SSHCommand = local.SSHCommand
+	}
+	if local.Path != "" {
+		base.Path = local.Path
+	}
+	if local.DB != nil {
+		if base.DB == nil {
+			base.DB = local.DB
+		} else {
+			db := *base.DB
+			if local.DB.Method != "" {
+				db.Method = local.DB.Method
+			}
+			if local.DB.Host != "" {
+				db.Host = local.DB.Host
+			}
+			if local.DB.Port != 0 {
+				db.Port = local.DB.Port
+			}
+			if local.DB.Name != "" {
+				db.Name = local.DB.Name
+			}
+			if local.DB.User != "" {
+				db.User = local.DB.User
+			}
+			if local.DB.Password != "" {
+				db.Password = local.DB.Password
+			}
+			base.DB = &db
+		}
+	}
+	return base
+}
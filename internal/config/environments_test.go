@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/remote"
+)
+
+func TestLoader_EnvironmentsWithLocalOverride(t *testing.T) {
+	dir := t.TempDir()
+	mainContent := `
+name: mystore
+domains:
+  - host: mystore.test
+php: "8.3"
+environments:
+  - name: staging
+    user: deploy
+    host: staging.example.com
+    path: /var/www/mystore
+    db:
+      method: mysqldump
+      name: mystore_staging
+      user: mystore
+  - name: production
+    user: deploy
+    host: prod.example.com
+    path: /var/www/mystore
+`
+	localContent := `
+environments:
+  - name: staging
+    host: mystore-staging
+    ssh_key: ~/.ssh/mystore
+    db:
+      password: secret
+  - name: sandbox
+    host: sandbox.example.com
+`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(mainContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(localContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(dir).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.Environments) != 3 {
+		t.Fatalf("len(Environments) = %d, want 3", len(cfg.Environments))
+	}
+
+	staging, err := cfg.GetEnvironment("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staging.Host != "mystore-staging" || staging.User != "deploy" || staging.SSHKeyPath != "~/.ssh/mystore" {
+		t.Errorf("staging SSH = %s@%s (key %s), want local host and key with shared user", staging.User, staging.Host, staging.SSHKeyPath)
+	}
+	if staging.Path != "/var/www/mystore" {
+		t.Errorf("staging.Path = %q, want shared path", staging.Path)
+	}
+	if staging.DB == nil || staging.DB.Name != "mystore_staging" || staging.DB.Password != "secret" || staging.DB.Method != remote.DBAccessMysqldump {
+		t.Errorf("staging.DB = %+v, want shared db settings with local password", staging.DB)
+	}
+
+	if _, err := cfg.GetEnvironment("sandbox"); err != nil {
+		t.Errorf("local-only environment should be added: %v", err)
+	}
+	if _, err := cfg.GetEnvironment("missing"); err == nil {
+		t.Error("expected error for unknown environment")
+	}
+}
+
+func TestConfig_ValidateEnvironments(t *testing.T) {
+	base := func(envs ...remote.Environment) *Config {
+		return &Config{
+			Name:         "mystore",
+			Domains:      []Domain{{Host: "mystore.test"}},
+			PHP:          "8.3",
+			Environments: envs,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		envs    []remote.Environment
+		wantErr string
+	}{
+		{
+			name: "valid",
+			envs: []remote.Environment{{Name: "staging", Host: "staging.example.com", Path: "/var/www"}},
+		},
+		{
+			name: "ssh command only",
+			envs: []remote.Environment{{Name: "jump", SSHCommand: "ssh -J bastion deploy@internal"}},
+		},
+		{
+			name:    "missing host",
+			envs:    []remote.Environment{{Name: "staging", User: "deploy"}},
+			wantErr: "needs a host",
+		},
+		{
+			name: "duplicate",
+			envs: []remote.Environment{
+				{Name: "staging", Host: "a.example.com"},
+				{Name: "staging", Host: "b.example.com"},
+			},
+			wantErr: "duplicate environment",
+		},
+		{
+			name:    "unknown db method",
+			envs:    []remote.Environment{{Name: "staging", Host: "a.example.com", DB: &remote.DBAccess{Method: "ftp"}}},
+			wantErr: "unknown db method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := base(tt.envs...).Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
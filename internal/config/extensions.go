@@ -0,0 +1,21 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// extensionNamePattern matches a PHP extension name, e.g. imagick or pdo_mysql
+const extensionNamePattern = `^[A-Za-z0-9_]+$`
+
+var extensionNameRegexp = regexp.MustCompile(extensionNamePattern)
+
+// validatePHPExtensions checks the php_extensions list
+func (c *Config) validatePHPExtensions() error {
+	for i, ext := range c.PHPExtensions {
+		if !extensionNameRegexp.MatchString(ext) {
+			return &ValidationError{Field: "php_extensions", Message: fmt.Sprintf("invalid extension name %q", ext), Index: i}
+		}
+	}
+	return nil
+}
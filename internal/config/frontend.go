@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDevServerPaths are the URL paths a Vite dev server answers, proxied
+// to it when the dev_server section lists none
+var DefaultDevServerPaths = []string{"/@vite", "/@id", "/@fs", "/node_modules"}
+
+// FrontendConfig is the frontend tooling of a project: the Node.js version
+// its themes are built with and the dev server nginx proxies to
+type FrontendConfig struct {
+	Node      string           `yaml:"node,omitempty"`       // Node.js version, e.g. "20" (default: .nvmrc, .node-version or engines.node of package.json)
+	Theme     string           `yaml:"theme,omitempty"`      // Theme 'magebox theme watch' runs without an argument, e.g. "Vendor/theme"
+	DevServer *DevServerConfig `yaml:"dev_server,omitempty"` // Dev server of headless and Vite based themes
+}
+
+// DevServerConfig is a frontend dev server running on the host, e.g. Vite.
+// Its paths are proxied on every domain of the project, websockets included,
+// so hot module replacement works on the project URL.
+type DevServerConfig struct {
+	Port  int      `yaml:"port"`            // Port the dev server listens on, e.g. 5173
+	Paths []string `yaml:"paths,omitempty"` // URL paths proxied to the dev server (default: DefaultDevServerPaths)
+}
+
+// GetPaths returns the proxied URL paths without trailing slashes
+func (d *DevServerConfig) GetPaths() []string {
+	paths := d.Paths
+	if len(paths) == 0 {
+		paths = DefaultDevServerPaths
+	}
+	locations := make([]string, 0, len(paths))
+	for _, p := range paths {
+		locations = append(locations, strings.TrimRight(p, "/"))
+	}
+	return locations
+}
+
+// URL returns the address of the dev server on the host
+func (d *DevServerConfig) URL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", d.Port)
+}
+
+// NodeVersion returns the configured Node.js version, empty when the project
+// leaves it to its version files
+func (c *Config) NodeVersion() string {
+	if c.Frontend == nil {
+		return ""
+	}
+	return c.Frontend.Node
+}
+
+// DevServer returns the dev server of the project, nil when it has none
+func (c *Config) DevServer() *DevServerConfig {
+	if c.Frontend == nil {
+		return nil
+	}
+	return c.Frontend.DevServer
+}
+
+// validateFrontend checks the frontend section
+func (c *Config) validateFrontend() error {
+	if c.Frontend == nil {
+		return nil
+	}
+	if strings.ContainsAny(c.Frontend.Node, " \t;&|$`\"'") {
+		return &ValidationError{Field: "frontend.node", Message: fmt.Sprintf("invalid node version %q", c.Frontend.Node)}
+	}
+	if c.Frontend.Theme != "" && strings.Count(c.Frontend.Theme, "/") != 1 {
+		return &ValidationError{Field: "frontend.theme", Message: fmt.Sprintf("theme %q must be Vendor/theme", c.Frontend.Theme)}
+	}
+
+	ds := c.Frontend.DevServer
+	if ds == nil {
+		return nil
+	}
+	if ds.Port < 1 || ds.Port > 65535 {
+		return &ValidationError{Field: "frontend.dev_server.port", Message: fmt.Sprintf("invalid port %d", ds.Port)}
+	}
+	for _, p := range ds.GetPaths() {
+		switch {
+		case p == "":
+			return &ValidationError{Field: "frontend.dev_server.paths", Message: "path '/' is served by the domain root"}
+		case !strings.HasPrefix(p, "/"):
+			return &ValidationError{Field: "frontend.dev_server.paths", Message: fmt.Sprintf("path '%s' must start with /", p)}
+		case strings.ContainsAny(p, " \t;{}$\"'"):
+			return &ValidationError{Field: "frontend.dev_server.paths", Message: fmt.Sprintf("path '%s' contains characters not allowed in an nginx location", p)}
+		}
+		if c.GetType() == ProjectTypeMagento {
+			for _, reserved := range magentoVhostPaths {
+				if p == reserved {
+					return &ValidationError{Field: "frontend.dev_server.paths", Message: fmt.Sprintf("path '%s' is already served by the Magento vhost", p)}
+				}
+			}
+		}
+		for i, d := range c.Domains {
+			for _, m := range d.Paths {
+				if m.Location() == p {
+					return &ValidationError{Field: "domains", Message: fmt.Sprintf("%s: path '%s' is also a dev server path", d.Host, p), Index: i}
+				}
+			}
+		}
+	}
+	return nil
+}
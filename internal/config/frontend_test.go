@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_Frontend(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+name: mystore
+domains:
+  - host: mystore.test
+php: "8.3"
+frontend:
+  node: "20"
+  theme: Vendor/hyva
+  dev_server:
+    port: 5173
+`
+	local := `
+frontend:
+  node: "22"
+`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(local), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(dir)
+	if err != nil {
+		t.Fatalf("LoadFromPath() error = %v", err)
+	}
+	if cfg.NodeVersion() != "22" {
+		t.Errorf("NodeVersion() = %q, want the local override", cfg.NodeVersion())
+	}
+	if cfg.Frontend.Theme != "Vendor/hyva" {
+		t.Errorf("Theme = %q, want it kept from the main config", cfg.Frontend.Theme)
+	}
+	ds := cfg.DevServer()
+	if ds == nil || ds.URL() != "http://127.0.0.1:5173" {
+		t.Fatalf("DevServer() = %+v", ds)
+	}
+	if strings.Join(ds.GetPaths(), ",") != strings.Join(DefaultDevServerPaths, ",") {
+		t.Errorf("GetPaths() = %v, want the Vite defaults", ds.GetPaths())
+	}
+}
+
+func TestConfig_ValidateFrontend(t *testing.T) {
+	tests := []struct {
+		name     string
+		frontend *FrontendConfig
+		paths    []PathMapping
+		wantErr  string
+	}{
+		{name: "none"},
+		{name: "valid", frontend: &FrontendConfig{Node: "lts/iron", Theme: "Vendor/theme", DevServer: &DevServerConfig{Port: 5173, Paths: []string{"/@vite/", "/src"}}}},
+		{name: "bad node", frontend: &FrontendConfig{Node: "20; rm -rf ~"}, wantErr: "invalid node version"},
+		{name: "bad theme", frontend: &FrontendConfig{Theme: "hyva"}, wantErr: "Vendor/theme"},
+		{name: "no port", frontend: &FrontendConfig{DevServer: &DevServerConfig{}}, wantErr: "invalid port 0"},
+		{name: "relative path", frontend: &FrontendConfig{DevServer: &DevServerConfig{Port: 5173, Paths: []string{"src"}}}, wantErr: "must start with /"},
+		{name: "root path", frontend: &FrontendConfig{DevServer: &DevServerConfig{Port: 5173, Paths: []string{"/"}}}, wantErr: "served by the domain root"},
+		{name: "injection", frontend: &FrontendConfig{DevServer: &DevServerConfig{Port: 5173, Paths: []string{"/a { deny all; }"}}}, wantErr: "not allowed"},
+		{name: "reserved", frontend: &FrontendConfig{DevServer: &DevServerConfig{Port: 5173, Paths: []string{"/static"}}}, wantErr: "already served by the Magento vhost"},
+		{
+			name:     "domain path",
+			frontend: &FrontendConfig{DevServer: &DevServerConfig{Port: 5173}},
+			paths:    []PathMapping{{Path: "/@vite", Proxy: "http://127.0.0.1:3000"}},
+			wantErr:  "also a dev server path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Name:     "mystore",
+				PHP:      "8.3",
+				Domains:  []Domain{{Host: "mystore.test", Paths: tt.paths}},
+				Frontend: tt.frontend,
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeFrontend(t *testing.T) {
+	main := &FrontendConfig{Node: "20", DevServer: &DevServerConfig{Port: 5173}}
+	local := &FrontendConfig{DevServer: &DevServerConfig{Port: 3000}}
+
+	got := mergeFrontend(main, local)
+	if got.Node != "20" || got.DevServer.Port != 3000 {
+		t.Errorf("mergeFrontend() = %+v", got)
+	}
+	if main.DevServer.Port != 5173 {
+		t.Error("mergeFrontend() modified the main settings")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 
+	"qoliber/magebox/internal/execctx"
 	"qoliber/magebox/internal/remote"
 
 	"gopkg.in/yaml.v3"
@@ -34,12 +36,19 @@ type GlobalConfig struct {
 	// TLD is the top-level domain for local development (default: "test")
 	TLD string `yaml:"tld,omitempty"`
 
+	// TLDs are additional top-level domains routed to this machine, e.g. the
+	// TLDs of projects that set their own
+	TLDs []string `yaml:"tlds,omitempty"`
+
 	// Editor is the preferred editor for opening files
 	Editor string `yaml:"editor,omitempty"`
 
 	// AutoStart enables automatic service startup
 	AutoStart bool `yaml:"auto_start,omitempty"`
 
+	// LowMemory makes 'magebox start' use the low-memory profile by default
+	LowMemory bool `yaml:"low_memory,omitempty"`
+
 	// DockerProvider specifies which Docker provider to use: "auto", "desktop", "colima", "orbstack", "rancher", "lima"
 	DockerProvider string `yaml:"docker_provider,omitempty"`
 
@@ -54,6 +63,37 @@ type GlobalConfig struct {
 
 	// Sandbox configures the bubblewrap sandbox for AI coding agents
 	Sandbox *SandboxConfig `yaml:"sandbox,omitempty"`
+
+	// Timeouts limits how long external commands may run before they are killed
+	Timeouts *TimeoutsConfig `yaml:"timeouts,omitempty"`
+
+	// VersionCatalog configures where 'magebox new' gets the Magento and MageOS releases
+	VersionCatalog *VersionCatalogConfig `yaml:"version_catalog,omitempty"`
+}
+
+// DefaultVersionCatalogTTL is how long fetched releases are cached
+const DefaultVersionCatalogTTL = 24 * time.Hour
+
+// VersionCatalogConfig configures the release list of 'magebox new'. By
+// default the releases are fetched from Packagist and the Mage-OS repository.
+type VersionCatalogConfig struct {
+	// URL of a manifest in the versions.yaml format, YAML or JSON, that
+	// replaces the package repositories
+	URL string `yaml:"url,omitempty"`
+
+	// Offline uses the bundled release list without fetching
+	Offline bool `yaml:"offline,omitempty"`
+
+	// TTL is how long fetched releases are cached, as a Go duration
+	TTL string `yaml:"ttl,omitempty"`
+}
+
+// TimeoutsConfig contains per-operation timeouts as Go durations ("10m", "90s").
+// "0" disables a timeout.
+type TimeoutsConfig struct {
+	Docker string `yaml:"docker,omitempty"`
+	Nginx  string `yaml:"nginx,omitempty"`
+	Ready  string `yaml:"ready,omitempty"`
 }
 
 // ProfilingConfig contains credentials for profiling tools
@@ -99,14 +139,14 @@ type TidewaysCredentials struct {
 
 // DefaultServices represents default service configurations
 type DefaultServices struct {
-	MySQL         string `yaml:"mysql,omitempty"`
-	MariaDB       string `yaml:"mariadb,omitempty"`
-	Redis         bool   `yaml:"redis,omitempty"`
-	Valkey        bool   `yaml:"valkey,omitempty"`
-	OpenSearch    string `yaml:"opensearch,omitempty"`
-	Elasticsearch string `yaml:"elasticsearch,omitempty"`
-	RabbitMQ      bool   `yaml:"rabbitmq,omitempty"`
-	Mailpit       bool   `yaml:"mailpit,omitempty"`
+	MySQL         string `yaml:"mysql,omitempty" json:"mysql,omitempty"`
+	MariaDB       string `yaml:"mariadb,omitempty" json:"mariadb,omitempty"`
+	Redis         bool   `yaml:"redis,omitempty" json:"redis,omitempty"`
+	Valkey        bool   `yaml:"valkey,omitempty" json:"valkey,omitempty"`
+	OpenSearch    string `yaml:"opensearch,omitempty" json:"opensearch,omitempty"`
+	Elasticsearch string `yaml:"elasticsearch,omitempty" json:"elasticsearch,omitempty"`
+	RabbitMQ      bool   `yaml:"rabbitmq,omitempty" json:"rabbitmq,omitempty"`
+	Mailpit       bool   `yaml:"mailpit,omitempty" json:"mailpit,omitempty"`
 }
 
 // GlobalConfigPath returns the path to the global config file
@@ -214,6 +254,44 @@ func (c *GlobalConfig) GetTLD() string {
 	return c.TLD
 }
 
+// GetTimeouts returns the configured command timeouts, falling back to the
+// defaults for unset or invalid values
+func (c *GlobalConfig) GetTimeouts() execctx.Timeouts {
+	t := execctx.DefaultTimeouts()
+	if c.Timeouts == nil {
+		return t
+	}
+	t.Docker = parseTimeout(c.Timeouts.Docker, t.Docker)
+	t.Nginx = parseTimeout(c.Timeouts.Nginx, t.Nginx)
+	t.Ready = parseTimeout(c.Timeouts.Ready, t.Ready)
+	return t
+}
+
+// GetVersionCatalogTTL returns how long fetched releases are cached, falling
+// back to a day for unset or invalid values
+func (c *GlobalConfig) GetVersionCatalogTTL() time.Duration {
+	if c.VersionCatalog == nil {
+		return DefaultVersionCatalogTTL
+	}
+	return parseTimeout(c.VersionCatalog.TTL, DefaultVersionCatalogTTL)
+}
+
+// parseTimeout parses a timeout setting, returning fallback when it is empty
+// or invalid
+func parseTimeout(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	if value == "0" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fallback
+	}
+	return d
+}
+
 // HasBlackfireCredentials returns true if Blackfire server credentials are configured
 func (c *GlobalConfig) HasBlackfireCredentials() bool {
 	return c.Profiling.Blackfire.ServerID != "" && c.Profiling.Blackfire.ServerToken != ""
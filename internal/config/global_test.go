@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"qoliber/magebox/internal/execctx"
 )
 
 func TestGlobalConfigPath(t *testing.T) {
@@ -163,6 +166,60 @@ func TestGlobalConfig_GetTLD(t *testing.T) {
 	}
 }
 
+func TestGlobalConfig_GetTimeouts(t *testing.T) {
+	defaults := execctx.DefaultTimeouts()
+
+	tests := []struct {
+		name       string
+		timeouts   *TimeoutsConfig
+		wantDocker time.Duration
+		wantNginx  time.Duration
+		wantReady  time.Duration
+	}{
+		{"unset", nil, defaults.Docker, defaults.Nginx, defaults.Ready},
+		{"custom", &TimeoutsConfig{Docker: "30m", Nginx: "10s", Ready: "5m"}, 30 * time.Minute, 10 * time.Second, 5 * time.Minute},
+		{"partial", &TimeoutsConfig{Nginx: "2m"}, defaults.Docker, 2 * time.Minute, defaults.Ready},
+		{"disabled", &TimeoutsConfig{Docker: "0", Ready: "0"}, 0, defaults.Nginx, 0},
+		{"invalid", &TimeoutsConfig{Docker: "soon", Nginx: "-5s"}, defaults.Docker, defaults.Nginx, defaults.Ready},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&GlobalConfig{Timeouts: tt.timeouts}).GetTimeouts()
+			if got.Docker != tt.wantDocker {
+				t.Errorf("Docker = %v, want %v", got.Docker, tt.wantDocker)
+			}
+			if got.Nginx != tt.wantNginx {
+				t.Errorf("Nginx = %v, want %v", got.Nginx, tt.wantNginx)
+			}
+			if got.Ready != tt.wantReady {
+				t.Errorf("Ready = %v, want %v", got.Ready, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestGlobalConfig_GetVersionCatalogTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog *VersionCatalogConfig
+		want    time.Duration
+	}{
+		{"unset", nil, DefaultVersionCatalogTTL},
+		{"custom", &VersionCatalogConfig{TTL: "6h"}, 6 * time.Hour},
+		{"always fetch", &VersionCatalogConfig{TTL: "0"}, 0},
+		{"invalid", &VersionCatalogConfig{TTL: "weekly"}, DefaultVersionCatalogTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&GlobalConfig{VersionCatalog: tt.catalog}).GetVersionCatalogTTL(); got != tt.want {
+				t.Errorf("GetVersionCatalogTTL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGlobalConfigExists(t *testing.T) {
 	tmpDir := t.TempDir()
 
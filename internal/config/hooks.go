@@ -0,0 +1,92 @@
+package config
+
+// Hook events, the points of the project lifecycle hooks run at
+const (
+	HookPreStart     = "pre_start"
+	HookPostStart    = "post_start"
+	HookPreStop      = "pre_stop"
+	HookPostDBImport = "post_db_import"
+	HookPostSync     = "post_sync"
+)
+
+// Hooks are shell commands run at points of the project lifecycle, from the
+// project directory with the project's PHP and env vars
+type Hooks struct {
+	PreStart     HookCommands `yaml:"pre_start,omitempty"`      // Before 'magebox start'
+	PostStart    HookCommands `yaml:"post_start,omitempty"`     // After 'magebox start'
+	PreStop      HookCommands `yaml:"pre_stop,omitempty"`       // Before 'magebox stop'
+	PostDBImport HookCommands `yaml:"post_db_import,omitempty"` // After 'magebox db import', also when syncing
+	PostSync     HookCommands `yaml:"post_sync,omitempty"`      // After 'magebox sync'
+}
+
+// HookCommands are the commands of a hook, run in order
+type HookCommands []string
+
+// UnmarshalYAML allows a hook to be a single command or a list
+func (h *HookCommands) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var run string
+	if err := unmarshal(&run); err == nil {
+		// hooks:
+		//   post_db_import: "php bin/magento setup:upgrade"
+		*h = HookCommands{run}
+		return nil
+	}
+
+	// hooks:
+	//   post_db_import:
+	//     - "php bin/magento setup:upgrade"
+	//     - "php bin/magento cache:flush"
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*h = list
+	return nil
+}
+
+// Commands returns the commands of a hook event
+func (h *Hooks) Commands(event string) []string {
+	if h == nil {
+		return nil
+	}
+	switch event {
+	case HookPreStart:
+		return h.PreStart
+	case HookPostStart:
+		return h.PostStart
+	case HookPreStop:
+		return h.PreStop
+	case HookPostDBImport:
+		return h.PostDBImport
+	case HookPostSync:
+		return h.PostSync
+	}
+	return nil
+}
+
+// mergeHooks merges hooks event by event, a hook defined in local replaces main's
+func mergeHooks(main, local *Hooks) *Hooks {
+	if local == nil {
+		return main
+	}
+	if main == nil {
+		return local
+	}
+	result := *main
+	if len(local.PreStart) > 0 {
+		result.PreStart = local.PreStart
+	}
+	if len(local.PostStart) > 0 {
+		result.PostStart = local.PostStart
+	}
+	if len(local.PreStop) > 0 {
+		result.PreStop = local.PreStop
+	}
+	if len(local.PostDBImport) > 0 {
+		result.PostDBImport = local.PostDBImport
+	}
+	if len(local.PostSync) > 0 {
+		result.PostSync = local.PostSync
+	}
+	return &result
+}
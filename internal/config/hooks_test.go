@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHooks_Load(t *testing.T) {
+	dir := t.TempDir()
+	main := `name: mystore
+domains:
+  - host: mystore.test
+php: "8.3"
+hooks:
+  post_start: "php bin/magento cache:flush"
+  post_db_import:
+    - "php bin/magento setup:upgrade"
+    - "php bin/magento cache:flush"
+`
+	local := `hooks:
+  post_start:
+    - "php bin/magento indexer:reindex"
+`
+	_ = os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(main), 0644)
+	_ = os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(local), 0644)
+
+	cfg, err := NewLoader(dir).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]string{
+		HookPostStart:    {"php bin/magento indexer:reindex"},
+		HookPostDBImport: {"php bin/magento setup:upgrade", "php bin/magento cache:flush"},
+		HookPreStop:      nil,
+	}
+	for event, want := range tests {
+		if got := cfg.Hooks.Commands(event); !reflect.DeepEqual(got, want) {
+			t.Errorf("Commands(%s) = %v, want %v", event, got, want)
+		}
+	}
+
+	issues, err := ValidateYAML([]byte(main))
+	if err != nil || len(issues) > 0 {
+		t.Errorf("ValidateYAML() = %v, %v", issues, err)
+	}
+	issues, _ = ValidateYAML([]byte("hooks:\n  post_import: \"true\"\n"))
+	if len(issues) != 1 {
+		t.Errorf("ValidateYAML() with an unknown hook = %v, want one issue", issues)
+	}
+}
+
+func TestHooks_CommandsNil(t *testing.T) {
+	var h *Hooks
+	if got := h.Commands(HookPreStart); got != nil {
+		t.Errorf("Commands() on nil hooks = %v", got)
+	}
+}
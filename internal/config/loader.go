@@ -19,25 +19,51 @@ const (
 	LocalConfigFileName = ".magebox.local.yaml"
 	// LocalConfigFileNameLegacy is the legacy local configuration file name (for backward compatibility)
 	LocalConfigFileNameLegacy = ".magebox.local"
+	// DefaultsFileName is the file in ~/.magebox merged under every project config
+	DefaultsFileName = "defaults.yaml"
 )
 
+// DefaultsPath returns the path of the global project defaults
+func DefaultsPath(homeDir string) string {
+	return filepath.Join(homeDir, ".magebox", DefaultsFileName)
+}
+
 // Loader handles loading and merging configuration files
 type Loader struct {
-	basePath string
+	basePath     string
+	defaultsPath string
+	loaded       []string
 }
 
 // NewLoader creates a new configuration loader
 func NewLoader(basePath string) *Loader {
-	return &Loader{basePath: basePath}
+	l := &Loader{basePath: basePath}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		l.defaultsPath = DefaultsPath(homeDir)
+	}
+	return l
 }
 
-// Load loads and merges the configuration from .magebox.yaml (or .magebox for backward compatibility) and .magebox.local
+// Load loads and merges the configuration from ~/.magebox/defaults.yaml,
+// .magebox.yaml (or .magebox for backward compatibility) and .magebox.local,
+// each taking precedence over the ones before it
 func (l *Loader) Load() (*Config, error) {
 	mainConfigPath := filepath.Join(l.basePath, ConfigFileName)
 	legacyConfigPath := filepath.Join(l.basePath, ConfigFileNameLegacy)
 	localConfigPath := filepath.Join(l.basePath, LocalConfigFileName)
 
 	visited := make(map[string]bool)
+	l.loaded = nil
+
+	// Global defaults (optional), merged under the project's own config
+	var defaults *Config
+	if l.defaultsPath != "" {
+		var err error
+		defaults, err = l.loadFileWithIncludes(l.defaultsPath, visited)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load %s: %w", l.defaultsPath, err)
+		}
+	}
 
 	// Try to load new format first, fall back to legacy
 	mainConfig, err := l.loadFileWithIncludes(mainConfigPath, visited)
@@ -65,9 +91,25 @@ func (l *Loader) Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load local config: %w", err)
 	}
 
+	if defaults != nil {
+		mainConfig = l.merge(defaults, mainConfig)
+	}
+
 	// Merge configs (local overrides main)
 	config := l.merge(mainConfig, localConfig)
 
+	// Apply the active profile between main and local, so local overrides
+	// still win over the profile
+	if config.Profile != "" {
+		profile, ok := config.Profiles[config.Profile]
+		if !ok {
+			return nil, &ValidationError{Field: "profile", Message: fmt.Sprintf("unknown profile %q (available: %s)", config.Profile, strings.Join(config.ProfileNames(), ", "))}
+		}
+		name, profiles := config.Profile, config.Profiles
+		config = l.merge(l.merge(mainConfig, profileOverlay(profile)), localConfig)
+		config.Profile, config.Profiles = name, profiles
+	}
+
 	// Validate the merged config
 	if err := config.Validate(); err != nil {
 		return nil, err
@@ -76,6 +118,12 @@ func (l *Loader) Load() (*Config, error) {
 	return config, nil
 }
 
+// LoadedFiles returns the absolute paths of every file read by the last Load call,
+// in the order they were read (main config and its includes, then local overrides)
+func (l *Loader) LoadedFiles() []string {
+	return l.loaded
+}
+
 // loadFileWithIncludes loads a config file and recursively processes include_config entries.
 // visited tracks absolute paths that have already been loaded to detect circular includes.
 func (l *Loader) loadFileWithIncludes(path string, visited map[string]bool) (*Config, error) {
@@ -93,19 +141,28 @@ func (l *Loader) loadFileWithIncludes(path string, visited map[string]bool) (*Co
 		return nil, fmt.Errorf("circular include detected: %s", path)
 	}
 	visited[absPath] = true
+	l.loaded = append(l.loaded, absPath)
 
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, &ParseError{Path: path, Err: err}
 	}
 
+	// Paths in the file are relative to the file's directory
+	baseDir := filepath.Dir(absPath)
+	if v := config.Services.Varnish; v != nil && v.VCLExtra != "" {
+		v.VCLExtraPath = v.VCLExtra
+		if !filepath.IsAbs(v.VCLExtra) {
+			v.VCLExtraPath = filepath.Join(baseDir, v.VCLExtra)
+		}
+	}
+
 	if len(config.IncludeConfig) == 0 {
 		return &config, nil
 	}
 
 	// Process include_config entries: accumulate included configs in order, then
 	// merge the current file's own fields on top so they take final precedence.
-	baseDir := filepath.Dir(absPath)
 	base := &Config{}
 
 	for _, includePath := range config.IncludeConfig {
@@ -169,7 +226,10 @@ func (l *Loader) resolveIncludePaths(baseDir, includePath string) ([]string, err
 	return paths, nil
 }
 
-// merge merges two configurations, with local taking precedence
+// merge merges two configurations, with local taking precedence.
+// Scalars set in local replace those of main, maps (env, commands, php_ini)
+// are merged key by key, services, testing tools and frontend field by field, hooks
+// event by event, and environments by name. Domains are a list and are replaced as a whole.
 func (l *Loader) merge(main, local *Config) *Config {
 	if local == nil {
 		return main
@@ -187,6 +247,9 @@ func (l *Loader) merge(main, local *Config) *Config {
 	if local.PHP != "" {
 		result.PHP = local.PHP
 	}
+	if local.TLD != "" {
+		result.TLD = local.TLD
+	}
 	if len(local.Domains) > 0 {
 		result.Domains = local.Domains
 	}
@@ -196,31 +259,35 @@ func (l *Loader) merge(main, local *Config) *Config {
 	if local.Isolated {
 		result.Isolated = local.Isolated
 	}
-	if local.Testing != nil {
-		result.Testing = local.Testing
-	}
 	if local.Sandbox != nil {
 		result.Sandbox = local.Sandbox
 	}
-
-	// Merge services
-	result.Services = l.mergeServices(main.Services, local.Services)
-
-	// Merge env vars
-	if result.Env == nil {
-		result.Env = make(map[string]string)
+	if local.Profiler != "" {
+		result.Profiler = local.Profiler
+	}
+	if local.Profile != "" {
+		result.Profile = local.Profile
+	}
+	if local.Deploy != nil {
+		result.Deploy = local.Deploy
 	}
-	for k, v := range main.Env {
-		result.Env[k] = v
+	if local.Sanitize != nil {
+		result.Sanitize = local.Sanitize
 	}
-	for k, v := range local.Env {
-		result.Env[k] = v
+	if len(local.PHPExtensions) > 0 {
+		result.PHPExtensions = local.PHPExtensions
 	}
+	result.Xdebug = mergeXdebug(main.Xdebug, local.Xdebug)
+	result.Frontend = mergeFrontend(main.Frontend, local.Frontend)
+	result.Hooks = mergeHooks(main.Hooks, local.Hooks)
+
+	result.Services = l.mergeServices(main.Services, local.Services)
+	result.Testing = mergeTesting(main.Testing, local.Testing)
+	result.Env = mergeStringMap(main.Env, local.Env)
+	result.PHPINI = mergeStringMap(main.PHPINI, local.PHPINI)
 
 	// Merge commands
-	if result.Commands == nil {
-		result.Commands = make(map[string]Command)
-	}
+	result.Commands = make(map[string]Command, len(main.Commands)+len(local.Commands))
 	for k, v := range main.Commands {
 		result.Commands[k] = v
 	}
@@ -228,52 +295,223 @@ func (l *Loader) merge(main, local *Config) *Config {
 		result.Commands[k] = v
 	}
 
-	// Merge PHP INI overrides
-	if result.PHPINI == nil {
-		result.PHPINI = make(map[string]string)
-	}
-	for k, v := range main.PHPINI {
-		result.PHPINI[k] = v
-	}
-	for k, v := range local.PHPINI {
-		result.PHPINI[k] = v
+	// Merge remote environments by name
+	result.Environments = mergeEnvironments(main.Environments, local.Environments)
+
+	// Merge profiles by name; a profile defined in local replaces main's
+	if len(local.Profiles) > 0 {
+		result.Profiles = make(map[string]*Config, len(main.Profiles)+len(local.Profiles))
+		for k, v := range main.Profiles {
+			result.Profiles[k] = v
+		}
+		for k, v := range local.Profiles {
+			result.Profiles[k] = v
+		}
 	}
 
 	return &result
 }
 
-// mergeServices merges service configurations
+// profileOverlay returns a profile without the keys that only make sense at
+// the top level, so a profile can't select or define other profiles
+func profileOverlay(profile *Config) *Config {
+	if profile == nil {
+		return nil
+	}
+	overlay := *profile
+	overlay.Profile = ""
+	overlay.Profiles = nil
+	overlay.IncludeConfig = nil
+	return &overlay
+}
+
+// mergeStringMap returns a new map with the entries of main overridden by local
+func mergeStringMap(main, local map[string]string) map[string]string {
+	result := make(map[string]string, len(main)+len(local))
+	for k, v := range main {
+		result[k] = v
+	}
+	for k, v := range local {
+		result[k] = v
+	}
+	return result
+}
+
+// mergeServices merges service configurations. Enabling MySQL, MariaDB or
+// Percona (Redis or Valkey, OpenSearch or Elasticsearch) in local switches
+// engines: the others of main are dropped unless local configures them too.
 func (l *Loader) mergeServices(main, local Services) Services {
-	result := main
+	result := Services{
+		MySQL:         mergeService(main.MySQL, local.MySQL),
+		MariaDB:       mergeService(main.MariaDB, local.MariaDB),
+		Percona:       mergeService(main.Percona, local.Percona),
+		Redis:         mergeService(main.Redis, local.Redis),
+		Valkey:        mergeService(main.Valkey, local.Valkey),
+		OpenSearch:    mergeService(main.OpenSearch, local.OpenSearch),
+		Elasticsearch: mergeService(main.Elasticsearch, local.Elasticsearch),
+		Meilisearch:   mergeService(main.Meilisearch, local.Meilisearch),
+		Typesense:     mergeService(main.Typesense, local.Typesense),
+		RabbitMQ:      mergeService(main.RabbitMQ, local.RabbitMQ),
+		Mailpit:       mergeService(main.Mailpit, local.Mailpit),
+		Varnish:       mergeService(main.Varnish, local.Varnish),
+		PhpMyAdmin:    mergeService(main.PhpMyAdmin, local.PhpMyAdmin),
+
+		OpenSearchDashboards: mergeService(main.OpenSearchDashboards, local.OpenSearchDashboards),
 
-	if local.MySQL != nil {
-		result.MySQL = local.MySQL
+		ComposerMirror: mergeService(main.ComposerMirror, local.ComposerMirror),
 	}
-	if local.MariaDB != nil {
-		result.MariaDB = local.MariaDB
+
+	localDB := local.HasMySQL() || local.HasMariaDB() || local.HasPercona()
+	if localDB && local.MySQL == nil {
+		result.MySQL = nil
 	}
-	if local.Redis != nil {
-		result.Redis = local.Redis
+	if localDB && local.MariaDB == nil {
+		result.MariaDB = nil
 	}
-	if local.OpenSearch != nil {
-		result.OpenSearch = local.OpenSearch
+	if localDB && local.Percona == nil {
+		result.Percona = nil
 	}
-	if local.Elasticsearch != nil {
-		result.Elasticsearch = local.Elasticsearch
+	if local.HasValkey() && local.Redis == nil {
+		result.Redis = nil
 	}
-	if local.RabbitMQ != nil {
-		result.RabbitMQ = local.RabbitMQ
+	if local.HasRedis() && local.Valkey == nil {
+		result.Valkey = nil
 	}
-	if local.Mailpit != nil {
-		result.Mailpit = local.Mailpit
+	if local.HasElasticsearch() && local.OpenSearch == nil {
+		result.OpenSearch = nil
 	}
-	if local.Varnish != nil {
-		result.Varnish = local.Varnish
+	if local.HasOpenSearch() && local.Elasticsearch == nil {
+		result.Elasticsearch = nil
 	}
 
 	return result
 }
 
+// mergeService overrides the fields of main that are set in local, server
+// config settings key by key. A service local turns off, or that main doesn't
+// enable, is taken from local as is.
+func mergeService(main, local *ServiceConfig) *ServiceConfig {
+	if local == nil {
+		return main
+	}
+	if main == nil || !main.Enabled || !local.Enabled {
+		return local
+	}
+
+	merged := *main
+	if local.Version != "" {
+		merged.Version = local.Version
+	}
+	if local.Port != 0 {
+		merged.Port = local.Port
+	}
+	if local.Memory != "" {
+		merged.Memory = local.Memory
+	}
+	if local.User != "" {
+		merged.User = local.User
+	}
+	if local.Password != "" {
+		merged.Password = local.Password
+	}
+	if local.Database != "" {
+		merged.Database = local.Database
+	}
+	if len(local.Databases) > 0 {
+		merged.Databases = local.Databases
+	}
+	if local.VCLExtra != "" {
+		merged.VCLExtra = local.VCLExtra
+		merged.VCLExtraPath = local.VCLExtraPath
+	}
+	if local.Mode != "" {
+		merged.Mode = local.Mode
+	}
+	if local.Platform != "" {
+		merged.Platform = local.Platform
+	}
+	if len(local.Config) > 0 {
+		merged.Config = mergeStringMap(main.Config, local.Config)
+	}
+	return &merged
+}
+
+// mergeXdebug merges Xdebug settings field by field
+func mergeXdebug(main, local *XdebugSettings) *XdebugSettings {
+	if local == nil {
+		return main
+	}
+	if main == nil {
+		return local
+	}
+
+	merged := *main
+	if local.Mode != "" {
+		merged.Mode = local.Mode
+	}
+	if local.StartWithRequest != "" {
+		merged.StartWithRequest = local.StartWithRequest
+	}
+	if local.OutputDir != "" {
+		merged.OutputDir = local.OutputDir
+	}
+	return &merged
+}
+
+// mergeFrontend merges frontend settings field by field, a dev server in
+// local replaces main's
+func mergeFrontend(main, local *FrontendConfig) *FrontendConfig {
+	if local == nil {
+		return main
+	}
+	if main == nil {
+		return local
+	}
+
+	merged := *main
+	if local.Node != "" {
+		merged.Node = local.Node
+	}
+	if local.Theme != "" {
+		merged.Theme = local.Theme
+	}
+	if local.DevServer != nil {
+		merged.DevServer = local.DevServer
+	}
+	return &merged
+}
+
+// mergeTesting merges testing configurations tool by tool
+func mergeTesting(main, local *TestingConfig) *TestingConfig {
+	if local == nil {
+		return main
+	}
+	if main == nil {
+		return local
+	}
+
+	merged := *main
+	if local.PHPUnit != nil {
+		merged.PHPUnit = local.PHPUnit
+	}
+	if local.Integration != nil {
+		merged.Integration = local.Integration
+	}
+	if local.PHPStan != nil {
+		merged.PHPStan = local.PHPStan
+	}
+	if local.PHPCS != nil {
+		merged.PHPCS = local.PHPCS
+	}
+	if local.PHPMD != nil {
+		merged.PHPMD = local.PHPMD
+	}
+	if local.PHPCSFixer != nil {
+		merged.PHPCSFixer = local.PHPCSFixer
+	}
+	return &merged
+}
+
 // ConfigNotFoundError indicates the configuration file was not found
 type ConfigNotFoundError struct {
 	Path string
@@ -312,26 +550,38 @@ func LoadFromCurrentDir() (*Config, error) {
 func SaveToPath(cfg *Config, path string) error {
 	configPath := filepath.Join(path, ConfigFileName)
 
-	var buf bytes.Buffer
-	enc := yaml.NewEncoder(&buf)
-	enc.SetIndent(2)
-	if err := enc.Encode(cfg); err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	data, err := Marshal(cfg)
+	if err != nil {
+		return err
 	}
-	enc.Close()
 
-	if err := os.WriteFile(configPath, buf.Bytes(), 0644); err != nil {
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
-// LocalConfig represents local config overrides that can be saved independently
+// Marshal encodes a config as YAML the way it is written to .magebox.yaml
+func Marshal(cfg *Config) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	enc.Close()
+	return buf.Bytes(), nil
+}
+
+// LocalConfig represents local config overrides that can be saved independently.
+// Keys other than php, php_ini and env are kept in Other so saving the file
+// doesn't drop the rest of the overlay.
 type LocalConfig struct {
-	PHP    string            `yaml:"php,omitempty"`
-	PHPINI map[string]string `yaml:"php_ini,omitempty"`
-	Env    map[string]string `yaml:"env,omitempty"`
+	PHP    string                 `yaml:"php,omitempty"`
+	PHPINI map[string]string      `yaml:"php_ini,omitempty"`
+	Env    map[string]string      `yaml:"env,omitempty"`
+	Other  map[string]interface{} `yaml:",inline"`
 }
 
 // LoadLocalConfig loads only the local config file
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -225,6 +226,104 @@ env:
 	})
 }
 
+func TestLoader_LocalDeepMerge(t *testing.T) {
+	dir := t.TempDir()
+
+	mainConfig := `
+name: mystore
+domains:
+  - host: mystore.test
+php: "8.2"
+services:
+  mysql:
+    version: "8.0"
+    user: mystore
+    password: secret
+  redis: true
+  opensearch: "2.19"
+commands:
+  reindex: "php bin/magento indexer:reindex"
+testing:
+  phpstan:
+    enabled: true
+    level: 5
+`
+	localConfig := `
+services:
+  mysql:
+    memory: 4g
+  valkey: true
+  opensearch: false
+commands:
+  deploy: "php bin/magento setup:upgrade"
+testing:
+  phpunit:
+    enabled: true
+`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(mainConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(localConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(dir).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mysql := cfg.Services.MySQL
+	if mysql.Version != "8.0" || mysql.User != "mystore" || mysql.Password != "secret" || mysql.Memory != "4g" {
+		t.Errorf("MySQL = %+v, want main fields with local memory", mysql)
+	}
+	if !cfg.Services.HasValkey() || cfg.Services.Redis != nil {
+		t.Error("enabling valkey locally should replace redis from main")
+	}
+	if cfg.Services.HasOpenSearch() {
+		t.Error("opensearch: false in local should disable OpenSearch")
+	}
+	if len(cfg.Commands) != 2 {
+		t.Errorf("Commands = %v, want main and local commands", cfg.Commands)
+	}
+	if cfg.Testing.PHPStan == nil || cfg.Testing.PHPStan.Level != 5 || cfg.Testing.PHPUnit == nil {
+		t.Errorf("Testing = %+v, want phpstan from main and phpunit from local", cfg.Testing)
+	}
+}
+
+func TestSaveLocalConfig_KeepsOverlay(t *testing.T) {
+	dir := t.TempDir()
+	localConfig := `
+php: "8.3"
+services:
+  mysql: "8.4"
+domains:
+  - host: mystore.localhost
+`
+	if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(localConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := LoadLocalConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadLocalConfig() error = %v", err)
+	}
+	local.PHPINI = map[string]string{"memory_limit": "2G"}
+	if err := SaveLocalConfig(dir, local); err != nil {
+		t.Fatalf("SaveLocalConfig() error = %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte("name: mystore\nphp: \"8.2\"\ndomains:\n  - host: mystore.test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := NewLoader(dir).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.PHPINI["memory_limit"] != "2G" || !cfg.Services.HasMySQL() || cfg.Domains[0].Host != "mystore.localhost" {
+		t.Errorf("saving the local config dropped overrides: %+v", cfg)
+	}
+}
+
 func TestLoader_FullConfig(t *testing.T) {
 	dir := t.TempDir()
 
@@ -749,3 +848,314 @@ include_config:
 		}
 	})
 }
+
+func TestLoader_LoadedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mainContent := `name: mystore
+include_config:
+  - shared.yaml
+domains:
+  - host: mystore.test
+php: "8.2"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, ConfigFileName), []byte(mainContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "shared.yaml"), []byte("env:\n  A: b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(tmpDir)
+	if _, err := loader.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	files := loader.LoadedFiles()
+	if len(files) != 2 {
+		t.Fatalf("LoadedFiles() = %v, want 2 files", files)
+	}
+	if filepath.Base(files[0]) != ConfigFileName || filepath.Base(files[1]) != "shared.yaml" {
+		t.Errorf("LoadedFiles() = %v, want main config then include", files)
+	}
+}
+
+func TestLoader_Profiles(t *testing.T) {
+	mainConfig := `
+name: mystore
+domains:
+  - host: mystore.test
+php: "8.2"
+php_ini:
+  memory_limit: 2G
+env:
+  MAGE_MODE: developer
+services:
+  mysql: "8.0"
+profiles:
+  performance:
+    php: "8.3"
+    php_ini:
+      xdebug.mode: "off"
+    env:
+      MAGE_MODE: production
+    services:
+      varnish: true
+  minimal:
+    services:
+      mysql: false
+`
+
+	tests := []struct {
+		name        string
+		local       string
+		wantErr     bool
+		wantProfile string
+		wantPHP     string
+		wantMode    string
+		wantVarnish bool
+	}{
+		{
+			name:     "no profile active",
+			wantPHP:  "8.2",
+			wantMode: "developer",
+		},
+		{
+			name:        "profile merged over main",
+			local:       "profile: performance\n",
+			wantProfile: "performance",
+			wantPHP:     "8.3",
+			wantMode:    "production",
+			wantVarnish: true,
+		},
+		{
+			name:        "local wins over profile",
+			local:       "profile: performance\nphp: \"8.1\"\nenv:\n  MAGE_MODE: default\n",
+			wantProfile: "performance",
+			wantPHP:     "8.1",
+			wantMode:    "default",
+			wantVarnish: true,
+		},
+		{
+			name:    "unknown profile",
+			local:   "profile: turbo\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(mainConfig), 0644); err != nil {
+				t.Fatalf("failed to write main config: %v", err)
+			}
+			if tt.local != "" {
+				if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(tt.local), 0644); err != nil {
+					t.Fatalf("failed to write local config: %v", err)
+				}
+			}
+
+			cfg, err := NewLoader(dir).Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error for unknown profile")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cfg.Profile != tt.wantProfile {
+				t.Errorf("Profile = %q, want %q", cfg.Profile, tt.wantProfile)
+			}
+			if cfg.PHP != tt.wantPHP {
+				t.Errorf("PHP = %q, want %q", cfg.PHP, tt.wantPHP)
+			}
+			if cfg.Env["MAGE_MODE"] != tt.wantMode {
+				t.Errorf("MAGE_MODE = %q, want %q", cfg.Env["MAGE_MODE"], tt.wantMode)
+			}
+			if cfg.Services.HasVarnish() != tt.wantVarnish {
+				t.Errorf("HasVarnish() = %v, want %v", cfg.Services.HasVarnish(), tt.wantVarnish)
+			}
+			if cfg.PHPINI["memory_limit"] != "2G" {
+				t.Errorf("memory_limit = %q, want 2G kept from main", cfg.PHPINI["memory_limit"])
+			}
+			if !cfg.Services.HasMySQL() {
+				t.Error("MySQL should stay enabled")
+			}
+			if got := cfg.ProfileNames(); len(got) != 2 || got[0] != "minimal" || got[1] != "performance" {
+				t.Errorf("ProfileNames() = %v, want [minimal performance]", got)
+			}
+		})
+	}
+}
+
+func TestLoader_MergeServicesSwitchesDatabase(t *testing.T) {
+	main := Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0"}}
+	local := Services{Percona: &ServiceConfig{Enabled: true, Version: "8.0"}}
+
+	got := NewLoader(t.TempDir()).mergeServices(main, local)
+	if got.MySQL != nil {
+		t.Error("enabling percona locally should replace mysql from main")
+	}
+	if db := got.GetDatabaseService(); db == nil || db != got.Percona {
+		t.Errorf("GetDatabaseService() = %+v, want percona", db)
+	}
+}
+
+func TestLoader_MergeServicesSwitchesSearch(t *testing.T) {
+	main := Services{OpenSearch: &ServiceConfig{Enabled: true, Version: "2.19"}}
+	local := Services{Elasticsearch: &ServiceConfig{Enabled: true, Version: "8.17"}}
+
+	got := NewLoader(t.TempDir()).mergeServices(main, local)
+	if got.OpenSearch != nil {
+		t.Error("enabling elasticsearch locally should replace opensearch from main")
+	}
+	if search := got.GetSearchService(); search == nil || search != got.Elasticsearch {
+		t.Errorf("GetSearchService() = %+v, want elasticsearch", search)
+	}
+}
+
+func TestLoader_Defaults(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	defaults := `php: "8.3"
+services:
+  mysql: "8.0"
+  redis: true
+  mailpit: true
+env:
+  COMPOSER_MEMORY_LIMIT: "-1"
+  MAGE_MODE: developer
+commands:
+  cc: "bin/magento cache:clean"
+`
+	if err := os.MkdirAll(filepath.Join(homeDir, ".magebox"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(DefaultsPath(homeDir), []byte(defaults), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	mainContent := `name: mystore
+domains:
+  - host: mystore.test
+services:
+  mariadb: "10.6"
+env:
+  MAGE_MODE: production
+`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(mainContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte("php: \"8.4\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(dir)
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.PHP != "8.4" {
+		t.Errorf("PHP = %q, want local override 8.4", cfg.PHP)
+	}
+	if cfg.Services.HasMySQL() || !cfg.Services.HasMariaDB() {
+		t.Error("the project's mariadb should replace the default mysql")
+	}
+	if !cfg.Services.HasRedis() || !cfg.Services.HasMailpit() {
+		t.Error("expected redis and mailpit from the defaults")
+	}
+	if cfg.Env["MAGE_MODE"] != "production" || cfg.Env["COMPOSER_MEMORY_LIMIT"] != "-1" {
+		t.Errorf("Env = %v, want project values over the defaults", cfg.Env)
+	}
+	if cfg.Commands["cc"].Run != "bin/magento cache:clean" {
+		t.Errorf("Commands = %v, want cc from the defaults", cfg.Commands)
+	}
+
+	files := loader.LoadedFiles()
+	if len(files) != 3 || files[0] != DefaultsPath(homeDir) {
+		t.Errorf("LoadedFiles() = %v, want defaults first", files)
+	}
+}
+
+func TestLoader_DefaultsInvalid(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	if err := os.MkdirAll(filepath.Join(homeDir, ".magebox"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(DefaultsPath(homeDir), []byte("services: [unclosed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte("name: a\ndomains:\n  - host: a.test\nphp: \"8.3\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewLoader(dir).Load()
+	if err == nil || !strings.Contains(err.Error(), DefaultsFileName) {
+		t.Errorf("Load() error = %v, want one naming %s", err, DefaultsFileName)
+	}
+}
+
+func TestLoader_VarnishVCLExtraPath(t *testing.T) {
+	dir := t.TempDir()
+	includeDir := filepath.Join(dir, "magebox")
+	if err := os.MkdirAll(includeDir, 0755); err != nil {
+		t.Fatalf("failed to create include dir: %v", err)
+	}
+
+	varnishYAML := `
+services:
+  varnish:
+    version: "7.5"
+    vcl_extra: custom.vcl
+`
+	if err := os.WriteFile(filepath.Join(includeDir, "varnish.yaml"), []byte(varnishYAML), 0644); err != nil {
+		t.Fatalf("failed to write include file: %v", err)
+	}
+
+	mainConfig := `
+name: mystore
+domains:
+  - host: mystore.test
+php: "8.3"
+include_config:
+  - ./magebox/varnish.yaml
+`
+	if err := os.WriteFile(filepath.Join(dir, ".magebox.yaml"), []byte(mainConfig), 0644); err != nil {
+		t.Fatalf("failed to write main config: %v", err)
+	}
+
+	config, err := NewLoader(dir).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	varnish := config.Services.Varnish
+	if varnish == nil || !varnish.Enabled {
+		t.Fatal("expected Varnish to be enabled")
+	}
+	if varnish.VCLExtra != "custom.vcl" {
+		t.Errorf("VCLExtra = %q, want custom.vcl", varnish.VCLExtra)
+	}
+	// Relative to the file that sets it, like include_config
+	if want := filepath.Join(includeDir, "custom.vcl"); varnish.VCLExtraPath != want {
+		t.Errorf("VCLExtraPath = %q, want %q", varnish.VCLExtraPath, want)
+	}
+
+	// The resolved path is not written back
+	data, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), includeDir) {
+		t.Errorf("marshaled config should keep the relative vcl_extra:\n%s", data)
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+// Magento deploy modes
+const (
+	ModeDeveloper  = "developer"
+	ModeProduction = "production"
+	ModeDefault    = "default"
+)
+
+// MageModes lists the deploy modes 'magebox mode' switches between
+var MageModes = []string{ModeDeveloper, ModeProduction, ModeDefault}
+
+// IsMageMode reports whether mode is a Magento deploy mode
+func IsMageMode(mode string) bool {
+	for _, m := range MageModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// MageMode returns the deploy mode the project is configured for, taken from
+// MAGE_MODE in env and defaulting to developer
+func (c *Config) MageMode() string {
+	if mode := c.Env["MAGE_MODE"]; mode != "" {
+		return mode
+	}
+	return ModeDeveloper
+}
+
+// ModePHPINI returns the PHP settings that go with a deploy mode. Production
+// code doesn't change between deploys, so OPcache stops checking file
+// timestamps; in the other modes edits must show up on the next request.
+func ModePHPINI(mode string) map[string]string {
+	if mode == ModeProduction {
+		return map[string]string{"opcache.validate_timestamps": "0"}
+	}
+	return map[string]string{"opcache.validate_timestamps": "1"}
+}
+
+// PoolPHPINI returns the PHP settings of the project pool: the settings of
+// its deploy mode, profiler and xdebug section overridden by php_ini from
+// .magebox.yaml
+func (c *Config) PoolPHPINI() map[string]string {
+	ini := ModePHPINI(c.MageMode())
+	for k, v := range ProfilerPHPINI(c.ActiveProfiler()) {
+		ini[k] = v
+	}
+	for k, v := range c.XdebugPHPINI() {
+		ini[k] = v
+	}
+	for k, v := range c.PHPINI {
+		ini[k] = v
+	}
+	return ini
+}
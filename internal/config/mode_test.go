@@ -0,0 +1,48 @@
+package config
+
+import "testing"
+
+func TestConfig_MageMode(t *testing.T) {
+	if got := (&Config{}).MageMode(); got != ModeDeveloper {
+		t.Errorf("MageMode() = %q, want %q", got, ModeDeveloper)
+	}
+	cfg := &Config{Env: map[string]string{"MAGE_MODE": ModeProduction}}
+	if got := cfg.MageMode(); got != ModeProduction {
+		t.Errorf("MageMode() = %q, want %q", got, ModeProduction)
+	}
+}
+
+func TestConfig_PoolPHPINI(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{
+			name:     "developer validates timestamps",
+			cfg:      Config{},
+			expected: "1",
+		},
+		{
+			name:     "production skips timestamp checks",
+			cfg:      Config{Env: map[string]string{"MAGE_MODE": ModeProduction}},
+			expected: "0",
+		},
+		{
+			name: "php_ini overrides the mode",
+			cfg: Config{
+				Env:    map[string]string{"MAGE_MODE": ModeProduction},
+				PHPINI: map[string]string{"opcache.validate_timestamps": "1"},
+			},
+			expected: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.PoolPHPINI()["opcache.validate_timestamps"]; got != tt.expected {
+				t.Errorf("opcache.validate_timestamps = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// PathMapping serves a URL path of a domain from another directory or an
+// upstream server, e.g. the setup app on /setup or a Magento 1 store on a
+// subpath during a migration
+type PathMapping struct {
+	Path  string `yaml:"path"`            // URL path prefix, e.g. "/setup"
+	Root  string `yaml:"root,omitempty"`  // Directory served on the path, relative to the project root
+	Index string `yaml:"index,omitempty"` // Front controller for requests that don't match a file (default: "index.php")
+	Proxy string `yaml:"proxy,omitempty"` // Upstream URL the path is proxied to instead of served from Root
+}
+
+// Location returns the URL path without a trailing slash
+func (p *PathMapping) Location() string {
+	return strings.TrimRight(p.Path, "/")
+}
+
+// GetIndex returns the front controller, defaulting to "index.php"
+func (p *PathMapping) GetIndex() string {
+	if p.Index == "" {
+		return "index.php"
+	}
+	return p.Index
+}
+
+// RootForProject returns the absolute directory served on the path
+func (p *PathMapping) RootForProject(projectPath string) string {
+	if filepath.IsAbs(p.Root) {
+		return filepath.Clean(p.Root)
+	}
+	return filepath.Join(projectPath, p.Root)
+}
+
+// validate checks a single path mapping
+func (p *PathMapping) validate() error {
+	location := p.Location()
+	switch {
+	case !strings.HasPrefix(p.Path, "/"):
+		return fmt.Errorf("path '%s' must start with /", p.Path)
+	case location == "":
+		return fmt.Errorf("path '/' is served by the domain root")
+	case strings.ContainsAny(p.Path, " \t;{}$\"'"):
+		return fmt.Errorf("path '%s' contains characters not allowed in an nginx location", p.Path)
+	case p.Root == "" && p.Proxy == "":
+		return fmt.Errorf("path '%s' needs a root or a proxy", location)
+	case p.Root != "" && p.Proxy != "":
+		return fmt.Errorf("path '%s' can't have both a root and a proxy", location)
+	case p.Proxy != "" && !strings.HasPrefix(p.Proxy, "http://") && !strings.HasPrefix(p.Proxy, "https://"):
+		return fmt.Errorf("path '%s': proxy must be an http:// or https:// URL", location)
+	case strings.ContainsAny(p.Proxy+p.Root+p.Index, " \t;{}\"'"):
+		return fmt.Errorf("path '%s' contains characters not allowed in an nginx directive", location)
+	case strings.Contains(p.Index, "/"):
+		return fmt.Errorf("path '%s': index must be a file name", location)
+	}
+	return nil
+}
+
+// magentoVhostPaths are locations the Magento vhost already defines; nginx
+// refuses to load a vhost that declares one of them twice
+var magentoVhostPaths = []string{"/pub", "/static", "/media", "/errors", "/media/customer", "/media/downloadable", "/media/import", "/media/custom_options"}
+
+// validateDomainPaths checks the paths: block of every domain
+func (c *Config) validateDomainPaths() error {
+	for i, d := range c.Domains {
+		seen := make(map[string]bool, len(d.Paths))
+		for _, p := range d.Paths {
+			if err := p.validate(); err != nil {
+				return &ValidationError{Field: "domains", Message: fmt.Sprintf("%s: %v", d.Host, err), Index: i}
+			}
+			if c.GetType() == ProjectTypeMagento {
+				for _, reserved := range magentoVhostPaths {
+					if p.Location() == reserved {
+						return &ValidationError{Field: "domains", Message: fmt.Sprintf("%s: path '%s' is already served by the Magento vhost", d.Host, reserved), Index: i}
+					}
+				}
+			}
+			if seen[p.Location()] {
+				return &ValidationError{Field: "domains", Message: fmt.Sprintf("%s: duplicate path '%s'", d.Host, p.Location()), Index: i}
+			}
+			seen[p.Location()] = true
+		}
+	}
+	return nil
+}
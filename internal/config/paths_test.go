@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_DomainPaths(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+name: mystore
+domains:
+  - host: mystore.test
+    paths:
+      - path: /setup/
+        root: setup
+      - path: /shop
+        root: legacy/m1
+        index: index.html
+      - path: /static-proxy
+        proxy: https://www.example.com/static/
+php: "8.3"
+`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromPath(dir)
+	if err != nil {
+		t.Fatalf("LoadFromPath() error = %v", err)
+	}
+	paths := cfg.Domains[0].Paths
+	if len(paths) != 3 {
+		t.Fatalf("got %d paths, want 3", len(paths))
+	}
+	if paths[0].Location() != "/setup" || paths[0].GetIndex() != "index.php" {
+		t.Errorf("setup path = %+v", paths[0])
+	}
+	if got := paths[1].RootForProject("/var/www/mystore"); got != "/var/www/mystore/legacy/m1" {
+		t.Errorf("RootForProject() = %q", got)
+	}
+	if paths[2].Proxy != "https://www.example.com/static/" {
+		t.Errorf("proxy path = %+v", paths[2])
+	}
+}
+
+func TestConfig_ValidateDomainPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []PathMapping
+		wantErr string
+	}{
+		{name: "valid", paths: []PathMapping{{Path: "/setup", Root: "setup"}, {Path: "/proxy", Proxy: "http://127.0.0.1:3000"}}},
+		{name: "relative path", paths: []PathMapping{{Path: "setup", Root: "setup"}}, wantErr: "must start with /"},
+		{name: "root path", paths: []PathMapping{{Path: "/", Root: "pub"}}, wantErr: "served by the domain root"},
+		{name: "no target", paths: []PathMapping{{Path: "/setup"}}, wantErr: "needs a root or a proxy"},
+		{name: "both targets", paths: []PathMapping{{Path: "/setup", Root: "setup", Proxy: "http://x"}}, wantErr: "both a root and a proxy"},
+		{name: "bad proxy", paths: []PathMapping{{Path: "/p", Proxy: "ftp://x"}}, wantErr: "http:// or https://"},
+		{name: "injection", paths: []PathMapping{{Path: "/a { deny all; }", Root: "a"}}, wantErr: "not allowed"},
+		{name: "index with dir", paths: []PathMapping{{Path: "/a", Root: "a", Index: "sub/index.php"}}, wantErr: "file name"},
+		{name: "duplicate", paths: []PathMapping{{Path: "/a", Root: "a"}, {Path: "/a/", Root: "b"}}, wantErr: "duplicate path '/a'"},
+		{name: "reserved", paths: []PathMapping{{Path: "/static", Root: "static"}}, wantErr: "already served by the Magento vhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Name:    "mystore",
+				PHP:     "8.3",
+				Domains: []Domain{{Host: "mystore.test", Paths: tt.paths}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import "fmt"
+
+// Profilers a project can use
+const (
+	ProfilerBlackfire = "blackfire"
+	ProfilerTideways  = "tideways"
+	// ProfilerOff turns off a profiler set in .magebox.yaml, e.g. from .magebox.local.yaml
+	ProfilerOff = "off"
+)
+
+// Profilers lists the profilers 'magebox profiler' can turn on
+var Profilers = []string{ProfilerBlackfire, ProfilerTideways}
+
+// Host ports of the profiler agent containers. They differ from the default
+// ports so a Blackfire agent or Tideways daemon installed on the host keeps
+// working next to them.
+const (
+	BlackfireAgentPort = 18307
+	TidewaysDaemonPort = 19135
+)
+
+// IsProfiler reports whether name is a profiler 'magebox profiler' supports
+func IsProfiler(name string) bool {
+	for _, p := range Profilers {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveProfiler returns the profiler loaded into the project's PHP-FPM pool,
+// empty when there is none
+func (c *Config) ActiveProfiler() string {
+	if c.Profiler == ProfilerOff {
+		return ""
+	}
+	return c.Profiler
+}
+
+// ProfilerPHPINI returns the pool settings that load a profiler extension
+// and point it at its agent container. The extension is loaded by the
+// project's pool only, other projects on the same PHP version don't pay for it.
+func ProfilerPHPINI(profiler string) map[string]string {
+	switch profiler {
+	case ProfilerBlackfire:
+		return map[string]string{
+			"extension":              "blackfire.so",
+			"blackfire.agent_socket": fmt.Sprintf("tcp://127.0.0.1:%d", BlackfireAgentPort),
+		}
+	case ProfilerTideways:
+		return map[string]string{
+			"extension":           "tideways.so",
+			"tideways.connection": fmt.Sprintf("tcp://127.0.0.1:%d", TidewaysDaemonPort),
+		}
+	}
+	return nil
+}
+
+// validateProfiler checks the profiler key
+func (c *Config) validateProfiler() error {
+	if c.Profiler == "" || c.Profiler == ProfilerOff || IsProfiler(c.Profiler) {
+		return nil
+	}
+	return &ValidationError{Field: "profiler", Message: fmt.Sprintf("unknown profiler %q (expected blackfire, tideways or off)", c.Profiler)}
+}
@@ -0,0 +1,69 @@
+package config
+
+import "testing"
+
+func TestConfig_ProfilerPoolPHPINI(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           Config
+		wantExtension string
+		wantSetting   string
+		wantValue     string
+	}{
+		{
+			name: "no profiler",
+			cfg:  Config{},
+		},
+		{
+			name:          "blackfire talks to its agent container",
+			cfg:           Config{Profiler: ProfilerBlackfire},
+			wantExtension: "blackfire.so",
+			wantSetting:   "blackfire.agent_socket",
+			wantValue:     "tcp://127.0.0.1:18307",
+		},
+		{
+			name:          "tideways talks to its daemon container",
+			cfg:           Config{Profiler: ProfilerTideways},
+			wantExtension: "tideways.so",
+			wantSetting:   "tideways.connection",
+			wantValue:     "tcp://127.0.0.1:19135",
+		},
+		{
+			name: "off loads nothing",
+			cfg:  Config{Profiler: ProfilerOff},
+		},
+		{
+			name: "php_ini overrides the profiler",
+			cfg: Config{
+				Profiler: ProfilerTideways,
+				PHPINI:   map[string]string{"tideways.connection": "tcp://10.0.0.5:9135"},
+			},
+			wantExtension: "tideways.so",
+			wantSetting:   "tideways.connection",
+			wantValue:     "tcp://10.0.0.5:9135",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ini := tt.cfg.PoolPHPINI()
+			if ini["extension"] != tt.wantExtension {
+				t.Errorf("extension = %q, want %q", ini["extension"], tt.wantExtension)
+			}
+			if tt.wantSetting != "" && ini[tt.wantSetting] != tt.wantValue {
+				t.Errorf("%s = %q, want %q", tt.wantSetting, ini[tt.wantSetting], tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateProfiler(t *testing.T) {
+	for _, profiler := range []string{"", ProfilerBlackfire, ProfilerTideways, ProfilerOff} {
+		if err := (&Config{Profiler: profiler}).validateProfiler(); err != nil {
+			t.Errorf("validateProfiler(%q) = %v, want nil", profiler, err)
+		}
+	}
+	if err := (&Config{Profiler: "xhprof"}).validateProfiler(); err == nil {
+		t.Error("validateProfiler(xhprof) should fail")
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// sanitizeTablePattern matches a table name listed under sanitize.truncate
+var sanitizeTablePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// SanitizeConfig is the ruleset 'magebox db import --sanitize' applies to an
+// imported database, typically a production dump
+type SanitizeConfig struct {
+	Always        bool     `yaml:"always,omitempty"`         // Sanitize every import, --sanitize=false skips it
+	Customers     bool     `yaml:"customers,omitempty"`      // Replace customer names, emails, addresses and phone numbers
+	AdminPassword string   `yaml:"admin_password,omitempty"` // New password of every admin user
+	BaseURLs      bool     `yaml:"base_urls,omitempty"`      // Point the base URLs at the project's domains
+	Truncate      []string `yaml:"truncate,omitempty"`       // Tables to empty, without the env.php table prefix
+	SQL           []string `yaml:"sql,omitempty"`            // Statements run last
+}
+
+// IsEmpty reports whether the ruleset changes nothing
+func (s *SanitizeConfig) IsEmpty() bool {
+	return s == nil || (!s.Customers && s.AdminPassword == "" && !s.BaseURLs && len(s.Truncate) == 0 && len(s.SQL) == 0)
+}
+
+// validateSanitize checks the sanitize section
+func (c *Config) validateSanitize() error {
+	if c.Sanitize == nil {
+		return nil
+	}
+	for _, table := range c.Sanitize.Truncate {
+		if !sanitizeTablePattern.MatchString(table) {
+			return &ValidationError{Field: "sanitize.truncate", Message: fmt.Sprintf("invalid table name %q", table)}
+		}
+	}
+	if c.Sanitize.BaseURLs && len(c.Domains) == 0 {
+		return &ValidationError{Field: "sanitize.base_urls", Message: "the project has no domains to point the base URLs at"}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeConfig_IsEmpty(t *testing.T) {
+	var none *SanitizeConfig
+	if !none.IsEmpty() || !(&SanitizeConfig{Always: true}).IsEmpty() {
+		t.Error("a ruleset without rules should be empty")
+	}
+	if (&SanitizeConfig{AdminPassword: "admin123"}).IsEmpty() {
+		t.Error("a ruleset with an admin password is not empty")
+	}
+}
+
+func TestConfig_ValidateSanitize(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"valid", Config{Domains: []Domain{{Host: "mystore.test"}}, Sanitize: &SanitizeConfig{BaseURLs: true, Truncate: []string{"report_event"}}}, ""},
+		{"table name", Config{Sanitize: &SanitizeConfig{Truncate: []string{"report_event; DROP"}}}, "invalid table name"},
+		{"no domains", Config{Sanitize: &SanitizeConfig{BaseURLs: true}}, "no domains"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateSanitize()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoader_SanitizeLocalReplacesMain(t *testing.T) {
+	dir := t.TempDir()
+	main := "name: shop\ndomains:\n  - host: shop.test\nphp: \"8.3\"\nsanitize:\n  customers: true\n  admin_password: admin123\n"
+	local := "sanitize:\n  always: true\n  base_urls: true\n"
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LocalConfigFileName), []byte(local), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewLoader(dir).Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if s := cfg.Sanitize; s == nil || !s.Always || !s.BaseURLs || s.Customers {
+		t.Errorf("Sanitize = %+v, want the local ruleset", s)
+	}
+}
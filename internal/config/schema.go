@@ -0,0 +1,394 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/php"
+)
+
+// SchemaURI is the JSON Schema dialect of Schema
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// DatabaseVersions are the database versions MageBox has images and host
+// ports for, by service
+var DatabaseVersions = map[string][]string{
+	"mysql":   {"5.7", "8.0", "8.4"},
+	"mariadb": {"10.4", "10.5", "10.6", "10.11", "11.0", "11.4"},
+	"percona": {"5.7", "8.0", "8.4"},
+}
+
+var (
+	// hostPattern matches a host name with at least two labels
+	hostPattern = `^([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)+[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`
+	// tldPattern matches a TLD with an optional leading dot, e.g. test or .acme.test
+	tldPattern = `^\.?[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`
+	// memoryPattern matches a memory size such as 512m or 2g
+	memoryPattern = `^[0-9]+[kKmMgG]?$`
+	// searchVersionPattern matches an OpenSearch or Elasticsearch version:
+	// major, major.minor or major.minor.patch
+	searchVersionPattern = `^[0-9]+(\.[0-9]+){0,2}$`
+)
+
+// Schema is a JSON Schema of a configuration value. Only the keywords the
+// .magebox.yaml schema needs are supported.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false or a *Schema
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+
+	// quoted rejects unquoted numbers, which the value isn't read from
+	quoted bool
+}
+
+// Issue is a problem found by checking a configuration file against the schema
+type Issue struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Path, i.Message)
+}
+
+// ConfigSchema returns the JSON Schema of .magebox.yaml, .magebox.local.yaml
+// and the files they include
+func ConfigSchema() *Schema {
+	s := schemaOf(reflect.TypeOf(Config{}), true)
+	s.Schema = SchemaURI
+	s.Title = "MageBox project configuration"
+
+	s.Properties["type"].Enum = []string{ProjectTypeMagento, ProjectTypeLaravel}
+	s.Properties["php"].Enum = php.SupportedVersions
+	s.Properties["php"].Description = "PHP version of the project"
+	s.Properties["profiler"].Enum = append(append([]string{}, Profilers...), ProfilerOff)
+	s.Properties["php_extensions"].Items.Pattern = extensionNamePattern
+	s.Properties["php_extensions"].Description = "PHP extensions the project needs, e.g. imagick"
+
+	s.Properties["tld"].Pattern = tldPattern
+	s.Properties["tld"].Description = "TLD of the project's domains when it differs from the global tld, e.g. localhost"
+
+	domain := s.Properties["domains"].Items
+	domain.Properties["host"].Pattern = hostPattern
+	domain.Properties["host"].Description = "Host name, e.g. mystore.test"
+	domain.Properties["mage_run_type"].Enum = []string{"store", "website"}
+	domain.Properties["php"].Enum = php.SupportedVersions
+	domain.Properties["php"].Description = "PHP version of the domain when it differs from the project's"
+
+	for name, svc := range s.Properties["services"].Properties {
+		version, object := svc.AnyOf[1], svc.AnyOf[2]
+		objectVersion := object.Properties["version"]
+		if versions, ok := DatabaseVersions[name]; ok {
+			version.Enum, objectVersion.Enum = versions, versions
+		}
+		if name == "opensearch" || name == "elasticsearch" {
+			version.Pattern, objectVersion.Pattern = searchVersionPattern, searchVersionPattern
+			version.Description = "Version, e.g. 2.19"
+			objectVersion.Description = version.Description
+		}
+		object.Properties["memory"].Pattern = memoryPattern
+		object.Properties["memory"].Description = "Memory size with a unit, e.g. 512m or 2g"
+		object.Properties["mode"].Enum = []string{VarnishModeFront, VarnishModeBehind}
+		object.Properties["platform"].Enum = ServicePlatforms
+		if _, ok := DatabaseVersions[name]; ok {
+			object.Properties["config"] = &Schema{
+				Type:                 "object",
+				Description:          "Server settings written to the my.cnf of the service version",
+				Properties:           DBServerSettings,
+				AdditionalProperties: &Schema{Type: "string"},
+			}
+		} else {
+			delete(object.Properties, "config")
+		}
+	}
+	return s
+}
+
+var (
+	configType        = reflect.TypeOf(Config{})
+	serviceConfigType = reflect.TypeOf(ServiceConfig{})
+	commandType       = reflect.TypeOf(Command{})
+	deployStepType    = reflect.TypeOf(DeployStep{})
+	hookCommandsType  = reflect.TypeOf(HookCommands{})
+)
+
+// schemaOf builds the schema of a type from its yaml tags. Types with a
+// custom UnmarshalYAML accept the short forms it reads, and a nested Config
+// (a profile) refers back to the root.
+func schemaOf(t reflect.Type, root bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case configType:
+		if !root {
+			return &Schema{Ref: "#"}
+		}
+	case serviceConfigType:
+		return &Schema{AnyOf: []*Schema{
+			{Type: "boolean"},
+			{Type: "string", quoted: true},
+			objectSchemaOf(t),
+		}}
+	case commandType, deployStepType:
+		return &Schema{AnyOf: []*Schema{{Type: "string"}, objectSchemaOf(t)}}
+	case hookCommandsType:
+		return &Schema{AnyOf: []*Schema{{Type: "string"}, {Type: "array", Items: &Schema{Type: "string"}}}}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return objectSchemaOf(t)
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), false)}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), false)}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	}
+	return &Schema{}
+}
+
+// objectSchemaOf returns the schema of a struct: its yaml fields and no others
+func objectSchemaOf(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		s.Properties[name] = schemaOf(field.Type, false)
+	}
+	return s
+}
+
+// ValidateFile checks a configuration file against the schema
+func ValidateFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateYAML(data)
+}
+
+// ValidateYAML checks a configuration document against the schema: unknown
+// keys, values of the wrong type and values the schema doesn't allow, such
+// as unsupported PHP and database versions, host names and memory sizes.
+func ValidateYAML(data []byte) ([]Issue, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	v := &validator{root: ConfigSchema()}
+	v.check(doc.Content[0], v.root, "")
+	return v.issues, nil
+}
+
+// validator collects the issues of a document
+type validator struct {
+	root   *Schema
+	issues []Issue
+}
+
+func (v *validator) add(node *yaml.Node, path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+// check validates node against s. The branches of an anyOf are told apart
+// by the kind of node, so an issue is reported against the branch the value
+// was meant for.
+func (v *validator) check(node *yaml.Node, s *Schema, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if s.Ref == "#" {
+		s = v.root
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		var kinds []string
+		for _, branch := range s.AnyOf {
+			if matchesType(node, branch.Type) {
+				v.check(node, branch, path)
+				return
+			}
+			kinds = append(kinds, branch.Type)
+		}
+		v.add(node, path, "expected %s", strings.Join(kinds, " or "))
+		return
+	}
+
+	if s.Type != "" && !matchesType(node, s.Type) {
+		v.add(node, path, "expected %s, got %s", s.Type, describeNode(node))
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.checkObject(node, s, path)
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for i, item := range node.Content {
+				v.check(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case yaml.ScalarNode:
+		v.checkScalar(node, s, path)
+	}
+}
+
+func (v *validator) checkObject(node *yaml.Node, s *Schema, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+
+		if prop, ok := s.Properties[key.Value]; ok {
+			v.check(value, prop, keyPath)
+			continue
+		}
+		switch additional := s.AdditionalProperties.(type) {
+		case *Schema:
+			v.check(value, additional, keyPath)
+		case bool:
+			if additional {
+				continue
+			}
+			if suggestion := closestKey(key.Value, s.Properties); suggestion != "" {
+				v.add(key, keyPath, "unknown key %q (did you mean %q?)", key.Value, suggestion)
+			} else {
+				v.add(key, keyPath, "unknown key %q", key.Value)
+			}
+		}
+	}
+}
+
+func (v *validator) checkScalar(node *yaml.Node, s *Schema, path string) {
+	if s.quoted && (node.Tag == "!!float" || node.Tag == "!!int") {
+		v.add(node, path, "unquoted number %s is not read as a version, quote it: \"%s\"", node.Value, node.Value)
+		return
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, node.Value) {
+		v.add(node, path, "unsupported value %q (use %s)", node.Value, strings.Join(s.Enum, ", "))
+		return
+	}
+	if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(node.Value) {
+		if s.Description != "" {
+			v.add(node, path, "invalid value %q (expected %s)", node.Value, lowerFirst(s.Description))
+		} else {
+			v.add(node, path, "invalid value %q", node.Value)
+		}
+	}
+}
+
+// matchesType reports whether a node has a JSON Schema type. Any scalar is a
+// string, as the loader reads numbers and booleans into strings as written.
+func matchesType(node *yaml.Node, typ string) bool {
+	switch typ {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		return node.Kind == yaml.ScalarNode
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!int"
+	}
+	return true
+}
+
+// describeNode names the kind of a node for type errors
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a map"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+// closestKey returns the property closest to an unknown key, when it's
+// within two edits
+func closestKey(key string, properties map[string]*Schema) string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", 3
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateYAML_Valid(t *testing.T) {
+	issues, err := ValidateYAML([]byte(`
+name: mystore
+php: 8.3
+domains:
+  - host: mystore.test
+    root: pub
+services:
+  mysql: "8.0"
+  redis: true
+  opensearch:
+    version: "2.19"
+    memory: 2g
+  varnish:
+    mode: behind
+env:
+  MAGE_MODE: developer
+commands:
+  reindex: "php bin/magento indexer:reindex"
+profiles:
+  slim:
+    services:
+      opensearch: false
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("issues = %v, want none", issues)
+	}
+}
+
+func TestValidateYAML_Issues(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		path string
+		want string
+	}{
+		{"unknown key", "name: a\nservises: {}", "servises", `unknown key "servises" (did you mean "services"?)`},
+		{"unknown service", "services:\n  mysq: \"8.0\"", "services.mysq", `did you mean "mysql"?`},
+		{"unknown service option", "services:\n  mysql:\n    verison: \"8.0\"", "services.mysql.verison", "unknown key"},
+		{"php version", "php: \"7.4\"", "php", `unsupported value "7.4"`},
+		{"database version", "services:\n  mariadb: \"10.3\"", "services.mariadb", `unsupported value "10.3"`},
+		{"database object version", "services:\n  mysql:\n    version: \"9.0\"", "services.mysql.version", "unsupported value"},
+		{"unquoted version", "services:\n  mysql: 8.0", "services.mysql", "quote it"},
+		{"search version", "services:\n  opensearch: latest", "services.opensearch", `invalid value "latest"`},
+		{"domain", "domains:\n  - host: my_store", "domains[0].host", "expected host name"},
+		{"memory", "services:\n  mysql:\n    memory: 2gb", "services.mysql.memory", `invalid value "2gb"`},
+		{"port type", "services:\n  mysql:\n    port: abc", "services.mysql.port", "expected integer"},
+		{"db server config size", "services:\n  mysql:\n    config:\n      innodb_buffer_pool_size: 2gb", "services.mysql.config.innodb_buffer_pool_size", `invalid value "2gb"`},
+		{"server config on redis", "services:\n  redis:\n    config:\n      maxmemory: 1g", "services.redis.config", "unknown key"},
+		{"domains type", "domains: mystore.test", "domains", "expected array"},
+		{"profile", "profiles:\n  slim:\n    php: \"5.6\"", "profiles.slim.php", "unsupported value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := ValidateYAML([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(issues) != 1 {
+				t.Fatalf("issues = %v, want one", issues)
+			}
+			if issues[0].Path != tt.path || !strings.Contains(issues[0].Message, tt.want) {
+				t.Errorf("issue = %s, want %s: ...%s...", issues[0], tt.path, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateYAML_Line(t *testing.T) {
+	issues, err := ValidateYAML([]byte("name: a\nphp: \"8.3\"\nservices:\n  rediss: true\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Line != 4 {
+		t.Errorf("issues = %v, want one on line 4", issues)
+	}
+}
+
+func TestValidateYAML_ParseError(t *testing.T) {
+	if _, err := ValidateYAML([]byte("name: [unclosed")); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestConfigSchema_JSON(t *testing.T) {
+	data, err := json.Marshal(ConfigSchema())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schema["$schema"] != SchemaURI || schema["additionalProperties"] != false {
+		t.Errorf("schema root = %v", schema)
+	}
+	props := schema["properties"].(map[string]interface{})
+	profiles := props["profiles"].(map[string]interface{})
+	if ref := profiles["additionalProperties"].(map[string]interface{})["$ref"]; ref != "#" {
+		t.Errorf("profiles $ref = %v, want #", ref)
+	}
+}
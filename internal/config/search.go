@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Defaults for the alternative search engines. Both run with a fixed local
+// key so modules can be configured the same way on every machine.
+const (
+	DefaultMeilisearchVersion = "v1.11"
+	DefaultMeilisearchKey     = "magebox"
+	MeilisearchPort           = 7700
+
+	DefaultTypesenseVersion = "27.1"
+	DefaultTypesenseAPIKey  = "magebox"
+	TypesensePort           = 8108
+)
+
+// HasMeilisearch returns true if Meilisearch service is configured
+func (s *Services) HasMeilisearch() bool {
+	return s.Meilisearch != nil && s.Meilisearch.Enabled
+}
+
+// HasTypesense returns true if Typesense service is configured
+func (s *Services) HasTypesense() bool {
+	return s.Typesense != nil && s.Typesense.Enabled
+}
+
+// MeilisearchImageTag returns the getmeili/meilisearch tag for the configured
+// version. Meilisearch tags carry a "v" prefix, so "1.11" becomes "v1.11".
+func (s *Services) MeilisearchImageTag() string {
+	if s.Meilisearch == nil || s.Meilisearch.Version == "" {
+		return DefaultMeilisearchVersion
+	}
+	if v := s.Meilisearch.Version; v != "latest" && !strings.HasPrefix(v, "v") {
+		return "v" + v
+	}
+	return s.Meilisearch.Version
+}
+
+// TypesenseImageTag returns the typesense/typesense tag for the configured version
+func (s *Services) TypesenseImageTag() string {
+	if s.Typesense == nil || s.Typesense.Version == "" {
+		return DefaultTypesenseVersion
+	}
+	return strings.TrimPrefix(s.Typesense.Version, "v")
+}
+
+// SearchEngineEnv returns the connection variables of the alternative search
+// engines the project uses, for search modules that read them from the
+// environment
+func (c *Config) SearchEngineEnv() map[string]string {
+	env := make(map[string]string)
+	if c.Services.HasMeilisearch() {
+		env["MEILISEARCH_HOST"] = "http://127.0.0.1:" + strconv.Itoa(MeilisearchPort)
+		env["MEILISEARCH_API_KEY"] = DefaultMeilisearchKey
+	}
+	if c.Services.HasTypesense() {
+		env["TYPESENSE_HOST"] = "127.0.0.1"
+		env["TYPESENSE_PORT"] = strconv.Itoa(TypesensePort)
+		env["TYPESENSE_PROTOCOL"] = "http"
+		env["TYPESENSE_API_KEY"] = DefaultTypesenseAPIKey
+	}
+	return env
+}
+
+// PHPEnv returns the env vars passed to the project's PHP processes: the
+// search engine connection variables overridden by the env from .magebox.yaml
+func (c *Config) PHPEnv() map[string]string {
+	env := c.SearchEngineEnv()
+	if len(env) == 0 {
+		return c.Env
+	}
+	for k, v := range c.Env {
+		env[k] = v
+	}
+	return env
+}
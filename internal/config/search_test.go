@@ -0,0 +1,54 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServices_SearchEngineImageTags(t *testing.T) {
+	tests := []struct {
+		name          string
+		services      Services
+		wantMeili     string
+		wantTypesense string
+	}{
+		{"defaults", Services{}, DefaultMeilisearchVersion, DefaultTypesenseVersion},
+		{"plain versions", Services{
+			Meilisearch: &ServiceConfig{Enabled: true, Version: "1.10"},
+			Typesense:   &ServiceConfig{Enabled: true, Version: "26.0"},
+		}, "v1.10", "26.0"},
+		{"prefixed versions", Services{
+			Meilisearch: &ServiceConfig{Enabled: true, Version: "v1.9"},
+			Typesense:   &ServiceConfig{Enabled: true, Version: "v27.0"},
+		}, "v1.9", "27.0"},
+		{"latest", Services{Meilisearch: &ServiceConfig{Enabled: true, Version: "latest"}}, "latest", DefaultTypesenseVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.services.MeilisearchImageTag(); got != tt.wantMeili {
+				t.Errorf("MeilisearchImageTag() = %q, want %q", got, tt.wantMeili)
+			}
+			if got := tt.services.TypesenseImageTag(); got != tt.wantTypesense {
+				t.Errorf("TypesenseImageTag() = %q, want %q", got, tt.wantTypesense)
+			}
+		})
+	}
+}
+
+func TestConfig_PHPEnv(t *testing.T) {
+	cfg := &Config{Env: map[string]string{"MAGE_MODE": "developer"}}
+	if got := cfg.PHPEnv(); !reflect.DeepEqual(got, cfg.Env) {
+		t.Errorf("PHPEnv() without search engines = %v, want project env", got)
+	}
+
+	cfg.Services.Meilisearch = &ServiceConfig{Enabled: true}
+	cfg.Env["MEILISEARCH_API_KEY"] = "custom"
+	want := map[string]string{
+		"MAGE_MODE":           "developer",
+		"MEILISEARCH_HOST":    "http://127.0.0.1:7700",
+		"MEILISEARCH_API_KEY": "custom",
+	}
+	if got := cfg.PHPEnv(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PHPEnv() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ServiceInfo describes a service of .magebox.yaml for `magebox service add`
+type ServiceInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Versions MageBox supports, nil when the service takes any image tag
+	// (DefaultVersion set) or runs a fixed image (DefaultVersion empty)
+	Versions []string `json:"versions,omitempty"`
+	// DefaultVersion is added when no version is given
+	DefaultVersion string `json:"default_version,omitempty"`
+
+	// group of services only one of which a project runs, e.g. the databases
+	group string
+}
+
+// Versioned reports whether the service takes a version
+func (i ServiceInfo) Versioned() bool {
+	return i.DefaultVersion != ""
+}
+
+// ServiceCatalog lists the services a project can add, in the order of the
+// services section of .magebox.yaml
+var ServiceCatalog = []ServiceInfo{
+	{Name: "mysql", Description: "MySQL database", Versions: DatabaseVersions["mysql"], DefaultVersion: "8.0", group: "database"},
+	{Name: "mariadb", Description: "MariaDB database", Versions: DatabaseVersions["mariadb"], DefaultVersion: "10.6", group: "database"},
+	{Name: "percona", Description: "Percona Server database", Versions: DatabaseVersions["percona"], DefaultVersion: "8.0", group: "database"},
+	{Name: "redis", Description: "Redis cache and sessions", group: "cache"},
+	{Name: "valkey", Description: "Valkey cache and sessions", group: "cache"},
+	{Name: "opensearch", Description: "OpenSearch catalog search", Versions: []string{"1.3", "2.5", "2.10", "2.11", "2.12", "2.13", "2.15", "2.17", "2.19", "3.0", "3.3"}, DefaultVersion: "2.19.4", group: "search"},
+	{Name: "elasticsearch", Description: "Elasticsearch catalog search", Versions: []string{"7.6", "7.9", "7.10", "7.16", "7.17", "8.0", "8.4", "8.7", "8.11", "8.14", "8.15", "8.17"}, DefaultVersion: "8.17", group: "search"},
+	{Name: "meilisearch", Description: "Meilisearch search engine", DefaultVersion: DefaultMeilisearchVersion},
+	{Name: "typesense", Description: "Typesense search engine", DefaultVersion: DefaultTypesenseVersion},
+	{Name: "rabbitmq", Description: "RabbitMQ message queue"},
+	{Name: "mailpit", Description: "Mailpit mail catcher (enabled by default)"},
+	{Name: "varnish", Description: "Varnish full page cache", Versions: []string{"6.0", "7.4", "7.5", "7.6"}, DefaultVersion: "7.5"},
+	{Name: "phpmyadmin", Description: "phpMyAdmin database UI"},
+	{Name: "opensearch_dashboards", Description: "OpenSearch Dashboards for the project's OpenSearch"},
+	{Name: "composer-mirror", Description: "Shared Packeton mirror of repo.magento.com"},
+}
+
+var (
+	memoryRegexp        = regexp.MustCompile(memoryPattern)
+	searchVersionRegexp = regexp.MustCompile(searchVersionPattern)
+	// searchMinorVersion matches the major.minor prefix of a search engine
+	// version such as 2.19.4
+	searchMinorVersion = regexp.MustCompile(`^[0-9]+\.[0-9]+`)
+)
+
+// LookupService returns the catalog entry of a service
+func LookupService(name string) (ServiceInfo, bool) {
+	for _, info := range ServiceCatalog {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return ServiceInfo{}, false
+}
+
+// ServiceNames returns the names of the catalog services
+func ServiceNames() []string {
+	names := make([]string, len(ServiceCatalog))
+	for i, info := range ServiceCatalog {
+		names[i] = info.Name
+	}
+	return names
+}
+
+// ResolveVersion checks a version against the supported versions of the
+// service and returns the version to configure: the default one when version
+// is empty. OpenSearch and Elasticsearch take patch versions of a supported
+// major.minor, e.g. 2.19.4.
+func (i ServiceInfo) ResolveVersion(version string) (string, error) {
+	if !i.Versioned() {
+		if version != "" {
+			return "", fmt.Errorf("%s runs a fixed image and takes no version", i.Name)
+		}
+		return "", nil
+	}
+	if version == "" {
+		return i.DefaultVersion, nil
+	}
+	if i.Versions == nil || slices.Contains(i.Versions, version) {
+		return version, nil
+	}
+	if i.group == "search" && searchVersionRegexp.MatchString(version) &&
+		slices.Contains(i.Versions, searchMinorVersion.FindString(version)) {
+		return version, nil
+	}
+	return "", fmt.Errorf("unsupported %s version %q (use %s)", i.Name, version, strings.Join(i.Versions, ", "))
+}
+
+// ValidateMemory checks a memory size such as 512m or 2g
+func ValidateMemory(memory string) error {
+	if !memoryRegexp.MatchString(memory) {
+		return fmt.Errorf("invalid memory size %q (use a number with a unit, e.g. 512m or 2g)", memory)
+	}
+	return nil
+}
+
+// Enabled returns the names of the enabled services in catalog order
+func (s *Services) Enabled() []string {
+	services := s.byName()
+	var names []string
+	for _, info := range ServiceCatalog {
+		if svc := services[info.Name]; svc != nil && svc.Enabled {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}
+
+// Get returns the configuration of a service, nil when it isn't configured
+func (s *Services) Get(name string) *ServiceConfig {
+	return s.byName()[name]
+}
+
+// Add enables a service with the given configuration, replacing the one it
+// has. A service can't be added next to another of its group, e.g. a second
+// database.
+func (s *Services) Add(name string, svc *ServiceConfig) error {
+	info, ok := LookupService(name)
+	if !ok {
+		return fmt.Errorf("unknown service %q (use %s)", name, strings.Join(ServiceNames(), ", "))
+	}
+	if info.group != "" {
+		for _, other := range ServiceCatalog {
+			if other.Name != name && other.group == info.group && s.enabled(other.Name) {
+				return fmt.Errorf("%s can't run next to %s, remove %s first", name, other.Name, other.Name)
+			}
+		}
+	}
+	if name == "opensearch_dashboards" && !s.HasOpenSearch() {
+		return fmt.Errorf("opensearch_dashboards needs opensearch, add it first")
+	}
+
+	svc.Enabled = true
+	*s.slot(name) = svc
+	return nil
+}
+
+// Remove removes a service and reports whether it was enabled. Mailpit, which
+// runs unless it is turned off, is set to false instead.
+func (s *Services) Remove(name string) bool {
+	slot := s.slot(name)
+	if slot == nil {
+		return false
+	}
+	if name == "mailpit" {
+		if s.MailpitDisabled() {
+			return false
+		}
+		*slot = &ServiceConfig{Enabled: false}
+		return true
+	}
+	removed := s.enabled(name)
+	*slot = nil
+	if name == "opensearch" {
+		s.OpenSearchDashboards = nil
+	}
+	return removed
+}
+
+// enabled reports whether a service is configured and enabled
+func (s *Services) enabled(name string) bool {
+	svc := s.Get(name)
+	return svc != nil && svc.Enabled
+}
+
+// slot returns the field of a service, nil for unknown services
+func (s *Services) slot(name string) **ServiceConfig {
+	switch name {
+	case "mysql":
+		return &s.MySQL
+	case "mariadb":
+		return &s.MariaDB
+	case "percona":
+		return &s.Percona
+	case "redis":
+		return &s.Redis
+	case "valkey":
+		return &s.Valkey
+	case "opensearch":
+		return &s.OpenSearch
+	case "elasticsearch":
+		return &s.Elasticsearch
+	case "meilisearch":
+		return &s.Meilisearch
+	case "typesense":
+		return &s.Typesense
+	case "rabbitmq":
+		return &s.RabbitMQ
+	case "mailpit":
+		return &s.Mailpit
+	case "varnish":
+		return &s.Varnish
+	case "phpmyadmin":
+		return &s.PhpMyAdmin
+	case "opensearch_dashboards":
+		return &s.OpenSearchDashboards
+	case "composer-mirror":
+		return &s.ComposerMirror
+	}
+	return nil
+}
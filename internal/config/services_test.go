@@ -0,0 +1,115 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServiceInfo_ResolveVersion(t *testing.T) {
+	tests := []struct {
+		service string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"mysql", "", "8.0", false},
+		{"mysql", "8.4", "8.4", false},
+		{"mysql", "9.1", "", true},
+		{"opensearch", "2.19", "2.19", false},
+		{"opensearch", "2.19.4", "2.19.4", false},
+		{"opensearch", "2.18.0", "", true},
+		{"elasticsearch", "", "8.17", false},
+		{"varnish", "7.6", "7.6", false},
+		{"meilisearch", "v1.12", "v1.12", false},
+		{"rabbitmq", "", "", false},
+		{"rabbitmq", "3.13", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service+" "+tt.version, func(t *testing.T) {
+			info, ok := LookupService(tt.service)
+			if !ok {
+				t.Fatalf("LookupService(%q) not found", tt.service)
+			}
+			got, err := info.ResolveVersion(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveVersion(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveVersion(%q) = %q, want %q", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceCatalog_CoversServices(t *testing.T) {
+	var s Services
+	for name := range s.byName() {
+		if _, ok := LookupService(name); !ok {
+			t.Errorf("service %s is missing from ServiceCatalog", name)
+		}
+		if s.slot(name) == nil {
+			t.Errorf("service %s has no slot", name)
+		}
+	}
+	if len(ServiceCatalog) != len(s.byName()) {
+		t.Errorf("ServiceCatalog has %d services, want %d", len(ServiceCatalog), len(s.byName()))
+	}
+}
+
+func TestServices_AddRemove(t *testing.T) {
+	s := Services{MySQL: &ServiceConfig{Enabled: true, Version: "8.0"}}
+
+	if err := s.Add("opensearch", &ServiceConfig{Version: "2.19", Memory: "2g"}); err != nil {
+		t.Fatalf("Add(opensearch) error = %v", err)
+	}
+	if !s.HasOpenSearch() || s.OpenSearch.Memory != "2g" {
+		t.Errorf("OpenSearch = %+v, want enabled with 2g", s.OpenSearch)
+	}
+	if err := s.Add("mariadb", &ServiceConfig{Version: "10.11"}); err == nil {
+		t.Error("Add(mariadb) next to mysql succeeded")
+	}
+	if err := s.Add("elasticsearch", &ServiceConfig{Version: "8.17"}); err == nil {
+		t.Error("Add(elasticsearch) next to opensearch succeeded")
+	}
+	if err := s.Add("unknown", &ServiceConfig{}); err == nil {
+		t.Error("Add(unknown) succeeded")
+	}
+	if err := s.Add("opensearch_dashboards", &ServiceConfig{}); err != nil {
+		t.Fatalf("Add(opensearch_dashboards) error = %v", err)
+	}
+
+	if want := []string{"mysql", "opensearch", "opensearch_dashboards"}; !reflect.DeepEqual(s.Enabled(), want) {
+		t.Errorf("Enabled() = %v, want %v", s.Enabled(), want)
+	}
+
+	if !s.Remove("opensearch") {
+		t.Error("Remove(opensearch) = false, want true")
+	}
+	if s.OpenSearch != nil || s.OpenSearchDashboards != nil {
+		t.Error("Remove(opensearch) kept OpenSearch or its dashboards")
+	}
+	if s.Remove("rabbitmq") {
+		t.Error("Remove(rabbitmq) of a missing service = true")
+	}
+
+	// Mailpit runs by default, removing it turns it off
+	if !s.Remove("mailpit") || !s.MailpitDisabled() {
+		t.Error("Remove(mailpit) didn't turn Mailpit off")
+	}
+	if s.Remove("mailpit") {
+		t.Error("Remove(mailpit) of a disabled Mailpit = true")
+	}
+}
+
+func TestValidateMemory(t *testing.T) {
+	for _, memory := range []string{"512m", "2g", "1024"} {
+		if err := ValidateMemory(memory); err != nil {
+			t.Errorf("ValidateMemory(%q) error = %v", memory, err)
+		}
+	}
+	for _, memory := range []string{"2 GB", "lots", ""} {
+		if err := ValidateMemory(memory); err == nil {
+			t.Errorf("ValidateMemory(%q) succeeded", memory)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// tldLabelPattern matches one label of a TLD: letters, digits and inner hyphens
+var tldLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// localTLDs are the TLDs reserved for local use, which never resolve on the
+// internet (RFC 2606, RFC 6761 and ICANN's .internal)
+var localTLDs = []string{"test", "localhost", "example", "invalid", "internal"}
+
+// NormalizeTLD returns a TLD without its leading dot, lowercased
+func NormalizeTLD(tld string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tld), "."))
+}
+
+// ValidTLD reports whether tld is a usable local TLD, e.g. "test", "localhost"
+// or "acme.test"
+func ValidTLD(tld string) bool {
+	if tld == "" {
+		return false
+	}
+	for _, label := range strings.Split(tld, ".") {
+		if !tldLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalTLD reports whether tld is a reserved local TLD or a name under one,
+// e.g. "test" or "acme.test". Routing any other TLD to this machine would
+// take over the lookups of a public TLD.
+func LocalTLD(tld string) bool {
+	labels := strings.Split(NormalizeTLD(tld), ".")
+	return slices.Contains(localTLDs, labels[len(labels)-1])
+}
+
+// validateTLD checks the tld of the project. As a project config comes with
+// the repository, only local TLDs are accepted: the TLD is routed to this
+// machine on start.
+func (c *Config) validateTLD() error {
+	if c.TLD == "" {
+		return nil
+	}
+	tld := NormalizeTLD(c.TLD)
+	if !ValidTLD(tld) {
+		return &ValidationError{Field: "tld", Message: fmt.Sprintf("invalid tld %q (use e.g. test or localhost)", c.TLD)}
+	}
+	if !LocalTLD(tld) {
+		return &ValidationError{Field: "tld", Message: fmt.Sprintf("tld %q is not a local TLD (use test, localhost, example, invalid, internal or a name under them)", c.TLD)}
+	}
+	return nil
+}
+
+// GetTLD returns the TLD of the project, fallback when it doesn't set one
+func (c *Config) GetTLD(fallback string) string {
+	if c.TLD == "" {
+		return fallback
+	}
+	return NormalizeTLD(c.TLD)
+}
+
+// GetTLDs returns every TLD routed to this machine: the TLD first, then the
+// additional TLDs, without duplicates
+func (c *GlobalConfig) GetTLDs() []string {
+	tlds := []string{c.GetTLD()}
+	for _, tld := range c.TLDs {
+		tld = NormalizeTLD(tld)
+		if tld != "" && !slices.Contains(tlds, tld) {
+			tlds = append(tlds, tld)
+		}
+	}
+	return tlds
+}
+
+// AddTLD adds tld to the additional TLDs, reporting whether it wasn't routed
+// yet
+func (c *GlobalConfig) AddTLD(tld string) bool {
+	tld = NormalizeTLD(tld)
+	if tld == "" || slices.Contains(c.GetTLDs(), tld) {
+		return false
+	}
+	c.TLDs = append(c.TLDs, tld)
+	return true
+}
+
+// RemoveTLD removes tld from the additional TLDs, reporting whether it was
+// one of them
+func (c *GlobalConfig) RemoveTLD(tld string) bool {
+	tld = NormalizeTLD(tld)
+	for i, t := range c.TLDs {
+		if NormalizeTLD(t) == tld {
+			c.TLDs = slices.Delete(c.TLDs, i, i+1)
+			return true
+		}
+	}
+	return false
+}
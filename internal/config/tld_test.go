@@ -0,0 +1,109 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidTLD(t *testing.T) {
+	tests := []struct {
+		tld  string
+		want bool
+	}{
+		{"test", true},
+		{"localhost", true},
+		{"acme.test", true},
+		{"my-client", true},
+		{"", false},
+		{"-acme", false},
+		{"acme.", false},
+		{"ac me", false},
+		{"acme/test", false},
+	}
+	for _, tt := range tests {
+		if got := ValidTLD(tt.tld); got != tt.want {
+			t.Errorf("ValidTLD(%q) = %v, want %v", tt.tld, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_ValidateTLD(t *testing.T) {
+	for tld, valid := range map[string]bool{"": true, "localhost": true, ".Acme.Test": true, "acme test": false, "com": false, "dev": false, "acme": false} {
+		err := (&Config{TLD: tld}).validateTLD()
+		if (err == nil) != valid {
+			t.Errorf("validateTLD(%q) = %v, want valid %v", tld, err, valid)
+		}
+	}
+}
+
+func TestLocalTLD(t *testing.T) {
+	tests := map[string]bool{
+		"test":          true,
+		"localhost":     true,
+		"example":       true,
+		"invalid":       true,
+		"internal":      true,
+		"acme.test":     true,
+		".Acme.Test":    true,
+		"com":           false,
+		"dev":           false,
+		"app":           false,
+		"test.com":      false,
+		"mytest":        false,
+		"localhost.dev": false,
+	}
+	for tld, want := range tests {
+		if got := LocalTLD(tld); got != want {
+			t.Errorf("LocalTLD(%q) = %v, want %v", tld, got, want)
+		}
+	}
+}
+
+func TestConfig_GetTLD(t *testing.T) {
+	if got := (&Config{}).GetTLD("test"); got != "test" {
+		t.Errorf("GetTLD() without tld = %q, want the fallback", got)
+	}
+	if got := (&Config{TLD: ".Localhost"}).GetTLD("test"); got != "localhost" {
+		t.Errorf("GetTLD() = %q, want localhost", got)
+	}
+}
+
+func TestGlobalConfig_GetTLDs(t *testing.T) {
+	cfg := &GlobalConfig{TLD: "test", TLDs: []string{".acme", "test", "localhost", "acme"}}
+	if got := cfg.GetTLDs(); !reflect.DeepEqual(got, []string{"test", "acme", "localhost"}) {
+		t.Errorf("GetTLDs() = %v, want [test acme localhost]", got)
+	}
+}
+
+func TestGlobalConfig_AddTLD(t *testing.T) {
+	cfg := &GlobalConfig{TLD: "test"}
+	if cfg.AddTLD("test") {
+		t.Error("AddTLD(test) added the global TLD")
+	}
+	if !cfg.AddTLD(".acme") {
+		t.Error("AddTLD(.acme) = false, want true")
+	}
+	if cfg.AddTLD("acme") {
+		t.Error("AddTLD(acme) added a routed TLD again")
+	}
+	if !reflect.DeepEqual(cfg.TLDs, []string{"acme"}) {
+		t.Errorf("TLDs = %v, want [acme]", cfg.TLDs)
+	}
+
+	if !cfg.RemoveTLD(".Acme") || len(cfg.TLDs) != 0 {
+		t.Errorf("RemoveTLD(.Acme) left TLDs = %v", cfg.TLDs)
+	}
+	if cfg.RemoveTLD("test") {
+		t.Error("RemoveTLD(test) removed the main TLD")
+	}
+}
+
+func TestLoader_MergeTLD(t *testing.T) {
+	l := NewLoader(t.TempDir())
+	if got := l.merge(&Config{TLD: "test"}, &Config{TLD: "localhost"}); got.TLD != "localhost" {
+		t.Errorf("merged TLD = %q, want the local one", got.TLD)
+	}
+	if got := l.merge(&Config{TLD: "test"}, &Config{}); got.TLD != "test" {
+		t.Errorf("merged TLD = %q, want the main one", got.TLD)
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+
+	"qoliber/magebox/internal/remote"
 )
 
 // Project types
@@ -13,21 +18,41 @@ const (
 	ProjectTypeLaravel = "laravel"
 )
 
+// Varnish topologies (services.varnish.mode)
+const (
+	// VarnishModeFront sends every request through Varnish, nginx only terminates TLS
+	VarnishModeFront = "front"
+	// VarnishModeBehind lets nginx serve assets and uncacheable areas itself
+	// and send only storefront pages to Varnish
+	VarnishModeBehind = "behind"
+)
+
 // Config represents the merged configuration from .magebox and .magebox.local
 type Config struct {
-	Name          string             `yaml:"name"`
-	Type          string             `yaml:"type,omitempty"` // Project type: "magento" (default) or "laravel"
-	Domains       []Domain           `yaml:"domains"`
-	PHP           string             `yaml:"php"`
-	PHPINI        map[string]string  `yaml:"php_ini,omitempty"`
-	Isolated      bool               `yaml:"isolated,omitempty"` // Use dedicated PHP-FPM master for this project
-	Services      Services           `yaml:"services"`
-	Env           map[string]string  `yaml:"env,omitempty"`
-	Commands      map[string]Command `yaml:"commands,omitempty"`
-	Testing       *TestingConfig     `yaml:"testing,omitempty"`
-	ComposeFile   string             `yaml:"compose_file,omitempty"` // Path to project-specific docker-compose.yml
-	Sandbox       *SandboxConfig     `yaml:"sandbox,omitempty"`
-	IncludeConfig []string           `yaml:"include_config,omitempty"` // Paths to additional config files or directories to merge
+	Name          string               `yaml:"name"`
+	Type          string               `yaml:"type,omitempty"` // Project type: "magento" (default) or "laravel"
+	Domains       []Domain             `yaml:"domains"`
+	TLD           string               `yaml:"tld,omitempty"` // TLD of the project's domains when it differs from the global one
+	PHP           string               `yaml:"php"`
+	PHPINI        map[string]string    `yaml:"php_ini,omitempty"`
+	PHPExtensions []string             `yaml:"php_extensions,omitempty"` // PHP extensions the project needs, checked on start
+	Isolated      bool                 `yaml:"isolated,omitempty"`       // Use dedicated PHP-FPM master for this project
+	Services      Services             `yaml:"services"`
+	Env           map[string]string    `yaml:"env,omitempty"`
+	Commands      map[string]Command   `yaml:"commands,omitempty"`
+	Hooks         *Hooks               `yaml:"hooks,omitempty"` // Commands run before and after start, stop, db import and sync
+	Testing       *TestingConfig       `yaml:"testing,omitempty"`
+	ComposeFile   string               `yaml:"compose_file,omitempty"` // Path to project-specific docker-compose.yml
+	Sandbox       *SandboxConfig       `yaml:"sandbox,omitempty"`
+	IncludeConfig []string             `yaml:"include_config,omitempty"` // Paths to additional config files or directories to merge
+	Environments  []remote.Environment `yaml:"environments,omitempty"`   // Remote environments (staging, production) for sync and SSH
+	Profiler      string               `yaml:"profiler,omitempty"`       // PHP profiler of the project: blackfire, tideways or off
+	Xdebug        *XdebugSettings      `yaml:"xdebug,omitempty"`         // Xdebug mode, trigger and output dir of the project
+	Deploy        *DeployConfig        `yaml:"deploy,omitempty"`         // Deployment pipeline of 'magebox deploy'
+	Frontend      *FrontendConfig      `yaml:"frontend,omitempty"`       // Node.js version and dev server of the project's themes
+	Sanitize      *SanitizeConfig      `yaml:"sanitize,omitempty"`       // Ruleset applied to imported databases
+	Profile       string               `yaml:"profile,omitempty"`        // Active profile, set in .magebox.local.yaml by 'magebox profile use'
+	Profiles      map[string]*Config   `yaml:"profiles,omitempty"`       // Named overlays of services, PHP and env vars
 }
 
 // GetType returns the project type, defaulting to "magento"
@@ -63,6 +88,7 @@ type TestingConfig struct {
 	PHPStan     *PHPStanTestConfig     `yaml:"phpstan,omitempty"`
 	PHPCS       *PHPCSTestConfig       `yaml:"phpcs,omitempty"`
 	PHPMD       *PHPMDTestConfig       `yaml:"phpmd,omitempty"`
+	PHPCSFixer  *PHPCSFixerTestConfig  `yaml:"php_cs_fixer,omitempty"`
 }
 
 // PHPUnitTestConfig represents PHPUnit configuration
@@ -110,6 +136,13 @@ type PHPMDTestConfig struct {
 	Paths   []string `yaml:"paths,omitempty"`
 }
 
+// PHPCSFixerTestConfig represents PHP-CS-Fixer configuration
+type PHPCSFixerTestConfig struct {
+	Enabled bool     `yaml:"enabled,omitempty"`
+	Config  string   `yaml:"config,omitempty"`
+	Paths   []string `yaml:"paths,omitempty"`
+}
+
 // SandboxConfig configures the bubblewrap sandbox for AI coding agents
 type SandboxConfig struct {
 	// DefaultTool is the default command to run (default: "claude")
@@ -173,34 +206,64 @@ func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // Domain represents a domain configuration
 type Domain struct {
-	Host        string `yaml:"host"`
-	Root        string `yaml:"root,omitempty"`
-	SSL         *bool  `yaml:"ssl,omitempty"`
-	MageRunCode string `yaml:"mage_run_code,omitempty"` // Magento store/website code for multi-store setup
-	MageRunType string `yaml:"mage_run_type,omitempty"` // "store" or "website" (default: "store")
+	Host        string        `yaml:"host"`
+	Root        string        `yaml:"root,omitempty"`
+	SSL         *bool         `yaml:"ssl,omitempty"`
+	MageRunCode string        `yaml:"mage_run_code,omitempty"` // Magento store/website code for multi-store setup
+	MageRunType string        `yaml:"mage_run_type,omitempty"` // "store" or "website" (default: "store")
+	Paths       []PathMapping `yaml:"paths,omitempty"`         // Extra URL paths served from other directories or upstreams
+	PHP         string        `yaml:"php,omitempty"`           // PHP version of the domain when it differs from the project's
 }
 
 // Services represents the services configuration
 type Services struct {
 	MySQL         *ServiceConfig `yaml:"mysql,omitempty"`
 	MariaDB       *ServiceConfig `yaml:"mariadb,omitempty"`
+	Percona       *ServiceConfig `yaml:"percona,omitempty"`
 	Redis         *ServiceConfig `yaml:"redis,omitempty"`
 	Valkey        *ServiceConfig `yaml:"valkey,omitempty"`
 	OpenSearch    *ServiceConfig `yaml:"opensearch,omitempty"`
 	Elasticsearch *ServiceConfig `yaml:"elasticsearch,omitempty"`
+	Meilisearch   *ServiceConfig `yaml:"meilisearch,omitempty"`
+	Typesense     *ServiceConfig `yaml:"typesense,omitempty"`
 	RabbitMQ      *ServiceConfig `yaml:"rabbitmq,omitempty"`
 	Mailpit       *ServiceConfig `yaml:"mailpit,omitempty"`
 	Varnish       *ServiceConfig `yaml:"varnish,omitempty"`
 	PhpMyAdmin    *ServiceConfig `yaml:"phpmyadmin,omitempty"`
+
+	// OpenSearchDashboards runs OpenSearch Dashboards against the project's
+	// OpenSearch, in the same version
+	OpenSearchDashboards *ServiceConfig `yaml:"opensearch_dashboards,omitempty"`
+
+	// ComposerMirror runs a shared Packeton mirror of repo.magento.com and
+	// points the project's composer.json at it
+	ComposerMirror *ServiceConfig `yaml:"composer-mirror,omitempty"`
 }
 
 // ServiceConfig represents a service configuration
 // Can be specified as just a version string "8.0" or as an object with more options
 type ServiceConfig struct {
-	Enabled bool   `yaml:"-"`
-	Version string `yaml:"version,omitempty"`
-	Port    int    `yaml:"port,omitempty"`
-	Memory  string `yaml:"memory,omitempty"` // RAM allocation (e.g., "2g", "1024m")
+	Enabled  bool   `yaml:"-"`
+	Version  string `yaml:"version,omitempty"`
+	Port     int    `yaml:"port,omitempty"`
+	Memory   string `yaml:"memory,omitempty"`   // RAM allocation (e.g., "2g", "1024m")
+	User     string `yaml:"user,omitempty"`     // Project user (MySQL/MariaDB/Percona, Redis/Valkey ACL, RabbitMQ)
+	Password string `yaml:"password,omitempty"` // Password for User
+	Database string `yaml:"database,omitempty"` // Database name override (MySQL/MariaDB/Percona)
+	// Additional databases created next to the main one (MySQL/MariaDB/Percona)
+	Databases []string `yaml:"databases,omitempty"`
+	// VCL file merged into the generated VCL (Varnish), relative to the config file
+	VCLExtra string `yaml:"vcl_extra,omitempty"`
+	// VCLExtraPath is VCLExtra resolved by the loader
+	VCLExtraPath string `yaml:"-"`
+	// Topology of Varnish: front (default) or behind nginx (Varnish)
+	Mode string `yaml:"mode,omitempty"`
+	// Docker platform of the service image (e.g., "linux/amd64"), overriding
+	// the one MageBox selects for the host architecture
+	Platform string `yaml:"platform,omitempty"`
+	// Server settings written to the my.cnf of the service version
+	// (MySQL/MariaDB/Percona), e.g. innodb_buffer_pool_size: 2G
+	Config map[string]string `yaml:"config,omitempty"`
 }
 
 // UnmarshalYAML implements custom unmarshaling to handle both string and object formats
@@ -236,6 +299,37 @@ func (s *ServiceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		if memory, ok := v["memory"].(string); ok {
 			s.Memory = memory
 		}
+		if user, ok := v["user"].(string); ok {
+			s.User = user
+		}
+		if password, ok := v["password"]; ok && password != nil {
+			s.Password = fmt.Sprint(password)
+		}
+		if database, ok := v["database"].(string); ok {
+			s.Database = database
+		}
+		if databases, ok := v["databases"].([]interface{}); ok {
+			for _, db := range databases {
+				s.Databases = append(s.Databases, fmt.Sprint(db))
+			}
+		}
+		if vclExtra, ok := v["vcl_extra"].(string); ok {
+			s.VCLExtra = vclExtra
+		}
+		if mode, ok := v["mode"].(string); ok {
+			s.Mode = mode
+		}
+		if platform, ok := v["platform"].(string); ok {
+			s.Platform = platform
+		}
+		if settings, ok := v["config"].(map[string]interface{}); ok {
+			s.Config = make(map[string]string, len(settings))
+			for key, value := range settings {
+				if value != nil {
+					s.Config[key] = fmt.Sprint(value)
+				}
+			}
+		}
 		return nil
 	default:
 		s.Enabled = true
@@ -244,14 +338,15 @@ func (s *ServiceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 // MarshalYAML implements custom marshaling to preserve the original format.
-// - If only Enabled is set (no version/port/memory), marshals as `true`
+// - If only Enabled is set (no version/port/memory/credentials), marshals as `true`
 // - If only version is set, marshals as the version string `"8.0"`
 // - Otherwise marshals as an object
 func (s ServiceConfig) MarshalYAML() (interface{}, error) {
-	if s.Version == "" && s.Port == 0 && s.Memory == "" {
+	simple := s.Port == 0 && s.Memory == "" && !s.HasCredentials() && s.Database == "" && len(s.Databases) == 0 && s.VCLExtra == "" && s.Mode == "" && s.Platform == "" && len(s.Config) == 0
+	if simple && s.Version == "" {
 		return s.Enabled, nil
 	}
-	if s.Port == 0 && s.Memory == "" {
+	if simple {
 		return s.Version, nil
 	}
 	// Return as struct — use an alias to avoid infinite recursion
@@ -352,15 +447,136 @@ func (c *Config) Validate() error {
 	if c.PHP == "" {
 		return &ValidationError{Field: "php", Message: "php version is required"}
 	}
+	if db := c.Services.GetDatabaseService(); db != nil && db.User == DefaultDBUser && db.Password != "" && db.Password != DefaultDBPassword {
+		return &ValidationError{Field: "services", Message: "the database root password is shared by all projects; set a project user instead of root"}
+	}
+	if err := c.validateTLD(); err != nil {
+		return err
+	}
+	if err := c.validateDomainPaths(); err != nil {
+		return err
+	}
+	if err := c.validateDomainPHP(); err != nil {
+		return err
+	}
+	if err := c.validateEnvironments(); err != nil {
+		return err
+	}
+	if err := c.validateProfiler(); err != nil {
+		return err
+	}
+	if err := c.validateXdebug(); err != nil {
+		return err
+	}
+	if err := c.validatePHPExtensions(); err != nil {
+		return err
+	}
+	if err := c.validateDeploy(); err != nil {
+		return err
+	}
+	if err := c.validateFrontend(); err != nil {
+		return err
+	}
+	if err := c.validateDatabases(); err != nil {
+		return err
+	}
+	if err := c.validateDBServerConfig(); err != nil {
+		return err
+	}
+	if err := c.validateSanitize(); err != nil {
+		return err
+	}
+	if mode := c.Services.VarnishMode(); mode != VarnishModeFront && mode != VarnishModeBehind {
+		return &ValidationError{Field: "services", Message: fmt.Sprintf("invalid varnish mode %q (use front or behind)", mode)}
+	}
+	if err := c.validatePlatforms(); err != nil {
+		return err
+	}
 	return nil
 }
 
-// DatabaseName returns the sanitized database name
+// DatabaseName returns the database name: the `database` override of the
+// database service, or the sanitized project name.
 // MySQL doesn't handle hyphens well in database names, so we replace them with underscores
 func (c *Config) DatabaseName() string {
+	if db := c.Services.GetDatabaseService(); db != nil && db.Database != "" {
+		return db.Database
+	}
 	return strings.ReplaceAll(c.Name, "-", "_")
 }
 
+// Hosts returns the host names of the project domains
+func (c *Config) Hosts() []string {
+	hosts := make([]string, 0, len(c.Domains))
+	for _, d := range c.Domains {
+		hosts = append(hosts, d.Host)
+	}
+	return hosts
+}
+
+// DatabaseNames returns the main database followed by the additional
+// databases declared under `databases`, without duplicates
+func (c *Config) DatabaseNames() []string {
+	names := []string{c.DatabaseName()}
+	db := c.Services.GetDatabaseService()
+	if db == nil {
+		return names
+	}
+	for _, name := range db.Databases {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// HasDatabase reports whether name is one of the project's databases
+func (c *Config) HasDatabase(name string) bool {
+	return slices.Contains(c.DatabaseNames(), name)
+}
+
+// databaseNamePattern matches the database names MageBox creates unquoted
+// in the shell commands it runs
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// validateDatabases checks the additional database names
+func (c *Config) validateDatabases() error {
+	db := c.Services.GetDatabaseService()
+	if db == nil {
+		return nil
+	}
+	for i, name := range db.Databases {
+		if !databaseNamePattern.MatchString(name) {
+			return &ValidationError{Field: "databases", Message: fmt.Sprintf("invalid database name %q (letters, digits and underscores only)", name), Index: i}
+		}
+	}
+	return nil
+}
+
+// ServicePlatforms are the Docker platforms services.<name>.platform accepts
+var ServicePlatforms = []string{"linux/amd64", "linux/arm64", "linux/arm64/v8"}
+
+// validatePlatforms checks the platform overrides of the services
+func (c *Config) validatePlatforms() error {
+	for name, svc := range c.Services.byName() {
+		if svc == nil || svc.Platform == "" || slices.Contains(ServicePlatforms, svc.Platform) {
+			continue
+		}
+		return &ValidationError{Field: "services", Message: fmt.Sprintf("invalid platform %q for %s (use %s)", svc.Platform, name, strings.Join(ServicePlatforms, ", "))}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the configured profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // ValidationError represents a configuration validation error
 type ValidationError struct {
 	Field   string
@@ -375,6 +591,27 @@ func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
 
+// byName returns the services by their config key
+func (s *Services) byName() map[string]*ServiceConfig {
+	return map[string]*ServiceConfig{
+		"mysql":                 s.MySQL,
+		"mariadb":               s.MariaDB,
+		"percona":               s.Percona,
+		"redis":                 s.Redis,
+		"valkey":                s.Valkey,
+		"opensearch":            s.OpenSearch,
+		"elasticsearch":         s.Elasticsearch,
+		"meilisearch":           s.Meilisearch,
+		"typesense":             s.Typesense,
+		"rabbitmq":              s.RabbitMQ,
+		"mailpit":               s.Mailpit,
+		"varnish":               s.Varnish,
+		"phpmyadmin":            s.PhpMyAdmin,
+		"opensearch_dashboards": s.OpenSearchDashboards,
+		"composer-mirror":       s.ComposerMirror,
+	}
+}
+
 // HasMySQL returns true if MySQL service is configured
 func (s *Services) HasMySQL() bool {
 	return s.MySQL != nil && s.MySQL.Enabled
@@ -385,6 +622,11 @@ func (s *Services) HasMariaDB() bool {
 	return s.MariaDB != nil && s.MariaDB.Enabled
 }
 
+// HasPercona returns true if Percona Server service is configured
+func (s *Services) HasPercona() bool {
+	return s.Percona != nil && s.Percona.Enabled
+}
+
 // HasRedis returns true if Redis service is configured
 func (s *Services) HasRedis() bool {
 	return s.Redis != nil && s.Redis.Enabled
@@ -421,6 +663,12 @@ func (s *Services) HasOpenSearch() bool {
 	return s.OpenSearch != nil && s.OpenSearch.Enabled
 }
 
+// HasOpenSearchDashboards returns true if OpenSearch Dashboards is enabled
+// for a project that runs OpenSearch
+func (s *Services) HasOpenSearchDashboards() bool {
+	return s.OpenSearchDashboards != nil && s.OpenSearchDashboards.Enabled && s.HasOpenSearch()
+}
+
 // HasElasticsearch returns true if Elasticsearch service is configured
 func (s *Services) HasElasticsearch() bool {
 	return s.Elasticsearch != nil && s.Elasticsearch.Enabled
@@ -436,17 +684,37 @@ func (s *Services) HasMailpit() bool {
 	return s.Mailpit != nil && s.Mailpit.Enabled
 }
 
+// MailpitDisabled returns true if Mailpit was explicitly turned off (mailpit: false).
+// Mailpit is enabled by default; when disabled, outgoing mail is captured to var/mail.
+func (s *Services) MailpitDisabled() bool {
+	return s.Mailpit != nil && !s.Mailpit.Enabled
+}
+
 // HasVarnish returns true if Varnish service is configured
 func (s *Services) HasVarnish() bool {
 	return s.Varnish != nil && s.Varnish.Enabled
 }
 
+// VarnishMode returns the Varnish topology, VarnishModeFront unless set
+func (s *Services) VarnishMode() string {
+	if s.Varnish != nil && s.Varnish.Mode != "" {
+		return s.Varnish.Mode
+	}
+	return VarnishModeFront
+}
+
+// HasComposerMirror returns true if the Composer mirror is enabled
+func (s *Services) HasComposerMirror() bool {
+	return s.ComposerMirror != nil && s.ComposerMirror.Enabled
+}
+
 // HasPhpMyAdmin returns true if phpMyAdmin service is configured
 func (s *Services) HasPhpMyAdmin() bool {
 	return s.PhpMyAdmin != nil && s.PhpMyAdmin.Enabled
 }
 
-// GetDatabaseService returns the configured database service (MySQL or MariaDB)
+// GetDatabaseService returns the configured database service (MySQL, MariaDB
+// or Percona Server)
 func (s *Services) GetDatabaseService() *ServiceConfig {
 	if s.HasMySQL() {
 		return s.MySQL
@@ -454,6 +722,9 @@ func (s *Services) GetDatabaseService() *ServiceConfig {
 	if s.HasMariaDB() {
 		return s.MariaDB
 	}
+	if s.HasPercona() {
+		return s.Percona
+	}
 	return nil
 }
 
@@ -130,6 +130,48 @@ func TestConfig_Validate(t *testing.T) {
 			expectError: true,
 			errorField:  "php",
 		},
+		{
+			name: "varnish behind nginx",
+			config: Config{
+				Name:     "mystore",
+				Domains:  []Domain{{Host: "mystore.test"}},
+				PHP:      "8.2",
+				Services: Services{Varnish: &ServiceConfig{Enabled: true, Mode: VarnishModeBehind}},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid varnish mode",
+			config: Config{
+				Name:     "mystore",
+				Domains:  []Domain{{Host: "mystore.test"}},
+				PHP:      "8.2",
+				Services: Services{Varnish: &ServiceConfig{Enabled: true, Mode: "sideways"}},
+			},
+			expectError: true,
+			errorField:  "services",
+		},
+		{
+			name: "amd64 platform override",
+			config: Config{
+				Name:     "mystore",
+				Domains:  []Domain{{Host: "mystore.test"}},
+				PHP:      "8.2",
+				Services: Services{MySQL: &ServiceConfig{Enabled: true, Version: "5.7", Platform: "linux/amd64"}},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid platform",
+			config: Config{
+				Name:     "mystore",
+				Domains:  []Domain{{Host: "mystore.test"}},
+				PHP:      "8.2",
+				Services: Services{OpenSearch: &ServiceConfig{Enabled: true, Version: "2.19", Platform: "amd64"}},
+			},
+			expectError: true,
+			errorField:  "services",
+		},
 	}
 
 	for _, tt := range tests {
@@ -296,6 +338,7 @@ func TestServices_HasMethods(t *testing.T) {
 func TestServices_GetDatabaseService(t *testing.T) {
 	mysql := &ServiceConfig{Enabled: true, Version: "8.0"}
 	mariadb := &ServiceConfig{Enabled: true, Version: "10.6"}
+	percona := &ServiceConfig{Enabled: true, Version: "8.4"}
 
 	tests := []struct {
 		name            string
@@ -321,6 +364,12 @@ func TestServices_GetDatabaseService(t *testing.T) {
 			expectedNil:     false,
 			expectedVersion: "8.0",
 		},
+		{
+			name:            "Percona configured",
+			services:        Services{Percona: percona},
+			expectedNil:     false,
+			expectedVersion: "8.4",
+		},
 		{
 			name:        "no database configured",
 			services:    Services{},
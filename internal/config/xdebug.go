@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Xdebug modes a project can run with
+const (
+	XdebugModeDebug    = "debug"
+	XdebugModeProfile  = "profile"
+	XdebugModeTrace    = "trace"
+	XdebugModeCoverage = "coverage"
+)
+
+// XdebugModes lists the modes 'magebox xdebug mode' accepts
+var XdebugModes = []string{XdebugModeDebug, XdebugModeProfile, XdebugModeTrace, XdebugModeCoverage}
+
+// Values of xdebug.start_with_request
+const (
+	XdebugStartTrigger = "trigger"
+	XdebugStartYes     = "yes"
+)
+
+// XdebugSettings are the Xdebug settings of a project. The trigger and
+// output dir go into the project's PHP-FPM pool. Xdebug reads the mode only
+// at startup, so it goes into the xdebug ini of the PHP version instead.
+// Xdebug itself is loaded with 'magebox xdebug on'.
+type XdebugSettings struct {
+	Mode             string `yaml:"mode,omitempty"`               // Comma separated, e.g. "debug,profile"
+	StartWithRequest string `yaml:"start_with_request,omitempty"` // trigger (default) or yes
+	OutputDir        string `yaml:"output_dir,omitempty"`         // Where profiles and traces are written
+}
+
+// IsXdebugMode reports whether mode is an Xdebug mode MageBox supports
+func IsXdebugMode(mode string) bool {
+	for _, m := range XdebugModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseXdebugMode splits a comma separated mode list, checking every mode
+func ParseXdebugMode(mode string) ([]string, error) {
+	var modes []string
+	for _, m := range strings.Split(mode, ",") {
+		m = strings.TrimSpace(m)
+		if !IsXdebugMode(m) {
+			return nil, fmt.Errorf("unknown xdebug mode %q (expected %s)", m, strings.Join(XdebugModes, ", "))
+		}
+		modes = append(modes, m)
+	}
+	return modes, nil
+}
+
+// XdebugPHPINI returns the pool settings for the project's Xdebug settings,
+// none when the project has no xdebug section. The mode is left out, Xdebug
+// ignores it outside php.ini.
+func (c *Config) XdebugPHPINI() map[string]string {
+	ini := map[string]string{}
+	if c.Xdebug == nil {
+		return ini
+	}
+	if c.Xdebug.StartWithRequest != "" {
+		ini["xdebug.start_with_request"] = c.Xdebug.StartWithRequest
+	}
+	if c.Xdebug.OutputDir != "" {
+		ini["xdebug.output_dir"] = c.Xdebug.OutputDir
+	}
+	return ini
+}
+
+// validateXdebug checks the xdebug section
+func (c *Config) validateXdebug() error {
+	if c.Xdebug == nil {
+		return nil
+	}
+	if c.Xdebug.Mode != "" {
+		if _, err := ParseXdebugMode(c.Xdebug.Mode); err != nil {
+			return &ValidationError{Field: "xdebug.mode", Message: err.Error()}
+		}
+	}
+	switch c.Xdebug.StartWithRequest {
+	case "", XdebugStartTrigger, XdebugStartYes:
+		return nil
+	}
+	return &ValidationError{Field: "xdebug.start_with_request", Message: fmt.Sprintf("unknown value %q (expected trigger or yes)", c.Xdebug.StartWithRequest)}
+}
@@ -0,0 +1,64 @@
+package config
+
+import "testing"
+
+func TestConfig_XdebugPoolPHPINI(t *testing.T) {
+	cfg := Config{
+		Xdebug: &XdebugSettings{Mode: "debug,profile", StartWithRequest: XdebugStartYes, OutputDir: "/srv/shop/var/xdebug"},
+		PHPINI: map[string]string{"xdebug.output_dir": "/tmp/xdebug"},
+	}
+
+	ini := cfg.PoolPHPINI()
+	if _, ok := ini["xdebug.mode"]; ok {
+		t.Errorf("xdebug.mode = %q, the mode belongs in the xdebug ini", ini["xdebug.mode"])
+	}
+	if ini["xdebug.start_with_request"] != "yes" {
+		t.Errorf("xdebug.start_with_request = %q, want yes", ini["xdebug.start_with_request"])
+	}
+	if ini["xdebug.output_dir"] != "/tmp/xdebug" {
+		t.Errorf("xdebug.output_dir = %q, php_ini should win", ini["xdebug.output_dir"])
+	}
+
+	if ini := (&Config{}).PoolPHPINI(); ini["xdebug.start_with_request"] != "" {
+		t.Errorf("xdebug.start_with_request = %q without an xdebug section", ini["xdebug.start_with_request"])
+	}
+}
+
+func TestParseXdebugMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    int
+		wantErr bool
+	}{
+		{mode: "debug", want: 1},
+		{mode: "debug, trace", want: 2},
+		{mode: "profile,coverage", want: 2},
+		{mode: "develop", wantErr: true},
+		{mode: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			modes, err := ParseXdebugMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseXdebugMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if len(modes) != tt.want {
+				t.Errorf("ParseXdebugMode(%q) = %v, want %d modes", tt.mode, modes, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeXdebug(t *testing.T) {
+	main := &XdebugSettings{Mode: "debug", StartWithRequest: XdebugStartTrigger}
+	local := &XdebugSettings{Mode: "profile", OutputDir: "/tmp/profiles"}
+
+	got := mergeXdebug(main, local)
+	if got.Mode != "profile" || got.StartWithRequest != XdebugStartTrigger || got.OutputDir != "/tmp/profiles" {
+		t.Errorf("mergeXdebug() = %+v", got)
+	}
+	if main.Mode != "debug" {
+		t.Error("mergeXdebug() modified the main settings")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+// Package credentials keeps secrets of the CLI, e.g. team server session
+// tokens, in the OS keychain: the macOS Keychain or the Secret Service of
+// libsecret on Linux. Where neither is available they go to a file store
+// encrypted with a key of its own.
+package credentials
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Service is the service name credentials are saved under in the keychain
+const Service = "magebox"
+
+// StoreFile in MAGEBOX_CREDENTIAL_STORE selects the file store, e.g. on
+// machines whose keychain is locked or for tests
+const StoreFile = "file"
+
+// ErrNotFound is returned for credentials that aren't stored
+var ErrNotFound = errors.New("credential not found")
+
+// Store saves credentials by key
+type Store interface {
+	// Name describes where credentials are kept
+	Name() string
+	// Get returns a credential, ErrNotFound when there is none
+	Get(key string) (string, error)
+	// Set saves a credential, replacing the previous one
+	Set(key, value string) error
+	// Delete removes a credential, deleting a missing one is no error
+	Delete(key string) error
+}
+
+// Open returns the keychain of the OS when there is one, else the file store
+// in dir. MAGEBOX_CREDENTIAL_STORE=file selects the file store.
+func Open(dir string) Store {
+	if os.Getenv("MAGEBOX_CREDENTIAL_STORE") == StoreFile {
+		return NewFileStore(dir)
+	}
+	if keychain := osKeychain(); keychain != nil {
+		return keychain
+	}
+	return NewFileStore(dir)
+}
+
+// osKeychain returns the keychain of the OS, nil when it has none
+func osKeychain() Store {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			return newMacKeychain()
+		}
+	case "linux":
+		// secret-tool needs a session bus to reach the Secret Service
+		if _, err := exec.LookPath("secret-tool"); err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+			return newSecretService()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// FileStore keeps credentials in credentials.enc, encrypted with AES-256-GCM
+// and the key in credentials.key next to it. Both are only readable by the
+// user. It keeps credentials out of plain config files, e.g. when those are
+// copied or backed up, but doesn't protect them from the user's own processes
+// the way a keychain does.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns the file store in dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (f *FileStore) Name() string {
+	return "encrypted file " + f.dataPath()
+}
+
+func (f *FileStore) dataPath() string {
+	return filepath.Join(f.dir, "credentials.enc")
+}
+
+func (f *FileStore) keyPath() string {
+	return filepath.Join(f.dir, "credentials.key")
+}
+
+func (f *FileStore) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *FileStore) Set(key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return f.save(values)
+}
+
+func (f *FileStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return f.save(values)
+}
+
+// load decrypts the stored credentials, none when there is no file yet
+func (f *FileStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := os.ReadFile(f.dataPath())
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	gcm, err := f.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("credentials file %s is corrupt", f.dataPath())
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s, was %s replaced?", f.dataPath(), f.keyPath())
+	}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("credentials file %s is corrupt: %w", f.dataPath(), err)
+	}
+	return values, nil
+}
+
+// save encrypts and writes the credentials
+func (f *FileStore) save(values map[string]string) error {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", f.dir, err)
+	}
+	gcm, err := f.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return fileutil.WriteFileAtomic(f.dataPath(), gcm.Seal(nonce, nonce, plaintext, nil), 0600)
+}
+
+// cipher returns the AES-GCM cipher of the store's key, creating the key
+// when create is set and there is none
+func (f *FileStore) cipher(create bool) (cipher.AEAD, error) {
+	key, err := f.readKey()
+	if errors.Is(err, os.ErrNotExist) && create {
+		key, err = f.createKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (f *FileStore) readKey() ([]byte, error) {
+	data, err := os.ReadFile(f.keyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("credentials key %s is missing: %w", f.keyPath(), os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to read credentials key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("credentials key %s is invalid", f.keyPath())
+	}
+	return key, nil
+}
+
+func (f *FileStore) createKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate credentials key: %w", err)
+	}
+	if err := fileutil.WriteFileAtomic(f.keyPath(), []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save credentials key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runFunc runs a command with stdin and returns its output and exit code.
+// The error is only set when the command couldn't run.
+type runFunc func(stdin, name string, args ...string) (stdout string, code int, err error)
+
+// runCommand is the runFunc of the keychains
+func runCommand(stdin, name string, args ...string) (string, int, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return stdout.String(), 0, nil
+}
+
+// Keychain values are base64 encoded; private keys and the like span lines,
+// which the keychain tools don't round-trip
+func encodeValue(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func decodeValue(stored string) (string, error) {
+	value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stored))
+	if err != nil {
+		return "", fmt.Errorf("invalid credential in the keychain: %w", err)
+	}
+	return string(value), nil
+}
+
+// macKeychain keeps credentials in the macOS Keychain through security(1)
+type macKeychain struct {
+	run runFunc
+}
+
+func newMacKeychain() *macKeychain {
+	return &macKeychain{run: runCommand}
+}
+
+// securityItemNotFound is the exit code of security for missing items
+const securityItemNotFound = 44
+
+func (k *macKeychain) Name() string {
+	return "macOS Keychain"
+}
+
+func (k *macKeychain) Get(key string) (string, error) {
+	out, code, err := k.run("", "security", "find-generic-password", "-s", Service, "-a", key, "-w")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keychain: %w", err)
+	}
+	switch code {
+	case 0:
+		return decodeValue(out)
+	case securityItemNotFound:
+		return "", ErrNotFound
+	default:
+		return "", fmt.Errorf("failed to read %s from the keychain (exit code %d)", key, code)
+	}
+}
+
+func (k *macKeychain) Set(key, value string) error {
+	// Passed on stdin in interactive mode, the secret doesn't show up in
+	// the process list; -X takes it hex encoded
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		Service, key, hex.EncodeToString([]byte(encodeValue(value))))
+	_, code, err := k.run(command, "security", "-i")
+	if err != nil {
+		return fmt.Errorf("failed to write the keychain: %w", err)
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to save %s in the keychain (exit code %d)", key, code)
+	}
+	return nil
+}
+
+func (k *macKeychain) Delete(key string) error {
+	_, code, err := k.run("", "security", "delete-generic-password", "-s", Service, "-a", key)
+	if err != nil {
+		return fmt.Errorf("failed to write the keychain: %w", err)
+	}
+	if code != 0 && code != securityItemNotFound {
+		return fmt.Errorf("failed to delete %s from the keychain (exit code %d)", key, code)
+	}
+	return nil
+}
+
+// secretService keeps credentials in the Secret Service of the desktop,
+// e.g. GNOME Keyring or KWallet, through secret-tool of libsecret
+type secretService struct {
+	run runFunc
+}
+
+func newSecretService() *secretService {
+	return &secretService{run: runCommand}
+}
+
+func (s *secretService) Name() string {
+	return "Secret Service (libsecret)"
+}
+
+func (s *secretService) Get(key string) (string, error) {
+	out, code, err := s.run("", "secret-tool", "lookup", "service", Service, "account", key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the keyring: %w", err)
+	}
+	// lookup exits with 1 and prints nothing for missing items
+	if code != 0 || out == "" {
+		if code == 0 || code == 1 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read %s from the keyring (exit code %d)", key, code)
+	}
+	return decodeValue(out)
+}
+
+func (s *secretService) Set(key, value string) error {
+	_, code, err := s.run(encodeValue(value), "secret-tool", "store", "--label", "MageBox "+key,
+		"service", Service, "account", key)
+	if err != nil {
+		return fmt.Errorf("failed to write the keyring: %w", err)
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to save %s in the keyring (exit code %d)", key, code)
+	}
+	return nil
+}
+
+func (s *secretService) Delete(key string) error {
+	// clear succeeds for missing items too
+	_, code, err := s.run("", "secret-tool", "clear", "service", Service, "account", key)
+	if err != nil {
+		return fmt.Errorf("failed to write the keyring: %w", err)
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to delete %s from the keyring (exit code %d)", key, code)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package cron manages the crontab entries that run Magento cron for
+// MageBox projects.
+package cron
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LogFile is where cron output is appended, relative to the project
+const LogFile = "var/log/magento.cron.log"
+
+// Entry is the crontab entry of one project
+type Entry struct {
+	Project   string // Project name, used in the block markers
+	Path      string // Absolute project path
+	PHPBinary string // PHP binary of the project's PHP version
+}
+
+// Line returns the crontab line running bin/magento cron:run every minute.
+// Like Magento's own cron:install it drops the "Ran jobs by schedule" line,
+// so the log only grows when a job has something to say.
+func (e Entry) Line() string {
+	return fmt.Sprintf("* * * * * cd %s && %s bin/magento cron:run 2>&1 | grep -v \"Ran jobs by schedule\" >> %s",
+		quote(e.Path), quote(e.PHPBinary), quote(filepath.Join(e.Path, LogFile)))
+}
+
+// beginMarker and endMarker delimit a project's block in the crontab
+func beginMarker(project string) string {
+	return "# magebox cron " + project + " - managed by 'magebox cron', do not edit"
+}
+
+func endMarker(project string) string {
+	return "# magebox cron " + project + " end"
+}
+
+// Install returns crontab with the project's block replaced by entry, or
+// appended when the project has none yet
+func Install(crontab string, entry Entry) (string, error) {
+	crontab, _, err := Remove(crontab, entry.Project)
+	if err != nil {
+		return "", err
+	}
+	crontab = strings.TrimRight(crontab, "\n")
+	if crontab != "" {
+		crontab += "\n"
+	}
+	return crontab + beginMarker(entry.Project) + "\n" + entry.Line() + "\n" + endMarker(entry.Project) + "\n", nil
+}
+
+// Remove returns crontab without the project's block, and whether it had one.
+// A block without its end marker is an error, removing it would take every
+// line after it along.
+func Remove(crontab, project string) (string, bool, error) {
+	begin, end := beginMarker(project), endMarker(project)
+	var out []string
+	inBlock, found := false, false
+	for _, line := range strings.Split(crontab, "\n") {
+		switch {
+		case line == begin:
+			inBlock, found = true, true
+		case inBlock && line == end:
+			inBlock = false
+		case !inBlock:
+			out = append(out, line)
+		}
+	}
+	if inBlock {
+		return crontab, true, fmt.Errorf("the crontab block of %s has no %q line, fix it with 'crontab -e'", project, end)
+	}
+	return strings.Join(out, "\n"), found, nil
+}
+
+// Find returns the cron line of the project's block
+func Find(crontab, project string) (string, bool) {
+	begin, end := beginMarker(project), endMarker(project)
+	inBlock := false
+	for _, line := range strings.Split(crontab, "\n") {
+		switch {
+		case line == begin:
+			inBlock = true
+		case line == end:
+			inBlock = false
+		case inBlock && strings.TrimSpace(line) != "":
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// Unmanaged returns the crontab lines outside MageBox blocks that run
+// cron:run in projectPath, e.g. entries added by hand before 'magebox cron'
+func Unmanaged(crontab, projectPath string) []string {
+	var lines []string
+	inBlock := false
+	for _, line := range strings.Split(crontab, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "# magebox cron ") && strings.HasSuffix(trimmed, " end"):
+			inBlock = false
+		case strings.HasPrefix(trimmed, "# magebox cron "):
+			inBlock = true
+		case inBlock || trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.Contains(line, projectPath) && strings.Contains(line, "cron:run"):
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Read returns the user's crontab, empty when there is none
+func Read() (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("crontab", "-l")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(strings.ToLower(stderr.String()), "no crontab") {
+			return "", nil
+		}
+		return "", fmt.Errorf("crontab -l: %s", strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// Write replaces the user's crontab
+func Write(crontab string) error {
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = strings.NewReader(crontab)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("crontab: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// quote quotes a string for the shell cron runs lines with
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+)
+
+var testEntry = Entry{Project: "mystore", Path: "/home/me/mystore", PHPBinary: "/usr/bin/php8.3"}
+
+func TestEntry_Line(t *testing.T) {
+	want := `* * * * * cd '/home/me/mystore' && '/usr/bin/php8.3' bin/magento cron:run 2>&1 | grep -v "Ran jobs by schedule" >> '/home/me/mystore/var/log/magento.cron.log'`
+	if got := testEntry.Line(); got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestInstallRemove(t *testing.T) {
+	existing := "MAILTO=\"\"\n0 3 * * * /usr/local/bin/backup\n"
+
+	crontab, err := Install(existing, testEntry)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if !strings.HasPrefix(crontab, existing) {
+		t.Errorf("Install() should keep existing entries:\n%s", crontab)
+	}
+	if line, ok := Find(crontab, "mystore"); !ok || line != testEntry.Line() {
+		t.Errorf("Find() = %q, %v", line, ok)
+	}
+
+	// Installing again replaces the block instead of adding a second one
+	updated := testEntry
+	updated.PHPBinary = "/usr/bin/php8.4"
+	crontab, _ = Install(crontab, updated)
+	if n := strings.Count(crontab, "cron:run"); n != 1 {
+		t.Errorf("Install() twice left %d cron:run lines:\n%s", n, crontab)
+	}
+	if line, _ := Find(crontab, "mystore"); !strings.Contains(line, "php8.4") {
+		t.Errorf("Find() after update = %q", line)
+	}
+
+	// Other projects are left alone
+	crontab, _ = Install(crontab, Entry{Project: "other", Path: "/home/me/other", PHPBinary: "/usr/bin/php8.1"})
+	crontab, found, err := Remove(crontab, "mystore")
+	if err != nil || !found {
+		t.Fatal("Remove() should find the mystore block")
+	}
+	if _, ok := Find(crontab, "mystore"); ok {
+		t.Error("Remove() left the mystore block")
+	}
+	if _, ok := Find(crontab, "other"); !ok {
+		t.Error("Remove() should keep other projects")
+	}
+	if !strings.Contains(crontab, "/usr/local/bin/backup") {
+		t.Error("Remove() should keep unmanaged entries")
+	}
+
+	if _, found, _ := Remove(existing, "mystore"); found {
+		t.Error("Remove() reported a block that isn't there")
+	}
+}
+
+func TestRemoveWithoutEndMarker(t *testing.T) {
+	crontab := beginMarker("mystore") + "\n" + testEntry.Line() + "\n0 3 * * * /usr/local/bin/backup\n"
+
+	updated, found, err := Remove(crontab, "mystore")
+	if err == nil {
+		t.Fatal("Remove() should fail for a block without its end marker")
+	}
+	if !found || updated != crontab {
+		t.Errorf("Remove() = %q, %v, want the crontab unchanged", updated, found)
+	}
+	if _, err := Install(crontab, testEntry); err == nil {
+		t.Error("Install() should fail for a block without its end marker")
+	}
+}
+
+func TestUnmanaged(t *testing.T) {
+	crontab, _ := Install("* * * * * php /home/me/mystore/bin/magento cron:run\n# * * * * * php /home/me/mystore/bin/magento cron:run\n", testEntry)
+	got := Unmanaged(crontab, "/home/me/mystore")
+	if len(got) != 1 || !strings.HasPrefix(got[0], "* * * * * php /home/me/mystore") {
+		t.Errorf("Unmanaged() = %q, want only the hand-written entry", got)
+	}
+}
@@ -0,0 +1,168 @@
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// uiFiles is the single-page dashboard, which renders the JSON API
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// contentSecurityPolicy only allows the dashboard's own scripts, styles and
+// API requests
+const contentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self'; connect-src 'self'; form-action 'none'; base-uri 'none'; frame-ancestors 'none'"
+
+// actionHeader must be sent with every action. Browsers don't send custom
+// headers cross-origin without a CORS preflight, which the dashboard doesn't
+// answer, so other sites can't start or stop projects.
+const actionHeader = "X-MageBox-Dashboard"
+
+// Project is a MageBox project as the dashboard lists it
+type Project struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	PHP      string    `json:"php"`
+	Domains  []string  `json:"domains"`
+	Running  bool      `json:"running"`
+	Services []Service `json:"services"`
+	Links    []Link    `json:"links"`
+}
+
+// Service is a service of a project
+type Service struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+}
+
+// Link is a web UI of a project, e.g. Mailpit or RabbitMQ management
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Source lists the projects and starts and stops them. Actions get the path
+// of a listed project, never one from the request.
+type Source interface {
+	Projects() ([]Project, error)
+	Start(projectPath string) error
+	Stop(projectPath string) error
+}
+
+// Server serves the dashboard and its JSON API
+type Server struct {
+	source Source
+	hosts  map[string]bool
+	action sync.Mutex // One start or stop at a time
+}
+
+// NewServer creates a dashboard server that answers requests for the given
+// hosts (host:port as the browser sends it) only, so other sites can't reach
+// it through DNS rebinding
+func NewServer(source Source, hosts ...string) *Server {
+	s := &Server{source: source, hosts: make(map[string]bool)}
+	for _, host := range hosts {
+		s.hosts[strings.ToLower(host)] = true
+	}
+	return s
+}
+
+// Handler returns the HTTP handler of the dashboard
+func (s *Server) Handler() http.Handler {
+	root, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(root)))
+	mux.HandleFunc("GET /api/projects", s.handleProjects)
+	mux.HandleFunc("POST /api/projects/{name}/{action}", s.handleAction)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		if !s.hosts[strings.ToLower(r.Host)] {
+			writeError(w, http.StatusForbidden, "unknown host "+r.Host)
+			return
+		}
+		if r.Method == http.MethodPost && r.Header.Get(actionHeader) == "" {
+			writeError(w, http.StatusForbidden, "missing "+actionHeader+" header")
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := s.source.Projects()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if projects == nil {
+		projects = []Project{}
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
+	var run func(string) error
+	switch r.PathValue("action") {
+	case "start":
+		run = s.source.Start
+	case "stop":
+		run = s.source.Stop
+	default:
+		writeError(w, http.StatusNotFound, "unknown action "+r.PathValue("action"))
+		return
+	}
+
+	projects, err := s.source.Projects()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	path := ""
+	for _, p := range projects {
+		if p.Name == r.PathValue("name") {
+			path = p.Path
+		}
+	}
+	if path == "" {
+		writeError(w, http.StatusNotFound, "unknown project "+r.PathValue("name"))
+		return
+	}
+
+	if !s.action.TryLock() {
+		writeError(w, http.StatusConflict, "another project is starting or stopping")
+		return
+	}
+	defer s.action.Unlock()
+
+	if err := run(path); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
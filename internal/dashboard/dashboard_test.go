@@ -0,0 +1,122 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeSource struct {
+	projects []Project
+	started  []string
+	stopped  []string
+	err      error
+}
+
+func (f *fakeSource) Projects() ([]Project, error) { return f.projects, nil }
+
+func (f *fakeSource) Start(projectPath string) error {
+	f.started = append(f.started, projectPath)
+	return f.err
+}
+
+func (f *fakeSource) Stop(projectPath string) error {
+	f.stopped = append(f.stopped, projectPath)
+	return f.err
+}
+
+const testHost = "127.0.0.1:7070"
+
+func request(t *testing.T, h http.Handler, method, path string, header bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = testHost
+	if header {
+		req.Header.Set(actionHeader, "1")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Projects(t *testing.T) {
+	source := &fakeSource{projects: []Project{{Name: "mystore", Path: "/home/dev/mystore", PHP: "8.3", Running: true}}}
+	h := NewServer(source, testHost).Handler()
+
+	rec := request(t, h, http.MethodGet, "/api/projects", false)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var projects []Project
+	if err := json.Unmarshal(rec.Body.Bytes(), &projects); err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 || projects[0].Name != "mystore" || !projects[0].Running {
+		t.Errorf("projects = %+v", projects)
+	}
+
+	rec = request(t, h, http.MethodGet, "/", false)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "app.js") {
+		t.Errorf("GET / = %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" {
+		t.Error("missing Content-Security-Policy")
+	}
+}
+
+func TestServer_Actions(t *testing.T) {
+	source := &fakeSource{projects: []Project{{Name: "mystore", Path: "/home/dev/mystore"}}}
+	h := NewServer(source, testHost).Handler()
+
+	if rec := request(t, h, http.MethodPost, "/api/projects/mystore/start", true); rec.Code != http.StatusOK {
+		t.Errorf("start = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, h, http.MethodPost, "/api/projects/mystore/stop", true); rec.Code != http.StatusOK {
+		t.Errorf("stop = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(source.started) != 1 || source.started[0] != "/home/dev/mystore" || len(source.stopped) != 1 {
+		t.Errorf("started = %v, stopped = %v", source.started, source.stopped)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		header bool
+		want   int
+	}{
+		{"unknown project", "/api/projects/other/start", true, http.StatusNotFound},
+		{"unknown action", "/api/projects/mystore/destroy", true, http.StatusNotFound},
+		{"without header", "/api/projects/mystore/start", false, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := request(t, h, http.MethodPost, tt.path, tt.header); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if len(source.started) != 1 {
+		t.Errorf("rejected requests started projects: %v", source.started)
+	}
+
+	source.err = errors.New("nginx failed")
+	rec := request(t, h, http.MethodPost, "/api/projects/mystore/start", true)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "nginx failed") {
+		t.Errorf("failed start = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestServer_UnknownHost(t *testing.T) {
+	h := NewServer(&fakeSource{}, testHost).Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects", nil)
+	req.Host = "attacker.example:7070"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
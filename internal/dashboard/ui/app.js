@@ -0,0 +1,107 @@
+// MageBox dashboard. Lists the projects from the dashboard API and starts
+// and stops them; the API only answers requests carrying the action header.
+'use strict';
+
+const refreshInterval = 10000;
+
+let busy = false;
+
+function $(selector, root) {
+  return (root || document).querySelector(selector);
+}
+
+// el creates an element; text is always set as text, never parsed as HTML
+function el(tag, props, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, props || {});
+  for (const child of children) {
+    if (child === null || child === undefined) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+function showMessage(text, isError) {
+  const message = $('#message');
+  message.textContent = text;
+  message.className = isError ? 'error' : '';
+  message.hidden = !text;
+}
+
+async function api(method, path) {
+  const response = await fetch(path, {
+    method: method,
+    headers: { 'X-MageBox-Dashboard': '1' },
+  });
+  const data = await response.json().catch(() => null);
+  if (!response.ok) {
+    throw new Error((data && data.error) || response.statusText);
+  }
+  return data;
+}
+
+function link(url, text) {
+  return el('a', { href: url, target: '_blank', rel: 'noopener noreferrer' }, text || url);
+}
+
+function projectRow(project) {
+  const domains = el('td');
+  for (const domain of project.domains || []) {
+    domains.append(link('https://' + domain, domain), el('br'));
+  }
+
+  const services = el('td');
+  for (const service of project.services || []) {
+    services.append(el('span', { className: 'tag ' + (service.running ? 'up' : 'down') }, service.name));
+  }
+
+  const links = el('td');
+  for (const l of project.links || []) {
+    links.append(link(l.url, l.name), el('br'));
+  }
+
+  const action = project.running ? 'stop' : 'start';
+  const button = el('button', { type: 'button', className: project.running ? 'danger' : '' }, project.running ? 'Stop' : 'Start');
+  button.disabled = busy;
+  button.addEventListener('click', () => act(project.name, action));
+
+  const name = el('td', {}, el('strong', {}, project.name), el('br'), el('span', { className: 'hint' }, project.path));
+  return el('tr', { className: project.running ? '' : 'stopped' },
+    name, el('td', {}, project.php), domains, services, links, el('td', {}, button));
+}
+
+async function load() {
+  try {
+    const projects = await api('GET', '/api/projects');
+    const tbody = $('#projects tbody');
+    tbody.replaceChildren();
+    if (projects.length === 0) {
+      tbody.append(el('tr', {}, el('td', { className: 'empty', colSpan: 6 }, 'No projects yet, run magebox start in a project')));
+    }
+    for (const project of projects) {
+      tbody.append(projectRow(project));
+    }
+  } catch (err) {
+    showMessage(err.message, true);
+  }
+}
+
+async function act(name, action) {
+  busy = true;
+  showMessage((action === 'start' ? 'Starting ' : 'Stopping ') + name + '…', false);
+  for (const button of document.querySelectorAll('#projects button')) {
+    button.disabled = true;
+  }
+  try {
+    await api('POST', '/api/projects/' + encodeURIComponent(name) + '/' + action);
+    showMessage(name + (action === 'start' ? ' started' : ' stopped'), false);
+  } catch (err) {
+    showMessage(err.message, true);
+  }
+  busy = false;
+  await load();
+}
+
+$('#refresh').addEventListener('click', load);
+setInterval(() => { if (!busy) load(); }, refreshInterval);
+load();
@@ -0,0 +1,179 @@
+// Package dbimport reads database dumps for 'magebox db import' and builds
+// the statements that sanitize an imported Magento database.
+//
+// Dumps are streamed: plain SQL, gzip (.sql.gz) and zstd (.sql.zst) files are
+// decompressed on the fly while the compressed bytes are counted for the
+// progress bar, so a dump is never unpacked to disk. zstd runs the zstd
+// binary, like backup archives.
+package dbimport
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"qoliber/magebox/internal/progress"
+)
+
+// Compression of a dump file
+type Compression string
+
+// Compressions a dump is read with
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ErrZstdNotInstalled is returned when a zstd dump is opened without the
+// zstd binary on PATH
+var ErrZstdNotInstalled = errors.New("zstd is not installed")
+
+// DetectCompression returns the compression of a dump from its first bytes,
+// so a .sql.gz that is plain SQL or a .zst renamed to .gz is still read
+func DetectCompression(path string) (Compression, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return CompressionNone, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(file, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return CompressionNone, err
+	}
+	return compressionOf(magic[:n]), nil
+}
+
+// compressionOf returns the compression the magic bytes stand for
+func compressionOf(magic []byte) Compression {
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		return CompressionZstd
+	case bytes.HasPrefix(magic, gzipMagic):
+		return CompressionGzip
+	default:
+		return CompressionNone
+	}
+}
+
+// Dump is an open dump file read as uncompressed SQL
+type Dump struct {
+	Compression Compression
+	Size        int64 // Size of the file on disk, which the progress counts against
+
+	file   *os.File
+	sql    io.Reader
+	gzip   *gzip.Reader
+	zstd   *exec.Cmd
+	stderr strings.Builder
+	eof    bool
+	closed bool
+}
+
+// Open opens a dump for streaming. onProgress, when set, is called with the
+// bytes read from the file, compressed bytes for a compressed dump.
+func Open(path string, onProgress func(progress.Progress)) (*Dump, error) {
+	compression, err := DetectCompression(path)
+	if err != nil {
+		return nil, err
+	}
+	if compression == CompressionZstd {
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return nil, ErrZstdNotInstalled
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	d := &Dump{Compression: compression, Size: info.Size(), file: file}
+	var raw io.Reader = file
+	if onProgress != nil {
+		raw = progress.NewReader(file, d.Size, onProgress)
+	}
+
+	switch compression {
+	case CompressionGzip:
+		d.gzip, err = gzip.NewReader(bufio.NewReader(raw))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read gzip dump: %w", err)
+		}
+		d.sql = d.gzip
+	case CompressionZstd:
+		d.zstd = exec.Command("zstd", "-q", "-d", "-c")
+		d.zstd.Stdin = raw
+		d.zstd.Stderr = &d.stderr
+		out, err := d.zstd.StdoutPipe()
+		if err == nil {
+			err = d.zstd.Start()
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to start zstd: %w", err)
+		}
+		d.sql = out
+	default:
+		d.sql = raw
+	}
+	return d, nil
+}
+
+// Read reads uncompressed SQL
+func (d *Dump) Read(p []byte) (int, error) {
+	n, err := d.sql.Read(p)
+	if err == io.EOF {
+		d.eof = true
+	}
+	return n, err
+}
+
+// Close closes the dump. It reports a corrupt or truncated zstd dump once the
+// whole dump was read; zstd is stopped when the import ends early.
+func (d *Dump) Close() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+
+	var err error
+	if d.gzip != nil {
+		err = d.gzip.Close()
+	}
+	if d.zstd != nil {
+		if !d.eof {
+			_ = d.zstd.Process.Kill()
+			_ = d.zstd.Wait()
+		} else if werr := d.zstd.Wait(); werr != nil {
+			msg := strings.TrimSpace(d.stderr.String())
+			if msg == "" {
+				msg = werr.Error()
+			}
+			err = fmt.Errorf("zstd failed: %s", msg)
+		}
+	}
+	if cerr := d.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package dbimport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"qoliber/magebox/internal/progress"
+)
+
+const testSQL = "CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1),(2),(3);\n"
+
+func writeGzip(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(testSQL)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readDump(t *testing.T, path string) (string, Compression) {
+	t.Helper()
+	var read int64
+	d, err := Open(path, func(p progress.Progress) { read = p.Read })
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	sql, err := io.ReadAll(d)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if read != d.Size {
+		t.Errorf("progress = %d bytes, want the file size %d", read, d.Size)
+	}
+	return string(sql), d.Compression
+}
+
+func TestOpen_Plain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(path, []byte(testSQL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sql, compression := readDump(t, path)
+	if sql != testSQL || compression != CompressionNone {
+		t.Errorf("read %q (%q), want plain SQL", sql, compression)
+	}
+}
+
+func TestOpen_Gzip(t *testing.T) {
+	// Detected by content, not by the file name
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	writeGzip(t, path)
+
+	sql, compression := readDump(t, path)
+	if sql != testSQL || compression != CompressionGzip {
+		t.Errorf("read %q (%q), want decompressed SQL", sql, compression)
+	}
+}
+
+func TestOpen_Zstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "dump.sql")
+	if err := os.WriteFile(plain, []byte(testSQL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("zstd", "-q", plain, "-o", plain+".zst").CombinedOutput(); err != nil {
+		t.Fatalf("zstd: %v: %s", err, out)
+	}
+
+	sql, compression := readDump(t, plain+".zst")
+	if sql != testSQL || compression != CompressionZstd {
+		t.Errorf("read %q (%q), want decompressed SQL", sql, compression)
+	}
+}
+
+func TestOpen_ZstdTruncated(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "dump.sql")
+	if err := os.WriteFile(plain, bytes.Repeat([]byte(testSQL), 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("zstd", "-q", plain, "-o", plain+".zst").CombinedOutput(); err != nil {
+		t.Fatalf("zstd: %v: %s", err, out)
+	}
+	data, err := os.ReadFile(plain + ".zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(plain+".zst", data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(plain+".zst", nil)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	_, _ = io.ReadAll(d)
+	if err := d.Close(); err == nil {
+		t.Error("Close() should report a truncated zstd dump")
+	}
+}
+
+func TestCompressionOf(t *testing.T) {
+	tests := []struct {
+		magic []byte
+		want  Compression
+	}{
+		{[]byte{0x1f, 0x8b, 0x08, 0x00}, CompressionGzip},
+		{[]byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd},
+		{[]byte("-- M"), CompressionNone},
+		{[]byte{}, CompressionNone},
+	}
+	for _, tt := range tests {
+		if got := compressionOf(tt.magic); got != tt.want {
+			t.Errorf("compressionOf(%x) = %q, want %q", tt.magic, got, tt.want)
+		}
+	}
+}
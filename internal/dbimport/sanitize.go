@@ -0,0 +1,220 @@
+package dbimport
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"qoliber/magebox/internal/baseurl"
+	"qoliber/magebox/internal/config"
+)
+
+// column is a column replaced by a SQL expression
+type column struct {
+	name  string
+	value string
+}
+
+// piiTable lists the personal data columns of a table and their replacement.
+// Only the columns a table has are replaced, Magento versions differ.
+type piiTable struct {
+	name    string
+	columns []column
+}
+
+// Replacements shared by the address tables
+var addressColumns = []column{
+	{"firstname", "'Customer'"},
+	{"middlename", "NULL"},
+	{"lastname", "CAST(entity_id AS CHAR)"},
+	{"company", "NULL"},
+	{"street", "CONCAT(entity_id, ' Example Street')"},
+	{"telephone", "'0000000000'"},
+	{"fax", "NULL"},
+	{"vat_id", "NULL"},
+}
+
+// Replacements shared by the invoice, credit memo and shipment grids
+var salesGridColumns = []column{
+	{"customer_email", "CONCAT('order', order_id, '@example.com')"},
+	{"customer_name", "'Customer'"},
+	{"billing_name", "'Customer'"},
+	{"shipping_name", "'Customer'"},
+	{"billing_address", "NULL"},
+	{"shipping_address", "NULL"},
+}
+
+// customerPII are the tables with customer data replaced by sanitize.customers.
+// Emails stay unique: customer<id>, order<id>, quote<id> @example.com.
+var customerPII = []piiTable{
+	{"customer_entity", []column{
+		{"email", "CONCAT('customer', entity_id, '@example.com')"},
+		{"firstname", "'Customer'"},
+		{"middlename", "NULL"},
+		{"lastname", "CAST(entity_id AS CHAR)"},
+		{"dob", "NULL"},
+		{"taxvat", "NULL"},
+		{"gender", "NULL"},
+		{"password_hash", "NULL"},
+		{"rp_token", "NULL"},
+	}},
+	{"customer_address_entity", addressColumns},
+	{"sales_order", []column{
+		{"customer_email", "CONCAT('order', entity_id, '@example.com')"},
+		{"customer_firstname", "'Customer'"},
+		{"customer_middlename", "NULL"},
+		{"customer_lastname", "CAST(entity_id AS CHAR)"},
+		{"customer_dob", "NULL"},
+		{"customer_taxvat", "NULL"},
+		{"remote_ip", "NULL"},
+		{"x_forwarded_for", "NULL"},
+	}},
+	{"sales_order_address", append([]column{{"email", "CONCAT('order', parent_id, '@example.com')"}}, addressColumns...)},
+	{"sales_order_grid", []column{
+		{"customer_email", "CONCAT('order', entity_id, '@example.com')"},
+		{"customer_name", "'Customer'"},
+		{"billing_name", "'Customer'"},
+		{"shipping_name", "'Customer'"},
+		{"billing_address", "NULL"},
+		{"shipping_address", "NULL"},
+	}},
+	{"sales_invoice_grid", salesGridColumns},
+	{"sales_creditmemo_grid", salesGridColumns},
+	{"sales_shipment_grid", salesGridColumns},
+	{"quote", []column{
+		{"customer_email", "CONCAT('quote', entity_id, '@example.com')"},
+		{"customer_firstname", "'Customer'"},
+		{"customer_middlename", "NULL"},
+		{"customer_lastname", "CAST(entity_id AS CHAR)"},
+		{"customer_dob", "NULL"},
+		{"customer_taxvat", "NULL"},
+		{"remote_ip", "NULL"},
+	}},
+	{"quote_address", append([]column{
+		{"email", "CONCAT('quote', quote_id, '@example.com')"},
+		{"lastname", "CAST(address_id AS CHAR)"},
+		{"street", "CONCAT(address_id, ' Example Street')"},
+	}, addressColumns...)},
+	{"newsletter_subscriber", []column{
+		{"subscriber_email", "CONCAT('subscriber', subscriber_id, '@example.com')"},
+	}},
+}
+
+// customerIndexTables are emptied by sanitize.customers; the customer grid
+// indexer rebuilds them
+var customerIndexTables = []string{"customer_grid_flat"}
+
+// Sanitizer builds the statements of a sanitize ruleset for one database
+type Sanitizer struct {
+	Rules   *config.SanitizeConfig
+	Domains []config.Domain
+	// Prefix is the table prefix of app/etc/env.php
+	Prefix string
+	// Columns are the column names of every table in the database, by table
+	// name with the prefix
+	Columns map[string][]string
+}
+
+// Statements returns the SQL that applies the ruleset, and the truncate
+// tables the database doesn't have
+func (s *Sanitizer) Statements() ([]string, []string) {
+	var stmts, missing []string
+	rules := s.Rules
+
+	if rules.Customers {
+		for _, t := range customerPII {
+			if stmt := s.update(t); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+		}
+		for _, table := range customerIndexTables {
+			if s.hasTable(table) {
+				stmts = append(stmts, fmt.Sprintf("TRUNCATE TABLE %s", s.table(table)))
+			}
+		}
+	}
+
+	if rules.AdminPassword != "" && s.hasTable("admin_user") {
+		set := []string{"password = " + quote(AdminPasswordHash(rules.AdminPassword, newSalt()))}
+		for _, c := range []column{{"failures_num", "0"}, {"lock_expires", "NULL"}, {"first_failure", "NULL"}} {
+			if s.hasColumn("admin_user", c.name) {
+				set = append(set, c.name+" = "+c.value)
+			}
+		}
+		stmts = append(stmts, fmt.Sprintf("UPDATE %s SET %s", s.table("admin_user"), strings.Join(set, ", ")))
+	}
+
+	if rules.BaseURLs && s.hasTable("core_config_data") {
+		stmts = append(stmts, baseurl.Statements(baseurl.Targets(s.Domains), s.Prefix)...)
+	}
+
+	for _, table := range rules.Truncate {
+		if !s.hasTable(table) {
+			missing = append(missing, table)
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("TRUNCATE TABLE %s", s.table(table)))
+	}
+
+	stmts = append(stmts, rules.SQL...)
+	return stmts, missing
+}
+
+// update returns the UPDATE replacing the personal data of a table, empty
+// when the database has none of its columns
+func (s *Sanitizer) update(t piiTable) string {
+	var set []string
+	seen := make(map[string]bool)
+	for _, c := range t.columns {
+		if seen[c.name] || !s.hasColumn(t.name, c.name) {
+			continue
+		}
+		seen[c.name] = true
+		set = append(set, c.name+" = "+c.value)
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("UPDATE %s SET %s", s.table(t.name), strings.Join(set, ", "))
+}
+
+// table returns a table name with the prefix, quoted
+func (s *Sanitizer) table(name string) string {
+	return "`" + s.Prefix + name + "`"
+}
+
+func (s *Sanitizer) hasTable(name string) bool {
+	_, ok := s.Columns[s.Prefix+name]
+	return ok
+}
+
+func (s *Sanitizer) hasColumn(table, name string) bool {
+	for _, c := range s.Columns[s.Prefix+table] {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminPasswordHash returns a Magento password hash of password: the SHA-256
+// hash version, which every Magento 2 release verifies and upgrades to its
+// current algorithm on the next login
+func AdminPasswordHash(password, salt string) string {
+	sum := sha256.Sum256([]byte(salt + password))
+	return hex.EncodeToString(sum[:]) + ":" + salt + ":1"
+}
+
+// newSalt returns a random 32 character password salt
+func newSalt() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// quote returns a SQL string literal
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,102 @@
+package dbimport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+// magentoColumns is a cut-down Magento schema
+var magentoColumns = map[string][]string{
+	"customer_entity":         {"entity_id", "email", "firstname", "lastname", "dob", "password_hash"},
+	"customer_address_entity": {"entity_id", "parent_id", "firstname", "lastname", "street", "telephone"},
+	"customer_grid_flat":      {"entity_id", "name", "email"},
+	"quote_address":           {"address_id", "quote_id", "email", "firstname", "lastname", "street", "telephone"},
+	"admin_user":              {"user_id", "password", "failures_num", "lock_expires"},
+	"core_config_data":        {"config_id", "scope", "scope_id", "path", "value"},
+	"store":                   {"store_id", "code"},
+	"store_website":           {"website_id", "code"},
+	"report_event":            {"event_id"},
+}
+
+func TestSanitizer_Customers(t *testing.T) {
+	s := &Sanitizer{Rules: &config.SanitizeConfig{Customers: true}, Columns: magentoColumns}
+	stmts, _ := s.Statements()
+
+	want := []string{
+		"UPDATE `customer_entity` SET email = CONCAT('customer', entity_id, '@example.com'), firstname = 'Customer', lastname = CAST(entity_id AS CHAR), dob = NULL, password_hash = NULL",
+		"UPDATE `customer_address_entity` SET firstname = 'Customer', lastname = CAST(entity_id AS CHAR), street = CONCAT(entity_id, ' Example Street'), telephone = '0000000000'",
+		"UPDATE `quote_address` SET email = CONCAT('quote', quote_id, '@example.com'), lastname = CAST(address_id AS CHAR), street = CONCAT(address_id, ' Example Street'), firstname = 'Customer', telephone = '0000000000'",
+		"TRUNCATE TABLE `customer_grid_flat`",
+	}
+	if len(stmts) != len(want) {
+		t.Fatalf("Statements() =\n%s\nwant %d statements, none for missing tables", strings.Join(stmts, "\n"), len(want))
+	}
+	for i := range want {
+		if stmts[i] != want[i] {
+			t.Errorf("statement %d =\n%s\nwant\n%s", i, stmts[i], want[i])
+		}
+	}
+}
+
+func TestSanitizer_AdminPasswordAndTruncate(t *testing.T) {
+	s := &Sanitizer{
+		Rules:   &config.SanitizeConfig{AdminPassword: "admin123", Truncate: []string{"report_event", "mageworx_log"}, SQL: []string{"DELETE FROM admin_user WHERE username <> 'admin'"}},
+		Prefix:  "m2_",
+		Columns: map[string][]string{"m2_admin_user": magentoColumns["admin_user"], "m2_report_event": {"event_id"}},
+	}
+	stmts, missing := s.Statements()
+
+	if len(stmts) != 3 {
+		t.Fatalf("Statements() = %v, want 3", stmts)
+	}
+	if !strings.HasPrefix(stmts[0], "UPDATE `m2_admin_user` SET password = '") || !strings.HasSuffix(stmts[0], ":1', failures_num = 0, lock_expires = NULL") {
+		t.Errorf("admin statement = %s", stmts[0])
+	}
+	if stmts[1] != "TRUNCATE TABLE `m2_report_event`" {
+		t.Errorf("truncate statement = %s", stmts[1])
+	}
+	if stmts[2] != s.Rules.SQL[0] {
+		t.Errorf("custom statements should run last, got %s", stmts[2])
+	}
+	if len(missing) != 1 || missing[0] != "mageworx_log" {
+		t.Errorf("missing = %v, want [mageworx_log]", missing)
+	}
+}
+
+func TestSanitizer_BaseURLs(t *testing.T) {
+	s := &Sanitizer{
+		Rules:   &config.SanitizeConfig{BaseURLs: true},
+		Domains: []config.Domain{{Host: "mystore.test"}},
+		Prefix:  "m2_",
+		Columns: map[string][]string{"m2_core_config_data": magentoColumns["core_config_data"]},
+	}
+	stmts, _ := s.Statements()
+
+	if len(stmts) == 0 || !strings.Contains(stmts[0], "INSERT INTO `m2_core_config_data`") || !strings.Contains(stmts[0], "'https://mystore.test/'") {
+		t.Errorf("Statements() = %v, want the base URLs of the domains", stmts)
+	}
+
+	// Without core_config_data there is nothing to point
+	s.Columns = magentoColumns
+	if stmts, _ := s.Statements(); len(stmts) != 0 {
+		t.Errorf("Statements() = %v, want none without the table", stmts)
+	}
+}
+
+func TestAdminPasswordHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("saltadmin123"))
+	want := hex.EncodeToString(sum[:]) + ":salt:1"
+	if got := AdminPasswordHash("admin123", "salt"); got != want {
+		t.Errorf("AdminPasswordHash() = %s, want %s", got, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote(`it's a \ test`); got != `'it\'s a \\ test'` {
+		t.Errorf("quote() = %s", got)
+	}
+}
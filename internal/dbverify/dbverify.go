@@ -0,0 +1,167 @@
+// Package dbverify checks an imported database against the export it came
+// from.
+//
+// At export time a manifest with the row count and CHECKSUM TABLE value of
+// every table is written next to the dump. After an import the same numbers
+// are taken from the local database and compared, which catches truncated
+// dumps (missing tables, fewer rows) and charset mangling (same rows,
+// different checksum).
+package dbverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// ManifestSuffix is appended to a dump file name to get its manifest
+const ManifestSuffix = ".manifest.json"
+
+// Table is the state of one table at export time
+type Table struct {
+	Name     string `json:"name"`
+	Rows     int64  `json:"rows"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Manifest is the state of a database at export time
+type Manifest struct {
+	Database  string    `json:"database"`
+	Server    string    `json:"server"` // SELECT VERSION(), checksums only compare within one version
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []Table   `json:"tables"`
+}
+
+// Problem is a discrepancy between a manifest and the imported database
+type Problem struct {
+	Table   string
+	Message string
+}
+
+// ManifestPath returns the manifest path of a dump file
+func ManifestPath(dumpPath string) string {
+	return dumpPath + ManifestSuffix
+}
+
+// Load reads a manifest
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to path
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// TablesQuery lists the base tables of database, one per row
+func TablesQuery(database string) string {
+	return fmt.Sprintf("SELECT table_name FROM information_schema.tables WHERE table_schema = '%s' AND table_type = 'BASE TABLE' ORDER BY table_name",
+		strings.ReplaceAll(database, "'", "''"))
+}
+
+// CountQuery counts the rows of every table, one "table<TAB>count" row each
+func CountQuery(database string, tables []string) string {
+	parts := make([]string, 0, len(tables))
+	for _, t := range tables {
+		parts = append(parts, fmt.Sprintf("SELECT '%s', COUNT(*) FROM %s",
+			strings.ReplaceAll(t, "'", "''"), qualified(database, t)))
+	}
+	return strings.Join(parts, " UNION ALL ")
+}
+
+// ChecksumQuery checksums every table, one "database.table<TAB>checksum" row each
+func ChecksumQuery(database string, tables []string) string {
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, qualified(database, t))
+	}
+	return "CHECKSUM TABLE " + strings.Join(names, ", ")
+}
+
+func qualified(database, table string) string {
+	quote := func(s string) string { return "`" + strings.ReplaceAll(s, "`", "``") + "`" }
+	return quote(database) + "." + quote(table)
+}
+
+// Build assembles a manifest from the output rows of CountQuery and
+// ChecksumQuery
+func Build(database, server string, countRows, checksumRows []string) (*Manifest, error) {
+	m := &Manifest{Database: database, Server: server, CreatedAt: time.Now().UTC()}
+
+	checksums := make(map[string]string)
+	for _, row := range checksumRows {
+		name, sum, ok := strings.Cut(row, "\t")
+		if !ok || sum == "NULL" {
+			continue
+		}
+		// CHECKSUM TABLE reports database.table
+		checksums[strings.TrimPrefix(name, database+".")] = sum
+	}
+
+	for _, row := range countRows {
+		name, count, ok := strings.Cut(row, "\t")
+		if !ok {
+			return nil, fmt.Errorf("unexpected row count output: %q", row)
+		}
+		rows, err := strconv.ParseInt(count, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected row count for %s: %q", name, count)
+		}
+		m.Tables = append(m.Tables, Table{Name: name, Rows: rows, Checksum: checksums[name]})
+	}
+
+	sort.Slice(m.Tables, func(i, j int) bool { return m.Tables[i].Name < m.Tables[j].Name })
+	return m, nil
+}
+
+// ChecksumsComparable reports whether the checksums of two manifests can be
+// compared. CHECKSUM TABLE depends on the row format, so values taken on
+// different server versions differ even for identical data.
+func ChecksumsComparable(expected, actual *Manifest) bool {
+	return expected.Server != "" && expected.Server == actual.Server
+}
+
+// Compare returns the tables of expected that are missing from actual or
+// differ in row count or, when comparable, checksum. Tables that only exist
+// in actual are ignored, an import doesn't drop them.
+func Compare(expected, actual *Manifest) []Problem {
+	tables := make(map[string]Table, len(actual.Tables))
+	for _, t := range actual.Tables {
+		tables[t.Name] = t
+	}
+	checksums := ChecksumsComparable(expected, actual)
+
+	var problems []Problem
+	for _, want := range expected.Tables {
+		got, ok := tables[want.Name]
+		switch {
+		case !ok:
+			problems = append(problems, Problem{Table: want.Name, Message: "missing"})
+		case got.Rows != want.Rows:
+			problems = append(problems, Problem{Table: want.Name,
+				Message: fmt.Sprintf("%d rows, expected %d", got.Rows, want.Rows)})
+		case checksums && want.Checksum != "" && got.Checksum != want.Checksum:
+			problems = append(problems, Problem{Table: want.Name,
+				Message: "checksum differs with the same row count (data changed, e.g. by a charset conversion)"})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,86 @@
+package dbverify
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestQueries(t *testing.T) {
+	tables := []string{"catalog_product_entity", "sales_order"}
+
+	wantCount := "SELECT 'catalog_product_entity', COUNT(*) FROM `mystore`.`catalog_product_entity` UNION ALL SELECT 'sales_order', COUNT(*) FROM `mystore`.`sales_order`"
+	if got := CountQuery("mystore", tables); got != wantCount {
+		t.Errorf("CountQuery() = %q, want %q", got, wantCount)
+	}
+
+	wantChecksum := "CHECKSUM TABLE `mystore`.`catalog_product_entity`, `mystore`.`sales_order`"
+	if got := ChecksumQuery("mystore", tables); got != wantChecksum {
+		t.Errorf("ChecksumQuery() = %q, want %q", got, wantChecksum)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	m, err := Build("mystore", "8.0.36",
+		[]string{"sales_order\t12", "catalog_product_entity\t2048"},
+		[]string{"mystore.catalog_product_entity\t123456", "mystore.sales_order\tNULL"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := []Table{
+		{Name: "catalog_product_entity", Rows: 2048, Checksum: "123456"},
+		{Name: "sales_order", Rows: 12},
+	}
+	if !reflect.DeepEqual(m.Tables, want) {
+		t.Errorf("Build() tables = %+v, want %+v", m.Tables, want)
+	}
+
+	if _, err := Build("mystore", "8.0.36", []string{"sales_order\tmany"}, nil); err == nil {
+		t.Error("Build() should fail on a non-numeric row count")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	expected := &Manifest{Server: "8.0.36", Tables: []Table{
+		{Name: "catalog_product_entity", Rows: 2048, Checksum: "1"},
+		{Name: "cms_page", Rows: 5, Checksum: "2"},
+		{Name: "sales_order", Rows: 12, Checksum: "3"},
+		{Name: "url_rewrite", Rows: 900, Checksum: "4"},
+	}}
+	actual := &Manifest{Server: "8.0.36", Tables: []Table{
+		{Name: "catalog_product_entity", Rows: 2048, Checksum: "1"},
+		{Name: "cms_page", Rows: 5, Checksum: "mangled"},
+		{Name: "sales_order", Rows: 7, Checksum: "3"},
+		{Name: "admin_user", Rows: 1, Checksum: "5"},
+	}}
+
+	got := Compare(expected, actual)
+	var tables []string
+	for _, p := range got {
+		tables = append(tables, p.Table)
+	}
+	if want := []string{"cms_page", "sales_order", "url_rewrite"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("Compare() tables = %q, want %q", tables, want)
+	}
+
+	// Checksums of another server version are not compared
+	actual.Server = "8.4.2"
+	if got := Compare(expected, actual); len(got) != 2 {
+		t.Errorf("Compare() across versions = %+v, want only the row count problems", got)
+	}
+}
+
+func TestManifest_SaveLoad(t *testing.T) {
+	path := ManifestPath(filepath.Join(t.TempDir(), "mystore.sql"))
+	m := &Manifest{Database: "mystore", Server: "8.0.36", Tables: []Table{{Name: "cms_page", Rows: 5, Checksum: "2"}}}
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Tables, m.Tables) || loaded.Server != m.Server {
+		t.Errorf("Load() = %+v, want %+v", loaded, m)
+	}
+}
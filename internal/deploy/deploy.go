@@ -0,0 +1,90 @@
+// Package deploy runs the deployment pipeline of a project on a remote
+// environment.
+//
+// Every step is a shell command run over SSH in the environment's project
+// path. Output is streamed as it comes, and the pipeline stops at the first
+// step that fails, so a broken build never reaches cache:flush.
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/remote"
+)
+
+// StepResult is the outcome of a pipeline step
+type StepResult struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Result is the outcome of a deployment
+type Result struct {
+	Steps    []StepResult
+	Duration time.Duration
+}
+
+// Failed returns the step that failed, nil when all steps succeeded
+func (r *Result) Failed() *StepResult {
+	for i := range r.Steps {
+		if r.Steps[i].Err != nil {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Runner runs deployment steps on an environment
+type Runner struct {
+	Env    *remote.Environment
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// OnStep is called before each step with its 1-based index
+	OnStep func(i, total int, step config.DeployStep)
+
+	// command builds the command of a step, Env.BuildRemoteCommand when nil
+	command func(run string) *exec.Cmd
+}
+
+// NewRunner returns a runner that streams to the terminal
+func NewRunner(env *remote.Environment) *Runner {
+	return &Runner{Env: env, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// Run runs the steps in order and stops at the first failure, which is
+// also returned as the error
+func (r *Runner) Run(steps []config.DeployStep) (*Result, error) {
+	build := r.command
+	if build == nil {
+		build = func(run string) *exec.Cmd { return r.Env.BuildRemoteCommand(run) }
+	}
+
+	result := &Result{}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	for i, step := range steps {
+		if r.OnStep != nil {
+			r.OnStep(i+1, len(steps), step)
+		}
+
+		cmd := build(step.Run)
+		cmd.Stdout = r.Stdout
+		cmd.Stderr = r.Stderr
+
+		stepStart := time.Now()
+		err := cmd.Run()
+		result.Steps = append(result.Steps, StepResult{Name: step.Name, Duration: time.Since(stepStart), Err: err})
+		if err != nil {
+			return result, fmt.Errorf("step '%s' failed: %w", step.Name, err)
+		}
+	}
+	return result, nil
+}
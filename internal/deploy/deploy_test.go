@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func localRunner(stdout *bytes.Buffer) *Runner {
+	return &Runner{
+		Stdout:  stdout,
+		Stderr:  stdout,
+		command: func(run string) *exec.Cmd { return exec.Command("sh", "-c", run) },
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	var out bytes.Buffer
+	r := localRunner(&out)
+
+	var seen []string
+	r.OnStep = func(i, total int, step config.DeployStep) {
+		seen = append(seen, step.Name)
+		if total != 2 {
+			t.Errorf("total = %d, want 2", total)
+		}
+	}
+
+	result, err := r.Run([]config.DeployStep{
+		{Name: "one", Run: "echo first"},
+		{Name: "two", Run: "echo second >&2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Failed() != nil {
+		t.Errorf("Failed() = %+v, want nil", result.Failed())
+	}
+	if len(result.Steps) != 2 || strings.Join(seen, ",") != "one,two" {
+		t.Errorf("steps = %+v, seen = %v", result.Steps, seen)
+	}
+	if out.String() != "first\nsecond\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestRunner_RunStopsAtFailure(t *testing.T) {
+	var out bytes.Buffer
+	r := localRunner(&out)
+
+	result, err := r.Run([]config.DeployStep{
+		{Name: "build", Run: "echo build"},
+		{Name: "migrate", Run: "exit 3"},
+		{Name: "flush", Run: "echo flush"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "migrate") {
+		t.Fatalf("err = %v, want a failure of migrate", err)
+	}
+	if len(result.Steps) != 2 {
+		t.Errorf("ran %d steps, want 2", len(result.Steps))
+	}
+	if failed := result.Failed(); failed == nil || failed.Name != "migrate" {
+		t.Errorf("Failed() = %+v, want migrate", failed)
+	}
+	if strings.Contains(out.String(), "flush") {
+		t.Error("steps after the failure should not run")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -16,6 +17,9 @@ import (
 	"qoliber/magebox/internal/platform"
 )
 
+// systemdResolvedConfDir is where MageBox installs its systemd-resolved drop-in
+const systemdResolvedConfDir = "/etc/systemd/resolved.conf.d"
+
 //go:embed templates/systemd-resolved.conf.tmpl
 var systemdResolvedTemplateEmbed string
 
@@ -30,11 +34,15 @@ type SystemdResolvedConfig struct {
 	Domains string
 }
 
-// DefaultSystemdResolvedConfig returns the default systemd-resolved configuration for the given TLD
-func DefaultSystemdResolvedConfig(tld string) SystemdResolvedConfig {
+// DefaultSystemdResolvedConfig returns the default systemd-resolved configuration for the given TLDs
+func DefaultSystemdResolvedConfig(tlds ...string) SystemdResolvedConfig {
+	domains := make([]string, len(tlds))
+	for i, tld := range tlds {
+		domains[i] = "~" + tld
+	}
 	return SystemdResolvedConfig{
 		DNS:     "127.0.0.2",
-		Domains: "~" + tld,
+		Domains: strings.Join(domains, " "),
 	}
 }
 
@@ -71,9 +79,79 @@ func NewDnsmasqManager(p *platform.Platform) *DnsmasqManager {
 
 // getTLD returns the configured TLD from global config
 func (m *DnsmasqManager) getTLD() string {
+	return m.getTLDs()[0]
+}
+
+// getTLDs returns the TLDs routed to localhost: the configured TLD and the
+// additional TLDs of the global config
+func (m *DnsmasqManager) getTLDs() []string {
 	homeDir, _ := os.UserHomeDir()
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
-	return globalCfg.GetTLD()
+	return globalCfg.GetTLDs()
+}
+
+// RouteTLD adds tld to the TLDs of the global config and reconfigures
+// dnsmasq when it is set up, so the domains of a project with its own TLD
+// resolve. It reports whether the TLD wasn't routed before. Only local TLDs
+// are routed for projects.
+func (m *DnsmasqManager) RouteTLD(tld string) (bool, error) {
+	if !config.LocalTLD(tld) {
+		return false, fmt.Errorf("not a local TLD: %s", tld)
+	}
+	homeDir, _ := os.UserHomeDir()
+	globalCfg, err := config.LoadGlobalConfig(homeDir)
+	if err != nil {
+		return false, err
+	}
+	if !globalCfg.AddTLD(tld) {
+		return false, nil
+	}
+	if err := config.SaveGlobalConfig(homeDir, globalCfg); err != nil {
+		return false, err
+	}
+
+	if !m.IsConfigured() {
+		return true, nil
+	}
+	if err := m.Configure(); err != nil {
+		return true, err
+	}
+	if m.IsRunning() {
+		return true, m.Restart()
+	}
+	return true, nil
+}
+
+// UnrouteTLD removes tld from the additional TLDs of the global config and
+// reconfigures dnsmasq when it is set up, once no project uses the TLD
+func (m *DnsmasqManager) UnrouteTLD(tld string) error {
+	homeDir, _ := os.UserHomeDir()
+	globalCfg, err := config.LoadGlobalConfig(homeDir)
+	if err != nil {
+		return err
+	}
+	if !globalCfg.RemoveTLD(tld) {
+		return nil
+	}
+	if err := config.SaveGlobalConfig(homeDir, globalCfg); err != nil {
+		return err
+	}
+
+	if !m.IsConfigured() {
+		return nil
+	}
+	if m.platform.Type == platform.Darwin {
+		if err := exec.Command("sudo", "rm", "-f", "/etc/resolver/"+config.NormalizeTLD(tld)).Run(); err != nil {
+			return fmt.Errorf("failed to remove the resolver of .%s: %w", tld, err)
+		}
+	}
+	if err := m.Configure(); err != nil {
+		return err
+	}
+	if m.IsRunning() {
+		return m.Restart()
+	}
+	return nil
 }
 
 // IsInstalled checks if dnsmasq is installed
@@ -94,7 +172,7 @@ func (m *DnsmasqManager) IsRunning() bool {
 	return cmd.Run() == nil
 }
 
-// Configure sets up dnsmasq to resolve *.test to localhost
+// Configure sets up dnsmasq to resolve the domains of every TLD to localhost
 func (m *DnsmasqManager) Configure() error {
 	// Create config directory if needed (requires sudo for system directories)
 	configDir := m.getConfigDir()
@@ -127,7 +205,7 @@ func (m *DnsmasqManager) Configure() error {
 		}
 	}
 
-	// On Linux with systemd-resolved, configure it to use dnsmasq for .test
+	// On Linux with systemd-resolved, configure it to use dnsmasq for the TLDs
 	if m.platform.Type == platform.Linux {
 		if err := m.setupSystemdResolved(); err != nil {
 			return fmt.Errorf("failed to setup systemd-resolved: %w", err)
@@ -200,19 +278,48 @@ func (m *DnsmasqManager) Remove() error {
 		}
 	}
 
-	// On macOS, also remove the resolver
+	// On macOS, also remove the resolvers
 	if m.platform.Type == platform.Darwin {
-		tld := m.getTLD()
-		resolverPath := "/etc/resolver/" + tld
-		if _, err := os.Stat(resolverPath); err == nil {
-			cmd := exec.Command("sudo", "rm", resolverPath)
-			_ = cmd.Run() // Ignore errors - resolver may not exist
+		for _, resolverPath := range m.ResolverConfigPaths() {
+			if _, err := os.Stat(resolverPath); err == nil {
+				cmd := exec.Command("sudo", "rm", resolverPath)
+				_ = cmd.Run() // Ignore errors - resolver may not exist
+			}
+		}
+	}
+
+	// On Linux, also remove the systemd-resolved drop-in
+	if m.platform.Type == platform.Linux {
+		resolvedPath := m.ResolverConfigPaths()[0]
+		if _, err := os.Stat(resolvedPath); err == nil {
+			if err := exec.Command("sudo", "rm", resolvedPath).Run(); err == nil {
+				_ = exec.Command("sudo", "systemctl", "restart", "systemd-resolved").Run()
+			}
 		}
 	}
 
 	return nil
 }
 
+// ResolverConfigPaths returns the OS resolver configuration MageBox installs:
+// an /etc/resolver/<tld> per TLD on macOS, the systemd-resolved drop-in on
+// Linux
+func (m *DnsmasqManager) ResolverConfigPaths() []string {
+	if m.platform.Type == platform.Darwin {
+		var paths []string
+		for _, tld := range m.getTLDs() {
+			paths = append(paths, "/etc/resolver/"+tld)
+		}
+		return paths
+	}
+	return []string{filepath.Join(systemdResolvedConfDir, "magebox.conf")}
+}
+
+// ConfigPath returns the path of the MageBox dnsmasq configuration
+func (m *DnsmasqManager) ConfigPath() string {
+	return m.getConfigPath()
+}
+
 // InstallCommand returns the command to install dnsmasq
 func (m *DnsmasqManager) InstallCommand() string {
 	switch m.platform.Type {
@@ -252,48 +359,54 @@ func (m *DnsmasqManager) getConfigPath() string {
 
 // generateConfig generates the dnsmasq configuration
 func (m *DnsmasqManager) generateConfig() string {
-	tld := m.getTLD()
+	tlds := m.getTLDs()
 
-	// On Linux, listen on 127.0.0.2 to avoid systemd-resolved conflicts
-	// On macOS, listen on 127.0.0.1 (used by /etc/resolver/<tld>)
-	if m.platform.Type == platform.Linux {
-		return fmt.Sprintf(`# MageBox DNS Configuration
-# Routes *.%s domains to localhost
+	zones := make([]string, len(tlds))
+	for i, tld := range tlds {
+		zones[i] = "*." + tld
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `# MageBox DNS Configuration
+# Routes %s domains to localhost
 # Generated by MageBox - do not edit manually
+`, strings.Join(zones, ", "))
 
+	for _, tld := range tlds {
+		fmt.Fprintf(&b, `
 # Route .%s TLD to localhost (IPv4 and IPv6)
 address=/%s/127.0.0.1
 address=/%s/::1
+`, tld, tld, tld)
+	}
 
+	if !slices.Contains(tlds, "localhost") {
+		b.WriteString(`
 # Additional local TLDs
 address=/localhost/127.0.0.1
 address=/localhost/::1
+`)
+	}
 
+	// On Linux, listen on 127.0.0.2 to avoid systemd-resolved conflicts
+	// On macOS, listen on 127.0.0.1 (used by /etc/resolver/<tld>)
+	if m.platform.Type == platform.Linux {
+		b.WriteString(`
 # Listen on 127.0.0.2 to avoid conflicts with systemd-resolved
-# systemd-resolved will forward .%s queries here
+# systemd-resolved will forward queries for the TLDs here
 listen-address=127.0.0.2
 port=53
 bind-interfaces
-`, tld, tld, tld, tld, tld)
+`)
+		return b.String()
 	}
 
-	// macOS config with configurable TLD
-	return fmt.Sprintf(`# MageBox DNS Configuration
-# Routes *.%s domains to localhost
-# Generated by MageBox - do not edit manually
-
-# Route .%s TLD to localhost (IPv4 and IPv6)
-address=/%s/127.0.0.1
-address=/%s/::1
-
-# Additional local TLDs
-address=/localhost/127.0.0.1
-address=/localhost/::1
-
+	b.WriteString(`
 # Security settings
 listen-address=127.0.0.1
 bind-interfaces
-`, tld, tld, tld, tld)
+`)
+	return b.String()
 }
 
 // writeConfigWithSudo writes config file using sudo
@@ -317,16 +430,24 @@ func (m *DnsmasqManager) writeConfigWithSudo(path, content string) error {
 	return cmd.Run()
 }
 
-// setupMacOSResolver sets up the macOS resolver for the configured TLD
+// setupMacOSResolver sets up a macOS resolver for every TLD
 func (m *DnsmasqManager) setupMacOSResolver() error {
-	tld := m.getTLD()
-
 	// Create /etc/resolver directory
 	cmd := exec.Command("sudo", "mkdir", "-p", "/etc/resolver")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to create resolver directory: %w", err)
 	}
 
+	for _, tld := range m.getTLDs() {
+		if err := m.writeMacOSResolver(tld); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMacOSResolver writes /etc/resolver/<tld>
+func (m *DnsmasqManager) writeMacOSResolver(tld string) error {
 	// Create resolver config for the TLD
 	resolverContent := "nameserver 127.0.0.1\n"
 
@@ -345,7 +466,7 @@ func (m *DnsmasqManager) setupMacOSResolver() error {
 
 	// Copy to /etc/resolver/<tld>
 	resolverPath := "/etc/resolver/" + tld
-	cmd = exec.Command("sudo", "cp", tmpPath, resolverPath)
+	cmd := exec.Command("sudo", "cp", tmpPath, resolverPath)
 	return cmd.Run()
 }
 
@@ -368,11 +489,11 @@ func (m *DnsmasqManager) TestResolution(domain string) bool {
 
 // Status returns the current dnsmasq status
 type DnsmasqStatus struct {
-	Installed  bool
-	Configured bool
-	Running    bool
-	TestDomain string
-	Resolving  bool
+	Installed  bool   `json:"installed"`
+	Configured bool   `json:"configured"`
+	Running    bool   `json:"running"`
+	TestDomain string `json:"test_domain"`
+	Resolving  bool   `json:"resolving"`
 }
 
 // GetStatus returns the current dnsmasq status
@@ -423,10 +544,10 @@ func (m *DnsmasqManager) enableDnsmasqConfDir() error {
 	return sedCmd.Run()
 }
 
-// setupSystemdResolved configures systemd-resolved to use dnsmasq for the configured TLD
+// setupSystemdResolved configures systemd-resolved to use dnsmasq for the configured TLDs
 // This is needed on modern Linux distros (Fedora, Ubuntu 18.04+) that use systemd-resolved
 func (m *DnsmasqManager) setupSystemdResolved() error {
-	tld := m.getTLD()
+	tlds := m.getTLDs()
 
 	// Check if systemd-resolved is running
 	cmd := exec.Command("systemctl", "is-active", "systemd-resolved")
@@ -436,14 +557,14 @@ func (m *DnsmasqManager) setupSystemdResolved() error {
 	}
 
 	// Create config directory
-	confDir := "/etc/systemd/resolved.conf.d"
+	confDir := systemdResolvedConfDir
 	cmd = exec.Command("sudo", "mkdir", "-p", confDir)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to create resolved.conf.d: %w", err)
 	}
 
 	// Generate resolved config from template
-	resolvedConfig, err := GenerateSystemdResolvedConfig(DefaultSystemdResolvedConfig(tld))
+	resolvedConfig, err := GenerateSystemdResolvedConfig(DefaultSystemdResolvedConfig(tlds...))
 	if err != nil {
 		return fmt.Errorf("failed to generate systemd-resolved config: %w", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/platform"
 )
 
@@ -100,6 +101,59 @@ func TestDnsmasqManager_generateConfig(t *testing.T) {
 	}
 }
 
+func TestDnsmasqManager_generateConfigTLDs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := config.SaveGlobalConfig(home, &config.GlobalConfig{TLD: "test", TLDs: []string{"acme", "localhost"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewDnsmasqManager(&platform.Platform{Type: platform.Linux})
+	conf := m.generateConfig()
+
+	for _, line := range []string{
+		"# Routes *.test, *.acme, *.localhost domains to localhost",
+		"address=/test/127.0.0.1",
+		"address=/acme/127.0.0.1",
+		"address=/acme/::1",
+		"address=/localhost/127.0.0.1",
+	} {
+		if !strings.Contains(conf, line) {
+			t.Errorf("config should contain %q:\n%s", line, conf)
+		}
+	}
+	if n := strings.Count(conf, "address=/localhost/127.0.0.1"); n != 1 {
+		t.Errorf("localhost routed %d times, want once", n)
+	}
+}
+
+func TestDnsmasqManager_ResolverConfigPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := config.SaveGlobalConfig(home, &config.GlobalConfig{TLD: "test", TLDs: []string{"acme"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	darwin := NewDnsmasqManager(&platform.Platform{Type: platform.Darwin}).ResolverConfigPaths()
+	if want := []string{"/etc/resolver/test", "/etc/resolver/acme"}; strings.Join(darwin, " ") != strings.Join(want, " ") {
+		t.Errorf("macOS ResolverConfigPaths() = %v, want %v", darwin, want)
+	}
+	linux := NewDnsmasqManager(&platform.Platform{Type: platform.Linux}).ResolverConfigPaths()
+	if len(linux) != 1 || !strings.HasSuffix(linux[0], "resolved.conf.d/magebox.conf") {
+		t.Errorf("Linux ResolverConfigPaths() = %v, want the systemd-resolved drop-in", linux)
+	}
+}
+
+func TestDefaultSystemdResolvedConfig(t *testing.T) {
+	cfg := DefaultSystemdResolvedConfig("test", "acme")
+	if cfg.DNS != "127.0.0.2" {
+		t.Errorf("DNS = %q, want 127.0.0.2", cfg.DNS)
+	}
+	if cfg.Domains != "~test ~acme" {
+		t.Errorf("Domains = %q, want ~test ~acme", cfg.Domains)
+	}
+}
+
 func TestDnsmasqManager_InstallCommand(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -145,6 +146,39 @@ func (m *HostsManager) DomainExists(domain string) bool {
 	return false
 }
 
+// MissingDomains returns the domains that have no entry in the hosts file.
+// Entries outside the MageBox section, e.g. added by hand, count as present.
+func (m *HostsManager) MissingDomains(domains []string) ([]string, error) {
+	content, err := os.ReadFile(m.hostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	present := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		for _, host := range parts[1:] {
+			present[strings.ToLower(host)] = true
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, d := range domains {
+		if !present[strings.ToLower(d)] {
+			missing = append(missing, d)
+		}
+	}
+	return missing, nil
+}
+
 // extractMageBoxDomains extracts MageBox-managed domains from hosts content
 func (m *HostsManager) extractMageBoxDomains(content string) []string {
 	domains := make([]string, 0)
@@ -190,28 +224,57 @@ func (m *HostsManager) writeDomains(currentContent string, domains []string) err
 		newContent += MageBoxEndMarker + "\n"
 	}
 
-	// Write to temp file first
+	// Write to a synced temp file first, readable by everyone like /etc/hosts
 	tmpFile, err := os.CreateTemp("", "hosts-*")
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
 
 	if _, err := tmpFile.WriteString(newContent); err != nil {
 		tmpFile.Close()
-		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmpFile.Chmod(0644); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to set temp file permissions: %w", err)
+	}
 	tmpFile.Close()
 
-	// Copy to /etc/hosts with sudo
-	cmd := exec.Command("sudo", "cp", tmpPath, m.hostsFile)
+	return m.replaceHostsFile(tmpPath)
+}
+
+// replaceHostsFile installs a prepared hosts file with sudo.
+// The file is staged next to the hosts file and renamed over it, so an
+// interrupted run never leaves a truncated /etc/hosts behind. Symlinks (macOS
+// /etc/hosts -> /private/etc/hosts) are resolved so the link is kept intact.
+// When the hosts file cannot be renamed over (e.g. bind-mounted in a
+// container), it falls back to copying in place.
+func (m *HostsManager) replaceHostsFile(tmpPath string) error {
+	target := m.hostsFile
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	staged := target + ".magebox-new"
+
+	if err := exec.Command("sudo", "cp", tmpPath, staged).Run(); err == nil {
+		// sync flushes the staged copy before the rename makes it visible
+		_ = exec.Command("sync").Run()
+		if err := exec.Command("sudo", "mv", "-f", staged, target).Run(); err == nil {
+			return nil
+		}
+		_ = exec.Command("sudo", "rm", "-f", staged).Run()
+	}
+
+	cmd := exec.Command("sudo", "cp", tmpPath, target)
 	if err := cmd.Run(); err != nil {
-		os.Remove(tmpPath)
 		return fmt.Errorf("failed to update hosts file (sudo required): %w", err)
 	}
-
-	os.Remove(tmpPath)
 	return nil
 }
 
@@ -0,0 +1,109 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+)
+
+// resolveTimeout bounds the lookup of a single domain in dnsmasq mode
+const resolveTimeout = 2 * time.Second
+
+// SyncResult is the outcome of reconciling the DNS entries of domains
+type SyncResult struct {
+	Mode       string   `json:"mode"`
+	Domains    []string `json:"domains"`
+	Added      []string `json:"added,omitempty"`
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// Syncer reconciles the DNS entries of project domains with the configured
+// DNS mode. The hosts file is only rewritten when entries are actually
+// missing or stale, so a project start doesn't ask for sudo every time.
+type Syncer struct {
+	hosts   *HostsManager
+	resolve func(domain string) bool
+}
+
+// NewSyncer creates a new DNS syncer
+func NewSyncer(p *platform.Platform) *Syncer {
+	return &Syncer{
+		hosts:   NewHostsManager(p),
+		resolve: resolvesToLoopback,
+	}
+}
+
+// Sync makes sure the domains resolve to this machine. In hosts mode the
+// missing domains are added to the hosts file. In dnsmasq mode nothing is
+// written: the wildcard resolution of every domain is verified and the
+// domains that don't resolve, e.g. outside the TLD, are reported.
+func (s *Syncer) Sync(globalCfg *config.GlobalConfig, domains []string) (*SyncResult, error) {
+	result := &SyncResult{Mode: globalCfg.DNSMode, Domains: domains}
+
+	if globalCfg.UseHosts() {
+		result.Mode = "hosts"
+		missing, err := s.hosts.MissingDomains(domains)
+		if err != nil {
+			return result, err
+		}
+		if len(missing) == 0 {
+			return result, nil
+		}
+		if err := s.hosts.AddDomains(missing); err != nil {
+			return result, err
+		}
+		result.Added = missing
+		return result, nil
+	}
+
+	for _, domain := range domains {
+		if !s.resolve(domain) {
+			result.Unresolved = append(result.Unresolved, domain)
+		}
+	}
+	return result, nil
+}
+
+// Remove drops the MageBox hosts entries of the domains in hosts mode. The
+// hosts file is left alone when none of them is present.
+func (s *Syncer) Remove(globalCfg *config.GlobalConfig, domains []string) error {
+	if !globalCfg.UseHosts() {
+		return nil
+	}
+
+	managed, err := s.hosts.ListDomains()
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(managed))
+	for _, d := range managed {
+		present[d] = true
+	}
+	for _, d := range domains {
+		if present[d] {
+			return s.hosts.RemoveDomains(domains)
+		}
+	}
+	return nil
+}
+
+// resolvesToLoopback reports whether the system resolver answers a domain
+// with a loopback address
+func resolvesToLoopback(domain string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, domain)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+)
+
+// writeHostsFile points a hosts manager at a temporary hosts file
+func writeHostsFile(t *testing.T, m *HostsManager, content string) {
+	t.Helper()
+	m.hostsFile = filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(m.hostsFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHostsManager_MissingDomains(t *testing.T) {
+	m := NewHostsManager(&platform.Platform{Type: platform.Linux})
+	writeHostsFile(t, m, `127.0.0.1 localhost
+127.0.0.1 Manual.test # added by hand
+# 127.0.0.1 commented.test
+# >>> MageBox managed hosts - do not edit manually >>>
+127.0.0.1 mystore.test
+# <<< MageBox managed hosts <<<
+`)
+
+	missing, err := m.MissingDomains([]string{"mystore.test", "manual.test", "commented.test", "new.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"commented.test", "new.test"}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("MissingDomains() = %v, want %v", missing, want)
+	}
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	s := NewSyncer(&platform.Platform{Type: platform.Linux})
+	writeHostsFile(t, s.hosts, "127.0.0.1 localhost\n127.0.0.1 mystore.test\n")
+	s.resolve = func(domain string) bool { return domain == "mystore.test" }
+
+	// Hosts mode with every domain present doesn't touch the file
+	result, err := s.Sync(&config.GlobalConfig{DNSMode: "hosts"}, []string{"mystore.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Mode != "hosts" || len(result.Added) != 0 {
+		t.Errorf("hosts mode: got %+v, want nothing added", result)
+	}
+
+	// dnsmasq mode reports the domains that don't resolve
+	result, err = s.Sync(&config.GlobalConfig{DNSMode: "dnsmasq"}, []string{"mystore.test", "mystore.local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Unresolved, []string{"mystore.local"}) {
+		t.Errorf("dnsmasq mode: Unresolved = %v, want [mystore.local]", result.Unresolved)
+	}
+
+	// Removing domains without MageBox entries leaves the file alone
+	if err := s.Remove(&config.GlobalConfig{DNSMode: "hosts"}, []string{"mystore.test"}); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+)
+
+// PlatformAMD64 is the platform of images run under emulation on arm64 hosts
+const PlatformAMD64 = "linux/amd64"
+
+// arm64Since maps the repositories of service images whose older tags have no
+// arm64 build to the first version that has one. Every other image MageBox
+// uses is multi-arch.
+var arm64Since = map[string]string{
+	"mysql":                  "8.0",
+	"percona/percona-server": "8.0",
+	"mariadb":                "10.2",
+	"elasticsearch":          "7.8",
+}
+
+// ImagePlatform returns the platform an image has to run with on a host of
+// the given architecture (GOARCH), or "" when it runs natively
+func ImagePlatform(image, arch string) string {
+	if arch != "arm64" {
+		return ""
+	}
+	repo, tag, ok := strings.Cut(image, ":")
+	if !ok {
+		return ""
+	}
+	tag, _, _ = strings.Cut(tag, "@")
+	since, known := arm64Since[repo]
+	if !known {
+		return ""
+	}
+	version := versionPrefix(tag)
+	if version == "" || compareVersionStrings(version, since) >= 0 {
+		return ""
+	}
+	return PlatformAMD64
+}
+
+// versionPrefix returns the leading numeric version of an image tag, e.g.
+// "5.7" of "5.7-debian"
+func versionPrefix(tag string) string {
+	end := 0
+	for end < len(tag) && (tag[end] >= '0' && tag[end] <= '9' || tag[end] == '.') {
+		end++
+	}
+	return strings.Trim(tag[:end], ".")
+}
+
+// hostArch returns the architecture Docker runs images with. An amd64 build
+// of MageBox on Apple Silicon still talks to an arm64 Docker.
+func (g *ComposeGenerator) hostArch() string {
+	if g.arch != "" {
+		return g.arch
+	}
+	if g.platform.IsAppleSilicon {
+		return "arm64"
+	}
+	return g.platform.Arch
+}
+
+// platformOverrides returns the services.<name>.platform settings of the
+// projects by compose service name
+func platformOverrides(configs []*config.Config) map[string]string {
+	overrides := make(map[string]string)
+	for _, cfg := range configs {
+		for name, svc := range composeServiceConfigs(cfg) {
+			if svc.Platform != "" {
+				overrides[name] = svc.Platform
+			}
+		}
+	}
+	return overrides
+}
+
+// composeServiceConfigs returns the enabled Docker services of a project by
+// compose service name
+func composeServiceConfigs(cfg *config.Config) map[string]*config.ServiceConfig {
+	s := &cfg.Services
+	services := make(map[string]*config.ServiceConfig)
+	versioned := func(prefix string, svc *config.ServiceConfig) {
+		services[prefix+strings.ReplaceAll(svc.Version, ".", "")] = svc
+	}
+	if s.HasMySQL() {
+		versioned("mysql", s.MySQL)
+	}
+	if s.HasMariaDB() {
+		versioned("mariadb", s.MariaDB)
+	}
+	if s.HasPercona() {
+		versioned("percona", s.Percona)
+	}
+	if s.HasRedis() {
+		services["redis"] = s.Redis
+	}
+	if s.HasValkey() {
+		services["valkey"] = s.Valkey
+	}
+	if s.HasOpenSearch() {
+		versioned("opensearch", s.OpenSearch)
+	}
+	if s.HasOpenSearchDashboards() {
+		services[OpenSearchDashboardsService(s.OpenSearch.Version)] = s.OpenSearchDashboards
+	}
+	if s.HasElasticsearch() {
+		versioned("elasticsearch", s.Elasticsearch)
+	}
+	if s.HasMeilisearch() {
+		services["meilisearch"] = s.Meilisearch
+	}
+	if s.HasTypesense() {
+		services["typesense"] = s.Typesense
+	}
+	if s.HasRabbitMQ() {
+		services["rabbitmq"] = s.RabbitMQ
+	}
+	if s.HasMailpit() {
+		services["mailpit"] = s.Mailpit
+	}
+	if s.HasVarnish() {
+		services["varnish"] = s.Varnish
+	}
+	if s.HasPhpMyAdmin() {
+		services["phpmyadmin"] = s.PhpMyAdmin
+	}
+	if s.HasComposerMirror() {
+		services["composer-mirror"] = s.ComposerMirror
+	}
+	return services
+}
+
+// applyPlatforms sets the platform of every service that has no build for
+// the host architecture, or that a project pins with services.<name>.platform
+func (g *ComposeGenerator) applyPlatforms(compose *ComposeConfig, overrides map[string]string) {
+	arch := g.hostArch()
+	for name, svc := range compose.Services {
+		if platform, ok := overrides[name]; ok {
+			svc.Platform = platform
+		} else {
+			svc.Platform = ImagePlatform(svc.Image, arch)
+		}
+		compose.Services[name] = svc
+	}
+}
+
+// PlatformWarnings returns a warning for every service of the project that
+// runs under emulation because its image has no build for the host
+// architecture. Services with a platform set in the config are left out.
+func (g *ComposeGenerator) PlatformWarnings(cfg *config.Config) []string {
+	arch := g.hostArch()
+	overrides := platformOverrides([]*config.Config{cfg})
+
+	var warnings []string
+	for name, image := range g.ProjectImages(cfg) {
+		if _, ok := overrides[name]; ok {
+			continue
+		}
+		if platform := ImagePlatform(image, arch); platform != "" {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s has no %s image and runs as %s under emulation, which is slow and may crash; use a newer version or set services.<name>.platform to silence this",
+				image, arch, platform))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
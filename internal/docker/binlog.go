@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binlogRetentionDays is how long database containers keep binary logs.
+// Point-in-time restores can only reach back to the oldest kept binlog.
+const binlogRetentionDays = 7
+
+// BinlogServerArgs returns the server arguments that enable row-based binary
+// logging for point-in-time restores
+func BinlogServerArgs(dbType, version string) string {
+	args := []string{"--log-bin=mysql-bin", "--server-id=1", "--binlog-format=ROW"}
+	if (dbType == "mysql" || dbType == "percona") && version != "5.7" {
+		// expire_logs_days was removed in MySQL 8.4
+		args = append(args, fmt.Sprintf("--binlog-expire-logs-seconds=%d", binlogRetentionDays*24*3600))
+	} else {
+		args = append(args, fmt.Sprintf("--expire-logs-days=%d", binlogRetentionDays))
+	}
+	return strings.Join(args, " ")
+}
+
+// DumpPositionFlag returns the mysqldump flag that writes the binlog position
+// of the dump as a comment in its header
+func DumpPositionFlag(dbType, version string) string {
+	if (dbType == "mysql" || dbType == "percona") && version != "5.7" {
+		// --master-data is deprecated since 8.0.26 and removed in 8.4
+		return "--source-data=2"
+	}
+	return "--master-data=2"
+}
+
+// BinlogTool returns the binlog reader shipped in the database image
+func BinlogTool(dbType string) string {
+	if dbType == "mariadb" {
+		return "mariadb-binlog"
+	}
+	return "mysqlbinlog"
+}
+
+// BinlogPosition is the binlog coordinate a snapshot was taken at
+type BinlogPosition struct {
+	File      string    `json:"file"`
+	Position  int64     `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// binlogPositionRe matches the CHANGE MASTER TO / CHANGE REPLICATION SOURCE TO
+// comment mysqldump writes with --master-data=2 / --source-data=2
+var binlogPositionRe = regexp.MustCompile(`(?:MASTER|SOURCE)_LOG_FILE='([^']+)',\s*(?:MASTER|SOURCE)_LOG_POS=(\d+)`)
+
+// binlogHeaderLines bounds how far into a dump the position comment is searched
+const binlogHeaderLines = 100
+
+// ParseBinlogPosition reads the binlog position from the header of a dump
+func ParseBinlogPosition(r io.Reader) (*BinlogPosition, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for i := 0; i < binlogHeaderLines && scanner.Scan(); i++ {
+		m := binlogPositionRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		pos, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binlog position %q", m[2])
+		}
+		return &BinlogPosition{File: m[1], Position: pos}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("dump does not contain a binlog position")
+}
+
+// BinlogsFrom returns the binlog files from start onwards, in order.
+// all is the list from SHOW BINARY LOGS.
+func BinlogsFrom(all []string, start string) ([]string, error) {
+	for i, name := range all {
+		if name == start {
+			return all[i:], nil
+		}
+	}
+	return nil, fmt.Errorf("binlog %s is no longer available (binlogs are kept for %d days)", start, binlogRetentionDays)
+}
+
+// BinlogReplayScript returns a bash script, run inside the database
+// container, that replays the changes to dbName from the snapshot position up
+// to stop (UTC, the container time zone) into the mysql client
+func BinlogReplayScript(dbType, dir string, files []string, startPos int64, stop time.Time, dbName, user, password string) string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = shellQuote(strings.TrimSuffix(dir, "/") + "/" + f)
+	}
+	return fmt.Sprintf("set -o pipefail; %s --start-position=%d --stop-datetime=%s --database=%s %s | mysql -u%s -p%s %s",
+		BinlogTool(dbType), startPos, shellQuote(stop.UTC().Format("2006-01-02 15:04:05")),
+		shellQuote(dbName), strings.Join(paths, " "),
+		shellQuote(user), shellQuote(password), shellQuote(dbName))
+}
+
+// shellQuote quotes a string for sh -c
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBinlogServerArgs(t *testing.T) {
+	tests := []struct {
+		dbType, version string
+		want            string
+	}{
+		{"mysql", "8.0", "--log-bin=mysql-bin --server-id=1 --binlog-format=ROW --binlog-expire-logs-seconds=604800"},
+		{"mysql", "8.4", "--log-bin=mysql-bin --server-id=1 --binlog-format=ROW --binlog-expire-logs-seconds=604800"},
+		{"mysql", "5.7", "--log-bin=mysql-bin --server-id=1 --binlog-format=ROW --expire-logs-days=7"},
+		{"mariadb", "10.6", "--log-bin=mysql-bin --server-id=1 --binlog-format=ROW --expire-logs-days=7"},
+	}
+
+	for _, tt := range tests {
+		if got := BinlogServerArgs(tt.dbType, tt.version); got != tt.want {
+			t.Errorf("BinlogServerArgs(%q, %q) = %q, want %q", tt.dbType, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestDumpPositionFlag(t *testing.T) {
+	if got := DumpPositionFlag("mysql", "8.4"); got != "--source-data=2" {
+		t.Errorf("DumpPositionFlag(mysql, 8.4) = %q", got)
+	}
+	if got := DumpPositionFlag("percona", "8.0"); got != "--source-data=2" {
+		t.Errorf("DumpPositionFlag(percona, 8.0) = %q", got)
+	}
+	if got := DumpPositionFlag("mysql", "5.7"); got != "--master-data=2" {
+		t.Errorf("DumpPositionFlag(mysql, 5.7) = %q", got)
+	}
+	if got := DumpPositionFlag("mariadb", "11.4"); got != "--master-data=2" {
+		t.Errorf("DumpPositionFlag(mariadb, 11.4) = %q", got)
+	}
+}
+
+func TestParseBinlogPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		dump     string
+		wantFile string
+		wantPos  int64
+		wantErr  bool
+	}{
+		{
+			name:     "mysql 8.4",
+			dump:     "-- MySQL dump 10.13\n--\n-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='mysql-bin.000003', SOURCE_LOG_POS=157;\n",
+			wantFile: "mysql-bin.000003",
+			wantPos:  157,
+		},
+		{
+			name:     "mariadb",
+			dump:     "-- MariaDB dump 10.19\n-- CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000012', MASTER_LOG_POS=4521;\n",
+			wantFile: "mysql-bin.000012",
+			wantPos:  4521,
+		},
+		{
+			name:    "no position",
+			dump:    "-- MySQL dump 10.13\nCREATE TABLE `a` (id int);\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := ParseBinlogPosition(strings.NewReader(tt.dump))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", pos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pos.File != tt.wantFile || pos.Position != tt.wantPos {
+				t.Errorf("got %s:%d, want %s:%d", pos.File, pos.Position, tt.wantFile, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestBinlogsFrom(t *testing.T) {
+	all := []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003"}
+
+	got, err := BinlogsFrom(all, "mysql-bin.000002")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, ",") != "mysql-bin.000002,mysql-bin.000003" {
+		t.Errorf("BinlogsFrom() = %v", got)
+	}
+
+	if _, err := BinlogsFrom(all, "mysql-bin.000000"); err == nil {
+		t.Error("expected error for purged binlog")
+	}
+}
+
+func TestBinlogReplayScript(t *testing.T) {
+	stop := time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC)
+	got := BinlogReplayScript("mariadb", "/var/lib/mysql/", []string{"mysql-bin.000002", "mysql-bin.000003"},
+		4521, stop, "shop", "root", "magebox")
+
+	want := "set -o pipefail; mariadb-binlog --start-position=4521 --stop-datetime='2024-05-01 14:30:00' --database='shop' " +
+		"'/var/lib/mysql/mysql-bin.000002' '/var/lib/mysql/mysql-bin.000003' | mysql -u'root' -p'magebox' 'shop'"
+	if got != want {
+		t.Errorf("BinlogReplayScript() =\n%s\nwant\n%s", got, want)
+	}
+}
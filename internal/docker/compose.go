@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,8 +9,11 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/execctx"
+	"qoliber/magebox/internal/fileutil"
 	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/varnish"
 	"qoliber/magebox/internal/verbose"
@@ -74,22 +78,21 @@ func getComposeCommand() []string {
 // BuildComposeCmd builds a compose command with the given arguments
 // It auto-detects whether to use "docker compose" (V2) or "docker-compose" (standalone)
 func BuildComposeCmd(composeFile string, args ...string) *exec.Cmd {
-	baseCmd := getComposeCommand()
-	var fullArgs []string
-	var cmd *exec.Cmd
+	name, fullArgs := composeCommandLine(composeFile, args...)
+	cmd := exec.Command(name, fullArgs...)
+	verbose.Command(cmd.Path, cmd.Args[1:]...)
+	return cmd
+}
 
+// composeCommandLine returns the binary and arguments of a compose command
+func composeCommandLine(composeFile string, args ...string) (string, []string) {
+	baseCmd := getComposeCommand()
 	if len(baseCmd) == 1 {
 		// docker-compose -f file args...
-		fullArgs = append([]string{"-f", composeFile}, args...)
-		cmd = exec.Command(baseCmd[0], fullArgs...)
-	} else {
-		// docker compose -f file args...
-		fullArgs = append([]string{baseCmd[1], "-f", composeFile}, args...)
-		cmd = exec.Command(baseCmd[0], fullArgs...)
+		return baseCmd[0], append([]string{"-f", composeFile}, args...)
 	}
-
-	verbose.Command(cmd.Path, cmd.Args[1:]...)
-	return cmd
+	// docker compose -f file args...
+	return baseCmd[0], append([]string{baseCmd[1], "-f", composeFile}, args...)
 }
 
 // buildComposeCmd is an alias for internal use
@@ -116,10 +119,29 @@ const (
 
 // ComposeGenerator generates Docker Compose configurations for global services
 type ComposeGenerator struct {
-	platform   *platform.Platform
-	composeDir string
+	platform          *platform.Platform
+	composeDir        string
+	imageLock         *ImageLock
+	imageLockServices []string // Compose services the image lock applies to
+	lowMemory         bool
+	arch              string // Docker architecture, the host's unless set
+
+	ports          *PortRegistry     // Reserved host ports, nil for the conventional ones
+	portMoves      []PortMove        // Services moved off taken ports by the last generation
+	serviceRunning func(string) bool // Whether a service runs, the Docker controller's unless set
 }
 
+// Low-memory mode defaults, used where a project sets no memory of its own
+const (
+	lowMemorySearchHeap = "512m"
+	lowMemoryBufferPool = "128M"
+	defaultSearchHeap   = "1g"
+)
+
+// ComposeProjectName is the Compose project of the shared services, which
+// prefixes their container and volume names
+const ComposeProjectName = "magebox"
+
 // ComposeConfig represents a Docker Compose configuration
 type ComposeConfig struct {
 	Name     string                    `yaml:"name,omitempty"`
@@ -132,6 +154,7 @@ type ComposeConfig struct {
 type ComposeService struct {
 	ContainerName string            `yaml:"container_name,omitempty"`
 	Image         string            `yaml:"image"`
+	Platform      string            `yaml:"platform,omitempty"`
 	Ports         []string          `yaml:"ports,omitempty"`
 	Environment   map[string]string `yaml:"environment,omitempty"`
 	Volumes       []string          `yaml:"volumes,omitempty"`
@@ -174,6 +197,7 @@ func NewComposeGenerator(p *platform.Platform) *ComposeGenerator {
 	return &ComposeGenerator{
 		platform:   p,
 		composeDir: filepath.Join(p.MageBoxDir(), "docker"),
+		ports:      LoadPorts(p),
 	}
 }
 
@@ -183,8 +207,65 @@ func (g *ComposeGenerator) GenerateGlobalServices(configs []*config.Config) erro
 		return fmt.Errorf("failed to create compose directory: %w", err)
 	}
 
+	if err := g.reservePorts(configs); err != nil {
+		return fmt.Errorf("failed to reserve ports: %w", err)
+	}
+
+	compose := g.RenderGlobalServices(configs)
+	requiredServices := g.collectRequiredServices(configs)
+
+	// Generate the VCL configuration Varnish mounts
+	if requiredServices.varnish != nil {
+		vclGen := varnish.NewVCLGenerator(g.platform)
+		if err := vclGen.Generate(configs); err != nil {
+			return fmt.Errorf("failed to generate VCL: %w", err)
+		}
+	}
+	if requiredServices.composerMirror {
+		if err := g.writeComposerMirrorConfig(); err != nil {
+			return fmt.Errorf("failed to write composer mirror config: %w", err)
+		}
+	}
+	if err := g.writeDBServerConfigs(requiredServices); err != nil {
+		return fmt.Errorf("failed to write database server config: %w", err)
+	}
+
+	// Write compose file
+	data, err := yaml.Marshal(compose)
+	if err != nil {
+		return fmt.Errorf("failed to marshal compose config: %w", err)
+	}
+
+	composeFile := filepath.Join(g.composeDir, "docker-compose.yml")
+	if err := fileutil.WriteGenerated(composeFile, data, 0644, fileutil.ValidateYAML); err != nil {
+		return fmt.Errorf("failed to write compose file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadComposeFile reads the compose file last generated for the shared
+// services; a missing file has no services
+func (g *ComposeGenerator) LoadComposeFile() (*ComposeConfig, error) {
+	compose := &ComposeConfig{Services: make(map[string]ComposeService)}
+	data, err := os.ReadFile(g.ComposeFilePath())
+	if os.IsNotExist(err) {
+		return compose, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, compose); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", g.ComposeFilePath(), err)
+	}
+	return compose, nil
+}
+
+// RenderGlobalServices builds the shared services compose configuration for
+// the projects without writing anything
+func (g *ComposeGenerator) RenderGlobalServices(configs []*config.Config) ComposeConfig {
 	compose := ComposeConfig{
-		Name:     "magebox",
+		Name:     ComposeProjectName,
 		Services: make(map[string]ComposeService),
 		Networks: map[string]ComposeNetwork{
 			"magebox": {Driver: "bridge"},
@@ -237,6 +318,14 @@ func (g *ComposeGenerator) GenerateGlobalServices(configs []*config.Config) erro
 		compose.Volumes[fmt.Sprintf("mariadb%s_data", strings.ReplaceAll(version, ".", ""))] = ComposeVolume{}
 	}
 
+	// Add Percona Server services. They only have their version port, the
+	// standard port belongs to the default MySQL or MariaDB.
+	for version, svcCfg := range requiredServices.percona {
+		serviceName := fmt.Sprintf("percona%s", strings.ReplaceAll(version, ".", ""))
+		compose.Services[serviceName] = g.getPerconaService(svcCfg)
+		compose.Volumes[fmt.Sprintf("percona%s_data", strings.ReplaceAll(version, ".", ""))] = ComposeVolume{}
+	}
+
 	// Add Redis or Valkey (cache service)
 	if requiredServices.valkey {
 		compose.Services["valkey"] = g.getValkeyService()
@@ -253,6 +342,11 @@ func (g *ComposeGenerator) GenerateGlobalServices(configs []*config.Config) erro
 		compose.Volumes[fmt.Sprintf("opensearch%s_plugins", strings.ReplaceAll(version, ".", ""))] = ComposeVolume{}
 	}
 
+	// Add OpenSearch Dashboards next to the OpenSearch versions that want them
+	for version, port := range requiredServices.opensearchDashboards {
+		compose.Services[OpenSearchDashboardsService(version)] = g.getOpenSearchDashboardsService(version, port)
+	}
+
 	// Add Elasticsearch services
 	for version, svcCfg := range requiredServices.elasticsearch {
 		serviceName := fmt.Sprintf("elasticsearch%s", strings.ReplaceAll(version, ".", ""))
@@ -262,15 +356,28 @@ func (g *ComposeGenerator) GenerateGlobalServices(configs []*config.Config) erro
 		compose.Volumes[fmt.Sprintf("elasticsearch%s_plugins", strings.ReplaceAll(version, ".", ""))] = ComposeVolume{}
 	}
 
+	// Add alternative search engines if needed
+	if requiredServices.meilisearch != "" {
+		compose.Services["meilisearch"] = g.getMeilisearchService(requiredServices.meilisearch)
+		compose.Volumes["meilisearch_data"] = ComposeVolume{}
+	}
+	if requiredServices.typesense != "" {
+		compose.Services["typesense"] = g.getTypesenseService(requiredServices.typesense)
+		compose.Volumes["typesense_data"] = ComposeVolume{}
+	}
+
 	// Add RabbitMQ if needed
 	if requiredServices.rabbitmq {
 		compose.Services["rabbitmq"] = g.getRabbitMQService()
 		compose.Volumes["rabbitmq_data"] = ComposeVolume{}
 	}
 
-	// Always add Mailpit for local development safety
-	// This prevents accidental emails to real addresses
-	compose.Services["mailpit"] = g.getMailpitService()
+	// Add Mailpit for local development safety, this prevents accidental
+	// emails to real addresses. Low-memory mode only runs it for projects that
+	// haven't disabled it.
+	if !g.lowMemory || requiredServices.mailpit {
+		compose.Services["mailpit"] = g.getMailpitService()
+	}
 
 	// Add Elasticvue if enabled in global config
 	if globalCfg != nil && globalCfg.Elasticvue {
@@ -288,40 +395,190 @@ func (g *ComposeGenerator) GenerateGlobalServices(configs []*config.Config) erro
 
 	// Add Varnish if needed
 	if requiredServices.varnish != nil {
-		// Generate VCL configuration first
-		vclGen := varnish.NewVCLGenerator(g.platform)
-		if err := vclGen.Generate(configs); err != nil {
-			return fmt.Errorf("failed to generate VCL: %w", err)
-		}
 		compose.Services["varnish"] = g.getVarnishService(requiredServices.varnish)
 	}
 
-	// Write compose file
-	data, err := yaml.Marshal(compose)
-	if err != nil {
-		return fmt.Errorf("failed to marshal compose config: %w", err)
+	// Add the Composer mirror if any project uses it
+	if requiredServices.composerMirror {
+		compose.Services["composer-mirror"] = g.getComposerMirrorService()
+		compose.Volumes["composer_mirror_data"] = ComposeVolume{}
 	}
 
-	composeFile := filepath.Join(g.composeDir, "docker-compose.yml")
-	if err := os.WriteFile(composeFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write compose file: %w", err)
+	// Add the agents of the profilers projects load
+	if requiredServices.blackfire {
+		compose.Services[BlackfireService] = g.getBlackfireAgentService(globalCfg)
+	}
+	if requiredServices.tideways {
+		compose.Services[TidewaysService] = g.getTidewaysDaemonService(globalCfg)
 	}
 
-	return nil
+	// Run images without a build for the host architecture under emulation
+	g.applyPlatforms(&compose, platformOverrides(configs))
+
+	// Pin images to the digests recorded in the project lock file
+	g.applyImageLock(&compose)
+
+	return compose
+}
+
+// SetLowMemory makes generated services use less memory: smaller search
+// heaps and database buffer pools, and no Mailpit unless a project uses it
+func (g *ComposeGenerator) SetLowMemory(enabled bool) {
+	g.lowMemory = enabled
+}
+
+// SetImageLock sets the image lock of a project, used to pin the images of
+// its compose services to digests. Services of other projects in the shared
+// compose file keep their tags.
+func (g *ComposeGenerator) SetImageLock(lock *ImageLock, services []string) {
+	g.imageLock = lock
+	g.imageLockServices = services
+}
+
+// applyImageLock rewrites the locked image references of the lock's services
+// to their pinned digests
+func (g *ComposeGenerator) applyImageLock(compose *ComposeConfig) {
+	if g.imageLock == nil {
+		return
+	}
+	for _, name := range g.imageLockServices {
+		svc, ok := compose.Services[name]
+		if !ok {
+			continue
+		}
+		svc.Image = g.imageLock.Pin(svc.Image)
+		compose.Services[name] = svc
+	}
+}
+
+// ProjectImages returns the image reference for every Docker service the
+// project uses, keyed by compose service name
+func (g *ComposeGenerator) ProjectImages(cfg *config.Config) map[string]string {
+	images := make(map[string]string)
+	for name, svc := range g.projectServices(cfg) {
+		images[name] = svc.Image
+	}
+	return images
+}
+
+// ProjectPorts returns the host ports of every Docker service the project
+// uses, keyed by compose service name
+func (g *ComposeGenerator) ProjectPorts(cfg *config.Config) map[string][]int {
+	ports := make(map[string][]int)
+	for name, svc := range g.projectServices(cfg) {
+		for _, mapping := range svc.Ports {
+			if port, err := strconv.Atoi(hostPort(mapping)); err == nil {
+				ports[name] = append(ports[name], port)
+			}
+		}
+	}
+	return ports
+}
+
+// ProjectVolumes returns the named volumes of every Docker service the
+// project uses, keyed by compose service name
+func (g *ComposeGenerator) ProjectVolumes(cfg *config.Config) map[string][]string {
+	volumes := make(map[string][]string)
+	for name, svc := range g.projectServices(cfg) {
+		for _, mount := range svc.Volumes {
+			source := strings.SplitN(mount, ":", 2)[0]
+			// Bind mounts are paths, named volumes are plain names
+			if source == "" || strings.ContainsAny(source, "/.~") {
+				continue
+			}
+			volumes[name] = append(volumes[name], source)
+		}
+	}
+	return volumes
+}
+
+// hostPort returns the host side of a compose port mapping such as
+// "33080:3306" or "127.0.0.1:8025:8025"
+func hostPort(mapping string) string {
+	parts := strings.Split(mapping, ":")
+	if len(parts) < 2 {
+		return parts[0]
+	}
+	return parts[len(parts)-2]
+}
+
+// projectServices returns every Docker service the project uses, keyed by
+// compose service name
+func (g *ComposeGenerator) projectServices(cfg *config.Config) map[string]ComposeService {
+	rs := g.collectRequiredServices([]*config.Config{cfg})
+	services := make(map[string]ComposeService)
+
+	for version, svcCfg := range rs.mysql {
+		services["mysql"+strings.ReplaceAll(version, ".", "")] = g.getMySQLService(svcCfg, false)
+	}
+	for version, svcCfg := range rs.mariadb {
+		services["mariadb"+strings.ReplaceAll(version, ".", "")] = g.getMariaDBService(svcCfg, false)
+	}
+	for version, svcCfg := range rs.percona {
+		services["percona"+strings.ReplaceAll(version, ".", "")] = g.getPerconaService(svcCfg)
+	}
+	if rs.valkey {
+		services["valkey"] = g.getValkeyService()
+	} else if rs.redis {
+		services["redis"] = g.getRedisService()
+	}
+	for version, svcCfg := range rs.opensearch {
+		services["opensearch"+strings.ReplaceAll(version, ".", "")] = g.getOpenSearchService(svcCfg, false)
+	}
+	for version, port := range rs.opensearchDashboards {
+		services[OpenSearchDashboardsService(version)] = g.getOpenSearchDashboardsService(version, port)
+	}
+	for version, svcCfg := range rs.elasticsearch {
+		services["elasticsearch"+strings.ReplaceAll(version, ".", "")] = g.getElasticsearchService(svcCfg, false)
+	}
+	if rs.meilisearch != "" {
+		services["meilisearch"] = g.getMeilisearchService(rs.meilisearch)
+	}
+	if rs.typesense != "" {
+		services["typesense"] = g.getTypesenseService(rs.typesense)
+	}
+	if rs.rabbitmq {
+		services["rabbitmq"] = g.getRabbitMQService()
+	}
+	if rs.varnish != nil {
+		services["varnish"] = g.getVarnishService(rs.varnish)
+	}
+	if rs.composerMirror {
+		services["composer-mirror"] = g.getComposerMirrorService()
+	}
+	if rs.blackfire {
+		services[BlackfireService] = g.getBlackfireAgentService(nil)
+	}
+	if rs.tideways {
+		services[TidewaysService] = g.getTidewaysDaemonService(nil)
+	}
+	services["mailpit"] = g.getMailpitService()
+
+	return services
 }
 
 // requiredServices tracks which services are needed
 type requiredServices struct {
-	mysql         map[string]*config.ServiceConfig
-	mariadb       map[string]*config.ServiceConfig
-	redis         bool
-	valkey        bool
-	opensearch    map[string]*config.ServiceConfig
-	elasticsearch map[string]*config.ServiceConfig
-	rabbitmq      bool
-	varnish       *config.ServiceConfig
-	phpmyadmin    *config.ServiceConfig
-	// Note: Mailpit is always enabled for local dev safety, not tracked here
+	mysql          map[string]*config.ServiceConfig
+	mariadb        map[string]*config.ServiceConfig
+	percona        map[string]*config.ServiceConfig
+	redis          bool
+	valkey         bool
+	opensearch     map[string]*config.ServiceConfig
+	elasticsearch  map[string]*config.ServiceConfig
+	meilisearch    string // image tag, empty when unused
+	typesense      string // image tag, empty when unused
+	rabbitmq       bool
+	varnish        *config.ServiceConfig
+	phpmyadmin     *config.ServiceConfig
+	mailpit        bool // only consulted in low-memory mode
+	composerMirror bool
+	blackfire      bool
+	tideways       bool
+
+	// opensearchDashboards maps OpenSearch versions to the Dashboards host
+	// port, 0 for the default port of the version
+	opensearchDashboards map[string]int
 }
 
 // firstDBHost returns the container name of the first available database service.
@@ -347,6 +604,10 @@ func (rs *requiredServices) firstDBHost(defaultMySQL, defaultMariaDB string) str
 	for version := range rs.mariadb {
 		return fmt.Sprintf("magebox-mariadb-%s", version)
 	}
+	// Then try any Percona Server version
+	for version := range rs.percona {
+		return fmt.Sprintf("magebox-percona-%s", version)
+	}
 	return ""
 }
 
@@ -355,16 +616,25 @@ func (g *ComposeGenerator) collectRequiredServices(configs []*config.Config) req
 	rs := requiredServices{
 		mysql:         make(map[string]*config.ServiceConfig),
 		mariadb:       make(map[string]*config.ServiceConfig),
+		percona:       make(map[string]*config.ServiceConfig),
 		opensearch:    make(map[string]*config.ServiceConfig),
 		elasticsearch: make(map[string]*config.ServiceConfig),
+
+		opensearchDashboards: make(map[string]int),
 	}
 
 	for _, cfg := range configs {
 		if cfg.Services.HasMySQL() {
-			rs.mysql[cfg.Services.MySQL.Version] = cfg.Services.MySQL
+			version := cfg.Services.MySQL.Version
+			rs.mysql[version] = sharedDBService(rs.mysql[version], cfg.Services.MySQL)
 		}
 		if cfg.Services.HasMariaDB() {
-			rs.mariadb[cfg.Services.MariaDB.Version] = cfg.Services.MariaDB
+			version := cfg.Services.MariaDB.Version
+			rs.mariadb[version] = sharedDBService(rs.mariadb[version], cfg.Services.MariaDB)
+		}
+		if cfg.Services.HasPercona() {
+			version := cfg.Services.Percona.Version
+			rs.percona[version] = sharedDBService(rs.percona[version], cfg.Services.Percona)
 		}
 		if cfg.Services.HasRedis() {
 			rs.redis = true
@@ -375,19 +645,39 @@ func (g *ComposeGenerator) collectRequiredServices(configs []*config.Config) req
 		if cfg.Services.HasOpenSearch() {
 			rs.opensearch[cfg.Services.OpenSearch.Version] = cfg.Services.OpenSearch
 		}
+		if cfg.Services.HasOpenSearchDashboards() {
+			rs.opensearchDashboards[cfg.Services.OpenSearch.Version] = cfg.Services.OpenSearchDashboards.Port
+		}
 		if cfg.Services.HasElasticsearch() {
 			rs.elasticsearch[cfg.Services.Elasticsearch.Version] = cfg.Services.Elasticsearch
 		}
+		if cfg.Services.HasMeilisearch() {
+			rs.meilisearch = cfg.Services.MeilisearchImageTag()
+		}
+		if cfg.Services.HasTypesense() {
+			rs.typesense = cfg.Services.TypesenseImageTag()
+		}
 		if cfg.Services.HasRabbitMQ() {
 			rs.rabbitmq = true
 		}
-		// Note: Mailpit is always enabled, no need to track
+		if !cfg.Services.MailpitDisabled() {
+			rs.mailpit = true
+		}
 		if cfg.Services.HasPhpMyAdmin() {
 			rs.phpmyadmin = cfg.Services.PhpMyAdmin
 		}
 		if cfg.Services.HasVarnish() {
 			rs.varnish = cfg.Services.Varnish
 		}
+		if cfg.Services.HasComposerMirror() {
+			rs.composerMirror = true
+		}
+		switch cfg.ActiveProfiler() {
+		case config.ProfilerBlackfire:
+			rs.blackfire = true
+		case config.ProfilerTideways:
+			rs.tideways = true
+		}
 	}
 
 	return rs
@@ -396,7 +686,7 @@ func (g *ComposeGenerator) collectRequiredServices(configs []*config.Config) req
 // getMySQLService returns a MySQL service configuration
 func (g *ComposeGenerator) getMySQLService(svcCfg *config.ServiceConfig, addStandardPort bool) ComposeService {
 	version := svcCfg.Version
-	port := g.getMySQLPort(version)
+	port := g.ports.DBPort("mysql", version)
 
 	env := map[string]string{
 		"MYSQL_ROOT_PASSWORD": DefaultDBRootPassword,
@@ -422,7 +712,7 @@ func (g *ComposeGenerator) getMySQLService(svcCfg *config.ServiceConfig, addStan
 		volumes = append(volumes, customCnf+":/etc/mysql/conf.d/custom.cnf:ro")
 	}
 
-	return ComposeService{
+	svc := ComposeService{
 		ContainerName: fmt.Sprintf("magebox-mysql-%s", version),
 		Image:         fmt.Sprintf("mysql:%s", version),
 		Ports:         ports,
@@ -430,6 +720,7 @@ func (g *ComposeGenerator) getMySQLService(svcCfg *config.ServiceConfig, addStan
 		Volumes:       volumes,
 		Networks:      []string{"magebox"},
 		Restart:       "unless-stopped",
+		Command:       g.dbServerArgs("mysql", svcCfg),
 		HealthCheck: &HealthCheck{
 			Test:     []string{"CMD", "mysqladmin", "ping", "-h", "localhost", "-uroot", "-p" + DefaultDBRootPassword},
 			Interval: "10s",
@@ -437,12 +728,14 @@ func (g *ComposeGenerator) getMySQLService(svcCfg *config.ServiceConfig, addStan
 			Retries:  5,
 		},
 	}
+	g.mountDBServerConfig(&svc, "mysql", svcCfg, "/etc/mysql/conf.d/magebox.cnf")
+	return svc
 }
 
 // getMariaDBService returns a MariaDB service configuration
 func (g *ComposeGenerator) getMariaDBService(svcCfg *config.ServiceConfig, addStandardPort bool) ComposeService {
 	version := svcCfg.Version
-	port := g.getMariaDBPort(version)
+	port := g.ports.DBPort("mariadb", version)
 
 	env := map[string]string{
 		"MYSQL_ROOT_PASSWORD": DefaultDBRootPassword,
@@ -468,7 +761,7 @@ func (g *ComposeGenerator) getMariaDBService(svcCfg *config.ServiceConfig, addSt
 		volumes = append(volumes, customCnf+":/etc/mysql/conf.d/custom.cnf:ro")
 	}
 
-	return ComposeService{
+	svc := ComposeService{
 		ContainerName: fmt.Sprintf("magebox-mariadb-%s", version),
 		Image:         fmt.Sprintf("mariadb:%s", version),
 		Ports:         ports,
@@ -476,6 +769,7 @@ func (g *ComposeGenerator) getMariaDBService(svcCfg *config.ServiceConfig, addSt
 		Volumes:       volumes,
 		Networks:      []string{"magebox"},
 		Restart:       "unless-stopped",
+		Command:       g.dbServerArgs("mariadb", svcCfg),
 		HealthCheck: &HealthCheck{
 			Test:     []string{"CMD", "healthcheck.sh", "--connect", "--innodb_initialized"},
 			Interval: "10s",
@@ -483,14 +777,68 @@ func (g *ComposeGenerator) getMariaDBService(svcCfg *config.ServiceConfig, addSt
 			Retries:  5,
 		},
 	}
+	g.mountDBServerConfig(&svc, "mariadb", svcCfg, "/etc/mysql/conf.d/magebox.cnf")
+	return svc
 }
 
+// getPerconaService returns a Percona Server service configuration. Percona
+// Server is a drop-in MySQL replacement, so it is set up like MySQL.
+func (g *ComposeGenerator) getPerconaService(svcCfg *config.ServiceConfig) ComposeService {
+	version := svcCfg.Version
+	port := g.ports.DBPort("percona", version)
+
+	env := map[string]string{
+		"MYSQL_ROOT_PASSWORD": DefaultDBRootPassword,
+	}
+
+	volumes := []string{
+		fmt.Sprintf("percona%s_data:/var/lib/mysql", strings.ReplaceAll(version, ".", "")),
+	}
+
+	// Mount custom Percona config if it exists
+	customCnf := filepath.Join(g.platform.MageBoxDir(), "docker", "percona-custom.cnf")
+	if _, err := os.Stat(customCnf); err == nil {
+		volumes = append(volumes, customCnf+":/etc/my.cnf.d/custom.cnf:ro")
+	}
+
+	args := g.dbServerArgs("percona", svcCfg)
+	// The image has no buffer pool variable like the MySQL and MariaDB ones
+	if _, set := svcCfg.DBServerConfig()["innodb_buffer_pool_size"]; svcCfg.Memory != "" && !set {
+		args += " --innodb-buffer-pool-size=" + svcCfg.Memory
+	}
+
+	svc := ComposeService{
+		ContainerName: fmt.Sprintf("magebox-percona-%s", version),
+		Image:         fmt.Sprintf("percona/percona-server:%s", version),
+		Ports:         []string{fmt.Sprintf("%d:3306", port)},
+		Environment:   env,
+		Volumes:       volumes,
+		Networks:      []string{"magebox"},
+		Restart:       "unless-stopped",
+		Command:       args,
+		HealthCheck: &HealthCheck{
+			Test:     []string{"CMD", "mysqladmin", "ping", "-h", "localhost", "-uroot", "-p" + DefaultDBRootPassword},
+			Interval: "10s",
+			Timeout:  "5s",
+			Retries:  5,
+		},
+	}
+	g.mountDBServerConfig(&svc, "percona", svcCfg, "/etc/my.cnf.d/magebox.cnf")
+	return svc
+}
+
+// RedisDatabases is the number of databases the shared Redis/Valkey container
+// provides, enough for every project to get its own cache, page cache and
+// session database
+const RedisDatabases = 96
+
 // getRedisService returns a Redis service configuration
 func (g *ComposeGenerator) getRedisService() ComposeService {
 	return ComposeService{
 		ContainerName: "magebox-redis",
 		Image:         "redis:7-alpine",
-		Ports:         []string{"6379:6379"},
+		Command:       fmt.Sprintf("redis-server --databases %d", RedisDatabases),
+		Ports:         []string{fmt.Sprintf("%d:6379", g.servicePort(CachePortName, redisPort))},
 		Networks:      []string{"magebox"},
 		Restart:       "unless-stopped",
 		HealthCheck: &HealthCheck{
@@ -508,7 +856,8 @@ func (g *ComposeGenerator) getValkeyService() ComposeService {
 	return ComposeService{
 		ContainerName: "magebox-valkey",
 		Image:         "valkey/valkey:8-alpine",
-		Ports:         []string{"6379:6379"},
+		Command:       fmt.Sprintf("valkey-server --databases %d", RedisDatabases),
+		Ports:         []string{fmt.Sprintf("%d:6379", g.servicePort(CachePortName, redisPort))},
 		Networks:      []string{"magebox"},
 		Restart:       "unless-stopped",
 		HealthCheck: &HealthCheck{
@@ -520,17 +869,43 @@ func (g *ComposeGenerator) getValkeyService() ComposeService {
 	}
 }
 
+// dbServerArgs returns the server args of a database container. Low-memory
+// mode shrinks the buffer pool unless the project sets its own memory or
+// innodb_buffer_pool_size, and
+// turns off the MySQL performance schema.
+func (g *ComposeGenerator) dbServerArgs(dbType string, svcCfg *config.ServiceConfig) string {
+	args := BinlogServerArgs(dbType, svcCfg.Version)
+	if !g.lowMemory {
+		return args
+	}
+	if _, set := svcCfg.DBServerConfig()["innodb_buffer_pool_size"]; svcCfg.Memory == "" && !set {
+		args += " --innodb-buffer-pool-size=" + lowMemoryBufferPool
+	}
+	if dbType == "mysql" || dbType == "percona" {
+		args += " --performance-schema=OFF"
+	}
+	return args
+}
+
+// searchHeap returns the JVM heap of a search container, 1g by default and
+// 512m in low-memory mode
+func (g *ComposeGenerator) searchHeap(svcCfg *config.ServiceConfig) string {
+	if svcCfg.Memory != "" {
+		return svcCfg.Memory
+	}
+	if g.lowMemory {
+		return lowMemorySearchHeap
+	}
+	return defaultSearchHeap
+}
+
 // getOpenSearchService returns an OpenSearch service configuration
 func (g *ComposeGenerator) getOpenSearchService(svcCfg *config.ServiceConfig, addStandardPort bool) ComposeService {
 	version := svcCfg.Version
 	imageVersion := ResolveOpenSearchVersion(version)
-	port := GetOpenSearchPort(imageVersion)
+	port := g.servicePort(SearchServiceName("opensearch", version), GetOpenSearchPort(imageVersion))
 
-	// Default to 1GB if not specified
-	memory := "1g"
-	if svcCfg.Memory != "" {
-		memory = svcCfg.Memory
-	}
+	memory := g.searchHeap(svcCfg)
 
 	heapSize := fmt.Sprintf("-Xms%s -Xmx%s", memory, memory)
 
@@ -563,13 +938,9 @@ func (g *ComposeGenerator) getOpenSearchService(svcCfg *config.ServiceConfig, ad
 func (g *ComposeGenerator) getElasticsearchService(svcCfg *config.ServiceConfig, addStandardPort bool) ComposeService {
 	version := svcCfg.Version
 	imageVersion := ResolveElasticsearchVersion(version)
-	port := GetElasticsearchPort(imageVersion)
+	port := g.servicePort(SearchServiceName("elasticsearch", version), GetElasticsearchPort(imageVersion))
 
-	// Default to 1GB if not specified
-	memory := "1g"
-	if svcCfg.Memory != "" {
-		memory = svcCfg.Memory
-	}
+	memory := g.searchHeap(svcCfg)
 
 	heapSize := fmt.Sprintf("-Xms%s -Xmx%s", memory, memory)
 
@@ -597,14 +968,51 @@ func (g *ComposeGenerator) getElasticsearchService(svcCfg *config.ServiceConfig,
 	}
 }
 
+// getMeilisearchService returns a Meilisearch service configuration
+func (g *ComposeGenerator) getMeilisearchService(tag string) ComposeService {
+	return ComposeService{
+		ContainerName: "magebox-meilisearch",
+		Image:         fmt.Sprintf("getmeili/meilisearch:%s", tag),
+		Ports:         []string{fmt.Sprintf("%d:7700", config.MeilisearchPort)},
+		Environment: map[string]string{
+			"MEILI_ENV":        "development",
+			"MEILI_MASTER_KEY": config.DefaultMeilisearchKey,
+		},
+		Volumes: []string{
+			"meilisearch_data:/meili_data",
+		},
+		Networks: []string{"magebox"},
+		Restart:  "unless-stopped",
+	}
+}
+
+// getTypesenseService returns a Typesense service configuration
+func (g *ComposeGenerator) getTypesenseService(tag string) ComposeService {
+	return ComposeService{
+		ContainerName: "magebox-typesense",
+		Image:         fmt.Sprintf("typesense/typesense:%s", tag),
+		Ports:         []string{fmt.Sprintf("%d:8108", config.TypesensePort)},
+		Environment: map[string]string{
+			"TYPESENSE_API_KEY":     config.DefaultTypesenseAPIKey,
+			"TYPESENSE_DATA_DIR":    "/data",
+			"TYPESENSE_ENABLE_CORS": "true",
+		},
+		Volumes: []string{
+			"typesense_data:/data",
+		},
+		Networks: []string{"magebox"},
+		Restart:  "unless-stopped",
+	}
+}
+
 // getRabbitMQService returns a RabbitMQ service configuration
 func (g *ComposeGenerator) getRabbitMQService() ComposeService {
 	return ComposeService{
 		ContainerName: "magebox-rabbitmq",
 		Image:         "rabbitmq:3-management-alpine",
 		Ports: []string{
-			"5672:5672",
-			"15672:15672",
+			fmt.Sprintf("%d:5672", g.servicePort(RabbitMQPortName, rabbitMQPort)),
+			fmt.Sprintf("%d:15672", g.servicePort(RabbitMQManagementPortName, rabbitMQManagementPort)),
 		},
 		Environment: map[string]string{
 			"RABBITMQ_DEFAULT_USER": DefaultRabbitMQUser,
@@ -624,8 +1032,8 @@ func (g *ComposeGenerator) getMailpitService() ComposeService {
 		ContainerName: "magebox-mailpit",
 		Image:         "axllent/mailpit:latest",
 		Ports: []string{
-			"1025:1025",
-			"8025:8025",
+			fmt.Sprintf("%d:1025", g.servicePort(MailpitSMTPPortName, mailpitSMTPPort)),
+			fmt.Sprintf("%d:8025", g.servicePort(MailpitWebPortName, mailpitWebPort)),
 		},
 		Networks: []string{"magebox"},
 		Restart:  "unless-stopped",
@@ -701,7 +1109,7 @@ func (g *ComposeGenerator) getVarnishService(svcCfg *config.ServiceConfig) Compo
 		ContainerName: "magebox-varnish",
 		Image:         fmt.Sprintf("varnish:%s", version),
 		Ports: []string{
-			"6081:80",
+			fmt.Sprintf("%d:80", varnish.Port),
 			"6082:6082",
 		},
 		Environment: map[string]string{
@@ -725,34 +1133,6 @@ func (g *ComposeGenerator) getVarnishService(svcCfg *config.ServiceConfig) Compo
 	}
 }
 
-// Port mapping functions to avoid conflicts
-func (g *ComposeGenerator) getMySQLPort(version string) int {
-	ports := map[string]int{
-		"5.7": 33057,
-		"8.0": 33080,
-		"8.4": 33084,
-	}
-	if port, ok := ports[version]; ok {
-		return port
-	}
-	return 33080 // default
-}
-
-func (g *ComposeGenerator) getMariaDBPort(version string) int {
-	ports := map[string]int{
-		"10.4":  33104,
-		"10.5":  33105,
-		"10.6":  33106,
-		"10.11": 33111,
-		"11.0":  33110,
-		"11.4":  33114,
-	}
-	if port, ok := ports[version]; ok {
-		return port
-	}
-	return 33106 // default
-}
-
 // normalizeSearchVersion extracts major.minor from a version string like "2.19.4".
 // If the input does not contain at least two dot-separated parts, it is returned unchanged.
 func normalizeSearchVersion(version string) string {
@@ -869,16 +1249,44 @@ func (g *ComposeGenerator) ComposeFilePath() string {
 // DockerController manages Docker Compose operations
 type DockerController struct {
 	composeFile string
+	ctx         context.Context
+	timeout     time.Duration
 }
 
-// NewDockerController creates a new Docker controller
+// NewDockerController creates a new Docker controller using the default
+// context and docker timeout
 func NewDockerController(composeFile string) *DockerController {
-	return &DockerController{composeFile: composeFile}
+	ctx, timeouts := execctx.Defaults()
+	return &DockerController{composeFile: composeFile, ctx: ctx, timeout: timeouts.Docker}
+}
+
+// WithContext returns a copy of the controller whose commands are cancelled with ctx
+func (c *DockerController) WithContext(ctx context.Context) *DockerController {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// compose builds a compose command bound to the controller context and timeout
+func (c *DockerController) compose(args ...string) *execctx.Cmd {
+	name, fullArgs := composeCommandLine(c.composeFile, args...)
+	cmd := execctx.Command(c.ctx, c.timeout, "docker", name, fullArgs...)
+	verbose.Command(cmd.Path, cmd.Args[1:]...)
+	return cmd
+}
+
+// composeInteractive builds a compose command for interactive use; it is
+// cancelled with the controller context but has no timeout
+func (c *DockerController) composeInteractive(args ...string) *execctx.Cmd {
+	name, fullArgs := composeCommandLine(c.composeFile, args...)
+	cmd := execctx.Command(c.ctx, 0, "docker", name, fullArgs...)
+	verbose.Command(cmd.Path, cmd.Args[1:]...)
+	return cmd
 }
 
 // Up starts all services
 func (c *DockerController) Up() error {
-	cmd := buildComposeCmd(c.composeFile, "up", "-d", "--remove-orphans")
+	cmd := c.compose("up", "-d", "--remove-orphans")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -891,7 +1299,7 @@ func (c *DockerController) UpServices(serviceNames []string) error {
 		return c.Up()
 	}
 	args := append([]string{"up", "-d"}, serviceNames...)
-	cmd := buildComposeCmd(c.composeFile, args...)
+	cmd := c.compose(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -899,7 +1307,15 @@ func (c *DockerController) UpServices(serviceNames []string) error {
 
 // Down stops all services
 func (c *DockerController) Down() error {
-	cmd := buildComposeCmd(c.composeFile, "down")
+	cmd := c.compose("down")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// DownWithVolumes stops all services and removes their named volumes
+func (c *DockerController) DownWithVolumes() error {
+	cmd := c.compose("down", "--volumes", "--remove-orphans")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -907,7 +1323,7 @@ func (c *DockerController) Down() error {
 
 // StartService starts a specific service
 func (c *DockerController) StartService(serviceName string) error {
-	cmd := buildComposeCmd(c.composeFile, "up", "-d", serviceName)
+	cmd := c.compose("up", "-d", serviceName)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -915,14 +1331,39 @@ func (c *DockerController) StartService(serviceName string) error {
 
 // StopService stops a specific service
 func (c *DockerController) StopService(serviceName string) error {
-	cmd := buildComposeCmd(c.composeFile, "stop", serviceName)
+	cmd := c.compose("stop", serviceName)
+	return cmd.Run()
+}
+
+// RemoveService stops a service and removes its container, so its volumes
+// can be removed
+func (c *DockerController) RemoveService(serviceName string) error {
+	cmd := c.compose("rm", "--stop", "--force", serviceName)
 	return cmd.Run()
 }
 
+// RemoveVolumes removes named volumes of the shared services, given by their
+// name in the compose file
+func (c *DockerController) RemoveVolumes(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	args := []string{"rm"}
+	for _, name := range names {
+		args = append(args, ComposeProjectName+"_"+name)
+	}
+	cmd := execctx.Command(c.ctx, c.timeout, "docker", "volume", args...)
+	verbose.Command(cmd.Path, cmd.Args[1:]...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // IsServiceRunning checks if a service is running
 func (c *DockerController) IsServiceRunning(serviceName string) bool {
 	// First try docker compose
-	cmd := buildComposeCmd(c.composeFile, "ps", "-q", serviceName)
+	cmd := c.compose("ps", "-q", serviceName)
 	output, err := cmd.Output()
 	if err == nil && len(strings.TrimSpace(string(output))) > 0 {
 		return true
@@ -932,15 +1373,15 @@ func (c *DockerController) IsServiceRunning(serviceName string) bool {
 	// Container names follow pattern: magebox-{service}-{version} or magebox-{service}
 	// Service names like mysql80, elasticsearch8170 map to containers magebox-mysql-8.0, magebox-elasticsearch-8.17.0
 	containerPattern := serviceNameToContainerPattern(serviceName)
-	cmd = exec.Command("docker", "ps", "-q", "--filter", fmt.Sprintf("name=%s", containerPattern))
-	output, err = cmd.Output()
+	psCmd := execctx.Command(c.ctx, c.timeout, "docker", "docker", "ps", "-q", "--filter", fmt.Sprintf("name=%s", containerPattern))
+	output, err = psCmd.Output()
 	return err == nil && len(strings.TrimSpace(string(output))) > 0
 }
 
 // serviceNameToContainerPattern converts a service name to a container name pattern
 func serviceNameToContainerPattern(serviceName string) string {
 	// Handle versioned services: mysql80 -> magebox-mysql-8.0, elasticsearch8170 -> magebox-elasticsearch-8.17.0
-	prefixes := []string{"mysql", "mariadb", "opensearch", "elasticsearch"}
+	prefixes := []string{"mysql", "mariadb", "percona", "opensearch", "elasticsearch"}
 	for _, prefix := range prefixes {
 		if strings.HasPrefix(serviceName, prefix) {
 			versionPart := strings.TrimPrefix(serviceName, prefix)
@@ -1023,7 +1464,7 @@ func insertVersionDots(v string) string {
 // Exec executes a command in a running container
 func (c *DockerController) Exec(serviceName string, command ...string) error {
 	args := append([]string{"exec", serviceName}, command...)
-	cmd := buildComposeCmd(c.composeFile, args...)
+	cmd := c.composeInteractive(args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -1033,25 +1474,104 @@ func (c *DockerController) Exec(serviceName string, command ...string) error {
 // ExecSilent executes a command in a running container without terminal attachment
 func (c *DockerController) ExecSilent(serviceName string, command ...string) error {
 	args := append([]string{"exec", "-T", serviceName}, command...)
-	cmd := buildComposeCmd(c.composeFile, args...)
+	cmd := c.composeInteractive(args...)
 	return cmd.Run()
 }
 
 // CreateDatabase creates a database in the MySQL/MariaDB service
 func (c *DockerController) CreateDatabase(serviceName, dbName string) error {
-	cmd := buildComposeCmd(c.composeFile, "exec", "-T", serviceName,
+	cmd := c.compose("exec", "-T", serviceName,
 		"mysql", "-uroot", "-p"+DefaultDBRootPassword, "-e", fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", dbName))
 	return cmd.Run()
 }
 
+// DropDatabase drops a database in the MySQL/MariaDB service
+func (c *DockerController) DropDatabase(serviceName, dbName string) error {
+	cmd := c.compose("exec", "-T", serviceName,
+		"mysql", "-uroot", "-p"+DefaultDBRootPassword, "-e", fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", dbName))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to drop database %s: %s", dbName, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ListDatabases returns the databases of the MySQL/MariaDB service
+func (c *DockerController) ListDatabases(serviceName string) ([]string, error) {
+	cmd := c.compose("exec", "-T", serviceName,
+		"mysql", "-uroot", "-p"+DefaultDBRootPassword, "-N", "-e", "SHOW DATABASES")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases of %s: %w", serviceName, err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
 // DatabaseExists checks if a database exists
 func (c *DockerController) DatabaseExists(serviceName, dbName string) bool {
-	cmd := buildComposeCmd(c.composeFile, "exec", "-T", serviceName,
+	cmd := c.compose("exec", "-T", serviceName,
 		"mysql", "-uroot", "-p"+DefaultDBRootPassword, "-e", fmt.Sprintf("SHOW DATABASES LIKE '%s'", dbName))
 	output, err := cmd.Output()
 	return err == nil && strings.Contains(string(output), dbName)
 }
 
+// EnsureDatabaseUser creates or updates a project database user with full
+// privileges on its database. It is idempotent and runs on every start, since
+// the shared database container predates the project.
+func (c *DockerController) EnsureDatabaseUser(serviceName, dbName, user, password string) error {
+	cmd := c.compose("exec", "-T", serviceName,
+		"mysql", "-uroot", "-p"+DefaultDBRootPassword, "-e", DatabaseUserSQL(dbName, user, password))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to provision database user %s: %s", user, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// DatabaseUserSQL returns the statements that create (or update) a user with
+// all privileges on a single database
+func DatabaseUserSQL(dbName, user, password string) string {
+	u, p := sqlQuote(user), sqlQuote(password)
+	return fmt.Sprintf("CREATE USER IF NOT EXISTS %s@'%%' IDENTIFIED BY %s; "+
+		"ALTER USER %s@'%%' IDENTIFIED BY %s; "+
+		"GRANT ALL PRIVILEGES ON `%s`.* TO %s@'%%'; "+
+		"FLUSH PRIVILEGES;",
+		u, p, u, p, strings.ReplaceAll(dbName, "`", "``"), u)
+}
+
+// sqlQuote quotes a string literal for MySQL
+func sqlQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// EnsureRedisUser creates or updates a Redis/Valkey ACL user with access to
+// all keys. ACLs are not persisted by the container, so this runs on every start.
+func (c *DockerController) EnsureRedisUser(serviceName, cliBinary, user, password string) error {
+	cmd := c.compose("exec", "-T", serviceName,
+		cliBinary, "ACL", "SETUSER", user, "on", "resetpass", ">"+password, "~*", "&*", "+@all")
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "OK") {
+		return fmt.Errorf("failed to provision %s ACL user %s: %s", serviceName, user, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// EnsureRabbitMQUser creates or updates a RabbitMQ user with full permissions on the default vhost
+func (c *DockerController) EnsureRabbitMQUser(user, password string) error {
+	add := c.compose("exec", "-T", "rabbitmq", "rabbitmqctl", "add_user", user, password)
+	if err := add.Run(); err != nil {
+		// User exists already, make sure the password matches the config
+		change := c.compose("exec", "-T", "rabbitmq", "rabbitmqctl", "change_password", user, password)
+		if output, err := change.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to provision RabbitMQ user %s: %s", user, strings.TrimSpace(string(output)))
+		}
+	}
+	perms := c.compose("exec", "-T", "rabbitmq", "rabbitmqctl", "set_permissions", "-p", "/", user, ".*", ".*", ".*")
+	if output, err := perms.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to grant RabbitMQ permissions to %s: %s", user, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // GenerateDefaultServices generates a default docker-compose.yml with common services
 // This is used during bootstrap when no projects exist yet
 func (g *ComposeGenerator) GenerateDefaultServices(globalCfg *config.GlobalConfig) error {
@@ -1140,7 +1660,7 @@ func (g *ComposeGenerator) GenerateDefaultServices(globalCfg *config.GlobalConfi
 	}
 
 	composeFile := filepath.Join(g.composeDir, "docker-compose.yml")
-	if err := os.WriteFile(composeFile, data, 0644); err != nil {
+	if err := fileutil.WriteGenerated(composeFile, data, 0644, fileutil.ValidateYAML); err != nil {
 		return fmt.Errorf("failed to write compose file: %w", err)
 	}
 
@@ -1149,7 +1669,7 @@ func (g *ComposeGenerator) GenerateDefaultServices(globalCfg *config.GlobalConfi
 
 // GetRunningServices returns a list of running services
 func (c *DockerController) GetRunningServices() ([]string, error) {
-	cmd := buildComposeCmd(c.composeFile, "ps", "--services", "--filter", "status=running")
+	cmd := c.compose("ps", "--services", "--filter", "status=running")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -1166,7 +1686,7 @@ func (c *DockerController) GetRunningServices() ([]string, error) {
 
 // GetAllServices returns a list of all defined services
 func (c *DockerController) GetAllServices() ([]string, error) {
-	cmd := buildComposeCmd(c.composeFile, "ps", "--services")
+	cmd := c.compose("ps", "--services")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
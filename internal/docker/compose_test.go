@@ -234,9 +234,7 @@ func TestComposeGenerator_GenerateAllServices(t *testing.T) {
 	}
 }
 
-func TestComposeGenerator_getMySQLPort(t *testing.T) {
-	g, _ := setupTestComposeGenerator(t)
-
+func TestDBPort_MySQL(t *testing.T) {
 	tests := []struct {
 		version  string
 		expected int
@@ -249,16 +247,14 @@ func TestComposeGenerator_getMySQLPort(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.version, func(t *testing.T) {
-			if got := g.getMySQLPort(tt.version); got != tt.expected {
-				t.Errorf("getMySQLPort(%v) = %v, want %v", tt.version, got, tt.expected)
+			if got := DBPort("mysql", tt.version); got != tt.expected {
+				t.Errorf("DBPort(mysql, %v) = %v, want %v", tt.version, got, tt.expected)
 			}
 		})
 	}
 }
 
-func TestComposeGenerator_getMariaDBPort(t *testing.T) {
-	g, _ := setupTestComposeGenerator(t)
-
+func TestDBPort_MariaDB(t *testing.T) {
 	tests := []struct {
 		version  string
 		expected int
@@ -271,8 +267,8 @@ func TestComposeGenerator_getMariaDBPort(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.version, func(t *testing.T) {
-			if got := g.getMariaDBPort(tt.version); got != tt.expected {
-				t.Errorf("getMariaDBPort(%v) = %v, want %v", tt.version, got, tt.expected)
+			if got := DBPort("mariadb", tt.version); got != tt.expected {
+				t.Errorf("DBPort(mariadb, %v) = %v, want %v", tt.version, got, tt.expected)
 			}
 		})
 	}
@@ -418,6 +414,9 @@ func TestComposeService_Redis(t *testing.T) {
 	if len(svc.Ports) != 1 || svc.Ports[0] != "6379:6379" {
 		t.Errorf("Ports = %v, want [6379:6379]", svc.Ports)
 	}
+	if svc.Command != "redis-server --databases 96" {
+		t.Errorf("Command = %q, want per-project database room", svc.Command)
+	}
 }
 
 func TestComposeService_OpenSearch(t *testing.T) {
@@ -843,6 +842,118 @@ func TestComposeGenerator_GenerateWithValkey(t *testing.T) {
 	}
 }
 
+func TestComposeGenerator_GenerateWithPercona(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+
+	configs := []*config.Config{
+		{
+			Name: "perconaproject",
+			Services: config.Services{
+				Percona: &config.ServiceConfig{Enabled: true, Version: "8.0", Memory: "2g"},
+			},
+		},
+	}
+
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+
+	svc, ok := compose.Services["percona80"]
+	if !ok {
+		t.Fatal("Compose should contain percona80 service")
+	}
+	if svc.Image != "percona/percona-server:8.0" {
+		t.Errorf("Image = %q, want percona/percona-server:8.0", svc.Image)
+	}
+	if svc.ContainerName != "magebox-percona-8.0" {
+		t.Errorf("ContainerName = %q, want magebox-percona-8.0", svc.ContainerName)
+	}
+	if len(svc.Ports) != 1 || svc.Ports[0] != "33180:3306" {
+		t.Errorf("Ports = %v, want [33180:3306]", svc.Ports)
+	}
+	if !strings.Contains(svc.Command, "--innodb-buffer-pool-size=2g") {
+		t.Errorf("Command = %q, want the configured buffer pool", svc.Command)
+	}
+	if _, ok := compose.Volumes["percona80_data"]; !ok {
+		t.Error("Compose should contain percona80_data volume")
+	}
+	if _, ok := compose.Services["mysql80"]; ok {
+		t.Error("Compose should NOT contain mysql80 for a Percona project")
+	}
+}
+
+func TestComposeGenerator_GenerateWithAlternativeSearch(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+
+	configs := []*config.Config{
+		{
+			Name: "searchproject",
+			Services: config.Services{
+				MySQL:       &config.ServiceConfig{Enabled: true, Version: "8.0"},
+				Meilisearch: &config.ServiceConfig{Enabled: true, Version: "1.10"},
+				Typesense:   &config.ServiceConfig{Enabled: true},
+			},
+		},
+	}
+
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+
+	meili, ok := compose.Services["meilisearch"]
+	if !ok {
+		t.Fatal("Compose should contain meilisearch service")
+	}
+	if meili.Image != "getmeili/meilisearch:v1.10" {
+		t.Errorf("meilisearch image = %q, want getmeili/meilisearch:v1.10", meili.Image)
+	}
+	if meili.Environment["MEILI_MASTER_KEY"] != config.DefaultMeilisearchKey {
+		t.Errorf("MEILI_MASTER_KEY = %q, want %q", meili.Environment["MEILI_MASTER_KEY"], config.DefaultMeilisearchKey)
+	}
+
+	typesense, ok := compose.Services["typesense"]
+	if !ok {
+		t.Fatal("Compose should contain typesense service")
+	}
+	if typesense.Image != "typesense/typesense:"+config.DefaultTypesenseVersion {
+		t.Errorf("typesense image = %q, want the default version", typesense.Image)
+	}
+	if len(typesense.Ports) != 1 || typesense.Ports[0] != "8108:8108" {
+		t.Errorf("typesense ports = %v, want [8108:8108]", typesense.Ports)
+	}
+
+	for _, volume := range []string{"meilisearch_data", "typesense_data"} {
+		if _, ok := compose.Volumes[volume]; !ok {
+			t.Errorf("Compose should declare volume %s", volume)
+		}
+	}
+
+	images := g.ProjectImages(configs[0])
+	if images["meilisearch"] != meili.Image || images["typesense"] != typesense.Image {
+		t.Errorf("ProjectImages() = %v, should include the search engine images", images)
+	}
+}
+
 func TestComposeService_RabbitMQ(t *testing.T) {
 	g, _ := setupTestComposeGenerator(t)
 
@@ -1282,3 +1393,380 @@ func TestComposeGenerator_GenerateGlobalServices_NoRedisWhenNotRequired(t *testi
 		t.Error("Compose should not contain valkey service when no project requires it")
 	}
 }
+
+func TestComposeGenerator_GenerateGlobalServices_LowMemory(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+	g.SetLowMemory(true)
+
+	configs := []*config.Config{
+		{
+			Name: "project1",
+			Services: config.Services{
+				MySQL:      &config.ServiceConfig{Enabled: true, Version: "8.0"},
+				MariaDB:    &config.ServiceConfig{Enabled: true, Version: "10.6", Memory: "512M"},
+				OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+				Mailpit:    &config.ServiceConfig{Enabled: false},
+			},
+		},
+	}
+
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+
+	mysql := compose.Services["mysql80"].Command
+	if !strings.Contains(mysql, "--innodb-buffer-pool-size=128M") || !strings.Contains(mysql, "--performance-schema=OFF") {
+		t.Errorf("mysql command = %q, want a shrunk buffer pool and no performance schema", mysql)
+	}
+	if mariadb := compose.Services["mariadb106"].Command; strings.Contains(mariadb, "--innodb-buffer-pool-size") {
+		t.Errorf("mariadb command = %q, a configured memory should not be overridden", mariadb)
+	}
+	if opts := compose.Services["opensearch219"].Environment["OPENSEARCH_JAVA_OPTS"]; opts != "-Xms512m -Xmx512m" {
+		t.Errorf("OPENSEARCH_JAVA_OPTS = %q, want a 512m heap", opts)
+	}
+	if _, ok := compose.Services["mailpit"]; ok {
+		t.Error("Compose should not contain mailpit in low-memory mode when no project uses it")
+	}
+
+	// A project that doesn't disable Mailpit still gets it
+	configs[0].Services.Mailpit = nil
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+	content, _ = os.ReadFile(g.ComposeFilePath())
+	compose = ComposeConfig{}
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+	if _, ok := compose.Services["mailpit"]; !ok {
+		t.Error("Compose should contain mailpit when a project uses it")
+	}
+}
+
+func TestDatabaseUserSQL(t *testing.T) {
+	sql := DatabaseUserSQL("shop`db", "app", `it's\secret`)
+
+	for _, want := range []string{
+		"CREATE USER IF NOT EXISTS 'app'@'%' IDENTIFIED BY 'it''s\\\\secret';",
+		"ALTER USER 'app'@'%' IDENTIFIED BY 'it''s\\\\secret';",
+		"GRANT ALL PRIVILEGES ON `shop``db`.* TO 'app'@'%';",
+		"FLUSH PRIVILEGES;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("DatabaseUserSQL() missing %q\ngot: %s", want, sql)
+		}
+	}
+}
+
+func TestComposeGenerator_GenerateGlobalServices_ComposerMirror(t *testing.T) {
+	g, tmpDir := setupTestComposeGenerator(t)
+	t.Setenv("COMPOSER_HOME", "")
+
+	authDir := filepath.Join(tmpDir, ".composer")
+	if err := os.MkdirAll(authDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	auth := `{"http-basic": {"repo.magento.com": {"username": "pubkey", "password": "privkey"}}}`
+	if err := os.WriteFile(filepath.Join(authDir, "auth.json"), []byte(auth), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := []*config.Config{{
+		Name: "project1",
+		Services: config.Services{
+			MySQL:          &config.ServiceConfig{Enabled: true, Version: "8.0"},
+			ComposerMirror: &config.ServiceConfig{Enabled: true},
+		},
+	}}
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+	mirror, ok := compose.Services["composer-mirror"]
+	if !ok {
+		t.Fatal("Compose should contain composer-mirror when a project enables it")
+	}
+	if mirror.Image != ComposerMirrorImage || strings.HasSuffix(mirror.Image, ":latest") {
+		t.Errorf("composer-mirror image = %q, want the pinned %q", mirror.Image, ComposerMirrorImage)
+	}
+	password, err := ComposerMirrorPassword(g.ComposeDir())
+	if err != nil {
+		t.Fatalf("ComposerMirrorPassword() error = %v", err)
+	}
+	if len(password) < 32 || mirror.Environment["ADMIN_PASSWORD"] != password {
+		t.Errorf("ADMIN_PASSWORD = %q, want the generated %q", mirror.Environment["ADMIN_PASSWORD"], password)
+	}
+
+	mirrorCfg, err := os.ReadFile(g.composerMirrorConfigPath())
+	if err != nil {
+		t.Fatalf("Failed to read mirror config: %v", err)
+	}
+	for _, want := range []string{"url: " + MagentoRepoURL, `username: "pubkey"`, `password: "privkey"`, "enable_dist_mirror: false"} {
+		if !strings.Contains(string(mirrorCfg), want) {
+			t.Errorf("mirror config should contain %q:\n%s", want, mirrorCfg)
+		}
+	}
+	if strings.Contains(string(mirrorCfg), "public_access") {
+		t.Errorf("mirror config should not allow public access:\n%s", mirrorCfg)
+	}
+}
+
+func TestComposeGenerator_GenerateWithProfilers(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+	t.Setenv("TIDEWAYS_ENVIRONMENT", "")
+
+	configs := []*config.Config{
+		{Name: "profiled", Profiler: config.ProfilerBlackfire},
+		{Name: "traced", Profiler: config.ProfilerTideways},
+		{Name: "plain", Profiler: config.ProfilerOff},
+	}
+
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+
+	agent, ok := compose.Services[BlackfireService]
+	if !ok {
+		t.Fatal("Compose should contain the blackfire agent")
+	}
+	if len(agent.Ports) != 1 || agent.Ports[0] != "127.0.0.1:18307:8307" {
+		t.Errorf("blackfire ports = %v, want [127.0.0.1:18307:8307]", agent.Ports)
+	}
+
+	daemon, ok := compose.Services[TidewaysService]
+	if !ok {
+		t.Fatal("Compose should contain the tideways daemon")
+	}
+	if len(daemon.Ports) != 1 || daemon.Ports[0] != "127.0.0.1:19135:9135" {
+		t.Errorf("tideways ports = %v, want [127.0.0.1:19135:9135]", daemon.Ports)
+	}
+	if !strings.Contains(daemon.Command, "--env=local_") {
+		t.Errorf("tideways command = %q, should label traces with a local environment", daemon.Command)
+	}
+
+	images := g.ProjectImages(configs[2])
+	if _, ok := images[BlackfireService]; ok {
+		t.Error("ProjectImages() should not include profiler agents for a project with the profiler off")
+	}
+}
+
+func TestComposeGenerator_GenerateWithOpenSearchDashboards(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+
+	configs := []*config.Config{
+		{
+			Name: "dashboards",
+			Services: config.Services{
+				OpenSearch:           &config.ServiceConfig{Enabled: true, Version: "2.19"},
+				OpenSearchDashboards: &config.ServiceConfig{Enabled: true},
+			},
+		},
+		{
+			Name: "no-search",
+			Services: config.Services{
+				OpenSearchDashboards: &config.ServiceConfig{Enabled: true},
+			},
+		},
+	}
+
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+
+	dashboards, ok := compose.Services["opensearch-dashboards219"]
+	if !ok {
+		t.Fatal("Compose should contain opensearch-dashboards219")
+	}
+	osTag := strings.TrimPrefix(compose.Services["opensearch219"].Image, "opensearchproject/opensearch:")
+	if dashboards.Image != "opensearchproject/opensearch-dashboards:"+osTag {
+		t.Errorf("dashboards image = %q, want the version of OpenSearch", dashboards.Image)
+	}
+	if len(dashboards.Ports) != 1 || dashboards.Ports[0] != "5659:5601" {
+		t.Errorf("dashboards ports = %v, want [5659:5601]", dashboards.Ports)
+	}
+	if dashboards.Environment["OPENSEARCH_HOSTS"] != `["http://magebox-opensearch-2.19:9200"]` {
+		t.Errorf("OPENSEARCH_HOSTS = %q, should point at the OpenSearch container", dashboards.Environment["OPENSEARCH_HOSTS"])
+	}
+
+	count := 0
+	for name := range compose.Services {
+		if strings.HasPrefix(name, "opensearch-dashboards") {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("got %d dashboards services, want 1 (none without OpenSearch)", count)
+	}
+}
+
+func TestGetOpenSearchDashboardsPort(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+	}{
+		{"2.19", 5659},
+		{"2.19.4", 5659},
+		{"3.3", 5663},
+		{"1.3", 5623},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := GetOpenSearchDashboardsPort(tt.version); got != tt.want {
+				t.Errorf("GetOpenSearchDashboardsPort(%q) = %d, want %d", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImagePlatform(t *testing.T) {
+	tests := []struct {
+		image string
+		arch  string
+		want  string
+	}{
+		{"mysql:5.7", "arm64", PlatformAMD64},
+		{"mysql:8.0", "arm64", ""},
+		{"mysql:8.4", "arm64", ""},
+		{"mysql:5.7", "amd64", ""},
+		{"percona/percona-server:5.7", "arm64", PlatformAMD64},
+		{"mariadb:10.1", "arm64", PlatformAMD64},
+		{"mariadb:10.6", "arm64", ""},
+		{"elasticsearch:7.6.2", "arm64", PlatformAMD64},
+		{"elasticsearch:7.17.24", "arm64", ""},
+		{"mysql:5.7@sha256:abc", "arm64", PlatformAMD64},
+		{"opensearchproject/opensearch:2.19.1", "arm64", ""},
+		{"redis:7-alpine", "arm64", ""},
+		{"mysql", "arm64", ""},
+	}
+	for _, tt := range tests {
+		if got := ImagePlatform(tt.image, tt.arch); got != tt.want {
+			t.Errorf("ImagePlatform(%q, %q) = %q, want %q", tt.image, tt.arch, got, tt.want)
+		}
+	}
+}
+
+func TestComposeGenerator_GeneratePlatforms(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+	g.arch = "arm64"
+
+	configs := []*config.Config{
+		{
+			Name: "legacy",
+			Services: config.Services{
+				MySQL: &config.ServiceConfig{Enabled: true, Version: "5.7"},
+				Redis: &config.ServiceConfig{Enabled: true},
+			},
+		},
+		{
+			Name: "modern",
+			Services: config.Services{
+				MySQL:      &config.ServiceConfig{Enabled: true, Version: "8.0"},
+				OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19.1", Platform: PlatformAMD64},
+			},
+		},
+	}
+
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.ComposeFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	var compose ComposeConfig
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		t.Fatalf("Failed to parse compose file: %v", err)
+	}
+
+	want := map[string]string{
+		"mysql57":        PlatformAMD64,
+		"mysql80":        "",
+		"redis":          "",
+		"opensearch2191": PlatformAMD64,
+	}
+	for name, platform := range want {
+		svc, ok := compose.Services[name]
+		if !ok {
+			t.Errorf("Compose should contain %s service", name)
+			continue
+		}
+		if svc.Platform != platform {
+			t.Errorf("%s platform = %q, want %q", name, svc.Platform, platform)
+		}
+	}
+
+	warnings := g.PlatformWarnings(configs[0])
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "mysql:5.7") {
+		t.Errorf("PlatformWarnings() = %v, want one warning for mysql:5.7", warnings)
+	}
+	if warnings := g.PlatformWarnings(configs[1]); len(warnings) != 0 {
+		t.Errorf("PlatformWarnings() = %v, want none", warnings)
+	}
+
+	g.arch = "amd64"
+	if warnings := g.PlatformWarnings(configs[0]); len(warnings) != 0 {
+		t.Errorf("PlatformWarnings() on amd64 = %v, want none", warnings)
+	}
+}
+
+func TestComposeGenerator_ImageLockOnlyPinsProjectServices(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+	configs := []*config.Config{
+		{Name: "shop", Services: config.Services{MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"}}},
+		{Name: "blog", Services: config.Services{Redis: &config.ServiceConfig{Enabled: true}}},
+	}
+
+	lock := &ImageLock{Images: map[string]LockedImage{
+		"mysql:8.0":      {Digest: "sha256:aaa"},
+		"redis:7-alpine": {Digest: "sha256:bbb"},
+	}}
+	g.SetImageLock(lock, []string{"mysql80"})
+
+	compose := g.RenderGlobalServices(configs)
+	if got := compose.Services["mysql80"].Image; got != "mysql:8.0@sha256:aaa" {
+		t.Errorf("mysql80 image = %q, want it pinned by the shop lock", got)
+	}
+	if got := compose.Services["redis"].Image; got != "redis:7-alpine" {
+		t.Errorf("redis image = %q, another project's service must keep its tag", got)
+	}
+}
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qoliber/magebox/internal/composer"
+	"qoliber/magebox/internal/fileutil"
+)
+
+// Composer mirror (Packeton) settings
+const (
+	// ComposerMirrorPort is the host port of the Packeton web UI and API
+	ComposerMirrorPort = 8088
+	// ComposerMirrorAlias is the Packeton mirror of repo.magento.com, served
+	// under /mirror/<alias>
+	ComposerMirrorAlias = "magento"
+	// ComposerMirrorImage is the Packeton image, pinned so a release can't
+	// change the mirror config format under us
+	ComposerMirrorImage = "packeton/packeton:2.6"
+	// ComposerMirrorUser is the Packeton admin Composer signs in as
+	ComposerMirrorUser = "admin"
+	// MagentoRepoURL is the Magento Marketplace Composer repository
+	MagentoRepoURL = "https://repo.magento.com/"
+)
+
+// MagentoRepoCredentials returns the repo.magento.com keys from the user's
+// Composer auth.json, empty when there are none
+func MagentoRepoCredentials(homeDir string) (username, password string) {
+	creds, _ := composer.FindHTTPBasic(homeDir, composer.MagentoRepoHost)
+	return creds.Username, creds.Password
+}
+
+// ComposerMirrorConfig renders the Packeton config mirroring repo.magento.com.
+// Only metadata is mirrored, dist URLs keep pointing at repo.magento.com so
+// composer.lock stays usable without the mirror; Composer's own file cache
+// already shares downloaded archives between projects.
+func ComposerMirrorConfig(username, password string) string {
+	var b strings.Builder
+	b.WriteString("# Generated by MageBox\n")
+	b.WriteString("packeton:\n")
+	b.WriteString("    mirrors:\n")
+	b.WriteString(fmt.Sprintf("        %s:\n", ComposerMirrorAlias))
+	b.WriteString(fmt.Sprintf("            url: %s\n", MagentoRepoURL))
+	if username != "" {
+		b.WriteString("            http_basic:\n")
+		b.WriteString(fmt.Sprintf("                username: %q\n", username))
+		b.WriteString(fmt.Sprintf("                password: %q\n", password))
+	}
+	b.WriteString("            sync_lazy: true\n")
+	b.WriteString("            enable_dist_mirror: false\n")
+	return b.String()
+}
+
+// ComposerMirrorPassword returns the Packeton admin password, generated on
+// first use and kept next to the mirror config in composeDir
+func ComposerMirrorPassword(composeDir string) (string, error) {
+	return composerMirrorSecret(composeDir, "admin-password")
+}
+
+// composerMirrorSecret reads a secret of the Composer mirror, creating a
+// random one readable only by the user when there is none yet
+func composerMirrorSecret(composeDir, name string) (string, error) {
+	path := filepath.Join(composeDir, "composer-mirror", name)
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := fileutil.WriteFileAtomic(path, []byte(secret+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// composerMirrorConfigPath returns where the generated Packeton config lives
+func (g *ComposeGenerator) composerMirrorConfigPath() string {
+	return filepath.Join(g.composeDir, "composer-mirror", "magebox.yaml")
+}
+
+// writeComposerMirrorConfig writes the Packeton config with the user's
+// repo.magento.com keys
+func (g *ComposeGenerator) writeComposerMirrorConfig() error {
+	path := g.composerMirrorConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Create the secrets the service reads before it is rendered
+	if _, err := ComposerMirrorPassword(g.composeDir); err != nil {
+		return err
+	}
+	if _, err := composerMirrorSecret(g.composeDir, "app-secret"); err != nil {
+		return err
+	}
+	username, password := MagentoRepoCredentials(g.platform.HomeDir)
+	return fileutil.WriteGenerated(path, []byte(ComposerMirrorConfig(username, password)), 0600, fileutil.ValidateYAML)
+}
+
+// getComposerMirrorService returns a Packeton service mirroring repo.magento.com.
+// Its secrets are created by writeComposerMirrorConfig.
+func (g *ComposeGenerator) getComposerMirrorService() ComposeService {
+	password, _ := ComposerMirrorPassword(g.composeDir)
+	appSecret, _ := composerMirrorSecret(g.composeDir, "app-secret")
+	return ComposeService{
+		ContainerName: "magebox-composer-mirror",
+		Image:         ComposerMirrorImage,
+		Ports:         []string{fmt.Sprintf("%d:80", ComposerMirrorPort)},
+		Environment: map[string]string{
+			"APP_SECRET":     appSecret,
+			"ADMIN_USER":     ComposerMirrorUser,
+			"ADMIN_PASSWORD": password,
+			"ADMIN_EMAIL":    "admin@magebox.local",
+		},
+		Volumes: []string{
+			"composer_mirror_data:/data",
+			g.composerMirrorConfigPath() + ":/var/www/packeton/config/packages/ext/magebox.yaml:ro",
+		},
+		Networks: []string{"magebox"},
+		Restart:  "unless-stopped",
+	}
+}
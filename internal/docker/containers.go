@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"qoliber/magebox/internal/execctx"
+	"qoliber/magebox/internal/verbose"
+)
+
+// ContainerState is the state of a container of the global services
+type ContainerState struct {
+	Name         string
+	Service      string // Compose service name
+	State        string // running, restarting, exited, ...
+	Health       string // healthy, unhealthy or starting; empty without a healthcheck
+	RestartCount int    // Restarts by the restart policy, i.e. crashes
+	StartedAt    time.Time
+}
+
+// containerStateFormat prints one tab separated line per container for parseContainerStates
+const containerStateFormat = `{{.Name}}	{{index .Config.Labels "com.docker.compose.service"}}	{{.State.Status}}	{{if .State.Health}}{{.State.Health.Status}}{{end}}	{{.RestartCount}}	{{.State.StartedAt}}`
+
+// ContainerStates returns the state of every container of the compose file,
+// including stopped ones
+func (c *DockerController) ContainerStates() ([]ContainerState, error) {
+	output, err := c.compose("ps", "-a", "-q").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := append([]string{"inspect", "--format", containerStateFormat}, ids...)
+	cmd := execctx.Command(c.ctx, c.timeout, "docker", "docker", args...)
+	verbose.Command(cmd.Path, cmd.Args[1:]...)
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect containers: %w", err)
+	}
+	return parseContainerStates(string(output)), nil
+}
+
+// parseContainerStates parses the output of docker inspect with containerStateFormat
+func parseContainerStates(output string) []ContainerState {
+	var states []ContainerState
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 {
+			continue
+		}
+		restarts, _ := strconv.Atoi(fields[4])
+		started, _ := time.Parse(time.RFC3339Nano, fields[5])
+		states = append(states, ContainerState{
+			Name:         strings.TrimPrefix(fields[0], "/"),
+			Service:      fields[1],
+			State:        fields[2],
+			Health:       fields[3],
+			RestartCount: restarts,
+			StartedAt:    started,
+		})
+	}
+	return states
+}
+
+// EventsCommand returns a docker compose events command, which prints a line
+// per container event (start, die, health_status, ...) until it is cancelled
+func (c *DockerController) EventsCommand() *execctx.Cmd {
+	return c.composeInteractive("events")
+}
@@ -0,0 +1,32 @@
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseContainerStates(t *testing.T) {
+	output := "/magebox-mysql-8.0\tmysql80\trunning\thealthy\t3\t2026-10-15T09:30:00.123456789Z\n" +
+		"/magebox-redis\tredis\texited\t\t0\t2026-10-14T08:00:00Z\n" +
+		"garbage line\n"
+
+	states := parseContainerStates(output)
+	if len(states) != 2 {
+		t.Fatalf("got %d states, want 2: %+v", len(states), states)
+	}
+
+	mysql := states[0]
+	if mysql.Name != "magebox-mysql-8.0" || mysql.Service != "mysql80" || mysql.State != "running" || mysql.Health != "healthy" || mysql.RestartCount != 3 {
+		t.Errorf("mysql = %+v", mysql)
+	}
+	if want := time.Date(2026, 10, 15, 9, 30, 0, 123456789, time.UTC); !mysql.StartedAt.Equal(want) {
+		t.Errorf("StartedAt = %v, want %v", mysql.StartedAt, want)
+	}
+	if states[1].Health != "" || states[1].State != "exited" {
+		t.Errorf("redis = %+v", states[1])
+	}
+
+	if states := parseContainerStates(""); len(states) != 0 {
+		t.Errorf("parseContainerStates(\"\") = %+v", states)
+	}
+}
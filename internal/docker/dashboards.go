@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+)
+
+// OpenSearchDashboardsService returns the compose service name of the
+// Dashboards of an OpenSearch version
+func OpenSearchDashboardsService(version string) string {
+	return "opensearch-dashboards" + strings.ReplaceAll(version, ".", "")
+}
+
+// GetOpenSearchDashboardsPort returns the host port of the Dashboards of an
+// OpenSearch version, following the OpenSearch ports: 5600 + major*20 + minor
+// (e.g., OS 2.19 → 5659, OS 3.3 → 5663)
+func GetOpenSearchDashboardsPort(version string) int {
+	return computeSearchPort(5600, resolveSearchPortVersion(version, ResolveOpenSearchVersion))
+}
+
+// OpenSearchDashboardsPort returns the host port of the project's
+// Dashboards: the configured port, or the one of its OpenSearch version
+func OpenSearchDashboardsPort(services *config.Services) int {
+	if services.OpenSearchDashboards != nil && services.OpenSearchDashboards.Port > 0 {
+		return services.OpenSearchDashboards.Port
+	}
+	return GetOpenSearchDashboardsPort(services.OpenSearch.Version)
+}
+
+// getOpenSearchDashboardsService returns an OpenSearch Dashboards service
+// connected to the OpenSearch container of the same version. Security is off
+// like on the OpenSearch container, so there is no login.
+func (g *ComposeGenerator) getOpenSearchDashboardsService(version string, port int) ComposeService {
+	imageVersion := ResolveOpenSearchVersion(version)
+	if port == 0 {
+		port = GetOpenSearchDashboardsPort(imageVersion)
+	}
+	return ComposeService{
+		ContainerName: fmt.Sprintf("magebox-opensearch-dashboards-%s", version),
+		Image:         fmt.Sprintf("opensearchproject/opensearch-dashboards:%s", imageVersion),
+		Ports:         []string{fmt.Sprintf("%d:5601", port)},
+		Environment: map[string]string{
+			"OPENSEARCH_HOSTS":                   fmt.Sprintf(`["http://magebox-opensearch-%s:9200"]`, version),
+			"DISABLE_SECURITY_DASHBOARDS_PLUGIN": "true",
+		},
+		Networks: []string{"magebox"},
+		Restart:  "unless-stopped",
+	}
+}
@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/fileutil"
+)
+
+// dbConfigPath returns the generated my.cnf of a database service version
+func (g *ComposeGenerator) dbConfigPath(dbType, version string) string {
+	return filepath.Join(g.composeDir, "db", fmt.Sprintf("%s-%s.cnf", dbType, version))
+}
+
+// DBServerConfig renders the my.cnf of a database service version from the
+// services.<database>.config settings of its projects
+func DBServerConfig(dbType string, settings map[string]string) string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# Generated by MageBox from services.%s.config, do not edit\n", dbType))
+	b.WriteString("[mysqld]\n")
+	for _, key := range keys {
+		value := settings[key]
+		if value == "" || strings.ContainsAny(value, " #;") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(fmt.Sprintf("%s = %s\n", key, value))
+	}
+	return b.String()
+}
+
+// dbConfigHash returns a short hash of the rendered my.cnf. It goes into the
+// container environment, so compose recreates the container when the
+// settings change and the server reads the new file.
+func dbConfigHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// mountDBServerConfig mounts the generated my.cnf of a database service with
+// settings into its container at target
+func (g *ComposeGenerator) mountDBServerConfig(svc *ComposeService, dbType string, svcCfg *config.ServiceConfig, target string) {
+	settings := svcCfg.DBServerConfig()
+	if len(settings) == 0 {
+		return
+	}
+	svc.Volumes = append(svc.Volumes, g.dbConfigPath(dbType, svcCfg.Version)+":"+target+":ro")
+	svc.Environment["MAGEBOX_CONFIG_HASH"] = dbConfigHash(DBServerConfig(dbType, settings))
+}
+
+// writeDBServerConfigs writes the my.cnf of every database version whose
+// projects have server settings
+func (g *ComposeGenerator) writeDBServerConfigs(rs requiredServices) error {
+	for dbType, services := range map[string]map[string]*config.ServiceConfig{
+		"mysql":   rs.mysql,
+		"mariadb": rs.mariadb,
+		"percona": rs.percona,
+	} {
+		for version, svcCfg := range services {
+			settings := svcCfg.DBServerConfig()
+			if len(settings) == 0 {
+				continue
+			}
+			path := g.dbConfigPath(dbType, version)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := fileutil.WriteGenerated(path, []byte(DBServerConfig(dbType, settings)), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sharedDBService returns the service config of a database version shared
+// by svc and the projects collected before it: svc, with the server settings
+// of all of them merged
+func sharedDBService(existing, svc *config.ServiceConfig) *config.ServiceConfig {
+	if existing == nil || len(existing.Config) == 0 {
+		return svc
+	}
+	merged := *svc
+	merged.Config = mergeDBServerConfig(existing.DBServerConfig(), svc.DBServerConfig())
+	return &merged
+}
+
+// mergeDBServerConfig merges the server settings of two projects sharing a
+// database container. When both set an option, the larger size or number
+// wins, so neither project's import gets slower; other values keep the
+// first project's.
+func mergeDBServerConfig(first, second map[string]string) map[string]string {
+	merged := make(map[string]string, len(first)+len(second))
+	for key, value := range first {
+		merged[key] = value
+	}
+	for key, value := range second {
+		current, ok := merged[key]
+		if !ok {
+			merged[key] = value
+			continue
+		}
+		a, aOK := parseDBSize(current)
+		b, bOK := parseDBSize(value)
+		if aOK && bOK && b > a {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// parseDBSize parses a number with an optional K, M or G suffix, as MySQL
+// reads sizes in option files
+func parseDBSize(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	multiplier := int64(1)
+	if value != "" {
+		switch strings.ToUpper(value[len(value)-1:]) {
+		case "K":
+			multiplier = 1 << 10
+		case "M":
+			multiplier = 1 << 20
+		case "G":
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * multiplier, true
+}
@@ -0,0 +1,135 @@
+package docker
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestDBServerConfig(t *testing.T) {
+	got := DBServerConfig("mysql", map[string]string{
+		"max_connections":         "500",
+		"innodb_buffer_pool_size": "2G",
+		"sql_mode":                "",
+		"init_connect":            "SET NAMES utf8mb4",
+	})
+
+	want := `# Generated by MageBox from services.mysql.config, do not edit
+[mysqld]
+init_connect = "SET NAMES utf8mb4"
+innodb_buffer_pool_size = 2G
+max_connections = 500
+sql_mode = ""
+`
+	if got != want {
+		t.Errorf("DBServerConfig() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeDBServerConfig(t *testing.T) {
+	merged := mergeDBServerConfig(
+		map[string]string{"innodb_buffer_pool_size": "1G", "max_connections": "500", "sql_mode": "STRICT_ALL_TABLES"},
+		map[string]string{"innodb_buffer_pool_size": "512M", "max_connections": "800", "sql_mode": "", "max_allowed_packet": "256M"},
+	)
+
+	want := map[string]string{
+		"innodb_buffer_pool_size": "1G",
+		"max_connections":         "800",
+		"sql_mode":                "STRICT_ALL_TABLES",
+		"max_allowed_packet":      "256M",
+	}
+	for key, value := range want {
+		if merged[key] != value {
+			t.Errorf("merged[%s] = %q, want %q", key, merged[key], value)
+		}
+	}
+}
+
+func TestParseDBSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		ok    bool
+	}{
+		{"500", 500, true},
+		{"64k", 64 << 10, true},
+		{"512M", 512 << 20, true},
+		{"2g", 2 << 30, true},
+		{"", 0, false},
+		{"STRICT_ALL_TABLES", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDBSize(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseDBSize(%q) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestComposeService_MySQL_WithServerConfig(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+	g.lowMemory = true
+
+	svcCfg := &config.ServiceConfig{
+		Enabled: true,
+		Version: "8.0",
+		Config:  map[string]string{"innodb-buffer-pool-size": "2G"},
+	}
+	svc := g.getMySQLService(svcCfg, false)
+
+	mount := g.dbConfigPath("mysql", "8.0") + ":/etc/mysql/conf.d/magebox.cnf:ro"
+	if len(svc.Volumes) != 2 || svc.Volumes[1] != mount {
+		t.Errorf("Volumes = %v, want the server config mounted as %s", svc.Volumes, mount)
+	}
+	if svc.Environment["MAGEBOX_CONFIG_HASH"] == "" {
+		t.Error("MAGEBOX_CONFIG_HASH should be set, so the container is recreated when the config changes")
+	}
+	// The configured buffer pool replaces the low-memory one
+	if strings.Contains(svc.Command, "--innodb-buffer-pool-size") {
+		t.Errorf("Command = %q, should leave the buffer pool to the server config", svc.Command)
+	}
+}
+
+func TestComposeGenerator_GenerateGlobalServices_DBServerConfig(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+
+	configs := []*config.Config{
+		{
+			Name: "project1",
+			Services: config.Services{
+				MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0", Config: map[string]string{"innodb_buffer_pool_size": "1G"}},
+			},
+		},
+		{
+			Name: "project2",
+			Services: config.Services{
+				MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0", Config: map[string]string{"innodb_buffer_pool_size": "2G", "max_allowed_packet": "256M"}},
+			},
+		},
+		{
+			Name: "project3",
+			Services: config.Services{
+				MySQL: &config.ServiceConfig{Enabled: true, Version: "8.4"},
+			},
+		},
+	}
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.dbConfigPath("mysql", "8.0"))
+	if err != nil {
+		t.Fatalf("Failed to read server config: %v", err)
+	}
+	for _, want := range []string{"innodb_buffer_pool_size = 2G", "max_allowed_packet = 256M"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("server config should contain %q:\n%s", want, content)
+		}
+	}
+
+	if _, err := os.Stat(g.dbConfigPath("mysql", "8.4")); !os.IsNotExist(err) {
+		t.Error("No server config should be written for a version without settings")
+	}
+}
@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFileName is the per-project image lock file, meant to be committed so
+// every teammate runs the exact same service images
+const LockFileName = ".magebox.lock"
+
+// ImageLock records the digest each service image reference was pinned to
+type ImageLock struct {
+	Images map[string]LockedImage `yaml:"images"`
+}
+
+// LockedImage is a single pinned image reference
+type LockedImage struct {
+	Digest    string    `yaml:"digest"`
+	UpdatedAt time.Time `yaml:"updated_at"`
+}
+
+// DigestDrift describes an image whose upstream tag no longer points at the locked digest
+type DigestDrift struct {
+	Image    string
+	Locked   string
+	Upstream string
+}
+
+// LoadImageLock loads the lock file from a project directory.
+// A missing lock file yields an empty lock.
+func LoadImageLock(projectPath string) (*ImageLock, error) {
+	lock := &ImageLock{Images: make(map[string]LockedImage)}
+
+	data, err := os.ReadFile(filepath.Join(projectPath, LockFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFileName, err)
+	}
+	if lock.Images == nil {
+		lock.Images = make(map[string]LockedImage)
+	}
+	return lock, nil
+}
+
+// Save writes the lock file to a project directory
+func (l *ImageLock) Save(projectPath string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", LockFileName, err)
+	}
+	header := "# Generated by `magebox services update`. Commit this file.\n"
+	return os.WriteFile(filepath.Join(projectPath, LockFileName), append([]byte(header), data...), 0644)
+}
+
+// Set records the digest for an image reference
+func (l *ImageLock) Set(image, digest string) {
+	l.Images[image] = LockedImage{Digest: digest, UpdatedAt: time.Now().UTC()}
+}
+
+// Pin returns the image reference pinned to its locked digest, or the
+// reference unchanged when it is not locked
+func (l *ImageLock) Pin(image string) string {
+	if l == nil {
+		return image
+	}
+	locked, ok := l.Images[image]
+	if !ok || locked.Digest == "" {
+		return image
+	}
+	return image + "@" + locked.Digest
+}
+
+// SortedImages returns the locked image references in a stable order
+func (l *ImageLock) SortedImages() []string {
+	images := make([]string, 0, len(l.Images))
+	for image := range l.Images {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// CheckDrift compares every locked image with its upstream digest.
+// Images whose upstream digest cannot be determined are skipped.
+func (l *ImageLock) CheckDrift(lookup func(image string) (string, error)) []DigestDrift {
+	var drifts []DigestDrift
+	for _, image := range l.SortedImages() {
+		upstream, err := lookup(image)
+		if err != nil || upstream == "" {
+			continue
+		}
+		if locked := l.Images[image].Digest; upstream != locked {
+			drifts = append(drifts, DigestDrift{Image: image, Locked: locked, Upstream: upstream})
+		}
+	}
+	return drifts
+}
+
+// ShortDigest abbreviates a digest for display (e.g. "sha256:0123456789ab")
+func ShortDigest(digest string) string {
+	const short = len("sha256:") + 12
+	if len(digest) > short {
+		return digest[:short]
+	}
+	return digest
+}
+
+// ParseImageRef splits an image reference like "valkey/valkey:8-alpine" into
+// its Docker Hub namespace, repository name and tag. Official images use the
+// "library" namespace and a missing tag means "latest".
+func ParseImageRef(image string) (namespace, name, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	tag = "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag = image[i+1:]
+		image = image[:i]
+	}
+	namespace = "library"
+	name = image
+	if i := strings.Index(image, "/"); i >= 0 {
+		namespace = image[:i]
+		name = image[i+1:]
+	}
+	return namespace, name, tag
+}
+
+// hubTagResponse mirrors the Docker Hub single-tag API response
+type hubTagResponse struct {
+	Digest string `json:"digest"`
+}
+
+// RemoteImageDigest returns the digest an image tag currently points at on Docker Hub
+func RemoteImageDigest(image string) (string, error) {
+	namespace, name, tag := ParseImageRef(image)
+	url := fmt.Sprintf("%s/v2/repositories/%s/%s/tags/%s", dockerHubAPIBase, namespace, name, tag)
+
+	resp, err := dockerRegistryHTTPClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry returned %s for %s", resp.Status, image)
+	}
+
+	var result hubTagResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.Digest == "" {
+		return "", fmt.Errorf("no digest published for %s", image)
+	}
+	return result.Digest, nil
+}
+
+// PullImageDigest pulls an image and returns the repository digest Docker resolved it to
+func PullImageDigest(image string) (string, error) {
+	pull := exec.Command("docker", "pull", "--quiet", image)
+	if output, err := pull.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("docker pull %s: %s", image, strings.TrimSpace(string(output)))
+	}
+
+	inspect := exec.Command("docker", "image", "inspect", "--format", "{{json .RepoDigests}}", image)
+	output, err := inspect.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker image inspect %s: %w", image, err)
+	}
+
+	var repoDigests []string
+	if err := json.Unmarshal(output, &repoDigests); err != nil {
+		return "", fmt.Errorf("failed to parse digests for %s: %w", image, err)
+	}
+	for _, rd := range repoDigests {
+		if i := strings.Index(rd, "@"); i >= 0 {
+			return rd[i+1:], nil
+		}
+	}
+	return "", fmt.Errorf("no repository digest found for %s", image)
+}
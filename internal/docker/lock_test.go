@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImageLock_SaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	empty, err := LoadImageLock(dir)
+	if err != nil {
+		t.Fatalf("LoadImageLock on missing file: %v", err)
+	}
+	if len(empty.Images) != 0 {
+		t.Fatalf("expected empty lock, got %v", empty.Images)
+	}
+
+	empty.Set("mysql:8.0", "sha256:aaa")
+	if err := empty.Save(dir); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadImageLock(dir)
+	if err != nil {
+		t.Fatalf("LoadImageLock: %v", err)
+	}
+	if got := loaded.Images["mysql:8.0"].Digest; got != "sha256:aaa" {
+		t.Errorf("digest = %q, want sha256:aaa", got)
+	}
+}
+
+func TestImageLock_Pin(t *testing.T) {
+	lock := &ImageLock{Images: map[string]LockedImage{"mysql:8.0": {Digest: "sha256:aaa"}}}
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"mysql:8.0", "mysql:8.0@sha256:aaa"},
+		{"redis:7-alpine", "redis:7-alpine"},
+	}
+	for _, tt := range tests {
+		if got := lock.Pin(tt.image); got != tt.want {
+			t.Errorf("Pin(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+
+	var nilLock *ImageLock
+	if got := nilLock.Pin("mysql:8.0"); got != "mysql:8.0" {
+		t.Errorf("nil lock Pin = %q, want unchanged", got)
+	}
+}
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		image, namespace, name, tag string
+	}{
+		{"mysql:8.0", "library", "mysql", "8.0"},
+		{"valkey/valkey:8-alpine", "valkey", "valkey", "8-alpine"},
+		{"opensearchproject/opensearch", "opensearchproject", "opensearch", "latest"},
+		{"mysql:8.0@sha256:aaa", "library", "mysql", "8.0"},
+	}
+	for _, tt := range tests {
+		ns, name, tag := ParseImageRef(tt.image)
+		if ns != tt.namespace || name != tt.name || tag != tt.tag {
+			t.Errorf("ParseImageRef(%q) = %s/%s:%s, want %s/%s:%s", tt.image, ns, name, tag, tt.namespace, tt.name, tt.tag)
+		}
+	}
+}
+
+func TestImageLock_CheckDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/repositories/library/mysql/tags/8.0":
+			fmt.Fprint(w, `{"digest":"sha256:aaa"}`)
+		case "/v2/repositories/opensearchproject/opensearch/tags/2":
+			fmt.Fprint(w, `{"digest":"sha256:new"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origBase := dockerHubAPIBase
+	dockerHubAPIBase = server.URL
+	defer func() { dockerHubAPIBase = origBase }()
+
+	lock := &ImageLock{Images: map[string]LockedImage{
+		"mysql:8.0":                      {Digest: "sha256:aaa"},
+		"opensearchproject/opensearch:2": {Digest: "sha256:old"},
+		"unknown:1":                      {Digest: "sha256:zzz"},
+	}}
+
+	drifts := lock.CheckDrift(RemoteImageDigest)
+	if len(drifts) != 1 {
+		t.Fatalf("CheckDrift = %v, want exactly one drift", drifts)
+	}
+	if drifts[0].Image != "opensearchproject/opensearch:2" || drifts[0].Upstream != "sha256:new" {
+		t.Errorf("unexpected drift %+v", drifts[0])
+	}
+}
+
+func TestShortDigest(t *testing.T) {
+	if got := ShortDigest("sha256:0123456789abcdef"); got != "sha256:0123456789ab" {
+		t.Errorf("ShortDigest = %q", got)
+	}
+	if got := ShortDigest("sha256:01"); got != "sha256:01" {
+		t.Errorf("ShortDigest short input = %q", got)
+	}
+}
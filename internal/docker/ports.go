@@ -0,0 +1,400 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+)
+
+// PortsFileName is the port registry in ~/.magebox
+const PortsFileName = "ports.json"
+
+// Host ports handed out when the conventional port of a service is taken
+const (
+	fallbackPortStart = 34000
+	fallbackPortEnd   = 34999
+)
+
+// Conventional host ports of the other shared services
+const (
+	redisPort              = 6379
+	rabbitMQPort           = 5672
+	rabbitMQManagementPort = 15672
+	mailpitSMTPPort        = 1025
+	mailpitWebPort         = 8025
+)
+
+// Registry names of the ports of the other shared services. Redis and Valkey
+// share one, only one of them runs. RabbitMQ and Mailpit have a second port
+// for their web UI.
+const (
+	CachePortName              = "redis"
+	RabbitMQPortName           = "rabbitmq"
+	RabbitMQManagementPortName = "rabbitmq-management"
+	MailpitSMTPPortName        = "mailpit"
+	MailpitWebPortName         = "mailpit-web"
+)
+
+// portServices are the compose services of the registry names that aren't
+// one themselves
+var portServices = map[string][]string{
+	CachePortName:              {"redis", "valkey"},
+	RabbitMQManagementPortName: {"rabbitmq"},
+	MailpitWebPortName:         {"mailpit"},
+}
+
+// portListening reports whether something listens on a host port. Tests
+// replace it.
+var portListening = func(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return true
+	}
+	l.Close()
+	return false
+}
+
+// PortInUse reports whether something listens on a host port
+func PortInUse(port int) bool {
+	return portListening(port)
+}
+
+// DBServiceName returns the compose service of a database version, e.g.
+// mysql80 for MySQL 8.0
+func DBServiceName(dbType, version string) string {
+	return dbType + strings.ReplaceAll(version, ".", "")
+}
+
+// SearchServiceName returns the compose service of a search engine version,
+// e.g. opensearch219 for OpenSearch 2.19
+func SearchServiceName(engine, version string) string {
+	return engine + strings.ReplaceAll(version, ".", "")
+}
+
+// DBPort returns the conventional host port of a database version: mysql,
+// mariadb or percona
+func DBPort(dbType, version string) int {
+	switch dbType {
+	case "mariadb":
+		return mariaDBPort(version)
+	case "percona":
+		return perconaPort(version)
+	default:
+		return mysqlPort(version)
+	}
+}
+
+// Conventional host ports of the database versions, one per version so
+// they can run side by side. The PortRegistry hands out others when they
+// are taken.
+func mysqlPort(version string) int {
+	ports := map[string]int{
+		"5.7": 33057,
+		"8.0": 33080,
+		"8.4": 33084,
+	}
+	if port, ok := ports[version]; ok {
+		return port
+	}
+	return 33080 // default
+}
+
+func mariaDBPort(version string) int {
+	ports := map[string]int{
+		"10.4":  33104,
+		"10.5":  33105,
+		"10.6":  33106,
+		"10.11": 33111,
+		"11.0":  33110,
+		"11.4":  33114,
+	}
+	if port, ok := ports[version]; ok {
+		return port
+	}
+	return 33106 // default
+}
+
+func perconaPort(version string) int {
+	ports := map[string]int{
+		"5.7": 33157,
+		"8.0": 33180,
+		"8.4": 33184,
+	}
+	if port, ok := ports[version]; ok {
+		return port
+	}
+	return 33180 // default
+}
+
+// PortReservation is the host port of a shared service and the projects
+// using it
+type PortReservation struct {
+	Port     int      `json:"port"`
+	Projects []string `json:"projects,omitempty"`
+}
+
+// PortMove is a service that got another host port because its port was
+// taken by another program, and the projects using it
+type PortMove struct {
+	Service  string
+	From     int
+	To       int
+	Projects []string
+}
+
+// PortRegistry reserves the host ports of the shared services. A service gets
+// its conventional port (33080 for MySQL 8.0, 6379 for Redis) unless
+// another service or program has it, then one from 34000-34999, and keeps it
+// across starts.
+type PortRegistry struct {
+	registryPath string
+	Services     map[string]PortReservation
+}
+
+// LoadPortRegistry loads the port registry of the platform
+func LoadPortRegistry(p *platform.Platform) (*PortRegistry, error) {
+	r := &PortRegistry{
+		registryPath: filepath.Join(p.MageBoxDir(), PortsFileName),
+		Services:     make(map[string]PortReservation),
+	}
+
+	data, err := os.ReadFile(r.registryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.Services); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", r.registryPath, err)
+	}
+	return r, nil
+}
+
+// LoadPorts returns the port registry of the platform, an empty one when it
+// can't be read so every service has its conventional port
+func LoadPorts(p *platform.Platform) *PortRegistry {
+	r, err := LoadPortRegistry(p)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// Save saves the registry to disk
+func (r *PortRegistry) Save() error {
+	data, err := json.MarshalIndent(r.Services, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.registryPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.registryPath, data, 0644)
+}
+
+// Port returns the host port of a service: the reserved one, or conventional
+// when it has none
+func (r *PortRegistry) Port(service string, conventional int) int {
+	if r == nil {
+		return conventional
+	}
+	if res, ok := r.Services[service]; ok {
+		return res.Port
+	}
+	return conventional
+}
+
+// DBPort returns the host port of a database version
+func (r *PortRegistry) DBPort(dbType, version string) int {
+	return r.Port(DBServiceName(dbType, version), DBPort(dbType, version))
+}
+
+// OpenSearchPort returns the host port of an OpenSearch version
+func (r *PortRegistry) OpenSearchPort(version string) int {
+	return r.Port(SearchServiceName("opensearch", version), GetOpenSearchPort(ResolveOpenSearchVersion(version)))
+}
+
+// ElasticsearchPort returns the host port of an Elasticsearch version
+func (r *PortRegistry) ElasticsearchPort(version string) int {
+	return r.Port(SearchServiceName("elasticsearch", version), GetElasticsearchPort(ResolveElasticsearchVersion(version)))
+}
+
+// CachePort returns the host port of the Redis or Valkey service
+func (r *PortRegistry) CachePort() int {
+	return r.Port(CachePortName, redisPort)
+}
+
+// RabbitMQPort returns the AMQP host port of RabbitMQ
+func (r *PortRegistry) RabbitMQPort() int {
+	return r.Port(RabbitMQPortName, rabbitMQPort)
+}
+
+// RabbitMQManagementPort returns the host port of the RabbitMQ management UI
+func (r *PortRegistry) RabbitMQManagementPort() int {
+	return r.Port(RabbitMQManagementPortName, rabbitMQManagementPort)
+}
+
+// MailpitSMTPPort returns the SMTP host port of Mailpit
+func (r *PortRegistry) MailpitSMTPPort() int {
+	return r.Port(MailpitSMTPPortName, mailpitSMTPPort)
+}
+
+// MailpitWebPort returns the host port of the Mailpit web UI
+func (r *PortRegistry) MailpitWebPort() int {
+	return r.Port(MailpitWebPortName, mailpitWebPort)
+}
+
+// Reserve returns the port of a service used by project, reserving one when
+// it has none. A reserved port another program listens on while the service
+// is down is replaced, and the move returned. A first reservation off the
+// conventional port is returned as a move too, env.php files written before
+// the registry use the conventional one.
+func (r *PortRegistry) Reserve(service string, conventional int, project string, running func(service string) bool) (int, *PortMove) {
+	res, ok := r.Services[service]
+	if project != "" && !slices.Contains(res.Projects, project) {
+		res.Projects = append(res.Projects, project)
+		sort.Strings(res.Projects)
+	}
+
+	var move *PortMove
+	switch {
+	case !ok:
+		res.Port = r.freePort(service, conventional, running)
+		if res.Port != conventional {
+			move = &PortMove{Service: service, From: conventional, To: res.Port, Projects: slices.Clone(res.Projects)}
+		}
+	case portListening(res.Port) && !running(service):
+		if port := r.freePort(service, conventional, running); port != res.Port {
+			move = &PortMove{Service: service, From: res.Port, To: port, Projects: slices.Clone(res.Projects)}
+			res.Port = port
+		}
+	}
+
+	r.Services[service] = res
+	return res.Port, move
+}
+
+// freePort returns the preferred port when it's free, or the service itself
+// has it, else the first free port of the fallback range
+func (r *PortRegistry) freePort(service string, preferred int, running func(service string) bool) int {
+	if !r.reserved(preferred, service) && (!portListening(preferred) || running(service)) {
+		return preferred
+	}
+	for port := fallbackPortStart; port <= fallbackPortEnd; port++ {
+		if !r.reserved(port, service) && !portListening(port) {
+			return port
+		}
+	}
+	return preferred
+}
+
+// reserved reports whether a service other than except has the port
+func (r *PortRegistry) reserved(port int, except string) bool {
+	for service, res := range r.Services {
+		if service != except && res.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+// projectServicePorts returns the conventional host ports of the shared
+// services of a project, keyed by compose service name or the registry names
+// of the other ports
+func projectServicePorts(cfg *config.Config) map[string]int {
+	ports := make(map[string]int)
+	if cfg.Services.HasMySQL() {
+		version := cfg.Services.MySQL.Version
+		ports[DBServiceName("mysql", version)] = mysqlPort(version)
+	}
+	if cfg.Services.HasMariaDB() {
+		version := cfg.Services.MariaDB.Version
+		ports[DBServiceName("mariadb", version)] = mariaDBPort(version)
+	}
+	if cfg.Services.HasPercona() {
+		version := cfg.Services.Percona.Version
+		ports[DBServiceName("percona", version)] = perconaPort(version)
+	}
+	if cfg.Services.HasOpenSearch() {
+		version := cfg.Services.OpenSearch.Version
+		ports[SearchServiceName("opensearch", version)] = GetOpenSearchPort(ResolveOpenSearchVersion(version))
+	}
+	if cfg.Services.HasElasticsearch() {
+		version := cfg.Services.Elasticsearch.Version
+		ports[SearchServiceName("elasticsearch", version)] = GetElasticsearchPort(ResolveElasticsearchVersion(version))
+	}
+	if cfg.Services.HasCacheService() {
+		ports[CachePortName] = redisPort
+	}
+	if cfg.Services.HasRabbitMQ() {
+		ports[RabbitMQPortName] = rabbitMQPort
+		ports[RabbitMQManagementPortName] = rabbitMQManagementPort
+	}
+	if !cfg.Services.MailpitDisabled() {
+		ports[MailpitSMTPPortName] = mailpitSMTPPort
+		ports[MailpitWebPortName] = mailpitWebPort
+	}
+	return ports
+}
+
+// reservePorts reserves the ports of the projects' shared services, moving
+// the ones taken by other programs
+func (g *ComposeGenerator) reservePorts(configs []*config.Config) error {
+	ports, err := LoadPortRegistry(g.platform)
+	if err != nil {
+		return err
+	}
+
+	serviceRunning := g.serviceRunning
+	if serviceRunning == nil {
+		serviceRunning = NewDockerController(g.ComposeFilePath()).IsServiceRunning
+	}
+	running := func(name string) bool {
+		services, ok := portServices[name]
+		if !ok {
+			return serviceRunning(name)
+		}
+		return slices.ContainsFunc(services, serviceRunning)
+	}
+
+	g.portMoves = nil
+	for _, cfg := range configs {
+		servicePorts := projectServicePorts(cfg)
+		services := make([]string, 0, len(servicePorts))
+		for service := range servicePorts {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+
+		for _, service := range services {
+			if _, move := ports.Reserve(service, servicePorts[service], cfg.Name, running); move != nil {
+				g.portMoves = append(g.portMoves, *move)
+			}
+		}
+	}
+
+	g.ports = ports
+	return ports.Save()
+}
+
+// servicePort returns the host port of a service, the conventional one when
+// it has no reservation
+func (g *ComposeGenerator) servicePort(service string, conventional int) int {
+	return g.ports.Port(service, conventional)
+}
+
+// PortMoves returns the services that got another host port when the
+// compose file was last generated
+func (g *ComposeGenerator) PortMoves() []PortMove {
+	return g.portMoves
+}
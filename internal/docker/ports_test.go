@@ -0,0 +1,168 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+// fakeListening makes the given ports look taken for the test
+func fakeListening(t *testing.T, ports ...int) {
+	t.Helper()
+	taken := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		taken[p] = true
+	}
+	orig := portListening
+	portListening = func(port int) bool { return taken[port] }
+	t.Cleanup(func() { portListening = orig })
+}
+
+func notRunning(string) bool { return false }
+
+func TestPortRegistry_Reserve(t *testing.T) {
+	fakeListening(t, 33080)
+	g, _ := setupTestComposeGenerator(t)
+	r, err := LoadPortRegistry(g.platform)
+	if err != nil {
+		t.Fatalf("LoadPortRegistry() error = %v", err)
+	}
+
+	if port, _ := r.Reserve("mysql57", 33057, "shop", notRunning); port != 33057 {
+		t.Errorf("mysql57 port = %d, want the conventional 33057", port)
+	}
+	port, move := r.Reserve("mysql80", 33080, "shop", notRunning)
+	if port != fallbackPortStart {
+		t.Errorf("mysql80 port = %d, want %d as 33080 is taken", port, fallbackPortStart)
+	}
+	if move == nil || move.From != 33080 || strings.Join(move.Projects, ",") != "shop" {
+		t.Errorf("mysql80 move = %+v, want one from the conventional 33080 for shop", move)
+	}
+	if port, _ := r.Reserve("opensearch219", fallbackPortStart, "blog", notRunning); port != fallbackPortStart+1 {
+		t.Errorf("opensearch219 port = %d, want %d as mysql80 has %d", port, fallbackPortStart+1, fallbackPortStart)
+	}
+
+	// A service keeps its port, and collects its projects
+	if port, move := r.Reserve("mysql80", 33080, "blog", notRunning); port != fallbackPortStart || move != nil {
+		t.Errorf("mysql80 port = %d, move = %v, want %d kept", port, move, fallbackPortStart)
+	}
+	if got := strings.Join(r.Services["mysql80"].Projects, ","); got != "blog,shop" {
+		t.Errorf("mysql80 projects = %s, want blog,shop", got)
+	}
+
+	// Its own running container doesn't count as a conflict
+	if port, _ := r.Reserve("mysql84", 33084, "shop", func(string) bool { return true }); port != 33084 {
+		t.Errorf("mysql84 port = %d, want 33084", port)
+	}
+}
+
+func TestPortRegistry_ReserveMovesTakenPort(t *testing.T) {
+	g, _ := setupTestComposeGenerator(t)
+	r, _ := LoadPortRegistry(g.platform)
+	r.Services["mariadb106"] = PortReservation{Port: 33106, Projects: []string{"shop"}}
+
+	fakeListening(t, 33106)
+	port, move := r.Reserve("mariadb106", 33106, "shop", notRunning)
+	if port != fallbackPortStart || move == nil || move.From != 33106 || move.To != fallbackPortStart {
+		t.Errorf("port = %d, move = %+v, want a move from 33106 to %d", port, move, fallbackPortStart)
+	}
+
+	if port, move := r.Reserve("mariadb106", 33106, "shop", notRunning); port != fallbackPortStart || move != nil {
+		t.Errorf("port = %d, move = %+v, want the new port kept", port, move)
+	}
+}
+
+func TestPortRegistry_Port(t *testing.T) {
+	var r *PortRegistry
+	if got := r.DBPort("mysql", "8.0"); got != 33080 {
+		t.Errorf("nil registry DBPort = %d, want 33080", got)
+	}
+
+	r = &PortRegistry{Services: map[string]PortReservation{"percona80": {Port: 34002}}}
+	if got := r.DBPort("percona", "8.0"); got != 34002 {
+		t.Errorf("DBPort(percona, 8.0) = %d, want 34002", got)
+	}
+	if got := r.DBPort("percona", "8.4"); got != 33184 {
+		t.Errorf("DBPort(percona, 8.4) = %d, want 33184", got)
+	}
+}
+
+func TestComposeGenerator_GenerateReservesPorts(t *testing.T) {
+	fakeListening(t, 33080)
+	g, tmpDir := setupTestComposeGenerator(t)
+	g.serviceRunning = notRunning
+
+	configs := []*config.Config{
+		{Name: "shop", Services: config.Services{MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"}}},
+		{Name: "blog", Services: config.Services{MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"}}},
+	}
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	compose, err := g.LoadComposeFile()
+	if err != nil {
+		t.Fatalf("LoadComposeFile() error = %v", err)
+	}
+	if ports := compose.Services["mysql80"].Ports; len(ports) == 0 || ports[0] != "34000:3306" {
+		t.Errorf("mysql80 ports = %v, want 34000:3306 first", ports)
+	}
+
+	// The reservation is saved and read back by new generators
+	if _, err := os.Stat(filepath.Join(tmpDir, ".magebox", PortsFileName)); err != nil {
+		t.Fatalf("ports.json not written: %v", err)
+	}
+	if got := LoadPorts(g.platform).DBPort("mysql", "8.0"); got != 34000 {
+		t.Errorf("reserved mysql80 port = %d, want 34000", got)
+	}
+	if got := NewComposeGenerator(g.platform).ProjectPorts(configs[0])["mysql80"]; len(got) == 0 || got[0] != 34000 {
+		t.Errorf("ProjectPorts mysql80 = %v, want 34000", got)
+	}
+}
+
+func TestComposeGenerator_GenerateMovesTakenRedisPort(t *testing.T) {
+	fakeListening(t, redisPort, mailpitSMTPPort)
+	g, _ := setupTestComposeGenerator(t)
+	g.serviceRunning = notRunning
+
+	configs := []*config.Config{
+		{Name: "shop", Services: config.Services{
+			Redis:    &config.ServiceConfig{Enabled: true},
+			RabbitMQ: &config.ServiceConfig{Enabled: true},
+		}},
+	}
+	if err := g.GenerateGlobalServices(configs); err != nil {
+		t.Fatalf("GenerateGlobalServices failed: %v", err)
+	}
+
+	compose, err := g.LoadComposeFile()
+	if err != nil {
+		t.Fatalf("LoadComposeFile() error = %v", err)
+	}
+	redisPorts := compose.Services["redis"].Ports
+	if len(redisPorts) != 1 || !strings.HasSuffix(redisPorts[0], ":6379") || redisPorts[0] == "6379:6379" {
+		t.Errorf("redis ports = %v, want a fallback port as 6379 is taken", redisPorts)
+	}
+	if got := compose.Services["rabbitmq"].Ports; len(got) != 2 || got[0] != "5672:5672" || got[1] != "15672:15672" {
+		t.Errorf("rabbitmq ports = %v, want the conventional ones", got)
+	}
+	if got := compose.Services["mailpit"].Ports; len(got) != 2 || got[0] == "1025:1025" || got[1] != "8025:8025" {
+		t.Errorf("mailpit ports = %v, want SMTP moved off the taken 1025", got)
+	}
+
+	ports := LoadPorts(g.platform)
+	if got := ports.CachePort(); got == redisPort || redisPorts[0] != fmt.Sprintf("%d:6379", got) {
+		t.Errorf("CachePort() = %d, want the port of the compose file", got)
+	}
+	var moved []string
+	for _, move := range g.PortMoves() {
+		moved = append(moved, move.Service)
+	}
+	if strings.Join(moved, ",") != "mailpit,redis" {
+		t.Errorf("moves = %v, want mailpit and redis", moved)
+	}
+}
@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"fmt"
+
+	"qoliber/magebox/internal/config"
+)
+
+// Compose service names of the profiler agents
+const (
+	BlackfireService = "blackfire"
+	TidewaysService  = "tideways"
+)
+
+// ProfilerService returns the compose service name of a profiler's agent
+func ProfilerService(profiler string) string {
+	switch profiler {
+	case config.ProfilerBlackfire:
+		return BlackfireService
+	case config.ProfilerTideways:
+		return TidewaysService
+	}
+	return ""
+}
+
+// getBlackfireAgentService returns the Blackfire agent service. The agent
+// authenticates with the server credentials of 'magebox blackfire config'.
+func (g *ComposeGenerator) getBlackfireAgentService(globalCfg *config.GlobalConfig) ComposeService {
+	env := map[string]string{"BLACKFIRE_LOG_LEVEL": "1"}
+	if globalCfg != nil {
+		creds := globalCfg.GetBlackfireCredentials()
+		env["BLACKFIRE_SERVER_ID"] = creds.ServerID
+		env["BLACKFIRE_SERVER_TOKEN"] = creds.ServerToken
+	}
+	return ComposeService{
+		ContainerName: "magebox-blackfire",
+		Image:         "blackfire/blackfire:2",
+		Ports:         []string{fmt.Sprintf("127.0.0.1:%d:8307", config.BlackfireAgentPort)},
+		Environment:   env,
+		Networks:      []string{"magebox"},
+		Restart:       "unless-stopped",
+	}
+}
+
+// getTidewaysDaemonService returns the Tideways daemon service, labelling
+// traces with the environment of 'magebox tideways config'
+func (g *ComposeGenerator) getTidewaysDaemonService(globalCfg *config.GlobalConfig) ComposeService {
+	environment := config.DefaultTidewaysEnvironment()
+	if globalCfg != nil {
+		environment = globalCfg.GetTidewaysCredentials().Environment
+	}
+	return ComposeService{
+		ContainerName: "magebox-tideways",
+		Image:         "ghcr.io/tideways/daemon:latest",
+		Ports:         []string{fmt.Sprintf("127.0.0.1:%d:9135", config.TidewaysDaemonPort)},
+		Command:       "--address=0.0.0.0:9135 --env=" + environment,
+		Networks:      []string{"magebox"},
+		Restart:       "unless-stopped",
+	}
+}
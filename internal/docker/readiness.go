@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// readinessInterval is how long WaitReady sleeps between probes
+const readinessInterval = time.Second
+
+// ReadinessResult is the outcome of waiting for one service to accept connections
+type ReadinessResult struct {
+	Service string
+	Ready   bool
+	Elapsed time.Duration
+	Err     error // Last probe error when the service did not become ready
+}
+
+// ReadinessProbe returns the command run inside a service container to check
+// that it accepts connections, nil for services without a probe
+func ReadinessProbe(serviceName string) []string {
+	switch {
+	case strings.HasPrefix(serviceName, "mysql"), strings.HasPrefix(serviceName, "percona"):
+		return []string{"mysqladmin", "ping", "-h", "localhost", "-uroot", "-p" + DefaultDBRootPassword, "--silent"}
+	case strings.HasPrefix(serviceName, "mariadb"):
+		return []string{"healthcheck.sh", "--connect", "--innodb_initialized"}
+	case serviceName == "redis":
+		return []string{"redis-cli", "ping"}
+	case serviceName == "valkey":
+		return []string{"valkey-cli", "ping"}
+	case strings.HasPrefix(serviceName, "opensearch-dashboards"):
+		return nil
+	case strings.HasPrefix(serviceName, "opensearch"), strings.HasPrefix(serviceName, "elasticsearch"):
+		return []string{"curl", "-fsS", "http://localhost:9200/_cluster/health?wait_for_status=yellow&timeout=1s"}
+	case serviceName == "rabbitmq":
+		return []string{"rabbitmq-diagnostics", "-q", "check_port_connectivity"}
+	}
+	return nil
+}
+
+// WaitReady polls the given services until each accepts connections or the
+// timeout expires. Services without a probe are reported ready right away.
+func (c *DockerController) WaitReady(services []string, timeout time.Duration) []ReadinessResult {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	results := make([]ReadinessResult, 0, len(services))
+
+	for _, service := range services {
+		probe := ReadinessProbe(service)
+		if probe == nil {
+			continue
+		}
+
+		result := ReadinessResult{Service: service}
+		for {
+			err := c.probe(service, probe)
+			if err == nil {
+				result.Ready = true
+				break
+			}
+			if c.ctx.Err() != nil || time.Now().After(deadline) {
+				result.Err = err
+				break
+			}
+			time.Sleep(readinessInterval)
+		}
+		result.Elapsed = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// probe runs a readiness probe once inside the service container
+func (c *DockerController) probe(service string, probe []string) error {
+	args := append([]string{"exec", "-T", service}, probe...)
+	output, err := c.compose(args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s", lastLine(msg))
+		}
+		return err
+	}
+	return nil
+}
+
+// lastLine returns the last line of a command's output
+func lastLine(s string) string {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
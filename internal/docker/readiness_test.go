@@ -0,0 +1,35 @@
+package docker
+
+import "testing"
+
+func TestReadinessProbe(t *testing.T) {
+	tests := []struct {
+		service string
+		want    string // First word of the probe, empty for none
+	}{
+		{"mysql80", "mysqladmin"},
+		{"percona84", "mysqladmin"},
+		{"mariadb1011", "healthcheck.sh"},
+		{"redis", "redis-cli"},
+		{"valkey", "valkey-cli"},
+		{"opensearch219", "curl"},
+		{"elasticsearch817", "curl"},
+		{"opensearch-dashboards219", ""},
+		{"rabbitmq", "rabbitmq-diagnostics"},
+		{"mailpit", ""},
+		{"varnish", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			probe := ReadinessProbe(tt.service)
+			got := ""
+			if len(probe) > 0 {
+				got = probe[0]
+			}
+			if got != tt.want {
+				t.Errorf("ReadinessProbe(%q) = %v, want %q", tt.service, probe, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package execctx runs external commands under a context with a per-operation
+// timeout.
+//
+// A hung docker compose or an nginx -t stuck on a dead NFS mount is killed when
+// its timeout expires and reported as a TimeoutError naming the setting to
+// raise. Commands are also tied to the command-line context, which is cancelled
+// on Ctrl+C, so child processes are torn down instead of outliving MageBox.
+package execctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// killGrace is how long a cancelled command gets to exit after SIGTERM
+// before it is killed
+const killGrace = 5 * time.Second
+
+// Timeouts are the per-operation limits for external commands. Zero disables
+// the limit.
+type Timeouts struct {
+	// Docker bounds docker and docker compose commands (pulls, up, exec)
+	Docker time.Duration
+	// Nginx bounds nginx -t, reloads and service start/stop
+	Nginx time.Duration
+	// Ready bounds how long start waits for services to accept connections
+	Ready time.Duration
+}
+
+// DefaultTimeouts returns the timeouts used when none are configured
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Docker: 10 * time.Minute,
+		Nginx:  time.Minute,
+		Ready:  2 * time.Minute,
+	}
+}
+
+var (
+	defaultsMu    sync.RWMutex
+	defaultCtx    = context.Background()
+	defaultLimits = DefaultTimeouts()
+)
+
+// SetDefaults sets the context and timeouts picked up by controllers that are
+// not given a context explicitly. The CLI sets them once at startup.
+func SetDefaults(ctx context.Context, t Timeouts) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaultCtx = ctx
+	defaultLimits = t
+}
+
+// Defaults returns the context and timeouts set with SetDefaults
+func Defaults() (context.Context, Timeouts) {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return defaultCtx, defaultLimits
+}
+
+// TimeoutError is returned when a command is killed because its timeout expired
+type TimeoutError struct {
+	Command string
+	Timeout time.Duration
+	// Setting is the timeouts key in the global config that controls the limit
+	Setting string
+}
+
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("%s timed out after %s", e.Command, e.Timeout)
+	if e.Setting != "" {
+		msg += fmt.Sprintf(" (raise timeouts.%s in ~/.magebox/config.yaml if it needs longer)", e.Setting)
+	}
+	return msg
+}
+
+// Cmd is an exec.Cmd bound to a context and timeout. Run, Output and
+// CombinedOutput release the timeout and translate context errors.
+type Cmd struct {
+	*exec.Cmd
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	setting string
+}
+
+// Command creates a command that is cancelled with ctx and killed after
+// timeout (when non-zero). setting names the timeouts key for error messages.
+func Command(ctx context.Context, timeout time.Duration, setting, name string, args ...string) *Cmd {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Ask politely first so docker compose and sudo can stop their own children
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killGrace
+
+	return &Cmd{Cmd: cmd, ctx: ctx, cancel: cancel, timeout: timeout, setting: setting}
+}
+
+// Run starts the command and waits for it to finish
+func (c *Cmd) Run() error {
+	defer c.cancel()
+	return c.wrap(c.Cmd.Run())
+}
+
+// Output runs the command and returns its standard output
+func (c *Cmd) Output() ([]byte, error) {
+	defer c.cancel()
+	out, err := c.Cmd.Output()
+	return out, c.wrap(err)
+}
+
+// CombinedOutput runs the command and returns its combined output
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	defer c.cancel()
+	out, err := c.Cmd.CombinedOutput()
+	return out, c.wrap(err)
+}
+
+// wrap replaces the exit error of a killed command with the reason it was killed
+func (c *Cmd) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(c.ctx.Err(), context.DeadlineExceeded):
+		return &TimeoutError{Command: c.String(), Timeout: c.timeout, Setting: c.setting}
+	case errors.Is(c.ctx.Err(), context.Canceled):
+		return fmt.Errorf("%s: %w", c.String(), context.Canceled)
+	}
+	return err
+}
+
+// String returns the command line without the binary path
+func (c *Cmd) String() string {
+	if len(c.Args) == 0 {
+		return c.Path
+	}
+	return strings.Join(c.Args, " ")
+}
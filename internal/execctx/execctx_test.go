@@ -0,0 +1,74 @@
+package execctx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommand_Success(t *testing.T) {
+	out, err := Command(context.Background(), time.Second, "docker", "echo", "hello").Output()
+	if err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "hello" {
+		t.Errorf("Output() = %q, want hello", out)
+	}
+}
+
+func TestCommand_ExitErrorUnchanged(t *testing.T) {
+	err := Command(context.Background(), time.Second, "docker", "false").Run()
+	if err == nil {
+		t.Fatal("Run() should fail")
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) || errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want plain exit error", err)
+	}
+}
+
+func TestCommand_Timeout(t *testing.T) {
+	start := time.Now()
+	err := Command(context.Background(), 100*time.Millisecond, "nginx", "sleep", "5").Run()
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Run() error = %v, want TimeoutError", err)
+	}
+	if timeoutErr.Setting != "nginx" || timeoutErr.Timeout != 100*time.Millisecond {
+		t.Errorf("TimeoutError = %+v", timeoutErr)
+	}
+	if !strings.Contains(err.Error(), "sleep 5 timed out after 100ms") || !strings.Contains(err.Error(), "timeouts.nginx") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command was not killed promptly, took %v", elapsed)
+	}
+}
+
+func TestCommand_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := Command(ctx, 0, "docker", "sleep", "5").Run()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	origCtx, origTimeouts := Defaults()
+	defer SetDefaults(origCtx, origTimeouts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	want := Timeouts{Docker: time.Minute, Nginx: time.Second}
+	SetDefaults(ctx, want)
+
+	gotCtx, got := Defaults()
+	if gotCtx != ctx || got != want {
+		t.Errorf("Defaults() = %v, %+v; want %v, %+v", gotCtx, got, ctx, want)
+	}
+}
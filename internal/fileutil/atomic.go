@@ -0,0 +1,83 @@
+// Package fileutil provides crash-safe file writes for generated configuration.
+//
+// Generators write through WriteFileAtomic so an interrupted run (Ctrl+C, a
+// killed terminal, power loss) leaves either the previous file or the new one
+// on disk, never a half-written config that breaks global services.
+//
+// Files users may tune by hand (vhosts, PHP-FPM pools, VCL, compose files)
+// go through WriteGenerated, which also keeps a checksum manifest to notice
+// manual edits instead of silently overwriting them.
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Validator checks a fully written temp file before it replaces the target.
+// Returning an error keeps the previous file in place.
+type Validator func(path string) error
+
+// WriteFileAtomic writes data to a temp file next to path, fsyncs it, runs the
+// validators against it and renames it over path. The parent directory is
+// fsynced afterwards so the rename itself survives a crash.
+//
+// The temp file starts with a dot and does not keep the original extension, so
+// glob includes like "vhosts/*.conf" never pick up a partial file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode, validators ...Validator) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temp file on every failure path
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+
+	for _, validate := range validators {
+		if err := validate(tmpPath); err != nil {
+			return fmt.Errorf("refusing to write invalid %s: %w", path, err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	success = true
+
+	// Best effort: not every platform supports syncing a directory
+	_ = syncDir(dir)
+	return nil
+}
+
+// syncDir flushes directory metadata (the rename) to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,134 @@
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mystore.conf")
+
+	if err := WriteFileAtomic(path, []byte("server { }\n"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "server { }\n" {
+		t.Errorf("content = %q", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileAtomic_ValidatorKeepsPreviousFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	if err := os.WriteFile(path, []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reject := func(string) error { return errors.New("broken") }
+	if err := WriteFileAtomic(path, []byte("garbage"), 0644, reject); err == nil {
+		t.Fatal("expected validator error")
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "services: {}\n" {
+		t.Errorf("previous file was replaced: %q", data)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestValidateYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "compose.yml")
+
+	if err := WriteFileAtomic(path, []byte("services:\n  redis:\n    image: redis\n"), 0644, ValidateYAML); err != nil {
+		t.Errorf("valid YAML rejected: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("services:\n  redis: [\n"), 0644, ValidateYAML); err == nil {
+		t.Error("truncated YAML accepted")
+	}
+}
+
+func TestValidateBraces(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"balanced", "server {\n    location / {\n    }\n}\n", false},
+		{"truncated", "server {\n    location / {\n", true},
+		{"extra closing", "server {\n}\n}\n", true},
+		{"braces in comments", "# don't count { here\nserver {\n    # or } here\n}\n", false},
+		{"braces in strings", "server {\n    return 200 '{\"ok\":true}';\n}\n", false},
+		{"glob include", "server {\n    include /projects/mystore/.magebox/nginx/*.conf;\n}\n", false},
+		{"double slash", "server {\n    location ~ ^//static/ {\n    }\n}\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.conf")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateBraces(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBraces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVCLBraces(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"balanced", "sub vcl_recv {\n    if (req.method == \"PURGE\") {\n    }\n}\n", false},
+		{"truncated", "sub vcl_recv {\n    if (true) {\n", true},
+		{"braces in comments", "# don't count { here\nsub vcl_recv {\n    // or } here\n    /* { */\n}\n", false},
+		{"long string", "sub vcl_synth {\n    synthetic({\"<html>}</html>\"});\n}\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "default.vcl")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateVCLBraces(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateVCLBraces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".conf" && filepath.Ext(e.Name()) != ".yml" {
+			t.Errorf("leftover temp file %s", e.Name())
+		}
+	}
+}
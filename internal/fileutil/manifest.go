@@ -0,0 +1,125 @@
+package fileutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// EditAction is what to do with a generated file that was edited by hand
+type EditAction int
+
+const (
+	// KeepEdits leaves the edited file in place
+	KeepEdits EditAction = iota
+	// Overwrite replaces the edited file with the generated content
+	Overwrite
+)
+
+// EditResolver decides what happens to a hand-edited generated file. current
+// is the file on disk, generated the content MageBox wants to write.
+type EditResolver func(path string, current, generated []byte) EditAction
+
+// manifestEntry is the state of one generated file
+type manifestEntry struct {
+	// Checksum is the content MageBox generated last
+	Checksum string `json:"checksum"`
+	// Kept is the hand-edited content the user chose to keep over Checksum
+	Kept string `json:"kept,omitempty"`
+}
+
+// checksumManifest records the checksum of every file MageBox generates, so
+// a later run can tell a hand-tuned file from one it wrote itself
+type checksumManifest struct {
+	path    string
+	resolve EditResolver
+	mu      sync.Mutex
+}
+
+var (
+	manifestMu sync.Mutex
+	manifest   *checksumManifest
+)
+
+// SetManifest enables edit detection for WriteGenerated, with checksums kept
+// in the JSON file at path. A nil resolver keeps every edited file.
+func SetManifest(path string, resolve EditResolver) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	if resolve == nil {
+		resolve = func(string, []byte, []byte) EditAction { return KeepEdits }
+	}
+	manifest = &checksumManifest{path: path, resolve: resolve}
+}
+
+// Checksum returns the hex SHA-256 of data
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteGenerated writes a generated file like WriteFileAtomic and records its
+// checksum. When the file changed since MageBox last wrote it, the resolver
+// decides whether the edit is kept. A kept edit is only brought up again once
+// MageBox generates something different for the file.
+//
+// Without SetManifest it is the same as WriteFileAtomic.
+func WriteGenerated(path string, data []byte, perm os.FileMode, validators ...Validator) error {
+	manifestMu.Lock()
+	m := manifest
+	manifestMu.Unlock()
+	if m == nil {
+		return WriteFileAtomic(path, data, perm, validators...)
+	}
+	return m.write(path, data, perm, validators)
+}
+
+func (m *checksumManifest) write(path string, data []byte, perm os.FileMode, validators []Validator) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := m.load()
+	entry, known := entries[path]
+	generated := Checksum(data)
+
+	if current, err := os.ReadFile(path); err == nil && known {
+		sum := Checksum(current)
+		edited := sum != entry.Checksum && sum != generated
+		if edited && sum == entry.Kept && generated == entry.Checksum {
+			return nil
+		}
+		if edited && m.resolve(path, current, data) == KeepEdits {
+			entries[path] = manifestEntry{Checksum: generated, Kept: sum}
+			return m.save(entries)
+		}
+	}
+
+	if err := WriteFileAtomic(path, data, perm, validators...); err != nil {
+		return err
+	}
+	entries[path] = manifestEntry{Checksum: generated}
+	return m.save(entries)
+}
+
+// load reads the manifest, treating a missing or unreadable one as empty
+func (m *checksumManifest) load() map[string]manifestEntry {
+	entries := make(map[string]manifestEntry)
+	if data, err := os.ReadFile(m.path); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return entries
+}
+
+func (m *checksumManifest) save(entries map[string]manifestEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(m.path, data, 0644)
+}
@@ -0,0 +1,105 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useManifest enables the manifest for a test with a resolver that answers
+// action and counts its calls
+func useManifest(t *testing.T, action *EditAction) *int {
+	t.Helper()
+	calls := 0
+	SetManifest(filepath.Join(t.TempDir(), "generated.json"), func(string, []byte, []byte) EditAction {
+		calls++
+		return *action
+	})
+	t.Cleanup(func() {
+		manifestMu.Lock()
+		manifest = nil
+		manifestMu.Unlock()
+	})
+	return &calls
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteGenerated_UneditedFilesAreOverwritten(t *testing.T) {
+	action := KeepEdits
+	calls := useManifest(t, &action)
+	path := filepath.Join(t.TempDir(), "mystore.conf")
+
+	// A file MageBox didn't record yet is taken over without asking
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"v1\n", "v2\n"} {
+		if err := WriteGenerated(path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteGenerated() error = %v", err)
+		}
+		if got := readString(t, path); got != content {
+			t.Errorf("content = %q, want %q", got, content)
+		}
+	}
+	if *calls != 0 {
+		t.Errorf("resolver called %d times for unedited files", *calls)
+	}
+}
+
+func TestWriteGenerated_KeepEdits(t *testing.T) {
+	action := KeepEdits
+	calls := useManifest(t, &action)
+	path := filepath.Join(t.TempDir(), "mystore.conf")
+
+	if err := WriteGenerated(path, []byte("v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("tuned\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteGenerated(path, []byte("v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, path); got != "tuned\n" || *calls != 1 {
+		t.Fatalf("content = %q after %d prompts, want the edit kept after 1 prompt", got, *calls)
+	}
+
+	// Generating the same content again doesn't ask again
+	if err := WriteGenerated(path, []byte("v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 {
+		t.Errorf("resolver called again for unchanged generated content")
+	}
+
+	// New generated content brings the edit up again
+	action = Overwrite
+	if err := WriteGenerated(path, []byte("v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, path); got != "v2\n" || *calls != 2 {
+		t.Errorf("content = %q after %d prompts, want v2 after 2 prompts", got, *calls)
+	}
+}
+
+func TestWriteGenerated_WithoutManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mystore.conf")
+	if err := os.WriteFile(path, []byte("tuned\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteGenerated(path, []byte("v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readString(t, path); got != "v1\n" {
+		t.Errorf("content = %q, want v1", got)
+	}
+}
@@ -0,0 +1,99 @@
+package fileutil
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateYAML checks that a file parses as YAML
+func ValidateYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var out interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	return nil
+}
+
+// ValidateBraces checks that the curly braces of an nginx or PHP-FPM config
+// are balanced. Braces inside quoted strings and # comments are ignored.
+func ValidateBraces(path string) error {
+	return validateBraces(path, false)
+}
+
+// ValidateVCLBraces checks that the curly braces of a VCL file are balanced.
+// Braces inside quoted strings, long strings ({"..."}) and comments (#, //,
+// /* */) are ignored.
+func ValidateVCLBraces(path string) error {
+	return validateBraces(path, true)
+}
+
+func validateBraces(path string, vcl bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if depth, line := braceDepth(string(data), vcl); depth != 0 {
+		if depth < 0 {
+			return fmt.Errorf("unexpected '}' on line %d", line)
+		}
+		return fmt.Errorf("%d unclosed '{'", depth)
+	}
+	return nil
+}
+
+// braceDepth returns the final brace depth, or the negative depth and line
+// number of the first unmatched closing brace. Only VCL has // and /* */
+// comments and long strings: in nginx, /* is part of paths like
+// include snippets/*.conf.
+func braceDepth(s string, vcl bool) (int, int) {
+	depth, line := 0, 1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == '#' || (vcl && c == '/' && i+1 < len(s) && s[i+1] == '/'):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			line++
+		case vcl && c == '/' && i+1 < len(s) && s[i+1] == '*':
+			for i += 2; i+1 < len(s) && !(s[i] == '*' && s[i+1] == '/'); i++ {
+				if s[i] == '\n' {
+					line++
+				}
+			}
+			i++
+		case vcl && c == '{' && i+1 < len(s) && s[i+1] == '"':
+			for i += 2; i+1 < len(s) && !(s[i] == '"' && s[i+1] == '}'); i++ {
+				if s[i] == '\n' {
+					line++
+				}
+			}
+			i++
+		case c == '"' || c == '\'':
+			for i++; i < len(s) && s[i] != c && s[i] != '\n'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i < len(s) && s[i] == '\n' {
+				line++
+			}
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth < 0 {
+				return depth, line
+			}
+		}
+	}
+	return depth, line
+}
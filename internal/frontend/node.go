@@ -0,0 +1,370 @@
+// Package frontend finds the Node.js version and the theme watchers of a
+// project, so theme builds run with the project's toolchain instead of
+// whatever node happens to be first on PATH.
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sources of a project's Node.js version, in the order they are checked
+const (
+	SourceConfig      = ".magebox.yaml"
+	SourceNvmrc       = ".nvmrc"
+	SourceNodeVersion = ".node-version"
+	SourcePackageJSON = "package.json"
+)
+
+// Node version managers MageBox finds installations of
+const (
+	ManagerNvm    = "nvm"
+	ManagerFnm    = "fnm"
+	ManagerVolta  = "volta"
+	ManagerSystem = "system"
+)
+
+// ltsCodenames maps the codenames of Node.js LTS lines, as used in .nvmrc
+// ("lts/iron"), to their major version
+var ltsCodenames = map[string]int{
+	"argon": 4, "boron": 6, "carbon": 8, "dubnium": 10, "erbium": 12,
+	"fermium": 14, "gallium": 16, "hydrogen": 18, "iron": 20, "jod": 22, "krypton": 24,
+}
+
+// Requirement is the Node.js version a project asks for
+type Requirement struct {
+	Version string // As written, e.g. "20", "v20.11.1", "lts/iron" or ">=18 <23"
+	Source  string // Where it was found, one of the Source constants
+}
+
+// DetectRequirement returns the Node.js version of a project: the one set in
+// its config, else the one of .nvmrc, .node-version or engines.node of its
+// package.json. ok is false when the project pins none.
+func DetectRequirement(projectPath, configured string) (req Requirement, ok bool) {
+	if configured != "" {
+		return Requirement{Version: configured, Source: SourceConfig}, true
+	}
+	for _, name := range []string{SourceNvmrc, SourceNodeVersion} {
+		data, err := os.ReadFile(filepath.Join(projectPath, name))
+		if err != nil {
+			continue
+		}
+		// Only the first line counts, nvm ignores trailing comments
+		line, _, _ := strings.Cut(string(data), "\n")
+		line, _, _ = strings.Cut(line, "#")
+		if v := strings.TrimSpace(line); v != "" {
+			return Requirement{Version: v, Source: name}, true
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectPath, SourcePackageJSON))
+	if err != nil {
+		return Requirement{}, false
+	}
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if json.Unmarshal(data, &pkg) == nil {
+		if v := strings.TrimSpace(pkg.Engines["node"]); v != "" {
+			return Requirement{Version: v, Source: SourcePackageJSON}, true
+		}
+	}
+	return Requirement{}, false
+}
+
+// Matches reports whether an installed version, e.g. "20.11.1", satisfies the
+// requirement. Versions, x-ranges ("20", "20.x"), LTS aliases and npm style
+// ranges (">=18 <21", "^20.9", "18 || 20") are understood.
+func (r Requirement) Matches(version string) bool {
+	installed, _, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+
+	want := strings.ToLower(strings.TrimSpace(r.Version))
+	switch {
+	case want == "" || want == "*" || want == "node" || want == "stable" || want == "latest":
+		return true
+	case want == "lts/*" || want == "lts":
+		// Even majors are the LTS lines
+		return installed[0]%2 == 0
+	case strings.HasPrefix(want, "lts/"):
+		return installed[0] == ltsCodenames[strings.TrimPrefix(want, "lts/")]
+	}
+
+	for _, alternative := range strings.Split(want, "||") {
+		comparators := strings.Fields(alternative)
+		if len(comparators) == 0 {
+			continue
+		}
+		all := true
+		for _, c := range comparators {
+			if !matchComparator(installed, c) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallVersion returns the version to hand to a version manager to install
+// a Node.js satisfying the requirement. For ranges it is the major version of
+// the first bound.
+func (r Requirement) InstallVersion() string {
+	want := strings.ToLower(strings.TrimSpace(r.Version))
+	switch {
+	case want == "lts/*" || want == "lts":
+		return "lts"
+	case want == "" || want == "*" || want == "node" || want == "stable" || want == "latest":
+		return "latest"
+	case strings.HasPrefix(want, "lts/"):
+		if major, ok := ltsCodenames[strings.TrimPrefix(want, "lts/")]; ok {
+			return strconv.Itoa(major)
+		}
+		return "lts"
+	}
+
+	first := strings.Fields(strings.Split(want, "||")[0])
+	if len(first) == 0 {
+		return "lts"
+	}
+	op, v := splitOperator(first[0])
+	parts, wildcards, ok := parseVersion(v)
+	if !ok {
+		return "lts"
+	}
+	if op == "" || op == "=" {
+		// An exact version or x-range installs as written
+		return strings.Join(strings.Split(strings.TrimPrefix(v, "v"), ".")[:3-wildcards], ".")
+	}
+	return strconv.Itoa(parts[0])
+}
+
+// matchComparator checks an installed version against one comparator of a
+// range, e.g. ">=18", "^20.9.0" or "20.x"
+func matchComparator(installed [3]int, comparator string) bool {
+	op, v := splitOperator(comparator)
+	want, wildcards, ok := parseVersion(v)
+	if !ok {
+		return false
+	}
+	given := 3 - wildcards
+	cmp := compareVersions(installed, want)
+
+	switch op {
+	case "", "=":
+		for i := 0; i < given; i++ {
+			if installed[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	case "^":
+		return cmp >= 0 && installed[0] == want[0]
+	case "~":
+		if given == 1 {
+			return cmp >= 0 && installed[0] == want[0]
+		}
+		return cmp >= 0 && installed[0] == want[0] && installed[1] == want[1]
+	}
+	return false
+}
+
+// splitOperator splits ">=18.0" into ">=" and "18.0"
+func splitOperator(comparator string) (op, version string) {
+	for _, candidate := range []string{">=", "<=", ">", "<", "^", "~", "="} {
+		if strings.HasPrefix(comparator, candidate) {
+			return candidate, strings.TrimSpace(comparator[len(candidate):])
+		}
+	}
+	return "", comparator
+}
+
+// parseVersion parses "v20.11.1", "20.11" or "20.x" into its numbers. Missing
+// and x components are 0 and counted as wildcards.
+func parseVersion(version string) (parts [3]int, wildcards int, ok bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	fields := strings.Split(version, ".")
+	if version == "" || len(fields) > 3 {
+		return parts, 0, false
+	}
+	for i := 0; i < 3; i++ {
+		if i >= len(fields) || fields[i] == "x" || fields[i] == "X" || fields[i] == "*" {
+			wildcards = 3 - i
+			return parts, wildcards, i > 0
+		}
+		// Pre-release suffixes ("22.0.0-rc.1") don't affect matching
+		field, _, _ := strings.Cut(fields[i], "-")
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, 0, false
+		}
+		parts[i] = n
+	}
+	return parts, 0, true
+}
+
+// compareVersions returns -1, 0 or 1 as a is lower, equal or higher than b
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Node is a Node.js installation
+type Node struct {
+	Version string // e.g. "20.11.1"
+	BinDir  string // Directory holding node, npm and npx
+	Manager string // One of the Manager constants
+}
+
+// Env returns environ with the installation's bin directory first on PATH
+func (n *Node) Env(environ []string) []string {
+	env := make([]string, 0, len(environ)+1)
+	path := n.BinDir
+	for _, kv := range environ {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path += string(os.PathListSeparator) + v
+			continue
+		}
+		env = append(env, kv)
+	}
+	return append(env, "PATH="+path)
+}
+
+// InstalledNodes returns the Node.js versions installed with nvm, fnm or
+// Volta, newest first
+func InstalledNodes(homeDir string) []Node {
+	var nodes []Node
+	add := func(manager, dir, binSuffix string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			version := strings.TrimPrefix(e.Name(), "v")
+			if _, _, ok := parseVersion(version); !ok {
+				continue
+			}
+			bin := filepath.Join(dir, e.Name(), binSuffix)
+			if _, err := os.Stat(filepath.Join(bin, "node")); err != nil {
+				continue
+			}
+			nodes = append(nodes, Node{Version: version, BinDir: bin, Manager: manager})
+		}
+	}
+
+	add(ManagerNvm, filepath.Join(nvmDir(homeDir), "versions", "node"), "bin")
+	for _, dir := range fnmDirs(homeDir) {
+		add(ManagerFnm, filepath.Join(dir, "node-versions"), filepath.Join("installation", "bin"))
+	}
+	add(ManagerVolta, filepath.Join(homeDir, ".volta", "tools", "image", "node"), "bin")
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, _, _ := parseVersion(nodes[i].Version)
+		b, _, _ := parseVersion(nodes[j].Version)
+		return compareVersions(a, b) > 0
+	})
+	return nodes
+}
+
+// SystemNode returns the node first on PATH, nil when there is none
+func SystemNode() *Node {
+	bin, err := exec.LookPath("node")
+	if err != nil {
+		return nil
+	}
+	out, err := exec.Command(bin, "--version").Output()
+	if err != nil {
+		return nil
+	}
+	return &Node{Version: strings.TrimPrefix(strings.TrimSpace(string(out)), "v"), BinDir: filepath.Dir(bin), Manager: ManagerSystem}
+}
+
+// Resolve finds the newest installed Node.js satisfying the requirement,
+// preferring version manager installations over the system node
+func Resolve(req Requirement, homeDir string) (*Node, error) {
+	for _, n := range InstalledNodes(homeDir) {
+		if req.Matches(n.Version) {
+			return &n, nil
+		}
+	}
+	if system := SystemNode(); system != nil && req.Matches(system.Version) {
+		return system, nil
+	}
+	return nil, fmt.Errorf("node %s (from %s) is not installed", req.Version, req.Source)
+}
+
+// InstallCommand returns the command installing a Node.js satisfying the
+// requirement with fnm, or nvm when fnm is not installed
+func InstallCommand(req Requirement, homeDir string) (*exec.Cmd, error) {
+	version := req.InstallVersion()
+	if fnm, err := exec.LookPath("fnm"); err == nil {
+		switch version {
+		case "lts":
+			return exec.Command(fnm, "install", "--lts"), nil
+		case "latest":
+			return exec.Command(fnm, "install", "--latest"), nil
+		}
+		return exec.Command(fnm, "install", version), nil
+	}
+
+	script := filepath.Join(nvmDir(homeDir), "nvm.sh")
+	if _, err := os.Stat(script); err == nil {
+		arg := version
+		switch version {
+		case "lts":
+			arg = "--lts"
+		case "latest":
+			arg = "node"
+		}
+		// nvm is a shell function, it only exists once nvm.sh is sourced
+		return exec.Command("bash", "-c", `. "$0" && nvm install "$1"`, script, arg), nil
+	}
+	return nil, fmt.Errorf("neither fnm nor nvm is installed")
+}
+
+// nvmDir returns the nvm directory, $NVM_DIR or ~/.nvm
+func nvmDir(homeDir string) string {
+	if dir := os.Getenv("NVM_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(homeDir, ".nvm")
+}
+
+// fnmDirs returns the directories fnm may keep its versions in
+func fnmDirs(homeDir string) []string {
+	if dir := os.Getenv("FNM_DIR"); dir != "" {
+		return []string{dir}
+	}
+	dirs := []string{filepath.Join(homeDir, ".local", "share", "fnm"), filepath.Join(homeDir, ".fnm")}
+	if runtime.GOOS == "darwin" {
+		dirs = append(dirs, filepath.Join(homeDir, "Library", "Application Support", "fnm"))
+	}
+	return dirs
+}
@@ -0,0 +1,139 @@
+package frontend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetectRequirement(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := DetectRequirement(dir, ""); ok {
+		t.Error("a project without version files should pin no version")
+	}
+
+	writeFile(t, filepath.Join(dir, "package.json"), `{"engines": {"node": ">=18 <23"}}`)
+	if req, _ := DetectRequirement(dir, ""); req.Version != ">=18 <23" || req.Source != SourcePackageJSON {
+		t.Errorf("engines.node: got %+v", req)
+	}
+
+	writeFile(t, filepath.Join(dir, ".node-version"), "20.11.1\n")
+	if req, _ := DetectRequirement(dir, ""); req.Version != "20.11.1" || req.Source != SourceNodeVersion {
+		t.Errorf(".node-version: got %+v", req)
+	}
+
+	writeFile(t, filepath.Join(dir, ".nvmrc"), "lts/iron # the shop's LTS\n")
+	if req, _ := DetectRequirement(dir, ""); req.Version != "lts/iron" || req.Source != SourceNvmrc {
+		t.Errorf(".nvmrc: got %+v", req)
+	}
+
+	if req, _ := DetectRequirement(dir, "22"); req.Version != "22" || req.Source != SourceConfig {
+		t.Errorf("config: got %+v", req)
+	}
+}
+
+func TestRequirement_Matches(t *testing.T) {
+	tests := []struct {
+		want    string
+		version string
+		match   bool
+	}{
+		{"20", "20.11.1", true},
+		{"v20", "22.1.0", false},
+		{"20.11", "20.11.1", true},
+		{"20.11", "20.12.0", false},
+		{"20.x", "20.0.0", true},
+		{"v20.11.1", "20.11.1", true},
+		{"lts/iron", "20.18.0", true},
+		{"lts/iron", "22.11.0", false},
+		{"lts/*", "22.11.0", true},
+		{"lts/*", "23.1.0", false},
+		{"node", "23.1.0", true},
+		{">=18", "22.0.0", true},
+		{">=18 <21", "22.0.0", false},
+		{"^20.9.0", "20.10.0", true},
+		{"^20.9.0", "20.8.0", false},
+		{"~20.9", "20.9.5", true},
+		{"~20.9", "20.10.0", false},
+		{"18.x || 20.x", "20.1.0", true},
+		{"18.x || 20.x", "22.1.0", false},
+		{"20", "not-a-version", false},
+	}
+	for _, tt := range tests {
+		if got := (Requirement{Version: tt.want}).Matches(tt.version); got != tt.match {
+			t.Errorf("Requirement(%q).Matches(%q) = %v, want %v", tt.want, tt.version, got, tt.match)
+		}
+	}
+}
+
+func TestRequirement_InstallVersion(t *testing.T) {
+	tests := map[string]string{
+		"20":           "20",
+		"v20.11.1":     "20.11.1",
+		"20.x":         "20",
+		"lts/iron":     "20",
+		"lts/*":        "lts",
+		"node":         "latest",
+		">=18 <23":     "18",
+		"^20.9.0":      "20",
+		"18.x || 20.x": "18",
+	}
+	for want, version := range tests {
+		if got := (Requirement{Version: want}).InstallVersion(); got != version {
+			t.Errorf("Requirement(%q).InstallVersion() = %q, want %q", want, got, version)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("NVM_DIR", "")
+	t.Setenv("FNM_DIR", "")
+	writeFile(t, filepath.Join(home, ".nvm", "versions", "node", "v18.20.4", "bin", "node"), "")
+	writeFile(t, filepath.Join(home, ".nvm", "versions", "node", "v20.11.1", "bin", "node"), "")
+	writeFile(t, filepath.Join(home, ".local", "share", "fnm", "node-versions", "v20.18.0", "installation", "bin", "node"), "")
+	// A version directory without a node binary is an aborted install
+	if err := os.MkdirAll(filepath.Join(home, ".nvm", "versions", "node", "v22.0.0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := InstalledNodes(home)
+	var versions []string
+	for _, n := range nodes {
+		versions = append(versions, n.Manager+" "+n.Version)
+	}
+	if got := strings.Join(versions, ", "); got != "fnm 20.18.0, nvm 20.11.1, nvm 18.20.4" {
+		t.Errorf("InstalledNodes() = %s", got)
+	}
+
+	node, err := Resolve(Requirement{Version: "20", Source: SourceNvmrc}, home)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if node.Version != "20.18.0" || node.BinDir != filepath.Join(home, ".local", "share", "fnm", "node-versions", "v20.18.0", "installation", "bin") {
+		t.Errorf("Resolve() = %+v, want the newest 20", node)
+	}
+
+	if node, err := Resolve(Requirement{Version: "18.20"}, home); err != nil || node.Manager != ManagerNvm {
+		t.Errorf("Resolve(18.20) = %+v, %v", node, err)
+	}
+}
+
+func TestNode_Env(t *testing.T) {
+	node := &Node{BinDir: "/home/me/.nvm/versions/node/v20.11.1/bin"}
+	env := node.Env([]string{"HOME=/home/me", "PATH=/usr/bin:/bin"})
+	if len(env) != 2 || env[1] != "PATH=/home/me/.nvm/versions/node/v20.11.1/bin:/usr/bin:/bin" {
+		t.Errorf("Env() = %v", env)
+	}
+}
@@ -0,0 +1,184 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Watchers of a theme, by the build setup it ships with
+const (
+	WatcherHyva  = "hyva"  // Hyvä's Tailwind build in web/tailwind
+	WatcherVite  = "vite"  // A Vite dev server, for headless and Vite based themes
+	WatcherNPM   = "npm"   // Any other package.json with a watch script
+	WatcherGrunt = "grunt" // Magento's Grunt LESS build of Luma based themes
+)
+
+// viteConfigs are the file names of a Vite config
+var viteConfigs = []string{"vite.config.js", "vite.config.ts", "vite.config.mjs", "vite.config.mts", "vite.config.cjs"}
+
+// Theme is a frontend theme of a Magento project
+type Theme struct {
+	Name    string // Vendor/theme
+	Dir     string // Absolute theme directory
+	Watcher string // One of the Watcher constants, empty when the theme has no build setup
+	WorkDir string // Directory the watcher runs in
+}
+
+// WatchArgs returns the command line of the theme's watcher. A Vite dev
+// server is started on devServerPort when it is set.
+func (t *Theme) WatchArgs(devServerPort int) []string {
+	switch t.Watcher {
+	case WatcherHyva, WatcherNPM:
+		return []string{"npm", "run", "watch"}
+	case WatcherVite:
+		args := []string{"npm", "run", "dev"}
+		if devServerPort > 0 && strings.HasPrefix(packageScripts(filepath.Join(t.WorkDir, "package.json"))["dev"], "vite") {
+			args = append(args, "--", "--port", strconv.Itoa(devServerPort), "--strictPort")
+		}
+		return args
+	case WatcherGrunt:
+		// Magento's Gruntfile knows the theme by the key it is registered
+		// with in dev/tools/grunt/configs/local-themes.js
+		key := t.GruntKey()
+		return []string{"npx", "grunt", "exec:" + key, "less:" + key, "watch"}
+	}
+	return nil
+}
+
+// GruntKey returns the key the theme is expected under in Magento's Grunt
+// theme list, its lowercased directory name
+func (t *Theme) GruntKey() string {
+	return strings.ToLower(filepath.Base(t.Dir))
+}
+
+// NeedsInstall reports whether the npm dependencies of the watcher are missing
+func (t *Theme) NeedsInstall() bool {
+	if t.WorkDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(t.WorkDir, "node_modules"))
+	return os.IsNotExist(err)
+}
+
+// FindThemes returns the themes under app/design/frontend, sorted by name
+func FindThemes(projectPath string) []Theme {
+	designDir := filepath.Join(projectPath, "app", "design", "frontend")
+	vendors, err := os.ReadDir(designDir)
+	if err != nil {
+		return nil
+	}
+
+	_, gruntErr := os.Stat(filepath.Join(projectPath, "Gruntfile.js"))
+	hasGrunt := gruntErr == nil
+
+	var themes []Theme
+	for _, vendor := range vendors {
+		if !vendor.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(designDir, vendor.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			theme := Theme{
+				Name: vendor.Name() + "/" + entry.Name(),
+				Dir:  filepath.Join(designDir, vendor.Name(), entry.Name()),
+			}
+			detectWatcher(&theme, projectPath, hasGrunt)
+			themes = append(themes, theme)
+		}
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].Name < themes[j].Name })
+	return themes
+}
+
+// FindTheme returns the theme with the given Vendor/theme name
+func FindTheme(projectPath, name string) (*Theme, error) {
+	themes := FindThemes(projectPath)
+	for i := range themes {
+		if strings.EqualFold(themes[i].Name, name) {
+			return &themes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("theme %s not found in app/design/frontend", name)
+}
+
+// WatchableThemes returns the themes that have a watcher
+func WatchableThemes(projectPath string) []Theme {
+	var watchable []Theme
+	for _, t := range FindThemes(projectPath) {
+		if t.Watcher != "" {
+			watchable = append(watchable, t)
+		}
+	}
+	return watchable
+}
+
+// detectWatcher sets the watcher of a theme from the build setup it ships
+// with: Hyvä's web/tailwind, a Vite config, a package.json with a watch
+// script anywhere in the theme, or Magento's Gruntfile for Luma based themes
+func detectWatcher(t *Theme, projectPath string, hasGrunt bool) {
+	tailwind := filepath.Join(t.Dir, "web", "tailwind")
+	if _, ok := packageScripts(filepath.Join(tailwind, "package.json"))["watch"]; ok {
+		t.Watcher, t.WorkDir = WatcherHyva, tailwind
+		return
+	}
+
+	if _, ok := packageScripts(filepath.Join(t.Dir, "package.json"))["dev"]; ok {
+		for _, name := range viteConfigs {
+			if _, err := os.Stat(filepath.Join(t.Dir, name)); err == nil {
+				t.Watcher, t.WorkDir = WatcherVite, t.Dir
+				return
+			}
+		}
+	}
+
+	_ = filepath.WalkDir(t.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && (d.Name() == "node_modules" || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		if d.Name() == "package.json" {
+			if _, ok := packageScripts(path)["watch"]; ok {
+				t.Watcher, t.WorkDir = WatcherNPM, filepath.Dir(path)
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	if t.Watcher != "" {
+		return
+	}
+
+	if hasGrunt {
+		if _, err := os.Stat(filepath.Join(t.Dir, "theme.xml")); err == nil {
+			t.Watcher, t.WorkDir = WatcherGrunt, projectPath
+		}
+	}
+}
+
+// packageScripts returns the scripts of a package.json, nil when it can't be read
+func packageScripts(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil
+	}
+	return pkg.Scripts
+}
@@ -0,0 +1,80 @@
+package frontend
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindThemes(t *testing.T) {
+	project := t.TempDir()
+	design := filepath.Join(project, "app", "design", "frontend")
+	writeFile(t, filepath.Join(design, "Acme", "hyva", "web", "tailwind", "package.json"), `{"scripts": {"watch": "tailwindcss --watch"}}`)
+	writeFile(t, filepath.Join(design, "Acme", "headless", "package.json"), `{"scripts": {"dev": "vite"}}`)
+	writeFile(t, filepath.Join(design, "Acme", "headless", "vite.config.ts"), "")
+	writeFile(t, filepath.Join(design, "Acme", "custom", "web", "css", "package.json"), `{"scripts": {"watch": "gulp watch"}}`)
+	writeFile(t, filepath.Join(design, "Acme", "luma", "theme.xml"), "<theme/>")
+	writeFile(t, filepath.Join(design, "Acme", "plain", "theme.xml"), "<theme/>")
+	// node_modules of a theme are not searched for watch scripts
+	writeFile(t, filepath.Join(design, "Acme", "plain", "node_modules", "dep", "package.json"), `{"scripts": {"watch": "x"}}`)
+
+	themes := FindThemes(project)
+	var got []string
+	for _, th := range themes {
+		got = append(got, th.Name+"="+th.Watcher)
+	}
+	if want := "Acme/custom=npm, Acme/headless=vite, Acme/hyva=hyva, Acme/luma=, Acme/plain="; strings.Join(got, ", ") != want {
+		t.Errorf("FindThemes() = %s, want %s", strings.Join(got, ", "), want)
+	}
+	if themes[0].WorkDir != filepath.Join(design, "Acme", "custom", "web", "css") {
+		t.Errorf("npm watcher WorkDir = %s", themes[0].WorkDir)
+	}
+
+	// Luma based themes build with Magento's Gruntfile
+	writeFile(t, filepath.Join(project, "Gruntfile.js"), "")
+	luma, err := FindTheme(project, "acme/luma")
+	if err != nil {
+		t.Fatalf("FindTheme() error = %v", err)
+	}
+	if luma.Watcher != WatcherGrunt || luma.WorkDir != project {
+		t.Errorf("luma = %+v, want a grunt watcher in the project root", luma)
+	}
+	if got := strings.Join(luma.WatchArgs(0), " "); got != "npx grunt exec:luma less:luma watch" {
+		t.Errorf("WatchArgs() = %s", got)
+	}
+	if !luma.NeedsInstall() {
+		t.Error("a project without node_modules needs an npm install")
+	}
+
+	if _, err := FindTheme(project, "Acme/missing"); err == nil {
+		t.Error("FindTheme() should fail for a missing theme")
+	}
+	if n := len(WatchableThemes(project)); n != 5 {
+		t.Errorf("WatchableThemes() returned %d themes, want 5", n)
+	}
+}
+
+func TestTheme_WatchArgs(t *testing.T) {
+	project := t.TempDir()
+	dir := filepath.Join(project, "app", "design", "frontend", "Acme", "headless")
+	writeFile(t, filepath.Join(dir, "package.json"), `{"scripts": {"dev": "vite --host"}}`)
+
+	vite := &Theme{Dir: dir, WorkDir: dir, Watcher: WatcherVite}
+	if got := strings.Join(vite.WatchArgs(5173), " "); got != "npm run dev -- --port 5173 --strictPort" {
+		t.Errorf("vite WatchArgs() = %s", got)
+	}
+	if got := strings.Join(vite.WatchArgs(0), " "); got != "npm run dev" {
+		t.Errorf("vite WatchArgs() without a dev server = %s", got)
+	}
+
+	// A dev script that isn't Vite itself doesn't take its flags
+	writeFile(t, filepath.Join(dir, "package.json"), `{"scripts": {"dev": "node server.js"}}`)
+	if got := strings.Join(vite.WatchArgs(5173), " "); got != "npm run dev" {
+		t.Errorf("WatchArgs() for a custom dev script = %s", got)
+	}
+
+	hyva := &Theme{Watcher: WatcherHyva}
+	if got := strings.Join(hyva.WatchArgs(5173), " "); got != "npm run watch" {
+		t.Errorf("hyva WatchArgs() = %s", got)
+	}
+}
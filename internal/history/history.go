@@ -0,0 +1,274 @@
+// Package history keeps an audit trail of state-changing MageBox commands.
+//
+// Every entry records who ran which command, when, where and which files it
+// changed. For tracked files the content from before the command is kept, so
+// operations such as domain add/remove and config set can be undone.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxBackupSize is the largest file whose previous content is kept for undo
+const maxBackupSize = 1 << 20
+
+// FileChange is a file modified by a command
+type FileChange struct {
+	Path string `json:"path"`
+	// Existed is false when the command created the file
+	Existed bool `json:"existed"`
+	// Before is the content before the command; empty when the file did not
+	// exist or was too large to keep
+	Before string `json:"before,omitempty"`
+	// Restorable is false when Before was not kept
+	Restorable bool `json:"restorable"`
+	// After is the SHA-256 of the content the command left, empty when it
+	// removed the file
+	After string `json:"after,omitempty"`
+}
+
+// Entry is one recorded command
+type Entry struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	// Command is the command path, e.g. "domain add"
+	Command string `json:"command"`
+	// CommandLine is the full command line as typed
+	CommandLine string       `json:"command_line"`
+	Dir         string       `json:"dir"`
+	Error       string       `json:"error,omitempty"`
+	Files       []FileChange `json:"files,omitempty"`
+	// UndoOf is the ID of the entry this entry reverted
+	UndoOf int `json:"undo_of,omitempty"`
+}
+
+// Log is the append-only history file
+type Log struct {
+	path string
+}
+
+// LogPath returns the path of the history log
+func LogPath(homeDir string) string {
+	return filepath.Join(homeDir, ".magebox", "history.log")
+}
+
+// NewLog returns the history log stored at path
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Entries returns all entries, oldest first. Unreadable lines are skipped.
+func (l *Log) Entries() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*maxBackupSize)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Get returns the entry with the given ID
+func (l *Log) Get(id int) (*Entry, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("history entry %d not found", id)
+}
+
+// UndoneBy returns the ID of the entry that reverted id, or 0
+func (l *Log) UndoneBy(id int) (int, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if e.UndoOf == id {
+			return e.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// Append assigns the next ID to e and writes it to the log
+func (l *Log) Append(e *Entry) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+
+	entries, err := l.Entries()
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// The log holds previous file contents, keep it private
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recorder collects the files changed by the running command. All methods are
+// no-ops on a nil Recorder.
+type Recorder struct {
+	files []FileChange
+	seen  map[string]bool
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{seen: make(map[string]bool)}
+}
+
+// Track records the current content of path; call it before changing the
+// file. Only the first call per path counts.
+func (r *Recorder) Track(path string) {
+	if r == nil {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if r.seen[path] {
+		return
+	}
+	r.seen[path] = true
+
+	change := FileChange{Path: path, Restorable: true}
+	if info, err := os.Stat(path); err == nil {
+		change.Existed = true
+		if info.Size() > maxBackupSize {
+			change.Restorable = false
+		} else if data, err := os.ReadFile(path); err == nil {
+			change.Before = string(data)
+		} else {
+			change.Restorable = false
+		}
+	}
+	r.files = append(r.files, change)
+}
+
+// Changes returns the tracked files with the hash of their current content,
+// leaving out files the command did not modify
+func (r *Recorder) Changes() []FileChange {
+	if r == nil {
+		return nil
+	}
+	var changes []FileChange
+	for _, c := range r.files {
+		c.After = fileHash(c.Path)
+		unchanged := (!c.Existed && c.After == "") ||
+			(c.Existed && c.Restorable && c.After == contentHash(c.Before))
+		if unchanged {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// Modified returns the tracked paths that changed since the entry was
+// recorded, which undoing would overwrite
+func (e *Entry) Modified() []string {
+	var modified []string
+	for _, c := range e.Files {
+		if fileHash(c.Path) != c.After {
+			modified = append(modified, c.Path)
+		}
+	}
+	return modified
+}
+
+// Undoable reports whether every file change of the entry can be reverted
+func (e *Entry) Undoable() bool {
+	if len(e.Files) == 0 || e.UndoOf != 0 {
+		return false
+	}
+	for _, c := range e.Files {
+		if !c.Restorable {
+			return false
+		}
+	}
+	return true
+}
+
+// Undo restores the files of the entry to their state before the command,
+// tracking them in r so the undo itself is recorded
+func (e *Entry) Undo(r *Recorder) error {
+	if !e.Undoable() {
+		return fmt.Errorf("history entry %d cannot be undone", e.ID)
+	}
+	for _, c := range e.Files {
+		r.Track(c.Path)
+		if !c.Existed {
+			if err := os.Remove(c.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(c.Path, []byte(c.Before), 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", c.Path, err)
+		}
+	}
+	return nil
+}
+
+// fileHash returns the SHA-256 of a file, or "" when it does not exist
+func fileHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return contentHash(string(data))
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
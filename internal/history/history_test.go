@@ -0,0 +1,163 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLog_AppendAssignsIDs(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "history.log"))
+
+	for _, command := range []string{"start", "domain add", "stop"} {
+		if err := log.Append(&Entry{Time: time.Now(), Command: command}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	entries, err := log.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	for i, e := range entries {
+		if e.ID != i+1 {
+			t.Errorf("entry %d has ID %d", i, e.ID)
+		}
+	}
+
+	e, err := log.Get(2)
+	if err != nil || e.Command != "domain add" {
+		t.Errorf("Get(2) = %+v, %v", e, err)
+	}
+	if _, err := log.Get(9); err == nil {
+		t.Error("Get(9) should fail")
+	}
+}
+
+func TestLog_EntriesMissingFile(t *testing.T) {
+	entries, err := NewLog(filepath.Join(t.TempDir(), "none.log")).Entries()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Entries() = %v, %v; want empty", entries, err)
+	}
+}
+
+func TestRecorder_Changes(t *testing.T) {
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "modified.yaml")
+	untouched := filepath.Join(dir, "untouched.yaml")
+	created := filepath.Join(dir, "created.conf")
+	removed := filepath.Join(dir, "removed.conf")
+	writeFile(t, modified, "a")
+	writeFile(t, untouched, "b")
+	writeFile(t, removed, "c")
+
+	r := NewRecorder()
+	for _, path := range []string{modified, untouched, created, removed, modified} {
+		r.Track(path)
+	}
+	writeFile(t, modified, "a2")
+	writeFile(t, created, "new")
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := r.Changes()
+	if len(changes) != 3 {
+		t.Fatalf("got %d changes, want 3: %+v", len(changes), changes)
+	}
+	byPath := make(map[string]FileChange)
+	for _, c := range changes {
+		byPath[c.Path] = c
+	}
+	if c := byPath[modified]; !c.Existed || c.Before != "a" || c.After != contentHash("a2") {
+		t.Errorf("modified change = %+v", c)
+	}
+	if c := byPath[created]; c.Existed || c.After == "" {
+		t.Errorf("created change = %+v", c)
+	}
+	if c := byPath[removed]; !c.Existed || c.Before != "c" || c.After != "" {
+		t.Errorf("removed change = %+v", c)
+	}
+}
+
+func TestEntry_Undo(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, ".magebox.yaml")
+	vhost := filepath.Join(dir, "store.test.conf")
+	writeFile(t, cfg, "domains: [a.test]")
+
+	r := NewRecorder()
+	r.Track(cfg)
+	r.Track(vhost)
+	writeFile(t, cfg, "domains: [a.test, store.test]")
+	writeFile(t, vhost, "server {}")
+	entry := &Entry{ID: 1, Files: r.Changes()}
+
+	if !entry.Undoable() {
+		t.Fatal("entry should be undoable")
+	}
+	if modified := entry.Modified(); len(modified) != 0 {
+		t.Errorf("Modified() = %v, want none", modified)
+	}
+
+	undo := NewRecorder()
+	if err := entry.Undo(undo); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if data, _ := os.ReadFile(cfg); string(data) != "domains: [a.test]" {
+		t.Errorf("config after undo = %q", data)
+	}
+	if _, err := os.Stat(vhost); !os.IsNotExist(err) {
+		t.Error("created vhost should be removed by undo")
+	}
+	if len(undo.Changes()) != 2 {
+		t.Errorf("undo should record both files, got %+v", undo.Changes())
+	}
+}
+
+func TestEntry_ModifiedAfterRecording(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config.yaml")
+	writeFile(t, cfg, "tld: test")
+
+	r := NewRecorder()
+	r.Track(cfg)
+	writeFile(t, cfg, "tld: local")
+	entry := &Entry{ID: 1, Files: r.Changes()}
+
+	writeFile(t, cfg, "tld: dev")
+	if modified := entry.Modified(); len(modified) != 1 || modified[0] != cfg {
+		t.Errorf("Modified() = %v, want [%s]", modified, cfg)
+	}
+}
+
+func TestEntry_Undoable(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{"no files", Entry{}, false},
+		{"restorable", Entry{Files: []FileChange{{Path: "a", Restorable: true}}}, true},
+		{"too large", Entry{Files: []FileChange{{Path: "a", Existed: true}}}, false},
+		{"undo entry", Entry{UndoOf: 3, Files: []FileChange{{Path: "a", Restorable: true}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Undoable(); got != tt.want {
+				t.Errorf("Undoable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
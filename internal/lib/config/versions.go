@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -21,6 +23,8 @@ type VersionsConfig struct {
 	Defaults      VersionDefaults `yaml:"defaults"`
 	Magento       DistroConfig    `yaml:"magento"`
 	MageOS        DistroConfig    `yaml:"mageos"`
+
+	Compatibility []CompatibilityEntry `yaml:"compatibility,omitempty"`
 }
 
 // VersionDefaults contains default settings for quick install
@@ -46,6 +50,63 @@ type VersionEntry struct {
 	Base    string   `yaml:"base,omitempty"` // For MageOS: which Magento version it's based on
 }
 
+// CompatibilityEntry lists the versions of PHP and the services a Magento
+// release line supports, newest first. MageOS releases use the line of the
+// Magento version they're based on.
+type CompatibilityEntry struct {
+	Magento       string   `yaml:"magento" json:"magento"` // Release line, e.g. "2.4.8"
+	PHP           []string `yaml:"php" json:"php"`
+	MySQL         []string `yaml:"mysql,omitempty" json:"mysql,omitempty"`
+	MariaDB       []string `yaml:"mariadb,omitempty" json:"mariadb,omitempty"`
+	OpenSearch    []string `yaml:"opensearch,omitempty" json:"opensearch,omitempty"`
+	Elasticsearch []string `yaml:"elasticsearch,omitempty" json:"elasticsearch,omitempty"`
+	Redis         []string `yaml:"redis,omitempty" json:"redis,omitempty"`
+	Valkey        []string `yaml:"valkey,omitempty" json:"valkey,omitempty"`
+	RabbitMQ      []string `yaml:"rabbitmq,omitempty" json:"rabbitmq,omitempty"`
+	Composer      string   `yaml:"composer,omitempty" json:"composer,omitempty"`
+}
+
+// ServiceVersions returns the supported versions of a service: php, mysql,
+// mariadb, percona (as mysql), opensearch, elasticsearch, redis, valkey or
+// rabbitmq
+func (e *CompatibilityEntry) ServiceVersions(service string) []string {
+	switch service {
+	case "php":
+		return e.PHP
+	case "mysql", "percona":
+		return e.MySQL
+	case "mariadb":
+		return e.MariaDB
+	case "opensearch":
+		return e.OpenSearch
+	case "elasticsearch":
+		return e.Elasticsearch
+	case "redis":
+		return e.Redis
+	case "valkey":
+		return e.Valkey
+	case "rabbitmq":
+		return e.RabbitMQ
+	}
+	return nil
+}
+
+// Supports reports whether the release line supports a service version. The
+// versions are compared by major.minor, so "2.19.4" matches "2.19". Services
+// without a list are not checked.
+func (e *CompatibilityEntry) Supports(service, version string) bool {
+	versions := e.ServiceVersions(service)
+	if len(versions) == 0 {
+		return true
+	}
+	for _, v := range versions {
+		if MinorVersion(v) == MinorVersion(version) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadVersions loads the versions configuration
 // It checks for a custom file first, then falls back to embedded
 func LoadVersions(mageboxDir string) (*VersionsConfig, error) {
@@ -118,6 +179,29 @@ func (c *VersionsConfig) GetDefaultMageOSVersion() *VersionEntry {
 	return nil
 }
 
+// GetCompatibility returns the compatibility of the release line of a Magento
+// version, e.g. "2.4.8" for "2.4.8-p4", or nil if the line is unknown
+func (c *VersionsConfig) GetCompatibility(magentoVersion string) *CompatibilityEntry {
+	line := ReleaseLine(magentoVersion)
+	for i := range c.Compatibility {
+		if c.Compatibility[i].Magento == line {
+			return &c.Compatibility[i]
+		}
+	}
+	return nil
+}
+
+// GetMageOSCompatibility returns the compatibility of a MageOS version,
+// looked up by the Magento version the release is based on
+func (c *VersionsConfig) GetMageOSCompatibility(version string) *CompatibilityEntry {
+	for _, v := range c.MageOS.Versions {
+		if v.Version == version && v.Base != "" {
+			return c.GetCompatibility(v.Base)
+		}
+	}
+	return nil
+}
+
 // GetMagentoPackage returns the Magento composer package name
 func (c *VersionsConfig) GetMagentoPackage() string {
 	return c.Magento.Package
@@ -127,3 +211,62 @@ func (c *VersionsConfig) GetMagentoPackage() string {
 func (c *VersionsConfig) GetMageOSPackage() string {
 	return c.MageOS.Package
 }
+
+// ReleaseLine returns the release line of a Magento version: the version
+// without its patch suffix, "2.4.8" for "2.4.8-p4"
+func ReleaseLine(version string) string {
+	if i := strings.Index(version, "-"); i >= 0 {
+		version = version[:i]
+	}
+	return version
+}
+
+// MinorVersion returns the major.minor part of a version: "2.19" for "2.19.4"
+func MinorVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// CompareVersions compares two Magento or MageOS versions numerically,
+// counting a -pN patch as a fourth part. It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts splits "2.4.8-p4" into 2, 4, 8, 4
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	patch := 0
+	if i := strings.Index(version, "-p"); i >= 0 {
+		patch, _ = strconv.Atoi(version[i+2:])
+		version = version[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	for len(parts) < 3 {
+		parts = append(parts, 0)
+	}
+	return append(parts, patch)
+}
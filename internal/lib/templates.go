@@ -106,6 +106,7 @@ var TemplateNames = map[string][]string{
 		"php-fpm.conf.tmpl",
 		"not-installed-message.tmpl",
 		"mailpit-sendmail.sh",
+		"file-sendmail.sh",
 	},
 	TemplateVarnish: {
 		"default.vcl.tmpl",
@@ -0,0 +1,299 @@
+// Package lint checks a local Magento installation for common
+// misconfigurations, typically after importing a production database.
+//
+// All checks are read-only: they inspect app/etc/env.php and run SELECT
+// queries against the project database.
+package lint
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity of a finding
+type Severity string
+
+// Finding severities
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// cronStaleAfter is how long cron may be silent before it is reported
+const cronStaleAfter = time.Hour
+
+// Finding is a single problem reported by a check
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"`
+}
+
+// Querier runs a read-only SQL query and returns the rows as string columns.
+// SQL NULL is returned as "NULL", matching the mysql client in batch mode.
+type Querier interface {
+	Query(sql string) ([][]string, error)
+}
+
+// Context is the input of the checks
+type Context struct {
+	// EnvPHP is app/etc/env.php decoded from JSON; nil when it could not be read
+	EnvPHP map[string]interface{}
+	// DB runs queries against the project database; nil skips database checks
+	DB Querier
+	// Domains are the hosts configured in .magebox.yaml
+	Domains []string
+	// Now is the reference time for cron checks
+	Now time.Time
+}
+
+// Check is a named lint rule
+type Check struct {
+	Name        string
+	Description string
+	NeedsDB     bool
+	Run         func(ctx *Context) ([]Finding, error)
+}
+
+// Checks are all lint rules, in the order they are reported
+var Checks = []Check{
+	{Name: "production-mode", Description: "Production mode enabled locally", Run: checkProductionMode},
+	{Name: "full-page-cache", Description: "Full page cache disabled", Run: checkFullPageCache},
+	{Name: "asset-merging", Description: "JS/CSS merging, bundling or minification on in development", NeedsDB: true, Run: checkAssetMerging},
+	{Name: "base-urls", Description: "Base URLs not pointing at the local domains", NeedsDB: true, Run: checkBaseURLs},
+	{Name: "cron", Description: "Cron not running", NeedsDB: true, Run: checkCron},
+	{Name: "indexers", Description: "Invalid indexers pending a reindex", NeedsDB: true, Run: checkIndexers},
+}
+
+// Run executes all checks. A check that cannot run (e.g. a missing table)
+// is reported as a warning instead of aborting the lint.
+func Run(ctx *Context) []Finding {
+	var findings []Finding
+	for _, check := range Checks {
+		if check.NeedsDB && ctx.DB == nil {
+			continue
+		}
+		if !check.NeedsDB && ctx.EnvPHP == nil {
+			continue
+		}
+		result, err := check.Run(ctx)
+		if err != nil {
+			findings = append(findings, Finding{
+				Check:    check.Name,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("check could not run: %v", err),
+			})
+			continue
+		}
+		findings = append(findings, result...)
+	}
+	return findings
+}
+
+// mageMode returns the MAGE_MODE from env.php, defaulting to "default"
+func (ctx *Context) mageMode() string {
+	if mode, ok := ctx.EnvPHP["MAGE_MODE"].(string); ok && mode != "" {
+		return mode
+	}
+	return "default"
+}
+
+// table returns a table name with the env.php table prefix applied
+func (ctx *Context) table(name string) string {
+	if db, ok := ctx.EnvPHP["db"].(map[string]interface{}); ok {
+		if prefix, ok := db["table_prefix"].(string); ok {
+			return prefix + name
+		}
+	}
+	return name
+}
+
+func checkProductionMode(ctx *Context) ([]Finding, error) {
+	if ctx.mageMode() != "production" {
+		return nil, nil
+	}
+	return []Finding{{
+		Check:    "production-mode",
+		Severity: SeverityWarning,
+		Message:  "MAGE_MODE is production; static content and DI are not regenerated on changes",
+		Fix:      "bin/magento deploy:mode:set developer",
+	}}, nil
+}
+
+func checkFullPageCache(ctx *Context) ([]Finding, error) {
+	types, ok := ctx.EnvPHP["cache_types"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if enabled, ok := types["full_page"]; ok && !truthy(enabled) {
+		return []Finding{{
+			Check:    "full-page-cache",
+			Severity: SeverityWarning,
+			Message:  "the full_page cache type is disabled, so local performance and Varnish behaviour differ from production",
+			Fix:      "bin/magento cache:enable full_page",
+		}}, nil
+	}
+	return nil, nil
+}
+
+// assetSettings are the config paths that hide individual JS/CSS files during development
+var assetSettings = []string{
+	"dev/js/merge_files",
+	"dev/js/enable_js_bundling",
+	"dev/js/minify_files",
+	"dev/css/merge_css_files",
+	"dev/css/minify_files",
+	"dev/template/minify_html",
+}
+
+func checkAssetMerging(ctx *Context) ([]Finding, error) {
+	if ctx.mageMode() == "production" {
+		return nil, nil
+	}
+
+	quoted := make([]string, len(assetSettings))
+	for i, path := range assetSettings {
+		quoted[i] = "'" + path + "'"
+	}
+	rows, err := ctx.DB.Query(fmt.Sprintf(
+		"SELECT DISTINCT path FROM %s WHERE path IN (%s) AND value = '1' ORDER BY path",
+		ctx.table("core_config_data"), strings.Join(quoted, ", ")))
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, row := range rows {
+		findings = append(findings, Finding{
+			Check:    "asset-merging",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%s is enabled in %s mode", row[0], ctx.mageMode()),
+			Fix:      fmt.Sprintf("bin/magento config:set %s 0", row[0]),
+		})
+	}
+	return findings, nil
+}
+
+func checkBaseURLs(ctx *Context) ([]Finding, error) {
+	rows, err := ctx.DB.Query(fmt.Sprintf(
+		"SELECT path, scope, scope_id, value FROM %s WHERE path IN ('web/unsecure/base_url', 'web/secure/base_url') ORDER BY scope, scope_id, path",
+		ctx.table("core_config_data")))
+	if err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]bool, len(ctx.Domains))
+	for _, d := range ctx.Domains {
+		local[strings.ToLower(d)] = true
+	}
+
+	var findings []Finding
+	for _, row := range rows {
+		if len(row) < 4 {
+			continue
+		}
+		path, scope, scopeID, value := row[0], row[1], row[2], row[3]
+		// Placeholders like {{unsecure_base_url}} resolve to another setting
+		if value == "NULL" || strings.Contains(value, "{{") {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			findings = append(findings, Finding{
+				Check:    "base-urls",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("%s (%s %s) is not a valid URL: %q", path, scope, scopeID, value),
+			})
+			continue
+		}
+		if !local[strings.ToLower(u.Hostname())] {
+			findings = append(findings, Finding{
+				Check:    "base-urls",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("%s (%s %s) points to %s, which is not a project domain", path, scope, scopeID, u.Host),
+				Fix:      fmt.Sprintf("bin/magento config:set --scope=%s --scope-code=<code> %s https://<domain>/", scope, path),
+			})
+			continue
+		}
+		if path == "web/secure/base_url" && u.Scheme != "https" {
+			findings = append(findings, Finding{
+				Check:    "base-urls",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s (%s %s) uses %s instead of https", path, scope, scopeID, u.Scheme),
+			})
+		}
+	}
+	return findings, nil
+}
+
+func checkCron(ctx *Context) ([]Finding, error) {
+	rows, err := ctx.DB.Query(fmt.Sprintf(
+		"SELECT MAX(executed_at) FROM %s", ctx.table("cron_schedule")))
+	if err != nil {
+		return nil, err
+	}
+
+	fix := "run 'magebox cron enable'"
+	if len(rows) == 0 || len(rows[0]) == 0 || rows[0][0] == "NULL" {
+		return []Finding{{
+			Check:    "cron",
+			Severity: SeverityWarning,
+			Message:  "cron has never run for this database",
+			Fix:      fix,
+		}}, nil
+	}
+
+	last, err := time.ParseInLocation("2006-01-02 15:04:05", rows[0][0], time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected executed_at value %q", rows[0][0])
+	}
+	if age := ctx.Now.Sub(last); age > cronStaleAfter {
+		return []Finding{{
+			Check:    "cron",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("last cron job ran %s ago (%s UTC)", age.Round(time.Minute), rows[0][0]),
+			Fix:      fix,
+		}}, nil
+	}
+	return nil, nil
+}
+
+func checkIndexers(ctx *Context) ([]Finding, error) {
+	rows, err := ctx.DB.Query(fmt.Sprintf(
+		"SELECT indexer_id FROM %s WHERE status = 'invalid' ORDER BY indexer_id", ctx.table("indexer_state")))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row[0])
+	}
+	sort.Strings(ids)
+	return []Finding{{
+		Check:    "indexers",
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("%d indexer(s) invalid: %s", len(ids), strings.Join(ids, ", ")),
+		Fix:      "bin/magento indexer:reindex",
+	}}, nil
+}
+
+// truthy interprets the 0/1 values PHP arrays decode to
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != "" && v != "0"
+	}
+	return v != nil
+}
@@ -0,0 +1,110 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDB answers queries by matching a substring of the SQL
+type fakeDB map[string][][]string
+
+func (f fakeDB) Query(sql string) ([][]string, error) {
+	for match, rows := range f {
+		if strings.Contains(sql, match) {
+			return rows, nil
+		}
+	}
+	return nil, nil
+}
+
+func findingsFor(findings []Finding, check string) []Finding {
+	var out []Finding
+	for _, f := range findings {
+		if f.Check == check {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestRun(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ctx := &Context{
+		EnvPHP: map[string]interface{}{
+			"MAGE_MODE":   "developer",
+			"cache_types": map[string]interface{}{"config": float64(1), "full_page": float64(0)},
+			"db":          map[string]interface{}{"table_prefix": "m2_"},
+		},
+		DB: fakeDB{
+			"m2_core_config_data WHERE path IN ('dev/js": {{"dev/js/merge_files"}},
+			"web/unsecure/base_url": {
+				{"web/unsecure/base_url", "default", "0", "https://mystore.test/"},
+				{"web/secure/base_url", "default", "0", "https://www.mystore.com/"},
+				{"web/secure/base_url", "websites", "1", "{{unsecure_base_url}}"},
+			},
+			"m2_cron_schedule": {{"2026-03-01 09:00:00"}},
+			"m2_indexer_state": {{"catalogsearch_fulltext"}, {"catalog_product_price"}},
+		},
+		Domains: []string{"mystore.test"},
+		Now:     now,
+	}
+
+	findings := Run(ctx)
+
+	if got := findingsFor(findings, "production-mode"); len(got) != 0 {
+		t.Errorf("production-mode findings = %v, want none in developer mode", got)
+	}
+	if got := findingsFor(findings, "full-page-cache"); len(got) != 1 {
+		t.Errorf("full-page-cache findings = %v, want 1", got)
+	}
+	if got := findingsFor(findings, "asset-merging"); len(got) != 1 || got[0].Fix != "bin/magento config:set dev/js/merge_files 0" {
+		t.Errorf("asset-merging findings = %v", got)
+	}
+
+	baseURLs := findingsFor(findings, "base-urls")
+	if len(baseURLs) != 1 || baseURLs[0].Severity != SeverityError || !strings.Contains(baseURLs[0].Message, "www.mystore.com") {
+		t.Errorf("base-urls findings = %v, want one error for the production URL", baseURLs)
+	}
+
+	cron := findingsFor(findings, "cron")
+	if len(cron) != 1 || !strings.Contains(cron[0].Message, "3h0m0s") {
+		t.Errorf("cron findings = %v, want stale cron", cron)
+	}
+
+	indexers := findingsFor(findings, "indexers")
+	if len(indexers) != 1 || !strings.Contains(indexers[0].Message, "catalog_product_price, catalogsearch_fulltext") {
+		t.Errorf("indexers findings = %v", indexers)
+	}
+}
+
+func TestRun_ProductionModeSkipsAssetChecks(t *testing.T) {
+	ctx := &Context{
+		EnvPHP: map[string]interface{}{"MAGE_MODE": "production"},
+		DB: fakeDB{
+			"dev/js":           {{"dev/js/merge_files"}},
+			"cron_schedule":    {{"NULL"}},
+			"core_config_data": nil,
+		},
+		Now: time.Now(),
+	}
+
+	findings := Run(ctx)
+
+	if got := findingsFor(findings, "production-mode"); len(got) != 1 {
+		t.Errorf("production-mode findings = %v, want 1", got)
+	}
+	if got := findingsFor(findings, "asset-merging"); len(got) != 0 {
+		t.Errorf("asset merging is expected in production mode, got %v", got)
+	}
+	if got := findingsFor(findings, "cron"); len(got) != 1 || !strings.Contains(got[0].Message, "never run") {
+		t.Errorf("cron findings = %v, want never run", got)
+	}
+}
+
+func TestRun_WithoutSources(t *testing.T) {
+	if findings := Run(&Context{}); len(findings) != 0 {
+		t.Errorf("Run() without env.php and database = %v, want no findings", findings)
+	}
+}
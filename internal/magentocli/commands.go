@@ -0,0 +1,138 @@
+// Package magentocli caches the command list of a project's bin/magento for
+// shell completion.
+//
+// Listing Magento commands boots the application and takes seconds, too slow
+// to run on every <tab>. The list is cached per project together with a
+// fingerprint of the files that change it: bin/magento itself, the module
+// list in app/etc/config.php and composer.lock. The cache is rebuilt as soon
+// as the fingerprint no longer matches.
+package magentocli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// fingerprintFiles are the project files whose changes add or remove
+// bin/magento commands
+var fingerprintFiles = []string{"bin/magento", "app/etc/config.php", "composer.lock"}
+
+// Command is a bin/magento command
+type Command struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Cache is the cached command list of a project
+type Cache struct {
+	Fingerprint string    `json:"fingerprint"`
+	Commands    []Command `json:"commands"`
+}
+
+// CachePath returns the command cache file of a project in the MageBox directory
+func CachePath(mageboxDir, project string) string {
+	return filepath.Join(mageboxDir, "cache", "magento-commands", project+".json")
+}
+
+// Fingerprint identifies the state of the files that change the command list
+// of the project at projectPath
+func Fingerprint(projectPath string) string {
+	h := sha256.New()
+	for _, name := range fingerprintFiles {
+		info, err := os.Stat(filepath.Join(projectPath, name))
+		if err != nil {
+			fmt.Fprintf(h, "%s:-\n", name)
+			continue
+		}
+		fmt.Fprintf(h, "%s:%d:%d\n", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ParseList parses the output of 'bin/magento list --format json'. Hidden
+// commands are left out.
+func ParseList(data []byte) ([]Command, error) {
+	// Magento may print deprecation notices before the JSON document
+	if i := strings.IndexByte(string(data), '{'); i > 0 {
+		data = data[i:]
+	}
+
+	var list struct {
+		Commands []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Hidden      bool   `json:"hidden"`
+		} `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse command list: %w", err)
+	}
+
+	commands := make([]Command, 0, len(list.Commands))
+	for _, c := range list.Commands {
+		if c.Hidden || c.Name == "" {
+			continue
+		}
+		commands = append(commands, Command{Name: c.Name, Description: c.Description})
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands, nil
+}
+
+// LoadCache reads a command cache. A missing file returns an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Cache{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cache to path
+func (c *Cache) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, data, 0644)
+}
+
+// Fresh reports whether the cache matches the fingerprint
+func (c *Cache) Fresh(fingerprint string) bool {
+	return c.Fingerprint == fingerprint && len(c.Commands) > 0
+}
+
+// Complete returns the commands starting with prefix in shell completion
+// form, "name<TAB>description"
+func Complete(commands []Command, prefix string) []string {
+	var completions []string
+	for _, c := range commands {
+		if !strings.HasPrefix(c.Name, prefix) {
+			continue
+		}
+		if c.Description != "" {
+			completions = append(completions, c.Name+"\t"+c.Description)
+		} else {
+			completions = append(completions, c.Name)
+		}
+	}
+	return completions
+}
@@ -0,0 +1,125 @@
+package magentocli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const listJSON = `Deprecated Functionality: something in vendor/foo on line 12
+{
+  "application": {"name": "Magento CLI", "version": "2.4.7"},
+  "commands": [
+    {"name": "setup:upgrade", "description": "Upgrades the Magento application", "hidden": false},
+    {"name": "cache:flush", "description": "Flushes cache storage", "hidden": false},
+    {"name": "_complete", "description": "Internal", "hidden": true},
+    {"name": "cache:clean", "description": "", "hidden": false}
+  ]
+}`
+
+func TestParseList(t *testing.T) {
+	got, err := ParseList([]byte(listJSON))
+	if err != nil {
+		t.Fatalf("ParseList() error = %v", err)
+	}
+	want := []Command{
+		{Name: "cache:clean"},
+		{Name: "cache:flush", Description: "Flushes cache storage"},
+		{Name: "setup:upgrade", Description: "Upgrades the Magento application"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseList() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseList([]byte("PHP Fatal error: out of memory")); err == nil {
+		t.Error("ParseList() should fail on output without JSON")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	commands := []Command{
+		{Name: "cache:clean"},
+		{Name: "cache:flush", Description: "Flushes cache storage"},
+		{Name: "setup:upgrade", Description: "Upgrades the Magento application"},
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"cache:", []string{"cache:clean", "cache:flush\tFlushes cache storage"}},
+		{"setup", []string{"setup:upgrade\tUpgrades the Magento application"}},
+		{"indexer", nil},
+	}
+	for _, tt := range tests {
+		if got := Complete(commands, tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("bin/magento", "#!/usr/bin/env php")
+	write("app/etc/config.php", "<?php return ['modules' => []];")
+	before := Fingerprint(dir)
+	if Fingerprint(dir) != before {
+		t.Fatal("Fingerprint() should be stable while files are unchanged")
+	}
+
+	write("app/code/Vendor/Module/registration.php", "<?php")
+	if Fingerprint(dir) != before {
+		t.Error("Fingerprint() should ignore files outside the fingerprinted ones")
+	}
+
+	write("app/etc/config.php", "<?php return ['modules' => ['Vendor_Module' => 1]];")
+	if Fingerprint(dir) == before {
+		t.Error("Fingerprint() should change when the module list changes")
+	}
+
+	before = Fingerprint(dir)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "bin/magento"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint(dir) == before {
+		t.Error("Fingerprint() should change when bin/magento is touched")
+	}
+}
+
+func TestCache_SaveLoad(t *testing.T) {
+	path := CachePath(t.TempDir(), "mystore")
+
+	empty, err := LoadCache(path)
+	if err != nil {
+		t.Fatalf("LoadCache() on missing file error = %v", err)
+	}
+	if empty.Fresh("") {
+		t.Error("an empty cache should never be fresh")
+	}
+
+	cache := &Cache{Fingerprint: "abc", Commands: []Command{{Name: "cache:flush"}}}
+	if err := cache.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadCache(path)
+	if err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+	if !loaded.Fresh("abc") || loaded.Fresh("def") {
+		t.Errorf("Fresh() mismatch for %+v", loaded)
+	}
+}
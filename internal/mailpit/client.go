@@ -0,0 +1,152 @@
+// Package mailpit talks to the HTTP API of the shared Mailpit container
+package mailpit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Latest is the message ID Mailpit resolves to the newest message
+const Latest = "latest"
+
+// ErrNotFound is returned for a message ID Mailpit doesn't know, and for
+// Latest when there are no messages
+var ErrNotFound = errors.New("message not found")
+
+// Address is a sender or recipient of a message
+type Address struct {
+	Name    string `json:"Name"`
+	Address string `json:"Address"`
+}
+
+// String formats the address like a mail header
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Address
+	}
+	return fmt.Sprintf("%s <%s>", a.Name, a.Address)
+}
+
+// MessageSummary is a message as listed by Mailpit
+type MessageSummary struct {
+	ID      string    `json:"ID"`
+	Read    bool      `json:"Read"`
+	From    Address   `json:"From"`
+	To      []Address `json:"To"`
+	Subject string    `json:"Subject"`
+	Created time.Time `json:"Created"`
+	Size    int64     `json:"Size"`
+	Snippet string    `json:"Snippet"`
+}
+
+// Message is a message with its bodies
+type Message struct {
+	ID      string    `json:"ID"`
+	From    Address   `json:"From"`
+	To      []Address `json:"To"`
+	Subject string    `json:"Subject"`
+	Date    time.Time `json:"Date"`
+	Text    string    `json:"Text"`
+	HTML    string    `json:"HTML"`
+}
+
+// Recipients joins the To addresses of a message
+func Recipients(to []Address) string {
+	parts := make([]string, 0, len(to))
+	for _, a := range to {
+		parts = append(parts, a.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// messagesResponse is the body of the list and search endpoints
+type messagesResponse struct {
+	Total    int              `json:"total"`
+	Messages []MessageSummary `json:"messages"`
+}
+
+// Client is a Mailpit API client
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the Mailpit at baseURL, e.g. http://localhost:8025
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// List returns the newest messages, up to limit. A non-empty query uses
+// Mailpit's search syntax, e.g. "to:customer@example.com subject:order".
+// The second value is the number of messages matching in total.
+func (c *Client) List(limit int, query string) ([]MessageSummary, int, error) {
+	params := url.Values{"limit": {strconv.Itoa(limit)}}
+	endpoint := "/api/v1/messages"
+	if query != "" {
+		endpoint = "/api/v1/search"
+		params.Set("query", query)
+	}
+
+	var resp messagesResponse
+	if err := c.do(http.MethodGet, endpoint+"?"+params.Encode(), &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.Messages, resp.Total, nil
+}
+
+// Message returns a message with its text and HTML bodies. id may be Latest.
+func (c *Client) Message(id string) (*Message, error) {
+	var msg Message
+	if err := c.do(http.MethodGet, "/api/v1/message/"+url.PathEscape(id), &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// DeleteAll deletes every message
+func (c *Client) DeleteAll() error {
+	return c.do(http.MethodDelete, "/api/v1/messages", nil)
+}
+
+// ViewURL returns the web UI address of a message. id may be Latest.
+func (c *Client) ViewURL(id string) string {
+	return c.baseURL + "/view/" + url.PathEscape(id)
+}
+
+// do sends a request and decodes the JSON response into out, if given
+func (c *Client) do(method, endpoint string, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Mailpit: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mailpit returned %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse Mailpit response: %w", err)
+	}
+	return nil
+}
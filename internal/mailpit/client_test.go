@@ -0,0 +1,73 @@
+package mailpit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	var deleted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/messages":
+			if r.URL.Query().Get("limit") != "20" {
+				t.Errorf("limit = %q, want 20", r.URL.Query().Get("limit"))
+			}
+			_, _ = w.Write([]byte(`{"total": 3, "messages": [{"ID": "a1", "Subject": "Your order #1", "From": {"Name": "Shop", "Address": "shop@example.com"}, "To": [{"Address": "jane@example.com"}]}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/search":
+			if q := r.URL.Query().Get("query"); q != "subject:order" {
+				t.Errorf("query = %q, want subject:order", q)
+			}
+			_, _ = w.Write([]byte(`{"total": 1, "messages": [{"ID": "a1"}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/message/latest":
+			_, _ = w.Write([]byte(`{"ID": "a1", "Subject": "Your order #1", "Text": "Thanks", "HTML": "<p>Thanks</p>"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/messages":
+			deleted = true
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL + "/")
+
+	messages, total, err := c.List(20, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if total != 3 || len(messages) != 1 {
+		t.Fatalf("List() = %d messages of %d, want 1 of 3", len(messages), total)
+	}
+	if got := messages[0].From.String(); got != "Shop <shop@example.com>" {
+		t.Errorf("From = %q", got)
+	}
+	if got := Recipients(messages[0].To); got != "jane@example.com" {
+		t.Errorf("Recipients() = %q", got)
+	}
+
+	if _, total, err := c.List(20, "subject:order"); err != nil || total != 1 {
+		t.Errorf("search = %d, %v, want 1 match", total, err)
+	}
+
+	msg, err := c.Message(Latest)
+	if err != nil {
+		t.Fatalf("Message failed: %v", err)
+	}
+	if msg.Text != "Thanks" || msg.HTML != "<p>Thanks</p>" {
+		t.Errorf("Message() = %+v", msg)
+	}
+
+	if _, err := c.Message("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Message() of an unknown ID = %v, want ErrNotFound", err)
+	}
+
+	if err := c.DeleteAll(); err != nil || !deleted {
+		t.Errorf("DeleteAll() = %v, deleted %v", err, deleted)
+	}
+
+	if got := c.ViewURL(Latest); got != server.URL+"/view/latest" {
+		t.Errorf("ViewURL() = %q", got)
+	}
+}
@@ -0,0 +1,367 @@
+// Package media optimizes the images in a project's pub/media directory.
+//
+// Images are compressed in place with jpegoptim and pngquant, and optionally
+// get a WebP copy from cwebp. Every processed file is recorded in a manifest
+// with its size and modification time, so a later run only touches new or
+// replaced images and an interrupted run picks up where it stopped.
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// DefaultQuality is the JPEG/WebP quality used when none is given
+const DefaultQuality = 85
+
+// saveEvery is how many processed files go between manifest saves
+const saveEvery = 50
+
+// skipDirs are pub/media directories that are regenerated or not served
+// as-is, relative to the media directory
+var skipDirs = []string{
+	"catalog/product/cache",
+	"tmp",
+	"import",
+	"captcha",
+	"sitemap",
+}
+
+// ManifestEntry records the state of a file after it was optimized
+type ManifestEntry struct {
+	Size         int64 `json:"size"`
+	ModTime      int64 `json:"mtime"`
+	OriginalSize int64 `json:"original_size"`
+	WebP         bool  `json:"webp,omitempty"`
+}
+
+// Manifest lists the optimized files of a media directory, keyed by path
+// relative to it
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// LoadManifest reads a manifest; a missing file yields an empty manifest
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]ManifestEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]ManifestEntry)
+	}
+	return m, nil
+}
+
+// Save writes the manifest atomically
+func (m *Manifest) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, data, 0644)
+}
+
+// Savings returns the total original and current size of the manifest files
+func (m *Manifest) Savings() (original, current int64) {
+	for _, e := range m.Files {
+		original += e.OriginalSize
+		current += e.Size
+	}
+	return original, current
+}
+
+// upToDate reports whether a file is unchanged since it was optimized
+func (m *Manifest) upToDate(rel string, info fs.FileInfo, webp bool) bool {
+	e, ok := m.Files[rel]
+	if !ok || e.Size != info.Size() || e.ModTime != info.ModTime().Unix() {
+		return false
+	}
+	return e.WebP || !webp
+}
+
+// Options control an optimization run
+type Options struct {
+	// Quality is the maximum JPEG quality and the WebP quality
+	Quality int
+	// WebP writes a <file>.webp copy next to every image
+	WebP bool
+	// Pause is slept between files to leave I/O to other work
+	Pause time.Duration
+}
+
+// Tools are the optimizer binaries found on the system, empty when missing
+type Tools struct {
+	JPEGOptim string
+	PNGQuant  string
+	CWebP     string
+	Nice      string
+	IONice    string
+}
+
+// DetectTools looks up the optimizer binaries in PATH
+func DetectTools() Tools {
+	look := func(name string) string {
+		path, _ := exec.LookPath(name)
+		return path
+	}
+	t := Tools{
+		JPEGOptim: look("jpegoptim"),
+		PNGQuant:  look("pngquant"),
+		CWebP:     look("cwebp"),
+		Nice:      look("nice"),
+	}
+	// ionice is Linux only; on macOS nice also lowers I/O priority
+	if runtime.GOOS == "linux" {
+		t.IONice = look("ionice")
+	}
+	return t
+}
+
+// Missing returns the names of the optimizers needed for opts that are not installed
+func (t Tools) Missing(opts Options) []string {
+	var missing []string
+	if t.JPEGOptim == "" {
+		missing = append(missing, "jpegoptim")
+	}
+	if t.PNGQuant == "" {
+		missing = append(missing, "pngquant")
+	}
+	if opts.WebP && t.CWebP == "" {
+		missing = append(missing, "cwebp")
+	}
+	return missing
+}
+
+// Scan returns the images under dir that are not in the manifest or changed
+// since they were optimized, relative to dir and sorted
+func Scan(dir string, m *Manifest, opts Options) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || isSkipped(rel)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || imageType(rel) == "" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !m.upToDate(rel, info, opts.WebP) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Result is the outcome of optimizing one file
+type Result struct {
+	Path   string
+	Before int64
+	After  int64
+	Err    error
+}
+
+// Optimizer processes the images of a media directory
+type Optimizer struct {
+	Dir          string
+	ManifestPath string
+	Manifest     *Manifest
+	Tools        Tools
+	Options      Options
+}
+
+// Run optimizes files in order, reporting each result to progress. It stops
+// early when ctx is cancelled; the manifest is saved periodically and on return.
+func (o *Optimizer) Run(ctx context.Context, files []string, progress func(Result)) error {
+	for i, rel := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		res := o.optimize(ctx, rel)
+		if progress != nil {
+			progress(res)
+		}
+		if (i+1)%saveEvery == 0 {
+			if err := o.Manifest.Save(o.ManifestPath); err != nil {
+				return err
+			}
+		}
+		if o.Options.Pause > 0 && i < len(files)-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(o.Options.Pause):
+			}
+		}
+	}
+	return o.Manifest.Save(o.ManifestPath)
+}
+
+// optimize runs the optimizers for one file and records it in the manifest
+func (o *Optimizer) optimize(ctx context.Context, rel string) Result {
+	path := filepath.Join(o.Dir, filepath.FromSlash(rel))
+	res := Result{Path: rel}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Before = info.Size()
+
+	for _, args := range o.Commands(path) {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		if out, err := cmd.CombinedOutput(); err != nil && !acceptableExit(args, err) {
+			res.Err = fmt.Errorf("%s: %v: %s", filepath.Base(o.toolOf(args)), err, strings.TrimSpace(string(out)))
+			return res
+		}
+	}
+
+	info, err = os.Stat(path)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.After = info.Size()
+
+	original := res.Before
+	if prev, ok := o.Manifest.Files[rel]; ok && prev.Size == res.Before && prev.OriginalSize > 0 {
+		// Only the WebP copy was missing; keep the size from before the first run
+		original = prev.OriginalSize
+	}
+	o.Manifest.Files[rel] = ManifestEntry{
+		Size:         info.Size(),
+		ModTime:      info.ModTime().Unix(),
+		OriginalSize: original,
+		WebP:         o.Options.WebP,
+	}
+	return res
+}
+
+// Commands returns the optimizer command lines for an image, wrapped to run
+// at the lowest CPU and I/O priority
+func (o *Optimizer) Commands(path string) [][]string {
+	quality := o.Options.Quality
+	if quality <= 0 {
+		quality = DefaultQuality
+	}
+
+	var cmds [][]string
+	switch imageType(path) {
+	case "jpeg":
+		if o.Tools.JPEGOptim != "" {
+			cmds = append(cmds, []string{o.Tools.JPEGOptim, "--quiet", "--strip-all", "--all-progressive",
+				fmt.Sprintf("--max=%d", quality), path})
+		}
+	case "png":
+		if o.Tools.PNGQuant != "" {
+			cmds = append(cmds, []string{o.Tools.PNGQuant, "--force", "--skip-if-larger", "--strip",
+				"--quality=65-90", "--ext", ".png", path})
+		}
+	}
+	if o.Options.WebP && o.Tools.CWebP != "" {
+		cmds = append(cmds, []string{o.Tools.CWebP, "-quiet", "-q", fmt.Sprint(quality), path, "-o", path + ".webp"})
+	}
+
+	for i, args := range cmds {
+		cmds[i] = o.lowPriority(args)
+	}
+	return cmds
+}
+
+// lowPriority prefixes a command line with nice and ionice when available
+func (o *Optimizer) lowPriority(args []string) []string {
+	var prefix []string
+	if o.Tools.IONice != "" {
+		prefix = append(prefix, o.Tools.IONice, "-c3")
+	}
+	if o.Tools.Nice != "" {
+		prefix = append(prefix, o.Tools.Nice, "-n", "19")
+	}
+	return append(prefix, args...)
+}
+
+// toolOf returns the optimizer binary of a wrapped command line
+func (o *Optimizer) toolOf(args []string) string {
+	for _, arg := range args {
+		if arg == o.Tools.JPEGOptim || arg == o.Tools.PNGQuant || arg == o.Tools.CWebP {
+			return arg
+		}
+	}
+	return args[0]
+}
+
+// acceptableExit reports whether a non-zero exit means "left unchanged"
+// rather than failure: pngquant exits 98 when the result would be larger and
+// 99 when the quality range cannot be met
+func acceptableExit(args []string, err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	isPNGQuant := false
+	for _, arg := range args {
+		if filepath.Base(arg) == "pngquant" {
+			isPNGQuant = true
+		}
+	}
+	code := exitErr.ExitCode()
+	return isPNGQuant && (code == 98 || code == 99)
+}
+
+// imageType returns "jpeg" or "png" for supported image files
+func imageType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".png":
+		return "png"
+	}
+	return ""
+}
+
+// isSkipped reports whether a directory relative to the media root is excluded
+func isSkipped(rel string) bool {
+	for _, dir := range skipDirs {
+		if rel == dir {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "catalog/product/a/b/ab.jpg", "jpeg")
+	writeFile(t, dir, "catalog/product/c/d/cd.PNG", "png")
+	writeFile(t, dir, "catalog/product/cache/1/ab.jpg", "cached")
+	writeFile(t, dir, "wysiwyg/banner.jpeg", "banner")
+	writeFile(t, dir, "wysiwyg/readme.txt", "text")
+	writeFile(t, dir, "tmp/upload.jpg", "tmp")
+	writeFile(t, dir, ".thumbs/wysiwyg/banner.jpeg", "thumb")
+	writeFile(t, dir, "catalog/product/c/d/cd.PNG.webp", "webp")
+
+	m := &Manifest{Files: map[string]ManifestEntry{}}
+	files, err := Scan(dir, m, Options{})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	want := []string{"catalog/product/a/b/ab.jpg", "catalog/product/c/d/cd.PNG", "wysiwyg/banner.jpeg"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Scan() = %v, want %v", files, want)
+	}
+
+	// Record one file as optimized; it is skipped until it changes
+	info, _ := os.Stat(filepath.Join(dir, "wysiwyg/banner.jpeg"))
+	m.Files["wysiwyg/banner.jpeg"] = ManifestEntry{Size: info.Size(), ModTime: info.ModTime().Unix(), OriginalSize: 100}
+
+	files, _ = Scan(dir, m, Options{})
+	if len(files) != 2 {
+		t.Errorf("expected optimized file to be skipped, got %v", files)
+	}
+
+	// Asking for WebP copies picks it up again
+	files, _ = Scan(dir, m, Options{WebP: true})
+	if len(files) != 3 {
+		t.Errorf("expected file without WebP copy to be rescanned, got %v", files)
+	}
+
+	writeFile(t, dir, "wysiwyg/banner.jpeg", "replaced banner")
+	files, _ = Scan(dir, m, Options{})
+	if len(files) != 3 {
+		t.Errorf("expected replaced file to be rescanned, got %v", files)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media", "mystore.json")
+
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest on missing file failed: %v", err)
+	}
+	if len(m.Files) != 0 {
+		t.Fatalf("expected empty manifest, got %v", m.Files)
+	}
+
+	m.Files["a.jpg"] = ManifestEntry{Size: 60, ModTime: 1700000000, OriginalSize: 100}
+	m.Files["b.png"] = ManifestEntry{Size: 30, ModTime: 1700000000, OriginalSize: 50, WebP: true}
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Files, m.Files) {
+		t.Errorf("loaded %v, want %v", loaded.Files, m.Files)
+	}
+
+	original, current := loaded.Savings()
+	if original != 150 || current != 90 {
+		t.Errorf("Savings() = %d, %d, want 150, 90", original, current)
+	}
+}
+
+func TestOptimizerCommands(t *testing.T) {
+	o := &Optimizer{
+		Tools: Tools{
+			JPEGOptim: "/usr/bin/jpegoptim",
+			PNGQuant:  "/usr/bin/pngquant",
+			CWebP:     "/usr/bin/cwebp",
+			Nice:      "/usr/bin/nice",
+			IONice:    "/usr/bin/ionice",
+		},
+		Options: Options{Quality: 80, WebP: true},
+	}
+
+	cmds := o.Commands("/media/a.jpg")
+	if len(cmds) != 2 {
+		t.Fatalf("expected jpegoptim and cwebp, got %v", cmds)
+	}
+	want := "/usr/bin/ionice -c3 /usr/bin/nice -n 19 /usr/bin/jpegoptim --quiet --strip-all --all-progressive --max=80 /media/a.jpg"
+	if got := strings.Join(cmds[0], " "); got != want {
+		t.Errorf("jpeg command = %q, want %q", got, want)
+	}
+	if got := strings.Join(cmds[1], " "); !strings.HasSuffix(got, "/usr/bin/cwebp -quiet -q 80 /media/a.jpg -o /media/a.jpg.webp") {
+		t.Errorf("webp command = %q", got)
+	}
+
+	cmds = o.Commands("/media/b.png")
+	if len(cmds) != 2 || !strings.Contains(strings.Join(cmds[0], " "), "pngquant --force --skip-if-larger") {
+		t.Errorf("unexpected png commands %v", cmds)
+	}
+
+	// Without nice/ionice the optimizer runs directly
+	o.Tools.Nice, o.Tools.IONice = "", ""
+	o.Options = Options{}
+	cmds = o.Commands("/media/a.jpg")
+	if len(cmds) != 1 || cmds[0][0] != "/usr/bin/jpegoptim" || cmds[0][4] != "--max=85" {
+		t.Errorf("unexpected commands %v", cmds)
+	}
+}
+
+func TestToolsMissing(t *testing.T) {
+	tools := Tools{JPEGOptim: "/usr/bin/jpegoptim"}
+	if got := tools.Missing(Options{}); !reflect.DeepEqual(got, []string{"pngquant"}) {
+		t.Errorf("Missing() = %v", got)
+	}
+	if got := tools.Missing(Options{WebP: true}); !reflect.DeepEqual(got, []string{"pngquant", "cwebp"}) {
+		t.Errorf("Missing(webp) = %v", got)
+	}
+}
@@ -0,0 +1,107 @@
+package nginx
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// HealthPath is the location every project vhost answers health checks on
+const HealthPath = "/magebox-health"
+
+// healthScript is run by the project's PHP-FPM pool for HealthPath. Nginx
+// passes the project name and configured PHP version; when the pool can't be
+// reached Nginx answers with a "down" document itself.
+const healthScript = `<?php
+// Generated by MageBox - answers /magebox-health on every project vhost
+$expected = $_SERVER['MAGEBOX_PHP'] ?? '';
+$running = PHP_MAJOR_VERSION . '.' . PHP_MINOR_VERSION;
+$ok = $expected === '' || $expected === $running;
+
+http_response_code($ok ? 200 : 503);
+header('Content-Type: application/json');
+header('Cache-Control: no-store');
+echo json_encode([
+    'status' => $ok ? 'ok' : 'php_mismatch',
+    'project' => $_SERVER['MAGEBOX_PROJECT'] ?? '',
+    'fpm' => true,
+    'php' => $expected,
+    'php_version' => PHP_VERSION,
+]);
+`
+
+// Health is the document served on HealthPath
+type Health struct {
+	Status     string `json:"status"`      // "ok", "php_mismatch" or "down"
+	Project    string `json:"project"`     // Project name
+	FPM        bool   `json:"fpm"`         // Whether PHP-FPM answered
+	PHP        string `json:"php"`         // PHP version the project is configured for
+	PHPVersion string `json:"php_version"` // Full version of the PHP that answered
+}
+
+// OK reports whether PHP-FPM answered with the configured PHP version
+func (h *Health) OK() bool {
+	return h.Status == "ok"
+}
+
+// HealthScriptPath returns where the health check script is written
+func (g *VhostGenerator) HealthScriptPath() string {
+	return filepath.Join(g.platform.MageBoxDir(), "nginx", "health.php")
+}
+
+// writeHealthScript writes the script PHP-FPM runs for HealthPath
+func (g *VhostGenerator) writeHealthScript() error {
+	path := g.HealthScriptPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteGenerated(path, []byte(healthScript), 0644)
+}
+
+// HealthURL returns the health check URL of a domain
+func HealthURL(domain string, sslEnabled bool) string {
+	scheme := "https"
+	if !sslEnabled {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, domain, HealthPath)
+}
+
+// CheckHealth requests the health document of a vhost. Certificates aren't
+// verified, this checks PHP-FPM behind Nginx and not the SSL setup.
+func CheckHealth(ctx context.Context, url string) (*Health, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // local health check
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	var health Health
+	if err := json.Unmarshal(data, &health); err != nil || health.Status == "" {
+		return nil, fmt.Errorf("no health document at %s (HTTP %d), regenerate the vhost with 'magebox start'", url, resp.StatusCode)
+	}
+	return &health, nil
+}
@@ -0,0 +1,127 @@
+package nginx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantOK  bool
+		wantFPM bool
+		wantErr bool
+	}{
+		{
+			name:    "fpm answers",
+			status:  http.StatusOK,
+			body:    `{"status":"ok","project":"mystore","fpm":true,"php":"8.3","php_version":"8.3.12"}`,
+			wantOK:  true,
+			wantFPM: true,
+		},
+		{
+			name:    "fpm down",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"status":"down","project":"mystore","fpm":false,"php":"8.3","php_version":""}`,
+			wantFPM: false,
+		},
+		{
+			name:    "wrong php version",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"status":"php_mismatch","project":"mystore","fpm":true,"php":"8.3","php_version":"8.1.30"}`,
+			wantFPM: true,
+		},
+		{
+			name:    "vhost without health location",
+			status:  http.StatusNotFound,
+			body:    `<html>404</html>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != HealthPath {
+					t.Errorf("requested %s, want %s", r.URL.Path, HealthPath)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			health, err := CheckHealth(context.Background(), srv.URL+HealthPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("CheckHealth() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckHealth() error = %v", err)
+			}
+			if health.OK() != tt.wantOK || health.FPM != tt.wantFPM {
+				t.Errorf("CheckHealth() = %+v, want ok=%v fpm=%v", health, tt.wantOK, tt.wantFPM)
+			}
+		})
+	}
+}
+
+func TestHealthURL(t *testing.T) {
+	if got := HealthURL("mystore.test", true); got != "https://mystore.test/magebox-health" {
+		t.Errorf("HealthURL() = %q", got)
+	}
+	if got := HealthURL("mystore.test", false); got != "http://mystore.test/magebox-health" {
+		t.Errorf("HealthURL() = %q", got)
+	}
+}
+
+func TestRenderVhost_HealthLocation(t *testing.T) {
+	g, _ := setupTestGenerator(t)
+
+	content, err := g.renderVhost(VhostConfig{
+		ProjectName:  "mystore",
+		Domain:       "mystore.test",
+		DocumentRoot: "/var/www/mystore/pub",
+		PHPVersion:   "8.3",
+		MageMode:     "developer",
+		HealthScript: g.HealthScriptPath(),
+	})
+	if err != nil {
+		t.Fatalf("renderVhost failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"location = /magebox-health {",
+		"fastcgi_param SCRIPT_FILENAME " + g.HealthScriptPath() + ";",
+		"fastcgi_param MAGEBOX_PHP 8.3;",
+		// The health script's own 503 must reach the client
+		"fastcgi_intercept_errors off;",
+		"error_page 502 504 = @magebox_health_down;",
+		`"status":"down","project":"mystore"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("vhost should contain %q", want)
+		}
+	}
+}
+
+func TestVhostGenerator_WritesHealthScript(t *testing.T) {
+	g, _ := setupTestGenerator(t)
+	if err := g.writeHealthScript(); err != nil {
+		t.Fatalf("writeHealthScript() error = %v", err)
+	}
+	data, err := os.ReadFile(g.HealthScriptPath())
+	if err != nil {
+		t.Fatalf("health script not written: %v", err)
+	}
+	if !strings.Contains(string(data), "MAGEBOX_PHP") {
+		t.Error("health script should compare against MAGEBOX_PHP")
+	}
+}
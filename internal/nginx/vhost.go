@@ -2,6 +2,7 @@ package nginx
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"os"
@@ -9,12 +10,16 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/execctx"
+	"qoliber/magebox/internal/fileutil"
 	"qoliber/magebox/internal/lib"
 	"qoliber/magebox/internal/php"
 	"qoliber/magebox/internal/platform"
 	"qoliber/magebox/internal/ssl"
+	"qoliber/magebox/internal/varnish"
 )
 
 //go:embed templates/vhost.conf.tmpl
@@ -43,11 +48,20 @@ func init() {
 // - DocumentRoot: Absolute path to document root (e.g., "/var/www/mystore/pub")
 // - PHPVersion: PHP version (e.g., "8.2")
 // - PHPSocketPath: Path to PHP-FPM socket (e.g., "/tmp/magebox/mystore-php8.2.sock")
+// - FastCGIBackend: Upstream of the PHP version of the domain (e.g.,
+//   "fastcgi_backend_mystore", "fastcgi_backend_mystore_php83" for a domain
+//   with a PHP version of its own)
 // - SSLEnabled: Boolean indicating if SSL is enabled
 // - SSLCertFile: Path to SSL certificate file (only if SSLEnabled=true)
 // - SSLKeyFile: Path to SSL key file (only if SSLEnabled=true)
-// - UseVarnish: Boolean indicating if Varnish is enabled (currently unused)
-// - VarnishPort: Varnish port number (currently unused)
+// - UseVarnish: Boolean indicating if Varnish is enabled
+// - VarnishPort: Varnish port number
+// - VarnishMode: Varnish topology, "front" (every request goes through
+//   Varnish) or "behind" (nginx serves assets, admin, customer, checkout and
+//   authorized GraphQL itself)
+// - BackendPort: Port of the server Varnish fetches from
+// - Paths: Extra URL paths of the domain (Location, Alias, Index, Proxy)
+// - DevServer: Frontend dev server proxied on the domain (URL, Paths), nil without one
 
 // VhostGenerator generates Nginx vhost configurations
 type VhostGenerator struct {
@@ -70,15 +84,37 @@ type VhostConfig struct {
 	SSLKeyFile     string
 	UseVarnish     bool
 	VarnishPort    int
+	VarnishMode    string // config.VarnishModeFront or config.VarnishModeBehind
 	HTTPPort       int    // 80 on Linux, 8080 on macOS (port forwarding)
 	HTTPSPort      int    // 443 on Linux, 8443 on macOS (port forwarding)
-	BackendPort    int    // Backend port for Varnish (always 8080 when Varnish enabled)
+	BackendPort    int    // Backend port for Varnish (varnish.BackendPort when Varnish enabled)
 	EnableIPv6     bool   // true on Linux to add [::]:port listen directives
 	StoreCode      string // Magento store code for multi-store setup (default: "default")
 	MageRunType    string // Magento run type: "store" or "website" (default: "store")
+	MageMode       string // Magento deploy mode, production enables long browser caching of static files
 	AccessLog      string // Path to access log file
 	ErrorLog       string // Path to error log file
 	CustomNginxDir string // Path to project-level custom nginx snippets directory (if it exists)
+	HealthScript   string // Path to the PHP script answering /magebox-health
+	Paths          []VhostPath
+	DevServer      *VhostDevServer
+
+	// FastCGIBackend is the upstream of the domain's PHP version
+	FastCGIBackend string
+}
+
+// VhostPath is an extra URL path of a domain rendered as its own location block
+type VhostPath struct {
+	Location string // URL path without trailing slash (e.g., "/setup")
+	Alias    string // Absolute directory served on the path (empty when proxied)
+	Index    string // Front controller for requests that don't match a file
+	Proxy    string // Upstream URL (empty when served from Alias)
+}
+
+// VhostDevServer is a frontend dev server (e.g. Vite) proxied on a domain
+type VhostDevServer struct {
+	URL   string   // Address of the dev server (e.g., "http://127.0.0.1:5173")
+	Paths []string // URL paths proxied to it, without trailing slash
 }
 
 // ProxyConfig contains data needed to generate a proxy vhost
@@ -87,6 +123,7 @@ type ProxyConfig struct {
 	Domain      string
 	ProxyHost   string
 	ProxyPort   int
+	UpstreamSSL bool // Proxy to an HTTPS upstream, passing the requested host as SNI
 	SSLEnabled  bool
 	SSLCertFile string
 	SSLKeyFile  string
@@ -99,6 +136,17 @@ type ProxyConfig struct {
 type UpstreamConfig struct {
 	ProjectName   string
 	PHPSocketPath string
+
+	// Extra are the upstreams of the PHP versions domains run next to the
+	// project's
+	Extra []PHPUpstream
+}
+
+// PHPUpstream is the upstream of a PHP version of a project
+type PHPUpstream struct {
+	Name          string // Upstream name (e.g., "fastcgi_backend_mystore_php83")
+	PHPVersion    string
+	PHPSocketPath string
 }
 
 // NewVhostGenerator creates a new vhost generator
@@ -118,19 +166,69 @@ func (g *VhostGenerator) Generate(cfg *config.Config, projectPath string) error
 	}
 
 	// Ensure nginx logs directory exists
-	logsDir := filepath.Join(g.platform.MageBoxDir(), "logs", "nginx")
-	if err := os.MkdirAll(logsDir, 0755); err != nil {
+	if err := os.MkdirAll(g.logsDir(), 0755); err != nil {
 		return fmt.Errorf("failed to create nginx logs directory: %w", err)
 	}
 
-	// Generate upstream config (once per project, not per domain)
+	if err := g.writeHealthScript(); err != nil {
+		return fmt.Errorf("failed to write health check script: %w", err)
+	}
+
+	files, err := g.render(cfg, projectPath)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := fileutil.WriteGenerated(file.Path, []byte(file.Content), 0644, fileutil.ValidateBraces); err != nil {
+			return fmt.Errorf("failed to write vhost file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RenderedFile is a generated configuration file that has been rendered but not written
+type RenderedFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Preview renders the upstream and vhost files for a project without touching the filesystem
+func (g *VhostGenerator) Preview(cfg *config.Config, projectPath string) ([]RenderedFile, error) {
+	return g.render(cfg, projectPath)
+}
+
+// logsDir returns the directory nginx access/error logs are written to
+func (g *VhostGenerator) logsDir() string {
+	return filepath.Join(g.platform.MageBoxDir(), "logs", "nginx")
+}
+
+// render renders the upstream config followed by one vhost per domain
+func (g *VhostGenerator) render(cfg *config.Config, projectPath string) ([]RenderedFile, error) {
+	logsDir := g.logsDir()
+	files := make([]RenderedFile, 0, len(cfg.Domains)+1)
+
+	// Render upstream config (once per project, not per domain)
 	upstreamCfg := UpstreamConfig{
 		ProjectName:   cfg.Name,
 		PHPSocketPath: g.getPHPSocketPath(cfg.Name, cfg.PHP),
 	}
-	if err := g.generateUpstream(upstreamCfg); err != nil {
-		return fmt.Errorf("failed to generate upstream config: %w", err)
+	for _, version := range cfg.PHPVersions()[1:] {
+		upstreamCfg.Extra = append(upstreamCfg.Extra, PHPUpstream{
+			Name:          FastCGIBackend(cfg.Name, cfg.PHP, version),
+			PHPVersion:    version,
+			PHPSocketPath: g.getPHPSocketPath(cfg.Name, version),
+		})
+	}
+	upstream, err := g.renderUpstream(upstreamCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate upstream config: %w", err)
 	}
+	files = append(files, RenderedFile{
+		Path:    g.upstreamFile(cfg.Name),
+		Content: upstream,
+	})
 
 	// Determine ports based on platform
 	// macOS uses port forwarding (80->8080, 443->8443), Linux uses standard ports
@@ -143,14 +241,15 @@ func (g *VhostGenerator) Generate(cfg *config.Config, projectPath string) error
 	}
 
 	for _, domain := range cfg.Domains {
-		// Backend port for Varnish is always 8080
+		// Varnish fetches from a server of its own port
 		backendPort := httpPort
 		if cfg.Services.HasVarnish() {
-			backendPort = 8080
+			backendPort = varnish.BackendPort
 		}
 
 		// Generate sanitized domain name for log files
 		sanitizedDomain := sanitizeDomain(domain.Host)
+		phpVersion := domain.GetPHP(cfg.PHP)
 
 		vhostCfg := VhostConfig{
 			ProjectName:   cfg.Name,
@@ -158,19 +257,28 @@ func (g *VhostGenerator) Generate(cfg *config.Config, projectPath string) error
 			ProjectType:   cfg.GetType(),
 			Domain:        domain.Host,
 			DocumentRoot:  filepath.Join(projectPath, domain.GetRootForProject(projectPath, cfg.GetType())),
-			PHPVersion:    cfg.PHP,
-			PHPSocketPath: g.getPHPSocketPath(cfg.Name, cfg.PHP),
+			PHPVersion:    phpVersion,
+			PHPSocketPath: g.getPHPSocketPath(cfg.Name, phpVersion),
 			SSLEnabled:    domain.IsSSLEnabled(),
 			UseVarnish:    cfg.Services.HasVarnish(),
-			VarnishPort:   6081,
+			VarnishPort:   varnish.Port,
+			VarnishMode:   cfg.Services.VarnishMode(),
 			HTTPPort:      httpPort,
 			HTTPSPort:     httpsPort,
 			BackendPort:   backendPort,
 			EnableIPv6:    enableIPv6,
 			StoreCode:     domain.GetStoreCode(),
 			MageRunType:   domain.GetMageRunType(),
+			MageMode:      cfg.MageMode(),
 			AccessLog:     filepath.Join(logsDir, fmt.Sprintf("%s-access.log", sanitizedDomain)),
 			ErrorLog:      filepath.Join(logsDir, fmt.Sprintf("%s-error.log", sanitizedDomain)),
+			HealthScript:  g.HealthScriptPath(),
+			Paths:         vhostPaths(domain.Paths, projectPath),
+		}
+		vhostCfg.FastCGIBackend = FastCGIBackend(cfg.Name, cfg.PHP, phpVersion)
+
+		if ds := cfg.DevServer(); ds != nil {
+			vhostCfg.DevServer = &VhostDevServer{URL: ds.URL(), Paths: ds.GetPaths()}
 		}
 
 		// Check for project-level custom nginx snippets directory
@@ -181,23 +289,62 @@ func (g *VhostGenerator) Generate(cfg *config.Config, projectPath string) error
 
 		// Get SSL cert paths if SSL is enabled
 		if vhostCfg.SSLEnabled {
-			certPaths := g.sslManager.GetCertPaths(ssl.ExtractBaseDomain(domain.Host))
+			certPaths := g.sslManager.GetCertPaths(ssl.ExtractBaseDomainForTLD(domain.Host, cfg.TLD))
 			vhostCfg.SSLCertFile = certPaths.CertFile
 			vhostCfg.SSLKeyFile = certPaths.KeyFile
 		}
 
 		content, err := g.renderVhost(vhostCfg)
 		if err != nil {
-			return fmt.Errorf("failed to render vhost for %s: %w", domain.Host, err)
+			return nil, fmt.Errorf("failed to render vhost for %s: %w", domain.Host, err)
 		}
-
-		vhostFile := filepath.Join(g.vhostsDir, fmt.Sprintf("%s-%s.conf", cfg.Name, sanitizeDomain(domain.Host)))
-		if err := os.WriteFile(vhostFile, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to write vhost file: %w", err)
+		// A template from before per-domain PHP passes every domain to the
+		// project's upstream
+		if phpVersion != cfg.PHP && !strings.Contains(content, vhostCfg.FastCGIBackend) {
+			return nil, fmt.Errorf("vhost template of %s doesn't use {{.FastCGIBackend}}, update it to run PHP %s", domain.Host, phpVersion)
 		}
+
+		files = append(files, RenderedFile{
+			Path:    g.domainVhostFile(cfg.Name, domain.Host),
+			Content: content,
+		})
 	}
 
-	return nil
+	return files, nil
+}
+
+// upstreamFile returns the path of the upstream config of a project
+func (g *VhostGenerator) upstreamFile(projectName string) string {
+	return filepath.Join(g.vhostsDir, fmt.Sprintf("%s-upstream.conf", projectName))
+}
+
+// domainVhostFile returns the path of the vhost of a project domain
+func (g *VhostGenerator) domainVhostFile(projectName, host string) string {
+	return filepath.Join(g.vhostsDir, fmt.Sprintf("%s-%s.conf", projectName, sanitizeDomain(host)))
+}
+
+// VhostFiles returns the paths of the upstream config and vhosts Generate
+// writes for a project. Unlike a <project>-*.conf glob, they never match
+// the files of another project whose name starts with this one's.
+func (g *VhostGenerator) VhostFiles(cfg *config.Config) []string {
+	files := []string{g.upstreamFile(cfg.Name)}
+	for _, domain := range cfg.Domains {
+		files = append(files, g.domainVhostFile(cfg.Name, domain.Host))
+	}
+	return files
+}
+
+// vhostPaths converts the path mappings of a domain to template data
+func vhostPaths(mappings []config.PathMapping, projectPath string) []VhostPath {
+	paths := make([]VhostPath, 0, len(mappings))
+	for _, m := range mappings {
+		p := VhostPath{Location: m.Location(), Index: m.GetIndex(), Proxy: m.Proxy}
+		if m.Proxy == "" {
+			p.Alias = m.RootForProject(projectPath)
+		}
+		paths = append(paths, p)
+	}
+	return paths
 }
 
 // Remove removes vhost configurations for a project
@@ -217,6 +364,16 @@ func (g *VhostGenerator) Remove(projectName string) error {
 	return nil
 }
 
+// FastCGIBackend returns the name of the upstream of a PHP version of a
+// project: fastcgi_backend_<project> for the project's version, with a
+// _php<version> suffix for the other versions of its domains
+func FastCGIBackend(projectName, projectPHP, phpVersion string) string {
+	if phpVersion == projectPHP {
+		return "fastcgi_backend_" + projectName
+	}
+	return fmt.Sprintf("fastcgi_backend_%s_php%s", projectName, strings.ReplaceAll(phpVersion, ".", ""))
+}
+
 // getPHPSocketPath returns the PHP-FPM socket path for a project
 // If the project has isolation enabled, returns the isolated socket path
 func (g *VhostGenerator) getPHPSocketPath(projectName, phpVersion string) string {
@@ -269,17 +426,6 @@ func (g *VhostGenerator) renderVhost(cfg VhostConfig) (string, error) {
 	return buf.String(), nil
 }
 
-// generateUpstream generates the upstream config file for a project
-func (g *VhostGenerator) generateUpstream(cfg UpstreamConfig) error {
-	content, err := g.renderUpstream(cfg)
-	if err != nil {
-		return err
-	}
-
-	upstreamFile := filepath.Join(g.vhostsDir, fmt.Sprintf("%s-upstream.conf", cfg.ProjectName))
-	return os.WriteFile(upstreamFile, []byte(content), 0644)
-}
-
 // renderUpstream renders the upstream template
 func (g *VhostGenerator) renderUpstream(cfg UpstreamConfig) (string, error) {
 	tmplContent, err := lib.GetTemplate(lib.TemplateNginx, "upstream.conf.tmpl")
@@ -344,13 +490,22 @@ func (g *VhostGenerator) GenerateProxyVhost(cfg ProxyConfig) error {
 	}
 
 	vhostFile := filepath.Join(g.vhostsDir, fmt.Sprintf("%s.conf", cfg.Name))
-	if err := os.WriteFile(vhostFile, []byte(content), 0644); err != nil {
+	if err := fileutil.WriteGenerated(vhostFile, []byte(content), 0644, fileutil.ValidateBraces); err != nil {
 		return fmt.Errorf("failed to write proxy vhost file: %w", err)
 	}
 
 	return nil
 }
 
+// RemoveProxyVhost removes a proxy vhost generated by GenerateProxyVhost
+func (g *VhostGenerator) RemoveProxyVhost(name string) error {
+	err := os.Remove(filepath.Join(g.vhostsDir, fmt.Sprintf("%s.conf", name)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // renderProxyVhost renders the proxy vhost template
 func (g *VhostGenerator) renderProxyVhost(cfg ProxyConfig) (string, error) {
 	tmplContent, err := lib.GetTemplate(lib.TemplateNginx, "proxy.conf.tmpl")
@@ -379,11 +534,27 @@ func sanitizeDomain(domain string) string {
 // Controller manages Nginx service
 type Controller struct {
 	platform *platform.Platform
+	ctx      context.Context
+	timeout  time.Duration
 }
 
-// NewController creates a new Nginx controller
+// NewController creates a new Nginx controller using the default context and
+// nginx timeout
 func NewController(p *platform.Platform) *Controller {
-	return &Controller{platform: p}
+	ctx, timeouts := execctx.Defaults()
+	return &Controller{platform: p, ctx: ctx, timeout: timeouts.Nginx}
+}
+
+// WithContext returns a copy of the controller whose commands are cancelled with ctx
+func (c *Controller) WithContext(ctx context.Context) *Controller {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// command builds a command bound to the controller context and timeout
+func (c *Controller) command(name string, args ...string) *execctx.Cmd {
+	return execctx.Command(c.ctx, c.timeout, "nginx", name, args...)
 }
 
 // Reload reloads Nginx configuration
@@ -391,7 +562,7 @@ func (c *Controller) Reload() error {
 	switch c.platform.Type {
 	case platform.Darwin:
 		// On macOS, use nginx -s reload directly (more reliable than brew services)
-		cmd := exec.Command("nginx", "-s", "reload")
+		cmd := c.command("nginx", "-s", "reload")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("failed to reload nginx: %w\nOutput: %s", err, output)
@@ -399,7 +570,7 @@ func (c *Controller) Reload() error {
 		return nil
 	case platform.Linux:
 		// Use nginx -s reload directly (more reliable than systemctl reload)
-		cmd := exec.Command("sudo", "nginx", "-s", "reload")
+		cmd := c.command("sudo", "nginx", "-s", "reload")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("failed to reload nginx: %w\nOutput: %s", err, output)
@@ -411,12 +582,12 @@ func (c *Controller) Reload() error {
 
 // Test tests Nginx configuration
 func (c *Controller) Test() error {
-	var cmd *exec.Cmd
+	var cmd *execctx.Cmd
 	switch c.platform.Type {
 	case platform.Darwin:
-		cmd = exec.Command("nginx", "-t")
+		cmd = c.command("nginx", "-t")
 	default:
-		cmd = exec.Command("sudo", "nginx", "-t")
+		cmd = c.command("sudo", "nginx", "-t")
 	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -429,10 +600,10 @@ func (c *Controller) Test() error {
 func (c *Controller) Start() error {
 	switch c.platform.Type {
 	case platform.Darwin:
-		cmd := exec.Command("brew", "services", "start", "nginx")
+		cmd := c.command("brew", "services", "start", "nginx")
 		return cmd.Run()
 	case platform.Linux:
-		cmd := exec.Command("sudo", "systemctl", "start", "nginx")
+		cmd := c.command("sudo", "systemctl", "start", "nginx")
 		return cmd.Run()
 	}
 	return fmt.Errorf("unsupported platform")
@@ -442,10 +613,10 @@ func (c *Controller) Start() error {
 func (c *Controller) Stop() error {
 	switch c.platform.Type {
 	case platform.Darwin:
-		cmd := exec.Command("brew", "services", "stop", "nginx")
+		cmd := c.command("brew", "services", "stop", "nginx")
 		return cmd.Run()
 	case platform.Linux:
-		cmd := exec.Command("sudo", "systemctl", "stop", "nginx")
+		cmd := c.command("sudo", "systemctl", "stop", "nginx")
 		return cmd.Run()
 	}
 	return fmt.Errorf("unsupported platform")
@@ -455,10 +626,10 @@ func (c *Controller) Stop() error {
 func (c *Controller) Restart() error {
 	switch c.platform.Type {
 	case platform.Darwin:
-		cmd := exec.Command("brew", "services", "restart", "nginx")
+		cmd := c.command("brew", "services", "restart", "nginx")
 		return cmd.Run()
 	case platform.Linux:
-		cmd := exec.Command("sudo", "systemctl", "restart", "nginx")
+		cmd := c.command("sudo", "systemctl", "restart", "nginx")
 		return cmd.Run()
 	}
 	return fmt.Errorf("unsupported platform")
@@ -101,6 +101,107 @@ func TestVhostGenerator_Generate(t *testing.T) {
 	}
 }
 
+func TestVhostGenerator_GenerateCustomNginxDir(t *testing.T) {
+	for _, projectType := range []string{config.ProjectTypeMagento, config.ProjectTypeLaravel} {
+		t.Run(projectType, func(t *testing.T) {
+			g, tmpDir := setupTestGenerator(t)
+
+			// The include of the snippets contains "/*", which isn't a comment in nginx
+			projectPath := filepath.Join(tmpDir, "projects", "mystore")
+			customDir := filepath.Join(projectPath, ".magebox", "nginx")
+			if err := os.MkdirAll(customDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.Config{
+				Name:    "mystore",
+				Type:    projectType,
+				Domains: []config.Domain{{Host: "mystore.test"}},
+				PHP:     "8.2",
+			}
+
+			if err := g.Generate(cfg, projectPath); err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(g.vhostsDir, "mystore-mystore.test.conf"))
+			if err != nil {
+				t.Fatalf("Failed to read vhost file: %v", err)
+			}
+			if !strings.Contains(string(content), "include "+customDir+"/*.conf;") {
+				t.Error("Vhost should include the custom nginx snippets")
+			}
+		})
+	}
+}
+
+func TestVhostGenerator_GenerateDomainPHP(t *testing.T) {
+	g, tmpDir := setupTestGenerator(t)
+
+	projectPath := filepath.Join(tmpDir, "projects", "mystore")
+	cfg := &config.Config{
+		Name: "mystore",
+		Domains: []config.Domain{
+			{Host: "mystore.test"},
+			{Host: "next.mystore.test", PHP: "8.3"},
+		},
+		PHP: "8.2",
+	}
+
+	files, err := g.Preview(cfg, projectPath)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Preview rendered %d files, want 3", len(files))
+	}
+
+	upstream := files[0].Content
+	for _, want := range []string{
+		"upstream fastcgi_backend_mystore {",
+		"mystore-php8.2.sock",
+		"upstream fastcgi_backend_mystore_php83 {",
+		"mystore-php8.3.sock",
+	} {
+		if !strings.Contains(upstream, want) {
+			t.Errorf("upstream config should contain %q", want)
+		}
+	}
+
+	if !strings.Contains(files[1].Content, "fastcgi_pass fastcgi_backend_mystore;") {
+		t.Error("main domain should pass PHP to the project's upstream")
+	}
+	next := files[2].Content
+	if !strings.Contains(next, "fastcgi_pass fastcgi_backend_mystore_php83;") || strings.Contains(next, "fastcgi_pass fastcgi_backend_mystore;") {
+		t.Error("domain on PHP 8.3 should pass PHP to the 8.3 upstream only")
+	}
+	if !strings.Contains(next, "fastcgi_param MAGEBOX_PHP 8.3;") {
+		t.Error("health check of the domain should report PHP 8.3")
+	}
+}
+
+func TestVhostGenerator_DomainPHPNeedsTemplateSupport(t *testing.T) {
+	g, tmpDir := setupTestGenerator(t)
+
+	projectPath := filepath.Join(tmpDir, "projects", "mystore")
+	tmplDir := filepath.Join(projectPath, ".magebox", "templates", "nginx")
+	if err := os.MkdirAll(tmplDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A template from before per-domain PHP
+	old := "server { server_name {{.Domain}}; location ~ \\.php$ { fastcgi_pass fastcgi_backend_{{.ProjectName}}; } }\n"
+	if err := os.WriteFile(filepath.Join(tmplDir, "vhost.conf.tmpl"), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Name:    "mystore",
+		Domains: []config.Domain{{Host: "mystore.test"}, {Host: "next.mystore.test", PHP: "8.3"}},
+		PHP:     "8.2",
+	}
+	if _, err := g.Preview(cfg, projectPath); err == nil || !strings.Contains(err.Error(), "FastCGIBackend") {
+		t.Errorf("Preview() error = %v, want a template error", err)
+	}
+}
+
 func TestVhostGenerator_GenerateMultipleDomains(t *testing.T) {
 	g, tmpDir := setupTestGenerator(t)
 
@@ -309,6 +410,142 @@ func TestRenderVhost_SSLDisabled(t *testing.T) {
 	}
 }
 
+func TestRenderVhost_MageMode(t *testing.T) {
+	g, tmpDir := setupTestGenerator(t)
+
+	tests := []struct {
+		mode     string
+		contains []string
+		excludes []string
+	}{
+		{
+			mode:     "developer",
+			contains: []string{"set $MAGE_MODE developer;", "expires off;", `add_header Cache-Control "no-cache";`},
+			excludes: []string{"expires max;"},
+		},
+		{
+			mode:     "production",
+			contains: []string{"set $MAGE_MODE production;", "expires max;", "expires +1y;"},
+			excludes: []string{`add_header Cache-Control "no-cache";`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			content, err := g.renderVhost(VhostConfig{
+				ProjectName:   "mystore",
+				Domain:        "mystore.test",
+				DocumentRoot:  "/var/www/mystore/pub",
+				PHPVersion:    "8.2",
+				PHPSocketPath: filepath.Join(tmpDir, ".magebox", "run", "mystore-php8.2.sock"),
+				MageMode:      tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("renderVhost failed: %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(content, s) {
+					t.Errorf("%s vhost should contain %q", tt.mode, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(content, s) {
+					t.Errorf("%s vhost should not contain %q", tt.mode, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderVhost_VarnishMode(t *testing.T) {
+	g, tmpDir := setupTestGenerator(t)
+
+	tests := []struct {
+		name     string
+		mode     string
+		ssl      bool
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "front with ssl",
+			mode: "front",
+			ssl:  true,
+			contains: []string{
+				"listen 8443 ssl http2;",
+				"return 301 https://$host$request_uri;",
+				"proxy_pass http://127.0.0.1:6081;",
+				"proxy_set_header X-Forwarded-Proto https;",
+				"listen 8081;",
+			},
+			excludes: []string{"proxy_pass http://127.0.0.1:8081;", "location /graphql"},
+		},
+		{
+			name: "front without ssl",
+			mode: "front",
+			contains: []string{
+				"listen 8080;",
+				"proxy_pass http://127.0.0.1:6081;",
+				"proxy_set_header X-Forwarded-Proto http;",
+				"listen 8081;",
+			},
+			excludes: []string{"ssl_certificate", "return 301 https://"},
+		},
+		{
+			name: "behind with ssl",
+			mode: "behind",
+			ssl:  true,
+			contains: []string{
+				"proxy_pass http://127.0.0.1:6081;",
+				"location ~ ^/(static|media)/ {",
+				`location ~ ^/(index\.php/)?(admin|customer|checkout)(/|$) {`,
+				"location /graphql {",
+				`if ($http_authorization != "") {`,
+				"location @magebox_backend {",
+				"proxy_pass http://127.0.0.1:8081;",
+				"listen 8081;",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := g.renderVhost(VhostConfig{
+				ProjectName:   "mystore",
+				Domain:        "mystore.test",
+				DocumentRoot:  "/var/www/mystore/pub",
+				PHPVersion:    "8.2",
+				PHPSocketPath: filepath.Join(tmpDir, ".magebox", "run", "mystore-php8.2.sock"),
+				SSLEnabled:    tt.ssl,
+				SSLCertFile:   "/path/to/cert.pem",
+				SSLKeyFile:    "/path/to/key.pem",
+				UseVarnish:    true,
+				VarnishPort:   6081,
+				VarnishMode:   tt.mode,
+				HTTPPort:      8080,
+				HTTPSPort:     8443,
+				BackendPort:   8081,
+			})
+			if err != nil {
+				t.Fatalf("renderVhost failed: %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(content, s) {
+					t.Errorf("%s vhost should contain %q", tt.name, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(content, s) {
+					t.Errorf("%s vhost should not contain %q", tt.name, s)
+				}
+			}
+			if strings.Count(content, "{") != strings.Count(content, "}") {
+				t.Errorf("%s vhost has unbalanced braces", tt.name)
+			}
+		})
+	}
+}
+
 func TestNewController(t *testing.T) {
 	p := &platform.Platform{Type: platform.Linux}
 	if c := NewController(p); c == nil {
@@ -364,7 +601,7 @@ func TestVhostTemplateValidity(t *testing.T) {
 		"root $MAGE_ROOT",
 		"{{if .SSLEnabled}}",
 		"ssl_certificate {{.SSLCertFile}}",
-		"fastcgi_pass fastcgi_backend_{{.ProjectName}}",
+		"fastcgi_pass {{.FastCGIBackend}}",
 		"location /static/",
 		"location /media/",
 	}
@@ -788,3 +1025,88 @@ func TestIncludeDirective_UsesCorrectPath(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderVhost_Paths(t *testing.T) {
+	g, tmpDir := setupTestGenerator(t)
+
+	cfg := VhostConfig{
+		ProjectName:   "mystore",
+		Domain:        "mystore.test",
+		DocumentRoot:  "/var/www/mystore/pub",
+		PHPVersion:    "8.2",
+		PHPSocketPath: filepath.Join(tmpDir, ".magebox", "run", "mystore-php8.2.sock"),
+		Paths: vhostPaths([]config.PathMapping{
+			{Path: "/setup/", Root: "setup"},
+			{Path: "/legacy", Root: "/srv/m1", Index: "index.html"},
+			{Path: "/static-proxy", Proxy: "https://www.example.com/static/"},
+		}, "/var/www/mystore"),
+	}
+
+	content, err := g.renderVhost(cfg)
+	if err != nil {
+		t.Fatalf("renderVhost failed: %v", err)
+	}
+
+	expected := []string{
+		"location ^~ /setup/ {",
+		"alias /var/www/mystore/setup/;",
+		"try_files $uri $uri/ /setup/index.php$is_args$args;",
+		"return 301 /setup/$is_args$args;",
+		"alias /srv/m1/;",
+		"try_files $uri $uri/ /legacy/index.html$is_args$args;",
+		"location ^~ /static-proxy/ {",
+		"proxy_pass https://www.example.com/static/;",
+		"fastcgi_param SCRIPT_FILENAME $request_filename;",
+	}
+	for _, want := range expected {
+		if !strings.Contains(content, want) {
+			t.Errorf("vhost should contain %q", want)
+		}
+	}
+	if strings.Count(content, "{") != strings.Count(content, "}") {
+		t.Error("vhost braces are unbalanced")
+	}
+}
+
+func TestRenderVhost_DevServer(t *testing.T) {
+	g, tmpDir := setupTestGenerator(t)
+	devServer := &VhostDevServer{URL: "http://127.0.0.1:5173", Paths: config.DefaultDevServerPaths}
+
+	for _, useVarnish := range []bool{false, true} {
+		content, err := g.renderVhost(VhostConfig{
+			ProjectName:   "mystore",
+			Domain:        "mystore.test",
+			DocumentRoot:  "/var/www/mystore/pub",
+			PHPVersion:    "8.2",
+			PHPSocketPath: filepath.Join(tmpDir, ".magebox", "run", "mystore-php8.2.sock"),
+			UseVarnish:    useVarnish,
+			VarnishPort:   6081,
+			VarnishMode:   config.VarnishModeFront,
+			HTTPPort:      80,
+			BackendPort:   8081,
+			DevServer:     devServer,
+		})
+		if err != nil {
+			t.Fatalf("renderVhost failed: %v", err)
+		}
+
+		// With Varnish the dev server is proxied by the server facing the
+		// browser, not by the backend Varnish fetches from
+		if got := strings.Count(content, "location ^~ /@vite {"); got != 1 {
+			t.Errorf("varnish=%v: /@vite is proxied %d times, want once", useVarnish, got)
+		}
+		for _, want := range []string{
+			"location ^~ /node_modules {",
+			"proxy_pass http://127.0.0.1:5173;",
+			"proxy_set_header Upgrade $http_upgrade;",
+			"proxy_set_header Connection $http_connection;",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("varnish=%v: vhost should contain %q", useVarnish, want)
+			}
+		}
+		if strings.Count(content, "{") != strings.Count(content, "}") {
+			t.Errorf("varnish=%v: vhost braces are unbalanced", useVarnish)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package php
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheStatsScript reports OPcache and realpath cache state as JSON.
+// It must run inside the FPM pool: the CLI has its own, empty OPcache.
+const cacheStatsScript = `<?php
+header('Content-Type: application/json');
+$status = function_exists('opcache_get_status') ? @opcache_get_status(false) : false;
+$config = function_exists('opcache_get_configuration') ? @opcache_get_configuration() : false;
+echo json_encode([
+    'opcache' => $status ?: null,
+    'directives' => $config ? $config['directives'] : null,
+    'realpath_cache_used' => realpath_cache_size(),
+    'realpath_cache_limit' => ini_get('realpath_cache_size'),
+    'realpath_cache_entries' => count(realpath_cache_get()),
+]);
+`
+
+// cacheWarnRatio is the utilization above which a cache is flagged as too small
+const cacheWarnRatio = 0.9
+
+// CacheStats describes the OPcache and realpath cache utilization of a pool
+type CacheStats struct {
+	OPcacheEnabled bool `json:"opcache_enabled"`
+	CacheFull      bool `json:"cache_full"`
+
+	MemoryUsed   int64 `json:"memory_used"`
+	MemoryFree   int64 `json:"memory_free"`
+	MemoryWasted int64 `json:"memory_wasted"`
+
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+
+	CachedScripts       int64 `json:"cached_scripts"`
+	MaxAcceleratedFiles int64 `json:"max_accelerated_files"`
+
+	InternedUsed int64 `json:"interned_strings_used"`
+	InternedSize int64 `json:"interned_strings_size"`
+
+	RealpathUsed    int64 `json:"realpath_cache_used"`
+	RealpathLimit   int64 `json:"realpath_cache_limit"`
+	RealpathEntries int64 `json:"realpath_cache_entries"`
+}
+
+// cacheStatsResponse mirrors the JSON written by cacheStatsScript
+type cacheStatsResponse struct {
+	OPcache *struct {
+		Enabled     bool `json:"opcache_enabled"`
+		CacheFull   bool `json:"cache_full"`
+		MemoryUsage struct {
+			Used   int64 `json:"used_memory"`
+			Free   int64 `json:"free_memory"`
+			Wasted int64 `json:"wasted_memory"`
+		} `json:"memory_usage"`
+		InternedStrings struct {
+			BufferSize int64 `json:"buffer_size"`
+			Used       int64 `json:"used_memory"`
+		} `json:"interned_strings_usage"`
+		Statistics struct {
+			CachedScripts int64   `json:"num_cached_scripts"`
+			MaxCachedKeys int64   `json:"max_cached_keys"`
+			Hits          int64   `json:"hits"`
+			Misses        int64   `json:"misses"`
+			HitRate       float64 `json:"opcache_hit_rate"`
+		} `json:"opcache_statistics"`
+	} `json:"opcache"`
+	Directives *struct {
+		MaxAcceleratedFiles int64 `json:"opcache.max_accelerated_files"`
+	} `json:"directives"`
+	RealpathUsed    int64  `json:"realpath_cache_used"`
+	RealpathLimit   string `json:"realpath_cache_limit"`
+	RealpathEntries int64  `json:"realpath_cache_entries"`
+}
+
+// QueryCacheStats runs a temporary script in the project's FPM pool and
+// returns its cache utilization. The script is written to the project's var/
+// directory (not web accessible, and visible to FPM even with PrivateTmp) and
+// removed afterwards.
+func QueryCacheStats(socketPath, projectPath string) (*CacheStats, error) {
+	varDir := filepath.Join(projectPath, "var")
+	if err := os.MkdirAll(varDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create var directory: %w", err)
+	}
+
+	script, err := os.CreateTemp(varDir, ".magebox-cache-stats-*.php")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stats script: %w", err)
+	}
+	scriptPath := script.Name()
+	defer os.Remove(scriptPath)
+
+	if _, err := script.WriteString(cacheStatsScript); err != nil {
+		script.Close()
+		return nil, fmt.Errorf("failed to write stats script: %w", err)
+	}
+	script.Close()
+
+	body, err := fastCGIRequest(socketPath, map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"SERVER_SOFTWARE":   "magebox",
+		"SERVER_PROTOCOL":   "HTTP/1.1",
+		"REQUEST_METHOD":    "GET",
+		"REMOTE_ADDR":       "127.0.0.1",
+		"SCRIPT_FILENAME":   scriptPath,
+		"SCRIPT_NAME":       "/" + filepath.Base(scriptPath),
+		"DOCUMENT_ROOT":     varDir,
+		"QUERY_STRING":      "",
+	}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCacheStats(body)
+}
+
+// parseCacheStats converts the stats script output into CacheStats
+func parseCacheStats(body []byte) (*CacheStats, error) {
+	var resp cacheStatsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("unexpected stats output: %w", err)
+	}
+
+	stats := &CacheStats{
+		RealpathUsed:    resp.RealpathUsed,
+		RealpathLimit:   parseINISize(resp.RealpathLimit),
+		RealpathEntries: resp.RealpathEntries,
+	}
+	if resp.Directives != nil {
+		stats.MaxAcceleratedFiles = resp.Directives.MaxAcceleratedFiles
+	}
+	if o := resp.OPcache; o != nil {
+		stats.OPcacheEnabled = o.Enabled
+		stats.CacheFull = o.CacheFull
+		stats.MemoryUsed = o.MemoryUsage.Used
+		stats.MemoryFree = o.MemoryUsage.Free
+		stats.MemoryWasted = o.MemoryUsage.Wasted
+		stats.Hits = o.Statistics.Hits
+		stats.Misses = o.Statistics.Misses
+		stats.HitRate = o.Statistics.HitRate
+		stats.CachedScripts = o.Statistics.CachedScripts
+		if o.Statistics.MaxCachedKeys > 0 {
+			stats.MaxAcceleratedFiles = o.Statistics.MaxCachedKeys
+		}
+		stats.InternedUsed = o.InternedStrings.Used
+		stats.InternedSize = o.InternedStrings.BufferSize
+	}
+	return stats, nil
+}
+
+// MemoryTotal returns the configured OPcache memory (opcache.memory_consumption)
+func (s *CacheStats) MemoryTotal() int64 {
+	return s.MemoryUsed + s.MemoryFree + s.MemoryWasted
+}
+
+// Warnings returns a recommendation for every cache that is close to full
+func (s *CacheStats) Warnings() []string {
+	var warnings []string
+
+	if s.OPcacheEnabled {
+		if s.CacheFull || ratio(s.MemoryUsed+s.MemoryWasted, s.MemoryTotal()) >= cacheWarnRatio {
+			warnings = append(warnings, fmt.Sprintf("OPcache memory is %.0f%% used, raise opcache.memory_consumption (currently %dM)",
+				100*ratio(s.MemoryUsed+s.MemoryWasted, s.MemoryTotal()), s.MemoryTotal()>>20))
+		}
+		if ratio(s.InternedUsed, s.InternedSize) >= cacheWarnRatio {
+			warnings = append(warnings, fmt.Sprintf("Interned strings buffer is %.0f%% used, raise opcache.interned_strings_buffer (currently %dM)",
+				100*ratio(s.InternedUsed, s.InternedSize), s.InternedSize>>20))
+		}
+		if ratio(s.CachedScripts, s.MaxAcceleratedFiles) >= cacheWarnRatio {
+			warnings = append(warnings, fmt.Sprintf("%d of %d script slots used, raise opcache.max_accelerated_files",
+				s.CachedScripts, s.MaxAcceleratedFiles))
+		}
+	}
+	if ratio(s.RealpathUsed, s.RealpathLimit) >= cacheWarnRatio {
+		warnings = append(warnings, fmt.Sprintf("Realpath cache is %.0f%% used, raise realpath_cache_size (currently %dK)",
+			100*ratio(s.RealpathUsed, s.RealpathLimit), s.RealpathLimit>>10))
+	}
+
+	return warnings
+}
+
+// ratio returns part/total, or 0 when total is unknown
+func ratio(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+// parseINISize parses PHP shorthand byte values like "4096K", "128M" or "1G"
+func parseINISize(value string) int64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	multiplier := int64(1)
+	switch strings.ToUpper(value[len(value)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n * multiplier
+}
@@ -0,0 +1,159 @@
+package php
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleCacheStats = `{
+	"opcache": {
+		"opcache_enabled": true,
+		"cache_full": false,
+		"memory_usage": {"used_memory": 250000000, "free_memory": 6435456, "wasted_memory": 12000000},
+		"interned_strings_usage": {"buffer_size": 16777216, "used_memory": 8000000},
+		"opcache_statistics": {"num_cached_scripts": 9500, "max_cached_keys": 16229, "hits": 990, "misses": 10, "opcache_hit_rate": 99.0}
+	},
+	"directives": {"opcache.max_accelerated_files": 10000},
+	"realpath_cache_used": 4000000,
+	"realpath_cache_limit": "4096K",
+	"realpath_cache_entries": 12000
+}`
+
+func TestParseCacheStats(t *testing.T) {
+	stats, err := parseCacheStats([]byte(sampleCacheStats))
+	if err != nil {
+		t.Fatalf("parseCacheStats failed: %v", err)
+	}
+
+	if !stats.OPcacheEnabled {
+		t.Error("OPcacheEnabled should be true")
+	}
+	if stats.MemoryTotal() != 268435456 {
+		t.Errorf("MemoryTotal = %d, want 268435456", stats.MemoryTotal())
+	}
+	if stats.MaxAcceleratedFiles != 16229 {
+		t.Errorf("MaxAcceleratedFiles = %d, want the real key count 16229", stats.MaxAcceleratedFiles)
+	}
+	if stats.RealpathLimit != 4096*1024 {
+		t.Errorf("RealpathLimit = %d, want %d", stats.RealpathLimit, 4096*1024)
+	}
+
+	warnings := stats.Warnings()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings (memory, realpath), got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "opcache.memory_consumption") {
+		t.Errorf("first warning should flag opcache.memory_consumption, got %q", warnings[0])
+	}
+	if !strings.Contains(warnings[1], "realpath_cache_size") {
+		t.Errorf("second warning should flag realpath_cache_size, got %q", warnings[1])
+	}
+}
+
+func TestParseCacheStats_OPcacheDisabled(t *testing.T) {
+	stats, err := parseCacheStats([]byte(`{"opcache": null, "directives": null, "realpath_cache_used": 100, "realpath_cache_limit": "4M", "realpath_cache_entries": 1}`))
+	if err != nil {
+		t.Fatalf("parseCacheStats failed: %v", err)
+	}
+	if stats.OPcacheEnabled {
+		t.Error("OPcacheEnabled should be false")
+	}
+	if len(stats.Warnings()) != 0 {
+		t.Errorf("unexpected warnings: %v", stats.Warnings())
+	}
+}
+
+func TestParseINISize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"4096K", 4096 << 10},
+		{"128M", 128 << 20},
+		{"1g", 1 << 30},
+		{"1024", 1024},
+		{"", 0},
+		{"lots", 0},
+	}
+	for _, tt := range tests {
+		if got := parseINISize(tt.in); got != tt.want {
+			t.Errorf("parseINISize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQueryCacheStats_FastCGI(t *testing.T) {
+	projectPath := t.TempDir()
+	socketPath := filepath.Join(t.TempDir(), "fpm.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	scriptSeen := make(chan bool, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scriptSeen <- serveFakeFPM(conn, "Content-Type: application/json\r\n\r\n"+sampleCacheStats)
+	}()
+
+	stats, err := QueryCacheStats(socketPath, projectPath)
+	if err != nil {
+		t.Fatalf("QueryCacheStats failed: %v", err)
+	}
+	if !<-scriptSeen {
+		t.Error("SCRIPT_FILENAME did not point at an existing stats script")
+	}
+	if stats.CachedScripts != 9500 {
+		t.Errorf("CachedScripts = %d, want 9500", stats.CachedScripts)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(projectPath, "var", ".magebox-cache-stats-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("stats script was not removed: %v", leftovers)
+	}
+}
+
+// serveFakeFPM reads one FastCGI request, reports whether its script exists
+// and answers with the given CGI output
+func serveFakeFPM(conn net.Conn, output string) bool {
+	reader := bufio.NewReader(conn)
+	scriptExists := false
+	for {
+		var h fcgiHeader
+		if err := binary.Read(reader, binary.BigEndian, &h); err != nil {
+			return false
+		}
+		content := make([]byte, int(h.ContentLength)+int(h.PaddingLength))
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return false
+		}
+		content = content[:h.ContentLength]
+		if h.Type == fcgiParams {
+			if idx := strings.Index(string(content), "SCRIPT_FILENAME"); idx >= 0 {
+				rest := string(content[idx+len("SCRIPT_FILENAME"):])
+				if end := strings.Index(rest, ".php"); end >= 0 {
+					_, err := os.Stat(rest[:end+4])
+					scriptExists = err == nil
+				}
+			}
+		}
+		if h.Type == fcgiStdin && h.ContentLength == 0 {
+			break
+		}
+	}
+	_ = writeFCGIRecord(conn, fcgiStdout, []byte(output))
+	_ = writeFCGIRecord(conn, fcgiEndRequest, make([]byte, 8))
+	return scriptExists
+}
@@ -556,3 +556,61 @@ func (m *ExtensionManager) peclBinary(phpVersion string) string {
 	// Linux: pecl is typically in PATH
 	return "pecl"
 }
+
+// zendExtensions are loaded with zend_extension, which a PHP-FPM pool can't do
+var zendExtensions = map[string]bool{"xdebug": true, "opcache": true}
+
+// ExtensionCheck sorts the PHP extensions a project needs by how they load
+type ExtensionCheck struct {
+	Loaded  []string // enabled for the PHP version
+	Pool    []string // installed but not enabled, loaded by the project's pool
+	Missing []string // not installed, or a zend extension that isn't enabled
+}
+
+// CheckExtensions checks the extensions a project needs against the ones the
+// PHP version loads and the ones in its extension directory
+func (m *ExtensionManager) CheckExtensions(extensions []string, phpVersion string) (*ExtensionCheck, error) {
+	if len(extensions) == 0 {
+		return &ExtensionCheck{}, nil
+	}
+
+	loaded, err := m.List(phpVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command(m.platform.PHPBinary(phpVersion), "-r", `echo ini_get("extension_dir");`).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the extension directory: %w", err)
+	}
+
+	return checkExtensions(extensions, loaded, strings.TrimSpace(string(out))), nil
+}
+
+// checkExtensions sorts extensions by whether they are loaded (as listed by
+// php -m), installed in extDir or missing
+func checkExtensions(extensions, loaded []string, extDir string) *ExtensionCheck {
+	loadedSet := make(map[string]bool, len(loaded))
+	for _, ext := range loaded {
+		// php -m lists OPcache as "Zend OPcache"
+		loadedSet[strings.TrimPrefix(strings.ToLower(ext), "zend ")] = true
+	}
+
+	check := &ExtensionCheck{}
+	for _, ext := range extensions {
+		name := strings.ToLower(ext)
+		switch {
+		case loadedSet[name]:
+			check.Loaded = append(check.Loaded, name)
+		case zendExtensions[name] || extDir == "":
+			check.Missing = append(check.Missing, name)
+		default:
+			if _, err := os.Stat(filepath.Join(extDir, name+".so")); err == nil {
+				check.Pool = append(check.Pool, name)
+			} else {
+				check.Missing = append(check.Missing, name)
+			}
+		}
+	}
+	return check
+}
@@ -1,6 +1,9 @@
 package php
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"qoliber/magebox/internal/platform"
@@ -274,3 +277,26 @@ func TestPieExtensionName(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckExtensions(t *testing.T) {
+	extDir := t.TempDir()
+	for _, so := range []string{"imagick.so", "xdebug.so"} {
+		if err := os.WriteFile(filepath.Join(extDir, so), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded := []string{"Core", "gd", "intl", "Zend OPcache"}
+	check := checkExtensions([]string{"gd", "Intl", "opcache", "imagick", "xdebug", "soap"}, loaded, extDir)
+
+	if strings.Join(check.Loaded, ",") != "gd,intl,opcache" {
+		t.Errorf("Loaded = %v, want gd, intl, opcache", check.Loaded)
+	}
+	if strings.Join(check.Pool, ",") != "imagick" {
+		t.Errorf("Pool = %v, want imagick", check.Pool)
+	}
+	// xdebug is a zend extension, which the pool can't load
+	if strings.Join(check.Missing, ",") != "xdebug,soap" {
+		t.Errorf("Missing = %v, want xdebug, soap", check.Missing)
+	}
+}
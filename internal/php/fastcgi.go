@@ -0,0 +1,143 @@
+package php
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// FastCGI record types (see the FastCGI 1.0 specification)
+const (
+	fcgiBeginRequest uint8 = 1
+	fcgiEndRequest   uint8 = 3
+	fcgiParams       uint8 = 4
+	fcgiStdin        uint8 = 5
+	fcgiStdout       uint8 = 6
+	fcgiStderr       uint8 = 7
+
+	fcgiVersion   uint8  = 1
+	fcgiResponder uint16 = 1
+	fcgiRequestID uint16 = 1
+)
+
+// fcgiHeader is the fixed 8-byte header of a FastCGI record
+type fcgiHeader struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+// fastCGIRequest runs a single request against a PHP-FPM socket and returns the
+// response body (without CGI headers). It lets MageBox execute a script inside
+// a pool, where shared state like OPcache lives, without going through Nginx.
+func fastCGIRequest(socketPath string, params map[string]string, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", socketPath, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", socketPath, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	// BEGIN_REQUEST: role responder, no keep-alive
+	begin := make([]byte, 8)
+	binary.BigEndian.PutUint16(begin, fcgiResponder)
+	if err := writeFCGIRecord(conn, fcgiBeginRequest, begin); err != nil {
+		return nil, err
+	}
+
+	var encoded bytes.Buffer
+	for name, value := range params {
+		writeFCGILength(&encoded, len(name))
+		writeFCGILength(&encoded, len(value))
+		encoded.WriteString(name)
+		encoded.WriteString(value)
+	}
+	if err := writeFCGIRecord(conn, fcgiParams, encoded.Bytes()); err != nil {
+		return nil, err
+	}
+	// Empty PARAMS and STDIN records terminate the streams
+	if err := writeFCGIRecord(conn, fcgiParams, nil); err != nil {
+		return nil, err
+	}
+	if err := writeFCGIRecord(conn, fcgiStdin, nil); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	reader := bufio.NewReader(conn)
+	for {
+		var h fcgiHeader
+		if err := binary.Read(reader, binary.BigEndian, &h); err != nil {
+			return nil, fmt.Errorf("failed to read FastCGI response: %w", err)
+		}
+		content := make([]byte, int(h.ContentLength)+int(h.PaddingLength))
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return nil, fmt.Errorf("failed to read FastCGI response: %w", err)
+		}
+		content = content[:h.ContentLength]
+
+		switch h.Type {
+		case fcgiStdout:
+			stdout.Write(content)
+		case fcgiStderr:
+			stderr.Write(content)
+		case fcgiEndRequest:
+			return splitCGIBody(stdout.Bytes(), stderr.Bytes())
+		}
+	}
+}
+
+// splitCGIBody strips the CGI response headers PHP-FPM writes before the body
+func splitCGIBody(stdout, stderr []byte) ([]byte, error) {
+	sep := []byte("\r\n\r\n")
+	idx := bytes.Index(stdout, sep)
+	if idx < 0 {
+		if len(stderr) > 0 {
+			return nil, fmt.Errorf("PHP-FPM error: %s", bytes.TrimSpace(stderr))
+		}
+		return nil, fmt.Errorf("malformed PHP-FPM response")
+	}
+	headers := stdout[:idx]
+	if bytes.Contains(headers, []byte("Status: 404")) {
+		return nil, fmt.Errorf("PHP-FPM could not find the script: %s", bytes.TrimSpace(stderr))
+	}
+	return stdout[idx+len(sep):], nil
+}
+
+// writeFCGIRecord writes one FastCGI record, padded to 8 bytes
+func writeFCGIRecord(w io.Writer, recType uint8, content []byte) error {
+	padding := uint8((8 - len(content)%8) % 8)
+	h := fcgiHeader{
+		Version:       fcgiVersion,
+		Type:          recType,
+		RequestID:     fcgiRequestID,
+		ContentLength: uint16(len(content)),
+		PaddingLength: padding,
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, h); err != nil {
+		return err
+	}
+	buf.Write(content)
+	buf.Write(make([]byte, padding))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeFCGILength encodes a name/value length (1 byte, or 4 bytes with the high bit set)
+func writeFCGILength(buf *bytes.Buffer, n int) {
+	if n < 128 {
+		buf.WriteByte(byte(n))
+		return
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n)|1<<31)
+	buf.Write(b[:])
+}
@@ -0,0 +1,61 @@
+package php
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PoolStatus is the state of a PHP-FPM pool, as reported by its status page
+// (pm.status_path in the pool config)
+type PoolStatus struct {
+	Pool               string `json:"pool"`
+	ProcessManager     string `json:"process manager"`
+	StartSince         int64  `json:"start since"`
+	AcceptedConn       int64  `json:"accepted conn"`
+	ListenQueue        int64  `json:"listen queue"`
+	MaxListenQueue     int64  `json:"max listen queue"`
+	IdleProcesses      int64  `json:"idle processes"`
+	ActiveProcesses    int64  `json:"active processes"`
+	TotalProcesses     int64  `json:"total processes"`
+	MaxActiveProcesses int64  `json:"max active processes"`
+	MaxChildrenReached int64  `json:"max children reached"`
+	SlowRequests       int64  `json:"slow requests"`
+}
+
+// poolStatusPath is the pm.status_path of the generated pools
+const poolStatusPath = "/status"
+
+// QueryPoolStatus reads the status page of the pool listening on socketPath.
+// FPM answers the status path itself, so no script is involved.
+func QueryPoolStatus(socketPath string) (*PoolStatus, error) {
+	body, err := fastCGIRequest(socketPath, map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"SERVER_SOFTWARE":   "magebox",
+		"SERVER_PROTOCOL":   "HTTP/1.1",
+		"REQUEST_METHOD":    "GET",
+		"REMOTE_ADDR":       "127.0.0.1",
+		"SCRIPT_FILENAME":   poolStatusPath,
+		"SCRIPT_NAME":       poolStatusPath,
+		"REQUEST_URI":       poolStatusPath + "?json",
+		"QUERY_STRING":      "json",
+	}, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolStatus(body)
+}
+
+// parsePoolStatus decodes the JSON status page
+func parsePoolStatus(body []byte) (*PoolStatus, error) {
+	var status PoolStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("unexpected status page: %w", err)
+	}
+	return &status, nil
+}
+
+// Utilization returns the share of the pool's processes busy with a request
+func (s *PoolStatus) Utilization() float64 {
+	return ratio(s.ActiveProcesses, s.TotalProcesses)
+}
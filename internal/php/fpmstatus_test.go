@@ -0,0 +1,52 @@
+package php
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+const samplePoolStatus = `{"pool":"mystore","process manager":"dynamic","start time":1760000000,"start since":3600,"accepted conn":1200,"listen queue":0,"max listen queue":3,"listen queue len":511,"idle processes":2,"active processes":3,"total processes":5,"max active processes":5,"max children reached":1,"slow requests":0}`
+
+func TestParsePoolStatus(t *testing.T) {
+	status, err := parsePoolStatus([]byte(samplePoolStatus))
+	if err != nil {
+		t.Fatalf("parsePoolStatus failed: %v", err)
+	}
+	if status.Pool != "mystore" || status.ActiveProcesses != 3 || status.MaxChildrenReached != 1 || status.MaxListenQueue != 3 {
+		t.Errorf("status = %+v", status)
+	}
+	if got := status.Utilization(); got != 0.6 {
+		t.Errorf("Utilization() = %v, want 0.6", got)
+	}
+
+	if _, err := parsePoolStatus([]byte("pool: mystore")); err == nil {
+		t.Error("expected an error for the plain text status page")
+	}
+}
+
+func TestQueryPoolStatus_FastCGI(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "fpm.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveFakeFPM(conn, "Content-Type: application/json\r\n\r\n"+samplePoolStatus)
+	}()
+
+	status, err := QueryPoolStatus(socketPath)
+	if err != nil {
+		t.Fatalf("QueryPoolStatus failed: %v", err)
+	}
+	if status.TotalProcesses != 5 {
+		t.Errorf("TotalProcesses = %d, want 5", status.TotalProcesses)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"text/template"
 	"time"
 
+	"qoliber/magebox/internal/fileutil"
 	"qoliber/magebox/internal/lib"
 	"qoliber/magebox/internal/platform"
 )
@@ -318,7 +319,7 @@ func (c *IsolatedFPMController) generateConfig(project *IsolatedProject) error {
 		return err
 	}
 
-	return os.WriteFile(project.ConfigPath, buf.Bytes(), 0644)
+	return fileutil.WriteFileAtomic(project.ConfigPath, buf.Bytes(), 0644)
 }
 
 // start starts an isolated PHP-FPM master
@@ -0,0 +1,103 @@
+package php
+
+import (
+	"bufio"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CapturedMail is an email written to var/mail by the file sendmail wrapper
+type CapturedMail struct {
+	Path    string    `json:"path"`
+	Date    time.Time `json:"date"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+}
+
+// MailDir returns the directory captured emails of a project are written to
+func MailDir(projectPath string) string {
+	return filepath.Join(projectPath, "var", "mail")
+}
+
+// ListCapturedMail returns the captured emails of a project, newest first.
+// A missing mail directory yields an empty list.
+func ListCapturedMail(projectPath string) ([]CapturedMail, error) {
+	matches, err := filepath.Glob(filepath.Join(MailDir(projectPath), "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]CapturedMail, 0, len(matches))
+	for _, path := range matches {
+		msg, err := readCapturedMail(path)
+		if err != nil {
+			// Skip unreadable files rather than hiding every other message
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Date.After(messages[j].Date)
+	})
+	return messages, nil
+}
+
+// readCapturedMail parses the headers of a captured email
+func readCapturedMail(path string) (CapturedMail, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return CapturedMail{}, err
+	}
+	defer f.Close()
+
+	msg := CapturedMail{Path: path}
+	if info, err := f.Stat(); err == nil {
+		msg.Date = info.ModTime()
+	}
+
+	parsed, err := mail.ReadMessage(bufio.NewReader(f))
+	if err != nil {
+		// Not a valid RFC 822 message, still list it by file time
+		return msg, nil
+	}
+
+	decoder := new(mime.WordDecoder)
+	header := func(key string) string {
+		value := parsed.Header.Get(key)
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+		return strings.TrimSpace(value)
+	}
+
+	msg.From = header("From")
+	msg.To = header("To")
+	msg.Subject = header("Subject")
+	if date, err := parsed.Header.Date(); err == nil {
+		msg.Date = date
+	}
+	return msg, nil
+}
+
+// ClearCapturedMail removes the captured emails of a project and returns
+// how many were removed
+func ClearCapturedMail(projectPath string) (int, error) {
+	matches, err := filepath.Glob(filepath.Join(MailDir(projectPath), "*.eml"))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, path := range matches {
+		if err := os.Remove(path); err != nil {
+			return i, err
+		}
+	}
+	return len(matches), nil
+}
@@ -0,0 +1,93 @@
+package php
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListCapturedMail(t *testing.T) {
+	projectPath := t.TempDir()
+
+	messages, err := ListCapturedMail(projectPath)
+	if err != nil {
+		t.Fatalf("ListCapturedMail failed on missing dir: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages, got %d", len(messages))
+	}
+
+	mailDir := MailDir(projectPath)
+	if err := os.MkdirAll(mailDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"older.eml": "From: Store <store@example.com>\r\nTo: customer@example.com\r\nSubject: Your order #100\r\nDate: Mon, 02 Jan 2006 15:04:05 +0000\r\n\r\nThanks\r\n",
+		"newer.eml": "From: store@example.com\r\nTo: other@example.com\r\nSubject: =?UTF-8?B?V2VsY29tZSDwn5GL?=\r\nDate: Tue, 03 Jan 2006 15:04:05 +0000\r\n\r\nHi\r\n",
+		"notes.txt": "not an email",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(mailDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err = ListCapturedMail(projectPath)
+	if err != nil {
+		t.Fatalf("ListCapturedMail failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+
+	tests := []struct {
+		file    string
+		to      string
+		subject string
+	}{
+		{"newer.eml", "other@example.com", "Welcome 👋"},
+		{"older.eml", "customer@example.com", "Your order #100"},
+	}
+	for i, tt := range tests {
+		msg := messages[i]
+		if filepath.Base(msg.Path) != tt.file {
+			t.Errorf("messages[%d].Path = %s, want %s", i, msg.Path, tt.file)
+		}
+		if msg.To != tt.to {
+			t.Errorf("messages[%d].To = %q, want %q", i, msg.To, tt.to)
+		}
+		if msg.Subject != tt.subject {
+			t.Errorf("messages[%d].Subject = %q, want %q", i, msg.Subject, tt.subject)
+		}
+	}
+}
+
+func TestClearCapturedMail(t *testing.T) {
+	projectPath := t.TempDir()
+
+	if n, err := ClearCapturedMail(projectPath); err != nil || n != 0 {
+		t.Fatalf("ClearCapturedMail() on missing dir = %d, %v", n, err)
+	}
+
+	mailDir := MailDir(projectPath)
+	if err := os.MkdirAll(mailDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.eml", "b.eml", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(mailDir, name), []byte("Subject: test\r\n\r\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := ClearCapturedMail(projectPath)
+	if err != nil {
+		t.Fatalf("ClearCapturedMail failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("ClearCapturedMail() = %d, want 2", n)
+	}
+	if _, err := os.Stat(filepath.Join(mailDir, "notes.txt")); err != nil {
+		t.Error("ClearCapturedMail should only remove .eml files")
+	}
+}
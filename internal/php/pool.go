@@ -9,9 +9,11 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"text/template"
 
+	"qoliber/magebox/internal/fileutil"
 	"qoliber/magebox/internal/lib"
 	"qoliber/magebox/internal/platform"
 )
@@ -25,11 +27,15 @@ var fpmConfigTemplateEmbed string
 //go:embed templates/mailpit-sendmail.sh
 var mailpitSendmailScriptEmbed string
 
+//go:embed templates/file-sendmail.sh
+var fileSendmailScriptEmbed string
+
 func init() {
 	// Register embedded templates as fallbacks
 	lib.RegisterFallbackTemplate(lib.TemplatePHP, "pool.conf.tmpl", poolTemplateEmbed)
 	lib.RegisterFallbackTemplate(lib.TemplatePHP, "php-fpm.conf.tmpl", fpmConfigTemplateEmbed)
 	lib.RegisterFallbackTemplate(lib.TemplatePHP, "mailpit-sendmail.sh", mailpitSendmailScriptEmbed)
+	lib.RegisterFallbackTemplate(lib.TemplatePHP, "file-sendmail.sh", fileSendmailScriptEmbed)
 }
 
 // Mailpit SMTP configuration constants
@@ -54,6 +60,9 @@ const (
 // - MaxRequests: Number of requests each child process should execute before respawning
 // - Env: Map of environment variables to set (e.g., {"MAGE_MODE": "developer"})
 // - PHPINI: Map of PHP INI overrides (e.g., {"opcache.enable": "0"})
+// - HasMailpit: Whether mail() is routed to Mailpit
+// - SendmailPath: Path to the sendmail wrapper (Mailpit or file capture)
+// - MailDir: Directory captured emails are written to when Mailpit is disabled
 
 // PoolGenerator generates PHP-FPM pool configurations
 type PoolGenerator struct {
@@ -61,6 +70,10 @@ type PoolGenerator struct {
 	basePoolsDir string // Base pools directory (version subdirs will be created)
 	runDir       string
 	systemINIMgr *SystemINIManager
+	lowMemory    bool
+	extensions   []string
+	versions     []string
+	mailpitPort  int // Reserved Mailpit SMTP port, 0 for MailpitSMTPPort
 }
 
 // GenerateResult contains the result of pool generation
@@ -89,8 +102,10 @@ type PoolConfig struct {
 	MaxRequests     int
 	Env             map[string]string
 	PHPINI          map[string]string
+	Extensions      []string
 	HasMailpit      bool
 	SendmailPath    string
+	MailDir         string
 }
 
 // defaultPHPINI returns the default PHP INI settings for Magento
@@ -138,6 +153,39 @@ func NewPoolGenerator(p *platform.Platform) *PoolGenerator {
 	}
 }
 
+// SetLowMemory makes generated pools start a single worker and cap the pool
+// at a handful of children instead of the default dynamic sizing
+func (g *PoolGenerator) SetLowMemory(enabled bool) {
+	g.lowMemory = enabled
+}
+
+// SetExtensions makes the pools generated next load the extensions, for a
+// project that needs extensions its PHP version doesn't enable
+func (g *PoolGenerator) SetExtensions(extensions []string) {
+	g.extensions = extensions
+}
+
+// SetVersions sets the PHP versions of the project the pools generated next
+// belong to, for a project whose domains run PHP versions of their own. Its
+// pools of other versions are removed.
+func (g *PoolGenerator) SetVersions(versions []string) {
+	g.versions = versions
+}
+
+// SetMailpitPort sets the host port the pools generated next send mail to
+// Mailpit on, when the port registry moved it off MailpitSMTPPort
+func (g *PoolGenerator) SetMailpitPort(port int) {
+	g.mailpitPort = port
+}
+
+// smtpPort returns the Mailpit SMTP port of the pools
+func (g *PoolGenerator) smtpPort() int {
+	if g.mailpitPort == 0 {
+		return MailpitSMTPPort
+	}
+	return g.mailpitPort
+}
+
 // GetSystemINIManager returns the system INI manager
 func (g *PoolGenerator) GetSystemINIManager() *SystemINIManager {
 	return g.systemINIMgr
@@ -175,7 +223,11 @@ func (g *PoolGenerator) GenerateWithResult(projectName, projectPath, phpVersion
 		return nil, fmt.Errorf("failed to create logs directory: %w", err)
 	}
 
-	// Setup Mailpit sendmail wrapper if Mailpit is enabled
+	// Setup the sendmail wrapper: Mailpit when enabled, otherwise capture to
+	// var/mail so no email can escape to a real address
+	if env == nil {
+		env = make(map[string]string)
+	}
 	var sendmailPath string
 	if hasMailpit {
 		var err error
@@ -185,11 +237,15 @@ func (g *PoolGenerator) GenerateWithResult(projectName, projectPath, phpVersion
 		}
 
 		// Add Mailpit environment variables
-		if env == nil {
-			env = make(map[string]string)
-		}
 		env["MAILPIT_HOST"] = MailpitSMTPHost
-		env["MAILPIT_PORT"] = fmt.Sprintf("%d", MailpitSMTPPort)
+		env["MAILPIT_PORT"] = fmt.Sprintf("%d", g.smtpPort())
+	} else {
+		var err error
+		sendmailPath, err = g.setupFileSendmail()
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup file sendmail: %w", err)
+		}
+		env["MAGEBOX_MAIL_DIR"] = MailDir(projectPath)
 	}
 
 	// Merge with defaults and separate system vs pool settings
@@ -209,6 +265,67 @@ func (g *PoolGenerator) GenerateWithResult(projectName, projectPath, phpVersion
 		result.SystemINIChanged = previousOwner != nil
 	}
 
+	// Only pool-level settings go in pool config
+	cfg := g.newPoolConfig(projectName, projectPath, phpVersion, env, poolSettings, hasMailpit, sendmailPath)
+
+	content, err := g.renderPool(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render pool config: %w", err)
+	}
+
+	poolFile := filepath.Join(versionPoolsDir, fmt.Sprintf("%s.conf", projectName))
+	if err := fileutil.WriteGenerated(poolFile, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write pool file: %w", err)
+	}
+	result.PoolPath = poolFile
+
+	// Clean up old pool configs from PHP versions the project no longer runs
+	keep := []string{phpVersion}
+	if slices.Contains(g.versions, phpVersion) {
+		keep = g.versions
+	}
+	if err := g.removeOldVersionPools(projectName, keep); err != nil {
+		// Log but don't fail - old pools won't cause issues, just clutter
+		fmt.Printf("[WARN] Failed to remove old version pool configs: %v\n", err)
+	}
+
+	return result, nil
+}
+
+// Preview renders the pool configuration for a project without writing the pool,
+// the system INI or the sendmail wrapper. It returns the path the pool would be
+// written to together with its content.
+func (g *PoolGenerator) Preview(projectName, projectPath, phpVersion string, env map[string]string, phpIni map[string]string, hasMailpit bool) (string, string, error) {
+	poolEnv := make(map[string]string, len(env)+2)
+	for k, v := range env {
+		poolEnv[k] = v
+	}
+
+	var sendmailPath string
+	if hasMailpit {
+		sendmailPath = g.mailpitSendmailPath()
+		poolEnv["MAILPIT_HOST"] = MailpitSMTPHost
+		poolEnv["MAILPIT_PORT"] = fmt.Sprintf("%d", g.smtpPort())
+	} else {
+		sendmailPath = g.fileSendmailPath()
+		poolEnv["MAGEBOX_MAIL_DIR"] = MailDir(projectPath)
+	}
+
+	_, poolSettings := SeparateSettings(mergePHPINI(phpIni))
+	cfg := g.newPoolConfig(projectName, projectPath, phpVersion, poolEnv, poolSettings, hasMailpit, sendmailPath)
+
+	content, err := g.renderPool(cfg)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render pool config: %w", err)
+	}
+
+	poolFile := filepath.Join(g.getVersionPoolsDir(phpVersion), fmt.Sprintf("%s.conf", projectName))
+	return poolFile, content, nil
+}
+
+// newPoolConfig builds the template data for a project pool
+func (g *PoolGenerator) newPoolConfig(projectName, projectPath, phpVersion string, env, poolSettings map[string]string, hasMailpit bool, sendmailPath string) PoolConfig {
+	logsDir := filepath.Join(g.platform.MageBoxDir(), "logs", "php-fpm")
 	cfg := PoolConfig{
 		ProjectName:     projectName,
 		ProjectPath:     projectPath,
@@ -223,33 +340,25 @@ func (g *PoolGenerator) GenerateWithResult(projectName, projectPath, phpVersion
 		MaxSpareServers: 12,
 		MaxRequests:     1000,
 		Env:             env,
-		PHPINI:          poolSettings, // Only pool-level settings go in pool config
+		PHPINI:          poolSettings,
+		Extensions:      g.extensions,
 		HasMailpit:      hasMailpit,
 		SendmailPath:    sendmailPath,
+		MailDir:         MailDir(projectPath),
 	}
-
-	content, err := g.renderPool(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to render pool config: %w", err)
-	}
-
-	poolFile := filepath.Join(versionPoolsDir, fmt.Sprintf("%s.conf", projectName))
-	if err := os.WriteFile(poolFile, []byte(content), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write pool file: %w", err)
-	}
-	result.PoolPath = poolFile
-
-	// Clean up old pool configs from other PHP versions (project can only use one version)
-	if err := g.removeOldVersionPools(projectName, phpVersion); err != nil {
-		// Log but don't fail - old pools won't cause issues, just clutter
-		fmt.Printf("[WARN] Failed to remove old version pool configs: %v\n", err)
+	if g.lowMemory {
+		// A single idle worker, growing only as far as a few parallel requests
+		cfg.MaxChildren = 6
+		cfg.StartServers = 1
+		cfg.MinSpareServers = 1
+		cfg.MaxSpareServers = 2
 	}
-
-	return result, nil
+	return cfg
 }
 
-// removeOldVersionPools removes pool configs for a project from other PHP version directories
-func (g *PoolGenerator) removeOldVersionPools(projectName, currentVersion string) error {
+// removeOldVersionPools removes pool configs for a project from the PHP version
+// directories of versions other than keep
+func (g *PoolGenerator) removeOldVersionPools(projectName string, keep []string) error {
 	entries, err := os.ReadDir(g.basePoolsDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -260,7 +369,7 @@ func (g *PoolGenerator) removeOldVersionPools(projectName, currentVersion string
 
 	poolFileName := fmt.Sprintf("%s.conf", projectName)
 	for _, entry := range entries {
-		if !entry.IsDir() || entry.Name() == currentVersion {
+		if !entry.IsDir() || slices.Contains(keep, entry.Name()) {
 			continue
 		}
 		oldPoolFile := filepath.Join(g.basePoolsDir, entry.Name(), poolFileName)
@@ -280,7 +389,7 @@ func (g *PoolGenerator) setupMailpitSendmail() (string, error) {
 		return "", fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
-	sendmailPath := filepath.Join(binDir, "mailpit-sendmail")
+	sendmailPath := g.mailpitSendmailPath()
 
 	// Load sendmail script from lib (with embedded fallback)
 	script, err := lib.GetTemplate(lib.TemplatePHP, "mailpit-sendmail.sh")
@@ -289,13 +398,44 @@ func (g *PoolGenerator) setupMailpitSendmail() (string, error) {
 	}
 
 	// Write the sendmail wrapper script
-	if err := os.WriteFile(sendmailPath, []byte(script), 0755); err != nil {
+	if err := fileutil.WriteFileAtomic(sendmailPath, []byte(script), 0755); err != nil {
 		return "", fmt.Errorf("failed to write mailpit-sendmail script: %w", err)
 	}
 
 	return sendmailPath, nil
 }
 
+// mailpitSendmailPath returns the path of the Mailpit sendmail wrapper script
+func (g *PoolGenerator) mailpitSendmailPath() string {
+	return filepath.Join(g.platform.MageBoxDir(), "bin", "mailpit-sendmail")
+}
+
+// setupFileSendmail creates the file-capture sendmail wrapper script used when Mailpit is disabled
+func (g *PoolGenerator) setupFileSendmail() (string, error) {
+	binDir := filepath.Join(g.platform.MageBoxDir(), "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	sendmailPath := g.fileSendmailPath()
+
+	script, err := lib.GetTemplate(lib.TemplatePHP, "file-sendmail.sh")
+	if err != nil {
+		return "", fmt.Errorf("failed to load file-sendmail script: %w", err)
+	}
+
+	if err := fileutil.WriteFileAtomic(sendmailPath, []byte(script), 0755); err != nil {
+		return "", fmt.Errorf("failed to write file-sendmail script: %w", err)
+	}
+
+	return sendmailPath, nil
+}
+
+// fileSendmailPath returns the path of the file-capture sendmail wrapper script
+func (g *PoolGenerator) fileSendmailPath() string {
+	return filepath.Join(g.platform.MageBoxDir(), "bin", "file-sendmail")
+}
+
 // Remove removes the pool configuration for a project from all version directories
 func (g *PoolGenerator) Remove(projectName string) error {
 	entries, err := os.ReadDir(g.basePoolsDir)
@@ -584,7 +724,7 @@ func (c *FPMController) GenerateConfig() error {
 		return fmt.Errorf("failed to execute fpm config template: %w", err)
 	}
 
-	return os.WriteFile(c.getConfigPath(), buf.Bytes(), 0644)
+	return fileutil.WriteFileAtomic(c.getConfigPath(), buf.Bytes(), 0644)
 }
 
 // Reload reloads PHP-FPM configuration
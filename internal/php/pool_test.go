@@ -106,6 +106,60 @@ func TestPoolGenerator_Generate(t *testing.T) {
 	}
 }
 
+func TestPoolGenerator_GenerateExtensions(t *testing.T) {
+	g, _ := setupTestPoolGenerator(t)
+
+	g.SetExtensions([]string{"imagick", "mailparse"})
+	if err := g.Generate("mystore", "/tmp/mystore", "8.3", nil, nil, false); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(g.PoolsDirForVersion("8.3"), "mystore.conf"))
+	if err != nil {
+		t.Fatalf("Failed to read pool file: %v", err)
+	}
+	for _, want := range []string{"php_admin_value[extension] = imagick.so", "php_admin_value[extension] = mailparse.so"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Pool content should contain %q", want)
+		}
+	}
+
+	g.SetExtensions(nil)
+	if err := g.Generate("mystore", "/tmp/mystore", "8.3", nil, nil, false); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	content, _ = os.ReadFile(filepath.Join(g.PoolsDirForVersion("8.3"), "mystore.conf"))
+	if strings.Contains(string(content), "php_admin_value[extension]") {
+		t.Error("Pool content should not load extensions after SetExtensions(nil)")
+	}
+}
+
+func TestPoolGenerator_GenerateVersions(t *testing.T) {
+	g, _ := setupTestPoolGenerator(t)
+
+	// A domain on 8.3 next to the project's 8.2 keeps both pools
+	g.SetVersions([]string{"8.2", "8.3"})
+	for _, version := range []string{"8.2", "8.3"} {
+		if err := g.Generate("mystore", "/tmp/mystore", version, nil, nil, false); err != nil {
+			t.Fatalf("Generate(%s) failed: %v", version, err)
+		}
+	}
+	for _, version := range []string{"8.2", "8.3"} {
+		if _, err := os.Stat(filepath.Join(g.PoolsDirForVersion(version), "mystore.conf")); err != nil {
+			t.Errorf("pool of PHP %s missing: %v", version, err)
+		}
+	}
+
+	// Without the domain, generating the project's pool removes the other
+	g.SetVersions([]string{"8.2"})
+	if err := g.Generate("mystore", "/tmp/mystore", "8.2", nil, nil, false); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(g.PoolsDirForVersion("8.3"), "mystore.conf")); !os.IsNotExist(err) {
+		t.Error("pool of PHP 8.3 should have been removed")
+	}
+}
+
 func TestPoolGenerator_GenerateWithoutEnv(t *testing.T) {
 	g, _ := setupTestPoolGenerator(t)
 
@@ -375,6 +429,25 @@ func TestGenerate_WithPHPINI(t *testing.T) {
 	}
 }
 
+func TestGenerate_LowMemory(t *testing.T) {
+	g, _ := setupTestPoolGenerator(t)
+	g.SetLowMemory(true)
+
+	if err := g.Generate("testproject", "/tmp/testproject", "8.2", nil, nil, false); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(g.PoolsDirForVersion("8.2"), "testproject.conf"))
+	if err != nil {
+		t.Fatalf("Failed to read pool file: %v", err)
+	}
+	for _, want := range []string{"pm.max_children = 6", "pm.start_servers = 1", "pm.min_spare_servers = 1", "pm.max_spare_servers = 2"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Pool should contain %q in low-memory mode", want)
+		}
+	}
+}
+
 func TestGenerate_WithMailpit(t *testing.T) {
 	g, tmpDir := setupTestPoolGenerator(t)
 
@@ -413,6 +486,35 @@ func TestGenerate_WithMailpit(t *testing.T) {
 	}
 }
 
+func TestGenerate_WithoutMailpitCapturesMail(t *testing.T) {
+	g, tmpDir := setupTestPoolGenerator(t)
+
+	err := g.Generate("testproject", "/tmp/testproject", "8.2", nil, nil, false)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(g.PoolsDirForVersion("8.2"), "testproject.conf"))
+	if err != nil {
+		t.Fatalf("Failed to read pool file: %v", err)
+	}
+	contentStr := string(content)
+
+	sendmailPath := filepath.Join(tmpDir, ".magebox", "bin", "file-sendmail")
+	if !strings.Contains(contentStr, "php_admin_value[sendmail_path] = "+sendmailPath+" -t") {
+		t.Error("Pool should route sendmail_path to the file-sendmail wrapper when Mailpit is disabled")
+	}
+	if strings.Contains(contentStr, "smtp_port") {
+		t.Error("Pool should not configure Mailpit SMTP when Mailpit is disabled")
+	}
+	if !strings.Contains(contentStr, "env[MAGEBOX_MAIL_DIR] = /tmp/testproject/var/mail") {
+		t.Error("Pool should export MAGEBOX_MAIL_DIR pointing at var/mail")
+	}
+	if _, err := os.Stat(sendmailPath); os.IsNotExist(err) {
+		t.Error("file-sendmail script should have been created")
+	}
+}
+
 func TestMailpitConstants(t *testing.T) {
 	if MailpitSMTPHost != "127.0.0.1" {
 		t.Errorf("MailpitSMTPHost = %v, want 127.0.0.1", MailpitSMTPHost)
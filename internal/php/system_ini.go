@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"qoliber/magebox/internal/fileutil"
 	"qoliber/magebox/internal/platform"
 )
 
@@ -173,7 +174,7 @@ func (m *SystemINIManager) WriteSystemINI(phpVersion, projectName, projectPath s
 
 	// Write INI file
 	iniPath := m.GetSystemINIPath(phpVersion)
-	if err := os.WriteFile(iniPath, []byte(content.String()), 0644); err != nil {
+	if err := fileutil.WriteFileAtomic(iniPath, []byte(content.String()), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write system INI: %w", err)
 	}
 
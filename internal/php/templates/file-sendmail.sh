@@ -0,0 +1,19 @@
+#!/bin/bash
+# MageBox file sendmail wrapper
+# Captures PHP mail() calls as .eml files instead of delivering them
+#
+# Usage: This script is set as PHP's sendmail_path when Mailpit is disabled
+# It reads the email from stdin and writes it to $MAGEBOX_MAIL_DIR (var/mail
+# of the project), so no message can ever reach a real mailbox.
+# Captured messages are listed with: magebox mail list
+
+MAIL_DIR="${MAGEBOX_MAIL_DIR:-/tmp/magebox-mail}"
+
+mkdir -p "$MAIL_DIR" || exit 1
+
+# Timestamp first so files sort chronologically, PID and random suffix keep
+# concurrent workers from colliding
+FILE="$MAIL_DIR/$(date +%Y%m%d-%H%M%S)-$$-${RANDOM}.eml"
+
+# Write to a temporary name and rename, so readers never see partial messages
+cat > "$FILE.tmp" && mv "$FILE.tmp" "$FILE"
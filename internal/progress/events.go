@@ -0,0 +1,103 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventPhase    = "phase"
+	EventProgress = "progress"
+	EventDone     = "done"
+	EventError    = "error"
+)
+
+// Event is a machine-readable progress update, written as one JSON line
+type Event struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Type    string    `json:"event"`
+	Phase   string    `json:"phase,omitempty"`
+	// Percent is the progress of the whole command for phase events and of
+	// the phase for progress events; nil when unknown
+	Percent *float64 `json:"percent,omitempty"`
+	Current int64    `json:"current,omitempty"`
+	Total   int64    `json:"total,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// Emitter writes progress events as NDJSON for GUI wrappers and IDE plugins.
+// All methods are no-ops on a nil Emitter, so callers don't need to check
+// whether --progress json was given.
+type Emitter struct {
+	w       io.Writer
+	command string
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewEmitter creates an emitter writing events for command to w
+func NewEmitter(w io.Writer, command string) *Emitter {
+	return &Emitter{w: w, command: command, now: time.Now}
+}
+
+// Phase reports the start of a phase; percent is the overall progress of the
+// command when the phase starts
+func (e *Emitter) Phase(name string, percent float64, message string) {
+	e.emit(Event{Type: EventPhase, Phase: name, Percent: &percent, Message: message})
+}
+
+// Bytes reports byte progress within a phase, e.g. from a progress Reader
+func (e *Emitter) Bytes(phase string, p Progress) {
+	ev := Event{Type: EventProgress, Phase: phase, Current: p.Read, Total: p.Total}
+	if p.Total > 0 {
+		percent := p.Percentage
+		ev.Percent = &percent
+	}
+	e.emit(ev)
+}
+
+// Step reports item progress within a phase, e.g. files copied
+func (e *Emitter) Step(phase string, current, total int64, message string) {
+	ev := Event{Type: EventProgress, Phase: phase, Current: current, Total: total, Message: message}
+	if total > 0 {
+		percent := float64(current) / float64(total) * 100
+		ev.Percent = &percent
+	}
+	e.emit(ev)
+}
+
+// Done reports that the command finished successfully
+func (e *Emitter) Done(message string) {
+	percent := 100.0
+	e.emit(Event{Type: EventDone, Percent: &percent, Message: message})
+}
+
+// Fail reports that the command failed
+func (e *Emitter) Fail(err error) {
+	e.emit(Event{Type: EventError, Message: err.Error()})
+}
+
+// Enabled reports whether events are written
+func (e *Emitter) Enabled() bool {
+	return e != nil
+}
+
+func (e *Emitter) emit(ev Event) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ev.Time = e.now().UTC()
+	ev.Command = e.command
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	_, _ = e.w.Write(append(data, '\n'))
+}
@@ -0,0 +1,73 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmitter_WritesNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf, "db import")
+	e.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	e.Phase("import", 5, "Importing dump.sql")
+	e.Bytes("import", Progress{Read: 512, Total: 1024, Percentage: 50})
+	e.Step("download", 3, 0, "media.tgz")
+	e.Done("Import completed")
+	e.Fail(errors.New("import failed"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	want := `{"time":"2024-05-01T12:00:00Z","command":"db import","event":"phase","phase":"import","percent":5,"message":"Importing dump.sql"}`
+	if lines[0] != want {
+		t.Errorf("phase event = %s\nwant %s", lines[0], want)
+	}
+
+	var ev Event
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != EventProgress || ev.Current != 512 || ev.Total != 1024 || ev.Percent == nil || *ev.Percent != 50 {
+		t.Errorf("unexpected progress event %+v", ev)
+	}
+
+	ev = Event{}
+	if err := json.Unmarshal([]byte(lines[2]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Percent != nil {
+		t.Errorf("expected no percent for unknown total, got %v", *ev.Percent)
+	}
+
+	ev = Event{}
+	_ = json.Unmarshal([]byte(lines[3]), &ev)
+	if ev.Type != EventDone || *ev.Percent != 100 {
+		t.Errorf("unexpected done event %+v", ev)
+	}
+
+	ev = Event{}
+	_ = json.Unmarshal([]byte(lines[4]), &ev)
+	if ev.Type != EventError || ev.Message != "import failed" {
+		t.Errorf("unexpected error event %+v", ev)
+	}
+}
+
+func TestEmitter_NilIsNoop(t *testing.T) {
+	var e *Emitter
+	if e.Enabled() {
+		t.Error("nil emitter should not be enabled")
+	}
+	// Must not panic
+	e.Phase("start", 0, "")
+	e.Bytes("import", Progress{})
+	e.Step("files", 1, 2, "")
+	e.Done("")
+	e.Fail(errors.New("x"))
+}
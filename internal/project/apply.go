@@ -0,0 +1,284 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/testmode"
+)
+
+// Artifacts apply compares with the project config
+const (
+	ArtifactVhost   = "nginx vhost"
+	ArtifactPool    = "php-fpm pool"
+	ArtifactService = "docker service"
+)
+
+// Actions of an apply change
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeRemove = "remove"
+)
+
+// ApplyChange is a generated artifact that differs from the project config
+type ApplyChange struct {
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	Target string `json:"target"`
+}
+
+// ApplyPlan lists the changes apply makes to bring the generated nginx,
+// PHP-FPM and Docker Compose configuration in line with the project config
+type ApplyPlan struct {
+	Project string        `json:"project"`
+	Changes []ApplyChange `json:"changes"`
+
+	cfg         *config.Config
+	projectPath string
+	configs     []*config.Config
+}
+
+// Empty reports whether everything is up to date
+func (p *ApplyPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// has reports whether the plan changes an artifact kind
+func (p *ApplyPlan) has(kind string) bool {
+	for _, c := range p.Changes {
+		if c.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanApply compares the nginx vhosts, the PHP-FPM pool and the Docker
+// services of a running project with what its config declares
+func (m *Manager) PlanApply(projectPath string) (*ApplyPlan, error) {
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if m.lowMemory {
+		applyLowMemory(cfg, projectPath)
+	}
+
+	plan := &ApplyPlan{Project: cfg.Name, Changes: []ApplyChange{}, cfg: cfg, projectPath: projectPath}
+
+	vhosts, err := m.vhostGenerator.Preview(cfg, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	existing, _ := filepath.Glob(filepath.Join(m.vhostGenerator.VhostsDir(), cfg.Name+"-*.conf"))
+	plan.Changes = append(plan.Changes, diffFiles(ArtifactVhost, vhosts, existing)...)
+
+	// Isolated projects run their own master, which start configures
+	if !cfg.Isolated {
+		var rendered []nginx.RenderedFile
+		for _, version := range cfg.PHPVersions() {
+			_ = m.preparePoolExtensions(cfg, version)
+			poolFile, content, err := m.poolGenerator.Preview(cfg.Name, projectPath, version, cfg.PHPEnv(), cfg.PoolPHPINI(), !cfg.Services.MailpitDisabled())
+			if err != nil {
+				return nil, err
+			}
+			rendered = append(rendered, nginx.RenderedFile{Path: poolFile, Content: content})
+		}
+		pools, _ := filepath.Glob(filepath.Join(m.platform.MageBoxDir(), "php", "pools", "*", cfg.Name+".conf"))
+		plan.Changes = append(plan.Changes, diffFiles(ArtifactPool, rendered, pools)...)
+	}
+
+	services, err := m.diffServices(plan)
+	if err != nil {
+		return nil, err
+	}
+	plan.Changes = append(plan.Changes, services...)
+
+	return plan, nil
+}
+
+// diffFiles compares rendered files with the files on disk; existing files
+// that are no longer rendered are removed
+func diffFiles(kind string, rendered []nginx.RenderedFile, existing []string) []ApplyChange {
+	var changes []ApplyChange
+	wanted := make(map[string]bool, len(rendered))
+	for _, f := range rendered {
+		wanted[f.Path] = true
+		current, err := os.ReadFile(f.Path)
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, ApplyChange{Kind: kind, Action: ChangeCreate, Target: f.Path})
+		case err != nil || string(current) != f.Content:
+			changes = append(changes, ApplyChange{Kind: kind, Action: ChangeUpdate, Target: f.Path})
+		}
+	}
+	for _, path := range existing {
+		if !wanted[path] {
+			changes = append(changes, ApplyChange{Kind: kind, Action: ChangeRemove, Target: path})
+		}
+	}
+	return changes
+}
+
+// diffServices compares the Docker services of the project with the current
+// compose file. Services the project dropped are removed when no other
+// running project needs them.
+func (m *Manager) diffServices(plan *ApplyPlan) ([]ApplyChange, error) {
+	current, err := m.composeGen.LoadComposeFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if lock, err := docker.LoadImageLock(plan.projectPath); err == nil && len(lock.Images) > 0 {
+		m.composeGen.SetImageLock(lock, projectComposeServiceNames(plan.cfg))
+	}
+	plan.configs = m.collectAllProjectConfigs(plan.cfg)
+	desired := m.composeGen.RenderGlobalServices(plan.configs)
+
+	var changes []ApplyChange
+	for _, name := range projectComposeServiceNames(plan.cfg) {
+		want, ok := desired.Services[name]
+		if !ok {
+			continue
+		}
+		have, exists := current.Services[name]
+		switch {
+		case !exists:
+			changes = append(changes, ApplyChange{Kind: ArtifactService, Action: ChangeCreate, Target: name})
+		case !sameService(have, want):
+			changes = append(changes, ApplyChange{Kind: ArtifactService, Action: ChangeUpdate, Target: name})
+		}
+	}
+
+	var removed []string
+	for name := range current.Services {
+		if _, ok := desired.Services[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, ApplyChange{Kind: ArtifactService, Action: ChangeRemove, Target: name})
+	}
+	return changes, nil
+}
+
+// sameService compares two compose services the way they are written to
+// the compose file, so empty and missing fields are equal
+func sameService(a, b docker.ComposeService) bool {
+	da, errA := yaml.Marshal(a)
+	db, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && string(da) == string(db)
+}
+
+// Apply makes the changes of a plan: it rewrites the vhosts and the pool and
+// reloads nginx and PHP-FPM, and recreates only the Docker services that
+// changed. Failures of single steps are returned as warnings.
+func (m *Manager) Apply(plan *ApplyPlan) []string {
+	var warnings []string
+	cfg := plan.cfg
+
+	if plan.has(ArtifactPool) {
+		for _, c := range plan.Changes {
+			if c.Kind == ArtifactPool && c.Action == ChangeRemove {
+				if err := removeFile(c.Target); err != nil {
+					warnings = append(warnings, fmt.Sprintf("PHP-FPM pool: %v", err))
+				}
+				// The pool of the old PHP version is unloaded by a reload
+				oldVersion := filepath.Base(filepath.Dir(c.Target))
+				_ = php.NewFPMController(m.platform, oldVersion).Reload()
+			}
+		}
+		m.poolGenerator.SetVersions(cfg.PHPVersions())
+		warnings = append(warnings, m.preparePoolExtensions(cfg, cfg.PHP)...)
+		if _, err := m.poolGenerator.GenerateWithResult(cfg.Name, plan.projectPath, cfg.PHP, cfg.PHPEnv(), cfg.PoolPHPINI(), !cfg.Services.MailpitDisabled()); err != nil {
+			warnings = append(warnings, fmt.Sprintf("PHP-FPM pool: %v", err))
+		} else {
+			fpmController := php.NewFPMController(m.platform, cfg.PHP)
+			if fpmController.IsRunning() {
+				err = fpmController.Reload()
+			} else {
+				err = fpmController.Start()
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("PHP-FPM: %v", err))
+			}
+		}
+		domainWarnings, errs := m.generateDomainPools(cfg, plan.projectPath)
+		warnings = append(warnings, domainWarnings...)
+		for _, err := range errs {
+			warnings = append(warnings, err.Error())
+		}
+	}
+
+	if plan.has(ArtifactVhost) {
+		for _, c := range plan.Changes {
+			if c.Kind == ArtifactVhost && c.Action == ChangeRemove {
+				if err := removeFile(c.Target); err != nil {
+					warnings = append(warnings, fmt.Sprintf("Nginx vhost: %v", err))
+				}
+			}
+		}
+		if err := m.generateSSLCerts(cfg); err != nil {
+			warnings = append(warnings, fmt.Sprintf("SSL: %v", err))
+		}
+		if err := m.vhostGenerator.Generate(cfg, plan.projectPath); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Nginx vhost: %v", err))
+		} else {
+			nginxController := m.nginxController()
+			if err := nginxController.Test(); err != nil {
+				warnings = append(warnings, fmt.Sprintf("Nginx config test: %v", err))
+			} else if err := nginxController.Reload(); err != nil {
+				warnings = append(warnings, fmt.Sprintf("Nginx reload: %v", err))
+			}
+		}
+
+		// Domains that were added need to resolve too
+		if !testmode.SkipDNS() {
+			if globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir); err == nil {
+				if _, err := m.dnsSyncer.Sync(globalCfg, cfg.Hosts()); err != nil {
+					warnings = append(warnings, fmt.Sprintf("DNS: %v", err))
+				}
+			}
+		}
+	}
+
+	if plan.has(ArtifactService) && !testmode.SkipDocker() {
+		if err := m.composeGen.GenerateGlobalServices(plan.configs); err != nil {
+			return append(warnings, fmt.Sprintf("Docker: %v", err))
+		}
+		warnings = append(warnings, m.applyPortMoves(cfg, plan.projectPath)...)
+		dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+
+		var up []string
+		for _, c := range plan.Changes {
+			if c.Kind != ArtifactService {
+				continue
+			}
+			if c.Action == ChangeRemove {
+				if err := dockerController.RemoveService(c.Target); err != nil {
+					warnings = append(warnings, fmt.Sprintf("Docker: failed to remove %s: %v", c.Target, err))
+				}
+				continue
+			}
+			up = append(up, c.Target)
+		}
+		// Compose recreates the containers whose definition changed
+		if len(up) > 0 {
+			if err := dockerController.UpServices(up); err != nil {
+				warnings = append(warnings, fmt.Sprintf("Docker: %v", err))
+			}
+		}
+	}
+
+	return warnings
+}
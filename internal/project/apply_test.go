@@ -0,0 +1,81 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestManager_PlanApply(t *testing.T) {
+	m, tmpDir := setupTestManager(t)
+
+	projectPath := filepath.Join(tmpDir, "shop")
+	if err := os.MkdirAll(projectPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	yaml := "name: shop\nphp: \"8.3\"\ndomains:\n  - host: shop.test\nservices:\n  redis: true\n"
+	if err := os.WriteFile(filepath.Join(projectPath, config.ConfigFileName), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := m.PlanApply(projectPath)
+	if err != nil {
+		t.Fatalf("PlanApply() error = %v", err)
+	}
+	actions := func(plan *ApplyPlan, kind string) map[string]string {
+		got := make(map[string]string)
+		for _, c := range plan.Changes {
+			if c.Kind == kind {
+				got[c.Target] = c.Action
+			}
+		}
+		return got
+	}
+
+	// Nothing was generated yet
+	vhost := filepath.Join(m.vhostGenerator.VhostsDir(), "shop-shop.test.conf")
+	if got := actions(plan, ArtifactVhost); got[vhost] != ChangeCreate {
+		t.Errorf("vhost changes = %v, want create of %s", got, vhost)
+	}
+	pool := filepath.Join(m.platform.MageBoxDir(), "php", "pools", "8.3", "shop.conf")
+	if got := actions(plan, ArtifactPool); got[pool] != ChangeCreate {
+		t.Errorf("pool changes = %v, want create of %s", got, pool)
+	}
+	if got := actions(plan, ArtifactService); got["redis"] != ChangeCreate {
+		t.Errorf("service changes = %v, want create of redis", got)
+	}
+
+	// Generated files are up to date, edited and stale ones are not
+	if err := m.vhostGenerator.Generate(plan.cfg, projectPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vhost, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(m.vhostGenerator.VhostsDir(), "shop-old.test.conf")
+	if err := os.WriteFile(stale, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldPool := filepath.Join(m.platform.MageBoxDir(), "php", "pools", "8.2", "shop.conf")
+	if err := os.MkdirAll(filepath.Dir(oldPool), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(oldPool, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = m.PlanApply(projectPath)
+	if err != nil {
+		t.Fatalf("PlanApply() error = %v", err)
+	}
+	got := actions(plan, ArtifactVhost)
+	if got[vhost] != ChangeUpdate || got[stale] != ChangeRemove {
+		t.Errorf("vhost changes = %v, want update of %s and remove of %s", got, vhost, stale)
+	}
+	got = actions(plan, ArtifactPool)
+	if got[pool] != ChangeCreate || got[oldPool] != ChangeRemove {
+		t.Errorf("pool changes = %v, want create of %s and remove of %s", got, pool, oldPool)
+	}
+}
@@ -0,0 +1,90 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"qoliber/magebox/internal/composer"
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/nginx"
+	"qoliber/magebox/internal/testmode"
+)
+
+// magentoRepoURLRe matches a repository url of composer.json pointing at
+// repo.magento.com
+var magentoRepoURLRe = regexp.MustCompile(`"url"\s*:\s*"https?://repo\.magento\.com/?"`)
+
+// ComposerMirrorDomain returns the HTTPS domain Nginx serves the Composer
+// mirror on
+func ComposerMirrorDomain(tld string) string {
+	return "composer.magebox." + tld
+}
+
+// ComposerMirrorURL returns the repository URL of the repo.magento.com mirror
+func ComposerMirrorURL(tld string) string {
+	return fmt.Sprintf("https://%s/mirror/%s/", ComposerMirrorDomain(tld), docker.ComposerMirrorAlias)
+}
+
+// configureComposerMirror serves the Composer mirror over HTTPS and stores
+// its credentials in the user's Composer auth.json. composer.json is left
+// alone, it is usually tracked: the returned note, given when the
+// credentials are first stored, tells how to point it at the mirror.
+func (m *Manager) configureComposerMirror(cfg *config.Config, projectPath string) (string, error) {
+	if !cfg.Services.HasComposerMirror() {
+		return "", nil
+	}
+
+	globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir)
+	if err != nil {
+		return "", err
+	}
+	tld := globalCfg.GetTLD()
+	domain := ComposerMirrorDomain(tld)
+	if err := m.vhostGenerator.GenerateProxyVhost(nginx.ProxyConfig{
+		Name:       "composer-mirror",
+		Domain:     domain,
+		ProxyHost:  "127.0.0.1",
+		ProxyPort:  docker.ComposerMirrorPort,
+		SSLEnabled: true,
+	}); err != nil {
+		return "", err
+	}
+	if !testmode.SkipDNS() && globalCfg.UseHosts() {
+		if err := m.hostsManager.AddDomains([]string{domain}); err != nil {
+			return "", err
+		}
+	}
+
+	password, err := docker.ComposerMirrorPassword(filepath.Join(m.platform.MageBoxDir(), "docker"))
+	if err != nil {
+		return "", err
+	}
+	auth, err := composer.LoadAuth(composer.AuthPath(m.platform.HomeDir))
+	if err != nil {
+		return "", err
+	}
+	if creds, ok := auth.HTTPBasic(domain); ok && creds.Username == docker.ComposerMirrorUser && creds.Password == password {
+		return "", nil
+	}
+	if err := auth.SetHTTPBasic(domain, docker.ComposerMirrorUser, password); err != nil {
+		return "", err
+	}
+	if err := auth.Save(); err != nil {
+		return "", err
+	}
+
+	if !UsesMagentoRepo(projectPath) {
+		return "", nil
+	}
+	return fmt.Sprintf("Composer mirror credentials added to %s, point the repo.magento.com repository of composer.json at %s to use it", auth.Path, ComposerMirrorURL(tld)), nil
+}
+
+// UsesMagentoRepo reports whether the project's composer.json has a
+// repository at repo.magento.com
+func UsesMagentoRepo(projectPath string) bool {
+	data, err := os.ReadFile(filepath.Join(projectPath, "composer.json"))
+	return err == nil && magentoRepoURLRe.Match(data)
+}
@@ -0,0 +1,39 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComposerMirrorURL(t *testing.T) {
+	if got := ComposerMirrorURL("test"); got != "https://composer.magebox.test/mirror/magento/" {
+		t.Errorf("ComposerMirrorURL() = %q", got)
+	}
+}
+
+func TestUsesMagentoRepo(t *testing.T) {
+	tests := []struct {
+		name     string
+		composer string
+		want     bool
+	}{
+		{name: "magento repo", composer: `{"repositories": [{"type": "composer", "url": "https://repo.magento.com/"}]}`, want: true},
+		{name: "mirror", composer: `{"repositories": [{"type": "composer", "url": "https://composer.magebox.test/mirror/magento/"}]}`},
+		{name: "no composer.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.composer != "" {
+				if err := os.WriteFile(filepath.Join(dir, "composer.json"), []byte(tt.composer), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := UsesMagentoRepo(dir); got != tt.want {
+				t.Errorf("UsesMagentoRepo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
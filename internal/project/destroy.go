@@ -0,0 +1,341 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/dns"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/ssl"
+	"qoliber/magebox/internal/testmode"
+)
+
+// Kinds of things Destroy removes
+const (
+	DestroyVhost    = "vhost"
+	DestroyPool     = "php-fpm pool"
+	DestroyCert     = "certificate"
+	DestroyHosts    = "hosts entry"
+	DestroyRoute    = "dns route"
+	DestroyDatabase = "database"
+	DestroyVolume   = "volume"
+	DestroyState    = "state"
+)
+
+// DestroyOptions selects what Destroy removes. Shared certificates and
+// services still used by one of Others are always kept.
+type DestroyOptions struct {
+	Vhosts    bool // Nginx vhosts and the PHP-FPM pool
+	Certs     bool
+	DNS       bool
+	Databases bool
+	// Volumes removes the volumes of the project's database service, when no
+	// other project uses it and it holds only the project's databases
+	Volumes bool
+	// State removes the project from the MageBox registries (Redis
+	// databases, isolated PHP-FPM, known projects)
+	State  bool
+	Others []*config.Config
+}
+
+// DestroyAll selects everything for Destroy but the volumes, which are only
+// removed when asked for
+func DestroyAll(others []*config.Config) DestroyOptions {
+	return DestroyOptions{
+		Vhosts:    true,
+		Certs:     true,
+		DNS:       true,
+		Databases: true,
+		State:     true,
+		Others:    others,
+	}
+}
+
+// DestroyAction is one thing Destroy removes
+type DestroyAction struct {
+	Kind    string `json:"kind"`
+	Target  string `json:"target"`
+	Service string `json:"service,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DestroyPlan returns what Destroy would remove for the project, without
+// changing anything
+func (m *Manager) DestroyPlan(cfg *config.Config, opts DestroyOptions) []DestroyAction {
+	var actions []DestroyAction
+
+	if opts.Vhosts {
+		for _, vhost := range m.vhostGenerator.VhostFiles(cfg) {
+			if _, err := os.Stat(vhost); err == nil {
+				actions = append(actions, DestroyAction{Kind: DestroyVhost, Target: vhost})
+			}
+		}
+		pools, _ := filepath.Glob(filepath.Join(m.platform.MageBoxDir(), "php", "pools", "*", cfg.Name+".conf"))
+		for _, pool := range pools {
+			actions = append(actions, DestroyAction{Kind: DestroyPool, Target: pool})
+		}
+	}
+
+	if opts.DNS {
+		managed, _ := m.hostsManager.ListDomains()
+		for _, host := range cfg.Hosts() {
+			for _, d := range managed {
+				if d == host {
+					actions = append(actions, DestroyAction{Kind: DestroyHosts, Target: host})
+					break
+				}
+			}
+		}
+		if tld := m.exclusiveTLD(cfg, opts.Others); tld != "" {
+			actions = append(actions, DestroyAction{Kind: DestroyRoute, Target: tld})
+		}
+	}
+
+	if opts.Certs {
+		for _, base := range m.exclusiveCertDomains(cfg, opts.Others) {
+			if m.sslManager.CertExists(base) {
+				actions = append(actions, DestroyAction{Kind: DestroyCert, Target: base})
+			}
+		}
+	}
+
+	if opts.Databases {
+		if service := databaseServiceName(cfg); service != "" {
+			for _, name := range cfg.DatabaseNames() {
+				actions = append(actions, DestroyAction{Kind: DestroyDatabase, Target: name, Service: service})
+			}
+		}
+	}
+
+	// The database service is shared: its volume also holds the databases
+	// of stopped and unknown projects, so it's only removed when it holds
+	// nothing else
+	if opts.Volumes {
+		service := databaseServiceName(cfg)
+		if service != "" && slices.Contains(ExclusiveServices([]*config.Config{cfg}, opts.Others), service) &&
+			m.holdsOnlyDatabases(service, cfg.DatabaseNames()) {
+			for _, volume := range m.composeGen.ProjectVolumes(cfg)[service] {
+				actions = append(actions, DestroyAction{
+					Kind:    DestroyVolume,
+					Target:  docker.ComposeProjectName + "_" + volume,
+					Service: service,
+				})
+			}
+		}
+	}
+
+	if opts.State {
+		actions = append(actions, DestroyAction{Kind: DestroyState, Target: cfg.Name})
+	}
+
+	return actions
+}
+
+// Destroy removes everything the plan lists for the project. It carries on
+// past failures, which are recorded in the Error of each action.
+func (m *Manager) Destroy(cfg *config.Config, opts DestroyOptions) []DestroyAction {
+	actions := m.DestroyPlan(cfg, opts)
+
+	var hosts []string
+	removedVolumes := make(map[string]bool)
+	for i := range actions {
+		a := &actions[i]
+		var err error
+		switch a.Kind {
+		case DestroyVhost, DestroyPool:
+			err = removeFile(a.Target)
+		case DestroyHosts:
+			hosts = append(hosts, a.Target)
+		case DestroyRoute:
+			if !testmode.SkipDNS() {
+				err = dns.NewDnsmasqManager(m.platform).UnrouteTLD(a.Target)
+			}
+		case DestroyCert:
+			err = m.sslManager.RemoveCert(a.Target)
+		case DestroyDatabase:
+			err = m.dropDatabase(a.Service, a.Target)
+		case DestroyVolume:
+			if !removedVolumes[a.Service] {
+				removedVolumes[a.Service] = true
+				err = m.removeServiceVolumes(a.Service, actions)
+			}
+		case DestroyState:
+			err = m.forgetProject(cfg.Name)
+		}
+		if err != nil {
+			a.Error = err.Error()
+		}
+	}
+
+	if len(hosts) > 0 && !testmode.SkipDNS() {
+		if err := m.hostsManager.RemoveDomains(hosts); err != nil {
+			for i := range actions {
+				if actions[i].Kind == DestroyHosts {
+					actions[i].Error = err.Error()
+				}
+			}
+		}
+	}
+
+	if opts.Vhosts {
+		// Unload the pool and the vhosts
+		isolatedController := php.NewIsolatedFPMController(m.platform)
+		if isolatedController.IsIsolated(cfg.Name) {
+			_ = isolatedController.Stop(cfg.Name)
+		} else {
+			m.reloadPHPVersions(cfg)
+		}
+		_ = m.nginxController().Reload()
+	}
+
+	return actions
+}
+
+// exclusiveTLD returns the TLD the project set when start routed it for the
+// project and none of others uses it, so its route can go
+func (m *Manager) exclusiveTLD(cfg *config.Config, others []*config.Config) string {
+	tld := cfg.GetTLD("")
+	if tld == "" {
+		return ""
+	}
+	for _, other := range others {
+		if other.GetTLD("") == tld {
+			return ""
+		}
+		for _, host := range other.Hosts() {
+			if strings.HasSuffix(host, "."+tld) {
+				return ""
+			}
+		}
+	}
+	globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir)
+	if err != nil || tld == globalCfg.GetTLD() || !slices.Contains(globalCfg.GetTLDs(), tld) {
+		return ""
+	}
+	return tld
+}
+
+// exclusiveCertDomains returns the base domains of the project certificates
+// that no other project has domains under
+func (m *Manager) exclusiveCertDomains(cfg *config.Config, others []*config.Config) []string {
+	shared := make(map[string]bool)
+	for _, other := range others {
+		for _, host := range other.Hosts() {
+			shared[ssl.ExtractBaseDomainForTLD(host, other.TLD)] = true
+		}
+	}
+
+	var bases []string
+	for _, host := range cfg.Hosts() {
+		if base := ssl.ExtractBaseDomainForTLD(host, cfg.TLD); !shared[base] && !slices.Contains(bases, base) {
+			bases = append(bases, base)
+		}
+	}
+	sort.Strings(bases)
+	return bases
+}
+
+// dropDatabase drops a project database in its running database service
+func (m *Manager) dropDatabase(service, name string) error {
+	if testmode.SkipDocker() {
+		return nil
+	}
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+	if !dockerController.IsServiceRunning(service) {
+		return fmt.Errorf("database service %s is not running", service)
+	}
+	return dockerController.DropDatabase(service, name)
+}
+
+// holdsOnlyDatabases reports whether a running database service holds no
+// databases but the given ones. A service that isn't running can't be
+// checked and counts as holding others.
+func (m *Manager) holdsOnlyDatabases(service string, names []string) bool {
+	if testmode.SkipDocker() {
+		return false
+	}
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+	if !dockerController.IsServiceRunning(service) {
+		return false
+	}
+	databases, err := dockerController.ListDatabases(service)
+	if err != nil {
+		return false
+	}
+	return onlyDatabases(databases, names)
+}
+
+// systemDatabases are the databases every MySQL and MariaDB server has
+var systemDatabases = []string{"information_schema", "mysql", "performance_schema", "sys"}
+
+// onlyDatabases reports whether databases, leaving out the system ones, are
+// all among names
+func onlyDatabases(databases, names []string) bool {
+	for _, db := range databases {
+		if !slices.Contains(systemDatabases, db) && !slices.Contains(names, db) {
+			return false
+		}
+	}
+	return true
+}
+
+// removeServiceVolumes removes the container of a service and the volumes
+// the actions list for it
+func (m *Manager) removeServiceVolumes(service string, actions []DestroyAction) error {
+	if testmode.SkipDocker() {
+		return nil
+	}
+	var volumes []string
+	for _, a := range actions {
+		if a.Kind == DestroyVolume && a.Service == service {
+			volumes = append(volumes, strings.TrimPrefix(a.Target, docker.ComposeProjectName+"_"))
+		}
+	}
+
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+	if err := dockerController.RemoveService(service); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", service, err)
+	}
+	return dockerController.RemoveVolumes(volumes)
+}
+
+// forgetProject removes the project from the MageBox registries
+func (m *Manager) forgetProject(name string) error {
+	isolatedController := php.NewIsolatedFPMController(m.platform)
+	if isolatedController.IsIsolated(name) {
+		if err := isolatedController.Disable(name); err != nil {
+			return err
+		}
+	}
+	if err := NewRedisDBRegistry(m.platform).Release(name); err != nil {
+		return err
+	}
+	return NewKnownProjects(m.platform).Remove(name)
+}
+
+// removeFile removes a file that may already be gone
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// databaseServiceName returns the compose service of the project database
+func databaseServiceName(cfg *config.Config) string {
+	switch {
+	case cfg.Services.HasMySQL():
+		return "mysql" + strings.ReplaceAll(cfg.Services.MySQL.Version, ".", "")
+	case cfg.Services.HasMariaDB():
+		return "mariadb" + strings.ReplaceAll(cfg.Services.MariaDB.Version, ".", "")
+	case cfg.Services.HasPercona():
+		return "percona" + strings.ReplaceAll(cfg.Services.Percona.Version, ".", "")
+	}
+	return ""
+}
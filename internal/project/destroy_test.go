@@ -0,0 +1,149 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestManager_DestroyPlan(t *testing.T) {
+	m, _ := setupTestManager(t)
+
+	writeFile := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vhost := filepath.Join(m.vhostGenerator.VhostsDir(), "shop-shop.test.conf")
+	writeFile(vhost)
+	writeFile(filepath.Join(m.vhostGenerator.VhostsDir(), "other-other.test.conf"))
+	// Another project whose name starts with this one's
+	writeFile(filepath.Join(m.vhostGenerator.VhostsDir(), "shop-b2b-upstream.conf"))
+	writeFile(filepath.Join(m.vhostGenerator.VhostsDir(), "shop-b2b-b2b.test.conf"))
+	pool := filepath.Join(m.platform.MageBoxDir(), "php", "pools", "8.3", "shop.conf")
+	writeFile(pool)
+	for _, base := range []string{"shop.test", "shared.test"} {
+		writeFile(filepath.Join(m.sslManager.CertsDir(), base, "cert.pem"))
+		writeFile(filepath.Join(m.sslManager.CertsDir(), base, "key.pem"))
+	}
+
+	cfg := &config.Config{
+		Name:    "shop",
+		Domains: []config.Domain{{Host: "shop.test"}, {Host: "de.shared.test"}},
+		Services: config.Services{
+			MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"},
+			Redis: &config.ServiceConfig{Enabled: true},
+		},
+	}
+	other := &config.Config{
+		Name:     "other",
+		Domains:  []config.Domain{{Host: "shared.test"}},
+		Services: config.Services{Redis: &config.ServiceConfig{Enabled: true}},
+	}
+
+	actions := m.DestroyPlan(cfg, DestroyAll([]*config.Config{other}))
+
+	got := make(map[string][]string)
+	for _, a := range actions {
+		got[a.Kind] = append(got[a.Kind], a.Target)
+	}
+	if len(got[DestroyVhost]) != 1 || got[DestroyVhost][0] != vhost {
+		t.Errorf("vhosts = %v, want [%s]", got[DestroyVhost], vhost)
+	}
+	if len(got[DestroyPool]) != 1 || got[DestroyPool][0] != pool {
+		t.Errorf("pools = %v, want [%s]", got[DestroyPool], pool)
+	}
+	// The certificate of shared.test is kept for the other project
+	if len(got[DestroyCert]) != 1 || got[DestroyCert][0] != "shop.test" {
+		t.Errorf("certificates = %v, want [shop.test]", got[DestroyCert])
+	}
+	if len(got[DestroyDatabase]) != 1 || got[DestroyDatabase][0] != "shop" {
+		t.Errorf("databases = %v, want [shop]", got[DestroyDatabase])
+	}
+	// Volumes are only removed when asked for
+	if len(got[DestroyVolume]) != 0 {
+		t.Errorf("volumes = %v, want none", got[DestroyVolume])
+	}
+	if len(got[DestroyState]) != 1 {
+		t.Errorf("state = %v, want [shop]", got[DestroyState])
+	}
+
+	// Only the selected kinds are planned
+	actions = m.DestroyPlan(cfg, DestroyOptions{Certs: true, Others: []*config.Config{other}})
+	if len(actions) != 1 || actions[0].Kind != DestroyCert {
+		t.Errorf("certs only: got %+v", actions)
+	}
+}
+
+func TestOnlyDatabases(t *testing.T) {
+	names := []string{"shop", "shop_quote"}
+	if !onlyDatabases([]string{"information_schema", "mysql", "performance_schema", "sys", "shop", "shop_quote"}, names) {
+		t.Error("a service with only the project's databases should be removable")
+	}
+	if onlyDatabases([]string{"mysql", "shop", "blog"}, names) {
+		t.Error("a service holding another project's database must be kept")
+	}
+}
+
+func TestManager_DestroyCerts(t *testing.T) {
+	m, _ := setupTestManager(t)
+
+	certDir := filepath.Join(m.sslManager.CertsDir(), "shop.test")
+	if err := os.MkdirAll(certDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"cert.pem", "key.pem"} {
+		if err := os.WriteFile(filepath.Join(certDir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Name: "shop", Domains: []config.Domain{{Host: "shop.test"}}}
+	actions := m.Destroy(cfg, DestroyOptions{Certs: true})
+	if len(actions) != 1 || actions[0].Error != "" {
+		t.Fatalf("Destroy() = %+v, want one successful action", actions)
+	}
+	if _, err := os.Stat(certDir); !os.IsNotExist(err) {
+		t.Error("certificate directory should be removed")
+	}
+}
+
+func TestManager_DestroyPlanRoute(t *testing.T) {
+	m, home := setupTestManager(t)
+	if err := config.SaveGlobalConfig(home, &config.GlobalConfig{TLD: "test", TLDs: []string{"acme.test", "localhost"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Name: "shop", TLD: "acme.test", Domains: []config.Domain{{Host: "shop.acme.test"}}}
+	routes := func(others ...*config.Config) []string {
+		var tlds []string
+		for _, a := range m.DestroyPlan(cfg, DestroyOptions{DNS: true, Others: others}) {
+			if a.Kind == DestroyRoute {
+				tlds = append(tlds, a.Target)
+			}
+		}
+		return tlds
+	}
+
+	if got := routes(); len(got) != 1 || got[0] != "acme.test" {
+		t.Errorf("routes = %v, want [acme.test]", got)
+	}
+	// Kept while another project uses the TLD
+	if got := routes(&config.Config{Name: "other", TLD: ".acme.test"}); len(got) != 0 {
+		t.Errorf("routes = %v, want none while another project sets the TLD", got)
+	}
+	if got := routes(&config.Config{Name: "other", Domains: []config.Domain{{Host: "other.acme.test"}}}); len(got) != 0 {
+		t.Errorf("routes = %v, want none while another project has domains on the TLD", got)
+	}
+	// The main TLD is never removed
+	cfg.TLD = "test"
+	if got := routes(); len(got) != 0 {
+		t.Errorf("routes = %v, want none for the main TLD", got)
+	}
+}
@@ -0,0 +1,59 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/php"
+)
+
+// generateDomainPools generates the PHP-FPM pools of the PHP versions domains
+// of cfg run next to the project's, and starts or reloads their PHP-FPM. The
+// pool of the project's version is generated by the caller.
+func (m *Manager) generateDomainPools(cfg *config.Config, projectPath string) (warnings []string, errs []error) {
+	for _, version := range cfg.PHPVersions()[1:] {
+		if !m.phpDetector.IsVersionInstalled(version) {
+			errs = append(errs, fmt.Errorf("PHP %s of %s is not installed", version, domainsOnPHP(cfg, version)))
+			continue
+		}
+
+		warnings = append(warnings, m.preparePoolExtensions(cfg, version)...)
+		if _, err := m.poolGenerator.GenerateWithResult(cfg.Name, projectPath, version, cfg.PHPEnv(), cfg.PoolPHPINI(), !cfg.Services.MailpitDisabled()); err != nil {
+			errs = append(errs, fmt.Errorf("PHP-FPM %s pool: %w", version, err))
+			continue
+		}
+
+		fpmController := php.NewFPMController(m.platform, version)
+		var err error
+		if fpmController.IsRunning() {
+			err = fpmController.Reload()
+		} else {
+			err = fpmController.Start()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("PHP-FPM %s: %w", version, err))
+		}
+	}
+	return warnings, errs
+}
+
+// reloadPHPVersions reloads the PHP-FPM of every PHP version of the project,
+// e.g. to unload its pools
+func (m *Manager) reloadPHPVersions(cfg *config.Config) {
+	for _, version := range cfg.PHPVersions() {
+		_ = php.NewFPMController(m.platform, version).Reload()
+	}
+}
+
+// domainsOnPHP returns the hosts of the domains running a PHP version, for
+// messages
+func domainsOnPHP(cfg *config.Config, version string) string {
+	var hosts []string
+	for _, d := range cfg.Domains {
+		if d.GetPHP(cfg.PHP) == version {
+			hosts = append(hosts, d.Host)
+		}
+	}
+	return strings.Join(hosts, ", ")
+}
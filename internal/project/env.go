@@ -8,9 +8,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"text/template"
 
+	"qoliber/magebox/internal/baseurl"
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
 	"qoliber/magebox/internal/lib"
 )
 
@@ -38,9 +43,10 @@ type EnvPHPData struct {
 	DatabasePassword string
 
 	// Service flags (for conditionals)
-	HasRedis   bool
-	HasVarnish bool
-	HasMailpit bool
+	HasRedis    bool
+	HasVarnish  bool
+	HasMailpit  bool
+	HasRabbitMQ bool
 
 	// Redis configuration
 	RedisHost        string
@@ -48,16 +54,54 @@ type EnvPHPData struct {
 	RedisSessionDB   string
 	RedisCacheDB     string
 	RedisPageCacheDB string
+	RedisUser        string
+	RedisPassword    string
+
+	// RabbitMQ configuration
+	RabbitMQHost     string
+	RabbitMQPort     string
+	RabbitMQUser     string
+	RabbitMQPassword string
 
 	// Mailpit configuration
 	MailpitHost string
 	MailpitPort string
+
+	// Search configuration; SearchEngine is the catalog/search/engine value
+	// (opensearch or elasticsearch7) and prefixes the server settings
+	HasSearch         bool
+	SearchEngine      string
+	SearchHost        string
+	SearchPort        string
+	SearchIndexPrefix string
+
+	// Base URLs set in env.php, for a worktree sharing its parent's database
+	// whose base URLs in the database are the parent's. Empty otherwise.
+	BaseURL         string
+	WebsiteBaseURLs []EnvScopeBaseURL
+	StoreBaseURLs   []EnvScopeBaseURL
 }
 
+// EnvScopeBaseURL is the base URL of a website or store view in env.php
+type EnvScopeBaseURL struct {
+	Code string
+	URL  string
+}
+
+// Patterns for the values kept from an existing env.php, so regenerating it
+// doesn't invalidate encrypted data or cached entries
+var (
+	envCryptKeyPattern = regexp.MustCompile(`'crypt'\s*=>\s*\[\s*'key'\s*=>\s*'([^']*)'`)
+	envIDPrefixPattern = regexp.MustCompile(`'id_prefix'\s*=>\s*'([^']*)'`)
+)
+
 // envGenerator generates Magento 2 app/etc/env.php configuration
 type envGenerator struct {
 	projectPath string
 	config      *config.Config
+	redisDBs    RedisDBs
+	ports       *docker.PortRegistry // Reserved service ports, nil for the conventional ones
+	baseURLs    []baseurl.Target     // Base URLs set in env.php instead of the database
 }
 
 // newEnvGenerator creates a new env.php generator
@@ -65,20 +109,27 @@ func newEnvGenerator(projectPath string, cfg *config.Config) *envGenerator {
 	return &envGenerator{
 		projectPath: projectPath,
 		config:      cfg,
+		redisDBs:    DefaultRedisDBs,
 	}
 }
 
-// Generate creates the env.php file
-func (g *envGenerator) Generate() error {
-	envPath := filepath.Join(g.projectPath, "app", "etc", "env.php")
+// Render renders env.php without writing it
+func (g *envGenerator) Render() (string, error) {
+	content, err := g.renderTemplate(g.buildTemplateData())
+	if err != nil {
+		return "", fmt.Errorf("failed to render env.php template: %w", err)
+	}
+	return content, nil
+}
 
-	// Build template data
-	data := g.buildTemplateData()
+// Generate creates the env.php file. An existing file is copied to backupPath
+// first, when one is given.
+func (g *envGenerator) Generate(backupPath string) error {
+	envPath := g.envPath()
 
-	// Render template
-	content, err := g.renderTemplate(data)
+	content, err := g.Render()
 	if err != nil {
-		return fmt.Errorf("failed to render env.php template: %w", err)
+		return err
 	}
 
 	// Ensure directory exists
@@ -86,6 +137,14 @@ func (g *envGenerator) Generate() error {
 		return fmt.Errorf("failed to create app/etc directory: %w", err)
 	}
 
+	if backupPath != "" {
+		if existing, err := os.ReadFile(envPath); err == nil {
+			if err := os.WriteFile(backupPath, existing, 0644); err != nil {
+				return fmt.Errorf("failed to back up env.php: %w", err)
+			}
+		}
+	}
+
 	// Write the file
 	if err := os.WriteFile(envPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write env.php: %w", err)
@@ -94,6 +153,11 @@ func (g *envGenerator) Generate() error {
 	return nil
 }
 
+// envPath returns the path of the project's env.php
+func (g *envGenerator) envPath() string {
+	return filepath.Join(g.projectPath, "app", "etc", "env.php")
+}
+
 // buildTemplateData constructs the data structure for the template
 func (g *envGenerator) buildTemplateData() EnvPHPData {
 	data := EnvPHPData{
@@ -107,72 +171,95 @@ func (g *envGenerator) buildTemplateData() EnvPHPData {
 		DatabaseHost:     "127.0.0.1",
 		DatabasePort:     g.getDatabasePort(),
 		DatabaseName:     g.config.DatabaseName(),
-		DatabaseUser:     "root",
-		DatabasePassword: "magebox",
+		DatabaseUser:     phpEscape(g.config.DatabaseUser()),
+		DatabasePassword: phpEscape(g.config.DatabasePassword()),
 
 		// Service flags (Valkey is Redis-compatible, same Magento configuration)
-		HasRedis:   g.config.Services.HasCacheService(),
-		HasVarnish: g.config.Services.HasVarnish(),
-		HasMailpit: true, // Always enabled for local dev safety
+		HasRedis:    g.config.Services.HasCacheService(),
+		HasVarnish:  g.config.Services.HasVarnish(),
+		HasMailpit:  !g.config.Services.MailpitDisabled(), // PHP mail() is captured to var/mail otherwise
+		HasRabbitMQ: g.config.Services.HasRabbitMQ(),
 
 		// Redis configuration
 		RedisHost:        "127.0.0.1",
-		RedisPort:        "6379",
-		RedisSessionDB:   "2",
-		RedisCacheDB:     "0",
-		RedisPageCacheDB: "1",
+		RedisPort:        strconv.Itoa(g.ports.CachePort()),
+		RedisSessionDB:   strconv.Itoa(g.redisDBs.Session),
+		RedisCacheDB:     strconv.Itoa(g.redisDBs.Cache),
+		RedisPageCacheDB: strconv.Itoa(g.redisDBs.PageCache),
+
+		// RabbitMQ configuration
+		RabbitMQHost: "127.0.0.1",
+		RabbitMQPort: strconv.Itoa(g.ports.RabbitMQPort()),
 
 		// Mailpit configuration
 		MailpitHost: "127.0.0.1",
-		MailpitPort: "1025",
+		MailpitPort: strconv.Itoa(g.ports.MailpitSMTPPort()),
 	}
 
+	// Keep the crypt key and cache prefix of an existing env.php
+	if existing, err := os.ReadFile(g.envPath()); err == nil {
+		if m := envCryptKeyPattern.FindSubmatch(existing); m != nil {
+			data.CryptKey = string(m[1])
+		}
+		if m := envIDPrefixPattern.FindSubmatch(existing); m != nil {
+			data.CacheIDPrefix = string(m[1])
+		}
+	}
+
+	switch {
+	case g.config.Services.HasOpenSearch():
+		data.HasSearch, data.SearchEngine = true, "opensearch"
+		data.SearchPort = strconv.Itoa(g.ports.OpenSearchPort(g.config.Services.OpenSearch.Version))
+	case g.config.Services.HasElasticsearch():
+		data.HasSearch, data.SearchEngine = true, "elasticsearch7"
+		data.SearchPort = strconv.Itoa(g.ports.ElasticsearchPort(g.config.Services.Elasticsearch.Version))
+	}
+	if data.HasSearch {
+		data.SearchHost = "127.0.0.1"
+		data.SearchIndexPrefix = phpEscape(g.config.Name)
+	}
+
+	for _, t := range g.baseURLs {
+		scoped := EnvScopeBaseURL{Code: phpEscape(t.Code), URL: phpEscape(t.URL)}
+		switch t.Scope {
+		case baseurl.ScopeWebsites:
+			data.WebsiteBaseURLs = append(data.WebsiteBaseURLs, scoped)
+		case baseurl.ScopeStores:
+			data.StoreBaseURLs = append(data.StoreBaseURLs, scoped)
+		default:
+			data.BaseURL = scoped.URL
+		}
+	}
+
+	redisUser, redisPassword := g.config.RedisCredentials()
+	data.RedisUser, data.RedisPassword = phpEscape(redisUser), phpEscape(redisPassword)
+	rabbitUser, rabbitPassword := g.config.RabbitMQCredentials()
+	data.RabbitMQUser, data.RabbitMQPassword = phpEscape(rabbitUser), phpEscape(rabbitPassword)
+
 	return data
 }
 
 // getMageMode returns the MAGE_MODE from config or defaults to "developer"
 func (g *envGenerator) getMageMode() string {
-	if g.config.Env != nil {
-		if mode, ok := g.config.Env["MAGE_MODE"]; ok {
-			return mode
-		}
-	}
-	return "developer"
+	return g.config.MageMode()
 }
 
-// getDatabasePort returns the appropriate database port based on service config
-// Port mappings must match internal/docker/compose.go
+// getDatabasePort returns the host port of the project's database service
 func (g *envGenerator) getDatabasePort() string {
-	if g.config.Services.HasMySQL() {
-		version := g.config.Services.MySQL.Version
-		mysqlPorts := map[string]string{
-			"5.7": "33057",
-			"8.0": "33080",
-			"8.4": "33084",
-		}
-		if port, ok := mysqlPorts[version]; ok {
-			return port
-		}
-		return "33080" // Default MySQL 8.0 port
-	}
-
-	if g.config.Services.HasMariaDB() {
-		version := g.config.Services.MariaDB.Version
-		mariadbPorts := map[string]string{
-			"10.4":  "33104",
-			"10.5":  "33105",
-			"10.6":  "33106",
-			"10.11": "33111",
-			"11.0":  "33110",
-			"11.4":  "33114",
-		}
-		if port, ok := mariadbPorts[version]; ok {
-			return port
-		}
-		return "33106" // Default MariaDB port
+	switch {
+	case g.config.Services.HasMySQL():
+		return strconv.Itoa(g.ports.DBPort("mysql", g.config.Services.MySQL.Version))
+	case g.config.Services.HasMariaDB():
+		return strconv.Itoa(g.ports.DBPort("mariadb", g.config.Services.MariaDB.Version))
+	case g.config.Services.HasPercona():
+		return strconv.Itoa(g.ports.DBPort("percona", g.config.Services.Percona.Version))
 	}
+	return strconv.Itoa(docker.DBPort("mysql", "8.0")) // Default fallback
+}
 
-	return "33080" // Default fallback
+// phpEscape escapes a value for a single-quoted PHP string
+func phpEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
 }
 
 // renderTemplate renders the env.php template with the given data
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"qoliber/magebox/internal/baseurl"
 	"qoliber/magebox/internal/config"
 )
 
@@ -216,6 +217,12 @@ func TestEnvGenerator_BuildTemplateData_WithRedis(t *testing.T) {
 	if data.RedisPageCacheDB != "1" {
 		t.Errorf("RedisPageCacheDB = %v, want 1", data.RedisPageCacheDB)
 	}
+
+	g.redisDBs = redisDBBlock(2)
+	data = g.buildTemplateData()
+	if data.RedisCacheDB != "6" || data.RedisPageCacheDB != "7" || data.RedisSessionDB != "8" {
+		t.Errorf("Redis databases = %s/%s/%s, want the allocated 6/7/8", data.RedisCacheDB, data.RedisPageCacheDB, data.RedisSessionDB)
+	}
 }
 
 func TestEnvGenerator_BuildTemplateData_WithVarnish(t *testing.T) {
@@ -482,7 +489,7 @@ func TestEnvGenerator_Generate(t *testing.T) {
 	}
 	g := newEnvGenerator(projectPath, cfg)
 
-	if err := g.Generate(); err != nil {
+	if err := g.Generate(""); err != nil {
 		t.Fatalf("Generate failed: %v", err)
 	}
 
@@ -506,6 +513,73 @@ func TestEnvGenerator_Generate(t *testing.T) {
 	}
 }
 
+func TestEnvGenerator_GenerateKeepsCryptKeyAndBacksUp(t *testing.T) {
+	projectPath := t.TempDir()
+	appEtcDir := filepath.Join(projectPath, "app", "etc")
+	if err := os.MkdirAll(appEtcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := "<?php\nreturn [\n    'crypt' => [\n        'key' => 'abcdef0123456789'\n    ],\n    'cache' => ['frontend' => ['default' => ['id_prefix' => 'x1y_']]]\n];\n"
+	envPath := filepath.Join(appEtcDir, "env.php")
+	if err := os.WriteFile(envPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Name: "testproject",
+		Services: config.Services{
+			MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"},
+		},
+	}
+	backupPath := filepath.Join(appEtcDir, "env.php.bak")
+	if err := newEnvGenerator(projectPath, cfg).Generate(backupPath); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	backup, err := os.ReadFile(backupPath)
+	if err != nil || string(backup) != existing {
+		t.Errorf("backup = %q, %v, want the previous env.php", backup, err)
+	}
+	content, _ := os.ReadFile(envPath)
+	if !strings.Contains(string(content), "'key' => 'abcdef0123456789'") {
+		t.Error("regenerated env.php should keep the crypt key")
+	}
+	if !strings.Contains(string(content), "'id_prefix' => 'x1y_'") {
+		t.Error("regenerated env.php should keep the cache id_prefix")
+	}
+}
+
+func TestEnvGenerator_BuildTemplateData_Search(t *testing.T) {
+	tests := []struct {
+		name       string
+		services   config.Services
+		wantEngine string
+		wantPort   string
+	}{
+		{"opensearch", config.Services{OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"}}, "opensearch", "9259"},
+		{"elasticsearch", config.Services{Elasticsearch: &config.ServiceConfig{Enabled: true, Version: "7.17"}}, "elasticsearch7", "9657"},
+		{"none", config.Services{}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newEnvGenerator("/path/to/project", &config.Config{Name: "shop", Services: tt.services})
+			data := g.buildTemplateData()
+			if data.HasSearch != (tt.wantEngine != "") || data.SearchEngine != tt.wantEngine || data.SearchPort != tt.wantPort {
+				t.Errorf("search = %v %q %q, want %q %q", data.HasSearch, data.SearchEngine, data.SearchPort, tt.wantEngine, tt.wantPort)
+			}
+
+			content, err := g.renderTemplate(data)
+			if err != nil {
+				t.Fatalf("renderTemplate() error = %v", err)
+			}
+			if tt.wantEngine != "" && !strings.Contains(content, "'"+tt.wantEngine+"_server_port' => '"+tt.wantPort+"'") {
+				t.Errorf("env.php is missing the %s server port", tt.wantEngine)
+			}
+		})
+	}
+}
+
 func TestEnvPHPTemplate_ValidSyntax(t *testing.T) {
 	// Test that the embedded template parses correctly
 	cfg := &config.Config{
@@ -589,3 +663,73 @@ func TestEnvPHPTemplate_AllConditionalBranches(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvGenerator_BuildTemplateData_CustomCredentials(t *testing.T) {
+	cfg := &config.Config{
+		Name: "testproject",
+		Services: config.Services{
+			MySQL:    &config.ServiceConfig{Enabled: true, Version: "8.0", User: "shop", Password: "it's-secret"},
+			Redis:    &config.ServiceConfig{Enabled: true, Password: "redispass"},
+			RabbitMQ: &config.ServiceConfig{Enabled: true, User: "mq", Password: "mqpass"},
+		},
+	}
+	g := newEnvGenerator("/path/to/project", cfg)
+
+	data := g.buildTemplateData()
+
+	if data.DatabaseUser != "shop" {
+		t.Errorf("DatabaseUser = %v, want shop", data.DatabaseUser)
+	}
+	if data.DatabasePassword != `it\'s-secret` {
+		t.Errorf("DatabasePassword = %v, want escaped it's-secret", data.DatabasePassword)
+	}
+	if data.RedisUser != "testproject" || data.RedisPassword != "redispass" {
+		t.Errorf("Redis credentials = %v/%v, want testproject/redispass", data.RedisUser, data.RedisPassword)
+	}
+	if !data.HasRabbitMQ || data.RabbitMQUser != "mq" || data.RabbitMQPassword != "mqpass" {
+		t.Errorf("RabbitMQ = %v %v/%v, want enabled mq/mqpass", data.HasRabbitMQ, data.RabbitMQUser, data.RabbitMQPassword)
+	}
+}
+
+func TestEnvGenerator_RenderTemplate_BaseURLs(t *testing.T) {
+	cfg := &config.Config{
+		Name: "shop-feature",
+		Domains: []config.Domain{
+			{Host: "feature.shop.test"},
+			{Host: "feature.shop-de.test", MageRunCode: "de"},
+			{Host: "feature.shop-fr.test", MageRunCode: "fr"},
+			{Host: "feature.b2b.test", MageRunCode: "b2b", MageRunType: "website"},
+		},
+		Services: config.Services{MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"}},
+	}
+	g := newEnvGenerator("/path/to/project", cfg)
+	g.baseURLs = baseurl.Targets(cfg.Domains)
+
+	content, err := g.renderTemplate(g.buildTemplateData())
+	if err != nil {
+		t.Fatalf("renderTemplate failed: %v", err)
+	}
+	for _, want := range []string{
+		"'base_url' => 'https://feature.shop.test/'",
+		"'de' => [",
+		"'base_url' => 'https://feature.shop-fr.test/'",
+		"'b2b' => [",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("env.php should contain %s:\n%s", want, content)
+		}
+	}
+	// Both store views must be in one stores array, a second one would replace the first
+	if n := strings.Count(content, "'stores' => ["); n != 1 {
+		t.Errorf("env.php has %d stores arrays, want 1", n)
+	}
+
+	g.baseURLs = nil
+	content, err = g.renderTemplate(g.buildTemplateData())
+	if err != nil {
+		t.Fatalf("renderTemplate failed: %v", err)
+	}
+	if strings.Contains(content, "'base_url'") {
+		t.Error("env.php should leave the base URLs to the database")
+	}
+}
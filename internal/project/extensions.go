@@ -0,0 +1,33 @@
+package project
+
+import (
+	"fmt"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/php"
+)
+
+// preparePoolExtensions checks the php_extensions of cfg for a PHP version of
+// the project and has the pool generator load the installed ones PHP doesn't
+// enable. It returns a warning with the install command for each missing
+// extension.
+func (m *Manager) preparePoolExtensions(cfg *config.Config, phpVersion string) []string {
+	m.poolGenerator.SetExtensions(nil)
+	if len(cfg.PHPExtensions) == 0 {
+		return nil
+	}
+
+	extMgr := php.NewExtensionManager(m.platform)
+	check, err := extMgr.CheckExtensions(cfg.PHPExtensions, phpVersion)
+	if err != nil {
+		return []string{fmt.Sprintf("PHP extensions: %v", err)}
+	}
+	m.poolGenerator.SetExtensions(check.Pool)
+
+	var warnings []string
+	for _, ext := range check.Missing {
+		warnings = append(warnings, fmt.Sprintf("PHP extension %s is missing for PHP %s, install it with: %s",
+			ext, phpVersion, extMgr.InstallCommand(ext, phpVersion)))
+	}
+	return warnings
+}
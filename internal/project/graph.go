@@ -0,0 +1,257 @@
+package project
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/php"
+	"qoliber/magebox/internal/testmode"
+)
+
+// NodeHealth is the health state of a node in the service graph
+type NodeHealth string
+
+// Node health states
+const (
+	HealthUp      NodeHealth = "up"
+	HealthDown    NodeHealth = "down"
+	HealthUnknown NodeHealth = "unknown"
+)
+
+// GraphNode is a service in the project topology
+type GraphNode struct {
+	ID     string     `json:"id"`
+	Label  string     `json:"label"`
+	Health NodeHealth `json:"health"`
+
+	// compose is the docker compose service backing the node, if any
+	compose string
+}
+
+// GraphEdge is a dependency between two nodes
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// ServiceGraph is the service topology of a project
+type ServiceGraph struct {
+	Name  string      `json:"name"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// BuildServiceGraph builds the request flow and backend dependencies of a
+// project from its configuration. All nodes start with unknown health.
+func BuildServiceGraph(cfg *config.Config) *ServiceGraph {
+	g := &ServiceGraph{Name: cfg.Name}
+	s := &cfg.Services
+
+	g.addNode("browser", "Browser", "")
+	g.addNode("nginx", "Nginx", "")
+	g.addNode("php-fpm", "PHP-FPM "+cfg.PHP, "")
+
+	if s.HasVarnish() {
+		// Nginx terminates TLS, Varnish caches, and misses go back through Nginx to PHP
+		g.addNode("varnish", "Varnish", "varnish")
+		g.addEdge("browser", "nginx", "https")
+		g.addEdge("nginx", "varnish", "http")
+		g.addEdge("varnish", "php-fpm", "miss")
+	} else {
+		g.addEdge("browser", "nginx", "https")
+		g.addEdge("nginx", "php-fpm", "fastcgi")
+	}
+
+	// Backends are used by web requests as well as by cron and queue consumers
+	clients := []string{"php-fpm"}
+	if cfg.IsMagento() {
+		g.addNode("cron", "Cron", "")
+		clients = append(clients, "cron")
+		if s.HasRabbitMQ() {
+			g.addNode("consumers", "Queue consumers", "")
+			clients = append(clients, "consumers")
+		}
+	}
+
+	var backends []string
+	if s.HasMySQL() {
+		g.addNode("mysql", "MySQL "+s.MySQL.Version, composeName("mysql", s.MySQL.Version))
+		backends = append(backends, "mysql")
+	}
+	if s.HasMariaDB() {
+		g.addNode("mariadb", "MariaDB "+s.MariaDB.Version, composeName("mariadb", s.MariaDB.Version))
+		backends = append(backends, "mariadb")
+	}
+	if s.HasPercona() {
+		g.addNode("percona", "Percona Server "+s.Percona.Version, composeName("percona", s.Percona.Version))
+		backends = append(backends, "percona")
+	}
+	if s.HasCacheService() {
+		name := s.GetCacheServiceName()
+		g.addNode(name, s.GetCacheServiceDisplayName(), name)
+		backends = append(backends, name)
+	}
+	if s.HasOpenSearch() {
+		g.addNode("opensearch", "OpenSearch "+s.OpenSearch.Version, composeName("opensearch", s.OpenSearch.Version))
+		backends = append(backends, "opensearch")
+	}
+	if s.HasElasticsearch() {
+		g.addNode("elasticsearch", "Elasticsearch "+s.Elasticsearch.Version, composeName("elasticsearch", s.Elasticsearch.Version))
+		backends = append(backends, "elasticsearch")
+	}
+	if s.HasMeilisearch() {
+		g.addNode("meilisearch", "Meilisearch "+s.MeilisearchImageTag(), "meilisearch")
+		backends = append(backends, "meilisearch")
+	}
+	if s.HasTypesense() {
+		g.addNode("typesense", "Typesense "+s.TypesenseImageTag(), "typesense")
+		backends = append(backends, "typesense")
+	}
+	if s.HasRabbitMQ() {
+		g.addNode("rabbitmq", "RabbitMQ", "rabbitmq")
+		backends = append(backends, "rabbitmq")
+	}
+	if !s.MailpitDisabled() {
+		g.addNode("mailpit", "Mailpit", "mailpit")
+		backends = append(backends, "mailpit")
+	}
+
+	for _, client := range clients {
+		for _, backend := range backends {
+			// Consumers only talk to the queue and the database; mail and
+			// search are driven by web requests and cron
+			if client == "consumers" && backend != "rabbitmq" && !isDatabaseNode(backend) {
+				continue
+			}
+			g.addEdge(client, backend, "")
+		}
+	}
+
+	return g
+}
+
+// GraphHealth builds the service graph of a project and colors it with the
+// current state of PHP-FPM, Nginx, the Docker services and the crontab
+func (m *Manager) GraphHealth(projectPath string, cfg *config.Config) *ServiceGraph {
+	g := BuildServiceGraph(cfg)
+
+	var dockerController *docker.DockerController
+	if !testmode.SkipDocker() {
+		dockerController = m.dockerController(m.composeGen.ComposeFilePath())
+	}
+
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		switch {
+		case node.ID == "nginx":
+			node.Health = healthOf(m.nginxController().IsRunning())
+		case node.ID == "php-fpm":
+			node.Health = healthOf(php.NewFPMController(m.platform, cfg.PHP).IsRunning())
+		case node.ID == "cron":
+			node.Health = cronHealth(projectPath)
+		case node.compose != "" && dockerController != nil:
+			node.Health = healthOf(dockerController.IsServiceRunning(node.compose))
+		}
+	}
+
+	return g
+}
+
+// DOT renders the graph in Graphviz format
+func (g *ServiceGraph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", g.Name)
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [label=%q, fillcolor=%q];\n", n.ID, n.Label, dotColors[n.Health])
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, e.Label)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *ServiceGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s[\"%s\"]:::%s\n", mermaidID(n.ID), n.Label, n.Health)
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", mermaidID(e.From), e.Label, mermaidID(e.To))
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", mermaidID(e.From), mermaidID(e.To))
+		}
+	}
+	for _, h := range []NodeHealth{HealthUp, HealthDown, HealthUnknown} {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", h, dotColors[h])
+	}
+	return b.String()
+}
+
+// dotColors maps node health to fill colors
+var dotColors = map[NodeHealth]string{
+	HealthUp:      "#b7e4c7",
+	HealthDown:    "#f4a6a6",
+	HealthUnknown: "#e0e0e0",
+}
+
+func (g *ServiceGraph) addNode(id, label, compose string) {
+	g.Nodes = append(g.Nodes, GraphNode{ID: id, Label: label, Health: HealthUnknown, compose: compose})
+}
+
+func (g *ServiceGraph) addEdge(from, to, label string) {
+	g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Label: label})
+}
+
+// composeName returns the compose service name for a versioned service (e.g. mysql80)
+func composeName(service, version string) string {
+	return service + strings.ReplaceAll(version, ".", "")
+}
+
+// isDatabaseNode returns true for the MySQL/MariaDB/Percona node
+func isDatabaseNode(id string) bool {
+	return id == "mysql" || id == "mariadb" || id == "percona"
+}
+
+// mermaidID makes a node ID safe for Mermaid
+func mermaidID(id string) string {
+	return strings.ReplaceAll(id, "-", "_")
+}
+
+func healthOf(running bool) NodeHealth {
+	if running {
+		return HealthUp
+	}
+	return HealthDown
+}
+
+// cronHealth reports whether the user's crontab runs cron for the project
+func cronHealth(projectPath string) NodeHealth {
+	out, err := exec.Command("crontab", "-l").Output()
+	if err != nil {
+		return HealthUnknown
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, projectPath) && strings.Contains(line, "cron:run") {
+			return HealthUp
+		}
+	}
+	return HealthDown
+}
@@ -0,0 +1,121 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func hasEdge(g *ServiceGraph, from, to string) bool {
+	for _, e := range g.Edges {
+		if e.From == from && e.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBuildServiceGraph(t *testing.T) {
+	cfg := &config.Config{
+		Name: "mystore",
+		PHP:  "8.3",
+		Services: config.Services{
+			MySQL:      &config.ServiceConfig{Enabled: true, Version: "8.0"},
+			Redis:      &config.ServiceConfig{Enabled: true},
+			OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+			RabbitMQ:   &config.ServiceConfig{Enabled: true},
+			Varnish:    &config.ServiceConfig{Enabled: true},
+		},
+	}
+
+	g := BuildServiceGraph(cfg)
+
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"browser", "nginx", true},
+		{"nginx", "varnish", true},
+		{"varnish", "php-fpm", true},
+		{"nginx", "php-fpm", false},
+		{"php-fpm", "mysql", true},
+		{"php-fpm", "redis", true},
+		{"php-fpm", "opensearch", true},
+		{"cron", "mysql", true},
+		{"consumers", "rabbitmq", true},
+		{"consumers", "mysql", true},
+		{"consumers", "opensearch", false},
+		{"php-fpm", "mailpit", true},
+	}
+	for _, tt := range tests {
+		if got := hasEdge(g, tt.from, tt.to); got != tt.want {
+			t.Errorf("edge %s -> %s = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	for _, n := range g.Nodes {
+		if n.Health != HealthUnknown {
+			t.Errorf("node %s health = %s, want unknown before checks", n.ID, n.Health)
+		}
+		if n.ID == "mysql" && n.compose != "mysql80" {
+			t.Errorf("mysql compose service = %q, want mysql80", n.compose)
+		}
+	}
+}
+
+func TestBuildServiceGraph_WithoutVarnish(t *testing.T) {
+	cfg := &config.Config{
+		Name: "mystore",
+		PHP:  "8.3",
+		Services: config.Services{
+			MariaDB: &config.ServiceConfig{Enabled: true, Version: "10.6"},
+			Mailpit: &config.ServiceConfig{Enabled: false},
+		},
+	}
+
+	g := BuildServiceGraph(cfg)
+
+	if !hasEdge(g, "nginx", "php-fpm") {
+		t.Error("nginx should pass requests to php-fpm directly without varnish")
+	}
+	if !hasEdge(g, "php-fpm", "mariadb") {
+		t.Error("php-fpm should depend on mariadb")
+	}
+	for _, n := range g.Nodes {
+		if n.ID == "mailpit" || n.ID == "consumers" || n.ID == "varnish" {
+			t.Errorf("unexpected node %s", n.ID)
+		}
+	}
+}
+
+func TestServiceGraph_Render(t *testing.T) {
+	g := &ServiceGraph{Name: "mystore"}
+	g.addNode("php-fpm", "PHP-FPM 8.3", "")
+	g.addNode("mysql", "MySQL 8.0", "mysql80")
+	g.Nodes[1].Health = HealthDown
+	g.addEdge("php-fpm", "mysql", "sql")
+
+	dot := g.DOT()
+	for _, want := range []string{
+		`digraph "mystore" {`,
+		`"mysql" [label="MySQL 8.0", fillcolor="#f4a6a6"];`,
+		`"php-fpm" -> "mysql" [label="sql"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot)
+		}
+	}
+
+	mermaid := g.Mermaid()
+	for _, want := range []string{
+		"flowchart LR",
+		`php_fpm["PHP-FPM 8.3"]:::unknown`,
+		"php_fpm -->|sql| mysql",
+		"classDef down fill:#f4a6a6",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/nginx"
+)
+
+// Inspection is the fully resolved, read-only model of a project.
+// It is intended for CI validators and review bots and is built without
+// writing files, starting services or contacting the network.
+type Inspection struct {
+	Name           string                 `json:"name"`
+	Path           string                 `json:"path"`
+	Type           string                 `json:"type"`
+	PHPVersion     string                 `json:"php"`
+	ConfigFiles    []string               `json:"config_files"`
+	Config         map[string]interface{} `json:"config"`
+	Services       []InspectedService     `json:"services"`
+	GeneratedFiles []nginx.RenderedFile   `json:"generated_files"`
+}
+
+// InspectedService describes an effective service of the project
+type InspectedService struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	ComposeService string `json:"compose_service"`
+}
+
+// Inspect resolves the project configuration (including include_config and
+// local overrides) and renders previews of the files MageBox would generate
+func (m *Manager) Inspect(projectPath string) (*Inspection, error) {
+	loader := config.NewLoader(projectPath)
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := configToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &Inspection{
+		Name:        cfg.Name,
+		Path:        projectPath,
+		Type:        cfg.GetType(),
+		PHPVersion:  cfg.PHP,
+		ConfigFiles: loader.LoadedFiles(),
+		Config:      resolved,
+		Services:    effectiveServices(cfg),
+	}
+
+	poolPath, poolContent, err := m.poolGenerator.Preview(cfg.Name, projectPath, cfg.PHP, cfg.PHPEnv(), cfg.PHPINI, !cfg.Services.MailpitDisabled())
+	if err != nil {
+		return nil, fmt.Errorf("PHP-FPM pool: %w", err)
+	}
+	inspection.GeneratedFiles = append(inspection.GeneratedFiles, nginx.RenderedFile{Path: poolPath, Content: poolContent})
+
+	vhosts, err := m.vhostGenerator.Preview(cfg, projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("nginx vhost: %w", err)
+	}
+	inspection.GeneratedFiles = append(inspection.GeneratedFiles, vhosts...)
+
+	return inspection, nil
+}
+
+// configToMap converts the merged config to a generic map using its YAML
+// representation, so field names match what users write in .magebox.yaml
+func configToMap(cfg *config.Config) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var out map[string]interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to convert config: %w", err)
+	}
+	return out, nil
+}
+
+// effectiveServices returns the Docker services the project resolves to,
+// including Mailpit which is enabled for local dev safety unless disabled
+func effectiveServices(cfg *config.Config) []InspectedService {
+	svc := cfg.Services
+	var services []InspectedService
+	versioned := func(name string, sc *config.ServiceConfig) {
+		services = append(services, InspectedService{
+			Name:           name,
+			Version:        sc.Version,
+			ComposeService: name + strings.ReplaceAll(sc.Version, ".", ""),
+		})
+	}
+
+	if svc.HasMySQL() {
+		versioned("mysql", svc.MySQL)
+	}
+	if svc.HasMariaDB() {
+		versioned("mariadb", svc.MariaDB)
+	}
+	if svc.HasPercona() {
+		versioned("percona", svc.Percona)
+	}
+	if svc.HasCacheService() {
+		name := svc.GetCacheServiceName()
+		services = append(services, InspectedService{Name: name, ComposeService: name})
+	}
+	if svc.HasOpenSearch() {
+		versioned("opensearch", svc.OpenSearch)
+	}
+	if svc.HasOpenSearchDashboards() {
+		services = append(services, InspectedService{Name: "opensearch-dashboards", Version: svc.OpenSearch.Version, ComposeService: docker.OpenSearchDashboardsService(svc.OpenSearch.Version)})
+	}
+	if svc.HasElasticsearch() {
+		versioned("elasticsearch", svc.Elasticsearch)
+	}
+	if svc.HasMeilisearch() {
+		services = append(services, InspectedService{Name: "meilisearch", Version: svc.MeilisearchImageTag(), ComposeService: "meilisearch"})
+	}
+	if svc.HasTypesense() {
+		services = append(services, InspectedService{Name: "typesense", Version: svc.TypesenseImageTag(), ComposeService: "typesense"})
+	}
+	if svc.HasRabbitMQ() {
+		services = append(services, InspectedService{Name: "rabbitmq", ComposeService: "rabbitmq"})
+	}
+	if svc.HasVarnish() {
+		services = append(services, InspectedService{Name: "varnish", Version: svc.Varnish.Version, ComposeService: "varnish"})
+	}
+	if svc.HasComposerMirror() {
+		services = append(services, InspectedService{Name: "composer-mirror", ComposeService: "composer-mirror"})
+	}
+	if !svc.MailpitDisabled() {
+		services = append(services, InspectedService{Name: "mailpit", ComposeService: "mailpit"})
+	}
+
+	return services
+}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManager_Inspect(t *testing.T) {
+	m, tmpDir := setupTestManager(t)
+
+	projectPath := filepath.Join(tmpDir, "mystore")
+	if err := os.MkdirAll(projectPath, 0755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+	mainCfg := `name: mystore
+domains:
+  - host: mystore.test
+php: "8.2"
+services:
+  mysql: "8.0"
+  redis: true
+`
+	localCfg := `php: "8.3"
+`
+	if err := os.WriteFile(filepath.Join(projectPath, ".magebox.yaml"), []byte(mainCfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectPath, ".magebox.local.yaml"), []byte(localCfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inspection, err := m.Inspect(projectPath)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+
+	if inspection.PHPVersion != "8.3" {
+		t.Errorf("PHPVersion = %q, want local override 8.3", inspection.PHPVersion)
+	}
+	if len(inspection.ConfigFiles) != 2 {
+		t.Errorf("ConfigFiles = %v, want main and local config", inspection.ConfigFiles)
+	}
+	if inspection.Config["name"] != "mystore" {
+		t.Errorf("Config[name] = %v, want mystore", inspection.Config["name"])
+	}
+
+	var composeNames []string
+	for _, svc := range inspection.Services {
+		composeNames = append(composeNames, svc.ComposeService)
+	}
+	if got := strings.Join(composeNames, ","); got != "mysql80,redis,mailpit" {
+		t.Errorf("services = %s, want mysql80,redis,mailpit", got)
+	}
+
+	// pool + upstream + one vhost
+	if len(inspection.GeneratedFiles) != 3 {
+		t.Fatalf("GeneratedFiles = %d, want 3", len(inspection.GeneratedFiles))
+	}
+	for _, f := range inspection.GeneratedFiles {
+		if f.Content == "" {
+			t.Errorf("preview %s is empty", f.Path)
+		}
+	}
+
+	// Nothing may have been written to the MageBox directory
+	if _, err := os.Stat(filepath.Join(tmpDir, ".magebox")); !os.IsNotExist(err) {
+		t.Errorf("Inspect must not create %s", filepath.Join(tmpDir, ".magebox"))
+	}
+}
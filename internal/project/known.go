@@ -0,0 +1,143 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/fileutil"
+	"qoliber/magebox/internal/platform"
+)
+
+// KnownProjects remembers the path of every project MageBox started. Nginx
+// vhosts only exist while a project runs, so this is what finds stopped
+// projects by name.
+type KnownProjects struct {
+	registryPath string
+}
+
+// NewKnownProjects creates the known projects registry
+func NewKnownProjects(p *platform.Platform) *KnownProjects {
+	return &KnownProjects{
+		registryPath: filepath.Join(p.MageBoxDir(), "projects.json"),
+	}
+}
+
+// Load returns the project paths by project name
+func (k *KnownProjects) Load() (map[string]string, error) {
+	projects := make(map[string]string)
+
+	data, err := os.ReadFile(k.registryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return projects, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// Add records the path of a project
+func (k *KnownProjects) Add(name, path string) error {
+	projects, err := k.Load()
+	if err != nil {
+		return err
+	}
+	if projects[name] == path {
+		return nil
+	}
+	projects[name] = path
+	return k.save(projects)
+}
+
+// Remove forgets a project
+func (k *KnownProjects) Remove(name string) error {
+	projects, err := k.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := projects[name]; !ok {
+		return nil
+	}
+	delete(projects, name)
+	return k.save(projects)
+}
+
+// save writes the registry atomically
+func (k *KnownProjects) save(projects map[string]string) error {
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.registryPath), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(k.registryPath, data, 0644)
+}
+
+// AllProjects returns the running projects found in the nginx vhosts and the
+// stopped projects MageBox started before, sorted by name. Known projects
+// whose config is gone are left out.
+func (d *ProjectDiscovery) AllProjects() ([]ProjectInfo, error) {
+	projects, err := d.DiscoverProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := NewKnownProjects(d.platform).Load()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, proj := range projects {
+		seen[proj.Path] = true
+	}
+	for _, path := range known {
+		if seen[path] {
+			continue
+		}
+		cfg, err := config.LoadFromPath(path)
+		if err != nil {
+			continue
+		}
+		info := ProjectInfo{
+			Name:       cfg.Name,
+			Path:       path,
+			PHPVersion: cfg.PHP,
+			HasConfig:  true,
+		}
+		for _, domain := range cfg.Domains {
+			info.Domains = append(info.Domains, domain.Host)
+		}
+		projects = append(projects, info)
+		seen[path] = true
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	return projects, nil
+}
+
+// FindProjectByName finds a running or known project by its name
+func (d *ProjectDiscovery) FindProjectByName(name string) (*ProjectInfo, error) {
+	projects, err := d.AllProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,70 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qoliber/magebox/internal/platform"
+)
+
+func TestProjectDiscovery_AllProjects(t *testing.T) {
+	tmpDir := t.TempDir()
+	p := &platform.Platform{Type: platform.Linux, HomeDir: tmpDir}
+
+	writeProject := func(name string) string {
+		dir := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Join(dir, "pub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "name: " + name + "\ndomains:\n  - host: " + name + ".test\nphp: \"8.3\"\n"
+		if err := os.WriteFile(filepath.Join(dir, ".magebox.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	running := writeProject("running")
+	stopped := writeProject("stopped")
+
+	vhostsDir := filepath.Join(tmpDir, ".magebox", "nginx", "vhosts")
+	if err := os.MkdirAll(vhostsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	vhost := "server {\n    server_name running.test;\n    set $MAGE_ROOT " + running + "/pub;\n}\n"
+	if err := os.WriteFile(filepath.Join(vhostsDir, "running.test.conf"), []byte(vhost), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	known := NewKnownProjects(p)
+	for name, path := range map[string]string{"running": running, "stopped": stopped, "deleted": filepath.Join(tmpDir, "deleted")} {
+		if err := known.Add(name, path); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	d := NewProjectDiscovery(p)
+	projects, err := d.AllProjects()
+	if err != nil {
+		t.Fatalf("AllProjects failed: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("AllProjects() = %+v, want running and stopped", projects)
+	}
+	if projects[0].Name != "running" || !projects[0].Running {
+		t.Errorf("projects[0] = %+v, want running project", projects[0])
+	}
+	if projects[1].Name != "stopped" || projects[1].Running || projects[1].Path != stopped {
+		t.Errorf("projects[1] = %+v, want stopped project", projects[1])
+	}
+	if len(projects[1].Domains) != 1 || projects[1].Domains[0] != "stopped.test" {
+		t.Errorf("stopped project domains = %v", projects[1].Domains)
+	}
+
+	info, err := d.FindProjectByName("stopped")
+	if err != nil || info == nil || info.Path != stopped {
+		t.Errorf("FindProjectByName(stopped) = %+v, %v", info, err)
+	}
+	if info, _ := d.FindProjectByName("deleted"); info != nil {
+		t.Errorf("a project whose config is gone should not be found, got %+v", info)
+	}
+}
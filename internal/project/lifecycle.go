@@ -1,18 +1,25 @@
 package project
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"qoliber/magebox/internal/baseurl"
 	"qoliber/magebox/internal/blackfire"
 	"qoliber/magebox/internal/config"
 	"qoliber/magebox/internal/dns"
 	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/execctx"
 	"qoliber/magebox/internal/nginx"
 	"qoliber/magebox/internal/php"
 	"qoliber/magebox/internal/platform"
+	"qoliber/magebox/internal/progress"
 	"qoliber/magebox/internal/ssl"
 	"qoliber/magebox/internal/testmode"
 	"qoliber/magebox/internal/xdebug"
@@ -20,29 +27,69 @@ import (
 
 // Manager manages project lifecycle
 type Manager struct {
-	platform       *platform.Platform
-	sslManager     *ssl.Manager
-	vhostGenerator *nginx.VhostGenerator
-	poolGenerator  *php.PoolGenerator
-	composeGen     *docker.ComposeGenerator
-	hostsManager   *dns.HostsManager
-	phpDetector    *php.Detector
+	platform         *platform.Platform
+	sslManager       *ssl.Manager
+	vhostGenerator   *nginx.VhostGenerator
+	poolGenerator    *php.PoolGenerator
+	composeGen       *docker.ComposeGenerator
+	hostsManager     *dns.HostsManager
+	dnsSyncer        *dns.Syncer
+	phpDetector      *php.Detector
+	events           *progress.Emitter
+	ctx              context.Context
+	lowMemory        bool
+	readyTimeout     time.Duration
+	regenerateEnvPHP bool
 }
 
 // NewManager creates a new project manager
 func NewManager(p *platform.Platform) *Manager {
 	sslMgr := ssl.NewManager(p)
+	ctx, timeouts := execctx.Defaults()
+	poolGen := php.NewPoolGenerator(p)
+	poolGen.SetMailpitPort(docker.LoadPorts(p).MailpitSMTPPort())
 	return &Manager{
+		ctx:            ctx,
+		readyTimeout:   timeouts.Ready,
 		platform:       p,
 		sslManager:     sslMgr,
 		vhostGenerator: nginx.NewVhostGenerator(p, sslMgr),
-		poolGenerator:  php.NewPoolGenerator(p),
+		poolGenerator:  poolGen,
 		composeGen:     docker.NewComposeGenerator(p),
 		hostsManager:   dns.NewHostsManager(p),
+		dnsSyncer:      dns.NewSyncer(p),
 		phpDetector:    php.NewDetector(p),
 	}
 }
 
+// SetContext sets the context that cancels the Docker and Nginx commands run
+// by the manager, e.g. on Ctrl+C
+func (m *Manager) SetContext(ctx context.Context) {
+	m.ctx = ctx
+}
+
+// SetReadyTimeout sets how long start waits for the database, cache, search
+// and queue services to accept connections; zero skips the wait
+func (m *Manager) SetReadyTimeout(d time.Duration) {
+	m.readyTimeout = d
+}
+
+// dockerController returns a Docker controller bound to the manager context
+func (m *Manager) dockerController(composeFile string) *docker.DockerController {
+	return docker.NewDockerController(composeFile).WithContext(m.ctx)
+}
+
+// nginxController returns an Nginx controller bound to the manager context
+func (m *Manager) nginxController() *nginx.Controller {
+	return nginx.NewController(m.platform).WithContext(m.ctx)
+}
+
+// SetProgress sets the emitter that receives progress events of long-running
+// operations; nil disables them
+func (m *Manager) SetProgress(e *progress.Emitter) {
+	m.events = e
+}
+
 // StartResult contains the result of a start operation
 type StartResult struct {
 	Config           *config.Config
@@ -55,10 +102,18 @@ type StartResult struct {
 	SystemINIInfo    string              // Instructions for system INI settings (if any)
 	SystemSettings   map[string]string   // PHP_INI_SYSTEM settings that were configured
 	PreviousINIOwner *php.SystemINIOwner // Previous owner if system settings were overwritten
+	Readiness        []docker.ReadinessResult
+	EnvPHPBackup     string // Backup of env.php when start regenerated it
 }
 
 // Start starts a project
 func (m *Manager) Start(projectPath string) (*StartResult, error) {
+	return m.StartTargets(projectPath, nil)
+}
+
+// StartTargets starts the selected components and services of a project;
+// nil targets start everything
+func (m *Manager) StartTargets(projectPath string, targets *Targets) (*StartResult, error) {
 	result := &StartResult{
 		ProjectPath: projectPath,
 		Errors:      make([]error, 0),
@@ -70,138 +125,212 @@ func (m *Manager) Start(projectPath string) (*StartResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	if m.lowMemory {
+		result.Warnings = append(result.Warnings, applyLowMemory(cfg, projectPath)...)
+	}
 	result.Config = cfg
 	result.PHPVersion = cfg.PHP
 
+	// Remember the path so the project can be addressed by name once stopped
+	if err := NewKnownProjects(m.platform).Add(cfg.Name, projectPath); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Project registry: %v", err))
+	}
+
 	// Extract domains
 	for _, d := range cfg.Domains {
 		result.Domains = append(result.Domains, d.Host)
 	}
 
-	// Check PHP version is installed
-	if !m.phpDetector.IsVersionInstalled(cfg.PHP) {
-		return nil, &PHPNotInstalledError{
-			Version:  cfg.PHP,
-			Platform: m.platform,
+	if targets.Web() {
+		// Check PHP version is installed
+		m.events.Phase("php", 5, "Checking PHP "+cfg.PHP)
+		if !m.phpDetector.IsVersionInstalled(cfg.PHP) {
+			return nil, &PHPNotInstalledError{
+				Version:  cfg.PHP,
+				Platform: m.platform,
+			}
 		}
-	}
 
-	// Generate SSL certificates
-	if err := m.generateSSLCerts(cfg); err != nil {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("SSL: %v", err))
-	}
-
-	// Check if project uses isolated PHP-FPM master
-	isolatedController := php.NewIsolatedFPMController(m.platform)
-
-	if cfg.Isolated {
-		// Enable isolated PHP-FPM master for this project
-		settings := cfg.PHPINI
-		if settings == nil {
-			settings = make(map[string]string)
-		}
-		// Default: disable opcache for isolated development projects if not specified
-		if _, hasOpcache := settings["opcache.enable"]; !hasOpcache {
-			settings["opcache.enable"] = "0"
+		// Generate SSL certificates
+		m.events.Phase("ssl", 10, "Generating SSL certificates")
+		if err := m.generateSSLCerts(cfg); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("SSL: %v", err))
 		}
 
-		_, err := isolatedController.Enable(cfg.Name, projectPath, cfg.PHP, settings)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("isolated PHP-FPM: %w", err))
-		}
-		result.Warnings = append(result.Warnings, "Using isolated PHP-FPM master")
-	} else {
-		// Disable isolation if it was previously enabled but config changed
-		if isolatedController.IsIsolated(cfg.Name) {
-			if err := isolatedController.Disable(cfg.Name); err != nil {
-				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to disable isolation: %v", err))
+		// Check if project uses isolated PHP-FPM master
+		m.events.Phase("php-fpm", 20, "Configuring PHP-FPM")
+		isolatedController := php.NewIsolatedFPMController(m.platform)
+
+		if cfg.Isolated {
+			// Enable isolated PHP-FPM master for this project
+			settings := cfg.PoolPHPINI()
+			// Default: disable opcache for isolated development projects if not specified
+			if _, hasOpcache := settings["opcache.enable"]; !hasOpcache {
+				settings["opcache.enable"] = "0"
 			}
-		}
 
-		// Generate PHP-FPM pool (Mailpit always enabled for local dev safety)
-		// This prevents accidental emails to real addresses during development
-		poolResult, err := m.poolGenerator.GenerateWithResult(cfg.Name, projectPath, cfg.PHP, cfg.Env, cfg.PHPINI, true)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("PHP-FPM pool: %w", err))
-		} else if poolResult != nil {
-			// Track system INI settings info
-			result.SystemSettings = poolResult.SystemSettings
-			result.PreviousINIOwner = poolResult.PreviousOwner
-
-			// Generate activation instructions if there are system settings
-			if len(poolResult.SystemSettings) > 0 {
-				sysMgr := m.poolGenerator.GetSystemINIManager()
-				result.SystemINIInfo = sysMgr.FormatActivationInstructions(cfg.PHP, poolResult.SystemSettings)
-
-				// Add warning if settings were taken over from another project
-				if poolResult.PreviousOwner != nil {
-					result.Warnings = append(result.Warnings,
-						fmt.Sprintf("PHP system settings taken over from project '%s'", poolResult.PreviousOwner.ProjectName))
+			_, err := isolatedController.Enable(cfg.Name, projectPath, cfg.PHP, settings)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("isolated PHP-FPM: %w", err))
+			}
+			result.Warnings = append(result.Warnings, "Using isolated PHP-FPM master")
+		} else {
+			// Disable isolation if it was previously enabled but config changed
+			if isolatedController.IsIsolated(cfg.Name) {
+				if err := isolatedController.Disable(cfg.Name); err != nil {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to disable isolation: %v", err))
 				}
 			}
-		}
 
-		// Start or reload shared PHP-FPM to pick up new pool configuration
-		fpmController := php.NewFPMController(m.platform, cfg.PHP)
-		if fpmController.IsRunning() {
-			// Reload to pick up new pool
-			if err := fpmController.Reload(); err != nil {
-				result.Errors = append(result.Errors, fmt.Errorf("PHP-FPM reload: %w", err))
+			// Generate PHP-FPM pool (Mailpit enabled unless explicitly disabled, in which
+			// case mail is captured to var/mail). This prevents accidental emails to real
+			// addresses during development
+			m.poolGenerator.SetVersions(cfg.PHPVersions())
+			result.Warnings = append(result.Warnings, m.preparePoolExtensions(cfg, cfg.PHP)...)
+			poolResult, err := m.poolGenerator.GenerateWithResult(cfg.Name, projectPath, cfg.PHP, cfg.PHPEnv(), cfg.PoolPHPINI(), !cfg.Services.MailpitDisabled())
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("PHP-FPM pool: %w", err))
+			} else if poolResult != nil {
+				// Track system INI settings info
+				result.SystemSettings = poolResult.SystemSettings
+				result.PreviousINIOwner = poolResult.PreviousOwner
+
+				// Generate activation instructions if there are system settings
+				if len(poolResult.SystemSettings) > 0 {
+					sysMgr := m.poolGenerator.GetSystemINIManager()
+					result.SystemINIInfo = sysMgr.FormatActivationInstructions(cfg.PHP, poolResult.SystemSettings)
+
+					// Add warning if settings were taken over from another project
+					if poolResult.PreviousOwner != nil {
+						result.Warnings = append(result.Warnings,
+							fmt.Sprintf("PHP system settings taken over from project '%s'", poolResult.PreviousOwner.ProjectName))
+					}
+				}
 			}
-		} else {
-			// Start PHP-FPM
-			if err := fpmController.Start(); err != nil {
-				result.Errors = append(result.Errors, fmt.Errorf("PHP-FPM: %w", err))
+
+			// Start or reload shared PHP-FPM to pick up new pool configuration
+			fpmController := php.NewFPMController(m.platform, cfg.PHP)
+			if fpmController.IsRunning() {
+				// Reload to pick up new pool
+				if err := fpmController.Reload(); err != nil {
+					result.Errors = append(result.Errors, fmt.Errorf("PHP-FPM reload: %w", err))
+				}
+			} else {
+				// Start PHP-FPM
+				if err := fpmController.Start(); err != nil {
+					result.Errors = append(result.Errors, fmt.Errorf("PHP-FPM: %w", err))
+				}
 			}
+
+			// Domains running a PHP version of their own get a pool in it
+			warnings, errs := m.generateDomainPools(cfg, projectPath)
+			result.Warnings = append(result.Warnings, warnings...)
+			result.Errors = append(result.Errors, errs...)
 		}
-	}
 
-	// Generate Nginx vhost
-	if err := m.vhostGenerator.Generate(cfg, projectPath); err != nil {
-		result.Errors = append(result.Errors, fmt.Errorf("nginx vhost: %w", err))
-	}
+		// Generate Nginx vhost
+		m.events.Phase("nginx", 35, "Configuring Nginx")
+		if err := m.vhostGenerator.Generate(cfg, projectPath); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("nginx vhost: %w", err))
+		}
+		if note, err := m.configureComposerMirror(cfg, projectPath); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Composer mirror: %v", err))
+		} else if note != "" {
+			result.Warnings = append(result.Warnings, note)
+		}
 
-	// Reload Nginx to pick up new vhost
-	nginxController := nginx.NewController(m.platform)
-	if err := nginxController.Reload(); err != nil {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("Nginx reload: %v", err))
-	}
+		// Reload Nginx to pick up new vhost
+		nginxController := m.nginxController()
+		if err := nginxController.Reload(); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Nginx reload: %v", err))
+		}
 
-	// Add domains to /etc/hosts only if using hosts mode (not dnsmasq)
-	// Skip in test mode
-	if !testmode.SkipDNS() {
-		globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir)
-		if err == nil && globalCfg.UseHosts() {
-			if err := m.hostsManager.AddDomains(result.Domains); err != nil {
-				result.Warnings = append(result.Warnings, fmt.Sprintf("DNS: %v", err))
+		// Add missing domains to /etc/hosts in hosts mode, verify wildcard
+		// resolution in dnsmasq mode. Skip in test mode
+		m.events.Phase("dns", 45, "Configuring DNS")
+		if !testmode.SkipDNS() {
+			globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir)
+			if err == nil {
+				// A project on its own TLD has it routed by dnsmasq first
+				if cfg.TLD != "" && globalCfg.UseDnsmasq() {
+					if added, err := dns.NewDnsmasqManager(m.platform).RouteTLD(cfg.TLD); err != nil {
+						result.Warnings = append(result.Warnings, fmt.Sprintf("DNS: failed to route .%s: %v", cfg.GetTLD(""), err))
+					} else if added {
+						result.Warnings = append(result.Warnings, fmt.Sprintf("DNS: dnsmasq now routes .%s to this machine", cfg.GetTLD("")))
+					}
+				}
+				sync, err := m.dnsSyncer.Sync(globalCfg, result.Domains)
+				if err != nil {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("DNS: %v", err))
+				} else if len(sync.Unresolved) > 0 {
+					result.Warnings = append(result.Warnings, fmt.Sprintf(
+						"DNS: %s not resolving to this machine, run 'magebox dns sync' for details",
+						strings.Join(sync.Unresolved, ", ")))
+				}
 			}
 		}
 	}
 
+	// Pin the project's service images to its lock file. Upstream drift is
+	// only checked by 'magebox services status', not on every start.
+	if !testmode.SkipDocker() {
+		lock, err := docker.LoadImageLock(projectPath)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Image lock: %v", err))
+		} else if len(lock.Images) > 0 {
+			m.composeGen.SetImageLock(lock, projectComposeServiceNames(cfg))
+		}
+	}
+
+	// Warn about images that have no build for the host architecture
+	result.Warnings = append(result.Warnings, m.composeGen.PlatformWarnings(cfg)...)
+
 	// Generate and start Docker services
-	if err := m.startDockerServices(cfg); err != nil {
+	m.events.Phase("docker", 50, "Starting Docker services")
+	err = m.startDockerServices(cfg, targets)
+	result.Warnings = append(result.Warnings, m.applyPortMoves(cfg, projectPath)...)
+	if err != nil {
 		result.Errors = append(result.Errors, fmt.Errorf("docker: %w", err))
+	} else {
+		m.events.Phase("ready", 70, "Waiting for services")
+		result.Readiness = m.waitForServices(cfg, targets)
+		for _, r := range result.Readiness {
+			if !r.Ready {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s not ready after %s: %v", r.Service, r.Elapsed.Round(time.Second), r.Err))
+			}
+		}
 	}
 
 	// Create database if needed
-	if err := m.ensureDatabase(cfg); err != nil {
+	m.events.Phase("database", 80, "Preparing database")
+	if err := m.ensureDatabase(cfg, targets); err != nil {
 		result.Warnings = append(result.Warnings, fmt.Sprintf("Database: %v", err))
 	}
 
+	// Provision per-project service users
+	if err := m.ensureServiceUsers(cfg, targets); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Service users: %v", err))
+	}
+
 	// Flush Redis cache on start (clean slate)
-	if cfg.Services.HasRedis() {
-		if err := m.flushRedis(); err != nil {
+	if cfg.Services.HasRedis() && targets.includes(cfg.Services.GetCacheServiceName()) {
+		if err := m.flushRedis(cfg, projectPath); err != nil {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("Redis flush: %v", err))
 		}
 	}
 
 	// Generate/update Magento env.php if it's a Magento project
-	if err := m.ensureEnvPHP(projectPath, cfg); err != nil {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("env.php: %v", err))
+	if targets.Web() {
+		m.events.Phase("env-php", 95, "Updating env.php")
+		backup, err := m.ensureEnvPHP(projectPath, cfg)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("env.php: %v", err))
+		}
+		result.EnvPHPBackup = backup
 	}
 
 	// Collect started services
-	result.Services = m.getStartedServices(cfg)
+	result.Services = m.startedServices(cfg, targets)
 
 	return result, nil
 }
@@ -219,7 +348,7 @@ func (m *Manager) Stop(projectPath string) error {
 	}
 
 	// Reload Nginx
-	nginxController := nginx.NewController(m.platform)
+	nginxController := m.nginxController()
 	_ = nginxController.Reload()
 
 	// Stop isolated PHP-FPM master if project uses isolation
@@ -236,22 +365,17 @@ func (m *Manager) Stop(projectPath string) error {
 		}
 	}
 
-	// Reload PHP-FPM to unload the pool (only for shared FPM)
+	// Reload PHP-FPM to unload the pools (only for shared FPM)
 	if !cfg.Isolated {
-		fpmController := php.NewFPMController(m.platform, cfg.PHP)
-		_ = fpmController.Reload()
+		m.reloadPHPVersions(cfg)
 	}
 
 	// Remove domains from /etc/hosts only if using hosts mode (not dnsmasq)
 	// Skip in test mode
 	if !testmode.SkipDNS() {
 		globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir)
-		if err == nil && globalCfg.UseHosts() {
-			domains := make([]string, 0, len(cfg.Domains))
-			for _, d := range cfg.Domains {
-				domains = append(domains, d.Host)
-			}
-			if err := m.hostsManager.RemoveDomains(domains); err != nil {
+		if err == nil {
+			if err := m.dnsSyncer.Remove(globalCfg, cfg.Hosts()); err != nil {
 				return fmt.Errorf("failed to remove dns entries: %w", err)
 			}
 		}
@@ -260,6 +384,72 @@ func (m *Manager) Stop(projectPath string) error {
 	return nil
 }
 
+// StopTargets stops the selected components and services of a project. Nil
+// targets behave like Stop and leave the shared Docker services running.
+func (m *Manager) StopTargets(projectPath string, targets *Targets) error {
+	if targets.Web() {
+		if err := m.Stop(projectPath); err != nil {
+			return err
+		}
+	}
+	return m.StopServices(targets.Services())
+}
+
+// SharedServices returns the other projects using each of the given compose
+// services, for services that are shared
+func (m *Manager) SharedServices(cfg *config.Config, services []string) map[string][]string {
+	shared := make(map[string][]string)
+	for _, other := range m.collectAllProjectConfigs(cfg)[1:] {
+		for _, name := range projectComposeServiceNames(other) {
+			for _, service := range services {
+				if name == service {
+					shared[service] = append(shared[service], other.Name)
+				}
+			}
+		}
+	}
+	return shared
+}
+
+// ExclusiveServices returns the compose services the stopped projects use and
+// none of the kept projects need, sorted by name
+func ExclusiveServices(stopped, kept []*config.Config) []string {
+	needed := make(map[string]bool)
+	for _, cfg := range kept {
+		for _, name := range projectComposeServiceNames(cfg) {
+			needed[name] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var exclusive []string
+	for _, cfg := range stopped {
+		for _, name := range projectComposeServiceNames(cfg) {
+			if !needed[name] && !seen[name] {
+				seen[name] = true
+				exclusive = append(exclusive, name)
+			}
+		}
+	}
+	sort.Strings(exclusive)
+	return exclusive
+}
+
+// StopServices stops shared Docker services by compose service name
+func (m *Manager) StopServices(services []string) error {
+	if len(services) == 0 || testmode.SkipDocker() {
+		return nil
+	}
+
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+	for _, service := range services {
+		if err := dockerController.StopService(service); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", service, err)
+		}
+	}
+	return nil
+}
+
 // Status returns the status of a project
 func (m *Manager) Status(projectPath string) (*ProjectStatus, error) {
 	cfg, err := config.LoadFromPath(projectPath)
@@ -271,6 +461,7 @@ func (m *Manager) Status(projectPath string) (*ProjectStatus, error) {
 		Name:       cfg.Name,
 		Path:       projectPath,
 		PHPVersion: cfg.PHP,
+		MageMode:   cfg.MageMode(),
 		Domains:    make([]string, 0, len(cfg.Domains)),
 		Services:   make(map[string]ServiceStatus),
 	}
@@ -279,6 +470,12 @@ func (m *Manager) Status(projectPath string) (*ProjectStatus, error) {
 		status.Domains = append(status.Domains, d.Host)
 	}
 
+	if cfg.Services.HasCacheService() {
+		if dbs, ok, _ := NewRedisDBRegistry(m.platform).Get(cfg.Name); ok {
+			status.RedisDBs = &dbs
+		}
+	}
+
 	// Check PHP-FPM
 	fpmController := php.NewFPMController(m.platform, cfg.PHP)
 	status.Services["php-fpm"] = ServiceStatus{
@@ -287,7 +484,7 @@ func (m *Manager) Status(projectPath string) (*ProjectStatus, error) {
 	}
 
 	// Check Nginx
-	nginxController := nginx.NewController(m.platform)
+	nginxController := m.nginxController()
 	status.Services["nginx"] = ServiceStatus{
 		Name:      "Nginx",
 		IsRunning: nginxController.IsRunning(),
@@ -295,15 +492,29 @@ func (m *Manager) Status(projectPath string) (*ProjectStatus, error) {
 
 	// Check Docker services (skip actual check in test mode)
 	if !testmode.SkipDocker() {
-		dockerController := docker.NewDockerController(m.composeGen.ComposeFilePath())
-		if cfg.Services.HasMySQL() {
-			// Service name in docker-compose removes dots from version (e.g., mysql80)
-			serviceName := fmt.Sprintf("mysql%s", strings.ReplaceAll(cfg.Services.MySQL.Version, ".", ""))
-			status.Services["mysql"] = ServiceStatus{
-				Name:      fmt.Sprintf("MySQL %s", cfg.Services.MySQL.Version),
-				IsRunning: dockerController.IsServiceRunning(serviceName),
+		dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+		ports := docker.LoadPorts(m.platform)
+		portStatus := func(name, service string, port int) ServiceStatus {
+			running := dockerController.IsServiceRunning(service)
+			return ServiceStatus{
+				Name:         name,
+				IsRunning:    running,
+				Port:         port,
+				PortConflict: !running && docker.PortInUse(port),
 			}
 		}
+		if cfg.Services.HasMySQL() {
+			version := cfg.Services.MySQL.Version
+			status.Services["mysql"] = portStatus("MySQL "+version, docker.DBServiceName("mysql", version), ports.DBPort("mysql", version))
+		}
+		if cfg.Services.HasMariaDB() {
+			version := cfg.Services.MariaDB.Version
+			status.Services["mariadb"] = portStatus("MariaDB "+version, docker.DBServiceName("mariadb", version), ports.DBPort("mariadb", version))
+		}
+		if cfg.Services.HasPercona() {
+			version := cfg.Services.Percona.Version
+			status.Services["percona"] = portStatus("Percona "+version, docker.DBServiceName("percona", version), ports.DBPort("percona", version))
+		}
 		if cfg.Services.HasCacheService() {
 			svcName := cfg.Services.GetCacheServiceName()
 			status.Services[svcName] = ServiceStatus{
@@ -312,20 +523,21 @@ func (m *Manager) Status(projectPath string) (*ProjectStatus, error) {
 			}
 		}
 		if cfg.Services.HasOpenSearch() {
-			// Service name in docker-compose removes dots from version (e.g., opensearch2194)
-			serviceName := fmt.Sprintf("opensearch%s", strings.ReplaceAll(cfg.Services.OpenSearch.Version, ".", ""))
-			status.Services["opensearch"] = ServiceStatus{
-				Name:      fmt.Sprintf("OpenSearch %s", cfg.Services.OpenSearch.Version),
-				IsRunning: dockerController.IsServiceRunning(serviceName),
+			version := cfg.Services.OpenSearch.Version
+			status.Services["opensearch"] = portStatus("OpenSearch "+version, docker.SearchServiceName("opensearch", version), ports.OpenSearchPort(version))
+		}
+		if cfg.Services.HasOpenSearchDashboards() {
+			port := docker.OpenSearchDashboardsPort(&cfg.Services)
+			status.Services["opensearch-dashboards"] = ServiceStatus{
+				Name:      "OS Dashboards",
+				IsRunning: dockerController.IsServiceRunning(docker.OpenSearchDashboardsService(cfg.Services.OpenSearch.Version)),
+				Port:      port,
+				URL:       fmt.Sprintf("http://localhost:%d", port),
 			}
 		}
 		if cfg.Services.HasElasticsearch() {
-			// Service name in docker-compose removes dots from version (e.g., elasticsearch8170)
-			serviceName := fmt.Sprintf("elasticsearch%s", strings.ReplaceAll(cfg.Services.Elasticsearch.Version, ".", ""))
-			status.Services["elasticsearch"] = ServiceStatus{
-				Name:      fmt.Sprintf("Elasticsearch %s", cfg.Services.Elasticsearch.Version),
-				IsRunning: dockerController.IsServiceRunning(serviceName),
-			}
+			version := cfg.Services.Elasticsearch.Version
+			status.Services["elasticsearch"] = portStatus("Elasticsearch "+version, docker.SearchServiceName("elasticsearch", version), ports.ElasticsearchPort(version))
 		}
 	} else {
 		// In test mode, report Docker services as "test mode"
@@ -399,7 +611,7 @@ func (m *Manager) generateSSLCerts(cfg *config.Config) error {
 		if !domain.IsSSLEnabled() {
 			continue
 		}
-		base := ssl.ExtractBaseDomain(domain.Host)
+		base := ssl.ExtractBaseDomainForTLD(domain.Host, cfg.TLD)
 		if _, seen := hostsByBase[base]; !seen {
 			bases = append(bases, base)
 		}
@@ -414,13 +626,22 @@ func (m *Manager) generateSSLCerts(cfg *config.Config) error {
 	return nil
 }
 
-// startDockerServices starts Docker services needed by the project
-func (m *Manager) startDockerServices(cfg *config.Config) error {
+// startDockerServices starts the selected Docker services of the project
+func (m *Manager) startDockerServices(cfg *config.Config, targets *Targets) error {
 	// Skip in test mode
 	if testmode.SkipDocker() {
 		return nil
 	}
 
+	services := projectComposeServiceNames(cfg)
+	if targets != nil {
+		services = targets.Services()
+	}
+	// An empty list would start every service in the shared compose file
+	if len(services) == 0 {
+		return nil
+	}
+
 	// Collect configs from ALL projects to avoid overwriting other projects' services
 	allConfigs := m.collectAllProjectConfigs(cfg)
 
@@ -432,8 +653,115 @@ func (m *Manager) startDockerServices(cfg *config.Config) error {
 	// Start only the services this project needs, so the output matches the
 	// Services summary instead of also touching containers owned by other
 	// projects in the shared compose file.
-	dockerController := docker.NewDockerController(m.composeGen.ComposeFilePath())
-	return dockerController.UpServices(projectComposeServiceNames(cfg))
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+	return dockerController.UpServices(services)
+}
+
+// applyPortMoves points the env.php of the projects using a service moved off
+// a port another program took at its new port, and describes the moves
+func (m *Manager) applyPortMoves(cfg *config.Config, projectPath string) []string {
+	moves := m.composeGen.PortMoves()
+	if len(moves) == 0 {
+		return nil
+	}
+
+	paths := knownProjectPaths(m.platform)
+	paths[cfg.Name] = projectPath
+	updated, err := updateEnvPHPPorts(moves, paths)
+
+	warnings := portMoveWarnings(moves)
+	if len(updated) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Updated the service ports in env.php of %s", strings.Join(updated, ", ")))
+	}
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("%v, run 'magebox env generate' there", err))
+	}
+	return append(warnings, m.applyMailpitMoves(cfg, projectPath, moves)...)
+}
+
+// applyMailpitMoves points the project's PHP-FPM pool and the Mailpit vhost
+// at the Mailpit ports moved off ports other programs took. Pools of other
+// projects pick up the SMTP port when they are restarted.
+func (m *Manager) applyMailpitMoves(cfg *config.Config, projectPath string, moves []docker.PortMove) []string {
+	var warnings []string
+	for _, move := range moves {
+		switch move.Service {
+		case docker.MailpitSMTPPortName:
+			m.poolGenerator.SetMailpitPort(move.To)
+			if !cfg.Services.MailpitDisabled() && !cfg.Isolated {
+				if err := m.poolGenerator.Generate(cfg.Name, projectPath, cfg.PHP, cfg.PHPEnv(), cfg.PoolPHPINI(), true); err != nil {
+					warnings = append(warnings, fmt.Sprintf("PHP-FPM pool: %v", err))
+				} else if fpm := php.NewFPMController(m.platform, cfg.PHP); fpm.IsRunning() {
+					if err := fpm.Reload(); err != nil {
+						warnings = append(warnings, fmt.Sprintf("PHP-FPM reload: %v", err))
+					}
+				}
+			}
+			var others []string
+			for _, name := range move.Projects {
+				if name != cfg.Name {
+					others = append(others, name)
+				}
+			}
+			if len(others) > 0 {
+				warnings = append(warnings, fmt.Sprintf("Restart %s to send mail to Mailpit on port %d", strings.Join(others, ", "), move.To))
+			}
+		case docker.MailpitWebPortName:
+			globalCfg, err := config.LoadGlobalConfig(m.platform.HomeDir)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("Mailpit vhost: %v", err))
+				continue
+			}
+			if err := m.vhostGenerator.GenerateProxyVhost(nginx.ProxyConfig{
+				Name:       "mailpit",
+				Domain:     fmt.Sprintf("mailpit.magebox.%s", globalCfg.GetTLD()),
+				ProxyHost:  "127.0.0.1",
+				ProxyPort:  move.To,
+				SSLEnabled: true,
+			}); err != nil {
+				warnings = append(warnings, fmt.Sprintf("Mailpit vhost: %v", err))
+			} else if err := m.nginxController().Reload(); err != nil {
+				warnings = append(warnings, fmt.Sprintf("Nginx reload: %v", err))
+			}
+		}
+	}
+	return warnings
+}
+
+// portMoveWarnings describes the services moved off ports other programs took
+func portMoveWarnings(moves []docker.PortMove) []string {
+	var warnings []string
+	for _, move := range moves {
+		warnings = append(warnings, fmt.Sprintf(
+			"Port %d of %s is used by another program, moved it to %d",
+			move.From, move.Service, move.To))
+	}
+	return warnings
+}
+
+// waitForServices waits until the started services accept connections, so
+// the database and search are usable once start returns
+func (m *Manager) waitForServices(cfg *config.Config, targets *Targets) []docker.ReadinessResult {
+	if testmode.SkipDocker() || m.readyTimeout <= 0 {
+		return nil
+	}
+
+	services := projectComposeServiceNames(cfg)
+	if targets != nil {
+		services = targets.Services()
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+	return dockerController.WaitReady(services, m.readyTimeout)
+}
+
+// ComposeServiceNames returns the services of the global compose file the
+// project uses
+func ComposeServiceNames(cfg *config.Config) []string {
+	return projectComposeServiceNames(cfg)
 }
 
 // projectComposeServiceNames returns the docker-compose service names that
@@ -446,23 +774,43 @@ func projectComposeServiceNames(cfg *config.Config) []string {
 	if cfg.Services.HasMariaDB() {
 		names = append(names, fmt.Sprintf("mariadb%s", strings.ReplaceAll(cfg.Services.MariaDB.Version, ".", "")))
 	}
+	if cfg.Services.HasPercona() {
+		names = append(names, fmt.Sprintf("percona%s", strings.ReplaceAll(cfg.Services.Percona.Version, ".", "")))
+	}
 	if cfg.Services.HasCacheService() {
 		names = append(names, cfg.Services.GetCacheServiceName())
 	}
 	if cfg.Services.HasOpenSearch() {
 		names = append(names, fmt.Sprintf("opensearch%s", strings.ReplaceAll(cfg.Services.OpenSearch.Version, ".", "")))
 	}
+	if cfg.Services.HasOpenSearchDashboards() {
+		names = append(names, docker.OpenSearchDashboardsService(cfg.Services.OpenSearch.Version))
+	}
 	if cfg.Services.HasElasticsearch() {
 		names = append(names, fmt.Sprintf("elasticsearch%s", strings.ReplaceAll(cfg.Services.Elasticsearch.Version, ".", "")))
 	}
+	if cfg.Services.HasMeilisearch() {
+		names = append(names, "meilisearch")
+	}
+	if cfg.Services.HasTypesense() {
+		names = append(names, "typesense")
+	}
 	if cfg.Services.HasRabbitMQ() {
 		names = append(names, "rabbitmq")
 	}
 	if cfg.Services.HasVarnish() {
 		names = append(names, "varnish")
 	}
-	// Mailpit is always started for local-dev safety, matching getStartedServices.
-	names = append(names, "mailpit")
+	if cfg.Services.HasComposerMirror() {
+		names = append(names, "composer-mirror")
+	}
+	if service := docker.ProfilerService(cfg.ActiveProfiler()); service != "" {
+		names = append(names, service)
+	}
+	// Mailpit is started for local-dev safety unless disabled, matching getStartedServices.
+	if !cfg.Services.MailpitDisabled() {
+		names = append(names, "mailpit")
+	}
 	return names
 }
 
@@ -492,6 +840,9 @@ func (m *Manager) collectAllProjectConfigs(currentCfg *config.Config) []*config.
 		if err != nil {
 			continue
 		}
+		if m.lowMemory {
+			applyLowMemory(projCfg, proj.Path)
+		}
 		configs = append(configs, projCfg)
 		seen[proj.Name] = true
 	}
@@ -499,8 +850,9 @@ func (m *Manager) collectAllProjectConfigs(currentCfg *config.Config) []*config.
 	return configs
 }
 
-// ensureDatabase creates the database if it doesn't exist
-func (m *Manager) ensureDatabase(cfg *config.Config) error {
+// ensureDatabase creates the database if it doesn't exist and the database
+// service is selected
+func (m *Manager) ensureDatabase(cfg *config.Config, targets *Targets) error {
 	// Skip in test mode
 	if testmode.SkipDocker() {
 		return nil
@@ -511,82 +863,158 @@ func (m *Manager) ensureDatabase(cfg *config.Config) error {
 		return nil
 	}
 
-	dockerController := docker.NewDockerController(m.composeGen.ComposeFilePath())
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
 
-	// Determine service name (version dots are removed in docker-compose service names)
-	var serviceName string
-	if cfg.Services.HasMySQL() {
-		serviceName = fmt.Sprintf("mysql%s", strings.ReplaceAll(cfg.Services.MySQL.Version, ".", ""))
-	} else if cfg.Services.HasMariaDB() {
-		serviceName = fmt.Sprintf("mariadb%s", strings.ReplaceAll(cfg.Services.MariaDB.Version, ".", ""))
-	}
+	// Version dots are removed in docker-compose service names
+	serviceName := databaseServiceName(cfg)
 
-	if serviceName == "" {
+	if serviceName == "" || !targets.includes(serviceName) {
 		return nil
 	}
 
-	// Wait for service to be healthy (simplified - in production would use proper health check)
+	// StartTargets has already waited for the service to accept connections
 	if !dockerController.IsServiceRunning(serviceName) {
 		return fmt.Errorf("database service %s is not running", serviceName)
 	}
 
-	// Create database (use sanitized name - hyphens replaced with underscores)
-	return dockerController.CreateDatabase(serviceName, cfg.DatabaseName())
+	// Create the main database (use sanitized name - hyphens replaced with
+	// underscores) and the additional ones declared under `databases`
+	for _, dbName := range cfg.DatabaseNames() {
+		if err := dockerController.CreateDatabase(serviceName, dbName); err != nil {
+			return err
+		}
+
+		// Provision the project's own database user, if configured
+		if cfg.HasCustomDatabaseUser() {
+			if err := dockerController.EnsureDatabaseUser(serviceName, dbName, cfg.DatabaseUser(), cfg.DatabasePassword()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ensureServiceUsers provisions the Redis/Valkey ACL user and RabbitMQ user
+// configured for the project. The shared containers keep serving other
+// projects with their default credentials.
+func (m *Manager) ensureServiceUsers(cfg *config.Config, targets *Targets) error {
+	if testmode.SkipDocker() {
+		return nil
+	}
+
+	dockerController := m.dockerController(m.composeGen.ComposeFilePath())
+
+	if user, password := cfg.RedisCredentials(); user != "" && targets.includes(cfg.Services.GetCacheServiceName()) {
+		serviceName := cfg.Services.GetCacheServiceName()
+		cliBinary := "redis-cli"
+		if cfg.Services.HasValkey() {
+			cliBinary = "valkey-cli"
+		}
+		if err := dockerController.EnsureRedisUser(serviceName, cliBinary, user, password); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Services.HasRabbitMQ() && targets.includes("rabbitmq") {
+		if user, password := cfg.RabbitMQCredentials(); user != config.DefaultRabbitMQUser {
+			if err := dockerController.EnsureRabbitMQUser(user, password); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // getStartedServices returns a list of started service names
 func (m *Manager) getStartedServices(cfg *config.Config) []string {
-	services := []string{
-		fmt.Sprintf("PHP-FPM %s", cfg.PHP),
-		"Nginx",
+	return m.startedServices(cfg, nil)
+}
+
+// startedServices returns the names of the services started for targets
+func (m *Manager) startedServices(cfg *config.Config, targets *Targets) []string {
+	var services []string
+	add := func(composeName, label string) {
+		if targets.includes(composeName) {
+			services = append(services, label)
+		}
+	}
+
+	if targets.Web() {
+		services = append(services, fmt.Sprintf("PHP-FPM %s", cfg.PHP), "Nginx")
 	}
 
 	if cfg.Services.HasMySQL() {
-		services = append(services, fmt.Sprintf("MySQL %s", cfg.Services.MySQL.Version))
+		add("mysql"+strings.ReplaceAll(cfg.Services.MySQL.Version, ".", ""), fmt.Sprintf("MySQL %s", cfg.Services.MySQL.Version))
 	}
 	if cfg.Services.HasMariaDB() {
-		services = append(services, fmt.Sprintf("MariaDB %s", cfg.Services.MariaDB.Version))
+		add("mariadb"+strings.ReplaceAll(cfg.Services.MariaDB.Version, ".", ""), fmt.Sprintf("MariaDB %s", cfg.Services.MariaDB.Version))
+	}
+	if cfg.Services.HasPercona() {
+		add("percona"+strings.ReplaceAll(cfg.Services.Percona.Version, ".", ""), fmt.Sprintf("Percona Server %s", cfg.Services.Percona.Version))
 	}
 	if cfg.Services.HasRedis() {
-		services = append(services, "Redis")
+		add(cfg.Services.GetCacheServiceName(), "Redis")
 	}
 	if cfg.Services.HasOpenSearch() {
-		services = append(services, fmt.Sprintf("OpenSearch %s", cfg.Services.OpenSearch.Version))
+		add("opensearch"+strings.ReplaceAll(cfg.Services.OpenSearch.Version, ".", ""), fmt.Sprintf("OpenSearch %s", cfg.Services.OpenSearch.Version))
+	}
+	if cfg.Services.HasOpenSearchDashboards() {
+		add(docker.OpenSearchDashboardsService(cfg.Services.OpenSearch.Version), "OpenSearch Dashboards")
 	}
 	if cfg.Services.HasElasticsearch() {
-		services = append(services, fmt.Sprintf("Elasticsearch %s", cfg.Services.Elasticsearch.Version))
+		add("elasticsearch"+strings.ReplaceAll(cfg.Services.Elasticsearch.Version, ".", ""), fmt.Sprintf("Elasticsearch %s", cfg.Services.Elasticsearch.Version))
+	}
+	if cfg.Services.HasMeilisearch() {
+		add("meilisearch", "Meilisearch "+cfg.Services.MeilisearchImageTag())
+	}
+	if cfg.Services.HasTypesense() {
+		add("typesense", "Typesense "+cfg.Services.TypesenseImageTag())
 	}
 	if cfg.Services.HasRabbitMQ() {
-		services = append(services, "RabbitMQ")
+		add("rabbitmq", "RabbitMQ")
+	}
+	if cfg.Services.HasComposerMirror() {
+		add("composer-mirror", "Composer mirror")
+	}
+	// Mailpit is enabled for local dev safety; when disabled, mail is captured to var/mail
+	if cfg.Services.MailpitDisabled() {
+		if targets.Web() {
+			services = append(services, "Mail capture (var/mail)")
+		}
+	} else {
+		add("mailpit", "Mailpit")
 	}
-	// Mailpit is always enabled for local dev safety
-	services = append(services, "Mailpit")
 
 	return services
 }
 
 // ConfigPaths contains paths to generated configuration files
 type ConfigPaths struct {
-	ProjectConfig string   // .magebox.yaml
-	PHPFPMPool    string   // ~/.magebox/php/pools/{version}/{project}.conf
-	NginxVhosts   []string // ~/.magebox/nginx/vhosts/{project}-*.conf
+	ProjectConfig string   `json:"project_config"` // .magebox.yaml
+	PHPFPMPool    string   `json:"php_fpm_pool"`   // ~/.magebox/php/pools/{version}/{project}.conf
+	NginxVhosts   []string `json:"nginx_vhosts"`   // ~/.magebox/nginx/vhosts/{project}-*.conf
 }
 
 // ProjectStatus represents the status of a project
 type ProjectStatus struct {
-	Name        string
-	Path        string
-	PHPVersion  string
-	Domains     []string
-	Services    map[string]ServiceStatus
-	ConfigPaths ConfigPaths
+	Name        string                   `json:"name"`
+	Path        string                   `json:"path"`
+	PHPVersion  string                   `json:"php"`
+	MageMode    string                   `json:"mage_mode"`
+	Domains     []string                 `json:"domains"`
+	Services    map[string]ServiceStatus `json:"services"`
+	ConfigPaths ConfigPaths              `json:"config_files"`
+	RedisDBs    *RedisDBs                `json:"redis_dbs,omitempty"` // Databases in the shared cache container, nil before the first start
 }
 
 // ServiceStatus represents the status of a service
 type ServiceStatus struct {
-	Name      string
-	IsRunning bool
-	Port      int
+	Name      string `json:"name"`
+	IsRunning bool   `json:"running"`
+	Port      int    `json:"port,omitempty"`
+	URL       string `json:"url,omitempty"` // Web UI of the service, empty when it has none
+
+	PortConflict bool `json:"port_conflict,omitempty"` // Another program listens on the port of the stopped service
 }
 
 // PHPNotInstalledError indicates PHP is not installed
@@ -599,21 +1027,37 @@ func (e *PHPNotInstalledError) Error() string {
 	return php.FormatNotInstalledMessage(e.Version, e.Platform)
 }
 
-// Init initializes a new .magebox.yaml file in the given directory
-func (m *Manager) Init(projectPath string, projectName string, projectType string, phpVersion string) error {
+// Init initializes a new .magebox.yaml file in the given directory. Services
+// needed by installed modules are enabled and the modules are returned. A tld
+// is written to the config and used for the domain instead of the global TLD.
+func (m *Manager) Init(projectPath string, projectName string, projectType string, phpVersion string, tld string) ([]DetectedModule, error) {
 	configPath := filepath.Join(projectPath, config.ConfigFileName)
 
 	// Check if file already exists
 	if _, err := os.Stat(configPath); err == nil {
-		return fmt.Errorf("%s file already exists", config.ConfigFileName)
+		return nil, fmt.Errorf("%s file already exists", config.ConfigFileName)
 	}
 
 	// Get configured defaults from global config
 	homeDir, _ := os.UserHomeDir()
 	globalCfg, _ := config.LoadGlobalConfig(homeDir)
-	tld := globalCfg.GetTLD()
 	defaults := globalCfg.DefaultServices
 
+	projectTLD := ""
+	if tld != "" {
+		projectTLD = fmt.Sprintf("tld: %s\n", tld)
+	} else {
+		tld = globalCfg.GetTLD()
+	}
+
+	// Enable the services required by installed modules on top of the defaults
+	var added []DetectedModule
+	for _, module := range DetectModules(projectPath) {
+		if module.addTo(&defaults) {
+			added = append(added, module)
+		}
+	}
+
 	// Derive domain from project name
 	domain := projectName + "." + tld
 
@@ -645,19 +1089,22 @@ func (m *Manager) Init(projectPath string, projectName string, projectType strin
 	if projectType == config.ProjectTypeLaravel {
 		content = fmt.Sprintf(`name: %s
 type: laravel
-domains:
+%sdomains:
   - host: %s
 php: "%s"
-%s`, projectName, domain, phpVersion, services.String())
+%s`, projectName, projectTLD, domain, phpVersion, services.String())
 	} else {
 		content = fmt.Sprintf(`name: %s
-domains:
+%sdomains:
   - host: %s
 php: "%s"
-%s`, projectName, domain, phpVersion, services.String())
+%s`, projectName, projectTLD, domain, phpVersion, services.String())
 	}
 
-	return os.WriteFile(configPath, []byte(content), 0644)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		return nil, err
+	}
+	return added, nil
 }
 
 // RegenerateConfigs regenerates PHP-FPM pool and Nginx vhost configs
@@ -668,9 +1115,13 @@ func (m *Manager) RegenerateConfigs(projectPath string) error {
 		return err
 	}
 
-	// Regenerate PHP-FPM pool
-	if err := m.poolGenerator.Generate(cfg.Name, projectPath, cfg.PHP, cfg.Env, cfg.PHPINI, true); err != nil {
-		return fmt.Errorf("failed to regenerate PHP-FPM pool: %w", err)
+	// Regenerate the PHP-FPM pools, missing extensions are reported by start
+	m.poolGenerator.SetVersions(cfg.PHPVersions())
+	for _, version := range cfg.PHPVersions() {
+		_ = m.preparePoolExtensions(cfg, version)
+		if err := m.poolGenerator.Generate(cfg.Name, projectPath, version, cfg.PHPEnv(), cfg.PoolPHPINI(), !cfg.Services.MailpitDisabled()); err != nil {
+			return fmt.Errorf("failed to regenerate PHP-FPM %s pool: %w", version, err)
+		}
 	}
 
 	// Regenerate Nginx vhost
@@ -681,6 +1132,25 @@ func (m *Manager) RegenerateConfigs(projectPath string) error {
 	return nil
 }
 
+// RegenerateDomains generates SSL certificates and Nginx vhosts for all
+// project domains in one pass, with one certificate per base domain
+func (m *Manager) RegenerateDomains(projectPath string) error {
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		return err
+	}
+
+	if err := m.generateSSLCerts(cfg); err != nil {
+		return fmt.Errorf("failed to generate SSL certificates: %w", err)
+	}
+
+	if err := m.vhostGenerator.Generate(cfg, projectPath); err != nil {
+		return fmt.Errorf("failed to regenerate nginx vhost: %w", err)
+	}
+
+	return nil
+}
+
 // ValidateConfig validates a project configuration
 func (m *Manager) ValidateConfig(projectPath string) (*config.Config, []string, error) {
 	cfg, err := config.LoadFromPath(projectPath)
@@ -690,9 +1160,11 @@ func (m *Manager) ValidateConfig(projectPath string) (*config.Config, []string,
 
 	warnings := make([]string, 0)
 
-	// Check if PHP version is installed
-	if !m.phpDetector.IsVersionInstalled(cfg.PHP) {
-		warnings = append(warnings, fmt.Sprintf("PHP %s is not installed", cfg.PHP))
+	// Check if the PHP versions of the project and its domains are installed
+	for _, version := range cfg.PHPVersions() {
+		if !m.phpDetector.IsVersionInstalled(version) {
+			warnings = append(warnings, fmt.Sprintf("PHP %s is not installed", version))
+		}
 	}
 
 	// Check if mkcert is installed for SSL
@@ -703,48 +1175,93 @@ func (m *Manager) ValidateConfig(projectPath string) (*config.Config, []string,
 		}
 	}
 
+	// Check for installed modules that need services the project doesn't enable
+	warnings = append(warnings, moduleWarnings(projectPath, cfg)...)
+
 	return cfg, warnings, nil
 }
 
-// flushRedis flushes all Redis/Valkey databases
-func (m *Manager) flushRedis() error {
+// flushRedis flushes the project's Redis/Valkey databases
+func (m *Manager) flushRedis(cfg *config.Config, projectPath string) error {
 	// Skip in test mode
 	if testmode.SkipDocker() {
 		return nil
 	}
 
+	dbs, err := m.redisDBs(cfg, projectPath)
+	if err != nil {
+		return err
+	}
+
 	composeFile := m.composeGen.ComposeFilePath()
-	dockerController := docker.NewDockerController(composeFile)
+	dockerController := m.dockerController(composeFile)
 
-	// Try Valkey first, then Redis
-	if dockerController.IsServiceRunning("valkey") {
-		return dockerController.ExecSilent("valkey", "valkey-cli", "FLUSHALL")
+	// Only the project's own databases, other projects share the container
+	service := cfg.Services.GetCacheServiceName()
+	if !dockerController.IsServiceRunning(service) {
+		return nil // No cache service running, nothing to flush
 	}
-	if dockerController.IsServiceRunning("redis") {
-		return dockerController.ExecSilent("redis", "redis-cli", "FLUSHALL")
+	for _, db := range dbs.Numbers() {
+		if err := dockerController.ExecSilent(service, service+"-cli", "-n", strconv.Itoa(db), "FLUSHDB"); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return nil // No cache service running, nothing to flush
+// redisDBs returns the Redis/Valkey databases allocated to the project
+func (m *Manager) redisDBs(cfg *config.Config, projectPath string) (RedisDBs, error) {
+	if !cfg.Services.HasCacheService() {
+		return DefaultRedisDBs, nil
+	}
+	return NewRedisDBRegistry(m.platform).Allocate(cfg.Name, projectPath)
+}
+
+// lookupRedisDBs returns the Redis/Valkey databases of the project without
+// allocating any: the registered ones, else those of an existing env.php,
+// else the default ones
+func (m *Manager) lookupRedisDBs(cfg *config.Config, projectPath string) (RedisDBs, error) {
+	if !cfg.Services.HasCacheService() {
+		return DefaultRedisDBs, nil
+	}
+	dbs, ok, err := NewRedisDBRegistry(m.platform).Get(cfg.Name)
+	if err != nil || ok {
+		return dbs, err
+	}
+	if existing, ok := envRedisDBs(filepath.Join(projectPath, "app", "etc", "env.php")); ok {
+		return existing, nil
+	}
+	return DefaultRedisDBs, nil
 }
 
-// ensureEnvPHP generates or updates Magento's app/etc/env.php
-func (m *Manager) ensureEnvPHP(projectPath string, cfg *config.Config) error {
+// ensureEnvPHP generates Magento's app/etc/env.php when it is missing, or
+// regenerates it when SetRegenerateEnvPHP is on. It returns the backup of the
+// replaced file, if any.
+func (m *Manager) ensureEnvPHP(projectPath string, cfg *config.Config) (string, error) {
 	// Check if this is a Magento project (has app/etc directory)
 	appEtcDir := filepath.Join(projectPath, "app", "etc")
 	if _, err := os.Stat(appEtcDir); os.IsNotExist(err) {
-		return nil // Not a Magento project, skip
+		return "", nil // Not a Magento project, skip
 	}
 
 	// Check if env.php already exists - don't overwrite existing config
 	envPath := filepath.Join(appEtcDir, "env.php")
 	if _, err := os.Stat(envPath); err == nil {
-		// env.php exists, don't overwrite (user may have customizations)
-		return nil
+		if !m.regenerateEnvPHP {
+			// env.php exists, don't overwrite (user may have customizations)
+			return "", nil
+		}
+		return m.writeEnvPHP(projectPath, cfg, true)
 	}
 
 	// Generate new env.php
-	envGen := newEnvGenerator(projectPath, cfg)
-	return envGen.Generate()
+	return m.writeEnvPHP(projectPath, cfg, false)
+}
+
+// SetRegenerateEnvPHP makes start regenerate an existing env.php from the
+// project config, backing it up first
+func (m *Manager) SetRegenerateEnvPHP(enabled bool) {
+	m.regenerateEnvPHP = enabled
 }
 
 // RegenerateEnvPHP overwrites Magento's app/etc/env.php with the MageBox template,
@@ -755,6 +1272,74 @@ func (m *Manager) RegenerateEnvPHP(projectPath string) error {
 		return err
 	}
 
+	_, err = m.writeEnvPHP(projectPath, cfg, false)
+	return err
+}
+
+// GenerateEnvPHP writes app/etc/env.php from the project's services: database,
+// Redis/Valkey, RabbitMQ, OpenSearch/Elasticsearch and Mailpit. The crypt key
+// and cache prefix of an existing file are kept and the file is backed up;
+// the backup path is returned, empty when there was no file.
+func (m *Manager) GenerateEnvPHP(projectPath string) (string, error) {
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	return m.writeEnvPHP(projectPath, cfg, true)
+}
+
+// RenderEnvPHP returns the env.php GenerateEnvPHP would write. It changes
+// nothing, a project without Redis databases yet is shown with the ones of
+// its env.php or the default ones.
+func (m *Manager) RenderEnvPHP(projectPath string) (string, error) {
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	dbs, err := m.lookupRedisDBs(cfg, projectPath)
+	if err != nil {
+		return "", err
+	}
+	return m.newEnvGenerator(projectPath, cfg, dbs).Render()
+}
+
+// writeEnvPHP writes env.php, backing up an existing file when backup is set
+func (m *Manager) writeEnvPHP(projectPath string, cfg *config.Config, backup bool) (string, error) {
+	envGen, err := m.envGenerator(projectPath, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	var backupPath string
+	if backup {
+		if _, err := os.Stat(envGen.envPath()); err == nil {
+			backupPath = fmt.Sprintf("%s.%s.bak", envGen.envPath(), time.Now().Format("20060102-150405"))
+		}
+	}
+	return backupPath, envGen.Generate(backupPath)
+}
+
+// envGenerator returns an env.php generator pointing at the project's Redis
+// databases, allocating them when the project has none
+func (m *Manager) envGenerator(projectPath string, cfg *config.Config) (*envGenerator, error) {
+	dbs, err := m.redisDBs(cfg, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	return m.newEnvGenerator(projectPath, cfg, dbs), nil
+}
+
+// newEnvGenerator returns an env.php generator pointing at dbs
+func (m *Manager) newEnvGenerator(projectPath string, cfg *config.Config, dbs RedisDBs) *envGenerator {
 	envGen := newEnvGenerator(projectPath, cfg)
-	return envGen.Generate()
+	envGen.redisDBs = dbs
+	envGen.ports = docker.LoadPorts(m.platform)
+
+	// The base URLs in a shared database are the parent's
+	if wt, err := NewWorktrees(m.platform).Get(cfg.Name); err == nil && wt != nil && wt.Database != "" && !wt.Cloned {
+		envGen.baseURLs = baseurl.Targets(cfg.Domains)
+	}
+	return envGen
 }
@@ -3,6 +3,7 @@ package project
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -56,7 +57,7 @@ func TestManager_Init(t *testing.T) {
 		t.Fatalf("failed to create project dir: %v", err)
 	}
 
-	err := m.Init(projectPath, "mystore", "magento", "8.2")
+	_, err := m.Init(projectPath, "mystore", "magento", "8.2", "")
 	if err != nil {
 		t.Fatalf("Init failed: %v", err)
 	}
@@ -89,6 +90,31 @@ func TestManager_Init(t *testing.T) {
 	}
 }
 
+func TestManager_InitWithTLD(t *testing.T) {
+	m, tmpDir := setupTestManager(t)
+	t.Setenv("HOME", tmpDir)
+
+	projectPath := filepath.Join(tmpDir, "myproject")
+	if err := os.MkdirAll(projectPath, 0755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+
+	if _, err := m.Init(projectPath, "mystore", "magento", "8.2", "localhost"); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.TLD != "localhost" {
+		t.Errorf("TLD = %q, want localhost", cfg.TLD)
+	}
+	if len(cfg.Domains) != 1 || cfg.Domains[0].Host != "mystore.localhost" {
+		t.Errorf("Domains = %+v, want mystore.localhost", cfg.Domains)
+	}
+}
+
 func TestManager_InitRespectsGlobalDefaults(t *testing.T) {
 	m, tmpDir := setupTestManager(t)
 	// Use temp dir as HOME so we can write a custom global config
@@ -116,7 +142,7 @@ default_services:
 		t.Fatalf("failed to create project dir: %v", err)
 	}
 
-	err := m.Init(projectPath, "mystore", "magento", "8.4")
+	_, err := m.Init(projectPath, "mystore", "magento", "8.4", "")
 	if err != nil {
 		t.Fatalf("Init failed: %v", err)
 	}
@@ -158,12 +184,12 @@ func TestManager_InitAlreadyExists(t *testing.T) {
 	}
 
 	// Create first time
-	if err := m.Init(projectPath, "mystore", "magento", "8.2"); err != nil {
+	if _, err := m.Init(projectPath, "mystore", "magento", "8.2", ""); err != nil {
 		t.Fatalf("Init failed: %v", err)
 	}
 
 	// Try to create again - should fail
-	err := m.Init(projectPath, "mystore", "magento", "8.2")
+	_, err := m.Init(projectPath, "mystore", "magento", "8.2", "")
 	if err == nil {
 		t.Errorf("Init should fail when %s already exists", config.ConfigFileName)
 	}
@@ -385,3 +411,69 @@ services:
 		t.Error("Services should always include Mailpit for local dev safety")
 	}
 }
+
+func TestExclusiveServices(t *testing.T) {
+	keep := &config.Config{Name: "focus", Services: config.Services{
+		MySQL: &config.ServiceConfig{Enabled: true, Version: "8.0"},
+		Redis: &config.ServiceConfig{Enabled: true},
+	}}
+	stopped := []*config.Config{
+		{Name: "legacy", Services: config.Services{
+			MySQL:      &config.ServiceConfig{Enabled: true, Version: "5.7"},
+			Redis:      &config.ServiceConfig{Enabled: true},
+			OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+		}},
+		{Name: "other", Services: config.Services{
+			MySQL:      &config.ServiceConfig{Enabled: true, Version: "8.0"},
+			OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+			RabbitMQ:   &config.ServiceConfig{Enabled: true},
+		}},
+	}
+
+	got := ExclusiveServices(stopped, []*config.Config{keep})
+	want := []string{"mysql57", "opensearch219", "rabbitmq"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExclusiveServices() = %v, want %v", got, want)
+	}
+
+	if got := ExclusiveServices(nil, []*config.Config{keep}); len(got) != 0 {
+		t.Errorf("ExclusiveServices() without stopped projects = %v", got)
+	}
+}
+
+func TestManager_RenderEnvPHPDoesNotAllocate(t *testing.T) {
+	m, tmpDir := setupTestManager(t)
+
+	projectPath := filepath.Join(tmpDir, "myproject")
+	if err := os.MkdirAll(projectPath, 0755); err != nil {
+		t.Fatalf("failed to create project dir: %v", err)
+	}
+	configContent := `name: mystore
+domains:
+  - host: mystore.test
+php: "8.2"
+services:
+  mysql: "8.0"
+  redis: true
+`
+	if err := os.WriteFile(filepath.Join(projectPath, config.ConfigFileName), []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	envPath := writeRedisEnvPHP(t, projectPath, 6, 7, 8)
+	before, _ := os.ReadFile(envPath)
+
+	content, err := m.RenderEnvPHP(projectPath)
+	if err != nil {
+		t.Fatalf("RenderEnvPHP() error = %v", err)
+	}
+	if !strings.Contains(content, "'database' => '6'") {
+		t.Errorf("rendered env.php should keep the databases of the existing one:\n%s", content)
+	}
+
+	if _, ok, _ := NewRedisDBRegistry(m.platform).Get("mystore"); ok {
+		t.Error("RenderEnvPHP allocated Redis databases")
+	}
+	if after, _ := os.ReadFile(envPath); string(after) != string(before) {
+		t.Error("RenderEnvPHP changed env.php")
+	}
+}
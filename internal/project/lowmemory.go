@@ -0,0 +1,47 @@
+package project
+
+import (
+	"qoliber/magebox/internal/config"
+)
+
+// SetLowMemory enables the low-memory profile for the services the manager
+// starts: small PHP-FPM pools, 512m search heaps, a shrunk database buffer
+// pool, and no RabbitMQ or Mailpit unless a project needs them
+func (m *Manager) SetLowMemory(enabled bool) {
+	m.lowMemory = enabled
+	m.poolGenerator.SetLowMemory(enabled)
+	m.composeGen.SetLowMemory(enabled)
+}
+
+// applyLowMemory drops the optional services of cfg for the low-memory
+// profile and returns a note for each one. Only the loaded config changes,
+// .magebox.yaml is left alone.
+//
+// RabbitMQ is kept when an installed module needs it, Mailpit when the
+// project enables it explicitly. Without Mailpit, mail is captured to
+// var/mail.
+func applyLowMemory(cfg *config.Config, projectPath string) []string {
+	var notes []string
+
+	if cfg.Services.HasRabbitMQ() && !needsRabbitMQ(projectPath) {
+		cfg.Services.RabbitMQ = nil
+		notes = append(notes, "Low-memory mode: RabbitMQ skipped, no installed module needs it")
+	}
+
+	if !cfg.Services.HasMailpit() && !cfg.Services.MailpitDisabled() {
+		cfg.Services.Mailpit = &config.ServiceConfig{Enabled: false}
+		notes = append(notes, "Low-memory mode: Mailpit skipped, mail is written to var/mail")
+	}
+
+	return notes
+}
+
+// needsRabbitMQ reports whether an installed module requires RabbitMQ
+func needsRabbitMQ(projectPath string) bool {
+	for _, m := range DetectModules(projectPath) {
+		if m.Service == RequiredRabbitMQ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package project
+
+import (
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func TestApplyLowMemory(t *testing.T) {
+	tests := []struct {
+		name         string
+		composer     string
+		mailpit      *config.ServiceConfig
+		wantRabbitMQ bool
+		wantMailpit  bool
+		wantNotes    int
+	}{
+		{
+			name:     "optional services dropped",
+			composer: `{"require": {"magento/product-community-edition": "2.4.7"}}`,
+			// Mailpit is on by default but not asked for explicitly
+			wantNotes: 2,
+		},
+		{
+			name:         "rabbitmq kept for B2B",
+			composer:     `{"require": {"magento/extension-b2b": "1.5.0"}}`,
+			wantRabbitMQ: true,
+			wantNotes:    1,
+		},
+		{
+			name:        "explicit mailpit kept",
+			composer:    `{"require": {}}`,
+			mailpit:     &config.ServiceConfig{Enabled: true},
+			wantMailpit: true,
+			wantNotes:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeProjectFile(t, dir, "composer.json", tt.composer)
+			cfg := &config.Config{Services: config.Services{
+				MySQL:    &config.ServiceConfig{Enabled: true, Version: "8.0"},
+				RabbitMQ: &config.ServiceConfig{Enabled: true},
+				Mailpit:  tt.mailpit,
+			}}
+
+			notes := applyLowMemory(cfg, dir)
+			if len(notes) != tt.wantNotes {
+				t.Errorf("notes = %q, want %d", notes, tt.wantNotes)
+			}
+			if cfg.Services.HasRabbitMQ() != tt.wantRabbitMQ {
+				t.Errorf("HasRabbitMQ() = %v, want %v", cfg.Services.HasRabbitMQ(), tt.wantRabbitMQ)
+			}
+			if cfg.Services.MailpitDisabled() == tt.wantMailpit {
+				t.Errorf("MailpitDisabled() = %v, want %v", cfg.Services.MailpitDisabled(), !tt.wantMailpit)
+			}
+			if !cfg.Services.HasMySQL() {
+				t.Error("low-memory mode should keep the database")
+			}
+		})
+	}
+}
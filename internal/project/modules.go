@@ -0,0 +1,232 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+)
+
+// Services that Magento modules can require
+const (
+	RequiredSearch   = "search"
+	RequiredRabbitMQ = "rabbitmq"
+)
+
+// moduleSearchVersion is the OpenSearch version added for modules that need a
+// search engine, matching the 'magebox new' default
+const moduleSearchVersion = "2.19.4"
+
+// ModuleRequirement is a known Magento module and the service it needs
+type ModuleRequirement struct {
+	// Name is the display name of the module
+	Name string
+	// Packages are composer package names or vendor prefixes ending in "/"
+	Packages []string
+	// Modules are Magento module names or prefixes ending in "_"
+	Modules []string
+	// Service is one of the Required* constants
+	Service string
+	// Reason completes the sentence "<Name> ..." explaining the requirement
+	Reason string
+}
+
+// KnownModuleRequirements lists modules that only work with extra services
+var KnownModuleRequirements = []ModuleRequirement{
+	{
+		Name:     "ElasticSuite",
+		Packages: []string{"smile/elasticsuite"},
+		Modules:  []string{"Smile_ElasticsuiteCore"},
+		Service:  RequiredSearch,
+		Reason:   "indexes the catalog in OpenSearch/Elasticsearch (analysis-icu and analysis-phonetic are installed automatically)",
+	},
+	{
+		Name:     "Algolia",
+		Packages: []string{"algolia/algoliasearch-magento-2"},
+		Modules:  []string{"Algolia_AlgoliaSearch"},
+		Service:  RequiredSearch,
+		Reason:   "replaces storefront search only, Magento's catalog indexers still need a local search engine",
+	},
+	{
+		Name:     "Klevu",
+		Packages: []string{"klevu/"},
+		Modules:  []string{"Klevu_"},
+		Service:  RequiredSearch,
+		Reason:   "replaces storefront search only, Magento's catalog indexers still need a local search engine",
+	},
+	{
+		Name:     "Magento B2B",
+		Packages: []string{"magento/extension-b2b", "magento/module-shared-catalog", "magento/module-negotiable-quote", "magento/module-company"},
+		Modules:  []string{"Magento_SharedCatalog", "Magento_NegotiableQuote", "Magento_Company"},
+		Service:  RequiredRabbitMQ,
+		Reason:   "processes shared catalog, quote and company updates with message queue consumers",
+	},
+}
+
+// DetectedModule is a module found in the project that needs a service
+type DetectedModule struct {
+	ModuleRequirement
+	// Source is the file the module was found in
+	Source string
+}
+
+// configModuleRe matches enabled modules in app/etc/config.php
+var configModuleRe = regexp.MustCompile(`'([A-Za-z0-9]+_[A-Za-z0-9]+)'\s*=>\s*1`)
+
+// DetectModules scans composer.json, composer.lock and app/etc/config.php for
+// modules that need extra services
+func DetectModules(projectPath string) []DetectedModule {
+	packages := make(map[string]string)
+	for _, name := range []string{"composer.json", "composer.lock"} {
+		for _, pkg := range composerPackages(filepath.Join(projectPath, name)) {
+			if _, ok := packages[pkg]; !ok {
+				packages[pkg] = name
+			}
+		}
+	}
+
+	modules := make(map[string]bool)
+	if data, err := os.ReadFile(filepath.Join(projectPath, "app", "etc", "config.php")); err == nil {
+		for _, m := range configModuleRe.FindAllStringSubmatch(string(data), -1) {
+			modules[m[1]] = true
+		}
+	}
+
+	var detected []DetectedModule
+	for _, req := range KnownModuleRequirements {
+		if source := req.match(packages, modules); source != "" {
+			detected = append(detected, DetectedModule{ModuleRequirement: req, Source: source})
+		}
+	}
+	return detected
+}
+
+// match returns where the module was found, or "" if it is not installed
+func (r ModuleRequirement) match(packages map[string]string, modules map[string]bool) string {
+	for _, want := range r.Modules {
+		for module := range modules {
+			if module == want || (strings.HasSuffix(want, "_") && strings.HasPrefix(module, want)) {
+				return "app/etc/config.php"
+			}
+		}
+	}
+
+	// Check packages in a stable order so the reported source is deterministic
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, want := range r.Packages {
+		for _, name := range names {
+			if name == want || (strings.HasSuffix(want, "/") && strings.HasPrefix(name, want)) {
+				return packages[name]
+			}
+		}
+	}
+	return ""
+}
+
+// composerPackages returns the required packages of composer.json or the
+// installed packages of composer.lock
+func composerPackages(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var composer struct {
+		Require    map[string]interface{} `json:"require"`
+		RequireDev map[string]interface{} `json:"require-dev"`
+		Packages   []struct {
+			Name string `json:"name"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &composer); err != nil {
+		return nil
+	}
+
+	var names []string
+	for name := range composer.Require {
+		names = append(names, name)
+	}
+	for name := range composer.RequireDev {
+		names = append(names, name)
+	}
+	for _, pkg := range composer.Packages {
+		names = append(names, pkg.Name)
+	}
+	return names
+}
+
+// SatisfiedBy reports whether the project services provide what the module needs
+func (r ModuleRequirement) SatisfiedBy(s *config.Services) bool {
+	switch r.Service {
+	case RequiredSearch:
+		return s.HasOpenSearch() || s.HasElasticsearch()
+	case RequiredRabbitMQ:
+		return s.HasRabbitMQ()
+	}
+	return true
+}
+
+// addTo enables the required service in d, reporting whether it was missing
+func (r ModuleRequirement) addTo(d *config.DefaultServices) bool {
+	switch r.Service {
+	case RequiredSearch:
+		if d.OpenSearch != "" || d.Elasticsearch != "" {
+			return false
+		}
+		d.OpenSearch = moduleSearchVersion
+	case RequiredRabbitMQ:
+		if d.RabbitMQ {
+			return false
+		}
+		d.RabbitMQ = true
+	default:
+		return false
+	}
+	return true
+}
+
+// ServiceLabel returns a human-readable name of the required service
+func (r ModuleRequirement) ServiceLabel() string {
+	switch r.Service {
+	case RequiredSearch:
+		return "a search engine"
+	case RequiredRabbitMQ:
+		return "RabbitMQ"
+	}
+	return r.Service
+}
+
+// ServiceSuggestion returns the .magebox.yaml services entry that satisfies
+// the module
+func (r ModuleRequirement) ServiceSuggestion() string {
+	switch r.Service {
+	case RequiredSearch:
+		return fmt.Sprintf("opensearch: %q", moduleSearchVersion)
+	case RequiredRabbitMQ:
+		return "rabbitmq: true"
+	}
+	return ""
+}
+
+// moduleWarnings returns a warning for every detected module whose service
+// the project does not enable
+func moduleWarnings(projectPath string, cfg *config.Config) []string {
+	var warnings []string
+	for _, m := range DetectModules(projectPath) {
+		if m.SatisfiedBy(&cfg.Services) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s (found in %s) needs %s: it %s. Add '%s' under services in %s",
+			m.Name, m.Source, m.ServiceLabel(), m.Reason, m.ServiceSuggestion(), config.ConfigFileName))
+	}
+	return warnings
+}
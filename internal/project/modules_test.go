@@ -0,0 +1,126 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func writeProjectFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func detectedNames(modules []DetectedModule) []string {
+	var names []string
+	for _, m := range modules {
+		names = append(names, m.Name+"@"+m.Source)
+	}
+	return names
+}
+
+func TestDetectModules(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "nothing installed",
+			files: map[string]string{
+				"composer.json": `{"require": {"magento/product-community-edition": "2.4.7"}}`,
+			},
+		},
+		{
+			name: "composer.json require",
+			files: map[string]string{
+				"composer.json": `{"require": {"smile/elasticsuite": "^2.11", "klevu/module-m2-search": "*"}}`,
+			},
+			want: []string{"ElasticSuite@composer.json", "Klevu@composer.json"},
+		},
+		{
+			name: "composer.lock package",
+			files: map[string]string{
+				"composer.json": `{"require": {"magento/extension-b2b": "^1.4"}}`,
+				"composer.lock": `{"packages": [{"name": "magento/module-shared-catalog"}, {"name": "algolia/algoliasearch-magento-2"}]}`,
+			},
+			want: []string{"Algolia@composer.lock", "Magento B2B@composer.json"},
+		},
+		{
+			name: "config.php modules",
+			files: map[string]string{
+				"app/etc/config.php": `<?php
+return [
+    'modules' => [
+        'Magento_Store' => 1,
+        'Smile_ElasticsuiteCore' => 1,
+        'Magento_SharedCatalog' => 0,
+    ],
+];`,
+			},
+			want: []string{"ElasticSuite@app/etc/config.php"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeProjectFile(t, dir, name, content)
+			}
+			got := detectedNames(DetectModules(dir))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("DetectModules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "composer.json", `{"require": {"smile/elasticsuite": "*", "magento/extension-b2b": "*"}}`)
+
+	cfg := &config.Config{Services: config.Services{
+		OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+	}}
+	warnings := moduleWarnings(dir, cfg)
+	if len(warnings) != 1 {
+		t.Fatalf("moduleWarnings() = %v, want one warning for B2B", warnings)
+	}
+	if !strings.Contains(warnings[0], "Magento B2B") || !strings.Contains(warnings[0], "rabbitmq: true") {
+		t.Errorf("warning = %q", warnings[0])
+	}
+}
+
+func TestManager_InitEnablesModuleServices(t *testing.T) {
+	m, tmpDir := setupTestManager(t)
+	t.Setenv("HOME", tmpDir)
+
+	projectPath := filepath.Join(tmpDir, "myproject")
+	writeProjectFile(t, projectPath, "composer.json", `{"require": {"smile/elasticsuite": "*", "magento/extension-b2b": "*"}}`)
+
+	added, err := m.Init(projectPath, "mystore", "magento", "8.3", "")
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if len(added) != 2 {
+		t.Errorf("Init() added %v, want ElasticSuite and B2B", detectedNames(added))
+	}
+
+	cfg, err := config.LoadFromPath(projectPath)
+	if err != nil {
+		t.Fatalf("failed to load generated config: %v", err)
+	}
+	if !cfg.Services.HasOpenSearch() || !cfg.Services.HasRabbitMQ() {
+		t.Errorf("generated config should enable OpenSearch and RabbitMQ, got %+v", cfg.Services)
+	}
+}
@@ -0,0 +1,114 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/fileutil"
+	"qoliber/magebox/internal/platform"
+)
+
+// UpdateEnvPHPPorts points the env.php of the known projects using a moved
+// service at the service's new port. It returns the projects whose env.php
+// changed.
+func UpdateEnvPHPPorts(p *platform.Platform, moves []docker.PortMove) ([]string, error) {
+	return updateEnvPHPPorts(moves, knownProjectPaths(p))
+}
+
+// knownProjectPaths returns the directories of the running and stopped
+// projects, keyed by name
+func knownProjectPaths(p *platform.Platform) map[string]string {
+	paths := make(map[string]string)
+	projects, err := NewProjectDiscovery(p).AllProjects()
+	if err != nil {
+		return paths
+	}
+	for _, proj := range projects {
+		paths[proj.Name] = proj.Path
+	}
+	return paths
+}
+
+// updateEnvPHPPorts changes the old port of every move to the new one in the
+// env.php of the projects using the service. Only the database host, the
+// search server port and the Redis, AMQP and Mailpit SMTP ports change, and
+// only where they use the old port.
+func updateEnvPHPPorts(moves []docker.PortMove, projectPaths map[string]string) ([]string, error) {
+	updated := make(map[string]bool)
+	var errs []string
+	for _, move := range moves {
+		pattern := envPortPattern(move)
+		if pattern == nil {
+			continue
+		}
+		for _, name := range move.Projects {
+			path, ok := projectPaths[name]
+			if !ok {
+				continue
+			}
+			changed, err := replaceEnvPHPPort(filepath.Join(path, "app", "etc", "env.php"), pattern, move.To)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			if changed {
+				updated[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(updated))
+	for name := range updated {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(errs) > 0 {
+		return names, fmt.Errorf("failed to update env.php of %s", strings.Join(errs, ", "))
+	}
+	return names, nil
+}
+
+// envPortPattern matches the env.php setting holding the old port of a moved
+// service, the port being the second of three groups. It returns nil for the
+// web UI ports env.php doesn't use.
+func envPortPattern(move docker.PortMove) *regexp.Regexp {
+	from := strconv.Itoa(move.From)
+	switch move.Service {
+	case docker.CachePortName, docker.RabbitMQPortName, docker.MailpitSMTPPortName:
+		return regexp.MustCompile(`('port'\s*=>\s*'?)(` + from + `)([',\s])`)
+	case docker.RabbitMQManagementPortName, docker.MailpitWebPortName:
+		return nil
+	}
+	if strings.HasPrefix(move.Service, "opensearch") || strings.HasPrefix(move.Service, "elasticsearch") {
+		return regexp.MustCompile(`('[a-z0-9]+_server_port'\s*=>\s*'?)(` + from + `)([',\s])`)
+	}
+	return regexp.MustCompile(`('host'\s*=>\s*'[^':]+:)(` + from + `)(')`)
+}
+
+// replaceEnvPHPPort replaces the port matched by pattern in an env.php. A
+// missing env.php is left alone.
+func replaceEnvPHPPort(envPath string, pattern *regexp.Regexp, port int) (bool, error) {
+	data, err := os.ReadFile(envPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(envPath)
+	if err != nil {
+		return false, err
+	}
+
+	out := pattern.ReplaceAll(data, []byte("${1}"+strconv.Itoa(port)+"${3}"))
+	if string(out) == string(data) {
+		return false, nil
+	}
+	return true, fileutil.WriteFileAtomic(envPath, out, info.Mode().Perm())
+}
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/docker"
+)
+
+func TestUpdateEnvPHPPorts(t *testing.T) {
+	shopPath, blogPath := t.TempDir(), t.TempDir()
+	env := `<?php
+return [
+    'db' => [
+        'connection' => [
+            'default' => [
+                'host' => '127.0.0.1:33080',
+            ],
+            'checkout' => [
+                'host' => '127.0.0.1:33080',
+            ]
+        ]
+    ],
+    'session' => [
+        'redis' => [
+            'port' => '6379',
+        ]
+    ],
+    'queue' => [
+        'amqp' => [
+            'port' => '5672',
+        ]
+    ],
+    'system' => [
+        'default' => [
+            'catalog' => [
+                'search' => [
+                    'opensearch_server_port' => '9219',
+                ]
+            ]
+        ]
+    ]
+];
+`
+	for _, path := range []string{shopPath, blogPath} {
+		if err := os.MkdirAll(filepath.Join(path, "app", "etc"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "app", "etc", "env.php"), []byte(env), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moves := []docker.PortMove{
+		{Service: "mysql80", From: 33080, To: 34000, Projects: []string{"shop"}},
+		{Service: "opensearch219", From: 9219, To: 34001, Projects: []string{"shop", "gone"}},
+		{Service: docker.CachePortName, From: 6379, To: 34002, Projects: []string{"shop"}},
+		{Service: docker.MailpitWebPortName, From: 8025, To: 34003, Projects: []string{"shop", "blog"}},
+	}
+	updated, err := updateEnvPHPPorts(moves, map[string]string{"shop": shopPath, "blog": blogPath})
+	if err != nil {
+		t.Fatalf("updateEnvPHPPorts() error = %v", err)
+	}
+	if strings.Join(updated, ",") != "shop" {
+		t.Errorf("updated = %v, want shop", updated)
+	}
+
+	shopEnv, _ := os.ReadFile(filepath.Join(shopPath, "app", "etc", "env.php"))
+	for _, want := range []string{`'host' => '127.0.0.1:34000'`, `'opensearch_server_port' => '34001'`, `'port' => '34002'`, `'port' => '5672'`} {
+		if !strings.Contains(string(shopEnv), want) {
+			t.Errorf("shop env.php should contain %s:\n%s", want, shopEnv)
+		}
+	}
+	if strings.Contains(string(shopEnv), "33080") {
+		t.Errorf("shop env.php still uses 33080:\n%s", shopEnv)
+	}
+	if info, err := os.Stat(filepath.Join(shopPath, "app", "etc", "env.php")); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("env.php mode changed: %v", err)
+	}
+
+	blogEnv, _ := os.ReadFile(filepath.Join(blogPath, "app", "etc", "env.php"))
+	if string(blogEnv) != env {
+		t.Errorf("blog doesn't use the moved services, its env.php changed:\n%s", blogEnv)
+	}
+}
@@ -0,0 +1,283 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/fileutil"
+	"qoliber/magebox/internal/platform"
+)
+
+// redisDBsPerProject is the number of databases a project uses: cache, page
+// cache and sessions
+const redisDBsPerProject = 3
+
+// RedisDBs are the databases of a project in the shared Redis/Valkey container
+type RedisDBs struct {
+	Cache     int    `json:"cache"`
+	PageCache int    `json:"page_cache"`
+	Session   int    `json:"session"`
+	Path      string `json:"path,omitempty"`
+}
+
+// DefaultRedisDBs are the databases Magento uses by default, and the first
+// block handed out
+var DefaultRedisDBs = redisDBBlock(0)
+
+// redisDBBlock returns the databases of the n-th block
+func redisDBBlock(n int) RedisDBs {
+	first := n * redisDBsPerProject
+	return RedisDBs{Cache: first, PageCache: first + 1, Session: first + 2}
+}
+
+// Numbers returns the database numbers in the order cache, page cache, session
+func (d RedisDBs) Numbers() []int {
+	return []int{d.Cache, d.PageCache, d.Session}
+}
+
+// RedisDBRegistry hands every project its own block of databases in the
+// shared Redis/Valkey container, so projects don't read or flush each other's
+// cache and sessions
+type RedisDBRegistry struct {
+	registryPath string
+}
+
+// NewRedisDBRegistry creates a Redis database registry
+func NewRedisDBRegistry(p *platform.Platform) *RedisDBRegistry {
+	return &RedisDBRegistry{
+		registryPath: filepath.Join(p.MageBoxDir(), "redis-databases.json"),
+	}
+}
+
+// Load loads the registry from disk
+func (r *RedisDBRegistry) Load() (map[string]RedisDBs, error) {
+	projects := make(map[string]RedisDBs)
+
+	data, err := os.ReadFile(r.registryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return projects, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// Save saves the registry to disk
+func (r *RedisDBRegistry) Save(projects map[string]RedisDBs) error {
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.registryPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.registryPath, data, 0644)
+}
+
+// Get returns the databases of a project, false when none are allocated
+func (r *RedisDBRegistry) Get(projectName string) (RedisDBs, bool, error) {
+	projects, err := r.Load()
+	if err != nil {
+		return RedisDBs{}, false, err
+	}
+
+	dbs, ok := projects[projectName]
+	return dbs, ok, nil
+}
+
+// Patterns for the Redis databases of an existing env.php: cache, page cache
+// and sessions
+var (
+	envRedisCacheDBPattern     = regexp.MustCompile(`'default'\s*=>\s*\[[^\]]*?'backend_options'\s*=>\s*\[[^\]]*?'database'\s*=>\s*'?(\d+)`)
+	envRedisPageCacheDBPattern = regexp.MustCompile(`'page_cache'\s*=>\s*\[[^\]]*?'backend_options'\s*=>\s*\[[^\]]*?'database'\s*=>\s*'?(\d+)`)
+	envRedisSessionDBPattern   = regexp.MustCompile(`'session'\s*=>\s*\[[^\]]*?'redis'\s*=>\s*\[[^\]]*?'database'\s*=>\s*'?(\d+)`)
+)
+
+// envRedisDBPatterns are the env.php patterns in the order of Numbers
+var envRedisDBPatterns = []*regexp.Regexp{envRedisCacheDBPattern, envRedisPageCacheDBPattern, envRedisSessionDBPattern}
+
+// Allocate returns the databases of a project, giving it a block when it has
+// none. A project whose env.php already uses a free block keeps it, otherwise
+// it gets the first free block and the databases in its env.php are changed
+// to match. When every block is taken, blocks of projects whose directory is
+// gone are reused.
+func (r *RedisDBRegistry) Allocate(projectName, projectPath string) (RedisDBs, error) {
+	projects, err := r.Load()
+	if err != nil {
+		return RedisDBs{}, err
+	}
+
+	if dbs, ok := projects[projectName]; ok {
+		if dbs.Path != projectPath {
+			dbs.Path = projectPath
+			projects[projectName] = dbs
+			if err := r.Save(projects); err != nil {
+				return RedisDBs{}, err
+			}
+		}
+		return dbs, nil
+	}
+
+	envPath := filepath.Join(projectPath, "app", "etc", "env.php")
+	existing, hasEnv := envRedisDBs(envPath)
+	if hasEnv && redisDBBlockFree(projects, existing) {
+		existing.Path = projectPath
+		projects[projectName] = existing
+		if err := r.Save(projects); err != nil {
+			return RedisDBs{}, err
+		}
+		return existing, nil
+	}
+
+	block, ok := freeRedisDBBlock(projects)
+	if !ok {
+		pruneRedisDBs(projects)
+		if block, ok = freeRedisDBBlock(projects); !ok {
+			return RedisDBs{}, fmt.Errorf("all %d Redis database blocks are in use, release one with 'magebox redis release <project>'", docker.RedisDatabases/redisDBsPerProject)
+		}
+	}
+
+	dbs := redisDBBlock(block)
+	dbs.Path = projectPath
+	if hasEnv {
+		if err := rewriteEnvRedisDBs(envPath, dbs); err != nil {
+			return RedisDBs{}, fmt.Errorf("failed to update the Redis databases in env.php: %w", err)
+		}
+	}
+	projects[projectName] = dbs
+	if err := r.Save(projects); err != nil {
+		return RedisDBs{}, err
+	}
+	return dbs, nil
+}
+
+// envRedisDBs returns the Redis databases an existing env.php uses, false
+// when it has none or doesn't use Redis for all three
+func envRedisDBs(envPath string) (RedisDBs, bool) {
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		return RedisDBs{}, false
+	}
+
+	var numbers []int
+	for _, pattern := range envRedisDBPatterns {
+		m := pattern.FindSubmatch(data)
+		if m == nil {
+			return RedisDBs{}, false
+		}
+		n, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			return RedisDBs{}, false
+		}
+		numbers = append(numbers, n)
+	}
+	return RedisDBs{Cache: numbers[0], PageCache: numbers[1], Session: numbers[2]}, true
+}
+
+// rewriteEnvRedisDBs changes the Redis databases of an existing env.php to
+// dbs, leaving the rest of the file as it is
+func rewriteEnvRedisDBs(envPath string, dbs RedisDBs) error {
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(envPath)
+	if err != nil {
+		return err
+	}
+
+	for i, db := range dbs.Numbers() {
+		loc := envRedisDBPatterns[i].FindSubmatchIndex(data)
+		if loc == nil {
+			return fmt.Errorf("no Redis database found in %s", envPath)
+		}
+		out := append([]byte{}, data[:loc[2]]...)
+		out = append(out, strconv.Itoa(db)...)
+		data = append(out, data[loc[3]:]...)
+	}
+	return fileutil.WriteFileAtomic(envPath, data, info.Mode().Perm())
+}
+
+// redisDBBlockFree reports whether dbs are a whole block no project uses
+func redisDBBlockFree(projects map[string]RedisDBs, dbs RedisDBs) bool {
+	block := dbs.Cache / redisDBsPerProject
+	if block >= docker.RedisDatabases/redisDBsPerProject || dbs != redisDBBlock(block) {
+		return false
+	}
+	for _, used := range projects {
+		if used.Cache/redisDBsPerProject == block {
+			return false
+		}
+	}
+	return true
+}
+
+// Release frees the databases of a project
+func (r *RedisDBRegistry) Release(projectName string) error {
+	projects, err := r.Load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := projects[projectName]; !ok {
+		return nil
+	}
+	delete(projects, projectName)
+	return r.Save(projects)
+}
+
+// List returns the allocated projects sorted by their first database
+func (r *RedisDBRegistry) List() ([]string, map[string]RedisDBs, error) {
+	projects, err := r.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return projects[names[i]].Cache < projects[names[j]].Cache
+	})
+	return names, projects, nil
+}
+
+// freeRedisDBBlock returns the lowest block no project uses
+func freeRedisDBBlock(projects map[string]RedisDBs) (int, bool) {
+	used := make(map[int]bool, len(projects))
+	for _, dbs := range projects {
+		used[dbs.Cache/redisDBsPerProject] = true
+	}
+	for block := 0; block < docker.RedisDatabases/redisDBsPerProject; block++ {
+		if !used[block] {
+			return block, true
+		}
+	}
+	return 0, false
+}
+
+// pruneRedisDBs drops projects whose directory no longer exists
+func pruneRedisDBs(projects map[string]RedisDBs) {
+	for name, dbs := range projects {
+		if dbs.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(dbs.Path); os.IsNotExist(err) {
+			delete(projects, name)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/platform"
+)
+
+func TestRedisDBRegistry_Allocate(t *testing.T) {
+	p := &platform.Platform{Type: platform.Linux, HomeDir: t.TempDir()}
+	registry := NewRedisDBRegistry(p)
+	shopPath, b2bPath := t.TempDir(), t.TempDir()
+
+	first, err := registry.Allocate("shop", shopPath)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if first.Cache != 0 || first.PageCache != 1 || first.Session != 2 {
+		t.Errorf("first project got %+v, want Magento's default databases", first)
+	}
+
+	second, err := registry.Allocate("b2b", b2bPath)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if second.Cache != 3 || second.PageCache != 4 || second.Session != 5 {
+		t.Errorf("second project got %+v, want 3/4/5", second)
+	}
+
+	again, err := registry.Allocate("shop", shopPath)
+	if err != nil || again.Cache != 0 {
+		t.Errorf("Allocate() again = %+v, %v, want the same block", again, err)
+	}
+
+	if err := registry.Release("shop"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	third, err := registry.Allocate("admin", t.TempDir())
+	if err != nil || third.Cache != 0 {
+		t.Errorf("Allocate() after release = %+v, %v, want the freed block", third, err)
+	}
+}
+
+func TestRedisDBRegistry_AllocateReusesBlocksOfRemovedProjects(t *testing.T) {
+	p := &platform.Platform{Type: platform.Linux, HomeDir: t.TempDir()}
+	registry := NewRedisDBRegistry(p)
+
+	// Fill every block, block 7 belongs to a project that was deleted
+	projects := make(map[string]RedisDBs)
+	for block := 0; block < docker.RedisDatabases/redisDBsPerProject; block++ {
+		dbs := redisDBBlock(block)
+		dbs.Path = t.TempDir()
+		if block == 7 {
+			dbs.Path = filepath.Join(dbs.Path, "deleted")
+		}
+		projects[fmt.Sprintf("project%d", block)] = dbs
+	}
+	if err := registry.Save(projects); err != nil {
+		t.Fatal(err)
+	}
+
+	dbs, err := registry.Allocate("new", t.TempDir())
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if dbs.Cache != 21 {
+		t.Errorf("Allocate() = %+v, want the block of the removed project", dbs)
+	}
+}
+
+// writeRedisEnvPHP writes an env.php using the given Redis databases
+func writeRedisEnvPHP(t *testing.T, projectPath string, cache, pageCache, session int) string {
+	t.Helper()
+	envPath := filepath.Join(projectPath, "app", "etc", "env.php")
+	if err := os.MkdirAll(filepath.Dir(envPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf(`<?php
+return [
+    'session' => [
+        'save' => 'redis',
+        'redis' => [
+            'host' => '127.0.0.1',
+            'database' => '%d',
+        ]
+    ],
+    'db' => [
+        'connection' => [
+            'default' => [
+                'host' => '127.0.0.1:33080',
+            ]
+        ]
+    ],
+    'cache' => [
+        'frontend' => [
+            'default' => [
+                'backend_options' => [
+                    'server' => '127.0.0.1',
+                    'database' => '%d',
+                ]
+            ],
+            'page_cache' => [
+                'backend_options' => [
+                    'server' => '127.0.0.1',
+                    'database' => '%d',
+                ]
+            ]
+        ]
+    ]
+];
+`, session, cache, pageCache)
+	if err := os.WriteFile(envPath, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return envPath
+}
+
+func TestRedisDBRegistry_AllocateKeepsEnvPHPDatabases(t *testing.T) {
+	p := &platform.Platform{Type: platform.Linux, HomeDir: t.TempDir()}
+	registry := NewRedisDBRegistry(p)
+
+	// Set up before MageBox allocated databases, both use the same block
+	b2bPath, shopPath := t.TempDir(), t.TempDir()
+	writeRedisEnvPHP(t, b2bPath, 6, 7, 8)
+	shopEnv := writeRedisEnvPHP(t, shopPath, 6, 7, 8)
+
+	b2b, err := registry.Allocate("b2b", b2bPath)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if b2b.Cache != 6 || b2b.PageCache != 7 || b2b.Session != 8 {
+		t.Errorf("b2b got %+v, want the 6/7/8 its env.php uses", b2b)
+	}
+
+	shop, err := registry.Allocate("shop", shopPath)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if shop.Cache != 0 || shop.PageCache != 1 || shop.Session != 2 {
+		t.Errorf("shop got %+v, want the first free block", shop)
+	}
+	if got, ok := envRedisDBs(shopEnv); !ok || got != redisDBBlock(0) {
+		t.Errorf("shop env.php uses %+v, want it moved to 0/1/2", got)
+	}
+	info, err := os.Stat(shopEnv)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("env.php mode = %v, %v, want it kept", info.Mode().Perm(), err)
+	}
+	data, _ := os.ReadFile(shopEnv)
+	if !strings.Contains(string(data), `'host' => '127.0.0.1:33080'`) {
+		t.Errorf("env.php lost its other settings:\n%s", data)
+	}
+}
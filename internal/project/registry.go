@@ -14,12 +14,13 @@ import (
 
 // ProjectInfo contains information about a discovered project
 type ProjectInfo struct {
-	Name       string
-	Path       string
-	Domains    []string
-	PHPVersion string
-	ConfigFile string
-	HasConfig  bool
+	Name       string   `json:"name"`
+	Path       string   `json:"path"`
+	Domains    []string `json:"domains"`
+	PHPVersion string   `json:"php,omitempty"`
+	ConfigFile string   `json:"config_file,omitempty"`
+	HasConfig  bool     `json:"has_config"`
+	Running    bool     `json:"running"` // Has nginx vhosts, see AllProjects for stopped projects
 }
 
 // ProjectDiscovery discovers MageBox projects
@@ -89,6 +90,7 @@ func (d *ProjectDiscovery) parseVhostFile(path string) (*ProjectInfo, error) {
 
 	info := &ProjectInfo{
 		Domains: make([]string, 0),
+		Running: true,
 	}
 
 	// Regex patterns
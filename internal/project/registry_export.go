@@ -0,0 +1,248 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/docker"
+	"qoliber/magebox/internal/fileutil"
+)
+
+// RegistryVersion is the format version of registry exports
+const RegistryVersion = 1
+
+// Registry is a portable snapshot of the projects registered on a machine,
+// for auditing environments and pre-seeding new machines
+type Registry struct {
+	Version    int             `json:"version" yaml:"version"`
+	ExportedAt time.Time       `json:"exported_at" yaml:"exported_at"`
+	Host       string          `json:"host,omitempty" yaml:"host,omitempty"`
+	MageBox    string          `json:"magebox,omitempty" yaml:"magebox,omitempty"`
+	Projects   []RegistryEntry `json:"projects" yaml:"projects"`
+	Stats      RegistryStats   `json:"stats" yaml:"stats"`
+}
+
+// RegistryEntry is one exported project
+type RegistryEntry struct {
+	Name       string           `json:"name" yaml:"name"`
+	Path       string           `json:"path" yaml:"path"`
+	Domains    []string         `json:"domains" yaml:"domains"`
+	PHPVersion string           `json:"php,omitempty" yaml:"php,omitempty"`
+	Services   []string         `json:"services,omitempty" yaml:"services,omitempty"`
+	Ports      map[string][]int `json:"ports,omitempty" yaml:"ports,omitempty"` // Host ports by compose service
+	Repository string           `json:"repository,omitempty" yaml:"repository,omitempty"`
+	ConfigFile string           `json:"config_file,omitempty" yaml:"config_file,omitempty"` // Base name of the project config
+	Config     string           `json:"config,omitempty" yaml:"config,omitempty"`           // Its content, without local overrides
+}
+
+// RegistryStats counts the projects per PHP version and per service. They
+// are computed locally and never sent anywhere.
+type RegistryStats struct {
+	Projects int            `json:"projects" yaml:"projects"`
+	PHP      map[string]int `json:"php" yaml:"php"`
+	Services map[string]int `json:"services" yaml:"services"`
+}
+
+// ExportRegistry collects the registered projects with their config,
+// services and allocated ports
+func (d *ProjectDiscovery) ExportRegistry() (*Registry, error) {
+	projects, err := d.DiscoverProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	registry := &Registry{
+		Version:    RegistryVersion,
+		ExportedAt: time.Now().UTC(),
+		Host:       host,
+		Projects:   make([]RegistryEntry, 0, len(projects)),
+	}
+
+	composeGen := docker.NewComposeGenerator(d.platform)
+	for _, info := range projects {
+		entry := RegistryEntry{
+			Name:       info.Name,
+			Path:       info.Path,
+			Domains:    info.Domains,
+			PHPVersion: info.PHPVersion,
+			Repository: gitRemote(info.Path),
+		}
+		if info.HasConfig {
+			if data, err := os.ReadFile(info.ConfigFile); err == nil {
+				entry.ConfigFile = filepath.Base(info.ConfigFile)
+				entry.Config = string(data)
+			}
+			if cfg, err := config.LoadFromPath(info.Path); err == nil {
+				for _, svc := range effectiveServices(cfg) {
+					entry.Services = append(entry.Services, svc.Name)
+				}
+				entry.Ports = composeGen.ProjectPorts(cfg)
+			}
+		}
+		registry.Projects = append(registry.Projects, entry)
+	}
+	sort.Slice(registry.Projects, func(i, j int) bool {
+		return registry.Projects[i].Name < registry.Projects[j].Name
+	})
+	registry.Stats = registryStats(registry.Projects)
+
+	return registry, nil
+}
+
+// registryStats counts the projects per PHP version and service
+func registryStats(entries []RegistryEntry) RegistryStats {
+	stats := RegistryStats{
+		Projects: len(entries),
+		PHP:      make(map[string]int),
+		Services: make(map[string]int),
+	}
+	for _, entry := range entries {
+		if entry.PHPVersion != "" {
+			stats.PHP[entry.PHPVersion]++
+		}
+		for _, svc := range entry.Services {
+			stats.Services[svc]++
+		}
+	}
+	return stats
+}
+
+// gitRemote returns the origin URL of the git repository at path, or ""
+func gitRemote(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "git", "-C", path, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// ImportAction is what importing did for a project
+type ImportAction string
+
+// Import actions
+const (
+	ImportExisting      ImportAction = "existing"       // Project and config are already there
+	ImportWroteConfig   ImportAction = "config written" // Config was recreated from the export
+	ImportCloned        ImportAction = "cloned"         // Repository was cloned and the config recreated
+	ImportMissing       ImportAction = "missing"        // Directory does not exist
+	ImportNoConfig      ImportAction = "no config"      // Directory exists, the export has no config
+	ImportConfigChanged ImportAction = "config differs" // Existing config differs from the export
+)
+
+// ImportOptions controls how a registry is imported
+type ImportOptions struct {
+	// Relocate rewrites path prefixes, e.g. /Users/jane to /home/jane
+	Relocate map[string]string
+	// Clone clones the repository of projects whose directory is missing
+	Clone bool
+	// DryRun reports what would happen without changing anything
+	DryRun bool
+}
+
+// ImportResult is the outcome of importing one project
+type ImportResult struct {
+	Name   string
+	Path   string
+	Action ImportAction
+	Err    error
+}
+
+// ImportRegistry recreates the projects of a registry on this machine: it
+// writes the config of projects whose directory exists without one, and
+// clones missing projects when opts.Clone is set. Projects are registered
+// with nginx on their next 'magebox start'.
+func ImportRegistry(registry *Registry, opts ImportOptions) []ImportResult {
+	results := make([]ImportResult, 0, len(registry.Projects))
+	for _, entry := range registry.Projects {
+		path := relocatePath(entry.Path, opts.Relocate)
+		result := ImportResult{Name: entry.Name, Path: path}
+		result.Action, result.Err = importEntry(entry, path, opts)
+		results = append(results, result)
+	}
+	return results
+}
+
+// importEntry imports one project at path
+func importEntry(entry RegistryEntry, path string, opts ImportOptions) (ImportAction, error) {
+	configName := entry.ConfigFile
+	if configName == "" {
+		configName = config.ConfigFileName
+	}
+	if configName != config.ConfigFileName && configName != config.ConfigFileNameLegacy {
+		return "", fmt.Errorf("invalid config file name %q", configName)
+	}
+
+	action := ImportWroteConfig
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if !opts.Clone || entry.Repository == "" {
+			return ImportMissing, nil
+		}
+		action = ImportCloned
+		if !opts.DryRun {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return "", err
+			}
+			if output, err := exec.Command("git", "clone", "--", entry.Repository, path).CombinedOutput(); err != nil {
+				return "", fmt.Errorf("git clone failed: %s", strings.TrimSpace(string(output)))
+			}
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	for _, name := range []string{config.ConfigFileName, config.ConfigFileNameLegacy} {
+		existing, err := os.ReadFile(filepath.Join(path, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case action == ImportCloned:
+			return ImportCloned, nil
+		case entry.Config != "" && string(existing) != entry.Config:
+			return ImportConfigChanged, nil
+		}
+		return ImportExisting, nil
+	}
+
+	if entry.Config == "" {
+		if action == ImportCloned {
+			return ImportCloned, nil
+		}
+		return ImportNoConfig, nil
+	}
+	if !opts.DryRun {
+		if err := fileutil.WriteFileAtomic(filepath.Join(path, configName), []byte(entry.Config), 0644); err != nil {
+			return "", err
+		}
+	}
+	return action, nil
+}
+
+// relocatePath applies the longest matching prefix rewrite to path
+func relocatePath(path string, relocate map[string]string) string {
+	best, to := "", ""
+	for from, target := range relocate {
+		from = strings.TrimSuffix(from, "/")
+		if (path == from || strings.HasPrefix(path, from+"/")) && len(from) > len(best) {
+			best, to = from, strings.TrimSuffix(target, "/")
+		}
+	}
+	if best == "" {
+		return path
+	}
+	return to + path[len(best):]
+}
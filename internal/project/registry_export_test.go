@@ -0,0 +1,138 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/platform"
+)
+
+func TestProjectDiscovery_ExportRegistry(t *testing.T) {
+	tmpDir := t.TempDir()
+	vhostsDir := filepath.Join(tmpDir, ".magebox", "nginx", "vhosts")
+	if err := os.MkdirAll(vhostsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := filepath.Join(tmpDir, "mystore")
+	if err := os.MkdirAll(filepath.Join(projectDir, "pub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	configContent := `name: mystore
+domains:
+  - host: mystore.test
+php: "8.3"
+services:
+  mysql: "8.0"
+  redis: true
+`
+	if err := os.WriteFile(filepath.Join(projectDir, config.ConfigFileName), []byte(configContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vhost := "server {\n    server_name mystore.test;\n    set $MAGE_ROOT " + projectDir + "/pub;\n}\n"
+	if err := os.WriteFile(filepath.Join(vhostsDir, "mystore.test.conf"), []byte(vhost), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewProjectDiscovery(&platform.Platform{Type: platform.Linux, HomeDir: tmpDir})
+	registry, err := d.ExportRegistry()
+	if err != nil {
+		t.Fatalf("ExportRegistry failed: %v", err)
+	}
+
+	if registry.Version != RegistryVersion || len(registry.Projects) != 1 {
+		t.Fatalf("ExportRegistry() = %+v, want one project", registry)
+	}
+	entry := registry.Projects[0]
+	if entry.Name != "mystore" || entry.Path != projectDir || entry.PHPVersion != "8.3" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.ConfigFile != config.ConfigFileName || entry.Config != configContent {
+		t.Errorf("config = %q (%s), want the project config", entry.Config, entry.ConfigFile)
+	}
+	if ports := entry.Ports["mysql80"]; len(ports) != 1 || ports[0] != 33080 {
+		t.Errorf("mysql80 ports = %v, want [33080]", ports)
+	}
+	if registry.Stats.PHP["8.3"] != 1 || registry.Stats.Services["mysql"] != 1 || registry.Stats.Services["redis"] != 1 {
+		t.Errorf("stats = %+v", registry.Stats)
+	}
+}
+
+func TestImportRegistry(t *testing.T) {
+	tmpDir := t.TempDir()
+	seed := filepath.Join(tmpDir, "new", "seed")
+	existing := filepath.Join(tmpDir, "new", "existing")
+	changed := filepath.Join(tmpDir, "new", "changed")
+	for _, dir := range []string{seed, existing, changed} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(existing, config.ConfigFileName), []byte("name: existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(changed, config.ConfigFileName), []byte("name: local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := filepath.Join(tmpDir, "old")
+	registry := &Registry{Projects: []RegistryEntry{
+		{Name: "seed", Path: old + "/seed", ConfigFile: config.ConfigFileName, Config: "name: seed\n"},
+		{Name: "existing", Path: old + "/existing", Config: "name: existing\n"},
+		{Name: "changed", Path: old + "/changed", Config: "name: changed\n"},
+		{Name: "gone", Path: old + "/gone", Config: "name: gone\n", Repository: "git@example.com:gone.git"},
+		{Name: "bad", Path: old + "/seed", ConfigFile: "../evil", Config: "x"},
+	}}
+
+	relocate := map[string]string{old + "/": filepath.Join(tmpDir, "new")}
+	dry := ImportRegistry(registry, ImportOptions{Relocate: relocate, DryRun: true})
+	if dry[0].Action != ImportWroteConfig {
+		t.Errorf("dry run action = %q, want %q", dry[0].Action, ImportWroteConfig)
+	}
+	if _, err := os.Stat(filepath.Join(seed, config.ConfigFileName)); !os.IsNotExist(err) {
+		t.Error("dry run should not write the config")
+	}
+
+	results := ImportRegistry(registry, ImportOptions{Relocate: relocate})
+	want := []ImportAction{ImportWroteConfig, ImportExisting, ImportConfigChanged, ImportMissing, ""}
+	for i, r := range results {
+		if r.Action != want[i] {
+			t.Errorf("%s: action = %q, want %q (err %v)", r.Name, r.Action, want[i], r.Err)
+		}
+	}
+	if results[0].Path != seed {
+		t.Errorf("relocated path = %q, want %q", results[0].Path, seed)
+	}
+	if results[4].Err == nil {
+		t.Error("an invalid config file name should fail")
+	}
+
+	data, err := os.ReadFile(filepath.Join(seed, config.ConfigFileName))
+	if err != nil || string(data) != "name: seed\n" {
+		t.Errorf("seeded config = %q, %v", data, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(changed, config.ConfigFileName)); string(data) != "name: local\n" {
+		t.Errorf("an existing config must not be overwritten, got %q", data)
+	}
+}
+
+func TestRelocatePath(t *testing.T) {
+	relocate := map[string]string{
+		"/Users/jane":          "/home/jane",
+		"/Users/jane/clients/": "/srv/clients",
+	}
+	tests := map[string]string{
+		"/Users/jane/mystore":        "/home/jane/mystore",
+		"/Users/jane/clients/acme":   "/srv/clients/acme",
+		"/Users/janet/mystore":       "/Users/janet/mystore",
+		"/Users/jane":                "/home/jane",
+		"/var/www/unrelated/project": "/var/www/unrelated/project",
+	}
+	for path, want := range tests {
+		if got := relocatePath(path, relocate); got != want {
+			t.Errorf("relocatePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package project
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+)
+
+// Components that can be started and stopped on their own
+const (
+	// ComponentWeb is PHP-FPM, Nginx, SSL certificates and DNS entries
+	ComponentWeb = "web"
+	// ComponentServices is every Docker service the project uses
+	ComponentServices = "services"
+)
+
+// serviceAliases maps generic service names to the compose service name
+// prefixes they stand for
+var serviceAliases = map[string][]string{
+	"db":       {"mysql", "mariadb", "percona"},
+	"database": {"mysql", "mariadb", "percona"},
+	"cache":    {"redis", "valkey"},
+	"search":   {"opensearch", "elasticsearch", "meilisearch", "typesense"},
+}
+
+// knownServices are the service names MageBox manages, used to tell a typo
+// from a service the project doesn't use
+var knownServices = []string{"mysql", "mariadb", "percona", "redis", "valkey", "opensearch", "elasticsearch", "meilisearch", "typesense", "rabbitmq", "varnish", "mailpit", "composer-mirror"}
+
+// Targets selects the parts of a project to start or stop. A nil *Targets
+// selects the whole project.
+type Targets struct {
+	web      bool
+	services []string
+}
+
+// ParseTargets resolves component and service names ("web", "services",
+// "mysql", "opensearch", "mysql80", ...) against the services cfg uses.
+// No names selects the whole project and returns nil.
+func ParseTargets(cfg *config.Config, names []string) (*Targets, error) {
+	all := projectComposeServiceNames(cfg)
+
+	var t *Targets
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if t == nil {
+			t = &Targets{}
+		}
+
+		switch name {
+		case ComponentWeb:
+			t.web = true
+			continue
+		case ComponentServices:
+			t.addServices(all...)
+			continue
+		}
+
+		matched, err := resolveService(all, name)
+		if err != nil {
+			return nil, err
+		}
+		t.addServices(matched...)
+	}
+	return t, nil
+}
+
+// resolveService returns the compose services of the project matching name
+func resolveService(all []string, name string) ([]string, error) {
+	prefixes, ok := serviceAliases[name]
+	if !ok {
+		prefixes = []string{name}
+	}
+
+	var matched []string
+	for _, svc := range all {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(svc, prefix) {
+				matched = append(matched, svc)
+				break
+			}
+		}
+	}
+	if len(matched) > 0 {
+		return matched, nil
+	}
+
+	if isKnownService(name) {
+		return nil, fmt.Errorf("project does not use %s", name)
+	}
+	if ok {
+		return nil, fmt.Errorf("project has no %s service", name)
+	}
+	return nil, fmt.Errorf("unknown target %q (use %s, %s or one of: %s)",
+		name, ComponentWeb, ComponentServices, strings.Join(all, ", "))
+}
+
+func (t *Targets) addServices(names ...string) {
+	for _, name := range names {
+		if !t.includes(name) {
+			t.services = append(t.services, name)
+		}
+	}
+	sort.Strings(t.services)
+}
+
+// Web reports whether PHP-FPM, Nginx, SSL and DNS are selected
+func (t *Targets) Web() bool {
+	return t == nil || t.web
+}
+
+// Services returns the selected compose services; nil selects all of them
+func (t *Targets) Services() []string {
+	if t == nil {
+		return nil
+	}
+	return t.services
+}
+
+// includes reports whether a compose service is selected
+func (t *Targets) includes(service string) bool {
+	if t == nil {
+		return true
+	}
+	for _, s := range t.services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// String lists the selected components and services
+func (t *Targets) String() string {
+	if t == nil {
+		return "all"
+	}
+	var parts []string
+	if t.web {
+		parts = append(parts, ComponentWeb)
+	}
+	parts = append(parts, t.services...)
+	return strings.Join(parts, ", ")
+}
+
+// ResolveService resolves a service name ("mysql", "db", "opensearch",
+// "mysql80", ...) to the single compose service of the project it stands for
+func ResolveService(cfg *config.Config, name string) (string, error) {
+	all := projectComposeServiceNames(cfg)
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	prefixes, alias := serviceAliases[name]
+	if !alias && !isKnownService(name) && !slices.Contains(all, name) {
+		return "", fmt.Errorf("unknown service %q (project services: %s)", name, strings.Join(all, ", "))
+	}
+
+	matched, err := resolveService(all, name)
+	if err != nil {
+		return "", err
+	}
+	if len(matched) == 1 {
+		return matched[0], nil
+	}
+
+	// "opensearch" also matches opensearch-dashboards219, prefer the service
+	// that only adds a version to the name
+	if !alias {
+		prefixes = []string{name}
+	}
+	var versioned []string
+	for _, svc := range matched {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(svc, prefix) && strings.Trim(svc[len(prefix):], "0123456789") == "" {
+				versioned = append(versioned, svc)
+				break
+			}
+		}
+	}
+	if len(versioned) == 1 {
+		return versioned[0], nil
+	}
+	return "", fmt.Errorf("%s matches several services (%s), name one of them", name, strings.Join(matched, ", "))
+}
+
+// isKnownService reports whether name is, or is a version of, a service
+// MageBox manages
+func isKnownService(name string) bool {
+	for _, known := range knownServices {
+		if strings.HasPrefix(name, known) {
+			return true
+		}
+	}
+	return false
+}
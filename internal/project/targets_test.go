@@ -0,0 +1,133 @@
+package project
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+func targetsTestConfig() *config.Config {
+	return &config.Config{
+		Name: "mystore",
+		PHP:  "8.2",
+		Services: config.Services{
+			MySQL:      &config.ServiceConfig{Enabled: true, Version: "8.0"},
+			Redis:      &config.ServiceConfig{Enabled: true},
+			OpenSearch: &config.ServiceConfig{Enabled: true, Version: "2.19"},
+		},
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		name         string
+		names        []string
+		wantWeb      bool
+		wantServices []string
+		wantErr      string
+	}{
+		{name: "web only", names: []string{"web"}, wantWeb: true},
+		{name: "services by name", names: []string{"mysql", "opensearch"}, wantServices: []string{"mysql80", "opensearch219"}},
+		{name: "compose service name", names: []string{"mysql80"}, wantServices: []string{"mysql80"}},
+		{name: "aliases", names: []string{"db", "cache", "search"}, wantServices: []string{"mysql80", "opensearch219", "redis"}},
+		{name: "all services", names: []string{"services"}, wantServices: []string{"mailpit", "mysql80", "opensearch219", "redis"}},
+		{name: "web and duplicates", names: []string{"web", "MySQL", "db", " "}, wantWeb: true, wantServices: []string{"mysql80"}},
+		{name: "unused service", names: []string{"rabbitmq"}, wantErr: "does not use rabbitmq"},
+		{name: "other version", names: []string{"mysql57"}, wantErr: "does not use mysql57"},
+		{name: "unknown", names: []string{"postgres"}, wantErr: "unknown target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTargets(targetsTestConfig(), tt.names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseTargets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTargets() error = %v", err)
+			}
+			if got.Web() != tt.wantWeb {
+				t.Errorf("Web() = %v, want %v", got.Web(), tt.wantWeb)
+			}
+			if !reflect.DeepEqual(got.Services(), tt.wantServices) {
+				t.Errorf("Services() = %v, want %v", got.Services(), tt.wantServices)
+			}
+		})
+	}
+}
+
+func TestParseTargets_NoNamesSelectsAll(t *testing.T) {
+	got, err := ParseTargets(targetsTestConfig(), nil)
+	if err != nil || got != nil {
+		t.Fatalf("ParseTargets(nil) = %v, %v; want nil, nil", got, err)
+	}
+	if !got.Web() || !got.includes("mysql80") || got.String() != "all" {
+		t.Error("nil targets should select everything")
+	}
+}
+
+func TestManager_startedServicesForTargets(t *testing.T) {
+	m, _ := setupTestManager(t)
+	cfg := targetsTestConfig()
+
+	targets, err := ParseTargets(cfg, []string{"mysql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.startedServices(cfg, targets); !reflect.DeepEqual(got, []string{"MySQL 8.0"}) {
+		t.Errorf("startedServices(mysql) = %v", got)
+	}
+
+	targets, err = ParseTargets(cfg, []string{"web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.startedServices(cfg, targets); !reflect.DeepEqual(got, []string{"PHP-FPM 8.2", "Nginx"}) {
+		t.Errorf("startedServices(web) = %v", got)
+	}
+}
+
+func TestResolveService(t *testing.T) {
+	cfg := targetsTestConfig()
+	cfg.Services.OpenSearchDashboards = &config.ServiceConfig{Enabled: true}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "mysql", want: "mysql80"},
+		{name: "db", want: "mysql80"},
+		{name: "MySQL80", want: "mysql80"},
+		{name: "cache", want: "redis"},
+		{name: "opensearch", want: "opensearch219"},
+		{name: "search", want: "opensearch219"},
+		{name: "opensearch-dashboards", want: "opensearch-dashboards219"},
+		{name: "mailpit", want: "mailpit"},
+		{name: "rabbitmq", wantErr: "does not use rabbitmq"},
+		{name: "postgres", wantErr: "unknown service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveService(cfg, tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveService() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveService() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveService(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
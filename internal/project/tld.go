@@ -0,0 +1,120 @@
+package project
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"qoliber/magebox/internal/config"
+)
+
+// DomainChange is a domain renamed by a TLD migration
+type DomainChange struct {
+	Old string
+	New string
+}
+
+// TLDMigration lists the domains of a project that move to a new TLD
+type TLDMigration struct {
+	Project ProjectInfo
+	Config  *config.Config
+	Changes []DomainChange
+}
+
+// ReplaceTLD returns host with oldTLD replaced by newTLD, reporting whether
+// host used oldTLD
+func ReplaceTLD(host, oldTLD, newTLD string) (string, bool) {
+	oldTLD = strings.TrimPrefix(oldTLD, ".")
+	newTLD = strings.TrimPrefix(newTLD, ".")
+	base, ok := strings.CutSuffix(host, "."+oldTLD)
+	if !ok || base == "" || oldTLD == newTLD {
+		return host, false
+	}
+	return base + "." + newTLD, true
+}
+
+// PlanTLDMigration returns the projects with domains on oldTLD and their new
+// domains. Projects without a loadable config and projects that set their own
+// tld are skipped.
+func PlanTLDMigration(projects []ProjectInfo, oldTLD, newTLD string) []TLDMigration {
+	var plan []TLDMigration
+	for _, p := range projects {
+		if !p.HasConfig {
+			continue
+		}
+		cfg, err := config.LoadFromPath(p.Path)
+		if err != nil || cfg.TLD != "" {
+			continue
+		}
+
+		var changes []DomainChange
+		for _, d := range cfg.Domains {
+			if host, ok := ReplaceTLD(d.Host, oldTLD, newTLD); ok {
+				changes = append(changes, DomainChange{Old: d.Host, New: host})
+			}
+		}
+		if len(changes) > 0 {
+			plan = append(plan, TLDMigration{Project: p, Config: cfg, Changes: changes})
+		}
+	}
+
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Config.Name < plan[j].Config.Name })
+	return plan
+}
+
+// ProjectTLDs returns the TLDs the projects set in their config, sorted
+func ProjectTLDs(projects []ProjectInfo) []string {
+	var tlds []string
+	for _, p := range projects {
+		if !p.HasConfig {
+			continue
+		}
+		cfg, err := config.LoadFromPath(p.Path)
+		if err != nil || cfg.TLD == "" {
+			continue
+		}
+		if tld := cfg.GetTLD(""); !slices.Contains(tlds, tld) {
+			tlds = append(tlds, tld)
+		}
+	}
+	sort.Strings(tlds)
+	return tlds
+}
+
+// Apply renames the domains of the migration in its config
+func (m *TLDMigration) Apply() {
+	for i := range m.Config.Domains {
+		for _, c := range m.Changes {
+			if m.Config.Domains[i].Host == c.Old {
+				m.Config.Domains[i].Host = c.New
+			}
+		}
+	}
+}
+
+// OldHosts returns the domains before the migration
+func (m *TLDMigration) OldHosts() []string {
+	hosts := make([]string, len(m.Changes))
+	for i, c := range m.Changes {
+		hosts[i] = c.Old
+	}
+	return hosts
+}
+
+// BaseURLUpdateSQL returns the statements rewriting Magento URLs in
+// core_config_data from the old domains to the new ones
+func (m *TLDMigration) BaseURLUpdateSQL() string {
+	statements := make([]string, 0, len(m.Changes))
+	for _, c := range m.Changes {
+		statements = append(statements, fmt.Sprintf(
+			"UPDATE core_config_data SET value = REPLACE(value, %s, %s) WHERE path LIKE 'web/%%' AND value LIKE %s",
+			sqlQuote("://"+c.Old+"/"), sqlQuote("://"+c.New+"/"), sqlQuote("%://"+c.Old+"/%")))
+	}
+	return strings.Join(statements, "; ")
+}
+
+// sqlQuote quotes a string literal for MySQL
+func sqlQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,110 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReplaceTLD(t *testing.T) {
+	tests := []struct {
+		host, oldTLD, newTLD string
+		want                 string
+		wantOK               bool
+	}{
+		{"mystore.test", "test", "localhost", "mystore.localhost", true},
+		{"de.mystore.test", ".test", ".local", "de.mystore.local", true},
+		{"mystore.testing", "test", "local", "mystore.testing", false},
+		{"mystore.local", "test", "local", "mystore.local", false},
+		{"test", "test", "local", "test", false},
+		{"mystore.test", "test", "test", "mystore.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+"->"+tt.newTLD, func(t *testing.T) {
+			got, ok := ReplaceTLD(tt.host, tt.oldTLD, tt.newTLD)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ReplaceTLD(%q, %q, %q) = %q, %v; want %q, %v", tt.host, tt.oldTLD, tt.newTLD, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPlanTLDMigration(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) ProjectInfo {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, ".magebox.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return ProjectInfo{Name: name, Path: path, HasConfig: true}
+	}
+
+	projects := []ProjectInfo{
+		write("shop", "name: shop\nphp: \"8.3\"\ndomains:\n  - host: shop.test\n  - host: de.shop.test\n  - host: shop.example.com\n"),
+		write("other", "name: other\nphp: \"8.3\"\ndomains:\n  - host: other.local\n"),
+		write("pinned", "name: pinned\nphp: \"8.3\"\ntld: test\ndomains:\n  - host: pinned.test\n"),
+		{Name: "legacy", Path: filepath.Join(dir, "legacy")},
+	}
+
+	plan := PlanTLDMigration(projects, "test", "localhost")
+	if len(plan) != 1 || plan[0].Config.Name != "shop" {
+		t.Fatalf("PlanTLDMigration() = %+v, want only shop", plan)
+	}
+	m := plan[0]
+	want := []DomainChange{{"shop.test", "shop.localhost"}, {"de.shop.test", "de.shop.localhost"}}
+	if !reflect.DeepEqual(m.Changes, want) {
+		t.Errorf("Changes = %+v, want %+v", m.Changes, want)
+	}
+
+	m.Apply()
+	var hosts []string
+	for _, d := range m.Config.Domains {
+		hosts = append(hosts, d.Host)
+	}
+	if strings.Join(hosts, ",") != "shop.localhost,de.shop.localhost,shop.example.com" {
+		t.Errorf("domains after Apply() = %v", hosts)
+	}
+	if !reflect.DeepEqual(m.OldHosts(), []string{"shop.test", "de.shop.test"}) {
+		t.Errorf("OldHosts() = %v", m.OldHosts())
+	}
+}
+
+func TestProjectTLDs(t *testing.T) {
+	dir := t.TempDir()
+	var projects []ProjectInfo
+	// A public TLD fails validation and is never routed
+	for name, tld := range map[string]string{"a": "tld: acme.test\n", "b": "tld: .localhost\n", "c": "tld: acme.test\n", "d": "", "e": "tld: com\n"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "name: " + name + "\nphp: \"8.3\"\n" + tld + "domains:\n  - host: " + name + ".test\n"
+		if err := os.WriteFile(filepath.Join(path, ".magebox.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, ProjectInfo{Name: name, Path: path, HasConfig: true})
+	}
+
+	if got := ProjectTLDs(projects); !reflect.DeepEqual(got, []string{"acme.test", "localhost"}) {
+		t.Errorf("ProjectTLDs() = %v, want [acme.test localhost]", got)
+	}
+}
+
+func TestTLDMigration_BaseURLUpdateSQL(t *testing.T) {
+	m := &TLDMigration{Changes: []DomainChange{{"shop.test", "shop.localhost"}, {"it's.test", "it's.local"}}}
+	got := m.BaseURLUpdateSQL()
+
+	want := "UPDATE core_config_data SET value = REPLACE(value, '://shop.test/', '://shop.localhost/') " +
+		"WHERE path LIKE 'web/%' AND value LIKE '%://shop.test/%'"
+	if !strings.Contains(got, want) {
+		t.Errorf("BaseURLUpdateSQL() = %q, want it to contain %q", got, want)
+	}
+	if !strings.Contains(got, `'://it\'s.test/'`) {
+		t.Errorf("BaseURLUpdateSQL() should escape quotes, got %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"qoliber/magebox/internal/fileutil"
+	"qoliber/magebox/internal/platform"
+)
+
+// Worktree is a project MageBox created for a git worktree of another
+// project, its parent
+type Worktree struct {
+	Name     string `json:"name"`
+	Parent   string `json:"parent"`
+	Branch   string `json:"branch"`
+	Path     string `json:"path"`
+	Database string `json:"database,omitempty"`
+	// Cloned is set when Database is a copy of the parent's database rather
+	// than the parent's database itself
+	Cloned bool `json:"cloned,omitempty"`
+}
+
+// Worktrees remembers the worktree projects and their parents, so stopping
+// a parent can stop its worktrees too
+type Worktrees struct {
+	registryPath string
+}
+
+// NewWorktrees creates the worktree registry
+func NewWorktrees(p *platform.Platform) *Worktrees {
+	return &Worktrees{
+		registryPath: filepath.Join(p.MageBoxDir(), "worktrees.json"),
+	}
+}
+
+// Load returns the worktrees by project name
+func (w *Worktrees) Load() (map[string]Worktree, error) {
+	worktrees := make(map[string]Worktree)
+
+	data, err := os.ReadFile(w.registryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return worktrees, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &worktrees); err != nil {
+		return nil, err
+	}
+
+	return worktrees, nil
+}
+
+// Get returns the worktree project with the given name, nil when the project
+// isn't a worktree
+func (w *Worktrees) Get(name string) (*Worktree, error) {
+	worktrees, err := w.Load()
+	if err != nil {
+		return nil, err
+	}
+	wt, ok := worktrees[name]
+	if !ok {
+		return nil, nil
+	}
+	return &wt, nil
+}
+
+// Children returns the worktrees of a parent project sorted by name
+func (w *Worktrees) Children(parent string) ([]Worktree, error) {
+	worktrees, err := w.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var children []Worktree
+	for _, wt := range worktrees {
+		if wt.Parent == parent {
+			children = append(children, wt)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	return children, nil
+}
+
+// Add records a worktree project, replacing one of the same name
+func (w *Worktrees) Add(wt Worktree) error {
+	worktrees, err := w.Load()
+	if err != nil {
+		return err
+	}
+	worktrees[wt.Name] = wt
+	return w.save(worktrees)
+}
+
+// Remove forgets a worktree project
+func (w *Worktrees) Remove(name string) error {
+	worktrees, err := w.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := worktrees[name]; !ok {
+		return nil
+	}
+	delete(worktrees, name)
+	return w.save(worktrees)
+}
+
+// save writes the registry atomically
+func (w *Worktrees) save(worktrees map[string]Worktree) error {
+	data, err := json.MarshalIndent(worktrees, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.registryPath), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(w.registryPath, data, 0644)
+}
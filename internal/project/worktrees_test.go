@@ -0,0 +1,55 @@
+package project
+
+import (
+	"testing"
+
+	"qoliber/magebox/internal/platform"
+)
+
+func TestWorktrees(t *testing.T) {
+	p := &platform.Platform{Type: platform.Linux, HomeDir: t.TempDir()}
+	w := NewWorktrees(p)
+
+	if children, err := w.Children("shop"); err != nil || len(children) != 0 {
+		t.Fatalf("Children() of an empty registry = %v, %v", children, err)
+	}
+
+	for _, wt := range []Worktree{
+		{Name: "shop-feature-b", Parent: "shop", Branch: "feature/b", Path: "/src/shop-feature-b", Database: "shop"},
+		{Name: "shop-feature-a", Parent: "shop", Branch: "feature/a", Path: "/src/shop-feature-a", Database: "shop_feature_a", Cloned: true},
+		{Name: "blog-fix", Parent: "blog", Branch: "fix", Path: "/src/blog-fix"},
+	} {
+		if err := w.Add(wt); err != nil {
+			t.Fatalf("Add(%s) failed: %v", wt.Name, err)
+		}
+	}
+
+	children, err := w.Children("shop")
+	if err != nil {
+		t.Fatalf("Children() failed: %v", err)
+	}
+	if len(children) != 2 || children[0].Name != "shop-feature-a" || children[1].Name != "shop-feature-b" {
+		t.Fatalf("Children(shop) = %+v, want shop-feature-a and shop-feature-b", children)
+	}
+	if !children[0].Cloned || children[0].Database != "shop_feature_a" {
+		t.Errorf("Children(shop)[0] = %+v, want its cloned database", children[0])
+	}
+
+	wt, err := w.Get("blog-fix")
+	if err != nil || wt == nil || wt.Parent != "blog" {
+		t.Errorf("Get(blog-fix) = %+v, %v", wt, err)
+	}
+	if wt, _ := w.Get("shop"); wt != nil {
+		t.Errorf("Get(shop) of a parent = %+v, want nil", wt)
+	}
+
+	if err := w.Remove("shop-feature-a"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := w.Remove("missing"); err != nil {
+		t.Fatalf("Remove of a missing worktree failed: %v", err)
+	}
+	if children, _ := w.Children("shop"); len(children) != 1 || children[0].Name != "shop-feature-b" {
+		t.Errorf("Children(shop) after Remove = %+v", children)
+	}
+}
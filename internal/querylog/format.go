@@ -0,0 +1,134 @@
+package querylog
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generalLogRe matches a general log line: an optional timestamp, the
+// connection id, the command and its argument. Lines of the same second in
+// the MariaDB format have no timestamp.
+var generalLogRe = regexp.MustCompile(`^[^\t]*\t+\s*(\d+) ([A-Z][a-z]+(?: [A-Za-z]+)?)\t?(.*)$`)
+
+// connectDBRe extracts the database of a Connect argument
+// ("user@host on mystore using TCP/IP")
+var connectDBRe = regexp.MustCompile(` on (\S*)`)
+
+// Formatter writes a query log to out, keeping only the project queries of
+// the general log and highlighting slow Magento queries
+type Formatter struct {
+	out       io.Writer
+	backend   string
+	database  string
+	slow      time.Duration
+	highlight func(string) string
+
+	// threads maps general log connection ids to their current database
+	threads map[string]string
+	// keep reports whether continuation lines of the last query are shown
+	keep bool
+	// entry buffers the lines of a Magento log entry until its TIME line
+	entry []string
+}
+
+// NewFormatter creates a formatter for the log of a state. highlight wraps
+// slow query entries, e.g. in color.
+func NewFormatter(out io.Writer, s *State, highlight func(string) string) *Formatter {
+	return &Formatter{
+		out:       out,
+		backend:   s.Backend,
+		database:  s.Database,
+		slow:      s.Slow,
+		highlight: highlight,
+		threads:   make(map[string]string),
+	}
+}
+
+// Line formats a single log line
+func (f *Formatter) Line(line string) {
+	line = strings.TrimRight(line, "\r")
+	if f.backend == BackendMagento {
+		f.magentoLine(line)
+		return
+	}
+	f.generalLine(line)
+}
+
+// Flush writes a partially buffered entry
+func (f *Formatter) Flush() {
+	if len(f.entry) > 0 {
+		f.writeEntry(false)
+	}
+}
+
+// magentoLine buffers Magento DB logger entries ("## <date>", "## <pid> ##
+// QUERY", "SQL: ...", "AFF: ...", "TIME: ...", blank line) and writes each
+// entry once its TIME line is known
+func (f *Formatter) magentoLine(line string) {
+	if line == "" {
+		f.Flush()
+		return
+	}
+	if strings.HasPrefix(line, "## ") && len(f.entry) > 0 && !strings.HasPrefix(f.entry[len(f.entry)-1], "## ") {
+		// A new entry starts without a blank line after the previous one
+		f.Flush()
+	}
+	f.entry = append(f.entry, line)
+
+	if seconds, ok := strings.CutPrefix(line, "TIME: "); ok {
+		d, err := strconv.ParseFloat(strings.TrimSpace(seconds), 64)
+		f.writeEntry(err == nil && f.slow > 0 && time.Duration(d*float64(time.Second)) >= f.slow)
+	}
+}
+
+func (f *Formatter) writeEntry(slow bool) {
+	text := strings.Join(f.entry, "\n")
+	f.entry = f.entry[:0]
+	if slow && f.highlight != nil {
+		text = f.highlight(text)
+	}
+	fmt.Fprintln(f.out, text)
+	fmt.Fprintln(f.out)
+}
+
+// generalLine tracks the database of every connection and writes the lines
+// of connections using the project database
+func (f *Formatter) generalLine(line string) {
+	m := generalLogRe.FindStringSubmatch(line)
+	if m == nil {
+		// Continuation of a multi-line query, or the log file header
+		if f.keep {
+			fmt.Fprintln(f.out, line)
+		}
+		return
+	}
+
+	id, command, arg := m[1], m[2], m[3]
+	switch command {
+	case "Connect":
+		db := ""
+		if dm := connectDBRe.FindStringSubmatch(arg); dm != nil {
+			db = dm[1]
+		}
+		f.threads[id] = db
+	case "Init DB":
+		f.threads[id] = strings.TrimSpace(arg)
+	case "Quit":
+		keep := f.threads[id] == f.database
+		delete(f.threads, id)
+		f.keep = false
+		if keep {
+			fmt.Fprintln(f.out, line)
+		}
+		return
+	}
+
+	f.keep = f.threads[id] == f.database
+	if f.keep {
+		fmt.Fprintln(f.out, line)
+	}
+}
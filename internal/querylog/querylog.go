@@ -0,0 +1,143 @@
+// Package querylog turns database query logging on and off for a project and
+// formats the log for streaming.
+//
+// Magento projects use Magento's own DB logger, which writes the queries of
+// that project only, with their duration, to var/debug/db.log. Other projects
+// use the MySQL/MariaDB general log of the shared database container, which
+// is filtered down to the connections using the project database.
+//
+// Query logs grow fast, so every log is turned on with an expiry time and
+// turned off again once it passes.
+package querylog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// Backends that can log queries
+const (
+	// BackendMagento is Magento's DB logger (bin/magento dev:query-log:enable)
+	BackendMagento = "magento"
+	// BackendMySQL is the general log of the database container
+	BackendMySQL = "mysql"
+)
+
+// DefaultDuration is how long a query log stays on when no duration is given
+const DefaultDuration = 30 * time.Minute
+
+// DefaultSlow is the query time from which a query is highlighted
+const DefaultSlow = 100 * time.Millisecond
+
+// MagentoLogFile is the Magento DB logger output, relative to the project
+const MagentoLogFile = "var/debug/db.log"
+
+// MySQLLogFile is the general log file inside the database container
+const MySQLLogFile = "/var/lib/mysql/magebox-general.log"
+
+// State describes a query log that is turned on for a project
+type State struct {
+	Project   string        `json:"project"`
+	Backend   string        `json:"backend"`
+	Database  string        `json:"database"`
+	Container string        `json:"container,omitempty"` // database container, mysql backend only
+	LogFile   string        `json:"log_file"`
+	Slow      time.Duration `json:"slow"`
+	Since     time.Time     `json:"since"`
+	Until     time.Time     `json:"until,omitempty"` // zero keeps the log on until turned off
+	TimerPID  int           `json:"timer_pid,omitempty"`
+}
+
+// StatePath returns the state file of a project in dir
+func StatePath(dir, project string) string {
+	return filepath.Join(dir, "querylog-"+project+".json")
+}
+
+// LoadState reads a state file; a missing file yields nil
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid query log state %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the state file atomically
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, data, 0644)
+}
+
+// Expired reports whether the log should have been turned off by now
+func (s *State) Expired(now time.Time) bool {
+	return !s.Until.IsZero() && !now.Before(s.Until)
+}
+
+// Remaining returns how long the log stays on, rounded to seconds
+func (s *State) Remaining(now time.Time) time.Duration {
+	if s.Until.IsZero() || s.Expired(now) {
+		return 0
+	}
+	return s.Until.Sub(now).Round(time.Second)
+}
+
+// ContainerInUse reports whether another project still has an unexpired
+// general log on the container, in which case it must stay on
+func ContainerInUse(dir, container, project string, now time.Time) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "querylog-*.json"))
+	for _, path := range matches {
+		s, err := LoadState(path)
+		if err != nil || s == nil {
+			continue
+		}
+		if s.Project != project && s.Backend == BackendMySQL && s.Container == container && !s.Expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// MagentoEnableArgs returns the bin/magento arguments turning the DB logger on
+func MagentoEnableArgs() []string {
+	return []string{"dev:query-log:enable", "--include-all-queries=true", "--include-call-stack=false"}
+}
+
+// MagentoDisableArgs returns the bin/magento arguments turning the DB logger off
+func MagentoDisableArgs() []string {
+	return []string{"dev:query-log:disable"}
+}
+
+// MySQLEnableStatements returns the SQL turning the general log on
+func MySQLEnableStatements() string {
+	return strings.Join([]string{
+		"SET GLOBAL log_output = 'FILE'",
+		fmt.Sprintf("SET GLOBAL general_log_file = '%s'", MySQLLogFile),
+		"SET GLOBAL general_log = 'ON'",
+	}, "; ")
+}
+
+// MySQLDisableStatements returns the SQL turning the general log off
+func MySQLDisableStatements() string {
+	return "SET GLOBAL general_log = 'OFF'"
+}
@@ -0,0 +1,148 @@
+package querylog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestState_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := StatePath(dir, "mystore")
+
+	if s, err := LoadState(path); err != nil || s != nil {
+		t.Fatalf("LoadState(missing) = %v, %v; want nil, nil", s, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	want := &State{Project: "mystore", Backend: BackendMySQL, Database: "mystore", Container: "magebox-mysql-8.0",
+		LogFile: MySQLLogFile, Slow: DefaultSlow, Since: now, Until: now.Add(time.Hour), TimerPID: 42}
+	if err := want.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if got.Backend != want.Backend || got.Slow != want.Slow || !got.Until.Equal(want.Until) || got.TimerPID != 42 {
+		t.Errorf("LoadState() = %+v, want %+v", got, want)
+	}
+}
+
+func TestState_Expired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		until         time.Time
+		wantExpired   bool
+		wantRemaining time.Duration
+	}{
+		{"no limit", time.Time{}, false, 0},
+		{"future", now.Add(10 * time.Minute), false, 10 * time.Minute},
+		{"past", now.Add(-time.Second), true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &State{Until: tt.until}
+			if got := s.Expired(now); got != tt.wantExpired {
+				t.Errorf("Expired() = %v, want %v", got, tt.wantExpired)
+			}
+			if got := s.Remaining(now); got != tt.wantRemaining {
+				t.Errorf("Remaining() = %v, want %v", got, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestContainerInUse(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	states := []*State{
+		{Project: "mystore", Backend: BackendMySQL, Container: "magebox-mysql-8.0", Until: now.Add(time.Hour)},
+		{Project: "expired", Backend: BackendMySQL, Container: "magebox-mysql-8.4", Until: now.Add(-time.Hour)},
+		{Project: "magento", Backend: BackendMagento, Container: "", Until: now.Add(time.Hour)},
+	}
+	for _, s := range states {
+		if err := s.Save(StatePath(dir, s.Project)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !ContainerInUse(dir, "magebox-mysql-8.0", "other", now) {
+		t.Error("container used by mystore should be in use for other projects")
+	}
+	if ContainerInUse(dir, "magebox-mysql-8.0", "mystore", now) {
+		t.Error("a project should not keep its own container in use")
+	}
+	if ContainerInUse(dir, "magebox-mysql-8.4", "other", now) {
+		t.Error("expired logs should not keep a container in use")
+	}
+}
+
+func TestFormatter_Magento(t *testing.T) {
+	log := "## 2024-05-01 10:00:00\r\n" +
+		"## 1234 ## QUERY\r\n" +
+		"SQL: SELECT * FROM store\r\n" +
+		"AFF: 1\r\n" +
+		"TIME: 0.0004\r\n" +
+		"\r\n" +
+		"## 2024-05-01 10:00:01\r\n" +
+		"## 1234 ## QUERY\r\n" +
+		"SQL: SELECT * FROM catalog_product_entity\r\n" +
+		"AFF: 5000\r\n" +
+		"TIME: 1.2500\r\n"
+
+	var out bytes.Buffer
+	f := NewFormatter(&out, &State{Backend: BackendMagento, Slow: 100 * time.Millisecond}, func(s string) string {
+		return "<slow>" + s + "</slow>"
+	})
+	for _, line := range strings.Split(log, "\n") {
+		f.Line(line)
+	}
+	f.Flush()
+
+	got := out.String()
+	if strings.Contains(got, "<slow>## 2024-05-01 10:00:00") {
+		t.Error("fast query should not be highlighted")
+	}
+	if !strings.Contains(got, "<slow>## 2024-05-01 10:00:01\n## 1234 ## QUERY\nSQL: SELECT * FROM catalog_product_entity\nAFF: 5000\nTIME: 1.2500</slow>") {
+		t.Errorf("slow query should be highlighted, got:\n%s", got)
+	}
+}
+
+func TestFormatter_GeneralLog(t *testing.T) {
+	log := []string{
+		"/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:",
+		"Time                 Id Command    Argument",
+		"2024-05-01T10:00:00.000001Z\t   12 Connect\tmystore@172.18.0.1 on mystore using TCP/IP",
+		"2024-05-01T10:00:00.000002Z\t   13 Connect\tother@172.18.0.1 on other using TCP/IP",
+		"2024-05-01T10:00:00.000003Z\t   12 Query\tSELECT *",
+		"FROM store",
+		"2024-05-01T10:00:00.000004Z\t   13 Query\tSELECT 'other project'",
+		"2024-05-01T10:00:00.000005Z\t   13 Init DB\tmystore",
+		"2024-05-01T10:00:00.000006Z\t   13 Query\tSELECT 'switched'",
+		"240501 10:00:00\t   14 Connect\troot@localhost on  using Socket",
+		"\t\t   14 Query\tSELECT 'no database'",
+		"2024-05-01T10:00:00.000007Z\t   12 Quit\t",
+	}
+
+	var out bytes.Buffer
+	f := NewFormatter(&out, &State{Backend: BackendMySQL, Database: "mystore"}, nil)
+	for _, line := range log {
+		f.Line(line)
+	}
+
+	got := out.String()
+	for _, want := range []string{"SELECT *", "FROM store", "SELECT 'switched'", "12 Quit"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"other project", "no database", "Version: 8.0.36"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output should not contain %q, got:\n%s", unwanted, got)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Package queue supervises Magento message queue consumers for MageBox
+// projects.
+//
+// A supervisor runs each consumer as a child process, restarts it when it
+// exits, crash or not, and records every consumer's PID, restart and crash
+// counts and last exit in a state file that 'magebox queue status' reads. Consumers that
+// keep exiting right after starting are restarted with a growing delay.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// Restart delays of a consumer that exits before MinUptime
+const (
+	DefaultMinUptime   = 10 * time.Second
+	DefaultMinBackoff  = time.Second
+	DefaultMaxBackoff  = time.Minute
+	DefaultStopTimeout = 15 * time.Second
+)
+
+// ParseConsumers returns the consumer names of bin/magento
+// queue:consumers:list output, one per line
+func ParseConsumers(output []byte) []string {
+	var consumers []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			consumers = append(consumers, name)
+		}
+	}
+	return consumers
+}
+
+// State is what a supervisor records about its consumers
+type State struct {
+	PID       int                       `json:"pid"` // Supervisor PID
+	StartedAt time.Time                 `json:"started_at"`
+	Consumers map[string]*ConsumerState `json:"consumers"`
+}
+
+// ConsumerState is the state of one supervised consumer
+type ConsumerState struct {
+	PID        int       `json:"pid,omitempty"` // 0 while waiting to restart
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	Restarts   int       `json:"restarts"`
+	Crashes    int       `json:"crashes"` // Restarts after a failed start or non-zero exit
+	LastExit   string    `json:"last_exit,omitempty"`
+	LastExitAt time.Time `json:"last_exit_at,omitempty"`
+}
+
+// Names returns the consumer names in alphabetical order
+func (s *State) Names() []string {
+	names := make([]string, 0, len(s.Consumers))
+	for name := range s.Consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadState reads a state file. A missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	s := &State{Consumers: make(map[string]*ConsumerState)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid queue state %s: %w", path, err)
+	}
+	if s.Consumers == nil {
+		s.Consumers = make(map[string]*ConsumerState)
+	}
+	return s, nil
+}
+
+// Save writes the state atomically
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, data, 0644)
+}
+
+// Supervisor runs consumers and restarts them when they exit
+type Supervisor struct {
+	Consumers []string
+	// Command returns the command running one consumer
+	Command   func(ctx context.Context, consumer string) *exec.Cmd
+	StatePath string
+	Log       io.Writer // Consumer output and restart messages
+
+	MinUptime   time.Duration // Exits sooner than this grow the restart delay
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	StopTimeout time.Duration // How long a consumer gets to exit after SIGTERM
+
+	mu    sync.Mutex
+	state *State
+}
+
+// Run starts every consumer and supervises them until ctx is done, then
+// stops them with SIGTERM
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.state = &State{PID: os.Getpid(), StartedAt: time.Now(), Consumers: make(map[string]*ConsumerState)}
+	for _, name := range s.Consumers {
+		s.state.Consumers[name] = &ConsumerState{}
+	}
+	if err := s.save(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range s.Consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.supervise(ctx, name)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// supervise runs one consumer until ctx is done
+func (s *Supervisor) supervise(ctx context.Context, name string) {
+	backoff := s.minBackoff()
+	for {
+		cmd := s.Command(ctx, name)
+		cmd.Stdout = s.Log
+		cmd.Stderr = s.Log
+		cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+		cmd.WaitDelay = s.stopTimeout()
+
+		started := time.Now()
+		err := cmd.Start()
+		if err == nil {
+			s.update(name, func(c *ConsumerState) {
+				c.PID = cmd.Process.Pid
+				c.StartedAt = started
+			})
+			s.logf("%s started (PID %d)", name, cmd.Process.Pid)
+			err = cmd.Wait()
+		}
+		if ctx.Err() != nil {
+			s.update(name, func(c *ConsumerState) { c.PID = 0 })
+			s.logf("%s stopped", name)
+			return
+		}
+
+		exit := "exited"
+		if err != nil {
+			exit = err.Error()
+		}
+		if time.Since(started) < s.minUptime() {
+			backoff = min(backoff*2, s.maxBackoff())
+		} else {
+			backoff = s.minBackoff()
+		}
+		s.update(name, func(c *ConsumerState) {
+			c.PID = 0
+			c.Restarts++
+			// Consumers exit with 0 after --max-messages, that's no crash
+			if err != nil {
+				c.Crashes++
+			}
+			c.LastExit = exit
+			c.LastExitAt = time.Now()
+		})
+		s.logf("%s %s, restarting in %s", name, exit, backoff)
+
+		select {
+		case <-ctx.Done():
+			s.logf("%s stopped", name)
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// update changes the state of a consumer and saves the state file
+func (s *Supervisor) update(name string, fn func(c *ConsumerState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.state.Consumers[name])
+	if err := s.state.Save(s.StatePath); err != nil {
+		s.logfLocked("failed to save queue state: %v", err)
+	}
+}
+
+func (s *Supervisor) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Save(s.StatePath)
+}
+
+func (s *Supervisor) logf(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logfLocked(format, args...)
+}
+
+func (s *Supervisor) logfLocked(format string, args ...any) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, "[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+	}
+}
+
+func (s *Supervisor) minUptime() time.Duration {
+	if s.MinUptime > 0 {
+		return s.MinUptime
+	}
+	return DefaultMinUptime
+}
+
+func (s *Supervisor) minBackoff() time.Duration {
+	if s.MinBackoff > 0 {
+		return s.MinBackoff
+	}
+	return DefaultMinBackoff
+}
+
+func (s *Supervisor) maxBackoff() time.Duration {
+	if s.MaxBackoff > 0 {
+		return s.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
+
+func (s *Supervisor) stopTimeout() time.Duration {
+	if s.StopTimeout > 0 {
+		return s.StopTimeout
+	}
+	return DefaultStopTimeout
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseConsumers(t *testing.T) {
+	output := "async.operations.all\nproduct_action_attribute.update\n\n  exportProcessor  \n"
+	want := []string{"async.operations.all", "product_action_attribute.update", "exportProcessor"}
+	if got := ParseConsumers([]byte(output)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseConsumers() = %v, want %v", got, want)
+	}
+	if got := ParseConsumers(nil); len(got) != 0 {
+		t.Errorf("ParseConsumers(nil) = %v", got)
+	}
+}
+
+func TestState_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "queue-mystore.json")
+
+	empty, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState of a missing file failed: %v", err)
+	}
+	if len(empty.Consumers) != 0 {
+		t.Errorf("missing state file should be empty, got %v", empty.Consumers)
+	}
+
+	s := &State{PID: 42, Consumers: map[string]*ConsumerState{
+		"b.consumer": {PID: 100, Restarts: 2, LastExit: "exit status 255"},
+		"a.consumer": {},
+	}}
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	got, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if got.PID != 42 || got.Consumers["b.consumer"].Restarts != 2 || got.Consumers["b.consumer"].LastExit != "exit status 255" {
+		t.Errorf("LoadState() = %+v", got)
+	}
+	if names := got.Names(); !reflect.DeepEqual(names, []string{"a.consumer", "b.consumer"}) {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the supervisor's goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSupervisor_RestartsAndStops(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	statePath := filepath.Join(t.TempDir(), "queue.json")
+	var log syncBuffer
+	s := &Supervisor{
+		Consumers: []string{"crashing", "finishing", "steady"},
+		Command: func(ctx context.Context, consumer string) *exec.Cmd {
+			switch consumer {
+			case "crashing":
+				return exec.CommandContext(ctx, "sh", "-c", "exit 3")
+			case "finishing":
+				return exec.CommandContext(ctx, "sh", "-c", "exit 0")
+			}
+			return exec.CommandContext(ctx, "sleep", "30")
+		},
+		StatePath:   statePath,
+		Log:         &log,
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+		StopTimeout: time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, err := LoadState(statePath)
+		if err == nil && state.Consumers["crashing"] != nil && state.Consumers["crashing"].Restarts >= 3 &&
+			state.Consumers["finishing"] != nil && state.Consumers["finishing"].Restarts >= 2 &&
+			state.Consumers["steady"] != nil && state.Consumers["steady"].PID != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("consumers were not restarted in time, log:\n%s", log.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Consumers["steady"].PID != 0 || state.Consumers["steady"].Restarts != 0 {
+		t.Errorf("steady consumer state = %+v", state.Consumers["steady"])
+	}
+	if c := state.Consumers["crashing"]; c.LastExit != "exit status 3" || c.Crashes != c.Restarts {
+		t.Errorf("crashing consumer state = %+v", c)
+	}
+	if c := state.Consumers["finishing"]; c.Crashes != 0 {
+		t.Errorf("a consumer exiting with 0 should not count as crashed: %+v", c)
+	}
+	if !strings.Contains(log.String(), "crashing exit status 3, restarting in") {
+		t.Errorf("log should report the restart, got:\n%s", log.String())
+	}
+}
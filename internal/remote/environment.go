@@ -19,8 +19,38 @@ type Environment struct {
 	Port       int    `yaml:"port,omitempty"`
 	SSHKeyPath string `yaml:"ssh_key,omitempty"`
 	SSHCommand string `yaml:"ssh_command,omitempty"` // Custom SSH command for tunnels
+
+	// Project environments (the environments: block of .magebox.yaml) also
+	// describe where the application lives and how to reach its database
+	Path string    `yaml:"path,omitempty"`
+	DB   *DBAccess `yaml:"db,omitempty"`
+}
+
+// Database access methods
+const (
+	// DBAccessMagerun dumps with n98-magerun2 on the remote host, which reads
+	// the credentials from the remote app/etc/env.php
+	DBAccessMagerun = "magerun"
+	// DBAccessMysqldump runs mysqldump on the remote host with the configured credentials
+	DBAccessMysqldump = "mysqldump"
+	// DBAccessTunnel forwards a local port to a database host that is only
+	// reachable from the remote server
+	DBAccessTunnel = "tunnel"
+)
+
+// DBAccess describes how to reach the database of an environment
+type DBAccess struct {
+	Method   string `yaml:"method,omitempty"`
+	Host     string `yaml:"host,omitempty"`
+	Port     int    `yaml:"port,omitempty"`
+	Name     string `yaml:"name,omitempty"`
+	User     string `yaml:"user,omitempty"`
+	Password string `yaml:"password,omitempty"`
 }
 
+// DefaultDBPort is the database port used when none is configured
+const DefaultDBPort = 3306
+
 // DefaultPort is the default SSH port
 const DefaultPort = 22
 
@@ -46,6 +76,37 @@ func (e *Environment) Validate() error {
 	return nil
 }
 
+// ValidateDB checks the database access settings
+func (e *Environment) ValidateDB() error {
+	if e.DB == nil {
+		return nil
+	}
+	switch e.GetDBMethod() {
+	case DBAccessMagerun:
+		if e.Path == "" {
+			return fmt.Errorf("db method %q requires path", DBAccessMagerun)
+		}
+	case DBAccessMysqldump, DBAccessTunnel:
+		if e.DB.Name == "" {
+			return fmt.Errorf("db method %q requires db.name", e.DB.Method)
+		}
+	default:
+		return fmt.Errorf("unknown db method %q (use %s, %s or %s)", e.DB.Method, DBAccessMagerun, DBAccessMysqldump, DBAccessTunnel)
+	}
+	if e.DB.Port < 0 || e.DB.Port > 65535 {
+		return fmt.Errorf("db.port must be between 0 and 65535")
+	}
+	return nil
+}
+
+// GetDBMethod returns the database access method, defaulting to magerun
+func (e *Environment) GetDBMethod() string {
+	if e.DB == nil || e.DB.Method == "" {
+		return DBAccessMagerun
+	}
+	return e.DB.Method
+}
+
 // GetPort returns the port, defaulting to 22 if not set
 func (e *Environment) GetPort() int {
 	if e.Port == 0 {
@@ -66,7 +127,7 @@ func (e *Environment) BuildSSHCommand(additionalArgs ...string) *exec.Cmd {
 
 	// Add SSH key if specified
 	if e.SSHKeyPath != "" {
-		args = append(args, "-i", e.SSHKeyPath)
+		args = append(args, "-i", expandHome(e.SSHKeyPath))
 	}
 
 	// Add port if non-standard
@@ -78,15 +139,148 @@ func (e *Environment) BuildSSHCommand(additionalArgs ...string) *exec.Cmd {
 	args = append(args, additionalArgs...)
 
 	// Add user@host
-	args = append(args, fmt.Sprintf("%s@%s", e.User, e.Host))
+	args = append(args, e.Destination())
 
 	return exec.Command("ssh", args...)
 }
 
+// BuildRemoteCommand builds an SSH command that runs command on the remote
+// host, inside the environment path when one is set
+func (e *Environment) BuildRemoteCommand(command string, additionalArgs ...string) *exec.Cmd {
+	if e.Path != "" {
+		command = "cd " + ShellQuote(e.Path) + " && " + command
+	}
+	if e.SSHCommand != "" {
+		return exec.Command("sh", "-c", e.SSHCommand+" "+ShellQuote(command))
+	}
+	cmd := e.BuildSSHCommand(additionalArgs...)
+	cmd.Args = append(cmd.Args, command)
+	return cmd
+}
+
+// DBDumpCommand returns the remote shell command that writes a SQL dump of
+// the environment's database to stdout
+func (e *Environment) DBDumpCommand() (string, error) {
+	if err := e.ValidateDB(); err != nil {
+		return "", err
+	}
+	switch e.GetDBMethod() {
+	case DBAccessMysqldump:
+		args := []string{"mysqldump", "--single-transaction", "--quick", "--routines", "--no-tablespaces"}
+		if e.DB.Host != "" {
+			args = append(args, "-h", ShellQuote(e.DB.Host))
+		}
+		if e.DB.Port != 0 {
+			args = append(args, "-P", strconv.Itoa(e.DB.Port))
+		}
+		if e.DB.User != "" {
+			args = append(args, "-u", ShellQuote(e.DB.User))
+		}
+		args = append(args, ShellQuote(e.DB.Name))
+		dump := strings.Join(args, " ")
+		// Pass the password through the environment so it does not show up in ps
+		if e.DB.Password != "" {
+			dump = "MYSQL_PWD=" + ShellQuote(e.DB.Password) + " " + dump
+		}
+		return dump, nil
+	case DBAccessTunnel:
+		return "", fmt.Errorf("db method %q connects through a local port forward, use TunnelArgs", DBAccessTunnel)
+	default:
+		return "n98-magerun2 db:dump --stdout --no-interaction", nil
+	}
+}
+
+// TunnelArgs returns the SSH arguments that forward localPort to the
+// environment's database host as seen from the remote server
+func (e *Environment) TunnelArgs(localPort int) []string {
+	host, port := "127.0.0.1", DefaultDBPort
+	if e.DB != nil {
+		if e.DB.Host != "" {
+			host = e.DB.Host
+		}
+		if e.DB.Port != 0 {
+			port = e.DB.Port
+		}
+	}
+	return []string{"-N", "-L", fmt.Sprintf("%d:%s:%d", localPort, host, port)}
+}
+
+// TunnelDumpCommand returns the local mysqldump command that dumps the
+// environment's database through a port forward opened with TunnelArgs
+func (e *Environment) TunnelDumpCommand(localPort int) (*exec.Cmd, error) {
+	if e.GetDBMethod() != DBAccessTunnel {
+		return nil, fmt.Errorf("db method %q dumps on the remote host, use DBDumpCommand", e.GetDBMethod())
+	}
+	if err := e.ValidateDB(); err != nil {
+		return nil, err
+	}
+	args := []string{"--single-transaction", "--quick", "--routines", "--no-tablespaces",
+		"-h", "127.0.0.1", "-P", strconv.Itoa(localPort)}
+	if e.DB.User != "" {
+		args = append(args, "-u", e.DB.User)
+	}
+	args = append(args, e.DB.Name)
+	cmd := exec.Command("mysqldump", args...)
+	// Pass the password through the environment so it does not show up in ps
+	if e.DB.Password != "" {
+		cmd.Env = append(os.Environ(), "MYSQL_PWD="+e.DB.Password)
+	}
+	return cmd, nil
+}
+
+// RsyncShell returns the remote shell for rsync -e, carrying the port and key
+func (e *Environment) RsyncShell() string {
+	parts := []string{"ssh"}
+	if e.GetPort() != DefaultPort {
+		parts = append(parts, "-p", strconv.Itoa(e.GetPort()))
+	}
+	if e.SSHKeyPath != "" {
+		parts = append(parts, "-i", ShellQuote(expandHome(e.SSHKeyPath)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// RsyncPath returns the rsync source/target for a path relative to the
+// environment path, e.g. deploy@host:/var/www/shop/pub/media
+func (e *Environment) RsyncPath(rel string) string {
+	p := e.Path
+	if rel != "" {
+		p = strings.TrimSuffix(p, "/") + "/" + strings.TrimPrefix(rel, "/")
+	}
+	return e.Destination() + ":" + p
+}
+
+// Destination returns user@host, or just the host when the user comes from
+// ~/.ssh/config (e.g. a personal SSH alias)
+func (e *Environment) Destination() string {
+	if e.User == "" {
+		return e.Host
+	}
+	return e.User + "@" + e.Host
+}
+
+// ShellQuote quotes a string for a POSIX shell
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// expandHome expands a leading ~ to the user's home directory, so key paths in
+// a shared .magebox.yaml work for every developer
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
 // GetConnectionString returns a human-readable connection string
 func (e *Environment) GetConnectionString() string {
 	var parts []string
-	parts = append(parts, fmt.Sprintf("%s@%s", e.User, e.Host))
+	parts = append(parts, e.Destination())
 
 	if e.GetPort() != DefaultPort {
 		parts[0] = fmt.Sprintf("%s:%d", parts[0], e.GetPort())
@@ -6,6 +6,7 @@ package remote
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -494,3 +495,107 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestEnvironment_BuildRemoteCommand(t *testing.T) {
+	env := Environment{User: "deploy", Host: "staging.example.com", Port: 2222, Path: "/var/www/my shop"}
+
+	cmd := env.BuildRemoteCommand("bin/magento cache:flush", "-t")
+	want := []string{"ssh", "-p", "2222", "-t", "deploy@staging.example.com", "cd '/var/www/my shop' && bin/magento cache:flush"}
+	if strings.Join(cmd.Args, "|") != strings.Join(want, "|") {
+		t.Errorf("Args = %q, want %q", cmd.Args, want)
+	}
+
+	custom := Environment{SSHCommand: "ssh -J bastion deploy@internal", Path: "/srv/app"}
+	cmd = custom.BuildRemoteCommand("ls")
+	if got := cmd.Args[len(cmd.Args)-1]; got != `ssh -J bastion deploy@internal 'cd '\''/srv/app'\'' && ls'` {
+		t.Errorf("custom command = %s", got)
+	}
+}
+
+func TestEnvironment_DBDumpCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     Environment
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "magerun by default",
+			env:  Environment{Path: "/var/www/shop"},
+			want: "n98-magerun2 db:dump --stdout --no-interaction",
+		},
+		{
+			name: "mysqldump with credentials",
+			env: Environment{DB: &DBAccess{
+				Method: DBAccessMysqldump, Host: "db.internal", Port: 3307, Name: "shop", User: "app", Password: "it's",
+			}},
+			want: `MYSQL_PWD='it'\''s' mysqldump --single-transaction --quick --routines --no-tablespaces -h 'db.internal' -P 3307 -u 'app' 'shop'`,
+		},
+		{
+			name:    "mysqldump without database name",
+			env:     Environment{DB: &DBAccess{Method: DBAccessMysqldump}},
+			wantErr: true,
+		},
+		{
+			name:    "tunnel dumps locally",
+			env:     Environment{DB: &DBAccess{Method: DBAccessTunnel, Name: "shop"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.env.DBDumpCommand()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DBDumpCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DBDumpCommand() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironment_TunnelAndRsync(t *testing.T) {
+	env := Environment{User: "deploy", Host: "prod.example.com", Port: 2222, Path: "/var/www/shop/"}
+
+	if got := strings.Join(env.TunnelArgs(33306), " "); got != "-N -L 33306:127.0.0.1:3306" {
+		t.Errorf("TunnelArgs() = %s", got)
+	}
+	env.DB = &DBAccess{Method: DBAccessTunnel, Host: "db.internal", Port: 3307, Name: "shop"}
+	if got := strings.Join(env.TunnelArgs(33306), " "); got != "-N -L 33306:db.internal:3307" {
+		t.Errorf("TunnelArgs() = %s", got)
+	}
+
+	if got := env.RsyncPath("pub/media/"); got != "deploy@prod.example.com:/var/www/shop/pub/media/" {
+		t.Errorf("RsyncPath() = %s", got)
+	}
+	if got := env.RsyncShell(); got != "ssh -p 2222" {
+		t.Errorf("RsyncShell() = %s", got)
+	}
+
+	alias := Environment{Host: "shop-prod", Path: "/srv"}
+	if got := alias.RsyncPath(""); got != "shop-prod:/srv" {
+		t.Errorf("RsyncPath() for SSH alias = %s", got)
+	}
+}
+
+func TestEnvironment_TunnelDumpCommand(t *testing.T) {
+	env := Environment{DB: &DBAccess{Method: DBAccessTunnel, Host: "db.internal", Name: "shop", User: "app", Password: "secret"}}
+
+	cmd, err := env.TunnelDumpCommand(33306)
+	if err != nil {
+		t.Fatalf("TunnelDumpCommand() error = %v", err)
+	}
+	want := "mysqldump --single-transaction --quick --routines --no-tablespaces -h 127.0.0.1 -P 33306 -u app shop"
+	if got := strings.Join(cmd.Args, " "); got != want {
+		t.Errorf("Args = %s, want %s", got, want)
+	}
+	if got := cmd.Env[len(cmd.Env)-1]; got != "MYSQL_PWD=secret" {
+		t.Errorf("password env = %s", got)
+	}
+
+	if _, err := (&Environment{Path: "/srv"}).TunnelDumpCommand(33306); err == nil {
+		t.Error("TunnelDumpCommand() should fail for magerun environments")
+	}
+}
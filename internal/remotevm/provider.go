@@ -0,0 +1,127 @@
+// Package remotevm runs MageBox stacks on a remote Linux VM while the
+// project's domains keep working on the local machine, through SSH tunnels
+// and local Nginx proxy vhosts.
+package remotevm
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Target is the VM a project runs on
+type Target struct {
+	Provider string `json:"provider"`          // Provider name, "ssh" by default
+	Host     string `json:"host"`              // Host name or ~/.ssh/config alias
+	User     string `json:"user,omitempty"`    // Login user, empty to use ~/.ssh/config
+	Port     int    `json:"port,omitempty"`    // SSH port, 0 for the default
+	SSHKey   string `json:"ssh_key,omitempty"` // Private key, empty to use the agent or ~/.ssh/config
+}
+
+// Forward forwards a local port to a port on the VM
+type Forward struct {
+	Local  int `json:"local"`
+	Remote int `json:"remote"`
+}
+
+// String returns the forward as local:remote
+func (f Forward) String() string {
+	return fmt.Sprintf("%d:%d", f.Local, f.Remote)
+}
+
+// Provider reaches a remote VM. SSH is built in; other providers (cloud APIs
+// that create the VM first, container hosts) plug in through Register.
+type Provider interface {
+	// Name returns the provider name recorded in the attach state
+	Name() string
+	// Command runs a shell command on the VM, inside dir when it isn't
+	// empty; tty allocates a terminal for interactive commands
+	Command(dir, command string, tty bool) *exec.Cmd
+	// Sync copies paths (relative to localRoot) to dir on the VM
+	Sync(localRoot string, paths []string, dir string) *exec.Cmd
+	// Tunnel forwards local ports to the VM until the command exits
+	Tunnel(forwards []Forward) *exec.Cmd
+}
+
+// DefaultProvider is the provider used when a target doesn't name one
+const DefaultProvider = "ssh"
+
+var providers = map[string]func(Target) (Provider, error){
+	DefaultProvider: newSSHProvider,
+}
+
+// Register adds a provider factory under name
+func Register(name string, factory func(Target) (Provider, error)) {
+	providers[name] = factory
+}
+
+// Providers returns the registered provider names
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider returns the provider that reaches target
+func NewProvider(target Target) (Provider, error) {
+	name := target.Provider
+	if name == "" {
+		name = DefaultProvider
+	}
+	factory, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown remote provider %q (available: %s)", name, strings.Join(Providers(), ", "))
+	}
+	return factory(target)
+}
+
+// ParseTarget parses [user@]host[:port] as given on the command line
+func ParseTarget(spec string) (Target, error) {
+	t := Target{Provider: DefaultProvider}
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		t.User, spec = spec[:at], spec[at+1:]
+	}
+	if colon := strings.LastIndex(spec, ":"); colon >= 0 {
+		port, err := strconv.Atoi(spec[colon+1:])
+		if err != nil || port <= 0 || port > 65535 {
+			return Target{}, fmt.Errorf("invalid SSH port in %q", spec)
+		}
+		t.Port, spec = port, spec[:colon]
+	}
+	if spec == "" || strings.ContainsAny(spec, " /") {
+		return Target{}, fmt.Errorf("invalid SSH host %q", spec)
+	}
+	t.Host = spec
+	return t, nil
+}
+
+// String returns the target as [user@]host[:port]
+func (t Target) String() string {
+	s := t.Host
+	if t.User != "" {
+		s = t.User + "@" + s
+	}
+	if t.Port != 0 {
+		s += ":" + strconv.Itoa(t.Port)
+	}
+	return s
+}
+
+// ParseForward parses port or local:remote
+func ParseForward(spec string) (Forward, error) {
+	local, remote, found := strings.Cut(spec, ":")
+	if !found {
+		remote = local
+	}
+	l, err1 := strconv.Atoi(local)
+	r, err2 := strconv.Atoi(remote)
+	if err1 != nil || err2 != nil || l <= 0 || l > 65535 || r <= 0 || r > 65535 {
+		return Forward{}, fmt.Errorf("invalid port forward %q (use port or local:remote)", spec)
+	}
+	return Forward{Local: l, Remote: r}, nil
+}
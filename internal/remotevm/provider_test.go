@@ -0,0 +1,119 @@
+package remotevm
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Target
+		wantErr bool
+	}{
+		{spec: "build-box", want: Target{Provider: "ssh", Host: "build-box"}},
+		{spec: "dev@build-box", want: Target{Provider: "ssh", Host: "build-box", User: "dev"}},
+		{spec: "dev@10.0.0.5:2222", want: Target{Provider: "ssh", Host: "10.0.0.5", User: "dev", Port: 2222}},
+		{spec: "dev@", wantErr: true},
+		{spec: "build-box:ssh", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseTarget(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseTarget() = %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.spec {
+				t.Errorf("String() = %q, want %q", got.String(), tt.spec)
+			}
+		})
+	}
+}
+
+func TestParseForward(t *testing.T) {
+	if f, err := ParseForward("8025"); err != nil || f != (Forward{Local: 8025, Remote: 8025}) {
+		t.Errorf("ParseForward(8025) = %+v, %v", f, err)
+	}
+	if f, err := ParseForward("13306:33080"); err != nil || f != (Forward{Local: 13306, Remote: 33080}) {
+		t.Errorf("ParseForward(13306:33080) = %+v, %v", f, err)
+	}
+	for _, spec := range []string{"", "mysql", "0", "70000:80"} {
+		if _, err := ParseForward(spec); err == nil {
+			t.Errorf("ParseForward(%q) should fail", spec)
+		}
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	if _, err := NewProvider(Target{Host: "build-box"}); err != nil {
+		t.Errorf("NewProvider() without provider should default to ssh: %v", err)
+	}
+	if _, err := NewProvider(Target{Provider: "hetzner", Host: "build-box"}); err == nil || !strings.Contains(err.Error(), "available: ssh") {
+		t.Errorf("NewProvider() error = %v, want unknown provider", err)
+	}
+}
+
+func TestSSHProvider_Commands(t *testing.T) {
+	p, err := NewProvider(Target{Host: "build-box", User: "dev", Port: 2222})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := p.Command("magebox/mystore", "magebox start", true)
+	want := []string{"ssh", "-p", "2222", "-t", "dev@build-box", "cd 'magebox/mystore' && magebox start"}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Command() args = %q, want %q", cmd.Args, want)
+	}
+
+	cmd = p.Sync("/home/me/mystore", []string{".magebox.yaml", ".magebox"}, "magebox/mystore")
+	want = []string{"rsync", "-az", "--relative", "-e", "ssh -p 2222", "./.magebox.yaml", "./.magebox", "dev@build-box:magebox/mystore/"}
+	if !reflect.DeepEqual(cmd.Args, want) || cmd.Dir != "/home/me/mystore" {
+		t.Errorf("Sync() args = %q in %s, want %q", cmd.Args, cmd.Dir, want)
+	}
+
+	cmd = p.Tunnel([]Forward{{Local: 18443, Remote: 443}})
+	if args := strings.Join(cmd.Args, " "); !strings.Contains(args, "-N") || !strings.Contains(args, "-L 127.0.0.1:18443:127.0.0.1:443") || !strings.HasSuffix(args, "dev@build-box") {
+		t.Errorf("Tunnel() args = %q", args)
+	}
+}
+
+func TestState_SaveLoad(t *testing.T) {
+	path := StatePath(t.TempDir(), "mystore")
+	if filepath.Base(path) != "mystore.json" {
+		t.Errorf("StatePath() = %s", path)
+	}
+
+	s := &State{
+		Project:    "mystore",
+		Target:     Target{Provider: "ssh", Host: "build-box"},
+		Dir:        DefaultDir("mystore"),
+		Domains:    []string{"mystore.test"},
+		HTTPPort:   DefaultHTTPPort,
+		HTTPSPort:  DefaultHTTPSPort,
+		Forwards:   []Forward{{Local: 8025, Remote: 8025}},
+		TunnelPID:  4242,
+		AttachedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("LoadState() = %+v, want %+v", loaded, s)
+	}
+
+	forwards := loaded.AllForwards()
+	if len(forwards) != 3 || forwards[0].Remote != 80 || forwards[1].Remote != 443 {
+		t.Errorf("AllForwards() = %v", forwards)
+	}
+}
@@ -0,0 +1,67 @@
+package remotevm
+
+import (
+	"fmt"
+	"os/exec"
+
+	"qoliber/magebox/internal/remote"
+)
+
+// sshProvider reaches a VM over plain SSH, with rsync for file syncs
+type sshProvider struct {
+	env remote.Environment
+}
+
+func newSSHProvider(t Target) (Provider, error) {
+	if t.Host == "" {
+		return nil, fmt.Errorf("SSH host is required")
+	}
+	return &sshProvider{env: remote.Environment{
+		Name:       t.Host,
+		User:       t.User,
+		Host:       t.Host,
+		Port:       t.Port,
+		SSHKeyPath: t.SSHKey,
+	}}, nil
+}
+
+func (p *sshProvider) Name() string {
+	return DefaultProvider
+}
+
+func (p *sshProvider) Command(dir, command string, tty bool) *exec.Cmd {
+	if dir != "" {
+		command = "cd " + remote.ShellQuote(dir) + " && " + command
+	}
+	var args []string
+	if tty {
+		args = append(args, "-t")
+	}
+	cmd := p.env.BuildSSHCommand(args...)
+	cmd.Args = append(cmd.Args, command)
+	return cmd
+}
+
+func (p *sshProvider) Sync(localRoot string, paths []string, dir string) *exec.Cmd {
+	// --relative keeps the paths below dir instead of flattening them
+	args := []string{"-az", "--relative", "-e", p.env.RsyncShell()}
+	for _, path := range paths {
+		args = append(args, "./"+path)
+	}
+	args = append(args, p.env.Destination()+":"+dir+"/")
+	cmd := exec.Command("rsync", args...)
+	cmd.Dir = localRoot
+	return cmd
+}
+
+func (p *sshProvider) Tunnel(forwards []Forward) *exec.Cmd {
+	args := []string{"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+		"-o", "ServerAliveCountMax=3",
+	}
+	for _, f := range forwards {
+		args = append(args, "-L", fmt.Sprintf("127.0.0.1:%d:127.0.0.1:%d", f.Local, f.Remote))
+	}
+	return p.env.BuildSSHCommand(args...)
+}
@@ -0,0 +1,104 @@
+package remotevm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"qoliber/magebox/internal/fileutil"
+)
+
+// Local ports the VM's Nginx is reached on; the local proxy vhosts forward
+// the project domains to them
+const (
+	DefaultHTTPPort  = 18080
+	DefaultHTTPSPort = 18443
+)
+
+// ProvisionScript installs MageBox on the VM unless it is already there
+const ProvisionScript = `set -e
+if [ "$(uname -s)" != "Linux" ]; then
+    echo "MageBox remote VMs must run Linux" >&2
+    exit 1
+fi
+export PATH="$HOME/.local/bin:/usr/local/bin:$PATH"
+if ! command -v magebox >/dev/null 2>&1; then
+    curl -fsSL https://get.magebox.dev | bash
+fi
+magebox --version`
+
+// projectFiles are the files that make up the project config
+var projectFiles = []string{".magebox.yaml", ".magebox.local.yaml", ".magebox"}
+
+// ProjectFiles returns the project config files that exist below projectPath
+func ProjectFiles(projectPath string) []string {
+	var files []string
+	for _, f := range projectFiles {
+		if _, err := os.Stat(filepath.Join(projectPath, f)); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// DefaultDir returns the project directory on the VM, relative to the
+// remote user's home
+func DefaultDir(project string) string {
+	return "magebox/" + project
+}
+
+// RemoteCommand prefixes a MageBox command so it finds the binary the
+// installer put in ~/.local/bin on non-login shells
+func RemoteCommand(command string) string {
+	return `PATH="$HOME/.local/bin:/usr/local/bin:$PATH" ` + command
+}
+
+// State records a project attached to a remote VM
+type State struct {
+	Project    string    `json:"project"`
+	Target     Target    `json:"target"`
+	Dir        string    `json:"dir"`
+	Domains    []string  `json:"domains"`
+	HTTPPort   int       `json:"http_port"`
+	HTTPSPort  int       `json:"https_port"`
+	Forwards   []Forward `json:"forwards,omitempty"`
+	TunnelPID  int       `json:"tunnel_pid,omitempty"`
+	AttachedAt time.Time `json:"attached_at"`
+}
+
+// AllForwards returns the web forwards followed by the extra forwards
+func (s *State) AllForwards() []Forward {
+	forwards := []Forward{{Local: s.HTTPPort, Remote: 80}, {Local: s.HTTPSPort, Remote: 443}}
+	return append(forwards, s.Forwards...)
+}
+
+// StatePath returns where the attach state of a project is kept
+func StatePath(mageboxDir, project string) string {
+	return filepath.Join(mageboxDir, "remote", project+".json")
+}
+
+// LoadState reads an attach state; os.IsNotExist reports detached projects
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save writes the attach state to path
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(path, append(data, '\n'), 0600)
+}
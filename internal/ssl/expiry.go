@@ -0,0 +1,87 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RenewBefore is how long before it expires a certificate is renewed. mkcert
+// issues certificates valid for a little over two years.
+const RenewBefore = 30 * 24 * time.Hour
+
+// CertInfo describes a generated certificate
+type CertInfo struct {
+	Domain   string    // Base domain the certificate is stored under
+	CertFile string    // Path of cert.pem
+	Hosts    []string  // Host names the certificate is valid for
+	NotAfter time.Time // Expiry time
+}
+
+// Expired reports whether the certificate has expired at now
+func (c *CertInfo) Expired(now time.Time) bool {
+	return now.After(c.NotAfter)
+}
+
+// Expiring reports whether the certificate expires within RenewBefore of now
+func (c *CertInfo) Expiring(now time.Time) bool {
+	return now.Add(RenewBefore).After(c.NotAfter)
+}
+
+// DaysLeft returns the whole days until the certificate expires, negative
+// once it has expired
+func (c *CertInfo) DaysLeft(now time.Time) int {
+	return int(c.NotAfter.Sub(now).Hours() / 24)
+}
+
+// CertInfo reads the certificate of a domain
+func (m *Manager) CertInfo(domain string) (*CertInfo, error) {
+	return readCertInfo(domain, filepath.Join(m.certsDir, domain, "cert.pem"))
+}
+
+// RenewCert regenerates the certificate of a domain for the hosts it is
+// valid for, with a new expiry date
+func (m *Manager) RenewCert(domain string) (*CertPaths, error) {
+	if !m.IsMkcertInstalled() {
+		return nil, &MkcertNotInstalledError{Platform: m.platform}
+	}
+
+	info, err := m.CertInfo(domain)
+	if err != nil {
+		return nil, err
+	}
+	return m.generateCert(domain, info.Hosts)
+}
+
+// readCertInfo parses the PEM certificate at certFile
+func readCertInfo(domain, certFile string) (*CertInfo, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM certificate", certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", certFile, err)
+	}
+
+	return &CertInfo{
+		Domain:   domain,
+		CertFile: certFile,
+		Hosts:    cert.DNSNames,
+		NotAfter: cert.NotAfter,
+	}, nil
+}
+
+// certExpiring reports whether the certificate at certFile is due for
+// renewal. A certificate that can't be read is renewed as well.
+func certExpiring(certFile string, now time.Time) bool {
+	info, err := readCertInfo("", certFile)
+	return err != nil || info.Expiring(now)
+}
@@ -0,0 +1,81 @@
+package ssl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertInfo(t *testing.T) {
+	certsDir := t.TempDir()
+	domainDir := filepath.Join(certsDir, "mystore.test")
+	if err := os.MkdirAll(domainDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestCert(t, domainDir, []string{"mystore.test", "*.mystore.test"})
+
+	m := &Manager{certsDir: certsDir}
+	info, err := m.CertInfo("mystore.test")
+	if err != nil {
+		t.Fatalf("CertInfo() error = %v", err)
+	}
+	if info.Domain != "mystore.test" || len(info.Hosts) != 2 || info.Hosts[1] != "*.mystore.test" {
+		t.Errorf("CertInfo() = %+v", info)
+	}
+	// The test certificate expires in an hour
+	now := time.Now()
+	if info.Expired(now) || !info.Expiring(now) || info.DaysLeft(now) != 0 {
+		t.Errorf("Expired = %v, Expiring = %v, DaysLeft = %d", info.Expired(now), info.Expiring(now), info.DaysLeft(now))
+	}
+
+	if _, err := m.CertInfo("missing.test"); err == nil {
+		t.Error("CertInfo() of a missing certificate succeeded")
+	}
+}
+
+func TestCertInfo_Expiry(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		expired  bool
+		expiring bool
+		daysLeft int
+	}{
+		{"valid", now.AddDate(1, 0, 0), false, false, 365},
+		{"expiring", now.AddDate(0, 0, 10), false, true, 10},
+		{"expired", now.AddDate(0, 0, -3), true, true, -3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &CertInfo{NotAfter: tt.notAfter}
+			if got := info.Expired(now); got != tt.expired {
+				t.Errorf("Expired() = %v, want %v", got, tt.expired)
+			}
+			if got := info.Expiring(now); got != tt.expiring {
+				t.Errorf("Expiring() = %v, want %v", got, tt.expiring)
+			}
+			if got := info.DaysLeft(now); got != tt.daysLeft {
+				t.Errorf("DaysLeft() = %d, want %d", got, tt.daysLeft)
+			}
+		})
+	}
+}
+
+func TestCertExpiring(t *testing.T) {
+	dir := t.TempDir()
+	certFile := writeTestCert(t, dir, []string{"mystore.test"})
+
+	if !certExpiring(certFile, time.Now()) {
+		t.Error("certificate expiring in an hour is not due for renewal")
+	}
+	if certExpiring(certFile, time.Now().Add(-RenewBefore-2*time.Hour)) {
+		t.Error("certificate is due for renewal long before it expires")
+	}
+	if !certExpiring(filepath.Join(dir, "missing.pem"), time.Now()) {
+		t.Error("unreadable certificate is not due for renewal")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"qoliber/magebox/internal/lib"
 	"qoliber/magebox/internal/platform"
@@ -116,6 +117,27 @@ func (m *Manager) InstallCA() error {
 	return nil
 }
 
+// UninstallCA removes the mkcert CA from the system trust stores (runs mkcert -uninstall)
+func (m *Manager) UninstallCA() error {
+	if !m.IsMkcertInstalled() {
+		return &MkcertNotInstalledError{Platform: m.platform}
+	}
+
+	cmd := exec.Command("mkcert", "-uninstall")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to uninstall CA: %w\nOutput: %s", err, output)
+	}
+
+	m.caInstalled = false
+	return nil
+}
+
+// CARoot returns the directory holding the mkcert CA files
+func (m *Manager) CARoot() (string, error) {
+	return m.getCARoot()
+}
+
 // IsMkcertInstalled checks if mkcert is installed
 func (m *Manager) IsMkcertInstalled() bool {
 	return platform.CommandExists("mkcert")
@@ -142,22 +164,30 @@ func (m *Manager) GenerateCert(domain string) (*CertPaths, error) {
 // matches a single label: a deeply nested host such as
 // "shop.nl.b2b-case.localhost" is NOT covered by "*.b2b-case.localhost" and must
 // be listed explicitly. An existing certificate is reused only when it already
-// covers every requested host; otherwise it is regenerated.
+// covers every requested host and doesn't expire soon; otherwise it is
+// regenerated.
 func (m *Manager) EnsureCert(baseDomain string, hosts ...string) (*CertPaths, error) {
 	if !m.IsMkcertInstalled() {
 		return nil, &MkcertNotInstalledError{Platform: m.platform}
 	}
 
+	// Reuse the existing certificate only if it already covers every host.
+	paths := m.GetCertPaths(baseDomain)
+	if m.CertExists(baseDomain) && certCovers(paths.CertFile, hosts) && !certExpiring(paths.CertFile, time.Now()) {
+		return paths, nil
+	}
+
+	return m.generateCert(baseDomain, hosts)
+}
+
+// generateCert runs mkcert for "baseDomain", "*.baseDomain" and hosts,
+// replacing the certificate stored under the base domain
+func (m *Manager) generateCert(baseDomain string, hosts []string) (*CertPaths, error) {
 	domainDir := filepath.Join(m.certsDir, baseDomain)
 	certFile := filepath.Join(domainDir, "cert.pem")
 	keyFile := filepath.Join(domainDir, "key.pem")
 	paths := &CertPaths{CertFile: certFile, KeyFile: keyFile, Domain: baseDomain}
 
-	// Reuse the existing certificate only if it already covers every host.
-	if m.CertExists(baseDomain) && certCovers(certFile, hosts) {
-		return paths, nil
-	}
-
 	if err := os.MkdirAll(domainDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create certs directory: %w", err)
 	}
@@ -203,6 +233,40 @@ func certCovers(certFile string, hosts []string) bool {
 	return true
 }
 
+// IssuedByLocalCA reports whether a PEM certificate was issued by this
+// machine's mkcert CA. Certificates restored from another machine usually
+// aren't, and browsers won't trust them here.
+func (m *Manager) IssuedByLocalCA(certPEM []byte) bool {
+	caRoot, err := m.getCARoot()
+	if err != nil {
+		return false
+	}
+	caPEM, err := os.ReadFile(filepath.Join(caRoot, "rootCA.pem"))
+	if err != nil {
+		return false
+	}
+	return certSignedBy(certPEM, caPEM)
+}
+
+// certSignedBy reports whether the PEM certificate chains to the PEM CA
+func certSignedBy(certPEM, caPEM []byte) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return false
+	}
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	return err == nil
+}
+
 // dedupeStrings returns in with duplicate values removed, preserving order.
 func dedupeStrings(in []string) []string {
 	seen := make(map[string]bool, len(in))
@@ -331,6 +395,33 @@ func ExtractBaseDomain(hostname string) string {
 	return strings.Join(parts[len(parts)-2:], ".")
 }
 
+// ExtractBaseDomainForTLD extracts the base domain of a hostname on tld: the
+// label in front of the TLD, so projects on a TLD with several labels get a
+// certificate each
+// e.g., "api.mystore.acme.test" on "acme.test" -> "mystore.acme.test"
+// Hosts outside tld fall back to ExtractBaseDomain.
+func ExtractBaseDomainForTLD(hostname, tld string) string {
+	tld = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tld), "."))
+	rest, ok := strings.CutSuffix(hostname, "."+tld)
+	if tld == "" || !ok || rest == "" {
+		return ExtractBaseDomain(hostname)
+	}
+	return rest[strings.LastIndex(rest, ".")+1:] + "." + tld
+}
+
+// ExtractBaseDomainForTLDs extracts the base domain of a hostname on the
+// longest of tlds it ends with, e.g. "acme.test" rather than "test"
+func ExtractBaseDomainForTLDs(hostname string, tlds []string) string {
+	var match string
+	for _, tld := range tlds {
+		tld = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tld), "."))
+		if strings.HasSuffix(hostname, "."+tld) && len(tld) > len(match) {
+			match = tld
+		}
+	}
+	return ExtractBaseDomainForTLD(hostname, match)
+}
+
 // GroupDomainsByBase groups domains by their base domain
 // This helps generate fewer certificates with wildcards
 func GroupDomainsByBase(domains []string) map[string][]string {
@@ -201,6 +201,44 @@ func TestExtractBaseDomain(t *testing.T) {
 	}
 }
 
+func TestExtractBaseDomainForTLD(t *testing.T) {
+	tests := []struct {
+		host     string
+		tld      string
+		expected string
+	}{
+		{"api.mystore.test", "", "mystore.test"},
+		{"api.mystore.test", "test", "mystore.test"},
+		{"mystore.acme.test", "acme.test", "mystore.acme.test"},
+		{"api.mystore.acme.test", ".acme.test", "mystore.acme.test"},
+		{"shop.nl.b2b.localhost", "localhost", "b2b.localhost"},
+		{"api.other.test", "acme.test", "other.test"},
+		{"acme.test", "acme.test", "acme.test"},
+		{"api.mystore.acme.test", "Acme.Test", "mystore.acme.test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+"/"+tt.tld, func(t *testing.T) {
+			if got := ExtractBaseDomainForTLD(tt.host, tt.tld); got != tt.expected {
+				t.Errorf("ExtractBaseDomainForTLD(%q, %q) = %v, want %v", tt.host, tt.tld, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractBaseDomainForTLDs(t *testing.T) {
+	tlds := []string{"test", ".acme.test"}
+	if got := ExtractBaseDomainForTLDs("api.mystore.acme.test", tlds); got != "mystore.acme.test" {
+		t.Errorf("ExtractBaseDomainForTLDs() = %v, want mystore.acme.test", got)
+	}
+	if got := ExtractBaseDomainForTLDs("api.other.test", tlds); got != "other.test" {
+		t.Errorf("ExtractBaseDomainForTLDs() = %v, want other.test", got)
+	}
+	if got := ExtractBaseDomainForTLDs("api.shop.localhost", tlds); got != "shop.localhost" {
+		t.Errorf("ExtractBaseDomainForTLDs() = %v, want shop.localhost", got)
+	}
+}
+
 func TestGroupDomainsByBase(t *testing.T) {
 	domains := []string{
 		"mystore.test",
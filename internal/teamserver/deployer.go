@@ -23,6 +23,23 @@ import (
 // validUsernameRegex matches valid usernames (alphanumeric, underscore, hyphen, dot)
 var validUsernameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 
+// validHostnameRegex matches DNS host names: labels of letters, digits and
+// hyphens separated by dots
+var validHostnameRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+// ValidateSSHTarget checks the host and deploy user of an environment. They
+// end up in ssh_config and on ssh command lines, so whitespace, control
+// characters and a leading dash are refused.
+func ValidateSSHTarget(host, user string) error {
+	if net.ParseIP(host) == nil && (len(host) > 253 || !validHostnameRegex.MatchString(host)) {
+		return fmt.Errorf("invalid host %q: must be a host name or IP address", host)
+	}
+	if strings.HasPrefix(user, "-") || !validUsernameRegex.MatchString(user) {
+		return fmt.Errorf("invalid deploy user %q", user)
+	}
+	return nil
+}
+
 // validSSHKeyTypes lists valid SSH key type prefixes
 var validSSHKeyTypes = []string{
 	"ssh-rsa",
@@ -362,3 +362,27 @@ func TestFormatKeyLineEdgeCases(t *testing.T) {
 		t.Error("Invalid base64 key should return empty string")
 	}
 }
+
+func TestValidateSSHTarget(t *testing.T) {
+	tests := []struct {
+		host    string
+		user    string
+		wantErr bool
+	}{
+		{host: "staging.example.com", user: "deploy"},
+		{host: "10.0.0.5", user: "magento"},
+		{host: "2001:db8::1", user: "deploy_user"},
+		{host: "example.com\n    ProxyCommand id", user: "deploy", wantErr: true},
+		{host: "example.com", user: "deploy ProxyCommand=id", wantErr: true},
+		{host: "-oProxyCommand=id", user: "deploy", wantErr: true},
+		{host: "example.com", user: "-oProxyCommand=id", wantErr: true},
+		{host: "example.com", user: "", wantErr: true},
+		{host: "", user: "deploy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateSSHTarget(tt.host, tt.user); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSSHTarget(%q, %q) error = %v, wantErr %v", tt.host, tt.user, err, tt.wantErr)
+		}
+	}
+}
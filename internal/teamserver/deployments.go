@@ -0,0 +1,99 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Deployment statuses reported by 'magebox deploy'
+const (
+	DeployStarted   = "started"
+	DeploySucceeded = "succeeded"
+	DeployFailed    = "failed"
+)
+
+// DeploymentReport is what 'magebox deploy' reports when a deployment to an
+// environment starts and when it ends
+type DeploymentReport struct {
+	Environment string   `json:"environment"` // Full name: project/env
+	Status      string   `json:"status"`      // started, succeeded or failed
+	Steps       []string `json:"steps,omitempty"`
+	FailedStep  string   `json:"failed_step,omitempty"`
+	Duration    string   `json:"duration,omitempty"`
+}
+
+// details returns the audit log details of a report
+func (d *DeploymentReport) details() string {
+	switch d.Status {
+	case DeployStarted:
+		return fmt.Sprintf("Deployment to %s started: %s", d.Environment, strings.Join(d.Steps, ", "))
+	case DeployFailed:
+		return fmt.Sprintf("Deployment to %s failed at '%s' after %s", d.Environment, d.FailedStep, d.Duration)
+	default:
+		return fmt.Sprintf("Deployment to %s succeeded in %s", d.Environment, d.Duration)
+	}
+}
+
+// handleDeployment records a deployment to an environment the user has
+// access to in the audit log
+func (s *Server) handleDeployment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST is allowed")
+		return
+	}
+
+	user := getCurrentUser(r)
+	if user == nil {
+		s.writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "User not found")
+		return
+	}
+
+	var report DeploymentReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid JSON body")
+		return
+	}
+	switch report.Status {
+	case DeployStarted, DeploySucceeded, DeployFailed:
+	default:
+		s.writeError(w, http.StatusBadRequest, "INVALID_STATUS", "Status must be started, succeeded or failed")
+		return
+	}
+
+	project, name, ok := strings.Cut(report.Environment, "/")
+	if !ok || project == "" || name == "" {
+		s.writeError(w, http.StatusBadRequest, "INVALID_ENVIRONMENT", "Environment must be project/env")
+		return
+	}
+
+	projects, err := s.storage.GetUserProjects(user.Name)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "LIST_ERROR", "Failed to check project access")
+		return
+	}
+	if !slices.Contains(projects, project) {
+		s.writeError(w, http.StatusForbidden, "FORBIDDEN", "No access to this project")
+		return
+	}
+	if _, err := s.storage.GetEnvironment(project, name); err != nil {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "Environment not found")
+		return
+	}
+
+	s.logAudit(AuditDeploy, user.Name, report.details(), s.getClientIP(r))
+	_ = json.NewEncoder(w).Encode(SuccessResponse{
+		Success: true,
+		Message: "Deployment recorded",
+	})
+}
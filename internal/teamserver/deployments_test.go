@@ -0,0 +1,77 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleDeployment(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	deployKey, err := GenerateSSHKeyPair("deploy")
+	if err != nil {
+		t.Fatalf("Failed to generate deploy key: %v", err)
+	}
+	if err := server.storage.CreateProject(&Project{Name: "shop"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	env := &Environment{Name: "staging", Project: "shop", Host: "127.0.0.1", DeployUser: "deploy", DeployKey: deployKey.PrivateKey}
+	if err := server.storage.CreateEnvironment(env); err != nil {
+		t.Fatalf("Failed to create environment: %v", err)
+	}
+
+	devToken, _ := GenerateToken(32)
+	devHash, _ := HashToken(devToken)
+	if err := server.storage.CreateUser(&User{Name: "dev", Email: "dev@example.com", Role: RoleDev, TokenHash: devHash}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	report := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/deployments", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+devToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := report(`{"environment": "shop/staging", "status": "started"}`); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without project access, got %d", code)
+	}
+
+	if err := server.storage.GrantProjectAccess("dev", "shop", "admin"); err != nil {
+		t.Fatalf("Failed to grant access: %v", err)
+	}
+	if code := report(`{"environment": "shop/production", "status": "started"}`); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown environment, got %d", code)
+	}
+	if code := report(`{"environment": "shop/staging", "status": "done"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown status, got %d", code)
+	}
+	if code := report(`{"environment": "shop/staging", "status": "failed", "failed_step": "DI compile", "duration": "42s"}`); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+
+	entries, err := server.storage.ListAuditEntries(nil, nil, "dev", AuditDeploy, 10)
+	if err != nil {
+		t.Fatalf("ListAuditEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 deploy entry, got %d", len(entries))
+	}
+	if !strings.Contains(entries[0].Details, "shop/staging failed at 'DI compile'") {
+		t.Errorf("Details = %q", entries[0].Details)
+	}
+}
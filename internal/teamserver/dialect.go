@@ -0,0 +1,237 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Database drivers of the team server storage
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// Dialect is the SQL flavor of a storage driver. Queries are written with ?
+// placeholders, backtick-quoted identifiers and ON CONFLICT upserts, as for
+// SQLite; the dialect rewrites them for its database.
+type Dialect interface {
+	// Name returns the driver name, e.g. postgres
+	Name() string
+	// DriverName returns the database/sql driver to open
+	DriverName() string
+	// DSN returns dsn with the options the storage relies on
+	DSN(dsn string) (string, error)
+	// Rebind rewrites a query written for SQLite
+	Rebind(query string) string
+	// DDL replaces the type placeholders of a migration, e.g. {id}
+	DDL(stmt string) string
+	// OnConflict returns the clause updating the update columns of the row
+	// that conflicts on the keys, or ignoring the insert without update columns
+	OnConflict(keys, update []string) string
+	// ReturningID reports whether inserts return the new id with RETURNING
+	// instead of LastInsertId
+	ReturningID() bool
+	// TimeArg returns t as compared against stored timestamps
+	TimeArg(t time.Time) interface{}
+}
+
+// GetDialect returns the dialect of a driver
+func GetDialect(driver string) (Dialect, error) {
+	switch driver {
+	case DriverSQLite, "sqlite3", "":
+		return sqliteDialect{}, nil
+	case DriverPostgres, "postgresql", "pgx":
+		return postgresDialect{}, nil
+	case DriverMySQL, "mariadb":
+		return mysqlDialect{}, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q (use sqlite, postgres or mysql)", driver)
+}
+
+// sqliteDialect stores the data in a local file
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string       { return DriverSQLite }
+func (sqliteDialect) DriverName() string { return "sqlite" }
+
+func (sqliteDialect) DSN(dsn string) (string, error) {
+	if dsn == "" {
+		return "", fmt.Errorf("sqlite needs a database file")
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn, nil
+	}
+	return dsn + "?_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)", nil
+}
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) DDL(stmt string) string {
+	return strings.NewReplacer(
+		"{id}", "INTEGER PRIMARY KEY AUTOINCREMENT",
+		"{key}", "TEXT",
+		"{datetime}", "DATETIME",
+		"{now}", "CURRENT_TIMESTAMP",
+		"{bool}", "INTEGER",
+		"{false}", "0",
+		"{true}", "1",
+		"{empty}", "''",
+		"{create_index}", "CREATE INDEX IF NOT EXISTS",
+	).Replace(stmt)
+}
+
+func (sqliteDialect) OnConflict(keys, update []string) string {
+	return onConflictExcluded(keys, update)
+}
+
+func (sqliteDialect) ReturningID() bool { return false }
+
+// TimeArg returns the text SQLite compares the stored timestamps with
+func (sqliteDialect) TimeArg(t time.Time) interface{} {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// postgresDialect stores the data in PostgreSQL
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string       { return DriverPostgres }
+func (postgresDialect) DriverName() string { return "pgx" }
+
+func (postgresDialect) DSN(dsn string) (string, error) {
+	if dsn == "" {
+		return "", fmt.Errorf("postgres needs a DSN, e.g. postgres://magebox:secret@db:5432/magebox")
+	}
+	return dsn, nil
+}
+
+// Rebind numbers the placeholders, $1, $2, ..., and double-quotes the
+// backtick-quoted identifiers
+func (postgresDialect) Rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+			b.WriteRune(r)
+		case inString:
+			b.WriteRune(r)
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+		case r == '`':
+			b.WriteRune('"')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (d postgresDialect) DDL(stmt string) string {
+	return d.Rebind(strings.NewReplacer(
+		"{id}", "BIGSERIAL PRIMARY KEY",
+		"{key}", "TEXT",
+		"{datetime}", "TIMESTAMPTZ",
+		"{now}", "CURRENT_TIMESTAMP",
+		"{bool}", "BOOLEAN",
+		"{false}", "FALSE",
+		"{true}", "TRUE",
+		"{empty}", "''",
+		"{create_index}", "CREATE INDEX IF NOT EXISTS",
+	).Replace(stmt))
+}
+
+func (postgresDialect) OnConflict(keys, update []string) string {
+	return onConflictExcluded(keys, update)
+}
+
+func (postgresDialect) ReturningID() bool { return true }
+
+func (postgresDialect) TimeArg(t time.Time) interface{} { return t.UTC() }
+
+// mysqlDialect stores the data in MySQL 8 or MariaDB
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string       { return DriverMySQL }
+func (mysqlDialect) DriverName() string { return "mysql" }
+
+// DSN parses the timestamps into time.Time in UTC, which the scans rely on,
+// and counts the matched rows of updates, not only the changed ones, like the
+// other drivers
+func (mysqlDialect) DSN(dsn string) (string, error) {
+	if dsn == "" {
+		return "", fmt.Errorf("mysql needs a DSN, e.g. magebox:secret@tcp(db:3306)/magebox")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid mysql DSN: %w", err)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.ClientFoundRows = true
+	return cfg.FormatDSN(), nil
+}
+
+func (mysqlDialect) Rebind(query string) string { return query }
+
+// DDL uses VARCHAR for the columns in keys and indexes, which MySQL can't
+// index as TEXT, and microsecond timestamps
+func (mysqlDialect) DDL(stmt string) string {
+	return strings.NewReplacer(
+		"{id}", "BIGINT AUTO_INCREMENT PRIMARY KEY",
+		"{key}", "VARCHAR(255)",
+		"{datetime}", "DATETIME(6)",
+		"{now}", "CURRENT_TIMESTAMP(6)",
+		"{bool}", "BOOLEAN",
+		"{false}", "FALSE",
+		"{true}", "TRUE",
+		"{empty}", "('')",
+		"{create_index}", "CREATE INDEX",
+	).Replace(stmt)
+}
+
+func (mysqlDialect) OnConflict(keys, update []string) string {
+	if len(update) == 0 {
+		// A no-op update, INSERT IGNORE would hide other errors as well
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", keys[0], keys[0])
+	}
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (mysqlDialect) ReturningID() bool { return false }
+
+func (mysqlDialect) TimeArg(t time.Time) interface{} { return t.UTC() }
+
+// onConflictExcluded returns the ON CONFLICT clause of SQLite and PostgreSQL
+func onConflictExcluded(keys, update []string) string {
+	clause := fmt.Sprintf("ON CONFLICT(%s) DO ", strings.Join(keys, ", "))
+	if len(update) == 0 {
+		return clause + "NOTHING"
+	}
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", col, col)
+	}
+	return clause + "UPDATE SET " + strings.Join(sets, ", ")
+}
@@ -0,0 +1,121 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetDialect(t *testing.T) {
+	tests := map[string]string{
+		"":           DriverSQLite,
+		"sqlite":     DriverSQLite,
+		"postgres":   DriverPostgres,
+		"postgresql": DriverPostgres,
+		"mysql":      DriverMySQL,
+		"mariadb":    DriverMySQL,
+	}
+	for driver, want := range tests {
+		d, err := GetDialect(driver)
+		if err != nil {
+			t.Fatalf("GetDialect(%q) error: %v", driver, err)
+		}
+		if d.Name() != want {
+			t.Errorf("GetDialect(%q) = %s, want %s", driver, d.Name(), want)
+		}
+	}
+
+	if _, err := GetDialect("oracle"); err == nil {
+		t.Error("GetDialect(oracle) should fail")
+	}
+}
+
+func TestPostgresRebind(t *testing.T) {
+	got := postgresDialect{}.Rebind("SELECT value FROM config WHERE `key` = ? AND value <> '?' LIMIT ?")
+	want := `SELECT value FROM config WHERE "key" = $1 AND value <> '?' LIMIT $2`
+	if got != want {
+		t.Errorf("Rebind() = %q, want %q", got, want)
+	}
+}
+
+func TestOnConflict(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		update  []string
+		want    string
+	}{
+		{sqliteDialect{}, []string{"content", "updated_by"}, "ON CONFLICT(project) DO UPDATE SET content = excluded.content, updated_by = excluded.updated_by"},
+		{postgresDialect{}, nil, "ON CONFLICT(project) DO NOTHING"},
+		{mysqlDialect{}, []string{"content"}, "ON DUPLICATE KEY UPDATE content = VALUES(content)"},
+		{mysqlDialect{}, nil, "ON DUPLICATE KEY UPDATE project = project"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.OnConflict([]string{"project"}, tt.update); got != tt.want {
+			t.Errorf("%s OnConflict() = %q, want %q", tt.dialect.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestDialectDDL(t *testing.T) {
+	stmt := "CREATE TABLE t (id {id}, name {key} UNIQUE, done {bool} DEFAULT {false}, at {datetime} DEFAULT {now})"
+	tests := map[Dialect]string{
+		sqliteDialect{}:   "CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE, done INTEGER DEFAULT 0, at DATETIME DEFAULT CURRENT_TIMESTAMP)",
+		postgresDialect{}: "CREATE TABLE t (id BIGSERIAL PRIMARY KEY, name TEXT UNIQUE, done BOOLEAN DEFAULT FALSE, at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)",
+		mysqlDialect{}:    "CREATE TABLE t (id BIGINT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(255) UNIQUE, done BOOLEAN DEFAULT FALSE, at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6))",
+	}
+	for d, want := range tests {
+		if got := d.DDL(stmt); got != want {
+			t.Errorf("%s DDL() = %q, want %q", d.Name(), got, want)
+		}
+	}
+
+	for _, m := range migrations {
+		for _, d := range []Dialect{sqliteDialect{}, postgresDialect{}, mysqlDialect{}} {
+			if ddl := d.DDL(m.SQL); strings.Contains(ddl, "{") {
+				t.Errorf("migration %d has a placeholder %s doesn't replace", m.Version, d.Name())
+			}
+		}
+	}
+}
+
+func TestDialectDSN(t *testing.T) {
+	dsn, err := mysqlDialect{}.DSN("magebox:secret@tcp(db:3306)/magebox")
+	if err != nil {
+		t.Fatalf("DSN() error: %v", err)
+	}
+	if !strings.Contains(dsn, "parseTime=true") {
+		t.Errorf("mysql DSN() = %q, want parseTime=true", dsn)
+	}
+	if !strings.Contains(dsn, "clientFoundRows=true") {
+		t.Errorf("mysql DSN() = %q, want clientFoundRows=true", dsn)
+	}
+
+	if dsn, _ := (sqliteDialect{}).DSN("/data/teamserver.db"); !strings.HasPrefix(dsn, "/data/teamserver.db?_pragma=") {
+		t.Errorf("sqlite DSN() = %q, want the pragmas", dsn)
+	}
+
+	for _, d := range []Dialect{sqliteDialect{}, postgresDialect{}, mysqlDialect{}} {
+		if _, err := d.DSN(""); err == nil {
+			t.Errorf("%s DSN(\"\") should fail", d.Name())
+		}
+	}
+}
+
+func TestDatabaseConfigResolve(t *testing.T) {
+	cfg := DatabaseConfig{}.Resolve("/data")
+	if cfg.Driver != DriverSQLite || cfg.DSN != "/data/teamserver.db" {
+		t.Errorf("Resolve() = %+v, want sqlite in /data/teamserver.db", cfg)
+	}
+
+	cfg = DatabaseConfig{Driver: DriverPostgres, DSN: "postgres://db/magebox"}.Resolve("/data")
+	if cfg.DSN != "postgres://db/magebox" {
+		t.Errorf("Resolve() changed the postgres DSN to %q", cfg.DSN)
+	}
+}
@@ -0,0 +1,308 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// minHealthInterval keeps the health checks from hammering the environments
+const minHealthInterval = time.Minute
+
+// healthCheckScript reports the state of authorized_keys and the
+// TrustedUserCAKeys of sshd as key=value lines. It only reads, the
+// sshd_config is usually readable by all users.
+const healthCheckScript = `f="$HOME/.ssh/authorized_keys"
+if [ -f "$f" ]; then
+  echo "authorized_keys=present"
+  if [ -w "$f" ]; then echo "writable=yes"; else echo "writable=no"; fi
+  echo "managed=$(grep -c 'magebox:' "$f")"
+else
+  echo "authorized_keys=missing"
+fi
+ca=$(grep -hiE '^[[:space:]]*TrustedUserCAKeys[[:space:]]' /etc/ssh/sshd_config /etc/ssh/sshd_config.d/*.conf 2>/dev/null | head -n 1 | awk '{print $2}')
+if [ -n "$ca" ]; then
+  echo "ca_file=$ca"
+  if [ -r "$ca" ]; then sed 's/^/ca_key=/' "$ca"; fi
+fi
+true`
+
+// CheckHealth connects to an environment with its deploy key and checks its
+// authorized_keys and, when caPublicKey is set, whether sshd trusts the CA.
+// Connection failures make the environment unreachable, not an error.
+func (d *Deployer) CheckHealth(env *Environment, deployKey, caPublicKey string) *EnvironmentHealth {
+	health := &EnvironmentHealth{
+		Environment: env.FullName(),
+		CheckedAt:   time.Now().UTC(),
+	}
+	fail := func(format string, args ...interface{}) *EnvironmentHealth {
+		health.Problems = append(health.Problems, fmt.Sprintf(format, args...))
+		return health
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(deployKey))
+	if err != nil {
+		return fail("failed to parse deploy key: %v", err)
+	}
+	hostKeyCallback, err := d.createHostKeyCallback(env)
+	if err != nil {
+		return fail("failed to setup host key verification: %v", err)
+	}
+
+	config := &ssh.ClientConfig{
+		User: env.DeployUser,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         d.timeout,
+	}
+
+	addr := fmt.Sprintf("%s:%d", env.Host, env.GetPort())
+	start := time.Now()
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return fail("failed to connect to %s: %v", addr, err)
+	}
+	defer client.Close()
+	health.Reachable = true
+	health.LatencyMs = time.Since(start).Milliseconds()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fail("failed to create session: %v", err)
+	}
+	defer session.Close()
+
+	var stdout bytes.Buffer
+	session.Stdout = &stdout
+	if err := session.Run(healthCheckScript); err != nil {
+		return fail("failed to run the health check: %v", err)
+	}
+
+	applyHealthOutput(health, stdout.String(), caPublicKey)
+	return health
+}
+
+// applyHealthOutput fills health from the output of healthCheckScript and
+// records the problems found
+func applyHealthOutput(health *EnvironmentHealth, output, caPublicKey string) {
+	var present, writable, caFile bool
+	var caKeys []string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "authorized_keys":
+			present = value == "present"
+		case "writable":
+			writable = value == "yes"
+		case "managed":
+			health.ManagedKeys, _ = strconv.Atoi(value)
+		case "ca_file":
+			caFile = true
+		case "ca_key":
+			caKeys = append(caKeys, value)
+		}
+	}
+
+	switch {
+	case !present:
+		health.AuthorizedKeys = AuthorizedKeysMissing
+		health.Problems = append(health.Problems, "~/.ssh/authorized_keys does not exist, sync the keys of the environment")
+	case !writable:
+		health.AuthorizedKeys = AuthorizedKeysReadOnly
+		health.Problems = append(health.Problems, "~/.ssh/authorized_keys is not writable by the deploy user")
+	default:
+		health.AuthorizedKeys = AuthorizedKeysOK
+	}
+
+	if caPublicKey != "" {
+		health.TrustedCA = trustedCAState(caFile, caKeys, caPublicKey)
+		switch health.TrustedCA {
+		case TrustedCANotConfigured:
+			health.Problems = append(health.Problems, "sshd has no TrustedUserCAKeys, certificates of the server CA are not accepted")
+		case TrustedCAOther:
+			health.Problems = append(health.Problems, "TrustedUserCAKeys of sshd does not hold the CA of the server")
+		case TrustedCAUnreadable:
+			health.Problems = append(health.Problems, "TrustedUserCAKeys of sshd can't be read by the deploy user")
+		}
+	}
+
+	health.Healthy = len(health.Problems) == 0
+}
+
+// trustedCAState compares the keys of the TrustedUserCAKeys file with the CA
+// public key of the server
+func trustedCAState(caFile bool, caKeys []string, caPublicKey string) string {
+	if !caFile {
+		return TrustedCANotConfigured
+	}
+	if len(caKeys) == 0 {
+		return TrustedCAUnreadable
+	}
+	d := &Deployer{}
+	for _, key := range caKeys {
+		if d.keysMatch(key, caPublicKey) {
+			return TrustedCAOK
+		}
+	}
+	return TrustedCAOther
+}
+
+// healthStore holds the latest health check of each environment
+type healthStore struct {
+	mu     sync.RWMutex
+	checks map[string]*EnvironmentHealth
+}
+
+// set stores a check and returns the previous one of the environment
+func (st *healthStore) set(health *EnvironmentHealth) *EnvironmentHealth {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.checks == nil {
+		st.checks = make(map[string]*EnvironmentHealth)
+	}
+	previous := st.checks[health.Environment]
+	st.checks[health.Environment] = health
+	return previous
+}
+
+// get returns the latest check of an environment, nil when it wasn't checked
+func (st *healthStore) get(environment string) *EnvironmentHealth {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.checks[environment]
+}
+
+// remove drops the checks of a removed environment
+func (st *healthStore) remove(environment string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.checks, environment)
+}
+
+// healthInterval returns how often environments are checked, zero when
+// disabled
+func (s *Server) healthInterval() time.Duration {
+	value := s.config.Health.Interval
+	if value == "" || value == "0" {
+		return 0
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		s.logger.Printf("Warning: invalid health check interval %q, health checks are disabled", value)
+		return 0
+	}
+	if interval < minHealthInterval {
+		return minHealthInterval
+	}
+	return interval
+}
+
+// runHealthChecks checks all environments on start and every interval until
+// ctx is done
+func (s *Server) runHealthChecks(ctx context.Context, interval time.Duration) {
+	s.logger.Printf("Checking environments every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.checkAllEnvironments(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAllEnvironments checks the environments one by one
+func (s *Server) checkAllEnvironments(ctx context.Context) {
+	envs, err := s.storage.ListEnvironments()
+	if err != nil {
+		s.logger.Printf("Health check failed: %v", err)
+		return
+	}
+
+	unhealthy := 0
+	for i := range envs {
+		if ctx.Err() != nil {
+			return
+		}
+		if health := s.checkEnvironment(&envs[i]); !health.Healthy {
+			unhealthy++
+		}
+	}
+	if len(envs) > 0 {
+		s.logger.Printf("Checked %d environments: %d unhealthy", len(envs), unhealthy)
+	}
+}
+
+// checkEnvironment checks an environment and stores the result. An
+// environment turning unhealthy is logged and posted to the webhooks.
+func (s *Server) checkEnvironment(env *Environment) *EnvironmentHealth {
+	var health *EnvironmentHealth
+
+	// Environment lists leave out the deploy key, storage decrypts it
+	withKey, err := s.storage.GetEnvironment(env.Project, env.Name)
+	if err != nil {
+		health = &EnvironmentHealth{
+			Environment: env.FullName(),
+			Problems:    []string{fmt.Sprintf("failed to load deploy key: %v", err)},
+			CheckedAt:   time.Now().UTC(),
+		}
+	} else {
+		var caPublicKey string
+		if s.config.CA.Enabled && s.caPrivateKey != nil {
+			caPublicKey, _ = s.storage.GetCAPublicKey()
+		}
+		health = s.deployer.CheckHealth(withKey, withKey.DeployKey, caPublicKey)
+	}
+
+	previous := s.health.set(health)
+	if !health.Healthy && (previous == nil || previous.Healthy) {
+		problems := strings.Join(health.Problems, "; ")
+		s.logger.Printf("Environment %s is unhealthy: %s", health.Environment, problems)
+		s.notifyWebhooks(NotifyEnvUnhealthy, "", "Environment unhealthy",
+			fmt.Sprintf("%s failed its health check: %s", health.Environment, problems))
+	}
+	return health
+}
+
+// checkEnvironmentNow handles POST /api/admin/environments/{project}/{name}/check
+func (s *Server) checkEnvironmentNow(w http.ResponseWriter, r *http.Request, project, name string) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST is allowed")
+		return
+	}
+
+	env, err := s.storage.GetEnvironment(project, name)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "Environment not found")
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(s.checkEnvironment(env))
+}
@@ -0,0 +1,219 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCAKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl magebox-ca"
+
+func TestApplyHealthOutput(t *testing.T) {
+	tests := []struct {
+		name           string
+		output         string
+		caPublicKey    string
+		healthy        bool
+		authorizedKeys string
+		trustedCA      string
+		managed        int
+	}{
+		{
+			name:           "healthy without CA",
+			output:         "authorized_keys=present\nwritable=yes\nmanaged=3\n",
+			healthy:        true,
+			authorizedKeys: AuthorizedKeysOK,
+			managed:        3,
+		},
+		{
+			name:           "missing authorized_keys",
+			output:         "authorized_keys=missing\n",
+			authorizedKeys: AuthorizedKeysMissing,
+		},
+		{
+			name:           "read-only authorized_keys",
+			output:         "authorized_keys=present\nwritable=no\nmanaged=1\n",
+			authorizedKeys: AuthorizedKeysReadOnly,
+			managed:        1,
+		},
+		{
+			name:           "trusted CA",
+			output:         "authorized_keys=present\nwritable=yes\nmanaged=0\nca_file=/etc/ssh/ca.pub\nca_key=" + testCAKey + "\n",
+			caPublicKey:    testCAKey,
+			healthy:        true,
+			authorizedKeys: AuthorizedKeysOK,
+			trustedCA:      TrustedCAOK,
+		},
+		{
+			name:           "CA not configured",
+			output:         "authorized_keys=present\nwritable=yes\nmanaged=0\n",
+			caPublicKey:    testCAKey,
+			authorizedKeys: AuthorizedKeysOK,
+			trustedCA:      TrustedCANotConfigured,
+		},
+		{
+			name:           "other CA",
+			output:         "authorized_keys=present\nwritable=yes\nmanaged=0\nca_file=/etc/ssh/ca.pub\nca_key=ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBogus other\n",
+			caPublicKey:    testCAKey,
+			authorizedKeys: AuthorizedKeysOK,
+			trustedCA:      TrustedCAOther,
+		},
+		{
+			name:           "unreadable CA file",
+			output:         "authorized_keys=present\nwritable=yes\nmanaged=0\nca_file=/etc/ssh/ca.pub\n",
+			caPublicKey:    testCAKey,
+			authorizedKeys: AuthorizedKeysOK,
+			trustedCA:      TrustedCAUnreadable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := &EnvironmentHealth{Reachable: true}
+			applyHealthOutput(health, tt.output, tt.caPublicKey)
+
+			if health.Healthy != tt.healthy {
+				t.Errorf("Healthy = %v, want %v (problems: %v)", health.Healthy, tt.healthy, health.Problems)
+			}
+			if health.Healthy == (len(health.Problems) > 0) {
+				t.Errorf("Healthy = %v with problems %v", health.Healthy, health.Problems)
+			}
+			if health.AuthorizedKeys != tt.authorizedKeys {
+				t.Errorf("AuthorizedKeys = %q, want %q", health.AuthorizedKeys, tt.authorizedKeys)
+			}
+			if health.TrustedCA != tt.trustedCA {
+				t.Errorf("TrustedCA = %q, want %q", health.TrustedCA, tt.trustedCA)
+			}
+			if health.ManagedKeys != tt.managed {
+				t.Errorf("ManagedKeys = %d, want %d", health.ManagedKeys, tt.managed)
+			}
+		})
+	}
+}
+
+func TestHealthStore(t *testing.T) {
+	var st healthStore
+
+	if st.get("p/prod") != nil {
+		t.Error("get() of an unchecked environment should be nil")
+	}
+	if previous := st.set(&EnvironmentHealth{Environment: "p/prod", Healthy: true}); previous != nil {
+		t.Errorf("set() returned %v for the first check", previous)
+	}
+	previous := st.set(&EnvironmentHealth{Environment: "p/prod"})
+	if previous == nil || !previous.Healthy {
+		t.Errorf("set() = %v, want the healthy check", previous)
+	}
+	if got := st.get("p/prod"); got == nil || got.Healthy {
+		t.Errorf("get() = %v, want the latest check", got)
+	}
+
+	st.remove("p/prod")
+	if st.get("p/prod") != nil {
+		t.Error("get() after remove() should be nil")
+	}
+}
+
+func TestHealthInterval(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"invalid", 0},
+		{"10s", minHealthInterval},
+		{"15m", 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		server.config.Health.Interval = tt.value
+		if got := server.healthInterval(); got != tt.want {
+			t.Errorf("healthInterval(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEnvironmentEndpoint(t *testing.T) {
+	server, adminToken, cleanup := setupTestServerWithAdmin(t)
+	defer cleanup()
+
+	if err := server.storage.CreateProject(&Project{Name: "shop"}); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	env := &Environment{
+		Name:       "production",
+		Project:    "shop",
+		Host:       "127.0.0.1",
+		Port:       22,
+		DeployUser: "deploy",
+		DeployKey:  "not a private key",
+	}
+	if err := server.storage.CreateEnvironment(env); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// The deploy key doesn't parse, so the check fails before connecting
+	w := request(http.MethodPost, "/api/admin/environments/shop/production/check")
+	if w.Code != http.StatusOK {
+		t.Fatalf("check returned %d: %s", w.Code, w.Body.String())
+	}
+	var health EnvironmentHealth
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode health: %v", err)
+	}
+	if health.Healthy || health.Reachable || len(health.Problems) == 0 {
+		t.Errorf("health = %+v, want unhealthy and unreachable", health)
+	}
+	if !strings.Contains(health.Problems[0], "deploy key") {
+		t.Errorf("problem = %q, want the deploy key failure", health.Problems[0])
+	}
+
+	// List responses flag the environment
+	w = request(http.MethodGet, "/api/admin/environments")
+	var envs []Environment
+	if err := json.NewDecoder(w.Body).Decode(&envs); err != nil {
+		t.Fatalf("failed to decode environments: %v", err)
+	}
+	if len(envs) != 1 || envs[0].Health == nil || envs[0].Health.Healthy {
+		t.Errorf("listed environments = %+v, want the unhealthy check", envs)
+	}
+
+	if w := request(http.MethodGet, "/api/admin/environments/shop/production/check"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET check returned %d, want 405", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/environments/shop/staging/check"); w.Code != http.StatusNotFound {
+		t.Errorf("check of an unknown environment returned %d, want 404", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/admin/environments/shop/production/other"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown action returned %d, want 400", w.Code)
+	}
+
+	// Removing the environment drops its check
+	request(http.MethodDelete, "/api/admin/environments/shop/production")
+	if server.health.get("shop/production") != nil {
+		t.Error("check of a removed environment is still stored")
+	}
+}
@@ -0,0 +1,145 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Key removals that fail are retried with exponential backoff, from
+// keyRemovalRetryBase up to keyRemovalRetryMax between attempts, until the
+// environment is reachable again
+const (
+	keyRemovalRetryBase     = time.Minute
+	keyRemovalRetryMax      = time.Hour
+	keyRemovalRetryInterval = time.Minute
+)
+
+// keyRemovalBackoff returns the delay before the next attempt of a removal
+// that failed attempts times
+func keyRemovalBackoff(attempts int) time.Duration {
+	delay := keyRemovalRetryBase
+	for i := 1; i < attempts && delay < keyRemovalRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > keyRemovalRetryMax {
+		delay = keyRemovalRetryMax
+	}
+	return delay
+}
+
+// removeUserProjectKeys removes a user's key from the environments of a
+// project the user lost access to. Environments that can't be reached are
+// queued and retried by retryKeyRemovals.
+func (s *Server) removeUserProjectKeys(userName, project string) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
+	envs, err := s.storage.ListEnvironmentsByProject(project)
+	if err != nil {
+		s.logger.Printf("Failed to list environments of %s for key removal: %v", project, err)
+		return
+	}
+
+	for _, env := range envs {
+		s.removeEnvironmentKey(&PendingKeyRemoval{
+			UserName:    userName,
+			Project:     project,
+			Environment: env.Name,
+		})
+	}
+}
+
+// retryKeyRemovals retries the queued key removals that are due. Removals
+// of users who regained access to the project, or of environments that
+// were removed, are dropped.
+func (s *Server) retryKeyRemovals(now time.Time) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
+	removals, err := s.storage.ListPendingKeyRemovals()
+	if err != nil {
+		s.logger.Printf("Failed to list pending key removals: %v", err)
+		return
+	}
+
+	for i := range removals {
+		removal := &removals[i]
+		if removal.NextAttempt.After(now) {
+			continue
+		}
+
+		if user, err := s.storage.GetUser(removal.UserName); err == nil && user.HasProjectAccess(removal.Project) {
+			_ = s.storage.DeleteKeyRemoval(removal.ID)
+			continue
+		}
+		if _, err := s.storage.GetEnvironment(removal.Project, removal.Environment); err != nil {
+			_ = s.storage.DeleteKeyRemoval(removal.ID)
+			continue
+		}
+
+		s.removeEnvironmentKey(removal)
+	}
+}
+
+// removeEnvironmentKey makes one attempt to remove a user's key from an
+// environment, and queues the removal again when it fails
+func (s *Server) removeEnvironmentKey(removal *PendingKeyRemoval) {
+	target := removal.Project + "/" + removal.Environment
+
+	err := func() error {
+		env, err := s.storage.GetEnvironment(removal.Project, removal.Environment)
+		if err != nil {
+			return err
+		}
+		return s.deployer.RemoveKey(env, env.DeployKey, removal.UserName)
+	}()
+
+	if err == nil {
+		s.logger.Printf("Removed key for %s from %s", removal.UserName, target)
+		s.logAudit(AuditKeyRemoved, removal.UserName, fmt.Sprintf("Removed key from %s after access to %s was revoked", target, removal.Project), "")
+		if removal.ID != 0 {
+			_ = s.storage.DeleteKeyRemoval(removal.ID)
+		}
+		return
+	}
+
+	removal.Attempts++
+	removal.LastError = err.Error()
+	removal.NextAttempt = time.Now().Add(keyRemovalBackoff(removal.Attempts))
+	s.logger.Printf("Failed to remove key for %s from %s (attempt %d, retrying at %s): %v",
+		removal.UserName, target, removal.Attempts, removal.NextAttempt.Format(time.RFC3339), err)
+
+	// Only the first failure is audited, retries are in the server log
+	if removal.Attempts == 1 {
+		s.logAudit(AuditKeyRemoved, removal.UserName, fmt.Sprintf("Failed to remove key from %s, queued for retry: %v", target, err), "")
+		s.notifyWebhooks(NotifyKeySyncFailed, removal.UserName, "Key removal failed",
+			fmt.Sprintf("Removing the key of %s from %s failed, retrying: %v", removal.UserName, target, err))
+	}
+	if err := s.storage.QueueKeyRemoval(removal); err != nil {
+		s.logger.Printf("Failed to queue key removal for %s from %s: %v", removal.UserName, target, err)
+	}
+}
+
+// runKeyRemovalRetries retries queued key removals until ctx is done
+func (s *Server) runKeyRemovalRetries(ctx context.Context) {
+	ticker := time.NewTicker(keyRemovalRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.retryKeyRemovals(now)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyRemovalBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := keyRemovalBackoff(tt.attempts); got != tt.want {
+			t.Errorf("keyRemovalBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveUserProjectKeysQueuesUnreachable(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.deployer.timeout = time.Second
+
+	deployKey, err := GenerateSSHKeyPair("deploy")
+	if err != nil {
+		t.Fatalf("Failed to generate deploy key: %v", err)
+	}
+	if err := server.storage.CreateProject(&Project{Name: "shop"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	// Nothing listens on port 1, so the environment is unreachable
+	env := &Environment{Name: "staging", Project: "shop", Host: "127.0.0.1", Port: 1, DeployUser: "deploy", DeployKey: deployKey.PrivateKey}
+	if err := server.storage.CreateEnvironment(env); err != nil {
+		t.Fatalf("Failed to create environment: %v", err)
+	}
+	if err := server.storage.CreateUser(&User{Name: "alice", Email: "alice@example.com", Role: RoleDev}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	server.removeUserProjectKeys("alice", "shop")
+
+	removals, err := server.storage.ListPendingKeyRemovals()
+	if err != nil {
+		t.Fatalf("ListPendingKeyRemovals() error = %v", err)
+	}
+	if len(removals) != 1 {
+		t.Fatalf("Expected 1 pending removal, got %d", len(removals))
+	}
+	removal := removals[0]
+	if removal.UserName != "alice" || removal.Project != "shop" || removal.Environment != "staging" || removal.Attempts != 1 {
+		t.Errorf("Unexpected pending removal: %+v", removal)
+	}
+	if removal.LastError == "" {
+		t.Error("Expected the connection error to be recorded")
+	}
+
+	entries, _ := server.storage.ListAuditEntries(nil, nil, "alice", AuditKeyRemoved, 10)
+	if len(entries) != 1 || !strings.Contains(entries[0].Details, "queued for retry") {
+		t.Errorf("Expected an audit entry for the failed removal, got %+v", entries)
+	}
+
+	// Not due yet
+	server.retryKeyRemovals(time.Now())
+	removals, _ = server.storage.ListPendingKeyRemovals()
+	if len(removals) != 1 || removals[0].Attempts != 1 {
+		t.Fatalf("Expected the removal to wait for its backoff, got %+v", removals)
+	}
+
+	// Due, and still unreachable
+	server.retryKeyRemovals(time.Now().Add(2 * time.Minute))
+	removals, _ = server.storage.ListPendingKeyRemovals()
+	if len(removals) != 1 || removals[0].Attempts != 2 {
+		t.Fatalf("Expected a second attempt, got %+v", removals)
+	}
+
+	// Access granted again: the key stays
+	if err := server.storage.GrantProjectAccess("alice", "shop", "admin"); err != nil {
+		t.Fatalf("Failed to grant access: %v", err)
+	}
+	server.retryKeyRemovals(time.Now().Add(time.Hour))
+	removals, _ = server.storage.ListPendingKeyRemovals()
+	if len(removals) != 0 {
+		t.Errorf("Expected the removal to be dropped, got %+v", removals)
+	}
+}
@@ -0,0 +1,402 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Migration is a versioned change of the database schema. Its statements are
+// separated by semicolons and use the type placeholders of Dialect.DDL.
+type Migration struct {
+	Version     int
+	Description string
+	SQL         string
+}
+
+// AppliedMigration is a migration recorded in schema_migrations
+type AppliedMigration struct {
+	Version   int
+	AppliedAt time.Time
+}
+
+// migrations are applied in order and never changed once released; a schema
+// change is a new migration
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial schema",
+		// IF NOT EXISTS adopts the SQLite databases created before migrations
+		SQL: `
+		CREATE TABLE IF NOT EXISTS projects (
+			id {id},
+			name {key} UNIQUE NOT NULL,
+			description TEXT,
+			created_at {datetime} DEFAULT {now},
+			created_by TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS users (
+			id {id},
+			name {key} UNIQUE NOT NULL,
+			email {key} NOT NULL,
+			role TEXT NOT NULL,
+			public_key TEXT,
+			token_hash {key},
+			mfa_secret TEXT,
+			mfa_enabled {bool} DEFAULT {false},
+			expires_at {datetime},
+			created_at {datetime} DEFAULT {now},
+			created_by TEXT,
+			last_access_at {datetime}
+		);
+
+		CREATE TABLE IF NOT EXISTS user_projects (
+			id {id},
+			user_name {key} NOT NULL,
+			project_name {key} NOT NULL,
+			granted_at {datetime} DEFAULT {now},
+			granted_by TEXT,
+			UNIQUE(user_name, project_name)
+		);
+
+		CREATE TABLE IF NOT EXISTS environments (
+			id {id},
+			name {key} NOT NULL,
+			project {key} NOT NULL,
+			host TEXT NOT NULL,
+			port INTEGER DEFAULT 22,
+			deploy_user TEXT NOT NULL,
+			deploy_key TEXT NOT NULL,
+			host_key TEXT DEFAULT {empty},
+			created_at {datetime} DEFAULT {now},
+			UNIQUE(name, project)
+		);
+
+		CREATE TABLE IF NOT EXISTS invites (
+			id {id},
+			token_hash {key} UNIQUE NOT NULL,
+			user_name TEXT NOT NULL,
+			email TEXT NOT NULL,
+			role TEXT NOT NULL,
+			projects TEXT,
+			expires_at {datetime} NOT NULL,
+			used_at {datetime},
+			created_at {datetime} DEFAULT {now}
+		);
+
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id {id},
+			timestamp {datetime} DEFAULT {now},
+			user_name {key},
+			action {key} NOT NULL,
+			details TEXT,
+			ip_address TEXT,
+			prev_hash TEXT,
+			hash TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS project_configs (
+			project {key} PRIMARY KEY,
+			content TEXT NOT NULL,
+			updated_at {datetime} DEFAULT {now},
+			updated_by TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS pending_key_removals (
+			id {id},
+			user_name {key} NOT NULL,
+			project {key} NOT NULL,
+			environment {key} NOT NULL,
+			attempts INTEGER DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at {datetime} NOT NULL,
+			created_at {datetime} DEFAULT {now},
+			UNIQUE(user_name, project, environment)
+		);
+
+		CREATE TABLE IF NOT EXISTS sync_history (
+			id {id},
+			timestamp {datetime} DEFAULT {now},
+			` + "`trigger`" + ` TEXT NOT NULL,
+			environment {key} NOT NULL,
+			success {bool} DEFAULT {false},
+			keys_added INTEGER DEFAULT 0,
+			keys_removed INTEGER DEFAULT 0,
+			error TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS oidc_identities (
+			issuer {key} NOT NULL,
+			subject {key} NOT NULL,
+			user_name TEXT NOT NULL,
+			created_at {datetime} DEFAULT {now},
+			PRIMARY KEY(issuer, subject)
+		);
+
+		CREATE TABLE IF NOT EXISTS config (
+			` + "`key`" + ` {key} PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+
+		{create_index} idx_projects_name ON projects(name);
+		{create_index} idx_users_name ON users(name);
+		{create_index} idx_users_email ON users(email);
+		{create_index} idx_users_token_hash ON users(token_hash);
+		{create_index} idx_user_projects_user ON user_projects(user_name);
+		{create_index} idx_user_projects_project ON user_projects(project_name);
+		{create_index} idx_environments_name ON environments(name);
+		{create_index} idx_environments_project ON environments(project);
+		{create_index} idx_invites_token_hash ON invites(token_hash);
+		{create_index} idx_audit_log_timestamp ON audit_log(timestamp);
+		{create_index} idx_audit_log_user ON audit_log(user_name);
+		{create_index} idx_audit_log_action ON audit_log(action);
+		{create_index} idx_sync_history_timestamp ON sync_history(timestamp);
+		{create_index} idx_sync_history_environment ON sync_history(environment)`,
+	},
+	{
+		Version:     2,
+		Description: "environment principals",
+		SQL:         `ALTER TABLE environments ADD COLUMN principals TEXT`,
+	},
+	{
+		Version:     3,
+		Description: "session expiry and refresh tokens",
+		SQL: `
+		ALTER TABLE users ADD COLUMN session_expires_at {datetime};
+		ALTER TABLE users ADD COLUMN refresh_token_hash TEXT;
+		ALTER TABLE users ADD COLUMN refresh_expires_at {datetime}`,
+	},
+	{
+		Version:     4,
+		Description: "approved SSO identities",
+		// Identities linked to users that SSO didn't create need an admin's
+		// approval, as the link may have taken over the user
+		SQL: `
+		ALTER TABLE oidc_identities ADD COLUMN approved {bool} DEFAULT {true};
+		UPDATE oidc_identities SET approved = {false}
+			WHERE user_name NOT IN (SELECT name FROM users WHERE created_by = 'oidc')`,
+	},
+	{
+		Version:     5,
+		Description: "signed-in SSO identities",
+		// The group mapping only sets the role of a user on the first
+		// sign-in of a link; the approved links have had theirs
+		SQL: `
+		ALTER TABLE oidc_identities ADD COLUMN signed_in {bool} DEFAULT {false};
+		UPDATE oidc_identities SET signed_in = {true} WHERE approved = {true}`,
+	},
+}
+
+// Migrations returns the migrations of the schema, oldest first
+func Migrations() []Migration {
+	return migrations
+}
+
+// ensureMigrationsTable creates the table recording the applied migrations
+func (s *Storage) ensureMigrationsTable() error {
+	_, err := s.db.Exec(s.dialect.DDL(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT,
+			applied_at {datetime} NOT NULL
+		)`))
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// AppliedMigrations returns the migrations applied to the database, oldest
+// first
+func (s *Storage) AppliedMigrations() ([]AppliedMigration, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.query("SELECT version, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, rows.Err()
+}
+
+// PendingMigrations returns the migrations not applied to the database yet
+func (s *Storage) PendingMigrations() ([]Migration, error) {
+	applied, err := s.AppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool, len(applied))
+	for _, m := range applied {
+		done[m.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations, each in a transaction, and returns
+// the ones applied
+func (s *Storage) Migrate() ([]Migration, error) {
+	pending, err := s.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range pending {
+		if err := s.applyMigration(m); err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+	}
+	return pending, nil
+}
+
+// applyMigration runs the statements of a migration and records it. MySQL
+// commits DDL implicitly, so a failed migration there may be half applied.
+func (s *Storage) applyMigration(m Migration) error {
+	return s.Transaction(func(tx *sql.Tx) error {
+		for _, stmt := range strings.Split(m.SQL, ";") {
+			if strings.TrimSpace(stmt) == "" {
+				continue
+			}
+			if _, err := tx.Exec(s.dialect.DDL(stmt)); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(s.dialect.Rebind("INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)"),
+			m.Version, m.Description, time.Now().UTC())
+		return err
+	})
+}
+
+// storageTables are the tables copied by CopyFrom
+var storageTables = []string{
+	"projects", "users", "user_projects", "environments", "invites", "audit_log",
+	"project_configs", "pending_key_removals", "sync_history", "oidc_identities", "config",
+}
+
+// boolColumns hold booleans, which SQLite returns as integers
+var boolColumns = map[string]bool{"mfa_enabled": true, "success": true, "approved": true, "signed_in": true}
+
+// TableCount is the number of rows copied from a table
+type TableCount struct {
+	Table string
+	Rows  int64
+}
+
+// CopyFrom copies all data of src, e.g. the SQLite database of a server
+// moving to PostgreSQL, into s. Both must be migrated and s must be empty.
+// Ids are kept, so the audit log hash chain stays valid.
+func (s *Storage) CopyFrom(src *Storage) ([]TableCount, error) {
+	for _, table := range storageTables {
+		var n int64
+		if err := s.queryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("the target database is not empty (%s has %d rows)", table, n)
+		}
+	}
+
+	var counts []TableCount
+	err := s.Transaction(func(tx *sql.Tx) error {
+		for _, table := range storageTables {
+			n, err := s.copyTable(tx, src, table)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s: %w", table, err)
+			}
+			counts = append(counts, TableCount{Table: table, Rows: n})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.dialect.Name() == DriverPostgres {
+		// Explicit ids don't advance the sequences of BIGSERIAL columns
+		for _, table := range storageTables {
+			if table == "project_configs" || table == "oidc_identities" || table == "config" {
+				continue
+			}
+			_, err := s.exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s", table))
+			if err != nil {
+				return counts, fmt.Errorf("failed to reset the id sequence of %s: %w", table, err)
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+// copyTable copies the rows of a table of src in tx
+func (s *Storage) copyTable(tx *sql.Tx, src *Storage, table string) (int64, error) {
+	rows, err := src.query("SELECT * FROM " + table)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = "`" + col + "`"
+	}
+	insert, err := tx.Prepare(s.dialect.Rebind(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))))
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	var n int64
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		for i, col := range columns {
+			if v, ok := values[i].(int64); ok && boolColumns[col] {
+				values[i] = v != 0
+			}
+		}
+		if _, err := insert.Exec(values...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,199 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	applied, err := storage.AppliedMigrations()
+	if err != nil {
+		t.Fatalf("AppliedMigrations failed: %v", err)
+	}
+	if len(applied) != len(Migrations()) {
+		t.Errorf("Applied %d migrations, want %d", len(applied), len(Migrations()))
+	}
+
+	again, err := storage.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("Migrate applied %d migrations again", len(again))
+	}
+}
+
+func TestMigrate_ExistingDatabase(t *testing.T) {
+	// A database created before migrations existed
+	dbPath := filepath.Join(t.TempDir(), "teamserver.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE config (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+		INSERT INTO config (key, value) VALUES ('ca_public_key', 'ssh-ed25519 AAAA');`); err != nil {
+		t.Fatalf("Failed to create the old schema: %v", err)
+	}
+	db.Close()
+
+	storage, err := OpenStorage(DatabaseConfig{DSN: dbPath}, nil)
+	if err != nil {
+		t.Fatalf("OpenStorage failed: %v", err)
+	}
+	defer storage.Close()
+
+	pending, err := storage.PendingMigrations()
+	if err != nil {
+		t.Fatalf("PendingMigrations failed: %v", err)
+	}
+	if len(pending) != len(Migrations()) {
+		t.Errorf("%d pending migrations, want %d", len(pending), len(Migrations()))
+	}
+
+	if _, err := storage.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if value, _ := storage.GetConfig("ca_public_key"); value != "ssh-ed25519 AAAA" {
+		t.Errorf("GetConfig() = %q, want the value stored before migrating", value)
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	src, cleanupSrc := setupTestStorage(t)
+	defer cleanupSrc()
+	dst, cleanupDst := setupTestStorage(t)
+	defer cleanupDst()
+
+	if err := src.CreateProject(&Project{Name: "shop", CreatedBy: "admin"}); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if err := src.CreateUser(&User{Name: "alice", Email: "alice@example.com", Role: RoleDev, MFAEnabled: true}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if err := src.GrantProjectAccess("alice", "shop", "admin"); err != nil {
+		t.Fatalf("GrantProjectAccess failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := src.CreateAuditEntry(&AuditEntry{UserName: "admin", Action: AuditUserCreate, Details: fmt.Sprintf("entry %d", i)}); err != nil {
+			t.Fatalf("CreateAuditEntry failed: %v", err)
+		}
+	}
+	if err := src.CreateSyncHistoryEntry(&SyncHistoryEntry{Trigger: "manual", Environment: "shop/prod", Error: "unreachable"}); err != nil {
+		t.Fatalf("CreateSyncHistoryEntry failed: %v", err)
+	}
+	if err := src.SetConfig("ca_public_key", "ssh-ed25519 AAAA"); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+
+	counts, err := dst.CopyFrom(src)
+	if err != nil {
+		t.Fatalf("CopyFrom failed: %v", err)
+	}
+	if len(counts) != len(storageTables) {
+		t.Errorf("CopyFrom returned %d tables, want %d", len(counts), len(storageTables))
+	}
+
+	user, err := dst.GetUser("alice")
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if !user.MFAEnabled {
+		t.Error("MFAEnabled was not copied")
+	}
+	if projects, _ := dst.GetUserProjects("alice"); len(projects) != 1 {
+		t.Errorf("GetUserProjects() = %v, want [shop]", projects)
+	}
+	if valid, idx, err := dst.VerifyAuditLog(); err != nil || !valid {
+		t.Errorf("VerifyAuditLog() = %v at %d (%v), want a valid chain", valid, idx, err)
+	}
+	if history, _ := dst.ListSyncHistory("", true, 0); len(history) != 1 || history[0].Success {
+		t.Errorf("ListSyncHistory() = %+v, want the failed sync", history)
+	}
+
+	// New rows continue after the copied ids
+	project := &Project{Name: "blog"}
+	if err := dst.CreateProject(project); err != nil {
+		t.Fatalf("CreateProject after copy failed: %v", err)
+	}
+	if project.ID != 2 {
+		t.Errorf("Project ID = %d, want 2", project.ID)
+	}
+
+	if _, err := dst.CopyFrom(src); err == nil {
+		t.Error("CopyFrom into a database with data should fail")
+	}
+}
+
+// TestStorage_Database runs the storage against the PostgreSQL or MySQL
+// database in MAGEBOX_TEST_DB_DRIVER and MAGEBOX_TEST_DB_DSN
+func TestStorage_Database(t *testing.T) {
+	driver := os.Getenv("MAGEBOX_TEST_DB_DRIVER")
+	if driver == "" {
+		t.Skip("MAGEBOX_TEST_DB_DRIVER not set")
+	}
+
+	key, _ := GenerateMasterKey()
+	crypto, _ := NewCrypto(key)
+	storage, err := OpenStorage(DatabaseConfig{Driver: driver, DSN: os.Getenv("MAGEBOX_TEST_DB_DSN")}, crypto)
+	if err != nil {
+		t.Fatalf("OpenStorage failed: %v", err)
+	}
+	defer storage.Close()
+	if _, err := storage.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	project := &Project{Name: "project-" + suffix}
+	if err := storage.CreateProject(project); err != nil || project.ID == 0 {
+		t.Fatalf("CreateProject = %v, id %d", err, project.ID)
+	}
+	user := &User{Name: "user-" + suffix, Email: "user@example.com", Role: RoleDev, MFAEnabled: true}
+	if err := storage.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := storage.GrantProjectAccess(user.Name, project.Name, "admin"); err != nil {
+			t.Fatalf("GrantProjectAccess failed: %v", err)
+		}
+		if err := storage.SetConfig("test-"+suffix, fmt.Sprint(i)); err != nil {
+			t.Fatalf("SetConfig failed: %v", err)
+		}
+	}
+	if value, _ := storage.GetConfig("test-" + suffix); value != "1" {
+		t.Errorf("GetConfig() = %q, want 1", value)
+	}
+	if got, err := storage.GetUser(user.Name); err != nil || !got.MFAEnabled {
+		t.Errorf("GetUser() = %+v, %v", got, err)
+	}
+
+	from := time.Now().Add(-time.Minute)
+	if err := storage.CreateAuditEntry(&AuditEntry{UserName: user.Name, Action: AuditUserCreate}); err != nil {
+		t.Fatalf("CreateAuditEntry failed: %v", err)
+	}
+	if entries, err := storage.ListAuditEntries(&from, nil, user.Name, "", 10); err != nil || len(entries) != 1 {
+		t.Errorf("ListAuditEntries() = %d entries, %v", len(entries), err)
+	}
+	if err := storage.CreateSyncHistoryEntry(&SyncHistoryEntry{Trigger: "manual", Environment: "env-" + suffix}); err != nil {
+		t.Fatalf("CreateSyncHistoryEntry failed: %v", err)
+	}
+	if history, err := storage.ListSyncHistory("env-"+suffix, true, 10); err != nil || len(history) != 1 {
+		t.Errorf("ListSyncHistory() = %d entries, %v", len(history), err)
+	}
+}
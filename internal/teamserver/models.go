@@ -9,6 +9,7 @@
 package teamserver
 
 import (
+	"path/filepath"
 	"time"
 )
 
@@ -60,6 +61,15 @@ type Project struct {
 	CreatedBy   string    `json:"created_by,omitempty"`
 }
 
+// ProjectConfig is the canonical .magebox.yaml of a project, shared with
+// its team members through 'magebox team pull-config'
+type ProjectConfig struct {
+	Project   string    `json:"project"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+}
+
 // User represents a team member
 type User struct {
 	ID           int64      `json:"id"`
@@ -106,6 +116,9 @@ type Environment struct {
 	DeployKey  string    `json:"-"` // Never expose - encrypted private key
 	HostKey    string    `json:"-"` // SSH host key fingerprint for verification (SHA256:...)
 	CreatedAt  time.Time `json:"created_at"`
+
+	Principals []string           `json:"principals,omitempty"` // Principals of certificates for it; empty uses the default principals
+	Health     *EnvironmentHealth `json:"health,omitempty"`     // Latest health check, in admin responses
 }
 
 // GetPort returns port with default fallback
@@ -121,6 +134,45 @@ func (e *Environment) FullName() string {
 	return e.Project + "/" + e.Name
 }
 
+// Environment health states
+const (
+	AuthorizedKeysOK       = "ok"        // authorized_keys exists and is writable
+	AuthorizedKeysMissing  = "missing"   // No authorized_keys, keys were never synced
+	AuthorizedKeysReadOnly = "read_only" // authorized_keys can't be written by the deploy user
+
+	TrustedCAOK            = "trusted"        // sshd trusts the CA of the server
+	TrustedCANotConfigured = "not_configured" // sshd has no TrustedUserCAKeys
+	TrustedCAOther         = "other_ca"       // TrustedUserCAKeys doesn't hold the CA of the server
+	TrustedCAUnreadable    = "unreadable"     // The TrustedUserCAKeys file can't be read by the deploy user
+)
+
+// EnvironmentHealth is the result of checking an environment over SSH with
+// its deploy key
+type EnvironmentHealth struct {
+	Environment    string    `json:"environment"`
+	Healthy        bool      `json:"healthy"`
+	Reachable      bool      `json:"reachable"`
+	LatencyMs      int64     `json:"latency_ms,omitempty"` // Time to connect and authenticate
+	AuthorizedKeys string    `json:"authorized_keys,omitempty"`
+	ManagedKeys    int       `json:"managed_keys"`         // Keys in authorized_keys deployed by MageBox
+	TrustedCA      string    `json:"trusted_ca,omitempty"` // Only checked when the SSH CA is enabled
+	Problems       []string  `json:"problems,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// PendingKeyRemoval is a user key that still has to be removed from an
+// environment that couldn't be reached when the user lost access
+type PendingKeyRemoval struct {
+	ID          int64     `json:"id"`
+	UserName    string    `json:"user_name"`
+	Project     string    `json:"project"`
+	Environment string    `json:"environment"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 // Invite represents a pending user invitation
 type Invite struct {
 	ID        int64      `json:"id"`
@@ -158,6 +210,7 @@ const (
 	// Environment actions
 	AuditEnvCreate AuditAction = "ENV_CREATE"
 	AuditEnvRemove AuditAction = "ENV_REMOVE"
+	AuditEnvUpdate AuditAction = "ENV_UPDATE"
 	AuditEnvAccess AuditAction = "ENV_ACCESS"
 
 	// Key actions
@@ -172,6 +225,9 @@ const (
 	AuditMFASetup    AuditAction = "MFA_SETUP"
 	AuditMFAVerify   AuditAction = "MFA_VERIFY"
 
+	// Deployment actions
+	AuditDeploy AuditAction = "DEPLOY"
+
 	// Admin actions
 	AuditAdminAction  AuditAction = "ADMIN_ACTION"
 	AuditConfigChange AuditAction = "CONFIG_CHANGE"
@@ -189,6 +245,30 @@ type AuditEntry struct {
 	Hash      string      `json:"-"` // Hash chain - this entry hash
 }
 
+// Sync triggers recorded in the sync history
+const (
+	SyncTriggerManual    = "manual"
+	SyncTriggerScheduled = "scheduled"
+)
+
+// SyncHistoryEntry is the result of syncing the keys of one environment
+type SyncHistoryEntry struct {
+	ID          int64     `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Trigger     string    `json:"trigger"`     // manual or scheduled
+	Environment string    `json:"environment"` // project/name
+	Success     bool      `json:"success"`
+	KeysAdded   int       `json:"keys_added"`   // Keys that were missing
+	KeysRemoved int       `json:"keys_removed"` // Keys that shouldn't have been there
+	Error       string    `json:"error,omitempty"`
+}
+
+// Drift reports whether the environment's keys differed from what they
+// should be
+func (e *SyncHistoryEntry) Drift() bool {
+	return e.KeysAdded > 0 || e.KeysRemoved > 0
+}
+
 // Session represents an authenticated session
 type Session struct {
 	UserID    int64     `json:"user_id"`
@@ -221,6 +301,77 @@ type ServerConfig struct {
 	Notifications NotificationConfig `yaml:"notifications"`
 
 	Audit AuditConfig `yaml:"audit"`
+
+	UI UIConfig `yaml:"ui"`
+
+	Sync SyncConfig `yaml:"sync"`
+
+	Health HealthConfig `yaml:"health"`
+
+	OIDC OIDCConfig `yaml:"oidc"`
+
+	Database DatabaseConfig `yaml:"database"`
+}
+
+// DatabaseConfig selects the database of the team server
+type DatabaseConfig struct {
+	Driver string `yaml:"driver"` // sqlite (default), postgres or mysql
+	DSN    string `yaml:"dsn"`    // Default for sqlite: <data_dir>/teamserver.db
+}
+
+// GetDriver returns the driver, sqlite when none is set
+func (c DatabaseConfig) GetDriver() string {
+	if c.Driver == "" {
+		return DriverSQLite
+	}
+	return c.Driver
+}
+
+// Resolve returns the config with the SQLite file in dataDir when no DSN is
+// set
+func (c DatabaseConfig) Resolve(dataDir string) DatabaseConfig {
+	c.Driver = c.GetDriver()
+	if c.DSN == "" && c.Driver == DriverSQLite {
+		c.DSN = filepath.Join(dataDir, "teamserver.db")
+	}
+	return c
+}
+
+// OIDCConfig holds settings of single sign-on through an OpenID Connect
+// provider, e.g. Google, Azure AD or Keycloak
+type OIDCConfig struct {
+	Enabled        bool                        `yaml:"enabled"`
+	Issuer         string                      `yaml:"issuer"` // e.g. https://accounts.google.com
+	ClientID       string                      `yaml:"client_id"`
+	ClientSecret   string                      `yaml:"client_secret"`
+	RedirectURL    string                      `yaml:"redirect_url"`    // Default: <server URL>/api/oidc/callback
+	Scopes         []string                    `yaml:"scopes"`          // Default: openid, email, profile
+	GroupsClaim    string                      `yaml:"groups_claim"`    // ID token claim with the user's groups, default: groups
+	AllowedDomains []string                    `yaml:"allowed_domains"` // Email domains allowed to sign in; empty allows all
+	DefaultRole    Role                        `yaml:"default_role"`    // Role of users in no mapped group; empty denies them
+	Groups         map[string]OIDCGroupMapping `yaml:"groups"`          // By group name
+}
+
+// OIDCGroupMapping is the role and project access of the members of a group
+type OIDCGroupMapping struct {
+	Role     Role     `yaml:"role"`
+	Projects []string `yaml:"projects"`
+}
+
+// SyncConfig holds settings of the background key reconciliation
+type SyncConfig struct {
+	Interval    string `yaml:"interval"`     // How often keys are reconciled, e.g. "1h"; "0" disables it
+	HistoryDays int    `yaml:"history_days"` // How long sync history is kept; 0 keeps it forever
+}
+
+// HealthConfig holds settings of the environment health checks
+type HealthConfig struct {
+	Interval string `yaml:"interval"` // How often environments are checked, e.g. "15m"; "0" disables it
+}
+
+// UIConfig holds settings of the web admin UI
+type UIConfig struct {
+	Disabled bool `yaml:"disabled"` // Don't serve the UI under /ui
 }
 
 // TLSConfig holds TLS settings
@@ -237,6 +388,7 @@ type SecurityConfig struct {
 	AdminMFA           string   `yaml:"admin_mfa"` // required, optional, disabled
 	InviteExpiry       string   `yaml:"invite_expiry"`
 	SessionExpiry      string   `yaml:"session_expiry"`
+	RefreshExpiry      string   `yaml:"refresh_expiry"`
 	RateLimitEnabled   bool     `yaml:"rate_limit_enabled"`
 	RateLimitPerMinute int      `yaml:"rate_limit_per_minute"`
 	LoginAttempts      int      `yaml:"login_attempts"`
@@ -250,12 +402,16 @@ type CAConfig struct {
 	Enabled           bool     `yaml:"enabled"`            // Enable SSH CA (default: true)
 	CertValidity      string   `yaml:"cert_validity"`      // Certificate validity duration (default: 24h)
 	DefaultPrincipals []string `yaml:"default_principals"` // Default principals for certificates (default: ["deploy"])
+
+	// RolePrincipals limits the principals users of a role get, e.g.
+	// readonly: [www-data]. Roles not listed get all.
+	RolePrincipals map[Role][]string `yaml:"role_principals"`
 }
 
 // NotificationConfig holds notification settings
 type NotificationConfig struct {
-	SMTP    SMTPConfig    `yaml:"smtp"`
-	Webhook WebhookConfig `yaml:"webhook"`
+	SMTP     SMTPConfig      `yaml:"smtp"`
+	Webhooks []WebhookConfig `yaml:"webhooks"`
 }
 
 // SMTPConfig holds email settings
@@ -268,10 +424,11 @@ type SMTPConfig struct {
 	From     string `yaml:"from"`
 }
 
-// WebhookConfig holds webhook settings
+// WebhookConfig is a webhook notified of team server events
 type WebhookConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	URL     string `yaml:"url"`
+	URL    string              `yaml:"url"`
+	Format string              `yaml:"format"` // slack, teams or json (default: from the URL)
+	Events []NotificationEvent `yaml:"events"` // Events to post; empty posts all
 }
 
 // AuditConfig holds audit settings
@@ -291,7 +448,8 @@ func DefaultServerConfig() *ServerConfig {
 		Security: SecurityConfig{
 			AdminMFA:           "optional",
 			InviteExpiry:       "48h",
-			SessionExpiry:      "720h", // 30 days
+			SessionExpiry:      "720h",  // 30 days
+			RefreshExpiry:      "2160h", // 90 days
 			RateLimitEnabled:   false,
 			RateLimitPerMinute: 0,
 			LoginAttempts:      5,
@@ -305,6 +463,13 @@ func DefaultServerConfig() *ServerConfig {
 		Audit: AuditConfig{
 			RetentionDays: 365,
 		},
+		Sync: SyncConfig{
+			Interval:    "1h",
+			HistoryDays: 90,
+		},
+		Health: HealthConfig{
+			Interval: "15m",
+		},
 	}
 }
 
@@ -342,6 +507,10 @@ type JoinResponse struct {
 	ServerHost   string               `json:"server_host"`             // Team server hostname for key storage
 	CAEnabled    bool                 `json:"ca_enabled"`              // Whether SSH CA is enabled
 	CAPublicKey  string               `json:"ca_public_key,omitempty"` // CA public key for reference
+
+	SessionExpiresAt *time.Time `json:"session_expires_at,omitempty"`
+	RefreshToken     string     `json:"refresh_token,omitempty"` // Rotates the session token
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
 }
 
 // EnvironmentForUser represents environment info returned to users (for SSH connection)
@@ -355,12 +524,18 @@ type EnvironmentForUser struct {
 
 // CreateEnvironmentRequest represents environment creation request
 type CreateEnvironmentRequest struct {
-	Name       string `json:"name"`
-	Project    string `json:"project"` // Project this environment belongs to
-	Host       string `json:"host"`
-	Port       int    `json:"port,omitempty"`
-	DeployUser string `json:"deploy_user"`
-	DeployKey  string `json:"deploy_key"`
+	Name       string   `json:"name"`
+	Project    string   `json:"project"` // Project this environment belongs to
+	Host       string   `json:"host"`
+	Port       int      `json:"port,omitempty"`
+	DeployUser string   `json:"deploy_user"`
+	DeployKey  string   `json:"deploy_key"`
+	Principals []string `json:"principals,omitempty"`
+}
+
+// UpdateEnvironmentRequest represents environment update request
+type UpdateEnvironmentRequest struct {
+	Principals *[]string `json:"principals,omitempty"` // Empty list resets to the default principals
 }
 
 // CreateProjectRequest represents project creation request
@@ -369,6 +544,11 @@ type CreateProjectRequest struct {
 	Description string `json:"description,omitempty"`
 }
 
+// SetProjectConfigRequest represents a request to store a project's .magebox.yaml
+type SetProjectConfigRequest struct {
+	Content string `json:"content"`
+}
+
 // GrantAccessRequest represents a request to grant project access to a user
 type GrantAccessRequest struct {
 	Project string `json:"project"`
@@ -419,6 +599,8 @@ type CAInfoResponse struct {
 	CertValidity string   `json:"cert_validity"` // Default certificate validity
 	Principals   []string `json:"principals"`    // Default principals
 	Fingerprint  string   `json:"fingerprint"`   // CA key fingerprint
+
+	RolePrincipals map[Role][]string `json:"role_principals,omitempty"` // Principals each listed role is limited to
 }
 
 // Audit actions for certificate operations
@@ -427,3 +609,43 @@ const (
 	AuditCertRenew AuditAction = "CERT_RENEW"
 	AuditCertDeny  AuditAction = "CERT_DENY"
 )
+
+// UserSession is the session of a user: the hashes of the session token and
+// of the refresh token that rotates it. Nil expiry times never expire.
+type UserSession struct {
+	UserName         string
+	TokenHash        string
+	ExpiresAt        *time.Time
+	RefreshTokenHash string
+	RefreshExpiresAt *time.Time
+}
+
+// IsExpired checks if the session token has expired
+func (s *UserSession) IsExpired() bool {
+	return s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt)
+}
+
+// RefreshExpired checks if the refresh token has expired
+func (s *UserSession) RefreshExpired() bool {
+	return s.RefreshExpiresAt != nil && time.Now().After(*s.RefreshExpiresAt)
+}
+
+// TokenRotateRequest rotates a session with its refresh token. Without one
+// the session token of the request is rotated.
+type TokenRotateRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// TokenRotateResponse holds the tokens of a new session
+type TokenRotateResponse struct {
+	SessionToken     string     `json:"session_token"`
+	SessionExpiresAt *time.Time `json:"session_expires_at,omitempty"`
+	RefreshToken     string     `json:"refresh_token"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+}
+
+// Audit actions for sessions
+const (
+	AuditTokenRotate   AuditAction = "TOKEN_ROTATE"
+	AuditSessionRevoke AuditAction = "SESSION_REVOKE"
+)
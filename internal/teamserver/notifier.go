@@ -14,18 +14,21 @@ import (
 	"fmt"
 	"html/template"
 	"net"
+	"net/http"
 	"net/smtp"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// Notifier handles email notifications
+// Notifier handles email and webhook notifications
 type Notifier struct {
-	config    SMTPConfig
-	from      string
-	enabled   bool
-	templates map[string]*template.Template
+	config     SMTPConfig
+	from       string
+	enabled    bool
+	templates  map[string]*template.Template
+	webhooks   []WebhookConfig
+	httpClient *http.Client
 }
 
 // NewNotifier creates a new email notifier
@@ -35,6 +38,9 @@ func NewNotifier(config SMTPConfig) *Notifier {
 		from:      config.From,
 		enabled:   config.Enabled,
 		templates: make(map[string]*template.Template),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
 	}
 
 	if n.from == "" {
@@ -47,7 +53,7 @@ func NewNotifier(config SMTPConfig) *Notifier {
 	return n
 }
 
-// IsEnabled returns whether notifications are enabled
+// IsEnabled returns whether email notifications are enabled
 func (n *Notifier) IsEnabled() bool {
 	return n.enabled && n.config.Host != ""
 }
@@ -480,6 +486,10 @@ const (
 	NotifyAccessExpiry  NotificationEvent = "ACCESS_EXPIRY"
 	NotifyKeyDeployed   NotificationEvent = "KEY_DEPLOYED"
 	NotifyKeyRemoved    NotificationEvent = "KEY_REMOVED"
+	NotifyAccessGranted NotificationEvent = "ACCESS_GRANTED"
+	NotifyAccessRevoked NotificationEvent = "ACCESS_REVOKED"
+	NotifyKeySyncFailed NotificationEvent = "KEY_SYNC_FAILED"
+	NotifyEnvUnhealthy  NotificationEvent = "ENV_UNHEALTHY"
 )
 
 // String returns the string representation of the event
@@ -0,0 +1,1012 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcClockSkew is the clock difference tolerated when checking ID tokens
+const oidcClockSkew = time.Minute
+
+// OIDCProvider talks to an OpenID Connect provider: it builds the
+// authorization URL, exchanges codes for ID tokens and verifies them
+type OIDCProvider struct {
+	config     OIDCConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey // By key ID
+	keysFetchedAt time.Time
+}
+
+// oidcDiscovery is the part of the provider's discovery document MageBox uses
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims are the ID token claims MageBox uses
+type OIDCClaims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Groups            []string
+}
+
+// NewOIDCProvider creates a provider client. The discovery document is
+// fetched on first use.
+func NewOIDCProvider(config OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// scopes returns the requested scopes
+func (p *OIDCProvider) scopes() []string {
+	if len(p.config.Scopes) > 0 {
+		return p.config.Scopes
+	}
+	return []string{"openid", "email", "profile"}
+}
+
+// getJSON fetches a JSON document from the provider
+func (p *OIDCProvider) getJSON(endpoint string, v any) error {
+	resp, err := p.httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// discover fetches and caches the discovery document
+func (p *OIDCProvider) discover() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	endpoint := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(endpoint, &d); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return nil, fmt.Errorf("OIDC provider issuer %q doesn't match the configured %q", d.Issuer, p.config.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC provider discovery document is incomplete")
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// AuthURL returns the URL the user signs in at. The verifier is the PKCE
+// code verifier that goes with the code exchange.
+func (p *OIDCProvider) AuthURL(redirectURL, state, nonce, verifier string) (string, error) {
+	d, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(p.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange trades an authorization code for the user's verified ID token
+// claims
+func (p *OIDCProvider) Exchange(code, redirectURL, verifier, nonce string) (*OIDCClaims, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("failed to exchange code: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no ID token")
+	}
+
+	return p.Verify(token.IDToken, nonce)
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID
+// token and returns its claims
+func (p *OIDCProvider) Verify(idToken, nonce string) (*OIDCClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed ID token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token signature: %w", err)
+	}
+
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+	return p.checkClaims(claims, nonce)
+}
+
+// checkClaims validates the registered claims of a verified ID token
+func (p *OIDCProvider) checkClaims(claims map[string]any, nonce string) (*OIDCClaims, error) {
+	issuer, _ := claims["iss"].(string)
+	if strings.TrimSuffix(issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return nil, fmt.Errorf("ID token issued by %q, not %q", issuer, p.config.Issuer)
+	}
+
+	audienceOK := false
+	switch aud := claims["aud"].(type) {
+	case string:
+		audienceOK = aud == p.config.ClientID
+	case []any:
+		for _, a := range aud {
+			if a == p.config.ClientID {
+				audienceOK = true
+			}
+		}
+	}
+	if !audienceOK {
+		return nil, fmt.Errorf("ID token isn't meant for this server")
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, fmt.Errorf("ID token expired")
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("ID token nonce doesn't match")
+	}
+
+	result := &OIDCClaims{Issuer: issuer}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	// A missing claim counts as unverified: linking accounts by email address
+	// relies on it
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	groupsClaim := p.config.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	switch groups := claims[groupsClaim].(type) {
+	case []any:
+		for _, g := range groups {
+			if name, ok := g.(string); ok {
+				result.Groups = append(result.Groups, name)
+			}
+		}
+	case string:
+		result.Groups = []string{groups}
+	}
+
+	if result.Subject == "" {
+		return nil, fmt.Errorf("ID token has no subject")
+	}
+	return result, nil
+}
+
+// key returns the provider's signing key with an ID. The keys are fetched
+// again when the ID is unknown, as providers rotate them.
+func (p *OIDCProvider) key(kid string) (crypto.PublicKey, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lookup := func() crypto.PublicKey {
+		if key, ok := p.keys[kid]; ok {
+			return key
+		}
+		// Tokens of providers with a single key may not name it
+		if kid == "" && len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key
+			}
+		}
+		return nil
+	}
+
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	// Don't let tokens with made-up key IDs hammer the provider
+	if time.Since(p.keysFetchedAt) < time.Minute && p.keys != nil {
+		return nil, fmt.Errorf("unknown ID token signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	p.keys = make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			p.keys[k.Kid] = key
+		}
+	}
+	p.keysFetchedAt = time.Now()
+
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown ID token signing key %q", kid)
+}
+
+// jsonWebKey is an RSA or EC public key of a JWK set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(v string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// verifyJWTSignature checks an RS256 or ES256 signature
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("ID token signing key isn't an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid ID token signature")
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("invalid ID token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("invalid ID token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported ID token algorithm %q", alg)
+}
+
+// decodeJWTPart decodes a base64url JSON part of a JWT
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// roleRank orders roles by privilege
+var roleRank = map[Role]int{RoleReadonly: 1, RoleDev: 2, RoleAdmin: 3}
+
+// errNoOIDCRole is returned for users who are in no mapped group while
+// there is no default role
+var errNoOIDCRole = errors.New("your account isn't in a group with access to this team server")
+
+// MapGroups returns the role and projects of a user in groups: the most
+// privileged role of the mapped groups, else the default role, and the
+// projects of all mapped groups
+func (c *OIDCConfig) MapGroups(groups []string) (Role, []string, error) {
+	var role Role
+	var projects []string
+	seen := make(map[string]bool)
+
+	for _, group := range groups {
+		mapping, ok := c.Groups[group]
+		if !ok {
+			continue
+		}
+		if roleRank[mapping.Role] > roleRank[role] {
+			role = mapping.Role
+		}
+		for _, project := range mapping.Projects {
+			if !seen[project] {
+				seen[project] = true
+				projects = append(projects, project)
+			}
+		}
+	}
+
+	if role == "" {
+		role = c.DefaultRole
+	}
+	if !role.IsValid() {
+		return "", nil, errNoOIDCRole
+	}
+	return role, projects, nil
+}
+
+// EmailAllowed reports whether users with an email address may sign in
+func (c *OIDCConfig) EmailAllowed(email string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range c.AllowedDomains {
+		if domain == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// oidcUserName derives a user name from the claims: the preferred user
+// name, else the local part of the email address, restricted to the
+// characters user names may have
+func oidcUserName(claims *OIDCClaims) string {
+	name := claims.PreferredUsername
+	if name == "" || strings.Contains(name, "@") {
+		name = claims.Email
+		if at := strings.Index(name, "@"); at >= 0 {
+			name = name[:at]
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+		}
+	}
+	return strings.Trim(b.String(), ".-_")
+}
+
+// oidcLoginTimeout is how long a user has to complete a sign-in
+const oidcLoginTimeout = 10 * time.Minute
+
+// oidcLogin is a sign-in in progress. The CLI starts it, the browser
+// completes it at the callback and is redirected to the CLI's loopback
+// callback with the completion code. The CLI then collects the result with
+// the poll token and the completion code: someone who only had the browser
+// sign in gets neither, so sending the sign-in URL to another person
+// doesn't sign the sender in as them.
+type oidcLogin struct {
+	pollToken      string
+	completionCode string
+	clientCallback string
+	nonce          string
+	verifier       string
+	redirectURL    string
+	expiresAt      time.Time
+
+	done   bool
+	claims *OIDCClaims
+	err    error
+}
+
+// oidcLoginStore holds the sign-ins in progress by state
+type oidcLoginStore struct {
+	mu     sync.Mutex
+	logins map[string]*oidcLogin
+}
+
+// add stores a sign-in and drops the expired ones
+func (st *oidcLoginStore) add(state string, login *oidcLogin) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	for s, l := range st.logins {
+		if now.After(l.expiresAt) {
+			delete(st.logins, s)
+		}
+	}
+	if st.logins == nil {
+		st.logins = make(map[string]*oidcLogin)
+	}
+	st.logins[state] = login
+}
+
+// get returns an unexpired sign-in
+func (st *oidcLoginStore) get(state string) *oidcLogin {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	login, ok := st.logins[state]
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil
+	}
+	return login
+}
+
+// complete records the result of a sign-in
+func (st *oidcLoginStore) complete(state string, claims *OIDCClaims, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if login, ok := st.logins[state]; ok {
+		login.done = true
+		login.claims = claims
+		login.err = err
+	}
+}
+
+// oidcLoginResult is the state of a sign-in the CLI polls for
+type oidcLoginResult struct {
+	pending bool
+	claims  *OIDCClaims
+	err     error
+}
+
+// collect returns the result of a sign-in. Calls with the wrong poll token
+// find nothing; a completed sign-in stays pending until the completion code
+// matches, and is returned once.
+func (st *oidcLoginStore) collect(state, pollToken, completionCode string) (oidcLoginResult, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	login, ok := st.logins[state]
+	if !ok || time.Now().After(login.expiresAt) ||
+		subtle.ConstantTimeCompare([]byte(login.pollToken), []byte(pollToken)) != 1 {
+		return oidcLoginResult{}, false
+	}
+	switch {
+	case !login.done:
+		return oidcLoginResult{pending: true}, true
+	case login.err != nil:
+		delete(st.logins, state)
+		return oidcLoginResult{err: login.err}, true
+	case subtle.ConstantTimeCompare([]byte(login.completionCode), []byte(completionCode)) != 1:
+		return oidcLoginResult{pending: true}, true
+	}
+	delete(st.logins, state)
+	return oidcLoginResult{claims: login.claims}, true
+}
+
+// OIDCLoginRequest starts a sign-in
+type OIDCLoginRequest struct {
+	// ClientCallback is the loopback URL of the CLI the browser is
+	// redirected to, e.g. http://127.0.0.1:53682/callback
+	ClientCallback string `json:"client_callback"`
+}
+
+// OIDCLoginResponse is returned when a sign-in is started
+type OIDCLoginResponse struct {
+	AuthURL   string `json:"auth_url"`
+	LoginID   string `json:"login_id"`
+	PollToken string `json:"poll_token"`
+	ExpiresIn int    `json:"expires_in"` // Seconds
+}
+
+// OIDCPollRequest asks for the result of a sign-in
+type OIDCPollRequest struct {
+	LoginID        string `json:"login_id"`
+	PollToken      string `json:"poll_token"`
+	CompletionCode string `json:"completion_code,omitempty"` // From the loopback callback
+}
+
+// validateClientCallback checks that a CLI callback URL is an http URL on the
+// loopback interface, so the browser only hands the completion code to the
+// machine it runs on
+func validateClientCallback(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "http" || u.Port() == "" || u.User != nil || u.Fragment != "" {
+		return fmt.Errorf("client callback must be an http://127.0.0.1:<port> URL")
+	}
+	switch u.Hostname() {
+	case "127.0.0.1", "::1", "localhost":
+		return nil
+	}
+	return fmt.Errorf("client callback must be on the loopback interface")
+}
+
+// handleOIDCLogin starts a sign-in and returns the URL to open in a browser
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST is allowed")
+		return
+	}
+
+	var req OIDCLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body, update MageBox to sign in with SSO")
+		return
+	}
+	if err := validateClientCallback(req.ClientCallback); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_CALLBACK", err.Error())
+		return
+	}
+
+	state, err1 := GenerateToken(24)
+	pollToken, err2 := GenerateToken(32)
+	completionCode, err3 := GenerateToken(32)
+	nonce, err4 := GenerateToken(24)
+	verifier, err5 := GenerateToken(48)
+	if err := errors.Join(err1, err2, err3, err4, err5); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "TOKEN_ERROR", "Failed to generate login tokens")
+		return
+	}
+
+	redirectURL := s.config.OIDC.RedirectURL
+	if redirectURL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		redirectURL = fmt.Sprintf("%s://%s/api/oidc/callback", scheme, r.Host)
+	}
+
+	authURL, err := s.oidc.AuthURL(redirectURL, state, nonce, verifier)
+	if err != nil {
+		s.logger.Printf("OIDC login failed: %v", err)
+		s.writeError(w, http.StatusBadGateway, "OIDC_ERROR", "Identity provider is unavailable")
+		return
+	}
+
+	s.oidcLogins.add(state, &oidcLogin{
+		pollToken:      pollToken,
+		completionCode: completionCode,
+		clientCallback: req.ClientCallback,
+		nonce:          nonce,
+		verifier:       verifier,
+		redirectURL:    redirectURL,
+		expiresAt:      time.Now().Add(oidcLoginTimeout),
+	})
+
+	_ = json.NewEncoder(w).Encode(OIDCLoginResponse{
+		AuthURL:   authURL,
+		LoginID:   state,
+		PollToken: pollToken,
+		ExpiresIn: int(oidcLoginTimeout.Seconds()),
+	})
+}
+
+// handleOIDCCallback completes a sign-in when the provider redirects the
+// browser back, and redirects the browser on to the CLI with the completion
+// code
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET is allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	login := s.oidcLogins.get(state)
+	if login == nil || login.done {
+		WriteOIDCPage(w, http.StatusBadRequest, "Sign-in failed", "This sign-in expired or was already used. Run the command again.")
+		return
+	}
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		err := fmt.Errorf("identity provider refused sign-in: %s %s", providerErr, query.Get("error_description"))
+		s.logAudit(AuditAuthFailed, "", fmt.Sprintf("SSO login failed: %v", err), s.getClientIP(r))
+		s.oidcLogins.complete(state, nil, err)
+		WriteOIDCPage(w, http.StatusUnauthorized, "Sign-in failed", err.Error())
+		return
+	}
+
+	claims, err := s.oidc.Exchange(query.Get("code"), login.redirectURL, login.verifier, login.nonce)
+	if err != nil {
+		s.logAudit(AuditAuthFailed, "", fmt.Sprintf("SSO login failed: %v", err), s.getClientIP(r))
+		s.oidcLogins.complete(state, nil, fmt.Errorf("sign-in failed: %w", err))
+		WriteOIDCPage(w, http.StatusUnauthorized, "Sign-in failed", "The identity provider's response couldn't be verified.")
+		return
+	}
+
+	// Deny early, so the browser shows why; the user is only changed once
+	// the CLI collects the sign-in
+	if _, _, _, err := s.authorizeOIDC(claims); err != nil {
+		s.logAudit(AuditAuthFailed, claims.Email, fmt.Sprintf("SSO login denied: %v", err), s.getClientIP(r))
+		s.oidcLogins.complete(state, nil, err)
+		WriteOIDCPage(w, http.StatusForbidden, "Sign-in denied", err.Error())
+		return
+	}
+
+	s.oidcLogins.complete(state, claims, nil)
+
+	callback, _ := url.Parse(login.clientCallback)
+	params := callback.Query()
+	params.Set("code", login.completionCode)
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// handleOIDCPoll returns the result of a sign-in: 202 while it's pending,
+// then the join response once
+func (s *Server) handleOIDCPoll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST is allowed")
+		return
+	}
+
+	var req OIDCPollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		return
+	}
+
+	result, found := s.oidcLogins.collect(req.LoginID, req.PollToken, req.CompletionCode)
+	switch {
+	case !found:
+		s.writeError(w, http.StatusNotFound, "LOGIN_NOT_FOUND", "Sign-in not found or expired")
+		return
+	case result.pending:
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "pending"})
+		return
+	case result.err != nil:
+		s.writeError(w, http.StatusForbidden, "SSO_DENIED", result.err.Error())
+		return
+	}
+
+	response, err := s.oidcSignIn(r, result.claims)
+	if err != nil {
+		s.logAudit(AuditAuthFailed, result.claims.Email, fmt.Sprintf("SSO login denied: %v", err), s.getClientIP(r))
+		s.writeError(w, http.StatusForbidden, "SSO_DENIED", err.Error())
+		return
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// authorizeOIDC checks that verified claims may sign in and returns the
+// user, new and unsaved on first sign-in, and the role and projects of the
+// group mapping
+func (s *Server) authorizeOIDC(claims *OIDCClaims) (*User, Role, []string, error) {
+	config := &s.config.OIDC
+
+	if claims.Email == "" {
+		return nil, "", nil, fmt.Errorf("your identity provider didn't share your email address")
+	}
+	if !claims.EmailVerified {
+		return nil, "", nil, fmt.Errorf("your email address %s isn't verified", claims.Email)
+	}
+	if !config.EmailAllowed(claims.Email) {
+		return nil, "", nil, fmt.Errorf("accounts of %s may not sign in to this team server", claims.Email)
+	}
+
+	role, projects, err := config.MapGroups(claims.Groups)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	user, err := s.oidcUser(claims)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return user, role, projects, nil
+}
+
+// oidcSignIn creates or updates the user of verified claims, applies the
+// group mapping and issues a new session token and SSH key. The mapped role
+// is only given on the first sign-in of the account's link.
+func (s *Server) oidcSignIn(r *http.Request, claims *OIDCClaims) (*JoinResponse, error) {
+	user, role, projects, err := s.authorizeOIDC(claims)
+	if err != nil {
+		return nil, err
+	}
+	isNew := user.ID == 0
+	signedIn := false
+	if !isNew {
+		if signedIn, err = s.storage.OIDCIdentitySignedIn(claims.Issuer, claims.Subject); err != nil {
+			return nil, fmt.Errorf("failed to load identity")
+		}
+	}
+
+	keyPair, err := GenerateSSHKeyPair(fmt.Sprintf("magebox-%s@%s", user.Name, r.Host))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SSH key pair")
+	}
+
+	user.Email = claims.Email
+	user.PublicKey = keyPair.PublicKey
+
+	// The group mapping and default access period only shape an account on
+	// the first sign-in of its link, afterwards its role and expiry are the
+	// admin's to change
+	if !signedIn {
+		user.Role = role
+		user.ExpiresAt = nil
+		if s.config.Security.DefaultAccessDays > 0 {
+			exp := time.Now().AddDate(0, 0, s.config.Security.DefaultAccessDays)
+			user.ExpiresAt = &exp
+		}
+	}
+
+	if isNew {
+		user.CreatedBy = "oidc"
+		err = s.storage.CreateUser(user)
+	} else {
+		err = s.storage.UpdateUser(user)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user")
+	}
+	if err := s.storage.LinkOIDCIdentity(claims.Issuer, claims.Subject, user.Name); err != nil {
+		return nil, fmt.Errorf("failed to link identity")
+	}
+	tokens, err := s.issueSession(user.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.config.OIDC.Groups) > 0 {
+		s.applyOIDCProjects(user.Name, projects)
+	}
+	user, err = s.storage.GetUser(user.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user")
+	}
+
+	if isNew {
+		s.logAudit(AuditUserJoin, user.Name, fmt.Sprintf("User joined through SSO: %s (SSH key generated)", user.Email), s.getClientIP(r))
+		s.notifyWebhooks(NotifyUserJoined, user.Name, "User joined",
+			fmt.Sprintf("%s (%s) joined the team through SSO as %s", user.Name, user.Email, user.Role))
+	} else {
+		s.logAudit(AuditAuthSuccess, user.Name, fmt.Sprintf("SSO login: %s (SSH key generated)", user.Email), s.getClientIP(r))
+	}
+
+	// Deploy key to accessible environments (async, non-blocking)
+	go s.deployUserKey(user)
+
+	response := s.joinResponse(r, user, keyPair, tokens)
+	return &response, nil
+}
+
+// oidcUser returns the user linked to the claims' identity, or on first
+// sign-in a new, unsaved user. An existing user with the same name and
+// email address isn't linked right away: the identity waits for an admin's
+// approval, as the provider may let anyone claim the name.
+func (s *Server) oidcUser(claims *OIDCClaims) (*User, error) {
+	linked, err := s.storage.GetOIDCIdentity(claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != "" {
+		if user, err := s.storage.GetUser(linked); err == nil {
+			return user, nil
+		}
+		// The user was removed since; sign in as a new user
+	}
+
+	name := oidcUserName(claims)
+	if name == "" {
+		return nil, fmt.Errorf("couldn't derive a user name from your account")
+	}
+
+	existing, err := s.storage.GetUser(name)
+	if err != nil {
+		return &User{Name: name}, nil
+	}
+	if !strings.EqualFold(existing.Email, claims.Email) {
+		return nil, fmt.Errorf("user name %s is taken by another account, ask an admin to resolve it", name)
+	}
+	if err := s.storage.RequestOIDCLink(claims.Issuer, claims.Subject, name); err != nil {
+		return nil, fmt.Errorf("failed to link identity")
+	}
+	return nil, fmt.Errorf("user %s already exists, ask an admin to approve your sign-in with 'magebox server user approve-sso %s', then sign in again", name, name)
+}
+
+// approveOIDCLink approves the SSO identities waiting to be linked to a user
+func (s *Server) approveOIDCLink(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST is allowed")
+		return
+	}
+
+	approved, err := s.storage.ApproveOIDCLink(name)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "STORAGE_ERROR", "Failed to approve SSO sign-in")
+		return
+	}
+	if approved == 0 {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "No SSO sign-in of this user is waiting for approval")
+		return
+	}
+
+	admin := getCurrentUser(r)
+	s.logAudit(AuditAdminAction, admin.Name, fmt.Sprintf("Approved the SSO identity of %s", name), s.getClientIP(r))
+
+	_ = json.NewEncoder(w).Encode(SuccessResponse{
+		Success: true,
+		Message: fmt.Sprintf("SSO sign-in of %s approved", name),
+	})
+}
+
+// applyOIDCProjects grants a user the projects of the mapped groups and
+// revokes the ones an earlier sign-in granted that the groups no longer
+// map to. Access granted by admins is left alone.
+func (s *Server) applyOIDCProjects(userName string, projects []string) {
+	granted, err := s.storage.GetUserProjectsGrantedBy(userName, "oidc")
+	if err != nil {
+		s.logger.Printf("Failed to get SSO project access of %s: %v", userName, err)
+		return
+	}
+
+	for _, project := range projects {
+		if _, err := s.storage.GetProject(project); err != nil {
+			s.logger.Printf("OIDC group mapping names unknown project %s", project)
+			continue
+		}
+		if err := s.storage.GrantProjectAccess(userName, project, "oidc"); err != nil {
+			s.logger.Printf("Failed to grant %s access to %s: %v", userName, project, err)
+		}
+	}
+
+	for _, project := range granted {
+		if slices.Contains(projects, project) {
+			continue
+		}
+		if err := s.storage.RevokeProjectAccess(userName, project); err != nil {
+			s.logger.Printf("Failed to revoke %s access to %s: %v", userName, project, err)
+			continue
+		}
+		s.logAudit(AuditAdminAction, "oidc", fmt.Sprintf("Revoked project access: %s -> %s (no longer in a mapped group)", userName, project), "")
+		go s.removeUserProjectKeys(userName, project)
+	}
+}
+
+// WriteOIDCPage writes the page the browser shows at the end of a sign-in
+func WriteOIDCPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>MageBox - %[1]s</title>
+<style>body{font-family:sans-serif;max-width:32rem;margin:4rem auto;padding:0 1rem;color:#222}</style>
+</head><body><h1>%[1]s</h1><p>%[2]s</p></body></html>
+`, html.EscapeString(title), html.EscapeString(message))
+}
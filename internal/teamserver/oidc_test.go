@@ -0,0 +1,489 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIdP is an OpenID Connect provider that signs in whoever it's told to
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any // Claims of the next ID token
+
+	nonce     string // Nonce of the last authorization request
+	challenge string // PKCE challenge of the last authorization request
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	idp := &fakeIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if id != "magebox" || secret != "secret" || r.FormValue("code") != "good-code" ||
+			base64.RawURLEncoding.EncodeToString(verifier[:]) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := map[string]any{
+			"iss":   idp.server.URL,
+			"aud":   "magebox",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": idp.nonce,
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(t, claims)})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// sign returns an RS256 JWT of the claims
+func (idp *fakeIdP) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func setupOIDCTestServer(t *testing.T, idp *fakeIdP) *Server {
+	t.Helper()
+
+	server, cleanup := setupTestServer(t)
+	t.Cleanup(cleanup)
+
+	server.config.OIDC = OIDCConfig{
+		Enabled:        true,
+		Issuer:         idp.server.URL,
+		ClientID:       "magebox",
+		ClientSecret:   "secret",
+		AllowedDomains: []string{"example.com"},
+		Groups: map[string]OIDCGroupMapping{
+			"developers": {Role: RoleDev, Projects: []string{"shop"}},
+			"ops":        {Role: RoleAdmin, Projects: []string{"blog"}},
+		},
+	}
+	server.oidc = NewOIDCProvider(server.config.OIDC)
+
+	for _, name := range []string{"shop", "blog"} {
+		if err := server.storage.CreateProject(&Project{Name: name}); err != nil {
+			t.Fatalf("Failed to create project: %v", err)
+		}
+	}
+	return server
+}
+
+// testClientCallback is the loopback callback of the CLI in the tests
+const testClientCallback = "http://127.0.0.1:53682/callback"
+
+// startOIDCLogin starts a sign-in as the CLI would
+func startOIDCLogin(t *testing.T, server *Server, idp *fakeIdP) OIDCLoginResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(OIDCLoginRequest{ClientCallback: testClientCallback})
+	w := httptest.NewRecorder()
+	server.handleOIDCLogin(w, httptest.NewRequest(http.MethodPost, "/api/oidc/login", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d: %s", w.Code, w.Body.String())
+	}
+	var login OIDCLoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&login); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+
+	authURL, err := url.Parse(login.AuthURL)
+	if err != nil || !strings.HasPrefix(login.AuthURL, idp.server.URL+"/authorize?") {
+		t.Fatalf("unexpected auth URL %q", login.AuthURL)
+	}
+	idp.nonce = authURL.Query().Get("nonce")
+	idp.challenge = authURL.Query().Get("code_challenge")
+	return login
+}
+
+// completeOIDCLogin signs in in the browser and returns the callback
+// response and the completion code the browser is redirected to the CLI with
+func completeOIDCLogin(t *testing.T, server *Server, login OIDCLoginResponse) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	server.handleOIDCCallback(w, httptest.NewRequest(http.MethodGet, "/api/oidc/callback?code=good-code&state="+url.QueryEscape(login.LoginID), nil))
+	if w.Code != http.StatusFound {
+		return w, ""
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), testClientCallback+"?") {
+		t.Fatalf("callback redirected to %q, want the client callback", w.Header().Get("Location"))
+	}
+	return w, location.Query().Get("code")
+}
+
+// pollOIDCLogin polls for the result of a sign-in as the CLI would
+func pollOIDCLogin(server *Server, login OIDCLoginResponse, completionCode string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(OIDCPollRequest{LoginID: login.LoginID, PollToken: login.PollToken, CompletionCode: completionCode})
+	w := httptest.NewRecorder()
+	server.handleOIDCPoll(w, httptest.NewRequest(http.MethodPost, "/api/oidc/poll", bytes.NewReader(body)))
+	return w
+}
+
+// oidcSignIn runs a sign-in as the CLI and the browser would and returns
+// the status of the callback and the final poll
+func oidcSignIn(t *testing.T, server *Server, idp *fakeIdP) (int, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	login := startOIDCLogin(t, server, idp)
+	if w := pollOIDCLogin(server, login, ""); w.Code != http.StatusAccepted {
+		t.Fatalf("poll before callback status = %d, want 202", w.Code)
+	}
+
+	w, code := completeOIDCLogin(t, server, login)
+	return w.Code, pollOIDCLogin(server, login, code)
+}
+
+func TestOIDCSignInCreatesUser(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+	idp.claims = map[string]any{
+		"sub":                "1234",
+		"email":              "Alice.Smith@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice.smith@example.com",
+		"groups":             []string{"developers"},
+	}
+
+	status, w := oidcSignIn(t, server, idp)
+	if status != http.StatusFound {
+		t.Fatalf("callback status = %d, want a redirect to the CLI", status)
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("poll status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp JoinResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode join response: %v", err)
+	}
+	if resp.SessionToken == "" || resp.PrivateKey == "" {
+		t.Error("join response is missing the session token or private key")
+	}
+
+	user, err := server.storage.GetUser("alice.smith")
+	if err != nil {
+		t.Fatalf("user wasn't created: %v", err)
+	}
+	if user.Role != RoleDev || user.CreatedBy != "oidc" {
+		t.Errorf("user role = %s, created by %s", user.Role, user.CreatedBy)
+	}
+	if len(user.Projects) != 1 || user.Projects[0] != "shop" {
+		t.Errorf("user projects = %v, want [shop]", user.Projects)
+	}
+	if !VerifyToken(resp.SessionToken, user.TokenHash) {
+		t.Error("session token doesn't match the stored hash")
+	}
+}
+
+func TestOIDCPollRequiresPollToken(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+	idp.claims = map[string]any{"sub": "1", "email": "bob@example.com", "email_verified": true, "groups": []string{"ops"}}
+
+	login := startOIDCLogin(t, server, idp)
+
+	// Someone who saw the auth URL, but not the poll token, gets nothing
+	login.PollToken = "guess"
+	if w := pollOIDCLogin(server, login, ""); w.Code != http.StatusNotFound {
+		t.Errorf("poll with wrong token status = %d, want 404", w.Code)
+	}
+}
+
+func TestOIDCPollRequiresCompletionCode(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+	idp.claims = map[string]any{"sub": "1", "email": "bob@example.com", "email_verified": true, "groups": []string{"ops"}}
+
+	// An attacker starts a sign-in and has someone else complete it in their
+	// browser, which hands the completion code to that person's machine
+	login := startOIDCLogin(t, server, idp)
+	if w, code := completeOIDCLogin(t, server, login); w.Code != http.StatusFound || code == "" {
+		t.Fatalf("callback status = %d", w.Code)
+	}
+
+	for _, code := range []string{"", "guess"} {
+		if w := pollOIDCLogin(server, login, code); w.Code != http.StatusAccepted {
+			t.Errorf("poll with completion code %q status = %d, want 202", code, w.Code)
+		}
+	}
+	if _, err := server.storage.GetUser("bob"); err == nil {
+		t.Error("user was created without the completion code")
+	}
+}
+
+func TestOIDCLoginRequiresLoopbackCallback(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+
+	for _, callback := range []string{"", "https://evil.example.com/callback", "http://evil.example.com:8080/callback", "http://127.0.0.1/callback"} {
+		body, _ := json.Marshal(OIDCLoginRequest{ClientCallback: callback})
+		w := httptest.NewRecorder()
+		server.handleOIDCLogin(w, httptest.NewRequest(http.MethodPost, "/api/oidc/login", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("login with callback %q status = %d, want 400", callback, w.Code)
+		}
+	}
+}
+
+func TestOIDCSignInUpdatesGroupsOnNextLogin(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+	idp.claims = map[string]any{"sub": "42", "email": "carol@example.com", "email_verified": true, "groups": []string{"developers", "ops"}}
+
+	if _, w := oidcSignIn(t, server, idp); w.Code != http.StatusOK {
+		t.Fatalf("first sign-in failed: %s", w.Body.String())
+	}
+	user, _ := server.storage.GetUser("carol")
+	if user.Role != RoleAdmin || len(user.Projects) != 2 {
+		t.Fatalf("after first sign-in role = %s, projects = %v", user.Role, user.Projects)
+	}
+
+	// Access an admin granted survives group changes
+	if err := server.storage.CreateProject(&Project{Name: "docs"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	_ = server.storage.GrantProjectAccess("carol", "docs", "admin")
+
+	// So do the role and expiry an admin set
+	expires := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	user.Role = RoleReadonly
+	user.ExpiresAt = &expires
+	if err := server.storage.UpdateUser(user); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	idp.claims["groups"] = []string{"developers"}
+	if _, w := oidcSignIn(t, server, idp); w.Code != http.StatusOK {
+		t.Fatalf("second sign-in failed: %s", w.Body.String())
+	}
+	user, _ = server.storage.GetUser("carol")
+	if user.Role != RoleReadonly {
+		t.Errorf("role = %s, want the admin's readonly", user.Role)
+	}
+	if user.ExpiresAt == nil || !user.ExpiresAt.Equal(expires) {
+		t.Errorf("expires at = %v, want the admin's %v", user.ExpiresAt, expires)
+	}
+	if strings.Join(user.Projects, ",") != "docs,shop" {
+		t.Errorf("projects = %v, want [docs shop]", user.Projects)
+	}
+}
+
+func TestOIDCSignInDenied(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{"domain not allowed", map[string]any{"sub": "1", "email": "eve@evil.com", "email_verified": true, "groups": []string{"developers"}}},
+		{"email not verified", map[string]any{"sub": "1", "email": "eve@example.com", "email_verified": false, "groups": []string{"developers"}}},
+		{"email verification unknown", map[string]any{"sub": "1", "email": "eve@example.com", "groups": []string{"developers"}}},
+		{"no mapped group", map[string]any{"sub": "1", "email": "eve@example.com", "email_verified": true, "groups": []string{"marketing"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			server := setupOIDCTestServer(t, idp)
+			idp.claims = tt.claims
+
+			status, w := oidcSignIn(t, server, idp)
+			if status != http.StatusForbidden {
+				t.Errorf("callback status = %d, want 403", status)
+			}
+			if w.Code != http.StatusForbidden {
+				t.Errorf("poll status = %d, want 403", w.Code)
+			}
+			if _, err := server.storage.GetUser("eve"); err == nil {
+				t.Error("denied user was created")
+			}
+		})
+	}
+}
+
+func TestOIDCSignInDoesNotTakeOverUsers(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+	if err := server.storage.CreateUser(&User{Name: "dave", Email: "dave@other.org", Role: RoleAdmin}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	idp.claims = map[string]any{"sub": "7", "email": "dave@example.com", "email_verified": true, "groups": []string{"developers"}}
+
+	if status, _ := oidcSignIn(t, server, idp); status != http.StatusForbidden {
+		t.Errorf("callback status = %d, want 403", status)
+	}
+	user, _ := server.storage.GetUser("dave")
+	if user.Role != RoleAdmin {
+		t.Error("existing user was changed")
+	}
+}
+
+func TestOIDCSignInLinksExistingUsersAfterApproval(t *testing.T) {
+	idp := newFakeIdP(t)
+	server := setupOIDCTestServer(t, idp)
+	if err := server.storage.CreateUser(&User{Name: "frank", Email: "frank@example.com", Role: RoleAdmin, PublicKey: "ssh-ed25519 AAAA frank"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	idp.claims = map[string]any{"sub": "8", "email": "frank@example.com", "email_verified": true, "groups": []string{"developers"}}
+
+	// The same name and email address isn't enough to take the user over
+	if status, w := oidcSignIn(t, server, idp); status != http.StatusForbidden || w.Code != http.StatusForbidden {
+		t.Fatalf("sign-in before approval: callback %d, poll %d, want 403", status, w.Code)
+	}
+	user, _ := server.storage.GetUser("frank")
+	if user.Role != RoleAdmin || user.PublicKey != "ssh-ed25519 AAAA frank" {
+		t.Fatal("existing user was changed before approval")
+	}
+
+	if n, err := server.storage.ApproveOIDCLink("frank"); err != nil || n != 1 {
+		t.Fatalf("ApproveOIDCLink() = %d, %v", n, err)
+	}
+	if _, w := oidcSignIn(t, server, idp); w.Code != http.StatusOK {
+		t.Fatalf("sign-in after approval failed: %s", w.Body.String())
+	}
+	user, _ = server.storage.GetUser("frank")
+	if user.Role != RoleDev || user.PublicKey == "ssh-ed25519 AAAA frank" {
+		t.Errorf("after approval role = %s, public key %q", user.Role, user.PublicKey)
+	}
+}
+
+func TestOIDCVerifyRejectsBadTokens(t *testing.T) {
+	idp := newFakeIdP(t)
+	provider := NewOIDCProvider(OIDCConfig{Issuer: idp.server.URL, ClientID: "magebox"})
+
+	valid := map[string]any{"iss": idp.server.URL, "aud": "magebox", "sub": "1", "nonce": "n", "exp": time.Now().Add(time.Hour).Unix()}
+	if _, err := provider.Verify(idp.sign(t, valid), "n"); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	tests := map[string]func(map[string]any){
+		"wrong issuer":   func(c map[string]any) { c["iss"] = "https://evil.example.com" },
+		"wrong audience": func(c map[string]any) { c["aud"] = []string{"other"} },
+		"expired":        func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"wrong nonce":    func(c map[string]any) { c["nonce"] = "other" },
+	}
+	for name, mutate := range tests {
+		claims := make(map[string]any)
+		for k, v := range valid {
+			claims[k] = v
+		}
+		mutate(claims)
+		if _, err := provider.Verify(idp.sign(t, claims), "n"); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	// Tampered payload
+	token := idp.sign(t, valid)
+	parts := strings.Split(token, ".")
+	claims := map[string]any{"iss": idp.server.URL, "aud": "magebox", "sub": "admin", "nonce": "n", "exp": time.Now().Add(time.Hour).Unix()}
+	payload, _ := json.Marshal(claims)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	if _, err := provider.Verify(strings.Join(parts, "."), "n"); err == nil {
+		t.Error("tampered token accepted")
+	}
+}
+
+func TestOIDCMapGroups(t *testing.T) {
+	config := OIDCConfig{
+		DefaultRole: RoleReadonly,
+		Groups: map[string]OIDCGroupMapping{
+			"devs":  {Role: RoleDev, Projects: []string{"shop", "blog"}},
+			"leads": {Role: RoleAdmin, Projects: []string{"shop"}},
+		},
+	}
+
+	role, projects, err := config.MapGroups([]string{"devs", "leads", "other"})
+	if err != nil || role != RoleAdmin || len(projects) != 2 {
+		t.Errorf("MapGroups = %s, %v, %v", role, projects, err)
+	}
+
+	role, projects, err = config.MapGroups(nil)
+	if err != nil || role != RoleReadonly || len(projects) != 0 {
+		t.Errorf("MapGroups(nil) = %s, %v, %v", role, projects, err)
+	}
+
+	config.DefaultRole = ""
+	if _, _, err := config.MapGroups([]string{"other"}); err == nil {
+		t.Error("expected an error without a mapped group or default role")
+	}
+}
+
+func TestOIDCUserName(t *testing.T) {
+	tests := []struct {
+		claims OIDCClaims
+		want   string
+	}{
+		{OIDCClaims{PreferredUsername: "jdoe", Email: "john@example.com"}, "jdoe"},
+		{OIDCClaims{PreferredUsername: "john.doe@example.com", Email: "john.doe@example.com"}, "john.doe"},
+		{OIDCClaims{Email: "John O'Neil@example.com"}, "johnoneil"},
+		{OIDCClaims{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := oidcUserName(&tt.claims); got != tt.want {
+			t.Errorf("oidcUserName(%+v) = %q, want %q", tt.claims, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ValidatePrincipals checks that principals are user names, which is what
+// sshd matches them against
+func ValidatePrincipals(principals []string) error {
+	for _, p := range principals {
+		if !validUsernameRegex.MatchString(p) {
+			return fmt.Errorf("invalid principal %q", p)
+		}
+	}
+	return nil
+}
+
+// GetDefaultPrincipals returns the principals of environments without their
+// own, deploy when none are configured
+func (c CAConfig) GetDefaultPrincipals() []string {
+	if len(c.DefaultPrincipals) == 0 {
+		return []string{"deploy"}
+	}
+	return c.DefaultPrincipals
+}
+
+// EnvironmentPrincipals returns the principals certificates get for env
+func (c CAConfig) EnvironmentPrincipals(env *Environment) []string {
+	if len(env.Principals) > 0 {
+		return env.Principals
+	}
+	return c.GetDefaultPrincipals()
+}
+
+// RoleAllows reports whether users of role may get principal
+func (c CAConfig) RoleAllows(role Role, principal string) bool {
+	allowed, limited := c.RolePrincipals[role]
+	return !limited || slices.Contains(allowed, principal)
+}
+
+// PrincipalsFor returns the principals of a certificate for a user of role
+// with access to envs: the principals of each environment that the role
+// allows. A user with no environments gets the allowed default principals.
+func (c CAConfig) PrincipalsFor(role Role, envs []Environment) []string {
+	var candidates []string
+	for i := range envs {
+		candidates = append(candidates, c.EnvironmentPrincipals(&envs[i])...)
+	}
+	if len(envs) == 0 {
+		candidates = c.GetDefaultPrincipals()
+	}
+
+	var principals []string
+	for _, p := range candidates {
+		if c.RoleAllows(role, p) && !slices.Contains(principals, p) {
+			principals = append(principals, p)
+		}
+	}
+	return principals
+}
+
+// Validate checks the principals and roles of the CA config
+func (c CAConfig) Validate() error {
+	if err := ValidatePrincipals(c.DefaultPrincipals); err != nil {
+		return fmt.Errorf("default_principals: %w", err)
+	}
+	for role, principals := range c.RolePrincipals {
+		if !role.IsValid() {
+			return fmt.Errorf("role_principals: invalid role %q", role)
+		}
+		if err := ValidatePrincipals(principals); err != nil {
+			return fmt.Errorf("role_principals of %s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// certPrincipals returns the principals of a certificate for user, from the
+// environments of the projects the user has access to
+func (s *Server) certPrincipals(user *User) ([]string, error) {
+	envs, err := s.storage.ListEnvironmentsForUser(user.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.config.CA.PrincipalsFor(user.Role, envs), nil
+}
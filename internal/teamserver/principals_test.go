@@ -0,0 +1,138 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCAConfig_PrincipalsFor(t *testing.T) {
+	ca := CAConfig{
+		DefaultPrincipals: []string{"deploy"},
+		RolePrincipals: map[Role][]string{
+			RoleReadonly: {"www-data"},
+			RoleDev:      {"deploy", "www-data"},
+		},
+	}
+	staging := Environment{Name: "staging"}
+	production := Environment{Name: "production", Principals: []string{"deploy-prod", "www-data"}}
+
+	tests := []struct {
+		name string
+		role Role
+		envs []Environment
+		want []string
+	}{
+		{"admin gets all", RoleAdmin, []Environment{staging, production}, []string{"deploy", "deploy-prod", "www-data"}},
+		{"dev is limited", RoleDev, []Environment{staging, production}, []string{"deploy", "www-data"}},
+		{"readonly only gets www-data", RoleReadonly, []Environment{staging, production}, []string{"www-data"}},
+		{"readonly on staging gets none", RoleReadonly, []Environment{staging}, nil},
+		{"no environments uses the defaults", RoleAdmin, nil, []string{"deploy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ca.PrincipalsFor(tt.role, tt.envs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrincipalsFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCAConfig_GetDefaultPrincipals(t *testing.T) {
+	if got := (CAConfig{}).GetDefaultPrincipals(); !reflect.DeepEqual(got, []string{"deploy"}) {
+		t.Errorf("GetDefaultPrincipals() = %v, want [deploy]", got)
+	}
+}
+
+func TestCAConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ca      CAConfig
+		wantErr bool
+	}{
+		{"valid", CAConfig{DefaultPrincipals: []string{"deploy"}, RolePrincipals: map[Role][]string{RoleReadonly: {"www-data"}}}, false},
+		{"invalid role", CAConfig{RolePrincipals: map[Role][]string{"root": {"root"}}}, true},
+		{"invalid principal", CAConfig{RolePrincipals: map[Role][]string{RoleDev: {"deploy prod"}}}, true},
+		{"invalid default", CAConfig{DefaultPrincipals: []string{"a,b"}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.ca.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEnvironmentPrincipalsEndpoint(t *testing.T) {
+	server, adminToken, cleanup := setupTestServerWithAdmin(t)
+	defer cleanup()
+
+	if err := server.storage.CreateProject(&Project{Name: "shop"}); err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/admin/environments", `{"name": "production", "project": "shop", "host": "prod.example.com",
+		"deploy_user": "deploy", "deploy_key": "key", "principals": ["deploy prod"]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("create with an invalid principal returned %d, want 400", w.Code)
+	}
+
+	w = request(http.MethodPost, "/api/admin/environments", `{"name": "production", "project": "shop", "host": "prod.example.com",
+		"deploy_user": "deploy", "deploy_key": "key", "principals": ["deploy-prod"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", w.Code, w.Body.String())
+	}
+
+	w = request(http.MethodPut, "/api/admin/environments/shop/production", `{"principals": ["deploy-prod", "www-data"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update returned %d: %s", w.Code, w.Body.String())
+	}
+	var env Environment
+	if err := json.NewDecoder(w.Body).Decode(&env); err != nil {
+		t.Fatalf("failed to decode environment: %v", err)
+	}
+	if !reflect.DeepEqual(env.Principals, []string{"deploy-prod", "www-data"}) {
+		t.Errorf("Principals = %v, want [deploy-prod www-data]", env.Principals)
+	}
+
+	if w := request(http.MethodPut, "/api/admin/environments/shop/staging", `{"principals": []}`); w.Code != http.StatusNotFound {
+		t.Errorf("update of an unknown environment returned %d, want 404", w.Code)
+	}
+
+	// Certificates of a readonly user only get the principals the role allows
+	server.config.CA.RolePrincipals = map[Role][]string{RoleReadonly: {"www-data"}}
+	user := &User{Name: "viewer", Role: RoleReadonly}
+	if err := server.storage.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := server.storage.GrantProjectAccess("viewer", "shop", "admin"); err != nil {
+		t.Fatalf("GrantProjectAccess() error = %v", err)
+	}
+	principals, err := server.certPrincipals(user)
+	if err != nil {
+		t.Fatalf("certPrincipals() error = %v", err)
+	}
+	if !reflect.DeepEqual(principals, []string{"www-data"}) {
+		t.Errorf("certPrincipals() = %v, want [www-data]", principals)
+	}
+}
@@ -0,0 +1,85 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// minSyncInterval keeps the reconciliation from hammering the environments
+const minSyncInterval = time.Minute
+
+// syncInterval returns how often keys are reconciled, zero when disabled
+func (s *Server) syncInterval() time.Duration {
+	value := s.config.Sync.Interval
+	if value == "" || value == "0" {
+		return 0
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		s.logger.Printf("Warning: invalid sync interval %q, key reconciliation is disabled", value)
+		return 0
+	}
+	if interval < minSyncInterval {
+		return minSyncInterval
+	}
+	return interval
+}
+
+// runReconciliation syncs the keys of all environments every interval until
+// ctx is done
+func (s *Server) runReconciliation(ctx context.Context, interval time.Duration) {
+	s.logger.Printf("Reconciling keys every %s", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reconcileKeys()
+		}
+	}
+}
+
+// reconcileKeys syncs the keys of all environments, so keys that were added
+// or removed on a server by hand, or changes whose deployment failed, don't
+// stay in place until the next manual sync. Drift and failures are audited.
+func (s *Server) reconcileKeys() []SyncEnvResult {
+	results, err := s.syncKeys("", SyncTriggerScheduled)
+	if err != nil {
+		s.logger.Printf("Key reconciliation failed: %v", err)
+		return nil
+	}
+
+	var drifted, failed []string
+	for _, r := range results {
+		switch {
+		case !r.Success:
+			failed = append(failed, r.Environment)
+		case r.KeysAdded > 0 || r.KeysRemoved > 0:
+			drifted = append(drifted, fmt.Sprintf("%s (+%d, -%d)", r.Environment, r.KeysAdded, r.KeysRemoved))
+		}
+	}
+
+	if len(drifted) > 0 {
+		s.logAudit(AuditKeySync, "", "Scheduled sync corrected drift on "+strings.Join(drifted, ", "), "")
+	}
+	if len(failed) > 0 {
+		s.logAudit(AuditKeySync, "", "Scheduled sync failed on "+strings.Join(failed, ", "), "")
+	}
+	s.logger.Printf("Reconciled keys of %d environments: %d drifted, %d failed", len(results), len(drifted), len(failed))
+
+	return results
+}
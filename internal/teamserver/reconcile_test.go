@@ -0,0 +1,137 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncInterval(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"invalid", 0},
+		{"-1h", 0},
+		{"10s", minSyncInterval},
+		{"30m", 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		server.config.Sync.Interval = tt.value
+		if got := server.syncInterval(); got != tt.want {
+			t.Errorf("syncInterval(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReconcileKeysRecordsHistory(t *testing.T) {
+	server, adminToken, cleanup := setupTestServerWithAdmin(t)
+	defer cleanup()
+	server.deployer.timeout = time.Second
+
+	deployKey, err := GenerateSSHKeyPair("deploy")
+	if err != nil {
+		t.Fatalf("Failed to generate deploy key: %v", err)
+	}
+	if err := server.storage.CreateProject(&Project{Name: "shop"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	// Nothing listens on port 1, so the sync fails
+	env := &Environment{Name: "staging", Project: "shop", Host: "127.0.0.1", Port: 1, DeployUser: "deploy", DeployKey: deployKey.PrivateKey}
+	if err := server.storage.CreateEnvironment(env); err != nil {
+		t.Fatalf("Failed to create environment: %v", err)
+	}
+
+	results := server.reconcileKeys()
+	if len(results) != 1 || results[0].Success {
+		t.Fatalf("Expected one failed result, got %+v", results)
+	}
+	// The deploy key was loaded, so the failure is the connection
+	if !strings.Contains(results[0].Error, "failed to connect") {
+		t.Errorf("Error = %q, want a connection error", results[0].Error)
+	}
+
+	entries, _ := server.storage.ListAuditEntries(nil, nil, "", AuditKeySync, 10)
+	if len(entries) != 1 || !strings.Contains(entries[0].Details, "failed on shop/staging") {
+		t.Errorf("Expected an audit entry for the failed sync, got %+v", entries)
+	}
+
+	// A successful sync without changes isn't drift
+	if err := server.storage.CreateSyncHistoryEntry(&SyncHistoryEntry{Trigger: SyncTriggerManual, Environment: "shop/production", Success: true}); err != nil {
+		t.Fatalf("CreateSyncHistoryEntry() error = %v", err)
+	}
+
+	request := func(query string) []SyncHistoryEntry {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/sync/history"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET sync history%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var history []SyncHistoryEntry
+		if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return history
+	}
+
+	if history := request(""); len(history) != 2 {
+		t.Errorf("Expected 2 history entries, got %d", len(history))
+	}
+	history := request("?drift=true")
+	if len(history) != 1 || history[0].Environment != "shop/staging" || history[0].Trigger != SyncTriggerScheduled {
+		t.Errorf("Expected only the failed scheduled sync, got %+v", history)
+	}
+	if history := request("?environment=shop/production"); len(history) != 1 || !history[0].Success {
+		t.Errorf("Expected the production sync, got %+v", history)
+	}
+}
+
+func TestPruneSyncHistory(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	old := &SyncHistoryEntry{Trigger: SyncTriggerScheduled, Environment: "shop/staging", Timestamp: time.Now().AddDate(0, 0, -100)}
+	recent := &SyncHistoryEntry{Trigger: SyncTriggerScheduled, Environment: "shop/staging", Timestamp: time.Now().AddDate(0, 0, -1)}
+	for _, entry := range []*SyncHistoryEntry{old, recent} {
+		if err := server.storage.CreateSyncHistoryEntry(entry); err != nil {
+			t.Fatalf("CreateSyncHistoryEntry() error = %v", err)
+		}
+	}
+
+	// Zero keeps the history
+	server.config.Sync.HistoryDays = 0
+	server.pruneSyncHistory()
+	if history, _ := server.storage.ListSyncHistory("", false, 0); len(history) != 2 {
+		t.Fatalf("Expected 2 history entries without retention, got %d", len(history))
+	}
+
+	server.config.Sync.HistoryDays = 90
+	server.pruneSyncHistory()
+	history, err := server.storage.ListSyncHistory("", false, 0)
+	if err != nil {
+		t.Fatalf("ListSyncHistory() error = %v", err)
+	}
+	if len(history) != 1 || history[0].ID != recent.ID {
+		t.Errorf("Expected only the recent entry to be kept, got %+v", history)
+	}
+}
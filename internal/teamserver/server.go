@@ -18,12 +18,17 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// maxProjectConfigSize is the largest .magebox.yaml the server stores
+const maxProjectConfigSize = 256 * 1024
+
 // Server represents the team server
 type Server struct {
 	config       *ServerConfig
@@ -40,6 +45,14 @@ type Server struct {
 	masterKey    []byte
 	serverURL    string
 	caPrivateKey ed25519.PrivateKey // CA private key for signing certificates
+
+	keysMu         sync.Mutex         // Serializes changes to the authorized_keys of environments
+	stopBackground context.CancelFunc // Stops the background jobs started by Start
+
+	oidc       *OIDCProvider // Set when single sign-on is enabled
+	oidcLogins oidcLoginStore
+
+	health healthStore // Latest health check of each environment
 }
 
 // RateLimiter implements a simple token bucket rate limiter
@@ -171,11 +184,14 @@ func NewServer(config *ServerConfig, masterKey []byte) (*Server, error) {
 		return nil, fmt.Errorf("failed to create crypto: %w", err)
 	}
 
-	dbPath := filepath.Join(config.DataDir, "teamserver.db")
-	storage, err := NewStorage(dbPath, crypto)
+	storage, err := OpenStorage(config.Database.Resolve(config.DataDir), crypto)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage: %w", err)
 	}
+	if _, err := storage.Migrate(); err != nil {
+		storage.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
 
 	// Build server URL for notifications
 	protocol := "http"
@@ -199,6 +215,7 @@ func NewServer(config *ServerConfig, masterKey []byte) (*Server, error) {
 		serverURL: serverURL,
 		logger:    log.New(os.Stdout, "[teamserver] ", log.LstdFlags),
 	}
+	s.notifier.SetWebhooks(config.Notifications.Webhooks)
 
 	// Load CA private key if CA is enabled
 	if config.CA.Enabled {
@@ -227,6 +244,10 @@ func NewServer(config *ServerConfig, masterKey []byte) (*Server, error) {
 	}
 	s.loginTracker = NewLoginAttemptTracker(maxAttempts)
 
+	if config.OIDC.Enabled {
+		s.oidc = NewOIDCProvider(config.OIDC)
+	}
+
 	s.setupRoutes()
 
 	return s, nil
@@ -239,14 +260,22 @@ func (s *Server) setupRoutes() {
 
 	// Public endpoints
 	s.mux.HandleFunc("/api/join", s.withMiddleware(s.handleJoin, false))
+	s.mux.HandleFunc("/api/token/rotate", s.withMiddleware(s.handleTokenRotate, false))
+	if s.oidc != nil {
+		s.mux.HandleFunc("/api/oidc/login", s.withMiddleware(s.handleOIDCLogin, false))
+		s.mux.HandleFunc("/api/oidc/callback", s.withMiddleware(s.handleOIDCCallback, false))
+		s.mux.HandleFunc("/api/oidc/poll", s.withMiddleware(s.handleOIDCPoll, false))
+	}
 
 	// User endpoints (require authentication)
 	s.mux.HandleFunc("/api/me", s.withMiddleware(s.handleMe, true))
 	s.mux.HandleFunc("/api/environments", s.withMiddleware(s.handleUserEnvironments, true))
+	s.mux.HandleFunc("/api/projects/", s.withMiddleware(s.handleUserProjectConfig, true))
 	s.mux.HandleFunc("/api/mfa/setup", s.withMiddleware(s.handleMFASetup, true))
 	s.mux.HandleFunc("/api/mfa/verify", s.withMiddleware(s.handleMFAVerify, true))
 	s.mux.HandleFunc("/api/cert/renew", s.withMiddleware(s.handleCertRenew, true))
 	s.mux.HandleFunc("/api/cert/info", s.withMiddleware(s.handleCertInfo, true))
+	s.mux.HandleFunc("/api/deployments", s.withMiddleware(s.handleDeployment, true))
 
 	// Admin endpoints (require admin authentication)
 	s.mux.HandleFunc("/api/admin/users", s.withMiddleware(s.handleAdminUsers, true))
@@ -257,7 +286,14 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/api/admin/environments/", s.withMiddleware(s.handleAdminEnvironment, true))
 	s.mux.HandleFunc("/api/admin/audit", s.withMiddleware(s.handleAdminAudit, true))
 	s.mux.HandleFunc("/api/admin/sync", s.withMiddleware(s.handleAdminSync, true))
+	s.mux.HandleFunc("/api/admin/sync/history", s.withMiddleware(s.handleAdminSyncHistory, true))
 	s.mux.HandleFunc("/api/admin/ca", s.withMiddleware(s.handleAdminCA, true))
+
+	// Web admin UI, which authenticates against the API above
+	if !s.config.UI.Disabled {
+		s.mux.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+		s.mux.Handle("/ui/", s.uiHandler())
+	}
 }
 
 // withMiddleware wraps a handler with common middleware
@@ -303,42 +339,16 @@ func (s *Server) withMiddleware(handler http.HandlerFunc, requireAuth bool) http
 		}
 
 		// IP allowlist check
-		if len(s.config.Security.AllowedIPs) > 0 {
-			allowed := false
-			for _, allowedIP := range s.config.Security.AllowedIPs {
-				if ip == allowedIP || matchCIDR(ip, allowedIP) {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				s.writeError(w, http.StatusForbidden, "IP_NOT_ALLOWED", "IP address not in allowlist")
-				return
-			}
+		if !s.ipAllowed(ip) {
+			s.writeError(w, http.StatusForbidden, "IP_NOT_ALLOWED", "IP address not in allowlist")
+			return
 		}
 
 		// Authentication
 		if requireAuth {
 			user, err := s.authenticateRequest(r)
 			if err != nil {
-				// Record failed login attempt
-				if s.loginTracker != nil {
-					locked := s.loginTracker.RecordFailure(ip)
-					failCount := s.loginTracker.GetFailureCount(ip)
-					s.logAudit(AuditAuthFailed, "", fmt.Sprintf("Authentication failed (attempt %d)", failCount), ip)
-
-					// Alert on threshold breaches
-					if failCount == 3 {
-						s.logger.Printf("WARNING: Multiple failed login attempts from %s (3 failures)", ip)
-					}
-					if locked {
-						s.logger.Printf("ALERT: IP %s locked out due to %d failed login attempts", ip, failCount)
-						s.logAudit(AuditAuthFailed, "", fmt.Sprintf("IP locked out after %d failed attempts", failCount), ip)
-
-						// Send security alert to admins (async)
-						go s.sendSecurityAlertToAdmins("IP Lockout", ip, fmt.Sprintf("IP address %s has been locked out after %d failed login attempts", ip, failCount))
-					}
-				}
+				s.recordAuthFailure(ip)
 				s.writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", err.Error())
 				return
 			}
@@ -359,6 +369,19 @@ func (s *Server) withMiddleware(handler http.HandlerFunc, requireAuth bool) http
 	}
 }
 
+// ipAllowed checks an IP against the allowlist; without one every IP is allowed
+func (s *Server) ipAllowed(ip string) bool {
+	if len(s.config.Security.AllowedIPs) == 0 {
+		return true
+	}
+	for _, allowedIP := range s.config.Security.AllowedIPs {
+		if ip == allowedIP || matchCIDR(ip, allowedIP) {
+			return true
+		}
+	}
+	return false
+}
+
 type contextKey string
 
 const contextKeyUser contextKey = "user"
@@ -404,27 +427,55 @@ func (s *Server) authenticateRequest(r *http.Request) (*User, error) {
 		}, nil
 	}
 
-	// Find user by verifying their token hash
-	users, err := s.storage.ListUsers()
+	// Find the session by verifying the token hashes
+	session, err := s.findSession(token)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsExpired() {
+		return nil, fmt.Errorf("session has expired, rotate it with the refresh token or join again")
+	}
+
+	u, err := s.storage.GetUser(session.UserName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to lookup user")
 	}
 
-	for _, u := range users {
-		if u.TokenHash != "" && VerifyToken(token, u.TokenHash) {
-			// Check expiration
-			if u.IsExpired() {
-				return nil, fmt.Errorf("user access has expired")
-			}
+	// Check expiration
+	if u.IsExpired() {
+		return nil, fmt.Errorf("user access has expired")
+	}
 
-			// Update last access time
-			_ = s.storage.UpdateUserLastAccess(u.Name)
+	s.stampSessionExpiry(session)
 
-			return &u, nil
-		}
+	// Update last access time
+	_ = s.storage.UpdateUserLastAccess(u.Name)
+
+	return u, nil
+}
+
+// recordAuthFailure records a failed authentication from ip, alerting the
+// admins when it locks the IP out
+func (s *Server) recordAuthFailure(ip string) {
+	if s.loginTracker == nil {
+		return
+	}
+
+	locked := s.loginTracker.RecordFailure(ip)
+	failCount := s.loginTracker.GetFailureCount(ip)
+	s.logAudit(AuditAuthFailed, "", fmt.Sprintf("Authentication failed (attempt %d)", failCount), ip)
+
+	// Alert on threshold breaches
+	if failCount == 3 {
+		s.logger.Printf("WARNING: Multiple failed login attempts from %s (3 failures)", ip)
 	}
+	if locked {
+		s.logger.Printf("ALERT: IP %s locked out due to %d failed login attempts", ip, failCount)
+		s.logAudit(AuditAuthFailed, "", fmt.Sprintf("IP locked out after %d failed attempts", failCount), ip)
 
-	return nil, fmt.Errorf("invalid token")
+		// Send security alert to admins (async)
+		go s.sendSecurityAlertToAdmins("IP Lockout", ip, fmt.Sprintf("IP address %s has been locked out after %d failed login attempts", ip, failCount))
+	}
 }
 
 // getCurrentUser extracts the authenticated user from context
@@ -479,19 +530,6 @@ func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the user
-	sessionToken, err := GenerateSessionToken()
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, "TOKEN_ERROR", "Failed to generate session token")
-		return
-	}
-
-	tokenHash, err := HashToken(sessionToken)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, "HASH_ERROR", "Failed to hash token")
-		return
-	}
-
 	// Calculate expiry
 	var expiresAt *time.Time
 	if s.config.Security.DefaultAccessDays > 0 {
@@ -504,7 +542,6 @@ func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 		Email:     invites.Email,
 		Role:      invites.Role,
 		PublicKey: keyPair.PublicKey,
-		TokenHash: tokenHash,
 		ExpiresAt: expiresAt,
 		CreatedBy: "invite",
 	}
@@ -514,25 +551,22 @@ func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Start the user's session
+	tokens, err := s.issueSession(user.Name)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "TOKEN_ERROR", "Failed to start session")
+		return
+	}
+
 	// Mark invite as used
 	_ = s.storage.MarkInviteUsed(invites.ID)
 
 	// Get accessible environments (based on user's projects)
 	envs, _ := s.storage.ListEnvironmentsForUser(user.Name)
 
-	// Convert to user-friendly format
-	envsForUser := make([]EnvironmentForUser, len(envs))
-	for i, e := range envs {
-		envsForUser[i] = EnvironmentForUser{
-			Name:       e.FullName(),
-			Project:    e.Project,
-			Host:       e.Host,
-			Port:       e.GetPort(),
-			DeployUser: e.DeployUser,
-		}
-	}
-
 	s.logAudit(AuditUserJoin, user.Name, fmt.Sprintf("User joined: %s (SSH key generated)", user.Email), s.getClientIP(r))
+	s.notifyWebhooks(NotifyUserJoined, user.Name, "User joined",
+		fmt.Sprintf("%s (%s) joined the team as %s", user.Name, user.Email, user.Role))
 
 	// Send welcome email (async, non-blocking)
 	go func() {
@@ -548,6 +582,27 @@ func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 	// Deploy key to accessible environments (async, non-blocking)
 	go s.deployUserKey(user)
 
+	_ = json.NewEncoder(w).Encode(s.joinResponse(r, user, keyPair, tokens))
+}
+
+// joinResponse builds what a user receives on joining: the session tokens,
+// the private key and certificate, and the environments the user can access
+func (s *Server) joinResponse(r *http.Request, user *User, keyPair *SSHKeyPair, tokens *TokenRotateResponse) JoinResponse {
+	// Get accessible environments (based on user's projects)
+	envs, _ := s.storage.ListEnvironmentsForUser(user.Name)
+
+	// Convert to user-friendly format
+	envsForUser := make([]EnvironmentForUser, len(envs))
+	for i, e := range envs {
+		envsForUser[i] = EnvironmentForUser{
+			Name:       e.FullName(),
+			Project:    e.Project,
+			Host:       e.Host,
+			Port:       e.GetPort(),
+			DeployUser: e.DeployUser,
+		}
+	}
+
 	// Get server host for key storage naming
 	serverHost := r.Host
 	if serverHost == "" {
@@ -556,23 +611,30 @@ func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 
 	// Prepare response
 	response := JoinResponse{
-		SessionToken: sessionToken,
+		SessionToken: tokens.SessionToken,
 		PrivateKey:   keyPair.PrivateKey,
 		User:         user,
 		Environments: envsForUser,
 		ServerHost:   serverHost,
 		CAEnabled:    s.config.CA.Enabled && s.caPrivateKey != nil,
+
+		SessionExpiresAt: tokens.SessionExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
 	}
 
 	// Sign certificate if CA is enabled
 	if s.config.CA.Enabled && s.caPrivateKey != nil {
 		certValidity := s.getCertValiditySeconds()
-		principals := s.config.CA.DefaultPrincipals
-		if len(principals) == 0 {
-			principals = []string{"deploy"}
+		principals, err := s.certPrincipals(user)
+		if err == nil && len(principals) == 0 {
+			err = fmt.Errorf("the role %s is allowed none of the principals of the environments", user.Role)
 		}
 
-		cert, err := SignSSHCertificate(s.caPrivateKey, keyPair.PublicKey, user.Email, principals, certValidity)
+		var cert *SSHCertificate
+		if err == nil {
+			cert, err = SignSSHCertificate(s.caPrivateKey, keyPair.PublicKey, user.Email, principals, certValidity)
+		}
 		if err != nil {
 			s.logger.Printf("Warning: Failed to sign certificate for %s: %v", user.Name, err)
 		} else {
@@ -588,7 +650,7 @@ func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 		response.CAPublicKey = caPublicKey
 	}
 
-	_ = json.NewEncoder(w).Encode(response)
+	return response
 }
 
 // deployUserKey deploys a user's public key to all accessible environments
@@ -619,6 +681,8 @@ func (s *Server) deployUserKey(user *User) {
 		if err := s.deployer.AddKey(env, string(deployKey), userKey); err != nil {
 			s.logger.Printf("Failed to deploy key for %s to %s/%s: %v", user.Name, env.Project, env.Name, err)
 			s.logAudit(AuditKeyDeployed, user.Name, fmt.Sprintf("Failed to deploy key to %s/%s: %v", env.Project, env.Name, err), "")
+			s.notifyWebhooks(NotifyKeySyncFailed, user.Name, "Key deployment failed",
+				fmt.Sprintf("Deploying the key of %s to %s/%s failed: %v", user.Name, env.Project, env.Name, err))
 		} else {
 			s.logger.Printf("Deployed key for %s to %s/%s", user.Name, env.Project, env.Name)
 			s.logAudit(AuditKeyDeployed, user.Name, fmt.Sprintf("Deployed key to %s/%s", env.Project, env.Name), "")
@@ -637,7 +701,7 @@ func (s *Server) findValidInvite(token string) (*Invite, error) {
 	}
 
 	// Check all invites (in production, you might want to index by partial hash)
-	rows, err := s.storage.db.Query(`
+	rows, err := s.storage.query(`
 		SELECT id, token_hash, user_name, email, role, projects, expires_at, used_at, created_at
 		FROM invites WHERE used_at IS NULL`)
 	if err != nil {
@@ -999,6 +1063,8 @@ func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
 
 	admin := getCurrentUser(r)
 	s.logAudit(AuditUserCreate, admin.Name, fmt.Sprintf("Created invite for: %s (%s)", req.Name, req.Email), s.getClientIP(r))
+	s.notifyWebhooks(NotifyUserInvited, req.Name, "User invited",
+		fmt.Sprintf("%s invited %s (%s) as %s", admin.Name, req.Name, req.Email, req.Role))
 
 	// Send invitation email (async, non-blocking)
 	go func() {
@@ -1044,6 +1110,14 @@ func (s *Server) handleAdminUserOrAccess(w http.ResponseWriter, r *http.Request)
 		s.handleUserAccess(w, r, userName)
 		return
 	}
+	if strings.HasSuffix(path, "/sessions") {
+		s.revokeUserSessions(w, r, strings.TrimSuffix(path, "/sessions"))
+		return
+	}
+	if strings.HasSuffix(path, "/sso") {
+		s.approveOIDCLink(w, r, strings.TrimSuffix(path, "/sso"))
+		return
+	}
 
 	// Regular user operation
 	s.handleAdminUser(w, r, path)
@@ -1108,6 +1182,8 @@ func (s *Server) grantUserAccess(w http.ResponseWriter, r *http.Request, userNam
 	}
 
 	s.logAudit(AuditAdminAction, admin.Name, fmt.Sprintf("Granted project access: %s -> %s", userName, req.Project), s.getClientIP(r))
+	s.notifyWebhooks(NotifyAccessGranted, userName, "Access granted",
+		fmt.Sprintf("%s granted %s access to project %s", admin.Name, userName, req.Project))
 
 	// Get updated user with projects
 	user, _ := s.storage.GetUser(userName)
@@ -1142,13 +1218,16 @@ func (s *Server) revokeUserAccess(w http.ResponseWriter, r *http.Request, userNa
 	}
 
 	s.logAudit(AuditAdminAction, admin.Name, fmt.Sprintf("Revoked project access: %s -> %s", userName, req.Project), s.getClientIP(r))
+	s.notifyWebhooks(NotifyAccessRevoked, userName, "Access revoked",
+		fmt.Sprintf("%s revoked the access of %s to project %s", admin.Name, userName, req.Project))
 
 	_ = json.NewEncoder(w).Encode(SuccessResponse{
 		Success: true,
 		Message: fmt.Sprintf("Revoked %s access to project %s", userName, req.Project),
 	})
 
-	// TODO: Trigger key removal from the project's environments (async)
+	// Remove the user's key from the project's environments (async)
+	go s.removeUserProjectKeys(userName, req.Project)
 }
 
 func (s *Server) getUser(w http.ResponseWriter, r *http.Request, name string) {
@@ -1220,6 +1299,8 @@ func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request, name string)
 
 	admin := getCurrentUser(r)
 	s.logAudit(AuditUserRemove, admin.Name, fmt.Sprintf("Removed user: %s", name), s.getClientIP(r))
+	s.notifyWebhooks(NotifyUserRemoved, name, "User removed",
+		fmt.Sprintf("%s removed %s, their keys are removed from all environments", admin.Name, name))
 
 	// Send access revoked email (async, non-blocking)
 	go func() {
@@ -1343,6 +1424,12 @@ func (s *Server) handleAdminProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check if this is a config operation (path ends with /config)
+	if strings.HasSuffix(name, "/config") {
+		s.handleAdminProjectConfig(w, r, strings.TrimSuffix(name, "/config"))
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.getProject(w, r, name)
@@ -1378,6 +1465,114 @@ func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request, name stri
 	})
 }
 
+// handleAdminProjectConfig reads or replaces the canonical .magebox.yaml of a project
+func (s *Server) handleAdminProjectConfig(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getProjectConfig(w, name)
+	case http.MethodPut:
+		s.setProjectConfig(w, r, name)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET and PUT are allowed")
+	}
+}
+
+func (s *Server) getProjectConfig(w http.ResponseWriter, name string) {
+	config, err := s.storage.GetProjectConfig(name)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "No config stored for this project")
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(config)
+}
+
+func (s *Server) setProjectConfig(w http.ResponseWriter, r *http.Request, name string) {
+	if _, err := s.storage.GetProject(name); err != nil {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "Project not found")
+		return
+	}
+
+	var req SetProjectConfigRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProjectConfigSize+4096)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		return
+	}
+
+	if err := validateProjectConfig(req.Content); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_CONFIG", err.Error())
+		return
+	}
+
+	admin := getCurrentUser(r)
+	config := &ProjectConfig{
+		Project:   name,
+		Content:   req.Content,
+		UpdatedBy: admin.Name,
+	}
+
+	if err := s.storage.SetProjectConfig(config); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "UPDATE_ERROR", "Failed to store project config")
+		return
+	}
+
+	s.logAudit(AuditConfigChange, admin.Name, fmt.Sprintf("Updated config of project: %s", name), s.getClientIP(r))
+
+	_ = json.NewEncoder(w).Encode(config)
+}
+
+// validateProjectConfig checks that content is a YAML mapping the size of a
+// .magebox.yaml. The client validates the config itself after pulling it.
+func validateProjectConfig(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("config is empty")
+	}
+	if len(content) > maxProjectConfigSize {
+		return fmt.Errorf("config is larger than %d KB", maxProjectConfigSize/1024)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return fmt.Errorf("config is not valid YAML: %v", err)
+	}
+	return nil
+}
+
+// handleUserProjectConfig returns the canonical .magebox.yaml of a project
+// the user has access to
+func (s *Server) handleUserProjectConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET is allowed")
+		return
+	}
+
+	user := getCurrentUser(r)
+	if user == nil {
+		s.writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "User not found")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/projects/")
+	name, ok := strings.CutSuffix(path, "/config")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "Unknown endpoint")
+		return
+	}
+
+	if !user.Role.CanManageProjects() {
+		projects, err := s.storage.GetUserProjects(user.Name)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, "LIST_ERROR", "Failed to check project access")
+			return
+		}
+		if !slices.Contains(projects, name) {
+			s.writeError(w, http.StatusForbidden, "FORBIDDEN", "No access to this project")
+			return
+		}
+	}
+
+	s.getProjectConfig(w, name)
+}
+
 // handleAdminEnvironments handles environment listing and creation
 func (s *Server) handleAdminEnvironments(w http.ResponseWriter, r *http.Request) {
 	user := getCurrentUser(r)
@@ -1408,6 +1603,9 @@ func (s *Server) listEnvironments(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusInternalServerError, "LIST_ERROR", "Failed to list environments")
 		return
 	}
+	for i := range envs {
+		envs[i].Health = s.health.get(envs[i].FullName())
+	}
 
 	_ = json.NewEncoder(w).Encode(envs)
 }
@@ -1424,6 +1622,16 @@ func (s *Server) createEnvironment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := ValidateSSHTarget(req.Host, req.DeployUser); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_SSH_TARGET", err.Error())
+		return
+	}
+
+	if err := ValidatePrincipals(req.Principals); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_PRINCIPALS", err.Error())
+		return
+	}
+
 	// Verify project exists
 	_, err := s.storage.GetProject(req.Project)
 	if err != nil {
@@ -1443,6 +1651,7 @@ func (s *Server) createEnvironment(w http.ResponseWriter, r *http.Request) {
 		Port:       port,
 		DeployUser: req.DeployUser,
 		DeployKey:  req.DeployKey,
+		Principals: req.Principals,
 	}
 
 	if err := s.storage.CreateEnvironment(env); err != nil {
@@ -1459,7 +1668,7 @@ func (s *Server) createEnvironment(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleAdminEnvironment handles individual environment operations
-// URL format: /api/admin/environments/{project}/{name}
+// URL format: /api/admin/environments/{project}/{name}[/check]
 func (s *Server) handleAdminEnvironment(w http.ResponseWriter, r *http.Request) {
 	user := getCurrentUser(r)
 	if user == nil || !user.Role.CanManageEnvironments() {
@@ -1475,20 +1684,27 @@ func (s *Server) handleAdminEnvironment(w http.ResponseWriter, r *http.Request)
 
 	// Parse project/name from path
 	path := strings.TrimPrefix(r.URL.Path, "/api/admin/environments/")
-	parts := strings.SplitN(path, "/", 2)
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" || (len(parts) == 3 && parts[2] != "check") {
 		s.writeError(w, http.StatusBadRequest, "INVALID_PATH", "Path must be /api/admin/environments/{project}/{name}")
 		return
 	}
 	project, name := parts[0], parts[1]
 
+	if len(parts) == 3 {
+		s.checkEnvironmentNow(w, r, project, name)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.getEnvironment(w, r, project, name)
+	case http.MethodPut:
+		s.updateEnvironment(w, r, project, name)
 	case http.MethodDelete:
 		s.deleteEnvironment(w, r, project, name)
 	default:
-		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET and DELETE are allowed")
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET, PUT and DELETE are allowed")
 	}
 }
 
@@ -1501,15 +1717,46 @@ func (s *Server) getEnvironment(w http.ResponseWriter, r *http.Request, project,
 
 	// Don't expose deploy key
 	env.DeployKey = ""
+	env.Health = s.health.get(env.FullName())
 	_ = json.NewEncoder(w).Encode(env)
 }
 
+func (s *Server) updateEnvironment(w http.ResponseWriter, r *http.Request, project, name string) {
+	var req UpdateEnvironmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		return
+	}
+
+	if req.Principals != nil {
+		if err := ValidatePrincipals(*req.Principals); err != nil {
+			s.writeError(w, http.StatusBadRequest, "INVALID_PRINCIPALS", err.Error())
+			return
+		}
+		if err := s.storage.UpdateEnvironmentPrincipals(project, name, *req.Principals); err != nil {
+			s.writeError(w, http.StatusNotFound, "NOT_FOUND", "Environment not found")
+			return
+		}
+
+		principals := "default principals"
+		if len(*req.Principals) > 0 {
+			principals = strings.Join(*req.Principals, ", ")
+		}
+		admin := getCurrentUser(r)
+		s.logAudit(AuditEnvUpdate, admin.Name, fmt.Sprintf("Set principals of %s/%s: %s", project, name, principals), s.getClientIP(r))
+	}
+
+	s.getEnvironment(w, r, project, name)
+}
+
 func (s *Server) deleteEnvironment(w http.ResponseWriter, r *http.Request, project, name string) {
 	if err := s.storage.DeleteEnvironment(project, name); err != nil {
 		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "Environment not found")
 		return
 	}
 
+	s.health.remove(project + "/" + name)
+
 	admin := getCurrentUser(r)
 	s.logAudit(AuditEnvRemove, admin.Name, fmt.Sprintf("Removed environment: %s/%s", project, name), s.getClientIP(r))
 
@@ -1608,7 +1855,7 @@ func (s *Server) handleAdminSync(w http.ResponseWriter, r *http.Request) {
 	var req SyncRequest
 	_ = json.NewDecoder(r.Body).Decode(&req) // Optional body
 
-	results, err := s.syncKeys(req.Environment)
+	results, err := s.syncKeys(req.Environment, SyncTriggerManual)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, "SYNC_ERROR", err.Error())
 		return
@@ -1631,9 +1878,55 @@ func (s *Server) handleAdminSync(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// syncKeys synchronizes SSH keys to environments
+// handleAdminSyncHistory returns the results of past key syncs
+func (s *Server) handleAdminSyncHistory(w http.ResponseWriter, r *http.Request) {
+	user := getCurrentUser(r)
+	if user == nil || user.Role != RoleAdmin {
+		s.writeError(w, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	// Check MFA requirement for admin operations
+	if err := s.requireAdminMFA(user); err != nil {
+		s.writeError(w, http.StatusForbidden, "MFA_REQUIRED", err.Error())
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET is allowed")
+		return
+	}
+
+	// Parse query parameters
+	environment := r.URL.Query().Get("environment")
+	driftOnly := r.URL.Query().Get("drift") == "true"
+	limit := 100 // Default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit <= 0 {
+			s.writeError(w, http.StatusBadRequest, "INVALID_LIMIT", "limit must be a positive number")
+			return
+		}
+	}
+
+	entries, err := s.storage.ListSyncHistory(environment, driftOnly, limit)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "LIST_ERROR", "Failed to list sync history")
+		return
+	}
+	if entries == nil {
+		entries = []SyncHistoryEntry{}
+	}
+
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+// syncKeys synchronizes SSH keys to environments and records the results in
+// the sync history
 // envPath can be empty (sync all), "project" (sync all in project), or "project/name" (sync specific)
-func (s *Server) syncKeys(envPath string) ([]SyncEnvResult, error) {
+func (s *Server) syncKeys(envPath, trigger string) ([]SyncEnvResult, error) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+
 	var envs []Environment
 	var err error
 
@@ -1679,13 +1972,16 @@ func (s *Server) syncKeys(envPath string) ([]SyncEnvResult, error) {
 			Environment: env.FullName(),
 		}
 
-		// Get deploy key (decrypt it)
-		deployKey, err := s.crypto.Decrypt(env.DeployKey)
+		// Environment lists leave out the deploy key, storage decrypts it
+		withKey, err := s.storage.GetEnvironment(env.Project, env.Name)
 		if err != nil {
-			result.Error = fmt.Sprintf("failed to decrypt deploy key: %v", err)
+			result.Error = fmt.Sprintf("failed to load deploy key: %v", err)
+			s.recordSync(trigger, result)
 			results = append(results, result)
 			continue
 		}
+		env.DeployKey = withKey.DeployKey
+		env.HostKey = withKey.HostKey
 
 		// Build list of authorized keys for this environment
 		var authorizedKeys []UserKey
@@ -1703,7 +1999,7 @@ func (s *Server) syncKeys(envPath string) ([]SyncEnvResult, error) {
 		}
 
 		// Deploy keys
-		deployResult, err := s.deployer.SyncEnvironment(env, string(deployKey), authorizedKeys)
+		deployResult, err := s.deployer.SyncEnvironment(env, env.DeployKey, authorizedKeys)
 		if err != nil {
 			result.Error = err.Error()
 			s.logger.Printf("Failed to sync %s: %v", env.FullName(), err)
@@ -1715,12 +2011,45 @@ func (s *Server) syncKeys(envPath string) ([]SyncEnvResult, error) {
 			s.logger.Printf("Synced %s: %s", env.FullName(), deployResult.Message)
 		}
 
+		s.recordSync(trigger, result)
 		results = append(results, result)
 	}
 
+	s.pruneSyncHistory()
 	return results, nil
 }
 
+// pruneSyncHistory removes sync history older than the configured retention,
+// scheduled syncs would otherwise grow it without bound
+func (s *Server) pruneSyncHistory() {
+	days := s.config.Sync.HistoryDays
+	if days <= 0 {
+		return
+	}
+	if _, err := s.storage.DeleteOldSyncHistory(days); err != nil {
+		s.logger.Printf("Failed to prune sync history: %v", err)
+	}
+}
+
+// recordSync stores the result of syncing an environment in the sync history
+func (s *Server) recordSync(trigger string, result SyncEnvResult) {
+	entry := &SyncHistoryEntry{
+		Trigger:     trigger,
+		Environment: result.Environment,
+		Success:     result.Success,
+		KeysAdded:   result.KeysAdded,
+		KeysRemoved: result.KeysRemoved,
+		Error:       result.Error,
+	}
+	if err := s.storage.CreateSyncHistoryEntry(entry); err != nil {
+		s.logger.Printf("Failed to record sync of %s: %v", result.Environment, err)
+	}
+	if !result.Success {
+		s.notifyWebhooks(NotifyKeySyncFailed, "", "Key sync failed",
+			fmt.Sprintf("Syncing the keys of %s (%s) failed: %s", result.Environment, trigger, result.Error))
+	}
+}
+
 // logAudit creates an audit log entry
 func (s *Server) logAudit(action AuditAction, userName, details, ip string) {
 	entry := &AuditEntry{
@@ -1735,8 +2064,31 @@ func (s *Server) logAudit(action AuditAction, userName, details, ip string) {
 	}
 }
 
-// sendSecurityAlertToAdmins sends a security alert email to all admins
+// notifyWebhooks posts an event to the webhooks in the background
+func (s *Server) notifyWebhooks(event NotificationEvent, userName, title, text string) {
+	if !s.notifier.HasWebhooks() {
+		return
+	}
+
+	msg := WebhookMessage{
+		Event:     event,
+		Title:     title,
+		Text:      text,
+		User:      userName,
+		Server:    s.serverURL,
+		Timestamp: time.Now().UTC(),
+	}
+	go func() {
+		if err := s.notifier.SendWebhooks(msg); err != nil {
+			s.logger.Printf("Failed to send %s webhook: %v", event, err)
+		}
+	}()
+}
+
+// sendSecurityAlertToAdmins sends a security alert to the webhooks and an
+// email to all admins
 func (s *Server) sendSecurityAlertToAdmins(alertType, ip, details string) {
+	s.notifyWebhooks(NotifySecurityAlert, "", "Security alert: "+alertType, details)
 	if !s.notifier.IsEnabled() {
 		return
 	}
@@ -1768,6 +2120,17 @@ func (s *Server) writeError(w http.ResponseWriter, status int, code, message str
 func (s *Server) Start() error {
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 
+	// Background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopBackground = cancel
+	go s.runKeyRemovalRetries(ctx)
+	if interval := s.syncInterval(); interval > 0 {
+		go s.runReconciliation(ctx, interval)
+	}
+	if interval := s.healthInterval(); interval > 0 {
+		go s.runHealthChecks(ctx, interval)
+	}
+
 	s.httpServer = &http.Server{
 		Addr:         addr,
 		Handler:      s.mux,
@@ -1804,6 +2167,10 @@ func (s *Server) Start() error {
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Println("Shutting down server...")
 
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return err
 	}
@@ -1883,9 +2250,15 @@ func (s *Server) handleCertRenew(w http.ResponseWriter, r *http.Request) {
 
 	// Sign new certificate
 	certValidity := s.getCertValiditySeconds()
-	principals := s.config.CA.DefaultPrincipals
+	principals, err := s.certPrincipals(user)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "LIST_ERROR", "Failed to list environments")
+		return
+	}
 	if len(principals) == 0 {
-		principals = []string{"deploy"}
+		s.logAudit(AuditCertDeny, user.Name, "Certificate renewal denied: no allowed principals", s.getClientIP(r))
+		s.writeError(w, http.StatusForbidden, "NO_PRINCIPALS", "Your role is allowed none of the principals of your environments. Cannot renew certificate.")
+		return
 	}
 
 	cert, err := SignSSHCertificate(s.caPrivateKey, user.PublicKey, user.Email, principals, certValidity)
@@ -1929,12 +2302,17 @@ func (s *Server) handleCertInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return CA info and whether user can get a certificate
-	canGetCert := user.PublicKey != "" && len(user.Projects) > 0 && !user.IsExpired()
+	principals, err := s.certPrincipals(user)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "LIST_ERROR", "Failed to list environments")
+		return
+	}
+	canGetCert := user.PublicKey != "" && len(user.Projects) > 0 && !user.IsExpired() && len(principals) > 0
 
 	_ = json.NewEncoder(w).Encode(CertInfoResponse{
 		HasCertificate: canGetCert,
 		IsExpired:      !canGetCert,
-		Principals:     s.config.CA.DefaultPrincipals,
+		Principals:     principals,
 		KeyID:          user.Email,
 	})
 }
@@ -1972,8 +2350,10 @@ func (s *Server) handleAdminCA(w http.ResponseWriter, r *http.Request) {
 		Enabled:      true,
 		PublicKey:    caPublicKey,
 		CertValidity: s.config.CA.CertValidity,
-		Principals:   s.config.CA.DefaultPrincipals,
+		Principals:   s.config.CA.GetDefaultPrincipals(),
 		Fingerprint:  fingerprint,
+
+		RolePrincipals: s.config.CA.RolePrincipals,
 	})
 }
 
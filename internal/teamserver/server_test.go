@@ -1349,3 +1349,114 @@ func TestJoinInviteCanOnlyBeUsedOnce(t *testing.T) {
 		t.Error("Second join with same invite should fail")
 	}
 }
+
+func TestProjectConfig(t *testing.T) {
+	server, adminToken, cleanup := setupTestServerWithAdmin(t)
+	defer cleanup()
+
+	request := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request(http.MethodPost, "/api/admin/projects", `{"name": "shop"}`, adminToken); w.Code != http.StatusOK {
+		t.Fatalf("Failed to create project: %s", w.Body.String())
+	}
+	if w := request(http.MethodPut, "/api/admin/projects/missing/config", `{"content": "php: \"8.3\""}`, adminToken); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown project, got %d", w.Code)
+	}
+	if w := request(http.MethodPut, "/api/admin/projects/shop/config", `{"content": "php: [8.3"}`, adminToken); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid YAML, got %d", w.Code)
+	}
+
+	content := "name: shop\nphp: \"8.3\"\n"
+	body, _ := json.Marshal(SetProjectConfigRequest{Content: content})
+	if w := request(http.MethodPut, "/api/admin/projects/shop/config", string(body), adminToken); w.Code != http.StatusOK {
+		t.Fatalf("Failed to store config: %s", w.Body.String())
+	}
+
+	// A developer without access to the project can't read the config
+	devToken, _ := GenerateToken(32)
+	devHash, _ := HashToken(devToken)
+	if err := server.storage.CreateUser(&User{Name: "dev", Email: "dev@example.com", Role: RoleDev, TokenHash: devHash}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if w := request(http.MethodGet, "/api/projects/shop/config", "", devToken); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without project access, got %d", w.Code)
+	}
+
+	if err := server.storage.GrantProjectAccess("dev", "shop", "admin"); err != nil {
+		t.Fatalf("Failed to grant access: %v", err)
+	}
+	w := request(http.MethodGet, "/api/projects/shop/config", "", devToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to pull config: %s", w.Body.String())
+	}
+
+	var config ProjectConfig
+	json.NewDecoder(w.Body).Decode(&config)
+	if config.Content != content {
+		t.Errorf("Content = %q, want %q", config.Content, content)
+	}
+	if config.UpdatedBy != "admin" {
+		t.Errorf("UpdatedBy = %q, want admin", config.UpdatedBy)
+	}
+}
+
+func TestUIServed(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	server.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/ui/" {
+		t.Errorf("Expected redirect to /ui/, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	for path, contentType := range map[string]string{
+		"/ui/":          "text/html",
+		"/ui/app.js":    "javascript",
+		"/ui/style.css": "text/css",
+	} {
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected status 200, got %d", path, w.Code)
+			continue
+		}
+		if !strings.Contains(w.Header().Get("Content-Type"), contentType) {
+			t.Errorf("GET %s: Content-Type = %q, want %s", path, w.Header().Get("Content-Type"), contentType)
+		}
+		if !strings.Contains(w.Header().Get("Content-Security-Policy"), "script-src 'self'") {
+			t.Errorf("GET %s: missing Content-Security-Policy", path)
+		}
+	}
+}
+
+func TestUIRespectsAllowlistAndConfig(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	server.config.Security.AllowedIPs = []string{"10.0.0.0/8"}
+	req := httptest.NewRequest(http.MethodGet, "/ui/", nil)
+	req.RemoteAddr = "192.168.1.10:1234"
+	w := httptest.NewRecorder()
+	server.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside the allowlist, got %d", w.Code)
+	}
+
+	// A disabled UI isn't routed at all
+	server.config.UI.Disabled = true
+	server.mux = http.NewServeMux()
+	server.setupRoutes()
+	w = httptest.NewRecorder()
+	server.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ui/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 with the UI disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,216 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Lifetimes of session and refresh tokens when the configured ones don't parse
+const (
+	defaultSessionExpiry = 720 * time.Hour
+	defaultRefreshExpiry = 2160 * time.Hour
+)
+
+// tokenLifetime parses the lifetime of session or refresh tokens, zero when
+// they don't expire ("0"). Unset lifetimes are the fallback.
+func (s *Server) tokenLifetime(value string, fallback time.Duration) time.Duration {
+	switch value {
+	case "":
+		return fallback
+	case "0":
+		return 0
+	}
+	lifetime, err := time.ParseDuration(value)
+	if err != nil || lifetime <= 0 {
+		s.logger.Printf("Warning: invalid token lifetime %q, using %s", value, fallback)
+		return fallback
+	}
+	return lifetime
+}
+
+// sessionExpiresAt returns when a session token issued now expires, nil
+// when session tokens don't expire
+func (s *Server) sessionExpiresAt() *time.Time {
+	return expiresAfter(s.tokenLifetime(s.config.Security.SessionExpiry, defaultSessionExpiry))
+}
+
+// refreshExpiresAt returns when a refresh token issued now expires, nil
+// when refresh tokens don't expire
+func (s *Server) refreshExpiresAt() *time.Time {
+	return expiresAfter(s.tokenLifetime(s.config.Security.RefreshExpiry, defaultRefreshExpiry))
+}
+
+func expiresAfter(lifetime time.Duration) *time.Time {
+	if lifetime == 0 {
+		return nil
+	}
+	t := time.Now().Add(lifetime)
+	return &t
+}
+
+// issueSession starts a new session of a user with a new session token and
+// refresh token. The tokens of the previous session stop working.
+func (s *Server) issueSession(userName string) (*TokenRotateResponse, error) {
+	sessionToken, err := GenerateSessionToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token")
+	}
+	refreshToken, err := GenerateSessionToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token")
+	}
+	tokenHash, err := HashToken(sessionToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash token")
+	}
+	refreshHash, err := HashToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash token")
+	}
+
+	session := &UserSession{
+		UserName:         userName,
+		TokenHash:        tokenHash,
+		ExpiresAt:        s.sessionExpiresAt(),
+		RefreshTokenHash: refreshHash,
+		RefreshExpiresAt: s.refreshExpiresAt(),
+	}
+	if err := s.storage.SetUserSession(session); err != nil {
+		return nil, fmt.Errorf("failed to save session")
+	}
+
+	return &TokenRotateResponse{
+		SessionToken:     sessionToken,
+		SessionExpiresAt: session.ExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+	}, nil
+}
+
+// findSession returns the session whose session token is token
+func (s *Server) findSession(token string) (*UserSession, error) {
+	sessions, err := s.storage.ListUserSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup user")
+	}
+	for i := range sessions {
+		if sessions[i].TokenHash != "" && VerifyToken(token, sessions[i].TokenHash) {
+			return &sessions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("invalid token")
+}
+
+// findRefreshSession returns the session whose refresh token is token
+func (s *Server) findRefreshSession(token string) (*UserSession, error) {
+	sessions, err := s.storage.ListUserSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup user")
+	}
+	for i := range sessions {
+		if sessions[i].RefreshTokenHash != "" && VerifyToken(token, sessions[i].RefreshTokenHash) {
+			if sessions[i].RefreshExpired() {
+				return nil, fmt.Errorf("refresh token has expired, join the team server again")
+			}
+			return &sessions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("invalid refresh token")
+}
+
+// stampSessionExpiry gives sessions issued before session expiry was
+// enforced, or while it was disabled, an expiry from now on
+func (s *Server) stampSessionExpiry(session *UserSession) {
+	expiresAt := s.sessionExpiresAt()
+	if session.ExpiresAt != nil || expiresAt == nil {
+		return
+	}
+	session.ExpiresAt = expiresAt
+	if err := s.storage.SetUserSession(session); err != nil {
+		s.logger.Printf("Failed to set the session expiry of %s: %v", session.UserName, err)
+	}
+}
+
+// handleTokenRotate handles POST /api/token/rotate. A refresh token in the
+// body, or a valid session token in the Authorization header, gets a new
+// session; the tokens of the old one stop working.
+func (s *Server) handleTokenRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST is allowed")
+		return
+	}
+
+	var req TokenRotateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		return
+	}
+
+	ip := s.getClientIP(r)
+	var user *User
+	var err error
+	if req.RefreshToken != "" {
+		var session *UserSession
+		if session, err = s.findRefreshSession(req.RefreshToken); err == nil {
+			user, err = s.storage.GetUser(session.UserName)
+			if err == nil && user.IsExpired() {
+				err = fmt.Errorf("user access has expired")
+			}
+		}
+	} else {
+		user, err = s.authenticateRequest(r)
+		if err == nil && user.ID == 0 {
+			s.writeError(w, http.StatusBadRequest, "ADMIN_TOKEN", "The admin token can't be rotated")
+			return
+		}
+	}
+	if err != nil {
+		s.recordAuthFailure(ip)
+		s.writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", err.Error())
+		return
+	}
+
+	tokens, err := s.issueSession(user.Name)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "TOKEN_ERROR", err.Error())
+		return
+	}
+
+	s.logAudit(AuditTokenRotate, user.Name, "Session token rotated", ip)
+	_ = json.NewEncoder(w).Encode(tokens)
+}
+
+// revokeUserSessions handles DELETE /api/admin/users/{name}/sessions. The
+// user's session and refresh tokens stop working at once, the user and
+// their keys stay until removed.
+func (s *Server) revokeUserSessions(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodDelete {
+		s.writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only DELETE is allowed")
+		return
+	}
+
+	if err := s.storage.SetUserSession(&UserSession{UserName: name}); err != nil {
+		s.writeError(w, http.StatusNotFound, "NOT_FOUND", "User not found")
+		return
+	}
+
+	admin := getCurrentUser(r)
+	s.logAudit(AuditSessionRevoke, admin.Name, fmt.Sprintf("Revoked the sessions of %s", name), s.getClientIP(r))
+
+	_ = json.NewEncoder(w).Encode(SuccessResponse{
+		Success: true,
+		Message: fmt.Sprintf("Sessions of %s revoked", name),
+	})
+}
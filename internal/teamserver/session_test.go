@@ -0,0 +1,288 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenLifetime(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultSessionExpiry},
+		{"0", 0},
+		{"invalid", defaultSessionExpiry},
+		{"-1h", defaultSessionExpiry},
+		{"1h", time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := server.tokenLifetime(tt.value, defaultSessionExpiry); got != tt.want {
+			t.Errorf("tokenLifetime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUserSessionExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name           string
+		session        UserSession
+		expired        bool
+		refreshExpired bool
+	}{
+		{"no expiry", UserSession{}, false, false},
+		{"valid", UserSession{ExpiresAt: &future, RefreshExpiresAt: &future}, false, false},
+		{"session expired", UserSession{ExpiresAt: &past, RefreshExpiresAt: &future}, true, false},
+		{"both expired", UserSession{ExpiresAt: &past, RefreshExpiresAt: &past}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.IsExpired(); got != tt.expired {
+				t.Errorf("IsExpired() = %v, want %v", got, tt.expired)
+			}
+			if got := tt.session.RefreshExpired(); got != tt.refreshExpired {
+				t.Errorf("RefreshExpired() = %v, want %v", got, tt.refreshExpired)
+			}
+		})
+	}
+}
+
+func TestSetUserSession(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := storage.CreateUser(&User{Name: "alice", Email: "alice@example.com", Role: RoleDev, TokenHash: "hash1"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := storage.CreateUser(&User{Name: "bob", Email: "bob@example.com", Role: RoleDev}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	sessions, err := storage.ListUserSessions()
+	if err != nil {
+		t.Fatalf("ListUserSessions() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].UserName != "alice" || sessions[0].ExpiresAt != nil {
+		t.Fatalf("sessions = %+v, want the session of alice without expiry", sessions)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	err = storage.SetUserSession(&UserSession{
+		UserName:         "bob",
+		TokenHash:        "hash2",
+		ExpiresAt:        &expiresAt,
+		RefreshTokenHash: "refresh2",
+	})
+	if err != nil {
+		t.Fatalf("SetUserSession() error = %v", err)
+	}
+
+	sessions, _ = storage.ListUserSessions()
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	bob := sessions[1]
+	if bob.TokenHash != "hash2" || bob.RefreshTokenHash != "refresh2" || bob.ExpiresAt == nil || !bob.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("session of bob = %+v", bob)
+	}
+	if bob.RefreshExpiresAt != nil {
+		t.Errorf("RefreshExpiresAt = %v, want nil", bob.RefreshExpiresAt)
+	}
+
+	// An empty session signs alice out
+	if err := storage.SetUserSession(&UserSession{UserName: "alice"}); err != nil {
+		t.Fatalf("SetUserSession() error = %v", err)
+	}
+	sessions, _ = storage.ListUserSessions()
+	if len(sessions) != 1 || sessions[0].UserName != "bob" {
+		t.Errorf("sessions = %+v, want only bob's", sessions)
+	}
+
+	if err := storage.SetUserSession(&UserSession{UserName: "nobody"}); err == nil {
+		t.Error("SetUserSession() of an unknown user should fail")
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	server, adminToken, cleanup := setupTestServerWithAdmin(t)
+	defer cleanup()
+
+	if err := server.storage.CreateUser(&User{Name: "dev", Email: "dev@example.com", Role: RoleDev}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tokens, err := server.issueSession("dev")
+	if err != nil {
+		t.Fatalf("issueSession() error = %v", err)
+	}
+	if tokens.SessionExpiresAt == nil || tokens.RefreshExpiresAt == nil {
+		t.Fatalf("tokens = %+v, want both to expire", tokens)
+	}
+
+	request := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+	rotate := func(token string, body interface{}) *TokenRotateResponse {
+		t.Helper()
+		w := request(http.MethodPost, "/api/token/rotate", token, body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("rotate returned %d: %s", w.Code, w.Body.String())
+		}
+		var rotated TokenRotateResponse
+		if err := json.NewDecoder(w.Body).Decode(&rotated); err != nil {
+			t.Fatalf("failed to decode tokens: %v", err)
+		}
+		return &rotated
+	}
+
+	// Rotating with the session token replaces both tokens
+	rotated := rotate(tokens.SessionToken, nil)
+	if rotated.SessionToken == tokens.SessionToken || rotated.RefreshToken == tokens.RefreshToken {
+		t.Fatal("rotation returned the old tokens")
+	}
+	if w := request(http.MethodGet, "/api/me", tokens.SessionToken, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("old session token returned %d, want 401", w.Code)
+	}
+	if w := request(http.MethodPost, "/api/token/rotate", "", TokenRotateRequest{RefreshToken: tokens.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("old refresh token returned %d, want 401", w.Code)
+	}
+	if w := request(http.MethodGet, "/api/me", rotated.SessionToken, nil); w.Code != http.StatusOK {
+		t.Errorf("new session token returned %d, want 200", w.Code)
+	}
+
+	// An expired session token is refused, its refresh token still rotates it
+	past := time.Now().Add(-time.Minute)
+	sessions, _ := server.storage.ListUserSessions()
+	sessions[0].ExpiresAt = &past
+	if err := server.storage.SetUserSession(&sessions[0]); err != nil {
+		t.Fatalf("SetUserSession() error = %v", err)
+	}
+	if w := request(http.MethodGet, "/api/me", rotated.SessionToken, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired session token returned %d, want 401", w.Code)
+	}
+	refreshed := rotate("", TokenRotateRequest{RefreshToken: rotated.RefreshToken})
+	if w := request(http.MethodGet, "/api/me", refreshed.SessionToken, nil); w.Code != http.StatusOK {
+		t.Errorf("refreshed session token returned %d, want 200", w.Code)
+	}
+
+	// Expired refresh tokens don't rotate
+	sessions, _ = server.storage.ListUserSessions()
+	sessions[0].RefreshExpiresAt = &past
+	_ = server.storage.SetUserSession(&sessions[0])
+	if w := request(http.MethodPost, "/api/token/rotate", "", TokenRotateRequest{RefreshToken: refreshed.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired refresh token returned %d, want 401", w.Code)
+	}
+
+	if w := request(http.MethodPost, "/api/token/rotate", adminToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("rotating the admin token returned %d, want 400", w.Code)
+	}
+	if w := request(http.MethodGet, "/api/token/rotate", refreshed.SessionToken, nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET returned %d, want 405", w.Code)
+	}
+}
+
+func TestSessionExpiryStamped(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Sessions from before expiry was enforced have none
+	token, _ := GenerateSessionToken()
+	tokenHash, _ := HashToken(token)
+	if err := server.storage.CreateUser(&User{Name: "dev", Email: "dev@example.com", Role: RoleDev, TokenHash: tokenHash}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if _, err := server.authenticateRequest(req); err != nil {
+		t.Fatalf("authenticateRequest() error = %v", err)
+	}
+
+	sessions, _ := server.storage.ListUserSessions()
+	if len(sessions) != 1 || sessions[0].ExpiresAt == nil {
+		t.Fatalf("sessions = %+v, want an expiry", sessions)
+	}
+	if until := time.Until(*sessions[0].ExpiresAt); until < 719*time.Hour || until > 720*time.Hour {
+		t.Errorf("session expires in %v, want 720h", until)
+	}
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	server, adminToken, cleanup := setupTestServerWithAdmin(t)
+	defer cleanup()
+
+	if err := server.storage.CreateUser(&User{Name: "dev", Email: "dev@example.com", Role: RoleDev}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	tokens, err := server.issueSession("dev")
+	if err != nil {
+		t.Fatalf("issueSession() error = %v", err)
+	}
+
+	request := func(method, path, token string, body []byte) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(http.MethodDelete, "/api/admin/users/dev/sessions", tokens.SessionToken, nil); code != http.StatusForbidden {
+		t.Errorf("revoke by a developer returned %d, want 403", code)
+	}
+	if code := request(http.MethodDelete, "/api/admin/users/dev/sessions", adminToken, nil); code != http.StatusOK {
+		t.Fatalf("revoke returned %d, want 200", code)
+	}
+
+	if code := request(http.MethodGet, "/api/me", tokens.SessionToken, nil); code != http.StatusUnauthorized {
+		t.Errorf("revoked session token returned %d, want 401", code)
+	}
+	body, _ := json.Marshal(TokenRotateRequest{RefreshToken: tokens.RefreshToken})
+	if code := request(http.MethodPost, "/api/token/rotate", "", body); code != http.StatusUnauthorized {
+		t.Errorf("revoked refresh token returned %d, want 401", code)
+	}
+	if _, err := server.storage.GetUser("dev"); err != nil {
+		t.Errorf("revoking sessions removed the user: %v", err)
+	}
+
+	entries, _ := server.storage.ListAuditEntries(nil, nil, "", AuditSessionRevoke, 10)
+	if len(entries) != 1 {
+		t.Errorf("got %d %s audit entries, want 1", len(entries), AuditSessionRevoke)
+	}
+
+	if code := request(http.MethodDelete, "/api/admin/users/nobody/sessions", adminToken, nil); code != http.StatusNotFound {
+		t.Errorf("revoke of an unknown user returned %d, want 404", code)
+	}
+	if code := request(http.MethodGet, "/api/admin/users/dev/sessions", adminToken, nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET sessions returned %d, want 405", code)
+	}
+}
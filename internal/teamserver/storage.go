@@ -15,37 +15,61 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
-
-	_ "modernc.org/sqlite"
 )
 
 // Storage handles all database operations
 type Storage struct {
-	db     *sql.DB
-	crypto *Crypto
+	db      *sql.DB
+	dialect Dialect
+	crypto  *Crypto
 }
 
-// NewStorage creates a new storage instance
+// NewStorage opens the SQLite database at dbPath and applies the pending
+// migrations
 func NewStorage(dbPath string, crypto *Crypto) (*Storage, error) {
-	// Ensure directory exists
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	s, err := OpenStorage(DatabaseConfig{Driver: DriverSQLite, DSN: dbPath}, crypto)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)")
+	if _, err := s.Migrate(); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return s, nil
+}
+
+// OpenStorage connects to the database of config without migrating it
+func OpenStorage(config DatabaseConfig, crypto *Crypto) (*Storage, error) {
+	dialect, err := GetDialect(config.Driver)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	s := &Storage{db: db, crypto: crypto}
+	if dialect.Name() == DriverSQLite && config.DSN != "" {
+		// Ensure directory exists
+		dir := filepath.Dir(strings.TrimPrefix(config.DSN, "file:"))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
 
-	if err := s.migrate(); err != nil {
+	dsn, err := dialect.DSN(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+		return nil, fmt.Errorf("failed to connect to %s database: %w", dialect.Name(), err)
 	}
 
-	return s, nil
+	return &Storage{db: db, dialect: dialect, crypto: crypto}, nil
 }
 
 // Close closes the database connection
@@ -53,113 +77,47 @@ func (s *Storage) Close() error {
 	return s.db.Close()
 }
 
-// migrate creates or updates database schema
-func (s *Storage) migrate() error {
-	schema := `
-	-- Projects table
-	CREATE TABLE IF NOT EXISTS projects (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT UNIQUE NOT NULL,
-		description TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		created_by TEXT
-	);
-
-	-- Users table
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT UNIQUE NOT NULL,
-		email TEXT NOT NULL,
-		role TEXT NOT NULL,
-		public_key TEXT,
-		token_hash TEXT,
-		mfa_secret TEXT,
-		mfa_enabled INTEGER DEFAULT 0,
-		expires_at DATETIME,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		created_by TEXT,
-		last_access_at DATETIME
-	);
-
-	-- User-Project access mapping
-	CREATE TABLE IF NOT EXISTS user_projects (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_name TEXT NOT NULL,
-		project_name TEXT NOT NULL,
-		granted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		granted_by TEXT,
-		UNIQUE(user_name, project_name)
-	);
-
-	-- Environments table (belongs to a project)
-	CREATE TABLE IF NOT EXISTS environments (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		project TEXT NOT NULL,
-		host TEXT NOT NULL,
-		port INTEGER DEFAULT 22,
-		deploy_user TEXT NOT NULL,
-		deploy_key TEXT NOT NULL,
-		host_key TEXT DEFAULT '',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(name, project)
-	);
-
-	-- Invites table
-	CREATE TABLE IF NOT EXISTS invites (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		token_hash TEXT UNIQUE NOT NULL,
-		user_name TEXT NOT NULL,
-		email TEXT NOT NULL,
-		role TEXT NOT NULL,
-		projects TEXT,
-		expires_at DATETIME NOT NULL,
-		used_at DATETIME,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Audit log table
-	CREATE TABLE IF NOT EXISTS audit_log (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
-		user_name TEXT,
-		action TEXT NOT NULL,
-		details TEXT,
-		ip_address TEXT,
-		prev_hash TEXT,
-		hash TEXT NOT NULL
-	);
-
-	-- Config table
-	CREATE TABLE IF NOT EXISTS config (
-		key TEXT PRIMARY KEY,
-		value TEXT NOT NULL
-	);
-
-	-- Indexes
-	CREATE INDEX IF NOT EXISTS idx_projects_name ON projects(name);
-	CREATE INDEX IF NOT EXISTS idx_users_name ON users(name);
-	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-	CREATE INDEX IF NOT EXISTS idx_users_token_hash ON users(token_hash);
-	CREATE INDEX IF NOT EXISTS idx_user_projects_user ON user_projects(user_name);
-	CREATE INDEX IF NOT EXISTS idx_user_projects_project ON user_projects(project_name);
-	CREATE INDEX IF NOT EXISTS idx_environments_name ON environments(name);
-	CREATE INDEX IF NOT EXISTS idx_environments_project ON environments(project);
-	CREATE INDEX IF NOT EXISTS idx_invites_token_hash ON invites(token_hash);
-	CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log(user_name);
-	CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
-	`
-
-	_, err := s.db.Exec(schema)
-	return err
+// Dialect returns the SQL dialect of the database
+func (s *Storage) Dialect() Dialect {
+	return s.dialect
+}
+
+// exec runs a statement written for SQLite in the dialect of the database
+func (s *Storage) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.dialect.Rebind(query), args...)
+}
+
+// query runs a query written for SQLite in the dialect of the database
+func (s *Storage) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.dialect.Rebind(query), args...)
+}
+
+// queryRow runs a query written for SQLite in the dialect of the database
+func (s *Storage) queryRow(query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRow(s.dialect.Rebind(query), args...)
+}
+
+// insert runs an INSERT into a table with an id column and returns the id
+// of the new row
+func (s *Storage) insert(query string, args ...interface{}) (int64, error) {
+	if s.dialect.ReturningID() {
+		var id int64
+		err := s.queryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := s.exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
 }
 
 // Project operations
 
 // CreateProject creates a new project
 func (s *Storage) CreateProject(project *Project) error {
-	result, err := s.db.Exec(`
+	id, err := s.insert(`
 		INSERT INTO projects (name, description, created_by)
 		VALUES (?, ?, ?)`,
 		project.Name, project.Description, project.CreatedBy)
@@ -167,7 +125,6 @@ func (s *Storage) CreateProject(project *Project) error {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
 
-	id, _ := result.LastInsertId()
 	project.ID = id
 	project.CreatedAt = time.Now()
 	return nil
@@ -179,7 +136,7 @@ func (s *Storage) GetProject(name string) (*Project, error) {
 	var description sql.NullString
 	var createdBy sql.NullString
 
-	err := s.db.QueryRow(`
+	err := s.queryRow(`
 		SELECT id, name, description, created_at, created_by
 		FROM projects WHERE name = ?`, name).Scan(
 		&project.ID, &project.Name, &description, &project.CreatedAt, &createdBy)
@@ -202,7 +159,7 @@ func (s *Storage) GetProject(name string) (*Project, error) {
 
 // ListProjects returns all projects
 func (s *Storage) ListProjects() ([]Project, error) {
-	rows, err := s.db.Query(`
+	rows, err := s.query(`
 		SELECT id, name, description, created_at, created_by
 		FROM projects ORDER BY name`)
 	if err != nil {
@@ -236,19 +193,25 @@ func (s *Storage) ListProjects() ([]Project, error) {
 // DeleteProject deletes a project and all its environments
 func (s *Storage) DeleteProject(name string) error {
 	// Delete environments first
-	_, err := s.db.Exec("DELETE FROM environments WHERE project = ?", name)
+	_, err := s.exec("DELETE FROM environments WHERE project = ?", name)
 	if err != nil {
 		return fmt.Errorf("failed to delete project environments: %w", err)
 	}
 
 	// Remove user access to this project
-	_, err = s.db.Exec("DELETE FROM user_projects WHERE project_name = ?", name)
+	_, err = s.exec("DELETE FROM user_projects WHERE project_name = ?", name)
 	if err != nil {
 		return fmt.Errorf("failed to remove user access: %w", err)
 	}
 
+	// Delete the shared project config
+	_, err = s.exec("DELETE FROM project_configs WHERE project = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete project config: %w", err)
+	}
+
 	// Delete project
-	result, err := s.db.Exec("DELETE FROM projects WHERE name = ?", name)
+	result, err := s.exec("DELETE FROM projects WHERE name = ?", name)
 	if err != nil {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
@@ -259,13 +222,52 @@ func (s *Storage) DeleteProject(name string) error {
 	return nil
 }
 
+// SetProjectConfig stores the canonical .magebox.yaml of a project,
+// replacing the previous one
+func (s *Storage) SetProjectConfig(config *ProjectConfig) error {
+	config.UpdatedAt = time.Now()
+	_, err := s.exec(`
+		INSERT INTO project_configs (project, content, updated_at, updated_by)
+		VALUES (?, ?, ?, ?) `+
+		s.dialect.OnConflict([]string{"project"}, []string{"content", "updated_at", "updated_by"}),
+		config.Project, config.Content, config.UpdatedAt, config.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to store project config: %w", err)
+	}
+	return nil
+}
+
+// GetProjectConfig retrieves the canonical .magebox.yaml of a project
+func (s *Storage) GetProjectConfig(project string) (*ProjectConfig, error) {
+	config := &ProjectConfig{Project: project}
+	var updatedBy sql.NullString
+
+	err := s.queryRow(`
+		SELECT content, updated_at, updated_by
+		FROM project_configs WHERE project = ?`, project).Scan(
+		&config.Content, &config.UpdatedAt, &updatedBy)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no config stored for project: %s", project)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project config: %w", err)
+	}
+
+	if updatedBy.Valid {
+		config.UpdatedBy = updatedBy.String
+	}
+
+	return config, nil
+}
+
 // User-Project access operations
 
 // GrantProjectAccess grants a user access to a project
 func (s *Storage) GrantProjectAccess(userName, projectName, grantedBy string) error {
-	_, err := s.db.Exec(`
-		INSERT OR IGNORE INTO user_projects (user_name, project_name, granted_by)
-		VALUES (?, ?, ?)`,
+	_, err := s.exec(`
+		INSERT INTO user_projects (user_name, project_name, granted_by)
+		VALUES (?, ?, ?) `+
+		s.dialect.OnConflict([]string{"user_name", "project_name"}, nil),
 		userName, projectName, grantedBy)
 	if err != nil {
 		return fmt.Errorf("failed to grant project access: %w", err)
@@ -275,7 +277,7 @@ func (s *Storage) GrantProjectAccess(userName, projectName, grantedBy string) er
 
 // RevokeProjectAccess revokes a user's access to a project
 func (s *Storage) RevokeProjectAccess(userName, projectName string) error {
-	result, err := s.db.Exec(`
+	result, err := s.exec(`
 		DELETE FROM user_projects WHERE user_name = ? AND project_name = ?`,
 		userName, projectName)
 	if err != nil {
@@ -290,7 +292,7 @@ func (s *Storage) RevokeProjectAccess(userName, projectName string) error {
 
 // GetUserProjects returns all projects a user has access to
 func (s *Storage) GetUserProjects(userName string) ([]string, error) {
-	rows, err := s.db.Query(`
+	rows, err := s.query(`
 		SELECT project_name FROM user_projects WHERE user_name = ? ORDER BY project_name`,
 		userName)
 	if err != nil {
@@ -310,9 +312,32 @@ func (s *Storage) GetUserProjects(userName string) ([]string, error) {
 	return projects, nil
 }
 
+// GetUserProjectsGrantedBy returns the projects a user was granted access to
+// by grantedBy
+func (s *Storage) GetUserProjectsGrantedBy(userName, grantedBy string) ([]string, error) {
+	rows, err := s.query(`
+		SELECT project_name FROM user_projects WHERE user_name = ? AND granted_by = ? ORDER BY project_name`,
+		userName, grantedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var projectName string
+		if err := rows.Scan(&projectName); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, projectName)
+	}
+
+	return projects, rows.Err()
+}
+
 // GetProjectUsers returns all users with access to a project
 func (s *Storage) GetProjectUsers(projectName string) ([]string, error) {
-	rows, err := s.db.Query(`
+	rows, err := s.query(`
 		SELECT user_name FROM user_projects WHERE project_name = ? ORDER BY user_name`,
 		projectName)
 	if err != nil {
@@ -346,7 +371,7 @@ func (s *Storage) CreateUser(user *User) error {
 		mfaSecret = encrypted
 	}
 
-	result, err := s.db.Exec(`
+	id, err := s.insert(`
 		INSERT INTO users (name, email, role, public_key, token_hash, mfa_secret, mfa_enabled, expires_at, created_by)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		user.Name, user.Email, user.Role, user.PublicKey, user.TokenHash,
@@ -355,7 +380,6 @@ func (s *Storage) CreateUser(user *User) error {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
-	id, _ := result.LastInsertId()
 	user.ID = id
 	user.CreatedAt = time.Now()
 	return nil
@@ -368,7 +392,7 @@ func (s *Storage) GetUser(name string) (*User, error) {
 	var expiresAt sql.NullTime
 	var lastAccessAt sql.NullTime
 
-	err := s.db.QueryRow(`
+	err := s.queryRow(`
 		SELECT id, name, email, role, public_key, token_hash, mfa_secret, mfa_enabled,
 		       expires_at, created_at, created_by, last_access_at
 		FROM users WHERE name = ?`, name).Scan(
@@ -414,7 +438,7 @@ func (s *Storage) GetUserByTokenHash(tokenHash string) (*User, error) {
 	var expiresAt sql.NullTime
 	var lastAccessAt sql.NullTime
 
-	err := s.db.QueryRow(`
+	err := s.queryRow(`
 		SELECT id, name, email, role, public_key, token_hash, mfa_secret, mfa_enabled,
 		       expires_at, created_at, created_by, last_access_at
 		FROM users WHERE token_hash = ?`, tokenHash).Scan(
@@ -454,7 +478,7 @@ func (s *Storage) GetUserByTokenHash(tokenHash string) (*User, error) {
 
 // ListUsers returns all users
 func (s *Storage) ListUsers() ([]User, error) {
-	rows, err := s.db.Query(`
+	rows, err := s.query(`
 		SELECT id, name, email, role, public_key, token_hash, mfa_enabled, expires_at, created_at, created_by, last_access_at
 		FROM users ORDER BY name`)
 	if err != nil {
@@ -505,7 +529,7 @@ func (s *Storage) UpdateUser(user *User) error {
 		mfaSecret = encrypted
 	}
 
-	_, err := s.db.Exec(`
+	_, err := s.exec(`
 		UPDATE users SET email = ?, role = ?, public_key = ?, token_hash = ?,
 		       mfa_secret = ?, mfa_enabled = ?, expires_at = ?, last_access_at = ?
 		WHERE name = ?`,
@@ -519,7 +543,7 @@ func (s *Storage) UpdateUser(user *User) error {
 
 // DeleteUser deletes a user
 func (s *Storage) DeleteUser(name string) error {
-	result, err := s.db.Exec("DELETE FROM users WHERE name = ?", name)
+	result, err := s.exec("DELETE FROM users WHERE name = ?", name)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -532,10 +556,62 @@ func (s *Storage) DeleteUser(name string) error {
 
 // UpdateUserLastAccess updates the last access time
 func (s *Storage) UpdateUserLastAccess(name string) error {
-	_, err := s.db.Exec("UPDATE users SET last_access_at = ? WHERE name = ?", time.Now(), name)
+	_, err := s.exec("UPDATE users SET last_access_at = ? WHERE name = ?", time.Now(), name)
 	return err
 }
 
+// ListUserSessions returns the sessions of all users that have one
+func (s *Storage) ListUserSessions() ([]UserSession, error) {
+	rows, err := s.query(`
+		SELECT name, token_hash, session_expires_at, refresh_token_hash, refresh_expires_at
+		FROM users WHERE token_hash <> '' OR refresh_token_hash <> '' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []UserSession
+	for rows.Next() {
+		var session UserSession
+		var tokenHash, refreshHash sql.NullString
+		var expiresAt, refreshExpiresAt sql.NullTime
+
+		if err := rows.Scan(&session.UserName, &tokenHash, &expiresAt, &refreshHash, &refreshExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+
+		session.TokenHash = tokenHash.String
+		session.RefreshTokenHash = refreshHash.String
+		if expiresAt.Valid {
+			session.ExpiresAt = &expiresAt.Time
+		}
+		if refreshExpiresAt.Valid {
+			session.RefreshExpiresAt = &refreshExpiresAt.Time
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// SetUserSession replaces the session of a user. An empty session signs the
+// user out everywhere.
+func (s *Storage) SetUserSession(session *UserSession) error {
+	result, err := s.exec(`
+		UPDATE users SET token_hash = ?, session_expires_at = ?, refresh_token_hash = ?, refresh_expires_at = ?
+		WHERE name = ?`,
+		session.TokenHash, session.ExpiresAt, session.RefreshTokenHash, session.RefreshExpiresAt, session.UserName)
+	if err != nil {
+		return fmt.Errorf("failed to set session: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("user not found: %s", session.UserName)
+	}
+	return nil
+}
+
 // Environment operations
 
 // CreateEnvironment creates a new environment
@@ -546,15 +622,14 @@ func (s *Storage) CreateEnvironment(env *Environment) error {
 		return fmt.Errorf("failed to encrypt deploy key: %w", err)
 	}
 
-	result, err := s.db.Exec(`
-		INSERT INTO environments (name, project, host, port, deploy_user, deploy_key, host_key)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		env.Name, env.Project, env.Host, env.Port, env.DeployUser, encryptedKey, env.HostKey)
+	id, err := s.insert(`
+		INSERT INTO environments (name, project, host, port, deploy_user, deploy_key, host_key, principals)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		env.Name, env.Project, env.Host, env.Port, env.DeployUser, encryptedKey, env.HostKey, strings.Join(env.Principals, ","))
 	if err != nil {
 		return fmt.Errorf("failed to create environment: %w", err)
 	}
 
-	id, _ := result.LastInsertId()
 	env.ID = id
 	env.CreatedAt = time.Now()
 	return nil
@@ -564,12 +639,12 @@ func (s *Storage) CreateEnvironment(env *Environment) error {
 func (s *Storage) GetEnvironment(project, name string) (*Environment, error) {
 	env := &Environment{}
 	var encryptedKey string
-	var hostKey sql.NullString
+	var hostKey, principals sql.NullString
 
-	err := s.db.QueryRow(`
-		SELECT id, name, project, host, port, deploy_user, deploy_key, host_key, created_at
+	err := s.queryRow(`
+		SELECT id, name, project, host, port, deploy_user, deploy_key, host_key, principals, created_at
 		FROM environments WHERE project = ? AND name = ?`, project, name).Scan(
-		&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &encryptedKey, &hostKey, &env.CreatedAt)
+		&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &encryptedKey, &hostKey, &principals, &env.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("environment not found: %s/%s", project, name)
 	}
@@ -584,13 +659,14 @@ func (s *Storage) GetEnvironment(project, name string) (*Environment, error) {
 	}
 	env.DeployKey = decrypted
 	env.HostKey = hostKey.String
+	env.Principals = splitPrincipals(principals.String)
 
 	return env, nil
 }
 
 // UpdateEnvironmentHostKey updates the SSH host key fingerprint for an environment
 func (s *Storage) UpdateEnvironmentHostKey(project, name, hostKey string) error {
-	result, err := s.db.Exec(`
+	result, err := s.exec(`
 		UPDATE environments SET host_key = ? WHERE project = ? AND name = ?`,
 		hostKey, project, name)
 	if err != nil {
@@ -605,10 +681,36 @@ func (s *Storage) UpdateEnvironmentHostKey(project, name, hostKey string) error
 	return nil
 }
 
+// UpdateEnvironmentPrincipals sets the principals of certificates for an
+// environment, none for the default principals
+func (s *Storage) UpdateEnvironmentPrincipals(project, name string, principals []string) error {
+	result, err := s.exec(`
+		UPDATE environments SET principals = ? WHERE project = ? AND name = ?`,
+		strings.Join(principals, ","), project, name)
+	if err != nil {
+		return fmt.Errorf("failed to update principals: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("environment not found: %s/%s", project, name)
+	}
+
+	return nil
+}
+
+// splitPrincipals splits the comma-separated principals of an environment
+func splitPrincipals(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 // ListEnvironments returns all environments (without deploy keys for security)
 func (s *Storage) ListEnvironments() ([]Environment, error) {
-	rows, err := s.db.Query(`
-		SELECT id, name, project, host, port, deploy_user, created_at
+	rows, err := s.query(`
+		SELECT id, name, project, host, port, deploy_user, principals, created_at
 		FROM environments ORDER BY project, name`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list environments: %w", err)
@@ -618,10 +720,12 @@ func (s *Storage) ListEnvironments() ([]Environment, error) {
 	var envs []Environment
 	for rows.Next() {
 		var env Environment
+		var principals sql.NullString
 
-		if err := rows.Scan(&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &env.CreatedAt); err != nil {
+		if err := rows.Scan(&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &principals, &env.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan environment: %w", err)
 		}
+		env.Principals = splitPrincipals(principals.String)
 
 		envs = append(envs, env)
 	}
@@ -631,8 +735,8 @@ func (s *Storage) ListEnvironments() ([]Environment, error) {
 
 // ListEnvironmentsByProject returns environments for a specific project
 func (s *Storage) ListEnvironmentsByProject(projectName string) ([]Environment, error) {
-	rows, err := s.db.Query(`
-		SELECT id, name, project, host, port, deploy_user, created_at
+	rows, err := s.query(`
+		SELECT id, name, project, host, port, deploy_user, principals, created_at
 		FROM environments WHERE project = ? ORDER BY name`, projectName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list environments: %w", err)
@@ -642,10 +746,12 @@ func (s *Storage) ListEnvironmentsByProject(projectName string) ([]Environment,
 	var envs []Environment
 	for rows.Next() {
 		var env Environment
+		var principals sql.NullString
 
-		if err := rows.Scan(&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &env.CreatedAt); err != nil {
+		if err := rows.Scan(&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &principals, &env.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan environment: %w", err)
 		}
+		env.Principals = splitPrincipals(principals.String)
 
 		envs = append(envs, env)
 	}
@@ -674,11 +780,11 @@ func (s *Storage) ListEnvironmentsForUser(userName string) ([]Environment, error
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, name, project, host, port, deploy_user, created_at
+		SELECT id, name, project, host, port, deploy_user, principals, created_at
 		FROM environments WHERE project IN (%s) ORDER BY project, name`,
 		strings.Join(placeholders, ","))
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list environments: %w", err)
 	}
@@ -687,10 +793,12 @@ func (s *Storage) ListEnvironmentsForUser(userName string) ([]Environment, error
 	var envs []Environment
 	for rows.Next() {
 		var env Environment
+		var principals sql.NullString
 
-		if err := rows.Scan(&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &env.CreatedAt); err != nil {
+		if err := rows.Scan(&env.ID, &env.Name, &env.Project, &env.Host, &env.Port, &env.DeployUser, &principals, &env.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan environment: %w", err)
 		}
+		env.Principals = splitPrincipals(principals.String)
 
 		envs = append(envs, env)
 	}
@@ -700,7 +808,7 @@ func (s *Storage) ListEnvironmentsForUser(userName string) ([]Environment, error
 
 // DeleteEnvironment deletes an environment
 func (s *Storage) DeleteEnvironment(project, name string) error {
-	result, err := s.db.Exec("DELETE FROM environments WHERE project = ? AND name = ?", project, name)
+	result, err := s.exec("DELETE FROM environments WHERE project = ? AND name = ?", project, name)
 	if err != nil {
 		return fmt.Errorf("failed to delete environment: %w", err)
 	}
@@ -717,7 +825,7 @@ func (s *Storage) DeleteEnvironment(project, name string) error {
 func (s *Storage) CreateInvite(invite *Invite) error {
 	projects := strings.Join(invite.Projects, ",")
 
-	result, err := s.db.Exec(`
+	id, err := s.insert(`
 		INSERT INTO invites (token_hash, user_name, email, role, projects, expires_at)
 		VALUES (?, ?, ?, ?, ?, ?)`,
 		invite.TokenHash, invite.UserName, invite.Email, invite.Role, projects, invite.ExpiresAt)
@@ -725,7 +833,6 @@ func (s *Storage) CreateInvite(invite *Invite) error {
 		return fmt.Errorf("failed to create invite: %w", err)
 	}
 
-	id, _ := result.LastInsertId()
 	invite.ID = id
 	invite.CreatedAt = time.Now()
 	return nil
@@ -737,7 +844,7 @@ func (s *Storage) GetInviteByTokenHash(tokenHash string) (*Invite, error) {
 	var projectsStr string
 	var usedAt sql.NullTime
 
-	err := s.db.QueryRow(`
+	err := s.queryRow(`
 		SELECT id, token_hash, user_name, email, role, projects, expires_at, used_at, created_at
 		FROM invites WHERE token_hash = ?`, tokenHash).Scan(
 		&invite.ID, &invite.TokenHash, &invite.UserName, &invite.Email, &invite.Role,
@@ -761,13 +868,13 @@ func (s *Storage) GetInviteByTokenHash(tokenHash string) (*Invite, error) {
 
 // MarkInviteUsed marks an invite as used
 func (s *Storage) MarkInviteUsed(id int64) error {
-	_, err := s.db.Exec("UPDATE invites SET used_at = ? WHERE id = ?", time.Now(), id)
+	_, err := s.exec("UPDATE invites SET used_at = ? WHERE id = ?", time.Now(), id)
 	return err
 }
 
 // DeleteExpiredInvites removes expired invites
 func (s *Storage) DeleteExpiredInvites() (int64, error) {
-	result, err := s.db.Exec("DELETE FROM invites WHERE expires_at < ? AND used_at IS NULL", time.Now())
+	result, err := s.exec("DELETE FROM invites WHERE expires_at < ? AND used_at IS NULL", time.Now())
 	if err != nil {
 		return 0, err
 	}
@@ -780,7 +887,7 @@ func (s *Storage) DeleteExpiredInvites() (int64, error) {
 func (s *Storage) CreateAuditEntry(entry *AuditEntry) error {
 	// Get the last entry's hash for chaining
 	var prevHash string
-	err := s.db.QueryRow("SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+	err := s.queryRow("SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&prevHash)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("failed to get last audit hash: %w", err)
 	}
@@ -789,7 +896,7 @@ func (s *Storage) CreateAuditEntry(entry *AuditEntry) error {
 	entry.Timestamp = time.Now().UTC()
 
 	// Insert with placeholder hash first to get the ID
-	result, err := s.db.Exec(`
+	id, err := s.insert(`
 		INSERT INTO audit_log (timestamp, user_name, action, details, ip_address, prev_hash, hash)
 		VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		entry.Timestamp, entry.UserName, entry.Action, entry.Details, entry.IPAddress,
@@ -798,14 +905,13 @@ func (s *Storage) CreateAuditEntry(entry *AuditEntry) error {
 		return fmt.Errorf("failed to create audit entry: %w", err)
 	}
 
-	id, _ := result.LastInsertId()
 	entry.ID = id
 
 	// Compute hash with the actual ID
 	entry.Hash = ComputeAuditHash(entry, prevHash)
 
 	// Update with the correct hash
-	_, err = s.db.Exec("UPDATE audit_log SET hash = ? WHERE id = ?", entry.Hash, entry.ID)
+	_, err = s.exec("UPDATE audit_log SET hash = ? WHERE id = ?", entry.Hash, entry.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update audit hash: %w", err)
 	}
@@ -820,11 +926,11 @@ func (s *Storage) ListAuditEntries(from, to *time.Time, userName string, action
 
 	if from != nil {
 		query += " AND timestamp >= ?"
-		args = append(args, from.UTC().Format("2006-01-02 15:04:05"))
+		args = append(args, s.dialect.TimeArg(*from))
 	}
 	if to != nil {
 		query += " AND timestamp <= ?"
-		args = append(args, to.UTC().Format("2006-01-02 15:04:05"))
+		args = append(args, s.dialect.TimeArg(*to))
 	}
 	if userName != "" {
 		query += " AND user_name = ?"
@@ -842,7 +948,7 @@ func (s *Storage) ListAuditEntries(from, to *time.Time, userName string, action
 		args = append(args, limit)
 	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list audit entries: %w", err)
 	}
@@ -895,19 +1001,206 @@ func (s *Storage) VerifyAuditLog() (bool, int, error) {
 // DeleteOldAuditEntries removes entries older than retention period
 func (s *Storage) DeleteOldAuditEntries(retentionDays int) (int64, error) {
 	cutoff := time.Now().AddDate(0, 0, -retentionDays)
-	result, err := s.db.Exec("DELETE FROM audit_log WHERE timestamp < ?", cutoff)
+	result, err := s.exec("DELETE FROM audit_log WHERE timestamp < ?", cutoff)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected()
 }
 
+// OIDC identity operations
+
+// GetOIDCIdentity returns the user an OIDC subject is linked to, or an empty
+// name. Links waiting for an admin's approval don't count.
+func (s *Storage) GetOIDCIdentity(issuer, subject string) (string, error) {
+	var userName string
+	err := s.queryRow(`
+		SELECT user_name FROM oidc_identities WHERE issuer = ? AND subject = ? AND approved = ?`,
+		issuer, subject, true).Scan(&userName)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get OIDC identity: %w", err)
+	}
+	return userName, nil
+}
+
+// OIDCIdentitySignedIn reports whether an approved OIDC subject has signed
+// in before
+func (s *Storage) OIDCIdentitySignedIn(issuer, subject string) (bool, error) {
+	var signedIn bool
+	err := s.queryRow(`
+		SELECT signed_in FROM oidc_identities WHERE issuer = ? AND subject = ? AND approved = ?`,
+		issuer, subject, true).Scan(&signedIn)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get OIDC identity: %w", err)
+	}
+	return signedIn, nil
+}
+
+// LinkOIDCIdentity links an OIDC subject to a user and records that it
+// signed in
+func (s *Storage) LinkOIDCIdentity(issuer, subject, userName string) error {
+	return s.upsertOIDCIdentity(issuer, subject, userName, true)
+}
+
+// RequestOIDCLink records that an OIDC subject asks to be linked to an
+// existing user, which an admin approves with ApproveOIDCLink
+func (s *Storage) RequestOIDCLink(issuer, subject, userName string) error {
+	return s.upsertOIDCIdentity(issuer, subject, userName, false)
+}
+
+func (s *Storage) upsertOIDCIdentity(issuer, subject, userName string, approved bool) error {
+	_, err := s.exec(`
+		INSERT INTO oidc_identities (issuer, subject, user_name, approved, signed_in) VALUES (?, ?, ?, ?, ?) `+
+		s.dialect.OnConflict([]string{"issuer", "subject"}, []string{"user_name", "approved", "signed_in"}),
+		issuer, subject, userName, approved, approved)
+	if err != nil {
+		return fmt.Errorf("failed to link OIDC identity: %w", err)
+	}
+	return nil
+}
+
+// ApproveOIDCLink approves the pending links to a user and returns how many
+// there were
+func (s *Storage) ApproveOIDCLink(userName string) (int64, error) {
+	result, err := s.exec(`
+		UPDATE oidc_identities SET approved = ? WHERE user_name = ? AND approved = ?`,
+		true, userName, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to approve OIDC identity: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Sync history operations
+
+// DeleteOldSyncHistory removes sync history entries older than retentionDays
+func (s *Storage) DeleteOldSyncHistory(retentionDays int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays).UTC()
+	result, err := s.exec("DELETE FROM sync_history WHERE timestamp < ?", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CreateSyncHistoryEntry records the result of syncing an environment
+func (s *Storage) CreateSyncHistoryEntry(entry *SyncHistoryEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	id, err := s.insert(`
+		INSERT INTO sync_history (timestamp, `+"`trigger`"+`, environment, success, keys_added, keys_removed, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.Timestamp.UTC(), entry.Trigger, entry.Environment, entry.Success, entry.KeysAdded, entry.KeysRemoved, entry.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record sync history: %w", err)
+	}
+	entry.ID = id
+	return nil
+}
+
+// ListSyncHistory returns sync results, newest first. An empty environment
+// returns all environments; with driftOnly, only syncs that found drift or
+// failed are returned.
+func (s *Storage) ListSyncHistory(environment string, driftOnly bool, limit int) ([]SyncHistoryEntry, error) {
+	query := "SELECT id, timestamp, `trigger`, environment, success, keys_added, keys_removed, error FROM sync_history WHERE 1=1"
+	var args []interface{}
+
+	if environment != "" {
+		query += " AND environment = ?"
+		args = append(args, environment)
+	}
+	if driftOnly {
+		query += " AND (keys_added > 0 OR keys_removed > 0 OR NOT success)"
+	}
+
+	query += " ORDER BY timestamp DESC, id DESC"
+
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []SyncHistoryEntry
+	for rows.Next() {
+		var entry SyncHistoryEntry
+		var syncErr sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Timestamp, &entry.Trigger, &entry.Environment,
+			&entry.Success, &entry.KeysAdded, &entry.KeysRemoved, &syncErr); err != nil {
+			return nil, fmt.Errorf("failed to scan sync history: %w", err)
+		}
+		entry.Error = syncErr.String
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Pending key removal operations
+
+// QueueKeyRemoval records a failed key removal, or another failed attempt of
+// one already queued
+func (s *Storage) QueueKeyRemoval(removal *PendingKeyRemoval) error {
+	_, err := s.exec(`
+		INSERT INTO pending_key_removals (user_name, project, environment, attempts, last_error, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?) `+
+		s.dialect.OnConflict([]string{"user_name", "project", "environment"}, []string{"attempts", "last_error", "next_attempt_at"}),
+		removal.UserName, removal.Project, removal.Environment, removal.Attempts, removal.LastError, removal.NextAttempt)
+	if err != nil {
+		return fmt.Errorf("failed to queue key removal: %w", err)
+	}
+	return nil
+}
+
+// ListPendingKeyRemovals returns the queued key removals, the next due first
+func (s *Storage) ListPendingKeyRemovals() ([]PendingKeyRemoval, error) {
+	rows, err := s.query(`
+		SELECT id, user_name, project, environment, attempts, last_error, next_attempt_at, created_at
+		FROM pending_key_removals ORDER BY next_attempt_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending key removals: %w", err)
+	}
+	defer rows.Close()
+
+	var removals []PendingKeyRemoval
+	for rows.Next() {
+		var r PendingKeyRemoval
+		var lastError sql.NullString
+		if err := rows.Scan(&r.ID, &r.UserName, &r.Project, &r.Environment, &r.Attempts,
+			&lastError, &r.NextAttempt, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending key removal: %w", err)
+		}
+		r.LastError = lastError.String
+		removals = append(removals, r)
+	}
+
+	return removals, rows.Err()
+}
+
+// DeleteKeyRemoval removes a key removal from the queue
+func (s *Storage) DeleteKeyRemoval(id int64) error {
+	_, err := s.exec("DELETE FROM pending_key_removals WHERE id = ?", id)
+	return err
+}
+
 // Config operations
 
 // GetConfig retrieves a config value
 func (s *Storage) GetConfig(key string) (string, error) {
 	var value string
-	err := s.db.QueryRow("SELECT value FROM config WHERE key = ?", key).Scan(&value)
+	err := s.queryRow("SELECT value FROM config WHERE `key` = ?", key).Scan(&value)
 	if err == sql.ErrNoRows {
 		return "", nil
 	}
@@ -916,13 +1209,13 @@ func (s *Storage) GetConfig(key string) (string, error) {
 
 // SetConfig sets a config value
 func (s *Storage) SetConfig(key, value string) error {
-	_, err := s.db.Exec(`
-		INSERT INTO config (key, value) VALUES (?, ?)
-		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	_, err := s.exec("INSERT INTO config (`key`, value) VALUES (?, ?) "+
+		s.dialect.OnConflict([]string{"`key`"}, []string{"value"}), key, value)
 	return err
 }
 
-// Transaction helper
+// Transaction runs fn in a transaction, rolled back when fn fails. Queries
+// in fn run as written; rewrite them with Dialect().Rebind.
 func (s *Storage) Transaction(fn func(tx *sql.Tx) error) error {
 	tx, err := s.db.Begin()
 	if err != nil {
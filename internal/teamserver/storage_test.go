@@ -12,6 +12,7 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -562,6 +563,56 @@ func TestDeleteEnvironment(t *testing.T) {
 	}
 }
 
+func TestUpdateEnvironmentPrincipals(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := storage.CreateProject(&Project{Name: "testproject"}); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	env := &Environment{
+		Name:       "production",
+		Project:    "testproject",
+		Host:       "prod.example.com",
+		DeployUser: "deploy",
+		DeployKey:  "key",
+		Principals: []string{"deploy-prod"},
+	}
+	if err := storage.CreateEnvironment(env); err != nil {
+		t.Fatalf("CreateEnvironment failed: %v", err)
+	}
+
+	got, err := storage.GetEnvironment("testproject", "production")
+	if err != nil {
+		t.Fatalf("GetEnvironment failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.Principals, []string{"deploy-prod"}) {
+		t.Errorf("Principals = %v, want [deploy-prod]", got.Principals)
+	}
+
+	if err := storage.UpdateEnvironmentPrincipals("testproject", "production", []string{"deploy", "www-data"}); err != nil {
+		t.Fatalf("UpdateEnvironmentPrincipals failed: %v", err)
+	}
+	envs, err := storage.ListEnvironments()
+	if err != nil {
+		t.Fatalf("ListEnvironments failed: %v", err)
+	}
+	if len(envs) != 1 || !reflect.DeepEqual(envs[0].Principals, []string{"deploy", "www-data"}) {
+		t.Errorf("listed environments = %+v, want the new principals", envs)
+	}
+
+	if err := storage.UpdateEnvironmentPrincipals("testproject", "production", nil); err != nil {
+		t.Fatalf("UpdateEnvironmentPrincipals failed: %v", err)
+	}
+	if got, _ := storage.GetEnvironment("testproject", "production"); got.Principals != nil {
+		t.Errorf("Principals after reset = %v, want none", got.Principals)
+	}
+
+	if err := storage.UpdateEnvironmentPrincipals("testproject", "missing", nil); err == nil {
+		t.Error("expected an error for a missing environment")
+	}
+}
+
 // Invite tests
 
 func TestCreateAndGetInvite(t *testing.T) {
@@ -1221,3 +1272,49 @@ func TestGetProjectUsers(t *testing.T) {
 		t.Errorf("Expected 2 users, got %d", len(projectUsers))
 	}
 }
+
+func TestSetAndGetProjectConfig(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if _, err := storage.GetProjectConfig("shop"); err == nil {
+		t.Error("GetProjectConfig should fail before a config is stored")
+	}
+
+	if err := storage.SetProjectConfig(&ProjectConfig{Project: "shop", Content: "php: \"8.2\"\n", UpdatedBy: "admin"}); err != nil {
+		t.Fatalf("SetProjectConfig failed: %v", err)
+	}
+	if err := storage.SetProjectConfig(&ProjectConfig{Project: "shop", Content: "php: \"8.3\"\n", UpdatedBy: "alice"}); err != nil {
+		t.Fatalf("SetProjectConfig overwrite failed: %v", err)
+	}
+
+	config, err := storage.GetProjectConfig("shop")
+	if err != nil {
+		t.Fatalf("GetProjectConfig failed: %v", err)
+	}
+	if config.Content != "php: \"8.3\"\n" {
+		t.Errorf("Content = %q, want the last stored config", config.Content)
+	}
+	if config.UpdatedBy != "alice" {
+		t.Errorf("UpdatedBy = %q, want alice", config.UpdatedBy)
+	}
+}
+
+func TestDeleteProjectRemovesConfig(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if err := storage.CreateProject(&Project{Name: "shop", CreatedBy: "admin"}); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if err := storage.SetProjectConfig(&ProjectConfig{Project: "shop", Content: "php: \"8.3\"\n"}); err != nil {
+		t.Fatalf("SetProjectConfig failed: %v", err)
+	}
+
+	if err := storage.DeleteProject("shop"); err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if _, err := storage.GetProjectConfig("shop"); err == nil {
+		t.Error("Project config should be removed with the project")
+	}
+}
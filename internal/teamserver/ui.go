@@ -0,0 +1,56 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles is the single-page admin UI. It is static: the browser sends the
+// admin token to the /api endpoints like the CLI does.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiContentSecurityPolicy only allows the UI's own scripts, styles and API
+// requests, so injected markup can't run or send data elsewhere
+const uiContentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self'; connect-src 'self'; form-action 'none'; base-uri 'none'; frame-ancestors 'none'"
+
+// uiHandler serves the admin UI under /ui/
+func (s *Server) uiHandler() http.Handler {
+	root, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+	files := http.StripPrefix("/ui/", http.FileServer(http.FS(root)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-cache")
+		if s.config.TLS.Enabled {
+			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
+
+		if !s.ipAllowed(s.getClientIP(r)) {
+			http.Error(w, "IP address not in allowlist", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Only GET is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
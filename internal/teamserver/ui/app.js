@@ -0,0 +1,366 @@
+// MageBox team server admin UI. Every view is rendered from the admin REST
+// API with the token the admin signs in with; the token lives in
+// sessionStorage only, so it's gone when the tab is closed.
+'use strict';
+
+const tokenKey = 'magebox-admin-token';
+const views = ['users', 'projects', 'environments', 'audit'];
+
+const state = { projects: [], users: [], environments: [] };
+
+function $(selector, root) {
+  return (root || document).querySelector(selector);
+}
+
+// el creates an element; text is always set as text, never parsed as HTML
+function el(tag, props, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, props || {});
+  for (const child of children) {
+    if (child === null || child === undefined) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+function formatTime(value) {
+  if (!value) return '';
+  return new Date(value).toLocaleString();
+}
+
+function showMessage(text, isError) {
+  const message = $('#message');
+  message.textContent = text;
+  message.className = isError ? 'error' : '';
+  message.hidden = !text;
+}
+
+async function api(method, path, body) {
+  const options = {
+    method: method,
+    headers: { Authorization: 'Bearer ' + sessionStorage.getItem(tokenKey) },
+  };
+  if (body !== undefined) {
+    options.headers['Content-Type'] = 'application/json';
+    options.body = JSON.stringify(body);
+  }
+
+  const response = await fetch(path, options);
+  const data = await response.json().catch(() => null);
+  if (response.status === 401) {
+    signOut();
+  }
+  if (!response.ok) {
+    throw new Error((data && data.error) || response.statusText);
+  }
+  return data;
+}
+
+// run calls an action and shows its error, or its success message
+async function run(action, success) {
+  try {
+    await action();
+    showMessage(success || '', false);
+  } catch (err) {
+    showMessage(err.message, true);
+  }
+}
+
+function confirmed(question) {
+  return window.confirm(question);
+}
+
+function fillTable(section, rows, columns) {
+  const tbody = $('#' + section + ' tbody');
+  tbody.replaceChildren();
+  if (rows.length === 0) {
+    tbody.append(el('tr', {}, el('td', { className: 'empty', colSpan: columns }, 'Nothing here yet')));
+    return;
+  }
+  for (const cells of rows) {
+    tbody.append(el('tr', {}, ...cells.map((cell) => el('td', {}, cell))));
+  }
+}
+
+async function loadProjects() {
+  state.projects = (await api('GET', '/api/admin/projects')) || [];
+}
+
+// Users
+
+async function renderUsers() {
+  await loadProjects();
+  state.users = (await api('GET', '/api/admin/users')) || [];
+
+  fillTable('users', state.users.map((user) => {
+    const projects = el('div');
+    for (const project of user.projects || []) {
+      const revoke = el('button', { type: 'button', title: 'Revoke access' }, '×');
+      revoke.addEventListener('click', () => {
+        if (!confirmed('Revoke the access of ' + user.name + ' to ' + project + '?')) return;
+        run(async () => {
+          await api('DELETE', '/api/admin/users/' + encodeURIComponent(user.name) + '/access', { project: project });
+          await renderUsers();
+        }, 'Revoked the access of ' + user.name + ' to ' + project);
+      });
+      projects.append(el('span', { className: 'tag' }, project, revoke));
+    }
+
+    const grantable = state.projects.filter((p) => !(user.projects || []).includes(p.name));
+    if (grantable.length > 0) {
+      const select = el('select', {}, el('option', { value: '' }, 'Grant access…'),
+        ...grantable.map((p) => el('option', { value: p.name }, p.name)));
+      select.addEventListener('change', () => {
+        const project = select.value;
+        if (!project) return;
+        run(async () => {
+          await api('POST', '/api/admin/users/' + encodeURIComponent(user.name) + '/access', { project: project });
+          await renderUsers();
+        }, 'Granted ' + user.name + ' access to ' + project);
+      });
+      projects.append(select);
+    }
+
+    const remove = el('button', { type: 'button', className: 'danger small' }, 'Remove');
+    remove.addEventListener('click', () => {
+      if (!confirmed('Remove ' + user.name + '? Their keys are removed from every environment.')) return;
+      run(async () => {
+        await api('DELETE', '/api/admin/users/' + encodeURIComponent(user.name));
+        await renderUsers();
+      }, 'Removed ' + user.name);
+    });
+
+    return [user.name, user.email, user.role, projects, user.mfa_enabled ? 'yes' : 'no',
+      formatTime(user.expires_at), formatTime(user.last_access_at), remove];
+  }), 8);
+
+  const fieldset = $('#invite-projects');
+  fieldset.replaceChildren(el('legend', {}, 'Projects'));
+  for (const project of state.projects) {
+    fieldset.append(el('label', {}, el('input', { type: 'checkbox', name: 'projects', value: project.name }), project.name));
+  }
+}
+
+function onInvite(event) {
+  event.preventDefault();
+  const form = event.target;
+  const data = new FormData(form);
+  const request = {
+    name: data.get('name'),
+    email: data.get('email'),
+    role: data.get('role'),
+    projects: data.getAll('projects'),
+  };
+
+  run(async () => {
+    const response = await api('POST', '/api/admin/users', request);
+    const result = $('#invite-result');
+    $('pre', result).textContent = 'magebox server join ' + window.location.origin +
+      ' --token ' + response.invite_token;
+    result.hidden = false;
+    form.reset();
+    await renderUsers();
+  }, 'Invited ' + request.name);
+}
+
+// Projects
+
+async function renderProjects() {
+  await loadProjects();
+  state.users = (await api('GET', '/api/admin/users')) || [];
+  state.environments = (await api('GET', '/api/admin/environments')) || [];
+
+  fillTable('projects', state.projects.map((project) => {
+    const members = state.users.filter((u) => (u.projects || []).includes(project.name)).map((u) => u.name);
+    const environments = state.environments.filter((e) => e.project === project.name).map((e) => e.name);
+
+    const remove = el('button', { type: 'button', className: 'danger small' }, 'Remove');
+    remove.addEventListener('click', () => {
+      if (!confirmed('Remove project ' + project.name + ' and all its environments?')) return;
+      run(async () => {
+        await api('DELETE', '/api/admin/projects/' + encodeURIComponent(project.name));
+        await renderProjects();
+      }, 'Removed ' + project.name);
+    });
+
+    return [project.name, project.description || '', members.join(', '), environments.join(', '),
+      formatTime(project.created_at), remove];
+  }), 6);
+}
+
+function onAddProject(event) {
+  event.preventDefault();
+  const form = event.target;
+  const data = new FormData(form);
+  const request = { name: data.get('name'), description: data.get('description') };
+
+  run(async () => {
+    await api('POST', '/api/admin/projects', request);
+    form.reset();
+    await renderProjects();
+  }, 'Added project ' + request.name);
+}
+
+// Environments
+
+function syncKeys(environment) {
+  run(async () => {
+    const response = await api('POST', '/api/admin/sync', environment ? { environment: environment } : {});
+    const failed = (response.results || []).filter((r) => !r.success);
+    if (failed.length > 0) {
+      throw new Error(response.message + ': ' + failed.map((r) => r.environment + ' (' + r.error + ')').join(', '));
+    }
+    showMessage(response.message, false);
+  });
+}
+
+function healthCell(health) {
+  if (!health) return el('span', { className: 'muted' }, 'Not checked');
+  if (health.healthy) return el('span', { title: 'Checked ' + formatTime(health.checked_at) }, 'Healthy (' + health.latency_ms + ' ms)');
+  const label = health.reachable ? 'Unhealthy' : 'Unreachable';
+  return el('span', { className: 'unhealthy', title: health.problems.join('\n') }, label);
+}
+
+async function renderEnvironments() {
+  await loadProjects();
+  state.environments = (await api('GET', '/api/admin/environments')) || [];
+
+  fillTable('environments', state.environments.map((env) => {
+    const path = env.project + '/' + env.name;
+    const sync = el('button', { type: 'button', className: 'small' }, 'Sync keys');
+    sync.addEventListener('click', () => syncKeys(path));
+
+    const check = el('button', { type: 'button', className: 'small' }, 'Check');
+    check.addEventListener('click', () => run(async () => {
+      const health = await api('POST', '/api/admin/environments/' + encodeURIComponent(env.project) + '/' + encodeURIComponent(env.name) + '/check');
+      await renderEnvironments();
+      if (!health.healthy) throw new Error(path + ': ' + health.problems.join('; '));
+    }, path + ' is healthy'));
+
+    const remove = el('button', { type: 'button', className: 'danger small' }, 'Remove');
+    remove.addEventListener('click', () => {
+      if (!confirmed('Remove environment ' + path + '?')) return;
+      run(async () => {
+        await api('DELETE', '/api/admin/environments/' + encodeURIComponent(env.project) + '/' + encodeURIComponent(env.name));
+        await renderEnvironments();
+      }, 'Removed ' + path);
+    });
+
+    return [env.project, env.name, env.host + ':' + env.port, env.deploy_user, healthCell(env.health),
+      formatTime(env.created_at), el('span', {}, check, ' ', sync, ' ', remove)];
+  }), 7);
+
+  const select = $('#environment-form select[name=project]');
+  select.replaceChildren(...state.projects.map((p) => el('option', { value: p.name }, p.name)));
+}
+
+function onAddEnvironment(event) {
+  event.preventDefault();
+  const form = event.target;
+  const data = new FormData(form);
+  const request = {
+    project: data.get('project'),
+    name: data.get('name'),
+    host: data.get('host'),
+    port: Number(data.get('port')) || 22,
+    deploy_user: data.get('deploy_user'),
+    deploy_key: data.get('deploy_key'),
+    principals: data.get('principals').split(',').map((p) => p.trim()).filter((p) => p),
+  };
+
+  run(async () => {
+    await api('POST', '/api/admin/environments', request);
+    form.reset();
+    await renderEnvironments();
+  }, 'Added environment ' + request.project + '/' + request.name);
+}
+
+// Audit log
+
+async function renderAudit() {
+  const data = new FormData($('#audit-form'));
+  const query = new URLSearchParams();
+  if (data.get('user')) query.set('user', data.get('user'));
+  if (data.get('action')) query.set('action', data.get('action').toUpperCase());
+  if (data.get('from')) query.set('from', data.get('from') + 'T00:00:00Z');
+  if (data.get('to')) query.set('to', data.get('to') + 'T23:59:59Z');
+
+  const entries = (await api('GET', '/api/admin/audit?' + query.toString())) || [];
+  fillTable('audit', entries.map((entry) => [formatTime(entry.timestamp), entry.user_name || '',
+    entry.action, entry.details || '', entry.ip_address || '']), 5);
+}
+
+// Navigation
+
+const renderers = {
+  users: renderUsers,
+  projects: renderProjects,
+  environments: renderEnvironments,
+  audit: renderAudit,
+};
+
+function currentView() {
+  const view = window.location.hash.slice(1);
+  return views.includes(view) ? view : 'users';
+}
+
+function route() {
+  if (!sessionStorage.getItem(tokenKey)) {
+    $('#login').hidden = false;
+    $('#nav').hidden = true;
+    for (const view of views) $('#' + view).hidden = true;
+    return;
+  }
+
+  const view = currentView();
+  $('#login').hidden = true;
+  $('#nav').hidden = false;
+  for (const name of views) {
+    $('#' + name).hidden = name !== view;
+    $('#nav a[href="#' + name + '"]').classList.toggle('active', name === view);
+  }
+  showMessage('', false);
+  run(renderers[view]);
+}
+
+function signOut() {
+  sessionStorage.removeItem(tokenKey);
+  route();
+}
+
+function onLogin(event) {
+  event.preventDefault();
+  const form = event.target;
+  sessionStorage.setItem(tokenKey, new FormData(form).get('token'));
+
+  run(async () => {
+    try {
+      const me = await api('GET', '/api/me');
+      if (me.role !== 'admin') {
+        throw new Error('An admin token is required');
+      }
+    } catch (err) {
+      sessionStorage.removeItem(tokenKey);
+      throw err;
+    }
+    form.reset();
+    route();
+  });
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+  $('#login-form').addEventListener('submit', onLogin);
+  $('#logout').addEventListener('click', signOut);
+  $('#invite-form').addEventListener('submit', onInvite);
+  $('#project-form').addEventListener('submit', onAddProject);
+  $('#environment-form').addEventListener('submit', onAddEnvironment);
+  $('#audit-form').addEventListener('submit', (event) => {
+    event.preventDefault();
+    run(renderAudit);
+  });
+  $('#sync-all').addEventListener('click', () => {
+    if (confirmed('Sync the SSH keys of all users to every environment?')) syncKeys('');
+  });
+  window.addEventListener('hashchange', route);
+  route();
+});
@@ -0,0 +1,175 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook payload formats
+const (
+	WebhookSlack = "slack"
+	WebhookTeams = "teams"
+	WebhookJSON  = "json"
+)
+
+// NotificationEvents are the events webhooks can subscribe to
+var NotificationEvents = []NotificationEvent{
+	NotifyUserInvited, NotifyUserJoined, NotifyUserRemoved, NotifyAccessGranted,
+	NotifyAccessRevoked, NotifySecurityAlert, NotifyKeySyncFailed, NotifyEnvUnhealthy,
+}
+
+// WebhookMessage is an event posted to the webhooks. The json format posts
+// it as is.
+type WebhookMessage struct {
+	Event     NotificationEvent `json:"event"`
+	Title     string            `json:"title"`
+	Text      string            `json:"text"`
+	User      string            `json:"user,omitempty"`
+	Server    string            `json:"server,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// GetFormat returns the payload format of the webhook, detected from the URL
+// when none is set
+func (w WebhookConfig) GetFormat() string {
+	if w.Format != "" {
+		return w.Format
+	}
+	host := ""
+	if u, err := url.Parse(w.URL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	switch {
+	case host == "hooks.slack.com":
+		return WebhookSlack
+	case strings.HasSuffix(host, ".webhook.office.com"), strings.HasSuffix(host, ".logic.azure.com"),
+		strings.HasSuffix(host, ".powerplatform.com"):
+		return WebhookTeams
+	default:
+		return WebhookJSON
+	}
+}
+
+// Wants reports whether the webhook subscribed to event
+func (w WebhookConfig) Wants(event NotificationEvent) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// Validate checks the URL, format and events of the webhook
+func (w WebhookConfig) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", w.URL)
+	}
+	if !slices.Contains([]string{WebhookSlack, WebhookTeams, WebhookJSON}, w.GetFormat()) {
+		return fmt.Errorf("invalid webhook format %q (use slack, teams or json)", w.Format)
+	}
+	for _, e := range w.Events {
+		if !slices.Contains(NotificationEvents, e) {
+			return fmt.Errorf("unknown webhook event %q", e)
+		}
+	}
+	return nil
+}
+
+// redactedURL returns the webhook URL without the path, which holds the
+// secret of most webhooks
+func (w WebhookConfig) redactedURL() string {
+	if u, err := url.Parse(w.URL); err == nil {
+		return u.Scheme + "://" + u.Host + "/…"
+	}
+	return "webhook"
+}
+
+// webhookPayload returns the body posted to a webhook of format
+func webhookPayload(format string, msg WebhookMessage) interface{} {
+	switch format {
+	case WebhookSlack:
+		return map[string]string{"text": fmt.Sprintf("*%s*\n%s", msg.Title, msg.Text)}
+	case WebhookTeams:
+		// An Adaptive Card, as Teams workflows expect
+		return map[string]interface{}{
+			"type": "message",
+			"attachments": []map[string]interface{}{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body": []map[string]interface{}{
+						{"type": "TextBlock", "text": msg.Title, "weight": "Bolder", "size": "Medium", "wrap": true},
+						{"type": "TextBlock", "text": msg.Text, "wrap": true},
+					},
+				},
+			}},
+		}
+	default:
+		return msg
+	}
+}
+
+// HasWebhooks reports whether webhooks are configured
+func (n *Notifier) HasWebhooks() bool {
+	return len(n.webhooks) > 0
+}
+
+// SetWebhooks sets the webhooks events are posted to
+func (n *Notifier) SetWebhooks(webhooks []WebhookConfig) {
+	n.webhooks = webhooks
+}
+
+// SendWebhooks posts msg to the webhooks subscribed to its event
+func (n *Notifier) SendWebhooks(msg WebhookMessage) error {
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now().UTC()
+	}
+
+	var errs []error
+	for _, w := range n.webhooks {
+		if !w.Wants(msg.Event) {
+			continue
+		}
+		if err := n.postWebhook(w, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.redactedURL(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postWebhook posts msg to one webhook in its format
+func (n *Notifier) postWebhook(w WebhookConfig, msg WebhookMessage) error {
+	body, err := json.Marshal(webhookPayload(w.GetFormat(), msg))
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	resp, err := n.httpClient.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// Without the URL url.Error adds
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
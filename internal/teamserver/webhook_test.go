@@ -0,0 +1,124 @@
+/**
+ * Created by Qoliber
+ *
+ * @category    Qoliber
+ * @package     MageBox
+ * @author      Jakub Winkler <jwinkler@qoliber.com>
+ */
+
+package teamserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWebhookConfigGetFormat(t *testing.T) {
+	tests := []struct {
+		webhook WebhookConfig
+		want    string
+	}{
+		{WebhookConfig{URL: "https://hooks.slack.com/services/T0/B0/secret"}, WebhookSlack},
+		{WebhookConfig{URL: "https://acme.webhook.office.com/webhookb2/secret"}, WebhookTeams},
+		{WebhookConfig{URL: "https://prod-01.westeurope.logic.azure.com/workflows/secret"}, WebhookTeams},
+		{WebhookConfig{URL: "https://ops.example.com/hooks/magebox"}, WebhookJSON},
+		{WebhookConfig{URL: "https://hooks.slack.com/services/T0/B0/secret", Format: WebhookJSON}, WebhookJSON},
+	}
+	for _, tt := range tests {
+		if got := tt.webhook.GetFormat(); got != tt.want {
+			t.Errorf("GetFormat(%s) = %s, want %s", tt.webhook.URL, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookConfigValidate(t *testing.T) {
+	tests := []struct {
+		webhook WebhookConfig
+		valid   bool
+	}{
+		{WebhookConfig{URL: "https://hooks.slack.com/services/x"}, true},
+		{WebhookConfig{URL: "http://localhost:8080/hook", Events: []NotificationEvent{NotifySecurityAlert}}, true},
+		{WebhookConfig{URL: "ftp://example.com/hook"}, false},
+		{WebhookConfig{URL: "hooks.slack.com/services/x"}, false},
+		{WebhookConfig{URL: "https://example.com/hook", Format: "discord"}, false},
+		{WebhookConfig{URL: "https://example.com/hook", Events: []NotificationEvent{"USER_LOGIN"}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.webhook.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.webhook, err, tt.valid)
+		}
+	}
+}
+
+func TestWebhookConfigWants(t *testing.T) {
+	all := WebhookConfig{}
+	if !all.Wants(NotifyUserJoined) {
+		t.Error("A webhook without events should want all events")
+	}
+	alerts := WebhookConfig{Events: []NotificationEvent{NotifySecurityAlert}}
+	if alerts.Wants(NotifyUserJoined) || !alerts.Wants(NotifySecurityAlert) {
+		t.Error("A webhook with events should only want those")
+	}
+}
+
+func TestSendWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]map[string]interface{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("Invalid JSON posted to %s: %v", r.URL.Path, err)
+		}
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(SMTPConfig{})
+	n.SetWebhooks([]WebhookConfig{
+		{URL: srv.URL + "/slack", Format: WebhookSlack},
+		{URL: srv.URL + "/teams", Format: WebhookTeams},
+		{URL: srv.URL + "/json"},
+		{URL: srv.URL + "/alerts", Events: []NotificationEvent{NotifySecurityAlert}},
+	})
+	if !n.HasWebhooks() {
+		t.Fatal("HasWebhooks() = false")
+	}
+
+	err := n.SendWebhooks(WebhookMessage{Event: NotifyUserJoined, Title: "User joined", Text: "alice joined", User: "alice"})
+	if err != nil {
+		t.Fatalf("SendWebhooks failed: %v", err)
+	}
+
+	if text, _ := bodies["/slack"]["text"].(string); text != "*User joined*\nalice joined" {
+		t.Errorf("Slack text = %q", text)
+	}
+	if bodies["/teams"]["type"] != "message" || bodies["/teams"]["attachments"] == nil {
+		t.Errorf("Teams payload = %v, want an Adaptive Card message", bodies["/teams"])
+	}
+	if bodies["/json"]["event"] != "USER_JOINED" || bodies["/json"]["user"] != "alice" || bodies["/json"]["timestamp"] == nil {
+		t.Errorf("JSON payload = %v", bodies["/json"])
+	}
+	if _, ok := bodies["/alerts"]; ok {
+		t.Error("A webhook was posted an event it didn't subscribe to")
+	}
+
+	n.SetWebhooks([]WebhookConfig{{URL: srv.URL + "/fail/secret-token"}})
+	err = n.SendWebhooks(WebhookMessage{Event: NotifySecurityAlert, Title: "Security alert"})
+	if err == nil {
+		t.Fatal("SendWebhooks should fail on a 403")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Error %q leaks the webhook URL", err)
+	}
+}
@@ -6,6 +6,7 @@ package templates
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // ComposerJSON represents a composer.json structure
@@ -297,6 +298,49 @@ func GetLatestMageOSVersion() string {
 
 // GenerateMagentoComposerJSON generates a composer.json for Magento
 func GenerateMagentoComposerJSON(projectName, version string) ([]byte, error) {
+	return json.MarshalIndent(magentoComposer(projectName, version), "", "    ")
+}
+
+// b2bVersions maps an Adobe Commerce release line to the B2B extension
+// releases built for it
+var b2bVersions = map[string]string{
+	"2.4.8": "~1.5.0",
+	"2.4.7": "~1.4.0",
+	"2.4.6": "~1.3.4",
+}
+
+// B2BConstraint returns the magento/extension-b2b constraint for an Adobe
+// Commerce version, e.g. "~1.5.0" for 2.4.8-p2
+func B2BConstraint(version string) (string, error) {
+	line, _, _ := strings.Cut(version, "-")
+	constraint, ok := b2bVersions[line]
+	if !ok {
+		return "", fmt.Errorf("no B2B release is known for Adobe Commerce %s", version)
+	}
+	return constraint, nil
+}
+
+// GenerateCommerceComposerJSON generates a composer.json for Adobe Commerce,
+// optionally with the B2B extension
+func GenerateCommerceComposerJSON(projectName, version string, b2b bool) ([]byte, error) {
+	composer := magentoComposer(projectName, version)
+	composer.Description = "Adobe Commerce project created with MageBox"
+	composer.License = []string{"proprietary"}
+	composer.Require["magento/product-enterprise-edition"] = composer.Require["magento/product-community-edition"]
+	delete(composer.Require, "magento/product-community-edition")
+	if b2b {
+		constraint, err := B2BConstraint(version)
+		if err != nil {
+			return nil, err
+		}
+		composer.Require["magento/extension-b2b"] = constraint
+	}
+
+	return json.MarshalIndent(composer, "", "    ")
+}
+
+// magentoComposer builds the composer.json of Magento Open Source
+func magentoComposer(projectName, version string) ComposerJSON {
 	versions := GetMagentoVersions()
 	v, ok := versions[version]
 	if !ok {
@@ -365,7 +409,7 @@ func GenerateMagentoComposerJSON(projectName, version string) ([]byte, error) {
 		},
 	}
 
-	return json.MarshalIndent(composer, "", "    ")
+	return composer
 }
 
 // GenerateMageOSComposerJSON generates a composer.json for MageOS
@@ -0,0 +1,27 @@
+package templates
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerateCommerceComposerJSONPinsB2B(t *testing.T) {
+	data, err := GenerateCommerceComposerJSON("mystore", "2.4.8-p2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var composer ComposerJSON
+	if err := json.Unmarshal(data, &composer); err != nil {
+		t.Fatal(err)
+	}
+	if got := composer.Require["magento/extension-b2b"]; got != "~1.5.0" {
+		t.Errorf("magento/extension-b2b = %v, want ~1.5.0", got)
+	}
+
+	if _, err := GenerateCommerceComposerJSON("mystore", "2.3.7", true); err == nil {
+		t.Error("expected an error for a Commerce version without a known B2B release")
+	}
+	if _, err := GenerateCommerceComposerJSON("mystore", "2.3.7", false); err != nil {
+		t.Errorf("Commerce without B2B should not need a B2B release: %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"qoliber/magebox/internal/config"
+)
+
+// A project template seeds a new project. Its directory may hold:
+//
+//	magebox.yaml   merged into the project's .magebox.yaml
+//	nginx/         snippets copied to .magebox/nginx, included in the vhost
+//	bootstrap/     scripts copied to .magebox/bootstrap, run in name order
+//	               by 'magebox run bootstrap'
+//	files/         files copied into the project
+//
+// "{{name}}" and "{{domain}}" in magebox.yaml are replaced with the project
+// name and its first domain.
+
+//go:embed all:project
+var builtinProjectTemplates embed.FS
+
+// BuiltinProjectTemplates describes the templates shipped with MageBox
+var BuiltinProjectTemplates = map[string]string{
+	"hyva":     "Hyvä theme with Tailwind build commands",
+	"b2b":      "Adobe Commerce B2B with RabbitMQ and queue consumers",
+	"headless": "GraphQL backend for a headless storefront, with CORS",
+}
+
+// BootstrapCommand is the custom command that runs a template's bootstrap scripts
+const BootstrapCommand = "bootstrap"
+
+// ProjectTemplate is a template loaded from MageBox, a directory or a git repository
+type ProjectTemplate struct {
+	Name    string
+	fsys    fs.FS
+	tempDir string // Clone of a git template, removed by Close
+}
+
+// TemplateVars are the values of the placeholders in a template
+type TemplateVars struct {
+	Name   string
+	Domain string
+}
+
+// TemplateResult lists what applying a template changed in a project
+type TemplateResult struct {
+	Files     []string // Files created, relative to the project
+	Skipped   []string // Files the project already had
+	Bootstrap []string // Bootstrap scripts, in the order they run
+}
+
+// BuiltinProjectTemplateNames returns the names of the built-in templates, sorted
+func BuiltinProjectTemplateNames() []string {
+	names := make([]string, 0, len(BuiltinProjectTemplates))
+	for name := range BuiltinProjectTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProjectTemplate finds a template by reference: a git URL (with an
+// optional #branch), a directory, a template in ~/.magebox/templates or a
+// built-in template. Close the template when done with it.
+func LoadProjectTemplate(ref, homeDir string) (*ProjectTemplate, error) {
+	if isGitURL(ref) {
+		return cloneProjectTemplate(ref)
+	}
+
+	if info, err := os.Stat(ref); err == nil && info.IsDir() {
+		return &ProjectTemplate{Name: filepath.Base(filepath.Clean(ref)), fsys: os.DirFS(ref)}, nil
+	}
+
+	if homeDir != "" && !strings.ContainsAny(ref, `/\`) {
+		dir := filepath.Join(homeDir, ".magebox", "templates", ref)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return &ProjectTemplate{Name: ref, fsys: os.DirFS(dir)}, nil
+		}
+	}
+
+	if _, ok := BuiltinProjectTemplates[ref]; ok {
+		sub, err := fs.Sub(builtinProjectTemplates, path.Join("project", ref))
+		if err != nil {
+			return nil, err
+		}
+		return &ProjectTemplate{Name: ref, fsys: sub}, nil
+	}
+
+	return nil, fmt.Errorf("unknown template %q (built-in: %s)", ref, strings.Join(BuiltinProjectTemplateNames(), ", "))
+}
+
+// isGitURL reports whether a template reference points to a git repository
+func isGitURL(ref string) bool {
+	ref, _, _ = strings.Cut(ref, "#")
+	return strings.Contains(ref, "://") || strings.HasPrefix(ref, "git@") || strings.HasSuffix(ref, ".git")
+}
+
+// cloneProjectTemplate clones a template repository into a temporary directory
+func cloneProjectTemplate(ref string) (*ProjectTemplate, error) {
+	url, branch, _ := strings.Cut(ref, "#")
+
+	tempDir, err := os.MkdirTemp("", "magebox-template-")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, "--", url, tempDir)
+
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.RemoveAll(tempDir)
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to clone template %s: %s", url, msg)
+		}
+		return nil, fmt.Errorf("failed to clone template %s: %w", url, err)
+	}
+
+	name := strings.TrimSuffix(path.Base(strings.TrimSuffix(url, "/")), ".git")
+	return &ProjectTemplate{Name: name, fsys: os.DirFS(tempDir), tempDir: tempDir}, nil
+}
+
+// Close removes the clone of a git template
+func (t *ProjectTemplate) Close() error {
+	if t.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(t.tempDir)
+}
+
+// Apply merges the template into the project's .magebox.yaml, which must
+// exist, and copies its snippets, scripts and files. Files the project
+// already has are left alone.
+func (t *ProjectTemplate) Apply(projectDir string, vars TemplateVars) (*TemplateResult, error) {
+	result := &TemplateResult{}
+
+	copies := []struct {
+		dir, dest string
+		mode      os.FileMode
+	}{
+		{"nginx", filepath.Join(".magebox", "nginx"), 0644},
+		{"bootstrap", filepath.Join(".magebox", "bootstrap"), 0755},
+		{"files", "", 0644},
+	}
+	for _, c := range copies {
+		if err := t.copyDir(c.dir, projectDir, c.dest, c.mode, result); err != nil {
+			return nil, err
+		}
+	}
+
+	overlay, err := fs.ReadFile(t.fsys, "magebox.yaml")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	overlay = []byte(strings.NewReplacer("{{name}}", vars.Name, "{{domain}}", vars.Domain).Replace(string(overlay)))
+
+	if len(result.Bootstrap) > 0 {
+		bootstrap := fmt.Sprintf("commands:\n  %s:\n    description: %q\n    run: %q\n",
+			BootstrapCommand,
+			fmt.Sprintf("Run the bootstrap scripts of the %s template", t.Name),
+			`for f in .magebox/bootstrap/*; do bash "$f" || exit 1; done`)
+		// The template's own bootstrap command wins
+		if overlay, err = mergeYAML([]byte(bootstrap), overlay); err != nil {
+			return nil, fmt.Errorf("template magebox.yaml: %w", err)
+		}
+	}
+
+	if len(bytes.TrimSpace(overlay)) == 0 {
+		return result, nil
+	}
+
+	configPath := filepath.Join(projectDir, config.ConfigFileName)
+	base, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeYAML(base, overlay)
+	if err != nil {
+		return nil, fmt.Errorf("template magebox.yaml: %w", err)
+	}
+	if err := os.WriteFile(configPath, merged, 0644); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// copyDir copies a directory of the template into the project
+func (t *ProjectTemplate) copyDir(dir, projectDir, dest string, mode os.FileMode, result *TemplateResult) error {
+	if _, err := fs.Stat(t.fsys, dir); err != nil {
+		return nil
+	}
+
+	return fs.WalkDir(t.fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, filepath.FromSlash(name))
+		target := filepath.Join(dest, rel)
+		if dir == "bootstrap" {
+			result.Bootstrap = append(result.Bootstrap, target)
+		}
+
+		abs := filepath.Join(projectDir, target)
+		if _, err := os.Stat(abs); err == nil {
+			result.Skipped = append(result.Skipped, target)
+			return nil
+		}
+
+		data, err := fs.ReadFile(t.fsys, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(abs, data, mode); err != nil {
+			return err
+		}
+		result.Files = append(result.Files, target)
+		return nil
+	})
+}
+
+// mergeYAML merges the overlay document into the base document: mappings
+// are merged key by key, any other value of the overlay replaces the base's.
+// Comments and key order of the base are kept.
+func mergeYAML(base, overlay []byte) ([]byte, error) {
+	var baseDoc, overlayDoc yaml.Node
+	if err := yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(overlay, &overlayDoc); err != nil {
+		return nil, err
+	}
+	if len(overlayDoc.Content) == 0 {
+		return base, nil
+	}
+	if len(baseDoc.Content) == 0 {
+		return overlay, nil
+	}
+	if baseDoc.Content[0].Kind != yaml.MappingNode || overlayDoc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping at the top level")
+	}
+	mergeMapping(baseDoc.Content[0], overlayDoc.Content[0])
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&baseDoc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeMapping merges the keys of src into the mapping node dst
+func mergeMapping(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		found := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value != key.Value {
+				continue
+			}
+			found = true
+			if dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeMapping(dst.Content[j+1], value)
+			} else {
+				dst.Content[j+1] = value
+			}
+			break
+		}
+		if !found {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+# Installs the B2B extension. Needs Adobe Commerce and its Composer keys.
+set -e
+
+composer require magento/extension-b2b
+php bin/magento setup:upgrade
@@ -0,0 +1,9 @@
+#!/usr/bin/env bash
+# Installs CORS support for GraphQL and allows the frontend dev server.
+# Set STOREFRONT_ORIGIN for an app that doesn't run on localhost:3000.
+set -e
+
+composer require graycore/magento2-cors
+php bin/magento setup:upgrade
+php bin/magento config:set web/graphql/cors_allowed_origins "${STOREFRONT_ORIGIN:-http://localhost:3000}"
+php bin/magento config:set web/graphql/cors_allowed_headers "Content-Type,Authorization,Store,X-Magento-Cache-Id"
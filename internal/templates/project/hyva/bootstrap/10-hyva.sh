@@ -0,0 +1,9 @@
+#!/usr/bin/env bash
+# Installs the Hyvä default theme. Needs the Hyvä Composer repository, which
+# 'magebox new --hyva' configures from your Hyvä license key.
+set -e
+
+composer require hyva-themes/magento2-default-theme
+php bin/magento setup:upgrade
+
+echo "Select Hyva/default in Admin > Content > Design > Configuration"
@@ -0,0 +1,217 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qoliber/magebox/internal/config"
+)
+
+const baseConfig = `name: mystore
+domains:
+  - host: mystore.test
+php: "8.3"
+# Shared services
+services:
+  mysql: "8.0"
+commands:
+  cache:
+    description: "Flush all caches"
+    run: "php bin/magento cache:flush"
+`
+
+func writeProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, config.ConfigFileName), []byte(baseConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func loadProject(t *testing.T, dir string) *config.Config {
+	t.Helper()
+	cfg, err := config.NewLoader(dir).Load()
+	if err != nil {
+		t.Fatalf("loading the project config: %v", err)
+	}
+	return cfg
+}
+
+func TestMergeYAML(t *testing.T) {
+	overlay := `services:
+  rabbitmq: true
+  mysql: "8.4"
+env:
+  APP_MODE: developer
+`
+	merged, err := mergeYAML([]byte(baseConfig), []byte(overlay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(merged)
+	for _, want := range []string{`mysql: "8.4"`, "rabbitmq: true", "APP_MODE: developer", "# Shared services", "cache:"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("merged document misses %q:\n%s", want, content)
+		}
+	}
+	if strings.Index(content, "name:") > strings.Index(content, "env:") {
+		t.Errorf("merged document reordered the base keys:\n%s", content)
+	}
+
+	if _, err := mergeYAML([]byte(baseConfig), []byte("- a list\n")); err == nil {
+		t.Error("expected an error for an overlay that isn't a mapping")
+	}
+}
+
+func TestLoadProjectTemplate(t *testing.T) {
+	home := t.TempDir()
+	for _, name := range BuiltinProjectTemplateNames() {
+		tpl, err := LoadProjectTemplate(name, home)
+		if err != nil {
+			t.Fatalf("LoadProjectTemplate(%q) = %v", name, err)
+		}
+		if tpl.Name != name {
+			t.Errorf("Name = %q, want %q", tpl.Name, name)
+		}
+	}
+
+	// A user template shadows the built-in one of the same name
+	userDir := filepath.Join(home, ".magebox", "templates", "hyva")
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(userDir, "magebox.yaml"), []byte("env:\n  AGENCY: \"1\"\n"), 0644)
+	tpl, err := LoadProjectTemplate("hyva", home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeProject(t)
+	if _, err := tpl.Apply(dir, TemplateVars{Name: "mystore", Domain: "mystore.test"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := loadProject(t, dir); cfg.Env["AGENCY"] != "1" {
+		t.Errorf("env = %v, want the user template applied", cfg.Env)
+	}
+
+	if _, err := LoadProjectTemplate("nope", home); err == nil || !strings.Contains(err.Error(), "hyva") {
+		t.Errorf("LoadProjectTemplate(nope) = %v, want an error listing the built-in templates", err)
+	}
+}
+
+func TestIsGitURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/acme/magebox-template":   true,
+		"git@github.com:acme/magebox-template.git":   true,
+		"ssh://git@example.com/template#v2":          true,
+		"../templates/acme.git":                      true,
+		"hyva":                                       false,
+		"./templates/acme":                           false,
+		"/home/dev/templates/acme#not-a-branch-here": false,
+	}
+	for ref, want := range tests {
+		if got := isGitURL(ref); got != want {
+			t.Errorf("isGitURL(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestProjectTemplateApply(t *testing.T) {
+	tplDir := t.TempDir()
+	files := map[string]string{
+		"magebox.yaml":          "php_ini:\n  memory_limit: 4G\nenv:\n  BASE_URL: \"https://{{domain}}/\"\n  PROJECT: \"{{name}}\"\n",
+		"nginx/headers.conf":    "add_header X-Agency acme;\n",
+		"bootstrap/20-theme.sh": "echo theme\n",
+		"bootstrap/10-deps.sh":  "echo deps\n",
+		"files/.editorconfig":   "root = true\n",
+		"files/app/etc/README":  "config\n",
+		"files/composer.json":   "{}\n",
+		"README.md":             "not copied\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tplDir, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := writeProject(t)
+	_ = os.WriteFile(filepath.Join(dir, "composer.json"), []byte(`{"name": "acme/mystore"}`), 0644)
+
+	tpl, err := LoadProjectTemplate(tplDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := tpl.Apply(dir, TemplateVars{Name: "mystore", Domain: "mystore.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBootstrap := []string{filepath.Join(".magebox", "bootstrap", "10-deps.sh"), filepath.Join(".magebox", "bootstrap", "20-theme.sh")}
+	if strings.Join(result.Bootstrap, ",") != strings.Join(wantBootstrap, ",") {
+		t.Errorf("Bootstrap = %v, want %v", result.Bootstrap, wantBootstrap)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "composer.json" {
+		t.Errorf("Skipped = %v, want composer.json", result.Skipped)
+	}
+	for _, name := range []string{".magebox/nginx/headers.conf", ".editorconfig", "app/etc/README"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s not copied: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); err == nil {
+		t.Error("README.md outside files/ was copied")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "composer.json")); !strings.Contains(string(data), "acme/mystore") {
+		t.Error("the project's composer.json was overwritten")
+	}
+	if info, err := os.Stat(filepath.Join(dir, wantBootstrap[0])); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("bootstrap script not executable: %v", err)
+	}
+
+	cfg := loadProject(t, dir)
+	if cfg.Env["BASE_URL"] != "https://mystore.test/" || cfg.Env["PROJECT"] != "mystore" {
+		t.Errorf("env = %v, want the placeholders replaced", cfg.Env)
+	}
+	if cfg.PHPINI["memory_limit"] != "4G" || cfg.Services.MySQL == nil {
+		t.Errorf("php_ini = %v, mysql = %v", cfg.PHPINI, cfg.Services.MySQL)
+	}
+	if _, ok := cfg.Commands["cache"]; !ok {
+		t.Error("the project's own commands were lost")
+	}
+	if !strings.Contains(cfg.Commands[BootstrapCommand].Run, ".magebox/bootstrap/") {
+		t.Errorf("bootstrap command = %+v", cfg.Commands[BootstrapCommand])
+	}
+}
+
+func TestBuiltinProjectTemplatesApply(t *testing.T) {
+	for _, name := range BuiltinProjectTemplateNames() {
+		t.Run(name, func(t *testing.T) {
+			tpl, err := LoadProjectTemplate(name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := writeProject(t)
+			result, err := tpl.Apply(dir, TemplateVars{Name: "mystore", Domain: "mystore.test"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Bootstrap) == 0 {
+				t.Error("no bootstrap scripts")
+			}
+
+			data, _ := os.ReadFile(filepath.Join(dir, config.ConfigFileName))
+			if strings.Contains(string(data), "{{") {
+				t.Errorf("placeholders left in the config:\n%s", data)
+			}
+			issues, err := config.ValidateYAML(data)
+			if err != nil || len(issues) > 0 {
+				t.Errorf("ValidateYAML() = %v, %v", issues, err)
+			}
+			loadProject(t, dir)
+		})
+	}
+}
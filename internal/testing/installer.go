@@ -37,6 +37,11 @@ func (i *Installer) InstallPHPMD() error {
 	return i.installPackages("phpmd", ComposerPackages["phpmd"])
 }
 
+// InstallPHPCSFixer installs PHP-CS-Fixer via composer
+func (i *Installer) InstallPHPCSFixer() error {
+	return i.installPackages("php-cs-fixer", ComposerPackages["php-cs-fixer"])
+}
+
 // InstallAll installs all testing tools
 func (i *Installer) InstallAll() error {
 	allPackages := []string{}
@@ -62,6 +67,9 @@ func (i *Installer) InstallSelected(opts SetupOptions) error {
 	if opts.InstallPHPMD {
 		packages = append(packages, ComposerPackages["phpmd"]...)
 	}
+	if opts.InstallPHPCSFixer {
+		packages = append(packages, ComposerPackages["php-cs-fixer"]...)
+	}
 
 	if len(packages) == 0 {
 		return fmt.Errorf("no packages selected for installation")
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"qoliber/magebox/internal/platform"
 )
@@ -16,6 +17,7 @@ type Manager struct {
 	platform    *platform.Platform
 	phpVersion  string
 	projectPath string
+	phpWarning  sync.Once
 }
 
 // NewManager creates a new testing manager
@@ -35,6 +37,7 @@ func (m *Manager) GetStatus() *AllToolsStatus {
 		PHPStan:     m.getPHPStanStatus(),
 		PHPCS:       m.getPHPCSStatus(),
 		PHPMD:       m.getPHPMDStatus(),
+		PHPCSFixer:  m.getPHPCSFixerStatus(),
 	}
 }
 
@@ -168,6 +171,32 @@ func (m *Manager) getPHPMDStatus() ToolStatus {
 	return status
 }
 
+// getPHPCSFixerStatus checks PHP-CS-Fixer installation and configuration status
+func (m *Manager) getPHPCSFixerStatus() ToolStatus {
+	status := ToolStatus{Name: "PHP-CS-Fixer"}
+
+	// Check if installed via composer
+	if m.isComposerPackageInstalled("friendsofphp/php-cs-fixer") {
+		status.Installed = true
+		status.Version = m.getComposerPackageVersion("friendsofphp/php-cs-fixer")
+	}
+
+	// Check for .php-cs-fixer.php or .php-cs-fixer.dist.php
+	configPaths := []string{
+		filepath.Join(m.projectPath, ".php-cs-fixer.php"),
+		filepath.Join(m.projectPath, ".php-cs-fixer.dist.php"),
+	}
+	for _, p := range configPaths {
+		if _, err := os.Stat(p); err == nil {
+			status.Configured = true
+			status.ConfigPath = p
+			break
+		}
+	}
+
+	return status
+}
+
 // isComposerPackageInstalled checks if a composer package is installed
 func (m *Manager) isComposerPackageInstalled(packageName string) bool {
 	composerLock := filepath.Join(m.projectPath, "composer.lock")
@@ -230,9 +259,31 @@ func (m *Manager) getComposerPackageVersion(packageName string) string {
 	return ""
 }
 
-// GetPHPBinary returns the PHP binary path for the configured version
+// GetPHPBinary returns the PHP binary path for the configured version, or
+// the php on the PATH when that version isn't installed where MageBox puts
+// it, as in CI containers. A warning is printed once when the php on the
+// PATH is another version.
 func (m *Manager) GetPHPBinary() string {
-	return m.platform.PHPBinary(m.phpVersion)
+	phpBin := m.platform.PHPBinary(m.phpVersion)
+	if _, err := os.Stat(phpBin); err == nil {
+		return phpBin
+	}
+	path, err := exec.LookPath("php")
+	if err != nil {
+		return phpBin
+	}
+
+	m.phpWarning.Do(func() {
+		out, err := exec.Command(path, "-r", "echo PHP_MAJOR_VERSION.'.'.PHP_MINOR_VERSION;").Output()
+		version := strings.TrimSpace(string(out))
+		if err != nil {
+			version = "unknown"
+		}
+		if version != m.phpVersion {
+			fmt.Fprintf(os.Stderr, "Warning: PHP %s is not installed, using %s (PHP %s) from the PATH\n", m.phpVersion, path, version)
+		}
+	})
+	return path
 }
 
 // GetComposerBinary returns the composer binary path
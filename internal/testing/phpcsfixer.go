@@ -0,0 +1,142 @@
+package testing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"qoliber/magebox/internal/config"
+)
+
+// PHPCSFixerRunner handles PHP-CS-Fixer code style checking
+type PHPCSFixerRunner struct {
+	manager *Manager
+	config  *config.PHPCSFixerTestConfig
+}
+
+// NewPHPCSFixerRunner creates a new PHP-CS-Fixer runner
+func NewPHPCSFixerRunner(m *Manager, cfg *config.PHPCSFixerTestConfig) *PHPCSFixerRunner {
+	return &PHPCSFixerRunner{
+		manager: m,
+		config:  cfg,
+	}
+}
+
+// Run executes PHP-CS-Fixer. Without fix it only reports the files it would
+// change, with a diff, and fails when there are any.
+func (r *PHPCSFixerRunner) Run(paths []string, fix bool) error {
+	if !r.manager.isComposerPackageInstalled("friendsofphp/php-cs-fixer") {
+		return fmt.Errorf("PHP-CS-Fixer is not installed. Run: magebox lint init --install")
+	}
+
+	args := r.buildArgs(paths, fix)
+	return r.manager.StreamCommand("PHP-CS-Fixer", args...)
+}
+
+// buildArgs builds the PHP-CS-Fixer command arguments
+func (r *PHPCSFixerRunner) buildArgs(paths []string, fix bool) []string {
+	phpBin := r.manager.GetPHPBinary()
+	fixerBin := filepath.Join(r.manager.GetVendorBinPath(), "php-cs-fixer")
+
+	args := []string{phpBin, fixerBin, "fix"}
+
+	// The config file lists the paths itself; paths given on the command
+	// line are checked with its rules instead
+	configFile := r.getConfigFile()
+	if configFile != "" {
+		args = append(args, "--config="+configFile)
+	} else {
+		args = append(args, "--rules=@PSR12")
+	}
+
+	if !fix {
+		args = append(args, "--dry-run", "--diff")
+	}
+
+	if len(paths) > 0 || configFile == "" {
+		args = append(args, "--path-mode=intersection")
+		args = append(args, r.getPaths(paths)...)
+	}
+
+	return args
+}
+
+// getConfigFile returns the PHP-CS-Fixer config file path
+func (r *PHPCSFixerRunner) getConfigFile() string {
+	// Check for config from yaml
+	if r.config != nil && r.config.Config != "" {
+		configPath := filepath.Join(r.manager.projectPath, r.config.Config)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath
+		}
+	}
+
+	// Check for default config files
+	configPaths := []string{
+		filepath.Join(r.manager.projectPath, ".php-cs-fixer.php"),
+		filepath.Join(r.manager.projectPath, ".php-cs-fixer.dist.php"),
+	}
+	for _, p := range configPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// getPaths returns the paths to check
+func (r *PHPCSFixerRunner) getPaths(cliPaths []string) []string {
+	// CLI paths take precedence
+	if len(cliPaths) > 0 {
+		return cliPaths
+	}
+
+	// Then config paths
+	if r.config != nil && len(r.config.Paths) > 0 {
+		return r.config.Paths
+	}
+
+	// Default paths
+	return DefaultPaths()
+}
+
+// GenerateConfig generates a basic .php-cs-fixer.dist.php configuration file
+func (r *PHPCSFixerRunner) GenerateConfig(paths []string) error {
+	configPath := filepath.Join(r.manager.projectPath, ".php-cs-fixer.dist.php")
+
+	if _, err := os.Stat(configPath); err == nil {
+		return fmt.Errorf(".php-cs-fixer.dist.php already exists")
+	}
+
+	if len(paths) == 0 {
+		paths = DefaultPaths()
+	}
+
+	content := `<?php
+/**
+ * PHP-CS-Fixer configuration, generated by MageBox
+ */
+$finder = PhpCsFixer\Finder::create()
+`
+	for _, p := range paths {
+		content += fmt.Sprintf("    ->in(__DIR__ . '/%s')\n", p)
+	}
+
+	content += `    ->name('*.php')
+    ->exclude(['vendor', 'generated']);
+
+return (new PhpCsFixer\Config())
+    ->setRiskyAllowed(false)
+    ->setRules([
+        '@PSR12' => true,
+        'array_syntax' => ['syntax' => 'short'],
+        'no_unused_imports' => true,
+        'ordered_imports' => true,
+        'single_quote' => true,
+    ])
+    ->setFinder($finder);
+`
+
+	return os.WriteFile(configPath, []byte(content), 0644)
+}
@@ -16,6 +16,7 @@ type AllToolsStatus struct {
 	PHPStan     ToolStatus
 	PHPCS       ToolStatus
 	PHPMD       ToolStatus
+	PHPCSFixer  ToolStatus
 }
 
 // ComposerPackages defines the composer packages for each tool
@@ -24,6 +25,8 @@ var ComposerPackages = map[string][]string{
 	"phpstan": {"phpstan/phpstan", "bitexpert/phpstan-magento"},
 	"phpcs":   {"squizlabs/php_codesniffer", "magento/magento-coding-standard"},
 	"phpmd":   {"phpmd/phpmd"},
+
+	"php-cs-fixer": {"friendsofphp/php-cs-fixer"},
 }
 
 // DefaultPaths returns the default paths to analyze
@@ -62,4 +65,6 @@ type SetupOptions struct {
 	InstallPHPStan bool
 	InstallPHPCS   bool
 	InstallPHPMD   bool
+
+	InstallPHPCSFixer bool
 }
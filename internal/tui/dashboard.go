@@ -0,0 +1,394 @@
+// Package tui implements the full-screen dashboard behind "magebox ui".
+//
+// The dashboard lists projects, the services of the selected project and the
+// tail of its Magento logs. Actions (start, stop, cache flush, reindex, ...)
+// run as regular MageBox or bin/magento processes with the terminal handed
+// over, so prompts like sudo keep working.
+//
+// The live status view behind "magebox status --watch" lives here too.
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// refreshInterval is how often services and logs of the selected project are reloaded
+const refreshInterval = 2 * time.Second
+
+// logLines is the number of log lines kept for the log pane
+const logLines = 200
+
+// logFiles are the Magento logs the log pane cycles through
+var logFiles = []string{"system.log", "exception.log", "debug.log"}
+
+// Project is a project shown in the project list
+type Project struct {
+	Name       string
+	Path       string
+	PHPVersion string
+	Domains    []string
+	HasConfig  bool
+}
+
+// Service is a service of the selected project
+type Service struct {
+	Name    string
+	Running bool
+}
+
+// Source provides the data shown by the dashboard
+type Source interface {
+	Projects() ([]Project, error)
+	Services(projectPath string) ([]Service, error)
+}
+
+// Action is a keyboard shortcut that runs a command for the selected project
+type Action struct {
+	Key   string
+	Label string
+	// Command builds the process to run; it runs in the project directory
+	Command func(p Project) *exec.Cmd
+}
+
+// Model is the bubbletea model of the dashboard
+type Model struct {
+	source  Source
+	actions []Action
+
+	projects []Project
+	selected int
+	services []Service
+	logFile  int
+	logs     []string
+
+	status string
+	err    error
+	width  int
+	height int
+}
+
+// Messages
+type (
+	projectsMsg struct {
+		projects []Project
+		err      error
+	}
+	servicesMsg struct {
+		path     string
+		services []Service
+		err      error
+	}
+	logsMsg struct {
+		path  string
+		lines []string
+	}
+	actionDoneMsg struct {
+		label string
+		err   error
+	}
+	tickMsg time.Time
+)
+
+// New creates a dashboard model
+func New(source Source, actions []Action) Model {
+	return Model{source: source, actions: actions}
+}
+
+// Run starts the dashboard in the alternate screen and blocks until it quits
+func Run(source Source, actions []Action) error {
+	_, err := tea.NewProgram(New(source, actions), tea.WithAltScreen()).Run()
+	return err
+}
+
+// Init loads the project list and starts the refresh timer
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.loadProjects(), tick())
+}
+
+// Update handles keys, data refreshes and finished actions
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+
+	case projectsMsg:
+		m.err = msg.err
+		m.projects = msg.projects
+		if m.selected >= len(m.projects) {
+			m.selected = max(len(m.projects)-1, 0)
+		}
+		return m, m.loadSelected()
+
+	case servicesMsg:
+		if p, ok := m.current(); ok && p.Path == msg.path {
+			m.services = msg.services
+			if msg.err != nil {
+				m.err = msg.err
+			}
+		}
+		return m, nil
+
+	case logsMsg:
+		if p, ok := m.current(); ok && p.Path == msg.path {
+			m.logs = msg.lines
+		}
+		return m, nil
+
+	case actionDoneMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("✗ %s failed: %v", msg.label, msg.err)
+		} else {
+			m.status = fmt.Sprintf("✓ %s finished", msg.label)
+		}
+		return m, m.loadProjects()
+
+	case tickMsg:
+		return m, tea.Batch(m.loadSelected(), tick())
+	}
+
+	return m, nil
+}
+
+// handleKey processes navigation keys and action shortcuts
+func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+			m.services, m.logs = nil, nil
+			return m, m.loadSelected()
+		}
+		return m, nil
+	case "down", "j":
+		if m.selected < len(m.projects)-1 {
+			m.selected++
+			m.services, m.logs = nil, nil
+			return m, m.loadSelected()
+		}
+		return m, nil
+	case "l":
+		m.logFile = (m.logFile + 1) % len(logFiles)
+		m.logs = nil
+		return m, m.loadSelected()
+	case "R":
+		m.status = ""
+		return m, m.loadProjects()
+	}
+
+	p, ok := m.current()
+	if !ok {
+		return m, nil
+	}
+	for _, a := range m.actions {
+		if a.Key != msg.String() {
+			continue
+		}
+		cmd := a.Command(p)
+		cmd.Dir = p.Path
+		m.status = fmt.Sprintf("Running %s for %s...", a.Label, p.Name)
+		label := a.Label
+		return m, tea.ExecProcess(pauseAfter(cmd), func(err error) tea.Msg {
+			return actionDoneMsg{label: label, err: err}
+		})
+	}
+	return m, nil
+}
+
+// View renders the dashboard
+func (m Model) View() string {
+	width := m.width
+	if width == 0 {
+		width = 100
+	}
+	height := m.height
+	if height == 0 {
+		height = 30
+	}
+
+	left := m.renderProjects()
+	leftWidth := min(max(lipgloss.Width(left)+2, 24), width/3)
+	rightWidth := width - leftWidth - 4
+
+	// Header, footer and pane borders take 6 lines
+	bodyHeight := max(height-6, 6)
+	servicesView := m.renderServices()
+	servicesHeight := lipgloss.Height(servicesView)
+	logsHeight := max(bodyHeight-servicesHeight-2, 3)
+
+	projectsPane := paneStyle.Width(leftWidth).Height(bodyHeight).Render(left)
+	servicesPane := paneStyle.Width(rightWidth).Render(servicesView)
+	logsPane := paneStyle.Width(rightWidth).Height(logsHeight).Render(m.renderLogs(logsHeight, rightWidth))
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top, projectsPane, lipgloss.JoinVertical(lipgloss.Left, servicesPane, logsPane))
+
+	return lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render("MageBox"), body, m.renderFooter(width))
+}
+
+// renderProjects renders the project list
+func (m Model) renderProjects() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Projects") + "\n")
+	if m.err != nil && len(m.projects) == 0 {
+		b.WriteString(errorStyle.Render(m.err.Error()))
+		return b.String()
+	}
+	if len(m.projects) == 0 {
+		b.WriteString(dimStyle.Render("No projects found"))
+		return b.String()
+	}
+	for i, p := range m.projects {
+		line := "  " + p.Name
+		if i == m.selected {
+			line = selectedStyle.Render("▸ " + p.Name)
+		}
+		if !p.HasConfig {
+			line += dimStyle.Render(" (no .magebox)")
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderServices renders details and services of the selected project
+func (m Model) renderServices() string {
+	p, ok := m.current()
+	if !ok {
+		return headerStyle.Render("Services")
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(p.Name) + "\n")
+	b.WriteString(dimStyle.Render(p.Path) + "\n")
+	if p.PHPVersion != "" {
+		b.WriteString("PHP " + p.PHPVersion + "\n")
+	}
+	for _, d := range p.Domains {
+		b.WriteString("https://" + d + "\n")
+	}
+	b.WriteString("\n" + headerStyle.Render("Services") + "\n")
+	if m.services == nil {
+		b.WriteString(dimStyle.Render("Loading..."))
+		return b.String()
+	}
+	for _, s := range m.services {
+		if s.Running {
+			b.WriteString(runningStyle.Render("● ") + s.Name + "\n")
+		} else {
+			b.WriteString(stoppedStyle.Render("○ ") + s.Name + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderLogs renders the last lines of the current log file that fit the pane
+func (m Model) renderLogs(height, width int) string {
+	header := headerStyle.Render("Logs: var/log/"+logFiles[m.logFile]) + dimStyle.Render("  (l: next log)")
+	lines := m.logs
+	if len(lines) == 0 {
+		return header + "\n" + dimStyle.Render("No log entries")
+	}
+	if visible := height - 1; len(lines) > visible {
+		lines = lines[len(lines)-visible:]
+	}
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if width > 1 && len(line) > width {
+			line = line[:width-1] + "…"
+		}
+		out = append(out, line)
+	}
+	return header + "\n" + strings.Join(out, "\n")
+}
+
+// renderFooter renders the key bindings and the last action result
+func (m Model) renderFooter(width int) string {
+	keys := []string{"↑/↓ select"}
+	for _, a := range m.actions {
+		keys = append(keys, a.Key+" "+strings.ToLower(a.Label))
+	}
+	keys = append(keys, "l logs", "R refresh", "q quit")
+
+	footer := dimStyle.Width(width).Render(strings.Join(keys, " · "))
+	if m.status != "" {
+		footer = m.status + "\n" + footer
+	}
+	return footer
+}
+
+// current returns the selected project
+func (m Model) current() (Project, bool) {
+	if m.selected < 0 || m.selected >= len(m.projects) {
+		return Project{}, false
+	}
+	return m.projects[m.selected], true
+}
+
+// loadProjects reloads the project list
+func (m Model) loadProjects() tea.Cmd {
+	source := m.source
+	return func() tea.Msg {
+		projects, err := source.Projects()
+		return projectsMsg{projects: projects, err: err}
+	}
+}
+
+// loadSelected reloads services and logs of the selected project
+func (m Model) loadSelected() tea.Cmd {
+	p, ok := m.current()
+	if !ok {
+		return nil
+	}
+	source := m.source
+	logPath := filepath.Join(p.Path, "var", "log", logFiles[m.logFile])
+	return tea.Batch(
+		func() tea.Msg {
+			services, err := source.Services(p.Path)
+			return servicesMsg{path: p.Path, services: services, err: err}
+		},
+		func() tea.Msg {
+			return logsMsg{path: p.Path, lines: tailFile(logPath, logLines)}
+		},
+	)
+}
+
+// tick schedules the next refresh
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// pauseAfter wraps a command so its output stays on screen until Enter is
+// pressed, instead of disappearing when the dashboard takes the screen back
+func pauseAfter(cmd *exec.Cmd) *exec.Cmd {
+	script := `"$0" "$@"; status=$?; printf '\n[press Enter to return to the dashboard]'; read _; exit $status`
+	wrapped := exec.Command("sh", append([]string{"-c", script, cmd.Path}, cmd.Args[1:]...)...)
+	wrapped.Dir = cmd.Dir
+	wrapped.Env = cmd.Env
+	return wrapped
+}
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("208")).Padding(0, 1)
+	headerStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	selectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("208"))
+	runningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	stoppedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	warningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
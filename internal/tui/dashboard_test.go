@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeSource struct {
+	projects []Project
+	services map[string][]Service
+}
+
+func (f *fakeSource) Projects() ([]Project, error) { return f.projects, nil }
+
+func (f *fakeSource) Services(path string) ([]Service, error) { return f.services[path], nil }
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func newTestModel() Model {
+	m := New(&fakeSource{}, []Action{
+		{Key: "s", Label: "Start", Command: func(Project) *exec.Cmd { return exec.Command("true") }},
+	})
+	updated, _ := m.Update(projectsMsg{projects: []Project{
+		{Name: "alpha", Path: "/work/alpha", HasConfig: true},
+		{Name: "beta", Path: "/work/beta", HasConfig: true},
+	}})
+	return updated.(Model)
+}
+
+func TestModel_Navigation(t *testing.T) {
+	m := newTestModel()
+
+	for _, tt := range []struct {
+		key  string
+		want string
+	}{
+		{"down", "beta"},
+		{"down", "beta"}, // stays on the last project
+		{"k", "alpha"},
+		{"up", "alpha"}, // stays on the first project
+		{"j", "beta"},
+	} {
+		updated, _ := m.Update(key(tt.key))
+		m = updated.(Model)
+		if p, _ := m.current(); p.Name != tt.want {
+			t.Errorf("after %q selected %q, want %q", tt.key, p.Name, tt.want)
+		}
+	}
+}
+
+func TestModel_IgnoresStaleServices(t *testing.T) {
+	m := newTestModel()
+
+	updated, _ := m.Update(servicesMsg{path: "/work/beta", services: []Service{{Name: "Nginx", Running: true}}})
+	m = updated.(Model)
+	if m.services != nil {
+		t.Error("services of a project that is not selected should be ignored")
+	}
+
+	updated, _ = m.Update(servicesMsg{path: "/work/alpha", services: []Service{{Name: "Nginx", Running: true}}})
+	m = updated.(Model)
+	if len(m.services) != 1 {
+		t.Fatalf("services = %v, want 1 service", m.services)
+	}
+	if view := m.View(); !strings.Contains(view, "Nginx") || !strings.Contains(view, "alpha") {
+		t.Errorf("view should show the selected project and its services:\n%s", view)
+	}
+}
+
+func TestModel_ActionsAndQuit(t *testing.T) {
+	m := newTestModel()
+
+	updated, cmd := m.Update(key("s"))
+	m = updated.(Model)
+	if cmd == nil || !strings.Contains(m.status, "Start") {
+		t.Errorf("action key should run the action, status = %q", m.status)
+	}
+
+	updated, _ = m.Update(actionDoneMsg{label: "Start"})
+	if status := updated.(Model).status; !strings.Contains(status, "Start finished") {
+		t.Errorf("status = %q, want finished message", status)
+	}
+
+	_, cmd = m.Update(key("q"))
+	if cmd == nil {
+		t.Fatal("q should return a command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("q should quit")
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system.log")
+	if tailFile(path, 5) != nil {
+		t.Error("missing file should return nil")
+	}
+
+	var content strings.Builder
+	for i := 0; i < 10; i++ {
+		content.WriteString("line " + string(rune('0'+i)) + "\n")
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := tailFile(path, 3)
+	if strings.Join(lines, ",") != "line 7,line 8,line 9" {
+		t.Errorf("tailFile() = %v, want last 3 lines", lines)
+	}
+}
@@ -0,0 +1,384 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// statusLogLines is the number of nginx error log lines kept by the status view
+const statusLogLines = 50
+
+// StatusSnapshot is the state of a project shown by the live status view
+type StatusSnapshot struct {
+	Project    string
+	Services   []Service
+	Pool       *PoolUsage // nil when the pool status could not be read
+	PoolErr    error
+	Containers []Container
+	ErrorLogs  []string // nginx error logs of the project's domains
+}
+
+// PoolUsage is the utilization of the project's PHP-FPM pool
+type PoolUsage struct {
+	Active             int64
+	Idle               int64
+	Total              int64
+	MaxActive          int64
+	MaxChildrenReached int64
+	ListenQueue        int64
+	SlowRequests       int64
+}
+
+// Container is a Docker container of a service the project uses
+type Container struct {
+	Name      string
+	State     string
+	Health    string
+	Restarts  int
+	StartedAt time.Time
+}
+
+// StatusSource provides the snapshots shown by the status view
+type StatusSource interface {
+	Snapshot() (StatusSnapshot, error)
+}
+
+// StatusModel is the bubbletea model of 'magebox status --watch'
+type StatusModel struct {
+	source   StatusSource
+	interval time.Duration
+	events   <-chan struct{}
+
+	snapshot StatusSnapshot
+	logs     []string
+	err      error
+	updated  time.Time
+	loading  bool
+
+	// Restart counts of the first snapshot, to flag containers restarting
+	// while the view is open
+	baseRestarts map[string]int
+
+	width  int
+	height int
+}
+
+// Messages of the status view
+type (
+	snapshotMsg struct {
+		snapshot StatusSnapshot
+		logs     []string
+		err      error
+		at       time.Time
+	}
+	statusTickMsg  time.Time
+	statusEventMsg struct{}
+)
+
+// NewStatus creates the status view model. It refreshes every interval and
+// whenever a value arrives on events (nil for timed refreshes only).
+func NewStatus(source StatusSource, interval time.Duration, events <-chan struct{}) StatusModel {
+	return StatusModel{source: source, interval: interval, events: events, loading: true}
+}
+
+// RunStatus shows the status view in the alternate screen until it quits
+func RunStatus(source StatusSource, interval time.Duration, events <-chan struct{}) error {
+	_, err := tea.NewProgram(NewStatus(source, interval, events), tea.WithAltScreen()).Run()
+	return err
+}
+
+// Init takes the first snapshot and starts listening for events
+func (m StatusModel) Init() tea.Cmd {
+	return tea.Batch(m.load(), m.waitEvent())
+}
+
+// Update handles keys, snapshots, timer ticks and events
+func (m StatusModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case "r":
+			return m.refresh()
+		}
+		return m, nil
+
+	case snapshotMsg:
+		m.loading = false
+		m.err = msg.err
+		if msg.err == nil {
+			m.snapshot, m.logs, m.updated = msg.snapshot, msg.logs, msg.at
+			if m.baseRestarts == nil {
+				m.baseRestarts = make(map[string]int)
+				for _, c := range msg.snapshot.Containers {
+					m.baseRestarts[c.Name] = c.Restarts
+				}
+			}
+		}
+		return m, m.tick()
+
+	case statusTickMsg:
+		return m.refresh()
+
+	case statusEventMsg:
+		next, cmd := m.refresh()
+		return next, tea.Batch(cmd, m.waitEvent())
+	}
+
+	return m, nil
+}
+
+// refresh takes a new snapshot unless one is being taken
+func (m StatusModel) refresh() (tea.Model, tea.Cmd) {
+	if m.loading {
+		return m, nil
+	}
+	m.loading = true
+	return m, m.load()
+}
+
+// load takes a snapshot and reads the tail of the error logs
+func (m StatusModel) load() tea.Cmd {
+	source := m.source
+	return func() tea.Msg {
+		snapshot, err := source.Snapshot()
+		var logs []string
+		for _, path := range snapshot.ErrorLogs {
+			for _, line := range tailFile(path, statusLogLines) {
+				logs = append(logs, filepath.Base(path)+": "+line)
+			}
+		}
+		if len(snapshot.ErrorLogs) > 1 {
+			// nginx error log lines start with a sortable timestamp
+			sortByTimestamp(logs)
+		}
+		if len(logs) > statusLogLines {
+			logs = logs[len(logs)-statusLogLines:]
+		}
+		return snapshotMsg{snapshot: snapshot, logs: logs, err: err, at: time.Now()}
+	}
+}
+
+// tick schedules the next timed refresh
+func (m StatusModel) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(t time.Time) tea.Msg {
+		return statusTickMsg(t)
+	})
+}
+
+// waitEvent waits for the next event, if there is an event source
+func (m StatusModel) waitEvent() tea.Cmd {
+	if m.events == nil {
+		return nil
+	}
+	events := m.events
+	return func() tea.Msg {
+		if _, ok := <-events; !ok {
+			return nil
+		}
+		return statusEventMsg{}
+	}
+}
+
+// View renders the status view
+func (m StatusModel) View() string {
+	width := m.width
+	if width == 0 {
+		width = 100
+	}
+	height := m.height
+	if height == 0 {
+		height = 30
+	}
+
+	title := "MageBox status"
+	if m.snapshot.Project != "" {
+		title += ": " + m.snapshot.Project
+	}
+
+	var sections []string
+	sections = append(sections, m.renderServices(), m.renderPool(), m.renderContainers())
+	top := strings.Join(sections, "\n\n")
+
+	// Title, footer and pane borders take 6 lines
+	bodyHeight := max(height-6, 6)
+	logsHeight := max(bodyHeight-strings.Count(top, "\n")-3, 3)
+	innerWidth := width - 4
+
+	body := paneStyle.Width(innerWidth).Render(top) + "\n" +
+		paneStyle.Width(innerWidth).Height(logsHeight).Render(m.renderLogs(logsHeight, innerWidth-2))
+
+	return titleStyle.Render(title) + "\n" + body + "\n" + m.renderFooter(width)
+}
+
+// renderServices renders the services and whether they run
+func (m StatusModel) renderServices() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Services") + "\n")
+	if m.snapshot.Services == nil {
+		if m.err != nil {
+			return b.String() + errorStyle.Render(m.err.Error())
+		}
+		return b.String() + dimStyle.Render("Loading...")
+	}
+	for _, s := range m.snapshot.Services {
+		if s.Running {
+			b.WriteString(runningStyle.Render("● ") + s.Name + "\n")
+		} else {
+			b.WriteString(stoppedStyle.Render("○ ") + s.Name + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderPool renders the PHP-FPM pool utilization
+func (m StatusModel) renderPool() string {
+	header := headerStyle.Render("PHP-FPM pool") + "\n"
+	pool := m.snapshot.Pool
+	if pool == nil {
+		if m.snapshot.PoolErr != nil {
+			return header + dimStyle.Render("Not available: "+m.snapshot.PoolErr.Error())
+		}
+		return header + dimStyle.Render("Not available")
+	}
+
+	busy := 0.0
+	if pool.Total > 0 {
+		busy = float64(pool.Active) / float64(pool.Total)
+	}
+	line := fmt.Sprintf("%s %d of %d workers busy, %d idle, peak %d",
+		usageBar(busy, 20), pool.Active, pool.Total, pool.Idle, pool.MaxActive)
+
+	var warnings []string
+	if pool.ListenQueue > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d requests waiting", pool.ListenQueue))
+	}
+	if pool.MaxChildrenReached > 0 {
+		warnings = append(warnings, fmt.Sprintf("max children reached %d times", pool.MaxChildrenReached))
+	}
+	if pool.SlowRequests > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d slow requests", pool.SlowRequests))
+	}
+	if len(warnings) > 0 {
+		line += "\n" + warningStyle.Render(strings.Join(warnings, ", "))
+	}
+	return header + line
+}
+
+// renderContainers renders the containers with their health and restarts
+func (m StatusModel) renderContainers() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render("Containers") + "\n")
+	if len(m.snapshot.Containers) == 0 {
+		return b.String() + dimStyle.Render("No containers")
+	}
+
+	nameWidth := 0
+	for _, c := range m.snapshot.Containers {
+		nameWidth = max(nameWidth, len(c.Name))
+	}
+	for _, c := range m.snapshot.Containers {
+		marker := runningStyle.Render("● ")
+		if c.State != "running" || c.Health == "unhealthy" {
+			marker = stoppedStyle.Render("○ ")
+		}
+		state := c.State
+		if c.Health != "" {
+			state += ", " + c.Health
+		}
+		if c.State == "running" && !c.StartedAt.IsZero() {
+			state += ", up " + formatUptime(m.updated.Sub(c.StartedAt))
+		}
+		line := fmt.Sprintf("%s%-*s  %s", marker, nameWidth, c.Name, state)
+
+		restarts := fmt.Sprintf("  %d restart(s)", c.Restarts)
+		if base, ok := m.baseRestarts[c.Name]; ok && c.Restarts > base {
+			line += warningStyle.Render(fmt.Sprintf("%s (+%d while watching)", restarts, c.Restarts-base))
+		} else if c.Restarts > 0 {
+			line += warningStyle.Render(restarts)
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderLogs renders the last nginx error log lines that fit the pane
+func (m StatusModel) renderLogs(height, width int) string {
+	header := headerStyle.Render("Nginx errors")
+	lines := m.logs
+	if len(lines) == 0 {
+		return header + "\n" + dimStyle.Render("No errors logged")
+	}
+	if visible := height - 1; len(lines) > visible {
+		lines = lines[len(lines)-visible:]
+	}
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if width > 1 && len(line) > width {
+			line = line[:width-1] + "…"
+		}
+		out = append(out, line)
+	}
+	return header + "\n" + strings.Join(out, "\n")
+}
+
+// renderFooter renders the refresh time and the key bindings
+func (m StatusModel) renderFooter(width int) string {
+	updated := "updating..."
+	if !m.updated.IsZero() {
+		updated = "updated " + m.updated.Format("15:04:05")
+	}
+	if m.err != nil && m.snapshot.Services != nil {
+		updated = errorStyle.Render("refresh failed: " + m.err.Error())
+	}
+	return dimStyle.Width(width).Render(fmt.Sprintf("%s · every %s · r refresh · q quit", updated, m.interval))
+}
+
+// usageBar renders a utilization between 0 and 1 as a bar of the given width
+func usageBar(ratio float64, width int) string {
+	filled := int(ratio*float64(width) + 0.5)
+	filled = min(max(filled, 0), width)
+	style := runningStyle
+	switch {
+	case ratio >= 0.9:
+		style = stoppedStyle
+	case ratio >= 0.7:
+		style = warningStyle
+	}
+	return "[" + style.Render(strings.Repeat("█", filled)) + dimStyle.Render(strings.Repeat("·", width-filled)) + "]"
+}
+
+// formatUptime renders a duration as its largest unit, e.g. "42s", "5m" or "3h"
+func formatUptime(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", max(int(d.Seconds()), 0))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// sortByTimestamp orders "<file>: <timestamp> ..." lines from several error
+// logs by their timestamp, keeping the order of lines with equal timestamps
+func sortByTimestamp(lines []string) {
+	key := func(line string) string {
+		if _, rest, ok := strings.Cut(line, ": "); ok && len(rest) >= 19 {
+			return rest[:19] // 2006/01/02 15:04:05
+		}
+		return ""
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return key(lines[i]) < key(lines[j]) })
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeStatusSource struct {
+	snapshot StatusSnapshot
+	err      error
+}
+
+func (f *fakeStatusSource) Snapshot() (StatusSnapshot, error) {
+	return f.snapshot, f.err
+}
+
+func TestStatusModel_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	storeLog := filepath.Join(dir, "mystore.test-error.log")
+	apiLog := filepath.Join(dir, "api.mystore.test-error.log")
+	_ = os.WriteFile(storeLog, []byte("2026/10/15 09:00:01 [error] 12#0: upstream timed out\n2026/10/15 09:00:03 [error] 12#0: second\n"), 0644)
+	_ = os.WriteFile(apiLog, []byte("2026/10/15 09:00:02 [error] 12#0: api failure\n"), 0644)
+
+	source := &fakeStatusSource{snapshot: StatusSnapshot{
+		Project:  "mystore",
+		Services: []Service{{Name: "Nginx", Running: true}, {Name: "MySQL 8.0", Running: false}},
+		Pool:     &PoolUsage{Active: 9, Idle: 1, Total: 10, MaxActive: 10, MaxChildrenReached: 2},
+		Containers: []Container{
+			{Name: "magebox-mysql-8.0", State: "running", Health: "healthy", Restarts: 1, StartedAt: time.Now().Add(-90 * time.Second)},
+		},
+		ErrorLogs: []string{storeLog, apiLog},
+	}}
+
+	m := NewStatus(source, time.Second, nil)
+	msg := m.load()()
+	updated, cmd := m.Update(msg)
+	m = updated.(StatusModel)
+	if cmd == nil {
+		t.Error("a snapshot should schedule the next refresh")
+	}
+
+	wantLogs := []string{
+		"mystore.test-error.log: 2026/10/15 09:00:01 [error] 12#0: upstream timed out",
+		"api.mystore.test-error.log: 2026/10/15 09:00:02 [error] 12#0: api failure",
+		"mystore.test-error.log: 2026/10/15 09:00:03 [error] 12#0: second",
+	}
+	if strings.Join(m.logs, "\n") != strings.Join(wantLogs, "\n") {
+		t.Errorf("logs = %q, want the lines of both logs in time order", m.logs)
+	}
+
+	view := m.View()
+	for _, want := range []string{"mystore", "MySQL 8.0", "9 of 10 workers busy", "max children reached 2 times", "magebox-mysql-8.0", "healthy, up 1m", "1 restart(s)", "api failure"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view misses %q:\n%s", want, view)
+		}
+	}
+
+	// A container restarting while the view is open is flagged
+	source.snapshot.Containers[0].Restarts = 3
+	updated, _ = m.Update(m.load()())
+	if view := updated.(StatusModel).View(); !strings.Contains(view, "(+2 while watching)") {
+		t.Errorf("view should flag the new restarts:\n%s", view)
+	}
+}
+
+func TestStatusModel_Refresh(t *testing.T) {
+	source := &fakeStatusSource{}
+	m := NewStatus(source, time.Second, nil)
+
+	// The first snapshot is still being taken, so ticks are dropped
+	if _, cmd := m.Update(statusTickMsg(time.Now())); cmd != nil {
+		t.Error("a tick while loading should not take another snapshot")
+	}
+
+	updated, _ := m.Update(snapshotMsg{at: time.Now()})
+	m = updated.(StatusModel)
+	updated, cmd := m.Update(key("r"))
+	if cmd == nil || !updated.(StatusModel).loading {
+		t.Error("r should take a snapshot")
+	}
+
+	source.err = errors.New("no project config")
+	updated, _ = m.Update(m.load()())
+	if view := updated.(StatusModel).View(); !strings.Contains(view, "no project config") {
+		t.Errorf("view should show the error:\n%s", view)
+	}
+
+	_, cmd = m.Update(key("q"))
+	if cmd == nil {
+		t.Fatal("q should return a command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("q should quit")
+	}
+}
+
+func TestStatusModel_Events(t *testing.T) {
+	events := make(chan struct{}, 1)
+	m := NewStatus(&fakeStatusSource{}, time.Hour, events)
+	updated, _ := m.Update(snapshotMsg{at: time.Now()})
+	m = updated.(StatusModel)
+
+	events <- struct{}{}
+	if _, ok := m.waitEvent()().(statusEventMsg); !ok {
+		t.Fatal("an event should trigger a refresh message")
+	}
+	updated, cmd := m.Update(statusEventMsg{})
+	if cmd == nil || !updated.(StatusModel).loading {
+		t.Error("an event should take a snapshot")
+	}
+
+	close(events)
+	if msg := m.waitEvent()(); msg != nil {
+		t.Errorf("closed events should stop waiting, got %v", msg)
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	tests := map[time.Duration]string{
+		42 * time.Second: "42s",
+		5 * time.Minute:  "5m",
+		3 * time.Hour:    "3h",
+		72 * time.Hour:   "3d",
+		-time.Second:     "0s",
+	}
+	for d, want := range tests {
+		if got := formatUptime(d); got != want {
+			t.Errorf("formatUptime(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
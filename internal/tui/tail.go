@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// tailChunk is how much of the end of a log file is read; Magento log
+// entries are short, so this comfortably covers logLines entries
+const tailChunk = 64 * 1024
+
+// tailFile returns the last n lines of a file, or nil if it cannot be read
+func tailFile(path string, n int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+
+	offset := info.Size() - tailChunk
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	// The first line is likely cut in half when reading from an offset
+	if offset > 0 && len(lines) > 1 {
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
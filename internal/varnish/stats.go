@@ -0,0 +1,237 @@
+package varnish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Counters are varnishstat counters by name, e.g. "MAIN.cache_hit"
+type Counters map[string]int64
+
+// Sub returns the change of every counter since before
+func (c Counters) Sub(before Counters) Counters {
+	delta := make(Counters, len(c))
+	for name, value := range c {
+		delta[name] = value - before[name]
+	}
+	return delta
+}
+
+// CacheStats are the cache outcomes of client requests
+type CacheStats struct {
+	Requests   int64 `json:"requests"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Passes     int64 `json:"passes"`
+	Grace      int64 `json:"grace_hits"`      // Hits on stale objects served during grace
+	HitForMiss int64 `json:"hit_for_miss"`    // Misses of objects marked uncacheable
+	HitForPass int64 `json:"hit_for_pass"`    // Passes of objects marked uncacheable
+	Other      int64 `json:"other,omitempty"` // Pipe and synthetic responses
+}
+
+// HitRate returns the share of hits among hits, misses and passes
+func (s CacheStats) HitRate() float64 {
+	return ratio(s.Hits, s.Hits+s.Misses+s.Passes)
+}
+
+// MissRate returns the share of misses among hits, misses and passes
+func (s CacheStats) MissRate() float64 {
+	return ratio(s.Misses, s.Hits+s.Misses+s.Passes)
+}
+
+// PassRate returns the share of passes among hits, misses and passes
+func (s CacheStats) PassRate() float64 {
+	return ratio(s.Passes, s.Hits+s.Misses+s.Passes)
+}
+
+func ratio(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// StatsFromCounters reads the cache outcomes from varnishstat counters
+func StatsFromCounters(c Counters) CacheStats {
+	return CacheStats{
+		Requests:   c["MAIN.client_req"],
+		Hits:       c["MAIN.cache_hit"],
+		Misses:     c["MAIN.cache_miss"],
+		Passes:     c["MAIN.s_pass"],
+		Grace:      c["MAIN.cache_hit_grace"],
+		HitForMiss: c["MAIN.cache_hitmiss"],
+		HitForPass: c["MAIN.cache_hitpass"],
+		Other:      c["MAIN.s_pipe"] + c["MAIN.s_synth"],
+	}
+}
+
+// ParseCounters parses `varnishstat -1` output, whose lines are
+// "NAME VALUE RATE DESCRIPTION"
+func ParseCounters(output string) Counters {
+	counters := make(Counters)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			counters[fields[0]] = value
+		}
+	}
+	return counters
+}
+
+// requestFormat is the varnishncsa format of a sampled request: the Host
+// header, how Varnish handled the request and the URL path
+const requestFormat = `%{Host}i %{Varnish:handling}x %U`
+
+// Request is a client request sampled with varnishncsa
+type Request struct {
+	Host     string
+	Handling string // hit, miss, pass, pipe or synth
+	URL      string
+}
+
+// ParseRequests parses varnishncsa output in requestFormat
+func ParseRequests(output string) []Request {
+	var requests []Request
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		// Varnish compares hosts without the port and case insensitively
+		host := strings.ToLower(fields[0])
+		if i := strings.LastIndex(host, ":"); i > 0 && !strings.HasSuffix(host, "]") {
+			host = host[:i]
+		}
+		requests = append(requests, Request{Host: host, Handling: fields[1], URL: fields[2]})
+	}
+	return requests
+}
+
+// URLCount is a URL and how often it was requested
+type URLCount struct {
+	URL   string `json:"url"`
+	Count int64  `json:"count"`
+}
+
+// HostReport is the traffic of one Host header over the sampling window
+type HostReport struct {
+	Host     string     `json:"host"`
+	Stats    CacheStats `json:"stats"`
+	Uncached []URLCount `json:"top_uncached,omitempty"` // Most requested URLs that missed or passed
+}
+
+// HostReports groups sampled requests by host, sorted by request count. Only
+// the given hosts are reported, or all when hosts is empty. Each host lists
+// its top most requested uncached URLs.
+func HostReports(requests []Request, hosts []string, top int) []HostReport {
+	wanted := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		wanted[strings.ToLower(h)] = true
+	}
+
+	byHost := make(map[string]*HostReport)
+	uncached := make(map[string]map[string]int64)
+	for _, r := range requests {
+		if len(wanted) > 0 && !wanted[r.Host] {
+			continue
+		}
+		report, ok := byHost[r.Host]
+		if !ok {
+			report = &HostReport{Host: r.Host}
+			byHost[r.Host] = report
+			uncached[r.Host] = make(map[string]int64)
+		}
+
+		report.Stats.Requests++
+		switch r.Handling {
+		case "hit":
+			report.Stats.Hits++
+		case "miss":
+			report.Stats.Misses++
+			uncached[r.Host][r.URL]++
+		case "pass":
+			report.Stats.Passes++
+			uncached[r.Host][r.URL]++
+		default:
+			report.Stats.Other++
+		}
+	}
+
+	reports := make([]HostReport, 0, len(byHost))
+	for host, report := range byHost {
+		report.Uncached = topURLs(uncached[host], top)
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Stats.Requests != reports[j].Stats.Requests {
+			return reports[i].Stats.Requests > reports[j].Stats.Requests
+		}
+		return reports[i].Host < reports[j].Host
+	})
+	return reports
+}
+
+// topURLs returns the n URLs with the highest counts
+func topURLs(counts map[string]int64, n int) []URLCount {
+	urls := make([]URLCount, 0, len(counts))
+	for url, count := range counts {
+		urls = append(urls, URLCount{URL: url, Count: count})
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if urls[i].Count != urls[j].Count {
+			return urls[i].Count > urls[j].Count
+		}
+		return urls[i].URL < urls[j].URL
+	})
+	if len(urls) > n {
+		urls = urls[:n]
+	}
+	return urls
+}
+
+// Counters reads the varnishstat counters of the Varnish container
+func (c *Controller) Counters() (Counters, error) {
+	output, err := exec.Command("docker", "exec", "magebox-varnish", "varnishstat", "-1").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read varnishstat: %w", err)
+	}
+	return ParseCounters(string(output)), nil
+}
+
+// SampleRequests logs the client requests Varnish handles during window with
+// varnishncsa. It runs under timeout in the container, so varnishncsa stops
+// there as well.
+func (c *Controller) SampleRequests(ctx context.Context, window time.Duration) ([]Request, error) {
+	cmd := exec.CommandContext(ctx, "docker", "exec", "magebox-varnish",
+		"timeout", timeoutSeconds(window), "varnishncsa", "-c", "-F", requestFormat)
+	output, err := cmd.Output()
+
+	// timeout exits with 124 when the window is over
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 124) {
+		if ctx.Err() != nil {
+			return ParseRequests(string(output)), ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to run varnishncsa: %w", err)
+	}
+	return ParseRequests(string(output)), nil
+}
+
+// timeoutSeconds formats window as the duration argument of timeout, in
+// seconds to the millisecond. Shorter windows are raised to a millisecond, as
+// timeout 0 would never stop.
+func timeoutSeconds(window time.Duration) string {
+	if window < time.Millisecond {
+		window = time.Millisecond
+	}
+	return strconv.FormatFloat(window.Round(time.Millisecond).Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,93 @@
+package varnish
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseCounters(t *testing.T) {
+	output := `MAIN.uptime            3600         1.00 Child process uptime
+MAIN.client_req          100         0.01 Good client requests received
+MAIN.cache_hit            70         0.01 Cache hits
+MAIN.cache_hit_grace       5         0.00 Cache grace hits
+MAIN.cache_hitpass         3         0.00 Cache hits for pass
+MAIN.cache_hitmiss         4         0.00 Cache hits for miss
+MAIN.cache_miss           20         0.00 Cache misses
+MAIN.s_pass               10         0.00 Total pass-ed requests seen
+MAIN.s_synth               2         0.00 Total synthetic responses made
+`
+	counters := ParseCounters(output)
+	if counters["MAIN.uptime"] != 3600 || counters["MAIN.cache_hit"] != 70 {
+		t.Fatalf("ParseCounters() = %v", counters)
+	}
+
+	stats := StatsFromCounters(counters)
+	want := CacheStats{Requests: 100, Hits: 70, Misses: 20, Passes: 10, Grace: 5, HitForMiss: 4, HitForPass: 3, Other: 2}
+	if stats != want {
+		t.Errorf("StatsFromCounters() = %+v, want %+v", stats, want)
+	}
+	if math.Abs(stats.HitRate()-0.7) > 1e-9 || math.Abs(stats.MissRate()-0.2) > 1e-9 || math.Abs(stats.PassRate()-0.1) > 1e-9 {
+		t.Errorf("rates = %v/%v/%v, want 0.7/0.2/0.1", stats.HitRate(), stats.MissRate(), stats.PassRate())
+	}
+
+	later := Counters{"MAIN.cache_hit": 90, "MAIN.cache_miss": 25}
+	if delta := later.Sub(counters); delta["MAIN.cache_hit"] != 20 || delta["MAIN.cache_miss"] != 5 {
+		t.Errorf("Sub() = %v", delta)
+	}
+}
+
+func TestCacheStats_NoTraffic(t *testing.T) {
+	if rate := (CacheStats{}).HitRate(); rate != 0 {
+		t.Errorf("HitRate() without requests = %v, want 0", rate)
+	}
+}
+
+func TestHostReports(t *testing.T) {
+	requests := ParseRequests(`mystore.test hit /
+mystore.test miss /women.html
+MyStore.test:443 pass /checkout
+mystore.test miss /women.html
+mystore.test hit /
+other.test hit /
+malformed line
+`)
+	if len(requests) != 6 {
+		t.Fatalf("ParseRequests() = %d requests, want 6", len(requests))
+	}
+
+	reports := HostReports(requests, nil, 10)
+	if len(reports) != 2 || reports[0].Host != "mystore.test" || reports[1].Host != "other.test" {
+		t.Fatalf("HostReports() = %+v", reports)
+	}
+
+	mystore := reports[0]
+	want := CacheStats{Requests: 5, Hits: 2, Misses: 2, Passes: 1}
+	if mystore.Stats != want {
+		t.Errorf("stats = %+v, want %+v", mystore.Stats, want)
+	}
+	if len(mystore.Uncached) != 2 || mystore.Uncached[0] != (URLCount{URL: "/women.html", Count: 2}) || mystore.Uncached[1].URL != "/checkout" {
+		t.Errorf("uncached = %+v", mystore.Uncached)
+	}
+
+	if reports := HostReports(requests, []string{"other.test"}, 10); len(reports) != 1 || reports[0].Host != "other.test" {
+		t.Errorf("HostReports() for other.test = %+v", reports)
+	}
+	if reports := HostReports(requests, nil, 1); len(reports[0].Uncached) != 1 {
+		t.Errorf("top 1 = %+v", reports[0].Uncached)
+	}
+}
+
+func TestTimeoutSeconds(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * time.Second:        "30",
+		1500 * time.Millisecond: "1.5",
+		400 * time.Millisecond:  "0.4",
+		time.Microsecond:        "0.001",
+	}
+	for window, want := range tests {
+		if got := timeoutSeconds(window); got != want {
+			t.Errorf("timeoutSeconds(%s) = %q, want %q", window, got, want)
+		}
+	}
+}
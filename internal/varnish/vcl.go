@@ -4,12 +4,18 @@ import (
 	"bytes"
 	_ "embed"
 	"fmt"
+	"net/http"
+	"net/netip"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"text/template"
+	"time"
 
 	"qoliber/magebox/internal/config"
+	"qoliber/magebox/internal/fileutil"
 	"qoliber/magebox/internal/lib"
 	"qoliber/magebox/internal/platform"
 )
@@ -17,6 +23,20 @@ import (
 //go:embed templates/default.vcl.tmpl
 var vclTemplateEmbed string
 
+// BackendPort is the port of the nginx server Varnish fetches from. It must
+// differ from the public HTTP port, which is 8080 on macOS.
+const BackendPort = 8081
+
+// Port is the host port the Varnish container is published on
+const Port = 6081
+
+// bridgeNetwork is the Docker network the MageBox containers run on
+const bridgeNetwork = "magebox_magebox"
+
+// defaultBridgeNetwork is Docker's default address pool for bridge networks,
+// used when the subnet of the MageBox network can't be read
+var defaultBridgeNetwork = ACLNetwork{Addr: "172.16.0.0", Bits: 12}
+
 func init() {
 	// Register embedded template as fallback
 	lib.RegisterFallbackTemplate(lib.TemplateVarnish, "default.vcl.tmpl", vclTemplateEmbed)
@@ -27,11 +47,22 @@ func init() {
 //   - Name: Backend name (sanitized project name)
 //   - Host: Backend host (e.g., "127.0.0.1")
 //   - Port: Backend port (e.g., 80)
-//   - ProbeURL: Health check URL (e.g., "/health_check.php")
+//   - ProbeURL: Health check URL (e.g., "/magebox-health")
+//   - ProbeHost: Host header of the health check, the project's first domain
+//     (empty for the placeholder backend without projects, which has no probe)
 //   - ProbeInterval: Health check interval (e.g., "5s")
-// - DefaultBackend: Name of the default backend to use
+//   - Hosts: Domains of the project, routed to its director (e.g., ["mystore.test"])
+// - DefaultBackend: Name of the backend for hosts no project serves
 // - GracePeriod: Grace period for serving stale content (e.g., "300s")
-// - PurgeACL: Array of IP addresses/ranges allowed to purge (e.g., ["localhost", "127.0.0.1"])
+// - PurgeACL: Array of hosts/IP addresses allowed to purge (e.g., ["localhost", "127.0.0.1"])
+// - PurgeACLNetworks: Array of networks allowed to purge
+//   - Addr: Network address (e.g., "172.16.0.0")
+//   - Bits: Prefix length (e.g., 12)
+// - Snippets: Array of project VCL files (services.varnish.vcl_extra), inserted
+//   before the generated subroutines so their subs run first
+//   - Project: Project name
+//   - Path: Absolute path of the VCL file
+//   - Content: VCL code
 
 // VCLGenerator generates Varnish VCL configurations
 type VCLGenerator struct {
@@ -45,15 +76,32 @@ type BackendConfig struct {
 	Host          string
 	Port          int
 	ProbeURL      string
+	ProbeHost     string
 	ProbeInterval string
+	Hosts         []string
+}
+
+// ACLNetwork is a network entry of a VCL ACL (rendered as "Addr"/Bits)
+type ACLNetwork struct {
+	Addr string
+	Bits int
+}
+
+// VCLSnippet is a project's own VCL merged into the generated file
+type VCLSnippet struct {
+	Project string
+	Path    string
+	Content string
 }
 
 // VCLConfig contains all data needed to generate a VCL file
 type VCLConfig struct {
-	Backends       []BackendConfig
-	DefaultBackend string
-	GracePeriod    string
-	PurgeACL       []string
+	Backends         []BackendConfig
+	DefaultBackend   string
+	GracePeriod      string
+	PurgeACL         []string
+	PurgeACLNetworks []ACLNetwork
+	Snippets         []VCLSnippet
 }
 
 // NewVCLGenerator creates a new VCL generator
@@ -73,6 +121,11 @@ func (g *VCLGenerator) Generate(configs []*config.Config) error {
 
 	// Build VCL config from all projects
 	vclCfg := g.buildVCLConfig(configs)
+	snippets, err := loadSnippets(configs)
+	if err != nil {
+		return err
+	}
+	vclCfg.Snippets = snippets
 
 	// Render VCL
 	content, err := g.renderVCL(vclCfg)
@@ -82,7 +135,7 @@ func (g *VCLGenerator) Generate(configs []*config.Config) error {
 
 	// Write main VCL file
 	vclFile := filepath.Join(g.vclDir, "default.vcl")
-	if err := os.WriteFile(vclFile, []byte(content), 0644); err != nil {
+	if err := fileutil.WriteGenerated(vclFile, []byte(content), 0644, fileutil.ValidateVCLBraces); err != nil {
 		return fmt.Errorf("failed to write VCL file: %w", err)
 	}
 
@@ -95,11 +148,13 @@ func (g *VCLGenerator) buildVCLConfig(configs []*config.Config) VCLConfig {
 		Backends:    make([]BackendConfig, 0),
 		GracePeriod: "300s",
 		PurgeACL:    []string{"localhost", "127.0.0.1", "::1", "host.docker.internal"},
+		// The Docker bridge, so Magento on the host can purge through its gateway
+		PurgeACLNetworks: bridgeNetworks(),
 	}
 
 	// Backend host - detect host IP for Docker to reach nginx
 	backendHost := getHostIP()
-	backendPort := 8080 // Nginx backend listens on 8080 (Varnish connects here)
+	backendPort := BackendPort
 
 	for _, cfg := range configs {
 		// Each project gets a backend pointing to Nginx
@@ -107,9 +162,16 @@ func (g *VCLGenerator) buildVCLConfig(configs []*config.Config) VCLConfig {
 			Name:          sanitizeName(cfg.Name),
 			Host:          backendHost,
 			Port:          backendPort,
-			ProbeURL:      "/health_check.php",
+			ProbeURL:      "/magebox-health",
 			ProbeInterval: "5s",
 		}
+		// The backend server is matched by name, so probe a real domain
+		if len(cfg.Domains) > 0 {
+			backend.ProbeHost = cfg.Domains[0].Host
+		}
+		for _, domain := range cfg.Domains {
+			backend.Hosts = append(backend.Hosts, strings.ToLower(domain.Host))
+		}
 		vclCfg.Backends = append(vclCfg.Backends, backend)
 
 		// First project is default backend
@@ -131,6 +193,37 @@ func (g *VCLGenerator) buildVCLConfig(configs []*config.Config) VCLConfig {
 	return vclCfg
 }
 
+// loadSnippets reads the vcl_extra files of the projects using Varnish
+func loadSnippets(configs []*config.Config) ([]VCLSnippet, error) {
+	var snippets []VCLSnippet
+	for _, cfg := range configs {
+		v := cfg.Services.Varnish
+		if v == nil || !v.Enabled || v.VCLExtra == "" {
+			continue
+		}
+		path := v.VCLExtraPath
+		if path == "" {
+			path = v.VCLExtra
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: failed to read varnish vcl_extra: %w", cfg.Name, err)
+		}
+		if vclVersionPattern.Match(content) {
+			return nil, fmt.Errorf("project %s: %s must not declare a vcl version, it is merged into the generated VCL", cfg.Name, v.VCLExtra)
+		}
+		snippets = append(snippets, VCLSnippet{
+			Project: cfg.Name,
+			Path:    path,
+			Content: strings.TrimSpace(string(content)),
+		})
+	}
+	return snippets, nil
+}
+
+// vclVersionPattern matches a "vcl 4.1;" declaration, only valid at the top of the main VCL
+var vclVersionPattern = regexp.MustCompile(`(?m)^\s*vcl\s+[0-9.]+\s*;`)
+
 // renderVCL renders the VCL template
 func (g *VCLGenerator) renderVCL(cfg VCLConfig) (string, error) {
 	// Load template from lib (with embedded fallback)
@@ -237,22 +330,100 @@ func (c *Controller) IsRunning() bool {
 
 // Purge sends a purge request to Varnish
 func (c *Controller) Purge(host, url string) error {
-	cmd := exec.Command("curl", "-X", "PURGE", "-H", "Host: "+host, "http://127.0.0.1:6081"+url)
+	cmd := exec.Command("curl", "-X", "PURGE", "-H", "Host: "+host, varnishURL+url)
 	return cmd.Run()
 }
 
-// Ban sends a ban request to Varnish
-func (c *Controller) Ban(pattern string) error {
-	cmd := exec.Command("curl", "-X", "BAN", "-H", "X-Magento-Tags-Pattern: "+pattern, "http://127.0.0.1:6081/")
+// Ban sends a ban request to Varnish. A project domain as host limits the
+// ban to that project's pages.
+func (c *Controller) Ban(host, pattern string) error {
+	args := []string{"-X", "BAN", "-H", "X-Magento-Tags-Pattern: " + pattern}
+	if host != "" {
+		args = append(args, "-H", "Host: "+host)
+	}
+	cmd := exec.Command("curl", append(args, varnishURL+"/")...)
 	return cmd.Run()
 }
 
+// varnishURL is the address Varnish listens on (overridable in tests)
+var varnishURL = fmt.Sprintf("http://127.0.0.1:%d", Port)
+
+// TagsPattern builds the X-Magento-Tags-Pattern header value Magento sends
+// when it invalidates cache tags, e.g. "((^|,)cat_p_123(,|$))"
+func TagsPattern(tags []string) string {
+	parts := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		parts = append(parts, fmt.Sprintf("((^|,)%s(,|$))", regexp.QuoteMeta(tag)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// PurgeTags invalidates every cached object tagged with one of the given
+// Magento cache tags, using the same PURGE request Magento sends. A project
+// domain as host limits the purge to that project's pages, an empty host
+// purges the tags of all projects.
+func (c *Controller) PurgeTags(host string, tags []string) error {
+	if len(tags) == 0 {
+		return fmt.Errorf("no cache tags given")
+	}
+
+	req, err := http.NewRequest("PURGE", varnishURL+"/", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Magento-Tags-Pattern", TagsPattern(tags))
+	if host != "" {
+		req.Host = host
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Varnish: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("varnish returned %s", resp.Status)
+	}
+	return nil
+}
+
 // FlushAll flushes all cached content
 func (c *Controller) FlushAll() error {
 	cmd := exec.Command("docker", "exec", "magebox-varnish", "varnishadm", "ban", "req.url", "~", ".")
 	return cmd.Run()
 }
 
+// bridgeNetworks returns the IPv4 subnets of the MageBox Docker network, or
+// Docker's default bridge pool when the network can't be inspected
+// (overridable in tests)
+var bridgeNetworks = func() []ACLNetwork {
+	cmd := exec.Command("docker", "network", "inspect", bridgeNetwork, "--format", "{{range .IPAM.Config}}{{.Subnet}} {{end}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return []ACLNetwork{defaultBridgeNetwork}
+	}
+	if networks := parseACLNetworks(string(output)); len(networks) > 0 {
+		return networks
+	}
+	return []ACLNetwork{defaultBridgeNetwork}
+}
+
+// parseACLNetworks parses whitespace separated CIDRs, keeping IPv4 ones
+func parseACLNetworks(s string) []ACLNetwork {
+	var networks []ACLNetwork
+	for _, field := range strings.Fields(s) {
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil || !prefix.Addr().Is4() {
+			continue
+		}
+		prefix = prefix.Masked()
+		networks = append(networks, ACLNetwork{Addr: prefix.Addr().String(), Bits: prefix.Bits()})
+	}
+	return networks
+}
+
 // getHostIP returns the IP address that Docker containers use to reach the host
 func getHostIP() string {
 	// Use host.docker.internal which works reliably across Docker runtimes
@@ -1,6 +1,8 @@
 package varnish
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -90,6 +92,11 @@ func TestVCLGenerator_Generate(t *testing.T) {
 		"sub vcl_backend_response",
 		"sub vcl_deliver",
 		"X-Magento-Tags",
+		`.port = "8081";`,
+		`"GET /magebox-health HTTP/1.1"`,
+		`"Host: mystore.test"`,
+		".expected_response = 200;",
+		`if (req.url ~ "^/graphql") {`,
 	}
 
 	for _, check := range checks {
@@ -138,6 +145,43 @@ func TestVCLGenerator_GenerateMultipleProjects(t *testing.T) {
 	if !strings.Contains(contentStr, "backend store2") {
 		t.Error("VCL should contain backend store2")
 	}
+
+	// Each project is routed by its hosts to its own director
+	checks := []string{
+		"import directors;",
+		"new store1_director = directors.round_robin();",
+		"store2_director.add_backend(store2);",
+		"set req.backend_hint = store1_director.backend();",
+		`if (req.http.host == "store2.test") {
+        set req.backend_hint = store2_director.backend();
+        set req.http.X-MageBox-Project = "store2";`,
+		`ban(req.http.X-MageBox-Ban-Scope + "obj.http.X-Magento-Tags ~ "`,
+		"set beresp.http.X-MageBox-Project = bereq.http.X-MageBox-Project;",
+	}
+	for _, check := range checks {
+		if !strings.Contains(contentStr, check) {
+			t.Errorf("VCL content should contain %q", check)
+		}
+	}
+	if strings.Count(contentStr, "req.http.X-MageBox-Project = ") != 2 {
+		t.Error("VCL should set the project of each backend once")
+	}
+}
+
+func TestVCLGenerator_buildVCLConfig_Hosts(t *testing.T) {
+	g, _ := setupTestVCLGenerator(t)
+
+	vclCfg := g.buildVCLConfig([]*config.Config{
+		{Name: "store1", Domains: []config.Domain{{Host: "store1.test"}, {Host: "Store1-DE.test"}}},
+		{Name: "store2"},
+	})
+
+	if got := strings.Join(vclCfg.Backends[0].Hosts, ","); got != "store1.test,store1-de.test" {
+		t.Errorf("Hosts = %v, want both domains in lower case", vclCfg.Backends[0].Hosts)
+	}
+	if len(vclCfg.Backends[1].Hosts) != 0 {
+		t.Errorf("Hosts = %v, want none for a project without domains", vclCfg.Backends[1].Hosts)
+	}
 }
 
 func TestVCLGenerator_GenerateEmptyConfigs(t *testing.T) {
@@ -160,6 +204,10 @@ func TestVCLGenerator_GenerateEmptyConfigs(t *testing.T) {
 	if !strings.Contains(contentStr, "backend default") {
 		t.Error("VCL should contain default backend when no configs provided")
 	}
+	// Without a domain there is no host to probe
+	if strings.Contains(contentStr, ".probe") {
+		t.Error("default backend should not have a probe")
+	}
 }
 
 func TestSanitizeName(t *testing.T) {
@@ -397,3 +445,154 @@ func TestVCLTemplateValidity(t *testing.T) {
 		}
 	}
 }
+
+func TestTagsPattern(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{[]string{"cat_p_123"}, "((^|,)cat_p_123(,|$))"},
+		{[]string{"cat_c_5", "cms_b_footer"}, "((^|,)cat_c_5(,|$))|((^|,)cms_b_footer(,|$))"},
+		{[]string{"a.b"}, `((^|,)a\.b(,|$))`},
+	}
+	for _, tt := range tests {
+		if got := TagsPattern(tt.tags); got != tt.want {
+			t.Errorf("TagsPattern(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestController_PurgeTags(t *testing.T) {
+	var gotMethod, gotPattern, gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHost = r.Host
+		gotPattern = r.Header.Get("X-Magento-Tags-Pattern")
+		if gotPattern == "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	orig := varnishURL
+	varnishURL = server.URL
+	defer func() { varnishURL = orig }()
+
+	c := NewController(&platform.Platform{Type: platform.Linux}, "")
+	if err := c.PurgeTags("mystore.test", []string{"cat_p_123"}); err != nil {
+		t.Fatalf("PurgeTags failed: %v", err)
+	}
+	if gotMethod != "PURGE" {
+		t.Errorf("method = %s, want PURGE", gotMethod)
+	}
+	if gotPattern != "((^|,)cat_p_123(,|$))" {
+		t.Errorf("X-Magento-Tags-Pattern = %q", gotPattern)
+	}
+	if gotHost != "mystore.test" {
+		t.Errorf("Host = %q, want the project domain", gotHost)
+	}
+
+	if err := c.PurgeTags("", nil); err == nil {
+		t.Error("PurgeTags without tags should fail")
+	}
+}
+
+func TestVCLTemplate_PurgeACLNetworks(t *testing.T) {
+	g, _ := setupTestVCLGenerator(t)
+
+	orig := bridgeNetworks
+	bridgeNetworks = func() []ACLNetwork { return []ACLNetwork{{Addr: "172.18.0.0", Bits: 16}} }
+	defer func() { bridgeNetworks = orig }()
+
+	if err := g.Generate([]*config.Config{{Name: "magento"}}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.VCLFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read VCL file: %v", err)
+	}
+
+	if !strings.Contains(string(content), `"172.18.0.0"/16;`) {
+		t.Error("purge ACL should contain the MageBox bridge network")
+	}
+	for _, entry := range []string{`"192.168.0.0"/16;`, `"10.0.0.0"/8;`} {
+		if strings.Contains(string(content), entry) {
+			t.Errorf("purge ACL should not contain %s", entry)
+		}
+	}
+}
+
+func TestParseACLNetworks(t *testing.T) {
+	got := parseACLNetworks("172.18.0.0/16 fd00:1::/64 192.168.96.5/20 bogus\n")
+	want := []ACLNetwork{{Addr: "172.18.0.0", Bits: 16}, {Addr: "192.168.96.0", Bits: 20}}
+	if len(got) != len(want) {
+		t.Fatalf("parseACLNetworks() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseACLNetworks()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestVCLGenerator_Generate_Snippets(t *testing.T) {
+	g, tmpDir := setupTestVCLGenerator(t)
+
+	snippet := filepath.Join(tmpDir, "custom.vcl")
+	custom := "sub vcl_recv {\n    if (req.url ~ \"^/status\") {\n        return (pass);\n    }\n}"
+	if err := os.WriteFile(snippet, []byte(custom+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := []*config.Config{
+		{Name: "shop", Services: config.Services{Varnish: &config.ServiceConfig{Enabled: true, VCLExtra: "custom.vcl", VCLExtraPath: snippet}}},
+		{Name: "blog"},
+	}
+	if err := g.Generate(configs); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, err := os.ReadFile(g.VCLFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read VCL file: %v", err)
+	}
+	vcl := string(content)
+
+	idx := strings.Index(vcl, custom)
+	if idx < 0 {
+		t.Fatalf("VCL should contain the project snippet:\n%s", vcl)
+	}
+	if generated := strings.Index(vcl, "sub vcl_recv {\n    # Normalize the host header"); generated < idx {
+		t.Error("project snippet should come before the generated vcl_recv")
+	}
+	if !strings.Contains(vcl, "# Project VCL: shop ("+snippet+")") {
+		t.Error("VCL should name the project the snippet comes from")
+	}
+}
+
+func TestVCLGenerator_Generate_SnippetErrors(t *testing.T) {
+	g, tmpDir := setupTestVCLGenerator(t)
+
+	versioned := filepath.Join(tmpDir, "versioned.vcl")
+	if err := os.WriteFile(versioned, []byte("vcl 4.1;\nsub vcl_recv {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing file", path: filepath.Join(tmpDir, "missing.vcl")},
+		{name: "vcl version", path: versioned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Name: "shop", Services: config.Services{Varnish: &config.ServiceConfig{Enabled: true, VCLExtra: tt.path}}}
+			if err := g.Generate([]*config.Config{cfg}); err == nil {
+				t.Error("Generate should fail")
+			}
+		})
+	}
+}
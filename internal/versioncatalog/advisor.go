@@ -0,0 +1,233 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+package versioncatalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	libconfig "qoliber/magebox/internal/lib/config"
+)
+
+// Distributions, named like the choices of 'magebox new'
+const (
+	DistMagento  = "magento"
+	DistMageOS   = "mageos"
+	DistCommerce = "commerce"
+)
+
+// productPackages maps the product packages a project requires to their
+// distribution
+var productPackages = []struct {
+	name         string
+	distribution string
+}{
+	{"magento/product-enterprise-edition", DistCommerce},
+	{"magento/product-community-edition", DistMagento},
+	{"mage-os/product-community-edition", DistMageOS},
+}
+
+// Installed is the Magento installation of a project
+type Installed struct {
+	Distribution string `json:"distribution"`
+	Package      string `json:"package"`
+	Version      string `json:"version"`
+}
+
+// Stack is the PHP, database and search engine a project runs
+type Stack struct {
+	PHP             string `json:"php"`
+	Database        string `json:"database,omitempty"` // mysql, mariadb or percona
+	DatabaseVersion string `json:"database_version,omitempty"`
+	Search          string `json:"search,omitempty"` // opensearch or elasticsearch
+	SearchVersion   string `json:"search_version,omitempty"`
+}
+
+// Bump is a change of the stack an upgrade needs
+type Bump struct {
+	Service    string   `json:"service"` // php or the service of the stack
+	From       string   `json:"from"`
+	NewService string   `json:"new_service,omitempty"` // the service replacing Service, if it changes
+	To         string   `json:"to"`
+	Supported  []string `json:"supported"`
+}
+
+// Target is a release a project can upgrade to
+type Target struct {
+	Version string `json:"version"`
+	Name    string `json:"name"`
+	Latest  bool   `json:"latest,omitempty"`
+	Bumps   []Bump `json:"bumps"`
+
+	// Compatibility of the target's release line, nil if it's unknown
+	Compatibility *libconfig.CompatibilityEntry `json:"compatibility,omitempty"`
+}
+
+// DetectInstalled reads the installed Magento, MageOS or Adobe Commerce
+// version of a project from composer.lock, or from composer.json when the
+// project isn't installed yet and requires an exact version
+func DetectInstalled(projectDir string) (*Installed, error) {
+	if data, err := os.ReadFile(filepath.Join(projectDir, "composer.lock")); err == nil {
+		var lock struct {
+			Packages []struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"packages"`
+		}
+		if err := json.Unmarshal(data, &lock); err != nil {
+			return nil, fmt.Errorf("failed to parse composer.lock: %w", err)
+		}
+		for _, product := range productPackages {
+			for _, pkg := range lock.Packages {
+				if pkg.Name == product.name {
+					return &Installed{Distribution: product.distribution, Package: pkg.Name, Version: strings.TrimPrefix(pkg.Version, "v")}, nil
+				}
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, "composer.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no composer.json in %s", projectDir)
+		}
+		return nil, err
+	}
+	var manifest struct {
+		Require map[string]string `json:"require"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse composer.json: %w", err)
+	}
+	for _, product := range productPackages {
+		constraint, ok := manifest.Require[product.name]
+		if !ok {
+			continue
+		}
+		version := strings.TrimPrefix(strings.TrimPrefix(constraint, "="), "v")
+		if !stableVersion.MatchString(version) {
+			return nil, fmt.Errorf("composer.json requires %s %s, run composer install to lock a version", product.name, constraint)
+		}
+		return &Installed{Distribution: product.distribution, Package: product.name, Version: version}, nil
+	}
+	return nil, fmt.Errorf("the project requires no Magento, MageOS or Adobe Commerce product package")
+}
+
+// Advise returns the releases newer than the installed one the project can
+// upgrade to, oldest first: the newest release of each release line, with
+// the changes of the stack each needs. Adobe Commerce follows the Magento
+// Open Source releases.
+func Advise(versions *libconfig.VersionsConfig, installed *Installed, stack Stack) []Target {
+	releases := versions.GetMagentoVersions()
+	line := libconfig.ReleaseLine
+	if installed.Distribution == DistMageOS {
+		releases = versions.GetMageOSVersions()
+		line = libconfig.MinorVersion
+	}
+
+	var targets []Target
+	seen := make(map[string]bool)
+	for _, release := range releases {
+		if libconfig.CompareVersions(release.Version, installed.Version) <= 0 || seen[line(release.Version)] {
+			continue
+		}
+		seen[line(release.Version)] = true
+		targets = append(targets, newTarget(versions, installed.Distribution, release, stack))
+	}
+
+	// The releases are listed newest first, upgrades read oldest first
+	for i, j := 0, len(targets)-1; i < j; i, j = i+1, j-1 {
+		targets[i], targets[j] = targets[j], targets[i]
+	}
+	if len(targets) > 0 {
+		targets[len(targets)-1].Latest = true
+	}
+	return targets
+}
+
+// FindTarget returns the upgrade to a specific release, which has to be
+// newer than the installed one
+func FindTarget(versions *libconfig.VersionsConfig, installed *Installed, stack Stack, version string) (*Target, error) {
+	releases := versions.GetMagentoVersions()
+	if installed.Distribution == DistMageOS {
+		releases = versions.GetMageOSVersions()
+	}
+	for _, release := range releases {
+		if release.Version != version {
+			continue
+		}
+		if libconfig.CompareVersions(version, installed.Version) <= 0 {
+			return nil, fmt.Errorf("%s is not newer than the installed %s", version, installed.Version)
+		}
+		target := newTarget(versions, installed.Distribution, release, stack)
+		target.Latest = release.Default
+		return &target, nil
+	}
+	return nil, fmt.Errorf("unknown release %s, see 'magebox versions'", version)
+}
+
+// newTarget checks the stack against a release
+func newTarget(versions *libconfig.VersionsConfig, distribution string, release libconfig.VersionEntry, stack Stack) Target {
+	target := Target{Version: release.Version, Name: release.Name, Bumps: []Bump{}}
+	if distribution == DistMageOS {
+		target.Compatibility = versions.GetMageOSCompatibility(release.Version)
+	} else {
+		target.Compatibility = versions.GetCompatibility(release.Version)
+	}
+
+	// The PHP versions of the release itself are the most precise
+	php := release.PHP
+	if len(php) == 0 && target.Compatibility != nil {
+		php = target.Compatibility.PHP
+	}
+	if bump := checkVersion("php", stack.PHP, php); bump != nil {
+		target.Bumps = append(target.Bumps, *bump)
+	}
+
+	compat := target.Compatibility
+	if compat == nil {
+		return target
+	}
+	if stack.Database != "" {
+		if bump := checkVersion(stack.Database, stack.DatabaseVersion, compat.ServiceVersions(stack.Database)); bump != nil {
+			target.Bumps = append(target.Bumps, *bump)
+		}
+	}
+	if stack.Search != "" {
+		supported := compat.ServiceVersions(stack.Search)
+		switch {
+		case len(supported) > 0:
+			if bump := checkVersion(stack.Search, stack.SearchVersion, supported); bump != nil {
+				target.Bumps = append(target.Bumps, *bump)
+			}
+		case stack.Search == "elasticsearch" && len(compat.OpenSearch) > 0:
+			// Releases without Elasticsearch support move to OpenSearch
+			target.Bumps = append(target.Bumps, Bump{
+				Service:    stack.Search,
+				From:       stack.SearchVersion,
+				NewService: "opensearch",
+				To:         compat.OpenSearch[0],
+				Supported:  compat.OpenSearch,
+			})
+		}
+	}
+	return target
+}
+
+// checkVersion returns the bump to the newest supported version when the
+// current one isn't supported
+func checkVersion(service, current string, supported []string) *Bump {
+	if current == "" || len(supported) == 0 {
+		return nil
+	}
+	for _, v := range supported {
+		if libconfig.MinorVersion(v) == libconfig.MinorVersion(current) {
+			return nil
+		}
+	}
+	return &Bump{Service: service, From: current, To: supported[0], Supported: supported}
+}
@@ -0,0 +1,108 @@
+package versioncatalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	libconfig "qoliber/magebox/internal/lib/config"
+)
+
+func TestDetectInstalled(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := DetectInstalled(dir); err == nil {
+		t.Error("DetectInstalled without composer.json succeeded")
+	}
+
+	// composer.json alone needs an exact version
+	writeFile(t, filepath.Join(dir, "composer.json"), `{"require": {"magento/product-community-edition": "^2.4"}}`)
+	if _, err := DetectInstalled(dir); err == nil || !strings.Contains(err.Error(), "composer install") {
+		t.Errorf("DetectInstalled with a range = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "composer.json"), `{"require": {"magento/product-community-edition": "2.4.7-p3"}}`)
+	if installed, err := DetectInstalled(dir); err != nil || installed.Version != "2.4.7-p3" || installed.Distribution != DistMagento {
+		t.Errorf("DetectInstalled from composer.json = %+v, %v", installed, err)
+	}
+
+	// composer.lock wins, and Commerce includes the community edition
+	writeFile(t, filepath.Join(dir, "composer.lock"), `{"packages": [
+		{"name": "magento/product-community-edition", "version": "2.4.8-p2"},
+		{"name": "magento/product-enterprise-edition", "version": "2.4.8-p2"}
+	]}`)
+	installed, err := DetectInstalled(dir)
+	if err != nil || installed.Distribution != DistCommerce || installed.Version != "2.4.8-p2" {
+		t.Errorf("DetectInstalled from composer.lock = %+v, %v", installed, err)
+	}
+}
+
+func TestAdvise(t *testing.T) {
+	versions, err := libconfig.LoadEmbeddedVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	installed := &Installed{Distribution: DistMagento, Version: "2.4.6-p8"}
+	stack := Stack{PHP: "8.1", Database: "mysql", DatabaseVersion: "5.7", Search: "elasticsearch", SearchVersion: "7.17"}
+
+	targets := Advise(versions, installed, stack)
+	if len(targets) != 3 {
+		t.Fatalf("Advise() = %d targets, want the newest of 2.4.6, 2.4.7 and 2.4.8", len(targets))
+	}
+	if targets[0].Version != "2.4.6-p14" || targets[1].Version != "2.4.7-p9" || targets[2].Version != "2.4.8-p4" || !targets[2].Latest {
+		t.Errorf("targets = %s, %s, %s", targets[0].Version, targets[1].Version, targets[2].Version)
+	}
+	if len(targets[0].Bumps) != 0 {
+		t.Errorf("patch upgrade bumps = %+v, want none", targets[0].Bumps)
+	}
+
+	bumps := make(map[string]Bump)
+	for _, b := range targets[2].Bumps {
+		bumps[b.Service] = b
+	}
+	if b := bumps["php"]; b.From != "8.1" || b.To != "8.4" {
+		t.Errorf("PHP bump = %+v", b)
+	}
+	if b := bumps["mysql"]; b.To != "8.4" {
+		t.Errorf("MySQL bump = %+v", b)
+	}
+	if b := bumps["elasticsearch"]; b.To != "8.17" || b.NewService != "" {
+		t.Errorf("Elasticsearch bump = %+v", b)
+	}
+
+	// A release line without Elasticsearch moves to OpenSearch
+	versions.Compatibility[0].Elasticsearch = nil
+	target, err := FindTarget(versions, installed, stack, "2.4.8-p1")
+	if err != nil {
+		t.Fatalf("FindTarget failed: %v", err)
+	}
+	var moved bool
+	for _, b := range target.Bumps {
+		moved = moved || (b.Service == "elasticsearch" && b.NewService == "opensearch" && b.To == "2.19")
+	}
+	if !moved || target.Latest {
+		t.Errorf("FindTarget(2.4.8-p1) = %+v", target)
+	}
+
+	if _, err := FindTarget(versions, installed, stack, "2.4.6-p7"); err == nil {
+		t.Error("FindTarget of an older release succeeded")
+	}
+	if _, err := FindTarget(versions, installed, stack, "9.9.9"); err == nil {
+		t.Error("FindTarget of an unknown release succeeded")
+	}
+
+	// MageOS releases are grouped by minor version and checked by their base
+	mageOS := Advise(versions, &Installed{Distribution: DistMageOS, Version: "1.3.0"}, Stack{PHP: "8.4"})
+	if len(mageOS) != 4 || mageOS[0].Version != "1.3.1" || mageOS[3].Version != "2.2.2" {
+		t.Fatalf("MageOS targets = %+v", mageOS)
+	}
+	if mageOS[3].Compatibility == nil || mageOS[3].Compatibility.Magento != "2.4.8" {
+		t.Errorf("MageOS 2.2.2 compatibility = %+v", mageOS[3].Compatibility)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,440 @@
+// Copyright (c) qoliber
+// Author: Jakub Winkler <jwinkler@qoliber.com>
+
+// Package versioncatalog keeps the Magento and MageOS releases offered by
+// 'magebox new' current. The releases are fetched from the Composer
+// repositories, or from a published manifest, and cached; the bundled
+// versions.yaml is the offline fallback and provides the compatibility
+// matrix.
+package versioncatalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	libconfig "qoliber/magebox/internal/lib/config"
+)
+
+// Composer repositories the releases are fetched from
+const (
+	PackagistURL  = "https://repo.packagist.org"
+	MageOSRepoURL = "https://repo.mage-os.org"
+)
+
+// cacheFileName is the file in ~/.magebox the fetched releases are cached in
+const cacheFileName = "version-catalog.yaml"
+
+// Where the releases of a Result come from
+const (
+	SourceRemote  = "remote"
+	SourceCache   = "cache"
+	SourceBundled = "bundled"
+)
+
+// stableVersion matches releases, leaving out alphas, betas, release
+// candidates and dev branches
+var stableVersion = regexp.MustCompile(`^\d+\.\d+\.\d+(-p\d+)?$`)
+
+// phpVersion matches the major.minor version of a PHP constraint
+var phpVersion = regexp.MustCompile(`\d+\.\d+`)
+
+// Options configures a Catalog
+type Options struct {
+	// ManifestURL replaces the Composer repositories with a manifest in the
+	// versions.yaml format
+	ManifestURL string
+
+	// TTL is how long fetched releases are used before fetching again
+	TTL time.Duration
+
+	// Offline uses the bundled releases without fetching
+	Offline bool
+
+	// Refresh fetches even when the cached releases are fresh
+	Refresh bool
+}
+
+// Catalog loads the release list
+type Catalog struct {
+	mageboxDir   string
+	opts         Options
+	packagistURL string
+	mageOSURL    string
+	client       *http.Client
+}
+
+// Result is a loaded release list
+type Result struct {
+	Versions  *libconfig.VersionsConfig
+	Source    string    // SourceRemote, SourceCache or SourceBundled
+	FetchedAt time.Time // zero for the bundled releases
+	Err       error     // why fetching failed, if it did
+}
+
+// releases are the fetched releases, as cached
+type releases struct {
+	FetchedAt     time.Time                      `yaml:"fetched_at"`
+	Source        string                         `yaml:"source"`
+	Magento       []libconfig.VersionEntry       `yaml:"magento,omitempty"`
+	MageOS        []libconfig.VersionEntry       `yaml:"mageos,omitempty"`
+	Compatibility []libconfig.CompatibilityEntry `yaml:"compatibility,omitempty"`
+}
+
+// packageVersion is a version of a package in Composer v2 metadata
+type packageVersion struct {
+	Version string
+	Require map[string]string
+}
+
+// New creates a catalog for the MageBox directory (~/.magebox)
+func New(mageboxDir string, opts Options) *Catalog {
+	return &Catalog{
+		mageboxDir:   mageboxDir,
+		opts:         opts,
+		packagistURL: PackagistURL,
+		mageOSURL:    MageOSRepoURL,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Load returns the current releases. Fresh cached releases are used as they
+// are; otherwise the releases are fetched, and when that fails the cache,
+// however old, or the bundled list is used and Result.Err says why.
+func (c *Catalog) Load() (*Result, error) {
+	versions, err := libconfig.LoadVersions(c.mageboxDir)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Offline {
+		return &Result{Versions: versions, Source: SourceBundled}, nil
+	}
+
+	cached, cacheErr := c.loadCache()
+	if cacheErr == nil && !c.opts.Refresh && cached.Source == c.source() && time.Since(cached.FetchedAt) < c.opts.TTL {
+		cached.apply(versions)
+		return &Result{Versions: versions, Source: SourceCache, FetchedAt: cached.FetchedAt}, nil
+	}
+
+	fetched, err := c.fetch(versions)
+	if err != nil {
+		result := &Result{Versions: versions, Source: SourceBundled, Err: err}
+		if cacheErr == nil {
+			cached.apply(versions)
+			result.Source, result.FetchedAt = SourceCache, cached.FetchedAt
+		}
+		return result, nil
+	}
+
+	_ = c.saveCache(fetched)
+	fetched.apply(versions)
+	return &Result{Versions: versions, Source: SourceRemote, FetchedAt: fetched.FetchedAt}, nil
+}
+
+// source names where the releases are fetched from, so a cache of another
+// source isn't used as fresh
+func (c *Catalog) source() string {
+	if c.opts.ManifestURL != "" {
+		return c.opts.ManifestURL
+	}
+	return c.packagistURL + " " + c.mageOSURL
+}
+
+// fetch fetches the releases from the manifest or the Composer repositories
+func (c *Catalog) fetch(bundled *libconfig.VersionsConfig) (*releases, error) {
+	if c.opts.ManifestURL != "" {
+		return c.fetchManifest()
+	}
+
+	magento, err := c.fetchReleases(c.packagistURL, bundled.GetMagentoPackage(), bundled, false)
+	if err != nil {
+		return nil, err
+	}
+	mageOS, err := c.fetchReleases(c.mageOSURL, bundled.GetMageOSPackage(), bundled, true)
+	if err != nil {
+		return nil, err
+	}
+	return &releases{FetchedAt: time.Now(), Source: c.source(), Magento: magento, MageOS: mageOS}, nil
+}
+
+// fetchManifest fetches a manifest in the versions.yaml format. Its releases
+// and compatibility replace the bundled ones.
+func (c *Catalog) fetchManifest() (*releases, error) {
+	resp, err := c.get(c.opts.ManifestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var manifest libconfig.VersionsConfig
+	if err := yaml.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", c.opts.ManifestURL, err)
+	}
+	if len(manifest.Magento.Versions) == 0 && len(manifest.MageOS.Versions) == 0 {
+		return nil, fmt.Errorf("%s lists no releases", c.opts.ManifestURL)
+	}
+	return &releases{
+		FetchedAt:     time.Now(),
+		Source:        c.source(),
+		Magento:       manifest.Magento.Versions,
+		MageOS:        manifest.MageOS.Versions,
+		Compatibility: manifest.Compatibility,
+	}, nil
+}
+
+// fetchReleases fetches the stable releases of a project package, newest
+// first. Releases older than the oldest bundled one are out of support and
+// left out. PHP versions come from the product package when the repository
+// has it, otherwise from the bundled releases and compatibility matrix.
+func (c *Catalog) fetchReleases(repoURL, project string, bundled *libconfig.VersionsConfig, mageOS bool) ([]libconfig.VersionEntry, error) {
+	known := bundled.Magento.Versions
+	distro := "Magento"
+	if mageOS {
+		known = bundled.MageOS.Versions
+		distro = "MageOS"
+	}
+
+	projectVersions, err := c.fetchPackage(repoURL, project)
+	if err != nil {
+		return nil, err
+	}
+	productPHP := make(map[string][]string)
+	if productVersions, err := c.fetchPackage(repoURL, strings.Replace(project, "/project-", "/product-", 1)); err == nil {
+		for _, v := range productVersions {
+			productPHP[strings.TrimPrefix(v.Version, "v")] = parsePHPConstraint(v.Require["php"])
+		}
+	}
+
+	var entries []libconfig.VersionEntry
+	seen := make(map[string]bool)
+	for _, pv := range projectVersions {
+		version := strings.TrimPrefix(pv.Version, "v")
+		if !stableVersion.MatchString(version) || seen[version] || olderThanAll(version, known) {
+			continue
+		}
+		seen[version] = true
+
+		entry := libconfig.VersionEntry{Version: version, PHP: productPHP[version]}
+		if len(entry.PHP) == 0 {
+			entry.PHP = parsePHPConstraint(pv.Require["php"])
+		}
+		if mageOS {
+			entry.Base = mageOSBase(version, known)
+		}
+		if len(entry.PHP) == 0 {
+			entry.PHP = fallbackPHP(entry, known, bundled, mageOS)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s lists no releases of %s", repoURL, project)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return libconfig.CompareVersions(entries[i].Version, entries[j].Version) > 0
+	})
+	for i := range entries {
+		entries[i].Name = distro + " " + entries[i].Version
+	}
+	entries[0].Name += " (Latest)"
+	entries[0].Default = true
+	return entries, nil
+}
+
+// fetchPackage fetches the versions of a package from the Composer v2
+// metadata of a repository, expanding minified metadata
+func (c *Catalog) fetchPackage(repoURL, pkg string) ([]packageVersion, error) {
+	resp, err := c.get(repoURL + "/p2/" + pkg + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var metadata struct {
+		Packages map[string][]map[string]json.RawMessage `json:"packages"`
+		Minified string                                  `json:"minified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse the metadata of %s: %w", pkg, err)
+	}
+
+	raw := metadata.Packages[pkg]
+	if metadata.Minified == "composer/2.0" {
+		raw = expandMinified(raw)
+	}
+
+	var versions []packageVersion
+	for _, fields := range raw {
+		var v packageVersion
+		if err := json.Unmarshal(fields["version"], &v.Version); err != nil {
+			continue
+		}
+		_ = json.Unmarshal(fields["require"], &v.Require)
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// get fetches a URL, failing on anything but 200 OK
+func (c *Catalog) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "MageBox-VersionCatalog")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// expandMinified expands Composer v2 minified metadata, where each version
+// only lists the fields that differ from the version before it and
+// "__unset" removes a field
+func expandMinified(raw []map[string]json.RawMessage) []map[string]json.RawMessage {
+	expanded := make([]map[string]json.RawMessage, 0, len(raw))
+	current := make(map[string]json.RawMessage)
+	for _, fields := range raw {
+		next := maps.Clone(current)
+		for key, value := range fields {
+			if string(value) == `"__unset"` {
+				delete(next, key)
+				continue
+			}
+			next[key] = value
+		}
+		expanded = append(expanded, next)
+		current = next
+	}
+	return expanded
+}
+
+// parsePHPConstraint returns the PHP versions of a constraint such as
+// "~8.2.0||~8.3.0||~8.4.0", newest first
+func parsePHPConstraint(constraint string) []string {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, alternative := range strings.Split(constraint, "|") {
+		if v := phpVersion.FindString(alternative); v != "" && !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return libconfig.CompareVersions(versions[i], versions[j]) > 0
+	})
+	return versions
+}
+
+// olderThanAll reports whether a version is older than all known releases
+func olderThanAll(version string, known []libconfig.VersionEntry) bool {
+	if len(known) == 0 {
+		return false
+	}
+	for _, k := range known {
+		if libconfig.CompareVersions(version, k.Version) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mageOSBase returns the Magento version a MageOS release is based on: that
+// of the bundled release, or of the newest bundled release of the same
+// major.minor version
+func mageOSBase(version string, known []libconfig.VersionEntry) string {
+	for _, k := range known {
+		if k.Version == version {
+			return k.Base
+		}
+	}
+	for _, k := range known {
+		if libconfig.MinorVersion(k.Version) == libconfig.MinorVersion(version) {
+			return k.Base
+		}
+	}
+	return ""
+}
+
+// fallbackPHP returns the PHP versions of a release the repository doesn't
+// list them for: those of the bundled release, of its Magento release line
+// in the compatibility matrix, or of the newest bundled release
+func fallbackPHP(entry libconfig.VersionEntry, known []libconfig.VersionEntry, bundled *libconfig.VersionsConfig, mageOS bool) []string {
+	for _, k := range known {
+		if k.Version == entry.Version {
+			return k.PHP
+		}
+	}
+	magentoVersion := entry.Version
+	if mageOS {
+		magentoVersion = entry.Base
+	}
+	if compat := bundled.GetCompatibility(magentoVersion); compat != nil && len(compat.PHP) > 0 {
+		return compat.PHP
+	}
+	if len(known) > 0 {
+		return known[0].PHP
+	}
+	return nil
+}
+
+// apply replaces the releases and compatibility of a release list
+func (r *releases) apply(versions *libconfig.VersionsConfig) {
+	if len(r.Magento) > 0 {
+		versions.Magento.Versions = r.Magento
+	}
+	if len(r.MageOS) > 0 {
+		versions.MageOS.Versions = r.MageOS
+	}
+	if len(r.Compatibility) > 0 {
+		versions.Compatibility = r.Compatibility
+	}
+}
+
+func (c *Catalog) cachePath() string {
+	return filepath.Join(c.mageboxDir, cacheFileName)
+}
+
+func (c *Catalog) loadCache() (*releases, error) {
+	data, err := os.ReadFile(c.cachePath())
+	if err != nil {
+		return nil, err
+	}
+	var cached releases
+	if err := yaml.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// saveCache writes the cache through a temporary file, so an interrupted
+// write doesn't leave a broken cache
+func (c *Catalog) saveCache(r *releases) error {
+	if err := os.MkdirAll(c.mageboxDir, 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	tmp := c.cachePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.cachePath())
+}
@@ -0,0 +1,204 @@
+package versioncatalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// magentoMetadata is minified Composer v2 metadata: the versions after the
+// first only list what changed
+const magentoMetadata = `{
+  "minified": "composer/2.0",
+  "packages": {
+    "magento/project-community-edition": [
+      {"version": "2.4.9-beta1", "require": {"magento/product-community-edition": "2.4.9-beta1"}},
+      {"version": "2.4.8-p5", "require": {"magento/product-community-edition": "2.4.8-p5"}},
+      {"version": "2.4.8", "require": {"magento/product-community-edition": "2.4.8"}},
+      {"version": "2.4.7-p9", "require": {"magento/product-community-edition": "2.4.7-p9"}},
+      {"version": "2.4.6-p1", "require": "__unset"}
+    ]
+  }
+}`
+
+const mageOSMetadata = `{
+  "minified": "composer/2.0",
+  "packages": {
+    "mage-os/project-community-edition": [
+      {"version": "2.3.0"},
+      {"version": "2.2.2"},
+      {"version": "1.0.4"}
+    ]
+  }
+}`
+
+const mageOSProductMetadata = `{
+  "minified": "composer/2.0",
+  "packages": {
+    "mage-os/product-community-edition": [
+      {"version": "2.3.0", "require": {"php": "~8.3.0||~8.4.0||~8.5.0"}},
+      {"version": "2.2.2"}
+    ]
+  }
+}`
+
+func newTestRepository(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestCatalog(t *testing.T, server *httptest.Server, opts Options) *Catalog {
+	t.Helper()
+	c := New(t.TempDir(), opts)
+	c.packagistURL = server.URL
+	c.mageOSURL = server.URL
+	return c
+}
+
+func TestCatalog_LoadFetchesReleases(t *testing.T) {
+	server := newTestRepository(t, map[string]string{
+		"/p2/magento/project-community-edition.json": magentoMetadata,
+		"/p2/mage-os/project-community-edition.json": mageOSMetadata,
+		"/p2/mage-os/product-community-edition.json": mageOSProductMetadata,
+	})
+	c := newTestCatalog(t, server, Options{TTL: time.Hour})
+
+	result, err := c.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if result.Source != SourceRemote || result.Err != nil {
+		t.Fatalf("Source = %s, Err = %v, want remote releases", result.Source, result.Err)
+	}
+
+	// Betas and releases older than the bundled ones are left out
+	magento := result.Versions.GetMagentoVersions()
+	var versions []string
+	for _, v := range magento {
+		versions = append(versions, v.Version)
+	}
+	if !slices.Equal(versions, []string{"2.4.8-p5", "2.4.8", "2.4.7-p9"}) {
+		t.Fatalf("Magento versions = %v", versions)
+	}
+	if !magento[0].Default || magento[0].Name != "Magento 2.4.8-p5 (Latest)" || magento[1].Default {
+		t.Errorf("latest release = %+v", magento[0])
+	}
+	// Packagist has no PHP constraints, they come from the compatibility matrix
+	if !slices.Equal(magento[0].PHP, []string{"8.4", "8.3", "8.2"}) || !slices.Equal(magento[2].PHP, []string{"8.3", "8.2"}) {
+		t.Errorf("PHP = %v and %v", magento[0].PHP, magento[2].PHP)
+	}
+
+	mageOS := result.Versions.GetMageOSVersions()
+	if len(mageOS) != 2 || mageOS[0].Version != "2.3.0" {
+		t.Fatalf("MageOS versions = %+v", mageOS)
+	}
+	if !slices.Equal(mageOS[0].PHP, []string{"8.5", "8.4", "8.3"}) {
+		t.Errorf("MageOS 2.3.0 PHP = %v, want the product constraint", mageOS[0].PHP)
+	}
+	// The base of a new minor release is unknown
+	if mageOS[0].Base != "" || mageOS[1].Base != "2.4.8" {
+		t.Errorf("MageOS bases = %q, %q", mageOS[0].Base, mageOS[1].Base)
+	}
+	if compat := result.Versions.GetMageOSCompatibility("2.2.2"); compat == nil || compat.Magento != "2.4.8" {
+		t.Errorf("GetMageOSCompatibility(2.2.2) = %+v", compat)
+	}
+
+	if _, err := os.Stat(filepath.Join(c.mageboxDir, cacheFileName)); err != nil {
+		t.Errorf("releases were not cached: %v", err)
+	}
+}
+
+func TestCatalog_LoadFallsBack(t *testing.T) {
+	server := newTestRepository(t, map[string]string{
+		"/p2/magento/project-community-edition.json": magentoMetadata,
+		"/p2/mage-os/project-community-edition.json": mageOSMetadata,
+	})
+	c := newTestCatalog(t, server, Options{TTL: time.Hour})
+	if _, err := c.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// A fresh cache is used without fetching
+	server.Close()
+	result, err := c.Load()
+	if err != nil || result.Source != SourceCache || result.Err != nil {
+		t.Fatalf("Load with a fresh cache = %+v, %v", result, err)
+	}
+
+	// When fetching fails, an outdated cache is still used
+	c.opts.Refresh = true
+	result, err = c.Load()
+	if err != nil || result.Source != SourceCache || result.Err == nil {
+		t.Fatalf("Load offline with a cache = %+v, %v", result, err)
+	}
+	if result.Versions.GetMagentoVersions()[0].Version != "2.4.8-p5" {
+		t.Errorf("cached releases = %+v", result.Versions.GetMagentoVersions())
+	}
+
+	// Without a cache, the bundled releases are used
+	os.Remove(filepath.Join(c.mageboxDir, cacheFileName))
+	result, err = c.Load()
+	if err != nil || result.Source != SourceBundled || result.Err == nil {
+		t.Fatalf("Load offline without a cache = %+v, %v", result, err)
+	}
+	if len(result.Versions.GetMagentoVersions()) == 0 {
+		t.Error("bundled releases are empty")
+	}
+
+	c.opts.Offline = true
+	if result, _ := c.Load(); result.Source != SourceBundled || result.Err != nil {
+		t.Errorf("Load in offline mode = %+v", result)
+	}
+}
+
+func TestCatalog_LoadManifest(t *testing.T) {
+	server := newTestRepository(t, map[string]string{
+		"/versions.json": `{
+  "magento": {"versions": [{"version": "2.4.9", "name": "Magento 2.4.9", "php": ["8.5", "8.4"], "default": true}]},
+  "compatibility": [{"magento": "2.4.9", "php": ["8.5", "8.4"], "mysql": ["8.4"]}]
+}`,
+	})
+	c := newTestCatalog(t, server, Options{ManifestURL: server.URL + "/versions.json", TTL: time.Hour})
+
+	result, err := c.Load()
+	if err != nil || result.Source != SourceRemote {
+		t.Fatalf("Load = %+v, %v", result, err)
+	}
+	if v := result.Versions.GetDefaultMagentoVersion(); v == nil || v.Version != "2.4.9" {
+		t.Errorf("default Magento version = %+v", v)
+	}
+	// The manifest lists no MageOS releases, the bundled ones stay
+	if len(result.Versions.GetMageOSVersions()) == 0 {
+		t.Error("MageOS releases were dropped")
+	}
+	if compat := result.Versions.GetCompatibility("2.4.9-p1"); compat == nil || compat.Supports("mysql", "8.0") {
+		t.Errorf("GetCompatibility(2.4.9-p1) = %+v", compat)
+	}
+}
+
+func TestParsePHPConstraint(t *testing.T) {
+	tests := map[string][]string{
+		"~8.2.0||~8.3.0||~8.4.0": {"8.4", "8.3", "8.2"},
+		"~7.4.0|~8.1.0":          {"8.1", "7.4"},
+		">=8.1":                  {"8.1"},
+		"":                       nil,
+	}
+	for constraint, want := range tests {
+		if got := parsePHPConstraint(constraint); !slices.Equal(got, want) {
+			t.Errorf("parsePHPConstraint(%q) = %v, want %v", constraint, got, want)
+		}
+	}
+}
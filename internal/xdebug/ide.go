@@ -0,0 +1,164 @@
+package xdebug
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VSCodeLaunchName is the name of the launch configuration MageBox adds to
+// .vscode/launch.json
+const VSCodeLaunchName = "Listen for Xdebug (MageBox)"
+
+// PhpStormServer is a PHP server in PhpStorm's project settings. PhpStorm picks
+// the server whose name matches the host of the request being debugged.
+type PhpStormServer struct {
+	Name        string // Host name, also the server name
+	Port        int    // 443 with SSL, 80 without
+	ProjectPath string // Path PHP-FPM sees the project at
+}
+
+// CheckClient opens a test connection to the debug client, which succeeds
+// when the IDE listens for debug connections
+func CheckClient(host, port string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), timeout)
+	if err != nil {
+		return fmt.Errorf("nothing listens on %s:%s", host, port)
+	}
+	return conn.Close()
+}
+
+// WriteVSCodeLaunch adds a PHP Debug listen configuration to
+// .vscode/launch.json, creating the file if needed. It returns false when the
+// file already has the MageBox configuration.
+func WriteVSCodeLaunch(projectPath, port string) (bool, error) {
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return false, fmt.Errorf("invalid port %q", port)
+	}
+
+	launchPath := filepath.Join(projectPath, ".vscode", "launch.json")
+	launch := map[string]interface{}{"version": "0.2.0"}
+
+	if data, err := os.ReadFile(launchPath); err == nil {
+		if err := json.Unmarshal(data, &launch); err != nil {
+			return false, fmt.Errorf("%s is not plain JSON (comments?), add the configuration by hand", launchPath)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	configurations, _ := launch["configurations"].([]interface{})
+	for _, c := range configurations {
+		if m, ok := c.(map[string]interface{}); ok && m["name"] == VSCodeLaunchName {
+			return false, nil
+		}
+	}
+
+	// PHP-FPM runs on the host, so the paths only differ when the editor
+	// opened the project through another path, e.g. a symlink
+	launch["configurations"] = append(configurations, map[string]interface{}{
+		"name":    VSCodeLaunchName,
+		"type":    "php",
+		"request": "launch",
+		"port":    portNum,
+		"pathMappings": map[string]string{
+			projectPath: "${workspaceFolder}",
+		},
+	})
+
+	data, err := json.MarshalIndent(launch, "", "    ")
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(launchPath), 0755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(launchPath, append(data, '\n'), 0644)
+}
+
+// WritePhpStormServer adds a PHP server to .idea/workspace.xml, creating the
+// file if needed. It returns false when a server of that name exists.
+// PhpStorm rewrites the file while the project is open, so it should be closed.
+func WritePhpStormServer(projectPath string, server PhpStormServer) (bool, error) {
+	workspacePath := filepath.Join(projectPath, ".idea", "workspace.xml")
+
+	content := ""
+	if data, err := os.ReadFile(workspacePath); err == nil {
+		content = string(data)
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	serverXML := phpStormServerXML(server)
+	start := strings.Index(content, `<component name="PhpServers">`)
+	switch {
+	case content == "":
+		content = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<project version=\"4\">\n" +
+			phpStormServersComponent(serverXML) + "</project>\n"
+	case start >= 0:
+		component := content[start:]
+		if end := strings.Index(component, "</component>"); end >= 0 {
+			component = component[:end]
+		}
+		if strings.Contains(component, fmt.Sprintf(`name="%s"`, xmlEscape(server.Name))) {
+			return false, nil
+		}
+		i := strings.Index(component, "</servers>")
+		if i < 0 {
+			return false, fmt.Errorf("%s has no PHP servers list, add the server by hand", workspacePath)
+		}
+		// Insert at the start of the line closing the list
+		i = strings.LastIndex(content[:start+i], "\n") + 1
+		content = content[:i] + serverXML + content[i:]
+	case strings.Contains(content, "</project>"):
+		i := strings.LastIndex(content, "</project>")
+		content = content[:i] + phpStormServersComponent(serverXML) + content[i:]
+	default:
+		return false, fmt.Errorf("%s is not a PhpStorm workspace file", workspacePath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(workspacePath), 0755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(workspacePath, []byte(content), 0644)
+}
+
+// phpStormServersComponent wraps server elements in the PhpServers component
+func phpStormServersComponent(serverXML string) string {
+	return "  <component name=\"PhpServers\">\n    <servers>\n" + serverXML + "    </servers>\n  </component>\n"
+}
+
+// phpStormServerXML returns the server element of a PHP server, indented to
+// sit in the servers element
+func phpStormServerXML(server PhpStormServer) string {
+	return fmt.Sprintf(`      <server name="%s" host="%s" port="%d" id="%s" use_path_mappings="true">
+        <path_mappings>
+          <mapping local-root="$PROJECT_DIR$" remote-root="%s" />
+        </path_mappings>
+      </server>
+`, xmlEscape(server.Name), xmlEscape(server.Name), server.Port, newServerID(), xmlEscape(server.ProjectPath))
+}
+
+// xmlEscape escapes a value for an XML attribute
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// newServerID returns a random UUID, which PhpStorm uses as server id
+func newServerID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,144 @@
+package xdebug
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteVSCodeLaunch(t *testing.T) {
+	dir := t.TempDir()
+	launchPath := filepath.Join(dir, ".vscode", "launch.json")
+	if err := os.MkdirAll(filepath.Dir(launchPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := `{"version": "0.2.0", "configurations": [{"name": "Launch script", "type": "php", "request": "launch"}]}`
+	if err := os.WriteFile(launchPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := WriteVSCodeLaunch(dir, "9003")
+	if err != nil || !written {
+		t.Fatalf("WriteVSCodeLaunch() = %v, %v, want true", written, err)
+	}
+
+	var launch struct {
+		Configurations []map[string]interface{} `json:"configurations"`
+	}
+	data, _ := os.ReadFile(launchPath)
+	if err := json.Unmarshal(data, &launch); err != nil {
+		t.Fatalf("launch.json is not valid JSON: %v", err)
+	}
+	if len(launch.Configurations) != 2 {
+		t.Fatalf("configurations = %v, want the existing one and MageBox's", launch.Configurations)
+	}
+	added := launch.Configurations[1]
+	if added["name"] != VSCodeLaunchName || added["port"] != float64(9003) {
+		t.Errorf("added configuration = %v", added)
+	}
+	if mappings := added["pathMappings"].(map[string]interface{}); mappings[dir] != "${workspaceFolder}" {
+		t.Errorf("pathMappings = %v", mappings)
+	}
+
+	if written, err := WriteVSCodeLaunch(dir, "9003"); err != nil || written {
+		t.Errorf("second WriteVSCodeLaunch() = %v, %v, want false", written, err)
+	}
+}
+
+func TestWriteVSCodeLaunch_Comments(t *testing.T) {
+	dir := t.TempDir()
+	launchPath := filepath.Join(dir, ".vscode", "launch.json")
+	_ = os.MkdirAll(filepath.Dir(launchPath), 0755)
+	_ = os.WriteFile(launchPath, []byte("{\n  // comment\n  \"configurations\": []\n}"), 0644)
+
+	if _, err := WriteVSCodeLaunch(dir, "9003"); err == nil {
+		t.Error("expected an error for a launch.json with comments")
+	}
+}
+
+func TestWritePhpStormServer(t *testing.T) {
+	server := PhpStormServer{Name: "mystore.test", Port: 443, ProjectPath: "/home/dev/mystore"}
+
+	t.Run("new file", func(t *testing.T) {
+		dir := t.TempDir()
+		written, err := WritePhpStormServer(dir, server)
+		if err != nil || !written {
+			t.Fatalf("WritePhpStormServer() = %v, %v, want true", written, err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, ".idea", "workspace.xml"))
+		content := string(data)
+		for _, want := range []string{`<component name="PhpServers">`, `name="mystore.test"`, `port="443"`, `remote-root="/home/dev/mystore"`} {
+			if !strings.Contains(content, want) {
+				t.Errorf("workspace.xml misses %s:\n%s", want, content)
+			}
+		}
+
+		if written, err := WritePhpStormServer(dir, server); err != nil || written {
+			t.Errorf("second WritePhpStormServer() = %v, %v, want false", written, err)
+		}
+	})
+
+	t.Run("existing servers", func(t *testing.T) {
+		dir := t.TempDir()
+		workspace := `<?xml version="1.0" encoding="UTF-8"?>
+<project version="4">
+  <component name="PhpServers">
+    <servers>
+      <server host="other.test" id="1" name="other.test" />
+    </servers>
+  </component>
+</project>
+`
+		_ = os.MkdirAll(filepath.Join(dir, ".idea"), 0755)
+		_ = os.WriteFile(filepath.Join(dir, ".idea", "workspace.xml"), []byte(workspace), 0644)
+
+		if written, err := WritePhpStormServer(dir, server); err != nil || !written {
+			t.Fatalf("WritePhpStormServer() = %v, %v, want true", written, err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, ".idea", "workspace.xml"))
+		content := string(data)
+		if strings.Count(content, "<servers>") != 1 || !strings.Contains(content, `name="other.test"`) {
+			t.Errorf("workspace.xml = %s", content)
+		}
+		if !strings.Contains(content, "      <server name=\"mystore.test\"") {
+			t.Errorf("server not added to the list:\n%s", content)
+		}
+	})
+
+	t.Run("other components", func(t *testing.T) {
+		dir := t.TempDir()
+		workspace := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<project version=\"4\">\n  <component name=\"ChangeListManager\" />\n</project>\n"
+		_ = os.MkdirAll(filepath.Join(dir, ".idea"), 0755)
+		_ = os.WriteFile(filepath.Join(dir, ".idea", "workspace.xml"), []byte(workspace), 0644)
+
+		if written, err := WritePhpStormServer(dir, server); err != nil || !written {
+			t.Fatalf("WritePhpStormServer() = %v, %v, want true", written, err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, ".idea", "workspace.xml"))
+		content := string(data)
+		if !strings.Contains(content, "ChangeListManager") || !strings.HasSuffix(content, "  </component>\n</project>\n") {
+			t.Errorf("workspace.xml = %s", content)
+		}
+	})
+}
+
+func TestCheckClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	if err := CheckClient("127.0.0.1", port, time.Second); err != nil {
+		t.Errorf("CheckClient() with a listener = %v", err)
+	}
+
+	_ = ln.Close()
+	if err := CheckClient("127.0.0.1", port, time.Second); err == nil {
+		t.Error("CheckClient() without a listener succeeded")
+	}
+}
@@ -105,14 +105,32 @@ func (m *Manager) Enable(phpVersion string) error {
 		return fmt.Errorf("failed to enable xdebug: %w", err)
 	}
 
-	// Write Xdebug configuration settings
-	if err := m.writeConfig(phpVersion, DefaultXdebugConfig()); err != nil {
+	// Write Xdebug configuration settings, keeping the mode set before
+	cfg := DefaultXdebugConfig()
+	cfg.Mode = m.getXdebugMode(phpVersion)
+	if err := m.writeConfig(phpVersion, cfg); err != nil {
 		return fmt.Errorf("failed to write xdebug config: %w", err)
 	}
 
 	return nil
 }
 
+// SetMode sets xdebug.mode in the xdebug ini of a PHP version. Xdebug only
+// reads the mode at startup, so it applies to every project on that version
+// once PHP-FPM restarts. It reports whether the mode changed.
+func (m *Manager) SetMode(phpVersion, mode string) (bool, error) {
+	if m.getXdebugMode(phpVersion) == mode {
+		return false, nil
+	}
+
+	cfg := DefaultXdebugConfig()
+	cfg.Mode = mode
+	if err := m.writeConfig(phpVersion, cfg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Disable disables Xdebug for a specific PHP version
 func (m *Manager) Disable(phpVersion string) error {
 	iniFile := m.getXdebugIniPath(phpVersion)
@@ -65,7 +65,9 @@ export default defineConfig({
             { text: 'Bootstrap', link: '/guide/bootstrap' },
             { text: 'Laravel Support', link: '/guide/laravel' },
             { text: 'Common Workflows', link: '/guide/workflows' },
-            { text: 'Hyvä Theme', link: '/guide/hyva' }
+            { text: 'Project Templates', link: '/guide/project-templates' },
+            { text: 'Hyvä Theme', link: '/guide/hyva' },
+            { text: 'Frontend Tooling', link: '/guide/frontend' }
           ]
         },
         {
@@ -93,7 +95,7 @@ export default defineConfig({
           items: [
             { text: 'Nginx', link: '/services/nginx' },
             { text: 'PHP-FPM', link: '/services/php-fpm' },
-            { text: 'Database (MySQL/MariaDB)', link: '/services/database' },
+            { text: 'Database (MySQL/MariaDB/Percona)', link: '/services/database' },
             { text: 'Redis', link: '/services/redis' },
             { text: 'OpenSearch/Elasticsearch', link: '/services/opensearch' },
             { text: 'RabbitMQ', link: '/services/rabbitmq' },
@@ -184,7 +186,9 @@ export default defineConfig({
             { text: 'Bootstrap', link: '/guide/bootstrap' },
             { text: 'Laravel Support', link: '/guide/laravel' },
             { text: 'Common Workflows', link: '/guide/workflows' },
-            { text: 'Hyvä Theme', link: '/guide/hyva' }
+            { text: 'Project Templates', link: '/guide/project-templates' },
+            { text: 'Hyvä Theme', link: '/guide/hyva' },
+            { text: 'Frontend Tooling', link: '/guide/frontend' }
           ]
         },
         {
@@ -212,7 +216,7 @@ export default defineConfig({
           items: [
             { text: 'Nginx', link: '/services/nginx' },
             { text: 'PHP-FPM', link: '/services/php-fpm' },
-            { text: 'Database (MySQL/MariaDB)', link: '/services/database' },
+            { text: 'Database (MySQL/MariaDB/Percona)', link: '/services/database' },
             { text: 'Redis', link: '/services/redis' },
             { text: 'OpenSearch/Elasticsearch', link: '/services/opensearch' },
             { text: 'RabbitMQ', link: '/services/rabbitmq' },